@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"log"
@@ -10,9 +11,11 @@ import (
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/pressly/goose/v3"
 	"github.com/skufu/DianaV2/backend/internal/config"
+	"github.com/skufu/DianaV2/backend/migrations"
 )
 
-const migrationsDir = "./migrations"
+// migrationsDir is the root of the embedded migrations
+const migrationsDir = "."
 
 func main() {
 	// Load .env file if it exists
@@ -24,10 +27,15 @@ func main() {
 	}
 
 	// Define command-line flags
-	command := flag.String("command", "up", "Migration command: up, down, status, reset, version, create")
+	command := flag.String("command", "up", "Migration command: up, up-to, down, down-to, plan, status, reset, version, create")
 	name := flag.String("name", "", "Name for new migration (used with 'create' command)")
+	dir := flag.String("dir", "migrations", "Directory new migrations are written to (used with 'create' command)")
+	version := flag.Int64("version", -1, "Target version (used with 'up-to', 'down-to' and 'plan' commands)")
 	flag.Parse()
 
+	// Every command but create reads the migrations built into the binary
+	goose.SetBaseFS(migrations.FS)
+
 	// Open database connection using pgx stdlib driver
 	db, err := sql.Open("pgx", cfg.DBDSN)
 	if err != nil {
@@ -52,12 +60,35 @@ func main() {
 		}
 		log.Println("migrations applied successfully")
 
+	case "up-to":
+		if *version < 1 {
+			log.Fatal("a target version is required for 'up-to' command (use -version flag)")
+		}
+		if err := goose.UpTo(db, migrationsDir, *version); err != nil {
+			log.Fatalf("migration up-to failed: %v", err)
+		}
+		log.Printf("migrations applied up to version %d", *version)
+
 	case "down":
 		if err := goose.Down(db, migrationsDir); err != nil {
 			log.Fatalf("migration down failed: %v", err)
 		}
 		log.Println("last migration reverted successfully")
 
+	case "down-to":
+		if *version < 0 {
+			log.Fatal("a target version is required for 'down-to' command (use -version flag)")
+		}
+		if err := goose.DownTo(db, migrationsDir, *version); err != nil {
+			log.Fatalf("migration down-to failed: %v", err)
+		}
+		log.Printf("migrations reverted down to version %d", *version)
+
+	case "plan":
+		if err := printPlan(context.Background(), os.Stdout, db, *version); err != nil {
+			log.Fatalf("migration plan failed: %v", err)
+		}
+
 	case "status":
 		if err := goose.Status(db, migrationsDir); err != nil {
 			log.Fatalf("migration status failed: %v", err)
@@ -78,7 +109,8 @@ func main() {
 		if *name == "" {
 			log.Fatal("migration name is required for 'create' command (use -name flag)")
 		}
-		if err := goose.Create(db, migrationsDir, *name, "sql"); err != nil {
+		goose.SetBaseFS(nil)
+		if err := goose.Create(db, *dir, *name, "sql"); err != nil {
 			log.Fatalf("failed to create migration: %v", err)
 		}
 		log.Printf("migration '%s' created successfully", *name)
@@ -97,7 +129,7 @@ func main() {
 
 	default:
 		log.Printf("unknown command: %s", *command)
-		log.Println("available commands: up, down, status, reset, version, create, redo, up-one")
+		log.Println("available commands: up, up-to, down, down-to, plan, status, reset, version, create, redo, up-one")
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/pressly/goose/v3"
+	"github.com/skufu/DianaV2/backend/migrations"
+)
+
+// printPlan writes the migrations that up (or up-to target, when target is
+// positive) would apply and the SQL each would run. It only reads the
+// database, so it is safe to point at production.
+func printPlan(ctx context.Context, w io.Writer, db *sql.DB, target int64) error {
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return err
+	}
+	all, err := goose.CollectMigrations(migrationsDir, 0, goose.MaxVersion)
+	if err != nil {
+		return err
+	}
+	plan := pendingMigrations(all, applied, target)
+	if len(plan) == 0 {
+		fmt.Fprintln(w, "no pending migrations")
+		return nil
+	}
+	for _, m := range plan {
+		body, err := fs.ReadFile(migrations.FS, m.Source)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "-- version %d: %s\n%s\n\n", m.Version, m.Source, upSection(string(body)))
+	}
+	fmt.Fprintf(w, "-- %d pending migration(s)\n", len(plan))
+	return nil
+}
+
+// appliedVersions reads which versions the goose version table records as
+// applied, the latest row of each version winning. A database without the
+// table has applied none; unlike goose, this does not create it.
+func appliedVersions(ctx context.Context, db *sql.DB) (map[int64]bool, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", goose.TableName()).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up the version table: %w", err)
+	}
+	applied := map[int64]bool{}
+	if !exists {
+		return applied, nil
+	}
+	rows, err := db.QueryContext(ctx, "SELECT version_id, is_applied FROM "+goose.TableName()+" ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the version table: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		if err := rows.Scan(&version, &isApplied); err != nil {
+			return nil, err
+		}
+		applied[version] = isApplied
+	}
+	return applied, rows.Err()
+}
+
+// pendingMigrations keeps the migrations not yet applied, up to target when
+// it is positive
+func pendingMigrations(all goose.Migrations, applied map[int64]bool, target int64) goose.Migrations {
+	var pending goose.Migrations
+	for _, m := range all {
+		if m.Version == 0 || applied[m.Version] || (target > 0 && m.Version > target) {
+			continue
+		}
+		pending = append(pending, m)
+	}
+	return pending
+}
+
+// upSection is the SQL between the -- +goose Up and -- +goose Down
+// annotations of a migration file
+func upSection(body string) string {
+	_, up, found := strings.Cut(body, "-- +goose Up")
+	if !found {
+		return ""
+	}
+	up, _, _ = strings.Cut(up, "-- +goose Down")
+	return strings.TrimSpace(up)
+}
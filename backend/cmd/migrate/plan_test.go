@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+	"github.com/skufu/DianaV2/backend/migrations"
+)
+
+func TestPendingMigrations(t *testing.T) {
+	goose.SetBaseFS(migrations.FS)
+	defer goose.SetBaseFS(nil)
+	all, err := goose.CollectMigrations(migrationsDir, 0, goose.MaxVersion)
+	if err != nil || len(all) < 3 {
+		t.Fatalf("collect: %d migrations, %v", len(all), err)
+	}
+	first, second, last := all[0].Version, all[1].Version, all[len(all)-1].Version
+
+	if got := pendingMigrations(all, map[int64]bool{}, 0); len(got) != len(all) {
+		t.Errorf("fresh database: expected %d pending, got %d", len(all), len(got))
+	}
+	got := pendingMigrations(all, map[int64]bool{first: true, second: false}, all[2].Version)
+	if len(got) != 2 || got[0].Version != second || got[1].Version != all[2].Version {
+		t.Errorf("expected the rolled back %d and %d up to the target, got %v", second, all[2].Version, got)
+	}
+	applied := map[int64]bool{}
+	for _, m := range all {
+		applied[m.Version] = true
+	}
+	if got := pendingMigrations(all, applied, last); len(got) != 0 {
+		t.Errorf("up to date: expected nothing pending, got %v", got)
+	}
+}
+
+func TestUpSection(t *testing.T) {
+	body := "-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE t (id int);\n-- +goose StatementEnd\n\n-- +goose Down\nDROP TABLE t;\n"
+	got := upSection(body)
+	if !strings.Contains(got, "CREATE TABLE t") || strings.Contains(got, "DROP TABLE") {
+		t.Errorf("expected only the up SQL, got %q", got)
+	}
+	if got := upSection("SELECT 1;"); got != "" {
+		t.Errorf("expected nothing without an Up annotation, got %q", got)
+	}
+}
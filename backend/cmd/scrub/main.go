@@ -0,0 +1,264 @@
+// Command scrub irreversibly anonymizes a copy of the production database so
+// it can back a staging environment. Names, emails, contact details and free
+// text are replaced, each patient's dates are moved by a random per-patient
+// offset (intervals between a patient's visits are kept), and credentials
+// and tokens are removed. Clinical values, clusters and risk scores are left
+// as they are, so analytics keep their shape.
+//
+// It must never point at production itself: -confirm has to repeat the name
+// of the database being scrubbed.
+//
+//	go run ./cmd/scrub -dsn postgres://.../diana_staging -confirm diana_staging
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"github.com/skufu/DianaV2/backend/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// scrubStep is one anonymizing statement. Steps run in order in a single
+// transaction, so a failed run leaves the copy untouched.
+type scrubStep struct {
+	name string
+	sql  string
+}
+
+// scrubbedTables lists every table scrub rewrites or empties. keptTables
+// lists the rest with the reason they hold nothing personal; a test checks
+// that every table in migrations/ appears in one of the two.
+var scrubbedTables = []string{
+	"patients", "assessments", "patient_contacts", "patient_photos",
+	"risk_alerts", "baseline_discrepancies", "users", "audit_events",
+	"patient_versions", "clinics", "refresh_tokens", "email_verification_tokens",
+	"password_reset_tokens", "api_tokens", "rate_limit_buckets", "experiment_exposures",
+	"user_deletions", "webhooks", "webhook_deliveries", "assessment_drafts",
+	"api_keys", "user_mfa", "user_backup_codes", "patient_notes",
+	"assessment_attachments", "patient_medications", "follow_ups",
+	"tasks", "tags", "patient_list_views", "login_history",
+}
+
+var keptTables = map[string]string{
+	"model_runs":             "model versions and training notes",
+	"user_clinics":           "memberships keyed by id only",
+	"prediction_cache":       "model outputs keyed by a hash of clinical values",
+	"assessment_predictions": "model outputs keyed by assessment id",
+	"mfa_required_roles":     "role names only",
+	"patient_tags":           "tag memberships keyed by id only",
+	"role_permissions":       "role and permission names keyed by user id",
+}
+
+// scrubSteps builds the statements. Dates move by up to maxShiftDays either
+// way; ages above 89 are capped at 90 as in the HIPAA Safe Harbor rule.
+func scrubSteps(maxShiftDays int, passwordHash string) []scrubStep {
+	return []scrubStep{
+		// Audit actors are matched to users before their emails change
+		{"audit actors", `
+			UPDATE audit_events ae
+			SET actor = COALESCE(
+				(SELECT 'user' || u.id || '@example.invalid' FROM users u WHERE u.email = ae.actor),
+				'redacted')
+			WHERE ae.actor IS NOT NULL`},
+		{"user deletion actors", `
+			UPDATE user_deletions d
+			SET requested_by = COALESCE(
+				(SELECT 'user' || u.id || '@example.invalid' FROM users u WHERE u.email = d.requested_by),
+				'redacted'),
+			    resolved_by = CASE WHEN d.resolved_by IS NULL THEN NULL ELSE COALESCE(
+				(SELECT 'user' || u.id || '@example.invalid' FROM users u WHERE u.email = d.resolved_by),
+				'redacted') END`},
+		// Details hold request paths, IPs and changed values
+		{"audit details", `UPDATE audit_events SET details = NULL WHERE details IS NOT NULL`},
+		// Snapshots hold names and MRNs as they were before each edit
+		{"patient versions", `DELETE FROM patient_versions`},
+		{"patient date offsets", `
+			CREATE TEMP TABLE scrub_shift ON COMMIT DROP AS
+			SELECT id AS patient_id,
+			       make_interval(days => floor(random() * (2 * ` + strconv.Itoa(maxShiftDays) + ` + 1))::int - ` + strconv.Itoa(maxShiftDays) + `) AS shift
+			FROM patients`},
+		{"patients", `
+			UPDATE patients p
+			SET name = 'Patient ' || p.id,
+			    mrn = CASE WHEN COALESCE(p.mrn, '') = '' THEN p.mrn ELSE 'MRN-' || lpad(p.id::text, 8, '0') END,
+			    age = LEAST(p.age, 90),
+			    created_at = p.created_at + s.shift,
+			    updated_at = p.updated_at + s.shift
+			FROM scrub_shift s
+			WHERE s.patient_id = p.id`},
+		{"assessment dates", `
+			UPDATE assessments a
+			SET created_at = a.created_at + s.shift, updated_at = a.updated_at + s.shift
+			FROM scrub_shift s
+			WHERE s.patient_id = a.patient_id`},
+		{"risk alert dates", `
+			UPDATE risk_alerts r
+			SET created_at = r.created_at + s.shift, delivered_at = r.delivered_at + s.shift
+			FROM scrub_shift s
+			WHERE s.patient_id = r.patient_id`},
+		// Shifted with the patient so follow-up outcomes keep their timing
+		{"experiment exposure dates", `
+			UPDATE experiment_exposures e
+			SET exposed_at = e.exposed_at + s.shift
+			FROM scrub_shift s
+			WHERE s.patient_id = e.patient_id`},
+		{"baseline discrepancy dates", `
+			UPDATE baseline_discrepancies d
+			SET created_at = d.created_at + s.shift, resolved_at = d.resolved_at + s.shift
+			FROM scrub_shift s
+			WHERE s.patient_id = d.patient_id`},
+		// Shifted with the assessments so on_medication is unchanged on rescore
+		{"medication dates", `
+			UPDATE patient_medications m
+			SET started_at = m.started_at + s.shift, stopped_at = m.stopped_at + s.shift,
+			    created_at = m.created_at + s.shift, updated_at = m.updated_at + s.shift
+			FROM scrub_shift s
+			WHERE s.patient_id = m.patient_id`},
+		// Reasons are free text that can name the patient
+		{"follow-ups", `
+			UPDATE follow_ups f
+			SET due_at = f.due_at + s.shift, completed_at = f.completed_at + s.shift,
+			    reminded_at = f.reminded_at + s.shift, reason = '',
+			    created_at = f.created_at + s.shift, updated_at = f.updated_at + s.shift
+			FROM scrub_shift s
+			WHERE s.patient_id = f.patient_id`},
+		// Manual titles are free text; automatic ones are fixed wording
+		{"task titles", `
+			UPDATE tasks SET title = 'Task ' || id WHERE kind = 'manual'`},
+		{"task dates", `
+			UPDATE tasks t
+			SET due_at = t.due_at + s.shift, closed_at = t.closed_at + s.shift,
+			    created_at = t.created_at + s.shift, updated_at = t.updated_at + s.shift
+			FROM scrub_shift s
+			WHERE s.patient_id = t.patient_id`},
+		// Tag names are free text; which patients share a tag is kept
+		{"tag names", `
+			UPDATE tags SET name = 'Tag ' || id`},
+		// Names and searches are free text; the other filters are kept
+		{"patient list views", `
+			UPDATE patient_list_views SET name = 'View ' || id, filters = filters - 'search'`},
+		// Presence of each field is kept so consent and channel logic still
+		// has something to work on
+		{"patient contacts", `
+			UPDATE patient_contacts c
+			SET phone = CASE WHEN c.phone = '' THEN '' ELSE '+1555' || lpad((c.patient_id % 10000000)::text, 7, '0') END,
+			    email = CASE WHEN c.email = '' THEN '' ELSE 'patient' || c.patient_id || '@example.invalid' END,
+			    address_line1 = CASE WHEN c.address_line1 = '' THEN '' ELSE c.patient_id || ' Example Street' END,
+			    address_line2 = '',
+			    city = CASE WHEN c.city = '' THEN '' ELSE 'Anytown' END,
+			    region = '',
+			    postal_code = CASE WHEN c.postal_code = '' THEN '' ELSE '00000' END,
+			    consent_at = c.consent_at + s.shift,
+			    updated_at = c.updated_at + s.shift
+			FROM scrub_shift s
+			WHERE s.patient_id = c.patient_id`},
+		// Photo bytes live in object storage, which is never copied
+		{"patient photos", `DELETE FROM patient_photos`},
+		{"users", `
+			UPDATE users
+			SET email = 'user' || id || '@example.invalid',
+			    password_hash = '` + passwordHash + `',
+			    failed_login_attempts = 0,
+			    locked_until = NULL`},
+		{"clinics", `UPDATE clinics SET name = 'Clinic ' || id, address = NULL, report_logo = NULL`},
+		{"refresh tokens", `DELETE FROM refresh_tokens`},
+		{"email verification tokens", `DELETE FROM email_verification_tokens`},
+		{"password reset tokens", `DELETE FROM password_reset_tokens`},
+		{"api tokens", `DELETE FROM api_tokens`},
+		{"api keys", `DELETE FROM api_keys`},
+		// Staging logs in with the shared password only
+		{"two-factor enrollments", `DELETE FROM user_mfa`},
+		{"backup codes", `DELETE FROM user_backup_codes`},
+		// Bucket keys contain client IPs and emails
+		{"rate limit buckets", `DELETE FROM rate_limit_buckets`},
+		// Payloads hold MRNs
+		{"webhook deliveries", `DELETE FROM webhook_deliveries`},
+		// Staging must not call production receivers or hold their secrets
+		{"webhooks", `DELETE FROM webhooks`},
+		// Pending lab results are dated and carry the sending facility
+		{"assessment drafts", `DELETE FROM assessment_drafts`},
+		// Free text that can name the patient or their relatives
+		{"patient notes", `DELETE FROM patient_notes`},
+		// Lab reports live in object storage, which is never copied
+		{"assessment attachments", `DELETE FROM assessment_attachments`},
+		// IP addresses, user agents and emails tried, including mistyped ones
+		{"login history", `DELETE FROM login_history`},
+		// Recomputed from the capped ages and remaining rows
+		{"cohort stats by cluster", `REFRESH MATERIALIZED VIEW cohort_stats_by_cluster`},
+		{"cohort stats by age group", `REFRESH MATERIALIZED VIEW cohort_stats_by_age_group`},
+		{"cohort stats by menopause status", `REFRESH MATERIALIZED VIEW cohort_stats_by_menopause_status`},
+	}
+}
+
+func main() {
+	_ = godotenv.Load()
+
+	dsn := flag.String("dsn", "", "Database to scrub (default DB_DSN)")
+	confirm := flag.String("confirm", "", "Name of the database being scrubbed, as a safety check")
+	shiftDays := flag.Int("shift-days", 180, "Maximum per-patient date shift in days")
+	password := flag.String("password", "", "Password to give every user; empty disables all logins")
+	flag.Parse()
+
+	if *dsn == "" {
+		*dsn = config.Load().DBDSN
+	}
+	if *dsn == "" {
+		log.Fatal("-dsn or DB_DSN is required")
+	}
+	if *shiftDays < 1 {
+		log.Fatal("-shift-days must be at least 1")
+	}
+
+	// "!" never matches a bcrypt hash, so nobody can log in until an admin
+	// resets a password
+	passwordHash := "!"
+	if *password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Fatalf("hash password: %v", err)
+		}
+		passwordHash = string(hash)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, *dsn)
+	if err != nil {
+		log.Fatalf("failed to connect: %v", err)
+	}
+	defer pool.Close()
+
+	var dbName string
+	if err := pool.QueryRow(ctx, `SELECT current_database()`).Scan(&dbName); err != nil {
+		log.Fatalf("failed to read database name: %v", err)
+	}
+	if *confirm != dbName {
+		log.Fatalf("refusing to scrub %q: pass -confirm %s to confirm this is a copy", dbName, dbName)
+	}
+
+	if err := scrub(ctx, pool, scrubSteps(*shiftDays, passwordHash)); err != nil {
+		log.Fatalf("scrub failed, nothing was changed: %v", err)
+	}
+	log.Printf("scrub of %s complete", dbName)
+}
+
+func scrub(ctx context.Context, pool *pgxpool.Pool, steps []scrubStep) error {
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		for _, step := range steps {
+			tag, err := tx.Exec(ctx, step.sql)
+			if err != nil {
+				return err
+			}
+			log.Printf("%s: %d rows", step.name, tag.RowsAffected())
+		}
+		return nil
+	})
+}
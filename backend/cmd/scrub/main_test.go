@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var createTablePattern = regexp.MustCompile(`(?i)CREATE TABLE (?:IF NOT EXISTS )?([a-z_]+)`)
+
+// A new table must be scrubbed or explicitly kept, so personal data added
+// by a later migration cannot slip through to staging unnoticed.
+func TestEveryTableIsCovered(t *testing.T) {
+	files, err := filepath.Glob("../../migrations/*.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+	covered := map[string]bool{}
+	for _, name := range scrubbedTables {
+		covered[name] = true
+	}
+	for name := range keptTables {
+		covered[name] = true
+	}
+
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up, _, _ := strings.Cut(string(data), "-- +goose Down")
+		for _, m := range createTablePattern.FindAllStringSubmatch(up, -1) {
+			if !covered[m[1]] {
+				t.Errorf("%s: table %s is neither scrubbed nor listed in keptTables", filepath.Base(f), m[1])
+			}
+		}
+	}
+}
+
+func TestScrubStepsTouchScrubbedTables(t *testing.T) {
+	var all strings.Builder
+	for _, s := range scrubSteps(30, "!") {
+		all.WriteString(s.sql + "\n")
+	}
+	for _, name := range scrubbedTables {
+		if !regexp.MustCompile(`\b` + name + `\b`).MatchString(all.String()) {
+			t.Errorf("table %s is listed as scrubbed but no step touches it", name)
+		}
+	}
+	if !strings.Contains(all.String(), "2 * 30 + 1") {
+		t.Error("shift-days not applied to the date offsets")
+	}
+}
@@ -2,25 +2,48 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
+	"math/rand/v2"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/skufu/DianaV2/backend/internal/config"
+	"github.com/skufu/DianaV2/backend/internal/store"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// seedChunk is how many patients are written per transaction
+const seedChunk = 500
+
 func main() {
 	// Load .env file if it exists
 	_ = godotenv.Load()
 
+	clinicians := flag.Int("clinicians", 1, "Number of clinician users to create")
+	patients := flag.Int("patients", 0, "Synthetic patients to generate per clinician")
+	visits := flag.Int("assessments", 4, "Most assessments per synthetic patient (each gets at least one)")
+	months := flag.Int("months", 12, "How many months back synthetic assessment histories reach")
+	mix := flag.String("clusters", defaultClusterMix, "Relative share of synthetic patients per cluster")
+	seed := flag.Uint64("seed", 1, "Random seed; the same seed and flags give the same dataset")
+	flag.Parse()
+
+	if *clinicians < 1 || *patients < 0 || *visits < 1 || *months < 1 {
+		log.Fatalf("-clinicians, -assessments and -months must be at least 1 and -patients at least 0")
+	}
+	clusterMix, err := parseClusterMix(*mix)
+	if err != nil {
+		log.Fatalf("-clusters: %v", err)
+	}
+
 	cfg := config.Load()
 	if cfg.DBDSN == "" {
 		log.Fatalf("DB_DSN is required for seeding")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	ctx := context.Background()
 
 	pool, err := pgxpool.New(ctx, cfg.DBDSN)
 	if err != nil {
@@ -28,21 +51,120 @@ func main() {
 	}
 	defer pool.Close()
 
-	if err := seedUser(ctx, pool, "clinician@example.com", "password123", "clinician"); err != nil {
-		log.Fatalf("seed user: %v", err)
+	hash, err := bcrypt.GenerateFromPassword([]byte(store.DemoPassword), bcrypt.DefaultCost)
+	if err != nil {
+		log.Fatalf("hash password: %v", err)
+	}
+	rng := rand.New(rand.NewPCG(*seed, *seed))
+	opts := syntheticOptions{MaxVisits: *visits, Months: *months, Mix: clusterMix}
+	now := time.Now()
+	for i := 0; i < *clinicians; i++ {
+		email := clinicianEmail(i)
+		userID, err := seedUser(ctx, pool, email, string(hash), "clinician")
+		if err != nil {
+			log.Fatalf("seed user: %v", err)
+		}
+		if *patients == 0 {
+			continue
+		}
+		generated := generatePatients(rng, opts, now, i*(*patients)+1, *patients)
+		assessments, err := seedPatients(ctx, pool, userID, generated)
+		if err != nil {
+			log.Fatalf("seed patients of %s: %v", email, err)
+		}
+		log.Printf("seeded %d patients with %d assessments for %s", len(generated), assessments, email)
 	}
 	log.Println("seed complete")
 }
 
-func seedUser(ctx context.Context, pool *pgxpool.Pool, email, password, role string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
+// clinicianEmail is the address of the i-th seeded clinician; the first is
+// the demo clinician
+func clinicianEmail(i int) string {
+	if i == 0 {
+		return store.DemoClinicianEmail
 	}
+	return fmt.Sprintf("clinician%d@example.com", i+1)
+}
+
+// seedUser creates the user unless one with the email exists and returns
+// its id either way
+func seedUser(ctx context.Context, pool *pgxpool.Pool, email, passwordHash, role string) (int64, error) {
 	const q = `
 	INSERT INTO users (email, password_hash, role)
 	VALUES ($1, $2, $3)
-	ON CONFLICT (email) DO NOTHING`
-	_, err = pool.Exec(ctx, q, email, string(hash), role)
-	return err
+	ON CONFLICT (email) DO UPDATE SET email = EXCLUDED.email
+	RETURNING id`
+	var id int64
+	err := pool.QueryRow(ctx, q, email, passwordHash, role).Scan(&id)
+	return id, err
+}
+
+// seedPatients writes the patients of userID and their backdated
+// assessments, seedChunk patients per transaction, and returns how many
+// assessments were written
+func seedPatients(ctx context.Context, pool *pgxpool.Pool, userID int64, patients []syntheticPatient) (int, error) {
+	written := 0
+	for start := 0; start < len(patients); start += seedChunk {
+		chunk := patients[start:min(start+seedChunk, len(patients))]
+		n, err := seedChunkTx(ctx, pool, userID, chunk)
+		if err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return written, nil
+}
+
+func seedChunkTx(ctx context.Context, pool *pgxpool.Pool, userID int64, chunk []syntheticPatient) (int, error) {
+	const insertPatient = `
+	INSERT INTO patients (
+		user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
+		activity, phys_activity, smoking, hypertension, heart_disease, family_history,
+		chol, ldl, hdl, triglycerides, mrn, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, NULLIF($5, 0), $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+	RETURNING id`
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, s := range chunk {
+		p := s.Patient
+		batch.Queue(insertPatient, userID, p.Name, p.Age, p.MenopauseStatus, p.YearsMenopause, p.BMI, p.BPSystolic, p.BPDiastolic,
+			p.Activity, p.PhysActivity, p.Smoking, p.Hypertension, p.HeartDisease, p.FamilyHistory,
+			p.Chol, p.LDL, p.HDL, p.Triglycerides, p.MRN, p.CreatedAt, p.UpdatedAt)
+	}
+	results := tx.SendBatch(ctx, batch)
+	var rows [][]any
+	for _, s := range chunk {
+		var patientID int64
+		if err := results.QueryRow().Scan(&patientID); err != nil {
+			results.Close()
+			return 0, err
+		}
+		for _, a := range s.Assessments {
+			rows = append(rows, []any{
+				patientID, a.FBS, a.HbA1c, a.Cholesterol, a.LDL, a.HDL, a.Triglycerides, a.Systolic, a.Diastolic,
+				a.Activity, a.HistoryFlag, a.Smoking, a.Hypertension, a.HeartDisease, a.BMI, a.Cluster, a.RiskScore,
+				a.ModelVersion, a.Quality.Score, a.Quality.Completeness, a.Quality.OutOfRange, a.CreatedAt,
+			})
+		}
+	}
+	if err := results.Close(); err != nil {
+		return 0, err
+	}
+
+	columns := []string{
+		"patient_id", "fbs", "hba1c", "cholesterol", "ldl", "hdl", "triglycerides", "systolic", "diastolic",
+		"activity", "history_flag", "smoking", "hypertension", "heart_disease", "bmi", "cluster", "risk_score",
+		"model_version", "quality_score", "quality_completeness", "quality_out_of_range", "created_at",
+	}
+	n, err := tx.CopyFrom(ctx, pgx.Identifier{"assessments"}, columns, pgx.CopyFromRows(rows))
+	if err != nil {
+		return 0, err
+	}
+	return int(n), tx.Commit(ctx)
 }
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// syntheticModelVersion marks generated assessments so they can be told
+// apart from ones scored by a model
+const syntheticModelVersion = "synthetic"
+
+// clusterProfile describes the patients of one cluster: the ranges their
+// biomarkers are drawn from and the risk band their scores fall in
+type clusterProfile struct {
+	minAge, maxAge int
+	bmi, bmiSpread float64
+	hba1c, spread  float64
+	// drift is the change in HbA1c per month, so histories trend
+	drift            float64
+	minRisk, maxRisk int
+	hypertension     float64 // share of patients with hypertension
+}
+
+// clusterProfiles follow the clusters of the paper the model is built on
+var clusterProfiles = map[string]clusterProfile{
+	"SIDD": {minAge: 40, maxAge: 62, bmi: 24, bmiSpread: 2, hba1c: 7.6, spread: 0.6, drift: 0.04, minRisk: 80, maxRisk: 96, hypertension: 0.3},
+	"SIRD": {minAge: 45, maxAge: 66, bmi: 33, bmiSpread: 2.5, hba1c: 7.0, spread: 0.5, drift: 0.02, minRisk: 70, maxRisk: 90, hypertension: 0.6},
+	"MOD":  {minAge: 40, maxAge: 60, bmi: 31, bmiSpread: 2.5, hba1c: 6.2, spread: 0.4, drift: 0.01, minRisk: 28, maxRisk: 50, hypertension: 0.35},
+	"MARD": {minAge: 58, maxAge: 76, bmi: 27, bmiSpread: 2, hba1c: 6.3, spread: 0.4, drift: 0.005, minRisk: 35, maxRisk: 55, hypertension: 0.5},
+}
+
+// defaultClusterMix is the share of patients per cluster unless -clusters
+// says otherwise
+const defaultClusterMix = "SIDD=15,SIRD=20,MOD=35,MARD=30"
+
+// clusterWeight is one entry of a cluster mix
+type clusterWeight struct {
+	cluster string
+	weight  int
+}
+
+// parseClusterMix reads a mix such as "SIDD=15,SIRD=20,MOD=35,MARD=30".
+// Weights are relative; a cluster left out gets no patients.
+func parseClusterMix(v string) ([]clusterWeight, error) {
+	var mix []clusterWeight
+	total := 0
+	for _, part := range strings.Split(v, ",") {
+		cluster, weight, found := strings.Cut(strings.TrimSpace(part), "=")
+		cluster = strings.ToUpper(strings.TrimSpace(cluster))
+		if !found {
+			return nil, fmt.Errorf("%q: expected CLUSTER=weight", part)
+		}
+		if _, ok := clusterProfiles[cluster]; !ok {
+			return nil, fmt.Errorf("%q: unknown cluster, expected SIDD, SIRD, MOD or MARD", cluster)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(weight))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%q: weight must be a whole number of at least 0", part)
+		}
+		mix = append(mix, clusterWeight{cluster: cluster, weight: n})
+		total += n
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%q: at least one cluster needs a weight above 0", v)
+	}
+	return mix, nil
+}
+
+// syntheticOptions size the generated dataset
+type syntheticOptions struct {
+	// MaxVisits caps the assessments of one patient; each has at least one
+	MaxVisits int
+	// Months is how far back the first assessments go
+	Months int
+	Mix    []clusterWeight
+}
+
+// syntheticPatient is a generated patient and its assessments, oldest first
+type syntheticPatient struct {
+	Patient     models.Patient
+	Assessments []models.Assessment
+}
+
+var (
+	firstNames = []string{"Maria", "Elena", "Rosa", "Ana", "Carmen", "Luz", "Teresa", "Josefina", "Cristina", "Liza", "Marites", "Gloria", "Divina", "Imelda", "Corazon", "Aurora", "Perla", "Remedios", "Lourdes", "Nenita"}
+	lastNames  = []string{"Santos", "Cruz", "Reyes", "Villanueva", "Lopez", "Garcia", "Mendoza", "Bautista", "Ramos", "Aquino", "Castillo", "Flores", "Navarro", "Torres", "Dela Cruz", "Gonzales", "Domingo", "Pascual", "Salazar", "Tan"}
+)
+
+// generatePatients makes n patients with longitudinal assessments ending
+// before now. The same rng seed gives the same dataset; first numbers the
+// MRNs, so several calls can share one sequence.
+func generatePatients(rng *rand.Rand, opts syntheticOptions, now time.Time, first, n int) []syntheticPatient {
+	out := make([]syntheticPatient, 0, n)
+	for i := 0; i < n; i++ {
+		cluster := pickCluster(rng, opts.Mix)
+		out = append(out, generatePatient(rng, clusterProfiles[cluster], cluster, opts, now, first+i))
+	}
+	return out
+}
+
+func pickCluster(rng *rand.Rand, mix []clusterWeight) string {
+	total := 0
+	for _, w := range mix {
+		total += w.weight
+	}
+	n := rng.IntN(total)
+	for _, w := range mix {
+		if n < w.weight {
+			return w.cluster
+		}
+		n -= w.weight
+	}
+	return mix[len(mix)-1].cluster
+}
+
+func generatePatient(rng *rand.Rand, profile clusterProfile, cluster string, opts syntheticOptions, now time.Time, seq int) syntheticPatient {
+	age := profile.minAge + rng.IntN(profile.maxAge-profile.minAge+1)
+	p := models.Patient{
+		Name:          firstNames[rng.IntN(len(firstNames))] + " " + lastNames[rng.IntN(len(lastNames))],
+		Age:           age,
+		Activity:      pick(rng, "low", "moderate", "high"),
+		Smoking:       pick(rng, "never", "never", "never", "former", "current"),
+		Hypertension:  yesNo(rng.Float64() < profile.hypertension),
+		HeartDisease:  yesNo(rng.Float64() < 0.1+float64(age-40)/200),
+		FamilyHistory: rng.Float64() < 0.45,
+		MRN:           fmt.Sprintf("SYN-%06d", seq),
+	}
+	switch {
+	case age < 45:
+		p.MenopauseStatus = "premenopausal"
+	case age < 52:
+		p.MenopauseStatus = "perimenopausal"
+	default:
+		p.MenopauseStatus = "postmenopausal"
+		p.YearsMenopause = age - 50
+	}
+	p.PhysActivity = p.Activity != "low"
+
+	visits := 1 + rng.IntN(opts.MaxVisits)
+	span := opts.Months * 30 * 24
+	// visit hours before now, spread across the span and oldest first
+	hours := make([]int, visits)
+	for i := range hours {
+		hours[i] = span - (span*i)/visits - rng.IntN(span/visits+1)
+	}
+	bmi := normal(rng, profile.bmi, profile.bmiSpread)
+	hba1c := normal(rng, profile.hba1c, profile.spread)
+	systolic := 115 + rng.IntN(20)
+	if p.Hypertension == "yes" {
+		systolic += 20
+	}
+	var assessments []models.Assessment
+	for i, h := range hours {
+		months := float64(hours[0]-h) / (30 * 24)
+		a := models.Assessment{
+			FBS:           round(28.7*(hba1c+profile.drift*months)-46.7+normal(rng, 0, 6), 1),
+			HbA1c:         round(hba1c+profile.drift*months+normal(rng, 0, 0.1), 1),
+			BMI:           round(bmi+normal(rng, 0, 0.4), 1),
+			Systolic:      systolic + rng.IntN(9) - 4,
+			Cholesterol:   170 + rng.IntN(70),
+			LDL:           90 + rng.IntN(70),
+			HDL:           38 + rng.IntN(30),
+			Triglycerides: 100 + rng.IntN(120),
+			Activity:      p.Activity,
+			HistoryFlag:   p.FamilyHistory,
+			Smoking:       p.Smoking,
+			Hypertension:  p.Hypertension,
+			HeartDisease:  p.HeartDisease,
+			Cluster:       cluster,
+			ModelVersion:  syntheticModelVersion,
+			CreatedAt:     now.Add(-time.Duration(h) * time.Hour),
+		}
+		a.Diastolic = a.Systolic*6/10 + rng.IntN(6)
+		a.RiskScore = riskScore(profile, a.HbA1c)
+		quality := ml.AssessQuality(a)
+		a.Quality = &quality
+		assessments = append(assessments, a)
+		if i == 0 {
+			p.CreatedAt = a.CreatedAt.Add(-24 * time.Hour)
+		}
+	}
+	last := assessments[len(assessments)-1]
+	p.BMI, p.BPSystolic, p.BPDiastolic = last.BMI, last.Systolic, last.Diastolic
+	p.Chol, p.LDL, p.HDL, p.Triglycerides = last.Cholesterol, last.LDL, last.HDL, last.Triglycerides
+	p.UpdatedAt = last.CreatedAt
+	return syntheticPatient{Patient: p, Assessments: assessments}
+}
+
+// riskScore places hba1c within the risk band of its cluster: the higher
+// above the cluster's typical value, the higher the score
+func riskScore(profile clusterProfile, hba1c float64) int {
+	pos := 0.5 + (hba1c-profile.hba1c)/(4*profile.spread)
+	pos = math.Max(0, math.Min(1, pos))
+	return profile.minRisk + int(math.Round(pos*float64(profile.maxRisk-profile.minRisk)))
+}
+
+func normal(rng *rand.Rand, mean, spread float64) float64 {
+	return mean + rng.NormFloat64()*spread
+}
+
+func round(v float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(v*scale) / scale
+}
+
+func pick(rng *rand.Rand, choices ...string) string {
+	return choices[rng.IntN(len(choices))]
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
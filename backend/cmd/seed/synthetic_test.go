@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGeneratePatients_Deterministic(t *testing.T) {
+	mix, err := parseClusterMix(defaultClusterMix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := syntheticOptions{MaxVisits: 5, Months: 12, Mix: mix}
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	gen := func(seed uint64) []syntheticPatient {
+		return generatePatients(rand.New(rand.NewPCG(seed, seed)), opts, now, 1, 200)
+	}
+
+	a, b := gen(7), gen(7)
+	if !reflect.DeepEqual(a, b) {
+		t.Fatal("the same seed generated different datasets")
+	}
+	if reflect.DeepEqual(a, gen(8)) {
+		t.Error("different seeds generated the same dataset")
+	}
+
+	clusters := map[string]int{}
+	for _, p := range a {
+		if n := len(p.Assessments); n < 1 || n > opts.MaxVisits {
+			t.Fatalf("%s: %d assessments, expected 1 to %d", p.Patient.MRN, n, opts.MaxVisits)
+		}
+		if !p.Patient.CreatedAt.Before(p.Assessments[0].CreatedAt) || p.Patient.CreatedAt.Before(now.AddDate(0, -13, 0)) {
+			t.Errorf("%s: created %v, expected shortly before its first assessment", p.Patient.MRN, p.Patient.CreatedAt)
+		}
+		for i, as := range p.Assessments {
+			if as.CreatedAt.After(now) || (i > 0 && as.CreatedAt.Before(p.Assessments[i-1].CreatedAt)) {
+				t.Errorf("%s: assessments out of order or in the future: %v", p.Patient.MRN, as.CreatedAt)
+			}
+			profile := clusterProfiles[as.Cluster]
+			if as.RiskScore < profile.minRisk || as.RiskScore > profile.maxRisk || as.Quality == nil {
+				t.Errorf("%s: %s risk %d outside %d-%d", p.Patient.MRN, as.Cluster, as.RiskScore, profile.minRisk, profile.maxRisk)
+			}
+		}
+		clusters[p.Assessments[0].Cluster]++
+	}
+	if len(clusters) != 4 || clusters["MOD"] <= clusters["SIDD"] {
+		t.Errorf("expected all four clusters, MOD more common than SIDD, got %v", clusters)
+	}
+
+	only := generatePatients(rand.New(rand.NewPCG(1, 1)), syntheticOptions{MaxVisits: 1, Months: 1, Mix: []clusterWeight{{"SIRD", 1}}}, now, 1, 20)
+	for _, p := range only {
+		if p.Assessments[0].Cluster != "SIRD" {
+			t.Fatalf("expected only SIRD patients, got %s", p.Assessments[0].Cluster)
+		}
+	}
+}
+
+func TestParseClusterMix(t *testing.T) {
+	mix, err := parseClusterMix("sidd=1, MARD=3")
+	if err != nil || !reflect.DeepEqual(mix, []clusterWeight{{"SIDD", 1}, {"MARD", 3}}) {
+		t.Errorf("expected SIDD=1 and MARD=3, got %v, %v", mix, err)
+	}
+	for _, bad := range []string{"", "SIDD", "XYZ=1", "SIDD=-1", "SIDD=0,MOD=0"} {
+		if _, err := parseClusterMix(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
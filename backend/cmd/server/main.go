@@ -2,17 +2,30 @@ package main
 
 import (
 	"context"
-	"log"
+	"database/sql"
+	"flag"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+	"github.com/skufu/DianaV2/backend/internal/audit"
+	"github.com/skufu/DianaV2/backend/internal/chaos"
 	"github.com/skufu/DianaV2/backend/internal/config"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
 	"github.com/skufu/DianaV2/backend/internal/http/router"
+	"github.com/skufu/DianaV2/backend/internal/logging"
 	"github.com/skufu/DianaV2/backend/internal/store"
+	"github.com/skufu/DianaV2/backend/internal/tracing"
+	"github.com/skufu/DianaV2/backend/internal/worker"
+	"github.com/skufu/DianaV2/backend/migrations"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // @title           DIANA API
@@ -35,38 +48,134 @@ import (
 // @description JWT Bearer token. Format: Bearer <token>
 
 func main() {
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "File of KEY=VALUE settings; environment variables take precedence")
+	checkConfig := flag.Bool("check-config", false, "Validate the configuration and exit")
+	flag.Parse()
+
 	// Load .env file if it exists (not required in production)
-	if err := godotenv.Load(); err != nil {
-		log.Printf("No .env file found or error loading it: %v", err)
+	envErr := godotenv.Load()
+
+	cfg, err := loadConfig(*configFile)
+	if *checkConfig {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("configuration OK")
+		return
+	}
+	logging.Init(logging.Options{Env: cfg.Env, Level: cfg.LogLevel, RedactPII: cfg.LogRedactPII})
+	if err != nil {
+		log.Fatal().Msgf("invalid configuration:\n%v", err)
+	}
+	if envErr != nil {
+		log.Debug().Err(envErr).Msg("No .env file found or error loading it")
+	}
+	if cfg.ChaosEnabled {
+		log.Warn().Msgf("chaos mode on: latency %dms at %.0f%%, HTTP errors %.0f%%, ML errors %.0f%%, DB errors %.0f%%",
+			cfg.ChaosLatencyMS, cfg.ChaosLatencyRate*100, cfg.ChaosHTTPErrorRate*100,
+			cfg.ChaosMLErrorRate*100, cfg.ChaosDBErrorRate*100)
 	}
 
-	cfg := config.Load()
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Options{
+		Endpoint:    cfg.OTLPEndpoint,
+		SampleRatio: cfg.TraceSampleRatio,
+		Env:         cfg.Env,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to set up tracing")
+	}
+	if cfg.OTLPEndpoint != "" {
+		log.Info().Msgf("exporting traces to %s", cfg.OTLPEndpoint)
+	}
 
 	var pool *pgxpool.Pool
-	if cfg.DBDSN != "" {
+	var dbMonitor *store.ReadOnlyMonitor
+	if cfg.DBDSN != "" && cfg.StoreBackend != "memory" {
+		if cfg.MigrateOnStart {
+			migrateOnStart(cfg.DBDSN)
+		}
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
-		var err error
-		pool, err = pgxpool.New(ctx, cfg.DBDSN)
+		poolCfg, err := pgxpool.ParseConfig(cfg.DBDSN)
 		if err != nil {
-			log.Fatalf("failed to init pgx pool: %v", err)
+			log.Fatal().Err(err).Msg("invalid DB_DSN")
+		}
+		poolCfg.MaxConns = int32(cfg.DBMaxConns)
+		poolCfg.MinConns = int32(cfg.DBMinConns)
+		poolCfg.MaxConnLifetime = time.Duration(cfg.DBMaxConnLifetimeMinutes) * time.Minute
+		// Queries also stop when their request's deadline passes; this is the
+		// database's own ceiling for any the cancel never reaches
+		if cfg.DBStatementTimeoutMS > 0 {
+			poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.Itoa(cfg.DBStatementTimeoutMS)
+		}
+		// Flip the API to read-only when writes are rejected (e.g. after failover)
+		dbMonitor = store.NewReadOnlyMonitor()
+		poolCfg.ConnConfig.Tracer = dbMonitor
+		if faults := chaos.FromConfig(cfg); faults != nil {
+			poolCfg.ConnConfig.Tracer = faults.Tracer(dbMonitor)
+		}
+		poolCfg.ConnConfig.Tracer = tracing.QueryTracer(poolCfg.ConnConfig.Tracer)
+		pool, err = pgxpool.NewWithConfig(ctx, poolCfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to init pgx pool")
 		}
 		if err := pool.Ping(ctx); err != nil {
-			log.Fatalf("failed to ping database: %v", err)
+			log.Fatal().Err(err).Msg("failed to ping database")
 		}
-		log.Printf("connected to Postgres")
-	} else {
-		log.Printf("DB_DSN not set; running without database (handlers will error on DB access)")
+		log.Info().Msg("connected to Postgres")
+	} else if cfg.StoreBackend != "memory" {
+		log.Warn().Msg("DB_DSN not set; running without database (handlers will error on DB access; set STORE_BACKEND=memory for demo data)")
 	}
 
 	var st store.Store
-	if pool != nil {
-		st = store.NewPostgresStore(pool)
-	} else {
+	var cohortViews *store.PostgresStore
+	switch {
+	case cfg.StoreBackend == "memory":
+		mem := store.NewMemoryStore()
+		hash, err := bcrypt.GenerateFromPassword([]byte(store.DemoPassword), bcrypt.DefaultCost)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to hash demo password")
+		}
+		if err := mem.SeedDemo(string(hash)); err != nil {
+			log.Fatal().Err(err).Msg("failed to seed in-memory store")
+		}
+		log.Warn().Msgf("using the in-memory store; data is lost on restart. Sign in as %s or %s with password %s",
+			store.DemoClinicianEmail, store.DemoAdminEmail, store.DemoPassword)
+		st = mem
+	case pool != nil:
+		pg := store.NewPostgresStore(pool)
+		if cfg.CohortStatsViews {
+			pg.UseCohortStatsViews(true)
+			cohortViews = pg
+		}
+		st = pg
+	default:
 		st = store.NewPostgresStore(nil)
 	}
+	if cfg.AnalyticsCacheTTLSeconds > 0 {
+		st = store.WithAnalyticsCache(st, time.Duration(cfg.AnalyticsCacheTTLSeconds)*time.Second)
+	}
+	st = audit.WrapStore(st, audit.NewSink(cfg.AuditSinks, st, os.Stdout, cfg.AuditWebhookURL))
 
-	r := router.New(cfg, st)
+	var limits middleware.RateLimitStore
+	var pgLimits *store.PostgresRateLimitStore
+	if cfg.RateLimitStore == "postgres" {
+		if pool == nil {
+			log.Fatal().Msg("RATE_LIMIT_STORE=postgres requires DB_DSN")
+		}
+		pgLimits = store.NewPostgresRateLimitStore(pool)
+		limits = pgLimits
+	}
+
+	workers := worker.NewManager()
+	r := router.New(cfg, st, dbMonitor, limits, workers)
+	if dbMonitor != nil {
+		interval := time.Duration(cfg.DBReadOnlyProbeSeconds) * time.Second
+		workers.Go("db-readonly-probe", func(ctx context.Context) {
+			dbMonitor.Watch(ctx, pool, interval)
+		})
+	}
 	srv := &http.Server{
 		Addr:    ":" + cfg.Port,
 		Handler: r,
@@ -74,39 +183,93 @@ func main() {
 
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("listen: %s", err)
+			log.Fatal().Err(err).Msg("listen")
 		}
 	}()
 
-	// Start background job to clean up expired refresh tokens every 24 hours
-	go func() {
-		// Run once immediately on startup
-		if err := st.RefreshTokens().DeleteExpiredTokens(context.Background()); err != nil {
-			log.Printf("initial token cleanup error: %v", err)
-		}
-		ticker := time.NewTicker(24 * time.Hour)
-		defer ticker.Stop()
-		for range ticker.C {
-			if err := st.RefreshTokens().DeleteExpiredTokens(context.Background()); err != nil {
-				log.Printf("token cleanup error: %v", err)
-			} else {
-				log.Printf("expired tokens cleaned up successfully")
-			}
-		}
-	}()
+	// Clean up expired and long-revoked refresh tokens on startup and every 24 hours
+	retention := time.Duration(cfg.RevokedTokenRetentionDays) * 24 * time.Hour
+	workers.Every("token-cleanup", 24*time.Hour, true, func(ctx context.Context) error {
+		return cleanupTokens(ctx, st, retention)
+	})
+	if cohortViews != nil {
+		interval := time.Duration(cfg.CohortStatsRefreshMinutes) * time.Minute
+		workers.Every("cohort-stats-refresh", interval, false, cohortViews.RefreshCohortStatsViews)
+	}
+	if pgLimits != nil {
+		workers.Every("rate-limit-cleanup", 24*time.Hour, false, func(ctx context.Context) error {
+			_, err := pgLimits.Prune(ctx, time.Now().Add(-24*time.Hour))
+			return err
+		})
+	}
 
-	log.Printf("server started on :%s", cfg.Port)
+	log.Info().Msgf("server started on :%s", cfg.Port)
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
-	log.Printf("shutting down...")
+	log.Info().Msg("shutting down...")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Printf("server shutdown error: %v", err)
+		log.Error().Err(err).Msg("server shutdown error")
+	}
+	// Stop background jobs before closing the pool they write through
+	if err := workers.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("background workers did not stop in time")
 	}
 	st.Close()
-	log.Printf("shutdown complete")
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error().Err(err).Msg("failed to flush traces")
+	}
+	log.Info().Msg("shutdown complete")
+}
+
+// loadConfig loads the configuration from path, or from the environment
+// alone when path is empty, and validates it.
+func loadConfig(path string) (config.Config, error) {
+	if path == "" {
+		cfg := config.Load()
+		return cfg, cfg.Validate()
+	}
+	cfg, err := config.LoadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// migrateOnStart applies pending migrations before the pool opens. It uses
+// a connection of its own, so the pool's statement_timeout does not cut
+// long migrations short.
+func migrateOnStart(dsn string) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid DB_DSN")
+	}
+	defer db.Close()
+	results, err := migrations.Up(context.Background(), db)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to apply migrations")
+	}
+	for _, r := range results {
+		log.Info().Msgf("applied migration %s in %s", r.Source.Path, r.Duration)
+	}
+}
+
+// cleanupTokens deletes expired refresh tokens and those revoked longer ago
+// than the retention window. Revoked rows are kept for a while for auditing.
+func cleanupTokens(ctx context.Context, st store.Store, retention time.Duration) error {
+	if err := st.RefreshTokens().DeleteExpiredTokens(ctx); err != nil {
+		return err
+	}
+	n, err := st.RefreshTokens().DeleteRevokedTokens(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Info().Msgf("purged %d revoked refresh tokens", n)
+	}
+	return nil
 }
@@ -0,0 +1,224 @@
+// Package audit routes audit events to one or more sinks (database, stdout
+// JSON, webhook) so environments with central logging can skip the database
+// without losing the trail.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// Sink receives audit events. Implementations must be safe for concurrent use.
+type Sink interface {
+	Write(ctx context.Context, event models.AuditEvent) error
+}
+
+// DBSink persists events to the audit_events table.
+type DBSink struct {
+	repo store.AuditEventRepository
+}
+
+func NewDBSink(repo store.AuditEventRepository) *DBSink {
+	return &DBSink{repo: repo}
+}
+
+func (s *DBSink) Write(ctx context.Context, event models.AuditEvent) error {
+	return s.repo.Create(ctx, event)
+}
+
+// JSONSink writes one JSON object per event, for log shippers reading stdout.
+type JSONSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w, now: time.Now}
+}
+
+func (s *JSONSink) Write(ctx context.Context, event models.AuditEvent) error {
+	line, err := json.Marshal(envelope(event, s.now()))
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// WebhookSink POSTs each event as JSON to a collector URL.
+type WebhookSink struct {
+	client *http.Client
+	url    string
+	now    func() time.Time
+}
+
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{client: &http.Client{Timeout: timeout}, url: url, now: time.Now}
+}
+
+func (s *WebhookSink) Write(ctx context.Context, event models.AuditEvent) error {
+	body, err := json.Marshal(envelope(event, s.now()))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("audit webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// MultiSink fans each event out to every sink. A failing sink does not stop
+// the others; all errors are returned joined.
+type MultiSink []Sink
+
+func (m MultiSink) Write(ctx context.Context, event models.AuditEvent) error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Write(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// envelope stamps the event time; the DB sink relies on NOW() instead.
+func envelope(event models.AuditEvent, now time.Time) models.AuditEvent {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = now.UTC()
+	}
+	return event
+}
+
+// NewSink builds the sink stack named in kinds ("db", "stdout", "webhook").
+// Unknown kinds, and "webhook" without a URL, are logged and skipped.
+// An empty selection falls back to the database.
+func NewSink(kinds []string, st store.Store, w io.Writer, webhookURL string) Sink {
+	var sinks MultiSink
+	for _, kind := range kinds {
+		switch strings.ToLower(strings.TrimSpace(kind)) {
+		case "db":
+			sinks = append(sinks, NewDBSink(st.AuditEvents()))
+		case "stdout":
+			sinks = append(sinks, NewJSONSink(w))
+		case "webhook":
+			if webhookURL == "" {
+				log.Warn().Msg("audit: webhook sink requested but AUDIT_WEBHOOK_URL is empty; skipping")
+				continue
+			}
+			sinks = append(sinks, NewWebhookSink(webhookURL, 5*time.Second))
+		default:
+			log.Warn().Msgf("audit: unknown sink %q; skipping", kind)
+		}
+	}
+	if len(sinks) == 0 {
+		return NewDBSink(st.AuditEvents())
+	}
+	if len(sinks) == 1 {
+		return sinks[0]
+	}
+	return sinks
+}
+
+// WrapStore returns a store whose AuditEvents().Create writes to sink, so
+// every existing audit call site goes through the configured sinks. Listing
+// still reads from the database.
+func WrapStore(st store.Store, sink Sink) store.Store {
+	return &sinkStore{Store: st, sink: sink}
+}
+
+type sinkStore struct {
+	store.Store
+	sink Sink
+}
+
+func (s *sinkStore) AuditEvents() store.AuditEventRepository {
+	return &sinkRepo{AuditEventRepository: s.Store.AuditEvents(), sink: s.sink}
+}
+
+type sinkRepo struct {
+	store.AuditEventRepository
+	sink Sink
+}
+
+func (r *sinkRepo) Create(ctx context.Context, event models.AuditEvent) error {
+	return r.sink.Write(ctx, event)
+}
+
+// Subscribe records domain events published on bus as audit events.
+func Subscribe(bus *events.Bus, repo store.AuditEventRepository) {
+	events.Subscribe(bus, "audit", func(ctx context.Context, e events.AssessmentCreated) error {
+		return repo.Create(ctx, models.AuditEvent{
+			Actor:      e.Actor,
+			Action:     "assessment.create",
+			TargetType: "assessment",
+			TargetID:   int(e.Assessment.ID),
+			Details: map[string]interface{}{
+				"patient_id": e.Assessment.PatientID,
+				"risk_score": e.Assessment.RiskScore,
+			},
+		})
+	})
+	events.Subscribe(bus, "audit", func(ctx context.Context, e events.PatientDeleted) error {
+		return repo.Create(ctx, models.AuditEvent{
+			Actor:      e.Actor,
+			Action:     "patient.delete",
+			TargetType: "patient",
+			TargetID:   int(e.PatientID),
+		})
+	})
+	events.Subscribe(bus, "audit", func(ctx context.Context, e events.UserDeactivated) error {
+		return repo.Create(ctx, models.AuditEvent{
+			Actor:      e.Actor,
+			Action:     "user.deactivate",
+			TargetType: "user",
+			TargetID:   int(e.UserID),
+		})
+	})
+	events.Subscribe(bus, "audit", func(ctx context.Context, e events.UserActivated) error {
+		return repo.Create(ctx, models.AuditEvent{
+			Actor:      e.Actor,
+			Action:     "user.activate",
+			TargetType: "user",
+			TargetID:   int(e.UserID),
+		})
+	})
+	events.Subscribe(bus, "audit", func(ctx context.Context, e events.FollowUpDue) error {
+		return repo.Create(ctx, models.AuditEvent{
+			Actor:      "system:follow-ups",
+			Action:     "follow_up.remind",
+			TargetType: "patient",
+			TargetID:   int(e.FollowUp.PatientID),
+			Details: map[string]interface{}{
+				"follow_up_id": e.FollowUp.ID,
+				"user_id":      e.FollowUp.UserID,
+				"due_at":       e.FollowUp.DueAt,
+			},
+		})
+	})
+}
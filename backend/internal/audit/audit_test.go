@@ -0,0 +1,182 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+type fakeAuditRepo struct {
+	store.AuditEventRepository
+	events []models.AuditEvent
+}
+
+func (f *fakeAuditRepo) Create(ctx context.Context, e models.AuditEvent) error {
+	f.events = append(f.events, e)
+	return nil
+}
+
+type fakeStore struct {
+	store.Store
+	audit *fakeAuditRepo
+}
+
+func (f *fakeStore) AuditEvents() store.AuditEventRepository { return f.audit }
+
+type failingSink struct{}
+
+func (failingSink) Write(ctx context.Context, e models.AuditEvent) error {
+	return errors.New("sink down")
+}
+
+func TestJSONSink_WritesOneLinePerEvent(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONSink(&buf)
+	sink.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	if err := sink.Write(context.Background(), models.AuditEvent{Actor: "a@example.com", Action: "user.create", TargetType: "user", TargetID: 7}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := sink.Write(context.Background(), models.AuditEvent{Action: "user.update"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+	var got models.AuditEvent
+	if err := json.Unmarshal(lines[0], &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Action != "user.create" || got.TargetID != 7 || got.CreatedAt.IsZero() {
+		t.Errorf("unexpected event %+v", got)
+	}
+}
+
+func TestWebhookSink_PostsEvent(t *testing.T) {
+	var got models.AuditEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("content type = %q", r.Header.Get("Content-Type"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookSink(srv.URL, time.Second)
+	if err := sink.Write(context.Background(), models.AuditEvent{Action: "auth.login"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got.Action != "auth.login" {
+		t.Errorf("collector got %+v", got)
+	}
+}
+
+func TestWebhookSink_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	if err := NewWebhookSink(srv.URL, time.Second).Write(context.Background(), models.AuditEvent{}); err == nil {
+		t.Fatal("expected error for 500 response")
+	}
+}
+
+func TestMultiSink_ContinuesPastFailures(t *testing.T) {
+	repo := &fakeAuditRepo{}
+	sink := MultiSink{failingSink{}, NewDBSink(repo)}
+
+	if err := sink.Write(context.Background(), models.AuditEvent{Action: "x"}); err == nil {
+		t.Fatal("expected joined error")
+	}
+	if len(repo.events) != 1 {
+		t.Errorf("db sink should still receive the event, got %d", len(repo.events))
+	}
+}
+
+func TestNewSink(t *testing.T) {
+	st := &fakeStore{audit: &fakeAuditRepo{}}
+
+	tests := []struct {
+		name    string
+		kinds   []string
+		webhook string
+		want    int // number of sinks in the stack
+	}{
+		{"default db", []string{"db"}, "", 1},
+		{"stacked", []string{"db", "stdout", "webhook"}, "http://collector", 3},
+		{"webhook without url skipped", []string{"stdout", "webhook"}, "", 1},
+		{"unknown only falls back to db", []string{"kafka"}, "", 1},
+		{"empty falls back to db", nil, "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewSink(tt.kinds, st, &bytes.Buffer{}, tt.webhook)
+			n := 1
+			if m, ok := sink.(MultiSink); ok {
+				n = len(m)
+			}
+			if n != tt.want {
+				t.Errorf("got %d sinks, want %d", n, tt.want)
+			}
+		})
+	}
+
+	if _, ok := NewSink([]string{"kafka"}, st, &bytes.Buffer{}, "").(*DBSink); !ok {
+		t.Error("unknown sinks should fall back to the database")
+	}
+}
+
+func TestWrapStore_RoutesCreateThroughSink(t *testing.T) {
+	repo := &fakeAuditRepo{}
+	var buf bytes.Buffer
+	st := WrapStore(&fakeStore{audit: repo}, NewJSONSink(&buf))
+
+	if err := st.AuditEvents().Create(context.Background(), models.AuditEvent{Action: "patient.delete"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(repo.events) != 0 {
+		t.Errorf("stdout-only sink should bypass the database, got %d rows", len(repo.events))
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"patient.delete"`)) {
+		t.Errorf("stdout sink missing event: %s", buf.String())
+	}
+}
+
+func TestSubscribe_RecordsDomainEvents(t *testing.T) {
+	repo := &fakeAuditRepo{}
+	bus := events.NewBus()
+	Subscribe(bus, repo)
+
+	ctx := context.Background()
+	bus.Publish(ctx, events.UserDeactivated{Actor: "admin@example.com", UserID: 4})
+	bus.Publish(ctx, events.PatientDeleted{Actor: "doc@example.com", PatientID: 9})
+	bus.Publish(ctx, events.AssessmentCreated{Actor: "doc@example.com", Assessment: models.Assessment{ID: 3, PatientID: 9, RiskScore: 70}})
+	bus.Publish(ctx, events.UserActivated{Actor: "admin@example.com", UserID: 4})
+	bus.Publish(ctx, events.FollowUpDue{FollowUp: models.FollowUp{ID: 2, PatientID: 9, UserID: 4}})
+
+	want := []struct {
+		action string
+		target int
+	}{{"user.deactivate", 4}, {"patient.delete", 9}, {"assessment.create", 3}, {"user.activate", 4}, {"follow_up.remind", 9}}
+	if len(repo.events) != len(want) {
+		t.Fatalf("got %d audit events, want %d", len(repo.events), len(want))
+	}
+	for i, w := range want {
+		if e := repo.events[i]; e.Action != w.action || e.TargetID != w.target || e.Actor == "" {
+			t.Errorf("event %d = %+v, want %s on %d", i, e, w.action, w.target)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Package chaos injects faults (added latency, failed requests, failed model
+// calls and failed queries) at configurable rates, so fallback and retry
+// behaviour can be exercised against a running server. It is only enabled
+// with CHAOS_ENABLED, and config.Validate refuses to start a production
+// server with it on.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/config"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// ErrInjected is the cause attached to injected database failures.
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Config sets how often each fault is injected. Rates are fractions in [0, 1].
+type Config struct {
+	// Latency is added to a request or model call when LatencyRate hits
+	Latency     time.Duration
+	LatencyRate float64
+	// HTTPErrorRate fails API requests with 503 before they reach a handler
+	HTTPErrorRate float64
+	// MLErrorRate makes model calls fail the way an unreachable model does
+	MLErrorRate float64
+	// DBErrorRate fails queries before they are sent to Postgres
+	DBErrorRate float64
+}
+
+// Injector decides when to inject faults. A nil *Injector injects nothing,
+// so callers can wire it unconditionally.
+type Injector struct {
+	cfg  Config
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func New(cfg Config, seed int64) *Injector {
+	return &Injector{cfg: cfg, rand: rand.New(rand.NewSource(seed))}
+}
+
+// FromConfig returns an injector for the server config, or nil when chaos
+// mode is off.
+func FromConfig(cfg config.Config) *Injector {
+	if !cfg.ChaosEnabled {
+		return nil
+	}
+	return New(Config{
+		Latency:       time.Duration(cfg.ChaosLatencyMS) * time.Millisecond,
+		LatencyRate:   cfg.ChaosLatencyRate,
+		HTTPErrorRate: cfg.ChaosHTTPErrorRate,
+		MLErrorRate:   cfg.ChaosMLErrorRate,
+		DBErrorRate:   cfg.ChaosDBErrorRate,
+	}, time.Now().UnixNano())
+}
+
+func (i *Injector) hit(rate float64) bool {
+	if i == nil || rate <= 0 {
+		return false
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rand.Float64() < rate
+}
+
+// delay sleeps for the configured latency when LatencyRate hits, returning
+// early if ctx is done.
+func (i *Injector) delay(ctx context.Context) {
+	if i == nil || i.cfg.Latency <= 0 || !i.hit(i.cfg.LatencyRate) {
+		return
+	}
+	t := time.NewTimer(i.cfg.Latency)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+// Middleware delays or fails requests. Install it after the health routes so
+// orchestrator probes are never affected.
+func (i *Injector) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if i == nil {
+			c.Next()
+			return
+		}
+		i.delay(c.Request.Context())
+		if i.hit(i.cfg.HTTPErrorRate) {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "injected fault (chaos mode)"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Predictor wraps next so model calls are delayed or fail. A failed call
+// returns the "error" cluster, as HTTPPredictor does when the model service
+// cannot be reached.
+func (i *Injector) Predictor(next ml.Predictor) ml.Predictor {
+	if i == nil {
+		return next
+	}
+	return &predictor{inj: i, next: next}
+}
+
+type predictor struct {
+	inj  *Injector
+	next ml.Predictor
+}
+
+func (p *predictor) Predict(ctx context.Context, input models.Assessment) (string, int) {
+	p.inj.delay(context.Background())
+	if p.inj.hit(p.inj.cfg.MLErrorRate) {
+		return "error", 0
+	}
+	return p.next.Predict(ctx, input)
+}
+
+func (p *predictor) PredictWithExplanation(ctx context.Context, input models.Assessment) (string, int, map[string]interface{}) {
+	p.inj.delay(context.Background())
+	if p.inj.hit(p.inj.cfg.MLErrorRate) {
+		return "error", 0, nil
+	}
+	return p.next.PredictWithExplanation(ctx, input)
+}
+
+// ExplainWithModel forwards to next when it can pin a model version, so
+// injected failures also exercise the report fallback.
+func (p *predictor) ExplainWithModel(ctx context.Context, input models.Assessment, version, datasetHash string) (map[string]interface{}, bool) {
+	pinned, ok := p.next.(ml.PinnedExplainer)
+	if !ok {
+		return nil, false
+	}
+	p.inj.delay(context.Background())
+	if p.inj.hit(p.inj.cfg.MLErrorRate) {
+		return nil, false
+	}
+	return pinned.ExplainWithModel(ctx, input, version, datasetHash)
+}
+
+// PredictWithModel forwards to next when it can pin a model version, with
+// the same injected delays and failures as Predict.
+func (p *predictor) PredictWithModel(ctx context.Context, input models.Assessment, version, datasetHash string) (string, int, bool) {
+	pinned, ok := p.next.(ml.PinnedPredictor)
+	if !ok {
+		return "", 0, false
+	}
+	p.inj.delay(context.Background())
+	if p.inj.hit(p.inj.cfg.MLErrorRate) {
+		return "", 0, false
+	}
+	return pinned.PredictWithModel(ctx, input, version, datasetHash)
+}
+
+// Tracer wraps next (which may be nil) in a pgx query tracer that fails
+// queries at DBErrorRate. Failing queries get an already-cancelled context,
+// which pgx rejects before anything is sent, so the connection stays usable.
+// Callers see a context error whose cause is ErrInjected.
+func (i *Injector) Tracer(next pgx.QueryTracer) pgx.QueryTracer {
+	if i == nil {
+		return next
+	}
+	return &tracer{inj: i, next: next}
+}
+
+type tracer struct {
+	inj  *Injector
+	next pgx.QueryTracer
+}
+
+func (t *tracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if t.next != nil {
+		ctx = t.next.TraceQueryStart(ctx, conn, data)
+	}
+	if t.inj.hit(t.inj.cfg.DBErrorRate) {
+		failed, cancel := context.WithCancelCause(ctx)
+		cancel(ErrInjected)
+		return failed
+	}
+	return ctx
+}
+
+func (t *tracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	if t.next != nil {
+		t.next.TraceQueryEnd(ctx, conn, data)
+	}
+}
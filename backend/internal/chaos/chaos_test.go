@@ -0,0 +1,65 @@
+package chaos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestNilInjector_PassesThrough(t *testing.T) {
+	var inj *Injector
+	mock := ml.NewMockPredictor()
+	if inj.Predictor(mock) != ml.Predictor(mock) {
+		t.Error("nil injector should return the predictor unchanged")
+	}
+	if inj.Tracer(nil) != nil {
+		t.Error("nil injector should return the tracer unchanged")
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(inj.Middleware())
+	r.GET("/x", func(c *gin.Context) { c.Status(http.StatusOK) })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/x", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+}
+
+func TestMiddleware_InjectsErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(New(Config{HTTPErrorRate: 1}, 1).Middleware())
+	r.GET("/x", func(c *gin.Context) { t.Error("handler ran despite injected fault") })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/x", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+}
+
+func TestPredictor_InjectsModelFailures(t *testing.T) {
+	a := models.Assessment{HbA1c: 8}
+	if cluster, _ := New(Config{MLErrorRate: 1}, 1).Predictor(ml.NewMockPredictor()).Predict(context.Background(), a); cluster != "error" {
+		t.Errorf("cluster = %q, want error", cluster)
+	}
+	if cluster, _, _ := New(Config{}, 1).Predictor(ml.NewMockPredictor()).PredictWithExplanation(context.Background(), a); cluster == "error" {
+		t.Error("rate 0 should never fail")
+	}
+}
+
+func TestTracer_CancelsQueries(t *testing.T) {
+	tr := New(Config{DBErrorRate: 1}, 1).Tracer(nil)
+	ctx := tr.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{})
+	if ctx.Err() == nil || context.Cause(ctx) != ErrInjected {
+		t.Errorf("ctx err = %v, cause = %v; want cancelled with ErrInjected", ctx.Err(), context.Cause(ctx))
+	}
+	tr.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+}
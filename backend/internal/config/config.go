@@ -1,10 +1,15 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/joho/godotenv"
 )
 
 type Config struct {
@@ -18,48 +23,514 @@ type Config struct {
 	DatasetHash    string
 	ModelTimeoutMS int
 	ExportMaxRows  int
+	BatchMaxItems  int
+	BatchWorkers   int
+	// StoreBackend is "postgres" or "memory"; the in-memory store is seeded
+	// with demo data, lost on restart and refused in production
+	StoreBackend string
+	// JWTPreviousSecrets are still accepted for access tokens after the
+	// secret is rotated; new tokens are signed with JWTSecret
+	JWTPreviousSecrets []string
+	// AccessTokenTTLMinutes is how long an access token is valid
+	AccessTokenTTLMinutes int
+	// RefreshTokenTTLDays is how long a refresh token is valid; each refresh
+	// issues a new one
+	RefreshTokenTTLDays int
+	// SudoWindowMinutes is how long a POST /auth/sudo re-verification stays valid
+	SudoWindowMinutes int
+	// ImpersonationTTLMinutes is how long a token from
+	// POST /admin/users/:id/impersonate is valid; it cannot be refreshed
+	ImpersonationTTLMinutes int
+	// AuthActiveCheck refuses access tokens of users deactivated, or
+	// sessions revoked, since the token was issued, caching the answer for
+	// AuthActiveCacheSeconds; otherwise such tokens work until they expire
+	AuthActiveCheck        bool
+	AuthActiveCacheSeconds int
+	// MaxSessionsPerUser caps concurrent refresh tokens; 0 disables the cap
+	MaxSessionsPerUser int
+	// RevokedTokenRetentionDays is how long revoked refresh tokens are kept before cleanup
+	RevokedTokenRetentionDays int
+	// RetentionGraceDays is how long a deactivated user's data is kept before it is purged; 0, the default, disables purging
+	RetentionGraceDays int
+	// RetentionMode is how a purge treats the user and their patients: "anonymize" or "delete"
+	RetentionMode string
+	// AuditSinks lists where audit events go: any of "db", "stdout", "webhook"
+	AuditSinks []string
+	// AuditWebhookURL receives audit events as JSON when the webhook sink is enabled
+	AuditWebhookURL string
+	// RiskAlertThreshold is the risk score that queues a clinician alert; 0 disables it
+	RiskAlertThreshold int
+	// RiskAlertCooldownHours suppresses repeat alerts for the same patient
+	RiskAlertCooldownHours int
+	// FollowUpReminderHours is how long before a follow-up falls due its owner is reminded
+	FollowUpReminderHours int
+	// TaskOverdueAssessmentDays opens an overdue task for patients not assessed this long; 0 disables it
+	TaskOverdueAssessmentDays int
+	// RegistrationOpen allows POST /auth/register; closed by default
+	RegistrationOpen bool
+	// EmailVerificationTTLHours is how long a verification link stays valid
+	EmailVerificationTTLHours int
+	// AppBaseURL is the frontend origin used to build links in emails
+	AppBaseURL string
+	// SMTP settings; without SMTPHost emails are logged instead of sent
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	// DBReadOnlyProbeSeconds is how often the database is checked for read-only mode
+	DBReadOnlyProbeSeconds int
+	// PasswordResetTTLMinutes is how long a password reset link stays valid
+	PasswordResetTTLMinutes int
+	// PasswordResetsPerHour caps reset emails per address
+	PasswordResetsPerHour int
+	// StorageDir is where uploaded files (patient photos) are stored
+	StorageDir string
+	// PatientPhotoMaxBytes caps the size of an uploaded patient photo
+	PatientPhotoMaxBytes int64
+	// RateLimitStore is where rate limit buckets live: "memory" or "postgres"
+	RateLimitStore string
+	// LoginRateLimit is requests per minute per IP to /auth/login and /auth/refresh; 0 disables
+	LoginRateLimit int
+	// APIRateLimit is requests per minute per user across the authenticated API; 0 disables
+	APIRateLimit int
+	// LoginLockoutThreshold is how many consecutive failed logins lock an account; 0 disables
+	LoginLockoutThreshold int
+	// LoginLockoutMinutes is how long a locked account stays locked
+	LoginLockoutMinutes int
+	// SLODefaultTargetMS is the latency target for routes without their own
+	SLODefaultTargetMS int
+	// SLOTargetsMS maps "METHOD /route/pattern" to a latency target
+	SLOTargetsMS map[string]int
+	// SLOObjective is the fraction of requests expected within target
+	SLOObjective float64
+	// AssessmentsImmutable makes assessments append-only: edits create an
+	// amendment and deletes are refused
+	AssessmentsImmutable bool
+	// PredictionMode is "sync", or "async" to store new assessments as
+	// pending_prediction and score them in the background
+	PredictionMode string
+	// PredictionWebhookURL is notified as each async prediction completes
+	PredictionWebhookURL string
+	// PredictionCacheSize is how many model predictions are cached in memory,
+	// backed by the prediction_cache table; 0 disables the cache
+	PredictionCacheSize int
+	// AnalyticsCacheTTLSeconds is how long aggregate analytics reads are
+	// cached in memory; 0 disables the cache
+	AnalyticsCacheTTLSeconds int
+	// CohortStatsViews reads cohort statistics by cluster, age group and
+	// menopause status from materialized views refreshed every
+	// CohortStatsRefreshMinutes instead of scanning every assessment
+	CohortStatsViews          bool
+	CohortStatsRefreshMinutes int
+	// RecommendationExperiment splits report recommendation wording between
+	// clinics and records exposures
+	RecommendationExperiment bool
+	// ChaosEnabled turns on fault injection for resilience testing; never
+	// allowed in production
+	ChaosEnabled bool
+	// ChaosLatencyMS is added to requests and model calls at ChaosLatencyRate
+	ChaosLatencyMS   int
+	ChaosLatencyRate float64
+	// Chaos*ErrorRate are the fractions of API requests, model calls and
+	// queries that fail
+	ChaosHTTPErrorRate float64
+	ChaosMLErrorRate   float64
+	ChaosDBErrorRate   float64
+	// LogLevel is "debug", "info", "warn" or "error"; debug by default
+	// outside production and staging
+	LogLevel string
+	// LogRedactPII hashes emails and client IPs in logs; on by default in
+	// production
+	LogRedactPII bool
+	// OTLPEndpoint receives traces over OTLP/HTTP, e.g.
+	// http://localhost:4318; empty turns tracing off
+	OTLPEndpoint string
+	// TraceSampleRatio is the fraction of new traces recorded; requests
+	// that arrive with a sampled trace are always recorded
+	TraceSampleRatio float64
+	// StorageBackend is where uploaded files are kept: "local" under
+	// StorageDir, or "s3"
+	StorageBackend string
+	// S3* address the bucket used by the s3 backend. S3Endpoint is set
+	// for S3-compatible services such as MinIO and left empty for AWS.
+	S3Bucket          string
+	S3Region          string
+	S3Endpoint        string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	// AttachmentMaxBytes caps the size of an uploaded assessment attachment
+	AttachmentMaxBytes int64
+	// AttachmentURLTTLMinutes is how long a signed attachment download
+	// link stays valid
+	AttachmentURLTTLMinutes int
+	// DBMaxConns and DBMinConns bound the Postgres connection pool;
+	// DBMinConns connections stay open even when idle
+	DBMaxConns int
+	DBMinConns int
+	// DBMaxConnLifetimeMinutes recycles connections, so the pool follows
+	// failovers and DNS changes
+	DBMaxConnLifetimeMinutes int
+	// DBStatementTimeoutMS is the server-side statement_timeout of every
+	// connection; 0 leaves the database's own setting
+	DBStatementTimeoutMS int
+	// RequestTimeoutMS is the deadline of an API request, passed to its
+	// queries and model calls through the context; 0 disables it
+	RequestTimeoutMS int
+	// RequestTimeoutsMS maps "METHOD /route/pattern" to its own deadline;
+	// a pattern ending in * covers every route under it
+	RequestTimeoutsMS map[string]int
+	// MigrateOnStart applies pending migrations before the server starts
+	// taking requests
+	MigrateOnStart bool
+
+	// problems are the settings Load could not use, reported by Validate
+	problems []string
 }
 
+// Load reads the configuration from the environment. Values that cannot
+// be used fall back to their defaults and are reported by Validate.
 func Load() Config {
-	// Require JWT_SECRET in production
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		env := getEnv("ENV", "dev")
-		if env == "production" || env == "prod" {
-			log.Fatal("JWT_SECRET is required in production")
-		}
-		// Only allow default in dev
-		jwtSecret = "dev-secret-change-in-production"
+	return load(&source{})
+}
+
+// LoadFile reads the configuration from a file of KEY=VALUE lines, in the
+// same format as .env, with environment variables taking precedence.
+func LoadFile(path string) (Config, error) {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("read config file: %w", err)
+	}
+	return load(&source{file: values}), nil
+}
+
+func load(src *source) Config {
+	jwtSecret := src.get("JWT_SECRET")
+	env := src.str("ENV", "dev")
+	if jwtSecret == "" && !isProduction(env) {
+		// Only allow default in dev; Validate refuses a missing secret in production
+		jwtSecret = devJWTSecret
 		log.Println("WARNING: Using default JWT secret. Set JWT_SECRET environment variable!")
 	}
 
 	cfg := Config{
-		Port:           getEnv("PORT", "8080"),
-		Env:            getEnv("ENV", "dev"),
-		DBDSN:          getEnv("DB_DSN", ""),
+		Port:           src.str("PORT", "8080"),
+		Env:            env,
+		DBDSN:          src.str("DB_DSN", ""),
 		JWTSecret:      jwtSecret,
-		ModelURL:       getEnv("MODEL_URL", ""),
-		ModelVersion:   getEnv("MODEL_VERSION", "v0-placeholder"),
-		DatasetHash:    getEnv("MODEL_DATASET_HASH", ""),
-		ModelTimeoutMS: 2000,
-	}
-	cfg.CORSOrigins = splitAndTrim(getEnv("CORS_ORIGINS", "http://localhost:3000,http://localhost:3001"))
-	if v := os.Getenv("EXPORT_MAX_ROWS"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			cfg.ExportMaxRows = n
-		}
+		ModelURL:       src.str("MODEL_URL", ""),
+		ModelVersion:   src.str("MODEL_VERSION", "v0-placeholder"),
+		DatasetHash:    src.str("MODEL_DATASET_HASH", ""),
+		ModelTimeoutMS: src.int("MODEL_TIMEOUT_MS", 2000, 1),
 	}
-	if v := os.Getenv("MODEL_TIMEOUT_MS"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			cfg.ModelTimeoutMS = n
+	cfg.StoreBackend = src.oneOf("STORE_BACKEND", "postgres", "memory")
+	cfg.CORSOrigins = splitAndTrim(src.str("CORS_ORIGINS", "http://localhost:3000,http://localhost:3001"))
+	cfg.ExportMaxRows = src.int("EXPORT_MAX_ROWS", 5000, 1)
+	cfg.BatchMaxItems = src.int("BATCH_MAX_ITEMS", 500, 1)
+	cfg.BatchWorkers = src.int("BATCH_WORKERS", 8, 1)
+	cfg.JWTPreviousSecrets = splitAndTrim(src.str("JWT_PREVIOUS_SECRETS", ""))
+	cfg.AccessTokenTTLMinutes = src.int("ACCESS_TOKEN_TTL_MINUTES", 15, 1)
+	cfg.RefreshTokenTTLDays = src.int("REFRESH_TOKEN_TTL_DAYS", 7, 1)
+	cfg.SudoWindowMinutes = src.int("SUDO_WINDOW_MINUTES", 5, 1)
+	cfg.ImpersonationTTLMinutes = src.int("IMPERSONATION_TTL_MINUTES", 15, 1)
+	cfg.AuthActiveCheck = src.bool("AUTH_ACTIVE_CHECK")
+	cfg.AuthActiveCacheSeconds = src.int("AUTH_ACTIVE_CACHE_SECONDS", 30, 0)
+	cfg.MaxSessionsPerUser = src.int("MAX_SESSIONS_PER_USER", 5, 0)
+	cfg.RevokedTokenRetentionDays = src.int("REVOKED_TOKEN_RETENTION_DAYS", 30, 1)
+	cfg.RetentionGraceDays = src.int("RETENTION_GRACE_DAYS", 0, 0)
+	cfg.RetentionMode = src.oneOf("RETENTION_MODE", "anonymize", "delete")
+	cfg.AuditSinks = splitAndTrim(src.str("AUDIT_SINKS", "db"))
+	cfg.AuditWebhookURL = src.str("AUDIT_WEBHOOK_URL", "")
+	cfg.RiskAlertThreshold = src.int("RISK_ALERT_THRESHOLD", 67, 0)
+	cfg.RiskAlertCooldownHours = src.int("RISK_ALERT_COOLDOWN_HOURS", 24, 0)
+	cfg.FollowUpReminderHours = src.int("FOLLOW_UP_REMINDER_HOURS", 24, 0)
+	cfg.TaskOverdueAssessmentDays = src.int("TASK_OVERDUE_ASSESSMENT_DAYS", 180, 0)
+	cfg.RegistrationOpen = src.oneOf("REGISTRATION_MODE", "closed", "open") == "open"
+	cfg.EmailVerificationTTLHours = src.int("EMAIL_VERIFICATION_TTL_HOURS", 24, 1)
+	cfg.AppBaseURL = strings.TrimRight(src.str("APP_BASE_URL", "http://localhost:3000"), "/")
+	cfg.SMTPHost = src.str("SMTP_HOST", "")
+	cfg.SMTPPort = src.int("SMTP_PORT", 587, 1)
+	cfg.SMTPUsername = src.str("SMTP_USERNAME", "")
+	cfg.SMTPPassword = src.str("SMTP_PASSWORD", "")
+	cfg.SMTPFrom = src.str("SMTP_FROM", "no-reply@diana.local")
+	cfg.DBReadOnlyProbeSeconds = src.int("DB_READONLY_PROBE_SECONDS", 10, 1)
+	cfg.PasswordResetTTLMinutes = src.int("PASSWORD_RESET_TTL_MINUTES", 60, 1)
+	cfg.PasswordResetsPerHour = src.int("PASSWORD_RESETS_PER_HOUR", 3, 1)
+	cfg.StorageDir = src.str("STORAGE_DIR", "data/uploads")
+	cfg.PatientPhotoMaxBytes = int64(src.int("PATIENT_PHOTO_MAX_BYTES", 5<<20, 1))
+	cfg.RateLimitStore = src.oneOf("RATE_LIMIT_STORE", "memory", "postgres")
+	cfg.LoginRateLimit = src.int("LOGIN_RATE_LIMIT", 10, 0)
+	cfg.APIRateLimit = src.int("API_RATE_LIMIT", 300, 0)
+	cfg.LoginLockoutThreshold = src.int("LOGIN_LOCKOUT_THRESHOLD", 5, 0)
+	cfg.LoginLockoutMinutes = src.int("LOGIN_LOCKOUT_MINUTES", 15, 1)
+	cfg.SLODefaultTargetMS = src.int("SLO_DEFAULT_TARGET_MS", 500, 1)
+	// Assessment creation waits on the ML server, batches on many calls
+	cfg.SLOTargetsMS = parseRouteMS("SLO_TARGETS", src.str("SLO_TARGETS",
+		"POST /api/v1/patients/:id/assessments=2500,POST /api/v1/assessments/batch=30000"))
+	cfg.SLOObjective = 0.99
+	if v := src.get("SLO_OBJECTIVE"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 && f < 100 {
+			cfg.SLOObjective = f / 100
+		} else {
+			src.invalid("SLO_OBJECTIVE", v, "a percentage between 0 and 100")
 		}
 	}
-	if cfg.ExportMaxRows == 0 {
-		cfg.ExportMaxRows = 5000
+	cfg.AssessmentsImmutable = src.bool("ASSESSMENTS_IMMUTABLE")
+	cfg.PredictionMode = src.oneOf("PREDICTION_MODE", "sync", "async")
+	cfg.PredictionWebhookURL = src.str("PREDICTION_WEBHOOK_URL", "")
+	cfg.PredictionCacheSize = src.int("PREDICTION_CACHE_SIZE", 0, 0)
+	cfg.AnalyticsCacheTTLSeconds = src.int("ANALYTICS_CACHE_TTL_SECONDS", 30, 0)
+	cfg.CohortStatsViews = src.bool("COHORT_STATS_VIEWS")
+	cfg.CohortStatsRefreshMinutes = src.int("COHORT_STATS_REFRESH_MINUTES", 15, 1)
+	cfg.RecommendationExperiment = src.bool("RECOMMENDATION_EXPERIMENT")
+	cfg.ChaosEnabled = src.bool("CHAOS_ENABLED")
+	cfg.ChaosLatencyMS = src.int("CHAOS_LATENCY_MS", 1000, 0)
+	cfg.ChaosLatencyRate = src.percent("CHAOS_LATENCY_PERCENT")
+	cfg.ChaosHTTPErrorRate = src.percent("CHAOS_HTTP_ERROR_PERCENT")
+	cfg.ChaosMLErrorRate = src.percent("CHAOS_ML_ERROR_PERCENT")
+	cfg.ChaosDBErrorRate = src.percent("CHAOS_DB_ERROR_PERCENT")
+	if isProduction(env) || env == "staging" {
+		cfg.LogLevel = src.oneOf("LOG_LEVEL", "info", "debug", "warn", "error")
+	} else {
+		cfg.LogLevel = src.oneOf("LOG_LEVEL", "debug", "info", "warn", "error")
+	}
+	cfg.LogRedactPII = isProduction(env)
+	if src.get("LOG_REDACT_PII") != "" {
+		cfg.LogRedactPII = src.bool("LOG_REDACT_PII")
 	}
+	cfg.OTLPEndpoint = src.str("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	cfg.TraceSampleRatio = 1
+	if src.get("TRACE_SAMPLE_PERCENT") != "" {
+		cfg.TraceSampleRatio = src.percent("TRACE_SAMPLE_PERCENT")
+	}
+	cfg.StorageBackend = src.oneOf("STORAGE_BACKEND", "local", "s3")
+	cfg.S3Bucket = src.str("S3_BUCKET", "")
+	cfg.S3Region = src.str("S3_REGION", "us-east-1")
+	cfg.S3Endpoint = src.str("S3_ENDPOINT", "")
+	cfg.S3AccessKeyID = src.str("S3_ACCESS_KEY_ID", "")
+	cfg.S3SecretAccessKey = src.str("S3_SECRET_ACCESS_KEY", "")
+	cfg.AttachmentMaxBytes = int64(src.int("ATTACHMENT_MAX_BYTES", 10<<20, 1))
+	cfg.AttachmentURLTTLMinutes = src.int("ATTACHMENT_URL_TTL_MINUTES", 15, 1)
+	cfg.DBMaxConns = src.int("DB_MAX_CONNS", 10, 1)
+	cfg.DBMinConns = src.int("DB_MIN_CONNS", 0, 0)
+	cfg.DBMaxConnLifetimeMinutes = src.int("DB_MAX_CONN_LIFETIME_MINUTES", 60, 1)
+	cfg.DBStatementTimeoutMS = src.int("DB_STATEMENT_TIMEOUT_MS", 60000, 0)
+	cfg.RequestTimeoutMS = src.int("REQUEST_TIMEOUT_MS", 30000, 0)
+	// Batches make many model calls; exports stream up to EXPORT_MAX_ROWS
+	cfg.RequestTimeoutsMS = parseRouteMS("REQUEST_TIMEOUTS", src.str("REQUEST_TIMEOUTS",
+		"POST /api/v1/assessments/batch=120000,GET /api/v1/export/*=300000"))
+	cfg.MigrateOnStart = src.bool("MIGRATE_ON_START")
+	cfg.problems = src.problems
 	return cfg
 }
 
+// devJWTSecret signs tokens outside production when JWT_SECRET is unset
+const devJWTSecret = "dev-secret-change-in-production"
+
+// minProductionSecretLen is the shortest JWT secret accepted in production
+const minProductionSecretLen = 32
+
+func isProduction(env string) bool {
+	return env == "production" || env == "prod"
+}
+
+// IsProduction reports whether the server runs with ENV=production
+func (c Config) IsProduction() bool {
+	return isProduction(c.Env)
+}
+
+// Validate reports every setting that is malformed or inconsistent, so a
+// misconfigured server fails at startup rather than on first use.
+func (c Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	for _, p := range c.problems {
+		errs = append(errs, errors.New(p))
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		fail("PORT=%q is not a port number", c.Port)
+	}
+	if c.IsProduction() {
+		if c.JWTSecret == "" || c.JWTSecret == devJWTSecret {
+			fail("JWT_SECRET is required in production")
+		} else if len(c.JWTSecret) < minProductionSecretLen {
+			fail("JWT_SECRET must be at least %d characters in production", minProductionSecretLen)
+		}
+		for _, s := range c.JWTPreviousSecrets {
+			if len(s) < minProductionSecretLen {
+				fail("JWT_PREVIOUS_SECRETS entries must be at least %d characters in production", minProductionSecretLen)
+				break
+			}
+		}
+		if c.StoreBackend == "memory" {
+			fail("STORE_BACKEND=memory must not be used in production")
+		}
+		if c.StoreBackend == "postgres" && c.DBDSN == "" {
+			fail("DB_DSN is required in production")
+		}
+		if c.ChaosEnabled {
+			fail("CHAOS_ENABLED must not be set in production")
+		}
+	}
+	if c.AccessTokenTTLMinutes >= c.RefreshTokenTTLDays*24*60 {
+		fail("ACCESS_TOKEN_TTL_MINUTES must be shorter than REFRESH_TOKEN_TTL_DAYS")
+	}
+	if c.DBMinConns > c.DBMaxConns {
+		fail("DB_MIN_CONNS must not exceed DB_MAX_CONNS")
+	}
+	if c.RateLimitStore == "postgres" && c.DBDSN == "" {
+		fail("RATE_LIMIT_STORE=postgres requires DB_DSN")
+	}
+	if c.CohortStatsViews && (c.StoreBackend == "memory" || c.DBDSN == "") {
+		fail("COHORT_STATS_VIEWS requires the postgres store and DB_DSN")
+	}
+	for _, sink := range c.AuditSinks {
+		switch sink {
+		case "db", "stdout":
+		case "webhook":
+			if c.AuditWebhookURL == "" {
+				fail("AUDIT_SINKS includes webhook but AUDIT_WEBHOOK_URL is not set")
+			}
+		default:
+			fail("AUDIT_SINKS: unknown sink %q", sink)
+		}
+	}
+	if c.StorageBackend == "s3" {
+		if c.S3Bucket == "" {
+			fail("STORAGE_BACKEND=s3 requires S3_BUCKET")
+		}
+		if c.S3AccessKeyID == "" || c.S3SecretAccessKey == "" {
+			fail("STORAGE_BACKEND=s3 requires S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY")
+		}
+	}
+	if c.SMTPPort > 65535 {
+		fail("SMTP_PORT=%d is not a port number", c.SMTPPort)
+	}
+	if c.SMTPHost != "" && !strings.Contains(c.SMTPFrom, "@") {
+		fail("SMTP_FROM=%q is not an email address", c.SMTPFrom)
+	}
+	checkURL := func(key, value string, required bool) {
+		if value == "" {
+			if required {
+				fail("%s is required", key)
+			}
+			return
+		}
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("%s=%q is not an http(s) URL", key, value)
+		}
+	}
+	checkURL("APP_BASE_URL", c.AppBaseURL, true)
+	checkURL("MODEL_URL", c.ModelURL, false)
+	checkURL("AUDIT_WEBHOOK_URL", c.AuditWebhookURL, false)
+	checkURL("PREDICTION_WEBHOOK_URL", c.PredictionWebhookURL, false)
+	checkURL("OTEL_EXPORTER_OTLP_ENDPOINT", c.OTLPEndpoint, false)
+	checkURL("S3_ENDPOINT", c.S3Endpoint, false)
+	return errors.Join(errs...)
+}
+
+// source reads settings from the environment and, for keys it leaves
+// unset, from a config file. Values that cannot be used are recorded as
+// problems for Validate.
+type source struct {
+	file     map[string]string
+	problems []string
+}
+
+func (s *source) get(key string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return s.file[key]
+}
+
+func (s *source) invalid(key, value, want string) {
+	s.problems = append(s.problems, fmt.Sprintf("%s=%q is not %s", key, value, want))
+}
+
+func (s *source) str(key, def string) string {
+	if v := s.get(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// int reads a whole number of at least min.
+func (s *source) int(key string, def, min int) int {
+	v := s.get(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min {
+		s.invalid(key, v, fmt.Sprintf("a whole number of at least %d", min))
+		return def
+	}
+	return n
+}
+
+// oneOf reads def or one of the other allowed values.
+func (s *source) oneOf(key, def string, others ...string) string {
+	v := s.get(key)
+	if v == "" || v == def {
+		return def
+	}
+	for _, o := range others {
+		if v == o {
+			return v
+		}
+	}
+	s.invalid(key, v, "one of "+strings.Join(append([]string{def}, others...), ", "))
+	return def
+}
+
+// bool reads "true" or "false", defaulting to false.
+func (s *source) bool(key string) bool {
+	switch v := s.get(key); v {
+	case "", "false":
+		return false
+	case "true":
+		return true
+	default:
+		s.invalid(key, v, "true or false")
+		return false
+	}
+}
+
+// percent reads a 0-100 percentage as a fraction, defaulting to 0.
+func (s *source) percent(key string) float64 {
+	v := s.get(key)
+	if v == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 || f > 100 {
+		s.invalid(key, v, "a percentage between 0 and 100")
+		return 0
+	}
+	return f / 100
+}
+
+// parseRouteMS parses comma-separated "METHOD /route=ms" pairs from key,
+// skipping malformed entries.
+func parseRouteMS(key, v string) map[string]int {
+	targets := map[string]int{}
+	for _, pair := range splitAndTrim(v) {
+		route, ms, ok := strings.Cut(pair, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(ms))
+		if !ok || err != nil || n <= 0 {
+			log.Printf("ignoring malformed %s entry %q", key, pair)
+			continue
+		}
+		targets[strings.TrimSpace(route)] = n
+	}
+	return targets
+}
+
 func getEnv(key, def string) string {
 	val := os.Getenv(key)
 	if val == "" {
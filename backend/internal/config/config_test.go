@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -13,6 +15,10 @@ func TestLoad_Defaults(t *testing.T) {
 	os.Unsetenv("CORS_ORIGINS")
 	os.Unsetenv("EXPORT_MAX_ROWS")
 	os.Unsetenv("MODEL_TIMEOUT_MS")
+	os.Unsetenv("BATCH_MAX_ITEMS")
+	os.Unsetenv("BATCH_WORKERS")
+	os.Unsetenv("MAX_SESSIONS_PER_USER")
+	os.Unsetenv("REVOKED_TOKEN_RETENTION_DAYS")
 
 	cfg := Load()
 
@@ -28,6 +34,144 @@ func TestLoad_Defaults(t *testing.T) {
 	if cfg.ModelTimeoutMS != 2000 {
 		t.Errorf("ModelTimeoutMS = %d, want 2000", cfg.ModelTimeoutMS)
 	}
+	if cfg.BatchMaxItems != 500 {
+		t.Errorf("BatchMaxItems = %d, want 500", cfg.BatchMaxItems)
+	}
+	if cfg.BatchWorkers != 8 {
+		t.Errorf("BatchWorkers = %d, want 8", cfg.BatchWorkers)
+	}
+	if cfg.SudoWindowMinutes != 5 {
+		t.Errorf("SudoWindowMinutes = %d, want 5", cfg.SudoWindowMinutes)
+	}
+	if cfg.ImpersonationTTLMinutes != 15 {
+		t.Errorf("ImpersonationTTLMinutes = %d, want 15", cfg.ImpersonationTTLMinutes)
+	}
+	if cfg.AuthActiveCheck || cfg.AuthActiveCacheSeconds != 30 {
+		t.Errorf("AuthActiveCheck = %v, AuthActiveCacheSeconds = %d, want off and 30", cfg.AuthActiveCheck, cfg.AuthActiveCacheSeconds)
+	}
+	if cfg.MaxSessionsPerUser != 5 {
+		t.Errorf("MaxSessionsPerUser = %d, want 5", cfg.MaxSessionsPerUser)
+	}
+	if cfg.RevokedTokenRetentionDays != 30 {
+		t.Errorf("RevokedTokenRetentionDays = %d, want 30", cfg.RevokedTokenRetentionDays)
+	}
+	if cfg.RetentionGraceDays != 0 {
+		t.Errorf("RetentionGraceDays = %d, want 0", cfg.RetentionGraceDays)
+	}
+	if cfg.PredictionCacheSize != 0 {
+		t.Errorf("PredictionCacheSize = %d, want 0", cfg.PredictionCacheSize)
+	}
+	if cfg.AnalyticsCacheTTLSeconds != 30 {
+		t.Errorf("AnalyticsCacheTTLSeconds = %d, want 30", cfg.AnalyticsCacheTTLSeconds)
+	}
+	if cfg.CohortStatsViews || cfg.CohortStatsRefreshMinutes != 15 {
+		t.Errorf("CohortStatsViews = %v every %d minutes, want off every 15", cfg.CohortStatsViews, cfg.CohortStatsRefreshMinutes)
+	}
+	if cfg.PredictionMode != "sync" {
+		t.Errorf("PredictionMode = %q, want sync", cfg.PredictionMode)
+	}
+	if cfg.StoreBackend != "postgres" {
+		t.Errorf("StoreBackend = %q, want postgres", cfg.StoreBackend)
+	}
+	if cfg.RetentionMode != "anonymize" {
+		t.Errorf("RetentionMode = %q, want anonymize", cfg.RetentionMode)
+	}
+	if len(cfg.AuditSinks) != 1 || cfg.AuditSinks[0] != "db" {
+		t.Errorf("AuditSinks = %v, want [db]", cfg.AuditSinks)
+	}
+	if cfg.RiskAlertThreshold != 67 {
+		t.Errorf("RiskAlertThreshold = %d, want 67", cfg.RiskAlertThreshold)
+	}
+	if cfg.RiskAlertCooldownHours != 24 {
+		t.Errorf("RiskAlertCooldownHours = %d, want 24", cfg.RiskAlertCooldownHours)
+	}
+	if cfg.FollowUpReminderHours != 24 {
+		t.Errorf("FollowUpReminderHours = %d, want 24", cfg.FollowUpReminderHours)
+	}
+	if cfg.TaskOverdueAssessmentDays != 180 {
+		t.Errorf("TaskOverdueAssessmentDays = %d, want 180", cfg.TaskOverdueAssessmentDays)
+	}
+	if cfg.RegistrationOpen {
+		t.Error("RegistrationOpen = true, want closed by default")
+	}
+	if cfg.EmailVerificationTTLHours != 24 {
+		t.Errorf("EmailVerificationTTLHours = %d, want 24", cfg.EmailVerificationTTLHours)
+	}
+	if cfg.SMTPPort != 587 {
+		t.Errorf("SMTPPort = %d, want 587", cfg.SMTPPort)
+	}
+	if cfg.DBReadOnlyProbeSeconds != 10 {
+		t.Errorf("DBReadOnlyProbeSeconds = %d, want 10", cfg.DBReadOnlyProbeSeconds)
+	}
+	if cfg.PasswordResetTTLMinutes != 60 {
+		t.Errorf("PasswordResetTTLMinutes = %d, want 60", cfg.PasswordResetTTLMinutes)
+	}
+	if cfg.PasswordResetsPerHour != 3 {
+		t.Errorf("PasswordResetsPerHour = %d, want 3", cfg.PasswordResetsPerHour)
+	}
+	if cfg.StorageDir != "data/uploads" {
+		t.Errorf("StorageDir = %q, want %q", cfg.StorageDir, "data/uploads")
+	}
+	if cfg.PatientPhotoMaxBytes != 5<<20 {
+		t.Errorf("PatientPhotoMaxBytes = %d, want %d", cfg.PatientPhotoMaxBytes, 5<<20)
+	}
+	if cfg.RateLimitStore != "memory" {
+		t.Errorf("RateLimitStore = %q, want memory", cfg.RateLimitStore)
+	}
+	if cfg.LoginRateLimit != 10 {
+		t.Errorf("LoginRateLimit = %d, want 10", cfg.LoginRateLimit)
+	}
+	if cfg.APIRateLimit != 300 {
+		t.Errorf("APIRateLimit = %d, want 300", cfg.APIRateLimit)
+	}
+	if cfg.LoginLockoutThreshold != 5 {
+		t.Errorf("LoginLockoutThreshold = %d, want 5", cfg.LoginLockoutThreshold)
+	}
+	if cfg.LoginLockoutMinutes != 15 {
+		t.Errorf("LoginLockoutMinutes = %d, want 15", cfg.LoginLockoutMinutes)
+	}
+	if cfg.SLODefaultTargetMS != 500 || cfg.SLOObjective != 0.99 {
+		t.Errorf("SLO defaults = %dms/%v, want 500ms/0.99", cfg.SLODefaultTargetMS, cfg.SLOObjective)
+	}
+	if cfg.SLOTargetsMS["POST /api/v1/patients/:id/assessments"] != 2500 {
+		t.Errorf("SLOTargetsMS = %v, want assessment creation at 2500ms", cfg.SLOTargetsMS)
+	}
+	if cfg.DBMaxConns != 10 || cfg.DBMinConns != 0 || cfg.DBMaxConnLifetimeMinutes != 60 || cfg.DBStatementTimeoutMS != 60000 {
+		t.Errorf("pool defaults = %d-%d conns, %dmin lifetime, %dms statements; want 0-10, 60min, 60000ms",
+			cfg.DBMinConns, cfg.DBMaxConns, cfg.DBMaxConnLifetimeMinutes, cfg.DBStatementTimeoutMS)
+	}
+	if cfg.RequestTimeoutMS != 30000 || cfg.RequestTimeoutsMS["GET /api/v1/export/*"] != 300000 {
+		t.Errorf("request timeouts = %dms %v, want 30000ms with longer exports", cfg.RequestTimeoutMS, cfg.RequestTimeoutsMS)
+	}
+	if cfg.MigrateOnStart {
+		t.Error("MigrateOnStart should default to false")
+	}
+	if cfg.AssessmentsImmutable {
+		t.Error("AssessmentsImmutable should default to false")
+	}
+	if cfg.ChaosEnabled || cfg.ChaosHTTPErrorRate != 0 || cfg.ChaosDBErrorRate != 0 {
+		t.Errorf("chaos mode should be off by default: %+v", cfg)
+	}
+	if cfg.LogLevel != "debug" || cfg.LogRedactPII {
+		t.Errorf("logging = %q redact=%v, want debug without redaction in dev", cfg.LogLevel, cfg.LogRedactPII)
+	}
+
+	t.Setenv("ENV", "production")
+	cfg = Load()
+	if cfg.LogLevel != "info" || !cfg.LogRedactPII {
+		t.Errorf("logging = %q redact=%v, want info with redaction in production", cfg.LogLevel, cfg.LogRedactPII)
+	}
+	t.Setenv("LOG_REDACT_PII", "false")
+	if Load().LogRedactPII {
+		t.Error("LOG_REDACT_PII=false should turn redaction off")
+	}
+}
+
+func TestParseRouteMS(t *testing.T) {
+	got := parseRouteMS("SLO_TARGETS", "GET /api/v1/patients=300, POST /api/v1/auth/login = 800,broken,GET /x=-1")
+	if len(got) != 2 || got["GET /api/v1/patients"] != 300 || got["POST /api/v1/auth/login"] != 800 {
+		t.Errorf("parseRouteMS = %v", got)
+	}
 }
 
 func TestLoad_CustomValues(t *testing.T) {
@@ -39,7 +183,9 @@ func TestLoad_CustomValues(t *testing.T) {
 	os.Setenv("MODEL_TIMEOUT_MS", "5000")
 	os.Setenv("MODEL_URL", "http://ml:8001/predict")
 	os.Setenv("MODEL_VERSION", "v2.0")
+	os.Setenv("STORE_BACKEND", "memory")
 	defer func() {
+		os.Unsetenv("STORE_BACKEND")
 		os.Unsetenv("PORT")
 		os.Unsetenv("ENV")
 		os.Unsetenv("JWT_SECRET")
@@ -70,6 +216,9 @@ func TestLoad_CustomValues(t *testing.T) {
 	if cfg.ModelURL != "http://ml:8001/predict" {
 		t.Errorf("ModelURL = %q, want %q", cfg.ModelURL, "http://ml:8001/predict")
 	}
+	if cfg.StoreBackend != "memory" {
+		t.Errorf("StoreBackend = %q, want memory", cfg.StoreBackend)
+	}
 	if cfg.ModelVersion != "v2.0" {
 		t.Errorf("ModelVersion = %q, want %q", cfg.ModelVersion, "v2.0")
 	}
@@ -137,3 +286,79 @@ func TestLoad_InvalidNumbers(t *testing.T) {
 		t.Errorf("ModelTimeoutMS = %d, want 2000 (default)", cfg.ModelTimeoutMS)
 	}
 }
+
+func TestValidate(t *testing.T) {
+	valid := Load()
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("defaults should be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   string
+	}{
+		{"port", func(c *Config) { c.Port = "http" }, "PORT"},
+		{"production secret", func(c *Config) { c.Env = "production"; c.DBDSN = "postgres://db"; c.JWTSecret = "short" }, "JWT_SECRET"},
+		{"production memory store", func(c *Config) {
+			c.Env = "prod"
+			c.JWTSecret = strings.Repeat("x", 32)
+			c.StoreBackend = "memory"
+		}, "STORE_BACKEND"},
+		{"token lifetimes", func(c *Config) { c.AccessTokenTTLMinutes = 7 * 24 * 60 }, "ACCESS_TOKEN_TTL_MINUTES"},
+		{"pool size", func(c *Config) { c.DBMinConns = 20 }, "DB_MIN_CONNS"},
+		{"cohort views without a database", func(c *Config) { c.CohortStatsViews = true; c.DBDSN = "" }, "COHORT_STATS_VIEWS"},
+		{"audit sink", func(c *Config) { c.AuditSinks = []string{"kafka"} }, "AUDIT_SINKS"},
+		{"smtp sender", func(c *Config) { c.SMTPHost = "smtp.example.com"; c.SMTPFrom = "nobody" }, "SMTP_FROM"},
+		{"model url", func(c *Config) { c.ModelURL = "ml:5001" }, "MODEL_URL"},
+		{"s3 bucket", func(c *Config) { c.StorageBackend = "s3"; c.S3AccessKeyID, c.S3SecretAccessKey = "key", "secret" }, "S3_BUCKET"},
+		{"s3 credentials", func(c *Config) { c.StorageBackend = "s3"; c.S3Bucket = "diana" }, "S3_ACCESS_KEY_ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid
+			tt.modify(&c)
+			err := c.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want an error about %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_InvalidNumbersFailValidation(t *testing.T) {
+	os.Setenv("MODEL_TIMEOUT_MS", "invalid")
+	os.Setenv("PREDICTION_MODE", "batch")
+	defer func() {
+		os.Unsetenv("MODEL_TIMEOUT_MS")
+		os.Unsetenv("PREDICTION_MODE")
+	}()
+
+	err := Load().Validate()
+	if err == nil || !strings.Contains(err.Error(), "MODEL_TIMEOUT_MS") || !strings.Contains(err.Error(), "PREDICTION_MODE") {
+		t.Errorf("Validate() = %v, want both unusable values reported", err)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "diana.env")
+	if err := os.WriteFile(path, []byte("PORT=9090\nAPI_RATE_LIMIT=50\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("API_RATE_LIMIT", "70")
+	defer os.Unsetenv("API_RATE_LIMIT")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != "9090" {
+		t.Errorf("Port = %q, want 9090 from the file", cfg.Port)
+	}
+	if cfg.APIRateLimit != 70 {
+		t.Errorf("APIRateLimit = %d, want 70 from the environment", cfg.APIRateLimit)
+	}
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,151 @@
+// Package events is an in-process domain event bus. Handlers publish what
+// happened (an assessment was created, a patient deleted) and side effects
+// such as audit records, risk alerts and file cleanup subscribe to it, so a
+// handler does not need to know about every consumer of its changes.
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// Event names
+const (
+	AssessmentCreatedName = "assessment.created"
+	RiskAlertRaisedName   = "risk_alert.raised"
+	PatientCreatedName    = "patient.created"
+	PatientDeletedName    = "patient.deleted"
+	UserDeactivatedName   = "user.deactivated"
+	UserActivatedName     = "user.activated"
+	FollowUpDueName       = "follow_up.due"
+)
+
+// Event is a domain event. Name must not depend on the receiver's fields, as
+// it is also called on zero values to route subscriptions.
+type Event interface {
+	Name() string
+}
+
+// AssessmentCreated is published after a single assessment is stored.
+// Batch imports do not publish it.
+type AssessmentCreated struct {
+	Actor      string
+	UserID     int32
+	Assessment models.Assessment
+}
+
+func (AssessmentCreated) Name() string { return AssessmentCreatedName }
+
+// RiskAlertRaised is published after a risk alert is queued for a newly
+// created assessment.
+type RiskAlertRaised struct {
+	Alert models.RiskAlert
+}
+
+func (RiskAlertRaised) Name() string { return RiskAlertRaisedName }
+
+// PatientCreated is published after a patient is created.
+type PatientCreated struct {
+	Actor   string
+	UserID  int32
+	Patient models.Patient
+}
+
+func (PatientCreated) Name() string { return PatientCreatedName }
+
+// PatientDeleted is published after a patient is deleted. Photo carries the
+// photo metadata that was cascaded away with the patient, nil if none, and
+// AttachmentKeys the storage keys of its assessments' attachments.
+type PatientDeleted struct {
+	Actor          string
+	UserID         int32
+	PatientID      int64
+	Photo          *models.PatientPhoto
+	AttachmentKeys []string
+}
+
+func (PatientDeleted) Name() string { return PatientDeletedName }
+
+// UserDeactivated is published after an admin deactivates a user.
+type UserDeactivated struct {
+	Actor  string
+	UserID int64
+}
+
+func (UserDeactivated) Name() string { return UserDeactivatedName }
+
+// UserActivated is published after an admin reactivates a user.
+type UserActivated struct {
+	Actor  string
+	UserID int64
+}
+
+func (UserActivated) Name() string { return UserActivatedName }
+
+// FollowUpDue is published when a follow-up is about to fall due or is
+// overdue, once per due date, by the follow-up reminder job.
+type FollowUpDue struct {
+	FollowUp models.FollowUp
+}
+
+func (FollowUpDue) Name() string { return FollowUpDueName }
+
+type subscriber struct {
+	name string
+	fn   func(ctx context.Context, e Event) error
+}
+
+// Bus delivers events to subscribers synchronously, in subscription order.
+// A nil *Bus discards everything published to it.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[string][]subscriber
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: map[string][]subscriber{}}
+}
+
+// Subscribe registers fn for events of type E. name identifies the
+// subscriber in logs.
+func Subscribe[E Event](b *Bus, name string, fn func(ctx context.Context, e E) error) {
+	var zero E
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[zero.Name()] = append(b.subs[zero.Name()], subscriber{
+		name: name,
+		fn: func(ctx context.Context, e Event) error {
+			return fn(ctx, e.(E))
+		},
+	})
+}
+
+// Publish delivers e to every subscriber of its type before returning. The
+// publisher's change is already committed, so a failing or panicking
+// subscriber is logged and does not stop the others. Subscribers doing slow
+// work (network calls) should hand it off rather than block the request.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	subs := b.subs[e.Name()]
+	b.mu.RUnlock()
+	for _, s := range subs {
+		deliver(ctx, s, e)
+	}
+}
+
+func deliver(ctx context.Context, s subscriber, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Ctx(ctx).Error().Interface("panic", r).Msgf("events: subscriber %s panicked on %s", s.name, e.Name())
+		}
+	}()
+	if err := s.fn(ctx, e); err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("events: subscriber %s failed on %s", s.name, e.Name())
+	}
+}
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBus_DeliversByTypeInOrder(t *testing.T) {
+	bus := NewBus()
+	var got []string
+	Subscribe(bus, "first", func(ctx context.Context, e PatientDeleted) error {
+		got = append(got, "first")
+		return errors.New("boom")
+	})
+	Subscribe(bus, "panics", func(ctx context.Context, e PatientDeleted) error {
+		panic("subscriber bug")
+	})
+	Subscribe(bus, "second", func(ctx context.Context, e PatientDeleted) error {
+		got = append(got, "second")
+		if e.PatientID != 7 {
+			t.Errorf("PatientID = %d, want 7", e.PatientID)
+		}
+		return nil
+	})
+	Subscribe(bus, "other", func(ctx context.Context, e UserDeactivated) error {
+		got = append(got, "other")
+		return nil
+	})
+
+	bus.Publish(context.Background(), PatientDeleted{PatientID: 7})
+
+	if len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Fatalf("delivered to %v, want [first second]", got)
+	}
+}
+
+func TestBus_NilDiscards(t *testing.T) {
+	var bus *Bus
+	bus.Publish(context.Background(), UserDeactivated{UserID: 1})
+}
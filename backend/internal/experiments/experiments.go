@@ -0,0 +1,37 @@
+// Package experiments assigns users to the variants of soft-launched
+// changes, such as alternative recommendation wording. Assignment is a hash
+// of the experiment and unit, so it is stable across requests and restarts
+// without storing anything.
+package experiments
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+)
+
+// Experiment is a named change with two or more variants; the first is the
+// control, the behaviour everyone gets while the experiment is off.
+type Experiment struct {
+	Name     string
+	Variants []string
+}
+
+// Control returns the variant served when the experiment is off.
+func (e Experiment) Control() string {
+	return e.Variants[0]
+}
+
+// Assign returns unit's variant. Units should be as coarse as the change is
+// visible: a clinic, so colleagues sharing patients see the same wording,
+// or a user outside any clinic.
+func (e Experiment) Assign(unit string) string {
+	// Not FNV: its low bit only depends on the parity of the input bytes,
+	// which would pair up experiments' assignments
+	sum := sha256.Sum256([]byte(e.Name + "/" + unit))
+	return e.Variants[binary.BigEndian.Uint32(sum[:4])%uint32(len(e.Variants))]
+}
+
+// ClinicUnit and UserUnit name the units variants are assigned to.
+func ClinicUnit(clinicID int64) string { return "clinic:" + strconv.FormatInt(clinicID, 10) }
+func UserUnit(userID int64) string     { return "user:" + strconv.FormatInt(userID, 10) }
@@ -0,0 +1,31 @@
+package experiments
+
+import "testing"
+
+func TestAssign(t *testing.T) {
+	exp := Experiment{Name: "wording", Variants: []string{"a", "b"}}
+	counts := map[string]int{}
+	for i := int64(1); i <= 200; i++ {
+		unit := ClinicUnit(i)
+		v := exp.Assign(unit)
+		if v != exp.Assign(unit) {
+			t.Fatalf("assignment of %s is not stable", unit)
+		}
+		counts[v]++
+	}
+	if counts["a"] < 60 || counts["b"] < 60 {
+		t.Fatalf("assignment is badly skewed: %v", counts)
+	}
+
+	other := Experiment{Name: "other", Variants: exp.Variants}
+	differs := false
+	for i := int64(1); i <= 20 && !differs; i++ {
+		differs = exp.Assign(UserUnit(i)) != other.Assign(UserUnit(i))
+	}
+	if !differs {
+		t.Fatal("experiments should be assigned independently")
+	}
+	if exp.Control() != "a" {
+		t.Fatalf("control = %s, want the first variant", exp.Control())
+	}
+}
@@ -0,0 +1,168 @@
+// Package fhir maps DIANA patients and assessments to HL7 FHIR R4 resources
+// and reads FHIR Observation bundles back into assessments. Only the
+// elements DIANA has data for are modelled; everything else is left out of
+// the structs, so unknown elements in incoming resources are ignored.
+package fhir
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/loinc"
+)
+
+// ContentType is the media type of FHIR JSON requests and responses
+const ContentType = "application/fhir+json"
+
+// Code systems used in the resources DIANA produces and accepts
+const (
+	LOINC               = loinc.System
+	UCUM                = "http://unitsofmeasure.org"
+	SNOMED              = "http://snomed.info/sct"
+	ObservationCategory = "http://terminology.hl7.org/CodeSystem/observation-category"
+	// ClusterSystem codes the diabetes subtype clusters (SIDD, SIRD, MOD, MARD)
+	ClusterSystem = "https://diana.app/fhir/CodeSystem/cluster"
+	// MRNSystem identifies the medical record numbers clinics enter in DIANA
+	MRNSystem = "https://diana.app/fhir/NamingSystem/mrn"
+)
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+type Quantity struct {
+	Value  *float64 `json:"value,omitempty"`
+	Unit   string   `json:"unit,omitempty"`
+	System string   `json:"system,omitempty"`
+	Code   string   `json:"code,omitempty"`
+}
+
+type Reference struct {
+	Reference string `json:"reference,omitempty"`
+	Display   string `json:"display,omitempty"`
+}
+
+type Identifier struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value,omitempty"`
+}
+
+type HumanName struct {
+	Text string `json:"text,omitempty"`
+}
+
+type Meta struct {
+	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+}
+
+type Patient struct {
+	ResourceType string       `json:"resourceType"`
+	ID           string       `json:"id,omitempty"`
+	Meta         *Meta        `json:"meta,omitempty"`
+	Identifier   []Identifier `json:"identifier,omitempty"`
+	Name         []HumanName  `json:"name,omitempty"`
+}
+
+type Observation struct {
+	ResourceType      string                 `json:"resourceType"`
+	ID                string                 `json:"id,omitempty"`
+	Meta              *Meta                  `json:"meta,omitempty"`
+	Status            string                 `json:"status"`
+	Category          []CodeableConcept      `json:"category,omitempty"`
+	Code              CodeableConcept        `json:"code"`
+	Subject           *Reference             `json:"subject,omitempty"`
+	EffectiveDateTime string                 `json:"effectiveDateTime,omitempty"`
+	ValueQuantity     *Quantity              `json:"valueQuantity,omitempty"`
+	Component         []ObservationComponent `json:"component,omitempty"`
+}
+
+type ObservationComponent struct {
+	Code          CodeableConcept `json:"code"`
+	ValueQuantity *Quantity       `json:"valueQuantity,omitempty"`
+}
+
+type RiskAssessment struct {
+	ResourceType       string           `json:"resourceType"`
+	ID                 string           `json:"id,omitempty"`
+	Meta               *Meta            `json:"meta,omitempty"`
+	Status             string           `json:"status"`
+	Subject            Reference        `json:"subject"`
+	OccurrenceDateTime string           `json:"occurrenceDateTime,omitempty"`
+	Method             *CodeableConcept `json:"method,omitempty"`
+	Basis              []Reference      `json:"basis,omitempty"`
+	Prediction         []RiskPrediction `json:"prediction,omitempty"`
+}
+
+type RiskPrediction struct {
+	Outcome            CodeableConcept  `json:"outcome"`
+	ProbabilityDecimal *float64         `json:"probabilityDecimal,omitempty"`
+	QualitativeRisk    *CodeableConcept `json:"qualitativeRisk,omitempty"`
+}
+
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	ID           string        `json:"id,omitempty"`
+	Type         string        `json:"type"`
+	Total        *int          `json:"total,omitempty"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+type BundleEntry struct {
+	FullURL  string          `json:"fullUrl,omitempty"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+	Request  *BundleRequest  `json:"request,omitempty"`
+	Response *BundleResponse `json:"response,omitempty"`
+}
+
+type BundleRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+type BundleResponse struct {
+	Status   string `json:"status"`
+	Location string `json:"location,omitempty"`
+}
+
+// OperationOutcome carries errors in place of a resource
+type OperationOutcome struct {
+	ResourceType string  `json:"resourceType"`
+	Issue        []Issue `json:"issue"`
+}
+
+type Issue struct {
+	Severity    string   `json:"severity"`
+	Code        string   `json:"code"`
+	Diagnostics string   `json:"diagnostics,omitempty"`
+	Expression  []string `json:"expression,omitempty"`
+}
+
+// Outcome returns an OperationOutcome with one error issue of the given
+// FHIR issue type (e.g. "not-found", "invalid").
+func Outcome(code, diagnostics string) OperationOutcome {
+	return OperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue:        []Issue{{Severity: "error", Code: code, Diagnostics: diagnostics}},
+	}
+}
+
+// SearchSet wraps search results in a searchset Bundle.
+func SearchSet[R any](resources []R) (Bundle, error) {
+	total := len(resources)
+	b := Bundle{ResourceType: "Bundle", Type: "searchset", Total: &total, Entry: []BundleEntry{}}
+	for _, r := range resources {
+		raw, err := json.Marshal(r)
+		if err != nil {
+			return Bundle{}, err
+		}
+		b.Entry = append(b.Entry, BundleEntry{Resource: raw})
+	}
+	return b, nil
+}
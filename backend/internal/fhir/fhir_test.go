@@ -0,0 +1,116 @@
+package fhir
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestNewObservations(t *testing.T) {
+	a := models.Assessment{ID: 12, PatientID: 4, HbA1c: 6.8, LDL: 130, Systolic: 135, Diastolic: 85, BMI: 31}
+	obs := NewObservations(a)
+	ids := map[string]Observation{}
+	for _, o := range obs {
+		ids[o.ID] = o
+	}
+	if len(obs) != 4 || ids["12-hba1c"].ID == "" || ids["12-ldl"].ID == "" || ids["12-bmi"].ID == "" {
+		t.Fatalf("unexpected observations %+v", obs)
+	}
+	bp := ids["12-bp"]
+	if bp.Code.Coding[0].Code != "85354-9" || len(bp.Component) != 2 || bp.ValueQuantity != nil {
+		t.Fatalf("expected a blood pressure panel, got %+v", bp)
+	}
+	if bp.Subject.Reference != "Patient/4" {
+		t.Errorf("unexpected subject %q", bp.Subject.Reference)
+	}
+
+	risk := NewRiskAssessment(models.Assessment{ID: 12, PatientID: 4, HbA1c: 6.8, Cluster: "SIRD", RiskScore: 85, ModelVersion: "v2"})
+	if risk.Status != "final" || len(risk.Prediction) != 1 || *risk.Prediction[0].ProbabilityDecimal != 0.85 ||
+		risk.Prediction[0].QualitativeRisk.Coding[0].Code != "SIRD" || risk.Basis[0].Reference != "Observation/12-hba1c" {
+		t.Fatalf("unexpected risk assessment %+v", risk)
+	}
+	pending := NewRiskAssessment(models.Assessment{ID: 13, Cluster: models.ClusterPendingPrediction})
+	if pending.Status != "registered" || pending.Prediction != nil {
+		t.Errorf("expected a registered assessment without prediction, got %+v", pending)
+	}
+}
+
+func TestParseObservationID(t *testing.T) {
+	if id, key, ok := ParseObservationID("12-hba1c"); !ok || id != 12 || key != "hba1c" {
+		t.Errorf("got %d %q %v", id, key, ok)
+	}
+	for _, bad := range []string{"12", "x-hba1c", "12-", "-1-bp"} {
+		if _, _, ok := ParseObservationID(bad); ok {
+			t.Errorf("%q: expected invalid", bad)
+		}
+	}
+}
+
+func bundleOf(t *testing.T, typ string, resources ...string) Bundle {
+	t.Helper()
+	b := Bundle{ResourceType: "Bundle", Type: typ}
+	for _, r := range resources {
+		b.Entry = append(b.Entry, BundleEntry{Resource: json.RawMessage(r), Request: &BundleRequest{Method: "POST", URL: "Observation"}})
+	}
+	return b
+}
+
+func TestReadObservationBundle(t *testing.T) {
+	b := bundleOf(t, "transaction",
+		`{"resourceType":"Observation","status":"final","subject":{"reference":"Patient/4"},"effectiveDateTime":"2026-03-01",
+		  "code":{"coding":[{"system":"http://loinc.org","code":"4548-4"}]},"valueQuantity":{"value":6.8,"code":"%"}}`,
+		`{"resourceType":"Observation","status":"final","subject":{"reference":"Patient/4"},"effectiveDateTime":"2026-03-01",
+		  "code":{"coding":[{"system":"http://loinc.org","code":"1558-6"}]},"valueQuantity":{"value":7,"unit":"mmol/L"}}`,
+		`{"resourceType":"Observation","status":"final","subject":{"reference":"Patient/4"},"effectiveDateTime":"2026-03-01",
+		  "code":{"coding":[{"system":"http://loinc.org","code":"85354-9"}]},"component":[
+		    {"code":{"coding":[{"system":"http://loinc.org","code":"8480-6"}]},"valueQuantity":{"value":135,"code":"mm[Hg]"}},
+		    {"code":{"coding":[{"system":"http://loinc.org","code":"8462-4"}]},"valueQuantity":{"value":85,"code":"mm[Hg]"}}]}`,
+		`{"resourceType":"Observation","status":"final","subject":{"reference":"Patient/5"},
+		  "code":{"coding":[{"system":"http://loinc.org","code":"39156-5"}]},"valueQuantity":{"value":27.5,"code":"kg/m2"}}`,
+	)
+	imports, issues := ReadObservationBundle(b)
+	if len(issues) > 0 {
+		t.Fatalf("unexpected issues %+v", issues)
+	}
+	if len(imports) != 2 {
+		t.Fatalf("expected two assessments, got %d", len(imports))
+	}
+	a := imports[0].Assessment
+	if a.PatientID != 4 || a.HbA1c != 6.8 || math.Abs(a.FBS-126.112) > 0.001 || a.Systolic != 135 || a.Diastolic != 85 {
+		t.Fatalf("unexpected assessment %+v", a)
+	}
+	if len(imports[0].Entries) != 3 || imports[0].Entries[2].Key != "bp" {
+		t.Errorf("unexpected entries %+v", imports[0].Entries)
+	}
+	if imports[1].PatientID != 5 || imports[1].Assessment.BMI != 27.5 || imports[1].Entries[0].Index != 3 {
+		t.Errorf("unexpected second import %+v", imports[1])
+	}
+}
+
+func TestReadObservationBundle_Issues(t *testing.T) {
+	cases := map[string]Bundle{
+		"collection": bundleOf(t, "collection",
+			`{"resourceType":"Observation","status":"final","subject":{"reference":"Patient/4"},"code":{"coding":[{"system":"http://loinc.org","code":"4548-4"}]},"valueQuantity":{"value":6.8,"code":"%"}}`),
+		"not an observation": bundleOf(t, "batch", `{"resourceType":"Patient"}`),
+		"preliminary": bundleOf(t, "batch",
+			`{"resourceType":"Observation","status":"preliminary","subject":{"reference":"Patient/4"},"code":{"coding":[{"system":"http://loinc.org","code":"4548-4"}]},"valueQuantity":{"value":6.8,"code":"%"}}`),
+		"unknown code": bundleOf(t, "batch",
+			`{"resourceType":"Observation","status":"final","subject":{"reference":"Patient/4"},"code":{"coding":[{"system":"http://loinc.org","code":"2345-7"}]},"valueQuantity":{"value":99,"code":"mg/dL"}}`),
+		"wrong unit": bundleOf(t, "batch",
+			`{"resourceType":"Observation","status":"final","subject":{"reference":"Patient/4"},"code":{"coding":[{"system":"http://loinc.org","code":"4548-4"}]},"valueQuantity":{"value":48,"code":"mmol/mol"}}`),
+		"no subject": bundleOf(t, "batch",
+			`{"resourceType":"Observation","status":"final","code":{"coding":[{"system":"http://loinc.org","code":"4548-4"}]},"valueQuantity":{"value":6.8,"code":"%"}}`),
+		"duplicate": bundleOf(t, "batch",
+			`{"resourceType":"Observation","status":"final","subject":{"reference":"Patient/4"},"code":{"coding":[{"system":"http://loinc.org","code":"4548-4"}]},"valueQuantity":{"value":6.8,"code":"%"}}`,
+			`{"resourceType":"Observation","status":"final","subject":{"reference":"Patient/4"},"code":{"coding":[{"system":"http://loinc.org","code":"17856-6"}]},"valueQuantity":{"value":6.9,"code":"%"}}`),
+		"empty": {ResourceType: "Bundle", Type: "batch"},
+	}
+	for name, b := range cases {
+		imports, issues := ReadObservationBundle(b)
+		if len(issues) == 0 || imports != nil {
+			t.Errorf("%s: expected issues, got %+v", name, imports)
+		}
+	}
+}
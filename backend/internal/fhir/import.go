@@ -0,0 +1,201 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/skufu/DianaV2/backend/internal/loinc"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// Import is one assessment read from a bundle: the observations for one
+// patient with the same effectiveDateTime.
+type Import struct {
+	PatientID int64
+	// Effective is the shared effectiveDateTime, empty if none was given
+	Effective  string
+	Assessment models.Assessment
+	Entries    []ImportedEntry
+}
+
+// ImportedEntry ties a bundle entry to the biomarker it supplied, so the
+// response can point at the Observation it became.
+type ImportedEntry struct {
+	Index int
+	Key   string
+}
+
+// ReadObservationBundle reads a batch or transaction Bundle of
+// Observations into assessments, grouping observations by subject and
+// effectiveDateTime. Each supported LOINC code may appear once per group.
+// Any problem is reported as an issue and no imports are returned.
+func ReadObservationBundle(b Bundle) ([]Import, []Issue) {
+	var issues []Issue
+	fail := func(path, code, msg string) {
+		issues = append(issues, Issue{Severity: "error", Code: code, Diagnostics: msg, Expression: []string{path}})
+	}
+	if b.ResourceType != "Bundle" {
+		fail("Bundle.resourceType", "structure", "expected a Bundle")
+		return nil, issues
+	}
+	if b.Type != "batch" && b.Type != "transaction" {
+		fail("Bundle.type", "value", "bundle type must be batch or transaction")
+	}
+	if len(b.Entry) == 0 {
+		fail("Bundle.entry", "required", "bundle has no entries")
+	}
+
+	groups := map[string]*Import{}
+	var order []*Import
+	seen := map[*Import]map[string]bool{}
+	for i, e := range b.Entry {
+		path := fmt.Sprintf("Bundle.entry[%d]", i)
+		if e.Request != nil && e.Request.Method != "POST" {
+			fail(path+".request.method", "not-supported", "only POST entries can be imported")
+			continue
+		}
+		var o Observation
+		if err := json.Unmarshal(e.Resource, &o); err != nil || o.ResourceType != "Observation" {
+			fail(path+".resource", "not-supported", "only Observation resources can be imported")
+			continue
+		}
+		path += ".resource"
+		switch o.Status {
+		case "final", "amended", "corrected":
+		default:
+			fail(path+".status", "value", "status must be final, amended or corrected")
+			continue
+		}
+		if o.Subject == nil {
+			fail(path+".subject", "required", "subject must reference a Patient")
+			continue
+		}
+		patientID, ok := ParsePatientRef(o.Subject.Reference)
+		if !ok {
+			fail(path+".subject", "value", "subject must reference a Patient as Patient/<id>")
+			continue
+		}
+		key, values, problem := readObservation(o)
+		if problem != nil {
+			fail(path+problem.path, problem.code, problem.msg)
+			continue
+		}
+
+		groupKey := fmt.Sprintf("%d|%s", patientID, o.EffectiveDateTime)
+		imp := groups[groupKey]
+		if imp == nil {
+			imp = &Import{PatientID: patientID, Effective: o.EffectiveDateTime, Assessment: models.Assessment{PatientID: patientID}}
+			groups[groupKey] = imp
+			order = append(order, imp)
+			seen[imp] = map[string]bool{}
+		}
+		for _, v := range values {
+			if seen[imp][v.b.Key] {
+				fail(path+".code", "duplicate", v.b.Display+" appears more than once for this patient and time")
+				continue
+			}
+			seen[imp][v.b.Key] = true
+			v.b.Set(&imp.Assessment, v.value)
+		}
+		imp.Entries = append(imp.Entries, ImportedEntry{Index: i, Key: key})
+	}
+	if len(issues) > 0 {
+		return nil, issues
+	}
+
+	out := make([]Import, len(order))
+	for i, imp := range order {
+		out[i] = *imp
+	}
+	return out, nil
+}
+
+type readValue struct {
+	b     loinc.Biomarker
+	value float64
+}
+
+type readProblem struct {
+	path, code, msg string
+}
+
+// readObservation returns the biomarker values an Observation carries and
+// the key of the Observation id it maps to.
+func readObservation(o Observation) (string, []readValue, *readProblem) {
+	if hasCode(o.Code, loinc.BloodPressurePanel.Code) {
+		var values []readValue
+		for i, comp := range o.Component {
+			path := fmt.Sprintf(".component[%d]", i)
+			b, ok := lookup(comp.Code)
+			if !ok || (b.Key != "systolic" && b.Key != "diastolic") {
+				return "", nil, &readProblem{path + ".code", "not-supported", "blood pressure components must be systolic (8480-6) or diastolic (8462-4)"}
+			}
+			v, problem := convert(b, comp.ValueQuantity, path)
+			if problem != nil {
+				return "", nil, problem
+			}
+			values = append(values, readValue{b, v})
+		}
+		if len(values) == 0 {
+			return "", nil, &readProblem{".component", "required", "blood pressure panel has no components"}
+		}
+		return loinc.BloodPressurePanel.Key, values, nil
+	}
+
+	b, ok := lookup(o.Code)
+	if !ok {
+		return "", nil, &readProblem{".code", "not-supported", "no supported LOINC code; see the FHIR section of the backend docs"}
+	}
+	v, problem := convert(b, o.ValueQuantity, "")
+	if problem != nil {
+		return "", nil, problem
+	}
+	key := b.Key
+	if key == "systolic" || key == "diastolic" {
+		key = loinc.BloodPressurePanel.Key
+	}
+	return key, []readValue{{b, v}}, nil
+}
+
+func hasCode(cc CodeableConcept, code string) bool {
+	for _, c := range cc.Coding {
+		if c.System == loinc.System && c.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup finds the biomarker for the first supported LOINC coding
+func lookup(cc CodeableConcept) (loinc.Biomarker, bool) {
+	for _, c := range cc.Coding {
+		if c.System != loinc.System {
+			continue
+		}
+		if b, ok := loinc.Lookup(c.Code); ok {
+			return b, true
+		}
+	}
+	return loinc.Biomarker{}, false
+}
+
+// convert reads a positive quantity in the biomarker's unit, or in an SI
+// unit it can be converted from.
+func convert(b loinc.Biomarker, q *Quantity, path string) (float64, *readProblem) {
+	path += ".valueQuantity"
+	if q == nil || q.Value == nil {
+		return 0, &readProblem{path, "required", "valueQuantity with a value is required"}
+	}
+	if *q.Value <= 0 {
+		return 0, &readProblem{path + ".value", "value", "value must be positive"}
+	}
+	unit := q.Code
+	if unit == "" {
+		unit = q.Unit
+	}
+	v, err := b.Convert(*q.Value, unit)
+	if err != nil {
+		return 0, &readProblem{path + ".code", "value", err.Error()}
+	}
+	return v, nil
+}
@@ -0,0 +1,170 @@
+package fhir
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/loinc"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// diabetesOutcome is what RiskAssessment predictions are about
+var diabetesOutcome = CodeableConcept{
+	Coding: []Coding{{System: SNOMED, Code: "44054006", Display: "Diabetes mellitus type 2"}},
+	Text:   "Type 2 diabetes",
+}
+
+func quantity(value float64, unit string) *Quantity {
+	return &Quantity{Value: &value, Unit: unit, System: UCUM, Code: unit}
+}
+
+func loincConcept(code, display string) CodeableConcept {
+	return CodeableConcept{Coding: []Coding{{System: loinc.System, Code: code, Display: display}}, Text: display}
+}
+
+func categoryConcept(code string) []CodeableConcept {
+	return []CodeableConcept{{Coding: []Coding{{System: ObservationCategory, Code: code}}}}
+}
+
+func meta(t time.Time) *Meta {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &Meta{LastUpdated: &t}
+}
+
+func dateTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// PatientRef is the reference to a patient resource
+func PatientRef(id int64) string {
+	return "Patient/" + strconv.FormatInt(id, 10)
+}
+
+// ParsePatientRef reads a patient id from "Patient/<id>" or a bare "<id>".
+func ParsePatientRef(ref string) (int64, bool) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(ref, "Patient/"), 10, 32)
+	return id, err == nil && id > 0
+}
+
+// NewPatient maps a patient. Only the name and MRN are carried over: DIANA
+// does not record birth dates or administrative gender.
+func NewPatient(p models.Patient) Patient {
+	out := Patient{
+		ResourceType: "Patient",
+		ID:           strconv.FormatInt(p.ID, 10),
+		Meta:         meta(p.UpdatedAt),
+	}
+	if p.Name != "" {
+		out.Name = []HumanName{{Text: p.Name}}
+	}
+	if p.MRN != "" {
+		out.Identifier = []Identifier{{System: MRNSystem, Value: p.MRN}}
+	}
+	return out
+}
+
+// ObservationID is the id of the Observation for one biomarker of an
+// assessment: "<assessment id>-<biomarker>", e.g. "12-hba1c". Systolic and
+// diastolic pressure share the "<assessment id>-bp" panel.
+func ObservationID(assessmentID int64, key string) string {
+	return strconv.FormatInt(assessmentID, 10) + "-" + key
+}
+
+// ParseObservationID splits an Observation id into the assessment id and
+// biomarker key.
+func ParseObservationID(id string) (int64, string, bool) {
+	prefix, key, ok := strings.Cut(id, "-")
+	if !ok || key == "" {
+		return 0, "", false
+	}
+	assessmentID, err := strconv.ParseInt(prefix, 10, 32)
+	return assessmentID, key, err == nil && assessmentID > 0
+}
+
+// NewObservations maps the measured biomarkers of an assessment, one
+// Observation each, with blood pressure as a single panel.
+func NewObservations(a models.Assessment) []Observation {
+	out := []Observation{}
+	base := func(key string) Observation {
+		return Observation{
+			ResourceType:      "Observation",
+			ID:                ObservationID(a.ID, key),
+			Meta:              meta(a.UpdatedAt),
+			Status:            "final",
+			Subject:           &Reference{Reference: PatientRef(a.PatientID)},
+			EffectiveDateTime: dateTime(a.CreatedAt),
+		}
+	}
+	var bp *Observation
+	for _, b := range loinc.Biomarkers {
+		value := b.Get(a)
+		if value == 0 {
+			continue
+		}
+		if b.Key == "systolic" || b.Key == "diastolic" {
+			if bp == nil {
+				panel := loinc.BloodPressurePanel
+				o := base(panel.Key)
+				o.Category = categoryConcept(panel.Category)
+				o.Code = loincConcept(panel.Code, panel.Display)
+				out = append(out, o)
+				bp = &out[len(out)-1]
+			}
+			bp.Component = append(bp.Component, ObservationComponent{
+				Code:          loincConcept(b.Code, b.Display),
+				ValueQuantity: quantity(value, b.Unit),
+			})
+			continue
+		}
+		o := base(b.Key)
+		o.Category = categoryConcept(b.Category)
+		o.Code = loincConcept(b.Code, b.Display)
+		o.ValueQuantity = quantity(value, b.Unit)
+		out = append(out, o)
+	}
+	return out
+}
+
+// NewRiskAssessment maps an assessment's prediction. The risk score (0-100)
+// becomes probabilityDecimal and the cluster the qualitative risk; an
+// assessment still waiting on its prediction is "registered" with none.
+func NewRiskAssessment(a models.Assessment) RiskAssessment {
+	out := RiskAssessment{
+		ResourceType:       "RiskAssessment",
+		ID:                 strconv.FormatInt(a.ID, 10),
+		Meta:               meta(a.UpdatedAt),
+		Status:             "final",
+		Subject:            Reference{Reference: PatientRef(a.PatientID)},
+		OccurrenceDateTime: dateTime(a.CreatedAt),
+	}
+	if a.AmendsAssessmentID != nil {
+		out.Status = "amended"
+	}
+	if a.ModelVersion != "" {
+		out.Method = &CodeableConcept{Text: "DIANA model " + a.ModelVersion}
+	}
+	for _, o := range NewObservations(a) {
+		out.Basis = append(out.Basis, Reference{Reference: "Observation/" + o.ID})
+	}
+	if a.Cluster == models.ClusterPendingPrediction || a.Cluster == "" {
+		out.Status = "registered"
+		return out
+	}
+	probability := float64(a.RiskScore) / 100
+	out.Prediction = []RiskPrediction{{
+		Outcome:            diabetesOutcome,
+		ProbabilityDecimal: &probability,
+		QualitativeRisk: &CodeableConcept{
+			Coding: []Coding{{System: ClusterSystem, Code: a.Cluster}},
+			Text:   a.Cluster,
+		},
+	}}
+	return out
+}
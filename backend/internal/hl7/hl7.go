@@ -0,0 +1,188 @@
+// Package hl7 reads HL7 v2 lab result messages (ORU^R01) and writes the
+// acknowledgements (ACK) lab interfaces expect in reply. Only the segments
+// and fields DIANA uses are interpreted.
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of HL7 v2 messages in ER7 (pipe) encoding
+const ContentType = "x-application/hl7-v2+er7"
+
+// Acknowledgement codes (MSA-1)
+const (
+	// AckAccept means the message was processed
+	AckAccept = "AA"
+	// AckError means the message was understood but could not be processed
+	// in full
+	AckError = "AE"
+	// AckReject means the message was not understood
+	AckReject = "AR"
+)
+
+// Segment is one segment, split into fields. Fields are numbered as in the
+// HL7 standard: Field(1) of MSH is the field separator itself.
+type Segment struct {
+	Name   string
+	fields []string
+}
+
+// Message is a parsed message with its encoding characters.
+type Message struct {
+	Segments     []Segment
+	fieldSep     byte
+	componentSep byte
+	repeatSep    byte
+	escape       byte
+	subSep       byte
+}
+
+// Parse splits a message into segments. Segments may end in \r, \n or
+// \r\n; the first must be MSH.
+func Parse(raw string) (*Message, error) {
+	raw = strings.TrimLeft(raw, "\r\n\x0b")
+	if !strings.HasPrefix(raw, "MSH") || len(raw) < 8 {
+		return nil, errors.New("message must start with an MSH segment")
+	}
+	m := &Message{
+		fieldSep:     raw[3],
+		componentSep: raw[4],
+		repeatSep:    raw[5],
+		escape:       raw[6],
+		subSep:       raw[7],
+	}
+	lines := strings.FieldsFunc(raw, func(r rune) bool { return r == '\r' || r == '\n' || r == '\x1c' })
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		parts := strings.Split(line, string(m.fieldSep))
+		seg := Segment{Name: parts[0]}
+		if seg.Name == "MSH" {
+			// MSH-1 is the separator, so MSH-2 is the first split field
+			seg.fields = append([]string{string(m.fieldSep)}, parts[1:]...)
+		} else {
+			seg.fields = parts[1:]
+		}
+		m.Segments = append(m.Segments, seg)
+	}
+	return m, nil
+}
+
+// Field returns field n (1-based) as sent, or "" if absent.
+func (s Segment) Field(n int) string {
+	if n < 1 || n > len(s.fields) {
+		return ""
+	}
+	return s.fields[n-1]
+}
+
+// Component returns component c (1-based) of field n, unescaped, taking the
+// first repetition.
+func (m *Message) Component(s Segment, n, c int) string {
+	field := s.Field(n)
+	if i := strings.IndexByte(field, m.repeatSep); i >= 0 {
+		field = field[:i]
+	}
+	parts := strings.Split(field, string(m.componentSep))
+	if c < 1 || c > len(parts) {
+		return ""
+	}
+	part := parts[c-1]
+	if i := strings.IndexByte(part, m.subSep); i >= 0 {
+		part = part[:i]
+	}
+	return m.unescape(part)
+}
+
+// Value returns the first component of field n, unescaped.
+func (m *Message) Value(s Segment, n int) string {
+	return m.Component(s, n, 1)
+}
+
+// Header returns the MSH segment.
+func (m *Message) Header() Segment {
+	return m.Segments[0]
+}
+
+// ControlID is MSH-10, the id the sender matches acknowledgements by.
+func (m *Message) ControlID() string {
+	return m.Value(m.Header(), 10)
+}
+
+// Type is MSH-9 as "<message code>^<trigger event>", e.g. "ORU^R01".
+func (m *Message) Type() string {
+	h := m.Header()
+	return m.Component(h, 9, 1) + "^" + m.Component(h, 9, 2)
+}
+
+// SendingFacility is MSH-4.
+func (m *Message) SendingFacility() string {
+	return m.Value(m.Header(), 4)
+}
+
+func (m *Message) unescape(v string) string {
+	esc := string(m.escape)
+	if !strings.Contains(v, esc) {
+		return v
+	}
+	return strings.NewReplacer(
+		esc+"F"+esc, string(m.fieldSep),
+		esc+"S"+esc, string(m.componentSep),
+		esc+"R"+esc, string(m.repeatSep),
+		esc+"T"+esc, string(m.subSep),
+		esc+"E"+esc, esc,
+	).Replace(v)
+}
+
+// escapeText escapes the default delimiters in text written to an ACK
+func escapeText(v string) string {
+	return strings.NewReplacer(`\`, `\E\`, "|", `\F\`, "^", `\S\`, "~", `\R\`, "&", `\T\`, "\r", " ", "\n", " ").Replace(v)
+}
+
+// ParseTime reads an HL7 DTM such as 20260301083000 or 20260301083000+0800.
+// Values without an offset are taken as UTC.
+func ParseTime(v string) (time.Time, error) {
+	if i := strings.IndexByte(v, '.'); i >= 0 {
+		// Fractional seconds come before any offset
+		end := i + 1
+		for end < len(v) && v[end] >= '0' && v[end] <= '9' {
+			end++
+		}
+		v = v[:i] + v[end:]
+	}
+	for _, layout := range []string{"20060102150405-0700", "200601021504-0700", "20060102150405", "200601021504", "20060102"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid HL7 date %q", v)
+}
+
+// ACK builds the acknowledgement for m. m may be nil when the message could
+// not be parsed at all; the ACK then carries no control id.
+func ACK(m *Message, code, text string, now time.Time) string {
+	receivingApp, receivingFacility, controlID, processingID, version := "", "", "", "P", "2.5.1"
+	if m != nil {
+		h := m.Header()
+		receivingApp, receivingFacility = escapeText(m.Value(h, 3)), escapeText(m.Value(h, 4))
+		controlID = m.ControlID()
+		if p := m.Value(h, 11); p != "" {
+			processingID = escapeText(p)
+		}
+		if v := m.Value(h, 12); v != "" {
+			version = escapeText(v)
+		}
+	}
+	ts := now.UTC().Format("20060102150405")
+	msh := strings.Join([]string{
+		"MSH", `^~\&`, "DIANA", "DIANA", receivingApp, receivingFacility, ts, "",
+		"ACK^R01^ACK", "DIANA" + now.UTC().Format("20060102150405.000000"), processingID, version,
+	}, "|")
+	msa := strings.Join([]string{"MSA", code, escapeText(controlID), escapeText(text)}, "|")
+	return msh + "\r" + msa + "\r"
+}
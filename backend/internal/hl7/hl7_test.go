@@ -0,0 +1,85 @@
+package hl7
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const oru = "\x0bMSH|^~\\&|LIS|City\\T\\Lab|DIANA|Clinic|20260301090000||ORU^R01^ORU_R01|MSG1|T|2.3\r\n" +
+	"PID|1||MRN-1~OTHER^^^CityLab||Santos^Maria\r\n" +
+	"OBR|1|||24331-1^Lipid panel^LN|||20260301083000\r\n" +
+	"OBX|1|NM|LDLC^LDL direct^L^18262-6^LDL^LN||3.1|mmol/L|||||F|||20260301084500+0800\r\n" +
+	"OBX|2|NM|4548-4^HbA1c^LN||6.4|%|||||P\r\n" +
+	"OBX|3|NM|4548-4^HbA1c^LN||6.6|%|||||C\r\n" +
+	"OBX|4|NM|2085-9^HDL^LN||45|g/L|||||F\r\n" +
+	"OBX|5|NM|8480-6^Systolic^LN||130|mm[Hg]|||||F\r\n" +
+	"\x1c\r"
+
+func TestParse(t *testing.T) {
+	m, err := Parse(oru)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Type() != "ORU^R01" || m.ControlID() != "MSG1" || m.SendingFacility() != "City&Lab" {
+		t.Fatalf("unexpected header: %s %s %s", m.Type(), m.ControlID(), m.SendingFacility())
+	}
+	if got := m.Header().Field(2); got != `^~\&` {
+		t.Errorf("expected MSH-2 to hold the encoding characters, got %q", got)
+	}
+	pid := m.Segments[1]
+	if m.Value(pid, 3) != "MRN-1" || m.Component(pid, 5, 2) != "Maria" || m.Value(pid, 40) != "" {
+		t.Errorf("unexpected PID fields")
+	}
+
+	if _, err := Parse("PID|1"); err == nil {
+		t.Error("expected messages without MSH to be rejected")
+	}
+}
+
+func TestLabResults(t *testing.T) {
+	m, _ := Parse(oru)
+	results, err := m.LabResults()
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected one patient, got %v %v", results, err)
+	}
+	r := results[0]
+	if r.MRN != "MRN-1" || r.FamilyName != "Santos" || r.Found != 2 {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	// 3.1 mmol/L LDL via the alternate LOINC code, and the corrected HbA1c
+	if r.Values.LDL != 120 || r.Values.HbA1c != 6.6 || r.Values.HDL != 0 || r.Values.Systolic != 0 {
+		t.Errorf("unexpected values %+v", r.Values)
+	}
+	if want := time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC); r.ObservedAt == nil || !r.ObservedAt.Equal(want) {
+		t.Errorf("expected the latest observation time %v, got %v", want, r.ObservedAt)
+	}
+	if len(r.Skipped) != 3 || !strings.Contains(strings.Join(r.Skipped, ";"), `unit "g/L"`) {
+		t.Errorf("expected pending, unit and vital sign results to be skipped, got %q", r.Skipped)
+	}
+
+	adt, _ := Parse(strings.Replace(oru, "ORU^R01", "ADT^A01", 1))
+	if _, err := adt.LabResults(); err != ErrNotORU {
+		t.Errorf("expected ErrNotORU, got %v", err)
+	}
+}
+
+func TestACK(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	m, _ := Parse(oru)
+	ack := ACK(m, AckError, "no patient|matches", now)
+	lines := strings.Split(strings.TrimSuffix(ack, "\r"), "\r")
+	if len(lines) != 2 {
+		t.Fatalf("expected MSH and MSA, got %q", ack)
+	}
+	if !strings.HasPrefix(lines[0], `MSH|^~\&|DIANA|DIANA|LIS|City\T\Lab|20260301090000||ACK^R01^ACK|`) ||
+		!strings.HasSuffix(lines[0], "|T|2.3") {
+		t.Errorf("unexpected MSH %q", lines[0])
+	}
+	if lines[1] != `MSA|AE|MSG1|no patient\F\matches` {
+		t.Errorf("unexpected MSA %q", lines[1])
+	}
+	if got := ACK(nil, AckReject, "bad", now); !strings.Contains(got, "MSA|AR||bad") {
+		t.Errorf("unexpected ACK for an unparsable message %q", got)
+	}
+}
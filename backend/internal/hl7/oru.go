@@ -0,0 +1,121 @@
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/loinc"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// ErrNotORU is returned for messages other than ORU^R01
+var ErrNotORU = errors.New("only ORU^R01 messages are accepted")
+
+// PatientResult is the lab results for one PID group of an ORU^R01 message.
+type PatientResult struct {
+	// MRN is the first identifier in PID-3
+	MRN        string
+	FamilyName string
+	GivenName  string
+	// Values holds the lab biomarkers found; zero fields were not reported
+	Values models.Assessment
+	// Found counts the OBX segments mapped into Values
+	Found int
+	// ObservedAt is the latest observation time given, if any
+	ObservedAt *time.Time
+	// Skipped describes each OBX segment that was not used and why
+	Skipped []string
+}
+
+// LabResults reads the numeric, final lab results DIANA uses from an
+// ORU^R01 message: fasting glucose, HbA1c and the lipid panel, identified by
+// LOINC code (coding system LN) in OBX-3 or its alternate identifier.
+func (m *Message) LabResults() ([]PatientResult, error) {
+	if m.Type() != "ORU^R01" {
+		return nil, ErrNotORU
+	}
+	var out []PatientResult
+	var current *PatientResult
+	var orderTime *time.Time
+	for _, seg := range m.Segments[1:] {
+		switch seg.Name {
+		case "PID":
+			out = append(out, PatientResult{
+				MRN:        m.Component(seg, 3, 1),
+				FamilyName: m.Component(seg, 5, 1),
+				GivenName:  m.Component(seg, 5, 2),
+			})
+			current = &out[len(out)-1]
+			orderTime = nil
+		case "OBR":
+			if t, err := ParseTime(m.Value(seg, 7)); err == nil {
+				orderTime = &t
+			}
+		case "OBX":
+			if current == nil {
+				return nil, errors.New("OBX segment before any PID segment")
+			}
+			m.readOBX(seg, current, orderTime)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("message has no PID segment")
+	}
+	return out, nil
+}
+
+// readOBX maps one OBX segment into r, or records why it was skipped.
+func (m *Message) readOBX(seg Segment, r *PatientResult, orderTime *time.Time) {
+	setID := m.Value(seg, 1)
+	skip := func(format string, args ...interface{}) {
+		r.Skipped = append(r.Skipped, fmt.Sprintf("OBX %s: ", setID)+fmt.Sprintf(format, args...))
+	}
+
+	var b loinc.Biomarker
+	found := false
+	// OBX-3 is code^text^system, optionally followed by an alternate triple
+	for _, first := range []int{1, 4} {
+		system := m.Component(seg, 3, first+2)
+		if system != "LN" && system != "LOINC" {
+			continue
+		}
+		if b, found = loinc.Lookup(m.Component(seg, 3, first)); found {
+			break
+		}
+	}
+	if !found || b.Category != "laboratory" {
+		skip("%s is not a supported LOINC lab code", m.Component(seg, 3, 1))
+		return
+	}
+	if status := m.Value(seg, 11); status != "F" && status != "C" {
+		skip("result status %q is not final", status)
+		return
+	}
+	if vt := m.Value(seg, 2); vt != "NM" {
+		skip("value type %q is not numeric", vt)
+		return
+	}
+	value, err := strconv.ParseFloat(m.Value(seg, 5), 64)
+	if err != nil || value <= 0 {
+		skip("value %q is not a positive number", m.Value(seg, 5))
+		return
+	}
+	value, err = b.Convert(value, m.Component(seg, 6, 1))
+	if err != nil {
+		skip("%v", err)
+		return
+	}
+
+	// A later result for the same test, such as a correction, wins
+	b.Set(&r.Values, value)
+	r.Found++
+	observed := orderTime
+	if t, err := ParseTime(m.Value(seg, 14)); err == nil {
+		observed = &t
+	}
+	if observed != nil && (r.ObservedAt == nil || observed.After(*r.ObservedAt)) {
+		r.ObservedAt = observed
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// apiKeyScopes are the scopes an API key may be granted
+var apiKeyScopes = map[string]bool{
+	models.APIKeyScopeRead:   true,
+	models.APIKeyScopeWrite:  true,
+	models.APIKeyScopeExport: true,
+}
+
+// AdminAPIKeysHandler lets admins issue and revoke API keys that act as a
+// user, for integration scripts that cannot go through the login flow
+type AdminAPIKeysHandler struct {
+	store store.Store
+}
+
+func NewAdminAPIKeysHandler(store store.Store) *AdminAPIKeysHandler {
+	return &AdminAPIKeysHandler{store: store}
+}
+
+func (h *AdminAPIKeysHandler) Register(rg *gin.RouterGroup) {
+	keys := rg.Group("/api-keys")
+	keys.GET("", h.list)
+	// Issuing a long-lived credential needs a fresh re-authentication
+	keys.POST("", middleware.RequireSudo(), h.create)
+	keys.DELETE("/:id", h.revoke)
+}
+
+type createAPIKeyRequest struct {
+	UserID int64    `json:"user_id" binding:"required,gt=0"`
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	// ExpiresInDays of 0 creates a key that never expires
+	ExpiresInDays int `json:"expires_in_days" binding:"gte=0,lte=3650"`
+}
+
+// newAPIKey returns a fresh key value with the API key prefix
+func newAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return middleware.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// list returns every key, or one user's with ?user_id=
+func (h *AdminAPIKeysHandler) list(c *gin.Context) {
+	var userID int64
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		userID = id
+	}
+	keys, err := h.store.APIKeys().List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list API keys"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": keys})
+}
+
+// create issues a key for an active user. The plaintext value is only ever
+// returned here.
+func (h *AdminAPIKeysHandler) create(c *gin.Context) {
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	for _, s := range req.Scopes {
+		if !apiKeyScopes[s] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown scope: " + s})
+			return
+		}
+	}
+	user, err := h.store.Users().FindByID(c.Request.Context(), int32(req.UserID))
+	if err != nil || !user.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user not found or inactive"})
+		return
+	}
+
+	value, err := newAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "key error"})
+		return
+	}
+	claims := c.MustGet("user").(middleware.UserClaims)
+	key := models.APIKey{
+		UserID:    user.ID,
+		Name:      req.Name,
+		KeyHash:   hashToken(value),
+		Prefix:    value[:apiTokenPrefixLen],
+		Scopes:    req.Scopes,
+		CreatedBy: claims.UserID,
+	}
+	if req.ExpiresInDays > 0 {
+		expires := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expires
+	}
+
+	created, err := h.store.APIKeys().Create(c.Request.Context(), key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create API key"})
+		return
+	}
+
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "api_key.create",
+		TargetType: "user",
+		TargetID:   int(created.UserID),
+		Details: map[string]interface{}{
+			"api_key_id": created.ID,
+			"name":       created.Name,
+			"scopes":     created.Scopes,
+		},
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"key":     value,
+		"api_key": created,
+	})
+}
+
+func (h *AdminAPIKeysHandler) revoke(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid key ID"})
+		return
+	}
+
+	revoked, err := h.store.APIKeys().Revoke(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "API key not found or already revoked")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke API key"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "api_key.revoke",
+		TargetType: "user",
+		TargetID:   int(revoked.UserID),
+		Details: map[string]interface{}{
+			"api_key_id": revoked.ID,
+			"name":       revoked.Name,
+		},
+	})
+
+	c.JSON(http.StatusOK, revoked)
+}
+
+// APIKeyLookup resolves API keys against the store for
+// middleware.AuthOrAPIKey. The user is loaded on every request, so a role
+// change or deactivation applies to their keys at once. Last use is
+// recorded as for API tokens.
+func APIKeyLookup(st store.Store) middleware.APIKeyLookup {
+	return func(ctx context.Context, value string) (*middleware.APIKeyClaims, error) {
+		key, err := st.APIKeys().FindActive(ctx, hashToken(value))
+		if err != nil {
+			return nil, err
+		}
+		user, err := st.Users().FindByID(ctx, int32(key.UserID))
+		if err != nil {
+			return nil, err
+		}
+		if !user.IsActive {
+			return nil, errors.New("user is inactive")
+		}
+		if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > apiTokenTouchInterval {
+			if err := st.APIKeys().MarkUsed(ctx, key.ID); err != nil {
+				logging.Ctx(ctx).Error().Err(err).Msgf("Failed to record use of API key %d", key.ID)
+			}
+		}
+		return &middleware.APIKeyClaims{
+			KeyID:  key.ID,
+			Name:   key.Name,
+			Scopes: key.Scopes,
+			User:   middleware.UserClaims{UserID: user.ID, Email: user.Email, Role: user.Role},
+		}, nil
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+func TestAdminAPIKeys_Lifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mem := store.NewMemoryStore()
+	user, err := mem.Users().Create(context.Background(), models.User{Email: "script@example.com", Role: "clinician"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit := &fakeAuditRepo{}
+	st := &fakeStore{users: mem.Users(), apiKeys: mem.APIKeys(), audit: audit}
+
+	r := gin.New()
+	admin := r.Group("/admin")
+	admin.Use(sudoAuthMiddleware())
+	NewAdminAPIKeysHandler(st).Register(admin)
+	api := r.Group("/api")
+	api.Use(middleware.AuthOrAPIKey("secret", APIKeyLookup(st)))
+	api.GET("/me", func(c *gin.Context) { c.JSON(http.StatusOK, c.MustGet("user")) })
+
+	if w := postJSON(r, "/admin/api-keys", `{"user_id":`+strconv.FormatInt(user.ID, 10)+`,"name":"etl","scopes":["admin"]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown scope: expected 400, got %d", w.Code)
+	}
+	if w := postJSON(r, "/admin/api-keys", `{"user_id":999,"name":"etl","scopes":["read"]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown user: expected 400, got %d", w.Code)
+	}
+
+	w := postJSON(r, "/admin/api-keys", `{"user_id":`+strconv.FormatInt(user.ID, 10)+`,"name":"etl","scopes":["read"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Key    string        `json:"key"`
+		APIKey models.APIKey `json:"api_key"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if !strings.HasPrefix(created.Key, middleware.APIKeyPrefix) || created.APIKey.UserID != user.ID {
+		t.Fatalf("unexpected key %+v", created)
+	}
+
+	get := func(key string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/api/me", nil)
+		req.Header.Set(middleware.APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := get(created.Key); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "script@example.com") {
+		t.Fatalf("request with key: expected the key's user, got %d %s", w.Code, w.Body.String())
+	}
+
+	// Deactivating the user disables their keys at once
+	_ = mem.Users().Deactivate(context.Background(), int32(user.ID))
+	if w := get(created.Key); w.Code != http.StatusUnauthorized {
+		t.Fatalf("inactive user: expected 401, got %d", w.Code)
+	}
+	_ = mem.Users().Activate(context.Background(), int32(user.ID))
+
+	req, _ := http.NewRequest(http.MethodDelete, "/admin/api-keys/"+strconv.FormatInt(created.APIKey.ID, 10), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("revoke: expected 200, got %d", w.Code)
+	}
+	if w := get(created.Key); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked key: expected 401, got %d", w.Code)
+	}
+	if len(audit.events) != 2 || audit.events[0].Action != "api_key.create" || audit.events[1].Action != "api_key.revoke" {
+		t.Fatalf("unexpected audit events %+v", audit.events)
+	}
+}
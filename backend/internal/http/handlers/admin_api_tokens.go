@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// apiTokenScopes are the scopes an API token may be granted
+var apiTokenScopes = map[string]bool{
+	models.ScopeAnalyticsRead: true,
+	models.ScopeHL7Ingest:     true,
+}
+
+// apiTokenPrefixLen is how much of a token is kept in clear for identification
+const apiTokenPrefixLen = 12
+
+// apiTokenTouchInterval limits last_used_at writes to one per token per interval
+const apiTokenTouchInterval = time.Minute
+
+// AdminAPITokensHandler lets admins mint and revoke scoped API tokens
+type AdminAPITokensHandler struct {
+	store store.Store
+}
+
+func NewAdminAPITokensHandler(store store.Store) *AdminAPITokensHandler {
+	return &AdminAPITokensHandler{store: store}
+}
+
+func (h *AdminAPITokensHandler) Register(rg *gin.RouterGroup) {
+	tokens := rg.Group("/api-tokens")
+	tokens.GET("", h.list)
+	// Minting a long-lived credential needs a fresh re-authentication
+	tokens.POST("", middleware.RequireSudo(), h.create)
+	tokens.DELETE("/:id", h.revoke)
+}
+
+type createAPITokenRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	// ExpiresInDays of 0 creates a token that never expires
+	ExpiresInDays int `json:"expires_in_days" binding:"gte=0,lte=3650"`
+}
+
+// newAPIToken returns a fresh token value with the API token prefix
+func newAPIToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return middleware.APITokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (h *AdminAPITokensHandler) list(c *gin.Context) {
+	tokens, err := h.store.APITokens().List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list API tokens"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tokens})
+}
+
+// create mints a token. The plaintext value is only ever returned here.
+func (h *AdminAPITokensHandler) create(c *gin.Context) {
+	var req createAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	for _, s := range req.Scopes {
+		if !apiTokenScopes[s] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown scope: " + s})
+			return
+		}
+	}
+
+	value, err := newAPIToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+	claims := c.MustGet("user").(middleware.UserClaims)
+	token := models.APIToken{
+		Name:      req.Name,
+		TokenHash: hashToken(value),
+		Prefix:    value[:apiTokenPrefixLen],
+		Scopes:    req.Scopes,
+		CreatedBy: claims.UserID,
+	}
+	if req.ExpiresInDays > 0 {
+		expires := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expires
+	}
+
+	created, err := h.store.APITokens().Create(c.Request.Context(), token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create API token"})
+		return
+	}
+
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "api_token.create",
+		TargetType: "api_token",
+		TargetID:   int(created.ID),
+		Details: map[string]interface{}{
+			"name":   created.Name,
+			"scopes": created.Scopes,
+		},
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":     value,
+		"api_token": created,
+	})
+}
+
+func (h *AdminAPITokensHandler) revoke(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token ID"})
+		return
+	}
+
+	revoked, err := h.store.APITokens().Revoke(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "API token not found or already revoked")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke API token"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "api_token.revoke",
+		TargetType: "api_token",
+		TargetID:   int(revoked.ID),
+		Details: map[string]interface{}{
+			"name": revoked.Name,
+		},
+	})
+
+	c.JSON(http.StatusOK, revoked)
+}
+
+// APITokenLookup resolves API tokens against the store for
+// middleware.APITokenAuth. Last use is recorded at most once a minute and
+// only best-effort, so a read-only database does not lock dashboards out.
+func APITokenLookup(st store.Store) middleware.APITokenLookup {
+	return func(ctx context.Context, value string) (*middleware.APITokenClaims, error) {
+		token, err := st.APITokens().FindActive(ctx, hashToken(value))
+		if err != nil {
+			return nil, err
+		}
+		if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > apiTokenTouchInterval {
+			if err := st.APITokens().MarkUsed(ctx, token.ID); err != nil {
+				logging.Ctx(ctx).Error().Err(err).Msgf("Failed to record use of API token %d", token.ID)
+			}
+		}
+		return &middleware.APITokenClaims{TokenID: token.ID, Name: token.Name, Scopes: token.Scopes}, nil
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+type fakeAPITokenRepo struct {
+	tokens []*models.APIToken
+	marked int
+}
+
+func (f *fakeAPITokenRepo) Create(ctx context.Context, t models.APIToken) (*models.APIToken, error) {
+	t.ID = int64(len(f.tokens) + 1)
+	t.CreatedAt = time.Now()
+	f.tokens = append(f.tokens, &t)
+	return &t, nil
+}
+
+func (f *fakeAPITokenRepo) List(ctx context.Context) ([]models.APIToken, error) {
+	var out []models.APIToken
+	for _, t := range f.tokens {
+		out = append(out, *t)
+	}
+	return out, nil
+}
+
+func (f *fakeAPITokenRepo) Revoke(ctx context.Context, id int64) (*models.APIToken, error) {
+	for _, t := range f.tokens {
+		if t.ID == id && t.RevokedAt == nil {
+			now := time.Now()
+			t.RevokedAt = &now
+			return t, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (f *fakeAPITokenRepo) FindActive(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	for _, t := range f.tokens {
+		if t.TokenHash == tokenHash && t.RevokedAt == nil && (t.ExpiresAt == nil || t.ExpiresAt.After(time.Now())) {
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (f *fakeAPITokenRepo) MarkUsed(ctx context.Context, id int64) error {
+	f.marked++
+	now := time.Now()
+	f.tokens[id-1].LastUsedAt = &now
+	return nil
+}
+
+// sudoAuthMiddleware is mockAuthMiddleware with a fresh re-authentication
+func sudoAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user", middleware.UserClaims{
+			UserID:    1,
+			Email:     "admin@example.com",
+			Role:      "admin",
+			SudoUntil: time.Now().Add(time.Minute),
+		})
+		c.Next()
+	}
+}
+
+func TestAdminAPITokens_Lifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &fakeAPITokenRepo{}
+	audit := &fakeAuditRepo{}
+	st := &fakeStore{apiTokens: repo, audit: audit}
+
+	r := gin.New()
+	admin := r.Group("/admin")
+	admin.Use(sudoAuthMiddleware())
+	NewAdminAPITokensHandler(st).Register(admin)
+	reporting := r.Group("/reporting")
+	reporting.Use(middleware.APITokenAuth(APITokenLookup(st), models.ScopeAnalyticsRead))
+	reporting.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	if w := postJSON(r, "/admin/api-tokens", `{"name":"intranet","scopes":["patients:write"]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown scope: expected 400, got %d", w.Code)
+	}
+
+	w := postJSON(r, "/admin/api-tokens", `{"name":"intranet","scopes":["analytics:read"],"expires_in_days":30}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Token    string          `json:"token"`
+		APIToken models.APIToken `json:"api_token"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if !strings.HasPrefix(created.Token, middleware.APITokenPrefix) || created.APIToken.Prefix != created.Token[:apiTokenPrefixLen] {
+		t.Fatalf("unexpected token %+v", created)
+	}
+	if repo.tokens[0].TokenHash == created.Token || created.APIToken.ExpiresAt == nil {
+		t.Fatalf("token must be stored hashed with an expiry: %+v", repo.tokens[0])
+	}
+	if strings.Contains(w.Body.String(), repo.tokens[0].TokenHash) {
+		t.Fatal("hash must not be returned")
+	}
+
+	get := func(token string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/reporting/ping", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := get(created.Token); code != http.StatusOK {
+		t.Fatalf("reporting with token: expected 200, got %d", code)
+	}
+	get(created.Token)
+	if repo.marked != 1 {
+		t.Fatalf("expected last use recorded once within a minute, got %d", repo.marked)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, "/admin/api-tokens/1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("revoke: expected 200, got %d", w.Code)
+	}
+	if code := get(created.Token); code != http.StatusUnauthorized {
+		t.Fatalf("revoked token: expected 401, got %d", code)
+	}
+	if len(audit.events) != 2 || audit.events[0].Action != "api_token.create" || audit.events[1].Action != "api_token.revoke" {
+		t.Fatalf("unexpected audit events %+v", audit.events)
+	}
+}
+
+func TestAdminAPITokens_CreateRequiresSudo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	NewAdminAPITokensHandler(&fakeStore{apiTokens: &fakeAPITokenRepo{}}).Register(r.Group("/admin"))
+
+	if w := postJSON(r, "/admin/api-tokens", `{"name":"x","scopes":["analytics:read"]}`); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without sudo, got %d", w.Code)
+	}
+}
@@ -1,55 +1,80 @@
 package handlers
 
 import (
+	"encoding/csv"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/logging"
 	"github.com/skufu/DianaV2/backend/internal/models"
 	"github.com/skufu/DianaV2/backend/internal/store"
 )
 
 // AdminAuditHandler handles audit log viewing operations
 type AdminAuditHandler struct {
-	store store.Store
+	store   store.Store
+	maxRows int
 }
 
 // NewAdminAuditHandler creates a new AdminAuditHandler
 func NewAdminAuditHandler(store store.Store) *AdminAuditHandler {
-	return &AdminAuditHandler{store: store}
+	return &AdminAuditHandler{store: store, maxRows: defaultAuditExportMaxRows}
 }
 
-// Register registers audit log routes on the given router group
+// defaultAuditExportMaxRows caps a CSV export when WithExportLimit is not used
+const defaultAuditExportMaxRows = 5000
+
+// WithExportLimit caps how many events a CSV export returns
+func (h *AdminAuditHandler) WithExportLimit(maxRows int) *AdminAuditHandler {
+	h.maxRows = maxRows
+	return h
+}
+
+// Register registers audit log routes on the given router group. /audit is
+// the original path and is kept for existing clients.
 func (h *AdminAuditHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("/audit-events", h.listAuditEvents)
 	rg.GET("/audit", h.listAuditEvents)
 }
 
 // AuditQueryParams defines the query parameters for listing audit events
 type AuditQueryParams struct {
-	Page      int    `form:"page"`
-	PageSize  int    `form:"page_size"`
-	Actor     string `form:"actor"`
-	Action    string `form:"action"`
-	StartDate string `form:"start_date"` // ISO 8601 format
-	EndDate   string `form:"end_date"`   // ISO 8601 format
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+	Actor    string `form:"actor"`
+	Action   string `form:"action"`
+	// TargetType and TargetID narrow the log to one record
+	TargetType string `form:"target_type"`
+	TargetID   int    `form:"target_id"`
+	StartDate  string `form:"start_date"` // ISO 8601 format
+	EndDate    string `form:"end_date"`   // ISO 8601 format
+	// Format csv downloads every matching event instead of one page
+	Format string `form:"format" binding:"omitempty,oneof=json csv"`
 }
 
 // listAuditEvents returns paginated, filterable audit events
 // @Summary List audit events (admin only)
-// @Description Returns paginated list of audit events with optional filters
+// @Description Returns paginated list of audit events with optional filters, or every matching event as CSV with format=csv
 // @Tags Admin
 // @Produce json
+// @Produce text/csv
 // @Param page query int false "Page number (default 1)"
 // @Param page_size query int false "Items per page (default 20, max 100)"
 // @Param actor query string false "Filter by actor email"
 // @Param action query string false "Filter by action type"
+// @Param target_type query string false "Filter by target type, e.g. patient"
+// @Param target_id query int false "Filter by target ID"
 // @Param start_date query string false "Filter from date (ISO 8601)"
 // @Param end_date query string false "Filter to date (ISO 8601)"
+// @Param format query string false "json or csv" default(json)
 // @Success 200 {object} models.PaginatedResponse
 // @Failure 400 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
-// @Router /admin/audit [get]
+// @Router /admin/audit-events [get]
 func (h *AdminAuditHandler) listAuditEvents(c *gin.Context) {
 	var queryParams AuditQueryParams
 	if err := c.ShouldBindQuery(&queryParams); err != nil {
@@ -57,39 +82,14 @@ func (h *AdminAuditHandler) listAuditEvents(c *gin.Context) {
 		return
 	}
 
-	// Build params
-	params := models.AuditListParams{
-		Page:     queryParams.Page,
-		PageSize: queryParams.PageSize,
-		Actor:    queryParams.Actor,
-		Action:   queryParams.Action,
-	}
-
-	// Parse dates if provided
-	if queryParams.StartDate != "" {
-		t, err := time.Parse(time.RFC3339, queryParams.StartDate)
-		if err != nil {
-			// Try date-only format
-			t, err = time.Parse("2006-01-02", queryParams.StartDate)
-		}
-		if err == nil {
-			params.StartDate = t
-		}
+	params, err := auditListParams(queryParams)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-
-	if queryParams.EndDate != "" {
-		t, err := time.Parse(time.RFC3339, queryParams.EndDate)
-		if err != nil {
-			// Try date-only format
-			t, err = time.Parse("2006-01-02", queryParams.EndDate)
-			if err == nil {
-				// End of day
-				t = t.Add(24*time.Hour - time.Second)
-			}
-		}
-		if err == nil {
-			params.EndDate = t
-		}
+	if queryParams.Format == "csv" {
+		h.exportCSV(c, params)
+		return
 	}
 
 	// Set defaults
@@ -116,3 +116,85 @@ func (h *AdminAuditHandler) listAuditEvents(c *gin.Context) {
 		TotalPages: totalPages,
 	})
 }
+
+// auditListParams converts the query into store filters. Dates are RFC 3339
+// or YYYY-MM-DD; a date-only end_date includes that whole day.
+func auditListParams(q AuditQueryParams) (models.AuditListParams, error) {
+	params := models.AuditListParams{
+		Page:       q.Page,
+		PageSize:   q.PageSize,
+		Actor:      q.Actor,
+		Action:     q.Action,
+		TargetType: q.TargetType,
+		TargetID:   q.TargetID,
+	}
+
+	if q.StartDate != "" {
+		t, err := time.Parse(time.RFC3339, q.StartDate)
+		if err != nil {
+			// Try date-only format
+			t, err = time.Parse("2006-01-02", q.StartDate)
+		}
+		if err != nil {
+			return params, errors.New("start_date must be RFC 3339 or YYYY-MM-DD")
+		}
+		params.StartDate = t
+	}
+
+	if q.EndDate != "" {
+		t, err := time.Parse(time.RFC3339, q.EndDate)
+		if err != nil {
+			// Try date-only format
+			t, err = time.Parse("2006-01-02", q.EndDate)
+			if err == nil {
+				// End of day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+		}
+		if err != nil {
+			return params, errors.New("end_date must be RFC 3339 or YYYY-MM-DD")
+		}
+		params.EndDate = t
+	}
+	return params, nil
+}
+
+// exportCSV streams every event matching params, newest first, up to
+// maxRows. X-Total-Count carries the full match count so a client can tell
+// when the export was capped. Pages are read as they are written, so a
+// failure after the first page ends the file early and is only logged.
+func (h *AdminAuditHandler) exportCSV(c *gin.Context, params models.AuditListParams) {
+	ctx := c.Request.Context()
+	params.PageSize = exportAuditPageSize
+	params.Page = 1
+	events, total, err := h.store.AuditEvents().List(ctx, params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch audit events"})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"audit-events-%s.csv\"", time.Now().UTC().Format("20060102")))
+	c.Header("X-Total-Count", fmt.Sprint(total))
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(auditCSV(nil)[0])
+	written := 0
+	for {
+		if len(events) > h.maxRows-written {
+			events = events[:h.maxRows-written]
+		}
+		for _, row := range auditCSV(events)[1:] {
+			_ = w.Write(row)
+		}
+		written += len(events)
+		if len(events) < params.PageSize || written >= total || written >= h.maxRows {
+			break
+		}
+		params.Page++
+		if events, _, err = h.store.AuditEvents().List(ctx, params); err != nil {
+			logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to export audit events page %d", params.Page)
+			break
+		}
+	}
+	w.Flush()
+}
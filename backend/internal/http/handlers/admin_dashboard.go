@@ -2,9 +2,10 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
 	"github.com/skufu/DianaV2/backend/internal/store"
 )
 
@@ -21,6 +22,7 @@ func NewAdminDashboardHandler(store store.Store) *AdminDashboardHandler {
 // Register registers admin dashboard routes on the given router group
 func (h *AdminDashboardHandler) Register(rg *gin.RouterGroup) {
 	rg.GET("/dashboard", h.getDashboard)
+	rg.GET("/stats", h.getStats)
 	rg.GET("/clinics", h.listAllClinics)
 	rg.GET("/clinic-comparison", h.getClinicComparison)
 }
@@ -35,14 +37,7 @@ func (h *AdminDashboardHandler) Register(rg *gin.RouterGroup) {
 // @Failure 500 {object} map[string]string
 // @Router /admin/dashboard [get]
 func (h *AdminDashboardHandler) getDashboard(c *gin.Context) {
-	claims := c.MustGet("user").(middleware.UserClaims)
-
-	if claims.Role != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied - admin role required"})
-		return
-	}
-
-	stats, err := h.store.Clinics().AdminSystemStats(c.Request.Context())
+	stats, err := h.store.Clinics().AdminSystemStats(c.Request.Context(), models.StatsRange{})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load system statistics"})
 		return
@@ -52,7 +47,7 @@ func (h *AdminDashboardHandler) getDashboard(c *gin.Context) {
 	clusterDist, _ := h.store.Assessments().ClusterCounts(c.Request.Context())
 
 	// Get trends
-	trends, _ := h.store.Assessments().TrendAverages(c.Request.Context())
+	trends, _ := h.store.Assessments().TrendAverages(c.Request.Context(), models.TrendParams{})
 
 	c.JSON(http.StatusOK, gin.H{
 		"stats":                stats,
@@ -61,6 +56,68 @@ func (h *AdminDashboardHandler) getDashboard(c *gin.Context) {
 	})
 }
 
+// statsRangeQuery is the optional window of an admin statistics request.
+// Dates are calendar days and both ends are inclusive.
+type statsRangeQuery struct {
+	Start string `form:"start"`
+	End   string `form:"end"`
+}
+
+// bindStatsRange reads the start and end query parameters, writing a 400 and
+// returning false when they are malformed or out of order.
+func bindStatsRange(c *gin.Context) (models.StatsRange, bool) {
+	var q statsRangeQuery
+	var r models.StatsRange
+	_ = c.ShouldBindQuery(&q)
+	if q.Start != "" {
+		start, err := time.Parse("2006-01-02", q.Start)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start must be a date (YYYY-MM-DD)"})
+			return r, false
+		}
+		r.Start = &start
+	}
+	if q.End != "" {
+		end, err := time.Parse("2006-01-02", q.End)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "end must be a date (YYYY-MM-DD)"})
+			return r, false
+		}
+		end = end.AddDate(0, 0, 1)
+		r.End = &end
+	}
+	if r.Start != nil && r.End != nil && !r.Start.Before(*r.End) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start must not be after end"})
+		return r, false
+	}
+	return r, true
+}
+
+// getStats returns system-wide statistics, optionally windowed
+// @Summary Get system statistics (admin only)
+// @Description Returns system-wide totals. Assessment totals, average risk and high-risk count cover only the given window; user, patient and clinic totals and the this-month counts are unaffected.
+// @Tags Admin
+// @Produce json
+// @Param start query string false "First day included (YYYY-MM-DD)"
+// @Param end query string false "Last day included (YYYY-MM-DD)"
+// @Success 200 {object} models.SystemStats
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/stats [get]
+func (h *AdminDashboardHandler) getStats(c *gin.Context) {
+	r, ok := bindStatsRange(c)
+	if !ok {
+		return
+	}
+	stats, err := h.store.Clinics().AdminSystemStats(c.Request.Context(), r)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load system statistics"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
 // listAllClinics returns all clinics in the system
 // @Summary List all clinics (admin only)
 // @Description Returns all clinics in the system
@@ -71,13 +128,6 @@ func (h *AdminDashboardHandler) getDashboard(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /admin/clinics [get]
 func (h *AdminDashboardHandler) listAllClinics(c *gin.Context) {
-	claims := c.MustGet("user").(middleware.UserClaims)
-
-	if claims.Role != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied - admin role required"})
-		return
-	}
-
 	clinics, err := h.store.Clinics().List(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load clinics"})
@@ -89,22 +139,22 @@ func (h *AdminDashboardHandler) listAllClinics(c *gin.Context) {
 
 // getClinicComparison returns per-clinic statistics
 // @Summary Get clinic comparison (admin only)
-// @Description Returns statistics comparing all clinics
+// @Description Returns statistics comparing all clinics; assessment figures cover only the given window
 // @Tags Admin
 // @Produce json
+// @Param start query string false "First day included (YYYY-MM-DD)"
+// @Param end query string false "Last day included (YYYY-MM-DD)"
 // @Success 200 {array} models.ClinicComparison
+// @Failure 400 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /admin/clinic-comparison [get]
 func (h *AdminDashboardHandler) getClinicComparison(c *gin.Context) {
-	claims := c.MustGet("user").(middleware.UserClaims)
-
-	if claims.Role != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied - admin role required"})
+	r, ok := bindStatsRange(c)
+	if !ok {
 		return
 	}
-
-	comparison, err := h.store.Clinics().AdminClinicComparison(c.Request.Context())
+	comparison, err := h.store.Clinics().AdminClinicComparison(c.Request.Context(), r)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load clinic comparison"})
 		return
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// fakeStatsClinicRepo records the range each admin statistics call receives
+type fakeStatsClinicRepo struct {
+	store.ClinicRepository
+	ranges []models.StatsRange
+}
+
+func (f *fakeStatsClinicRepo) AdminSystemStats(ctx context.Context, r models.StatsRange) (*models.SystemStats, error) {
+	f.ranges = append(f.ranges, r)
+	return &models.SystemStats{TotalUsers: 3, TotalAssessments: 7}, nil
+}
+
+func (f *fakeStatsClinicRepo) AdminClinicComparison(ctx context.Context, r models.StatsRange) ([]models.ClinicComparison, error) {
+	f.ranges = append(f.ranges, r)
+	return []models.ClinicComparison{{ClinicID: 1, ClinicName: "North", AssessmentCount: 2}}, nil
+}
+
+func adminDashboardRouter(st store.Store) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	NewAdminDashboardHandler(st).Register(r.Group("/admin"))
+	return r
+}
+
+func TestAdminStats_Range(t *testing.T) {
+	clinics := &fakeStatsClinicRepo{}
+	r := adminDashboardRouter(&fakeStore{clinicRepo: clinics})
+
+	w := contactRequest(r, http.MethodGet, "/admin/stats", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var stats models.SystemStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || stats.TotalUsers != 3 || stats.TotalAssessments != 7 {
+		t.Fatalf("unexpected stats %+v (err %v)", stats, err)
+	}
+	if got := clinics.ranges[0]; got.Start != nil || got.End != nil {
+		t.Fatalf("expected an open range, got %+v", got)
+	}
+
+	w = contactRequest(r, http.MethodGet, "/admin/clinic-comparison?start=2024-01-01&end=2024-03-31", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	got := clinics.ranges[1]
+	if got.Start == nil || !got.Start.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) ||
+		got.End == nil || !got.End.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected an inclusive window, got %v to %v", got.Start, got.End)
+	}
+}
+
+func TestAdminStats_RangeInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		path string
+	}{
+		{"bad start", "/admin/stats?start=01/02/2024"},
+		{"bad end", "/admin/clinic-comparison?end=tomorrow"},
+		{"end before start", "/admin/stats?start=2024-03-01&end=2024-02-01"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clinics := &fakeStatsClinicRepo{}
+			r := adminDashboardRouter(&fakeStore{clinicRepo: clinics})
+			w := contactRequest(r, http.MethodGet, tc.path, "")
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+			}
+			if len(clinics.ranges) != 0 {
+				t.Fatalf("store called with %+v", clinics.ranges)
+			}
+		})
+	}
+}
+
+func TestAdminStats_MemoryStoreWindow(t *testing.T) {
+	mem := store.NewMemoryStore()
+	if err := mem.SeedDemo("hash"); err != nil {
+		t.Fatal(err)
+	}
+	r := adminDashboardRouter(mem)
+
+	var all, none models.SystemStats
+	w := contactRequest(r, http.MethodGet, "/admin/stats", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &all); err != nil || all.TotalAssessments == 0 {
+		t.Fatalf("expected seeded assessments, got %+v (err %v)", all, err)
+	}
+	w = contactRequest(r, http.MethodGet, "/admin/stats?end=2000-01-01", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &none); err != nil {
+		t.Fatal(err)
+	}
+	if none.TotalAssessments != 0 || none.HighRiskCount != 0 || none.AvgRiskScore != 0 {
+		t.Fatalf("expected no assessments before 2000, got %+v", none)
+	}
+	if none.TotalUsers != all.TotalUsers || none.TotalPatients != all.TotalPatients || none.AssessmentsThisMonth != all.AssessmentsThisMonth {
+		t.Fatalf("window must not affect totals outside assessments: %+v vs %+v", none, all)
+	}
+}
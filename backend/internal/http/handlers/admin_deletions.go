@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// AdminDeletionsHandler lets admins review scheduled user purges and cancel
+// pending ones before they run
+type AdminDeletionsHandler struct {
+	store store.Store
+}
+
+func NewAdminDeletionsHandler(store store.Store) *AdminDeletionsHandler {
+	return &AdminDeletionsHandler{store: store}
+}
+
+func (h *AdminDeletionsHandler) Register(rg *gin.RouterGroup) {
+	deletions := rg.Group("/deletions")
+	deletions.GET("", h.list)
+	deletions.POST("/:id/cancel", h.cancel)
+}
+
+type deletionListQuery struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending cancelled completed"`
+}
+
+// list returns scheduled user deletions, newest first
+// @Summary List user deletions (admin only)
+// @Description Deletions scheduled when users were deactivated, with their due date and outcome
+// @Tags Admin
+// @Produce json
+// @Param status query string false "pending, cancelled or completed"
+// @Success 200 {object} map[string][]models.UserDeletion
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/deletions [get]
+func (h *AdminDeletionsHandler) list(c *gin.Context) {
+	var q deletionListQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be 'pending', 'cancelled' or 'completed'"})
+		return
+	}
+	deletions, err := h.store.UserDeletions().List(c.Request.Context(), q.Status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list deletions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": deletions})
+}
+
+// cancel stops a pending deletion. The user stays deactivated; deactivating
+// them again schedules a new deletion.
+// @Summary Cancel a pending user deletion (admin only)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Deletion ID"
+// @Success 200 {object} models.UserDeletion
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/deletions/{id}/cancel [post]
+func (h *AdminDeletionsHandler) cancel(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid deletion ID"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	d, err := h.store.UserDeletions().Cancel(c.Request.Context(), id, claims.Email)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "deletion not found or no longer pending")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel deletion"})
+		return
+	}
+
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "user.deletion_cancelled",
+		TargetType: "user",
+		TargetID:   int(d.UserID),
+		Details:    map[string]interface{}{"deletion_id": d.ID},
+	})
+
+	c.JSON(http.StatusOK, d)
+}
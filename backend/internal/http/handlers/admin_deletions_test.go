@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/storage"
+)
+
+type fakeUserDeletionRepo struct {
+	deletions []models.UserDeletion
+	purge     models.UserPurge
+	purged    []string // modes, in purge order
+}
+
+func (f *fakeUserDeletionRepo) Schedule(ctx context.Context, userID int64, requestedBy string, dueAt time.Time) (*models.UserDeletion, error) {
+	for _, d := range f.deletions {
+		if d.UserID == userID && d.Status == models.DeletionPending {
+			return &d, nil
+		}
+	}
+	d := models.UserDeletion{ID: int64(len(f.deletions) + 1), UserID: userID, RequestedBy: requestedBy, DueAt: dueAt, Status: models.DeletionPending}
+	f.deletions = append(f.deletions, d)
+	return &d, nil
+}
+
+func (f *fakeUserDeletionRepo) List(ctx context.Context, status string) ([]models.UserDeletion, error) {
+	out := []models.UserDeletion{}
+	for _, d := range f.deletions {
+		if status == "" || d.Status == status {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeUserDeletionRepo) resolve(match func(models.UserDeletion) bool, status, by string) *models.UserDeletion {
+	for i, d := range f.deletions {
+		if match(d) && d.Status == models.DeletionPending {
+			f.deletions[i].Status, f.deletions[i].ResolvedBy = status, by
+			return &f.deletions[i]
+		}
+	}
+	return nil
+}
+
+func (f *fakeUserDeletionRepo) Cancel(ctx context.Context, id int64, by string) (*models.UserDeletion, error) {
+	if d := f.resolve(func(d models.UserDeletion) bool { return d.ID == id }, models.DeletionCancelled, by); d != nil {
+		return d, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (f *fakeUserDeletionRepo) CancelForUser(ctx context.Context, userID int64, by string) (*models.UserDeletion, error) {
+	return f.resolve(func(d models.UserDeletion) bool { return d.UserID == userID }, models.DeletionCancelled, by), nil
+}
+
+func (f *fakeUserDeletionRepo) Due(ctx context.Context, now time.Time) ([]models.UserDeletion, error) {
+	out := []models.UserDeletion{}
+	for _, d := range f.deletions {
+		if d.Status == models.DeletionPending && !d.DueAt.After(now) {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeUserDeletionRepo) Purge(ctx context.Context, d models.UserDeletion, mode, by string) (*models.UserPurge, error) {
+	if f.resolve(func(p models.UserDeletion) bool { return p.ID == d.ID }, models.DeletionCompleted, by) == nil {
+		return nil, nil
+	}
+	f.purged = append(f.purged, mode)
+	purge := f.purge
+	return &purge, nil
+}
+
+func auditActions(events []models.AuditEvent) []string {
+	var out []string
+	for _, e := range events {
+		out = append(out, e.Action)
+	}
+	return out
+}
+
+func TestUserRetention_SchedulesAndPurges(t *testing.T) {
+	ctx := context.Background()
+	blobs := storage.NewLocalStorage(t.TempDir())
+	for _, key := range []string{"photos/7.jpg", "assessment-attachments/7/3/lab"} {
+		if err := blobs.Put(ctx, key, bytes.NewReader([]byte("file"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deletions := &fakeUserDeletionRepo{purge: models.UserPurge{
+		Patients:       2,
+		PhotoKeys:      []string{"photos/7.jpg", "photos/missing.jpg"},
+		AttachmentKeys: []string{"assessment-attachments/7/3/lab"},
+	}}
+	audit := &fakeAuditRepo{}
+	st := &fakeStore{deletions: deletions, audit: audit}
+
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	retention := NewUserRetention(st, blobs, 30*24*time.Hour, models.RetentionAnonymize)
+	retention.now = func() time.Time { return now }
+	bus := events.NewBus()
+	retention.Subscribe(bus)
+
+	bus.Publish(ctx, events.UserDeactivated{Actor: "admin@example.com", UserID: 5})
+	bus.Publish(ctx, events.UserDeactivated{Actor: "admin@example.com", UserID: 6})
+	if len(deletions.deletions) != 2 || !deletions.deletions[0].DueAt.Equal(now.AddDate(0, 0, 30)) {
+		t.Fatalf("expected purges scheduled 30 days out, got %+v", deletions.deletions)
+	}
+
+	// Reactivating user 6 cancels theirs
+	bus.Publish(ctx, events.UserActivated{Actor: "admin@example.com", UserID: 6})
+	if d := deletions.deletions[1]; d.Status != models.DeletionCancelled || d.ResolvedBy != "admin@example.com" {
+		t.Fatalf("expected user 6's deletion cancelled, got %+v", d)
+	}
+
+	if err := retention.Run(ctx); err != nil || len(deletions.purged) != 0 {
+		t.Fatalf("nothing is due within the grace period, purged %v (%v)", deletions.purged, err)
+	}
+
+	now = now.AddDate(0, 0, 31)
+	if err := retention.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(deletions.purged) != 1 || deletions.purged[0] != models.RetentionAnonymize {
+		t.Fatalf("expected one anonymizing purge, got %v", deletions.purged)
+	}
+	if d := deletions.deletions[0]; d.Status != models.DeletionCompleted || d.ResolvedBy != retentionActor {
+		t.Fatalf("expected user 5's deletion completed by the scheduler, got %+v", d)
+	}
+	if _, err := blobs.Get(ctx, "photos/7.jpg"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected the purged user's photo object removed, got %v", err)
+	}
+	if _, err := blobs.Get(ctx, "assessment-attachments/7/3/lab"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected the purged user's attachment object removed, got %v", err)
+	}
+
+	want := []string{"user.deletion_scheduled", "user.deletion_scheduled", "user.deletion_cancelled", "user.purge"}
+	got := auditActions(audit.events)
+	if len(got) != len(want) {
+		t.Fatalf("audit actions = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("audit actions = %v, want %v", got, want)
+		}
+	}
+	if purge := audit.events[3]; purge.Actor != retentionActor || purge.TargetID != 5 || purge.Details["patients"] != 2 {
+		t.Fatalf("unexpected purge audit: %+v", purge)
+	}
+
+	// A second run finds nothing left to do
+	if err := retention.Run(ctx); err != nil || len(deletions.purged) != 1 {
+		t.Fatalf("expected no further purges, got %v (%v)", deletions.purged, err)
+	}
+}
+
+func TestAdminDeletions_ListAndCancel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	due := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	deletions := &fakeUserDeletionRepo{deletions: []models.UserDeletion{
+		{ID: 1, UserID: 5, Email: "old@example.com", RequestedBy: "admin@example.com", DueAt: due, Status: models.DeletionCompleted},
+		{ID: 2, UserID: 6, Email: "doc@example.com", RequestedBy: "admin@example.com", DueAt: due, Status: models.DeletionPending},
+	}}
+	audit := &fakeAuditRepo{}
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(mockAuthMiddleware())
+	NewAdminDeletionsHandler(&fakeStore{deletions: deletions, audit: audit}).Register(r.Group("/admin"))
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodGet, "/admin/deletions?status=pending")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data []models.UserDeletion `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].ID != 2 || resp.Data[0].Email != "doc@example.com" {
+		t.Fatalf("expected only the pending deletion, got %s", w.Body.String())
+	}
+	if w := request(http.MethodGet, "/admin/deletions?status=overdue"); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown status: expected 400, got %d", w.Code)
+	}
+
+	if w := request(http.MethodPost, "/admin/deletions/1/cancel"); w.Code != http.StatusNotFound {
+		t.Fatalf("completed deletion: expected 404, got %d", w.Code)
+	}
+	w = request(http.MethodPost, "/admin/deletions/2/cancel")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if d := deletions.deletions[1]; d.Status != models.DeletionCancelled || d.ResolvedBy != "test@example.com" {
+		t.Fatalf("expected the deletion cancelled by the admin, got %+v", d)
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != "user.deletion_cancelled" || audit.events[0].TargetID != 6 {
+		t.Fatalf("expected a cancellation audit event on user 6, got %+v", audit.events)
+	}
+	if w := request(http.MethodPost, "/admin/deletions/2/cancel"); w.Code != http.StatusNotFound {
+		t.Fatalf("second cancel: expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// AdminImpersonationHandler lets support staff act as a user to reproduce
+// an issue they reported. Requests made with the token are audited by
+// middleware.Impersonation.
+type AdminImpersonationHandler struct {
+	store     store.Store
+	perms     middleware.PermissionLookup
+	jwtSecret string
+	ttl       time.Duration
+}
+
+// NewAdminImpersonationHandler creates a new AdminImpersonationHandler
+// issuing tokens signed with jwtSecret and valid for ttl
+func NewAdminImpersonationHandler(store store.Store, jwtSecret string, ttl time.Duration) *AdminImpersonationHandler {
+	return &AdminImpersonationHandler{store: store, perms: PermissionLookup(store), jwtSecret: jwtSecret, ttl: ttl}
+}
+
+// Register registers the impersonation route on the admin group
+func (h *AdminImpersonationHandler) Register(rg *gin.RouterGroup) {
+	rg.POST("/users/:id/impersonate", middleware.RequireSudo(), h.impersonate)
+}
+
+type impersonateRequest struct {
+	// Reason is recorded in the audit trail, e.g. the support ticket
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// impersonate issues a short-lived access token acting as the user
+// @Summary Impersonate a user (admin only)
+// @Description Issues an access token acting as an active user whose role has no admin permissions, valid for IMPERSONATION_TTL_MINUTES and without a refresh token. Every request made with it is audited under the admin and answered with an X-Impersonated-By header. Needs sudo.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/users/{id}/impersonate [post]
+func (h *AdminImpersonationHandler) impersonate(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	var req impersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+	claims := c.MustGet("user").(middleware.UserClaims)
+	if claims.ImpersonatedBy != "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not available while impersonating"})
+		return
+	}
+	if id == claims.UserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot impersonate yourself"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.store.Users().FindByID(ctx, int32(id))
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "user not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
+		return
+	}
+	// Roles are checked by permission, not name: any role granted admin
+	// permissions is refused, as its token would carry them
+	target := middleware.UserClaims{UserID: user.ID, Email: user.Email, Role: user.Role}
+	for _, perm := range []string{models.PermAdminUsers, models.PermAdminSystem} {
+		granted, err := h.perms(ctx, target, 0, perm)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check permissions"})
+			return
+		}
+		if granted {
+			c.JSON(http.StatusForbidden, gin.H{"error": "users with admin permissions cannot be impersonated"})
+			return
+		}
+	}
+	if !user.IsActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "user is deactivated"})
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(h.ttl)
+	signed, err := middleware.SignJWT(h.jwtSecret, jwt.MapClaims{
+		"sub":             user.Email,
+		"user_id":         user.ID,
+		"role":            user.Role,
+		"exp":             expiresAt.Unix(),
+		"iat":             now.Unix(),
+		"scope":           "diana",
+		"impersonated_by": claims.Email,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "user.impersonate",
+		TargetType: "user",
+		TargetID:   int(user.ID),
+		Details: map[string]interface{}{
+			"reason":     req.Reason,
+			"expires_at": expiresAt.UTC().Format(time.RFC3339),
+		},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":    signed,
+		"token_type":      "Bearer",
+		"expires_in":      int(h.ttl.Seconds()),
+		"impersonated_by": claims.Email,
+		"user": gin.H{
+			"id":    user.ID,
+			"email": user.Email,
+			"role":  user.Role,
+		},
+	})
+}
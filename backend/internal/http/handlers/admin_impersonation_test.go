@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+func TestAdminImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	mem := store.NewMemoryStore()
+	users := mem.Users()
+	admin, _ := users.Create(ctx, models.User{Email: "admin@example.com", Role: "admin"})
+	clinician, _ := users.Create(ctx, models.User{Email: "clinician@example.com", Role: "clinician"})
+	other, _ := users.Create(ctx, models.User{Email: "other-admin@example.com", Role: "admin"})
+	gone, _ := users.Create(ctx, models.User{Email: "gone@example.com", Role: "clinician"})
+	_ = users.Deactivate(ctx, int32(gone.ID))
+	audit := &fakeAuditRepo{}
+	secret := "test-secret"
+
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	group := r.Group("/admin")
+	group.Use(sudoAuthMiddleware())
+	NewAdminImpersonationHandler(&fakeStore{users: users, audit: audit, roles: mem.Roles()}, secret, 10*time.Minute).Register(group)
+
+	path := func(id int64) string { return "/admin/users/" + strconv.FormatInt(id, 10) + "/impersonate" }
+	for _, tc := range []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"no reason", path(clinician.ID), `{}`, http.StatusBadRequest},
+		{"yourself", path(admin.ID), `{"reason":"ticket 1"}`, http.StatusBadRequest},
+		{"admin", path(other.ID), `{"reason":"ticket 1"}`, http.StatusForbidden},
+		{"deactivated", path(gone.ID), `{"reason":"ticket 1"}`, http.StatusConflict},
+		{"unknown", path(99), `{"reason":"ticket 1"}`, http.StatusNotFound},
+	} {
+		if w := postJSON(r, tc.path, tc.body); w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+	if len(audit.events) != 0 {
+		t.Fatalf("refused requests must not be audited: %+v", audit.events)
+	}
+
+	w := postJSON(r, path(clinician.ID), `{"reason":"ticket 42"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		AccessToken    string `json:"access_token"`
+		ExpiresIn      int    `json:"expires_in"`
+		ImpersonatedBy string `json:"impersonated_by"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.ExpiresIn != 600 || resp.ImpersonatedBy != "admin@example.com" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != "user.impersonate" || audit.events[0].TargetID != int(clinician.ID) ||
+		audit.events[0].Details["reason"] != "ticket 42" {
+		t.Fatalf("expected a user.impersonate event, got %+v", audit.events)
+	}
+
+	// The token authenticates as the clinician, naming the admin
+	check := gin.New()
+	check.Use(middleware.Auth(secret))
+	check.GET("/me", func(c *gin.Context) { c.JSON(http.StatusOK, c.MustGet("user")) })
+	req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+resp.AccessToken)
+	rec := httptest.NewRecorder()
+	check.ServeHTTP(rec, req)
+	var claims middleware.UserClaims
+	_ = json.Unmarshal(rec.Body.Bytes(), &claims)
+	if rec.Code != http.StatusOK || claims.UserID != clinician.ID || claims.Role != "clinician" || claims.ImpersonatedBy != "admin@example.com" {
+		t.Fatalf("unexpected claims %+v (status %d)", claims, rec.Code)
+	}
+
+	// Once the clinician role is granted admin.users, the token already
+	// issued is refused on admin routes and no new one is issued
+	grants := append(slices.Clone(models.DefaultRolePermissions["clinician"]), models.PermAdminUsers)
+	if err := mem.Roles().SetPermissions(ctx, "clinician", grants, admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	adminRoutes := gin.New()
+	adminRoutes.Use(middleware.Auth(secret), middleware.PermissionRequired(PermissionLookup(mem), models.PermAdminUsers))
+	adminRoutes.POST("/admin/users", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	req, _ = http.NewRequest(http.MethodPost, "/admin/users", nil)
+	req.Header.Set("Authorization", "Bearer "+resp.AccessToken)
+	rec = httptest.NewRecorder()
+	adminRoutes.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("admin route with an impersonation token: expected 403, got %d", rec.Code)
+	}
+	if w := postJSON(r, path(clinician.ID), `{"reason":"ticket 43"}`); w.Code != http.StatusForbidden {
+		t.Errorf("impersonating a role with admin permissions: expected 403, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAdminImpersonation_RequiresSudo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	admin := r.Group("/admin")
+	admin.Use(mockAuthMiddleware())
+	NewAdminImpersonationHandler(&fakeStore{}, "test-secret", time.Minute).Register(admin)
+
+	if w := postJSON(r, "/admin/users/2/impersonate", `{"reason":"ticket 1"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without sudo, got %d", w.Code)
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// AdminMFAHandler lets admins require two-factor authentication per role
+// and reset it for users who lost their authenticator
+type AdminMFAHandler struct {
+	store store.Store
+}
+
+// NewAdminMFAHandler creates a new AdminMFAHandler
+func NewAdminMFAHandler(store store.Store) *AdminMFAHandler {
+	return &AdminMFAHandler{store: store}
+}
+
+// Register registers the MFA policy and reset routes on the admin group
+func (h *AdminMFAHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("/mfa-policy", h.getPolicy)
+	rg.PUT("/mfa-policy", middleware.RequireSudo(), h.putPolicy)
+	rg.DELETE("/users/:id/mfa", middleware.RequireSudo(), h.reset)
+}
+
+type mfaPolicyRequest struct {
+	RequiredRoles []string `json:"required_roles" binding:"dive,oneof=clinician admin"`
+}
+
+func (h *AdminMFAHandler) getPolicy(c *gin.Context) {
+	roles, err := h.store.MFA().RequiredRoles(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load MFA policy"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"required_roles": roles})
+}
+
+// putPolicy replaces the roles that must use two-factor authentication.
+// Users of a newly required role must enroll at their next login or token
+// refresh.
+func (h *AdminMFAHandler) putPolicy(c *gin.Context) {
+	var req mfaPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RequiredRoles == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "required_roles must list clinician and/or admin"})
+		return
+	}
+	claims := c.MustGet("user").(middleware.UserClaims)
+	ctx := c.Request.Context()
+	before, err := h.store.MFA().RequiredRoles(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load MFA policy"})
+		return
+	}
+	if err := h.store.MFA().SetRequiredRoles(ctx, req.RequiredRoles, claims.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update MFA policy"})
+		return
+	}
+	roles, err := h.store.MFA().RequiredRoles(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load MFA policy"})
+		return
+	}
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "mfa.policy_update",
+		TargetType: "mfa_policy",
+		Details: map[string]interface{}{
+			"before": before,
+			"after":  roles,
+		},
+	})
+	c.JSON(http.StatusOK, gin.H{"required_roles": roles})
+}
+
+// reset removes a user's enrollment and backup codes. If their role
+// requires two-factor authentication they enroll again at the next login.
+func (h *AdminMFAHandler) reset(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	err = h.store.MFA().Disable(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "user has no two-factor authentication")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset two-factor authentication"})
+		return
+	}
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "mfa.reset",
+		TargetType: "user",
+		TargetID:   int(id),
+	})
+	c.Status(http.StatusNoContent)
+}
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/ml"
 	"github.com/skufu/DianaV2/backend/internal/models"
 	"github.com/skufu/DianaV2/backend/internal/store"
 )
@@ -26,6 +31,13 @@ func (h *AdminModelsHandler) Register(rg *gin.RouterGroup) {
 		models.GET("", h.listModelRuns)
 		models.GET("/active", h.getActiveModel)
 	}
+
+	runs := rg.Group("/model-runs")
+	{
+		runs.POST("", h.createModelRun)
+		runs.POST("/:id/activate", h.activateModelRun)
+		runs.GET("/:id/drift", h.modelRunDrift)
+	}
 }
 
 // listModelRuns returns paginated list of model training runs
@@ -77,7 +89,7 @@ func (h *AdminModelsHandler) listModelRuns(c *gin.Context) {
 
 // getActiveModel returns the currently active ML model
 // @Summary Get active model (admin only)
-// @Description Returns the currently active/latest ML model version
+// @Description Returns the ML model run activated via POST /admin/model-runs/{id}/activate
 // @Tags Admin
 // @Produce json
 // @Success 200 {object} models.ModelRun
@@ -88,9 +100,201 @@ func (h *AdminModelsHandler) listModelRuns(c *gin.Context) {
 func (h *AdminModelsHandler) getActiveModel(c *gin.Context) {
 	run, err := h.store.ModelRuns().GetActive(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "no model runs found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "no active model run"})
 		return
 	}
 
 	c.JSON(http.StatusOK, run)
 }
+
+// activateModelRun makes a model run the active one used for new assessments
+// @Summary Activate model run (admin only)
+// @Description Marks the given run as the single active model; new assessments are stamped with its version
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Model run ID"
+// @Success 200 {object} models.ModelRun
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/model-runs/{id}/activate [post]
+func (h *AdminModelsHandler) activateModelRun(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid model run ID"})
+		return
+	}
+
+	if err := h.store.ModelRuns().SetActive(c.Request.Context(), int32(id)); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			abortWithError(c, err, "model run not found")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to activate model run"})
+		return
+	}
+
+	run, err := h.store.ModelRuns().GetActive(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load active model run"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "model.activate",
+		TargetType: "model_run",
+		TargetID:   int(id),
+		Details: map[string]interface{}{
+			"model_version": run.ModelVersion,
+		},
+	})
+
+	c.JSON(http.StatusOK, run)
+}
+
+// createModelRunRequest is what the training pipeline reports for a run
+type createModelRunRequest struct {
+	ModelVersion         string                         `json:"model_version" binding:"required"`
+	DatasetHash          string                         `json:"dataset_hash"`
+	Notes                string                         `json:"notes"`
+	Accuracy             *float64                       `json:"accuracy" binding:"omitempty,min=0,max=1"`
+	AUC                  *float64                       `json:"auc" binding:"omitempty,min=0,max=1"`
+	CalibrationError     *float64                       `json:"calibration_error" binding:"omitempty,min=0,max=1"`
+	TrainingDistribution *models.PredictionDistribution `json:"training_distribution"`
+}
+
+// createModelRun records a training run and its evaluation metrics
+// @Summary Report model run (admin only)
+// @Description Called by the training pipeline after training. The run is inactive until activated. training_distribution holds the share (0-1) of each cluster in the training dataset and is the baseline for drift reports.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body createModelRunRequest true "Model run"
+// @Success 201 {object} models.ModelRun
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/model-runs [post]
+func (h *AdminModelsHandler) createModelRun(c *gin.Context) {
+	var req createModelRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if d := req.TrainingDistribution; d != nil {
+		if msg := checkDistribution(*d); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	run, err := h.store.ModelRuns().Create(c.Request.Context(), models.ModelRun{
+		ModelVersion:         req.ModelVersion,
+		DatasetHash:          req.DatasetHash,
+		Notes:                req.Notes,
+		Accuracy:             req.Accuracy,
+		AUC:                  req.AUC,
+		CalibrationError:     req.CalibrationError,
+		TrainingDistribution: req.TrainingDistribution,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create model run"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "model.create",
+		TargetType: "model_run",
+		TargetID:   int(run.ID),
+		Details: map[string]interface{}{
+			"model_version": run.ModelVersion,
+			"dataset_hash":  run.DatasetHash,
+		},
+	})
+
+	c.JSON(http.StatusCreated, run)
+}
+
+// checkDistribution returns why a reported training distribution is
+// unusable, or "" if it is fine
+func checkDistribution(d models.PredictionDistribution) string {
+	if len(d.Clusters) == 0 {
+		return "training_distribution.clusters is required"
+	}
+	total := 0.0
+	for cluster, share := range d.Clusters {
+		if share < 0 || share > 1 {
+			return "training_distribution share for " + cluster + " must be between 0 and 1"
+		}
+		total += share
+	}
+	if math.Abs(total-1) > 0.01 {
+		return "training_distribution shares must add up to 1"
+	}
+	return ""
+}
+
+// modelRunDrift compares a run's recent predictions with its training data
+// @Summary Model run drift (admin only)
+// @Description Compares the cluster distribution of assessments scored by the run in the last days days with the distribution of its training dataset. psi is the population stability index; status is stable below 0.1, moderate below 0.25 and significant above.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Model run ID"
+// @Param days query int false "Window in days (default 30, max 365)"
+// @Success 200 {object} models.ModelDriftReport
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/model-runs/{id}/drift [get]
+func (h *AdminModelsHandler) modelRunDrift(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid model run ID"})
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+		return
+	}
+
+	run, err := h.store.ModelRuns().Get(c.Request.Context(), int32(id))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			abortWithError(c, err, "model run not found")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load model run"})
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	recent, err := h.store.ModelRuns().Distribution(c.Request.Context(), run.ModelVersion, run.DatasetHash, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute prediction distribution"})
+		return
+	}
+
+	report := models.ModelDriftReport{
+		ModelRunID:   run.ID,
+		ModelVersion: run.ModelVersion,
+		DatasetHash:  run.DatasetHash,
+		Since:        since,
+		Recent:       recent,
+		Training:     run.TrainingDistribution,
+	}
+	if run.TrainingDistribution != nil && recent.Count > 0 {
+		psi := ml.PopulationStability(recent, *run.TrainingDistribution)
+		delta := recent.MeanRiskScore - run.TrainingDistribution.MeanRiskScore
+		report.PSI = &psi
+		report.MeanRiskDelta = &delta
+		report.Status = ml.DriftLevel(psi)
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func modelsRouter(st *fakeStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(mockAuthMiddleware())
+	NewAdminModelsHandler(st).Register(r.Group("/admin"))
+	return r
+}
+
+func TestAdminModels_CreateModelRun(t *testing.T) {
+	runs := &fakeModelRunRepo{}
+	audit := &fakeAuditRepo{}
+	r := modelsRouter(&fakeStore{modelRuns: runs, audit: audit})
+
+	cases := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"metrics", `{"model_version":"v2","dataset_hash":"abc","accuracy":0.91,"auc":0.95,"calibration_error":0.04,
+			"training_distribution":{"count":1000,"clusters":{"MOD":0.4,"SIRD":0.6},"mean_risk_score":52}}`, http.StatusCreated},
+		{"no version", `{"accuracy":0.9}`, http.StatusBadRequest},
+		{"metric out of range", `{"model_version":"v3","auc":1.2}`, http.StatusBadRequest},
+		{"shares not adding up", `{"model_version":"v3","training_distribution":{"clusters":{"MOD":0.4}}}`, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req, _ := http.NewRequest(http.MethodPost, "/admin/model-runs", bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	if len(runs.runs) != 1 {
+		t.Fatalf("expected one stored run, got %d", len(runs.runs))
+	}
+	run := runs.runs[0]
+	if run.AUC == nil || *run.AUC != 0.95 || run.TrainingDistribution == nil || run.TrainingDistribution.Clusters["SIRD"] != 0.6 {
+		t.Fatalf("metrics not stored: %+v", run)
+	}
+	if run.IsActive {
+		t.Error("expected a reported run to start inactive")
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != "model.create" {
+		t.Fatalf("expected a model.create audit event, got %+v", audit.events)
+	}
+}
+
+func TestAdminModels_Drift(t *testing.T) {
+	training := &models.PredictionDistribution{Count: 1000, Clusters: map[string]float64{"MOD": 0.5, "SIRD": 0.5}, MeanRiskScore: 50}
+	runs := &fakeModelRunRepo{
+		runs: []models.ModelRun{
+			{ID: 1, ModelVersion: "v2", TrainingDistribution: training},
+			{ID: 2, ModelVersion: "v1"},
+		},
+		recent: models.PredictionDistribution{Count: 40, Clusters: map[string]float64{"MOD": 0.7, "SIRD": 0.3}, MeanRiskScore: 44},
+	}
+	r := modelsRouter(&fakeStore{modelRuns: runs})
+
+	get := func(path string) (int, models.ModelDriftReport) {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var report models.ModelDriftReport
+		_ = json.Unmarshal(w.Body.Bytes(), &report)
+		return w.Code, report
+	}
+
+	code, report := get("/admin/model-runs/1/drift?days=7")
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if report.PSI == nil || report.Status != models.DriftModerate || report.MeanRiskDelta == nil || *report.MeanRiskDelta != -6 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	// Without a training distribution there is nothing to compare against
+	code, report = get("/admin/model-runs/2/drift")
+	if code != http.StatusOK || report.PSI != nil || report.Status != "" || report.Recent.Count != 40 {
+		t.Fatalf("expected only the recent distribution, got %d %+v", code, report)
+	}
+
+	for path, want := range map[string]int{
+		"/admin/model-runs/9/drift":         http.StatusNotFound,
+		"/admin/model-runs/1/drift?days=0":  http.StatusBadRequest,
+		"/admin/model-runs/x/drift":         http.StatusBadRequest,
+		"/admin/model-runs/1/drift?days=ab": http.StatusBadRequest,
+	} {
+		if code, _ := get(path); code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, code)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+)
+
+// AdminPredictionCacheHandler reports prediction cache hits and misses
+type AdminPredictionCacheHandler struct {
+	cache *ml.CachingPredictor
+}
+
+// NewAdminPredictionCacheHandler creates a new AdminPredictionCacheHandler;
+// cache is nil when caching is disabled
+func NewAdminPredictionCacheHandler(cache *ml.CachingPredictor) *AdminPredictionCacheHandler {
+	return &AdminPredictionCacheHandler{cache: cache}
+}
+
+// Register registers the cache report route on the given router group
+func (h *AdminPredictionCacheHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("/prediction-cache", h.report)
+}
+
+// report returns the prediction cache's size and hit counts
+// @Summary Prediction cache metrics (admin only)
+// @Description Returns the in-memory cache size and capacity and how many lookups hit memory, hit the database or missed since startup.
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Router /admin/prediction-cache [get]
+func (h *AdminPredictionCacheHandler) report(c *gin.Context) {
+	if h.cache == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "stats": h.cache.Stats()})
+}
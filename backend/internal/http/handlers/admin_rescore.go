@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+	"github.com/skufu/DianaV2/backend/internal/worker"
+)
+
+const (
+	rescoreBatchSize = 500
+	rescoreKeepJobs  = 20
+	// rescoreMaxFailures consecutive failed predictions stop a job, as the
+	// model service is most likely down or cannot serve the run's version
+	rescoreMaxFailures = 20
+)
+
+// AdminRescoreHandler re-runs predictions for stored assessments with a
+// model run, typically one just activated, and stores the results next to
+// the scores the assessments had so the two models can be compared. The
+// assessments themselves are not changed. Jobs run in the background, one
+// at a time, and are kept in memory only.
+type AdminRescoreHandler struct {
+	store     store.Store
+	predictor ml.Predictor
+	workers   *worker.Manager
+
+	mu     sync.Mutex
+	nextID int64
+	jobs   map[int64]*models.RescoreJob
+	order  []int64
+	// done is closed when the matching job finishes (used by tests)
+	done map[int64]chan struct{}
+}
+
+// NewAdminRescoreHandler creates a new AdminRescoreHandler. predictor must
+// implement ml.PinnedPredictor for jobs to start.
+func NewAdminRescoreHandler(store store.Store, predictor ml.Predictor) *AdminRescoreHandler {
+	return &AdminRescoreHandler{
+		store:     store,
+		predictor: predictor,
+		jobs:      map[int64]*models.RescoreJob{},
+		done:      map[int64]chan struct{}{},
+	}
+}
+
+// WithWorkers runs jobs under m so server shutdown stops them between
+// assessments and waits for them
+func (h *AdminRescoreHandler) WithWorkers(m *worker.Manager) *AdminRescoreHandler {
+	h.workers = m
+	return h
+}
+
+// Register registers rescore routes on the given router group
+func (h *AdminRescoreHandler) Register(rg *gin.RouterGroup) {
+	rg.POST("/model-runs/:id/rescore", h.start)
+	rg.GET("/model-runs/:id/rescore/:jobID", h.get)
+	rg.GET("/model-runs/:id/predictions", h.listPredictions)
+}
+
+// start launches a rescore job
+// @Summary Re-score assessments with a model run (admin only)
+// @Description Re-runs predictions for stored assessments with the given model run in a background job, storing each result beside the assessment's current score. Assessments are not changed.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Model run ID"
+// @Param since query string false "Only assessments created since (RFC3339 timestamp or YYYY-MM-DD)"
+// @Param cluster query string false "Only assessments currently in this cluster"
+// @Success 202 {object} models.RescoreJob
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Router /admin/model-runs/{id}/rescore [post]
+func (h *AdminRescoreHandler) start(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid model run ID"})
+		return
+	}
+	var since *time.Time
+	if v := c.Query("since"); v != "" {
+		t, ok := parseSince(v)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC3339 timestamp or YYYY-MM-DD date"})
+			return
+		}
+		since = &t
+	}
+	cluster := c.Query("cluster")
+	if _, ok := h.predictor.(ml.PinnedPredictor); !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "the configured model cannot score with a pinned version"})
+		return
+	}
+
+	run, err := h.store.ModelRuns().Get(c.Request.Context(), int32(id))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			abortWithError(c, err, "model run not found")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load model run"})
+		return
+	}
+	claims := c.MustGet("user").(middleware.UserClaims)
+
+	h.mu.Lock()
+	for _, j := range h.jobs {
+		if j.Status == models.RescoreRunning {
+			h.mu.Unlock()
+			c.JSON(http.StatusConflict, gin.H{"error": "a rescore job is already running", "job_id": j.ID})
+			return
+		}
+	}
+	h.nextID++
+	job := &models.RescoreJob{
+		ID:           h.nextID,
+		ModelRunID:   run.ID,
+		ModelVersion: run.ModelVersion,
+		Status:       models.RescoreRunning,
+		Since:        since,
+		Cluster:      cluster,
+		StartedBy:    claims.Email,
+		StartedAt:    time.Now(),
+	}
+	h.jobs[job.ID] = job
+	h.order = append(h.order, job.ID)
+	h.done[job.ID] = make(chan struct{})
+	h.pruneLocked()
+	snapshot := *job
+	h.mu.Unlock()
+
+	details := map[string]interface{}{
+		"job_id":        job.ID,
+		"model_version": run.ModelVersion,
+	}
+	if since != nil {
+		details["since"] = since.Format(time.RFC3339)
+	}
+	if cluster != "" {
+		details["cluster"] = cluster
+	}
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "model.rescore.start",
+		TargetType: "model_run",
+		TargetID:   int(id),
+		Details:    details,
+	})
+
+	// The job outlives the request and is cancelled by shutdown instead.
+	h.workers.Go("rescore", func(ctx context.Context) {
+		h.run(ctx, job.ID, *run)
+	})
+
+	c.Header("Location", fmt.Sprintf("/api/v1/admin/model-runs/%d/rescore/%d", id, job.ID))
+	c.JSON(http.StatusAccepted, snapshot)
+}
+
+// get returns a rescore job and its summary so far
+// @Summary Get rescore job (admin only)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Model run ID"
+// @Param jobID path int true "Job ID"
+// @Success 200 {object} models.RescoreJob
+// @Failure 404 {object} map[string]string
+// @Router /admin/model-runs/{id}/rescore/{jobID} [get]
+func (h *AdminRescoreHandler) get(c *gin.Context) {
+	runID, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid model run ID"})
+		return
+	}
+	id, err := parseIDParam(c, "jobID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job id"})
+		return
+	}
+	h.mu.Lock()
+	job, ok := h.jobs[id]
+	var snapshot models.RescoreJob
+	if ok {
+		snapshot = *job
+	}
+	h.mu.Unlock()
+	if !ok || snapshot.ModelRunID != runID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// listPredictions returns a model run's re-scores beside the scores the
+// assessments had
+// @Summary List model run re-scores (admin only)
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Model run ID"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Items per page (default 50, max 500)"
+// @Success 200 {object} models.PaginatedResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/model-runs/{id}/predictions [get]
+func (h *AdminRescoreHandler) listPredictions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid model run ID"})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 50
+	}
+	if pageSize > 500 {
+		pageSize = 500
+	}
+
+	predictions, total, err := h.store.ModelRuns().ListPredictions(c.Request.Context(), int32(id), pageSize, (page-1)*pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch predictions"})
+		return
+	}
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Data:       predictions,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: (total + pageSize - 1) / pageSize,
+	})
+}
+
+// run pages through the assessments, scores each one with the run's model
+// version and stores the result. Assessments still awaiting their first
+// prediction are skipped.
+func (h *AdminRescoreHandler) run(ctx context.Context, jobID int64, modelRun models.ModelRun) {
+	h.mu.Lock()
+	job := h.jobs[jobID]
+	var since time.Time
+	if job.Since != nil {
+		since = *job.Since
+	}
+	cluster, actor := job.Cluster, job.StartedBy
+	done := h.done[jobID]
+	h.mu.Unlock()
+	defer close(done)
+
+	pinned := h.predictor.(ml.PinnedPredictor)
+	var afterID int64
+	var riskDelta, failedInRow int
+	var runErr error
+	for runErr == nil {
+		if err := ctx.Err(); err != nil {
+			runErr = err
+			break
+		}
+		batch, err := h.store.Assessments().ListSince(ctx, since, afterID, rescoreBatchSize)
+		if err != nil {
+			runErr = err
+			break
+		}
+		for _, a := range batch {
+			afterID = a.ID
+			if a.Cluster == models.ClusterPendingPrediction || (cluster != "" && a.Cluster != cluster) {
+				continue
+			}
+			a.OnMedication = onMedication(ctx, h.store, a.PatientID, a.CreatedAt)
+			next, risk, ok := pinned.PredictWithModel(ctx, a, modelRun.ModelVersion, modelRun.DatasetHash)
+			if ok {
+				err := h.store.ModelRuns().SavePrediction(ctx, models.AssessmentPrediction{
+					AssessmentID:         a.ID,
+					ModelRunID:           modelRun.ID,
+					PreviousCluster:      a.Cluster,
+					PreviousRiskScore:    a.RiskScore,
+					PreviousModelVersion: a.ModelVersion,
+					Cluster:              next,
+					RiskScore:            risk,
+				})
+				if err != nil {
+					runErr = err
+					break
+				}
+				failedInRow = 0
+			} else {
+				failedInRow++
+			}
+
+			h.mu.Lock()
+			s := &job.Summary
+			s.Scanned++
+			if ok {
+				s.Rescored++
+				if next != a.Cluster {
+					s.ClusterChanged++
+				}
+				riskDelta += risk - a.RiskScore
+				s.MeanRiskDelta = float64(riskDelta) / float64(s.Rescored)
+			} else {
+				s.Failed++
+			}
+			h.mu.Unlock()
+
+			if failedInRow == rescoreMaxFailures {
+				runErr = fmt.Errorf("model %s failed %d predictions in a row", modelRun.ModelVersion, failedInRow)
+				break
+			}
+		}
+		if len(batch) < rescoreBatchSize {
+			break
+		}
+	}
+
+	now := time.Now()
+	h.mu.Lock()
+	job.FinishedAt = &now
+	if runErr != nil {
+		job.Status = models.RescoreFailed
+		job.Error = runErr.Error()
+	} else {
+		job.Status = models.RescoreCompleted
+	}
+	summary, errText := job.Summary, job.Error
+	h.mu.Unlock()
+
+	if runErr != nil {
+		logging.Ctx(ctx).Error().Err(runErr).Msgf("Rescore job %d failed", jobID)
+	}
+	// Record the outcome even when the job was cut short by shutdown
+	_ = h.store.AuditEvents().Create(context.WithoutCancel(ctx), models.AuditEvent{
+		Actor:      actor,
+		Action:     "model.rescore.finish",
+		TargetType: "model_run",
+		TargetID:   int(modelRun.ID),
+		Details: map[string]interface{}{
+			"job_id":          jobID,
+			"scanned":         summary.Scanned,
+			"rescored":        summary.Rescored,
+			"failed":          summary.Failed,
+			"cluster_changed": summary.ClusterChanged,
+			"mean_risk_delta": summary.MeanRiskDelta,
+			"error":           errText,
+		},
+	})
+}
+
+// pruneLocked drops the oldest finished jobs beyond rescoreKeepJobs.
+func (h *AdminRescoreHandler) pruneLocked() {
+	for len(h.order) > rescoreKeepJobs {
+		id := h.order[0]
+		if h.jobs[id].Status == models.RescoreRunning {
+			return
+		}
+		delete(h.jobs, id)
+		delete(h.done, id)
+		h.order = h.order[1:]
+	}
+}
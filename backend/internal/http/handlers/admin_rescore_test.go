@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// pinnedStub scores every assessment SIRD/90 with any version but "down"
+type pinnedStub struct{ ml.MockPredictor }
+
+func (p *pinnedStub) PredictWithModel(ctx context.Context, input models.Assessment, version, datasetHash string) (string, int, bool) {
+	if version == "down" {
+		return "", 0, false
+	}
+	return "SIRD", 90, true
+}
+
+func rescoreRouter(h *AdminRescoreHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(mockAuthMiddleware())
+	h.Register(r.Group("/admin"))
+	return r
+}
+
+func runRescore(t *testing.T, h *AdminRescoreHandler, path string) models.RescoreJob {
+	t.Helper()
+	r := rescoreRouter(h)
+	req, _ := http.NewRequest(http.MethodPost, path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var job models.RescoreJob
+	_ = json.Unmarshal(w.Body.Bytes(), &job)
+
+	h.mu.Lock()
+	done := h.done[job.ID]
+	h.mu.Unlock()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("rescore job did not finish")
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, w.Header().Get("Location")[len("/api/v1"):], nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var got models.RescoreJob
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return got
+}
+
+func TestRescore_StoresComparison(t *testing.T) {
+	recent := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeAssessmentRepo{all: []models.Assessment{
+		{ID: 1, Cluster: "MOD", RiskScore: 30, ModelVersion: "v1", CreatedAt: recent},
+		{ID: 2, Cluster: "SIRD", RiskScore: 80, ModelVersion: "v1", CreatedAt: recent},
+		{ID: 3, Cluster: "MARD", RiskScore: 45, CreatedAt: recent},
+		// Not yet scored at all
+		{ID: 4, Cluster: models.ClusterPendingPrediction, CreatedAt: recent},
+	}}
+	runs := &fakeModelRunRepo{runs: []models.ModelRun{{ID: 7, ModelVersion: "v2"}}}
+	audit := &fakeAuditRepo{}
+	h := NewAdminRescoreHandler(&fakeStore{repo: repo, modelRuns: runs, audit: audit}, &pinnedStub{})
+
+	job := runRescore(t, h, "/admin/model-runs/7/rescore")
+	if job.Status != models.RescoreCompleted || job.ModelVersion != "v2" {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	if s := job.Summary; s.Scanned != 3 || s.Rescored != 3 || s.ClusterChanged != 2 || s.MeanRiskDelta != 115.0/3 {
+		t.Fatalf("unexpected summary %+v", s)
+	}
+	if len(runs.predictions) != 3 {
+		t.Fatalf("expected 3 stored predictions, got %d", len(runs.predictions))
+	}
+	p := runs.predictions[0]
+	if p.AssessmentID != 1 || p.ModelRunID != 7 || p.PreviousCluster != "MOD" || p.PreviousRiskScore != 30 ||
+		p.PreviousModelVersion != "v1" || p.Cluster != "SIRD" || p.RiskScore != 90 {
+		t.Fatalf("unexpected prediction %+v", p)
+	}
+	if repo.all[0].Cluster != "MOD" {
+		t.Fatal("expected the assessment itself to be left alone")
+	}
+	if len(audit.events) != 2 || audit.events[1].Action != "model.rescore.finish" {
+		t.Fatalf("expected start and finish audit events, got %+v", audit.events)
+	}
+
+	runs.predictions = nil
+	job = runRescore(t, h, "/admin/model-runs/7/rescore?cluster=MOD")
+	if job.Summary.Scanned != 1 || len(runs.predictions) != 1 || runs.predictions[0].AssessmentID != 1 {
+		t.Fatalf("expected only the MOD assessment, got %+v", job.Summary)
+	}
+}
+
+func TestRescore_StopsWhenModelUnavailable(t *testing.T) {
+	var all []models.Assessment
+	for i := 1; i <= rescoreMaxFailures+5; i++ {
+		all = append(all, models.Assessment{ID: int64(i), Cluster: "MOD"})
+	}
+	runs := &fakeModelRunRepo{runs: []models.ModelRun{{ID: 7, ModelVersion: "down"}}}
+	h := NewAdminRescoreHandler(&fakeStore{repo: &fakeAssessmentRepo{all: all}, modelRuns: runs}, &pinnedStub{})
+
+	job := runRescore(t, h, "/admin/model-runs/7/rescore")
+	if job.Status != models.RescoreFailed || job.Summary.Failed != rescoreMaxFailures || len(runs.predictions) != 0 {
+		t.Fatalf("expected the job to stop after %d failures, got %+v", rescoreMaxFailures, job)
+	}
+}
+
+func TestRescore_Validation(t *testing.T) {
+	runs := &fakeModelRunRepo{runs: []models.ModelRun{{ID: 7, ModelVersion: "v2"}}}
+	st := &fakeStore{repo: &fakeAssessmentRepo{}, modelRuns: runs}
+	cases := []struct {
+		name      string
+		predictor ml.Predictor
+		path      string
+		want      int
+	}{
+		{"bad id", &pinnedStub{}, "/admin/model-runs/x/rescore", http.StatusBadRequest},
+		{"bad since", &pinnedStub{}, "/admin/model-runs/7/rescore?since=yesterday", http.StatusBadRequest},
+		{"unknown run", &pinnedStub{}, "/admin/model-runs/8/rescore", http.StatusNotFound},
+		{"unpinned model", ml.NewFallbackPredictor(), "/admin/model-runs/7/rescore", http.StatusNotImplemented},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := rescoreRouter(NewAdminRescoreHandler(st, tc.predictor))
+			req, _ := http.NewRequest(http.MethodPost, tc.path, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.want {
+				t.Fatalf("expected %d, got %d: %s", tc.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+	"github.com/skufu/DianaV2/backend/internal/worker"
+)
+
+const (
+	revalidateBatchSize   = 500
+	revalidateSampleLimit = 100
+	revalidateKeepJobs    = 20
+)
+
+// AdminRevalidationHandler re-runs validation rules over historical assessments,
+// e.g. after guideline cutoffs change. Jobs run in the background, one at a
+// time, and are kept in memory only.
+type AdminRevalidationHandler struct {
+	store   store.Store
+	workers *worker.Manager
+
+	mu     sync.Mutex
+	nextID int64
+	jobs   map[int64]*models.RevalidationJob
+	order  []int64
+	// done is closed when the matching job finishes (used by tests)
+	done map[int64]chan struct{}
+}
+
+// NewAdminRevalidationHandler creates a new AdminRevalidationHandler
+func NewAdminRevalidationHandler(store store.Store) *AdminRevalidationHandler {
+	return &AdminRevalidationHandler{
+		store: store,
+		jobs:  map[int64]*models.RevalidationJob{},
+		done:  map[int64]chan struct{}{},
+	}
+}
+
+// WithWorkers runs jobs under m so server shutdown stops them between
+// assessments and waits for them
+func (h *AdminRevalidationHandler) WithWorkers(m *worker.Manager) *AdminRevalidationHandler {
+	h.workers = m
+	return h
+}
+
+// Register registers revalidation routes on the given router group
+func (h *AdminRevalidationHandler) Register(rg *gin.RouterGroup) {
+	rg.POST("/assessments/revalidate", h.start)
+	rg.GET("/assessments/revalidate/:jobID", h.get)
+}
+
+// start launches a revalidation job
+// @Summary Re-validate assessments (admin only)
+// @Description Re-runs the current validation rules over assessments created since the given time in a background job. With dry_run=true statuses are only compared, not updated.
+// @Tags Admin
+// @Produce json
+// @Param since query string true "RFC3339 timestamp or YYYY-MM-DD"
+// @Param dry_run query bool false "Report changes without writing them"
+// @Success 202 {object} models.RevalidationJob
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/assessments/revalidate [post]
+func (h *AdminRevalidationHandler) start(c *gin.Context) {
+	since, ok := parseSince(c.Query("since"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC3339 timestamp or YYYY-MM-DD date"})
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+	claims := c.MustGet("user").(middleware.UserClaims)
+
+	h.mu.Lock()
+	for _, j := range h.jobs {
+		if j.Status == models.RevalidationRunning {
+			h.mu.Unlock()
+			c.JSON(http.StatusConflict, gin.H{"error": "a revalidation job is already running", "job_id": j.ID})
+			return
+		}
+	}
+	h.nextID++
+	job := &models.RevalidationJob{
+		ID:        h.nextID,
+		Status:    models.RevalidationRunning,
+		Since:     since,
+		DryRun:    dryRun,
+		StartedBy: claims.Email,
+		StartedAt: time.Now(),
+		Summary:   newRevalidationSummary(),
+	}
+	h.jobs[job.ID] = job
+	h.order = append(h.order, job.ID)
+	h.done[job.ID] = make(chan struct{})
+	h.pruneLocked()
+	snapshot := copyRevalidationJob(job)
+	h.mu.Unlock()
+
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "assessment.revalidate.start",
+		TargetType: "revalidation_job",
+		TargetID:   int(job.ID),
+		Details: map[string]interface{}{
+			"since":   since.Format(time.RFC3339),
+			"dry_run": dryRun,
+		},
+	})
+
+	// The job outlives the request and is cancelled by shutdown instead.
+	h.workers.Go("revalidate", func(ctx context.Context) {
+		h.run(ctx, job.ID)
+	})
+
+	c.JSON(http.StatusAccepted, snapshot)
+}
+
+// get returns a revalidation job and its summary so far
+// @Summary Get revalidation job (admin only)
+// @Tags Admin
+// @Produce json
+// @Param jobID path int true "Job ID"
+// @Success 200 {object} models.RevalidationJob
+// @Failure 404 {object} map[string]string
+// @Router /admin/assessments/revalidate/{jobID} [get]
+func (h *AdminRevalidationHandler) get(c *gin.Context) {
+	id, err := parseIDParam(c, "jobID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job id"})
+		return
+	}
+	h.mu.Lock()
+	job, ok := h.jobs[id]
+	var snapshot models.RevalidationJob
+	if ok {
+		snapshot = copyRevalidationJob(job)
+	}
+	h.mu.Unlock()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// run pages through the assessments and recomputes each validation status
+// and data quality score, filling in scores for assessments stored before
+// quality scoring existed.
+func (h *AdminRevalidationHandler) run(ctx context.Context, jobID int64) {
+	h.mu.Lock()
+	job := h.jobs[jobID]
+	since, dryRun, actor := job.Since, job.DryRun, job.StartedBy
+	done := h.done[jobID]
+	h.mu.Unlock()
+	defer close(done)
+
+	var afterID int64
+	var runErr error
+	for {
+		if err := ctx.Err(); err != nil {
+			runErr = err
+			break
+		}
+		batch, err := h.store.Assessments().ListSince(ctx, since, afterID, revalidateBatchSize)
+		if err != nil {
+			runErr = err
+			break
+		}
+		for _, a := range batch {
+			afterID = a.ID
+			next := validationStatus(a)
+			if next != a.ValidationStatus && !dryRun {
+				if err := h.store.Assessments().SetValidationStatus(ctx, int32(a.ID), next); err != nil {
+					runErr = err
+					break
+				}
+			}
+			quality := ml.AssessQuality(a)
+			rescored := a.Quality == nil || *a.Quality != quality
+			if rescored && !dryRun {
+				if err := h.store.Assessments().SetQuality(ctx, int32(a.ID), quality); err != nil {
+					runErr = err
+					break
+				}
+			}
+			h.mu.Lock()
+			tallyRevalidation(&job.Summary, a.ID, a.ValidationStatus, next)
+			if rescored {
+				job.Summary.QualityRescored++
+			}
+			h.mu.Unlock()
+		}
+		if runErr != nil || len(batch) < revalidateBatchSize {
+			break
+		}
+	}
+
+	now := time.Now()
+	h.mu.Lock()
+	job.FinishedAt = &now
+	if runErr != nil {
+		job.Status = models.RevalidationFailed
+		job.Error = runErr.Error()
+	} else {
+		job.Status = models.RevalidationCompleted
+	}
+	summary := job.Summary
+	h.mu.Unlock()
+
+	if runErr != nil {
+		logging.Ctx(ctx).Error().Err(runErr).Msgf("Revalidation job %d failed", jobID)
+	}
+	// Record the outcome even when the job was cut short by shutdown
+	_ = h.store.AuditEvents().Create(context.WithoutCancel(ctx), models.AuditEvent{
+		Actor:      actor,
+		Action:     "assessment.revalidate.finish",
+		TargetType: "revalidation_job",
+		TargetID:   int(jobID),
+		Details: map[string]interface{}{
+			"dry_run":        dryRun,
+			"scanned":        summary.Scanned,
+			"changed":        summary.Changed,
+			"became_ok":      summary.BecameOK,
+			"became_warning": summary.BecameWarning,
+			"error":          job.Error,
+		},
+	})
+}
+
+// pruneLocked drops the oldest finished jobs beyond revalidateKeepJobs.
+func (h *AdminRevalidationHandler) pruneLocked() {
+	for len(h.order) > revalidateKeepJobs {
+		id := h.order[0]
+		if h.jobs[id].Status == models.RevalidationRunning {
+			return
+		}
+		delete(h.jobs, id)
+		delete(h.done, id)
+		h.order = h.order[1:]
+	}
+}
+
+func newRevalidationSummary() models.RevalidationSummary {
+	return models.RevalidationSummary{
+		WarningsAdded:   map[string]int{},
+		WarningsRemoved: map[string]int{},
+		ChangedIDs:      []int64{},
+	}
+}
+
+// tallyRevalidation records one assessment's old and new status in the summary.
+func tallyRevalidation(s *models.RevalidationSummary, id int64, prev, next string) {
+	s.Scanned++
+	if prev == next {
+		return
+	}
+	s.Changed++
+	if len(s.ChangedIDs) < revalidateSampleLimit {
+		s.ChangedIDs = append(s.ChangedIDs, id)
+	}
+	switch {
+	case next == "ok":
+		s.BecameOK++
+	case prev == "ok" || prev == "":
+		s.BecameWarning++
+	}
+	before, after := warningSet(prev), warningSet(next)
+	for w := range after {
+		if !before[w] {
+			s.WarningsAdded[w]++
+		}
+	}
+	for w := range before {
+		if !after[w] {
+			s.WarningsRemoved[w]++
+		}
+	}
+}
+
+// warningSet splits a "warning:a,b" status into its warning codes.
+func warningSet(status string) map[string]bool {
+	out := map[string]bool{}
+	rest, ok := strings.CutPrefix(status, "warning:")
+	if !ok {
+		return out
+	}
+	for _, w := range strings.Split(rest, ",") {
+		if w != "" {
+			out[w] = true
+		}
+	}
+	return out
+}
+
+func copyRevalidationJob(j *models.RevalidationJob) models.RevalidationJob {
+	out := *j
+	out.Summary.WarningsAdded = make(map[string]int, len(j.Summary.WarningsAdded))
+	for k, v := range j.Summary.WarningsAdded {
+		out.Summary.WarningsAdded[k] = v
+	}
+	out.Summary.WarningsRemoved = make(map[string]int, len(j.Summary.WarningsRemoved))
+	for k, v := range j.Summary.WarningsRemoved {
+		out.Summary.WarningsRemoved[k] = v
+	}
+	out.Summary.ChangedIDs = append([]int64{}, j.Summary.ChangedIDs...)
+	return out
+}
+
+// parseSince accepts an RFC3339 timestamp or a plain YYYY-MM-DD date.
+func parseSince(v string) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func revalidationRouter(h *AdminRevalidationHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	h.Register(r.Group("/admin"))
+	return r
+}
+
+func startRevalidation(t *testing.T, r *gin.Engine, query string) (int, models.RevalidationJob) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, "/admin/assessments/revalidate?"+query, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var job models.RevalidationJob
+	_ = json.Unmarshal(w.Body.Bytes(), &job)
+	return w.Code, job
+}
+
+func waitRevalidation(t *testing.T, h *AdminRevalidationHandler, id int64) {
+	t.Helper()
+	h.mu.Lock()
+	done := h.done[id]
+	h.mu.Unlock()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("revalidation job did not finish")
+	}
+}
+
+func TestRevalidation_SummarizesStatusChanges(t *testing.T) {
+	old := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeAssessmentRepo{all: []models.Assessment{
+		// Before the cutoff: must be skipped
+		{ID: 1, FBS: 130, HbA1c: 5.0, ValidationStatus: "ok", CreatedAt: old},
+		// Unchanged
+		{ID: 2, FBS: 90, HbA1c: 5.0, ValidationStatus: "ok", CreatedAt: recent},
+		// ok -> warning
+		{ID: 3, FBS: 110, HbA1c: 5.0, ValidationStatus: "ok", CreatedAt: recent},
+		// warning -> ok
+		{ID: 4, FBS: 90, HbA1c: 5.0, ValidationStatus: "warning:fbs_prediabetic_range", CreatedAt: recent},
+		// warning code replaced
+		{ID: 5, FBS: 130, HbA1c: 5.0, ValidationStatus: "warning:fbs_prediabetic_range", CreatedAt: recent},
+	}}
+	audit := &fakeAuditRepo{}
+	h := NewAdminRevalidationHandler(&fakeStore{repo: repo, audit: audit})
+	r := revalidationRouter(h)
+
+	code, job := startRevalidation(t, r, "since=2024-01-01")
+	if code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", code)
+	}
+	waitRevalidation(t, h, job.ID)
+
+	req, _ := http.NewRequest(http.MethodGet, "/admin/assessments/revalidate/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var got models.RevalidationJob
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	s := got.Summary
+	if got.Status != models.RevalidationCompleted {
+		t.Fatalf("status = %s, want completed", got.Status)
+	}
+	if s.Scanned != 4 || s.Changed != 3 || s.BecameOK != 1 || s.BecameWarning != 1 {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+	if s.WarningsAdded["fbs_prediabetic_range"] != 1 || s.WarningsAdded["fbs_diabetic_range"] != 1 {
+		t.Fatalf("unexpected warnings added: %v", s.WarningsAdded)
+	}
+	if s.WarningsRemoved["fbs_prediabetic_range"] != 2 {
+		t.Fatalf("unexpected warnings removed: %v", s.WarningsRemoved)
+	}
+	if len(repo.statuses) != 3 || repo.statuses[4] != "ok" {
+		t.Fatalf("expected 3 status updates, got %v", repo.statuses)
+	}
+	if s.QualityRescored != 4 || len(repo.qualities) != 4 {
+		t.Fatalf("expected 4 unscored assessments backfilled, got %d (%v)", s.QualityRescored, repo.qualities)
+	}
+	if len(audit.events) != 2 {
+		t.Fatalf("expected start and finish audit events, got %d", len(audit.events))
+	}
+}
+
+func TestRevalidation_DryRunWritesNothing(t *testing.T) {
+	repo := &fakeAssessmentRepo{all: []models.Assessment{
+		{ID: 1, FBS: 110, ValidationStatus: "ok", CreatedAt: time.Now()},
+	}}
+	h := NewAdminRevalidationHandler(&fakeStore{repo: repo, audit: &fakeAuditRepo{}})
+	r := revalidationRouter(h)
+
+	code, job := startRevalidation(t, r, "since=2020-01-01T00:00:00Z&dry_run=true")
+	if code != http.StatusAccepted || !job.DryRun {
+		t.Fatalf("expected 202 dry run, got %d %+v", code, job)
+	}
+	waitRevalidation(t, h, job.ID)
+	if len(repo.statuses) != 0 || len(repo.qualities) != 0 {
+		t.Fatalf("dry run wrote statuses %v, qualities %v", repo.statuses, repo.qualities)
+	}
+	if job := h.jobs[job.ID]; job.Summary.Changed != 1 {
+		t.Fatalf("expected 1 change reported, got %d", job.Summary.Changed)
+	}
+}
+
+func TestRevalidation_Validation(t *testing.T) {
+	h := NewAdminRevalidationHandler(&fakeStore{repo: &fakeAssessmentRepo{}, audit: &fakeAuditRepo{}})
+	r := revalidationRouter(h)
+
+	if code, _ := startRevalidation(t, r, ""); code != http.StatusBadRequest {
+		t.Fatalf("missing since: expected 400, got %d", code)
+	}
+	if code, _ := startRevalidation(t, r, "since=yesterday"); code != http.StatusBadRequest {
+		t.Fatalf("bad since: expected 400, got %d", code)
+	}
+
+	// A job that is still running blocks a second one
+	h.jobs[99] = &models.RevalidationJob{ID: 99, Status: models.RevalidationRunning}
+	if code, _ := startRevalidation(t, r, "since=2024-01-01"); code != http.StatusConflict {
+		t.Fatalf("concurrent job: expected 409, got %d", code)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/admin/assessments/revalidate/42", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown job: expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// PermissionLookup resolves permissions against the store for
+// middleware.PermissionRequired. Grants are read on every request, so a
+// change applies at once.
+func PermissionLookup(st store.Store) middleware.PermissionLookup {
+	return func(ctx context.Context, user middleware.UserClaims, clinicID int64, permission string) (bool, error) {
+		ok, err := st.Roles().HasPermission(ctx, user.Role, permission)
+		if err != nil || ok || clinicID == 0 {
+			return ok, err
+		}
+		role, err := st.Clinics().ClinicRole(ctx, int32(user.UserID), int32(clinicID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return st.Roles().HasPermission(ctx, role, permission)
+	}
+}
+
+// AdminRolesHandler lets admins change the permissions each role grants
+type AdminRolesHandler struct {
+	store store.Store
+}
+
+// NewAdminRolesHandler creates a new AdminRolesHandler
+func NewAdminRolesHandler(store store.Store) *AdminRolesHandler {
+	return &AdminRolesHandler{store: store}
+}
+
+// Register registers the role routes on the admin group
+func (h *AdminRolesHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("/roles", h.listRoles)
+	rg.PUT("/roles/:role/permissions", middleware.RequireSudo(), h.setPermissions)
+}
+
+type rolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// listRoles returns every role with its permissions
+// @Summary List roles (admin only)
+// @Description Returns the global and clinic roles with the permissions each grants, and every known permission
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/roles [get]
+func (h *AdminRolesHandler) listRoles(c *gin.Context) {
+	roles, err := h.store.Roles().List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load roles"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": roles, "permissions": models.AllPermissions})
+}
+
+// setPermissions replaces the permissions a role grants
+// @Summary Set role permissions (admin only)
+// @Description Replaces the permissions a role grants. Admins keep admin.users so they cannot lock themselves out. Needs sudo.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param role path string true "Role name"
+// @Param permissions body rolePermissionsRequest true "Permissions"
+// @Success 200 {object} models.Role
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/roles/{role}/permissions [put]
+func (h *AdminRolesHandler) setPermissions(c *gin.Context) {
+	name := c.Param("role")
+	if _, ok := models.RoleScopes[name]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown role"})
+		return
+	}
+	var req rolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Permissions == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "permissions must be a list"})
+		return
+	}
+	for _, p := range req.Permissions {
+		if !slices.Contains(models.AllPermissions, p) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown permission: " + p})
+			return
+		}
+	}
+	if name == "admin" && !slices.Contains(req.Permissions, models.PermAdminUsers) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "admin must keep " + models.PermAdminUsers})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	ctx := c.Request.Context()
+	before, ok := h.findRole(c, name)
+	if !ok {
+		return
+	}
+	if err := h.store.Roles().SetPermissions(ctx, name, req.Permissions, claims.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update role"})
+		return
+	}
+	after, ok := h.findRole(c, name)
+	if !ok {
+		return
+	}
+
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "role.permissions_update",
+		TargetType: "role",
+		Details: map[string]interface{}{
+			"role":   name,
+			"before": before.Permissions,
+			"after":  after.Permissions,
+		},
+	})
+	c.JSON(http.StatusOK, after)
+}
+
+// findRole loads one role, writing a 500 and returning false on failure.
+func (h *AdminRolesHandler) findRole(c *gin.Context, name string) (models.Role, bool) {
+	roles, err := h.store.Roles().List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load roles"})
+		return models.Role{}, false
+	}
+	for _, r := range roles {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return models.Role{Name: name, Scope: models.RoleScopes[name], Permissions: []string{}}, true
+}
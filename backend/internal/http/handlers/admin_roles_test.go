@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestAdminRoles_ListAndSet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	audit := &fakeAuditRepo{}
+	st := &fakeStore{audit: audit}
+	r := gin.New()
+	group := r.Group("/admin")
+	group.Use(sudoAuthMiddleware())
+	NewAdminRolesHandler(st).Register(group)
+
+	w := contactRequest(r, http.MethodGet, "/admin/roles", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var list struct {
+		Roles       []models.Role `json:"roles"`
+		Permissions []string      `json:"permissions"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Roles) != len(models.RoleScopes) || len(list.Permissions) != len(models.AllPermissions) {
+		t.Fatalf("unexpected listing %+v", list)
+	}
+
+	for _, tc := range []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"unknown role", "/admin/roles/owner/permissions", `{"permissions":[]}`, http.StatusNotFound},
+		{"missing list", "/admin/roles/clinician/permissions", `{}`, http.StatusBadRequest},
+		{"unknown permission", "/admin/roles/clinician/permissions", `{"permissions":["patients.delete"]}`, http.StatusBadRequest},
+		{"admin lockout", "/admin/roles/admin/permissions", `{"permissions":["admin.system"]}`, http.StatusBadRequest},
+	} {
+		if w := contactRequest(r, http.MethodPut, tc.path, tc.body); w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+	if len(audit.events) != 0 {
+		t.Fatalf("refused requests must not be audited: %+v", audit.events)
+	}
+
+	w = contactRequest(r, http.MethodPut, "/admin/roles/clinician/permissions", `{"permissions":["patients.read"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	ctx := context.Background()
+	if ok, _ := st.Roles().HasPermission(ctx, "clinician", models.PermPatientsWrite); ok {
+		t.Fatal("expected patients.write to be revoked")
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != "role.permissions_update" {
+		t.Fatalf("expected a role.permissions_update event, got %+v", audit.events)
+	}
+	before, _ := audit.events[0].Details["before"].([]string)
+	if !slices.Contains(before, models.PermPatientsWrite) {
+		t.Fatalf("expected the previous permissions in the event, got %+v", audit.events[0].Details)
+	}
+}
+
+func TestAdminRoles_SetRequiresSudo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	group := r.Group("/admin")
+	group.Use(mockAuthMiddleware())
+	NewAdminRolesHandler(&fakeStore{}).Register(group)
+
+	if w := contactRequest(r, http.MethodPut, "/admin/roles/clinician/permissions", `{"permissions":[]}`); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without sudo, got %d", w.Code)
+	}
+}
+
+func TestPermissionLookup_ClinicRole(t *testing.T) {
+	lookup := PermissionLookup(&fakeStore{clinicRepo: newMembersRepo()})
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name       string
+		user       middleware.UserClaims
+		clinicID   int64
+		permission string
+		want       bool
+	}{
+		{"global role", middleware.UserClaims{UserID: 9, Role: "admin"}, 0, models.PermAdminUsers, true},
+		{"global role not granted", middleware.UserClaims{UserID: 9, Role: "clinician"}, 0, models.PermAdminUsers, false},
+		{"clinic admin of clinic", middleware.UserClaims{UserID: 1, Role: "clinician"}, 1, models.PermClinicManage, true},
+		{"clinic role ignored without clinic", middleware.UserClaims{UserID: 1, Role: "clinician"}, 0, models.PermClinicManage, false},
+		{"other clinic", middleware.UserClaims{UserID: 1, Role: "clinician"}, 2, models.PermClinicManage, false},
+		{"plain member", middleware.UserClaims{UserID: 2, Role: "clinician"}, 1, models.PermClinicManage, false},
+		{"plain member reads", middleware.UserClaims{UserID: 2, Role: "clinician"}, 1, models.PermClinicRead, true},
+	} {
+		got, err := lookup(ctx, tc.user, tc.clinicID, tc.permission)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: expected %v, got %v (%v)", tc.name, tc.want, got, err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+)
+
+// AdminSLOHandler reports per-route latency against SLO targets
+type AdminSLOHandler struct {
+	tracker *middleware.SLOTracker
+}
+
+// NewAdminSLOHandler creates a new AdminSLOHandler
+func NewAdminSLOHandler(tracker *middleware.SLOTracker) *AdminSLOHandler {
+	return &AdminSLOHandler{tracker: tracker}
+}
+
+// Register registers the SLO report route on the given router group
+func (h *AdminSLOHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("/slo", h.report)
+}
+
+// report returns latency percentiles and budget burn per route
+// @Summary Latency SLO report (admin only)
+// @Description Returns p50/p95/p99 latency, requests over target and error budget burn for each route over its most recent requests, worst burn first. Counts reset on restart.
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Router /admin/slo [get]
+func (h *AdminSLOHandler) report(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"objective": h.tracker.Objective(),
+		"window":    h.tracker.Window(),
+		"routes":    h.tracker.Report(),
+	})
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+)
+
+func TestAdminSLO_Report(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	route := "POST /api/v1/patients/:id/assessments"
+	tracker := middleware.NewSLOTracker(500*time.Millisecond, map[string]time.Duration{route: 2 * time.Second}, 0.99)
+	tracker.Record(route, 3*time.Second)
+	tracker.Record("GET /api/v1/patients", 10*time.Millisecond)
+
+	r := gin.New()
+	NewAdminSLOHandler(tracker).Register(r.Group("/admin"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/slo", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Objective float64               `json:"objective"`
+		Routes    []middleware.SLORoute `json:"routes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Objective != 0.99 || len(resp.Routes) != 2 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if got := resp.Routes[0]; got.Route != route || got.TargetMS != 2000 || got.OverTarget != 1 {
+		t.Errorf("worst route = %+v, want the slow assessment route first", got)
+	}
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/events"
 	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
 	"github.com/skufu/DianaV2/backend/internal/models"
 	"github.com/skufu/DianaV2/backend/internal/store"
 	"golang.org/x/crypto/bcrypt"
@@ -13,7 +16,8 @@ import (
 
 // AdminUsersHandler handles admin user management operations
 type AdminUsersHandler struct {
-	store store.Store
+	store  store.Store
+	events *events.Bus
 }
 
 // NewAdminUsersHandler creates a new AdminUsersHandler
@@ -21,6 +25,12 @@ func NewAdminUsersHandler(store store.Store) *AdminUsersHandler {
 	return &AdminUsersHandler{store: store}
 }
 
+// WithEvents publishes user.deactivated on bus when a user is deactivated
+func (h *AdminUsersHandler) WithEvents(bus *events.Bus) *AdminUsersHandler {
+	h.events = bus
+	return h
+}
+
 // Register registers admin user routes on the given router group
 // All routes require admin role (enforced by RBAC middleware at group level)
 func (h *AdminUsersHandler) Register(rg *gin.RouterGroup) {
@@ -30,8 +40,9 @@ func (h *AdminUsersHandler) Register(rg *gin.RouterGroup) {
 		users.POST("", h.createUser)
 		users.GET("/:id", h.getUser)
 		users.PUT("/:id", h.updateUser)
-		users.DELETE("/:id", h.deactivateUser)
+		users.DELETE("/:id", middleware.RequireSudo(), h.deactivateUser)
 		users.POST("/:id/activate", h.activateUser)
+		users.POST("/:id/unlock", h.unlockUser)
 	}
 }
 
@@ -50,7 +61,7 @@ type UpdateUserRequest struct {
 
 // listUsers returns a paginated list of users
 // @Summary List all users (admin only)
-// @Description Returns paginated list of users with optional filters
+// @Description Returns paginated list of users with optional filters and per-user activity stats
 // @Tags Admin
 // @Produce json
 // @Param page query int false "Page number (default 1)"
@@ -58,6 +69,8 @@ type UpdateUserRequest struct {
 // @Param search query string false "Search by email"
 // @Param role query string false "Filter by role"
 // @Param is_active query bool false "Filter by active status"
+// @Param sort query string false "Sort by email, created_at, last_login_at, patient_count, assessment_count, high_risk_assessment_count or last_activity_at"
+// @Param order query string false "asc or desc"
 // @Success 200 {object} models.PaginatedResponse
 // @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
@@ -134,9 +147,8 @@ func (h *AdminUsersHandler) createUser(c *gin.Context) {
 
 	createdUser, err := h.store.Users().Create(c.Request.Context(), user)
 	if err != nil {
-		// Check for duplicate email
-		if isDuplicateKeyError(err) {
-			c.JSON(http.StatusConflict, gin.H{"error": "email already exists"})
+		if errors.Is(err, store.ErrConflict) {
+			abortWithError(c, err, "email already exists")
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
@@ -243,7 +255,7 @@ func (h *AdminUsersHandler) updateUser(c *gin.Context) {
 
 // deactivateUser soft-deletes a user by setting is_active to false
 // @Summary Deactivate user (admin only)
-// @Description Soft-deletes a user account (can be reactivated)
+// @Description Soft-deletes a user account (can be reactivated) and signs out all of its sessions. Requires a recent POST /auth/sudo elevation.
 // @Tags Admin
 // @Produce json
 // @Param id path int true "User ID"
@@ -271,13 +283,14 @@ func (h *AdminUsersHandler) deactivateUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to deactivate user"})
 		return
 	}
+	// Their sessions cannot be refreshed any more
+	if err := h.store.RefreshTokens().RevokeAllUserTokens(c.Request.Context(), int32(id)); err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to revoke refresh tokens of deactivated user %d", id)
+	}
 
-	// Log the audit event
-	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
-		Actor:      claims.Email,
-		Action:     "user.deactivate",
-		TargetType: "user",
-		TargetID:   int(id),
+	h.events.Publish(c.Request.Context(), events.UserDeactivated{
+		Actor:  claims.Email,
+		UserID: id,
 	})
 
 	c.JSON(http.StatusOK, gin.H{"message": "user deactivated successfully"})
@@ -307,36 +320,47 @@ func (h *AdminUsersHandler) activateUser(c *gin.Context) {
 		return
 	}
 
-	// Log the audit event
 	claims := c.MustGet("user").(middleware.UserClaims)
-	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
-		Actor:      claims.Email,
-		Action:     "user.activate",
-		TargetType: "user",
-		TargetID:   int(id),
+	h.events.Publish(c.Request.Context(), events.UserActivated{
+		Actor:  claims.Email,
+		UserID: id,
 	})
 
 	c.JSON(http.StatusOK, gin.H{"message": "user activated successfully"})
 }
 
-// isDuplicateKeyError checks if the error is a PostgreSQL duplicate key violation
-func isDuplicateKeyError(err error) bool {
-	return err != nil && (
-		// PostgreSQL unique violation
-		containsString(err.Error(), "duplicate key") ||
-		containsString(err.Error(), "23505") ||
-		containsString(err.Error(), "unique constraint"))
-}
-
-func containsString(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsSubstr(s, substr))
-}
+// unlockUser lifts a login lockout before it expires
+// @Summary Unlock user (admin only)
+// @Description Clears a lockout caused by repeated failed logins
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/users/{id}/unlock [post]
+func (h *AdminUsersHandler) unlockUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
 
-func containsSubstr(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
+	if err := h.store.Users().Unlock(c.Request.Context(), int32(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unlock user"})
+		return
 	}
-	return false
+
+	// Log the audit event
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "user.unlock",
+		TargetType: "user",
+		TargetID:   int(id),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "user unlocked successfully"})
 }
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// listingUserRepo records the parameters of admin user list calls
+type listingUserRepo struct {
+	store.UserRepository
+	params models.UserListParams
+	users  []models.User
+}
+
+func (f *listingUserRepo) List(ctx context.Context, params models.UserListParams) ([]models.User, int, error) {
+	f.params = params
+	return f.users, len(f.users), nil
+}
+
+func TestAdminUsers_ListSortsByActivity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	users := &listingUserRepo{users: []models.User{
+		{ID: 2, Email: "doc@example.com", Activity: &models.UserActivity{PatientCount: 4, AssessmentCount: 9, HighRiskAssessmentCount: 2}},
+	}}
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	NewAdminUsersHandler(&fakeStore{users: users}).Register(r.Group("/admin"))
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/admin/users?page=1&page_size=20"+query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("&sort=password_hash"); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown sort: expected 400, got %d", w.Code)
+	}
+
+	w := get("&sort=assessment_count&order=desc")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if users.params.Sort != "assessment_count" || users.params.Order != "desc" {
+		t.Fatalf("sort not passed to the store: %+v", users.params)
+	}
+	var resp struct {
+		Data []models.User `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Data) != 1 || resp.Data[0].Activity == nil || resp.Data[0].Activity.AssessmentCount != 9 {
+		t.Fatalf("activity missing from response: %s", w.Body.String())
+	}
+}
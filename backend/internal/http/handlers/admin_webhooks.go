@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// webhookSecretPrefix marks webhook signing secrets
+const webhookSecretPrefix = "whsec_"
+
+// AdminWebhooksHandler lets admins manage the webhooks external systems
+// receive events on
+type AdminWebhooksHandler struct {
+	store     store.Store
+	allowHTTP bool
+}
+
+func NewAdminWebhooksHandler(store store.Store) *AdminWebhooksHandler {
+	return &AdminWebhooksHandler{store: store}
+}
+
+// WithAllowHTTP accepts plain http:// URLs, for local receivers outside
+// production. Otherwise only https:// is accepted.
+func (h *AdminWebhooksHandler) WithAllowHTTP(allow bool) *AdminWebhooksHandler {
+	h.allowHTTP = allow
+	return h
+}
+
+func (h *AdminWebhooksHandler) Register(rg *gin.RouterGroup) {
+	hooks := rg.Group("/webhooks")
+	hooks.GET("", h.list)
+	// Pointing a webhook somewhere new sends patient identifiers there
+	hooks.POST("", middleware.RequireSudo(), h.create)
+	hooks.GET("/:id", h.get)
+	hooks.PUT("/:id", middleware.RequireSudo(), h.update)
+	hooks.DELETE("/:id", h.delete)
+	hooks.GET("/:id/deliveries", h.deliveries)
+}
+
+type webhookRequest struct {
+	URL         string   `json:"url" binding:"required,max=2000"`
+	Description string   `json:"description" binding:"max=200"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	// Active defaults to true
+	Active *bool `json:"active"`
+}
+
+// newWebhookSecret returns a fresh signing secret
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// bindWebhook parses and validates a webhook request. Returns false if a
+// response has already been written.
+func (h *AdminWebhooksHandler) bindWebhook(c *gin.Context) (models.Webhook, bool) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return models.Webhook{}, false
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && !(h.allowHTTP && u.Scheme == "http")) {
+		msg := "url must be an absolute https:// URL"
+		if h.allowHTTP {
+			msg = "url must be an absolute http:// or https:// URL"
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return models.Webhook{}, false
+	}
+	for _, e := range req.Events {
+		if !slices.Contains(models.WebhookEvents, e) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown event: " + e, "events": models.WebhookEvents})
+			return models.Webhook{}, false
+		}
+	}
+	active := req.Active == nil || *req.Active
+	return models.Webhook{
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      slices.Compact(slices.Sorted(slices.Values(req.Events))),
+		Active:      active,
+	}, true
+}
+
+func (h *AdminWebhooksHandler) list(c *gin.Context) {
+	hooks, err := h.store.Webhooks().List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list webhooks"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": hooks})
+}
+
+// create registers a webhook. The signing secret is only ever returned here.
+func (h *AdminWebhooksHandler) create(c *gin.Context) {
+	hook, ok := h.bindWebhook(c)
+	if !ok {
+		return
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "secret error"})
+		return
+	}
+	claims := c.MustGet("user").(middleware.UserClaims)
+	hook.Secret = secret
+	hook.CreatedBy = claims.Email
+
+	created, err := h.store.Webhooks().Create(c.Request.Context(), hook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create webhook"})
+		return
+	}
+
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "webhook.create",
+		TargetType: "webhook",
+		TargetID:   int(created.ID),
+		Details: map[string]interface{}{
+			"url":    created.URL,
+			"events": created.Events,
+		},
+	})
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *AdminWebhooksHandler) get(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID"})
+		return
+	}
+	hook, err := h.store.Webhooks().Get(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "webhook not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load webhook"})
+		return
+	}
+	c.JSON(http.StatusOK, hook)
+}
+
+// update replaces a webhook's URL, description, events and active flag.
+// The secret is kept.
+func (h *AdminWebhooksHandler) update(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID"})
+		return
+	}
+	hook, ok := h.bindWebhook(c)
+	if !ok {
+		return
+	}
+	hook.ID = id
+
+	updated, err := h.store.Webhooks().Update(c.Request.Context(), hook)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "webhook not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update webhook"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "webhook.update",
+		TargetType: "webhook",
+		TargetID:   int(id),
+		Details: map[string]interface{}{
+			"url":    updated.URL,
+			"events": updated.Events,
+			"active": updated.Active,
+		},
+	})
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// delete removes a webhook together with its queued deliveries
+func (h *AdminWebhooksHandler) delete(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID"})
+		return
+	}
+	err = h.store.Webhooks().Delete(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "webhook not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete webhook"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "webhook.delete",
+		TargetType: "webhook",
+		TargetID:   int(id),
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+// deliveries lists a webhook's most recent deliveries, newest first
+func (h *AdminWebhooksHandler) deliveries(c *gin.Context) {
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+	if _, err := h.store.Webhooks().Get(c.Request.Context(), id); errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "webhook not found")
+		return
+	}
+	deliveries, err := h.store.Webhooks().ListDeliveries(c.Request.Context(), id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list deliveries"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": deliveries})
+}
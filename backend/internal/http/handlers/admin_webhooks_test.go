@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+func TestAdminWebhooks_Lifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	audit := &fakeAuditRepo{}
+	st := &fakeStore{webhooks: store.NewMemoryStore().Webhooks(), audit: audit}
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	admin := r.Group("/admin")
+	admin.Use(sudoAuthMiddleware())
+	NewAdminWebhooksHandler(st).Register(admin)
+
+	for body, want := range map[string]int{
+		`{"url":"http://ehr.example.com/hook","events":["patient.created"]}`:  http.StatusBadRequest,
+		`{"url":"https://ehr.example.com/hook","events":["patient.deleted"]}`: http.StatusBadRequest,
+		`{"url":"/hook","events":["patient.created"]}`:                        http.StatusBadRequest,
+		`{"url":"https://ehr.example.com/hook","events":[]}`:                  http.StatusBadRequest,
+	} {
+		if w := postJSON(r, "/admin/webhooks", body); w.Code != want {
+			t.Errorf("%s: expected %d, got %d", body, want, w.Code)
+		}
+	}
+
+	w := postJSON(r, "/admin/webhooks", `{"url":"https://ehr.example.com/hook","events":["assessment.high_risk","patient.created","patient.created"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created models.Webhook
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if !strings.HasPrefix(created.Secret, webhookSecretPrefix) || !created.Active || len(created.Events) != 2 {
+		t.Fatalf("unexpected webhook %+v", created)
+	}
+
+	// The secret is not shown again
+	req, _ := http.NewRequest(http.MethodGet, "/admin/webhooks", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), created.Secret) || !strings.Contains(w.Body.String(), "ehr.example.com") {
+		t.Fatalf("list: unexpected %d %s", w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest(http.MethodPut, "/admin/webhooks/1", bytes.NewBufferString(`{"url":"https://ehr.example.com/v2","events":["assessment.created"],"active":false}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var updated models.Webhook
+	_ = json.Unmarshal(w.Body.Bytes(), &updated)
+	if w.Code != http.StatusOK || updated.Active || updated.URL != "https://ehr.example.com/v2" || updated.Secret != "" {
+		t.Fatalf("update: unexpected %d %+v", w.Code, updated)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, "/admin/webhooks/1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", w.Code)
+	}
+	req, _ = http.NewRequest(http.MethodGet, "/admin/webhooks/1/deliveries", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("deliveries of a deleted webhook: expected 404, got %d", w.Code)
+	}
+
+	var actions []string
+	for _, e := range audit.events {
+		actions = append(actions, e.Action)
+	}
+	if strings.Join(actions, ",") != "webhook.create,webhook.update,webhook.delete" {
+		t.Errorf("unexpected audit trail %v", actions)
+	}
+}
+
+func TestAdminWebhooks_AllowHTTPAndSudo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	st := &fakeStore{webhooks: store.NewMemoryStore().Webhooks()}
+
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(mockAuthMiddleware())
+	NewAdminWebhooksHandler(st).WithAllowHTTP(true).Register(r.Group("/admin"))
+	if w := postJSON(r, "/admin/webhooks", `{"url":"http://localhost:9000/hook","events":["patient.created"]}`); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without sudo, got %d", w.Code)
+	}
+
+	r = gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(sudoAuthMiddleware())
+	NewAdminWebhooksHandler(st).WithAllowHTTP(true).Register(r.Group("/admin"))
+	if w := postJSON(r, "/admin/webhooks", `{"url":"http://localhost:9000/hook","events":["patient.created"]}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected http:// to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+}
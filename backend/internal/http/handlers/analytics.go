@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
 	"github.com/skufu/DianaV2/backend/internal/store"
 )
 
@@ -20,8 +23,31 @@ func (h *AnalyticsHandler) Register(rg *gin.RouterGroup) {
 	rg.GET("/biomarker-trends", h.trends)
 }
 
+// cluster returns the number of assessments per cluster
+// @Summary Cluster distribution
+// @Description Assessments per cluster, optionally scoped. Users without admin.system only count their clinics' patients.
+// @Tags Analytics
+// @Produce json
+// @Param user_id query int false "Only this clinician's patients"
+// @Param clinic_id query int false "Only patients of this clinic's members"
+// @Param tag_id query int false "Only patients carrying this tag"
+// @Success 200 {array} models.ClusterAnalytics
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /analytics/cluster-distribution [get]
 func (h *AnalyticsHandler) cluster(c *gin.Context) {
-	data, err := h.store.Assessments().ClusterCounts(c.Request.Context())
+	scope, ok := cohortScope(c, h.store)
+	if !ok {
+		return
+	}
+	var data []models.ClusterAnalytics
+	var err error
+	if scope.IsZero() {
+		data, err = h.store.Assessments().ClusterCounts(c.Request.Context())
+	} else {
+		data, err = h.store.Assessments().ClusterCountsInScope(c.Request.Context(), scope)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load distribution"})
 		return
@@ -29,8 +55,81 @@ func (h *AnalyticsHandler) cluster(c *gin.Context) {
 	c.JSON(http.StatusOK, data)
 }
 
+// trendQuery is the optional window, bucketing and biomarker selection of a
+// trend request. Dates are calendar days and both ends are inclusive.
+type trendQuery struct {
+	Start       string `form:"start"`
+	End         string `form:"end"`
+	Granularity string `form:"granularity" binding:"omitempty,oneof=week month quarter"`
+	Biomarkers  string `form:"biomarkers"`
+}
+
+// trends returns biomarker averages per time bucket
+// @Summary Biomarker trends
+// @Description Average biomarker values per week, month or quarter, optionally windowed and scoped
+// @Tags Analytics
+// @Produce json
+// @Param start query string false "First day included (YYYY-MM-DD)"
+// @Param end query string false "Last day included (YYYY-MM-DD)"
+// @Param granularity query string false "week, month or quarter" default(month)
+// @Param biomarkers query string false "Comma-separated biomarkers: hba1c, fbs, bmi, cholesterol, ldl, hdl, triglycerides, systolic, diastolic, risk_score" default(hba1c,fbs)
+// @Param user_id query int false "Only this clinician's patients"
+// @Param clinic_id query int false "Only patients of this clinic's members"
+// @Param tag_id query int false "Only patients carrying this tag"
+// @Success 200 {array} models.TrendPoint
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /analytics/biomarker-trends [get]
 func (h *AnalyticsHandler) trends(c *gin.Context) {
-	data, err := h.store.Assessments().TrendAverages(c.Request.Context())
+	var q trendQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be week, month or quarter"})
+		return
+	}
+	params := models.TrendParams{Granularity: q.Granularity}
+	if q.Start != "" {
+		start, err := time.Parse("2006-01-02", q.Start)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start must be a date (YYYY-MM-DD)"})
+			return
+		}
+		params.Start = &start
+	}
+	if q.End != "" {
+		end, err := time.Parse("2006-01-02", q.End)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "end must be a date (YYYY-MM-DD)"})
+			return
+		}
+		end = end.AddDate(0, 0, 1)
+		params.End = &end
+	}
+	if params.Start != nil && params.End != nil && !params.Start.Before(*params.End) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start must not be after end"})
+		return
+	}
+	if q.Biomarkers != "" {
+		seen := map[string]bool{}
+		for _, b := range strings.Split(q.Biomarkers, ",") {
+			b = strings.ToLower(strings.TrimSpace(b))
+			if (&models.TrendPoint{}).Field(b) == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "unknown biomarker: " + b})
+				return
+			}
+			if !seen[b] {
+				seen[b] = true
+				params.Biomarkers = append(params.Biomarkers, b)
+			}
+		}
+	}
+	scope, ok := cohortScope(c, h.store)
+	if !ok {
+		return
+	}
+	params.Scope = scope
+
+	data, err := h.store.Assessments().TrendAverages(c.Request.Context(), params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load trends"})
 		return
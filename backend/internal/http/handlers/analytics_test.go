@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+func TestAnalyticsHandler_TrendParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &fakeAssessmentRepo{}
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	NewAnalyticsHandler(&fakeStore{repo: repo}).Register(r.Group("/analytics"))
+
+	w := contactRequest(r, http.MethodGet, "/analytics/biomarker-trends", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if p := repo.trendParams; p.Start != nil || p.End != nil || p.Granularity != "" || p.Biomarkers != nil || !p.Scope.IsZero() {
+		t.Fatalf("expected store defaults, got %+v", p)
+	}
+
+	w = contactRequest(r, http.MethodGet, "/analytics/biomarker-trends?start=2024-01-01&end=2024-03-31&granularity=week&biomarkers=hba1c,%20LDL,hba1c&clinic_id=4", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	p := repo.trendParams
+	if !p.Start.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !p.End.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected an inclusive window, got %v to %v", p.Start, p.End)
+	}
+	if p.Granularity != models.TrendWeek || !reflect.DeepEqual(p.Biomarkers, []string{"hba1c", "ldl"}) {
+		t.Fatalf("unexpected params: %+v", p)
+	}
+	if p.Scope.ClinicID == nil || *p.Scope.ClinicID != 4 {
+		t.Fatalf("expected clinic scope, got %+v", p.Scope)
+	}
+}
+
+func TestAnalyticsHandler_TrendParamsInvalid(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"unknown granularity", "?granularity=day", http.StatusBadRequest},
+		{"unknown biomarker", "?biomarkers=hba1c,height", http.StatusBadRequest},
+		{"bad start", "?start=01/02/2024", http.StatusBadRequest},
+		{"end before start", "?start=2024-03-01&end=2024-02-01", http.StatusBadRequest},
+		{"other clinician", "?user_id=6", http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeAssessmentRepo{}
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("user", middleware.UserClaims{UserID: 5, Email: "doc@example.com", Role: "clinician"})
+				c.Next()
+			})
+			NewAnalyticsHandler(&fakeStore{repo: repo}).Register(r.Group("/analytics"))
+
+			w := contactRequest(r, http.MethodGet, "/analytics/biomarker-trends"+tc.query, "")
+			if w.Code != tc.want {
+				t.Fatalf("expected %d, got %d: %s", tc.want, w.Code, w.Body.String())
+			}
+			if repo.trendParams != nil {
+				t.Fatal("rejected request still queried the store")
+			}
+		})
+	}
+}
+
+func TestAnalyticsHandler_ClusterRestrictedToClinics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		name    string
+		role    string
+		clinics store.ClinicRepository
+		want    []int32
+	}{
+		{"clinic member", "clinician", &fakeSharingClinicRepo{role: models.ClinicRoleMember}, []int32{4}},
+		{"admin sees everything", "admin", &fakeSharingClinicRepo{role: models.ClinicRoleMember}, nil},
+		{"no clinics", "clinician", nil, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeAssessmentRepo{}
+			st := &fakeStore{repo: repo, clinicRepo: tc.clinics}
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("user", middleware.UserClaims{UserID: 5, Email: "doc@example.com", Role: tc.role})
+				c.Next()
+			})
+			r.Use(middleware.AccessScope(PermissionLookup(st), ClinicMembershipLookup(st)))
+			NewAnalyticsHandler(st).Register(r.Group("/analytics"))
+
+			if w := contactRequest(r, http.MethodGet, "/analytics/cluster-distribution", ""); w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+			switch {
+			case tc.role == "admin":
+				if repo.clusterScope != nil {
+					t.Fatalf("expected an unscoped count, got %+v", repo.clusterScope)
+				}
+			case tc.want == nil:
+				if repo.clusterScope == nil || repo.clusterScope.UserID == nil || *repo.clusterScope.UserID != 5 {
+					t.Fatalf("expected the caller's own patients, got %+v", repo.clusterScope)
+				}
+			default:
+				if repo.clusterScope == nil || !reflect.DeepEqual(repo.clusterScope.ClinicIDs, tc.want) {
+					t.Fatalf("expected clinics %v, got %+v", tc.want, repo.clusterScope)
+				}
+			}
+		})
+	}
+}
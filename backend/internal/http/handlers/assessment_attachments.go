@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/storage"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// attachmentContentTypes are the sniffed types an attachment may have
+var attachmentContentTypes = []string{"application/pdf", "image/jpeg", "image/png"}
+
+// AssessmentAttachmentsHandler stores files such as lab reports against an
+// assessment. Files are served through short-lived signed links rather than
+// the authenticated API, so browsers can open them directly; each link is
+// bound to the user it was issued to and every download is audited.
+type AssessmentAttachmentsHandler struct {
+	store    store.Store
+	blobs    storage.Storage
+	maxBytes int64
+	urlKey   []byte
+	urlTTL   time.Duration
+	now      func() time.Time
+}
+
+// NewAssessmentAttachmentsHandler signs download links with a key derived
+// from secret, valid for ttl.
+func NewAssessmentAttachmentsHandler(store store.Store, blobs storage.Storage, maxBytes int64, secret string, ttl time.Duration) *AssessmentAttachmentsHandler {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("assessment-attachment-urls"))
+	return &AssessmentAttachmentsHandler{
+		store:    store,
+		blobs:    blobs,
+		maxBytes: maxBytes,
+		urlKey:   mac.Sum(nil),
+		urlTTL:   ttl,
+		now:      time.Now,
+	}
+}
+
+// Subscribe removes a deleted patient's attachment objects; their rows are
+// already gone with the patient.
+func (h *AssessmentAttachmentsHandler) Subscribe(bus *events.Bus) {
+	events.Subscribe(bus, "assessment_attachments", func(ctx context.Context, e events.PatientDeleted) error {
+		var errs []error
+		for _, key := range e.AttachmentKeys {
+			if err := h.blobs.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				errs = append(errs, fmt.Errorf("delete attachment object %s: %w", key, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+func (h *AssessmentAttachmentsHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("/:id/assessments/:assessmentID/attachments", h.list)
+	rg.POST("/:id/assessments/:assessmentID/attachments", h.upload)
+	rg.DELETE("/:id/assessments/:assessmentID/attachments/:attachmentID", h.delete)
+}
+
+// RegisterDownload registers the signed download route. It must sit outside
+// authentication: the signature is the credential.
+func (h *AssessmentAttachmentsHandler) RegisterDownload(rg *gin.RouterGroup) {
+	rg.GET("/attachments/:attachmentID/download", h.download)
+}
+
+// assessment resolves :id and :assessmentID to an assessment the caller can
+// see, or, with owner set, one of a patient the caller owns. Returns false
+// if a response has already been written.
+func (h *AssessmentAttachmentsHandler) assessment(c *gin.Context, owner bool) (*models.Assessment, bool) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return nil, false
+	}
+	patientID, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return nil, false
+	}
+	assessmentID, err := strconv.ParseInt(c.Param("assessmentID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid assessment ID"})
+		return nil, false
+	}
+	ctx := c.Request.Context()
+	if owner {
+		_, err = h.store.Patients().Get(ctx, int32(patientID), userID)
+	} else {
+		_, err = h.store.Patients().GetVisible(ctx, int32(patientID), userID)
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return nil, false
+	}
+	a, err := h.store.Assessments().Get(ctx, int32(assessmentID), userID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && a.PatientID != patientID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "assessment not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get assessment"})
+		return nil, false
+	}
+	return a, true
+}
+
+// signature authenticates a download link for attachmentID issued to userID
+func (h *AssessmentAttachmentsHandler) signature(attachmentID, userID, expires int64) string {
+	mac := hmac.New(sha256.New, h.urlKey)
+	fmt.Fprintf(mac, "%d:%d:%d", attachmentID, userID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// withDownloadURL fills in a fresh signed link to the attachment for userID
+func (h *AssessmentAttachmentsHandler) withDownloadURL(a models.AssessmentAttachment, userID int32) models.AssessmentAttachment {
+	expiresAt := h.now().Add(h.urlTTL).Truncate(time.Second)
+	expires := expiresAt.Unix()
+	q := url.Values{
+		"user":      {strconv.FormatInt(int64(userID), 10)},
+		"expires":   {strconv.FormatInt(expires, 10)},
+		"signature": {h.signature(a.ID, int64(userID), expires)},
+	}
+	a.DownloadURL = fmt.Sprintf("/api/v1/attachments/%d/download?%s", a.ID, q.Encode())
+	a.URLExpiresAt = &expiresAt
+	return a
+}
+
+// list returns the assessment's attachments, oldest first, each with a
+// signed download link
+// @Summary List assessment attachments
+// @Tags Assessments
+// @Produce json
+// @Param id path int true "Patient ID"
+// @Param assessmentID path int true "Assessment ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /patients/{id}/assessments/{assessmentID}/attachments [get]
+func (h *AssessmentAttachmentsHandler) list(c *gin.Context) {
+	a, ok := h.assessment(c, false)
+	if !ok {
+		return
+	}
+	attachments, err := h.store.AssessmentAttachments().List(c.Request.Context(), a.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list attachments"})
+		return
+	}
+	userID, _ := getUserID(c)
+	for i := range attachments {
+		attachments[i] = h.withDownloadURL(attachments[i], userID)
+	}
+	c.JSON(http.StatusOK, gin.H{"data": attachments})
+}
+
+// upload stores a file against the assessment
+// @Summary Upload assessment attachment
+// @Description Accepts a PDF, JPEG or PNG as multipart field "file", such as a lab report. The type is detected from the content, not the filename.
+// @Tags Assessments
+// @Accept mpfd
+// @Produce json
+// @Param id path int true "Patient ID"
+// @Param assessmentID path int true "Assessment ID"
+// @Param file formData file true "Attachment"
+// @Success 201 {object} models.AssessmentAttachment
+// @Failure 400 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Router /patients/{id}/assessments/{assessmentID}/attachments [post]
+func (h *AssessmentAttachmentsHandler) upload(c *gin.Context) {
+	a, ok := h.assessment(c, true)
+	if !ok {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBytes+1<<20)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file must be at most %d bytes", h.maxBytes)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multipart field 'file' is required"})
+		return
+	}
+	if fh.Size > h.maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file must be at most %d bytes", h.maxBytes)})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, h.maxBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	if len(data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is empty"})
+		return
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	if !slices.Contains(attachmentContentTypes, contentType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "file must be a PDF, JPEG or PNG"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	key, err := newAttachmentKey(a.PatientID, a.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store file"})
+		return
+	}
+	if err := h.blobs.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to store attachment for assessment %d", a.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store file"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	created, err := h.store.AssessmentAttachments().Create(ctx, models.AssessmentAttachment{
+		AssessmentID: a.ID,
+		PatientID:    a.PatientID,
+		StorageKey:   key,
+		Filename:     attachmentFilename(fh.Filename),
+		ContentType:  contentType,
+		SizeBytes:    int64(len(data)),
+		UploadedBy:   int64(claims.UserID),
+	})
+	if err != nil {
+		h.removeBlob(ctx, key)
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to record attachment for assessment %d", a.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store file"})
+		return
+	}
+
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "assessment.attachment.upload",
+		TargetType: "assessment",
+		TargetID:   int(a.ID),
+		Details: map[string]interface{}{
+			"attachment_id": created.ID,
+			"patient_id":    a.PatientID,
+			"content_type":  contentType,
+			"size_bytes":    created.SizeBytes,
+		},
+	})
+	c.JSON(http.StatusCreated, h.withDownloadURL(*created, int32(claims.UserID)))
+}
+
+// delete removes an attachment and its file
+// @Summary Delete assessment attachment
+// @Tags Assessments
+// @Param id path int true "Patient ID"
+// @Param assessmentID path int true "Assessment ID"
+// @Param attachmentID path int true "Attachment ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /patients/{id}/assessments/{assessmentID}/attachments/{attachmentID} [delete]
+func (h *AssessmentAttachmentsHandler) delete(c *gin.Context) {
+	a, ok := h.assessment(c, true)
+	if !ok {
+		return
+	}
+	attachmentID, err := parseIDParam(c, "attachmentID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	attachment, err := h.store.AssessmentAttachments().Get(ctx, attachmentID, a.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "attachment not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete attachment"})
+		return
+	}
+	if err := h.store.AssessmentAttachments().Delete(ctx, attachmentID, a.ID); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete attachment"})
+		return
+	}
+	h.removeBlob(ctx, attachment.StorageKey)
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "assessment.attachment.delete",
+		TargetType: "assessment",
+		TargetID:   int(a.ID),
+		Details: map[string]interface{}{
+			"attachment_id": attachment.ID,
+			"patient_id":    a.PatientID,
+		},
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// download serves an attachment to the holder of a valid signed link
+// @Summary Download assessment attachment
+// @Description Serves the file behind a signed link from the attachment list. Links expire; request the list again for a fresh one.
+// @Tags Assessments
+// @Produce octet-stream
+// @Param attachmentID path int true "Attachment ID"
+// @Param user query int true "User the link was issued to"
+// @Param expires query int true "Expiry, Unix seconds"
+// @Param signature query string true "Link signature"
+// @Success 200 {file} binary
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /attachments/{attachmentID}/download [get]
+func (h *AssessmentAttachmentsHandler) download(c *gin.Context) {
+	attachmentID, err := parseIDParam(c, "attachmentID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment ID"})
+		return
+	}
+	userID, errUser := strconv.ParseInt(c.Query("user"), 10, 32)
+	expires, errExpires := strconv.ParseInt(c.Query("expires"), 10, 64)
+	want := h.signature(attachmentID, userID, expires)
+	if errUser != nil || errExpires != nil || !hmac.Equal([]byte(c.Query("signature")), []byte(want)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid download link"})
+		return
+	}
+	if h.now().Unix() > expires {
+		c.JSON(http.StatusForbidden, gin.H{"error": "download link has expired"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	// Links of a user deactivated since they were issued stop working
+	user, err := h.store.Users().FindByID(ctx, int32(userID))
+	if err != nil || !user.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid download link"})
+		return
+	}
+	attachment, err := h.store.AssessmentAttachments().GetByID(ctx, attachmentID)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "attachment not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load attachment"})
+		return
+	}
+	body, err := h.blobs.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to read attachment %d", attachment.ID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+		return
+	}
+	defer body.Close()
+
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      user.Email,
+		Action:     "assessment.attachment.download",
+		TargetType: "assessment",
+		TargetID:   int(attachment.AssessmentID),
+		Details: map[string]interface{}{
+			"attachment_id": attachment.ID,
+			"patient_id":    attachment.PatientID,
+		},
+	})
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, attachment.SizeBytes, attachment.ContentType, body, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(attachment.Filename)),
+	})
+}
+
+// removeBlob deletes a stored object, logging failures
+func (h *AssessmentAttachmentsHandler) removeBlob(ctx context.Context, key string) {
+	if err := h.blobs.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to delete attachment object %s", key)
+	}
+}
+
+// attachmentFilename keeps the base name of an uploaded filename, at most
+// 255 bytes, for display and the download's Content-Disposition
+func attachmentFilename(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, `\`, "/")))
+	if name == "." || name == "/" || name == "" {
+		return "attachment"
+	}
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// newAttachmentKey returns a fresh, unguessable storage key for a file of
+// the assessment
+func newAttachmentKey(patientID, assessmentID int64) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("assessment-attachments/%d/%d/%s", patientID, assessmentID, hex.EncodeToString(b)), nil
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/storage"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+var testPDF = []byte("%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj\n%%EOF\n")
+
+type attachmentTestEnv struct {
+	router  *gin.Engine
+	handler *AssessmentAttachmentsHandler
+	mem     *store.MemoryStore
+	blobs   *storage.LocalStorage
+	path    string
+}
+
+// newAttachmentTestEnv serves attachments of one assessment owned by the
+// mock user, with patient deletion publishing to the attachment handler.
+func newAttachmentTestEnv(t *testing.T) *attachmentTestEnv {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	mem := store.NewMemoryStore()
+	user, err := mem.Users().Create(ctx, models.User{Email: "test@example.com", Role: "clinician", IsActive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	patient, _ := mem.Patients().Create(ctx, models.Patient{UserID: user.ID, Name: "Juan"})
+	assessment, _ := mem.Assessments().Create(ctx, models.Assessment{PatientID: patient.ID, FBS: 110})
+
+	env := &attachmentTestEnv{mem: mem, blobs: storage.NewLocalStorage(t.TempDir())}
+	env.handler = NewAssessmentAttachmentsHandler(mem, env.blobs, 1<<10, "secret", 15*time.Minute)
+	bus := events.NewBus()
+	env.handler.Subscribe(bus)
+
+	env.router = gin.New()
+	env.router.Use(middleware.ErrorHandler())
+	env.handler.RegisterDownload(env.router.Group("/api/v1"))
+	protected := env.router.Group("", mockAuthMiddleware())
+	env.handler.Register(protected.Group("/patients"))
+	NewPatientsHandler(mem).WithEvents(bus).Register(protected.Group("/patients"))
+	NewAssessmentsHandler(mem, nil, "v1", "").Register(protected.Group("/patients"))
+	env.path = "/patients/" + strconv.FormatInt(patient.ID, 10) + "/assessments/" + strconv.FormatInt(assessment.ID, 10) + "/attachments"
+	return env
+}
+
+func (env *attachmentTestEnv) upload(t *testing.T, filename string, content []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", filename)
+	_, _ = fw.Write(content)
+	_ = mw.Close()
+	req, _ := http.NewRequest(http.MethodPost, env.path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+	return w
+}
+
+func (env *attachmentTestEnv) uploaded(t *testing.T) models.AssessmentAttachment {
+	t.Helper()
+	w := env.upload(t, `C:\labs\lipid panel.pdf`, testPDF)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var a models.AssessmentAttachment
+	_ = json.Unmarshal(w.Body.Bytes(), &a)
+	return a
+}
+
+func TestAssessmentAttachments_UploadListDownloadDelete(t *testing.T) {
+	env := newAttachmentTestEnv(t)
+	a := env.uploaded(t)
+	if a.Filename != "lipid panel.pdf" || a.ContentType != "application/pdf" || a.SizeBytes != int64(len(testPDF)) {
+		t.Fatalf("unexpected attachment %+v", a)
+	}
+	if a.DownloadURL == "" || a.URLExpiresAt == nil {
+		t.Fatalf("expected a signed download link, got %+v", a)
+	}
+
+	w := photoRequest(env.router, http.MethodGet, env.path)
+	var list struct{ Data []models.AssessmentAttachment }
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list.Data) != 1 || list.Data[0].DownloadURL == "" {
+		t.Fatalf("list: got %d %s", w.Code, w.Body.String())
+	}
+
+	w = photoRequest(env.router, http.MethodGet, list.Data[0].DownloadURL)
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), testPDF) {
+		t.Fatalf("download: got %d %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != "attachment; filename*=UTF-8''lipid%20panel.pdf" {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if w.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("Content-Type = %q", w.Header().Get("Content-Type"))
+	}
+
+	assessmentPath := strings.TrimSuffix(env.path, "/attachments")
+	if w := photoRequest(env.router, http.MethodDelete, assessmentPath); w.Code != http.StatusConflict {
+		t.Errorf("delete assessment with attachments: expected 409, got %d", w.Code)
+	}
+	if w := photoRequest(env.router, http.MethodDelete, env.path+"/"+strconv.FormatInt(a.ID, 10)); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", w.Code)
+	}
+	if w := photoRequest(env.router, http.MethodGet, a.DownloadURL); w.Code != http.StatusNotFound {
+		t.Errorf("download after delete: expected 404, got %d", w.Code)
+	}
+	stored, _ := env.mem.AssessmentAttachments().List(context.Background(), a.AssessmentID)
+	if len(stored) != 0 {
+		t.Errorf("expected no attachments left, got %+v", stored)
+	}
+
+	events, _, _ := env.mem.AuditEvents().List(context.Background(), models.AuditListParams{Page: 1, PageSize: 20})
+	var actions []string
+	for _, e := range events {
+		actions = append(actions, e.Action)
+	}
+	for _, want := range []string{"assessment.attachment.upload", "assessment.attachment.download", "assessment.attachment.delete"} {
+		if !strings.Contains(strings.Join(actions, ","), want) {
+			t.Errorf("expected audit action %s, got %v", want, actions)
+		}
+	}
+}
+
+func TestAssessmentAttachments_Validation(t *testing.T) {
+	env := newAttachmentTestEnv(t)
+	if w := env.upload(t, "notes.pdf", []byte("plain text pretending to be a PDF")); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("text file: expected 415, got %d", w.Code)
+	}
+	if w := env.upload(t, "scan.png", append(testPNG(t, 1, 1), make([]byte, 2<<10)...)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized file: expected 413, got %d", w.Code)
+	}
+	if w := env.upload(t, "empty.pdf", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("empty file: expected 400, got %d", w.Code)
+	}
+	if w := photoRequest(env.router, http.MethodPost, env.path); w.Code != http.StatusBadRequest {
+		t.Errorf("no file: expected 400, got %d", w.Code)
+	}
+}
+
+func TestAssessmentAttachments_DownloadLinks(t *testing.T) {
+	env := newAttachmentTestEnv(t)
+	a := env.uploaded(t)
+
+	for name, link := range map[string]string{
+		"tampered signature": a.DownloadURL[:len(a.DownloadURL)-1] + "0",
+		"other user":         strings.Replace(a.DownloadURL, "user=1", "user=2", 1),
+		"no signature":       "/api/v1/attachments/" + strconv.FormatInt(a.ID, 10) + "/download",
+	} {
+		if w := photoRequest(env.router, http.MethodGet, link); w.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d", name, w.Code)
+		}
+	}
+
+	env.handler.now = func() time.Time { return time.Now().Add(16 * time.Minute) }
+	if w := photoRequest(env.router, http.MethodGet, a.DownloadURL); w.Code != http.StatusForbidden {
+		t.Errorf("expired link: expected 403, got %d", w.Code)
+	}
+	env.handler.now = time.Now
+
+	_ = env.mem.Users().Deactivate(context.Background(), 1)
+	if w := photoRequest(env.router, http.MethodGet, a.DownloadURL); w.Code != http.StatusForbidden {
+		t.Errorf("deactivated user: expected 403, got %d", w.Code)
+	}
+}
+
+func TestAssessmentAttachments_RemovedWithPatient(t *testing.T) {
+	env := newAttachmentTestEnv(t)
+	a := env.uploaded(t)
+	stored, _ := env.mem.AssessmentAttachments().GetByID(context.Background(), a.ID)
+
+	if w := photoRequest(env.router, http.MethodDelete, "/patients/"+strconv.FormatInt(a.PatientID, 10)); w.Code != http.StatusNoContent {
+		t.Fatalf("delete patient: expected 204, got %d", w.Code)
+	}
+	if _, err := env.blobs.Get(context.Background(), stored.StorageKey); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected attachment object removed with patient, got %v", err)
+	}
+}
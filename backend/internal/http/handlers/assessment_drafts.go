@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// createAssessmentReq is an assessment, optionally completing a draft of
+// lab results received for the patient
+type createAssessmentReq struct {
+	assessmentReq
+	DraftID *int64 `json:"draft_id" binding:"omitempty,gt=0"`
+}
+
+// pendingDraft checks that draftID is a pending draft of the patient.
+// Returns false if a response has already been written.
+func (h *AssessmentsHandler) pendingDraft(c *gin.Context, patientID, draftID int64) bool {
+	drafts, err := h.store.AssessmentDrafts().ListPending(c.Request.Context(), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load assessment draft"})
+		return false
+	}
+	for _, d := range drafts {
+		if d.ID == draftID {
+			return true
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "assessment draft not found"})
+	return false
+}
+
+// completeDraft marks the draft as used by a. Failures are logged only: the
+// assessment is already stored.
+func (h *AssessmentsHandler) completeDraft(ctx context.Context, draftID *int64, a models.Assessment) {
+	if draftID == nil {
+		return
+	}
+	if err := h.store.AssessmentDrafts().Complete(ctx, *draftID, a.PatientID, a.ID); err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to complete assessment draft %d", *draftID)
+	}
+}
+
+// listDrafts returns the patient's pending lab result drafts, newest first
+func (h *AssessmentsHandler) listDrafts(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	patientID, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient id"})
+		return
+	}
+	if _, err := h.store.Patients().GetVisible(c.Request.Context(), int32(patientID), userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	drafts, err := h.store.AssessmentDrafts().ListPending(c.Request.Context(), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list assessment drafts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": drafts})
+}
+
+// discardDraft drops a draft that will not become an assessment
+func (h *AssessmentsHandler) discardDraft(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	patientID, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient id"})
+		return
+	}
+	draftID, err := parseIDParam(c, "draftID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid draft id"})
+		return
+	}
+	// Only the owner changes the patient's record, as for assessments
+	if _, err := h.store.Patients().Get(c.Request.Context(), int32(patientID), userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	err = h.store.AssessmentDrafts().Discard(c.Request.Context(), draftID, patientID)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "assessment draft not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to discard assessment draft"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "assessment_draft.discard",
+		TargetType: "patient",
+		TargetID:   int(patientID),
+		Details:    map[string]interface{}{"draft_id": draftID},
+	})
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"math"
+
+	"github.com/skufu/DianaV2/backend/internal/loinc"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// storedValue converts v, a value of the key biomarker in units, to the unit
+// it is stored in. Empty units mean the stored unit already. Converted
+// glucose is rounded to one decimal; lipids are stored whole.
+func storedValue(key string, v float64, units string) float64 {
+	if units == "" || units == models.UnitsMgDL {
+		return v
+	}
+	b, _ := loinc.ByKey(key)
+	converted, err := b.Convert(v, units)
+	if err != nil {
+		return v
+	}
+	return math.Round(converted*10) / 10
+}
+
+// storedWhole is storedValue rounded for the lipids, which are stored as
+// whole mg/dL
+func storedWhole(key string, v float64, units string) int {
+	return int(math.Round(storedValue(key, v, units)))
+}
+
+// storedFloat and storedInt convert an optional patch value like storedValue
+// and storedWhole
+func storedFloat(key string, v *float64, units string) *float64 {
+	if v == nil {
+		return nil
+	}
+	out := storedValue(key, *v, units)
+	return &out
+}
+
+func storedInt(key string, v *float64, units string) *int {
+	if v == nil {
+		return nil
+	}
+	out := storedWhole(key, *v, units)
+	return &out
+}
+
+// expressAssessment sets a.InUnits for a reader who prefers units other
+// than mg/dL, converting glucose to one and lipids to two decimals.
+func expressAssessment(a *models.Assessment, units string) {
+	if a == nil || units != models.UnitsMmolL {
+		return
+	}
+	express := func(key string, v float64, decimals float64) float64 {
+		b, _ := loinc.ByKey(key)
+		out, err := b.Express(v, units)
+		if err != nil {
+			return v
+		}
+		scale := math.Pow(10, decimals)
+		return math.Round(out*scale) / scale
+	}
+	a.InUnits = &models.BiomarkerUnits{
+		Units:         units,
+		FBS:           express("fbs", a.FBS, 1),
+		Cholesterol:   express("cholesterol", float64(a.Cholesterol), 2),
+		LDL:           express("ldl", float64(a.LDL), 2),
+		HDL:           express("hdl", float64(a.HDL), 2),
+		Triglycerides: express("triglycerides", float64(a.Triglycerides), 2),
+	}
+}
+
+// expressAssessments is expressAssessment over a list
+func expressAssessments(as []models.Assessment, units string) {
+	for i := range as {
+		expressAssessment(&as[i], units)
+	}
+}
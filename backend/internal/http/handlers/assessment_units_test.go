@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+func TestAssessments_MmolLInputAndResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	users := store.NewMemoryStore().Users()
+	_, _ = users.Create(context.Background(), models.User{Email: "test@example.com", Role: "admin"})
+	repo := &fakeAssessmentRepo{}
+	st := &fakeStore{repo: repo, patientRepo: &fakePatientRepo{}, users: users,
+		clinicRepo: &fakeClinicRepo{mode: models.ValidationModeStrict}}
+
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	NewAssessmentsHandler(st, ml.NewMockPredictor(), "v1", "hash123").Register(r.Group("/patients"))
+
+	// 7.2 mmol/L is diabetic; read as mg/dL it would be rejected as implausible
+	body := `{"units":"mmol/L","fbs":7.2,"hba1c":6.8,"cholesterol":5.2,"hdl":1.1,"bmi":27}`
+	w := contactRequest(r, http.MethodPost, "/patients/7/assessments", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if repo.last.FBS != 129.7 || repo.last.Cholesterol != 201 || repo.last.HDL != 43 {
+		t.Errorf("expected mg/dL to be stored, got fbs=%v cholesterol=%d hdl=%d", repo.last.FBS, repo.last.Cholesterol, repo.last.HDL)
+	}
+	var created models.Assessment
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if created.InUnits != nil {
+		t.Errorf("a mg/dL reader should not get converted values, got %+v", created.InUnits)
+	}
+
+	_ = users.SavePreferences(context.Background(), 1, models.UserPreferences{Units: models.UnitsMmolL})
+	w = contactRequest(r, http.MethodPost, "/patients/7/assessments", `{"fbs":129.7,"hba1c":6.8,"bmi":27}`)
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if created.FBS != 129.7 || created.InUnits == nil || created.InUnits.FBS != 7.2 {
+		t.Errorf("expected mg/dL with a mmol/L restatement, got fbs=%v in_units=%+v", created.FBS, created.InUnits)
+	}
+
+	if w := contactRequest(r, http.MethodPost, "/patients/7/assessments", `{"units":"mmol","fbs":7.2,"bmi":27}`); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown units: expected 400, got %d", w.Code)
+	}
+}
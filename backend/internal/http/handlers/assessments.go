@@ -1,16 +1,21 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
 	"github.com/skufu/DianaV2/backend/internal/ml"
 	"github.com/skufu/DianaV2/backend/internal/models"
-	"github.com/skufu/DianaV2/backend/internal/pdf"
 	"github.com/skufu/DianaV2/backend/internal/store"
 )
 
@@ -26,6 +31,12 @@ type AssessmentsHandler struct {
 	predictor   ml.Predictor
 	modelVer    string
 	datasetHash string
+	events      *events.Bus
+	immutable   bool
+	// recommendationExperiment splits report recommendation wording
+	recommendationExperiment bool
+	// predictions, when set, scores new assessments in the background
+	predictions *PredictionQueue
 }
 
 func NewAssessmentsHandler(store store.Store, predictor ml.Predictor, modelVersion, datasetHash string) *AssessmentsHandler {
@@ -37,22 +48,58 @@ func NewAssessmentsHandler(store store.Store, predictor ml.Predictor, modelVersi
 	}
 }
 
+// WithEvents publishes assessment.created on bus for each stored assessment.
+func (h *AssessmentsHandler) WithEvents(bus *events.Bus) *AssessmentsHandler {
+	h.events = bus
+	return h
+}
+
+// WithAsyncPredictions stores new assessments as pending_prediction and
+// leaves scoring them to q; creation then answers 202 Accepted and clients
+// poll the assessment until its cluster changes.
+func (h *AssessmentsHandler) WithAsyncPredictions(q *PredictionQueue) *AssessmentsHandler {
+	h.predictions = q
+	return h
+}
+
+// activeModel returns the model version and dataset hash stamped on new
+// assessments. The run activated by an admin wins; the configured values are
+// the fallback when no run is active or the store is unavailable.
+func (h *AssessmentsHandler) activeModel(ctx context.Context) (string, string) {
+	run, err := h.store.ModelRuns().GetActive(ctx)
+	if err != nil || run == nil {
+		return h.modelVer, h.datasetHash
+	}
+	return run.ModelVersion, run.DatasetHash
+}
+
 func (h *AssessmentsHandler) Register(rg *gin.RouterGroup) {
 	rg.POST("/:id/assessments", h.create)
+	rg.POST("/:id/assessments:action", h.assessmentAction)
 	rg.GET("/:id/assessments", h.list)
 	rg.GET("/:id/assessments/:assessmentID", h.get)
 	rg.PUT("/:id/assessments/:assessmentID", h.update)
+	rg.PATCH("/:id/assessments/:assessmentID", h.patch)
 	rg.DELETE("/:id/assessments/:assessmentID", h.delete)
 	rg.GET("/:id/assessments/:assessmentID/report", h.report)
+	rg.GET("/:id/assessments/:assessmentID/explanation", h.explanation)
+	rg.POST("/:id/assessments/:assessmentID/finalize", h.finalizeStatusDraft)
+	rg.GET("/:id/assessment-drafts", h.listDrafts)
+	rg.DELETE("/:id/assessment-drafts/:draftID", h.discardDraft)
 }
 
+// assessmentReq is an assessment as entered. Units says what FBS and the
+// lipids are in, mg/dL (the default) or mmol/L; they are converted to mg/dL
+// before validation, scoring and storage. Height and weight come as a pair;
+// BMI is computed from them and only required without them.
 type assessmentReq struct {
+	Units         string  `json:"units" binding:"omitempty,oneof=mg/dL mmol/L"`
 	FBS           float64 `json:"fbs" binding:"gte=0,lte=1000"`
 	HbA1c         float64 `json:"hba1c" binding:"gte=0,lte=20"`
-	Cholesterol   int     `json:"cholesterol" binding:"gte=0,lte=1000"`
-	LDL           int     `json:"ldl" binding:"gte=0,lte=500"`
-	HDL           int     `json:"hdl" binding:"gte=0,lte=200"`
-	Triglycerides int     `json:"triglycerides" binding:"gte=0,lte=2000"`
+	Cholesterol   float64 `json:"cholesterol" binding:"gte=0,lte=1000"`
+	LDL           float64 `json:"ldl" binding:"gte=0,lte=500"`
+	HDL           float64 `json:"hdl" binding:"gte=0,lte=200"`
+	Triglycerides float64 `json:"triglycerides" binding:"gte=0,lte=2000"`
 	Systolic      int     `json:"systolic" binding:"gte=0,lte=300"`
 	Diastolic     int     `json:"diastolic" binding:"gte=0,lte=200"`
 	Activity      string  `json:"activity" binding:"max=50,oneof='' 'sedentary' 'light' 'moderate' 'active' 'very_active'"`
@@ -60,7 +107,34 @@ type assessmentReq struct {
 	Smoking       string  `json:"smoking" binding:"max=20,oneof='' 'never' 'former' 'current'"`
 	Hypertension  string  `json:"hypertension" binding:"max=10,oneof='' 'yes' 'no'"`
 	HeartDisease  string  `json:"heart_disease" binding:"max=10,oneof='' 'yes' 'no'"`
-	BMI           float64 `json:"bmi" binding:"gte=10,lte=100"`
+	BMI           float64 `json:"bmi" binding:"required_without=HeightCM,omitempty,gte=10,lte=100"`
+	HeightCM      float64 `json:"height_cm" binding:"required_with=WeightKG,omitempty,gte=50,lte=250"`
+	WeightKG      float64 `json:"weight_kg" binding:"required_with=HeightCM,omitempty,gte=10,lte=400"`
+	SelfReported  bool    `json:"self_reported"`
+}
+
+func (r assessmentReq) toAssessment(patientID int64) models.Assessment {
+	a := models.Assessment{
+		PatientID:     patientID,
+		FBS:           storedValue("fbs", r.FBS, r.Units),
+		HbA1c:         r.HbA1c,
+		Cholesterol:   storedWhole("cholesterol", r.Cholesterol, r.Units),
+		LDL:           storedWhole("ldl", r.LDL, r.Units),
+		HDL:           storedWhole("hdl", r.HDL, r.Units),
+		Triglycerides: storedWhole("triglycerides", r.Triglycerides, r.Units),
+		Systolic:      r.Systolic,
+		Diastolic:     r.Diastolic,
+		Activity:      r.Activity,
+		HistoryFlag:   r.HistoryFlag,
+		Smoking:       r.Smoking,
+		Hypertension:  r.Hypertension,
+		HeartDisease:  r.HeartDisease,
+		HeightCM:      r.HeightCM,
+		WeightKG:      r.WeightKG,
+		SelfReported:  r.SelfReported,
+	}
+	a.BMI, a.BMISource = models.DeriveBMI(r.BMI, r.HeightCM, r.WeightKG)
+	return a
 }
 
 func (h *AssessmentsHandler) create(c *gin.Context) {
@@ -77,49 +151,77 @@ func (h *AssessmentsHandler) create(c *gin.Context) {
 	}
 
 	// Verify patient exists and belongs to user
-	_, err = h.store.Patients().Get(c.Request.Context(), int32(patientID), userID)
+	patient, err := h.store.Patients().Get(c.Request.Context(), int32(patientID), userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
 		return
 	}
 
-	var req assessmentReq
+	var req createAssessmentReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 		return
 	}
-	a := models.Assessment{
-		PatientID:     patientID,
-		FBS:           req.FBS,
-		HbA1c:         req.HbA1c,
-		Cholesterol:   req.Cholesterol,
-		LDL:           req.LDL,
-		HDL:           req.HDL,
-		Triglycerides: req.Triglycerides,
-		Systolic:      req.Systolic,
-		Diastolic:     req.Diastolic,
-		Activity:      req.Activity,
-		HistoryFlag:   req.HistoryFlag,
-		Smoking:       req.Smoking,
-		Hypertension:  req.Hypertension,
-		HeartDisease:  req.HeartDisease,
-		BMI:           req.BMI,
-		ModelVersion:  h.modelVer,
-		DatasetHash:   h.datasetHash,
+	if req.DraftID != nil && !h.pendingDraft(c, patientID, *req.DraftID) {
+		return
+	}
+	a := req.toAssessment(patientID)
+	a.PatientAge = patient.Age
+	a.OnMedication = onMedication(c.Request.Context(), h.store, patientID, time.Now())
+	a.ModelVersion, a.DatasetHash = h.activeModel(c.Request.Context())
+	if !h.checkPlausibility(c, userID, a) {
+		return
 	}
 	a.ValidationStatus = validationStatus(a)
-	cluster, risk := h.predictor.Predict(a)
-	a.Cluster = cluster
-	a.RiskScore = risk
+	a.Quality = dataQuality(a)
+	claims := c.MustGet("user").(middleware.UserClaims)
+	if h.predictions != nil {
+		if created := h.createPending(c, a, claims.Email, userID); created != nil {
+			h.completeDraft(c.Request.Context(), req.DraftID, *created)
+		}
+		return
+	}
+	explanation := ml.Score(c.Request.Context(), h.predictor, &a, true)
 	created, err := h.store.Assessments().Create(c.Request.Context(), a)
 	if err != nil {
-		log.Printf("Failed to create assessment: %v", err)
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to create assessment")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create assessment"})
 		return
 	}
+	if explanation != nil {
+		h.saveExplanation(c.Request.Context(), created.ID, explanation)
+	}
+	h.completeDraft(c.Request.Context(), req.DraftID, *created)
+	h.events.Publish(c.Request.Context(), events.AssessmentCreated{
+		Actor:      claims.Email,
+		UserID:     userID,
+		Assessment: *created,
+	})
+	expressAssessment(created, h.units(c, userID))
 	c.JSON(http.StatusCreated, created)
 }
 
+// createPending stores a unscored and queues its prediction. assessment.created
+// is published by the queue once the prediction is stored. Returns the
+// stored assessment, or nil if creation failed.
+func (h *AssessmentsHandler) createPending(c *gin.Context, a models.Assessment, actor string, userID int32) *models.Assessment {
+	a.Cluster, a.RiskScore = models.ClusterPendingPrediction, 0
+	created, err := h.store.Assessments().Create(c.Request.Context(), a)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to create assessment")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create assessment"})
+		return nil
+	}
+	queued := *created
+	queued.PatientAge, queued.OnMedication = a.PatientAge, a.OnMedication
+	h.predictions.Enqueue(queued, actor, userID)
+	c.Header("Location", fmt.Sprintf("/api/v1/patients/%d/assessments/%d", created.PatientID, created.ID))
+	response := *created
+	expressAssessment(&response, h.units(c, userID))
+	c.JSON(http.StatusAccepted, response)
+	return created
+}
+
 func (h *AssessmentsHandler) list(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
@@ -133,21 +235,188 @@ func (h *AssessmentsHandler) list(c *gin.Context) {
 		return
 	}
 
-	// Verify patient exists and belongs to user
-	_, err = h.store.Patients().Get(c.Request.Context(), int32(patientID), userID)
+	// Verify patient exists and is owned by or shared with the user
+	_, err = h.store.Patients().GetVisible(c.Request.Context(), int32(patientID), userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
 		return
 	}
 
+	var filter qualityFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_quality and max_quality must be between 0 and 100"})
+		return
+	}
+	after, keyset, err := listCursor(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var size assessmentPageSize
+	if err := c.ShouldBindQuery(&size); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page_size must be between 1 and 100"})
+		return
+	}
+
+	// The reader's units shape in_units, so they are part of the ETag
+	units := h.units(c, userID)
+	version, err := h.store.Assessments().ListVersion(c.Request.Context(), patientID)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to version assessments of patient %d", patientID)
+	} else if notModified(c, listETag(version, c.Request.URL.RawQuery, units)) {
+		return
+	}
+
+	if keyset {
+		h.listAfter(c, patientID, filter, after, size.pageSize(), units)
+		return
+	}
+
 	records, err := h.store.Assessments().ListByPatient(c.Request.Context(), patientID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list assessments"})
 		return
 	}
+	records = filter.apply(records)
+	expressAssessments(records, units)
 	c.JSON(http.StatusOK, records)
 }
 
+// assessmentPageSize is the page size of a keyset assessment list; the
+// plain list returns every assessment.
+type assessmentPageSize struct {
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+func (s assessmentPageSize) pageSize() int {
+	if s.PageSize < 1 {
+		return 20
+	}
+	return s.PageSize
+}
+
+// listAfter serves a keyset page of a patient's assessments, newest first.
+// One row beyond the page is read to tell whether another page follows.
+func (h *AssessmentsHandler) listAfter(c *gin.Context, patientID int64, filter qualityFilter, after *models.ListCursor, pageSize int, units string) {
+	records, err := h.store.Assessments().ListByPatientPage(c.Request.Context(), patientID, models.AssessmentPageParams{
+		After:      after,
+		Limit:      pageSize + 1,
+		MinQuality: filter.Min,
+		MaxQuality: filter.Max,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list assessments"})
+		return
+	}
+	resp := models.CursorResponse{PageSize: pageSize}
+	if len(records) > pageSize {
+		records = records[:pageSize]
+		last := records[pageSize-1]
+		resp.NextCursor = nextCursor(last.CreatedAt, last.ID)
+	}
+	expressAssessments(records, units)
+	resp.Data = records
+	c.JSON(http.StatusOK, resp)
+}
+
+// qualityFilter narrows an assessment list by data quality score. Once
+// either bound is set, assessments without a score are left out.
+type qualityFilter struct {
+	Min *int `form:"min_quality" binding:"omitempty,gte=0,lte=100"`
+	Max *int `form:"max_quality" binding:"omitempty,gte=0,lte=100"`
+}
+
+func (f qualityFilter) apply(records []models.Assessment) []models.Assessment {
+	if f.Min == nil && f.Max == nil {
+		return records
+	}
+	out := []models.Assessment{}
+	for _, a := range records {
+		if a.Quality == nil ||
+			(f.Min != nil && a.Quality.Score < *f.Min) ||
+			(f.Max != nil && a.Quality.Score > *f.Max) {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// saveExplanation persists the SHAP explanation for an assessment. Failures are
+// logged only: the prediction itself is already stored and remains valid.
+func (h *AssessmentsHandler) saveExplanation(ctx context.Context, id int64, explanation map[string]interface{}) {
+	if err := h.store.Assessments().SetExplanation(ctx, int32(id), explanation); err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to store explanation for assessment %d", id)
+	}
+}
+
+// pinnedExplanation returns the explanation for a stored assessment, produced
+// by the model version that scored it. A missing explanation is recomputed
+// only when the predictor can still serve that exact version; otherwise the
+// explanation is left out with a note, never replaced by the current model's.
+func (h *AssessmentsHandler) pinnedExplanation(ctx context.Context, a models.Assessment) (map[string]interface{}, string) {
+	explanation, err := h.store.Assessments().GetExplanation(ctx, int32(a.ID))
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to load explanation for assessment %d", a.ID)
+		return nil, "The explanation could not be loaded."
+	}
+	if explanation != nil {
+		return explanation, ""
+	}
+	if a.ModelVersion == "" {
+		return nil, "No explanation was recorded and the scoring model is unknown."
+	}
+
+	if pinned, ok := h.predictor.(ml.PinnedExplainer); ok {
+		if explanation, ok := pinned.ExplainWithModel(ctx, a, a.ModelVersion, a.DatasetHash); ok {
+			h.saveExplanation(ctx, a.ID, explanation)
+			return explanation, ""
+		}
+	}
+	return nil, fmt.Sprintf("No explanation was recorded and model %s is no longer available to recompute it.", a.ModelVersion)
+}
+
+// checkPlausibility enforces the caller's clinic validation mode. In strict mode
+// implausible biomarkers are rejected with 422 and per-field errors in the
+// caller's units; in advisory mode they pass through and surface as warnings
+// in the validation status. Returns false if a response has already been written.
+func (h *AssessmentsHandler) checkPlausibility(c *gin.Context, userID int32, a models.Assessment) bool {
+	issues := ml.CheckPlausibility(a)
+	if len(issues) == 0 {
+		return true
+	}
+	if h.validationMode(c.Request.Context(), userID) != models.ValidationModeStrict {
+		return true
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":  "biomarker values out of plausible range",
+		"fields": ml.ExpressFieldErrors(issues, h.units(c, userID)),
+	})
+	return false
+}
+
+// units returns the caller's preferred units, for requests and responses
+func (h *AssessmentsHandler) units(c *gin.Context, userID int32) string {
+	return userPreferences(c.Request.Context(), h.store, userID).Units
+}
+
+// validationMode resolves the caller's effective clinic validation mode.
+func (h *AssessmentsHandler) validationMode(ctx context.Context, userID int32) string {
+	mode, err := h.store.Clinics().ValidationModeForUser(ctx, userID)
+	if err != nil {
+		// Never block writes on a settings lookup failure; fall back to advisory.
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to resolve validation mode for user %d", userID)
+		return models.ValidationModeAdvisory
+	}
+	return mode
+}
+
+// dataQuality scores a on its way to the store.
+func dataQuality(a models.Assessment) *models.DataQuality {
+	q := ml.AssessQuality(a)
+	return &q
+}
+
 func validationStatus(a models.Assessment) string {
 	warnings := []string{}
 	if a.FBS >= 0 {
@@ -210,6 +479,9 @@ func validationStatus(a models.Assessment) string {
 			warnings = append(warnings, "bmi_overweight")
 		}
 	}
+	for _, fe := range ml.CheckPlausibility(a) {
+		warnings = append(warnings, fe.Field+"_out_of_range")
+	}
 	if len(warnings) == 0 {
 		return "ok"
 	}
@@ -243,8 +515,8 @@ func (h *AssessmentsHandler) get(c *gin.Context) {
 		return
 	}
 
-	// Verify patient exists and belongs to user
-	_, err = h.store.Patients().Get(c.Request.Context(), int32(patientID), userID)
+	// Verify patient exists and is owned by or shared with the user
+	_, err = h.store.Patients().GetVisible(c.Request.Context(), int32(patientID), userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
 		return
@@ -257,19 +529,32 @@ func (h *AssessmentsHandler) get(c *gin.Context) {
 		return
 	}
 
-	assessment, err := h.store.Assessments().Get(c.Request.Context(), int32(id))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "assessment not found"})
+	assessment, ok := h.getAssessment(c, userID, patientID, id)
+	if !ok {
 		return
 	}
 
-	// Verify the assessment belongs to the requested patient
-	if assessment.PatientID != patientID {
+	h.attachChain(c, assessment)
+	expressAssessment(assessment, h.units(c, userID))
+	c.JSON(http.StatusOK, assessment)
+}
+
+// getAssessment loads an assessment of patientID that userID can see. It
+// writes the response and returns false when there is none: 404 if the
+// assessment does not exist, belongs to another patient or is not visible
+// to the user, 500 if the lookup fails.
+func (h *AssessmentsHandler) getAssessment(c *gin.Context, userID int32, patientID, assessmentID int64) (*models.Assessment, bool) {
+	a, err := h.store.Assessments().Get(c.Request.Context(), int32(assessmentID), userID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && a.PatientID != patientID) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "assessment not found"})
-		return
+		return nil, false
 	}
-
-	c.JSON(http.StatusOK, assessment)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to load assessment %d", assessmentID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get assessment"})
+		return nil, false
+	}
+	return a, true
 }
 
 func (h *AssessmentsHandler) update(c *gin.Context) {
@@ -293,7 +578,7 @@ func (h *AssessmentsHandler) update(c *gin.Context) {
 	}
 
 	// Verify patient exists and belongs to user
-	_, err = h.store.Patients().Get(c.Request.Context(), int32(patientID), userID)
+	patient, err := h.store.Patients().Get(c.Request.Context(), int32(patientID), userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
 		return
@@ -305,38 +590,58 @@ func (h *AssessmentsHandler) update(c *gin.Context) {
 		return
 	}
 
-	a := models.Assessment{
-		ID:            assessmentID,
-		PatientID:     patientID,
-		FBS:           req.FBS,
-		HbA1c:         req.HbA1c,
-		Cholesterol:   req.Cholesterol,
-		LDL:           req.LDL,
-		HDL:           req.HDL,
-		Triglycerides: req.Triglycerides,
-		Systolic:      req.Systolic,
-		Diastolic:     req.Diastolic,
-		Activity:      req.Activity,
-		HistoryFlag:   req.HistoryFlag,
-		Smoking:       req.Smoking,
-		Hypertension:  req.Hypertension,
-		HeartDisease:  req.HeartDisease,
-		BMI:           req.BMI,
-		ModelVersion:  h.modelVer,
-		DatasetHash:   h.datasetHash,
+	existing, ok := h.getAssessment(c, userID, patientID, assessmentID)
+	if !ok || !refuseStatusDraft(c, existing) {
+		return
+	}
+
+	a := req.toAssessment(patientID)
+	a.ID = assessmentID
+	if a.BMISource == models.BMISourceProvided && a.BMI == existing.BMI {
+		// A BMI sent back unchanged keeps the source it was stored with
+		a.BMISource = existing.BMISource
 	}
+	a.PatientAge = patient.Age
+	// Scored with the medications of when the assessment was taken
+	a.OnMedication = onMedication(c.Request.Context(), h.store, patientID, existing.CreatedAt)
+	a.ModelVersion, a.DatasetHash = h.activeModel(c.Request.Context())
 
 	// Revalidate and re-predict on update
+	if !h.checkPlausibility(c, userID, a) {
+		return
+	}
 	a.ValidationStatus = validationStatus(a)
-	cluster, risk := h.predictor.Predict(a)
-	a.Cluster = cluster
-	a.RiskScore = risk
+	a.Quality = dataQuality(a)
+	explanation := ml.Score(c.Request.Context(), h.predictor, &a, true)
 
-	updated, err := h.store.Assessments().Update(c.Request.Context(), a)
+	if h.immutable {
+		h.amend(c, userID, *existing, a, explanation, nil)
+		return
+	}
+	updated, err := h.store.Assessments().Update(c.Request.Context(), a, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "assessment not found")
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update assessment"})
 		return
 	}
+	// Always overwrite so a stale explanation never outlives its prediction
+	h.saveExplanation(c.Request.Context(), updated.ID, explanation)
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "assessment.update",
+		TargetType: "assessment",
+		TargetID:   int(assessmentID),
+		Details: map[string]interface{}{
+			"patient_id": patientID,
+			"changes":    auditDiff(existing, updated),
+		},
+	})
+	expressAssessment(updated, h.units(c, userID))
 	c.JSON(http.StatusOK, updated)
 }
 
@@ -368,23 +673,91 @@ func (h *AssessmentsHandler) delete(c *gin.Context) {
 	}
 
 	// Verify the assessment exists and belongs to the patient
-	assessment, err := h.store.Assessments().Get(c.Request.Context(), int32(assessmentID))
+	assessment, ok := h.getAssessment(c, userID, patientID, assessmentID)
+	if !ok {
+		return
+	}
+	// Drafts were never part of the record, so they can be discarded
+	if h.immutable && !assessment.Draft() {
+		c.JSON(http.StatusConflict, gin.H{"error": "assessments are append-only; amend the assessment instead of deleting it"})
+		return
+	}
+	// Attachment rows would cascade away but leave their files behind
+	attachments, err := h.store.AssessmentAttachments().List(c.Request.Context(), assessmentID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "assessment not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete assessment"})
 		return
 	}
-	if assessment.PatientID != patientID {
-		c.JSON(http.StatusNotFound, gin.H{"error": "assessment not found"})
+	if len(attachments) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "delete the assessment's attachments first"})
 		return
 	}
 
-	if err := h.store.Assessments().Delete(c.Request.Context(), int32(assessmentID)); err != nil {
+	err = h.store.Assessments().Delete(c.Request.Context(), int32(assessmentID), userID)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "assessment not found")
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete assessment"})
 		return
 	}
+
+	// The deleted values are kept here, as nothing else records them
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "assessment.delete",
+		TargetType: "assessment",
+		TargetID:   int(assessmentID),
+		Details: map[string]interface{}{
+			"patient_id": patientID,
+			"changes":    auditDiff(assessment, models.Assessment{}),
+		},
+	})
 	c.Status(http.StatusNoContent)
 }
 
+// explanation returns the SHAP explanation from the model that scored the assessment
+func (h *AssessmentsHandler) explanation(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	patientID, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient id"})
+		return
+	}
+
+	// Verify patient exists and is owned by or shared with the user
+	_, err = h.store.Patients().GetVisible(c.Request.Context(), int32(patientID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+
+	assessmentID, err := strconv.ParseInt(c.Param("assessmentID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid assessment ID"})
+		return
+	}
+
+	assessment, ok := h.getAssessment(c, userID, patientID, assessmentID)
+	if !ok || !refuseStatusDraft(c, assessment) {
+		return
+	}
+
+	explanation, note := h.pinnedExplanation(c.Request.Context(), *assessment)
+	if explanation == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "explanation not available", "detail": note})
+		return
+	}
+	c.JSON(http.StatusOK, explanation)
+}
+
 // report generates a PDF report for an assessment
 func (h *AssessmentsHandler) report(c *gin.Context) {
 	userID, err := getUserID(c)
@@ -399,8 +772,8 @@ func (h *AssessmentsHandler) report(c *gin.Context) {
 		return
 	}
 
-	// Verify patient exists and belongs to user
-	patient, err := h.store.Patients().Get(c.Request.Context(), int32(patientID), userID)
+	// Verify patient exists and is owned by or shared with the user
+	patient, err := h.store.Patients().GetVisible(c.Request.Context(), int32(patientID), userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
 		return
@@ -413,21 +786,24 @@ func (h *AssessmentsHandler) report(c *gin.Context) {
 		return
 	}
 
-	assessment, err := h.store.Assessments().Get(c.Request.Context(), int32(assessmentID))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "assessment not found"})
+	assessment, ok := h.getAssessment(c, userID, patientID, assessmentID)
+	if !ok || !refuseStatusDraft(c, assessment) {
 		return
 	}
 
-	// Verify the assessment belongs to the patient
-	if assessment.PatientID != patientID {
-		c.JSON(http.StatusNotFound, gin.H{"error": "assessment not found"})
-		return
+	// A missing explanation omits the SHAP section and says why
+	shapData, shapNote := h.pinnedExplanation(c.Request.Context(), *assessment)
+
+	// The charts fall back to this assessment alone if the history fails to load
+	history, err := h.store.Assessments().GetTrend(c.Request.Context(), patientID)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to load trend for report on assessment %d", assessment.ID)
 	}
 
 	// Generate PDF
-	generator := pdf.NewReportGenerator("")
-	pdfBytes, err := generator.GenerateAssessmentReport(*patient, *assessment, nil)
+	variant := h.recommendationVariant(c.Request.Context(), userID, *assessment)
+	generator := reportGenerator(c.Request.Context(), h.store, userID, *patient).WithRecommendations(variant).WithHistory(history)
+	pdfBytes, err := generator.GenerateAssessmentReport(*patient, *assessment, shapData, shapNote)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
 		return
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// WithImmutable makes assessments append-only, as regulated clinics require:
+// PUT and PATCH store an amendment linked to the edited assessment instead of
+// overwriting it, and DELETE is refused.
+func (h *AssessmentsHandler) WithImmutable(immutable bool) *AssessmentsHandler {
+	h.immutable = immutable
+	return h
+}
+
+// amend stores a as an amendment of original and responds 201 with the new
+// assessment and its chain. fields lists the changed fields for the audit
+// event; nil when the whole assessment was replaced.
+func (h *AssessmentsHandler) amend(c *gin.Context, userID int32, original models.Assessment, a models.Assessment, explanation map[string]interface{}, fields []string) {
+	ctx := c.Request.Context()
+	a.ID = 0
+	amendment, err := h.store.Assessments().Amend(ctx, original.ID, a)
+	if errors.Is(err, store.ErrAlreadyAmended) {
+		abortWithError(c, err, "assessment has already been amended; amend the latest version")
+		return
+	}
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to amend assessment %d", original.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to amend assessment"})
+		return
+	}
+	if explanation != nil {
+		h.saveExplanation(ctx, amendment.ID, explanation)
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	details := map[string]interface{}{"amends_assessment_id": original.ID}
+	if fields != nil {
+		details["fields"] = fields
+	}
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "assessment.amend",
+		TargetType: "assessment",
+		TargetID:   int(amendment.ID),
+		Details:    details,
+	})
+	h.events.Publish(ctx, events.AssessmentCreated{
+		Actor:      claims.Email,
+		UserID:     userID,
+		Assessment: *amendment,
+	})
+
+	h.attachChain(c, amendment)
+	expressAssessment(amendment, h.units(c, userID))
+	c.JSON(http.StatusCreated, amendment)
+}
+
+// attachChain sets the amendment chain on a when it has more than one
+// version. A failed lookup is logged and the assessment returned without it.
+func (h *AssessmentsHandler) attachChain(c *gin.Context, a *models.Assessment) {
+	chain, err := h.store.Assessments().AmendmentChain(c.Request.Context(), a.ID)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to load amendment chain of assessment %d", a.ID)
+		return
+	}
+	if len(chain) < 2 {
+		return
+	}
+	for _, v := range chain {
+		if v.ID == a.ID {
+			a.AmendsAssessmentID = v.AmendsAssessmentID
+		}
+	}
+	a.AmendmentChain = chain
+}
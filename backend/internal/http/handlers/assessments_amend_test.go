@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestAssessmentsHandler_Immutable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	original := models.Assessment{ID: 9, PatientID: 7, HbA1c: 5.5, BMI: 24, Smoking: "never", Cluster: "stored", RiskScore: 10}
+	repo := &fakeAssessmentRepo{stored: &original}
+	audit := &fakeAuditRepo{}
+	st := &fakeStore{repo: repo, patientRepo: &fakePatientRepo{}, audit: audit}
+	h := NewAssessmentsHandler(st, ml.NewMockPredictor(), "v1", "hash123").WithImmutable(true)
+
+	r := gin.New()
+	r.Use(middleware.ErrorHandler(), mockAuthMiddleware())
+	h.Register(r.Group("/patients"))
+
+	w := contactRequest(r, http.MethodPatch, "/patients/7/assessments/9", `{"smoking":"former"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("patch: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var amended models.Assessment
+	_ = json.Unmarshal(w.Body.Bytes(), &amended)
+	if amended.ID == 9 || amended.AmendsAssessmentID == nil || *amended.AmendsAssessmentID != 9 {
+		t.Fatalf("expected a new assessment amending 9, got %+v", amended)
+	}
+	if amended.Smoking != "former" || amended.Cluster != "stored" {
+		t.Errorf("amendment should carry the change and keep the prediction: %+v", amended)
+	}
+	if len(amended.AmendmentChain) != 2 || amended.AmendmentChain[0].ID != 9 {
+		t.Errorf("expected chain [9, %d], got %+v", amended.ID, amended.AmendmentChain)
+	}
+	if repo.last.ID != 0 || repo.stored.Smoking != "never" {
+		t.Error("the original must not be updated in place")
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != "assessment.amend" {
+		t.Fatalf("expected an assessment.amend audit event, got %+v", audit.events)
+	}
+
+	// The original now has an amendment, so only the latest version can be amended
+	body := `{"fbs":90,"hba1c":5.5,"bmi":24,"cholesterol":180,"ldl":100,"hdl":55,"triglycerides":120,"systolic":120,"diastolic":80}`
+	if w := contactRequest(r, http.MethodPut, "/patients/7/assessments/9", body); w.Code != http.StatusConflict {
+		t.Fatalf("put on amended assessment: expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = contactRequest(r, http.MethodGet, "/patients/7/assessments/9", "")
+	var got models.Assessment
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	if len(got.AmendmentChain) != 2 {
+		t.Errorf("get: expected the amendment chain, got %+v", got.AmendmentChain)
+	}
+
+	if w := contactRequest(r, http.MethodDelete, "/patients/7/assessments/9", ""); w.Code != http.StatusConflict {
+		t.Errorf("delete: expected 409, got %d", w.Code)
+	}
+}
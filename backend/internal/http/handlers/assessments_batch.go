@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// BatchAssessmentsHandler scores many assessments in one request for research
+// re-scoring of historical cohorts. It shares the predictor and model metadata
+// of the single-assessment handler.
+type BatchAssessmentsHandler struct {
+	*AssessmentsHandler
+	maxItems int
+	workers  int
+}
+
+// NewBatchAssessmentsHandler creates a batch handler that accepts at most maxItems
+// assessments per request and runs predictions on a pool of workers goroutines.
+func NewBatchAssessmentsHandler(ah *AssessmentsHandler, maxItems, workers int) *BatchAssessmentsHandler {
+	if workers < 1 {
+		workers = 1
+	}
+	return &BatchAssessmentsHandler{AssessmentsHandler: ah, maxItems: maxItems, workers: workers}
+}
+
+// Register registers batch routes on the given router group
+func (h *BatchAssessmentsHandler) Register(rg *gin.RouterGroup) {
+	rg.POST("/batch", h.createBatch)
+}
+
+type batchAssessmentItem struct {
+	PatientID int64 `json:"patient_id" binding:"required,gt=0"`
+	assessmentReq
+}
+
+type batchAssessmentReq struct {
+	Assessments []batchAssessmentItem `json:"assessments" binding:"required"`
+}
+
+// batchItemError reports why a single item in a batch was rejected
+type batchItemError struct {
+	Index  int             `json:"index"`
+	Error  string          `json:"error"`
+	Fields []ml.FieldError `json:"fields,omitempty"`
+}
+
+// createBatch validates, scores, and persists a batch of assessments
+// @Summary Batch-score assessments
+// @Description Validates and scores up to BATCH_MAX_ITEMS assessments, persisting all of them in one transaction. If any item is invalid nothing is stored.
+// @Tags Assessments
+// @Accept json
+// @Produce json
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /assessments/batch [post]
+func (h *BatchAssessmentsHandler) createBatch(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req batchAssessmentReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if len(req.Assessments) == 0 || len(req.Assessments) > h.maxItems {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "batch size out of range",
+			"max_items": h.maxItems,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	mode := h.validationMode(ctx, userID)
+	units := userPreferences(ctx, h.store, userID).Units
+	modelVer, datasetHash := h.activeModel(ctx)
+	owned := make(map[int64]*models.Patient)
+	medicated := make(map[int64]bool)
+	var itemErrs []batchItemError
+	items := make([]models.Assessment, len(req.Assessments))
+
+	for i := range req.Assessments {
+		item := req.Assessments[i]
+		if err := binding.Validator.ValidateStruct(&item); err != nil {
+			itemErrs = append(itemErrs, batchItemError{Index: i, Error: "invalid payload"})
+			continue
+		}
+
+		// Verify patient exists and belongs to user (cached per patient)
+		patient, seen := owned[item.PatientID]
+		if !seen {
+			if p, err := h.store.Patients().Get(ctx, int32(item.PatientID), userID); err == nil {
+				patient = p
+				medicated[item.PatientID] = onMedication(ctx, h.store, item.PatientID, time.Now())
+			}
+			owned[item.PatientID] = patient
+		}
+		if patient == nil {
+			itemErrs = append(itemErrs, batchItemError{Index: i, Error: "patient not found"})
+			continue
+		}
+
+		a := item.toAssessment(item.PatientID)
+		a.PatientAge = patient.Age
+		a.OnMedication = medicated[item.PatientID]
+		a.ModelVersion, a.DatasetHash = modelVer, datasetHash
+		if issues := ml.CheckPlausibility(a); len(issues) > 0 && mode == models.ValidationModeStrict {
+			itemErrs = append(itemErrs, batchItemError{Index: i, Error: "biomarker values out of plausible range", Fields: ml.ExpressFieldErrors(issues, units)})
+			continue
+		}
+		a.ValidationStatus = validationStatus(a)
+		a.Quality = dataQuality(a)
+		items[i] = a
+	}
+
+	if len(itemErrs) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "batch validation failed",
+			"errors": itemErrs,
+		})
+		return
+	}
+
+	h.predictAll(ctx, items)
+
+	created, err := h.store.Assessments().CreateBatch(ctx, items)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to create assessment batch")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create assessments"})
+		return
+	}
+
+	expressAssessments(created, units)
+	c.JSON(http.StatusCreated, gin.H{
+		"count":       len(created),
+		"assessments": created,
+	})
+}
+
+// predictAll runs the predictor over every item using a bounded worker pool.
+// Results are written back in place so output order matches input order.
+func (h *BatchAssessmentsHandler) predictAll(ctx context.Context, items []models.Assessment) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	workers := h.workers
+	if workers > len(items) {
+		workers = len(items)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				ml.Score(ctx, h.predictor, &items[i], false)
+			}
+		}()
+	}
+
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+)
+
+func newBatchTestRouter(repo *fakeAssessmentRepo, maxItems int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	ah := NewAssessmentsHandler(&fakeStore{repo: repo, patientRepo: &fakePatientRepo{}}, ml.NewMockPredictor(), "v1", "hash123")
+	h := NewBatchAssessmentsHandler(ah, maxItems, 4)
+
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	h.Register(r.Group("/assessments"))
+	return r
+}
+
+func TestBatchAssessmentsHandler_ScoresAllItemsInOrder(t *testing.T) {
+	repo := &fakeAssessmentRepo{}
+	r := newBatchTestRouter(repo, 10)
+
+	body := bytes.NewBufferString(`{"assessments":[
+		{"patient_id":1,"hba1c":6.2,"bmi":32},
+		{"patient_id":2,"hba1c":7.0,"bmi":24},
+		{"patient_id":3,"hba1c":5.4,"bmi":22}
+	]}`)
+	req, _ := http.NewRequest(http.MethodPost, "/assessments/batch", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(repo.lastBatch) != 3 {
+		t.Fatalf("expected 3 persisted items, got %d", len(repo.lastBatch))
+	}
+	want := []string{"SIRD", "SIDD", "MOD"}
+	for i, a := range repo.lastBatch {
+		if a.Cluster != want[i] {
+			t.Errorf("item %d cluster = %q, want %q", i, a.Cluster, want[i])
+		}
+		if a.ModelVersion != "v1" {
+			t.Errorf("item %d model version = %q, want v1", i, a.ModelVersion)
+		}
+	}
+}
+
+func TestBatchAssessmentsHandler_RejectsWholeBatchOnInvalidItem(t *testing.T) {
+	repo := &fakeAssessmentRepo{}
+	r := newBatchTestRouter(repo, 10)
+
+	body := bytes.NewBufferString(`{"assessments":[
+		{"patient_id":1,"hba1c":6.2,"bmi":32},
+		{"patient_id":2,"hba1c":7.0,"bmi":5}
+	]}`)
+	req, _ := http.NewRequest(http.MethodPost, "/assessments/batch", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"index":1`) {
+		t.Fatalf("expected error for index 1, got %s", w.Body.String())
+	}
+	if repo.lastBatch != nil {
+		t.Fatalf("expected nothing persisted, got %d items", len(repo.lastBatch))
+	}
+}
+
+func TestBatchAssessmentsHandler_EnforcesMaxItems(t *testing.T) {
+	r := newBatchTestRouter(&fakeAssessmentRepo{}, 1)
+
+	body := bytes.NewBufferString(`{"assessments":[{"patient_id":1,"bmi":22},{"patient_id":2,"bmi":22}]}`)
+	req, _ := http.NewRequest(http.MethodPost, "/assessments/batch", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
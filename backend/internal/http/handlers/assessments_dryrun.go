@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// dryRunResult is the preview returned by POST /patients/:id/assessments:dryRun
+type dryRunResult struct {
+	// Assessment is exactly what create would store, minus ID and timestamps
+	Assessment     models.Assessment      `json:"assessment"`
+	Warnings       []string               `json:"warnings"`
+	Issues         []ml.FieldError        `json:"issues,omitempty"`
+	ValidationMode string                 `json:"validation_mode"`
+	WouldReject    bool                   `json:"would_reject"`
+	RiskLevel      string                 `json:"risk_level"`
+	Explanation    map[string]interface{} `json:"explanation,omitempty"`
+}
+
+// assessmentAction dispatches custom-method routes of the form
+// /:id/assessments:<verb>. Gin cannot register a literal colon in a path
+// segment, so the verb arrives as a parameter including its leading colon.
+func (h *AssessmentsHandler) assessmentAction(c *gin.Context) {
+	switch c.Param("action") {
+	case ":dryRun":
+		h.dryRun(c)
+	case ":saveDraft":
+		h.saveStatusDraft(c)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	}
+}
+
+// dryRun previews an assessment without persisting it
+// @Summary Preview an assessment
+// @Description Runs validation and prediction on the payload and returns what would be stored, without writing anything. Strict-mode plausibility failures are reported via would_reject instead of a 422.
+// @Tags Assessments
+// @Accept json
+// @Produce json
+// @Param id path int true "Patient ID"
+// @Param request body assessmentReq true "Assessment payload"
+// @Success 200 {object} dryRunResult
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /patients/{id}/assessments:dryRun [post]
+func (h *AssessmentsHandler) dryRun(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	patientID, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// Verify patient exists and belongs to user
+	patient, err := h.store.Patients().Get(ctx, int32(patientID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+
+	var req assessmentReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	a := req.toAssessment(patientID)
+	a.PatientAge = patient.Age
+	a.OnMedication = onMedication(ctx, h.store, patientID, time.Now())
+	a.ModelVersion, a.DatasetHash = h.activeModel(ctx)
+	a.ValidationStatus = validationStatus(a)
+	a.Quality = dataQuality(a)
+
+	units := h.units(c, userID)
+	res := dryRunResult{
+		Warnings:       statusWarnings(a.ValidationStatus),
+		Issues:         ml.ExpressFieldErrors(ml.CheckPlausibility(a), units),
+		ValidationMode: h.validationMode(ctx, userID),
+	}
+	res.WouldReject = len(res.Issues) > 0 && res.ValidationMode == models.ValidationModeStrict
+
+	// A record that would be rejected is never scored, so don't call the model
+	if !res.WouldReject {
+		res.Explanation = ml.Score(ctx, h.predictor, &a, true)
+		res.RiskLevel = riskLevel(a.RiskScore)
+	}
+	res.Assessment = a
+	expressAssessment(&res.Assessment, units)
+
+	c.JSON(http.StatusOK, res)
+}
+
+// statusWarnings splits a "warning:a,b" validation status into its codes
+func statusWarnings(status string) []string {
+	codes, ok := strings.CutPrefix(status, "warning:")
+	if !ok || codes == "" {
+		return []string{}
+	}
+	return strings.Split(codes, ",")
+}
+
+// riskLevel buckets a 0-100 risk score using the cohort report thresholds
+func riskLevel(score int) string {
+	switch {
+	case score >= 67:
+		return "High"
+	case score >= 34:
+		return "Moderate"
+	default:
+		return "Low"
+	}
+}
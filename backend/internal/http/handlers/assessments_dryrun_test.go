@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestAssessmentsHandler_DryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		path        string
+		mode        string
+		body        string
+		wantCode    int
+		wantReject  bool
+		wantCluster string
+		wantLevel   string
+	}{
+		{
+			name:        "previews prediction",
+			path:        "/patients/7/assessments:dryRun",
+			mode:        models.ValidationModeAdvisory,
+			body:        `{"fbs":130,"hba1c":6.8,"bmi":32}`,
+			wantCode:    http.StatusOK,
+			wantCluster: "SIRD",
+			wantLevel:   "High",
+		},
+		{
+			name:       "strict mode reports rejection",
+			path:       "/patients/7/assessments:dryRun",
+			mode:       models.ValidationModeStrict,
+			body:       `{"fbs":6.1,"hba1c":5.5,"bmi":24}`,
+			wantCode:   http.StatusOK,
+			wantReject: true,
+		},
+		{name: "unknown verb", path: "/patients/7/assessments:commit", body: `{}`, wantCode: http.StatusNotFound},
+		{name: "invalid payload", path: "/patients/7/assessments:dryRun", body: `{"bmi":5}`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeAssessmentRepo{}
+			st := &fakeStore{repo: repo, patientRepo: &fakePatientRepo{}, clinicRepo: &fakeClinicRepo{mode: tc.mode}}
+			h := NewAssessmentsHandler(st, ml.NewMockPredictor(), "v1", "hash123")
+
+			r := gin.New()
+			r.Use(mockAuthMiddleware())
+			h.Register(r.Group("/patients"))
+
+			req, _ := http.NewRequest(http.MethodPost, tc.path, bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, w.Code, w.Body.String())
+			}
+			if repo.last.PatientID != 0 {
+				t.Fatalf("dry run must not persist, stored %+v", repo.last)
+			}
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+
+			var got dryRunResult
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid json: %v", err)
+			}
+			if got.WouldReject != tc.wantReject {
+				t.Errorf("would_reject = %v, want %v", got.WouldReject, tc.wantReject)
+			}
+			if got.Assessment.Cluster != tc.wantCluster || got.RiskLevel != tc.wantLevel {
+				t.Errorf("got cluster=%q level=%q, want %q/%q", got.Assessment.Cluster, got.RiskLevel, tc.wantCluster, tc.wantLevel)
+			}
+			if got.Assessment.ModelVersion != "v1" {
+				t.Errorf("model version = %q, want v1", got.Assessment.ModelVersion)
+			}
+		})
+	}
+}
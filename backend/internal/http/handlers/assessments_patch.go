@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// predictiveFields are the assessment inputs the clustering model scores on
+// (see ml/clustering.py). Edits to other fields keep the stored prediction.
+var predictiveFields = map[string]bool{
+	"fbs":           true,
+	"hba1c":         true,
+	"bmi":           true,
+	"triglycerides": true,
+	"ldl":           true,
+	"hdl":           true,
+}
+
+// assessmentPatchReq is the sparse counterpart of assessmentReq: nil fields are
+// left untouched, so clients only send what changed.
+type assessmentPatchReq struct {
+	Units         string   `json:"units" binding:"omitempty,oneof=mg/dL mmol/L"`
+	FBS           *float64 `json:"fbs" binding:"omitempty,gte=0,lte=1000"`
+	HbA1c         *float64 `json:"hba1c" binding:"omitempty,gte=0,lte=20"`
+	Cholesterol   *float64 `json:"cholesterol" binding:"omitempty,gte=0,lte=1000"`
+	LDL           *float64 `json:"ldl" binding:"omitempty,gte=0,lte=500"`
+	HDL           *float64 `json:"hdl" binding:"omitempty,gte=0,lte=200"`
+	Triglycerides *float64 `json:"triglycerides" binding:"omitempty,gte=0,lte=2000"`
+	Systolic      *int     `json:"systolic" binding:"omitempty,gte=0,lte=300"`
+	Diastolic     *int     `json:"diastolic" binding:"omitempty,gte=0,lte=200"`
+	Activity      *string  `json:"activity" binding:"omitempty,max=50,oneof='' 'sedentary' 'light' 'moderate' 'active' 'very_active'"`
+	HistoryFlag   *bool    `json:"history_flag"`
+	Smoking       *string  `json:"smoking" binding:"omitempty,max=20,oneof='' 'never' 'former' 'current'"`
+	Hypertension  *string  `json:"hypertension" binding:"omitempty,max=10,oneof='' 'yes' 'no'"`
+	HeartDisease  *string  `json:"heart_disease" binding:"omitempty,max=10,oneof='' 'yes' 'no'"`
+	BMI           *float64 `json:"bmi" binding:"omitempty,gte=10,lte=100"`
+	HeightCM      *float64 `json:"height_cm" binding:"omitempty,gte=50,lte=250"`
+	WeightKG      *float64 `json:"weight_kg" binding:"omitempty,gte=10,lte=400"`
+	SelfReported  *bool    `json:"self_reported"`
+}
+
+// apply merges the non-nil fields, converted from r.Units, into a and
+// returns the JSON names of the fields whose value actually changed.
+func (r assessmentPatchReq) apply(a *models.Assessment) []string {
+	var changed []string
+	patchField(&changed, "fbs", &a.FBS, storedFloat("fbs", r.FBS, r.Units))
+	patchField(&changed, "hba1c", &a.HbA1c, r.HbA1c)
+	patchField(&changed, "cholesterol", &a.Cholesterol, storedInt("cholesterol", r.Cholesterol, r.Units))
+	patchField(&changed, "ldl", &a.LDL, storedInt("ldl", r.LDL, r.Units))
+	patchField(&changed, "hdl", &a.HDL, storedInt("hdl", r.HDL, r.Units))
+	patchField(&changed, "triglycerides", &a.Triglycerides, storedInt("triglycerides", r.Triglycerides, r.Units))
+	patchField(&changed, "systolic", &a.Systolic, r.Systolic)
+	patchField(&changed, "diastolic", &a.Diastolic, r.Diastolic)
+	patchField(&changed, "activity", &a.Activity, r.Activity)
+	patchField(&changed, "history_flag", &a.HistoryFlag, r.HistoryFlag)
+	patchField(&changed, "smoking", &a.Smoking, r.Smoking)
+	patchField(&changed, "hypertension", &a.Hypertension, r.Hypertension)
+	patchField(&changed, "heart_disease", &a.HeartDisease, r.HeartDisease)
+	bmi := a.BMI
+	patchField(&changed, "bmi", &a.BMI, r.BMI)
+	patchField(&changed, "height_cm", &a.HeightCM, r.HeightCM)
+	patchField(&changed, "weight_kg", &a.WeightKG, r.WeightKG)
+	patchField(&changed, "self_reported", &a.SelfReported, r.SelfReported)
+	return rederiveBMI(changed, bmi, &a.BMI, &a.BMISource, a.HeightCM, a.WeightKG)
+}
+
+// rederiveBMI recomputes a patched record's BMI from its merged height and
+// weight, as models.DeriveBMI does on create, and keeps "bmi" in changed in
+// step with whether the stored value moved from before.
+func rederiveBMI(changed []string, before float64, bmi *float64, source *string, heightCM, weightKG float64) []string {
+	if !slices.Contains(changed, "bmi") && !slices.Contains(changed, "height_cm") && !slices.Contains(changed, "weight_kg") {
+		return changed
+	}
+	*bmi, *source = models.DeriveBMI(*bmi, heightCM, weightKG)
+	changed = slices.DeleteFunc(changed, func(name string) bool { return name == "bmi" })
+	if *bmi != before {
+		changed = append(changed, "bmi")
+	}
+	return changed
+}
+
+func patchField[T comparable](changed *[]string, name string, dst *T, src *T) {
+	if src == nil || *dst == *src {
+		return
+	}
+	*dst = *src
+	*changed = append(*changed, name)
+}
+
+// patch applies a sparse update to an assessment
+// @Summary Partially update an assessment
+// @Description Merges the given fields into the stored assessment. Validation is re-run for any change; the model is only re-run when a predictive biomarker changed. Drafts are saved as they are, without validation or prediction.
+// @Tags Assessments
+// @Accept json
+// @Produce json
+// @Param id path int true "Patient ID"
+// @Param assessmentID path int true "Assessment ID"
+// @Param request body assessmentPatchReq true "Fields to change"
+// @Success 200 {object} models.Assessment
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /patients/{id}/assessments/{assessmentID} [patch]
+func (h *AssessmentsHandler) patch(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	patientID, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient id"})
+		return
+	}
+
+	assessmentID, err := strconv.ParseInt(c.Param("assessmentID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid assessment ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// Verify patient exists and belongs to user
+	patient, err := h.store.Patients().Get(ctx, int32(patientID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+
+	existing, ok := h.getAssessment(c, userID, patientID, assessmentID)
+	if !ok {
+		return
+	}
+
+	var req assessmentPatchReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	a := *existing
+	changed := req.apply(&a)
+	if len(changed) == 0 {
+		expressAssessment(existing, h.units(c, userID))
+		c.JSON(http.StatusOK, existing)
+		return
+	}
+	if existing.Draft() {
+		h.patchStatusDraft(c, userID, *existing, a, changed)
+		return
+	}
+
+	if !h.checkPlausibility(c, userID, a) {
+		return
+	}
+	a.ValidationStatus = validationStatus(a)
+	a.Quality = dataQuality(a)
+
+	repredict := false
+	for _, f := range changed {
+		if predictiveFields[f] {
+			repredict = true
+			break
+		}
+	}
+	var explanation map[string]interface{}
+	if repredict {
+		a.ModelVersion, a.DatasetHash = h.activeModel(ctx)
+		a.PatientAge = patient.Age
+		a.OnMedication = onMedication(ctx, h.store, patientID, a.CreatedAt)
+		explanation = ml.Score(ctx, h.predictor, &a, true)
+	}
+
+	if h.immutable {
+		if !repredict {
+			// The amendment keeps the original's prediction, so its explanation too
+			explanation, _ = h.store.Assessments().GetExplanation(ctx, int32(assessmentID))
+		}
+		h.amend(c, userID, *existing, a, explanation, changed)
+		return
+	}
+	updated, err := h.store.Assessments().Update(ctx, a, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "assessment not found")
+		return
+	}
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to patch assessment %d", assessmentID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update assessment"})
+		return
+	}
+	if repredict {
+		h.saveExplanation(ctx, updated.ID, explanation)
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "assessment.patch",
+		TargetType: "assessment",
+		TargetID:   int(assessmentID),
+		Details: map[string]interface{}{
+			"fields":      changed,
+			"repredicted": repredict,
+		},
+	})
+
+	expressAssessment(updated, h.units(c, userID))
+	c.JSON(http.StatusOK, updated)
+}
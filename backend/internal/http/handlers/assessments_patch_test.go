@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestAssessmentsHandler_Patch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		body          string
+		wantCode      int
+		wantCluster   string
+		wantFields    []string
+		wantRepredict bool
+	}{
+		{
+			name:          "predictive field re-runs model",
+			body:          `{"hba1c":7.0}`,
+			wantCode:      http.StatusOK,
+			wantCluster:   "SIDD",
+			wantFields:    []string{"hba1c"},
+			wantRepredict: true,
+		},
+		{
+			name:        "non-predictive field keeps prediction",
+			body:        `{"smoking":"former","systolic":120}`,
+			wantCode:    http.StatusOK,
+			wantCluster: "stored",
+			wantFields:  []string{"systolic", "smoking"},
+		},
+		{
+			name:        "unchanged values are a no-op",
+			body:        `{"hba1c":5.5}`,
+			wantCode:    http.StatusOK,
+			wantCluster: "stored",
+		},
+		{
+			name:     "invalid value rejected",
+			body:     `{"bmi":5}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeAssessmentRepo{stored: &models.Assessment{
+				ID: 9, PatientID: 7, HbA1c: 5.5, BMI: 24, Smoking: "never", Cluster: "stored", RiskScore: 10,
+			}}
+			audit := &fakeAuditRepo{}
+			st := &fakeStore{repo: repo, patientRepo: &fakePatientRepo{}, audit: audit}
+			h := NewAssessmentsHandler(st, ml.NewMockPredictor(), "v1", "hash123")
+
+			r := gin.New()
+			r.Use(mockAuthMiddleware())
+			h.Register(r.Group("/patients"))
+
+			req, _ := http.NewRequest(http.MethodPatch, "/patients/7/assessments/9", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, w.Code, w.Body.String())
+			}
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+			if len(tc.wantFields) == 0 {
+				if len(audit.events) != 0 {
+					t.Fatalf("expected no audit event for no-op patch, got %d", len(audit.events))
+				}
+				return
+			}
+			if repo.last.Cluster != tc.wantCluster {
+				t.Errorf("cluster = %q, want %q", repo.last.Cluster, tc.wantCluster)
+			}
+			if repo.last.BMI != 24 {
+				t.Errorf("omitted field was overwritten: bmi = %v", repo.last.BMI)
+			}
+			if len(audit.events) != 1 {
+				t.Fatalf("expected 1 audit event, got %d", len(audit.events))
+			}
+			details := audit.events[0].Details
+			fields := details["fields"].([]string)
+			if len(fields) != len(tc.wantFields) {
+				t.Fatalf("fields = %v, want %v", fields, tc.wantFields)
+			}
+			for i := range fields {
+				if fields[i] != tc.wantFields[i] {
+					t.Errorf("fields = %v, want %v", fields, tc.wantFields)
+				}
+			}
+			if details["repredicted"] != tc.wantRepredict {
+				t.Errorf("repredicted = %v, want %v", details["repredicted"], tc.wantRepredict)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// Draft assessments, assessments with status draft, let a clinician save
+// what has been entered so far and come back to it. They are stored without
+// validation, quality scoring or a prediction; all of that runs once, when
+// the draft is finalized. They are not the lab-result drafts of
+// assessment_drafts.go, which are HL7 results waiting to become an
+// assessment; identifiers here say StatusDraft to keep the two apart.
+
+// saveStatusDraft stores a new draft assessment
+// @Summary Save a draft assessment
+// @Description Stores whatever fields are given as a draft. Nothing is validated beyond the field ranges and no prediction is made; finalize the draft to score it.
+// @Tags Assessments
+// @Accept json
+// @Produce json
+// @Param id path int true "Patient ID"
+// @Param request body assessmentPatchReq true "Fields entered so far"
+// @Success 201 {object} models.Assessment
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /patients/{id}/assessments:saveDraft [post]
+func (h *AssessmentsHandler) saveStatusDraft(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	patientID, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient id"})
+		return
+	}
+
+	// Verify patient exists and belongs to user
+	if _, err := h.store.Patients().Get(c.Request.Context(), int32(patientID), userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+
+	var req assessmentPatchReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	a := models.Assessment{PatientID: patientID, Status: models.AssessmentStatusDraft}
+	req.apply(&a)
+	created, err := h.store.Assessments().Create(c.Request.Context(), a)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to save draft assessment")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save draft"})
+		return
+	}
+
+	// assessment.created waits for finalize, so the draft is audited here
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "assessment.save_draft",
+		TargetType: "assessment",
+		TargetID:   int(created.ID),
+		Details:    map[string]interface{}{"patient_id": patientID},
+	})
+	expressAssessment(created, h.units(c, userID))
+	c.JSON(http.StatusCreated, created)
+}
+
+// patchStatusDraft merges the changed fields of a PATCH into a draft, which
+// stays a draft: nothing is validated or predicted until it is finalized.
+func (h *AssessmentsHandler) patchStatusDraft(c *gin.Context, userID int32, existing models.Assessment, a models.Assessment, changed []string) {
+	ctx := c.Request.Context()
+	updated, err := h.store.Assessments().UpdateDraft(ctx, a, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusConflict, gin.H{"error": "assessment is no longer a draft"})
+		return
+	}
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to save draft assessment %d", existing.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save draft"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "assessment.patch",
+		TargetType: "assessment",
+		TargetID:   int(existing.ID),
+		Details: map[string]interface{}{
+			"fields": changed,
+			"draft":  true,
+		},
+	})
+	expressAssessment(updated, h.units(c, userID))
+	c.JSON(http.StatusOK, updated)
+}
+
+// finalizeStatusDraft validates and scores a draft and marks it final
+// @Summary Finalize a draft assessment
+// @Description Runs the checks of assessment creation on the draft as saved: the draft must be complete and, in strict validation mode, plausible. It is then scored and counted in analytics. Answers 202 when predictions run asynchronously.
+// @Tags Assessments
+// @Produce json
+// @Param id path int true "Patient ID"
+// @Param assessmentID path int true "Assessment ID"
+// @Success 200 {object} models.Assessment
+// @Success 202 {object} models.Assessment
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /patients/{id}/assessments/{assessmentID}/finalize [post]
+func (h *AssessmentsHandler) finalizeStatusDraft(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	patientID, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient id"})
+		return
+	}
+
+	assessmentID, err := strconv.ParseInt(c.Param("assessmentID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid assessment ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// Verify patient exists and belongs to user
+	patient, err := h.store.Patients().Get(ctx, int32(patientID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+
+	draft, ok := h.getAssessment(c, userID, patientID, assessmentID)
+	if !ok {
+		return
+	}
+	if !draft.Draft() {
+		c.JSON(http.StatusConflict, gin.H{"error": "assessment is already final"})
+		return
+	}
+
+	// The draft must pass the same checks as a newly created assessment
+	entered := statusDraftReq(*draft)
+	if err := binding.Validator.ValidateStruct(&entered); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "draft is incomplete or invalid; fix it before finalizing"})
+		return
+	}
+	a := entered.toAssessment(patientID)
+	a.ID = draft.ID
+	a.PatientAge = patient.Age
+	a.OnMedication = onMedication(ctx, h.store, patientID, draft.CreatedAt)
+	a.ModelVersion, a.DatasetHash = h.activeModel(ctx)
+	if !h.checkPlausibility(c, userID, a) {
+		return
+	}
+	a.ValidationStatus = validationStatus(a)
+	a.Quality = dataQuality(a)
+
+	var explanation map[string]interface{}
+	if h.predictions != nil {
+		a.Cluster, a.RiskScore = models.ClusterPendingPrediction, 0
+	} else {
+		explanation = ml.Score(ctx, h.predictor, &a, true)
+	}
+	finalized, err := h.store.Assessments().Finalize(ctx, a, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusConflict, gin.H{"error": "assessment is already final"})
+		return
+	}
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to finalize assessment %d", assessmentID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to finalize assessment"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "assessment.finalize",
+		TargetType: "assessment",
+		TargetID:   int(assessmentID),
+		Details:    map[string]interface{}{"patient_id": patientID},
+	})
+
+	status := http.StatusOK
+	if h.predictions != nil {
+		// assessment.created is published by the queue once the prediction is stored
+		queued := *finalized
+		queued.PatientAge, queued.OnMedication = a.PatientAge, a.OnMedication
+		h.predictions.Enqueue(queued, claims.Email, userID)
+		c.Header("Location", fmt.Sprintf("/api/v1/patients/%d/assessments/%d", patientID, finalized.ID))
+		status = http.StatusAccepted
+	} else {
+		if explanation != nil {
+			h.saveExplanation(ctx, finalized.ID, explanation)
+		}
+		h.events.Publish(ctx, events.AssessmentCreated{
+			Actor:      claims.Email,
+			UserID:     userID,
+			Assessment: *finalized,
+		})
+	}
+	expressAssessment(finalized, h.units(c, userID))
+	c.JSON(status, finalized)
+}
+
+// statusDraftReq restates a stored draft as a request in mg/dL, for
+// validation
+func statusDraftReq(a models.Assessment) assessmentReq {
+	return assessmentReq{
+		FBS:           a.FBS,
+		HbA1c:         a.HbA1c,
+		Cholesterol:   float64(a.Cholesterol),
+		LDL:           float64(a.LDL),
+		HDL:           float64(a.HDL),
+		Triglycerides: float64(a.Triglycerides),
+		Systolic:      a.Systolic,
+		Diastolic:     a.Diastolic,
+		Activity:      a.Activity,
+		HistoryFlag:   a.HistoryFlag,
+		Smoking:       a.Smoking,
+		Hypertension:  a.Hypertension,
+		HeartDisease:  a.HeartDisease,
+		BMI:           a.BMI,
+		HeightCM:      a.HeightCM,
+		WeightKG:      a.WeightKG,
+		SelfReported:  a.SelfReported,
+	}
+}
+
+// refuseStatusDraft answers 409 for reads and writes that need a final
+// assessment. Returns false if a response has been written.
+func refuseStatusDraft(c *gin.Context, a *models.Assessment) bool {
+	if !a.Draft() {
+		return true
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "assessment is a draft; finalize it first"})
+	return false
+}
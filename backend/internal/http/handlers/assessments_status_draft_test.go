@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+func TestAssessmentsHandler_StatusDraftLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	mem := store.NewMemoryStore()
+	user, _ := mem.Users().Create(ctx, models.User{Email: "test@example.com", Role: "clinician", IsActive: true})
+	patient, _ := mem.Patients().Create(ctx, models.Patient{UserID: user.ID, Name: "Ana", Age: 52})
+
+	h := NewAssessmentsHandler(mem, ml.NewMockPredictor(), "v1", "hash123").WithImmutable(true)
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	h.Register(r.Group("/patients"))
+	base := fmt.Sprintf("/patients/%d/assessments", patient.ID)
+	do := func(method, path, body string) (*httptest.ResponseRecorder, models.Assessment) {
+		t.Helper()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var a models.Assessment
+		_ = json.Unmarshal(w.Body.Bytes(), &a)
+		return w, a
+	}
+
+	// A draft is stored as entered, without validation or a prediction
+	w, draft := do(http.MethodPost, base+":saveDraft", `{"hba1c":6.1,"fbs":104}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("save draft: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if !draft.Draft() || draft.Cluster != "" || draft.ValidationStatus != "" || draft.Quality != nil {
+		t.Fatalf("draft was validated or scored: %+v", draft)
+	}
+	path := fmt.Sprintf("%s/%d", base, draft.ID)
+	if n, _ := mem.Cohort().TotalAssessmentCount(ctx); n != 0 {
+		t.Errorf("drafts counted in analytics: %d", n)
+	}
+
+	// Without a BMI the draft cannot be finalized yet
+	if w, _ := do(http.MethodPost, path+"/finalize", ``); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("finalize incomplete draft: expected 422, got %d: %s", w.Code, w.Body.String())
+	}
+	for _, method := range []string{http.MethodPut, http.MethodGet} {
+		target := path
+		if method == http.MethodGet {
+			target += "/report"
+		}
+		if w, _ := do(method, target, `{"bmi":27}`); w.Code != http.StatusConflict {
+			t.Errorf("%s %s on a draft: expected 409, got %d", method, target, w.Code)
+		}
+	}
+
+	// PATCH keeps it a draft, even with immutable assessments
+	w, patched := do(http.MethodPatch, path, `{"bmi":27}`)
+	if w.Code != http.StatusOK || !patched.Draft() || patched.BMI != 27 || patched.HbA1c != 6.1 || patched.ID != draft.ID {
+		t.Fatalf("patch draft: %d %+v", w.Code, patched)
+	}
+
+	w, final := do(http.MethodPost, path+"/finalize", ``)
+	if w.Code != http.StatusOK {
+		t.Fatalf("finalize: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if final.Draft() || final.Cluster == "" || final.ValidationStatus == "" || final.Quality == nil || final.ModelVersion != "v1" {
+		t.Errorf("finalized assessment was not validated and scored: %+v", final)
+	}
+	if n, _ := mem.Cohort().TotalAssessmentCount(ctx); n != 1 {
+		t.Errorf("finalized assessment not counted in analytics: %d", n)
+	}
+	if w, _ := do(http.MethodPost, path+"/finalize", ``); w.Code != http.StatusConflict {
+		t.Errorf("finalize twice: expected 409, got %d", w.Code)
+	}
+
+	// Immutable assessments cannot be deleted, but drafts can be discarded
+	if w, _ := do(http.MethodDelete, path, ``); w.Code != http.StatusConflict {
+		t.Errorf("delete final: expected 409, got %d", w.Code)
+	}
+	_, other := do(http.MethodPost, base+":saveDraft", `{}`)
+	if w, _ := do(http.MethodDelete, fmt.Sprintf("%s/%d", base, other.ID), ``); w.Code != http.StatusNoContent {
+		t.Errorf("discard draft: expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+}
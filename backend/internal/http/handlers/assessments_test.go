@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/skufu/DianaV2/backend/internal/http/middleware"
 	"github.com/skufu/DianaV2/backend/internal/ml"
 	"github.com/skufu/DianaV2/backend/internal/models"
@@ -132,22 +134,452 @@ func TestAssessmentsHandler_Create_HTTPPredictorError(t *testing.T) {
 	}
 }
 
+func TestAssessmentsHandler_Create_StoresExplanation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	modelSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/predict/explain" {
+			t.Fatalf("expected /predict/explain, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"risk_cluster": "SIRD",
+			"risk_score":   80,
+			"explanation": map[string]interface{}{
+				"base_value":     0.4,
+				"shap_values":    []float64{0.25, -0.1},
+				"feature_values": []float64{31.5, 60},
+				"feature_names":  []string{"bmi", "hdl"},
+			},
+		})
+	}))
+	defer modelSrv.Close()
+
+	repo := &fakeAssessmentRepo{}
+	h := NewAssessmentsHandler(&fakeStore{repo: repo, patientRepo: &fakePatientRepo{}}, ml.NewHTTPPredictor(modelSrv.URL+"/predict", "v1", defaultTestTimeout), "v1", "hash123")
+
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	h.Register(r.Group("/patients"))
+
+	body := bytes.NewBufferString(`{"fbs":95,"bmi":31.5,"hdl":60}`)
+	req, _ := http.NewRequest(http.MethodPost, "/patients/3/assessments", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if repo.last.Cluster != "SIRD" || repo.last.RiskScore != 80 {
+		t.Fatalf("expected explained prediction stored, got cluster=%s risk=%d", repo.last.Cluster, repo.last.RiskScore)
+	}
+
+	created := repo.last
+	created.ID = 1
+	repo.stored = &created
+
+	req, _ = http.NewRequest(http.MethodGet, "/patients/3/assessments/1/explanation", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var got struct {
+		BaseValue  float64 `json:"base_value"`
+		ShapValues []struct {
+			Feature   string  `json:"feature"`
+			ShapValue float64 `json:"shap_value"`
+		} `json:"shap_values"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if len(got.ShapValues) != 2 || got.ShapValues[0].Feature != "bmi" || got.ShapValues[0].ShapValue != 0.25 {
+		t.Fatalf("unexpected explanation: %+v", got)
+	}
+}
+
+func TestAssessmentsHandler_Explanation_NotAvailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := &fakeAssessmentRepo{stored: &models.Assessment{ID: 4, PatientID: 9}}
+	h := NewAssessmentsHandler(&fakeStore{repo: repo, patientRepo: &fakePatientRepo{}}, ml.NewMockPredictor(), "v1", "hash123")
+
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	h.Register(r.Group("/patients"))
+
+	req, _ := http.NewRequest(http.MethodGet, "/patients/9/assessments/4/explanation", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAssessmentsHandler_ScopedToUser(t *testing.T) {
+	// The patient check passes, as it would after a handler regression, but
+	// the assessment belongs to user 2's patient and the caller is user 1
+	repo := &fakeAssessmentRepo{stored: &models.Assessment{ID: 4, PatientID: 9, HbA1c: 6.1}, owner: 2}
+	r := baselineRouter(&fakeStore{repo: repo, patientRepo: &fakePatientRepo{}})
+
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodGet, "/patients/9/assessments/4", ""},
+		{http.MethodGet, "/patients/9/assessments/4/report", ""},
+		{http.MethodPut, "/patients/9/assessments/4", `{"fbs":100,"bmi":24}`},
+		{http.MethodPatch, "/patients/9/assessments/4", `{"fbs":100}`},
+		{http.MethodDelete, "/patients/9/assessments/4", ""},
+	} {
+		if w := contactRequest(r, req.method, req.path, req.body); w.Code != http.StatusNotFound || !bytes.Contains(w.Body.Bytes(), []byte("assessment not found")) {
+			t.Fatalf("%s %s: expected 404, got %d: %s", req.method, req.path, w.Code, w.Body.String())
+		}
+	}
+
+	repo.owner = 0
+	repo.getErr = errors.New("connection reset")
+	if w := contactRequest(r, http.MethodGet, "/patients/9/assessments/4", ""); w.Code != http.StatusInternalServerError {
+		t.Fatalf("failed lookup: expected 500, got %d", w.Code)
+	}
+}
+
+func TestAssessmentsHandler_Explanation_PinnedToStoredModel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	served := "v1"
+	modelSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Model-Version") != "v1" || r.Header.Get("X-Dataset-Hash") != "hash-old" {
+			t.Errorf("expected the assessment's model to be requested, got %v", r.Header)
+		}
+		w.Header().Set("X-Model-Version", served)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"risk_cluster": "MOD",
+			"risk_score":   40,
+			"explanation":  map[string]interface{}{"base_value": 0.3, "shap_values": []float64{0.2}, "feature_values": []float64{29}, "feature_names": []string{"bmi"}},
+		})
+	}))
+	defer modelSrv.Close()
+
+	for _, tc := range []struct {
+		served string
+		want   int
+	}{
+		{"v1", http.StatusOK},
+		// The service moved on to v2: its explanation must not be passed off as v1's
+		{"v2", http.StatusNotFound},
+	} {
+		served = tc.served
+		repo := &fakeAssessmentRepo{stored: &models.Assessment{ID: 4, PatientID: 9, ModelVersion: "v1", DatasetHash: "hash-old"}}
+		predictor := ml.NewHTTPPredictor(modelSrv.URL+"/predict", "v2", defaultTestTimeout)
+		h := NewAssessmentsHandler(&fakeStore{repo: repo, patientRepo: &fakePatientRepo{}}, predictor, "v2", "hash-new")
+
+		r := gin.New()
+		r.Use(mockAuthMiddleware())
+		h.Register(r.Group("/patients"))
+
+		req, _ := http.NewRequest(http.MethodGet, "/patients/9/assessments/4/explanation", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("served %s: expected status %d, got %d: %s", tc.served, tc.want, w.Code, w.Body.String())
+		}
+		if saved := repo.explanations[4] != nil; saved != (tc.want == http.StatusOK) {
+			t.Errorf("served %s: explanation saved = %v", tc.served, saved)
+		}
+	}
+}
+
+func TestAssessmentsHandler_Create_ValidationMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name       string
+		mode       string
+		wantStatus int
+	}{
+		{name: "strict rejects implausible values", mode: models.ValidationModeStrict, wantStatus: http.StatusUnprocessableEntity},
+		{name: "advisory stores with warnings", mode: models.ValidationModeAdvisory, wantStatus: http.StatusCreated},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeAssessmentRepo{}
+			st := &fakeStore{repo: repo, patientRepo: &fakePatientRepo{}, clinicRepo: &fakeClinicRepo{mode: tc.mode}}
+			h := NewAssessmentsHandler(st, ml.NewMockPredictor(), "v1", "hash123")
+
+			r := gin.New()
+			r.Use(mockAuthMiddleware())
+			r.POST("/:id/assessments", h.create)
+
+			body := bytes.NewBufferString(`{"fbs":6.1,"hba1c":5.5,"bmi":24}`)
+			req, _ := http.NewRequest(http.MethodPost, "/7/assessments", body)
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+			if tc.wantStatus == http.StatusCreated && repo.last.ValidationStatus != "warning:fbs_out_of_range" {
+				t.Fatalf("expected out-of-range warning, got %q", repo.last.ValidationStatus)
+			}
+		})
+	}
+}
+
+func TestAssessmentsHandler_Create_DerivesBMI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBMI    float64
+		wantSource string
+	}{
+		{name: "computed wins over provided", body: `{"bmi":24,"height_cm":165,"weight_kg":70}`, wantStatus: http.StatusCreated, wantBMI: 25.7, wantSource: models.BMISourceComputed},
+		{name: "height and weight alone", body: `{"height_cm":165,"weight_kg":70}`, wantStatus: http.StatusCreated, wantBMI: 25.7, wantSource: models.BMISourceComputed},
+		{name: "provided", body: `{"bmi":24}`, wantStatus: http.StatusCreated, wantBMI: 24, wantSource: models.BMISourceProvided},
+		{name: "height without weight", body: `{"height_cm":165}`, wantStatus: http.StatusBadRequest},
+		{name: "no bmi at all", body: `{}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeAssessmentRepo{}
+			st := &fakeStore{repo: repo, patientRepo: &fakePatientRepo{}}
+			h := NewAssessmentsHandler(st, ml.NewMockPredictor(), "v1", "hash123")
+
+			r := gin.New()
+			r.Use(mockAuthMiddleware())
+			r.POST("/:id/assessments", h.create)
+
+			req, _ := http.NewRequest(http.MethodPost, "/7/assessments", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+			if tc.wantStatus == http.StatusCreated && (repo.last.BMI != tc.wantBMI || repo.last.BMISource != tc.wantSource) {
+				t.Fatalf("expected bmi %v (%s), got %v (%s)", tc.wantBMI, tc.wantSource, repo.last.BMI, repo.last.BMISource)
+			}
+		})
+	}
+}
+
 const defaultTestTimeout = 2 * time.Second
 
+func TestAssessmentsHandler_Create_UsesActiveModelRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		active      *models.ModelRun
+		wantVersion string
+		wantHash    string
+	}{
+		{name: "active run", active: &models.ModelRun{ID: 3, ModelVersion: "v2", DatasetHash: "hash456", IsActive: true}, wantVersion: "v2", wantHash: "hash456"},
+		{name: "config fallback", wantVersion: "v1", wantHash: "hash123"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeAssessmentRepo{}
+			st := &fakeStore{repo: repo, patientRepo: &fakePatientRepo{}, modelRuns: &fakeModelRunRepo{active: tc.active}}
+			h := NewAssessmentsHandler(st, ml.NewMockPredictor(), "v1", "hash123")
+
+			r := gin.New()
+			r.Use(mockAuthMiddleware())
+			r.POST("/:id/assessments", h.create)
+
+			body := bytes.NewBufferString(`{"fbs":95,"hba1c":5.5,"bmi":24}`)
+			req, _ := http.NewRequest(http.MethodPost, "/7/assessments", body)
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+			}
+			if repo.last.ModelVersion != tc.wantVersion || repo.last.DatasetHash != tc.wantHash {
+				t.Fatalf("expected %s/%s, got %s/%s", tc.wantVersion, tc.wantHash, repo.last.ModelVersion, repo.last.DatasetHash)
+			}
+		})
+	}
+}
+
 type fakeStore struct {
 	repo        *fakeAssessmentRepo
 	patientRepo *fakePatientRepo
+	clinicRepo  store.ClinicRepository
+	modelRuns   *fakeModelRunRepo
+	audit       *fakeAuditRepo
+	users       store.UserRepository
+	tokens      store.RefreshTokenRepository
+	cohort      store.CohortRepository
+	alerts      store.RiskAlertRepository
+	verify      store.EmailVerificationRepository
+	resets      store.PasswordResetRepository
+	photos      store.PatientPhotoRepository
+	contacts    *fakePatientContactRepo
+	notes       store.PatientNoteRepository
+	attachments store.AssessmentAttachmentRepository
+	medications store.PatientMedicationRepository
+	followUps   store.FollowUpRepository
+	tasks       store.TaskRepository
+	tags        store.TagRepository
+	listViews   store.PatientListViewRepository
+	webhooks    store.WebhookRepository
+	apiTokens   store.APITokenRepository
+	apiKeys     store.APIKeyRepository
+	baseline    *fakeBaselineRepo
+	history     *fakePatientHistoryRepo
+	experiments *fakeExperimentRepo
+	deletions   *fakeUserDeletionRepo
+	drafts      store.AssessmentDraftRepository
+	mfa         store.MFARepository
+	roles       store.RoleRepository
+	logins      store.LoginHistoryRepository
 }
 
-func (f *fakeStore) Users() store.UserRepository                 { return nil }
+func (f *fakeStore) Users() store.UserRepository {
+	if f.users == nil {
+		return store.NewMemoryStore().Users()
+	}
+	return f.users
+}
 func (f *fakeStore) Patients() store.PatientRepository           { return f.patientRepo }
 func (f *fakeStore) Assessments() store.AssessmentRepository     { return f.repo }
-func (f *fakeStore) RefreshTokens() store.RefreshTokenRepository { return nil }
-func (f *fakeStore) Cohort() store.CohortRepository              { return nil }
-func (f *fakeStore) Clinics() store.ClinicRepository             { return nil }
-func (f *fakeStore) AuditEvents() store.AuditEventRepository     { return nil }
-func (f *fakeStore) ModelRuns() store.ModelRunRepository         { return nil }
-func (f *fakeStore) Close()                                      {}
+func (f *fakeStore) RefreshTokens() store.RefreshTokenRepository { return f.tokens }
+func (f *fakeStore) Cohort() store.CohortRepository              { return f.cohort }
+func (f *fakeStore) Clinics() store.ClinicRepository {
+	if f.clinicRepo == nil {
+		return &fakeClinicRepo{mode: models.ValidationModeAdvisory}
+	}
+	return f.clinicRepo
+}
+func (f *fakeStore) AuditEvents() store.AuditEventRepository {
+	if f.audit == nil {
+		return &fakeAuditRepo{}
+	}
+	return f.audit
+}
+func (f *fakeStore) ModelRuns() store.ModelRunRepository {
+	if f.modelRuns == nil {
+		return &fakeModelRunRepo{}
+	}
+	return f.modelRuns
+}
+func (f *fakeStore) RiskAlerts() store.RiskAlertRepository { return f.alerts }
+func (f *fakeStore) EmailVerifications() store.EmailVerificationRepository {
+	return f.verify
+}
+func (f *fakeStore) PasswordResets() store.PasswordResetRepository { return f.resets }
+func (f *fakeStore) PatientPhotos() store.PatientPhotoRepository   { return f.photos }
+func (f *fakeStore) PatientContacts() store.PatientContactRepository {
+	if f.contacts == nil {
+		return &fakePatientContactRepo{}
+	}
+	return f.contacts
+}
+func (f *fakeStore) PatientNotes() store.PatientNoteRepository {
+	if f.notes == nil {
+		f.notes = store.NewMemoryStore().PatientNotes()
+	}
+	return f.notes
+}
+func (f *fakeStore) PatientMedications() store.PatientMedicationRepository {
+	if f.medications == nil {
+		f.medications = store.NewMemoryStore().PatientMedications()
+	}
+	return f.medications
+}
+func (f *fakeStore) FollowUps() store.FollowUpRepository {
+	if f.followUps == nil {
+		f.followUps = store.NewMemoryStore().FollowUps()
+	}
+	return f.followUps
+}
+func (f *fakeStore) Tasks() store.TaskRepository {
+	if f.tasks == nil {
+		f.tasks = store.NewMemoryStore().Tasks()
+	}
+	return f.tasks
+}
+func (f *fakeStore) Tags() store.TagRepository {
+	if f.tags == nil {
+		f.tags = store.NewMemoryStore().Tags()
+	}
+	return f.tags
+}
+func (f *fakeStore) PatientListViews() store.PatientListViewRepository {
+	if f.listViews == nil {
+		f.listViews = store.NewMemoryStore().PatientListViews()
+	}
+	return f.listViews
+}
+func (f *fakeStore) AssessmentAttachments() store.AssessmentAttachmentRepository {
+	if f.attachments == nil {
+		f.attachments = store.NewMemoryStore().AssessmentAttachments()
+	}
+	return f.attachments
+}
+func (f *fakeStore) APITokens() store.APITokenRepository { return f.apiTokens }
+func (f *fakeStore) APIKeys() store.APIKeyRepository     { return f.apiKeys }
+func (f *fakeStore) BaselineDiscrepancies() store.BaselineDiscrepancyRepository {
+	if f.baseline == nil {
+		f.baseline = &fakeBaselineRepo{}
+	}
+	return f.baseline
+}
+func (f *fakeStore) PatientHistory() store.PatientHistoryRepository {
+	if f.history == nil {
+		f.history = &fakePatientHistoryRepo{}
+	}
+	return f.history
+}
+func (f *fakeStore) Experiments() store.ExperimentRepository {
+	if f.experiments == nil {
+		f.experiments = &fakeExperimentRepo{}
+	}
+	return f.experiments
+}
+func (f *fakeStore) UserDeletions() store.UserDeletionRepository {
+	if f.deletions == nil {
+		f.deletions = &fakeUserDeletionRepo{}
+	}
+	return f.deletions
+}
+func (f *fakeStore) PredictionCache() store.PredictionCacheRepository { return nil }
+func (f *fakeStore) Webhooks() store.WebhookRepository                { return f.webhooks }
+func (f *fakeStore) AssessmentDrafts() store.AssessmentDraftRepository {
+	if f.drafts == nil {
+		f.drafts = store.NewMemoryStore().AssessmentDrafts()
+	}
+	return f.drafts
+}
+func (f *fakeStore) MFA() store.MFARepository {
+	if f.mfa == nil {
+		f.mfa = store.NewMemoryStore().MFA()
+	}
+	return f.mfa
+}
+func (f *fakeStore) Roles() store.RoleRepository {
+	if f.roles == nil {
+		f.roles = store.NewMemoryStore().Roles()
+	}
+	return f.roles
+}
+func (f *fakeStore) LoginHistory() store.LoginHistoryRepository {
+	if f.logins == nil {
+		f.logins = store.NewMemoryStore().LoginHistory()
+	}
+	return f.logins
+}
+func (f *fakeStore) Close() {}
 
 // mockAuthMiddleware injects mock user claims for testing
 func mockAuthMiddleware() gin.HandlerFunc {
@@ -162,13 +594,26 @@ func mockAuthMiddleware() gin.HandlerFunc {
 }
 
 // fakePatientRepo mocks patient repository for tests
-type fakePatientRepo struct{}
+type fakePatientRepo struct {
+	matches    []models.PatientMatch
+	lastQuery  string
+	lastLimit  int
+	patients   []models.Patient
+	lastParams models.PatientListParams
+	stored     *models.Patient
+	lastUpdate *models.Patient
+	transfers  [][2]int32
+}
 
 func (f *fakePatientRepo) List(ctx context.Context, userID int32) ([]models.Patient, error) {
 	return nil, nil
 }
 
 func (f *fakePatientRepo) Get(ctx context.Context, id int32, userID int32) (*models.Patient, error) {
+	if f.stored != nil {
+		p := *f.stored
+		return &p, nil
+	}
 	return &models.Patient{ID: int64(id), UserID: int64(userID), Name: "Test"}, nil
 }
 
@@ -177,6 +622,7 @@ func (f *fakePatientRepo) Create(ctx context.Context, p models.Patient) (*models
 }
 
 func (f *fakePatientRepo) Update(ctx context.Context, p models.Patient) (*models.Patient, error) {
+	f.lastUpdate = &p
 	return &p, nil
 }
 
@@ -184,20 +630,146 @@ func (f *fakePatientRepo) Delete(ctx context.Context, id int32, userID int32) er
 	return nil
 }
 
+func (f *fakePatientRepo) Typeahead(ctx context.Context, userID int32, q string, limit int) ([]models.PatientMatch, error) {
+	f.lastQuery = q
+	f.lastLimit = limit
+	return f.matches, nil
+}
+
+func (f *fakePatientRepo) ListWithLatestAssessmentPaginated(ctx context.Context, userID int32, params models.PatientListParams) ([]models.PatientWithLatest, int, error) {
+	f.lastParams = params
+	out := make([]models.PatientWithLatest, 0, len(f.patients))
+	for _, p := range f.patients {
+		out = append(out, models.PatientWithLatest{Patient: p})
+	}
+	return out, len(f.patients), nil
+}
+
+func (f *fakePatientRepo) ListWithLatestAssessmentAfter(ctx context.Context, userID int32, params models.PatientListParams, after *models.ListCursor) ([]models.PatientWithLatest, error) {
+	rows, _, err := f.ListWithLatestAssessmentPaginated(ctx, userID, params)
+	return rows, err
+}
+
+func (f *fakePatientRepo) ListVersion(ctx context.Context, userID int32, params models.PatientListParams) (models.ListVersion, error) {
+	v := models.ListVersion{Count: len(f.patients)}
+	for _, p := range f.patients {
+		v.IDSum += p.ID
+	}
+	return v, nil
+}
+
 func (f *fakePatientRepo) ListAllLimited(ctx context.Context, userID int32, limit int) ([]models.Patient, error) {
 	return nil, nil
 }
 
+func (f *fakePatientRepo) EachLimited(ctx context.Context, userID int32, limit int, fn func(models.Patient) error) error {
+	return nil
+}
+
+func (f *fakePatientRepo) EachLimitedByClinic(ctx context.Context, clinicID int32, limit int, fn func(models.Patient) error) error {
+	return nil
+}
+
+func (f *fakePatientRepo) GetVisible(ctx context.Context, id int32, userID int32) (*models.Patient, error) {
+	return f.Get(ctx, id, userID)
+}
+
+func (f *fakePatientRepo) ListByMRN(ctx context.Context, mrn string) ([]models.Patient, error) {
+	out := []models.Patient{}
+	for _, p := range f.patients {
+		if p.MRN == mrn {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakePatientRepo) SetClinic(ctx context.Context, id int64, ownerID int32, clinicID *int32, changedBy int32) error {
+	if f.stored != nil {
+		f.stored.ClinicID = nil
+		if clinicID != nil {
+			c := int64(*clinicID)
+			f.stored.ClinicID = &c
+		}
+	}
+	return nil
+}
+
+func (f *fakePatientRepo) Owner(ctx context.Context, id int64) (int32, error) {
+	if f.stored != nil {
+		return int32(f.stored.UserID), nil
+	}
+	return 1, nil
+}
+
+func (f *fakePatientRepo) Transfer(ctx context.Context, id int64, fromUserID, toUserID, changedBy int32) error {
+	f.transfers = append(f.transfers, [2]int32{fromUserID, toUserID})
+	if f.stored != nil {
+		f.stored.UserID = int64(toUserID)
+	}
+	return nil
+}
+
+func (f *fakePatientRepo) Duplicates(ctx context.Context, userID int32, minSimilarity float64, limit int) ([]models.PatientDuplicate, error) {
+	return []models.PatientDuplicate{}, nil
+}
+
+func (f *fakePatientRepo) Merge(ctx context.Context, survivorID, mergedID int64, userID int32) (*models.PatientMerge, error) {
+	return &models.PatientMerge{PatientID: survivorID, MergedID: mergedID}, nil
+}
+
 type fakeAssessmentRepo struct {
-	last models.Assessment
+	last         models.Assessment
+	lastBatch    []models.Assessment
+	stored       *models.Assessment
+	explanations map[int32]map[string]interface{}
+	all          []models.Assessment
+	statuses     map[int32]string
+	qualities    map[int32]models.DataQuality
+	qualityUser  *int32
+	amendment    *models.Assessment
+	trendParams  *models.TrendParams
+	clusterScope *models.CohortScope
+	trend        []models.AssessmentTrend
+	// owner, when set, is the only user Get, Update and Delete reach stored for
+	owner  int32
+	getErr error
+}
+
+func (f *fakeAssessmentRepo) ListVersion(ctx context.Context, patientID int64) (models.ListVersion, error) {
+	var v models.ListVersion
+	for _, a := range f.all {
+		if a.PatientID == patientID {
+			v.Count++
+			v.IDSum += a.ID
+		}
+	}
+	return v, nil
 }
 
 func (f *fakeAssessmentRepo) ListByPatient(ctx context.Context, patientID int64) ([]models.Assessment, error) {
-	return nil, nil
+	var out []models.Assessment
+	for _, a := range f.all {
+		if a.PatientID == patientID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
 }
 
-func (f *fakeAssessmentRepo) Get(ctx context.Context, id int32) (*models.Assessment, error) {
-	return nil, nil
+func (f *fakeAssessmentRepo) ListByPatientPage(ctx context.Context, patientID int64, params models.AssessmentPageParams) ([]models.Assessment, error) {
+	return f.ListByPatient(ctx, patientID)
+}
+
+func (f *fakeAssessmentRepo) Get(ctx context.Context, id int32, userID int32) (*models.Assessment, error) {
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	if f.stored == nil || f.stored.ID != int64(id) || (f.owner != 0 && f.owner != userID) {
+		return nil, pgx.ErrNoRows
+	}
+	a := *f.stored
+	return &a, nil
 }
 
 func (f *fakeAssessmentRepo) Create(ctx context.Context, a models.Assessment) (*models.Assessment, error) {
@@ -206,11 +778,32 @@ func (f *fakeAssessmentRepo) Create(ctx context.Context, a models.Assessment) (*
 	return &a, nil
 }
 
-func (f *fakeAssessmentRepo) Update(ctx context.Context, a models.Assessment) (*models.Assessment, error) {
-	return nil, nil
+func (f *fakeAssessmentRepo) CreateBatch(ctx context.Context, items []models.Assessment) ([]models.Assessment, error) {
+	f.lastBatch = items
+	return items, nil
 }
 
-func (f *fakeAssessmentRepo) Delete(ctx context.Context, id int32) error {
+func (f *fakeAssessmentRepo) Update(ctx context.Context, a models.Assessment, userID int32) (*models.Assessment, error) {
+	if f.owner != 0 && f.owner != userID {
+		return nil, pgx.ErrNoRows
+	}
+	f.last = a
+	return &a, nil
+}
+
+func (f *fakeAssessmentRepo) UpdateDraft(ctx context.Context, a models.Assessment, userID int32) (*models.Assessment, error) {
+	return f.Update(ctx, a, userID)
+}
+
+func (f *fakeAssessmentRepo) Finalize(ctx context.Context, a models.Assessment, userID int32) (*models.Assessment, error) {
+	a.Status = models.AssessmentStatusFinal
+	return f.Update(ctx, a, userID)
+}
+
+func (f *fakeAssessmentRepo) Delete(ctx context.Context, id int32, userID int32) error {
+	if f.owner != 0 && f.owner != userID {
+		return pgx.ErrNoRows
+	}
 	return nil
 }
 
@@ -218,8 +811,14 @@ func (f *fakeAssessmentRepo) ClusterCounts(ctx context.Context) ([]models.Cluste
 	return nil, nil
 }
 
-func (f *fakeAssessmentRepo) TrendAverages(ctx context.Context) ([]models.TrendPoint, error) {
-	return nil, nil
+func (f *fakeAssessmentRepo) ClusterCountsInScope(ctx context.Context, scope models.CohortScope) ([]models.ClusterAnalytics, error) {
+	f.clusterScope = &scope
+	return []models.ClusterAnalytics{}, nil
+}
+
+func (f *fakeAssessmentRepo) TrendAverages(ctx context.Context, params models.TrendParams) ([]models.TrendPoint, error) {
+	f.trendParams = &params
+	return []models.TrendPoint{}, nil
 }
 
 func (f *fakeAssessmentRepo) ListAllLimited(ctx context.Context, limit int) ([]models.Assessment, error) {
@@ -227,9 +826,199 @@ func (f *fakeAssessmentRepo) ListAllLimited(ctx context.Context, limit int) ([]m
 }
 
 func (f *fakeAssessmentRepo) GetTrend(ctx context.Context, patientID int64) ([]models.AssessmentTrend, error) {
-	return nil, nil
+	return f.trend, nil
+}
+
+func (f *fakeAssessmentRepo) SetExplanation(ctx context.Context, id int32, explanation map[string]interface{}) error {
+	if f.explanations == nil {
+		f.explanations = map[int32]map[string]interface{}{}
+	}
+	f.explanations[id] = explanation
+	return nil
+}
+
+func (f *fakeAssessmentRepo) GetExplanation(ctx context.Context, id int32) (map[string]interface{}, error) {
+	return f.explanations[id], nil
 }
 
 func (f *fakeAssessmentRepo) ListAllLimitedByUser(ctx context.Context, userID int32, limit int) ([]models.Assessment, error) {
 	return nil, nil
 }
+
+func (f *fakeAssessmentRepo) EachLimitedByUser(ctx context.Context, userID int32, limit int, fn func(models.Assessment) error) error {
+	return nil
+}
+
+func (f *fakeAssessmentRepo) EachLimitedByClinic(ctx context.Context, clinicID int32, limit int, fn func(models.Assessment) error) error {
+	return nil
+}
+
+func (f *fakeAssessmentRepo) ListSince(ctx context.Context, since time.Time, afterID int64, limit int) ([]models.Assessment, error) {
+	var out []models.Assessment
+	for _, a := range f.all {
+		if a.ID > afterID && !a.CreatedAt.Before(since) && len(out) < limit {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAssessmentRepo) ListPendingPrediction(ctx context.Context, limit int) ([]models.Assessment, error) {
+	var out []models.Assessment
+	for _, a := range f.all {
+		if a.Cluster == models.ClusterPendingPrediction && len(out) < limit {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAssessmentRepo) CompletePrediction(ctx context.Context, a models.Assessment) error {
+	for i := range f.all {
+		if f.all[i].ID == a.ID && f.all[i].Cluster == models.ClusterPendingPrediction {
+			f.all[i].Cluster, f.all[i].RiskScore, f.all[i].ModelVersion = a.Cluster, a.RiskScore, a.ModelVersion
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (f *fakeAssessmentRepo) SetValidationStatus(ctx context.Context, id int32, status string) error {
+	if f.statuses == nil {
+		f.statuses = map[int32]string{}
+	}
+	f.statuses[id] = status
+	return nil
+}
+
+func (f *fakeAssessmentRepo) SetQuality(ctx context.Context, id int32, q models.DataQuality) error {
+	if f.qualities == nil {
+		f.qualities = map[int32]models.DataQuality{}
+	}
+	f.qualities[id] = q
+	return nil
+}
+
+func (f *fakeAssessmentRepo) QualityByClinician(ctx context.Context, userID *int32, lowBelow int) ([]models.ClinicianDataQuality, error) {
+	f.qualityUser = userID
+	return []models.ClinicianDataQuality{{UserID: 1, Assessments: 2, LowQuality: lowBelow}}, nil
+}
+
+func (f *fakeAssessmentRepo) Amend(ctx context.Context, originalID int64, a models.Assessment) (*models.Assessment, error) {
+	if f.amendment != nil && *f.amendment.AmendsAssessmentID == originalID {
+		return nil, store.ErrAlreadyAmended
+	}
+	a.ID = originalID + 100
+	a.AmendsAssessmentID = &originalID
+	f.amendment = &a
+	return &a, nil
+}
+
+// AmendmentChain knows only stored and its amendment, if any
+func (f *fakeAssessmentRepo) AmendmentChain(ctx context.Context, id int64) ([]models.Assessment, error) {
+	var chain []models.Assessment
+	if f.stored != nil {
+		chain = append(chain, *f.stored)
+	}
+	if f.amendment != nil {
+		chain = append(chain, *f.amendment)
+	}
+	return chain, nil
+}
+
+// fakeClinicRepo mocks the clinic repository; only per-user policy lookups are
+// exercised, and the user belongs to no clinic
+type fakeClinicRepo struct {
+	store.ClinicRepository
+	mode   string
+	policy string
+}
+
+func (f *fakeClinicRepo) ListUserClinics(ctx context.Context, userID int32) ([]models.UserClinic, error) {
+	return nil, nil
+}
+
+func (f *fakeClinicRepo) ValidationModeForUser(ctx context.Context, userID int32) (string, error) {
+	return f.mode, nil
+}
+
+func (f *fakeClinicRepo) BaselinePolicyForUser(ctx context.Context, userID int32) (string, error) {
+	if f.policy == "" {
+		return models.BaselinePolicyFlag, nil
+	}
+	return f.policy, nil
+}
+
+// fakeModelRunRepo mocks model run repository; active is nil when no run is active
+type fakeModelRunRepo struct {
+	store.ModelRunRepository
+	active      *models.ModelRun
+	runs        []models.ModelRun
+	predictions []models.AssessmentPrediction
+	recent      models.PredictionDistribution
+}
+
+func (f *fakeModelRunRepo) Get(ctx context.Context, id int32) (*models.ModelRun, error) {
+	for _, run := range f.runs {
+		if run.ID == int64(id) {
+			return &run, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (f *fakeModelRunRepo) Create(ctx context.Context, run models.ModelRun) (*models.ModelRun, error) {
+	run.ID = int64(len(f.runs) + 1)
+	f.runs = append(f.runs, run)
+	return &run, nil
+}
+
+// Distribution reports recent, whatever the version
+func (f *fakeModelRunRepo) Distribution(ctx context.Context, version, datasetHash string, since time.Time) (models.PredictionDistribution, error) {
+	return f.recent, nil
+}
+
+func (f *fakeModelRunRepo) SavePrediction(ctx context.Context, p models.AssessmentPrediction) error {
+	f.predictions = append(f.predictions, p)
+	return nil
+}
+
+func (f *fakeModelRunRepo) ListPredictions(ctx context.Context, runID int32, limit, offset int) ([]models.AssessmentPrediction, int, error) {
+	out := []models.AssessmentPrediction{}
+	for _, p := range f.predictions {
+		if p.ModelRunID == int64(runID) {
+			out = append(out, p)
+		}
+	}
+	return out, len(out), nil
+}
+
+func (f *fakeModelRunRepo) GetActive(ctx context.Context) (*models.ModelRun, error) {
+	if f.active == nil {
+		return nil, errors.New("no active model run")
+	}
+	return f.active, nil
+}
+
+// fakeAuditRepo records audit events written by handlers
+type fakeAuditRepo struct {
+	store.AuditEventRepository
+	events []models.AuditEvent
+}
+
+func (f *fakeAuditRepo) Create(ctx context.Context, event models.AuditEvent) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+// List filters by target only, newest first
+func (f *fakeAuditRepo) List(ctx context.Context, params models.AuditListParams) ([]models.AuditEvent, int, error) {
+	out := []models.AuditEvent{}
+	for i := len(f.events) - 1; i >= 0; i-- {
+		e := f.events[i]
+		if (params.TargetType == "" || e.TargetType == params.TargetType) && (params.TargetID == 0 || e.TargetID == params.TargetID) {
+			out = append(out, e)
+		}
+	}
+	return out, len(out), nil
+}
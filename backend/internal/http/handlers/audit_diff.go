@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// auditIgnoredFields are bookkeeping or derived fields left out of audit
+// diffs.
+var auditIgnoredFields = map[string]bool{
+	"id": true, "user_id": true, "created_at": true, "updated_at": true,
+	"contact": true, "quality": true, "amendment_chain": true,
+}
+
+// auditRedactedFields are identifiers. A change to one is recorded with its
+// values replaced by "redacted", so the audit log does not become a second copy of them; the
+// patient history keeps the values for those allowed to see them.
+var auditRedactedFields = map[string]bool{"name": true, "mrn": true}
+
+// auditDiff lists the fields that differ between two records, compared by
+// their JSON form and sorted by name. Use a zero value as before to list
+// what a new record was created with.
+func auditDiff(before, after interface{}) []models.PatientFieldChange {
+	old, cur := auditFields(before), auditFields(after)
+	fields := map[string]bool{}
+	for f := range old {
+		fields[f] = true
+	}
+	for f := range cur {
+		fields[f] = true
+	}
+
+	changes := []models.PatientFieldChange{}
+	for f := range fields {
+		if auditIgnoredFields[f] || reflect.DeepEqual(old[f], cur[f]) {
+			continue
+		}
+		change := models.PatientFieldChange{Field: f, Old: old[f], New: cur[f]}
+		if auditRedactedFields[f] {
+			change.Old, change.New = "redacted", "redacted"
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func auditFields(v interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if b, err := json.Marshal(v); err == nil {
+		_ = json.Unmarshal(b, &fields)
+	}
+	return fields
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// changesOf indexes an audit event's diff by field
+func changesOf(t *testing.T, e models.AuditEvent) map[string]models.PatientFieldChange {
+	t.Helper()
+	changes, ok := e.Details["changes"].([]models.PatientFieldChange)
+	if !ok {
+		t.Fatalf("%s: expected a diff, got %+v", e.Action, e.Details)
+	}
+	out := map[string]models.PatientFieldChange{}
+	for _, c := range changes {
+		out[c.Field] = c
+	}
+	return out
+}
+
+func TestMutationAudit_Patients(t *testing.T) {
+	audit := &fakeAuditRepo{}
+	patients := &fakePatientRepo{stored: &models.Patient{ID: 7, UserID: 1, Name: "Maria Santos", MRN: "MRN-1", Age: 52, BMI: 27.5}}
+	r := baselineRouter(&fakeStore{patientRepo: patients, audit: audit})
+
+	if w := contactRequest(r, http.MethodPost, "/patients", `{"name":"Ana Cruz","age":49,"mrn":"MRN-2"}`); w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := contactRequest(r, http.MethodPut, "/patients/7", `{"name":"Maria S. Santos","mrn":"MRN-1","age":53,"bmi":27.5}`); w.Code != http.StatusOK {
+		t.Fatalf("update: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(audit.events) != 2 {
+		t.Fatalf("expected create and update audited, got %+v", audit.events)
+	}
+
+	created := changesOf(t, audit.events[0])
+	if audit.events[0].Action != "patient.create" || created["age"].New != float64(49) {
+		t.Fatalf("unexpected create audit: %+v", audit.events[0])
+	}
+	if created["name"].New != "redacted" || created["mrn"].New != "redacted" {
+		t.Fatalf("expected identifiers redacted, got %+v", created)
+	}
+
+	updated := changesOf(t, audit.events[1])
+	if e := audit.events[1]; e.Action != "patient.update" || e.TargetID != 7 || e.Actor != "test@example.com" {
+		t.Fatalf("unexpected update audit: %+v", e)
+	}
+	if len(updated) != 2 || updated["age"].Old != float64(52) || updated["age"].New != float64(53) || updated["name"].Old != "redacted" {
+		t.Fatalf("expected only age and name changed, got %+v", updated)
+	}
+}
+
+func TestMutationAudit_Assessments(t *testing.T) {
+	audit := &fakeAuditRepo{}
+	assessments := &fakeAssessmentRepo{stored: &models.Assessment{ID: 3, PatientID: 7, FBS: 110, HbA1c: 6.1, Cluster: "SIRD", RiskScore: 60}}
+	r := baselineRouter(&fakeStore{patientRepo: &fakePatientRepo{}, repo: assessments, audit: audit})
+
+	if w := contactRequest(r, http.MethodPut, "/patients/7/assessments/3", `{"fbs":118,"hba1c":6.1,"bmi":24}`); w.Code != http.StatusOK {
+		t.Fatalf("update: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := contactRequest(r, http.MethodPut, "/patients/8/assessments/3", `{"fbs":118,"bmi":24}`); w.Code != http.StatusNotFound {
+		t.Fatalf("update through another patient: expected 404, got %d", w.Code)
+	}
+	if w := contactRequest(r, http.MethodDelete, "/patients/7/assessments/3", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(audit.events) != 2 {
+		t.Fatalf("expected update and delete audited, got %+v", audit.events)
+	}
+
+	if e := audit.events[0]; e.Action != "assessment.update" || e.TargetID != 3 || e.Details["patient_id"] != int64(7) {
+		t.Fatalf("unexpected update audit: %+v", e)
+	}
+	if fbs := changesOf(t, audit.events[0])["fbs"]; fbs.Old != float64(110) || fbs.New != float64(118) {
+		t.Fatalf("expected the FBS change recorded, got %+v", fbs)
+	}
+
+	deleted := changesOf(t, audit.events[1])
+	if audit.events[1].Action != "assessment.delete" || deleted["hba1c"].Old != 6.1 || deleted["hba1c"].New != nil {
+		t.Fatalf("expected the deleted values kept, got %+v", audit.events[1])
+	}
+}
@@ -4,23 +4,65 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
 	"github.com/skufu/DianaV2/backend/internal/config"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/mail"
+	"github.com/skufu/DianaV2/backend/internal/models"
 	"github.com/skufu/DianaV2/backend/internal/store"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthHandler struct {
-	cfg   config.Config
-	store store.Store
+	cfg    config.Config
+	store  store.Store
+	mailer mail.Mailer
+	// resetLimiter caps password reset emails per address
+	resetLimiter *middleware.RateLimiter
+	// credentialThrottle guards the endpoints that accept credentials
+	credentialThrottle []gin.HandlerFunc
+	// accessTTL and refreshTTL are the token lifetimes
+	accessTTL  time.Duration
+	refreshTTL time.Duration
 }
 
 func NewAuthHandler(cfg config.Config, store store.Store) *AuthHandler {
-	return &AuthHandler{cfg: cfg, store: store}
+	perHour := cfg.PasswordResetsPerHour
+	if perHour <= 0 {
+		perHour = 3
+	}
+	accessTTL := 15 * time.Minute
+	if cfg.AccessTokenTTLMinutes > 0 {
+		accessTTL = time.Duration(cfg.AccessTokenTTLMinutes) * time.Minute
+	}
+	refreshTTL := 7 * 24 * time.Hour
+	if cfg.RefreshTokenTTLDays > 0 {
+		refreshTTL = time.Duration(cfg.RefreshTokenTTLDays) * 24 * time.Hour
+	}
+	return &AuthHandler{
+		cfg:          cfg,
+		store:        store,
+		mailer:       mail.NewLogMailer(),
+		resetLimiter: middleware.NewRateLimiter(perHour, time.Hour),
+		accessTTL:    accessTTL,
+		refreshTTL:   refreshTTL,
+	}
+}
+
+// WithMailer sets how verification emails are delivered (logged by default).
+func (h *AuthHandler) WithMailer(m mail.Mailer) *AuthHandler {
+	h.mailer = m
+	return h
 }
 
 type loginRequest struct {
@@ -28,10 +70,28 @@ type loginRequest struct {
 	Password string `json:"password"`
 }
 
+// WithCredentialThrottle runs mw before /login and /refresh, the endpoints a
+// brute-force attack would target. Call it before Register.
+func (h *AuthHandler) WithCredentialThrottle(mw ...gin.HandlerFunc) *AuthHandler {
+	h.credentialThrottle = mw[:len(mw):len(mw)] // full slice: appends must not share a backing array
+	return h
+}
+
 func (h *AuthHandler) Register(rg *gin.RouterGroup) {
-	rg.POST("/login", h.login)
-	rg.POST("/refresh", h.refresh)
+	rg.POST("/login", append(h.credentialThrottle, h.login)...)
+	rg.POST("/login/mfa", append(h.credentialThrottle, h.loginMFA)...)
+	rg.POST("/refresh", append(h.credentialThrottle, h.refresh)...)
 	rg.POST("/logout", h.logout)
+	rg.POST("/register", h.register)
+	rg.POST("/verify-email", h.verifyEmail)
+	rg.POST("/resend-verification", h.resendVerification)
+	rg.POST("/forgot-password", h.forgotPassword)
+	rg.POST("/reset-password", h.resetPassword)
+}
+
+// RegisterProtected registers auth routes that need an already authenticated caller.
+func (h *AuthHandler) RegisterProtected(rg *gin.RouterGroup) {
+	rg.POST("/sudo", h.sudo)
 }
 
 func (h *AuthHandler) login(c *gin.Context) {
@@ -46,54 +106,199 @@ func (h *AuthHandler) login(c *gin.Context) {
 	}
 	user, err := h.store.Users().FindByEmail(c.Request.Context(), req.Email)
 	if err != nil {
+		h.recordLogin(c, nil, req.Email, models.LoginFailureUnknownEmail)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
+	// A locked account is refused before the password is even checked, so
+	// guesses made during the lockout reveal nothing.
+	if h.cfg.LoginLockoutThreshold > 0 && user.LockedAt(time.Now()) {
+		h.recordLogin(c, user, req.Email, models.LoginFailureLocked)
+		respondLocked(c, *user.LockedUntil)
+		return
+	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		h.recordLogin(c, user, req.Email, models.LoginFailureBadPassword)
+		h.recordFailedLogin(c, user)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
+	if user.FailedLoginAttempts > 0 {
+		if err := h.store.Users().ResetFailedLogins(c.Request.Context(), int32(user.ID)); err != nil {
+			logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to reset failed logins for user %d", user.ID)
+		}
+	}
+	// Only checked once the password matched, so guessers learn nothing
+	// about deactivated accounts
+	if !user.IsActive {
+		h.recordLogin(c, user, req.Email, models.LoginFailureInactive)
+		c.JSON(http.StatusForbidden, gin.H{"error": "account is deactivated"})
+		return
+	}
+	if user.EmailVerifiedAt == nil {
+		h.recordLogin(c, user, req.Email, models.LoginFailureUnverified)
+		c.JSON(http.StatusForbidden, gin.H{"error": "email not verified"})
+		return
+	}
 
-	// Generate access token (short-lived, 15 minutes)
-	now := time.Now()
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":     user.Email,
-		"user_id": user.ID,
-		"role":    user.Role,
-		"exp":     now.Add(15 * time.Minute).Unix(),
-		"iat":     now.Unix(),
-		"scope":   "diana",
-	})
-	signedAccessToken, err := accessToken.SignedString([]byte(h.cfg.JWTSecret))
+	// With two-factor authentication the password only earns a challenge
+	enrollment, err := h.mfaEnrollment(c.Request.Context(), user.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load two-factor settings"})
+		return
+	}
+	if enrollment != nil && enrollment.EnabledAt != nil {
+		h.respondMFAChallenge(c, user)
 		return
 	}
+	h.startSession(c, user)
+}
 
-	// Generate refresh token (long-lived, 7 days)
-	refreshTokenBytes := make([]byte, 32)
-	if _, err := rand.Read(refreshTokenBytes); err != nil {
+// startSession issues the access and refresh tokens of a completed login.
+func (h *AuthHandler) startSession(c *gin.Context, user *models.User) {
+	// Generate refresh token (long-lived, 7 days); it starts a new token family
+	refreshToken, err := newRefreshToken()
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
 		return
 	}
-	refreshToken := base64.URLEncoding.EncodeToString(refreshTokenBytes)
 	refreshTokenHash := hashToken(refreshToken)
 
 	// Store refresh token in database
-	_, err = h.store.RefreshTokens().CreateRefreshToken(c.Request.Context(), refreshTokenHash, int32(user.ID), time.Now().Add(7*24*time.Hour))
+	_, err = h.store.RefreshTokens().CreateRefreshToken(c.Request.Context(), refreshTokenHash, int32(user.ID), time.Now().Add(h.refreshTTL))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create refresh token"})
 		return
 	}
+	// Describe the client for the session list. The token family identifies
+	// the session in the access token.
+	var sessionID string
+	if t, err := h.store.RefreshTokens().RecordSessionClient(c.Request.Context(), refreshTokenHash, c.Request.UserAgent(), c.ClientIP()); err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to record session client for user %d", user.ID)
+	} else {
+		sessionID = t.FamilyID
+	}
 
-	c.JSON(http.StatusOK, gin.H{
+	// Generate access token (short-lived, 15 minutes)
+	now := time.Now()
+	claims, err := h.sessionClaims(c.Request.Context(), user, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+	withSessionID(claims, sessionID)
+	signedAccessToken, err := h.signToken(claims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+
+	resp := gin.H{
 		"access_token":  signedAccessToken,
 		"refresh_token": refreshToken,
 		"token_type":    "Bearer",
-		"expires_in":    900, // 15 minutes in seconds
+		"expires_in":    int(h.accessTTL.Seconds()),
+	}
+	if evicted := h.enforceSessionLimit(c, user); evicted > 0 {
+		resp["revoked_sessions"] = evicted
+		resp["notice"] = fmt.Sprintf("Signed out of %d older session(s): limit is %d concurrent sessions", evicted, h.cfg.MaxSessionsPerUser)
+	}
+	if err := h.store.Users().UpdateLastLogin(c.Request.Context(), int32(user.ID)); err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to update last login for user %d", user.ID)
+	}
+	h.recordLogin(c, user, user.Email, "")
+	c.JSON(http.StatusOK, resp)
+}
+
+// recordLogin adds an attempt to the login history under the email it was
+// made with; user is nil when that matched no account, and an empty
+// failureReason records a success. Failures are logged only so the history
+// never blocks a login.
+func (h *AuthHandler) recordLogin(c *gin.Context, user *models.User, email, failureReason string) {
+	attempt := models.LoginAttempt{
+		Email:         email,
+		IPAddress:     c.ClientIP(),
+		UserAgent:     c.Request.UserAgent(),
+		Success:       failureReason == "",
+		FailureReason: failureReason,
+	}
+	if user != nil {
+		id := user.ID
+		attempt.UserID = &id
+	}
+	if err := h.store.LoginHistory().Record(c.Request.Context(), attempt); err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to record login attempt")
+	}
+}
+
+// recordFailedLogin counts a failed password for user and, once the lockout
+// threshold is reached, audits the lock. Failures are logged only.
+func (h *AuthHandler) recordFailedLogin(c *gin.Context, user *models.User) {
+	if h.cfg.LoginLockoutThreshold <= 0 {
+		return
+	}
+	lockout := time.Duration(h.cfg.LoginLockoutMinutes) * time.Minute
+	lockedUntil, err := h.store.Users().RecordFailedLogin(c.Request.Context(), int32(user.ID), h.cfg.LoginLockoutThreshold, lockout)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to record failed login for user %d", user.ID)
+		return
+	}
+	if lockedUntil == nil {
+		return
+	}
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      user.Email,
+		Action:     "auth.account_locked",
+		TargetType: "user",
+		TargetID:   int(user.ID),
+		Details: map[string]interface{}{
+			"failed_attempts": h.cfg.LoginLockoutThreshold,
+			"locked_until":    lockedUntil.UTC().Format(time.RFC3339),
+			"ip":              c.ClientIP(),
+		},
 	})
 }
 
+// respondLocked answers 423 with a Retry-After until the lock expires.
+func respondLocked(c *gin.Context, until time.Time) {
+	secs := int(math.Ceil(time.Until(until).Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(secs))
+	c.JSON(http.StatusLocked, gin.H{
+		"error":       "account temporarily locked after repeated failed logins",
+		"retry_after": secs,
+	})
+}
+
+// enforceSessionLimit revokes the user's oldest refresh tokens beyond
+// MaxSessionsPerUser and records the eviction. Returns the number revoked.
+// Failures are logged only so a cleanup problem never blocks login.
+func (h *AuthHandler) enforceSessionLimit(c *gin.Context, user *models.User) int {
+	if h.cfg.MaxSessionsPerUser <= 0 {
+		return 0
+	}
+	evicted, err := h.store.RefreshTokens().RevokeExcessUserTokens(c.Request.Context(), int32(user.ID), h.cfg.MaxSessionsPerUser)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to enforce session limit for user %d", user.ID)
+		return 0
+	}
+	if evicted > 0 {
+		_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+			Actor:      user.Email,
+			Action:     "auth.sessions_evicted",
+			TargetType: "user",
+			TargetID:   int(user.ID),
+			Details: map[string]interface{}{
+				"revoked": evicted,
+				"limit":   h.cfg.MaxSessionsPerUser,
+			},
+		})
+	}
+	return evicted
+}
+
 func (h *AuthHandler) refresh(c *gin.Context) {
 	var req struct {
 		RefreshToken string `json:"refresh_token"`
@@ -108,18 +313,17 @@ func (h *AuthHandler) refresh(c *gin.Context) {
 		return
 	}
 
-	// Hash the refresh token to look it up in the database
+	// Hash the refresh token to look it up in the database. Revoked tokens are
+	// looked up too: presenting one means it was replayed after rotation.
 	tokenHash := hashToken(req.RefreshToken)
-
-	// Validate refresh token
-	tokenRecord, err := h.store.RefreshTokens().FindRefreshToken(c.Request.Context(), tokenHash)
+	tokenRecord, err := h.store.RefreshTokens().LookupRefreshToken(c.Request.Context(), tokenHash)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
 		return
 	}
 
-	// Check if token has been revoked
 	if tokenRecord.Revoked {
+		h.revokeReusedFamily(c, tokenRecord)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token has been revoked"})
 		return
 	}
@@ -136,30 +340,94 @@ func (h *AuthHandler) refresh(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 		return
 	}
+	// A deactivated user's sessions end here rather than being refreshed
+	// until the refresh token expires
+	if !user.IsActive {
+		if err := h.store.RefreshTokens().RevokeAllUserTokens(c.Request.Context(), int32(user.ID)); err != nil {
+			logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to revoke refresh tokens of inactive user %d", user.ID)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "account is deactivated"})
+		return
+	}
+
+	// Rotate: the presented token is revoked and replaced within its family
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+	_, err = h.store.RefreshTokens().RotateRefreshToken(c.Request.Context(), tokenHash, hashToken(refreshToken), time.Now().Add(h.refreshTTL))
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Another request rotated this token first: treat it as a replay
+		h.revokeReusedFamily(c, tokenRecord)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token has been revoked"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rotate refresh token"})
+		return
+	}
 
 	// Generate new access token
 	now := time.Now()
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":     user.Email,
-		"user_id": user.ID,
-		"role":    user.Role,
-		"exp":     now.Add(15 * time.Minute).Unix(),
-		"iat":     now.Unix(),
-		"scope":   "diana",
-	})
-	signedAccessToken, err := accessToken.SignedString([]byte(h.cfg.JWTSecret))
+	claims, err := h.sessionClaims(c.Request.Context(), user, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+	withSessionID(claims, tokenRecord.FamilyID)
+	signedAccessToken, err := h.signToken(claims)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"access_token": signedAccessToken,
-		"token_type":   "Bearer",
-		"expires_in":   900, // 15 minutes in seconds
+		"access_token":  signedAccessToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(h.accessTTL.Seconds()),
 	})
 }
 
+// revokeReusedFamily handles a replayed refresh token. The token was already
+// rotated or revoked, so either the client or an attacker holds a stale copy;
+// every session descended from the same login is revoked to be safe.
+func (h *AuthHandler) revokeReusedFamily(c *gin.Context, token *models.RefreshToken) {
+	n, err := h.store.RefreshTokens().RevokeTokenFamily(c.Request.Context(), token.FamilyID)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to revoke refresh token family for user %d", token.UserID)
+		return
+	}
+	if n == 0 {
+		// Nothing left to revoke (e.g. a token from a logged-out session)
+		return
+	}
+	actor := fmt.Sprintf("user:%d", token.UserID)
+	if user, err := h.store.Users().FindByID(c.Request.Context(), int32(token.UserID)); err == nil && user != nil {
+		actor = user.Email
+	}
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      actor,
+		Action:     "auth.refresh_reuse",
+		TargetType: "user",
+		TargetID:   int(token.UserID),
+		Details: map[string]interface{}{
+			"revoked_sessions": n,
+			"ip":               c.ClientIP(),
+		},
+	})
+}
+
+// newRefreshToken returns a random opaque refresh token.
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
 func (h *AuthHandler) logout(c *gin.Context) {
 	var req struct {
 		RefreshToken string `json:"refresh_token"`
@@ -178,6 +446,126 @@ func (h *AuthHandler) logout(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
 }
 
+type sudoRequest struct {
+	Password string `json:"password" binding:"required"`
+	// Code or BackupCode is required when two-factor authentication is on
+	Code       string `json:"code"`
+	BackupCode string `json:"backup_code"`
+}
+
+// sudo re-verifies the caller's password, and their two-factor code when
+// enabled, and issues an access token carrying a short-lived sudo_until
+// claim, required by destructive admin operations.
+// @Summary Re-authenticate for sensitive actions
+// @Description Confirms the current user's password and returns an elevated access token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body sudoRequest true "Current password"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/sudo [post]
+func (h *AuthHandler) sudo(c *gin.Context) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+
+	var req sudoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	user, err := h.store.Users().FindByID(c.Request.Context(), int32(claims.UserID))
+	if err != nil || !user.IsActive {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+			Actor:      claims.Email,
+			Action:     "auth.sudo_failed",
+			TargetType: "user",
+			TargetID:   int(user.ID),
+		})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		return
+	}
+	enrollment, err := h.mfaEnrollment(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load two-factor settings"})
+		return
+	}
+	if enrollment != nil && enrollment.EnabledAt != nil {
+		ok, _, err := h.verifySecondFactor(c.Request.Context(), enrollment, req.Code, req.BackupCode)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify code"})
+			return
+		}
+		if !ok {
+			_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+				Actor:      claims.Email,
+				Action:     "auth.sudo_failed",
+				TargetType: "user",
+				TargetID:   int(user.ID),
+			})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid two-factor code", "mfa_required": true})
+			return
+		}
+	}
+
+	now := time.Now()
+	sudoUntil := now.Add(time.Duration(h.cfg.SudoWindowMinutes) * time.Minute)
+	tokenClaims := h.accessTokenClaims(user, now)
+	tokenClaims["sudo_until"] = sudoUntil.Unix()
+	withSessionID(tokenClaims, claims.SessionID)
+	signedAccessToken, err := h.signToken(tokenClaims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "auth.sudo",
+		TargetType: "user",
+		TargetID:   int(user.ID),
+		Details:    map[string]interface{}{"sudo_until": sudoUntil.UTC().Format(time.RFC3339)},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": signedAccessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(h.accessTTL.Seconds()),
+		"sudo_until":   sudoUntil.UTC().Format(time.RFC3339),
+	})
+}
+
+// accessTokenClaims builds the standard access token claims for a user,
+// valid for ACCESS_TOKEN_TTL_MINUTES
+func (h *AuthHandler) accessTokenClaims(user *models.User, now time.Time) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":     user.Email,
+		"user_id": user.ID,
+		"role":    user.Role,
+		"exp":     now.Add(h.accessTTL).Unix(),
+		"iat":     now.Unix(),
+		"scope":   "diana",
+	}
+}
+
+// signToken signs claims with the current JWT secret.
+func (h *AuthHandler) signToken(claims jwt.MapClaims) (string, error) {
+	return middleware.SignJWT(h.cfg.JWTSecret, claims)
+}
+
+// withSessionID adds the sid claim naming the caller's session, the refresh
+// token family, so it can be told apart in the session list.
+func withSessionID(claims jwt.MapClaims, sessionID string) {
+	if sessionID != "" {
+		claims["sid"] = sessionID
+	}
+}
+
 // hashToken creates a SHA-256 hash of the token for storage
 func hashToken(token string) string {
 	hash := sha256.Sum256([]byte(token))
@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+	"github.com/skufu/DianaV2/backend/internal/totp"
+)
+
+// mfaIssuer names the account in authenticator apps
+const mfaIssuer = "DIANA"
+
+// mfaTokenTTL is how long the second login step may take
+const mfaTokenTTL = 5 * time.Minute
+
+// backupCodeCount is how many backup codes a user gets at a time
+const backupCodeCount = 10
+
+// RegisterMFA registers two-factor enrollment routes. They need an access
+// token but must stay reachable by users who still have to enroll, so the
+// group must not use middleware.RequireMFAEnrollment.
+func (h *AuthHandler) RegisterMFA(rg *gin.RouterGroup) {
+	rg.GET("", h.mfaStatus)
+	rg.POST("/setup", h.mfaSetup)
+	rg.POST("/enable", h.mfaEnable)
+	rg.DELETE("", middleware.RequireSudo(), h.mfaDisable)
+	rg.POST("/backup-codes", middleware.RequireSudo(), h.mfaRegenerateBackupCodes)
+}
+
+// mfaEnrollment returns the user's enrollment, or nil if they have none.
+func (h *AuthHandler) mfaEnrollment(ctx context.Context, userID int64) (*models.MFAEnrollment, error) {
+	e, err := h.store.MFA().Get(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return e, err
+}
+
+// mfaRequired reports whether role must use two-factor authentication.
+func (h *AuthHandler) mfaRequired(ctx context.Context, role string) (bool, error) {
+	roles, err := h.store.MFA().RequiredRoles(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range roles {
+		if r == role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// sessionClaims are accessTokenClaims, marked mfa_setup when the user's
+// role requires two-factor authentication they have not enabled. Such
+// tokens only reach the enrollment routes.
+func (h *AuthHandler) sessionClaims(ctx context.Context, user *models.User, now time.Time) (jwt.MapClaims, error) {
+	claims := h.accessTokenClaims(user, now)
+	required, err := h.mfaRequired(ctx, user.Role)
+	if err != nil || !required {
+		return claims, err
+	}
+	enrollment, err := h.mfaEnrollment(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil || enrollment.EnabledAt == nil {
+		claims["mfa_setup"] = true
+	}
+	return claims, nil
+}
+
+// respondMFAChallenge answers a correct password of a user with two-factor
+// authentication enabled. The mfa_token only works at /auth/login/mfa.
+func (h *AuthHandler) respondMFAChallenge(c *gin.Context, user *models.User) {
+	now := time.Now()
+	token, err := h.signToken(jwt.MapClaims{
+		"sub":     user.Email,
+		"user_id": user.ID,
+		"exp":     now.Add(mfaTokenTTL).Unix(),
+		"iat":     now.Unix(),
+		"scope":   "mfa",
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"mfa_required": true,
+		"mfa_token":    token,
+		"expires_in":   int(mfaTokenTTL.Seconds()),
+	})
+}
+
+// parseMFAToken returns the user id an mfa_token was issued to.
+func (h *AuthHandler) parseMFAToken(value string) (int64, error) {
+	token, err := middleware.ParseJWT(value, h.cfg.JWTSecret, h.cfg.JWTPreviousSecrets...)
+	if err != nil {
+		return 0, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["scope"] != "mfa" {
+		return 0, errors.New("not an MFA token")
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, errors.New("missing user_id claim")
+	}
+	return int64(userID), nil
+}
+
+// verifySecondFactor checks an authenticator code or, failing that, a
+// backup code. Accepted codes are used up. Reports whether one was valid and
+// whether it was a backup code.
+func (h *AuthHandler) verifySecondFactor(ctx context.Context, e *models.MFAEnrollment, code, backupCode string) (bool, bool, error) {
+	if code != "" {
+		step, ok := totp.Validate(e.Secret, code, time.Now())
+		if !ok {
+			return false, false, nil
+		}
+		ok, err := h.store.MFA().UseStep(ctx, e.UserID, step)
+		return ok, false, err
+	}
+	if backupCode != "" {
+		ok, err := h.store.MFA().UseBackupCode(ctx, e.UserID, hashToken(normalizeBackupCode(backupCode)))
+		return ok, ok, err
+	}
+	return false, false, nil
+}
+
+type mfaLoginRequest struct {
+	MFAToken   string `json:"mfa_token" binding:"required"`
+	Code       string `json:"code"`
+	BackupCode string `json:"backup_code"`
+}
+
+// loginMFA completes a login with an authenticator or backup code. Wrong
+// codes count towards the account lockout like wrong passwords.
+// @Summary Complete a two-factor login
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body mfaLoginRequest true "MFA token from /auth/login and a code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 423 {object} map[string]interface{}
+// @Router /auth/login/mfa [post]
+func (h *AuthHandler) loginMFA(c *gin.Context) {
+	var req mfaLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	userID, err := h.parseMFAToken(req.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired MFA token"})
+		return
+	}
+	ctx := c.Request.Context()
+	user, err := h.store.Users().FindByID(ctx, int32(userID))
+	if err != nil || !user.IsActive {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		return
+	}
+	if h.cfg.LoginLockoutThreshold > 0 && user.LockedAt(time.Now()) {
+		h.recordLogin(c, user, user.Email, models.LoginFailureLocked)
+		respondLocked(c, *user.LockedUntil)
+		return
+	}
+	enrollment, err := h.mfaEnrollment(ctx, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load two-factor settings"})
+		return
+	}
+	if enrollment == nil || enrollment.EnabledAt == nil {
+		// Disabled by an admin since the password step
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired MFA token"})
+		return
+	}
+
+	ok, usedBackup, err := h.verifySecondFactor(ctx, enrollment, req.Code, req.BackupCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify code"})
+		return
+	}
+	if !ok {
+		h.recordLogin(c, user, user.Email, models.LoginFailureInvalidMFACode)
+		h.recordFailedLogin(c, user)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+		return
+	}
+	if user.FailedLoginAttempts > 0 {
+		if err := h.store.Users().ResetFailedLogins(ctx, int32(user.ID)); err != nil {
+			logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to reset failed logins for user %d", user.ID)
+		}
+	}
+	if usedBackup {
+		_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+			Actor:      user.Email,
+			Action:     "auth.mfa_backup_code_used",
+			TargetType: "user",
+			TargetID:   int(user.ID),
+			Details:    map[string]interface{}{"remaining": enrollment.BackupCodesLeft - 1},
+		})
+	}
+	h.startSession(c, user)
+}
+
+// mfaStatus tells the caller whether two-factor authentication is enabled
+// or required for them.
+func (h *AuthHandler) mfaStatus(c *gin.Context) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	ctx := c.Request.Context()
+	enrollment, err := h.mfaEnrollment(ctx, claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load two-factor settings"})
+		return
+	}
+	required, err := h.mfaRequired(ctx, claims.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load two-factor settings"})
+		return
+	}
+	resp := gin.H{"enabled": false, "pending": false, "required": required}
+	if enrollment != nil {
+		resp["enabled"] = enrollment.EnabledAt != nil
+		resp["pending"] = enrollment.EnabledAt == nil
+		resp["enabled_at"] = enrollment.EnabledAt
+		resp["backup_codes_left"] = enrollment.BackupCodesLeft
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// mfaSetup starts enrollment with a new secret. The client shows
+// otpauth_uri as a QR code; the secret is for typing in by hand. Calling it
+// again replaces a secret that was never confirmed.
+func (h *AuthHandler) mfaSetup(c *gin.Context) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	secret, err := totp.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "secret error"})
+		return
+	}
+	err = h.store.MFA().Begin(c.Request.Context(), claims.UserID, secret)
+	if errors.Is(err, store.ErrConflict) {
+		abortWithError(c, err, "two-factor authentication is already enabled")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start two-factor setup"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": totp.URI(mfaIssuer, claims.Email, secret),
+	})
+}
+
+// mfaEnable confirms enrollment with a code from the app and returns the
+// backup codes, which are never shown again. The response carries a fresh
+// access token, as one issued before enrollment may be limited to setup.
+func (h *AuthHandler) mfaEnable(c *gin.Context) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	ctx := c.Request.Context()
+	enrollment, err := h.mfaEnrollment(ctx, claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load two-factor settings"})
+		return
+	}
+	if enrollment == nil || enrollment.EnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "no two-factor setup in progress"})
+		return
+	}
+	step, ok := totp.Validate(enrollment.Secret, req.Code, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+	codes, hashes, err := newBackupCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "secret error"})
+		return
+	}
+	if err := h.store.MFA().Enable(ctx, claims.UserID, step, hashes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enable two-factor authentication"})
+		return
+	}
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "auth.mfa_enabled",
+		TargetType: "user",
+		TargetID:   int(claims.UserID),
+	})
+
+	resp := gin.H{"backup_codes": codes}
+	if user, err := h.store.Users().FindByID(ctx, int32(claims.UserID)); err == nil {
+		tokenClaims := h.accessTokenClaims(user, time.Now())
+		withSessionID(tokenClaims, claims.SessionID)
+		signed, err := h.signToken(tokenClaims)
+		if err == nil {
+			resp["access_token"], resp["token_type"], resp["expires_in"] = signed, "Bearer", int(h.accessTTL.Seconds())
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// mfaDisable turns two-factor authentication off, unless the caller's role
+// requires it. Sudo has already checked a code.
+func (h *AuthHandler) mfaDisable(c *gin.Context) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	ctx := c.Request.Context()
+	required, err := h.mfaRequired(ctx, claims.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load two-factor settings"})
+		return
+	}
+	if required {
+		c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication is required for your role"})
+		return
+	}
+	err = h.store.MFA().Disable(ctx, claims.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "two-factor authentication is not set up")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to disable two-factor authentication"})
+		return
+	}
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "auth.mfa_disabled",
+		TargetType: "user",
+		TargetID:   int(claims.UserID),
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// mfaRegenerateBackupCodes replaces all backup codes, used or not.
+func (h *AuthHandler) mfaRegenerateBackupCodes(c *gin.Context) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	ctx := c.Request.Context()
+	enrollment, err := h.mfaEnrollment(ctx, claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load two-factor settings"})
+		return
+	}
+	if enrollment == nil || enrollment.EnabledAt == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "two-factor authentication is not set up"})
+		return
+	}
+	codes, hashes, err := newBackupCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "secret error"})
+		return
+	}
+	if err := h.store.MFA().ReplaceBackupCodes(ctx, claims.UserID, hashes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to replace backup codes"})
+		return
+	}
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "auth.mfa_backup_codes_regenerated",
+		TargetType: "user",
+		TargetID:   int(claims.UserID),
+	})
+	c.JSON(http.StatusOK, gin.H{"backup_codes": codes})
+}
+
+// newBackupCodes returns backupCodeCount codes formatted "xxxxx-xxxxx" and
+// their hashes.
+func newBackupCodes() ([]string, []string, error) {
+	codes := make([]string, backupCodeCount)
+	hashes := make([]string, backupCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeBackupCode accepts backup codes typed with or without the dash
+// and in any case.
+func normalizeBackupCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/skufu/DianaV2/backend/internal/config"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+	"github.com/skufu/DianaV2/backend/internal/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func mfaTestUser(t *testing.T, id int64, role string) *models.User {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := time.Now()
+	return &models.User{ID: id, Email: "doc@example.com", PasswordHash: string(hash), Role: role, IsActive: true, EmailVerifiedAt: &verified}
+}
+
+func TestAuthHandler_LoginMFA(t *testing.T) {
+	mem := store.NewMemoryStore()
+	audit := &fakeAuditRepo{}
+	st := &fakeStore{
+		users:  &fakeUserRepo{user: mfaTestUser(t, 7, "clinician")},
+		tokens: &fakeRefreshTokenRepo{},
+		audit:  audit,
+		mfa:    mem.MFA(),
+	}
+	r := authRouter(config.Config{LoginLockoutThreshold: 5, LoginLockoutMinutes: 15}, st, &fakeMailer{})
+
+	secret, _ := totp.NewSecret()
+	ctx := context.Background()
+	if err := mem.MFA().Begin(ctx, 7, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.MFA().Enable(ctx, 7, 0, []string{hashToken("abcdeabcde")}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"secret"}`)
+	var challenge struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+		AccessToken string `json:"access_token"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &challenge)
+	if w.Code != http.StatusOK || !challenge.MFARequired || challenge.MFAToken == "" || challenge.AccessToken != "" {
+		t.Fatalf("expected an MFA challenge without tokens, got %d %s", w.Code, w.Body.String())
+	}
+
+	// The challenge token is not an access token
+	if w := postJSON(r, "/auth/login/mfa", `{"mfa_token":"nope","code":"123456"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("bad mfa_token: expected 401, got %d", w.Code)
+	}
+
+	code, _ := totp.Code(secret, totp.Step(time.Now()))
+	body := `{"mfa_token":"` + challenge.MFAToken + `","code":"` + code + `"}`
+	if w := postJSON(r, "/auth/login/mfa", body); w.Code != http.StatusOK {
+		t.Fatalf("valid code: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := postJSON(r, "/auth/login/mfa", body); w.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed code: expected 401, got %d", w.Code)
+	}
+
+	body = `{"mfa_token":"` + challenge.MFAToken + `","backup_code":"ABCDE-abcde"}`
+	if w := postJSON(r, "/auth/login/mfa", body); w.Code != http.StatusOK {
+		t.Fatalf("backup code: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := postJSON(r, "/auth/login/mfa", body); w.Code != http.StatusUnauthorized {
+		t.Fatalf("reused backup code: expected 401, got %d", w.Code)
+	}
+	found := false
+	for _, e := range audit.events {
+		found = found || e.Action == "auth.mfa_backup_code_used"
+	}
+	if !found {
+		t.Errorf("expected a backup code audit event, got %+v", audit.events)
+	}
+}
+
+func TestAuthHandler_MFARequiredEnrollment(t *testing.T) {
+	mem := store.NewMemoryStore()
+	st := &fakeStore{
+		users:  &fakeUserRepo{user: mfaTestUser(t, 1, "admin")},
+		tokens: &fakeRefreshTokenRepo{},
+		audit:  &fakeAuditRepo{},
+		mfa:    mem.MFA(),
+	}
+	if err := mem.MFA().SetRequiredRoles(context.Background(), []string{"admin"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	r := authRouter(config.Config{}, st, &fakeMailer{})
+	mfa := r.Group("/mfa")
+	mfa.Use(mockAuthMiddleware())
+	NewAuthHandler(config.Config{JWTSecret: "test"}, st).RegisterMFA(mfa)
+
+	setupClaim := func(token string) interface{} {
+		t.Helper()
+		parsed, err := jwt.Parse(token, func(*jwt.Token) (interface{}, error) { return []byte("test"), nil })
+		if err != nil {
+			t.Fatalf("invalid access token: %v", err)
+		}
+		return parsed.Claims.(jwt.MapClaims)["mfa_setup"]
+	}
+
+	// An admin without two-factor authentication gets a setup-only session
+	w := postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"secret"}`)
+	var login struct {
+		AccessToken string `json:"access_token"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &login)
+	if w.Code != http.StatusOK || setupClaim(login.AccessToken) != true {
+		t.Fatalf("expected an mfa_setup access token, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = postJSON(r, "/mfa/setup", `{}`)
+	var setup struct {
+		Secret string `json:"secret"`
+		URI    string `json:"otpauth_uri"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &setup)
+	if w.Code != http.StatusOK || setup.Secret == "" || setup.URI == "" {
+		t.Fatalf("setup: unexpected %d %s", w.Code, w.Body.String())
+	}
+	if w := postJSON(r, "/mfa/enable", `{"code":"000000x"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("wrong code: expected 400, got %d", w.Code)
+	}
+	code, _ := totp.Code(setup.Secret, totp.Step(time.Now()))
+	w = postJSON(r, "/mfa/enable", `{"code":"`+code+`"}`)
+	var enabled struct {
+		BackupCodes []string `json:"backup_codes"`
+		AccessToken string   `json:"access_token"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &enabled)
+	if w.Code != http.StatusOK || len(enabled.BackupCodes) != backupCodeCount {
+		t.Fatalf("enable: unexpected %d %s", w.Code, w.Body.String())
+	}
+	if setupClaim(enabled.AccessToken) != nil {
+		t.Error("expected a full access token after enabling")
+	}
+	if w := postJSON(r, "/mfa/setup", `{}`); w.Code != http.StatusConflict {
+		t.Fatalf("setup when enabled: expected 409, got %d", w.Code)
+	}
+
+	// The next login asks for the second factor
+	w = postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"secret"}`)
+	var challenge struct {
+		MFARequired bool `json:"mfa_required"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &challenge)
+	if !challenge.MFARequired {
+		t.Fatalf("expected an MFA challenge, got %s", w.Body.String())
+	}
+}
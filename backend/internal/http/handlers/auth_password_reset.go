@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// forgotPassword emails a single-use reset link. The response is the same
+// whether or not the email is registered.
+// @Summary Request password reset
+// @Description Emails a reset link valid for PASSWORD_RESET_TTL_MINUTES; limited to PASSWORD_RESETS_PER_HOUR per address
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body forgotPasswordRequest true "Email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/forgot-password [post]
+func (h *AuthHandler) forgotPassword(c *gin.Context) {
+	var req forgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	// Keyed on the submitted address, so a 429 reveals nothing about whether it exists
+	if !h.resetLimiter.Allow(email) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many reset requests for this email, try again later"})
+		return
+	}
+	accepted := gin.H{"message": "if the account exists, a reset link has been sent"}
+
+	user, err := h.store.Users().FindByEmail(c.Request.Context(), email)
+	if err != nil || user == nil || !user.IsActive {
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	token, err := newURLToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+	ttl := time.Duration(h.cfg.PasswordResetTTLMinutes) * time.Minute
+	if err := h.store.PasswordResets().CreateToken(c.Request.Context(), int32(user.ID), hashToken(token), time.Now().Add(ttl)); err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to create password reset token for user %d", user.ID)
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", h.cfg.AppBaseURL, url.QueryEscape(token))
+	body := fmt.Sprintf("A password reset was requested for your DIANA account.\n\nChoose a new password:\n%s\n\nThis link expires in %d minutes. If you did not request it, ignore this email.\n",
+		link, h.cfg.PasswordResetTTLMinutes)
+	if err := h.mailer.Send(c.Request.Context(), user.Email, "Reset your DIANA password", body); err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to send password reset email to %s", logging.PII(user.Email))
+	}
+
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      user.Email,
+		Action:     "auth.password_reset_requested",
+		TargetType: "user",
+		TargetID:   int(user.ID),
+		Details: map[string]interface{}{
+			"ip": c.ClientIP(),
+		},
+	})
+	c.JSON(http.StatusAccepted, accepted)
+}
+
+// resetPassword sets a new password from a reset token and signs out every
+// existing session.
+// @Summary Reset password
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body resetPasswordRequest true "Reset token and new password (min 8 characters)"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/reset-password [post]
+func (h *AuthHandler) resetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token and a password of at least 8 characters are required"})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process password"})
+		return
+	}
+
+	userID, err := h.store.PasswordResets().Reset(c.Request.Context(), hashToken(req.Token), string(hash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired reset token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset password"})
+		return
+	}
+
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Action:     "auth.password_reset",
+		TargetType: "user",
+		TargetID:   int(userID),
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "password updated; sign in with your new password"})
+}
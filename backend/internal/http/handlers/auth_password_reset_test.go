@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/config"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakePasswordResetRepo maps token hashes to users and records password changes
+type fakePasswordResetRepo struct {
+	tokens   map[string]int32
+	password map[int32]string
+}
+
+func newFakePasswordResetRepo() *fakePasswordResetRepo {
+	return &fakePasswordResetRepo{tokens: map[string]int32{}, password: map[int32]string{}}
+}
+
+func (f *fakePasswordResetRepo) CreateToken(ctx context.Context, userID int32, tokenHash string, expiresAt time.Time) error {
+	f.tokens[tokenHash] = userID
+	return nil
+}
+
+func (f *fakePasswordResetRepo) Reset(ctx context.Context, tokenHash, passwordHash string) (int32, error) {
+	id, ok := f.tokens[tokenHash]
+	if !ok {
+		return 0, pgx.ErrNoRows
+	}
+	delete(f.tokens, tokenHash)
+	f.password[id] = passwordHash
+	return id, nil
+}
+
+func TestAuthHandler_PasswordResetFlow(t *testing.T) {
+	resets := newFakePasswordResetRepo()
+	mailer := &fakeMailer{}
+	st := &fakeStore{
+		users:  &fakeUserRepo{user: &models.User{ID: 5, Email: "doc@example.com", IsActive: true}},
+		resets: resets,
+		audit:  &fakeAuditRepo{},
+	}
+	r := authRouter(config.Config{PasswordResetTTLMinutes: 60, PasswordResetsPerHour: 3}, st, mailer)
+
+	if w := postJSON(r, "/auth/forgot-password", `{"email":"Doc@Example.com"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(mailer.to) != 1 {
+		t.Fatalf("expected one reset email, got %d", len(mailer.to))
+	}
+	token := tokenFromBody(t, mailer.body[0])
+
+	if w := postJSON(r, "/auth/reset-password", `{"token":"`+token+`","password":"short"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for short password, got %d", w.Code)
+	}
+	if w := postJSON(r, "/auth/reset-password", `{"token":"`+token+`","password":"new-password"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(resets.password[5]), []byte("new-password")); err != nil {
+		t.Fatalf("password not updated: %v", err)
+	}
+	if w := postJSON(r, "/auth/reset-password", `{"token":"`+token+`","password":"another-one"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("token should be single-use, got %d", w.Code)
+	}
+}
+
+func TestAuthHandler_ForgotPassword_UnknownEmail(t *testing.T) {
+	resets := newFakePasswordResetRepo()
+	mailer := &fakeMailer{}
+	st := &fakeStore{users: &fakeUserRepo{}, resets: resets}
+	r := authRouter(config.Config{PasswordResetsPerHour: 3}, st, mailer)
+
+	if w := postJSON(r, "/auth/forgot-password", `{"email":"nobody@example.com"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", w.Code)
+	}
+	if len(mailer.to) != 0 || len(resets.tokens) != 0 {
+		t.Fatal("no token or email expected for unknown address")
+	}
+}
+
+func TestAuthHandler_ForgotPassword_RateLimitedPerEmail(t *testing.T) {
+	st := &fakeStore{users: &fakeUserRepo{}, resets: newFakePasswordResetRepo()}
+	r := authRouter(config.Config{PasswordResetsPerHour: 2}, st, &fakeMailer{})
+
+	for i := 0; i < 2; i++ {
+		if w := postJSON(r, "/auth/forgot-password", `{"email":"a@example.com"}`); w.Code != http.StatusAccepted {
+			t.Fatalf("request %d: expected 202, got %d", i+1, w.Code)
+		}
+	}
+	if w := postJSON(r, "/auth/forgot-password", `{"email":"A@example.com"}`); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 after limit, got %d", w.Code)
+	}
+	if w := postJSON(r, "/auth/forgot-password", `{"email":"b@example.com"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("other addresses should not be limited, got %d", w.Code)
+	}
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// selfRegisteredRole is the global role given to self-registered accounts.
+const selfRegisteredRole = "clinician"
+
+type registerRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type resendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// register creates an unverified account and emails a verification link.
+// @Summary Self-register
+// @Description Creates an account that can log in once its email is verified (REGISTRATION_MODE=open only)
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body registerRequest true "Email and password (min 8 characters)"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/register [post]
+func (h *AuthHandler) register(c *gin.Context) {
+	if !h.cfg.RegistrationOpen {
+		c.JSON(http.StatusForbidden, gin.H{"error": "registration is closed"})
+		return
+	}
+
+	var req registerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "valid email and a password of at least 8 characters are required"})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process password"})
+		return
+	}
+	token, err := newURLToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+
+	user, err := h.store.EmailVerifications().Register(c.Request.Context(), models.User{
+		Email:        email,
+		PasswordHash: string(hash),
+		Role:         selfRegisteredRole,
+	}, hashToken(token), h.verificationExpiry())
+	if err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			abortWithError(c, err, "email already registered")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to register"})
+		return
+	}
+
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      user.Email,
+		Action:     "auth.register",
+		TargetType: "user",
+		TargetID:   int(user.ID),
+	})
+	h.sendVerification(c.Request.Context(), user.Email, token)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"user_id": user.ID,
+		"email":   user.Email,
+		"message": "check your email to verify your account",
+	})
+}
+
+// verifyEmail consumes a verification token and activates login for its user.
+// @Summary Verify email
+// @Description Confirms an email address with the token from the verification link
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body verifyEmailRequest true "Verification token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/verify-email [post]
+func (h *AuthHandler) verifyEmail(c *gin.Context) {
+	var req verifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	userID, err := h.store.EmailVerifications().Verify(c.Request.Context(), hashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired verification token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify email"})
+		return
+	}
+
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Action:     "auth.email_verified",
+		TargetType: "user",
+		TargetID:   int(userID),
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+}
+
+// resendVerification issues a fresh verification link. The response is the
+// same whether or not the email is registered, so it cannot be used to probe
+// for accounts.
+// @Summary Resend verification email
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body resendVerificationRequest true "Email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/resend-verification [post]
+func (h *AuthHandler) resendVerification(c *gin.Context) {
+	var req resendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	accepted := gin.H{"message": "if the account exists and is unverified, a new link has been sent"}
+
+	user, err := h.store.Users().FindByEmail(c.Request.Context(), strings.ToLower(strings.TrimSpace(req.Email)))
+	if err != nil || user == nil || user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	token, err := newURLToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+		return
+	}
+	if err := h.store.EmailVerifications().CreateToken(c.Request.Context(), int32(user.ID), hashToken(token), h.verificationExpiry()); err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to create verification token for user %d", user.ID)
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+	h.sendVerification(c.Request.Context(), user.Email, token)
+	c.JSON(http.StatusAccepted, accepted)
+}
+
+func (h *AuthHandler) verificationExpiry() time.Time {
+	return time.Now().Add(time.Duration(h.cfg.EmailVerificationTTLHours) * time.Hour)
+}
+
+// sendVerification emails the verification link. Delivery failures are logged
+// only; the user can request a new link via /auth/resend-verification.
+func (h *AuthHandler) sendVerification(ctx context.Context, email, token string) {
+	link := fmt.Sprintf("%s/verify-email?token=%s", h.cfg.AppBaseURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Welcome to DIANA.\n\nConfirm your email address to activate your account:\n%s\n\nThis link expires in %d hours.\n",
+		link, h.cfg.EmailVerificationTTLHours)
+	if err := h.mailer.Send(ctx, email, "Verify your DIANA account", body); err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to send verification email to %s", logging.PII(email))
+	}
+}
+
+// newURLToken returns a random token safe to embed in an emailed link.
+func newURLToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
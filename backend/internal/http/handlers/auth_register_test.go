@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/config"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeVerificationRepo stores registered users and token hashes in memory
+type fakeVerificationRepo struct {
+	users  map[string]*models.User
+	tokens map[string]int32
+}
+
+func newFakeVerificationRepo() *fakeVerificationRepo {
+	return &fakeVerificationRepo{users: map[string]*models.User{}, tokens: map[string]int32{}}
+}
+
+func (f *fakeVerificationRepo) Register(ctx context.Context, u models.User, tokenHash string, expiresAt time.Time) (*models.User, error) {
+	if _, ok := f.users[u.Email]; ok {
+		return nil, store.ErrConflict
+	}
+	u.ID = int64(len(f.users) + 1)
+	f.users[u.Email] = &u
+	f.tokens[tokenHash] = int32(u.ID)
+	return &u, nil
+}
+
+func (f *fakeVerificationRepo) CreateToken(ctx context.Context, userID int32, tokenHash string, expiresAt time.Time) error {
+	f.tokens[tokenHash] = userID
+	return nil
+}
+
+func (f *fakeVerificationRepo) Verify(ctx context.Context, tokenHash string) (int32, error) {
+	id, ok := f.tokens[tokenHash]
+	if !ok {
+		return 0, pgx.ErrNoRows
+	}
+	delete(f.tokens, tokenHash)
+	for _, u := range f.users {
+		if int32(u.ID) == id {
+			now := time.Now()
+			u.EmailVerifiedAt = &now
+		}
+	}
+	return id, nil
+}
+
+// fakeMailer records sent messages
+type fakeMailer struct {
+	to, body []string
+}
+
+func (m *fakeMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.to = append(m.to, to)
+	m.body = append(m.body, body)
+	return nil
+}
+
+// tokenFromBody extracts the token query parameter from a verification email.
+func tokenFromBody(t *testing.T, body string) string {
+	t.Helper()
+	for _, line := range strings.Split(body, "\n") {
+		if strings.Contains(line, "token=") {
+			u, err := url.Parse(strings.TrimSpace(line))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return u.Query().Get("token")
+		}
+	}
+	t.Fatalf("no link in body: %s", body)
+	return ""
+}
+
+func authRouter(cfg config.Config, st store.Store, m *fakeMailer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg.JWTSecret = "test"
+	cfg.EmailVerificationTTLHours = 24
+	cfg.AppBaseURL = "http://app.test"
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	NewAuthHandler(cfg, st).WithMailer(m).Register(r.Group("/auth"))
+	return r
+}
+
+func postJSON(r *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthHandler_Register_Closed(t *testing.T) {
+	r := authRouter(config.Config{}, &fakeStore{}, &fakeMailer{})
+	w := postJSON(r, "/auth/register", `{"email":"new@example.com","password":"longenough"}`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthHandler_Register_Validation(t *testing.T) {
+	r := authRouter(config.Config{RegistrationOpen: true}, &fakeStore{verify: newFakeVerificationRepo()}, &fakeMailer{})
+	for _, body := range []string{
+		`{"email":"not-an-email","password":"longenough"}`,
+		`{"email":"new@example.com","password":"short"}`,
+	} {
+		if w := postJSON(r, "/auth/register", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestAuthHandler_Register_VerifyFlow(t *testing.T) {
+	verify := newFakeVerificationRepo()
+	mailer := &fakeMailer{}
+	st := &fakeStore{verify: verify, audit: &fakeAuditRepo{}}
+	r := authRouter(config.Config{RegistrationOpen: true}, st, mailer)
+
+	w := postJSON(r, "/auth/register", `{"email":"New@Example.com","password":"longenough"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	u := verify.users["new@example.com"]
+	if u == nil || u.Role != selfRegisteredRole || u.EmailVerifiedAt != nil {
+		t.Fatalf("expected unverified self-registered user, got %+v", u)
+	}
+	if len(mailer.to) != 1 || mailer.to[0] != "new@example.com" {
+		t.Fatalf("expected one verification email, got %v", mailer.to)
+	}
+	token := tokenFromBody(t, mailer.body[0])
+	if !strings.Contains(mailer.body[0], "http://app.test/verify-email?token=") {
+		t.Errorf("link should point at the app: %s", mailer.body[0])
+	}
+	if w := postJSON(r, "/auth/register", `{"email":"new@example.com","password":"longenough"}`); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate email, got %d", w.Code)
+	}
+
+	// The plaintext token is never stored
+	if _, ok := verify.tokens[token]; ok {
+		t.Error("token stored unhashed")
+	}
+
+	if w := postJSON(r, "/auth/verify-email", `{"token":"bogus"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown token, got %d", w.Code)
+	}
+	if w := postJSON(r, "/auth/verify-email", `{"token":"`+token+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if u.EmailVerifiedAt == nil {
+		t.Error("user not marked verified")
+	}
+	if w := postJSON(r, "/auth/verify-email", `{"token":"`+token+`"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("token should be single-use, got %d", w.Code)
+	}
+}
+
+func TestAuthHandler_Login_Unverified(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := &fakeStore{users: &fakeUserRepo{user: &models.User{ID: 3, Email: "new@example.com", PasswordHash: string(hash), Role: "clinician", IsActive: true}}}
+	r := authRouter(config.Config{}, st, &fakeMailer{})
+
+	w := postJSON(r, "/auth/login", `{"email":"new@example.com","password":"secret"}`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthHandler_ResendVerification(t *testing.T) {
+	verified := time.Now()
+	cases := []struct {
+		name     string
+		user     *models.User
+		wantSent int
+	}{
+		{"unverified user gets new link", &models.User{ID: 3, Email: "new@example.com"}, 1},
+		{"verified user gets nothing", &models.User{ID: 3, Email: "new@example.com", EmailVerifiedAt: &verified}, 0},
+		{"unknown email gets nothing", nil, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			verify := newFakeVerificationRepo()
+			mailer := &fakeMailer{}
+			st := &fakeStore{users: &fakeUserRepo{user: tc.user}, verify: verify}
+			r := authRouter(config.Config{}, st, mailer)
+
+			w := postJSON(r, "/auth/resend-verification", `{"email":"new@example.com"}`)
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+			}
+			if len(mailer.to) != tc.wantSent || len(verify.tokens) != tc.wantSent {
+				t.Fatalf("sent %d emails with %d tokens, want %d", len(mailer.to), len(verify.tokens), tc.wantSent)
+			}
+		})
+	}
+}
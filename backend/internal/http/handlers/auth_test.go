@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/config"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeUserRepo serves a single user looked up by email or ID
+type fakeUserRepo struct {
+	store.UserRepository
+	user *models.User
+}
+
+func (f *fakeUserRepo) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	if f.user == nil || f.user.Email != email {
+		return nil, errors.New("not found")
+	}
+	return f.user, nil
+}
+
+func (f *fakeUserRepo) FindByID(ctx context.Context, id int32) (*models.User, error) {
+	return f.user, nil
+}
+
+func (f *fakeUserRepo) RecordFailedLogin(ctx context.Context, id int32, threshold int, lockout time.Duration) (*time.Time, error) {
+	f.user.FailedLoginAttempts++
+	if f.user.FailedLoginAttempts < threshold {
+		return nil, nil
+	}
+	until := time.Now().Add(lockout)
+	f.user.FailedLoginAttempts = 0
+	f.user.LockedUntil = &until
+	return &until, nil
+}
+
+func (f *fakeUserRepo) UpdateLastLogin(ctx context.Context, id int32) error {
+	now := time.Now()
+	f.user.LastLoginAt = &now
+	return nil
+}
+
+func (f *fakeUserRepo) ResetFailedLogins(ctx context.Context, id int32) error {
+	f.user.FailedLoginAttempts = 0
+	return nil
+}
+
+// fakeRefreshTokenRepo tracks active sessions as a newest-last list of hashes.
+// Every token ever issued is kept in byHash for rotation tests.
+type fakeRefreshTokenRepo struct {
+	store.RefreshTokenRepository
+	active []string
+	byHash map[string]*models.RefreshToken
+}
+
+func (f *fakeRefreshTokenRepo) CreateRefreshToken(ctx context.Context, tokenHash string, userID int32, expiresAt time.Time) (*models.RefreshToken, error) {
+	f.active = append(f.active, tokenHash)
+	t := &models.RefreshToken{TokenHash: tokenHash, UserID: int64(userID), FamilyID: tokenHash, ExpiresAt: expiresAt}
+	if f.byHash == nil {
+		f.byHash = map[string]*models.RefreshToken{}
+	}
+	f.byHash[tokenHash] = t
+	return t, nil
+}
+
+func (f *fakeRefreshTokenRepo) LookupRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	t, ok := f.byHash[tokenHash]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	cp := *t
+	return &cp, nil
+}
+
+func (f *fakeRefreshTokenRepo) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*models.RefreshToken, error) {
+	old, ok := f.byHash[oldHash]
+	if !ok || old.Revoked {
+		return nil, pgx.ErrNoRows
+	}
+	old.Revoked = true
+	t := &models.RefreshToken{TokenHash: newHash, UserID: old.UserID, FamilyID: old.FamilyID, ExpiresAt: expiresAt}
+	f.byHash[newHash] = t
+	return t, nil
+}
+
+func (f *fakeRefreshTokenRepo) RecordSessionClient(ctx context.Context, tokenHash, userAgent, ipAddress string) (*models.RefreshToken, error) {
+	t, ok := f.byHash[tokenHash]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	t.UserAgent, t.IPAddress = userAgent, ipAddress
+	cp := *t
+	return &cp, nil
+}
+
+func (f *fakeRefreshTokenRepo) RevokeTokenFamily(ctx context.Context, familyID string) (int, error) {
+	n := 0
+	for _, t := range f.byHash {
+		if t.FamilyID == familyID && !t.Revoked {
+			t.Revoked = true
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (f *fakeRefreshTokenRepo) RevokeExcessUserTokens(ctx context.Context, userID int32, keep int) (int, error) {
+	if len(f.active) <= keep {
+		return 0, nil
+	}
+	revoked := len(f.active) - keep
+	f.active = f.active[revoked:]
+	return revoked, nil
+}
+
+func TestAuthHandler_Login_ConfiguredLifetimes(t *testing.T) {
+	tokens := &fakeRefreshTokenRepo{}
+	st := &fakeStore{
+		users:  &fakeUserRepo{user: mfaTestUser(t, 7, "clinician")},
+		tokens: tokens,
+	}
+	r := authRouter(config.Config{AccessTokenTTLMinutes: 5, RefreshTokenTTLDays: 1}, st, &fakeMailer{})
+
+	w := postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"secret"}`)
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.ExpiresIn != 300 {
+		t.Fatalf("expected a 5 minute access token, got %d %s", w.Code, w.Body.String())
+	}
+	token, _, err := jwt.NewParser().ParseUnverified(resp.AccessToken, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Header["kid"] != middleware.JWTKeyID("test") {
+		t.Errorf("expected the signing key in kid, got %v", token.Header["kid"])
+	}
+	for _, rt := range tokens.byHash {
+		if until := time.Until(rt.ExpiresAt); until > 24*time.Hour || until < 23*time.Hour {
+			t.Errorf("expected a 1 day refresh token, expires in %s", until)
+		}
+	}
+}
+
+func TestAuthHandler_Login_SessionLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := time.Now()
+	tokens := &fakeRefreshTokenRepo{active: []string{"old-1", "old-2"}}
+	audit := &fakeAuditRepo{}
+	st := &fakeStore{
+		users:  &fakeUserRepo{user: &models.User{ID: 7, Email: "doc@example.com", PasswordHash: string(hash), Role: "clinician", IsActive: true, EmailVerifiedAt: &verified}},
+		tokens: tokens,
+		audit:  audit,
+	}
+	h := NewAuthHandler(config.Config{JWTSecret: "test", MaxSessionsPerUser: 2}, st)
+
+	r := gin.New()
+	h.Register(r.Group("/auth"))
+
+	req, _ := http.NewRequest(http.MethodPost, "/auth/login", bytes.NewBufferString(`{"email":"doc@example.com","password":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		RevokedSessions int    `json:"revoked_sessions"`
+		Notice          string `json:"notice"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if resp.RevokedSessions != 1 || resp.Notice == "" {
+		t.Errorf("expected one revoked session with notice, got %+v", resp)
+	}
+	if len(tokens.active) != 2 || tokens.active[0] != "old-2" {
+		t.Errorf("expected oldest session revoked, active = %v", tokens.active)
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != "auth.sessions_evicted" {
+		t.Errorf("expected eviction audit event, got %+v", audit.events)
+	}
+}
+
+func TestAuthHandler_Refresh_RotatesAndDetectsReuse(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := time.Now()
+	tokens := &fakeRefreshTokenRepo{}
+	audit := &fakeAuditRepo{}
+	st := &fakeStore{
+		users:  &fakeUserRepo{user: &models.User{ID: 7, Email: "doc@example.com", PasswordHash: string(hash), Role: "clinician", IsActive: true, EmailVerifiedAt: &verified}},
+		tokens: tokens,
+		audit:  audit,
+	}
+	r := authRouter(config.Config{}, st, &fakeMailer{})
+
+	refreshOf := func(w *httptest.ResponseRecorder) string {
+		t.Helper()
+		var resp struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.RefreshToken == "" {
+			t.Fatalf("no refresh token in %s", w.Body.String())
+		}
+		return resp.RefreshToken
+	}
+
+	w := postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"secret"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: expected 200, got %d", w.Code)
+	}
+	first := refreshOf(w)
+
+	w = postJSON(r, "/auth/refresh", `{"refresh_token":"`+first+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("refresh: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	second := refreshOf(w)
+	if second == first {
+		t.Fatal("expected a new refresh token")
+	}
+	if !tokens.byHash[hashToken(first)].Revoked {
+		t.Fatal("expected presented token to be revoked")
+	}
+
+	// Replaying the rotated token revokes the whole family, including the
+	// token issued by the rotation.
+	w = postJSON(r, "/auth/refresh", `{"refresh_token":"`+first+`"}`)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("replay: expected 401, got %d", w.Code)
+	}
+	if !tokens.byHash[hashToken(second)].Revoked {
+		t.Fatal("expected token family to be revoked after reuse")
+	}
+	if last := audit.events[len(audit.events)-1]; last.Action != "auth.refresh_reuse" || last.TargetID != 7 {
+		t.Fatalf("expected reuse audit event, got %+v", last)
+	}
+	if w := postJSON(r, "/auth/refresh", `{"refresh_token":"`+second+`"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked family: expected 401, got %d", w.Code)
+	}
+	if w := postJSON(r, "/auth/refresh", `{"refresh_token":"unknown"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown token: expected 401, got %d", w.Code)
+	}
+}
+
+func TestAuthHandler_Login_LocksAfterRepeatedFailures(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := time.Now()
+	user := &models.User{ID: 7, Email: "doc@example.com", PasswordHash: string(hash), Role: "clinician", IsActive: true, EmailVerifiedAt: &verified}
+	audit := &fakeAuditRepo{}
+	st := &fakeStore{users: &fakeUserRepo{user: user}, tokens: &fakeRefreshTokenRepo{}, audit: audit}
+	r := authRouter(config.Config{LoginLockoutThreshold: 3, LoginLockoutMinutes: 15}, st, &fakeMailer{})
+
+	// A success in between resets the count
+	postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"wrong"}`)
+	if w := postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"secret"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if user.FailedLoginAttempts != 0 {
+		t.Fatalf("failed attempts = %d after success, want 0", user.FailedLoginAttempts)
+	}
+
+	for i := 0; i < 3; i++ {
+		if w := postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"wrong"}`); w.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, w.Code)
+		}
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != "auth.account_locked" || audit.events[0].TargetID != 7 {
+		t.Fatalf("expected one lockout audit event, got %+v", audit.events)
+	}
+
+	// Even the right password is refused while locked
+	w := postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"secret"}`)
+	if w.Code != http.StatusLocked {
+		t.Fatalf("expected 423 while locked, got %d", w.Code)
+	}
+	if ra, _ := strconv.Atoi(w.Header().Get("Retry-After")); ra < 14*60 || ra > 15*60 {
+		t.Fatalf("Retry-After = %q, want about 900", w.Header().Get("Retry-After"))
+	}
+
+	// Once the lock expires the password works again
+	expired := time.Now().Add(-time.Second)
+	user.LockedUntil = &expired
+	if w := postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"secret"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 after lock expiry, got %d", w.Code)
+	}
+}
+
+func TestAuthHandler_DeactivatedUser(t *testing.T) {
+	user := mfaTestUser(t, 7, "clinician")
+	mem := store.NewMemoryStore()
+	st := &fakeStore{users: &fakeUserRepo{user: user}, tokens: mem.RefreshTokens(), audit: &fakeAuditRepo{}}
+	r := authRouter(config.Config{}, st, &fakeMailer{})
+	ctx := context.Background()
+
+	w := postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"secret"}`)
+	var resp struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: expected 200, got %d", w.Code)
+	}
+	token, _, _ := jwt.NewParser().ParseUnverified(resp.AccessToken, jwt.MapClaims{})
+	sid, _ := token.Claims.(jwt.MapClaims)["sid"].(string)
+	lookup := ActiveUserLookup(st)
+	if ok, err := lookup(ctx, 7, sid); err != nil || !ok {
+		t.Fatalf("expected the new session to be active, got %v %v", ok, err)
+	}
+	if ok, _ := lookup(ctx, 7, "signed-out"); ok {
+		t.Error("expected an unknown session to be refused")
+	}
+
+	user.IsActive = false
+	if ok, _ := lookup(ctx, 7, ""); ok {
+		t.Error("expected a deactivated user to be refused")
+	}
+	if w := postJSON(r, "/auth/refresh", `{"refresh_token":"`+resp.RefreshToken+`"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh: expected 401, got %d", w.Code)
+	}
+	if sessions, _ := mem.RefreshTokens().ListUserSessions(ctx, 7); len(sessions) != 0 {
+		t.Errorf("expected every session revoked, got %+v", sessions)
+	}
+	if w := postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"secret"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("login: expected 403, got %d", w.Code)
+	}
+	if w := postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"wrong"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: expected 401, got %d", w.Code)
+	}
+}
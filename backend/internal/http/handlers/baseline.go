@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// baselineField pairs a patient baseline field with the value an assessment
+// recorded for it.
+type baselineField struct {
+	name     string
+	baseline *string
+	value    string
+}
+
+func baselineFields(p *models.Patient, a models.Assessment) []baselineField {
+	return []baselineField{
+		{"smoking", &p.Smoking, a.Smoking},
+		{"hypertension", &p.Hypertension, a.Hypertension},
+		{"heart_disease", &p.HeartDisease, a.HeartDisease},
+	}
+}
+
+// baselineDiffs lists where the assessment disagrees with the patient's
+// baseline. Fields the assessment left blank were not recorded and never
+// count as a disagreement.
+func baselineDiffs(p models.Patient, a models.Assessment) []models.BaselineDiscrepancy {
+	var diffs []models.BaselineDiscrepancy
+	for _, f := range baselineFields(&p, a) {
+		if f.value == "" || f.value == *f.baseline {
+			continue
+		}
+		diffs = append(diffs, models.BaselineDiscrepancy{
+			PatientID:       a.PatientID,
+			AssessmentID:    a.ID,
+			Field:           f.name,
+			BaselineValue:   *f.baseline,
+			AssessmentValue: f.value,
+		})
+	}
+	return diffs
+}
+
+// setBaselineField sets one baseline field on p, reporting whether the field
+// is known.
+func setBaselineField(p *models.Patient, field, value string) bool {
+	for _, f := range baselineFields(p, models.Assessment{}) {
+		if f.name == field {
+			*f.baseline = value
+			return true
+		}
+	}
+	return false
+}
+
+// BaselineChecker compares new assessments with the patient baseline and,
+// depending on the clinic's baseline policy, either updates the baseline or
+// records the discrepancies for review.
+type BaselineChecker struct {
+	store store.Store
+}
+
+func NewBaselineChecker(store store.Store) *BaselineChecker {
+	return &BaselineChecker{store: store}
+}
+
+// Subscribe checks assessment.created events on bus.
+func (b *BaselineChecker) Subscribe(bus *events.Bus) {
+	events.Subscribe(bus, "baseline", b.check)
+}
+
+func (b *BaselineChecker) check(ctx context.Context, e events.AssessmentCreated) error {
+	a := e.Assessment
+	patient, err := b.store.Patients().Get(ctx, int32(a.PatientID), e.UserID)
+	if err != nil {
+		return err
+	}
+	diffs := baselineDiffs(*patient, a)
+	if len(diffs) == 0 {
+		return nil
+	}
+
+	policy, err := b.store.Clinics().BaselinePolicyForUser(ctx, e.UserID)
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to load baseline policy for user %d, flagging", e.UserID)
+		policy = models.BaselinePolicyFlag
+	}
+	if policy != models.BaselinePolicyUpdate {
+		_, err := b.store.BaselineDiscrepancies().Create(ctx, diffs)
+		return err
+	}
+
+	fields := make([]string, 0, len(diffs))
+	for _, d := range diffs {
+		setBaselineField(patient, d.Field, d.AssessmentValue)
+		fields = append(fields, d.Field)
+	}
+	if _, err := b.store.Patients().Update(ctx, *patient); err != nil {
+		return err
+	}
+	_ = b.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      e.Actor,
+		Action:     "patient.baseline_update",
+		TargetType: "patient",
+		TargetID:   int(a.PatientID),
+		Details: map[string]interface{}{
+			"fields":        fields,
+			"assessment_id": a.ID,
+		},
+	})
+	return nil
+}
+
+// ResolveDiscrepancyRequest defines how a baseline discrepancy is closed:
+// apply copies the assessment value to the baseline, dismiss keeps the baseline.
+type ResolveDiscrepancyRequest struct {
+	Action string `json:"action" binding:"required,oneof=apply dismiss"`
+}
+
+// listDiscrepancies returns the patient's open baseline discrepancies
+func (h *PatientsHandler) listDiscrepancies(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	if _, err := h.store.Patients().GetVisible(c.Request.Context(), int32(id), userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+
+	items, err := h.store.BaselineDiscrepancies().ListOpen(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load baseline discrepancies"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": items})
+}
+
+// resolveDiscrepancy closes an open discrepancy, updating the baseline first
+// when the assessment value is applied.
+func (h *PatientsHandler) resolveDiscrepancy(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	discrepancyID, err := parseIDParam(c, "discrepancyID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid discrepancy ID"})
+		return
+	}
+	var req ResolveDiscrepancyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be 'apply' or 'dismiss'"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	patient, err := h.store.Patients().Get(ctx, int32(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	open, err := h.store.BaselineDiscrepancies().ListOpen(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load baseline discrepancies"})
+		return
+	}
+	var target *models.BaselineDiscrepancy
+	for i := range open {
+		if open[i].ID == discrepancyID {
+			target = &open[i]
+		}
+	}
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "discrepancy not found or already resolved"})
+		return
+	}
+
+	resolution := models.BaselineResolutionDismissed
+	if req.Action == "apply" {
+		resolution = models.BaselineResolutionApplied
+		setBaselineField(patient, target.Field, target.AssessmentValue)
+		if _, err := h.store.Patients().Update(ctx, *patient); err != nil {
+			logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to apply baseline discrepancy %d", discrepancyID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update patient"})
+			return
+		}
+	}
+
+	resolved, err := h.store.BaselineDiscrepancies().Resolve(ctx, discrepancyID, id, resolution, int64(userID))
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "discrepancy not found or already resolved")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve discrepancy"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "patient.baseline_resolve",
+		TargetType: "patient",
+		TargetID:   int(id),
+		Details: map[string]interface{}{
+			"discrepancy_id": resolved.ID,
+			"field":          resolved.Field,
+			"resolution":     resolved.Resolution,
+		},
+	})
+	c.JSON(http.StatusOK, resolved)
+}
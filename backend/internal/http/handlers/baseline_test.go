@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+type fakeBaselineRepo struct {
+	items []models.BaselineDiscrepancy
+}
+
+func (f *fakeBaselineRepo) Create(ctx context.Context, items []models.BaselineDiscrepancy) ([]models.BaselineDiscrepancy, error) {
+	for _, d := range items {
+		d.ID = int64(len(f.items) + 1)
+		d.CreatedAt = time.Now()
+		f.items = append(f.items, d)
+	}
+	return items, nil
+}
+
+func (f *fakeBaselineRepo) ListOpen(ctx context.Context, patientID int64) ([]models.BaselineDiscrepancy, error) {
+	out := []models.BaselineDiscrepancy{}
+	for _, d := range f.items {
+		if d.PatientID == patientID && d.ResolvedAt == nil {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeBaselineRepo) Resolve(ctx context.Context, id, patientID int64, resolution string, userID int64) (*models.BaselineDiscrepancy, error) {
+	for i := range f.items {
+		d := &f.items[i]
+		if d.ID == id && d.PatientID == patientID && d.ResolvedAt == nil {
+			now := time.Now()
+			d.Resolution, d.ResolvedBy, d.ResolvedAt = resolution, &userID, &now
+			return d, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func TestBaselineDiffs(t *testing.T) {
+	p := models.Patient{ID: 3, Smoking: "never", Hypertension: "no"}
+	a := models.Assessment{ID: 8, PatientID: 3, Smoking: "current", Hypertension: "no", HeartDisease: "yes"}
+
+	diffs := baselineDiffs(p, a)
+	if len(diffs) != 2 {
+		t.Fatalf("expected 2 discrepancies, got %+v", diffs)
+	}
+	if d := diffs[0]; d.Field != "smoking" || d.BaselineValue != "never" || d.AssessmentValue != "current" || d.AssessmentID != 8 {
+		t.Fatalf("unexpected smoking discrepancy %+v", d)
+	}
+	if d := diffs[1]; d.Field != "heart_disease" || d.BaselineValue != "" {
+		t.Fatalf("unexpected heart disease discrepancy %+v", d)
+	}
+
+	// Blank assessment values were not recorded and never disagree
+	if diffs := baselineDiffs(p, models.Assessment{PatientID: 3}); len(diffs) != 0 {
+		t.Fatalf("expected no discrepancies, got %+v", diffs)
+	}
+}
+
+func baselineRouter(st *fakeStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	bus := events.NewBus()
+	NewBaselineChecker(st).Subscribe(bus)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler(), mockAuthMiddleware())
+	rg := r.Group("/patients")
+	NewPatientsHandler(st).Register(rg)
+	NewAssessmentsHandler(st, ml.NewMockPredictor(), "v1", "hash123").WithEvents(bus).Register(rg)
+	return r
+}
+
+func TestBaselineChecker_Policies(t *testing.T) {
+	body := `{"fbs":95,"hba1c":5.4,"bmi":24,"smoking":"current","hypertension":"no"}`
+
+	t.Run("flag", func(t *testing.T) {
+		patients := &fakePatientRepo{stored: &models.Patient{ID: 7, UserID: 1, Name: "Ana", Smoking: "never", Hypertension: "no"}}
+		st := &fakeStore{repo: &fakeAssessmentRepo{}, patientRepo: patients}
+		r := baselineRouter(st)
+
+		if w := contactRequest(r, http.MethodPost, "/patients/7/assessments", body); w.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+		if patients.lastUpdate != nil {
+			t.Fatal("flag policy must not touch the baseline")
+		}
+
+		w := contactRequest(r, http.MethodGet, "/patients/7/baseline-discrepancies", "")
+		var got struct {
+			Data []models.BaselineDiscrepancy `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &got)
+		if len(got.Data) != 1 || got.Data[0].Field != "smoking" || got.Data[0].AssessmentValue != "current" {
+			t.Fatalf("unexpected discrepancies %+v", got.Data)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		patients := &fakePatientRepo{stored: &models.Patient{ID: 7, UserID: 1, Name: "Ana", Smoking: "never", Hypertension: "no"}}
+		audit := &fakeAuditRepo{}
+		st := &fakeStore{
+			repo: &fakeAssessmentRepo{}, patientRepo: patients, audit: audit, baseline: &fakeBaselineRepo{},
+			clinicRepo: &fakeClinicRepo{mode: models.ValidationModeAdvisory, policy: models.BaselinePolicyUpdate},
+		}
+		r := baselineRouter(st)
+
+		if w := contactRequest(r, http.MethodPost, "/patients/7/assessments", body); w.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d", w.Code)
+		}
+		if patients.lastUpdate == nil || patients.lastUpdate.Smoking != "current" || patients.lastUpdate.Name != "Ana" {
+			t.Fatalf("baseline not updated: %+v", patients.lastUpdate)
+		}
+		if len(st.baseline.items) != 0 {
+			t.Fatal("update policy must not record discrepancies")
+		}
+		if last := audit.events[len(audit.events)-1]; last.Action != "patient.baseline_update" || last.TargetID != 7 {
+			t.Fatalf("unexpected audit event %+v", last)
+		}
+	})
+}
+
+func TestBaselineDiscrepancy_Resolve(t *testing.T) {
+	patients := &fakePatientRepo{stored: &models.Patient{ID: 7, UserID: 1, Name: "Ana", Smoking: "never"}}
+	baseline := &fakeBaselineRepo{items: []models.BaselineDiscrepancy{
+		{ID: 1, PatientID: 7, AssessmentID: 2, Field: "smoking", BaselineValue: "never", AssessmentValue: "former"},
+		{ID: 2, PatientID: 7, AssessmentID: 2, Field: "hypertension", BaselineValue: "", AssessmentValue: "yes"},
+	}}
+	st := &fakeStore{patientRepo: patients, baseline: baseline}
+	r := baselineRouter(st)
+
+	if w := contactRequest(r, http.MethodPost, "/patients/7/baseline-discrepancies/1/resolve", `{"action":"ignore"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown action: expected 400, got %d", w.Code)
+	}
+	if w := contactRequest(r, http.MethodPost, "/patients/7/baseline-discrepancies/1/resolve", `{"action":"apply"}`); w.Code != http.StatusOK {
+		t.Fatalf("apply: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if patients.lastUpdate == nil || patients.lastUpdate.Smoking != "former" {
+		t.Fatalf("baseline not updated: %+v", patients.lastUpdate)
+	}
+
+	patients.lastUpdate = nil
+	if w := contactRequest(r, http.MethodPost, "/patients/7/baseline-discrepancies/2/resolve", `{"action":"dismiss"}`); w.Code != http.StatusOK {
+		t.Fatalf("dismiss: expected 200, got %d", w.Code)
+	}
+	if patients.lastUpdate != nil || baseline.items[1].Resolution != models.BaselineResolutionDismissed {
+		t.Fatalf("dismiss must only close the discrepancy: %+v", baseline.items[1])
+	}
+
+	if w := contactRequest(r, http.MethodPost, "/patients/7/baseline-discrepancies/1/resolve", `{"action":"dismiss"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("already resolved: expected 404, got %d", w.Code)
+	}
+}
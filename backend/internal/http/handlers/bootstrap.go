@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// bootstrapMaxAge is how long clients may reuse a complete bootstrap
+// response without revalidating.
+const bootstrapMaxAge = "60"
+
+// Bootstrap is everything the frontend needs on app load. A section that
+// failed to load is nil and named in Partial.
+type Bootstrap struct {
+	User       *models.User        `json:"user"`
+	Features   map[string]bool     `json:"features"`
+	Clinics    []models.UserClinic `json:"clinics"`
+	Biomarkers []ml.PlausibleRange `json:"biomarkers"`
+	Partial    []string            `json:"partial,omitempty"`
+}
+
+// BootstrapHandler serves GET /bootstrap
+type BootstrapHandler struct {
+	store    store.Store
+	features map[string]bool
+}
+
+// NewBootstrapHandler creates a BootstrapHandler. features are the
+// deployment-wide flags; per-user flags are added on each request.
+func NewBootstrapHandler(store store.Store, features map[string]bool) *BootstrapHandler {
+	return &BootstrapHandler{store: store, features: features}
+}
+
+// Register registers the bootstrap route on the given router group
+func (h *BootstrapHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("/bootstrap", h.bootstrap)
+}
+
+// bootstrap returns the caller's profile, feature flags, clinic memberships
+// and biomarker metadata in one response
+// @Summary App bootstrap data
+// @Description Returns the user profile, feature flags, clinic memberships and biomarker ranges. Sections that fail to load are null and listed in partial; complete responses carry an ETag.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} Bootstrap
+// @Success 304
+// @Router /bootstrap [get]
+func (h *BootstrapHandler) bootstrap(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	ctx := c.Request.Context()
+	out := Bootstrap{Biomarkers: ml.PlausibleRanges}
+
+	if user, err := h.store.Users().FindByID(ctx, userID); err != nil || user == nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("bootstrap: failed to load user %d", userID)
+		out.Partial = append(out.Partial, "user")
+	} else {
+		out.User = user
+	}
+
+	if clinics, err := h.store.Clinics().ListUserClinics(ctx, userID); err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("bootstrap: failed to load clinics for user %d", userID)
+		out.Partial = append(out.Partial, "clinics")
+	} else {
+		out.Clinics = clinics
+	}
+
+	if photos, err := h.store.Clinics().PatientPhotosEnabledForUser(ctx, userID); err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("bootstrap: failed to load photo policy for user %d", userID)
+		out.Partial = append(out.Partial, "features")
+	} else {
+		out.Features = make(map[string]bool, len(h.features)+1)
+		for name, on := range h.features {
+			out.Features[name] = on
+		}
+		out.Features["patient_photos"] = photos
+	}
+
+	body, err := json.Marshal(out)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode bootstrap"})
+		return
+	}
+
+	// A partial response must not be cached, or the missing section would
+	// stay missing after the failure clears
+	c.Header("Vary", "Authorization")
+	if len(out.Partial) > 0 {
+		c.Header("Cache-Control", "private, no-store")
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("Cache-Control", "private, max-age="+bootstrapMaxAge)
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+type fakeBootstrapClinicRepo struct {
+	store.ClinicRepository
+	err error
+}
+
+func (f *fakeBootstrapClinicRepo) ListUserClinics(ctx context.Context, userID int32) ([]models.UserClinic, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []models.UserClinic{{Clinic: models.Clinic{ID: 4, Name: "North"}, Role: models.ClinicRoleMember}}, nil
+}
+
+func (f *fakeBootstrapClinicRepo) PatientPhotosEnabledForUser(ctx context.Context, userID int32) (bool, error) {
+	return true, nil
+}
+
+func bootstrapRequest(r *gin.Engine, etag string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/bootstrap", nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestBootstrap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(clinicErr error) *gin.Engine {
+		st := &fakeStore{
+			users:      &fakeUserRepo{user: &models.User{ID: 1, Email: "test@example.com", Role: "admin"}},
+			clinicRepo: &fakeBootstrapClinicRepo{err: clinicErr},
+		}
+		r := gin.New()
+		r.Use(mockAuthMiddleware())
+		NewBootstrapHandler(st, map[string]bool{"live_model": true}).Register(r.Group(""))
+		return r
+	}
+
+	t.Run("complete", func(t *testing.T) {
+		r := newRouter(nil)
+		w := bootstrapRequest(r, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var got Bootstrap
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("invalid json: %v", err)
+		}
+		if got.User == nil || got.User.Email != "test@example.com" || len(got.Clinics) != 1 || len(got.Partial) != 0 {
+			t.Fatalf("unexpected bootstrap %+v", got)
+		}
+		if !got.Features["live_model"] || !got.Features["patient_photos"] || len(got.Biomarkers) == 0 {
+			t.Fatalf("expected features and biomarkers, got %+v", got)
+		}
+		etag := w.Header().Get("ETag")
+		if etag == "" || w.Header().Get("Cache-Control") != "private, max-age=60" {
+			t.Fatalf("expected cache headers, got %v", w.Header())
+		}
+		if w := bootstrapRequest(r, etag); w.Code != http.StatusNotModified {
+			t.Fatalf("matching If-None-Match: expected 304, got %d", w.Code)
+		}
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		w := bootstrapRequest(newRouter(errors.New("db down")), "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200 despite a failed section, got %d", w.Code)
+		}
+		var got Bootstrap
+		_ = json.Unmarshal(w.Body.Bytes(), &got)
+		if got.User == nil || got.Clinics != nil || len(got.Partial) != 1 || got.Partial[0] != "clinics" {
+			t.Fatalf("expected only clinics to be missing, got %+v", got)
+		}
+		if w.Header().Get("ETag") != "" || w.Header().Get("Cache-Control") != "private, no-store" {
+			t.Fatalf("partial response must not be cached, got %v", w.Header())
+		}
+	})
+}
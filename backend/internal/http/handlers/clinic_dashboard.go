@@ -1,28 +1,81 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
 	"github.com/skufu/DianaV2/backend/internal/store"
 )
 
+// ClinicMembershipLookup resolves the caller's clinics against the store for
+// middleware.AccessScope.
+func ClinicMembershipLookup(st store.Store) middleware.ClinicMembershipLookup {
+	return func(ctx context.Context, userID int64) ([]int32, error) {
+		clinics, err := st.Clinics().ListUserClinics(ctx, int32(userID))
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]int32, 0, len(clinics))
+		for _, uc := range clinics {
+			ids = append(ids, int32(uc.ID))
+		}
+		return ids, nil
+	}
+}
+
 // ClinicDashboardHandler handles clinic-level dashboard endpoints
 type ClinicDashboardHandler struct {
 	store store.Store
+	perms middleware.PermissionLookup
 }
 
 // NewClinicDashboardHandler creates a new ClinicDashboardHandler
 func NewClinicDashboardHandler(store store.Store) *ClinicDashboardHandler {
-	return &ClinicDashboardHandler{store: store}
+	return &ClinicDashboardHandler{store: store, perms: PermissionLookup(store)}
 }
 
-// Register registers clinic dashboard routes on the given router group
+// Register registers clinic dashboard routes on the given router group.
+// Routes about one clinic need clinic.read or clinic.manage, held globally
+// or through the caller's role in that clinic.
 func (h *ClinicDashboardHandler) Register(rg *gin.RouterGroup) {
+	read := middleware.ClinicPermissionRequired(h.perms, models.PermClinicRead)
+	manage := middleware.ClinicPermissionRequired(h.perms, models.PermClinicManage)
 	rg.GET("", h.listClinics)
-	rg.GET("/:id/dashboard", h.getClinicDashboard)
+	rg.GET("/:id/dashboard", manage, h.getClinicDashboard)
+	rg.GET("/:id/validation-mode", manage, h.getValidationMode)
+	rg.PUT("/:id/validation-mode", manage, h.setValidationMode)
+	rg.GET("/:id/patient-photos", manage, h.getPatientPhotos)
+	rg.PUT("/:id/patient-photos", manage, h.setPatientPhotos)
+	rg.GET("/:id/baseline-policy", manage, h.getBaselinePolicy)
+	rg.PUT("/:id/baseline-policy", manage, h.setBaselinePolicy)
+	rg.GET("/:id/report-settings", manage, h.getReportSettings)
+	rg.PUT("/:id/report-settings", manage, h.setReportSettings)
+	rg.GET("/:id/report-logo", manage, h.getReportLogo)
+	rg.PUT("/:id/report-logo", manage, h.uploadReportLogo)
+	rg.DELETE("/:id/report-logo", manage, h.deleteReportLogo)
+	rg.GET("/:id/members", read, h.listMembers)
+	rg.POST("/:id/members", manage, h.addMember)
+	rg.DELETE("/:id/members/:userID", manage, h.removeMember)
+	rg.PUT("/:id/members/:userID/role", manage, h.setMemberRole)
+}
+
+// ValidationModeRequest defines the payload for changing a clinic's validation mode
+type ValidationModeRequest struct {
+	Mode string `json:"mode" binding:"required,oneof=strict advisory"`
+}
+
+// PatientPhotosRequest defines the payload for toggling patient photos in a clinic
+type PatientPhotosRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// BaselinePolicyRequest defines the payload for changing a clinic's baseline policy
+type BaselinePolicyRequest struct {
+	Policy string `json:"policy" binding:"required,oneof=update flag"`
 }
 
 // listClinics returns all clinics the user belongs to
@@ -57,24 +110,8 @@ func (h *ClinicDashboardHandler) listClinics(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /clinics/{id}/dashboard [get]
 func (h *ClinicDashboardHandler) getClinicDashboard(c *gin.Context) {
-	claims := c.MustGet("user").(middleware.UserClaims)
-	clinicIDStr := c.Param("id")
-
-	clinicID, err := strconv.Atoi(clinicIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid clinic ID"})
-		return
-	}
-
-	// Check if user is clinic_admin for this clinic or system admin
-	isAdmin, err := h.store.Clinics().IsClinicAdmin(c.Request.Context(), int32(claims.UserID), int32(clinicID))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify access"})
-		return
-	}
-
-	if !isAdmin && claims.Role != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied - clinic_admin role required"})
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
 		return
 	}
 
@@ -92,8 +129,8 @@ func (h *ClinicDashboardHandler) getClinicDashboard(c *gin.Context) {
 		return
 	}
 
-	// Get cluster distribution from assessments
-	clusterDist, _ := h.store.Assessments().ClusterCounts(c.Request.Context())
+	// Get cluster distribution from the clinic members' assessments
+	clusterDist, _ := h.store.Assessments().ClusterCountsInScope(c.Request.Context(), models.CohortScope{ClinicID: &clinicID})
 
 	c.JSON(http.StatusOK, gin.H{
 		"clinic_id":            clinic.ID,
@@ -102,3 +139,240 @@ func (h *ClinicDashboardHandler) getClinicDashboard(c *gin.Context) {
 		"cluster_distribution": clusterDist,
 	})
 }
+
+// getValidationMode returns the biomarker validation mode for a clinic
+// @Summary Get clinic validation mode
+// @Description Returns whether implausible biomarkers are rejected (strict) or stored with warnings (advisory)
+// @Tags Clinics
+// @Produce json
+// @Param id path int true "Clinic ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /clinics/{id}/validation-mode [get]
+func (h *ClinicDashboardHandler) getValidationMode(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+
+	mode, err := h.store.Clinics().GetValidationMode(c.Request.Context(), clinicID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "clinic not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clinic_id":       clinicID,
+		"validation_mode": mode,
+	})
+}
+
+// setValidationMode switches a clinic between strict and advisory validation
+// @Summary Set clinic validation mode
+// @Description Strict mode rejects implausible biomarkers with 422; advisory mode stores them with warnings (clinic_admin only)
+// @Tags Clinics
+// @Accept json
+// @Produce json
+// @Param id path int true "Clinic ID"
+// @Param mode body ValidationModeRequest true "Validation mode"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /clinics/{id}/validation-mode [put]
+func (h *ClinicDashboardHandler) setValidationMode(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req ValidationModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be 'strict' or 'advisory'"})
+		return
+	}
+
+	if err := h.store.Clinics().SetValidationMode(c.Request.Context(), clinicID, req.Mode); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "clinic not found"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "clinic.validation_mode",
+		TargetType: "clinic",
+		TargetID:   int(clinicID),
+		Details: map[string]interface{}{
+			"mode": req.Mode,
+		},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"clinic_id":       clinicID,
+		"validation_mode": req.Mode,
+	})
+}
+
+// getPatientPhotos reports whether patient photos are enabled for a clinic
+// @Summary Get clinic patient photo setting
+// @Tags Clinics
+// @Produce json
+// @Param id path int true "Clinic ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /clinics/{id}/patient-photos [get]
+func (h *ClinicDashboardHandler) getPatientPhotos(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+
+	enabled, err := h.store.Clinics().GetPatientPhotosEnabled(c.Request.Context(), clinicID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "clinic not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clinic_id":              clinicID,
+		"patient_photos_enabled": enabled,
+	})
+}
+
+// setPatientPhotos enables or disables patient photos for a clinic
+// @Summary Set clinic patient photo setting
+// @Description Disabling photos blocks upload and viewing for every member of the clinic (clinic_admin only)
+// @Tags Clinics
+// @Accept json
+// @Produce json
+// @Param id path int true "Clinic ID"
+// @Param setting body PatientPhotosRequest true "Photo setting"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /clinics/{id}/patient-photos [put]
+func (h *ClinicDashboardHandler) setPatientPhotos(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req PatientPhotosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "enabled must be true or false"})
+		return
+	}
+
+	if err := h.store.Clinics().SetPatientPhotosEnabled(c.Request.Context(), clinicID, *req.Enabled); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "clinic not found"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "clinic.patient_photos",
+		TargetType: "clinic",
+		TargetID:   int(clinicID),
+		Details: map[string]interface{}{
+			"enabled": *req.Enabled,
+		},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"clinic_id":              clinicID,
+		"patient_photos_enabled": *req.Enabled,
+	})
+}
+
+// getBaselinePolicy returns how a clinic handles assessments that disagree
+// with the patient baseline
+// @Summary Get clinic baseline policy
+// @Tags Clinics
+// @Produce json
+// @Param id path int true "Clinic ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /clinics/{id}/baseline-policy [get]
+func (h *ClinicDashboardHandler) getBaselinePolicy(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+
+	policy, err := h.store.Clinics().GetBaselinePolicy(c.Request.Context(), clinicID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "clinic not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clinic_id":       clinicID,
+		"baseline_policy": policy,
+	})
+}
+
+// setBaselinePolicy switches a clinic between updating the baseline and
+// flagging discrepancies
+// @Summary Set clinic baseline policy
+// @Description Update overwrites the patient's smoking/hypertension/heart disease baseline from new assessments; flag records discrepancies for review (clinic_admin only)
+// @Tags Clinics
+// @Accept json
+// @Produce json
+// @Param id path int true "Clinic ID"
+// @Param policy body BaselinePolicyRequest true "Baseline policy"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /clinics/{id}/baseline-policy [put]
+func (h *ClinicDashboardHandler) setBaselinePolicy(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req BaselinePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "policy must be 'update' or 'flag'"})
+		return
+	}
+
+	if err := h.store.Clinics().SetBaselinePolicy(c.Request.Context(), clinicID, req.Policy); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "clinic not found"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "clinic.baseline_policy",
+		TargetType: "clinic",
+		TargetID:   int(clinicID),
+		Details: map[string]interface{}{
+			"policy": req.Policy,
+		},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"clinic_id":       clinicID,
+		"baseline_policy": req.Policy,
+	})
+}
+
+// clinicIDParam parses the clinic ID; access was checked by the route's
+// ClinicPermissionRequired. Returns false if a response has already been
+// written.
+func clinicIDParam(c *gin.Context) (int32, bool) {
+	clinicID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid clinic ID"})
+		return 0, false
+	}
+	return int32(clinicID), true
+}
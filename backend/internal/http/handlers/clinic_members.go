@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// AddClinicMemberRequest defines the payload for adding a user to a clinic
+type AddClinicMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"omitempty,oneof=member clinic_admin"`
+}
+
+// ClinicRoleRequest defines the payload for changing a member's clinic role
+type ClinicRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=member clinic_admin"`
+}
+
+// listMembers returns the clinic directory with roles and activity stats
+// @Summary List clinic members
+// @Description Returns clinic members with clinic role, global role and activity (needs clinic.read for the clinic)
+// @Tags Clinics
+// @Produce json
+// @Param id path int true "Clinic ID"
+// @Success 200 {array} models.ClinicMember
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /clinics/{id}/members [get]
+func (h *ClinicDashboardHandler) listMembers(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.store.Clinics().Get(c.Request.Context(), clinicID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "clinic not found"})
+		return
+	}
+
+	members, err := h.store.Clinics().ListMembers(c.Request.Context(), clinicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load clinic members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// addMember adds an existing user to the clinic
+// @Summary Add clinic member
+// @Description Adds a registered user to the clinic by email with a clinic role (clinic_admin only)
+// @Tags Clinics
+// @Accept json
+// @Produce json
+// @Param id path int true "Clinic ID"
+// @Param member body AddClinicMemberRequest true "Member"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /clinics/{id}/members [post]
+func (h *ClinicDashboardHandler) addMember(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req AddClinicMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.ClinicRoleMember
+	}
+
+	user, err := h.store.Users().FindByEmail(c.Request.Context(), req.Email)
+	if err != nil || user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if !user.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user is deactivated"})
+		return
+	}
+
+	if err := h.store.Clinics().AddMember(c.Request.Context(), clinicID, int32(user.ID), req.Role); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			abortWithError(c, err, "user is already a member of this clinic")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add member"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "clinic.member_add",
+		TargetType: "clinic",
+		TargetID:   int(clinicID),
+		Details: map[string]interface{}{
+			"user_id":     user.ID,
+			"email":       user.Email,
+			"clinic_role": req.Role,
+		},
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"clinic_id":   clinicID,
+		"user_id":     user.ID,
+		"email":       user.Email,
+		"clinic_role": req.Role,
+	})
+}
+
+// removeMember removes a user from the clinic
+// @Summary Remove clinic member
+// @Description Removes a member from the clinic; the last clinic_admin cannot be removed (clinic_admin only)
+// @Tags Clinics
+// @Produce json
+// @Param id path int true "Clinic ID"
+// @Param userID path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /clinics/{id}/members/{userID} [delete]
+func (h *ClinicDashboardHandler) removeMember(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+	member, admins, ok := h.loadMember(c, clinicID)
+	if !ok {
+		return
+	}
+	if member.ClinicRole == models.ClinicRoleAdmin && admins == 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "cannot remove the last clinic_admin"})
+		return
+	}
+
+	if err := h.store.Clinics().RemoveMember(c.Request.Context(), clinicID, int32(member.UserID)); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			abortWithError(c, err, "member not found")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove member"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "clinic.member_remove",
+		TargetType: "clinic",
+		TargetID:   int(clinicID),
+		Details: map[string]interface{}{
+			"user_id": member.UserID,
+			"email":   member.Email,
+		},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "member removed"})
+}
+
+// setMemberRole promotes or demotes a clinic member
+// @Summary Change clinic role
+// @Description Promotes a member to clinic_admin or demotes to member; the last clinic_admin cannot be demoted (clinic_admin only)
+// @Tags Clinics
+// @Accept json
+// @Produce json
+// @Param id path int true "Clinic ID"
+// @Param userID path int true "User ID"
+// @Param role body ClinicRoleRequest true "Clinic role"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /clinics/{id}/members/{userID}/role [put]
+func (h *ClinicDashboardHandler) setMemberRole(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req ClinicRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be 'member' or 'clinic_admin'"})
+		return
+	}
+
+	member, admins, ok := h.loadMember(c, clinicID)
+	if !ok {
+		return
+	}
+	if member.ClinicRole == models.ClinicRoleAdmin && req.Role != models.ClinicRoleAdmin && admins == 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "cannot demote the last clinic_admin"})
+		return
+	}
+
+	if err := h.store.Clinics().SetMemberRole(c.Request.Context(), clinicID, int32(member.UserID), req.Role); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			abortWithError(c, err, "member not found")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update member role"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "clinic.member_role",
+		TargetType: "clinic",
+		TargetID:   int(clinicID),
+		Details: map[string]interface{}{
+			"user_id": member.UserID,
+			"email":   member.Email,
+			"from":    member.ClinicRole,
+			"to":      req.Role,
+		},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"clinic_id":   clinicID,
+		"user_id":     member.UserID,
+		"clinic_role": req.Role,
+	})
+}
+
+// loadMember resolves the :userID member of a clinic and counts the clinic's
+// admins, so callers can refuse to leave a clinic without one. Returns false
+// if a response has already been written.
+func (h *ClinicDashboardHandler) loadMember(c *gin.Context, clinicID int32) (*models.ClinicMember, int, bool) {
+	userID, err := parseIDParam(c, "userID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return nil, 0, false
+	}
+
+	members, err := h.store.Clinics().ListMembers(c.Request.Context(), clinicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load clinic members"})
+		return nil, 0, false
+	}
+
+	member := findMember(members, userID)
+	if member == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		return nil, 0, false
+	}
+
+	admins := 0
+	for _, m := range members {
+		if m.ClinicRole == models.ClinicRoleAdmin {
+			admins++
+		}
+	}
+	return member, admins, true
+}
+
+func findMember(members []models.ClinicMember, userID int64) *models.ClinicMember {
+	for i := range members {
+		if members[i].UserID == userID {
+			return &members[i]
+		}
+	}
+	return nil
+}
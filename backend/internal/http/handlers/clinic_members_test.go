@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// fakeClinicMembersRepo keeps the members of clinic 1 in memory
+type fakeClinicMembersRepo struct {
+	store.ClinicRepository
+	members []models.ClinicMember
+}
+
+func (f *fakeClinicMembersRepo) Get(ctx context.Context, id int32) (*models.Clinic, error) {
+	if id != 1 {
+		return nil, pgx.ErrNoRows
+	}
+	return &models.Clinic{ID: 1, Name: "North"}, nil
+}
+
+func (f *fakeClinicMembersRepo) ClinicRole(ctx context.Context, userID, clinicID int32) (string, error) {
+	m := findMember(f.members, int64(userID))
+	if clinicID != 1 || m == nil {
+		return "", pgx.ErrNoRows
+	}
+	return m.ClinicRole, nil
+}
+
+func (f *fakeClinicMembersRepo) ListMembers(ctx context.Context, clinicID int32) ([]models.ClinicMember, error) {
+	return append([]models.ClinicMember(nil), f.members...), nil
+}
+
+func (f *fakeClinicMembersRepo) AddMember(ctx context.Context, clinicID, userID int32, role string) error {
+	if findMember(f.members, int64(userID)) != nil {
+		return store.ErrConflict
+	}
+	f.members = append(f.members, models.ClinicMember{UserID: int64(userID), ClinicRole: role})
+	return nil
+}
+
+func (f *fakeClinicMembersRepo) RemoveMember(ctx context.Context, clinicID, userID int32) error {
+	for i, m := range f.members {
+		if m.UserID == int64(userID) {
+			f.members = append(f.members[:i], f.members[i+1:]...)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (f *fakeClinicMembersRepo) SetMemberRole(ctx context.Context, clinicID, userID int32, role string) error {
+	m := findMember(f.members, int64(userID))
+	if m == nil {
+		return pgx.ErrNoRows
+	}
+	m.ClinicRole = role
+	return nil
+}
+
+func clinicMembersRouter(userID int64, role string, repo *fakeClinicMembersRepo, users store.UserRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	st := &fakeStore{clinicRepo: repo, users: users, audit: &fakeAuditRepo{}}
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		c.Set("user", middleware.UserClaims{UserID: userID, Email: "caller@example.com", Role: role})
+		c.Next()
+	})
+	NewClinicDashboardHandler(st).Register(r.Group("/clinics"))
+	return r
+}
+
+func newMembersRepo() *fakeClinicMembersRepo {
+	return &fakeClinicMembersRepo{members: []models.ClinicMember{
+		{UserID: 1, Email: "admin@example.com", ClinicRole: models.ClinicRoleAdmin},
+		{UserID: 2, Email: "doc@example.com", ClinicRole: models.ClinicRoleMember},
+	}}
+}
+
+func TestClinicMembers_List(t *testing.T) {
+	cases := []struct {
+		name       string
+		userID     int64
+		role       string
+		path       string
+		wantStatus int
+	}{
+		{"member can view directory", 2, "clinician", "/clinics/1/members", http.StatusOK},
+		{"non-member forbidden", 9, "clinician", "/clinics/1/members", http.StatusForbidden},
+		{"system admin can view", 9, "admin", "/clinics/1/members", http.StatusOK},
+		{"unknown clinic", 9, "admin", "/clinics/5/members", http.StatusNotFound},
+		{"other clinic forbidden", 2, "clinician", "/clinics/5/members", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := clinicMembersRouter(tc.userID, tc.role, newMembersRepo(), nil)
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestClinicMembers_Add(t *testing.T) {
+	users := &fakeUserRepo{user: &models.User{ID: 3, Email: "new@example.com", IsActive: true}}
+	cases := []struct {
+		name       string
+		callerID   int64
+		body       string
+		wantStatus int
+	}{
+		{"clinic admin adds user", 1, `{"email":"new@example.com","role":"clinic_admin"}`, http.StatusCreated},
+		{"plain member forbidden", 2, `{"email":"new@example.com"}`, http.StatusForbidden},
+		{"unknown email", 1, `{"email":"nobody@example.com"}`, http.StatusNotFound},
+		{"invalid role", 1, `{"email":"new@example.com","role":"owner"}`, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMembersRepo()
+			r := clinicMembersRouter(tc.callerID, "clinician", repo, users)
+			req, _ := http.NewRequest(http.MethodPost, "/clinics/1/members", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+			if tc.wantStatus == http.StatusCreated {
+				if m := findMember(repo.members, 3); m == nil || m.ClinicRole != models.ClinicRoleAdmin {
+					t.Fatalf("member not added with role: %+v", repo.members)
+				}
+			}
+		})
+	}
+
+	t.Run("duplicate member conflicts", func(t *testing.T) {
+		repo := newMembersRepo()
+		dup := &fakeUserRepo{user: &models.User{ID: 2, Email: "doc@example.com", IsActive: true}}
+		r := clinicMembersRouter(1, "clinician", repo, dup)
+		req, _ := http.NewRequest(http.MethodPost, "/clinics/1/members", bytes.NewBufferString(`{"email":"doc@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestClinicMembers_RemoveAndRole(t *testing.T) {
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"remove member", http.MethodDelete, "/clinics/1/members/2", "", http.StatusOK},
+		{"remove last admin refused", http.MethodDelete, "/clinics/1/members/1", "", http.StatusConflict},
+		{"remove non-member", http.MethodDelete, "/clinics/1/members/9", "", http.StatusNotFound},
+		{"promote member", http.MethodPut, "/clinics/1/members/2/role", `{"role":"clinic_admin"}`, http.StatusOK},
+		{"demote last admin refused", http.MethodPut, "/clinics/1/members/1/role", `{"role":"member"}`, http.StatusConflict},
+		{"invalid role", http.MethodPut, "/clinics/1/members/2/role", `{"role":"owner"}`, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMembersRepo()
+			r := clinicMembersRouter(1, "clinician", repo, nil)
+			req, _ := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tc.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("demote admin when another admin remains", func(t *testing.T) {
+		repo := newMembersRepo()
+		repo.members[1].ClinicRole = models.ClinicRoleAdmin
+		r := clinicMembersRouter(1, "clinician", repo, nil)
+		req, _ := http.NewRequest(http.MethodPut, "/clinics/1/members/1/role", bytes.NewBufferString(`{"role":"member"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if repo.members[0].ClinicRole != models.ClinicRoleMember {
+			t.Fatalf("role not changed: %+v", repo.members[0])
+		}
+	})
+}
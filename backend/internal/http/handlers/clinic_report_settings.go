@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/storage"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// Clinic logos are re-encoded as JPEG no larger than clinicLogoMaxDim on
+// either side, which keeps them small enough to store with the clinic.
+const (
+	clinicLogoMaxBytes = 2 << 20
+	clinicLogoMaxDim   = 400
+)
+
+// ReportSettingsRequest defines the payload for changing a clinic's report settings
+type ReportSettingsRequest struct {
+	Locale string `json:"locale" binding:"required,oneof=en fil"`
+}
+
+// getReportSettings returns how a clinic's PDF reports are branded
+// @Summary Get clinic report settings
+// @Tags Clinics
+// @Produce json
+// @Param id path int true "Clinic ID"
+// @Success 200 {object} models.ClinicReportSettings
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /clinics/{id}/report-settings [get]
+func (h *ClinicDashboardHandler) getReportSettings(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+
+	settings, err := h.store.Clinics().GetReportSettings(c.Request.Context(), clinicID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "clinic not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// setReportSettings changes the language of a clinic's PDF reports
+// @Summary Set clinic report settings
+// @Description Locale selects the language of report headings and dates: en (English) or fil (Filipino) (clinic_admin only)
+// @Tags Clinics
+// @Accept json
+// @Produce json
+// @Param id path int true "Clinic ID"
+// @Param settings body ReportSettingsRequest true "Report settings"
+// @Success 200 {object} models.ClinicReportSettings
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /clinics/{id}/report-settings [put]
+func (h *ClinicDashboardHandler) setReportSettings(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req ReportSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "locale must be 'en' or 'fil'"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.store.Clinics().SetReportLocale(ctx, clinicID, req.Locale); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "clinic not found"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "clinic.report_settings",
+		TargetType: "clinic",
+		TargetID:   int(clinicID),
+		Details: map[string]interface{}{
+			"locale": req.Locale,
+		},
+	})
+
+	settings, err := h.store.Clinics().GetReportSettings(ctx, clinicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load report settings"})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// getReportLogo serves the logo printed on a clinic's reports
+// @Summary Get clinic report logo
+// @Tags Clinics
+// @Produce jpeg
+// @Param id path int true "Clinic ID"
+// @Success 200 {file} binary
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /clinics/{id}/report-logo [get]
+func (h *ClinicDashboardHandler) getReportLogo(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+
+	settings, err := h.store.Clinics().GetReportSettings(c.Request.Context(), clinicID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "clinic not found"})
+		return
+	}
+	if !settings.HasLogo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "clinic has no report logo"})
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "image/jpeg", settings.Logo)
+}
+
+// uploadReportLogo sets or replaces the logo printed on a clinic's reports
+// @Summary Upload clinic report logo
+// @Description Accepts a JPEG, PNG or GIF of at most 2 MB. The image is scaled down to 400px and re-encoded as JPEG (clinic_admin only)
+// @Tags Clinics
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Clinic ID"
+// @Param logo formData file true "Logo"
+// @Success 200 {object} models.ClinicReportSettings
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Router /clinics/{id}/report-logo [put]
+func (h *ClinicDashboardHandler) uploadReportLogo(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, clinicLogoMaxBytes+1<<20)
+	fh, err := c.FormFile("logo")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("logo must be at most %d bytes", clinicLogoMaxBytes)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multipart field 'logo' is required"})
+		return
+	}
+	if fh.Size > clinicLogoMaxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("logo must be at most %d bytes", clinicLogoMaxBytes)})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read logo"})
+		return
+	}
+	defer f.Close()
+
+	data, width, height, err := storage.Thumbnail(io.LimitReader(f, clinicLogoMaxBytes), clinicLogoMaxDim)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "logo must be a JPEG, PNG or GIF image"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.store.Clinics().SetReportLogo(ctx, clinicID, data); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			abortWithError(c, err, "clinic not found")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store logo"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "clinic.report_logo.upload",
+		TargetType: "clinic",
+		TargetID:   int(clinicID),
+		Details: map[string]interface{}{
+			"width":      width,
+			"height":     height,
+			"size_bytes": len(data),
+		},
+	})
+
+	settings, err := h.store.Clinics().GetReportSettings(ctx, clinicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load report settings"})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// deleteReportLogo removes a clinic's logo; its reports fall back to the
+// DIANA header
+// @Summary Delete clinic report logo
+// @Tags Clinics
+// @Param id path int true "Clinic ID"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /clinics/{id}/report-logo [delete]
+func (h *ClinicDashboardHandler) deleteReportLogo(c *gin.Context) {
+	clinicID, ok := clinicIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.store.Clinics().SetReportLogo(ctx, clinicID, nil); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			abortWithError(c, err, "clinic not found")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete logo"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "clinic.report_logo.delete",
+		TargetType: "clinic",
+		TargetID:   int(clinicID),
+	})
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func reportSettingsRouter(clinicRole string, repo *fakeSharingClinicRepo, audit *fakeAuditRepo) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	repo.role = clinicRole
+	st := &fakeStore{clinicRepo: repo, audit: audit}
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", middleware.UserClaims{UserID: 3, Email: "lead@example.com", Role: "clinician"})
+		c.Next()
+	})
+	NewClinicDashboardHandler(st).Register(r.Group("/clinics"))
+	return r
+}
+
+func logoUpload(t *testing.T, r *gin.Engine, path string, content []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("logo", "logo.png")
+	_, _ = fw.Write(content)
+	_ = mw.Close()
+	req, _ := http.NewRequest(http.MethodPut, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestClinicReportSettings(t *testing.T) {
+	repo := &fakeSharingClinicRepo{report: &models.ClinicReportSettings{ClinicID: 4, Name: "Northside Clinic", Locale: "en"}}
+	audit := &fakeAuditRepo{}
+	r := reportSettingsRouter(models.ClinicRoleAdmin, repo, audit)
+
+	w := contactRequest(r, http.MethodGet, "/clinics/4/report-settings", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("get: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = contactRequest(r, http.MethodPut, "/clinics/4/report-settings", `{"locale":"fil"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("set: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var got models.ClinicReportSettings
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Locale != "fil" || got.Name != "Northside Clinic" {
+		t.Fatalf("unexpected settings %s", w.Body.String())
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != "clinic.report_settings" {
+		t.Fatalf("expected an audit event, got %+v", audit.events)
+	}
+	if w := contactRequest(r, http.MethodPut, "/clinics/4/report-settings", `{"locale":"es"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unsupported locale: expected 400, got %d", w.Code)
+	}
+
+	member := reportSettingsRouter(models.ClinicRoleMember, repo, audit)
+	if w := contactRequest(member, http.MethodPut, "/clinics/4/report-settings", `{"locale":"en"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("member: expected 403, got %d", w.Code)
+	}
+	if repo.report.Locale != "fil" {
+		t.Fatalf("member changed the locale to %q", repo.report.Locale)
+	}
+}
+
+func TestClinicReportLogo(t *testing.T) {
+	repo := &fakeSharingClinicRepo{report: &models.ClinicReportSettings{ClinicID: 4, Name: "Northside Clinic", Locale: "en"}}
+	r := reportSettingsRouter(models.ClinicRoleAdmin, repo, &fakeAuditRepo{})
+
+	if w := photoRequest(r, http.MethodGet, "/clinics/4/report-logo"); w.Code != http.StatusNotFound {
+		t.Fatalf("no logo: expected 404, got %d", w.Code)
+	}
+
+	w := logoUpload(t, r, "/clinics/4/report-logo", testPNG(t, 1000, 500))
+	if w.Code != http.StatusOK {
+		t.Fatalf("upload: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = photoRequest(r, http.MethodGet, "/clinics/4/report-logo")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("get: expected a JPEG, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+	if err != nil || cfg.Width != 400 || cfg.Height != 200 {
+		t.Fatalf("expected a 400x200 logo, got %+v (%v)", cfg, err)
+	}
+
+	if w := logoUpload(t, r, "/clinics/4/report-logo", []byte("not an image")); w.Code != http.StatusBadRequest {
+		t.Fatalf("non-image: expected 400, got %d", w.Code)
+	}
+	if w := photoRequest(r, http.MethodDelete, "/clinics/4/report-logo"); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", w.Code)
+	}
+	if repo.report.Logo != nil {
+		t.Fatal("logo not removed")
+	}
+}
+
+func TestReport_ClinicBranding(t *testing.T) {
+	logo := &fakeSharingClinicRepo{report: &models.ClinicReportSettings{ClinicID: 4, Name: "Northside Clinic", Locale: "fil"}}
+	r := reportSettingsRouter(models.ClinicRoleAdmin, logo, &fakeAuditRepo{})
+	if w := logoUpload(t, r, "/clinics/4/report-logo", testPNG(t, 300, 100)); w.Code != http.StatusOK {
+		t.Fatalf("upload: expected 200, got %d", w.Code)
+	}
+
+	for name, tc := range map[string]struct {
+		clinics *fakeSharingClinicRepo
+		image   bool
+	}{
+		"clinic logo":        {logo, true},
+		"no report settings": {&fakeSharingClinicRepo{role: models.ClinicRoleMember}, false},
+	} {
+		st := &fakeStore{patientRepo: &fakePatientRepo{}, clinicRepo: tc.clinics,
+			repo: &fakeAssessmentRepo{stored: &models.Assessment{ID: 4, PatientID: 9, HbA1c: 6.8}}}
+		r := baselineRouter(st)
+		for _, path := range []string{"/patients/9/assessments/4/report", "/patients/9/report"} {
+			w := contactRequest(r, http.MethodGet, path, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s %s: expected 200, got %d: %s", name, path, w.Code, w.Body.String())
+			}
+			if got := bytes.Contains(w.Body.Bytes(), []byte("/Subtype /Image")); got != tc.image {
+				t.Fatalf("%s %s: expected image %v, got %v", name, path, tc.image, got)
+			}
+		}
+	}
+}
@@ -2,8 +2,12 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/stats"
 	"github.com/skufu/DianaV2/backend/internal/store"
 )
 
@@ -27,12 +31,34 @@ func (h *CohortHandler) Register(rg *gin.RouterGroup) {
 // @Description Returns risk factor comparison across patient groups
 // @Tags Analytics
 // @Produce json
-// @Param groupBy query string false "Grouping parameter: cluster, risk_level, age_group, menopause_status" default(cluster)
+// @Param group_by query string false "Grouping parameter: cluster, risk_level, age_group, menopause_status (groupBy is also accepted)" default(cluster)
+// @Param compare query string false "Two group names to compare, comma separated (e.g. SIRD,SIDD)"
+// @Param user_id query int false "Only this clinician's patients"
+// @Param clinic_id query int false "Only patients of this clinic's members"
+// @Param tag_id query int false "Only patients carrying this tag"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /analytics/cohort [get]
 func (h *CohortHandler) getCohortStats(c *gin.Context) {
-	groupBy := c.DefaultQuery("groupBy", "cluster")
+	groupBy := c.Query("group_by")
+	if groupBy == "" {
+		groupBy = c.DefaultQuery("groupBy", "cluster")
+	}
+	scope, ok := cohortScope(c, h.store)
+	if !ok {
+		return
+	}
+
+	if compare := c.Query("compare"); compare != "" {
+		h.compareGroups(c, groupBy, compare, scope)
+		return
+	}
+	if !scope.IsZero() {
+		h.scopedStats(c, groupBy, scope)
+		return
+	}
 
 	var groups interface{}
 	var err error
@@ -68,3 +94,184 @@ func (h *CohortHandler) getCohortStats(c *gin.Context) {
 		"group_by":          groupBy,
 	})
 }
+
+// cohortScopeQuery is the optional scoping of a cohort request
+type cohortScopeQuery struct {
+	UserID   *int32 `form:"user_id" binding:"omitempty,min=1"`
+	ClinicID *int32 `form:"clinic_id" binding:"omitempty,min=1"`
+	TagID    *int64 `form:"tag_id" binding:"omitempty,min=1"`
+}
+
+// requestAccessScope returns the caller's access scope, resolving it if
+// middleware.AccessScope did not run. Returns false if a response has
+// already been written.
+func requestAccessScope(c *gin.Context, st store.Store, user middleware.UserClaims) (models.AccessScope, bool) {
+	if access, ok := middleware.GetAccessScope(c); ok {
+		return access, true
+	}
+	access, err := middleware.ResolveAccessScope(c.Request.Context(), user, PermissionLookup(st), ClinicMembershipLookup(st))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load clinic membership"})
+		return access, false
+	}
+	return access, true
+}
+
+// cohortScope reads the requested scope and checks the caller may see it:
+// admins and reporting API tokens may scope to anyone, other users only to
+// themselves, a clinic they belong to or a tag of their own. Other users'
+// scopes are then restricted to their access scope, so unscoped requests
+// cover only their clinics. Returns false if a response has already been
+// written. Biomarker trends and the cluster distribution share it.
+func cohortScope(c *gin.Context, st store.Store) (models.CohortScope, bool) {
+	var q cohortScopeQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id, clinic_id and tag_id must be positive integers"})
+		return models.CohortScope{}, false
+	}
+	scope := models.CohortScope{UserID: q.UserID, ClinicID: q.ClinicID, TagID: q.TagID}
+
+	claims, ok := c.Get("user")
+	if !ok {
+		return scope, true
+	}
+	user := claims.(middleware.UserClaims)
+	access, ok := requestAccessScope(c, st, user)
+	if !ok {
+		return scope, false
+	}
+	if access.All {
+		return scope, true
+	}
+	if scope.UserID != nil && int64(*scope.UserID) != user.UserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied - you can only scope to your own patients"})
+		return scope, false
+	}
+	if scope.TagID != nil {
+		if _, err := st.Tags().Get(c.Request.Context(), *scope.TagID, user.UserID); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "access denied - you can only scope to your own tags"})
+			return scope, false
+		}
+	}
+	if scope.ClinicID != nil && !access.HasClinic(*scope.ClinicID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied - not a member of this clinic"})
+		return scope, false
+	}
+	return access.Restrict(scope), true
+}
+
+// isCohortDimension reports whether groupBy names a cohort dimension
+func isCohortDimension(groupBy string) bool {
+	switch groupBy {
+	case "cluster", "risk_level", "age_group", "menopause_status":
+		return true
+	}
+	return false
+}
+
+// scopedStats serves getCohortStats for a scoped request
+func (h *CohortHandler) scopedStats(c *gin.Context, groupBy string, scope models.CohortScope) {
+	if !isCohortDimension(groupBy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid groupBy parameter"})
+		return
+	}
+	cohortRepo := h.store.Cohort()
+	groups, err := cohortRepo.ScopedStats(c.Request.Context(), groupBy, scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load cohort statistics"})
+		return
+	}
+	totalPatients, totalAssessments, _ := cohortRepo.ScopedTotals(c.Request.Context(), scope)
+
+	c.JSON(http.StatusOK, gin.H{
+		"groups":            groups,
+		"total_patients":    totalPatients,
+		"total_assessments": totalAssessments,
+		"group_by":          groupBy,
+		"scope":             scope,
+	})
+}
+
+// comparisonAlpha is the significance level for cohort comparison tests
+const comparisonAlpha = 0.05
+
+// compareGroups runs significance tests between two groups of one dimension:
+// a Welch t-test with Cohen's d per metric, and a chi-square test on the risk
+// level distribution.
+func (h *CohortHandler) compareGroups(c *gin.Context, groupBy, compare string, scope models.CohortScope) {
+	if !isCohortDimension(groupBy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid groupBy parameter"})
+		return
+	}
+	names := strings.Split(compare, ",")
+	if len(names) != 2 || strings.TrimSpace(names[0]) == "" || strings.TrimSpace(names[1]) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "compare must name exactly two groups"})
+		return
+	}
+
+	cohortRepo := h.store.Cohort()
+	a, err := cohortRepo.GroupSample(c.Request.Context(), groupBy, strings.TrimSpace(names[0]), scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load cohort statistics"})
+		return
+	}
+	b, err := cohortRepo.GroupSample(c.Request.Context(), groupBy, strings.TrimSpace(names[1]), scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load cohort statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comparison": compareSamples(groupBy, *a, *b),
+		"group_by":   groupBy,
+	})
+}
+
+// compareSamples builds the comparison for two cohort samples. Metrics are
+// reported in a fixed order so responses are stable.
+func compareSamples(groupBy string, a, b models.CohortSample) models.CohortComparison {
+	out := models.CohortComparison{
+		GroupBy: groupBy,
+		Alpha:   comparisonAlpha,
+		GroupA:  a,
+		GroupB:  b,
+		Metrics: []models.MetricComparison{},
+	}
+
+	for _, metric := range []string{"hba1c", "fbs", "bmi", "systolic", "diastolic", "risk_score"} {
+		ma, okA := a.Metrics[metric]
+		mb, okB := b.Metrics[metric]
+		if !okA || !okB {
+			continue
+		}
+		sa := stats.Moments{N: ma.N, Mean: ma.Mean, StdDev: ma.StdDev}
+		sb := stats.Moments{N: mb.N, Mean: mb.Mean, StdDev: mb.StdDev}
+		mc := models.MetricComparison{
+			Metric:     metric,
+			MeanA:      ma.Mean,
+			MeanB:      mb.Mean,
+			Difference: ma.Mean - mb.Mean,
+			EffectSize: stats.CohensD(sa, sb),
+		}
+		if t, df, p, ok := stats.WelchTTest(sa, sb); ok {
+			mc.TStatistic, mc.DegreesOfFreedom, mc.PValue = &t, &df, &p
+			mc.Significant = p < comparisonAlpha
+		}
+		out.Metrics = append(out.Metrics, mc)
+	}
+
+	table := [][]float64{make([]float64, 3), make([]float64, 3)}
+	for i := 0; i < 3; i++ {
+		table[0][i] = float64(a.RiskLevelCounts[i])
+		table[1][i] = float64(b.RiskLevelCounts[i])
+	}
+	if stat, df, p, ok := stats.ChiSquare(table); ok {
+		out.RiskDistribution = &models.ChiSquareResult{
+			Statistic:        stat,
+			DegreesOfFreedom: df,
+			PValue:           p,
+			Significant:      p < comparisonAlpha,
+		}
+	}
+	return out
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// fakeCohortRepo serves canned group samples keyed by group name and
+// records the scope of scoped queries
+type fakeCohortRepo struct {
+	store.CohortRepository
+	samples map[string]models.CohortSample
+	scope   *models.CohortScope
+}
+
+func (f *fakeCohortRepo) GroupSample(ctx context.Context, groupBy, name string, scope models.CohortScope) (*models.CohortSample, error) {
+	s := f.samples[name]
+	s.Name = name
+	return &s, nil
+}
+
+func (f *fakeCohortRepo) ScopedStats(ctx context.Context, groupBy string, scope models.CohortScope) ([]models.CohortGroup, error) {
+	f.scope = &scope
+	return []models.CohortGroup{{Name: "SIRD", Count: 3}}, nil
+}
+
+func (f *fakeCohortRepo) ScopedTotals(ctx context.Context, scope models.CohortScope) (int, int, error) {
+	return 2, 3, nil
+}
+
+func TestCohortHandler_Compare(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := &fakeCohortRepo{samples: map[string]models.CohortSample{
+		"SIRD": {Count: 40, Metrics: map[string]models.MetricMoments{
+			"hba1c": {N: 40, Mean: 7.4, StdDev: 0.8},
+			"bmi":   {N: 40, Mean: 31.0, StdDev: 3.0},
+		}, RiskLevelCounts: [3]int{2, 8, 30}},
+		"MARD": {Count: 35, Metrics: map[string]models.MetricMoments{
+			"hba1c": {N: 35, Mean: 6.0, StdDev: 0.7},
+			"bmi":   {N: 35, Mean: 30.8, StdDev: 3.2},
+		}, RiskLevelCounts: [3]int{20, 12, 3}},
+	}}
+	h := NewCohortHandler(&fakeStore{cohort: repo})
+
+	r := gin.New()
+	h.Register(r.Group("/analytics"))
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+	}{
+		{name: "cluster vs cluster", query: "?groupBy=cluster&compare=SIRD,MARD", wantCode: http.StatusOK},
+		{name: "needs two groups", query: "?compare=SIRD", wantCode: http.StatusBadRequest},
+		{name: "invalid dimension", query: "?groupBy=height&compare=a,b", wantCode: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/analytics/cohort"+tc.query, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, w.Code, w.Body.String())
+			}
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Comparison models.CohortComparison `json:"comparison"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid json: %v", err)
+			}
+			cmp := resp.Comparison
+			if len(cmp.Metrics) != 2 {
+				t.Fatalf("expected 2 compared metrics, got %d", len(cmp.Metrics))
+			}
+			byMetric := map[string]models.MetricComparison{}
+			for _, m := range cmp.Metrics {
+				byMetric[m.Metric] = m
+			}
+			if !byMetric["hba1c"].Significant || byMetric["hba1c"].PValue == nil {
+				t.Errorf("expected significant HbA1c difference, got %+v", byMetric["hba1c"])
+			}
+			if byMetric["bmi"].Significant {
+				t.Errorf("expected no significant BMI difference, got %+v", byMetric["bmi"])
+			}
+			if cmp.RiskDistribution == nil || !cmp.RiskDistribution.Significant || cmp.RiskDistribution.DegreesOfFreedom != 2 {
+				t.Errorf("unexpected risk distribution test: %+v", cmp.RiskDistribution)
+			}
+		})
+	}
+}
+
+func TestCohortHandler_Scope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		name  string
+		role  string
+		query string
+		want  int
+	}{
+		{"clinician scopes to self", "clinician", "?group_by=risk_level&user_id=5", http.StatusOK},
+		{"clinician cannot scope to another clinician", "clinician", "?user_id=6", http.StatusForbidden},
+		{"clinician scopes to own clinic", "clinician", "?clinic_id=4", http.StatusOK},
+		{"clinician cannot scope to another clinic", "clinician", "?clinic_id=9", http.StatusForbidden},
+		{"admin scopes to anyone", "admin", "?user_id=6&clinic_id=9", http.StatusOK},
+		{"api token scopes to a clinic", "", "?clinic_id=9", http.StatusOK},
+		{"invalid dimension", "admin", "?group_by=height&user_id=6", http.StatusBadRequest},
+		{"invalid id", "admin", "?clinic_id=abc", http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakeCohortRepo{}
+			h := NewCohortHandler(&fakeStore{cohort: repo, clinicRepo: &fakeSharingClinicRepo{role: models.ClinicRoleMember}})
+			r := gin.New()
+			if tc.role != "" {
+				r.Use(func(c *gin.Context) {
+					c.Set("user", middleware.UserClaims{UserID: 5, Email: "doc@example.com", Role: tc.role})
+					c.Next()
+				})
+			}
+			h.Register(r.Group("/analytics"))
+
+			w := contactRequest(r, http.MethodGet, "/analytics/cohort"+tc.query, "")
+			if w.Code != tc.want {
+				t.Fatalf("expected %d, got %d: %s", tc.want, w.Code, w.Body.String())
+			}
+			if tc.want != http.StatusOK {
+				if repo.scope != nil {
+					t.Fatal("rejected request still queried the store")
+				}
+				return
+			}
+			if repo.scope == nil || repo.scope.IsZero() {
+				t.Fatal("expected a scoped query")
+			}
+			if tc.name == "clinician scopes to self" && *repo.scope.UserID != 5 {
+				t.Fatalf("scoped to %+v, want user 5", repo.scope)
+			}
+			var resp struct {
+				GroupBy       string `json:"group_by"`
+				TotalPatients int    `json:"total_patients"`
+			}
+			_ = json.Unmarshal(w.Body.Bytes(), &resp)
+			if resp.TotalPatients != 2 || (tc.name == "clinician scopes to self" && resp.GroupBy != "risk_level") {
+				t.Fatalf("unexpected response: %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// exportAuditPageSize is how many audit events each query of an export's
+// audit trail fetches.
+const exportAuditPageSize = 100
+
+type dataExportQuery struct {
+	Format string `form:"format" binding:"omitempty,oneof=json csv"`
+}
+
+// export returns the patient's complete record for a data-portability
+// request: profile and contact details, contact consent, every assessment,
+// the change history and the full audit trail. format=csv returns a ZIP of
+// one CSV per section. Audit actors other than the caller are only shown to
+// holders of admin.users, and event details are left out as in the bundle.
+// @Summary Export a patient's complete record
+// @Tags Patients
+// @Produce json
+// @Produce application/zip
+// @Param id path int true "Patient ID"
+// @Param format query string false "json or csv" default(json)
+// @Success 200 {object} models.PatientExport
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /patients/{id}/export [get]
+func (h *PatientsHandler) export(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	var q dataExportQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be 'json' or 'csv'"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	patient, err := h.store.Patients().Get(ctx, int32(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	claims := c.MustGet("user").(middleware.UserClaims)
+
+	e, err := loadPatientExport(ctx, h.store, *patient)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to export patient %d", id)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export patient"})
+		return
+	}
+	seesActors, err := middleware.HasPermission(ctx, h.perms, claims, 0, models.PermAdminUsers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check permissions"})
+		return
+	}
+	for i := range e.Audit {
+		a := &e.Audit[i]
+		a.Details = nil
+		if !seesActors && a.Actor != claims.Email {
+			a.Actor = "redacted"
+		}
+	}
+
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "patient.export",
+		TargetType: "patient",
+		TargetID:   int(id),
+		Details:    map[string]interface{}{"format": exportFormat(q)},
+	})
+
+	name := fmt.Sprintf("patient-%d-export", id)
+	if exportFormat(q) == "json" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.json\"", name))
+		c.JSON(http.StatusOK, e)
+		return
+	}
+	writeCSVZip(c, name+".zip", patientExportCSV(e))
+}
+
+// loadPatientExport gathers every section of the patient's record. Any
+// failure fails the whole export, which must not silently miss data.
+func loadPatientExport(ctx context.Context, st store.Store, patient models.Patient) (*models.PatientExport, error) {
+	e := &models.PatientExport{GeneratedAt: time.Now().UTC(), Patient: patient}
+	var err error
+	if e.Patient.Contact, err = st.PatientContacts().Get(ctx, patient.ID); err != nil {
+		return nil, fmt.Errorf("contact: %w", err)
+	}
+	e.Consent = models.PatientConsent{Channels: []string{}}
+	if contact := e.Patient.Contact; contact != nil {
+		e.Consent.ContactConsent = contact.ContactConsent
+		e.Consent.ConsentAt = contact.ConsentAt
+		e.Consent.Channels = append(e.Consent.Channels, contact.Channels()...)
+	}
+	if e.Assessments, err = st.Assessments().ListByPatient(ctx, patient.ID); err != nil {
+		return nil, fmt.Errorf("assessments: %w", err)
+	}
+	if e.Assessments == nil {
+		e.Assessments = []models.Assessment{}
+	}
+	if e.History, err = st.PatientHistory().List(ctx, patient.ID); err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	if e.History == nil {
+		e.History = []models.PatientVersion{}
+	}
+	if e.Audit, err = auditTrail(ctx, st, models.AuditListParams{TargetType: "patient", TargetID: int(patient.ID)}); err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	return e, nil
+}
+
+// UserExportHandler serves a user's own data for data-portability requests
+type UserExportHandler struct {
+	store store.Store
+	perms middleware.PermissionLookup
+}
+
+// NewUserExportHandler creates a new UserExportHandler
+func NewUserExportHandler(store store.Store) *UserExportHandler {
+	return &UserExportHandler{store: store, perms: PermissionLookup(store)}
+}
+
+// Register registers the export route on the given router group
+func (h *UserExportHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("/export", h.export)
+}
+
+// export returns everything stored about the caller's account: profile,
+// clinic memberships, the patients they own, the actions they performed and
+// the changes made to their account. Patients are listed without their
+// assessments; each has its own export. Actors of account events are only
+// shown to holders of admin.users.
+// @Summary Export the current user's data
+// @Tags Users
+// @Produce json
+// @Produce application/zip
+// @Param format query string false "json or csv" default(json)
+// @Success 200 {object} models.UserExport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/export [get]
+func (h *UserExportHandler) export(c *gin.Context) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	var q dataExportQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be 'json' or 'csv'"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	e, err := h.loadUserExport(ctx, int32(claims.UserID))
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to export user %d", claims.UserID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export user data"})
+		return
+	}
+	seesActors, err := middleware.HasPermission(ctx, h.perms, claims, 0, models.PermAdminUsers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check permissions"})
+		return
+	}
+	for i := range e.AccountEvents {
+		a := &e.AccountEvents[i]
+		if !seesActors && !strings.EqualFold(a.Actor, e.User.Email) {
+			a.Actor = "redacted"
+		}
+	}
+
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "user.export",
+		TargetType: "user",
+		TargetID:   int(claims.UserID),
+		Details:    map[string]interface{}{"format": exportFormat(q)},
+	})
+
+	name := fmt.Sprintf("user-%d-export", claims.UserID)
+	if exportFormat(q) == "json" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.json\"", name))
+		c.JSON(http.StatusOK, e)
+		return
+	}
+	writeCSVZip(c, name+".zip", userExportCSV(e))
+}
+
+func (h *UserExportHandler) loadUserExport(ctx context.Context, userID int32) (*models.UserExport, error) {
+	user, err := h.store.Users().FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user: %w", err)
+	}
+	e := &models.UserExport{GeneratedAt: time.Now().UTC(), User: *user}
+	if e.Clinics, err = h.store.Clinics().ListUserClinics(ctx, userID); err != nil {
+		return nil, fmt.Errorf("clinics: %w", err)
+	}
+	if e.Clinics == nil {
+		e.Clinics = []models.UserClinic{}
+	}
+	if e.Patients, err = h.store.Patients().List(ctx, userID); err != nil {
+		return nil, fmt.Errorf("patients: %w", err)
+	}
+	if e.Patients == nil {
+		e.Patients = []models.Patient{}
+	}
+
+	// The store matches actors by substring, so keep exact matches only
+	activity, err := auditTrail(ctx, h.store, models.AuditListParams{Actor: user.Email})
+	if err != nil {
+		return nil, fmt.Errorf("activity: %w", err)
+	}
+	e.Activity = []models.AuditEvent{}
+	for _, a := range activity {
+		if strings.EqualFold(a.Actor, user.Email) {
+			e.Activity = append(e.Activity, a)
+		}
+	}
+	if e.AccountEvents, err = auditTrail(ctx, h.store, models.AuditListParams{TargetType: "user", TargetID: int(userID)}); err != nil {
+		return nil, fmt.Errorf("account events: %w", err)
+	}
+	return e, nil
+}
+
+// auditTrail pages through every audit event matching params
+func auditTrail(ctx context.Context, st store.Store, params models.AuditListParams) ([]models.AuditEvent, error) {
+	out := []models.AuditEvent{}
+	params.PageSize = exportAuditPageSize
+	for params.Page = 1; ; params.Page++ {
+		events, total, err := st.AuditEvents().List(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, events...)
+		if len(events) < params.PageSize || len(out) >= total {
+			return out, nil
+		}
+	}
+}
+
+func exportFormat(q dataExportQuery) string {
+	if q.Format == "" {
+		return "json"
+	}
+	return q.Format
+}
+
+// csvFile is one table of a CSV export, header row first
+type csvFile struct {
+	name string
+	rows [][]string
+}
+
+// writeCSVZip streams files as a ZIP attachment. Headers are already sent
+// when a write fails, so failures are only logged.
+func writeCSVZip(c *gin.Context, filename string, files []csvFile) {
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	zw := zip.NewWriter(c.Writer)
+	var err error
+	for _, f := range files {
+		var w io.Writer
+		if w, err = zw.Create(f.name); err != nil {
+			break
+		}
+		cw := csv.NewWriter(w)
+		if err = cw.WriteAll(f.rows); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to write %s", filename)
+	}
+}
+
+func patientExportCSV(e *models.PatientExport) []csvFile {
+	p := e.Patient
+	contact := p.Contact
+	if contact == nil {
+		contact = &models.PatientContact{}
+	}
+	profile := append(patientCSVRow(p)[:len(patientCSVHeader)-1], p.MRN,
+		contact.Phone, contact.Email, contact.AddressLine1, contact.AddressLine2, contact.City,
+		contact.Region, contact.PostalCode, contact.Country,
+		boolToStr(e.Consent.ContactConsent), timeToStr(e.Consent.ConsentAt), strings.Join(e.Consent.Channels, ";"),
+		p.CreatedAt.Format(time.RFC3339))
+	profileHeader := append(append([]string{}, patientCSVHeader[:len(patientCSVHeader)-1]...), "mrn",
+		"phone", "email", "address_line1", "address_line2", "city", "region", "postal_code", "country",
+		"contact_consent", "consent_at", "consent_channels", "created_at")
+
+	assessments := [][]string{assessmentCSVHeader}
+	for _, a := range e.Assessments {
+		assessments = append(assessments, assessmentCSVRow(a))
+	}
+	history := [][]string{{"version", "changed_at", "changed_by", "field", "old", "new"}}
+	for _, v := range e.History {
+		changedBy := ""
+		if v.ChangedBy != nil {
+			changedBy = strconv.FormatInt(*v.ChangedBy, 10)
+		}
+		for _, ch := range v.Changes {
+			history = append(history, []string{intToStr(v.Version), v.ChangedAt.Format(time.RFC3339), changedBy,
+				ch.Field, jsonCell(ch.Old), jsonCell(ch.New)})
+		}
+	}
+	return []csvFile{
+		{"patient.csv", [][]string{profileHeader, profile}},
+		{"assessments.csv", assessments},
+		{"history.csv", history},
+		{"audit.csv", auditCSV(e.Audit)},
+	}
+}
+
+func userExportCSV(e *models.UserExport) []csvFile {
+	u := e.User
+	clinics := [][]string{{"clinic_id", "name", "role"}}
+	for _, uc := range e.Clinics {
+		clinics = append(clinics, []string{strconv.FormatInt(uc.ID, 10), uc.Name, uc.Role})
+	}
+	patients := [][]string{patientCSVHeader}
+	for _, p := range e.Patients {
+		patients = append(patients, patientCSVRow(p))
+	}
+	return []csvFile{
+		{"user.csv", [][]string{
+			{"id", "email", "role", "is_active", "email_verified_at", "last_login_at", "created_at"},
+			{strconv.FormatInt(u.ID, 10), u.Email, u.Role, boolToStr(u.IsActive),
+				timeToStr(u.EmailVerifiedAt), timeToStr(u.LastLoginAt), u.CreatedAt.Format(time.RFC3339)},
+		}},
+		{"clinics.csv", clinics},
+		{"patients.csv", patients},
+		{"activity.csv", auditCSV(e.Activity)},
+		{"account_events.csv", auditCSV(e.AccountEvents)},
+	}
+}
+
+func auditCSV(events []models.AuditEvent) [][]string {
+	rows := [][]string{{"id", "created_at", "actor", "action", "target_type", "target_id", "details"}}
+	for _, a := range events {
+		details := ""
+		if a.Details != nil {
+			details = jsonCell(a.Details)
+		}
+		rows = append(rows, []string{strconv.FormatInt(a.ID, 10), a.CreatedAt.Format(time.RFC3339), a.Actor,
+			a.Action, a.TargetType, intToStr(a.TargetID), details})
+	}
+	return rows
+}
+
+// jsonCell writes a value of unknown type as JSON, or empty for nil
+func jsonCell(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func timeToStr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// readCSVZip returns each CSV in a ZIP body by file name
+func readCSVZip(t *testing.T, body []byte) map[string][][]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("not a zip: %v", err)
+	}
+	out := map[string][][]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if out[f.Name], err = csv.NewReader(bytes.NewReader(data)).ReadAll(); err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+	}
+	return out
+}
+
+func TestPatientExport(t *testing.T) {
+	consentAt := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	changedBy := int64(3)
+	audit := &fakeAuditRepo{events: []models.AuditEvent{
+		{ID: 1, Actor: "doc@example.com", Action: "patient.create", TargetType: "patient", TargetID: 7},
+		{ID: 2, Actor: "colleague@example.com", Action: "patient.update", TargetType: "patient", TargetID: 7, Details: map[string]interface{}{"note": "x"}},
+		{ID: 3, Actor: "doc@example.com", Action: "patient.create", TargetType: "patient", TargetID: 8},
+	}}
+	st := &fakeStore{
+		patientRepo: &fakePatientRepo{},
+		repo:        &fakeAssessmentRepo{all: []models.Assessment{{ID: 1, PatientID: 7, HbA1c: 6.1}, {ID: 2, PatientID: 7, HbA1c: 6.4}, {ID: 3, PatientID: 8}}},
+		contacts: &fakePatientContactRepo{contacts: map[int64]models.PatientContact{
+			7: {PatientID: 7, Email: "pat@example.com", Phone: "+639170000000", ContactConsent: true, ConsentAt: &consentAt},
+		}},
+		history: &fakePatientHistoryRepo{versions: []models.PatientVersion{
+			{PatientID: 7, Version: 1, ChangedBy: &changedBy, Changes: []models.PatientFieldChange{{Field: "age", Old: 51, New: 52}}},
+		}},
+		audit: audit,
+	}
+	r := sharingRouter(st, 3)
+
+	w := contactRequest(r, http.MethodGet, "/patients/7/export", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var e models.PatientExport
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	if !e.Consent.ContactConsent || len(e.Consent.Channels) != 2 || e.Patient.Contact == nil {
+		t.Fatalf("unexpected consent %+v", e.Consent)
+	}
+	if len(e.Assessments) != 2 || len(e.History) != 1 {
+		t.Fatalf("expected 2 assessments and 1 version, got %d and %d", len(e.Assessments), len(e.History))
+	}
+	if len(e.Audit) != 2 {
+		t.Fatalf("expected the patient's 2 audit events, got %+v", e.Audit)
+	}
+	for _, a := range e.Audit {
+		if a.Details != nil || (a.ID == 2 && a.Actor != "redacted") || (a.ID == 1 && a.Actor != "doc@example.com") {
+			t.Fatalf("audit event not redacted for a clinician: %+v", a)
+		}
+	}
+	if last := audit.events[len(audit.events)-1]; last.Action != "patient.export" || last.TargetID != 7 {
+		t.Fatalf("expected the export to be audited, got %+v", last)
+	}
+
+	w = contactRequest(r, http.MethodGet, "/patients/7/export?format=csv", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("csv: expected a zip, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	files := readCSVZip(t, w.Body.Bytes())
+	// The audit trail now includes the JSON export
+	for name, rows := range map[string]int{"patient.csv": 2, "assessments.csv": 3, "history.csv": 2, "audit.csv": 4} {
+		if len(files[name]) != rows {
+			t.Fatalf("%s: expected %d rows, got %v", name, rows, files[name])
+		}
+	}
+	profile := map[string]string{}
+	for i, col := range files["patient.csv"][0] {
+		profile[col] = files["patient.csv"][1][i]
+	}
+	if profile["email"] != "pat@example.com" || profile["contact_consent"] != "true" || profile["consent_channels"] != "email;sms" {
+		t.Fatalf("unexpected profile row %v", profile)
+	}
+	if got := files["history.csv"][1]; got[3] != "age" || got[4] != "51" || got[5] != "52" {
+		t.Fatalf("unexpected history row %v", got)
+	}
+
+	if w := contactRequest(r, http.MethodGet, "/patients/7/export?format=xml", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown format: expected 400, got %d", w.Code)
+	}
+}
+
+func TestUserExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	audit := &fakeAuditRepo{events: []models.AuditEvent{
+		{ID: 1, Actor: "doc@example.com", Action: "patient.create", TargetType: "patient", TargetID: 7, Details: map[string]interface{}{"name": "x"}},
+		// Matches the store's substring filter but is someone else
+		{ID: 2, Actor: "ddoc@example.com", Action: "patient.create", TargetType: "patient", TargetID: 9},
+		{ID: 3, Actor: "admin@example.com", Action: "user.update", TargetType: "user", TargetID: 3},
+	}}
+	st := &fakeStore{
+		users:       &fakeUserRepo{user: &models.User{ID: 3, Email: "doc@example.com", Role: "clinician", IsActive: true}},
+		clinicRepo:  &fakeSharingClinicRepo{role: models.ClinicRoleMember},
+		patientRepo: &fakePatientRepo{},
+		audit:       audit,
+	}
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", middleware.UserClaims{UserID: 3, Email: "doc@example.com", Role: "clinician"})
+		c.Next()
+	})
+	NewUserExportHandler(st).Register(r.Group("/users"))
+
+	w := contactRequest(r, http.MethodGet, "/users/export", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "password") {
+		t.Fatal("export leaks the password hash")
+	}
+	var e models.UserExport
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.User.Email != "doc@example.com" || len(e.Clinics) != 1 || e.Patients == nil {
+		t.Fatalf("unexpected export %+v", e)
+	}
+	if len(e.Activity) != 1 || e.Activity[0].ID != 1 || e.Activity[0].Details == nil {
+		t.Fatalf("expected only the user's own actions, got %+v", e.Activity)
+	}
+	if len(e.AccountEvents) != 1 || e.AccountEvents[0].Actor != "redacted" {
+		t.Fatalf("expected the admin's change with the actor hidden, got %+v", e.AccountEvents)
+	}
+
+	// The JSON export above is now part of the activity
+	w = contactRequest(r, http.MethodGet, "/users/export?format=csv", "")
+	files := readCSVZip(t, w.Body.Bytes())
+	if len(files["user.csv"]) != 2 || files["user.csv"][1][1] != "doc@example.com" || len(files["activity.csv"]) != 3 {
+		t.Fatalf("unexpected csv export %v", files)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// defaultLowQualityBelow is the score under which an assessment counts as
+// low quality when the request does not set its own threshold.
+const defaultLowQualityBelow = 60
+
+// DataQualityHandler reports assessment data quality per clinician. It is
+// kept apart from AnalyticsHandler because the report names clinicians and
+// so is not served to reporting API tokens.
+type DataQualityHandler struct {
+	store store.Store
+}
+
+// NewDataQualityHandler creates a new DataQualityHandler
+func NewDataQualityHandler(store store.Store) *DataQualityHandler {
+	return &DataQualityHandler{store: store}
+}
+
+// Register registers data quality routes on the given router group
+func (h *DataQualityHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("/data-quality", h.byClinician)
+}
+
+type dataQualityQuery struct {
+	Below int `form:"below" binding:"gte=1,lte=100"`
+}
+
+// byClinician returns quality averages per clinician
+// @Summary Assessment data quality per clinician
+// @Description Averages assessment data quality scores per owning clinician, lowest first. Callers granted admin.system see every clinician; other users see only their own row.
+// @Tags Analytics
+// @Produce json
+// @Param below query int false "Scores under this count as low quality" default(60)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /analytics/data-quality [get]
+func (h *DataQualityHandler) byClinician(c *gin.Context) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	q := dataQualityQuery{Below: defaultLowQualityBelow}
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "below must be between 1 and 100"})
+		return
+	}
+
+	access, ok := requestAccessScope(c, h.store, claims)
+	if !ok {
+		return
+	}
+	var userID *int32
+	if !access.All {
+		id := int32(claims.UserID)
+		userID = &id
+	}
+	rows, err := h.store.Assessments().QualityByClinician(c.Request.Context(), userID, q.Below)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to load data quality report")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load data quality"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"below": q.Below, "data": rows})
+}
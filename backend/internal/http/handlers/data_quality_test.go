@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestDataQuality_ScopesToCaller(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		role     string
+		wantUser *int32
+	}{
+		{"admin", nil},
+		{"clinician", func() *int32 { id := int32(5); return &id }()},
+	} {
+		t.Run(tc.role, func(t *testing.T) {
+			repo := &fakeAssessmentRepo{}
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("user", middleware.UserClaims{UserID: 5, Email: "doc@example.com", Role: tc.role})
+				c.Next()
+			})
+			NewDataQualityHandler(&fakeStore{repo: repo}).Register(r.Group("/analytics"))
+
+			w := contactRequest(r, http.MethodGet, "/analytics/data-quality?below=70", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+			if (repo.qualityUser == nil) != (tc.wantUser == nil) ||
+				(tc.wantUser != nil && *repo.qualityUser != *tc.wantUser) {
+				t.Fatalf("report scoped to %v, want %v", repo.qualityUser, tc.wantUser)
+			}
+			var body struct {
+				Below int                           `json:"below"`
+				Data  []models.ClinicianDataQuality `json:"data"`
+			}
+			_ = json.Unmarshal(w.Body.Bytes(), &body)
+			if body.Below != 70 || len(body.Data) != 1 || body.Data[0].LowQuality != 70 {
+				t.Fatalf("threshold not passed through: %s", w.Body.String())
+			}
+		})
+	}
+
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	NewDataQualityHandler(&fakeStore{repo: &fakeAssessmentRepo{}}).Register(r.Group("/analytics"))
+	if w := contactRequest(r, http.MethodGet, "/analytics/data-quality?below=0", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for below=0, got %d", w.Code)
+	}
+}
+
+func TestAssessments_QualityScoreAndFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &fakeAssessmentRepo{all: []models.Assessment{
+		{ID: 1, PatientID: 9, Quality: &models.DataQuality{Score: 90}},
+		{ID: 2, PatientID: 9, Quality: &models.DataQuality{Score: 40}},
+		{ID: 3, PatientID: 9},
+	}}
+	h := NewAssessmentsHandler(&fakeStore{repo: repo, patientRepo: &fakePatientRepo{}}, ml.NewMockPredictor(), "v1", "hash123")
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	h.Register(r.Group("/patients"))
+
+	w := contactRequest(r, http.MethodPost, "/patients/9/assessments",
+		`{"fbs":95,"hba1c":5.4,"bmi":24,"self_reported":true}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if q := repo.last.Quality; !repo.last.SelfReported || q == nil || *q != ml.AssessQuality(repo.last) {
+		t.Fatalf("expected stored quality score, got %+v", q)
+	}
+
+	for _, tc := range []struct {
+		query string
+		want  []int64
+	}{
+		{"", []int64{1, 2, 3}},
+		{"?min_quality=50", []int64{1}},
+		{"?max_quality=50", []int64{2}},
+		{"?min_quality=0&max_quality=100", []int64{1, 2}},
+	} {
+		w := contactRequest(r, http.MethodGet, "/patients/9/assessments"+tc.query, "")
+		var got []models.Assessment
+		_ = json.Unmarshal(w.Body.Bytes(), &got)
+		if len(got) != len(tc.want) {
+			t.Fatalf("%q: got %d assessments, want %v", tc.query, len(got), tc.want)
+		}
+		for i, id := range tc.want {
+			if got[i].ID != id {
+				t.Fatalf("%q: got ids %v, want %v", tc.query, got, tc.want)
+			}
+		}
+	}
+	if w := contactRequest(r, http.MethodGet, "/patients/9/assessments?min_quality=101", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for min_quality=101, got %d", w.Code)
+	}
+}
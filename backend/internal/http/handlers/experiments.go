@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/experiments"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/pdf"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// recommendationExperiment trials the wording of report recommendations
+var recommendationExperiment = experiments.Experiment{
+	Name:     "recommendation_wording",
+	Variants: pdf.RecommendationVariants,
+}
+
+// knownExperiments are the experiments results can be reported for
+var knownExperiments = map[string]experiments.Experiment{
+	recommendationExperiment.Name: recommendationExperiment,
+}
+
+// defaultFollowUpDays is how long after exposure a follow-up assessment
+// still counts as the outcome
+const defaultFollowUpDays = 180
+
+// WithRecommendationExperiment splits report recommendation wording between
+// clinics (or users outside a clinic) and records which wording each
+// assessment's report showed. Off, every report uses the standard wording.
+func (h *AssessmentsHandler) WithRecommendationExperiment(enabled bool) *AssessmentsHandler {
+	h.recommendationExperiment = enabled
+	return h
+}
+
+// recommendationVariant picks the wording for a's report and records the
+// exposure. Any lookup or store failure falls back to the standard wording
+// so a report is never blocked by the experiment.
+func (h *AssessmentsHandler) recommendationVariant(ctx context.Context, userID int32, a models.Assessment) string {
+	exp := recommendationExperiment
+	if !h.recommendationExperiment {
+		return exp.Control()
+	}
+	unit, err := experimentUnit(ctx, h.store, userID)
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to resolve experiment unit for user %d", userID)
+		return exp.Control()
+	}
+	variant := exp.Assign(unit)
+	if err := h.store.Experiments().RecordExposure(ctx, models.ExperimentExposure{
+		Experiment:   exp.Name,
+		Variant:      variant,
+		Unit:         unit,
+		UserID:       int64(userID),
+		PatientID:    a.PatientID,
+		AssessmentID: a.ID,
+	}); err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to record %s exposure for assessment %d", exp.Name, a.ID)
+	}
+	return variant
+}
+
+// experimentUnit assigns a user through their clinic, the lowest-numbered if
+// they belong to several, so colleagues sharing patients see the same
+// variant. Users outside any clinic are assigned on their own.
+func experimentUnit(ctx context.Context, st store.Store, userID int32) (string, error) {
+	clinicID, ok, err := lowestClinic(ctx, st, userID)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return experiments.UserUnit(int64(userID)), nil
+	}
+	return experiments.ClinicUnit(clinicID), nil
+}
+
+// lowestClinic returns the lowest-numbered clinic the user belongs to, or
+// false if they belong to none.
+func lowestClinic(ctx context.Context, st store.Store, userID int32) (int64, bool, error) {
+	clinics, err := st.Clinics().ListUserClinics(ctx, userID)
+	if err != nil || len(clinics) == 0 {
+		return 0, false, err
+	}
+	lowest := clinics[0].ID
+	for _, uc := range clinics[1:] {
+		if uc.ID < lowest {
+			lowest = uc.ID
+		}
+	}
+	return lowest, true, nil
+}
+
+// AdminExperimentsHandler reports experiment outcomes
+type AdminExperimentsHandler struct {
+	store   store.Store
+	enabled map[string]bool
+}
+
+// NewAdminExperimentsHandler creates a new AdminExperimentsHandler. enabled
+// says which experiments are currently running.
+func NewAdminExperimentsHandler(store store.Store, enabled map[string]bool) *AdminExperimentsHandler {
+	return &AdminExperimentsHandler{store: store, enabled: enabled}
+}
+
+// Register registers experiment routes on the given router group
+func (h *AdminExperimentsHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("/experiments/:name/results", h.results)
+}
+
+type experimentResultsQuery struct {
+	FollowUpDays int `form:"follow_up_days" binding:"gte=1,lte=730"`
+}
+
+// results returns per-variant exposure and follow-up counts
+// @Summary Experiment results (admin only)
+// @Description Counts exposures per variant and how many exposed assessments were followed by another assessment of the same patient within follow_up_days. Exposures younger than the window are not yet eligible.
+// @Tags Admin
+// @Produce json
+// @Param name path string true "Experiment name"
+// @Param follow_up_days query int false "Follow-up window in days" default(180)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/experiments/{name}/results [get]
+func (h *AdminExperimentsHandler) results(c *gin.Context) {
+	exp, ok := knownExperiments[c.Param("name")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "experiment not found"})
+		return
+	}
+	q := experimentResultsQuery{FollowUpDays: defaultFollowUpDays}
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "follow_up_days must be between 1 and 730"})
+		return
+	}
+
+	rows, err := h.store.Experiments().Results(c.Request.Context(), exp.Name, time.Duration(q.FollowUpDays)*24*time.Hour)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to load results for experiment %s", exp.Name)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load experiment results"})
+		return
+	}
+	// Every variant is listed, in experiment order, even before exposures
+	byVariant := make(map[string]models.ExperimentVariantResult, len(rows))
+	for _, r := range rows {
+		byVariant[r.Variant] = r
+	}
+	results := make([]models.ExperimentVariantResult, 0, len(exp.Variants))
+	for _, v := range exp.Variants {
+		r := byVariant[v]
+		r.Variant = v
+		results = append(results, r)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"experiment":     exp.Name,
+		"enabled":        h.enabled[exp.Name],
+		"control":        exp.Control(),
+		"follow_up_days": q.FollowUpDays,
+		"variants":       results,
+	})
+}
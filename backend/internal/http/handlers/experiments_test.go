@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/pdf"
+)
+
+type fakeExperimentRepo struct {
+	exposures []models.ExperimentExposure
+	followUp  time.Duration
+}
+
+func (f *fakeExperimentRepo) RecordExposure(ctx context.Context, e models.ExperimentExposure) error {
+	f.exposures = append(f.exposures, e)
+	return nil
+}
+
+func (f *fakeExperimentRepo) Results(ctx context.Context, experiment string, followUp time.Duration) ([]models.ExperimentVariantResult, error) {
+	f.followUp = followUp
+	return []models.ExperimentVariantResult{{Variant: pdf.RecommendationsAction, Exposures: 4, Eligible: 2, FollowedUp: 1, Rate: 0.5}}, nil
+}
+
+func TestReport_RecordsRecommendationExposure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, enabled := range []bool{false, true} {
+		repo := &fakeAssessmentRepo{stored: &models.Assessment{ID: 4, PatientID: 9, HbA1c: 6.8}}
+		st := &fakeStore{repo: repo, patientRepo: &fakePatientRepo{}, clinicRepo: &fakeSharingClinicRepo{role: models.ClinicRoleMember}}
+		h := NewAssessmentsHandler(st, ml.NewMockPredictor(), "v1", "hash123").WithRecommendationExperiment(enabled)
+		r := gin.New()
+		r.Use(mockAuthMiddleware())
+		h.Register(r.Group("/patients"))
+
+		w := contactRequest(r, http.MethodGet, "/patients/9/assessments/4/report", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("enabled=%v: expected 200, got %d: %s", enabled, w.Code, w.Body.String())
+		}
+		exposures := st.Experiments().(*fakeExperimentRepo).exposures
+		if !enabled {
+			if len(exposures) != 0 {
+				t.Fatalf("experiment off but recorded %v", exposures)
+			}
+			continue
+		}
+		if len(exposures) != 1 {
+			t.Fatalf("expected one exposure, got %v", exposures)
+		}
+		e := exposures[0]
+		if e.Unit != "clinic:4" || e.AssessmentID != 4 || e.PatientID != 9 || e.Variant != recommendationExperiment.Assign("clinic:4") {
+			t.Fatalf("unexpected exposure %+v", e)
+		}
+	}
+}
+
+func TestAdminExperiments_Results(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	st := &fakeStore{}
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	NewAdminExperimentsHandler(st, map[string]bool{"recommendation_wording": true}).Register(r.Group("/admin"))
+
+	w := contactRequest(r, http.MethodGet, "/admin/experiments/recommendation_wording/results?follow_up_days=90", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := st.Experiments().(*fakeExperimentRepo).followUp; got != 90*24*time.Hour {
+		t.Fatalf("follow-up window = %s, want 90 days", got)
+	}
+	var body struct {
+		Enabled  bool                             `json:"enabled"`
+		Variants []models.ExperimentVariantResult `json:"variants"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if !body.Enabled || len(body.Variants) != 2 {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+	if body.Variants[0].Variant != pdf.RecommendationsStandard || body.Variants[0].Exposures != 0 ||
+		body.Variants[1].Variant != pdf.RecommendationsAction || body.Variants[1].Rate != 0.5 {
+		t.Fatalf("variants should be listed in order with zeros filled in: %+v", body.Variants)
+	}
+
+	for path, want := range map[string]int{
+		"/admin/experiments/unknown/results":                                 http.StatusNotFound,
+		"/admin/experiments/recommendation_wording/results?follow_up_days=0": http.StatusBadRequest,
+	} {
+		if w := contactRequest(r, http.MethodGet, path, ""); w.Code != want {
+			t.Fatalf("%s: expected %d, got %d", path, want, w.Code)
+		}
+	}
+}
@@ -1,14 +1,18 @@
-// ExportHandler: CSV export endpoints for patients, assessments, and dataset slices.
+// ExportHandler: Streamed CSV and JSON exports of patients and assessments, and dataset slices.
 package handlers
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
 	"github.com/skufu/DianaV2/backend/internal/store"
 )
 
@@ -23,96 +27,209 @@ func NewExportHandler(store store.Store, maxRows int) *ExportHandler {
 
 func (h *ExportHandler) Register(rg *gin.RouterGroup) {
 	rg.GET("/patients.csv", h.patientsCSV)
+	rg.GET("/patients.json", h.patientsJSON)
 	rg.GET("/assessments.csv", h.assessmentsCSV)
+	rg.GET("/assessments.json", h.assessmentsJSON)
 	rg.GET("/datasets/:slice", h.datasetSlice)
 }
 
-func (h *ExportHandler) patientsCSV(c *gin.Context) {
-	userID, err := getUserID(c)
-	if err != nil {
-		c.Status(http.StatusUnauthorized)
-		return
+// exportFlushRows is how many rows go out between flushes, so a large export
+// reaches the client in chunks as the store reads it instead of being held
+// whole in memory.
+const exportFlushRows = 500
+
+// exportStream sends an export as its rows arrive. Nothing is written until
+// the first row, so a store failure before it is still a 500; after it the
+// body ends early and the failure is only logged.
+type exportStream struct {
+	c           *gin.Context
+	contentType string
+	filename    string
+	rows        int
+}
+
+// row is called before each row is written; it sends the headers ahead of
+// the first and reports whether the rows so far should be flushed.
+func (s *exportStream) row() (first, flush bool) {
+	if s.rows == 0 {
+		s.start()
+	}
+	s.rows++
+	return s.rows == 1, s.rows%exportFlushRows == 0
+}
+
+func (s *exportStream) start() {
+	s.c.Header("Content-Type", s.contentType)
+	s.c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", s.filename))
+	s.c.Status(http.StatusOK)
+}
+
+// fail reports err and returns true if the response is finished
+func (s *exportStream) fail(err error) bool {
+	if err == nil {
+		return false
+	}
+	if s.rows == 0 {
+		s.c.JSON(http.StatusInternalServerError, gin.H{"error": "export failed"})
+		return true
 	}
+	logging.Ctx(s.c.Request.Context()).Error().Err(err).Msgf("Export %s ended after %d rows", s.filename, s.rows)
+	return true
+}
 
-	c.Header("Content-Type", "text/csv")
-	c.Header("Content-Disposition", "attachment; filename=\"patients.csv\"")
+// streamCSV writes header and then every row each produces
+func streamCSV(c *gin.Context, filename string, header []string, each func(emit func([]string) error) error) {
+	s := &exportStream{c: c, contentType: "text/csv", filename: filename}
 	w := csv.NewWriter(c.Writer)
-	_ = w.Write([]string{"id", "name", "age", "menopause_status", "years_menopause", "bmi", "bp_systolic", "bp_diastolic", "activity", "phys_activity", "smoking", "hypertension", "heart_disease", "family_history", "chol", "ldl", "hdl", "triglycerides", "cluster"})
-	patients, err := h.store.Patients().ListAllLimited(c.Request.Context(), userID, h.maxRows)
-	if err != nil {
-		c.Status(http.StatusInternalServerError)
+	err := each(func(row []string) error {
+		first, flush := s.row()
+		if first {
+			_ = w.Write(header)
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+		if flush {
+			w.Flush()
+			c.Writer.Flush()
+		}
+		return w.Error()
+	})
+	if s.fail(err) {
 		return
 	}
-	for _, p := range patients {
-		_ = w.Write([]string{
-			strconv.FormatInt(p.ID, 10),
-			p.Name,
-			intToStr(p.Age),
-			p.MenopauseStatus,
-			intToStr(p.YearsMenopause),
-			floatToStr(p.BMI),
-			intToStr(p.BPSystolic),
-			intToStr(p.BPDiastolic),
-			p.Activity,
-			boolToStr(p.PhysActivity),
-			p.Smoking,
-			p.Hypertension,
-			p.HeartDisease,
-			boolToStr(p.FamilyHistory),
-			intToStr(p.Chol),
-			intToStr(p.LDL),
-			intToStr(p.HDL),
-			intToStr(p.Triglycerides),
-			"", // cluster not stored on patient
-		})
+	if s.rows == 0 {
+		s.start()
+		_ = w.Write(header)
 	}
 	w.Flush()
 }
 
-func (h *ExportHandler) assessmentsCSV(c *gin.Context) {
+// streamJSON writes every value each produces as one JSON array
+func streamJSON[T any](c *gin.Context, filename string, each func(emit func(T) error) error) {
+	s := &exportStream{c: c, contentType: "application/json; charset=utf-8", filename: filename}
+	enc := json.NewEncoder(c.Writer)
+	err := each(func(v T) error {
+		first, flush := s.row()
+		sep := ","
+		if first {
+			sep = "["
+		}
+		if _, err := c.Writer.WriteString(sep); err != nil {
+			return err
+		}
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		if flush {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if s.fail(err) {
+		return
+	}
+	if s.rows == 0 {
+		s.start()
+		_, _ = c.Writer.WriteString("[")
+	}
+	_, _ = c.Writer.WriteString("]\n")
+}
+
+type exportQuery struct {
+	// ClinicID exports the patients shared with that clinic instead of the
+	// caller's own
+	ClinicID *int32 `form:"clinic_id" binding:"omitempty,min=1"`
+}
+
+// exportOwner returns whose patients to export: the caller's, or those
+// shared with the requested clinic, which must be in the caller's access
+// scope. Returns false if a response has already been written.
+func (h *ExportHandler) exportOwner(c *gin.Context) (userID int32, clinicID *int32, ok bool) {
 	userID, err := getUserID(c)
 	if err != nil {
 		c.Status(http.StatusUnauthorized)
+		return 0, nil, false
+	}
+	var q exportQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "clinic_id must be a positive integer"})
+		return 0, nil, false
+	}
+	if q.ClinicID == nil {
+		return userID, nil, true
+	}
+	access, ok := requestAccessScope(c, h.store, c.MustGet("user").(middleware.UserClaims))
+	if !ok {
+		return 0, nil, false
+	}
+	if !access.HasClinic(*q.ClinicID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied - not a member of this clinic"})
+		return 0, nil, false
+	}
+	return userID, q.ClinicID, true
+}
+
+// eachPatient reads the patients exportOwner chose
+func (h *ExportHandler) eachPatient(c *gin.Context, userID int32, clinicID *int32, fn func(models.Patient) error) error {
+	if clinicID != nil {
+		return h.store.Patients().EachLimitedByClinic(c.Request.Context(), *clinicID, h.maxRows, fn)
+	}
+	return h.store.Patients().EachLimited(c.Request.Context(), userID, h.maxRows, fn)
+}
+
+// eachAssessment reads the assessments of the patients exportOwner chose
+func (h *ExportHandler) eachAssessment(c *gin.Context, userID int32, clinicID *int32, fn func(models.Assessment) error) error {
+	if clinicID != nil {
+		return h.store.Assessments().EachLimitedByClinic(c.Request.Context(), *clinicID, h.maxRows, fn)
+	}
+	return h.store.Assessments().EachLimitedByUser(c.Request.Context(), userID, h.maxRows, fn)
+}
+
+func (h *ExportHandler) patientsCSV(c *gin.Context) {
+	userID, clinicID, ok := h.exportOwner(c)
+	if !ok {
 		return
 	}
+	streamCSV(c, "patients.csv", patientCSVHeader, func(emit func([]string) error) error {
+		return h.eachPatient(c, userID, clinicID, func(p models.Patient) error {
+			return emit(patientCSVRow(p))
+		})
+	})
+}
 
-	c.Header("Content-Type", "text/csv")
-	c.Header("Content-Disposition", "attachment; filename=\"assessments.csv\"")
-	w := csv.NewWriter(c.Writer)
-	_ = w.Write([]string{"id", "patient_id", "fbs", "hba1c", "cholesterol", "ldl", "hdl", "triglycerides", "systolic", "diastolic", "activity", "history_flag", "smoking", "hypertension", "heart_disease", "bmi", "cluster", "risk_score", "model_version", "dataset_hash", "validation_status", "created_at"})
-	// Only export assessments for patients owned by the authenticated user
-	rows, err := h.store.Assessments().ListAllLimitedByUser(c.Request.Context(), userID, h.maxRows)
-	if err != nil {
-		c.Status(http.StatusInternalServerError)
+func (h *ExportHandler) patientsJSON(c *gin.Context) {
+	userID, clinicID, ok := h.exportOwner(c)
+	if !ok {
+		return
+	}
+	streamJSON(c, "patients.json", func(emit func(models.Patient) error) error {
+		return h.eachPatient(c, userID, clinicID, emit)
+	})
+}
+
+// Only assessments of patients owned by the authenticated user, or shared
+// with the requested clinic, are exported
+func (h *ExportHandler) assessmentsCSV(c *gin.Context) {
+	userID, clinicID, ok := h.exportOwner(c)
+	if !ok {
 		return
 	}
-	for _, a := range rows {
-		_ = w.Write([]string{
-			strconv.FormatInt(a.ID, 10),
-			strconv.FormatInt(a.PatientID, 10),
-			floatToStr(a.FBS),
-			floatToStr(a.HbA1c),
-			intToStr(a.Cholesterol),
-			intToStr(a.LDL),
-			intToStr(a.HDL),
-			intToStr(a.Triglycerides),
-			intToStr(a.Systolic),
-			intToStr(a.Diastolic),
-			a.Activity,
-			boolToStr(a.HistoryFlag),
-			a.Smoking,
-			a.Hypertension,
-			a.HeartDisease,
-			floatToStr(a.BMI),
-			a.Cluster,
-			intToStr(a.RiskScore),
-			a.ModelVersion,
-			a.DatasetHash,
-			a.ValidationStatus,
-			a.CreatedAt.Format(time.RFC3339),
+	streamCSV(c, "assessments.csv", assessmentCSVHeader, func(emit func([]string) error) error {
+		return h.eachAssessment(c, userID, clinicID, func(a models.Assessment) error {
+			return emit(assessmentCSVRow(a))
 		})
+	})
+}
+
+func (h *ExportHandler) assessmentsJSON(c *gin.Context) {
+	userID, clinicID, ok := h.exportOwner(c)
+	if !ok {
+		return
 	}
-	w.Flush()
+	streamJSON(c, "assessments.json", func(emit func(models.Assessment) error) error {
+		return h.eachAssessment(c, userID, clinicID, emit)
+	})
 }
 
 func (h *ExportHandler) datasetSlice(c *gin.Context) {
@@ -127,6 +244,63 @@ func (h *ExportHandler) datasetSlice(c *gin.Context) {
 	})
 }
 
+var patientCSVHeader = []string{"id", "name", "age", "menopause_status", "years_menopause", "bmi", "bp_systolic", "bp_diastolic", "activity", "phys_activity", "smoking", "hypertension", "heart_disease", "family_history", "chol", "ldl", "hdl", "triglycerides", "cluster"}
+
+func patientCSVRow(p models.Patient) []string {
+	return []string{
+		strconv.FormatInt(p.ID, 10),
+		p.Name,
+		intToStr(p.Age),
+		p.MenopauseStatus,
+		intToStr(p.YearsMenopause),
+		floatToStr(p.BMI),
+		intToStr(p.BPSystolic),
+		intToStr(p.BPDiastolic),
+		p.Activity,
+		boolToStr(p.PhysActivity),
+		p.Smoking,
+		p.Hypertension,
+		p.HeartDisease,
+		boolToStr(p.FamilyHistory),
+		intToStr(p.Chol),
+		intToStr(p.LDL),
+		intToStr(p.HDL),
+		intToStr(p.Triglycerides),
+		"", // cluster not stored on patient
+	}
+}
+
+var assessmentCSVHeader = []string{"id", "patient_id", "fbs", "hba1c", "cholesterol", "ldl", "hdl", "triglycerides", "systolic", "diastolic", "activity", "history_flag", "smoking", "hypertension", "heart_disease", "bmi", "cluster", "risk_score", "model_version", "dataset_hash", "validation_status", "self_reported", "quality_score", "created_at"}
+
+func assessmentCSVRow(a models.Assessment) []string {
+	return []string{
+		strconv.FormatInt(a.ID, 10),
+		strconv.FormatInt(a.PatientID, 10),
+		floatToStr(a.FBS),
+		floatToStr(a.HbA1c),
+		intToStr(a.Cholesterol),
+		intToStr(a.LDL),
+		intToStr(a.HDL),
+		intToStr(a.Triglycerides),
+		intToStr(a.Systolic),
+		intToStr(a.Diastolic),
+		a.Activity,
+		boolToStr(a.HistoryFlag),
+		a.Smoking,
+		a.Hypertension,
+		a.HeartDisease,
+		floatToStr(a.BMI),
+		a.Cluster,
+		intToStr(a.RiskScore),
+		a.ModelVersion,
+		a.DatasetHash,
+		a.ValidationStatus,
+		boolToStr(a.SelfReported),
+		qualityScoreStr(a.Quality),
+		a.CreatedAt.Format(time.RFC3339),
+	}
+}
+
 func intToStr(v int) string {
 	return strconv.Itoa(v)
 }
@@ -141,3 +315,11 @@ func boolToStr(v bool) string {
 	}
 	return "false"
 }
+
+// qualityScoreStr leaves the cell empty for assessments never scored.
+func qualityScoreStr(q *models.DataQuality) string {
+	if q == nil {
+		return ""
+	}
+	return strconv.Itoa(q.Score)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+func TestExport_StreamsCappedRows(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mem := store.NewMemoryStore()
+	ctx := context.Background()
+	for _, p := range []models.Patient{
+		{UserID: 1, Name: "Ana Cruz"}, {UserID: 1, Name: "Bea Santos"}, {UserID: 2, Name: "Other"}, {UserID: 1, Name: "Cora Reyes"},
+	} {
+		created, err := mem.Patients().Create(ctx, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := mem.Assessments().Create(ctx, models.Assessment{PatientID: created.ID, FBS: 95, HbA1c: 5.4}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", ownerClaims)
+		c.Next()
+	})
+	NewExportHandler(mem, 2).Register(r.Group("/export"))
+
+	w := contactRequest(r, http.MethodGet, "/export/patients.csv", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("patients.csv: expected 200 text/csv, got %d %v", w.Code, w.Header())
+	}
+	rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("patients.csv: %v", err)
+	}
+	if len(rows) != 3 || rows[0][1] != "name" || rows[1][1] != "Cora Reyes" || rows[2][1] != "Bea Santos" {
+		t.Errorf("expected the header and the owner's 2 newest patients, got %v", rows)
+	}
+
+	w = contactRequest(r, http.MethodGet, "/export/assessments.json", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Disposition") != `attachment; filename="assessments.json"` {
+		t.Fatalf("assessments.json: expected a 200 download, got %d %v", w.Code, w.Header())
+	}
+	var assessments []models.Assessment
+	if err := json.Unmarshal(w.Body.Bytes(), &assessments); err != nil {
+		t.Fatalf("assessments.json is not a JSON array: %v: %s", err, w.Body.String())
+	}
+	if len(assessments) != 2 {
+		t.Errorf("expected 2 assessments, got %d", len(assessments))
+	}
+
+	empty := gin.New()
+	empty.Use(func(c *gin.Context) {
+		c.Set("user", ownerClaims)
+		c.Next()
+	})
+	NewExportHandler(store.NewMemoryStore(), 2).Register(empty.Group("/export"))
+	if w := contactRequest(empty, http.MethodGet, "/export/patients.json", ""); w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("empty export: expected [], got %d %q", w.Code, w.Body.String())
+	}
+	if w := contactRequest(empty, http.MethodGet, "/export/assessments.csv", ""); strings.TrimSpace(w.Body.String()) != strings.Join(assessmentCSVHeader, ",") {
+		t.Errorf("empty export: expected only the header, got %q", w.Body.String())
+	}
+}
+
+func TestExport_Clinic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mem := store.NewMemoryStore()
+	ctx := context.Background()
+	clinic, _ := mem.Clinics().Create(ctx, "North", "")
+	other, _ := mem.Clinics().Create(ctx, "South", "")
+	_ = mem.Clinics().AddMember(ctx, int32(clinic.ID), 1, models.ClinicRoleMember)
+	_ = mem.Clinics().AddMember(ctx, int32(clinic.ID), 2, models.ClinicRoleMember)
+	shared, _ := mem.Patients().Create(ctx, models.Patient{UserID: 2, Name: "Shared"})
+	_, _ = mem.Patients().Create(ctx, models.Patient{UserID: 2, Name: "Private"})
+	clinicID := int32(clinic.ID)
+	_ = mem.Patients().SetClinic(ctx, shared.ID, 2, &clinicID, 2)
+	_, _ = mem.Assessments().Create(ctx, models.Assessment{PatientID: shared.ID, FBS: 95})
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", ownerClaims)
+		c.Next()
+	})
+	NewExportHandler(mem, 10).Register(r.Group("/export"))
+
+	path := "/export/patients.json?clinic_id=" + strconv.FormatInt(clinic.ID, 10)
+	w := contactRequest(r, http.MethodGet, path, "")
+	var patients []models.Patient
+	_ = json.Unmarshal(w.Body.Bytes(), &patients)
+	if w.Code != http.StatusOK || len(patients) != 1 || patients[0].Name != "Shared" {
+		t.Fatalf("expected only the shared patient, got %d %s", w.Code, w.Body.String())
+	}
+	w = contactRequest(r, http.MethodGet, "/export/assessments.json?clinic_id="+strconv.FormatInt(clinic.ID, 10), "")
+	var assessments []models.Assessment
+	_ = json.Unmarshal(w.Body.Bytes(), &assessments)
+	if w.Code != http.StatusOK || len(assessments) != 1 {
+		t.Fatalf("expected the shared patient's assessment, got %d %s", w.Code, w.Body.String())
+	}
+
+	for query, want := range map[string]int{
+		"?clinic_id=" + strconv.FormatInt(other.ID, 10): http.StatusForbidden,
+		"?clinic_id=0": http.StatusBadRequest,
+	} {
+		if w := contactRequest(r, http.MethodGet, "/export/patients.csv"+query, ""); w.Code != want {
+			t.Errorf("%s: expected %d, got %d", query, want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/fhir"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// FHIRHandler serves patients and assessments as HL7 FHIR R4 resources for
+// hospital integrations, and imports Observation bundles as assessments.
+// Imports go through the same validation, scoring and events as the JSON
+// API. Errors are OperationOutcomes rather than {"error": ...}.
+type FHIRHandler struct {
+	*AssessmentsHandler
+	maxAssessments int
+}
+
+// NewFHIRHandler creates a FHIR handler whose imports create at most
+// maxAssessments assessments per bundle.
+func NewFHIRHandler(ah *AssessmentsHandler, maxAssessments int) *FHIRHandler {
+	return &FHIRHandler{AssessmentsHandler: ah, maxAssessments: maxAssessments}
+}
+
+func (h *FHIRHandler) Register(rg *gin.RouterGroup) {
+	rg.POST("", h.importBundle)
+	rg.GET("/Patient/:id", h.patient)
+	rg.GET("/Observation", h.searchObservations)
+	rg.GET("/Observation/:id", h.observation)
+	rg.GET("/RiskAssessment", h.searchRiskAssessments)
+	rg.GET("/RiskAssessment/:id", h.riskAssessment)
+}
+
+func writeFHIR(c *gin.Context, status int, resource interface{}) {
+	c.Header("Content-Type", fhir.ContentType+"; charset=utf-8")
+	c.JSON(status, resource)
+}
+
+func fhirError(c *gin.Context, status int, code, diagnostics string) {
+	writeFHIR(c, status, fhir.Outcome(code, diagnostics))
+}
+
+func (h *FHIRHandler) patient(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		fhirError(c, http.StatusUnauthorized, "login", "unauthorized")
+		return
+	}
+	id, ok := fhir.ParsePatientRef(c.Param("id"))
+	if !ok {
+		fhirError(c, http.StatusNotFound, "not-found", "patient not found")
+		return
+	}
+	p, err := h.store.Patients().GetVisible(c.Request.Context(), int32(id), userID)
+	if err != nil {
+		fhirError(c, http.StatusNotFound, "not-found", "patient not found")
+		return
+	}
+	writeFHIR(c, http.StatusOK, fhir.NewPatient(*p))
+}
+
+// patientAssessments loads the assessments of the patient named by the
+// patient (or subject) search parameter. Returns false if a response has
+// already been written.
+func (h *FHIRHandler) patientAssessments(c *gin.Context) ([]models.Assessment, bool) {
+	userID, err := getUserID(c)
+	if err != nil {
+		fhirError(c, http.StatusUnauthorized, "login", "unauthorized")
+		return nil, false
+	}
+	ref := c.Query("patient")
+	if ref == "" {
+		ref = c.Query("subject")
+	}
+	if ref == "" {
+		fhirError(c, http.StatusBadRequest, "required", "the patient search parameter is required")
+		return nil, false
+	}
+	id, ok := fhir.ParsePatientRef(ref)
+	if !ok {
+		fhirError(c, http.StatusBadRequest, "value", "patient must be Patient/<id> or <id>")
+		return nil, false
+	}
+	ctx := c.Request.Context()
+	// Searching a patient the user cannot see finds nothing, as for the
+	// JSON API
+	if _, err := h.store.Patients().GetVisible(ctx, int32(id), userID); err != nil {
+		fhirError(c, http.StatusNotFound, "not-found", "patient not found")
+		return nil, false
+	}
+	records, err := h.store.Assessments().ListByPatient(ctx, id)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to list assessments for FHIR search")
+		fhirError(c, http.StatusInternalServerError, "exception", "failed to list assessments")
+		return nil, false
+	}
+	// Drafts are unvalidated, so they are not shared as observations
+	return models.FinalAssessments(records), true
+}
+
+// loadAssessment loads an assessment the user can see by id. Returns false
+// if a response has already been written.
+func (h *FHIRHandler) loadAssessment(c *gin.Context, id int64) (*models.Assessment, bool) {
+	userID, err := getUserID(c)
+	if err != nil {
+		fhirError(c, http.StatusUnauthorized, "login", "unauthorized")
+		return nil, false
+	}
+	a, err := h.store.Assessments().Get(c.Request.Context(), int32(id), userID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && a.Draft()) {
+		fhirError(c, http.StatusNotFound, "not-found", "resource not found")
+		return nil, false
+	}
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to load assessment %d", id)
+		fhirError(c, http.StatusInternalServerError, "exception", "failed to load assessment")
+		return nil, false
+	}
+	return a, true
+}
+
+// searchObservations returns every biomarker Observation of a patient,
+// optionally narrowed to one LOINC code with code=<loinc> or
+// code=http://loinc.org|<loinc>.
+func (h *FHIRHandler) searchObservations(c *gin.Context) {
+	records, ok := h.patientAssessments(c)
+	if !ok {
+		return
+	}
+	code := strings.TrimPrefix(c.Query("code"), fhir.LOINC+"|")
+	out := []fhir.Observation{}
+	for _, a := range records {
+		for _, o := range fhir.NewObservations(a) {
+			if code != "" && !observationHasCode(o, code) {
+				continue
+			}
+			out = append(out, o)
+		}
+	}
+	writeSearchSet(c, out)
+}
+
+func observationHasCode(o fhir.Observation, code string) bool {
+	codings := o.Code.Coding
+	for _, comp := range o.Component {
+		codings = append(codings, comp.Code.Coding...)
+	}
+	for _, cd := range codings {
+		if cd.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *FHIRHandler) observation(c *gin.Context) {
+	assessmentID, key, ok := fhir.ParseObservationID(c.Param("id"))
+	if !ok {
+		fhirError(c, http.StatusNotFound, "not-found", "resource not found")
+		return
+	}
+	a, ok := h.loadAssessment(c, assessmentID)
+	if !ok {
+		return
+	}
+	want := fhir.ObservationID(assessmentID, key)
+	for _, o := range fhir.NewObservations(*a) {
+		if o.ID == want {
+			writeFHIR(c, http.StatusOK, o)
+			return
+		}
+	}
+	fhirError(c, http.StatusNotFound, "not-found", "resource not found")
+}
+
+func (h *FHIRHandler) searchRiskAssessments(c *gin.Context) {
+	records, ok := h.patientAssessments(c)
+	if !ok {
+		return
+	}
+	out := make([]fhir.RiskAssessment, len(records))
+	for i, a := range records {
+		out[i] = fhir.NewRiskAssessment(a)
+	}
+	writeSearchSet(c, out)
+}
+
+func (h *FHIRHandler) riskAssessment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		fhirError(c, http.StatusNotFound, "not-found", "resource not found")
+		return
+	}
+	a, ok := h.loadAssessment(c, id)
+	if !ok {
+		return
+	}
+	writeFHIR(c, http.StatusOK, fhir.NewRiskAssessment(*a))
+}
+
+// writeSearchSet answers a search with a searchset Bundle
+func writeSearchSet[R any](c *gin.Context, resources []R) {
+	bundle, err := fhir.SearchSet(resources)
+	if err != nil {
+		fhirError(c, http.StatusInternalServerError, "exception", "failed to encode bundle")
+		return
+	}
+	writeFHIR(c, http.StatusOK, bundle)
+}
+
+// importBundle creates assessments from a batch or transaction Bundle of
+// Observations, one per patient and effectiveDateTime. The bundle is
+// stored all or nothing; the response has one entry per input entry,
+// located at the Observation it became. The assessment's RiskAssessment
+// shares the Observation id's numeric prefix.
+func (h *FHIRHandler) importBundle(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		fhirError(c, http.StatusUnauthorized, "login", "unauthorized")
+		return
+	}
+	var bundle fhir.Bundle
+	if err := c.ShouldBindJSON(&bundle); err != nil {
+		fhirError(c, http.StatusBadRequest, "structure", "invalid JSON")
+		return
+	}
+	imports, issues := fhir.ReadObservationBundle(bundle)
+	if len(issues) > 0 {
+		writeFHIR(c, http.StatusBadRequest, fhir.OperationOutcome{ResourceType: "OperationOutcome", Issue: issues})
+		return
+	}
+	if len(imports) > h.maxAssessments {
+		fhirError(c, http.StatusBadRequest, "too-costly", fmt.Sprintf("a bundle may create at most %d assessments", h.maxAssessments))
+		return
+	}
+
+	ctx := c.Request.Context()
+	mode := h.validationMode(ctx, userID)
+	modelVer, datasetHash := h.activeModel(ctx)
+	owned := make(map[int64]*models.Patient)
+	medicated := make(map[int64]bool)
+	items := make([]models.Assessment, len(imports))
+	for i, imp := range imports {
+		// Verify patient exists and belongs to user (cached per patient)
+		patient, seen := owned[imp.PatientID]
+		if !seen {
+			if p, err := h.store.Patients().Get(ctx, int32(imp.PatientID), userID); err == nil {
+				patient = p
+				medicated[imp.PatientID] = onMedication(ctx, h.store, imp.PatientID, time.Now())
+			}
+			owned[imp.PatientID] = patient
+		}
+		expression := fmt.Sprintf("Bundle.entry[%d].resource.subject", imp.Entries[0].Index)
+		if patient == nil {
+			issues = append(issues, fhir.Issue{Severity: "error", Code: "not-found", Diagnostics: "patient not found", Expression: []string{expression}})
+			continue
+		}
+
+		a := imp.Assessment
+		a.PatientAge = patient.Age
+		a.OnMedication = medicated[imp.PatientID]
+		a.ModelVersion, a.DatasetHash = modelVer, datasetHash
+		if fields := ml.CheckPlausibility(a); len(fields) > 0 && mode == models.ValidationModeStrict {
+			for _, fe := range fields {
+				issues = append(issues, fhir.Issue{Severity: "error", Code: "business-rule", Diagnostics: fe.Field + ": " + fe.Message, Expression: []string{expression}})
+			}
+			continue
+		}
+		a.ValidationStatus = validationStatus(a)
+		a.Quality = dataQuality(a)
+		ml.Score(ctx, h.predictor, &a, false)
+		items[i] = a
+	}
+	if len(issues) > 0 {
+		writeFHIR(c, http.StatusUnprocessableEntity, fhir.OperationOutcome{ResourceType: "OperationOutcome", Issue: issues})
+		return
+	}
+
+	created, err := h.store.Assessments().CreateBatch(ctx, items)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to import FHIR bundle")
+		fhirError(c, http.StatusInternalServerError, "exception", "failed to create assessments")
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	response := fhir.Bundle{ResourceType: "Bundle", Type: bundle.Type + "-response", Entry: make([]fhir.BundleEntry, len(bundle.Entry))}
+	for i, a := range created {
+		h.events.Publish(ctx, events.AssessmentCreated{Actor: claims.Email, UserID: userID, Assessment: a})
+		for _, e := range imports[i].Entries {
+			response.Entry[e.Index].Response = &fhir.BundleResponse{
+				Status:   "201 Created",
+				Location: "Observation/" + fhir.ObservationID(a.ID, e.Key),
+			}
+		}
+	}
+	writeFHIR(c, http.StatusOK, response)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/fhir"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func newFHIRTestRouter(repo *fakeAssessmentRepo) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	ah := NewAssessmentsHandler(&fakeStore{repo: repo, patientRepo: &fakePatientRepo{}}, ml.NewMockPredictor(), "v1", "hash123")
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	NewFHIRHandler(ah, 2).Register(r.Group("/fhir"))
+	return r
+}
+
+func TestFHIRHandler_Reads(t *testing.T) {
+	a := models.Assessment{ID: 7, PatientID: 3, HbA1c: 6.9, Systolic: 140, Diastolic: 90, Cluster: "SIDD", RiskScore: 92}
+	repo := &fakeAssessmentRepo{stored: &a, all: []models.Assessment{a}}
+	r := newFHIRTestRouter(repo)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/fhir/Patient/3")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), fhir.ContentType) {
+		t.Fatalf("expected a FHIR patient, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	w = get("/fhir/Observation?patient=Patient/3&code=http://loinc.org|8480-6")
+	var bundle fhir.Bundle
+	_ = json.Unmarshal(w.Body.Bytes(), &bundle)
+	if w.Code != http.StatusOK || bundle.Type != "searchset" || *bundle.Total != 1 {
+		t.Fatalf("expected the blood pressure panel only, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = get("/fhir/Observation/7-hba1c")
+	var obs fhir.Observation
+	_ = json.Unmarshal(w.Body.Bytes(), &obs)
+	if w.Code != http.StatusOK || *obs.ValueQuantity.Value != 6.9 {
+		t.Fatalf("unexpected observation %d %s", w.Code, w.Body.String())
+	}
+
+	w = get("/fhir/RiskAssessment?patient=3")
+	_ = json.Unmarshal(w.Body.Bytes(), &bundle)
+	if w.Code != http.StatusOK || *bundle.Total != 1 {
+		t.Fatalf("unexpected risk search %d %s", w.Code, w.Body.String())
+	}
+
+	for path, want := range map[string]int{
+		"/fhir/Observation/7-ldl":   http.StatusNotFound,
+		"/fhir/Observation/8-hba1c": http.StatusNotFound,
+		"/fhir/RiskAssessment/x":    http.StatusNotFound,
+		"/fhir/Observation":         http.StatusBadRequest,
+	} {
+		w := get(path)
+		if w.Code != want || !strings.Contains(w.Body.String(), `"OperationOutcome"`) {
+			t.Errorf("%s: expected %d with an OperationOutcome, got %d %s", path, want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestFHIRHandler_ImportBundle(t *testing.T) {
+	repo := &fakeAssessmentRepo{}
+	r := newFHIRTestRouter(repo)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/fhir", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", fhir.ContentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	observation := func(patient, code, value, unit string) string {
+		return `{"resource":{"resourceType":"Observation","status":"final","subject":{"reference":"Patient/` + patient + `"},
+			"code":{"coding":[{"system":"http://loinc.org","code":"` + code + `"}]},
+			"valueQuantity":{"value":` + value + `,"code":"` + unit + `"}},"request":{"method":"POST","url":"Observation"}}`
+	}
+
+	w := post(`{"resourceType":"Bundle","type":"batch","entry":[` +
+		observation("1", "4548-4", "6.2", "%") + `,` + observation("1", "39156-5", "32", "kg/m2") + `,` +
+		observation("2", "4548-4", "7.0", "%") + `]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp fhir.Bundle
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Type != "batch-response" || len(resp.Entry) != 3 || resp.Entry[1].Response.Location != "Observation/0-bmi" {
+		t.Fatalf("unexpected response %s", w.Body.String())
+	}
+	if len(repo.lastBatch) != 2 {
+		t.Fatalf("expected 2 assessments, got %d", len(repo.lastBatch))
+	}
+	if a := repo.lastBatch[0]; a.PatientID != 1 || a.HbA1c != 6.2 || a.BMI != 32 || a.Cluster == "" || a.ModelVersion != "v1" {
+		t.Errorf("unexpected first assessment %+v", a)
+	}
+
+	repo.lastBatch = nil
+	cases := map[string]struct {
+		body string
+		want int
+	}{
+		"not json":        {`{`, http.StatusBadRequest},
+		"unsupported":     {`{"resourceType":"Bundle","type":"batch","entry":[` + observation("1", "2345-7", "99", "mg/dL") + `]}`, http.StatusBadRequest},
+		"too many":        {`{"resourceType":"Bundle","type":"batch","entry":[` + observation("1", "4548-4", "6", "%") + `,` + observation("2", "4548-4", "6", "%") + `,` + observation("3", "4548-4", "6", "%") + `]}`, http.StatusBadRequest},
+		"no measurements": {`{"resourceType":"Bundle","type":"batch","entry":[]}`, http.StatusBadRequest},
+	}
+	for name, tc := range cases {
+		if w := post(tc.body); w.Code != tc.want || !strings.Contains(w.Body.String(), `"OperationOutcome"`) {
+			t.Errorf("%s: expected %d with an OperationOutcome, got %d %s", name, tc.want, w.Code, w.Body.String())
+		}
+	}
+	if repo.lastBatch != nil {
+		t.Fatalf("expected nothing stored from rejected bundles, got %d", len(repo.lastBatch))
+	}
+}
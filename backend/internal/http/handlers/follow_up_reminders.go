@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/mail"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// followUpReminderBatch is how many follow-ups one claim reminds of
+const followUpReminderBatch = 100
+
+// FollowUpReminder reminds clinicians of follow-ups falling due within lead,
+// once per due date. Each reminder is emailed to the patient's owner and
+// published as follow_up.due.
+type FollowUpReminder struct {
+	store   store.Store
+	mailer  mail.Mailer
+	baseURL string
+	lead    time.Duration
+	events  *events.Bus
+	now     func() time.Time
+}
+
+func NewFollowUpReminder(store store.Store, mailer mail.Mailer, baseURL string, lead time.Duration) *FollowUpReminder {
+	return &FollowUpReminder{store: store, mailer: mailer, baseURL: baseURL, lead: lead, now: time.Now}
+}
+
+// WithEvents publishes follow_up.due on bus for each reminder.
+func (r *FollowUpReminder) WithEvents(bus *events.Bus) *FollowUpReminder {
+	r.events = bus
+	return r
+}
+
+// Run sends every reminder that is due. Follow-ups are marked reminded
+// before the email goes out, so a failed email is logged and not retried.
+func (r *FollowUpReminder) Run(ctx context.Context) error {
+	for {
+		now := r.now()
+		due, err := r.store.FollowUps().ClaimReminders(ctx, now.Add(r.lead), now, followUpReminderBatch)
+		if err != nil {
+			return err
+		}
+		for _, f := range due {
+			r.remind(ctx, f)
+		}
+		if len(due) < followUpReminderBatch {
+			return nil
+		}
+	}
+}
+
+// remind emails the owner of the follow-up's patient. The email names the
+// patient by id only, as it leaves the system.
+func (r *FollowUpReminder) remind(ctx context.Context, f models.FollowUp) {
+	r.events.Publish(ctx, events.FollowUpDue{FollowUp: f})
+
+	owner, err := r.store.Users().FindByID(ctx, int32(f.UserID))
+	if err != nil || owner == nil || !owner.IsActive {
+		return
+	}
+	subject, verb := "DIANA: a follow-up is due", "is due"
+	if f.DueAt.Before(r.now()) {
+		subject, verb = "DIANA: a follow-up is overdue", "was due"
+	}
+	body := fmt.Sprintf("A follow-up with patient #%d %s on %s.\n\n%s/patients/%d\n",
+		f.PatientID, verb, f.DueAt.UTC().Format("Mon 2 Jan 2006 15:04 MST"), r.baseURL, f.PatientID)
+	if err := r.mailer.Send(ctx, owner.Email, subject, body); err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to remind user %d of follow-up %d", f.UserID, f.ID)
+	}
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// followUpRequest is the body of a follow-up create or update. Absent fields
+// keep their current value on update; due_at is required on create.
+type followUpRequest struct {
+	DueAt     *time.Time `json:"due_at"`
+	Reason    *string    `json:"reason"`
+	Completed *bool      `json:"completed"`
+}
+
+// apply copies the request onto f and returns the first problem with the
+// result, or "" if it is valid. Completing sets CompletedAt to now.
+func (r followUpRequest) apply(f *models.FollowUp, now time.Time) string {
+	if r.DueAt != nil {
+		f.DueAt = *r.DueAt
+	}
+	if r.Reason != nil {
+		f.Reason = strings.TrimSpace(*r.Reason)
+	}
+	if r.Completed != nil {
+		switch {
+		case !*r.Completed:
+			f.CompletedAt = nil
+		case f.CompletedAt == nil:
+			f.CompletedAt = &now
+		}
+	}
+	switch {
+	case f.DueAt.IsZero():
+		return "due_at is required"
+	case utf8.RuneCountInString(f.Reason) > 500:
+		return "reason must be at most 500 characters"
+	}
+	return ""
+}
+
+// followUpAudit is how a follow-up appears in audit details. The reason is
+// free text and left out.
+func followUpAudit(f models.FollowUp) map[string]interface{} {
+	return map[string]interface{}{
+		"follow_up_id": f.ID,
+		"due_at":       f.DueAt,
+		"completed":    f.CompletedAt != nil,
+	}
+}
+
+// auditFollowUp records a follow-up change
+func (h *PatientsHandler) auditFollowUp(c *gin.Context, action string, f models.FollowUp) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     action,
+		TargetType: "patient",
+		TargetID:   int(f.PatientID),
+		Details:    followUpAudit(f),
+	})
+}
+
+// storedFollowUp loads the :followUpID follow-up of patientID
+func (h *PatientsHandler) storedFollowUp(c *gin.Context, patientID int64) (*models.FollowUp, bool) {
+	followUpID, err := parseIDParam(c, "followUpID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid follow-up ID"})
+		return nil, false
+	}
+	f, err := h.store.FollowUps().Get(c.Request.Context(), followUpID, patientID)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "follow-up not found")
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load follow-up"})
+		return nil, false
+	}
+	return f, true
+}
+
+// listFollowUps returns the patient's follow-ups, soonest due first
+func (h *PatientsHandler) listFollowUps(c *gin.Context) {
+	patientID, ok := h.patientParam(c, false)
+	if !ok {
+		return
+	}
+	followUps, err := h.store.FollowUps().List(c.Request.Context(), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list follow-ups"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": followUps})
+}
+
+// createFollowUp schedules a follow-up visit
+func (h *PatientsHandler) createFollowUp(c *gin.Context) {
+	patientID, ok := h.patientParam(c, true)
+	if !ok {
+		return
+	}
+	var req followUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	f := models.FollowUp{PatientID: patientID}
+	if msg := req.apply(&f, time.Now()); msg != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+		return
+	}
+
+	created, err := h.store.FollowUps().Create(c.Request.Context(), f)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to create follow-up for patient %d", patientID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create follow-up"})
+		return
+	}
+	h.auditFollowUp(c, "patient.follow_up.create", *created)
+	c.JSON(http.StatusCreated, created)
+}
+
+// updateFollowUp reschedules, completes or reopens a follow-up
+func (h *PatientsHandler) updateFollowUp(c *gin.Context) {
+	patientID, ok := h.patientParam(c, true)
+	if !ok {
+		return
+	}
+	f, ok := h.storedFollowUp(c, patientID)
+	if !ok {
+		return
+	}
+	var req followUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if msg := req.apply(f, time.Now()); msg != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+		return
+	}
+
+	updated, err := h.store.FollowUps().Update(c.Request.Context(), *f)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "follow-up not found")
+		return
+	}
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to update follow-up %d", f.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update follow-up"})
+		return
+	}
+	h.auditFollowUp(c, "patient.follow_up.update", *updated)
+	c.JSON(http.StatusOK, updated)
+}
+
+// deleteFollowUp cancels a follow-up
+func (h *PatientsHandler) deleteFollowUp(c *gin.Context) {
+	patientID, ok := h.patientParam(c, true)
+	if !ok {
+		return
+	}
+	f, ok := h.storedFollowUp(c, patientID)
+	if !ok {
+		return
+	}
+	err := h.store.FollowUps().Delete(c.Request.Context(), f.ID, patientID)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "follow-up not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete follow-up"})
+		return
+	}
+	h.auditFollowUp(c, "patient.follow_up.delete", *f)
+	c.Status(http.StatusNoContent)
+}
+
+// FollowUpsHandler lists the follow-ups a clinician has to attend to across
+// their patients.
+type FollowUpsHandler struct {
+	store store.Store
+	now   func() time.Time
+}
+
+func NewFollowUpsHandler(store store.Store) *FollowUpsHandler {
+	return &FollowUpsHandler{store: store, now: time.Now}
+}
+
+func (h *FollowUpsHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("", h.list)
+}
+
+// list returns the incomplete follow-ups of the caller's patients, soonest
+// due first. ?due=overdue keeps those past their due date and ?due=upcoming
+// the rest.
+func (h *FollowUpsHandler) list(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	due := c.Query("due")
+	if due != "" && due != models.FollowUpsOverdue && due != models.FollowUpsUpcoming {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "due must be overdue or upcoming"})
+		return
+	}
+	followUps, err := h.store.FollowUps().ListOpen(c.Request.Context(), int64(userID), due, h.now())
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to list follow-ups of user %d", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list follow-ups"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": followUps})
+}
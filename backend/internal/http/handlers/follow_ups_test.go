@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// followUpsRouter serves the patient and follow-up routes as the given user
+func followUpsRouter(st store.Store, claims middleware.UserClaims) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		c.Set("user", claims)
+		c.Next()
+	})
+	NewPatientsHandler(st).Register(r.Group("/patients"))
+	NewFollowUpsHandler(st).Register(r.Group("/follow-ups"))
+	return r
+}
+
+func listFollowUps(t *testing.T, r *gin.Engine, path string) []models.FollowUp {
+	t.Helper()
+	w := contactRequest(r, http.MethodGet, path, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: expected 200, got %d: %s", path, w.Code, w.Body.String())
+	}
+	var list struct{ Data []models.FollowUp }
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	return list.Data
+}
+
+func TestFollowUps_ScheduleAndList(t *testing.T) {
+	mem := store.NewMemoryStore()
+	r := followUpsRouter(mem, ownerClaims)
+	path := ownedPatientPath(t, mem)
+	yesterday := time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+	nextWeek := time.Now().Add(7 * 24 * time.Hour).UTC().Format(time.RFC3339)
+
+	w := contactRequest(r, http.MethodPost, path+"/follow-ups", `{"due_at":"`+nextWeek+`","reason":" Repeat HbA1c "}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var upcoming models.FollowUp
+	_ = json.Unmarshal(w.Body.Bytes(), &upcoming)
+	if upcoming.Reason != "Repeat HbA1c" || upcoming.UserID != 1 || upcoming.PatientName != "Ana Cruz" || upcoming.CompletedAt != nil {
+		t.Fatalf("unexpected follow-up %+v", upcoming)
+	}
+	contactRequest(r, http.MethodPost, path+"/follow-ups", `{"due_at":"`+yesterday+`"}`)
+
+	if got := listFollowUps(t, r, "/follow-ups"); len(got) != 2 || got[0].DueAt.After(got[1].DueAt) {
+		t.Fatalf("expected both follow-ups, soonest first, got %+v", got)
+	}
+	if got := listFollowUps(t, r, "/follow-ups?due=overdue"); len(got) != 1 || got[0].ID == upcoming.ID {
+		t.Fatalf("expected only the overdue follow-up, got %+v", got)
+	}
+	if got := listFollowUps(t, r, "/follow-ups?due=upcoming"); len(got) != 1 || got[0].ID != upcoming.ID {
+		t.Fatalf("expected only the upcoming follow-up, got %+v", got)
+	}
+	if w := contactRequest(r, http.MethodGet, "/follow-ups?due=soon", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown due: expected 400, got %d", w.Code)
+	}
+	other := followUpsRouter(mem, middleware.UserClaims{UserID: 2, Email: "nurse@example.com", Role: "clinician"})
+	if got := listFollowUps(t, other, "/follow-ups"); len(got) != 0 {
+		t.Errorf("expected no follow-ups for another clinician, got %+v", got)
+	}
+
+	// Completing the overdue visit takes it off the clinician's list but not
+	// the patient's
+	overdue := listFollowUps(t, r, "/follow-ups?due=overdue")[0]
+	w = contactRequest(r, http.MethodPatch, path+"/follow-ups/"+strconv.FormatInt(overdue.ID, 10), `{"completed":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("complete: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := listFollowUps(t, r, "/follow-ups?due=overdue"); len(got) != 0 {
+		t.Errorf("expected no overdue follow-ups once completed, got %+v", got)
+	}
+	if got := listFollowUps(t, r, path+"/follow-ups"); len(got) != 2 || got[0].CompletedAt == nil {
+		t.Errorf("expected the patient's history to keep the completed visit, got %+v", got)
+	}
+
+	if w := contactRequest(r, http.MethodDelete, path+"/follow-ups/"+strconv.FormatInt(upcoming.ID, 10), ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", w.Code)
+	}
+	if w := contactRequest(r, http.MethodDelete, path+"/follow-ups/"+strconv.FormatInt(upcoming.ID, 10), ""); w.Code != http.StatusNotFound {
+		t.Errorf("deleted follow-up: expected 404, got %d", w.Code)
+	}
+
+	events, _, _ := mem.AuditEvents().List(context.Background(), models.AuditListParams{Page: 1, PageSize: 20})
+	var actions []string
+	for _, e := range events {
+		actions = append(actions, e.Action)
+		if _, ok := e.Details["reason"]; ok {
+			t.Error("follow-up reasons must not be audited")
+		}
+	}
+	for _, want := range []string{"patient.follow_up.create", "patient.follow_up.update", "patient.follow_up.delete"} {
+		if !strings.Contains(strings.Join(actions, ","), want) {
+			t.Errorf("expected audit action %s, got %v", want, actions)
+		}
+	}
+}
+
+func TestFollowUps_Validation(t *testing.T) {
+	mem := store.NewMemoryStore()
+	path := ownedPatientPath(t, mem)
+	r := followUpsRouter(mem, ownerClaims)
+
+	for _, body := range []string{
+		`{"reason":"Repeat HbA1c"}`,
+		`{"due_at":"2026-11-01T09:00:00Z","reason":"` + strings.Repeat("a", 501) + `"}`,
+	} {
+		if w := contactRequest(r, http.MethodPost, path+"/follow-ups", body); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%.40s: expected 422, got %d", body, w.Code)
+		}
+	}
+	other := followUpsRouter(mem, middleware.UserClaims{UserID: 2, Email: "nurse@example.com", Role: "clinician"})
+	if w := contactRequest(other, http.MethodPost, path+"/follow-ups", `{"due_at":"2026-11-01T09:00:00Z"}`); w.Code != http.StatusNotFound {
+		t.Errorf("other user's patient: expected 404, got %d", w.Code)
+	}
+}
+
+func TestFollowUpReminder_RemindsOncePerDueDate(t *testing.T) {
+	ctx := context.Background()
+	mem := store.NewMemoryStore()
+	owner, _ := mem.Users().Create(ctx, models.User{Email: "doc@example.com", Role: "clinician", IsActive: true})
+	patient, _ := mem.Patients().Create(ctx, models.Patient{UserID: owner.ID, Name: "Ana Cruz"})
+	now := time.Now()
+	soon, _ := mem.FollowUps().Create(ctx, models.FollowUp{PatientID: patient.ID, DueAt: now.Add(3 * time.Hour)})
+	_, _ = mem.FollowUps().Create(ctx, models.FollowUp{PatientID: patient.ID, DueAt: now.Add(72 * time.Hour)})
+
+	mailer := &fakeMailer{}
+	bus := events.NewBus()
+	var published []models.FollowUp
+	events.Subscribe(bus, "test", func(ctx context.Context, e events.FollowUpDue) error {
+		published = append(published, e.FollowUp)
+		return nil
+	})
+	reminder := NewFollowUpReminder(mem, mailer, "https://diana.example", 24*time.Hour).WithEvents(bus)
+
+	for i := 0; i < 2; i++ {
+		if err := reminder.Run(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(mailer.to) != 1 || mailer.to[0] != "doc@example.com" || len(published) != 1 || published[0].ID != soon.ID {
+		t.Fatalf("expected one reminder for the follow-up due within a day, got mail %v and events %+v", mailer.to, published)
+	}
+	if body := mailer.body[0]; strings.Contains(body, "Ana") || !strings.Contains(body, "https://diana.example/patients/"+strconv.FormatInt(patient.ID, 10)) {
+		t.Errorf("reminder should link the patient without naming them: %q", body)
+	}
+
+	// Rescheduling into the reminder window reminds again
+	soon.DueAt = now.Add(2 * time.Hour)
+	if _, err := mem.FollowUps().Update(ctx, *soon); err != nil {
+		t.Fatal(err)
+	}
+	if err := reminder.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(mailer.to) != 2 {
+		t.Errorf("expected a second reminder after rescheduling, got %d", len(mailer.to))
+	}
+}
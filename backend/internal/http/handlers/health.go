@@ -2,8 +2,14 @@ package handlers
 
 import "github.com/gin-gonic/gin"
 
-func RegisterHealth(rg *gin.RouterGroup) {
+// RegisterHealth registers liveness endpoints. isReadOnly may be nil when the
+// database mode is not monitored.
+func RegisterHealth(rg *gin.RouterGroup, isReadOnly func() bool) {
 	rg.GET("/healthz", func(c *gin.Context) {
+		if isReadOnly != nil && isReadOnly() {
+			c.JSON(200, gin.H{"status": "ok", "database": "read_only"})
+			return
+		}
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 	rg.GET("/livez", func(c *gin.Context) {
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/hl7"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// maxHL7MessageBytes caps the size of one HL7 message
+const maxHL7MessageBytes = 1 << 20
+
+// HL7Handler receives lab results from lab interfaces that speak HL7 v2.
+// Results become assessment drafts for the matching patients; a clinician
+// completes a draft into an assessment. Requests are authenticated with an
+// API token carrying the integrations:hl7 scope.
+type HL7Handler struct {
+	store store.Store
+	now   func() time.Time
+}
+
+func NewHL7Handler(store store.Store) *HL7Handler {
+	return &HL7Handler{store: store, now: time.Now}
+}
+
+func (h *HL7Handler) Register(rg *gin.RouterGroup) {
+	rg.POST("/hl7", h.ingest)
+}
+
+// ack answers with an HL7 acknowledgement. msg is nil when the message could
+// not be parsed.
+func (h *HL7Handler) ack(c *gin.Context, status int, msg *hl7.Message, code, text string) {
+	c.Data(status, hl7.ContentType, []byte(hl7.ACK(msg, code, text, h.now())))
+}
+
+// ingest accepts one ORU^R01 message and answers with an ACK: AA when every
+// patient with usable results got a draft, AE when some could not be
+// matched, AR (with 400) when the message is not understood. A resent
+// message does not create drafts twice.
+func (h *HL7Handler) ingest(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxHL7MessageBytes+1))
+	if err != nil || len(body) > maxHL7MessageBytes {
+		h.ack(c, http.StatusRequestEntityTooLarge, nil, hl7.AckReject, "message too large or unreadable")
+		return
+	}
+	msg, err := hl7.Parse(string(body))
+	if err != nil {
+		h.ack(c, http.StatusBadRequest, nil, hl7.AckReject, err.Error())
+		return
+	}
+	if msg.ControlID() == "" {
+		h.ack(c, http.StatusBadRequest, msg, hl7.AckReject, "MSH-10 message control id is required")
+		return
+	}
+	results, err := msg.LabResults()
+	if err != nil {
+		h.ack(c, http.StatusBadRequest, msg, hl7.AckReject, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+	created, skipped := 0, 0
+	var problems []string
+	for _, r := range results {
+		skipped += len(r.Skipped)
+		if r.Found == 0 {
+			continue
+		}
+		patient, problem := h.matchPatient(ctx, r)
+		if problem != "" {
+			problems = append(problems, problem)
+			continue
+		}
+		_, err := h.store.AssessmentDrafts().Create(ctx, models.AssessmentDraft{
+			PatientID:        patient.ID,
+			Source:           models.DraftSourceHL7,
+			MessageControlID: msg.ControlID(),
+			SendingFacility:  msg.SendingFacility(),
+			FBS:              r.Values.FBS,
+			HbA1c:            r.Values.HbA1c,
+			Cholesterol:      r.Values.Cholesterol,
+			LDL:              r.Values.LDL,
+			HDL:              r.Values.HDL,
+			Triglycerides:    r.Values.Triglycerides,
+			ObservedAt:       r.ObservedAt,
+		})
+		if errors.Is(err, store.ErrConflict) {
+			// Already received; the sender missed our first ACK
+			continue
+		}
+		if err != nil {
+			logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to store HL7 results of message %s", msg.ControlID())
+			h.ack(c, http.StatusInternalServerError, msg, hl7.AckError, "failed to store results; please resend")
+			return
+		}
+		created++
+	}
+
+	token := c.MustGet("api_token").(middleware.APITokenClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      "api_token:" + token.Name,
+		Action:     "hl7.ingest",
+		TargetType: "api_token",
+		TargetID:   int(token.TokenID),
+		Details: map[string]interface{}{
+			"message_control_id": msg.ControlID(),
+			"sending_facility":   msg.SendingFacility(),
+			"drafts":             created,
+			"unmatched":          len(problems),
+			"skipped_results":    skipped,
+		},
+	})
+
+	if len(problems) > 0 {
+		h.ack(c, http.StatusOK, msg, hl7.AckError, strings.Join(problems, "; "))
+		return
+	}
+	h.ack(c, http.StatusOK, msg, hl7.AckAccept, fmt.Sprintf("%d draft(s) created, %d result(s) skipped", created, skipped))
+}
+
+// matchPatient finds the one patient with the result's MRN. When PID-5
+// carries a family name it must appear in the patient's name, so a reused
+// or mistyped MRN does not file results under someone else.
+func (h *HL7Handler) matchPatient(ctx context.Context, r hl7.PatientResult) (*models.Patient, string) {
+	if r.MRN == "" {
+		return nil, "PID-3 has no patient identifier"
+	}
+	patients, err := h.store.Patients().ListByMRN(ctx, r.MRN)
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msg("Failed to look up MRN for HL7 results")
+		return nil, "patient lookup failed for MRN " + r.MRN
+	}
+	var matches []models.Patient
+	for _, p := range patients {
+		if r.FamilyName == "" || strings.Contains(strings.ToLower(p.Name), strings.ToLower(r.FamilyName)) {
+			matches = append(matches, p)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, "no patient matches MRN " + r.MRN
+	case 1:
+		return &matches[0], ""
+	default:
+		return nil, "MRN " + r.MRN + " matches more than one patient"
+	}
+}
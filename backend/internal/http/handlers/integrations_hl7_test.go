@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+const testORU = "MSH|^~\\&|LIS|CityLab|DIANA|Clinic|20260301090000||ORU^R01|MSG0001|P|2.5.1\r" +
+	"PID|1||MRN-1^^^CityLab||Santos^Maria\r" +
+	"OBR|1|||24331-1^Lipid panel^LN|||20260301083000\r" +
+	"OBX|1|NM|4548-4^HbA1c^LN||6.8|%|||||F\r" +
+	"OBX|2|NM|2093-3^Cholesterol^LN||5.2|mmol/L|||||F\r" +
+	"OBX|3|ST|2085-9^HDL^LN||pending||||||P\r" +
+	"PID|2||MRN-9\r" +
+	"OBX|1|NM|1558-6^Glucose^LN||99|mg/dL|||||F\r"
+
+func newHL7TestRouter(st *fakeStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		c.Set("api_token", middleware.APITokenClaims{TokenID: 4, Name: "lab", Scopes: []string{models.ScopeHL7Ingest}})
+		c.Next()
+	})
+	NewHL7Handler(st).Register(r.Group("/integrations"))
+	return r
+}
+
+func postHL7(r *gin.Engine, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, "/integrations/hl7", strings.NewReader(body))
+	req.Header.Set("Content-Type", "x-application/hl7-v2+er7")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHL7Handler_IngestCreatesDrafts(t *testing.T) {
+	audit := &fakeAuditRepo{}
+	st := &fakeStore{
+		patientRepo: &fakePatientRepo{patients: []models.Patient{{ID: 3, UserID: 1, Name: "Maria Santos", MRN: "MRN-1"}}},
+		audit:       audit,
+	}
+	r := newHL7TestRouter(st)
+
+	w := postHL7(r, testORU)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "MSA|AE|MSG0001|no patient matches MRN MRN-9") {
+		t.Fatalf("expected AE for the unknown patient, got %d %q", w.Code, w.Body.String())
+	}
+	drafts, _ := st.AssessmentDrafts().ListPending(context.Background(), 3)
+	if len(drafts) != 1 {
+		t.Fatalf("expected one draft, got %+v", drafts)
+	}
+	d := drafts[0]
+	if d.HbA1c != 6.8 || d.Cholesterol != 201 || d.HDL != 0 || d.MessageControlID != "MSG0001" || d.SendingFacility != "CityLab" {
+		t.Errorf("unexpected draft %+v", d)
+	}
+	if d.ObservedAt == nil || d.ObservedAt.Hour() != 8 {
+		t.Errorf("expected the OBR observation time, got %v", d.ObservedAt)
+	}
+	if len(audit.events) != 1 || audit.events[0].Action != "hl7.ingest" || audit.events[0].Actor != "api_token:lab" {
+		t.Errorf("expected an hl7.ingest audit event, got %+v", audit.events)
+	}
+
+	// A resend after a lost ACK does not duplicate the draft
+	postHL7(r, testORU)
+	if drafts, _ := st.AssessmentDrafts().ListPending(context.Background(), 3); len(drafts) != 1 {
+		t.Errorf("expected the resend to be ignored, got %d drafts", len(drafts))
+	}
+}
+
+func TestHL7Handler_IngestMatchesFamilyName(t *testing.T) {
+	st := &fakeStore{patientRepo: &fakePatientRepo{patients: []models.Patient{
+		{ID: 3, Name: "Maria Santos", MRN: "MRN-1"},
+		{ID: 4, Name: "Ana Reyes", MRN: "MRN-1"},
+	}}}
+	w := postHL7(newHL7TestRouter(st), strings.Replace(testORU, "Santos^Maria", "Cruz^Maria", 1))
+	if !strings.Contains(w.Body.String(), "no patient matches MRN MRN-1") {
+		t.Fatalf("expected the surname mismatch to be reported, got %q", w.Body.String())
+	}
+	if drafts, _ := st.AssessmentDrafts().ListPending(context.Background(), 3); len(drafts) != 0 {
+		t.Errorf("expected no draft, got %+v", drafts)
+	}
+}
+
+func TestHL7Handler_IngestRejects(t *testing.T) {
+	r := newHL7TestRouter(&fakeStore{patientRepo: &fakePatientRepo{}})
+	for name, body := range map[string]string{
+		"not hl7":       "hello",
+		"no control id": strings.Replace(testORU, "MSG0001", "", 1),
+		"not ORU":       strings.Replace(testORU, "ORU^R01", "ADT^A01", 1),
+	} {
+		w := postHL7(r, body)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "MSA|AR|") {
+			t.Errorf("%s: expected AR with 400, got %d %q", name, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAssessmentsHandler_CompletesDraft(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	drafts := store.NewMemoryStore().AssessmentDrafts()
+	draft, err := drafts.Create(context.Background(), models.AssessmentDraft{PatientID: 123, Source: models.DraftSourceHL7, MessageControlID: "M1", HbA1c: 6.8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &fakeAssessmentRepo{}
+	h := NewAssessmentsHandler(&fakeStore{repo: repo, patientRepo: &fakePatientRepo{}, drafts: drafts}, ml.NewMockPredictor(), "v1", "hash123")
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(mockAuthMiddleware())
+	h.Register(r.Group(""))
+
+	w := postJSON(r, "/123/assessments", `{"fbs":110,"hba1c":6.8,"cholesterol":205,"bmi":25,"draft_id":99}`)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown draft, got %d %s", w.Code, w.Body.String())
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/123/assessment-drafts", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var listed struct {
+		Data []models.AssessmentDraft `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &listed)
+	if w.Code != http.StatusOK || len(listed.Data) != 1 || listed.Data[0].ID != draft.ID {
+		t.Fatalf("expected the pending draft, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = postJSON(r, "/123/assessments", `{"fbs":110,"hba1c":6.8,"cholesterol":205,"bmi":25,"draft_id":`+strconv.FormatInt(draft.ID, 10)+`}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d %s", w.Code, w.Body.String())
+	}
+	if pending, _ := drafts.ListPending(context.Background(), 123); len(pending) != 0 {
+		t.Errorf("expected the draft to be completed, got %+v", pending)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, "/123/assessment-drafts/"+strconv.FormatInt(draft.ID, 10), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected a completed draft not to be discardable, got %d", w.Code)
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+var errInvalidCursor = errors.New("cursor must be created_at,id as returned in next_cursor")
+
+// listCursor reads the cursor query parameter of a list that is ordered
+// newest first. ok is false when there is none, so the list keeps its
+// default form; an empty cursor asks for the first keyset page.
+func listCursor(c *gin.Context) (after *models.ListCursor, ok bool, err error) {
+	raw, ok := c.GetQuery("cursor")
+	if !ok || raw == "" {
+		return nil, ok, nil
+	}
+	createdAt, id, found := strings.Cut(raw, ",")
+	if !found {
+		return nil, true, errInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, true, errInvalidCursor
+	}
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || n < 1 {
+		return nil, true, errInvalidCursor
+	}
+	return &models.ListCursor{CreatedAt: t, ID: n}, true, nil
+}
+
+// nextCursor is the cursor of the page after a row created at createdAt
+// with id
+func nextCursor(createdAt time.Time, id int64) string {
+	return createdAt.UTC().Format(time.RFC3339Nano) + "," + strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// walkCursor follows next_cursor from the first keyset page of path and
+// returns the ids in the order served and the size of each page
+func walkCursor(t *testing.T, r *gin.Engine, path string) (ids []int64, pages []int) {
+	t.Helper()
+	cursor := ""
+	for len(pages) < 10 {
+		w := contactRequest(r, http.MethodGet, path+"&cursor="+url.QueryEscape(cursor), "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+		var page struct {
+			Data       []struct{ ID int64 }
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range page.Data {
+			ids = append(ids, row.ID)
+		}
+		pages = append(pages, len(page.Data))
+		if page.NextCursor == "" {
+			return ids, pages
+		}
+		cursor = page.NextCursor
+	}
+	t.Fatalf("%s: cursor never ended", path)
+	return nil, nil
+}
+
+func TestListCursor_PagesNewestFirst(t *testing.T) {
+	mem := store.NewMemoryStore()
+	r := medicationsRouter(mem, ml.NewMockPredictor(), ownerClaims)
+	ctx := context.Background()
+
+	var patientIDs []int64
+	for i := 0; i < 5; i++ {
+		p, err := mem.Patients().Create(ctx, models.Patient{UserID: 1, Name: "Patient " + strconv.Itoa(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		patientIDs = append(patientIDs, p.ID)
+	}
+	if _, err := mem.Patients().Create(ctx, models.Patient{UserID: 2, Name: "Someone else's"}); err != nil {
+		t.Fatal(err)
+	}
+	slices.Reverse(patientIDs)
+
+	ids, pages := walkCursor(t, r, "/patients?page_size=2")
+	if !slices.Equal(ids, patientIDs) || !slices.Equal(pages, []int{2, 2, 1}) {
+		t.Errorf("patients: expected %v in pages of 2, 2, 1, got %v in %v", patientIDs, ids, pages)
+	}
+
+	var assessmentIDs []int64
+	for i := 0; i < 3; i++ {
+		a, err := mem.Assessments().Create(ctx, models.Assessment{PatientID: patientIDs[0], FBS: 90, HbA1c: 5.2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assessmentIDs = append(assessmentIDs, a.ID)
+	}
+	slices.Reverse(assessmentIDs)
+	path := "/patients/" + strconv.FormatInt(patientIDs[0], 10) + "/assessments?page_size=2"
+	ids, pages = walkCursor(t, r, path)
+	if !slices.Equal(ids, assessmentIDs) || !slices.Equal(pages, []int{2, 1}) {
+		t.Errorf("assessments: expected %v in pages of 2, 1, got %v in %v", assessmentIDs, ids, pages)
+	}
+
+	for _, bad := range []string{
+		"/patients?cursor=yesterday",
+		"/patients?cursor=2026-01-01T00:00:00Z,0",
+		"/patients?cursor=&page=2",
+		"/patients?cursor=&sort=name",
+		path + "&cursor=not-a-cursor",
+	} {
+		if w := contactRequest(r, http.MethodGet, bad, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", bad, w.Code)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// listETag derives a weak ETag for a list from its version and whatever
+// else shapes the response, such as the query string. It is weak because
+// it is not a hash of the body.
+func listETag(v models.ListVersion, parts ...string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d:%d:%d", v.Count, v.IDSum, v.LastUpdated.UnixNano())
+	for _, p := range parts {
+		fmt.Fprintf(h, "\x00%s", p)
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header names etag, comparing
+// weakly as RFC 9110 requires for GET
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sends etag and answers 304 when the client already has that
+// version. Clients must revalidate every time, so a poll costs one cheap
+// version query instead of the whole list. Returns true if the response has
+// been written.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("Vary", "Authorization")
+	if header := c.GetHeader("If-None-Match"); header != "" && etagMatches(header, etag) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// conditionalGet fetches path, sending etag as If-None-Match when set
+func conditionalGet(r *gin.Engine, path, etag string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestListETags(t *testing.T) {
+	mem := store.NewMemoryStore()
+	r := medicationsRouter(mem, ml.NewMockPredictor(), ownerClaims)
+	path := ownedPatientPath(t, mem)
+
+	for _, list := range []string{"/patients?page_size=10", path + "/assessments"} {
+		w := conditionalGet(r, list, "")
+		etag := w.Header().Get("ETag")
+		if w.Code != http.StatusOK || etag == "" {
+			t.Fatalf("%s: expected 200 with an ETag, got %d %q", list, w.Code, etag)
+		}
+		if w := conditionalGet(r, list, `"other", `+etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("%s: unchanged list should be 304 without a body, got %d", list, w.Code)
+		}
+	}
+
+	patientsETag := conditionalGet(r, "/patients?page_size=10", "").Header().Get("ETag")
+	if w := conditionalGet(r, "/patients?page_size=20", patientsETag); w.Code != http.StatusOK {
+		t.Errorf("other query parameters: expected 200, got %d", w.Code)
+	}
+	assessmentsETag := conditionalGet(r, path+"/assessments", "").Header().Get("ETag")
+
+	// A new assessment changes both lists, as it is the patient's latest
+	if w := contactRequest(r, http.MethodPost, path+"/assessments", `{"fbs":110,"hba1c":6.0,"bmi":27}`); w.Code != http.StatusCreated {
+		t.Fatalf("create assessment: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := conditionalGet(r, "/patients?page_size=10", patientsETag); w.Code != http.StatusOK {
+		t.Errorf("patients after a new assessment: expected 200, got %d", w.Code)
+	}
+	if w := conditionalGet(r, path+"/assessments", assessmentsETag); w.Code != http.StatusOK {
+		t.Errorf("assessments after a new assessment: expected 200, got %d", w.Code)
+	}
+
+	// Switching to mmol/L adds in_units, so the assessment list changes too
+	_, _ = mem.Users().Create(context.Background(), models.User{Email: "doc@example.com", Role: "clinician"})
+	assessmentsETag = conditionalGet(r, path+"/assessments", "").Header().Get("ETag")
+	_ = mem.Users().SavePreferences(context.Background(), 1, models.UserPreferences{Units: models.UnitsMmolL})
+	if w := conditionalGet(r, path+"/assessments", assessmentsETag); w.Code != http.StatusOK {
+		t.Errorf("assessments in other units: expected 200, got %d", w.Code)
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// LoginHistoryHandler shows sign-in attempts to the account they targeted
+// and to admins reviewing suspicious activity
+type LoginHistoryHandler struct {
+	store store.Store
+}
+
+// NewLoginHistoryHandler creates a new LoginHistoryHandler
+func NewLoginHistoryHandler(store store.Store) *LoginHistoryHandler {
+	return &LoginHistoryHandler{store: store}
+}
+
+// Register registers the caller's login history route
+func (h *LoginHistoryHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("", h.listOwn)
+}
+
+// RegisterAdmin registers the login history review routes on the admin group
+func (h *LoginHistoryHandler) RegisterAdmin(rg *gin.RouterGroup) {
+	rg.GET("/login-history", h.list)
+	rg.GET("/login-history/suspicious", h.suspicious)
+}
+
+type loginHistoryQuery struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=200"`
+}
+
+// listOwn returns the sign-in attempts against the caller's account
+// @Summary List my login history
+// @Description Successful and failed sign-ins to the caller's account, newest first, with the IP address and user agent of each
+// @Tags Users
+// @Produce json
+// @Param limit query int false "Maximum attempts, 1-200" default(50)
+// @Success 200 {object} map[string][]models.LoginAttempt
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/login-history [get]
+func (h *LoginHistoryHandler) listOwn(c *gin.Context) {
+	var q loginHistoryQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+	claims := c.MustGet("user").(middleware.UserClaims)
+	attempts, err := h.store.LoginHistory().List(c.Request.Context(), models.LoginHistoryParams{
+		UserID: &claims.UserID,
+		Limit:  q.Limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load login history"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": attempts})
+}
+
+type adminLoginHistoryQuery struct {
+	loginHistoryQuery
+	UserID int64  `form:"user_id" binding:"omitempty,min=1"`
+	IP     string `form:"ip"`
+	Failed bool   `form:"failed"`
+	Since  string `form:"since"`
+}
+
+// list returns sign-in attempts across every account
+// @Summary List login history (admin only)
+// @Description Sign-in attempts across all accounts, newest first, including failures against unknown emails
+// @Tags Admin
+// @Produce json
+// @Param user_id query int false "Only attempts against this user"
+// @Param ip query string false "Only attempts from this IP address"
+// @Param failed query bool false "Only failed attempts"
+// @Param since query string false "Only attempts at or after this time (RFC3339 or YYYY-MM-DD)"
+// @Param limit query int false "Maximum attempts, 1-200" default(50)
+// @Success 200 {object} map[string][]models.LoginAttempt
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/login-history [get]
+func (h *LoginHistoryHandler) list(c *gin.Context) {
+	var q adminLoginHistoryQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+	params := models.LoginHistoryParams{IPAddress: q.IP, FailedOnly: q.Failed, Limit: q.Limit}
+	if q.UserID > 0 {
+		params.UserID = &q.UserID
+	}
+	if q.Since != "" {
+		since, ok := parseSince(q.Since)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be RFC3339 or YYYY-MM-DD"})
+			return
+		}
+		params.Since = &since
+	}
+	attempts, err := h.store.LoginHistory().List(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load login history"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": attempts})
+}
+
+type suspiciousLoginQuery struct {
+	Hours       int `form:"hours" binding:"min=1,max=720"`
+	MinFailures int `form:"min_failures" binding:"min=1"`
+}
+
+// suspicious returns the addresses with repeated failed logins
+// @Summary Suspicious login sources (admin only)
+// @Description IP addresses with at least min_failures failed sign-ins in the last hours, most failures first. Many accounts from one address suggests credential stuffing.
+// @Tags Admin
+// @Produce json
+// @Param hours query int false "Window in hours, 1-720" default(24)
+// @Param min_failures query int false "Minimum failed attempts" default(5)
+// @Success 200 {object} map[string][]models.SuspiciousIP
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/login-history/suspicious [get]
+func (h *LoginHistoryHandler) suspicious(c *gin.Context) {
+	q := suspiciousLoginQuery{Hours: 24, MinFailures: 5}
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hours must be between 1 and 720 and min_failures at least 1"})
+		return
+	}
+	since := time.Now().Add(-time.Duration(q.Hours) * time.Hour)
+	ips, err := h.store.LoginHistory().SuspiciousIPs(c.Request.Context(), since, q.MinFailures)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load login history"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": ips, "since": since.UTC().Format(time.RFC3339)})
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/config"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+func TestLoginHistory_RecordsAttempts(t *testing.T) {
+	user := mfaTestUser(t, 7, "clinician")
+	st := &fakeStore{
+		users:  &fakeUserRepo{user: user},
+		tokens: &fakeRefreshTokenRepo{},
+		audit:  &fakeAuditRepo{},
+		logins: store.NewMemoryStore().LoginHistory(),
+	}
+	r := authRouter(config.Config{}, st, &fakeMailer{})
+
+	if w := postJSON(r, "/auth/login", `{"email":"nobody@example.com","password":"secret"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown email: expected 401, got %d", w.Code)
+	}
+	if w := postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"wrong"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: expected 401, got %d", w.Code)
+	}
+	if user.LastLoginAt != nil {
+		t.Fatal("a failed login must not update the last login")
+	}
+	if w := postJSON(r, "/auth/login", `{"email":"doc@example.com","password":"secret"}`); w.Code != http.StatusOK {
+		t.Fatalf("login: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if user.LastLoginAt == nil {
+		t.Fatal("expected the last login to be updated")
+	}
+
+	all, _ := st.logins.List(context.Background(), models.LoginHistoryParams{})
+	if len(all) != 3 {
+		t.Fatalf("expected 3 attempts, got %+v", all)
+	}
+	if !all[0].Success || all[0].UserID == nil || *all[0].UserID != 7 {
+		t.Errorf("newest attempt should be the success: %+v", all[0])
+	}
+	if all[1].Success || all[1].FailureReason != models.LoginFailureBadPassword || all[1].UserID == nil {
+		t.Errorf("unexpected wrong password attempt %+v", all[1])
+	}
+	if all[2].FailureReason != models.LoginFailureUnknownEmail || all[2].UserID != nil || all[2].Email != "nobody@example.com" {
+		t.Errorf("unexpected unknown email attempt %+v", all[2])
+	}
+
+	// The user sees attempts against their own account only
+	mine := gin.New()
+	mine.Use(func(c *gin.Context) { c.Set("user", middleware.UserClaims{UserID: 7, Role: "clinician"}) })
+	NewLoginHistoryHandler(st).Register(mine.Group("/users/login-history"))
+	req, _ := http.NewRequest(http.MethodGet, "/users/login-history", nil)
+	w := httptest.NewRecorder()
+	mine.ServeHTTP(w, req)
+	var resp struct {
+		Data []models.LoginAttempt `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Data) != 2 {
+		t.Fatalf("expected 2 own attempts, got %d %+v", w.Code, resp.Data)
+	}
+}
+
+func TestLoginHistory_Admin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	logins := store.NewMemoryStore().LoginHistory()
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		_ = logins.Record(ctx, models.LoginAttempt{Email: email, IPAddress: "203.0.113.9", FailureReason: models.LoginFailureUnknownEmail})
+	}
+	uid := int64(2)
+	_ = logins.Record(ctx, models.LoginAttempt{UserID: &uid, Email: "doc@example.com", IPAddress: "198.51.100.1", FailureReason: models.LoginFailureBadPassword})
+	_ = logins.Record(ctx, models.LoginAttempt{UserID: &uid, Email: "doc@example.com", IPAddress: "198.51.100.1", Success: true})
+
+	r := gin.New()
+	admin := r.Group("/admin")
+	admin.Use(mockAuthMiddleware())
+	NewLoginHistoryHandler(&fakeStore{logins: logins}).RegisterAdmin(admin)
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	var attempts struct {
+		Data []models.LoginAttempt `json:"data"`
+	}
+	w := get("/admin/login-history?failed=true&user_id=2")
+	_ = json.Unmarshal(w.Body.Bytes(), &attempts)
+	if w.Code != http.StatusOK || len(attempts.Data) != 1 || attempts.Data[0].FailureReason != models.LoginFailureBadPassword {
+		t.Fatalf("filtered history: %d %+v", w.Code, attempts.Data)
+	}
+
+	var ips struct {
+		Data []models.SuspiciousIP `json:"data"`
+	}
+	w = get("/admin/login-history/suspicious?min_failures=2")
+	_ = json.Unmarshal(w.Body.Bytes(), &ips)
+	if w.Code != http.StatusOK || len(ips.Data) != 1 || ips.Data[0].IPAddress != "203.0.113.9" ||
+		ips.Data[0].Failures != 3 || ips.Data[0].Accounts != 3 {
+		t.Fatalf("suspicious: %d %+v", w.Code, ips.Data)
+	}
+
+	for _, path := range []string{"/admin/login-history?since=yesterday", "/admin/login-history/suspicious?hours=0"} {
+		if w := get(path); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// bundleAuditEvents is how many recent audit events a bundle includes.
+const bundleAuditEvents = 20
+
+// identifyingPatientFields are dropped from history snapshots when a bundle
+// is redacted.
+var identifyingPatientFields = map[string]bool{"name": true, "mrn": true}
+
+// bundle returns the patient's full record as one JSON document, or as a ZIP
+// holding bundle.json with format=zip. redact=identifiers leaves out the
+// name, MRN, contact details, photo, attachments and notes so the bundle can go to a specialist
+// outside the clinic. Audit actors other than the caller are only shown to
+// holders of admin.users.
+func (h *PatientsHandler) bundle(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	redact := false
+	switch c.Query("redact") {
+	case "":
+	case "identifiers":
+		redact = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "redact must be 'identifiers'"})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be 'json' or 'zip'"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	patient, err := h.store.Patients().Get(ctx, int32(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	claims := c.MustGet("user").(middleware.UserClaims)
+	seesActors, err := middleware.HasPermission(ctx, h.perms, claims, 0, models.PermAdminUsers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check permissions"})
+		return
+	}
+
+	b, err := h.loadBundle(c, userID, *patient)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to build bundle for patient %d", id)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build patient bundle"})
+		return
+	}
+	for i := range b.Audit.Recent {
+		e := &b.Audit.Recent[i]
+		e.Details = nil
+		if (redact || !seesActors) && e.Actor != claims.Email {
+			e.Actor = "redacted"
+		}
+	}
+	if redact {
+		redactBundle(b)
+	}
+
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "patient.bundle_export",
+		TargetType: "patient",
+		TargetID:   int(id),
+		Details: map[string]interface{}{
+			"redacted": redact,
+			"format":   format,
+		},
+	})
+
+	if format == "json" {
+		c.JSON(http.StatusOK, b)
+		return
+	}
+	body, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build patient bundle"})
+		return
+	}
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"patient-%d-bundle.zip\"", id))
+	zw := zip.NewWriter(c.Writer)
+	w, err := zw.Create("bundle.json")
+	if err == nil {
+		_, err = w.Write(body)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to write bundle for patient %d", id)
+	}
+}
+
+// loadBundle gathers every section of the patient's record. Any failure
+// fails the whole bundle: a referral must not silently miss data.
+func (h *PatientsHandler) loadBundle(c *gin.Context, userID int32, patient models.Patient) (*models.PatientBundle, error) {
+	ctx := c.Request.Context()
+	b := &models.PatientBundle{GeneratedAt: time.Now().UTC(), Patient: patient}
+	var err error
+
+	if b.Patient.Contact, err = h.store.PatientContacts().Get(ctx, patient.ID); err != nil {
+		return nil, fmt.Errorf("contact: %w", err)
+	}
+	photosEnabled, err := h.store.Clinics().PatientPhotosEnabledForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("photo setting: %w", err)
+	}
+	if photosEnabled {
+		if b.Photo, err = h.store.PatientPhotos().Get(ctx, patient.ID); err != nil {
+			return nil, fmt.Errorf("photo: %w", err)
+		}
+	}
+	if b.Assessments, err = h.store.Assessments().ListByPatient(ctx, patient.ID); err != nil {
+		return nil, fmt.Errorf("assessments: %w", err)
+	}
+	if b.Assessments == nil {
+		b.Assessments = []models.Assessment{}
+	}
+	// Metadata only: the files stay in object storage
+	if b.Attachments, err = h.store.AssessmentAttachments().ListByPatient(ctx, patient.ID); err != nil {
+		return nil, fmt.Errorf("attachments: %w", err)
+	}
+	if b.Attachments == nil {
+		b.Attachments = []models.AssessmentAttachment{}
+	}
+	if b.BaselineDiscrepancies, err = h.store.BaselineDiscrepancies().ListOpen(ctx, patient.ID); err != nil {
+		return nil, fmt.Errorf("baseline discrepancies: %w", err)
+	}
+	if b.History, err = h.store.PatientHistory().List(ctx, patient.ID); err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	if b.Notes, err = h.store.PatientNotes().List(ctx, patient.ID); err != nil {
+		return nil, fmt.Errorf("notes: %w", err)
+	}
+	if b.Medications, err = h.store.PatientMedications().List(ctx, patient.ID); err != nil {
+		return nil, fmt.Errorf("medications: %w", err)
+	}
+	if b.FollowUps, err = h.store.FollowUps().List(ctx, patient.ID); err != nil {
+		return nil, fmt.Errorf("follow-ups: %w", err)
+	}
+	if b.LastRiskAlert, err = h.store.RiskAlerts().LastForPatient(ctx, patient.ID); err != nil {
+		return nil, fmt.Errorf("risk alert: %w", err)
+	}
+	events, total, err := h.store.AuditEvents().List(ctx, models.AuditListParams{
+		Page:       1,
+		PageSize:   bundleAuditEvents,
+		TargetType: "patient",
+		TargetID:   int(patient.ID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	b.Audit = models.PatientBundleAudit{Total: total, Recent: events}
+	return b, nil
+}
+
+// redactBundle strips the identifiers a specialist outside the clinic does
+// not need. Notes are free text that may name the patient, so they are left
+// out entirely, as are follow-up reasons. Attachment filenames often carry
+// the patient's name, so attachments go with the photo.
+func redactBundle(b *models.PatientBundle) {
+	b.Redacted = true
+	b.Patient.Name = ""
+	b.Patient.MRN = ""
+	b.Patient.Contact = nil
+	b.Photo = nil
+	b.Attachments = []models.AssessmentAttachment{}
+	b.Notes = []models.PatientNote{}
+	for i := range b.FollowUps {
+		b.FollowUps[i].PatientName, b.FollowUps[i].Reason = "", ""
+	}
+	for i := range b.History {
+		v := &b.History[i]
+		for f := range identifyingPatientFields {
+			delete(v.Before, f)
+			delete(v.After, f)
+		}
+		changes := v.Changes[:0]
+		for _, ch := range v.Changes {
+			if !identifyingPatientFields[ch.Field] {
+				changes = append(changes, ch)
+			}
+		}
+		v.Changes = changes
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func bundleStore() *fakeStore {
+	return &fakeStore{
+		repo:        &fakeAssessmentRepo{},
+		patientRepo: &fakePatientRepo{stored: &models.Patient{ID: 7, UserID: 1, Name: "Ana Cruz", MRN: "MRN-1", BMI: 26}},
+		clinicRepo:  &fakePhotoClinicRepo{enabled: true},
+		contacts:    &fakePatientContactRepo{contacts: map[int64]models.PatientContact{7: {PatientID: 7, Phone: "+15550100"}}},
+		photos:      &fakePatientPhotoRepo{photos: map[int64]models.PatientPhoto{7: {PatientID: 7, ContentType: "image/jpeg"}}},
+		alerts:      &fakeRiskAlertRepo{},
+		history: &fakePatientHistoryRepo{versions: []models.PatientVersion{{
+			PatientID: 7, Version: 1,
+			Before:  map[string]interface{}{"name": "Ana", "bmi": 24.0},
+			After:   map[string]interface{}{"name": "Ana Cruz", "bmi": 26.0},
+			Changes: []models.PatientFieldChange{{Field: "bmi", Old: 24.0, New: 26.0}, {Field: "name", Old: "Ana", New: "Ana Cruz"}},
+		}}},
+		audit: &fakeAuditRepo{events: []models.AuditEvent{
+			{Actor: "other@example.com", Action: "patient.contact.update", TargetType: "patient", TargetID: 7, Details: map[string]interface{}{"ip": "10.0.0.1"}},
+			{Actor: "test@example.com", Action: "assessment.patch", TargetType: "assessment", TargetID: 3},
+		}},
+	}
+}
+
+func TestPatientBundle(t *testing.T) {
+	st := bundleStore()
+	if _, err := st.AssessmentAttachments().Create(context.Background(), models.AssessmentAttachment{AssessmentID: 3, PatientID: 7, StorageKey: "attachments/3/a", Filename: "ana-cruz-ecg.pdf", ContentType: "application/pdf"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.PatientNotes().Create(context.Background(), models.PatientNote{PatientID: 7, Category: models.NoteCarePlan, Body: "Ana to walk daily"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.PatientMedications().Create(context.Background(), models.PatientMedication{PatientID: 7, Name: "Metformin", StartedAt: time.Now(), Adherent: true}); err != nil {
+		t.Fatal(err)
+	}
+	r := baselineRouter(st)
+
+	w := contactRequest(r, http.MethodGet, "/patients/7/bundle", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var b models.PatientBundle
+	_ = json.Unmarshal(w.Body.Bytes(), &b)
+	if b.Redacted || b.Patient.Name != "Ana Cruz" || b.Patient.Contact == nil || b.Photo == nil {
+		t.Fatalf("unredacted bundle should carry identifiers: %+v", b)
+	}
+	if len(b.History) != 1 || len(b.Notes) != 1 || len(b.Medications) != 1 || b.Audit.Total != 1 || b.Audit.Recent[0].Actor != "other@example.com" {
+		t.Fatalf("expected history, notes, medications and the patient's audit event: %+v", b)
+	}
+	if len(b.Attachments) != 1 || b.Attachments[0].Filename != "ana-cruz-ecg.pdf" || b.Attachments[0].DownloadURL != "" {
+		t.Errorf("expected the attachment's metadata without a download link: %+v", b.Attachments)
+	}
+	if b.Audit.Recent[0].Details != nil {
+		t.Error("audit details must not be exported")
+	}
+	if last := st.audit.events[len(st.audit.events)-1]; last.Action != "patient.bundle_export" {
+		t.Errorf("expected a patient.bundle_export audit event, got %s", last.Action)
+	}
+}
+
+func TestPatientBundle_Redacted(t *testing.T) {
+	st := bundleStore()
+	if _, err := st.AssessmentAttachments().Create(context.Background(), models.AssessmentAttachment{AssessmentID: 3, PatientID: 7, StorageKey: "attachments/3/a", Filename: "ana-cruz-ecg.pdf"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.PatientNotes().Create(context.Background(), models.PatientNote{PatientID: 7, Category: models.NoteGeneral, Body: "Ana Cruz's daughter drives her"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.FollowUps().Create(context.Background(), models.FollowUp{PatientID: 7, DueAt: time.Now(), Reason: "Ana Cruz, repeat HbA1c"}); err != nil {
+		t.Fatal(err)
+	}
+	r := baselineRouter(st)
+
+	w := contactRequest(r, http.MethodGet, "/patients/7/bundle?redact=identifiers", "")
+	var b models.PatientBundle
+	_ = json.Unmarshal(w.Body.Bytes(), &b)
+	if !b.Redacted || b.Patient.Name != "" || b.Patient.MRN != "" || b.Patient.Contact != nil || b.Photo != nil || len(b.Notes) != 0 || len(b.Attachments) != 0 {
+		t.Fatalf("identifiers not redacted: %+v", b)
+	}
+	if len(b.FollowUps) != 1 || b.FollowUps[0].Reason != "" {
+		t.Errorf("follow-up reasons must be left out: %+v", b.FollowUps)
+	}
+	v := b.History[0]
+	if _, ok := v.Before["name"]; ok || len(v.Changes) != 1 || v.Changes[0].Field != "bmi" {
+		t.Errorf("history still identifies the patient: %+v", v)
+	}
+	if b.Audit.Recent[0].Actor != "redacted" {
+		t.Errorf("other users' emails must be redacted, got %q", b.Audit.Recent[0].Actor)
+	}
+
+	if w := contactRequest(r, http.MethodGet, "/patients/7/bundle?redact=all", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown redact: expected 400, got %d", w.Code)
+	}
+}
+
+func TestPatientBundle_Zip(t *testing.T) {
+	r := baselineRouter(bundleStore())
+
+	w := contactRequest(r, http.MethodGet, "/patients/7/bundle?format=zip", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("expected a zip, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil || len(zr.File) != 1 || zr.File[0].Name != "bundle.json" {
+		t.Fatalf("expected bundle.json in the archive: %v", err)
+	}
+	f, _ := zr.File[0].Open()
+	body, _ := io.ReadAll(f)
+	var b models.PatientBundle
+	if err := json.Unmarshal(body, &b); err != nil || b.Patient.ID != 7 {
+		t.Fatalf("bundle.json: %v %+v", err, b.Patient)
+	}
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+var (
+	// e164Pattern is an international number: +, country code, up to 15 digits
+	e164Pattern       = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	postalCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,10}$`)
+	countryPattern    = regexp.MustCompile(`^[A-Z]{2}$`)
+	phoneSeparators   = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+)
+
+// contactFieldError reports one invalid contact field
+type contactFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// normalizeContact trims and canonicalises contact details in place (phone
+// separators removed, country and postal code upper-cased) and returns any
+// validation errors.
+func normalizeContact(c *models.PatientContact) []contactFieldError {
+	var errs []contactFieldError
+	fail := func(field, msg string) {
+		errs = append(errs, contactFieldError{Field: field, Message: msg})
+	}
+
+	c.Phone = phoneSeparators.Replace(strings.TrimSpace(c.Phone))
+	c.Email = strings.TrimSpace(c.Email)
+	c.AddressLine1 = strings.TrimSpace(c.AddressLine1)
+	c.AddressLine2 = strings.TrimSpace(c.AddressLine2)
+	c.City = strings.TrimSpace(c.City)
+	c.Region = strings.TrimSpace(c.Region)
+	c.PostalCode = strings.ToUpper(strings.TrimSpace(c.PostalCode))
+	c.Country = strings.ToUpper(strings.TrimSpace(c.Country))
+
+	if c.Phone != "" && !e164Pattern.MatchString(c.Phone) {
+		fail("phone", "must be an international number such as +639171234567")
+	}
+	if c.Email != "" {
+		addr, err := mail.ParseAddress(c.Email)
+		if err != nil || addr.Address != c.Email || len(c.Email) > 254 {
+			fail("email", "must be a plain email address")
+		}
+	}
+
+	for _, f := range []struct{ name, value string }{
+		{"address_line1", c.AddressLine1},
+		{"address_line2", c.AddressLine2},
+		{"city", c.City},
+		{"region", c.Region},
+	} {
+		if len(f.value) > 200 {
+			fail(f.name, "must be at most 200 characters")
+		}
+	}
+	hasAddress := c.AddressLine1 != "" || c.AddressLine2 != "" || c.City != "" ||
+		c.Region != "" || c.PostalCode != "" || c.Country != ""
+	if hasAddress {
+		if c.AddressLine1 == "" {
+			fail("address_line1", "is required when an address is given")
+		}
+		if c.City == "" {
+			fail("city", "is required when an address is given")
+		}
+		if !countryPattern.MatchString(c.Country) {
+			fail("country", "must be a two-letter ISO 3166 country code")
+		}
+	}
+	if c.PostalCode != "" && !postalCodePattern.MatchString(c.PostalCode) {
+		fail("postal_code", "must be 2-11 letters, digits, spaces or dashes")
+	}
+
+	if c.ContactConsent && c.Phone == "" && c.Email == "" {
+		fail("contact_consent", "requires a phone number or email address")
+	}
+	return errs
+}
+
+// validContactPayload checks optional contact details sent with a patient
+// create or update before anything is written, answering 422 if invalid.
+func validContactPayload(c *gin.Context, contact *models.PatientContact) bool {
+	if contact == nil {
+		return true
+	}
+	if errs := normalizeContact(contact); len(errs) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "invalid contact details",
+			"fields": errs,
+		})
+		return false
+	}
+	return true
+}
+
+// contactChangedFields returns the JSON names of the contact fields that
+// differ between prev (nil when none were stored) and next. Values are left
+// out of audit details on purpose.
+func contactChangedFields(prev *models.PatientContact, next models.PatientContact) []string {
+	if prev == nil {
+		prev = &models.PatientContact{}
+	}
+	var changed []string
+	for _, f := range []struct {
+		name       string
+		prev, next string
+	}{
+		{"phone", prev.Phone, next.Phone},
+		{"email", prev.Email, next.Email},
+		{"address_line1", prev.AddressLine1, next.AddressLine1},
+		{"address_line2", prev.AddressLine2, next.AddressLine2},
+		{"city", prev.City, next.City},
+		{"region", prev.Region, next.Region},
+		{"postal_code", prev.PostalCode, next.PostalCode},
+		{"country", prev.Country, next.Country},
+		{"contact_consent", strconv.FormatBool(prev.ContactConsent), strconv.FormatBool(next.ContactConsent)},
+	} {
+		if f.prev != f.next {
+			changed = append(changed, f.name)
+		}
+	}
+	return changed
+}
+
+// saveContact validates and stores a patient's contact details, writing an
+// audit event listing the changed fields. It writes the error response itself
+// and returns false on failure.
+func (h *PatientsHandler) saveContact(c *gin.Context, patientID int64, contact models.PatientContact) (*models.PatientContact, bool) {
+	if !validContactPayload(c, &contact) {
+		return nil, false
+	}
+
+	ctx := c.Request.Context()
+	contacts := h.store.PatientContacts()
+	prev, err := contacts.Get(ctx, patientID)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to load contact for patient %d", patientID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save contact details"})
+		return nil, false
+	}
+	changed := contactChangedFields(prev, contact)
+	if len(changed) == 0 && prev != nil {
+		return prev, true
+	}
+
+	contact.PatientID = patientID
+	saved, err := contacts.Put(ctx, contact)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to save contact for patient %d", patientID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save contact details"})
+		return nil, false
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "patient.contact.update",
+		TargetType: "patient",
+		TargetID:   int(patientID),
+		Details: map[string]interface{}{
+			"fields":          changed,
+			"contact_consent": saved.ContactConsent,
+		},
+	})
+	return saved, true
+}
+
+// getContact returns the patient's contact details, or an empty record with
+// consent withheld if none are stored.
+func (h *PatientsHandler) getContact(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	if _, err := h.store.Patients().GetVisible(c.Request.Context(), int32(id), userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+
+	contact, err := h.store.PatientContacts().Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load contact details"})
+		return
+	}
+	if contact == nil {
+		contact = &models.PatientContact{PatientID: id}
+	}
+	c.JSON(http.StatusOK, contact)
+}
+
+// putContact replaces the patient's contact details and consent
+func (h *PatientsHandler) putContact(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	if _, err := h.store.Patients().Get(c.Request.Context(), int32(id), userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+
+	var req models.PatientContact
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	saved, ok := h.saveContact(c, id, req)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
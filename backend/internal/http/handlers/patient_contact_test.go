@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// fakePatientContactRepo mimics the store's consent_at bookkeeping
+type fakePatientContactRepo struct {
+	contacts map[int64]models.PatientContact
+	puts     int
+}
+
+func (f *fakePatientContactRepo) Get(ctx context.Context, patientID int64) (*models.PatientContact, error) {
+	c, ok := f.contacts[patientID]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (f *fakePatientContactRepo) Put(ctx context.Context, c models.PatientContact) (*models.PatientContact, error) {
+	if f.contacts == nil {
+		f.contacts = map[int64]models.PatientContact{}
+	}
+	prev, had := f.contacts[c.PatientID]
+	switch {
+	case !c.ContactConsent:
+		c.ConsentAt = nil
+	case had && prev.ContactConsent:
+		c.ConsentAt = prev.ConsentAt
+	default:
+		now := time.Now()
+		c.ConsentAt = &now
+	}
+	c.UpdatedAt = time.Now()
+	f.contacts[c.PatientID] = c
+	f.puts++
+	return &c, nil
+}
+
+func TestNormalizeContact(t *testing.T) {
+	cases := []struct {
+		name       string
+		in         models.PatientContact
+		wantFields []string
+	}{
+		{name: "empty is valid", in: models.PatientContact{}},
+		{
+			name: "valid full contact",
+			in: models.PatientContact{
+				Phone: "+63 (917) 123-4567", Email: "maria@example.com",
+				AddressLine1: "12 Rizal St", City: "Quezon City", PostalCode: "1100", Country: "ph",
+				ContactConsent: true,
+			},
+		},
+		{name: "local phone number", in: models.PatientContact{Phone: "09171234567"}, wantFields: []string{"phone"}},
+		{name: "email with display name", in: models.PatientContact{Email: "Maria <maria@example.com>"}, wantFields: []string{"email"}},
+		{name: "malformed email", in: models.PatientContact{Email: "maria@"}, wantFields: []string{"email"}},
+		{name: "partial address", in: models.PatientContact{City: "Manila"}, wantFields: []string{"address_line1", "country"}},
+		{name: "bad postal code", in: models.PatientContact{PostalCode: "#1"}, wantFields: []string{"address_line1", "city", "country", "postal_code"}},
+		{name: "consent without channel", in: models.PatientContact{ContactConsent: true}, wantFields: []string{"contact_consent"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := tc.in
+			errs := normalizeContact(&c)
+			if len(errs) != len(tc.wantFields) {
+				t.Fatalf("got errors %+v, want fields %v", errs, tc.wantFields)
+			}
+			for i, e := range errs {
+				if e.Field != tc.wantFields[i] {
+					t.Fatalf("got errors %+v, want fields %v", errs, tc.wantFields)
+				}
+			}
+		})
+	}
+
+	c := models.PatientContact{Phone: " +63 917-123-4567 ", Country: " ph ", PostalCode: "sw1a 1aa", AddressLine1: "x", City: "y"}
+	normalizeContact(&c)
+	if c.Phone != "+639171234567" || c.Country != "PH" || c.PostalCode != "SW1A 1AA" {
+		t.Fatalf("not normalised: %+v", c)
+	}
+}
+
+func contactRouter(st *fakeStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	NewPatientsHandler(st).Register(r.Group("/patients"))
+	return r
+}
+
+func contactRequest(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPatientContact_PutAndConsent(t *testing.T) {
+	contacts := &fakePatientContactRepo{}
+	audit := &fakeAuditRepo{}
+	r := contactRouter(&fakeStore{patientRepo: &fakePatientRepo{}, contacts: contacts, audit: audit})
+
+	w := contactRequest(r, http.MethodPut, "/patients/5/contact", `{"phone":"0917 123 4567","email":"bad"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid contact: expected 422, got %d", w.Code)
+	}
+	if contacts.puts != 0 {
+		t.Fatal("invalid contact must not be stored")
+	}
+
+	w = contactRequest(r, http.MethodPut, "/patients/5/contact", `{"phone":"+63 917 123 4567","email":"maria@example.com","contact_consent":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var got models.PatientContact
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	if got.Phone != "+639171234567" || got.ConsentAt == nil {
+		t.Fatalf("unexpected contact %+v", got)
+	}
+	last := audit.events[len(audit.events)-1]
+	if last.Action != "patient.contact.update" || last.TargetID != 5 {
+		t.Fatalf("unexpected audit event %+v", last)
+	}
+	if _, leaked := last.Details["phone"]; leaked {
+		t.Fatal("audit details must not carry contact values")
+	}
+
+	// Resending the same details is a no-op
+	contactRequest(r, http.MethodPut, "/patients/5/contact", `{"phone":"+639171234567","email":"maria@example.com","contact_consent":true}`)
+	if contacts.puts != 1 {
+		t.Fatalf("expected unchanged contact to skip the write, got %d puts", contacts.puts)
+	}
+
+	// Withdrawing consent clears the timestamp and the reachable channels
+	contactRequest(r, http.MethodPut, "/patients/5/contact", `{"phone":"+639171234567","email":"maria@example.com"}`)
+	stored := contacts.contacts[5]
+	if stored.ConsentAt != nil || len(stored.Channels()) != 0 {
+		t.Fatalf("consent not withdrawn: %+v", stored)
+	}
+}
+
+func TestPatientContact_CreateRejectsInvalidContact(t *testing.T) {
+	contacts := &fakePatientContactRepo{}
+	patients := &fakePatientRepo{}
+	r := contactRouter(&fakeStore{patientRepo: patients, contacts: contacts})
+
+	w := contactRequest(r, http.MethodPost, "/patients", `{"name":"Ana","contact":{"email":"nope"}}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", w.Code)
+	}
+
+	// PUT without contact leaves stored details alone
+	contacts.contacts = map[int64]models.PatientContact{5: {PatientID: 5, Email: "ana@example.com"}}
+	if w := contactRequest(r, http.MethodPut, "/patients/5", `{"name":"Ana"}`); w.Code != http.StatusOK {
+		t.Fatalf("update: expected 200, got %d", w.Code)
+	}
+	if contacts.puts != 0 || contacts.contacts[5].Email != "ana@example.com" {
+		t.Fatalf("contact changed by update without contact: %+v", contacts.contacts[5])
+	}
+}
+
+func TestRiskAlert_RecordsConsentedPatientChannels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	alerts := &fakeRiskAlertRepo{}
+	contacts := &fakePatientContactRepo{contacts: map[int64]models.PatientContact{
+		9: {PatientID: 9, Email: "maria@example.com", Phone: "+639171234567", ContactConsent: true},
+	}}
+	st := &fakeStore{repo: &fakeAssessmentRepo{}, patientRepo: &fakePatientRepo{}, alerts: alerts, contacts: contacts}
+	h := alertingAssessmentsHandler(st)
+
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	h.Register(r.Group("/patients"))
+	if w := contactRequest(r, http.MethodPost, "/patients/9/assessments", `{"fbs":140,"hba1c":7.2,"bmi":24}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", w.Code)
+	}
+	if len(alerts.queued) != 1 {
+		t.Fatalf("expected one alert, got %d", len(alerts.queued))
+	}
+	ch := alerts.queued[0].PatientChannels
+	if len(ch) != 2 || ch[0] != models.ContactChannelEmail || ch[1] != models.ContactChannelSMS {
+		t.Fatalf("patient channels = %v", ch)
+	}
+}
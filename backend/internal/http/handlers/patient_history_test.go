@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+type fakePatientHistoryRepo struct {
+	versions []models.PatientVersion
+}
+
+func (f *fakePatientHistoryRepo) List(ctx context.Context, patientID int64) ([]models.PatientVersion, error) {
+	out := []models.PatientVersion{}
+	for _, v := range f.versions {
+		if v.PatientID == patientID {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+func TestPatientHistory(t *testing.T) {
+	history := &fakePatientHistoryRepo{versions: []models.PatientVersion{
+		{ID: 2, PatientID: 7, Version: 2, Changes: []models.PatientFieldChange{{Field: "smoking", Old: "never", New: "current"}}},
+		{ID: 1, PatientID: 7, Version: 1, Changes: []models.PatientFieldChange{{Field: "bmi", Old: 24.5, New: 26.1}}},
+		{ID: 3, PatientID: 9, Version: 1},
+	}}
+	r := baselineRouter(&fakeStore{patientRepo: &fakePatientRepo{}, history: history})
+
+	if w := contactRequest(r, http.MethodGet, "/patients/abc/history", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid id: expected 400, got %d", w.Code)
+	}
+	w := contactRequest(r, http.MethodGet, "/patients/7/history", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var got struct {
+		Data []models.PatientVersion `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	if len(got.Data) != 2 || got.Data[0].Version != 2 || got.Data[0].Changes[0].Field != "smoking" {
+		t.Fatalf("unexpected history %+v", got.Data)
+	}
+}
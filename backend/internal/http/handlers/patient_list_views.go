@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// patientViewFilters are the patient list query parameters a view may save.
+// Paging is left to the client.
+var patientViewFilters = []string{
+	"search", "min_age", "max_age", "menopause_status", "cluster", "min_risk", "max_risk",
+	"sort", "order", "clinic_id", "tag_id", "page_size",
+}
+
+// validViewFilters returns the problem with filters as patient list query
+// parameters, or "" if GET /patients would accept them.
+func validViewFilters(filters map[string]string) string {
+	query := url.Values{}
+	for k, v := range filters {
+		if !slices.Contains(patientViewFilters, k) {
+			return "unknown filter " + k
+		}
+		query.Set(k, v)
+	}
+	req := &http.Request{URL: &url.URL{RawQuery: query.Encode()}}
+	var params models.PatientListParams
+	if err := binding.Query.Bind(req, &params); err != nil {
+		return "filters are not valid patient list parameters"
+	}
+	if (params.MinAge != nil && params.MaxAge != nil && *params.MinAge > *params.MaxAge) ||
+		(params.MinRisk != nil && params.MaxRisk != nil && *params.MinRisk > *params.MaxRisk) {
+		return "min must not exceed max"
+	}
+	return ""
+}
+
+// patientViewRequest is the body of a view create or update. Absent fields
+// keep their current value on update; name is required on create.
+type patientViewRequest struct {
+	Name      *string           `json:"name"`
+	Filters   map[string]string `json:"filters"`
+	IsDefault *bool             `json:"is_default"`
+}
+
+// apply copies the request onto v and returns the first problem with the
+// result, or "" if it is valid.
+func (r patientViewRequest) apply(v *models.PatientListView) string {
+	if r.Name != nil {
+		v.Name = strings.TrimSpace(*r.Name)
+	}
+	if r.Filters != nil {
+		v.Filters = r.Filters
+	}
+	if r.IsDefault != nil {
+		v.IsDefault = *r.IsDefault
+	}
+	if v.Filters == nil {
+		v.Filters = map[string]string{}
+	}
+	switch {
+	case v.Name == "":
+		return "name is required"
+	case utf8.RuneCountInString(v.Name) > 60:
+		return "name must be at most 60 characters"
+	}
+	return validViewFilters(v.Filters)
+}
+
+// PatientListViewsHandler manages the caller's saved patient list views
+type PatientListViewsHandler struct {
+	store store.Store
+}
+
+func NewPatientListViewsHandler(store store.Store) *PatientListViewsHandler {
+	return &PatientListViewsHandler{store: store}
+}
+
+func (h *PatientListViewsHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("", h.list)
+	rg.POST("", h.create)
+	rg.PATCH("/:viewID", h.update)
+	rg.DELETE("/:viewID", h.delete)
+}
+
+// audit records a view change. Filters can hold a name search and are left
+// out.
+func (h *PatientListViewsHandler) audit(c *gin.Context, action string, v models.PatientListView) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	filters := make([]string, 0, len(v.Filters))
+	for k := range v.Filters {
+		filters = append(filters, k)
+	}
+	sort.Strings(filters)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     action,
+		TargetType: "patient_list_view",
+		TargetID:   int(v.ID),
+		Details: map[string]interface{}{
+			"filters":    filters,
+			"is_default": v.IsDefault,
+		},
+	})
+}
+
+// respondViewSaveError answers a failed view create or update
+func respondViewSaveError(c *gin.Context, err error, v models.PatientListView) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		abortWithError(c, err, "view not found")
+	case errors.Is(err, store.ErrConflict):
+		abortWithError(c, err, "you already have a view with that name")
+	default:
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to save patient list view of user %d", v.UserID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save view"})
+	}
+}
+
+// list returns the caller's views by name
+func (h *PatientListViewsHandler) list(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	views, err := h.store.PatientListViews().List(c.Request.Context(), int64(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list views"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": views})
+}
+
+// create saves a view. Saving one as the default unsets the previous
+// default.
+func (h *PatientListViewsHandler) create(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req patientViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	v := models.PatientListView{UserID: int64(userID)}
+	if msg := req.apply(&v); msg != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+		return
+	}
+	created, err := h.store.PatientListViews().Create(c.Request.Context(), v)
+	if err != nil {
+		respondViewSaveError(c, err, v)
+		return
+	}
+	h.audit(c, "patient_list_view.create", *created)
+	c.JSON(http.StatusCreated, created)
+}
+
+// update renames a view, replaces its filters or makes it the default
+func (h *PatientListViewsHandler) update(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	viewID, err := parseIDParam(c, "viewID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid view ID"})
+		return
+	}
+	v, err := h.store.PatientListViews().Get(c.Request.Context(), viewID, int64(userID))
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "view not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load view"})
+		return
+	}
+	var req patientViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if msg := req.apply(v); msg != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+		return
+	}
+	updated, err := h.store.PatientListViews().Update(c.Request.Context(), *v)
+	if err != nil {
+		respondViewSaveError(c, err, *v)
+		return
+	}
+	h.audit(c, "patient_list_view.update", *updated)
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *PatientListViewsHandler) delete(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	viewID, err := parseIDParam(c, "viewID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid view ID"})
+		return
+	}
+	v, err := h.store.PatientListViews().Get(c.Request.Context(), viewID, int64(userID))
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "view not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load view"})
+		return
+	}
+	if err := h.store.PatientListViews().Delete(c.Request.Context(), viewID, int64(userID)); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete view"})
+		return
+	}
+	h.audit(c, "patient_list_view.delete", *v)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// viewsRouter serves the patient list view and preference routes as the
+// given user
+func viewsRouter(st store.Store, claims middleware.UserClaims) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		c.Set("user", claims)
+		c.Next()
+	})
+	NewPatientListViewsHandler(st).Register(r.Group("/users/patient-views"))
+	NewUserPreferencesHandler(st).Register(r.Group("/users/preferences"))
+	return r
+}
+
+func preferences(t *testing.T, r *gin.Engine) models.UserPreferences {
+	t.Helper()
+	w := contactRequest(r, http.MethodGet, "/users/preferences", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("preferences: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var prefs models.UserPreferences
+	_ = json.Unmarshal(w.Body.Bytes(), &prefs)
+	return prefs
+}
+
+func TestPatientListViews_SaveAndDefault(t *testing.T) {
+	mem := store.NewMemoryStore()
+	r := viewsRouter(mem, ownerClaims)
+
+	if prefs := preferences(t, r); prefs.DefaultPatientView != nil {
+		t.Fatalf("expected no default view yet, got %+v", prefs.DefaultPatientView)
+	}
+
+	w := contactRequest(r, http.MethodPost, "/users/patient-views",
+		`{"name":" High risk ","filters":{"min_risk":"67","sort":"risk_score","order":"desc"},"is_default":true}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var highRisk models.PatientListView
+	_ = json.Unmarshal(w.Body.Bytes(), &highRisk)
+	if highRisk.Name != "High risk" || highRisk.Filters["min_risk"] != "67" || !highRisk.IsDefault {
+		t.Fatalf("unexpected view %+v", highRisk)
+	}
+	if prefs := preferences(t, r); prefs.DefaultPatientView == nil || prefs.DefaultPatientView.ID != highRisk.ID {
+		t.Fatalf("expected the high risk view as default, got %+v", prefs.DefaultPatientView)
+	}
+
+	// A new default replaces the old one
+	w = contactRequest(r, http.MethodPost, "/users/patient-views", `{"name":"SIRD","filters":{"cluster":"SIRD"},"is_default":true}`)
+	var sird models.PatientListView
+	_ = json.Unmarshal(w.Body.Bytes(), &sird)
+	if prefs := preferences(t, r); prefs.DefaultPatientView == nil || prefs.DefaultPatientView.ID != sird.ID {
+		t.Fatalf("expected the SIRD view as default, got %+v", prefs.DefaultPatientView)
+	}
+	w = contactRequest(r, http.MethodGet, "/users/patient-views", "")
+	var list struct{ Data []models.PatientListView }
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Data) != 2 || list.Data[0].IsDefault || !list.Data[1].IsDefault {
+		t.Fatalf("expected both views by name with only SIRD the default, got %s", w.Body.String())
+	}
+
+	path := "/users/patient-views/" + strconv.FormatInt(highRisk.ID, 10)
+	if w := contactRequest(r, http.MethodPatch, path, `{"name":"sird"}`); w.Code != http.StatusConflict {
+		t.Errorf("rename onto another view: expected 409, got %d", w.Code)
+	}
+	other := viewsRouter(mem, middleware.UserClaims{UserID: 2, Email: "nurse@example.com", Role: "clinician"})
+	if w := contactRequest(other, http.MethodDelete, path, ""); w.Code != http.StatusNotFound {
+		t.Errorf("another user's view: expected 404, got %d", w.Code)
+	}
+	if prefs := preferences(t, other); prefs.DefaultPatientView != nil {
+		t.Errorf("expected another user to have no default, got %+v", prefs.DefaultPatientView)
+	}
+
+	if w := contactRequest(r, http.MethodDelete, "/users/patient-views/"+strconv.FormatInt(sird.ID, 10), ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", w.Code)
+	}
+	if prefs := preferences(t, r); prefs.DefaultPatientView != nil {
+		t.Errorf("expected no default once it is deleted, got %+v", prefs.DefaultPatientView)
+	}
+}
+
+func TestPatientListViews_Validation(t *testing.T) {
+	r := viewsRouter(store.NewMemoryStore(), ownerClaims)
+	for _, body := range []string{
+		`{"filters":{"cluster":"SIRD"}}`,
+		`{"name":"Mine","filters":{"page":"2"}}`,
+		`{"name":"Mine","filters":{"min_risk":"high"}}`,
+		`{"name":"Mine","filters":{"sort":"weight"}}`,
+		`{"name":"Mine","filters":{"min_age":"60","max_age":"40"}}`,
+	} {
+		if w := contactRequest(r, http.MethodPost, "/users/patient-views", body); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected 422, got %d", body, w.Code)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// patientMedicationRequest is the body of a medication create or replace.
+// Adherent defaults to true.
+type patientMedicationRequest struct {
+	Name      string     `json:"name"`
+	Dose      string     `json:"dose"`
+	StartedAt *time.Time `json:"started_at"`
+	StoppedAt *time.Time `json:"stopped_at"`
+	Adherent  *bool      `json:"adherent"`
+}
+
+// medication returns the medication the request describes for patientID,
+// or the first problem with it
+func (r patientMedicationRequest) medication(patientID int64) (models.PatientMedication, string) {
+	m := models.PatientMedication{
+		PatientID: patientID,
+		Name:      strings.TrimSpace(r.Name),
+		Dose:      strings.TrimSpace(r.Dose),
+		StoppedAt: r.StoppedAt,
+		Adherent:  r.Adherent == nil || *r.Adherent,
+	}
+	switch {
+	case m.Name == "":
+		return m, "name is required"
+	case utf8.RuneCountInString(m.Name) > 200:
+		return m, "name must be at most 200 characters"
+	case utf8.RuneCountInString(m.Dose) > 100:
+		return m, "dose must be at most 100 characters"
+	case r.StartedAt == nil:
+		return m, "started_at is required"
+	case r.StoppedAt != nil && r.StoppedAt.Before(*r.StartedAt):
+		return m, "stopped_at must not be before started_at"
+	}
+	m.StartedAt = *r.StartedAt
+	return m, ""
+}
+
+// onMedication reports whether the patient was taking a medication as
+// prescribed at t, for the predictor's on_medication feature. A lookup
+// failure is logged and counts as not on medication.
+func onMedication(ctx context.Context, st store.Store, patientID int64, at time.Time) bool {
+	medications, err := st.PatientMedications().List(ctx, patientID)
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to load medications of patient %d", patientID)
+		return false
+	}
+	for _, m := range medications {
+		if m.TakenAt(at) {
+			return true
+		}
+	}
+	return false
+}
+
+// patientParam resolves the :id parameter to a patient the caller can
+// see or, with owner set, owns, writing the error response itself.
+func (h *PatientsHandler) patientParam(c *gin.Context, owner bool) (int64, bool) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, false
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return 0, false
+	}
+	if owner {
+		_, err = h.store.Patients().Get(c.Request.Context(), int32(id), userID)
+	} else {
+		_, err = h.store.Patients().GetVisible(c.Request.Context(), int32(id), userID)
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return 0, false
+	}
+	return id, true
+}
+
+// storedMedication loads the :medicationID medication of patientID
+func (h *PatientsHandler) storedMedication(c *gin.Context, patientID int64) (*models.PatientMedication, bool) {
+	medicationID, err := parseIDParam(c, "medicationID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid medication ID"})
+		return nil, false
+	}
+	m, err := h.store.PatientMedications().Get(c.Request.Context(), medicationID, patientID)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "medication not found")
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load medication"})
+		return nil, false
+	}
+	return m, true
+}
+
+// medicationAudit is how a medication appears in audit details. auditDiff
+// is not used, as it would redact the medication's name as an identifier.
+func medicationAudit(m models.PatientMedication) map[string]interface{} {
+	return map[string]interface{}{
+		"name":       m.Name,
+		"dose":       m.Dose,
+		"started_at": m.StartedAt,
+		"stopped_at": m.StoppedAt,
+		"adherent":   m.Adherent,
+	}
+}
+
+// auditMedication records a medication change
+func (h *PatientsHandler) auditMedication(c *gin.Context, action string, patientID int64, details map[string]interface{}) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     action,
+		TargetType: "patient",
+		TargetID:   int(patientID),
+		Details:    details,
+	})
+}
+
+// listMedications returns the patient's medications, most recently started
+// first
+func (h *PatientsHandler) listMedications(c *gin.Context) {
+	patientID, ok := h.patientParam(c, false)
+	if !ok {
+		return
+	}
+	medications, err := h.store.PatientMedications().List(c.Request.Context(), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list medications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": medications})
+}
+
+// createMedication records a medication the patient takes
+func (h *PatientsHandler) createMedication(c *gin.Context) {
+	patientID, ok := h.patientParam(c, true)
+	if !ok {
+		return
+	}
+	var req patientMedicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	m, msg := req.medication(patientID)
+	if msg != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+		return
+	}
+
+	created, err := h.store.PatientMedications().Create(c.Request.Context(), m)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to create medication for patient %d", patientID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create medication"})
+		return
+	}
+	h.auditMedication(c, "patient.medication.create", patientID, map[string]interface{}{
+		"medication_id": created.ID,
+		"medication":    medicationAudit(*created),
+	})
+	c.JSON(http.StatusCreated, created)
+}
+
+// updateMedication replaces a medication, e.g. to record that it stopped
+func (h *PatientsHandler) updateMedication(c *gin.Context) {
+	patientID, ok := h.patientParam(c, true)
+	if !ok {
+		return
+	}
+	existing, ok := h.storedMedication(c, patientID)
+	if !ok {
+		return
+	}
+	var req patientMedicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	m, msg := req.medication(patientID)
+	if msg != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+		return
+	}
+	m.ID = existing.ID
+
+	updated, err := h.store.PatientMedications().Update(c.Request.Context(), m)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "medication not found")
+		return
+	}
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to update medication %d", m.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update medication"})
+		return
+	}
+	h.auditMedication(c, "patient.medication.update", patientID, map[string]interface{}{
+		"medication_id": updated.ID,
+		"before":        medicationAudit(*existing),
+		"after":         medicationAudit(*updated),
+	})
+	c.JSON(http.StatusOK, updated)
+}
+
+// deleteMedication removes a medication recorded in error. A medication the
+// patient stopped should be given a stopped_at instead, so earlier
+// assessments keep their context.
+func (h *PatientsHandler) deleteMedication(c *gin.Context) {
+	patientID, ok := h.patientParam(c, true)
+	if !ok {
+		return
+	}
+	existing, ok := h.storedMedication(c, patientID)
+	if !ok {
+		return
+	}
+	err := h.store.PatientMedications().Delete(c.Request.Context(), existing.ID, patientID)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "medication not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete medication"})
+		return
+	}
+	h.auditMedication(c, "patient.medication.delete", patientID, map[string]interface{}{
+		"medication_id": existing.ID,
+		"medication":    medicationAudit(*existing),
+	})
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// onMedicationStub records the on_medication feature of each prediction
+type onMedicationStub struct {
+	ml.MockPredictor
+	onMedication []bool
+}
+
+func (p *onMedicationStub) Predict(ctx context.Context, input models.Assessment) (string, int) {
+	p.onMedication = append(p.onMedication, input.OnMedication)
+	return p.MockPredictor.Predict(ctx, input)
+}
+
+func (p *onMedicationStub) PredictWithExplanation(ctx context.Context, input models.Assessment) (string, int, map[string]interface{}) {
+	p.onMedication = append(p.onMedication, input.OnMedication)
+	return p.MockPredictor.PredictWithExplanation(ctx, input)
+}
+
+// medicationsRouter serves the patient and assessment routes of a memory
+// store as the given user
+func medicationsRouter(st store.Store, predictor ml.Predictor, claims middleware.UserClaims) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		c.Set("user", claims)
+		c.Next()
+	})
+	NewPatientsHandler(st).Register(r.Group("/patients"))
+	NewAssessmentsHandler(st, predictor, "v1", "").Register(r.Group("/patients"))
+	return r
+}
+
+var ownerClaims = middleware.UserClaims{UserID: 1, Email: "doc@example.com", Role: "clinician"}
+
+// ownedPatientPath stores a patient of ownerClaims and returns its path
+func ownedPatientPath(t *testing.T, mem *store.MemoryStore) string {
+	t.Helper()
+	p, err := mem.Patients().Create(context.Background(), models.Patient{UserID: 1, Name: "Ana Cruz", Age: 54})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "/patients/" + strconv.FormatInt(p.ID, 10)
+}
+
+func TestPatientMedications_CRUD(t *testing.T) {
+	mem := store.NewMemoryStore()
+	r := medicationsRouter(mem, ml.NewMockPredictor(), ownerClaims)
+	path := ownedPatientPath(t, mem)
+
+	w := contactRequest(r, http.MethodPost, path+"/medications", `{"name":" Metformin ","dose":"500mg twice daily","started_at":"2026-01-10T00:00:00Z"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var first models.PatientMedication
+	_ = json.Unmarshal(w.Body.Bytes(), &first)
+	if first.Name != "Metformin" || !first.Adherent || first.StoppedAt != nil {
+		t.Fatalf("unexpected medication %+v", first)
+	}
+	contactRequest(r, http.MethodPost, path+"/medications", `{"name":"Atorvastatin","started_at":"2026-03-01T00:00:00Z","adherent":false}`)
+
+	w = contactRequest(r, http.MethodPut, path+"/medications/"+strconv.FormatInt(first.ID, 10),
+		`{"name":"Metformin","dose":"500mg twice daily","started_at":"2026-01-10T00:00:00Z","stopped_at":"2026-04-01T00:00:00Z"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = contactRequest(r, http.MethodGet, path+"/medications", "")
+	var list struct{ Data []models.PatientMedication }
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Data) != 2 || list.Data[0].Name != "Atorvastatin" || list.Data[1].StoppedAt == nil {
+		t.Fatalf("expected the latest medication first and the stop recorded, got %+v", list.Data)
+	}
+
+	if w := contactRequest(r, http.MethodDelete, path+"/medications/"+strconv.FormatInt(list.Data[0].ID, 10), ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", w.Code)
+	}
+	if w := contactRequest(r, http.MethodDelete, path+"/medications/"+strconv.FormatInt(list.Data[0].ID, 10), ""); w.Code != http.StatusNotFound {
+		t.Errorf("deleted medication: expected 404, got %d", w.Code)
+	}
+
+	events, _, _ := mem.AuditEvents().List(context.Background(), models.AuditListParams{Page: 1, PageSize: 20})
+	var actions []string
+	for _, e := range events {
+		actions = append(actions, e.Action)
+	}
+	for _, want := range []string{"patient.medication.create", "patient.medication.update", "patient.medication.delete"} {
+		if !strings.Contains(strings.Join(actions, ","), want) {
+			t.Errorf("expected audit action %s, got %v", want, actions)
+		}
+	}
+}
+
+func TestPatientMedications_Validation(t *testing.T) {
+	mem := store.NewMemoryStore()
+	r := medicationsRouter(mem, ml.NewMockPredictor(), ownerClaims)
+	path := ownedPatientPath(t, mem)
+
+	for _, body := range []string{
+		`{"name":"  ","started_at":"2026-01-10T00:00:00Z"}`,
+		`{"name":"Metformin"}`,
+		`{"name":"` + strings.Repeat("a", 201) + `","started_at":"2026-01-10T00:00:00Z"}`,
+		`{"name":"Metformin","started_at":"2026-01-10T00:00:00Z","stopped_at":"2026-01-09T00:00:00Z"}`,
+	} {
+		if w := contactRequest(r, http.MethodPost, path+"/medications", body); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%.40s: expected 422, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestPatientMedications_OnlyOwnerWrites(t *testing.T) {
+	mem := store.NewMemoryStore()
+	path := ownedPatientPath(t, mem)
+	other := medicationsRouter(mem, ml.NewMockPredictor(), middleware.UserClaims{UserID: 2, Email: "nurse@example.com", Role: "clinician"})
+
+	if w := contactRequest(other, http.MethodPost, path+"/medications", `{"name":"Metformin","started_at":"2026-01-10T00:00:00Z"}`); w.Code != http.StatusNotFound {
+		t.Errorf("other user's patient: expected 404, got %d", w.Code)
+	}
+}
+
+func TestPatientMedications_FeedOnMedication(t *testing.T) {
+	mem := store.NewMemoryStore()
+	predictor := &onMedicationStub{}
+	r := medicationsRouter(mem, predictor, ownerClaims)
+	path := ownedPatientPath(t, mem)
+	started := time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+
+	assess := func() bool {
+		t.Helper()
+		if w := contactRequest(r, http.MethodPost, path+"/assessments", `{"fbs":140,"hba1c":7.2,"bmi":24}`); w.Code != http.StatusCreated {
+			t.Fatalf("assess: expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+		return predictor.onMedication[len(predictor.onMedication)-1]
+	}
+
+	if assess() {
+		t.Error("expected on_medication false without medications")
+	}
+	contactRequest(r, http.MethodPost, path+"/medications", `{"name":"Atorvastatin","started_at":"`+started+`","adherent":false}`)
+	if assess() {
+		t.Error("expected a medication not taken as prescribed to be ignored")
+	}
+	contactRequest(r, http.MethodPost, path+"/medications", `{"name":"Metformin","started_at":"`+started+`"}`)
+	if !assess() {
+		t.Error("expected on_medication true with an ongoing adherent medication")
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// duplicateMinSimilarity is how alike two names must be, by trigram
+// similarity, before the pair is listed as a likely duplicate.
+const duplicateMinSimilarity = 0.6
+
+// duplicates lists pairs of the caller's patients that probably record the
+// same person, for review before merging
+func (h *PatientsHandler) duplicates(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	minSimilarity := duplicateMinSimilarity
+	if raw := c.Query("min_similarity"); raw != "" {
+		minSimilarity, err = strconv.ParseFloat(raw, 64)
+		if err != nil || minSimilarity <= 0 || minSimilarity > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_similarity must be above 0 and at most 1"})
+			return
+		}
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+
+	pairs, err := h.store.Patients().Duplicates(c.Request.Context(), userID, minSimilarity, limit)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msg("Failed to find duplicate patients")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to find duplicate patients"})
+		return
+	}
+	c.JSON(http.StatusOK, pairs)
+}
+
+// merge folds the patient named by otherID into the one named by id: its
+// assessments, notes, attachments and other records move across and it is
+// deleted. The survivor keeps its own demographics.
+func (h *PatientsHandler) merge(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	otherID, err := strconv.ParseInt(c.Param("otherID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	if id == otherID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot merge a patient into itself"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	for _, pid := range []int64{id, otherID} {
+		if _, err := h.store.Patients().GetVisible(ctx, int32(pid), userID); err != nil {
+			abortWithError(c, err, "patient not found")
+			return
+		}
+	}
+	// The merged patient's photo is not carried over; look it up first so
+	// its image can be removed once the row cascades away.
+	photo, err := h.store.PatientPhotos().Get(ctx, otherID)
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to look up photo for patient %d", otherID)
+	}
+
+	merged, err := h.store.Patients().Merge(ctx, id, otherID, userID)
+	switch {
+	case errors.Is(err, store.ErrForbidden):
+		abortWithError(c, err, "only the patients' owner can merge them")
+		return
+	case errors.Is(err, store.ErrNotFound):
+		// One of the patients was deleted or transferred since the lookup
+		abortWithError(c, err, "patient not found")
+		return
+	case err != nil:
+		abortWithError(c, err, "failed to merge patients")
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "patient.merge",
+		TargetType: "patient",
+		TargetID:   int(id),
+		Details: map[string]interface{}{
+			"merged_id":   otherID,
+			"assessments": merged.Assessments,
+			"notes":       merged.Notes,
+			"attachments": merged.Attachments,
+		},
+	})
+	// Attachments moved to the survivor, so only the photo is left to clean up
+	h.events.Publish(ctx, events.PatientDeleted{
+		Actor:     claims.Email,
+		UserID:    userID,
+		PatientID: otherID,
+		Photo:     photo,
+	})
+	c.JSON(http.StatusOK, merged)
+}
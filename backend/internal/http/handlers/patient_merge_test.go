@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+func TestPatientsHandler_DuplicatesAndMerge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	mem := store.NewMemoryStore()
+	user, _ := mem.Users().Create(ctx, models.User{Email: "test@example.com", Role: "clinician", IsActive: true})
+	other, _ := mem.Users().Create(ctx, models.User{Email: "other@example.com", Role: "clinician", IsActive: true})
+	maria, _ := mem.Patients().Create(ctx, models.Patient{UserID: user.ID, Name: "Maria Santos", Age: 52})
+	typo, _ := mem.Patients().Create(ctx, models.Patient{UserID: user.ID, Name: "Maria Santo", Age: 53})
+	_, _ = mem.Patients().Create(ctx, models.Patient{UserID: user.ID, Name: "Maria Santos", Age: 30})
+	byMRN, _ := mem.Patients().Create(ctx, models.Patient{UserID: user.ID, Name: "Juan Cruz", MRN: "MRN-7"})
+	sameMRN, _ := mem.Patients().Create(ctx, models.Patient{UserID: user.ID, Name: "J. Dela Cruz", MRN: "mrn-7"})
+	foreign, _ := mem.Patients().Create(ctx, models.Patient{UserID: other.ID, Name: "Maria Santos", Age: 52})
+
+	_, _ = mem.Assessments().Create(ctx, models.Assessment{PatientID: maria.ID, FBS: 100})
+	_, _ = mem.Assessments().Create(ctx, models.Assessment{PatientID: typo.ID, FBS: 120})
+	_, _ = mem.PatientNotes().Create(ctx, models.PatientNote{PatientID: typo.ID, Category: "general", Body: "Registered twice"})
+
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(mockAuthMiddleware())
+	NewPatientsHandler(mem).Register(r.Group("/patients"))
+	do := func(method, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(""))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "/patients/duplicates")
+	if w.Code != http.StatusOK {
+		t.Fatalf("duplicates: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var pairs []models.PatientDuplicate
+	_ = json.Unmarshal(w.Body.Bytes(), &pairs)
+	// The shared MRN comes first; the 30-year-old namesake is too far apart
+	// in age and the other clinician's patient is not theirs to merge
+	if len(pairs) != 2 {
+		t.Fatalf("expected 2 pairs, got %+v", pairs)
+	}
+	if !pairs[0].SameMRN || pairs[0].Patient.ID != byMRN.ID || pairs[0].Duplicate.ID != sameMRN.ID {
+		t.Errorf("expected the shared MRN first, got %+v", pairs[0])
+	}
+	if pairs[1].SameMRN || pairs[1].Patient.ID != maria.ID || pairs[1].Duplicate.ID != typo.ID || pairs[1].Similarity < 0.6 {
+		t.Errorf("expected the similar names second, got %+v", pairs[1])
+	}
+	if w := do(http.MethodGet, "/patients/duplicates?min_similarity=2"); w.Code != http.StatusBadRequest {
+		t.Errorf("out of range min_similarity: expected 400, got %d", w.Code)
+	}
+
+	for path, want := range map[string]int{
+		fmt.Sprintf("/patients/%d/merge/%d", maria.ID, maria.ID):   http.StatusBadRequest,
+		fmt.Sprintf("/patients/%d/merge/%d", maria.ID, foreign.ID): http.StatusNotFound,
+	} {
+		if w := do(http.MethodPost, path); w.Code != want {
+			t.Errorf("POST %s: expected %d, got %d", path, want, w.Code)
+		}
+	}
+
+	// A colleague's patient shared with a common clinic is visible but not
+	// the caller's to merge
+	clinic, _ := mem.Clinics().Create(ctx, "North", "")
+	_ = mem.Clinics().AddMember(ctx, int32(clinic.ID), int32(user.ID), models.ClinicRoleMember)
+	_ = mem.Clinics().AddMember(ctx, int32(clinic.ID), int32(other.ID), models.ClinicRoleMember)
+	clinicID := int32(clinic.ID)
+	_ = mem.Patients().SetClinic(ctx, foreign.ID, int32(other.ID), &clinicID, int32(other.ID))
+	if w := do(http.MethodPost, fmt.Sprintf("/patients/%d/merge/%d", maria.ID, foreign.ID)); w.Code != http.StatusForbidden {
+		t.Errorf("merging a shared patient: expected 403, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = do(http.MethodPost, fmt.Sprintf("/patients/%d/merge/%d", maria.ID, typo.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("merge: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var merged models.PatientMerge
+	_ = json.Unmarshal(w.Body.Bytes(), &merged)
+	if merged.Assessments != 1 || merged.Notes != 1 || merged.MergedID != typo.ID {
+		t.Errorf("unexpected merge counts: %+v", merged)
+	}
+	if _, err := mem.Patients().Get(ctx, int32(typo.ID), int32(user.ID)); err == nil {
+		t.Error("merged patient still exists")
+	}
+	if assessments, _ := mem.Assessments().ListByPatient(ctx, maria.ID); len(assessments) != 2 {
+		t.Errorf("expected both assessments on the survivor, got %d", len(assessments))
+	}
+	if notes, _ := mem.PatientNotes().List(ctx, maria.ID); len(notes) != 1 {
+		t.Errorf("expected the note on the survivor, got %d", len(notes))
+	}
+	events, _, _ := mem.AuditEvents().List(ctx, models.AuditListParams{})
+	if len(events) == 0 || events[0].Action != "patient.merge" || events[0].TargetID != int(maria.ID) {
+		t.Errorf("expected a patient.merge audit event, got %+v", events)
+	}
+}
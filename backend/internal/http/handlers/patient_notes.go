@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// maxNoteLength is the longest note body accepted, in characters
+const maxNoteLength = 5000
+
+// patientNoteRequest is the body of a note create or update. Absent fields
+// keep their current value on update.
+type patientNoteRequest struct {
+	Category *string `json:"category"`
+	Body     *string `json:"body"`
+	Pinned   *bool   `json:"pinned"`
+}
+
+// apply copies the request onto note and returns the first problem with
+// the result, or "" if it is valid
+func (r patientNoteRequest) apply(note *models.PatientNote) string {
+	if r.Category != nil {
+		note.Category = *r.Category
+	}
+	if r.Body != nil {
+		note.Body = strings.TrimSpace(*r.Body)
+	}
+	if r.Pinned != nil {
+		note.Pinned = *r.Pinned
+	}
+	switch {
+	case !slices.Contains(models.NoteCategories, note.Category):
+		return "category must be one of " + strings.Join(models.NoteCategories, ", ")
+	case note.Body == "":
+		return "body is required"
+	case utf8.RuneCountInString(note.Body) > maxNoteLength:
+		return "body must be at most 5000 characters"
+	}
+	return ""
+}
+
+// visibleNotePatient resolves the :id parameter to a patient the caller can
+// see, writing the error response itself.
+func (h *PatientsHandler) visibleNotePatient(c *gin.Context) (int64, bool) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, false
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return 0, false
+	}
+	if _, err := h.store.Patients().GetVisible(c.Request.Context(), int32(id), userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return 0, false
+	}
+	return id, true
+}
+
+// authoredNote loads the :noteID note of patientID for a change. Only its
+// author or a holder of admin.users may change a note.
+func (h *PatientsHandler) authoredNote(c *gin.Context, patientID int64) (*models.PatientNote, bool) {
+	noteID, err := parseIDParam(c, "noteID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid note ID"})
+		return nil, false
+	}
+	note, err := h.store.PatientNotes().Get(c.Request.Context(), noteID, patientID)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "note not found")
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load note"})
+		return nil, false
+	}
+	claims := c.MustGet("user").(middleware.UserClaims)
+	if note.AuthorID == nil || *note.AuthorID != int64(claims.UserID) {
+		admin, err := middleware.HasPermission(c.Request.Context(), h.perms, claims, 0, models.PermAdminUsers)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check permissions"})
+			return nil, false
+		}
+		if !admin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only the note's author can change it"})
+			return nil, false
+		}
+	}
+	return note, true
+}
+
+// auditNote records a note change. The body is left out of audit details,
+// as it may hold anything the clinician wrote.
+func (h *PatientsHandler) auditNote(c *gin.Context, action string, note models.PatientNote) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     action,
+		TargetType: "patient",
+		TargetID:   int(note.PatientID),
+		Details: map[string]interface{}{
+			"note_id":  note.ID,
+			"category": note.Category,
+			"pinned":   note.Pinned,
+		},
+	})
+}
+
+// listNotes returns the patient's notes, pinned first, then newest first
+func (h *PatientsHandler) listNotes(c *gin.Context) {
+	patientID, ok := h.visibleNotePatient(c)
+	if !ok {
+		return
+	}
+	notes, err := h.store.PatientNotes().List(c.Request.Context(), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list notes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": notes})
+}
+
+// createNote adds a note attributed to the caller. Anyone who can see the
+// patient may add one, so clinic colleagues can share context.
+func (h *PatientsHandler) createNote(c *gin.Context) {
+	patientID, ok := h.visibleNotePatient(c)
+	if !ok {
+		return
+	}
+	var req patientNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	claims := c.MustGet("user").(middleware.UserClaims)
+	authorID := int64(claims.UserID)
+	note := models.PatientNote{PatientID: patientID, AuthorID: &authorID, Category: models.NoteGeneral}
+	if msg := req.apply(&note); msg != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+		return
+	}
+
+	created, err := h.store.PatientNotes().Create(c.Request.Context(), note)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to create note for patient %d", patientID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create note"})
+		return
+	}
+	h.auditNote(c, "patient.note.create", *created)
+	c.JSON(http.StatusCreated, created)
+}
+
+// updateNote changes a note's category, body or pinning
+func (h *PatientsHandler) updateNote(c *gin.Context) {
+	patientID, ok := h.visibleNotePatient(c)
+	if !ok {
+		return
+	}
+	note, ok := h.authoredNote(c, patientID)
+	if !ok {
+		return
+	}
+	var req patientNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if msg := req.apply(note); msg != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+		return
+	}
+
+	updated, err := h.store.PatientNotes().Update(c.Request.Context(), *note)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "note not found")
+		return
+	}
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to update note %d", note.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update note"})
+		return
+	}
+	h.auditNote(c, "patient.note.update", *updated)
+	c.JSON(http.StatusOK, updated)
+}
+
+// deleteNote removes a note
+func (h *PatientsHandler) deleteNote(c *gin.Context) {
+	patientID, ok := h.visibleNotePatient(c)
+	if !ok {
+		return
+	}
+	note, ok := h.authoredNote(c, patientID)
+	if !ok {
+		return
+	}
+	err := h.store.PatientNotes().Delete(c.Request.Context(), note.ID, patientID)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "note not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete note"})
+		return
+	}
+	h.auditNote(c, "patient.note.delete", *note)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// notesRouter serves the patient routes as the given user
+func notesRouter(st *fakeStore, claims middleware.UserClaims) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		c.Set("user", claims)
+		c.Next()
+	})
+	NewPatientsHandler(st).Register(r.Group("/patients"))
+	return r
+}
+
+func TestPatientNotes_CRUD(t *testing.T) {
+	audit := &fakeAuditRepo{}
+	st := &fakeStore{patientRepo: &fakePatientRepo{}, audit: audit}
+	author := notesRouter(st, middleware.UserClaims{UserID: 2, Email: "doc@example.com", Role: "clinician"})
+
+	w := contactRequest(author, http.MethodPost, "/patients/5/notes", `{"body":"  Started metformin 500mg  ","category":"medication"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var first models.PatientNote
+	_ = json.Unmarshal(w.Body.Bytes(), &first)
+	if first.Body != "Started metformin 500mg" || first.AuthorID == nil || *first.AuthorID != 2 || first.Pinned {
+		t.Fatalf("unexpected note %+v", first)
+	}
+	contactRequest(author, http.MethodPost, "/patients/5/notes", `{"body":"Walk 30 minutes daily"}`)
+
+	w = contactRequest(author, http.MethodPatch, "/patients/5/notes/1", `{"pinned":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("pin: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = contactRequest(author, http.MethodGet, "/patients/5/notes", "")
+	var list struct{ Data []models.PatientNote }
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Data) != 2 || list.Data[0].ID != 1 || !list.Data[0].Pinned || list.Data[1].Category != models.NoteGeneral {
+		t.Fatalf("expected the pinned note first, got %+v", list.Data)
+	}
+
+	if w := contactRequest(author, http.MethodDelete, "/patients/5/notes/2", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", w.Code)
+	}
+	if w := contactRequest(author, http.MethodDelete, "/patients/5/notes/2", ""); w.Code != http.StatusNotFound {
+		t.Errorf("deleted note: expected 404, got %d", w.Code)
+	}
+
+	var actions []string
+	for _, e := range audit.events {
+		actions = append(actions, e.Action)
+		if _, ok := e.Details["body"]; ok {
+			t.Error("note bodies must not be audited")
+		}
+	}
+	if got := strings.Join(actions, ","); got != "patient.note.create,patient.note.create,patient.note.update,patient.note.delete" {
+		t.Errorf("audit actions = %s", got)
+	}
+}
+
+func TestPatientNotes_Validation(t *testing.T) {
+	st := &fakeStore{patientRepo: &fakePatientRepo{}}
+	r := notesRouter(st, middleware.UserClaims{UserID: 2, Email: "doc@example.com", Role: "clinician"})
+
+	for _, body := range []string{
+		`{"body":"   "}`,
+		`{"body":"x","category":"billing"}`,
+		`{"body":"` + strings.Repeat("a", maxNoteLength+1) + `"}`,
+	} {
+		if w := contactRequest(r, http.MethodPost, "/patients/5/notes", body); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%.40s: expected 422, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestPatientNotes_OnlyAuthorChanges(t *testing.T) {
+	st := &fakeStore{patientRepo: &fakePatientRepo{}}
+	author := notesRouter(st, middleware.UserClaims{UserID: 2, Email: "doc@example.com", Role: "clinician"})
+	colleague := notesRouter(st, middleware.UserClaims{UserID: 3, Email: "nurse@example.com", Role: "clinician"})
+	admin := notesRouter(st, middleware.UserClaims{UserID: 1, Email: "admin@example.com", Role: "admin"})
+
+	contactRequest(author, http.MethodPost, "/patients/5/notes", `{"body":"Care plan agreed","category":"care_plan"}`)
+	if w := contactRequest(colleague, http.MethodPatch, "/patients/5/notes/1", `{"body":"changed"}`); w.Code != http.StatusForbidden {
+		t.Errorf("colleague edit: expected 403, got %d", w.Code)
+	}
+	if w := contactRequest(colleague, http.MethodDelete, "/patients/5/notes/1", ""); w.Code != http.StatusForbidden {
+		t.Errorf("colleague delete: expected 403, got %d", w.Code)
+	}
+	if w := contactRequest(author, http.MethodPatch, "/patients/6/notes/1", `{"pinned":true}`); w.Code != http.StatusNotFound {
+		t.Errorf("note of another patient: expected 404, got %d", w.Code)
+	}
+	if w := contactRequest(admin, http.MethodDelete, "/patients/5/notes/1", ""); w.Code != http.StatusNoContent {
+		t.Errorf("admin delete: expected 204, got %d", w.Code)
+	}
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/storage"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// patientPhotoMaxDim is the longest side of a stored photo; uploads are scaled down to it.
+const patientPhotoMaxDim = 512
+
+// PatientPhotosHandler serves optional patient photos. Photos are only
+// reachable by the patient's owner, every view is audited, and the feature is
+// off for any user in a clinic that has disabled it.
+type PatientPhotosHandler struct {
+	store    store.Store
+	blobs    storage.Storage
+	maxBytes int64
+}
+
+func NewPatientPhotosHandler(store store.Store, blobs storage.Storage, maxBytes int64) *PatientPhotosHandler {
+	return &PatientPhotosHandler{store: store, blobs: blobs, maxBytes: maxBytes}
+}
+
+// Subscribe removes a deleted patient's stored photo object; its metadata
+// row is already gone with the patient.
+func (h *PatientPhotosHandler) Subscribe(bus *events.Bus) {
+	events.Subscribe(bus, "patient_photos", func(ctx context.Context, e events.PatientDeleted) error {
+		if e.Photo == nil {
+			return nil
+		}
+		if err := h.blobs.Delete(ctx, e.Photo.StorageKey); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("delete photo object %s: %w", e.Photo.StorageKey, err)
+		}
+		return nil
+	})
+}
+
+func (h *PatientPhotosHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("/:id/photo", h.get)
+	rg.PUT("/:id/photo", h.upload)
+	rg.DELETE("/:id/photo", h.delete)
+}
+
+// authorize resolves the patient for the caller and checks the clinic toggle.
+// Returns false if a response has already been written.
+func (h *PatientPhotosHandler) authorize(c *gin.Context) (*models.Patient, bool) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return nil, false
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return nil, false
+	}
+	enabled, err := h.store.Clinics().PatientPhotosEnabledForUser(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check clinic settings"})
+		return nil, false
+	}
+	if !enabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "patient photos are disabled for your clinic"})
+		return nil, false
+	}
+	patient, err := h.store.Patients().Get(c.Request.Context(), int32(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return nil, false
+	}
+	return patient, true
+}
+
+// get streams the patient's photo as JPEG
+// @Summary Get patient photo
+// @Description Returns the patient's photo. Each view is recorded in the audit log.
+// @Tags Patients
+// @Produce jpeg
+// @Param id path int true "Patient ID"
+// @Success 200 {file} binary
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /patients/{id}/photo [get]
+func (h *PatientPhotosHandler) get(c *gin.Context) {
+	patient, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	photo, err := h.store.PatientPhotos().Get(ctx, patient.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load photo"})
+		return
+	}
+	if photo == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient has no photo"})
+		return
+	}
+	body, err := h.blobs.Get(ctx, photo.StorageKey)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to read photo %s for patient %d", photo.StorageKey, patient.ID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient has no photo"})
+		return
+	}
+	defer body.Close()
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "patient.photo.view",
+		TargetType: "patient",
+		TargetID:   int(patient.ID),
+	})
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, int64(photo.SizeBytes), photo.ContentType, body, nil)
+}
+
+// upload stores a new photo for the patient, replacing any existing one
+// @Summary Upload patient photo
+// @Description Accepts a JPEG, PNG or GIF as multipart field "photo". The image is scaled to at most 512px and re-encoded as JPEG, which strips metadata.
+// @Tags Patients
+// @Accept mpfd
+// @Produce json
+// @Param id path int true "Patient ID"
+// @Param photo formData file true "Photo"
+// @Success 200 {object} models.PatientPhoto
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Router /patients/{id}/photo [put]
+func (h *PatientPhotosHandler) upload(c *gin.Context) {
+	patient, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBytes+1<<20)
+	fh, err := c.FormFile("photo")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("photo must be at most %d bytes", h.maxBytes)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multipart field 'photo' is required"})
+		return
+	}
+	if fh.Size > h.maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("photo must be at most %d bytes", h.maxBytes)})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read photo"})
+		return
+	}
+	defer f.Close()
+
+	data, width, height, err := storage.Thumbnail(io.LimitReader(f, h.maxBytes), patientPhotoMaxDim)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "photo must be a JPEG, PNG or GIF image"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	key, err := newPhotoKey(patient.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store photo"})
+		return
+	}
+	if err := h.blobs.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to store photo for patient %d", patient.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store photo"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	photo := models.PatientPhoto{
+		PatientID:   patient.ID,
+		StorageKey:  key,
+		ContentType: "image/jpeg",
+		Width:       width,
+		Height:      height,
+		SizeBytes:   len(data),
+		UploadedBy:  claims.UserID,
+	}
+	prev, err := h.store.PatientPhotos().Put(ctx, photo)
+	if err != nil {
+		h.removeBlob(ctx, key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store photo"})
+		return
+	}
+	if prev != nil {
+		h.removeBlob(ctx, prev.StorageKey)
+	}
+
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "patient.photo.upload",
+		TargetType: "patient",
+		TargetID:   int(patient.ID),
+		Details: map[string]interface{}{
+			"width":      width,
+			"height":     height,
+			"size_bytes": len(data),
+			"replaced":   prev != nil,
+		},
+	})
+
+	c.JSON(http.StatusOK, photo)
+}
+
+// delete removes the patient's photo
+// @Summary Delete patient photo
+// @Tags Patients
+// @Param id path int true "Patient ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /patients/{id}/photo [delete]
+func (h *PatientPhotosHandler) delete(c *gin.Context) {
+	patient, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	photo, err := h.store.PatientPhotos().Delete(ctx, patient.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete photo"})
+		return
+	}
+	if photo == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient has no photo"})
+		return
+	}
+	h.removeBlob(ctx, photo.StorageKey)
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "patient.photo.delete",
+		TargetType: "patient",
+		TargetID:   int(patient.ID),
+	})
+	c.Status(http.StatusNoContent)
+}
+
+func (h *PatientPhotosHandler) removeBlob(ctx context.Context, key string) {
+	if err := h.blobs.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to delete photo object %s", key)
+	}
+}
+
+// newPhotoKey returns a fresh, unguessable storage key so a replaced photo
+// never shares a key (or a cached response) with its predecessor.
+func newPhotoKey(patientID int64) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("patient-photos/%d/%s.jpg", patientID, hex.EncodeToString(b)), nil
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/storage"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+type fakePhotoClinicRepo struct {
+	store.ClinicRepository
+	enabled bool
+}
+
+func (f *fakePhotoClinicRepo) PatientPhotosEnabledForUser(ctx context.Context, userID int32) (bool, error) {
+	return f.enabled, nil
+}
+
+type fakePatientPhotoRepo struct {
+	photos map[int64]models.PatientPhoto
+}
+
+func (f *fakePatientPhotoRepo) Get(ctx context.Context, patientID int64) (*models.PatientPhoto, error) {
+	p, ok := f.photos[patientID]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func (f *fakePatientPhotoRepo) Put(ctx context.Context, photo models.PatientPhoto) (*models.PatientPhoto, error) {
+	prev, _ := f.Get(ctx, photo.PatientID)
+	f.photos[photo.PatientID] = photo
+	return prev, nil
+}
+
+func (f *fakePatientPhotoRepo) Delete(ctx context.Context, patientID int64) (*models.PatientPhoto, error) {
+	prev, _ := f.Get(ctx, patientID)
+	delete(f.photos, patientID)
+	return prev, nil
+}
+
+// ownedPatientRepo only returns patient 7, mimicking ownership checks
+type ownedPatientRepo struct {
+	fakePatientRepo
+}
+
+func (f *ownedPatientRepo) Get(ctx context.Context, id int32, userID int32) (*models.Patient, error) {
+	if id != 7 {
+		return nil, errors.New("not found")
+	}
+	return &models.Patient{ID: 7, UserID: int64(userID)}, nil
+}
+
+type photoTestEnv struct {
+	router *gin.Engine
+	photos *fakePatientPhotoRepo
+	clinic *fakePhotoClinicRepo
+	audit  *fakeAuditRepo
+	blobs  *storage.LocalStorage
+}
+
+func newPhotoTestEnv(t *testing.T) *photoTestEnv {
+	gin.SetMode(gin.TestMode)
+	env := &photoTestEnv{
+		photos: &fakePatientPhotoRepo{photos: map[int64]models.PatientPhoto{}},
+		clinic: &fakePhotoClinicRepo{enabled: true},
+		audit:  &fakeAuditRepo{},
+		blobs:  storage.NewLocalStorage(t.TempDir()),
+	}
+	st := &fakeStore{clinicRepo: env.clinic, audit: env.audit, photos: env.photos}
+	h := NewPatientPhotosHandler(&ownedPatientStore{fakeStore: st}, env.blobs, 1<<20)
+	env.router = gin.New()
+	env.router.Use(mockAuthMiddleware())
+	h.Register(env.router.Group("/patients"))
+	return env
+}
+
+// ownedPatientStore swaps in ownedPatientRepo for patient lookups
+type ownedPatientStore struct {
+	*fakeStore
+}
+
+func (s *ownedPatientStore) Patients() store.PatientRepository { return &ownedPatientRepo{} }
+
+func photoUpload(t *testing.T, r *gin.Engine, path string, content []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("photo", "photo.png")
+	_, _ = fw.Write(content)
+	_ = mw.Close()
+	req, _ := http.NewRequest(http.MethodPut, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func photoRequest(r *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPatientPhotos_UploadViewDelete(t *testing.T) {
+	env := newPhotoTestEnv(t)
+
+	w := photoUpload(t, env.router, "/patients/7/photo", testPNG(t, 1024, 768))
+	if w.Code != http.StatusOK {
+		t.Fatalf("upload: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	first := env.photos.photos[7]
+	if first.Width != 512 || first.Height != 384 || first.ContentType != "image/jpeg" {
+		t.Fatalf("expected resized 512x384 JPEG, got %+v", first)
+	}
+
+	w = photoRequest(env.router, http.MethodGet, "/patients/7/photo")
+	if w.Code != http.StatusOK {
+		t.Fatalf("view: expected 200, got %d", w.Code)
+	}
+	if w.Header().Get("Content-Type") != "image/jpeg" || w.Header().Get("Cache-Control") != "private, no-store" {
+		t.Fatalf("unexpected headers: %v", w.Header())
+	}
+	if last := env.audit.events[len(env.audit.events)-1]; last.Action != "patient.photo.view" || last.TargetID != 7 {
+		t.Fatalf("expected view audit event, got %+v", last)
+	}
+
+	// Replacing removes the previous object
+	if w := photoUpload(t, env.router, "/patients/7/photo", testPNG(t, 10, 10)); w.Code != http.StatusOK {
+		t.Fatalf("replace: expected 200, got %d", w.Code)
+	}
+	if _, err := env.blobs.Get(context.Background(), first.StorageKey); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected replaced object removed, got %v", err)
+	}
+
+	second := env.photos.photos[7]
+	if w := photoRequest(env.router, http.MethodDelete, "/patients/7/photo"); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", w.Code)
+	}
+	if _, err := env.blobs.Get(context.Background(), second.StorageKey); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected object removed on delete, got %v", err)
+	}
+	if w := photoRequest(env.router, http.MethodGet, "/patients/7/photo"); w.Code != http.StatusNotFound {
+		t.Fatalf("view after delete: expected 404, got %d", w.Code)
+	}
+}
+
+func TestPatientPhotos_AccessControl(t *testing.T) {
+	env := newPhotoTestEnv(t)
+
+	if w := photoUpload(t, env.router, "/patients/8/photo", testPNG(t, 10, 10)); w.Code != http.StatusNotFound {
+		t.Fatalf("other user's patient: expected 404, got %d", w.Code)
+	}
+	if w := photoUpload(t, env.router, "/patients/7/photo", []byte("not an image")); w.Code != http.StatusBadRequest {
+		t.Fatalf("non-image: expected 400, got %d", w.Code)
+	}
+	if w := photoUpload(t, env.router, "/patients/7/photo", make([]byte, 2<<20)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized: expected 413, got %d", w.Code)
+	}
+
+	env.clinic.enabled = false
+	if w := photoUpload(t, env.router, "/patients/7/photo", testPNG(t, 10, 10)); w.Code != http.StatusForbidden {
+		t.Fatalf("disabled clinic upload: expected 403, got %d", w.Code)
+	}
+	if w := photoRequest(env.router, http.MethodGet, "/patients/7/photo"); w.Code != http.StatusForbidden {
+		t.Fatalf("disabled clinic view: expected 403, got %d", w.Code)
+	}
+	if len(env.photos.photos) != 0 {
+		t.Fatalf("expected nothing stored, got %v", env.photos.photos)
+	}
+}
+
+func TestPatientPhotos_RemovedWithPatient(t *testing.T) {
+	env := newPhotoTestEnv(t)
+	if w := photoUpload(t, env.router, "/patients/7/photo", testPNG(t, 10, 10)); w.Code != http.StatusOK {
+		t.Fatalf("upload: expected 200, got %d", w.Code)
+	}
+	key := env.photos.photos[7].StorageKey
+
+	bus := events.NewBus()
+	NewPatientPhotosHandler(nil, env.blobs, 1<<20).Subscribe(bus)
+	st := &ownedPatientStore{fakeStore: &fakeStore{photos: env.photos}}
+	patients := NewPatientsHandler(st).WithEvents(bus)
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	patients.Register(r.Group("/patients"))
+
+	if w := photoRequest(r, http.MethodDelete, "/patients/7"); w.Code != http.StatusNoContent {
+		t.Fatalf("delete patient: expected 204, got %d", w.Code)
+	}
+	if _, err := env.blobs.Get(context.Background(), key); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected photo object removed with patient, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// report generates a PDF summary of the patient's whole assessment history,
+// branded and localized for the patient's clinic
+// @Summary Patient summary report
+// @Description PDF with every final assessment in a longitudinal table, sparkline charts of HbA1c, FBS, BMI and risk score, and the patient's notes, pinned first
+// @Tags Patients
+// @Produce application/pdf
+// @Param id path int true "Patient ID"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /patients/{id}/report [get]
+func (h *PatientsHandler) report(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	patient, err := h.store.Patients().GetVisible(ctx, int32(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	assessments, err := h.store.Assessments().ListByPatient(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load assessments"})
+		return
+	}
+	assessments = models.FinalAssessments(assessments)
+	trend, err := h.store.Assessments().GetTrend(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get trend data"})
+		return
+	}
+
+	notes, err := h.store.PatientNotes().List(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load notes"})
+		return
+	}
+
+	pdfBytes, err := reportGenerator(ctx, h.store, userID, *patient).GeneratePatientSummaryReport(*patient, assessments, trend, notes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
+		return
+	}
+
+	filename := fmt.Sprintf("diana_summary_%s_%s.pdf", sanitizeFilename(patient.Name), time.Now().Format("2006-01-02"))
+	c.Header("Content-Type", "application/pdf")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	c.Header("Content-Length", fmt.Sprintf("%d", len(pdfBytes)))
+	c.Data(http.StatusOK, "application/pdf", pdfBytes)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestPatientSummaryReport(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	var all []models.Assessment
+	var trend []models.AssessmentTrend
+	// Enough visits to run the table onto a second page
+	for i := 0; i < 60; i++ {
+		at := start.AddDate(0, 0, 14*i)
+		risk := 0.3 + float64(i)/200
+		all = append(all, models.Assessment{ID: int64(i + 1), PatientID: 7, HbA1c: 5.8 + float64(i)/50, FBS: 105, CreatedAt: at, Cluster: "MOD", RiskScore: int(risk * 100)})
+		trend = append(trend, models.AssessmentTrend{ID: int64(i + 1), CreatedAt: at, HbA1c: 5.8 + float64(i)/50, FBS: 105, RiskScore: &risk})
+	}
+	r := baselineRouter(&fakeStore{patientRepo: &fakePatientRepo{}, repo: &fakeAssessmentRepo{all: all, trend: trend}})
+
+	w := contactRequest(r, http.MethodGet, "/patients/7/report", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Fatalf("expected a PDF, got %q", ct)
+	}
+	if !bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF")) {
+		t.Fatal("body is not a PDF")
+	}
+
+	// A patient without assessments still gets a report
+	r = baselineRouter(&fakeStore{patientRepo: &fakePatientRepo{}, repo: &fakeAssessmentRepo{}})
+	if w := contactRequest(r, http.MethodGet, "/patients/8/report", ""); w.Code != http.StatusOK {
+		t.Fatalf("empty history: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := contactRequest(r, http.MethodGet, "/patients/abc/report", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid id: expected 400, got %d", w.Code)
+	}
+}
+
+func TestAssessmentReport_Charts(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	risk := func(v float64) *float64 { return &v }
+	trend := []models.AssessmentTrend{
+		{ID: 1, CreatedAt: start, HbA1c: 6.1, FBS: 110, RiskScore: risk(0.4)},
+		{ID: 2, CreatedAt: start.AddDate(0, 3, 0), RiskScore: risk(0.5)},
+		{ID: 4, CreatedAt: start.AddDate(0, 6, 0), HbA1c: 14.2, FBS: 310, RiskScore: risk(0.9)},
+		// Later than the reported assessment, so left off its charts
+		{ID: 5, CreatedAt: start.AddDate(0, 9, 0), HbA1c: 7.0, FBS: 130, RiskScore: risk(0.7)},
+	}
+	for name, history := range map[string][]models.AssessmentTrend{"history": trend, "first visit": nil} {
+		repo := &fakeAssessmentRepo{
+			stored: &models.Assessment{ID: 4, PatientID: 9, HbA1c: 14.2, FBS: 310, RiskScore: 90, CreatedAt: trend[2].CreatedAt},
+			trend:  history,
+		}
+		r := baselineRouter(&fakeStore{repo: repo, patientRepo: &fakePatientRepo{}})
+
+		w := contactRequest(r, http.MethodGet, "/patients/9/assessments/4/report", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", name, w.Code, w.Body.String())
+		}
+		if !bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF")) {
+			t.Fatalf("%s: body is not a PDF", name)
+		}
+	}
+}
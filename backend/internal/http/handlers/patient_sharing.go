@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// PatientClinicRequest shares a patient with a clinic; a null clinic_id
+// makes it private to its owner again.
+type PatientClinicRequest struct {
+	ClinicID *int32 `json:"clinic_id" binding:"omitempty,min=1"`
+}
+
+// requireClinicMember writes a 403 and returns false unless the user is a
+// member of the clinic.
+func (h *PatientsHandler) requireClinicMember(c *gin.Context, userID, clinicID int32) bool {
+	clinics, err := h.store.Clinics().ListUserClinics(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load clinic membership"})
+		return false
+	}
+	for _, uc := range clinics {
+		if uc.ID == int64(clinicID) {
+			return true
+		}
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "access denied - not a member of this clinic"})
+	return false
+}
+
+// setClinic shares a patient with one of the owner's clinics, or stops
+// sharing it. Only the owner can share; the owner or a holder of
+// clinic.manage in the clinic it is shared with can stop sharing.
+func (h *PatientsHandler) setClinic(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	var req PatientClinicRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "clinic_id must be a clinic ID or null"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	patient, err := h.store.Patients().GetVisible(ctx, int32(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	owner := int32(patient.UserID)
+
+	if req.ClinicID != nil {
+		if owner != userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only the patient's owner can share it"})
+			return
+		}
+		if !h.requireClinicMember(c, userID, *req.ClinicID) {
+			return
+		}
+	} else if owner != userID {
+		manages := false
+		if patient.ClinicID != nil {
+			claims := c.MustGet("user").(middleware.UserClaims)
+			manages, err = middleware.HasPermission(ctx, h.perms, claims, *patient.ClinicID, models.PermClinicManage)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check permissions"})
+				return
+			}
+		}
+		if !manages {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only the owner or a clinic manager can stop sharing"})
+			return
+		}
+	}
+
+	if err := h.store.Patients().SetClinic(ctx, id, owner, req.ClinicID, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusConflict, gin.H{"error": "patient was modified concurrently, retry"})
+			return
+		}
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to set clinic for patient %d", id)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update patient sharing"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "patient.share",
+		TargetType: "patient",
+		TargetID:   int(id),
+		Details: map[string]interface{}{
+			"from_clinic_id": patient.ClinicID,
+			"to_clinic_id":   req.ClinicID,
+		},
+	})
+	c.JSON(http.StatusOK, gin.H{"patient_id": id, "clinic_id": req.ClinicID})
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// fakeSharingClinicRepo makes the caller a member of clinic 4 with role.
+// report holds clinic 4's report settings, if any.
+type fakeSharingClinicRepo struct {
+	store.ClinicRepository
+	role   string
+	report *models.ClinicReportSettings
+}
+
+func (f *fakeSharingClinicRepo) GetReportSettings(ctx context.Context, clinicID int32) (*models.ClinicReportSettings, error) {
+	if clinicID != 4 || f.report == nil {
+		return nil, pgx.ErrNoRows
+	}
+	s := *f.report
+	s.HasLogo = len(s.Logo) > 0
+	return &s, nil
+}
+
+func (f *fakeSharingClinicRepo) SetReportLocale(ctx context.Context, clinicID int32, locale string) error {
+	if clinicID != 4 || f.report == nil {
+		return pgx.ErrNoRows
+	}
+	f.report.Locale = locale
+	return nil
+}
+
+func (f *fakeSharingClinicRepo) SetReportLogo(ctx context.Context, clinicID int32, logo []byte) error {
+	if clinicID != 4 || f.report == nil {
+		return pgx.ErrNoRows
+	}
+	f.report.Logo = logo
+	return nil
+}
+
+func (f *fakeSharingClinicRepo) ListUserClinics(ctx context.Context, userID int32) ([]models.UserClinic, error) {
+	return []models.UserClinic{{Clinic: models.Clinic{ID: 4}, Role: f.role}}, nil
+}
+
+func (f *fakeSharingClinicRepo) ClinicRole(ctx context.Context, userID, clinicID int32) (string, error) {
+	if clinicID != 4 || f.role == "" {
+		return "", pgx.ErrNoRows
+	}
+	return f.role, nil
+}
+
+func sharingRouter(st *fakeStore, userID int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", middleware.UserClaims{UserID: userID, Email: "doc@example.com", Role: "clinician"})
+		c.Next()
+	})
+	NewPatientsHandler(st).Register(r.Group("/patients"))
+	return r
+}
+
+func TestPatientSharing(t *testing.T) {
+	shared := int64(4)
+	for _, tc := range []struct {
+		name   string
+		caller int64
+		role   string
+		clinic *int64
+		body   string
+		want   int
+	}{
+		{"owner shares with own clinic", 2, models.ClinicRoleMember, nil, `{"clinic_id":4}`, http.StatusOK},
+		{"owner cannot share with another clinic", 2, models.ClinicRoleMember, nil, `{"clinic_id":9}`, http.StatusForbidden},
+		{"member cannot reshare", 3, models.ClinicRoleMember, &shared, `{"clinic_id":4}`, http.StatusForbidden},
+		{"member cannot unshare", 3, models.ClinicRoleMember, &shared, `{"clinic_id":null}`, http.StatusForbidden},
+		{"clinic admin unshares", 3, models.ClinicRoleAdmin, &shared, `{"clinic_id":null}`, http.StatusOK},
+		{"owner unshares", 2, models.ClinicRoleMember, &shared, `{}`, http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			patients := &fakePatientRepo{stored: &models.Patient{ID: 7, UserID: 2, ClinicID: tc.clinic}}
+			audit := &fakeAuditRepo{}
+			st := &fakeStore{patientRepo: patients, clinicRepo: &fakeSharingClinicRepo{role: tc.role}, audit: audit}
+			w := contactRequest(sharingRouter(st, tc.caller), http.MethodPut, "/patients/7/clinic", tc.body)
+			if w.Code != tc.want {
+				t.Fatalf("expected %d, got %d: %s", tc.want, w.Code, w.Body.String())
+			}
+			if tc.want != http.StatusOK {
+				if len(audit.events) != 0 {
+					t.Fatal("refused request must not be audited")
+				}
+				return
+			}
+			if len(audit.events) != 1 || audit.events[0].Action != "patient.share" {
+				t.Fatalf("expected a patient.share audit event, got %+v", audit.events)
+			}
+		})
+	}
+}
+
+func TestPatientSharing_UnshareRequiresClinicManage(t *testing.T) {
+	shared := int64(4)
+	patients := &fakePatientRepo{stored: &models.Patient{ID: 7, UserID: 2, ClinicID: &shared}}
+	st := &fakeStore{patientRepo: patients, clinicRepo: &fakeSharingClinicRepo{role: models.ClinicRoleAdmin}, audit: &fakeAuditRepo{}}
+	if err := st.Roles().SetPermissions(context.Background(), models.ClinicRoleAdmin, []string{models.PermClinicRead}, 0); err != nil {
+		t.Fatal(err)
+	}
+	w := contactRequest(sharingRouter(st, 3), http.MethodPut, "/patients/7/clinic", `{"clinic_id":null}`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("clinic_admin without clinic.manage: expected 403, got %d", w.Code)
+	}
+}
+
+func TestPatientList_ClinicFilterRequiresMembership(t *testing.T) {
+	patients := &fakePatientRepo{}
+	st := &fakeStore{patientRepo: patients, clinicRepo: &fakeSharingClinicRepo{role: models.ClinicRoleMember}}
+	r := sharingRouter(st, 3)
+
+	if w := contactRequest(r, http.MethodGet, "/patients?clinic_id=9", ""); w.Code != http.StatusForbidden {
+		t.Fatalf("non-member clinic: expected 403, got %d", w.Code)
+	}
+	w := contactRequest(r, http.MethodGet, "/patients?clinic_id=4", "")
+	if w.Code != http.StatusOK || patients.lastParams.ClinicID == nil || *patients.lastParams.ClinicID != 4 {
+		t.Fatalf("expected the clinic filter to reach the store, got %d %+v", w.Code, patients.lastParams)
+	}
+}
+
+func TestPatientSharing_RequiresPatientsRead(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	mem := store.NewMemoryStore()
+	member, _ := mem.Users().Create(ctx, models.User{Email: "member@example.com", Role: "clinician", IsActive: true})
+	owner, _ := mem.Users().Create(ctx, models.User{Email: "owner@example.com", Role: "clinician", IsActive: true})
+	clinic, _ := mem.Clinics().Create(ctx, "North", "")
+	_ = mem.Clinics().AddMember(ctx, int32(clinic.ID), int32(owner.ID), models.ClinicRoleMember)
+	_ = mem.Clinics().AddMember(ctx, int32(clinic.ID), int32(member.ID), models.ClinicRoleMember)
+	patient, _ := mem.Patients().Create(ctx, models.Patient{UserID: owner.ID, Name: "Ana Cruz"})
+	clinicID := int32(clinic.ID)
+	_ = mem.Patients().SetClinic(ctx, patient.ID, int32(owner.ID), &clinicID, int32(owner.ID))
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", middleware.UserClaims{UserID: member.ID, Email: member.Email, Role: member.Role})
+		c.Next()
+	})
+	// The same route check as the router's /patients group
+	access := middleware.ClinicReadWritePermission(PermissionLookup(mem), ClinicMembershipLookup(mem), models.PermPatientsRead, models.PermPatientsWrite)
+	NewPatientsHandler(mem).Register(r.Group("/patients", access))
+	path := fmt.Sprintf("/patients/%d", patient.ID)
+	list := fmt.Sprintf("/patients?clinic_id=%d", clinic.ID)
+
+	if w := contactRequest(r, http.MethodGet, path, ""); w.Code != http.StatusOK {
+		t.Fatalf("clinician member: expected 200, got %d", w.Code)
+	}
+	// Neither the member's global role nor their clinic role grants patients.read
+	_ = mem.Roles().SetPermissions(ctx, "clinician", []string{models.PermPatientsWrite, models.PermAnalyticsRead}, 0)
+	for _, p := range []string{path, list} {
+		if w := contactRequest(r, http.MethodGet, p, ""); w.Code != http.StatusForbidden {
+			t.Fatalf("member without patients.read: expected 403 for %s, got %d", p, w.Code)
+		}
+	}
+
+	// Only the member role grants it, through the route check to the patient
+	_ = mem.Roles().SetPermissions(ctx, models.ClinicRoleMember, []string{models.PermClinicRead, models.PermPatientsRead}, 0)
+	if w := contactRequest(r, http.MethodGet, path, ""); w.Code != http.StatusOK {
+		t.Fatalf("clinic role with patients.read: expected 200, got %d", w.Code)
+	}
+	if w := contactRequest(r, http.MethodGet, list, ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Ana Cruz") {
+		t.Fatalf("clinic role with patients.read: expected the shared patient listed, got %d %s", w.Code, w.Body.String())
+	}
+	if w := contactRequest(r, http.MethodPut, path, `{"name":"Ana C."}`); w.Code != http.StatusNotFound {
+		t.Fatalf("edits stay with the owner: expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// TransferPatientRequest names the clinician who takes over a patient.
+// Notify emails them about it.
+type TransferPatientRequest struct {
+	ToUserID int64 `json:"to_user_id" binding:"required,min=1"`
+	Notify   bool  `json:"notify"`
+}
+
+// transfer moves a patient to another clinician. Holders of admin.users can
+// transfer any patient; holders of clinic.manage in a clinic the caller,
+// the owner and the new owner all belong to can transfer between them.
+func (h *PatientsHandler) transfer(c *gin.Context) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	id, err := parseIDParam(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	var req TransferPatientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to_user_id is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	owner, err := h.store.Patients().Owner(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	toUserID := int32(req.ToUserID)
+	if owner == toUserID {
+		c.JSON(http.StatusConflict, gin.H{"error": "patient already belongs to that user"})
+		return
+	}
+
+	allowed, err := h.canTransfer(ctx, claims, owner, toUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check permissions"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied - admin.users, or clinic.manage in both clinicians' clinic, required"})
+		return
+	}
+
+	target, err := h.store.Users().FindByID(ctx, toUserID)
+	if err != nil || target == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if !target.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user is deactivated"})
+		return
+	}
+
+	if err := h.store.Patients().Transfer(ctx, id, owner, toUserID, int32(claims.UserID)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The owner changed between the lookup and the transfer
+			c.JSON(http.StatusConflict, gin.H{"error": "patient was modified concurrently, retry"})
+			return
+		}
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to transfer patient %d to user %d", id, toUserID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to transfer patient"})
+		return
+	}
+
+	notified := false
+	if req.Notify {
+		body := fmt.Sprintf("%s has transferred patient #%d to you. The patient now appears in your patient list.", claims.Email, id)
+		if err := h.mailer.Send(ctx, target.Email, "DIANA: a patient has been transferred to you", body); err != nil {
+			logging.Ctx(ctx).Error().Err(err).Msgf("Failed to notify user %d of patient %d transfer", toUserID, id)
+		} else {
+			notified = true
+		}
+	}
+
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "patient.transfer",
+		TargetType: "patient",
+		TargetID:   int(id),
+		Details: map[string]interface{}{
+			"from_user_id": owner,
+			"to_user_id":   toUserID,
+			"to_email":     target.Email,
+			"notified":     notified,
+		},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"patient_id":   id,
+		"from_user_id": owner,
+		"to_user_id":   toUserID,
+		"notified":     notified,
+	})
+}
+
+// canTransfer reports whether the caller may move a patient from one
+// clinician to another.
+func (h *PatientsHandler) canTransfer(ctx context.Context, claims middleware.UserClaims, from, to int32) (bool, error) {
+	ok, err := middleware.HasPermission(ctx, h.perms, claims, 0, models.PermAdminUsers)
+	if err != nil || ok {
+		return ok, err
+	}
+	clinics, err := h.store.Clinics().SharedClinics(ctx, int32(claims.UserID), from, to)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range clinics {
+		ok, err := middleware.HasPermission(ctx, h.perms, claims, int64(id), models.PermClinicManage)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// fakeTransferClinicRepo puts everyone in clinic 4, the caller with role,
+// when role is set
+type fakeTransferClinicRepo struct {
+	store.ClinicRepository
+	role string
+}
+
+func (f *fakeTransferClinicRepo) SharedClinics(ctx context.Context, userIDs ...int32) ([]int32, error) {
+	if f.role == "" {
+		return []int32{}, nil
+	}
+	return []int32{4}, nil
+}
+
+func (f *fakeTransferClinicRepo) ClinicRole(ctx context.Context, userID, clinicID int32) (string, error) {
+	if clinicID != 4 || f.role == "" {
+		return "", pgx.ErrNoRows
+	}
+	return f.role, nil
+}
+
+func transferRouter(st *fakeStore, role string, mailer *fakeMailer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", middleware.UserClaims{UserID: 5, Email: "lead@example.com", Role: role})
+		c.Next()
+	})
+	NewPatientsHandler(st).WithMailer(mailer).Register(r.Group("/patients"))
+	return r
+}
+
+func TestPatientTransfer(t *testing.T) {
+	newStore := func(clinicRole string, target models.User) (*fakeStore, *fakePatientRepo, *fakeAuditRepo) {
+		patients := &fakePatientRepo{stored: &models.Patient{ID: 7, UserID: 2, Name: "Ana"}}
+		audit := &fakeAuditRepo{}
+		return &fakeStore{
+			patientRepo: patients,
+			clinicRepo:  &fakeTransferClinicRepo{role: clinicRole},
+			users:       &fakeUserRepo{user: &target},
+			audit:       audit,
+		}, patients, audit
+	}
+	active := models.User{ID: 3, Email: "new@example.com", IsActive: true}
+
+	t.Run("clinician outside the clinic is refused", func(t *testing.T) {
+		st, patients, _ := newStore("", active)
+		w := contactRequest(transferRouter(st, "clinician", &fakeMailer{}), http.MethodPost, "/patients/7/transfer", `{"to_user_id":3}`)
+		if w.Code != http.StatusForbidden || len(patients.transfers) != 0 {
+			t.Fatalf("expected 403 and no transfer, got %d (%v)", w.Code, patients.transfers)
+		}
+	})
+
+	t.Run("clinic member without clinic.manage is refused", func(t *testing.T) {
+		st, patients, _ := newStore(models.ClinicRoleMember, active)
+		w := contactRequest(transferRouter(st, "clinician", &fakeMailer{}), http.MethodPost, "/patients/7/transfer", `{"to_user_id":3}`)
+		if w.Code != http.StatusForbidden || len(patients.transfers) != 0 {
+			t.Fatalf("expected 403 and no transfer, got %d (%v)", w.Code, patients.transfers)
+		}
+	})
+
+	t.Run("clinic admin with clinic.manage revoked is refused", func(t *testing.T) {
+		st, patients, _ := newStore(models.ClinicRoleAdmin, active)
+		if err := st.Roles().SetPermissions(context.Background(), models.ClinicRoleAdmin, []string{models.PermClinicRead}, 0); err != nil {
+			t.Fatal(err)
+		}
+		w := contactRequest(transferRouter(st, "clinician", &fakeMailer{}), http.MethodPost, "/patients/7/transfer", `{"to_user_id":3}`)
+		if w.Code != http.StatusForbidden || len(patients.transfers) != 0 {
+			t.Fatalf("expected 403 and no transfer, got %d (%v)", w.Code, patients.transfers)
+		}
+	})
+
+	t.Run("clinic admin transfers and notifies", func(t *testing.T) {
+		st, patients, audit := newStore(models.ClinicRoleAdmin, active)
+		mailer := &fakeMailer{}
+		w := contactRequest(transferRouter(st, "clinician", mailer), http.MethodPost, "/patients/7/transfer", `{"to_user_id":3,"notify":true}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if len(patients.transfers) != 1 || patients.transfers[0] != [2]int32{2, 3} {
+			t.Fatalf("expected a transfer from 2 to 3, got %v", patients.transfers)
+		}
+		if len(mailer.to) != 1 || mailer.to[0] != "new@example.com" {
+			t.Fatalf("expected the new owner to be notified, got %v", mailer.to)
+		}
+		if len(audit.events) != 1 || audit.events[0].Action != "patient.transfer" || audit.events[0].Details["notified"] != true {
+			t.Fatalf("expected a patient.transfer audit event, got %+v", audit.events)
+		}
+	})
+
+	t.Run("admin without notify", func(t *testing.T) {
+		st, patients, _ := newStore("", active)
+		mailer := &fakeMailer{}
+		w := contactRequest(transferRouter(st, "admin", mailer), http.MethodPost, "/patients/7/transfer", `{"to_user_id":3}`)
+		if w.Code != http.StatusOK || len(patients.transfers) != 1 || len(mailer.to) != 0 {
+			t.Fatalf("expected a silent transfer, got %d, %v, %v", w.Code, patients.transfers, mailer.to)
+		}
+	})
+
+	t.Run("rejected targets", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			body   string
+			target models.User
+			want   int
+		}{
+			"missing target": {`{}`, active, http.StatusBadRequest},
+			"current owner":  {`{"to_user_id":2}`, active, http.StatusConflict},
+			"deactivated":    {`{"to_user_id":3}`, models.User{ID: 3, Email: "gone@example.com"}, http.StatusBadRequest},
+		} {
+			st, patients, _ := newStore("", tc.target)
+			w := contactRequest(transferRouter(st, "admin", &fakeMailer{}), http.MethodPost, "/patients/7/transfer", tc.body)
+			if w.Code != tc.want || len(patients.transfers) != 0 {
+				t.Errorf("%s: expected %d and no transfer, got %d", name, tc.want, w.Code)
+			}
+		}
+	})
+}
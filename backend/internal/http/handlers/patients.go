@@ -3,15 +3,23 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/mail"
 	"github.com/skufu/DianaV2/backend/internal/models"
 	"github.com/skufu/DianaV2/backend/internal/store"
 )
 
 type PatientsHandler struct {
-	store store.Store
+	store  store.Store
+	events *events.Bus
+	mailer mail.Mailer
+	perms  middleware.PermissionLookup
 }
 
 // PatientSummary is the single source of truth for what the frontend expects
@@ -30,16 +38,59 @@ type PatientSummary struct {
 }
 
 func NewPatientsHandler(store store.Store) *PatientsHandler {
-	return &PatientsHandler{store: store}
+	return &PatientsHandler{store: store, mailer: mail.NewLogMailer(), perms: PermissionLookup(store)}
+}
+
+// WithEvents publishes patient.created and patient.deleted on bus.
+func (h *PatientsHandler) WithEvents(bus *events.Bus) *PatientsHandler {
+	h.events = bus
+	return h
+}
+
+// WithMailer sets how transfer notifications are delivered (logged by default).
+func (h *PatientsHandler) WithMailer(m mail.Mailer) *PatientsHandler {
+	h.mailer = m
+	return h
 }
 
 func (h *PatientsHandler) Register(rg *gin.RouterGroup) {
 	rg.GET("", h.list)
 	rg.POST("", h.create)
+	rg.GET("/typeahead", h.typeahead)
+	rg.GET("/duplicates", h.duplicates)
 	rg.GET("/:id", h.get)
 	rg.PUT("/:id", h.update)
+	rg.PATCH("/:id", h.patch)
 	rg.DELETE("/:id", h.delete)
 	rg.GET("/:id/trend", h.trend)
+	rg.GET("/:id/projection", h.projection)
+	rg.GET("/:id/report", h.report)
+	rg.GET("/:id/contact", h.getContact)
+	rg.PUT("/:id/contact", h.putContact)
+	rg.GET("/:id/notes", h.listNotes)
+	rg.POST("/:id/notes", h.createNote)
+	rg.PATCH("/:id/notes/:noteID", h.updateNote)
+	rg.DELETE("/:id/notes/:noteID", h.deleteNote)
+	rg.GET("/:id/medications", h.listMedications)
+	rg.POST("/:id/medications", h.createMedication)
+	rg.PUT("/:id/medications/:medicationID", h.updateMedication)
+	rg.DELETE("/:id/medications/:medicationID", h.deleteMedication)
+	rg.GET("/:id/follow-ups", h.listFollowUps)
+	rg.POST("/:id/follow-ups", h.createFollowUp)
+	rg.PATCH("/:id/follow-ups/:followUpID", h.updateFollowUp)
+	rg.DELETE("/:id/follow-ups/:followUpID", h.deleteFollowUp)
+	rg.GET("/:id/tags", h.listPatientTags)
+	rg.PUT("/:id/tags/:tagID", h.tagPatient)
+	rg.DELETE("/:id/tags/:tagID", h.untagPatient)
+	rg.GET("/:id/baseline-discrepancies", h.listDiscrepancies)
+	rg.POST("/:id/baseline-discrepancies/:discrepancyID/resolve", h.resolveDiscrepancy)
+	rg.GET("/:id/history", h.history)
+	// Whole-record exports need the export scope when called with an API key
+	rg.GET("/:id/bundle", middleware.RequireAPIKeyScope(models.APIKeyScopeExport), h.bundle)
+	rg.GET("/:id/export", middleware.RequireAPIKeyScope(models.APIKeyScopeExport), h.export)
+	rg.POST("/:id/transfer", h.transfer)
+	rg.POST("/:id/merge/:otherID", h.merge)
+	rg.PUT("/:id/clinic", h.setClinic)
 }
 
 func (h *PatientsHandler) list(c *gin.Context) {
@@ -49,30 +100,141 @@ func (h *PatientsHandler) list(c *gin.Context) {
 		return
 	}
 
-	patients, err := h.store.Patients().List(c.Request.Context(), userID)
+	var params models.PatientListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query parameters"})
+		return
+	}
+	if (params.MinAge != nil && params.MaxAge != nil && *params.MinAge > *params.MaxAge) ||
+		(params.MinRisk != nil && params.MaxRisk != nil && *params.MinRisk > *params.MaxRisk) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min must not exceed max"})
+		return
+	}
+
+	if params.ClinicID != nil {
+		if !h.requireClinicMember(c, userID, *params.ClinicID) {
+			return
+		}
+	}
+	if params.TagID != nil {
+		if _, err := h.store.Tags().Get(c.Request.Context(), *params.TagID, int64(userID)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown tag"})
+			return
+		}
+	}
+
+	after, keyset, err := listCursor(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if keyset && (params.Page != 0 || (params.Sort != "" && params.Sort != "created_at") || params.Order == "asc") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cursor pages are newest first and cannot be combined with page, sort or order"})
+		return
+	}
+
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 {
+		params.PageSize = 20
+	}
+
+	// Polling clients get a 304 from the version alone. A failed version
+	// lookup just serves the list without an ETag.
+	version, err := h.store.Patients().ListVersion(c.Request.Context(), userID, params)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to version patient list of user %d", userID)
+	} else if notModified(c, listETag(version, c.Request.URL.RawQuery)) {
+		return
+	}
+
+	if keyset {
+		h.listAfter(c, userID, params, after)
+		return
+	}
+
+	rows, total, err := h.store.Patients().ListWithLatestAssessmentPaginated(c.Request.Context(), userID, params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list patients"})
 		return
 	}
 
-	// Attach latest assessment summary so all consumers share a single source of truth.
-	summaries := make([]PatientSummary, 0, len(patients))
-	for _, p := range patients {
-		s := PatientSummary{Patient: p}
-		assessments, err := h.store.Assessments().ListByPatient(c.Request.Context(), p.ID)
-		if err == nil && len(assessments) > 0 {
-			latest := assessments[0]
-			s.Cluster = latest.Cluster
-			s.RiskScore = latest.RiskScore
-			s.Risk = latest.RiskScore
-			s.FBS = latest.FBS
-			s.HbA1c = latest.HbA1c
-			s.LastVisit = latest.CreatedAt
+	c.JSON(http.StatusOK, models.PaginatedResponse{
+		Data:       patientSummaries(rows),
+		Total:      total,
+		Page:       params.Page,
+		PageSize:   params.PageSize,
+		TotalPages: (total + params.PageSize - 1) / params.PageSize,
+	})
+}
+
+// listAfter serves a keyset page of the patient list. One row beyond the
+// page is read to tell whether another page follows; no total is counted.
+func (h *PatientsHandler) listAfter(c *gin.Context, userID int32, params models.PatientListParams, after *models.ListCursor) {
+	pageSize := params.PageSize
+	params.PageSize++
+	rows, err := h.store.Patients().ListWithLatestAssessmentAfter(c.Request.Context(), userID, params, after)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list patients"})
+		return
+	}
+	resp := models.CursorResponse{PageSize: pageSize}
+	if len(rows) > pageSize {
+		rows = rows[:pageSize]
+		last := rows[pageSize-1].Patient
+		resp.NextCursor = nextCursor(last.CreatedAt, last.ID)
+	}
+	resp.Data = patientSummaries(rows)
+	c.JSON(http.StatusOK, resp)
+}
+
+// patientSummaries shapes list rows for the response. The latest assessment
+// summary comes from the same query, so all consumers share a single source
+// of truth without a per-patient lookup.
+func patientSummaries(rows []models.PatientWithLatest) []PatientSummary {
+	summaries := make([]PatientSummary, 0, len(rows))
+	for _, row := range rows {
+		s := PatientSummary{
+			Patient:   row.Patient,
+			Cluster:   row.Cluster,
+			RiskScore: row.RiskScore,
+			Risk:      row.RiskScore,
+			FBS:       row.FBS,
+			HbA1c:     row.HbA1c,
+		}
+		if row.LastVisit != nil {
+			s.LastVisit = *row.LastVisit
 		}
 		summaries = append(summaries, s)
 	}
+	return summaries
+}
+
+// typeaheadLimit caps search-as-you-type results; the UI only shows a short dropdown.
+const typeaheadLimit = 10
+
+// typeahead returns lightweight patient matches for search-as-you-type lookups.
+// Queries shorter than two characters return an empty list without hitting the DB.
+func (h *PatientsHandler) typeahead(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
 
-	c.JSON(http.StatusOK, summaries)
+	q := strings.TrimSpace(c.Query("q"))
+	if len([]rune(q)) < 2 {
+		c.JSON(http.StatusOK, []models.PatientMatch{})
+		return
+	}
+
+	matches, err := h.store.Patients().Typeahead(c.Request.Context(), userID, q, typeaheadLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search patients"})
+		return
+	}
+	c.JSON(http.StatusOK, matches)
 }
 
 func (h *PatientsHandler) create(c *gin.Context) {
@@ -88,14 +250,43 @@ func (h *PatientsHandler) create(c *gin.Context) {
 		return
 	}
 
+	if !validContactPayload(c, req.Contact) {
+		return
+	}
+
 	// Set user_id for ownership
 	req.UserID = int64(userID)
+	req.BMI, req.BMISource = models.DeriveBMI(req.BMI, req.HeightCM, req.WeightKG)
 
 	created, err := h.store.Patients().Create(c.Request.Context(), req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create patient"})
 		return
 	}
+	if req.Contact != nil {
+		contact, ok := h.saveContact(c, created.ID, *req.Contact)
+		if !ok {
+			return
+		}
+		created.Contact = contact
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "patient.create",
+		TargetType: "patient",
+		TargetID:   int(created.ID),
+		Details: map[string]interface{}{
+			"changes":     auditDiff(models.Patient{}, created),
+			"has_contact": req.Contact != nil,
+		},
+	})
+	h.events.Publish(c.Request.Context(), events.PatientCreated{
+		Actor:   claims.Email,
+		UserID:  userID,
+		Patient: *created,
+	})
 	c.JSON(http.StatusCreated, created)
 }
 
@@ -113,15 +304,22 @@ func (h *PatientsHandler) get(c *gin.Context) {
 		return
 	}
 
-	patient, err := h.store.Patients().Get(c.Request.Context(), int32(id), userID)
+	patient, err := h.store.Patients().GetVisible(c.Request.Context(), int32(id), userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
 		return
 	}
 
+	contact, err := h.store.PatientContacts().Get(c.Request.Context(), patient.ID)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to load contact for patient %d", patient.ID)
+	}
+	patient.Contact = contact
+
 	// Attach latest assessment summary for consistency with list endpoint.
 	summary := PatientSummary{Patient: *patient}
 	assessments, err := h.store.Assessments().ListByPatient(c.Request.Context(), patient.ID)
+	assessments = models.FinalAssessments(assessments)
 	if err == nil && len(assessments) > 0 {
 		latest := assessments[0]
 		summary.Cluster = latest.Cluster
@@ -155,15 +353,50 @@ func (h *PatientsHandler) update(c *gin.Context) {
 		return
 	}
 
+	if !validContactPayload(c, req.Contact) {
+		return
+	}
+
+	existing, err := h.store.Patients().Get(c.Request.Context(), int32(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+
 	// Set the ID from the URL parameter and user_id for ownership
 	req.ID = id
 	req.UserID = int64(userID)
+	req.BMI, req.BMISource = models.DeriveBMI(req.BMI, req.HeightCM, req.WeightKG)
+	if req.BMISource == models.BMISourceProvided && req.BMI == existing.BMI {
+		// A BMI sent back unchanged keeps the source it was stored with
+		req.BMISource = existing.BMISource
+	}
 
 	updated, err := h.store.Patients().Update(c.Request.Context(), req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update patient"})
 		return
 	}
+	// Contact details are left untouched when the payload omits them
+	if req.Contact != nil {
+		contact, ok := h.saveContact(c, id, *req.Contact)
+		if !ok {
+			return
+		}
+		updated.Contact = contact
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "patient.update",
+		TargetType: "patient",
+		TargetID:   int(id),
+		Details: map[string]interface{}{
+			"changes":         auditDiff(existing, updated),
+			"contact_updated": req.Contact != nil,
+		},
+	})
 	c.JSON(http.StatusOK, updated)
 }
 
@@ -181,10 +414,38 @@ func (h *PatientsHandler) delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.store.Patients().Delete(c.Request.Context(), int32(id), userID); err != nil {
+	ctx := c.Request.Context()
+	if _, err := h.store.Patients().Get(ctx, int32(id), userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+	// Look up the photo and attachments first: their metadata rows cascade
+	// away with the patient.
+	photo, err := h.store.PatientPhotos().Get(ctx, id)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to look up photo for patient %d", id)
+	}
+	attachments, err := h.store.AssessmentAttachments().ListByPatient(ctx, id)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to look up attachments for patient %d", id)
+	}
+	attachmentKeys := make([]string, 0, len(attachments))
+	for _, a := range attachments {
+		attachmentKeys = append(attachmentKeys, a.StorageKey)
+	}
+
+	if err := h.store.Patients().Delete(ctx, int32(id), userID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete patient"})
 		return
 	}
+	claims := c.MustGet("user").(middleware.UserClaims)
+	h.events.Publish(ctx, events.PatientDeleted{
+		Actor:          claims.Email,
+		UserID:         userID,
+		PatientID:      id,
+		Photo:          photo,
+		AttachmentKeys: attachmentKeys,
+	})
 	c.JSON(http.StatusNoContent, nil)
 }
 
@@ -203,8 +464,8 @@ func (h *PatientsHandler) trend(c *gin.Context) {
 		return
 	}
 
-	// Verify patient exists and belongs to user
-	_, err = h.store.Patients().Get(c.Request.Context(), int32(id), userID)
+	// Verify patient exists and is owned by or shared with the user
+	_, err = h.store.Patients().GetVisible(c.Request.Context(), int32(id), userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
 		return
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// patientPatchReq carries a sparse patient update. Omitted fields (nil) keep
+// their stored value; fields sent explicitly, including zero values such as
+// "age": 0 or "smoking": "", overwrite it.
+type patientPatchReq struct {
+	Name            *string  `json:"name" binding:"omitempty,min=1,max=200"`
+	Age             *int     `json:"age" binding:"omitempty,gte=0,lte=150"`
+	MenopauseStatus *string  `json:"menopause_status"`
+	YearsMenopause  *int     `json:"years_menopause" binding:"omitempty,gte=0,lte=100"`
+	BMI             *float64 `json:"bmi" binding:"omitempty,gte=0,lte=100"`
+	BPSystolic      *int     `json:"bp_systolic" binding:"omitempty,gte=0,lte=300"`
+	BPDiastolic     *int     `json:"bp_diastolic" binding:"omitempty,gte=0,lte=200"`
+	Activity        *string  `json:"activity"`
+	PhysActivity    *bool    `json:"phys_activity"`
+	Smoking         *string  `json:"smoking"`
+	Hypertension    *string  `json:"hypertension"`
+	HeartDisease    *string  `json:"heart_disease"`
+	FamilyHistory   *bool    `json:"family_history"`
+	Chol            *int     `json:"chol" binding:"omitempty,gte=0,lte=1000"`
+	LDL             *int     `json:"ldl" binding:"omitempty,gte=0,lte=500"`
+	HDL             *int     `json:"hdl" binding:"omitempty,gte=0,lte=200"`
+	Triglycerides   *int     `json:"triglycerides" binding:"omitempty,gte=0,lte=2000"`
+	MRN             *string  `json:"mrn" binding:"omitempty,max=64"`
+	HeightCM        *float64 `json:"height_cm" binding:"omitempty,gte=0,lte=250"`
+	WeightKG        *float64 `json:"weight_kg" binding:"omitempty,gte=0,lte=400"`
+}
+
+// apply merges the non-nil fields into p and returns the JSON names of the
+// fields whose value actually changed.
+func (r patientPatchReq) apply(p *models.Patient) []string {
+	var changed []string
+	patchField(&changed, "name", &p.Name, r.Name)
+	patchField(&changed, "age", &p.Age, r.Age)
+	patchField(&changed, "menopause_status", &p.MenopauseStatus, r.MenopauseStatus)
+	patchField(&changed, "years_menopause", &p.YearsMenopause, r.YearsMenopause)
+	bmi := p.BMI
+	patchField(&changed, "bmi", &p.BMI, r.BMI)
+	patchField(&changed, "height_cm", &p.HeightCM, r.HeightCM)
+	patchField(&changed, "weight_kg", &p.WeightKG, r.WeightKG)
+	patchField(&changed, "bp_systolic", &p.BPSystolic, r.BPSystolic)
+	patchField(&changed, "bp_diastolic", &p.BPDiastolic, r.BPDiastolic)
+	patchField(&changed, "activity", &p.Activity, r.Activity)
+	patchField(&changed, "phys_activity", &p.PhysActivity, r.PhysActivity)
+	patchField(&changed, "smoking", &p.Smoking, r.Smoking)
+	patchField(&changed, "hypertension", &p.Hypertension, r.Hypertension)
+	patchField(&changed, "heart_disease", &p.HeartDisease, r.HeartDisease)
+	patchField(&changed, "family_history", &p.FamilyHistory, r.FamilyHistory)
+	patchField(&changed, "chol", &p.Chol, r.Chol)
+	patchField(&changed, "ldl", &p.LDL, r.LDL)
+	patchField(&changed, "hdl", &p.HDL, r.HDL)
+	patchField(&changed, "triglycerides", &p.Triglycerides, r.Triglycerides)
+	patchField(&changed, "mrn", &p.MRN, r.MRN)
+	return rederiveBMI(changed, bmi, &p.BMI, &p.BMISource, p.HeightCM, p.WeightKG)
+}
+
+// patch applies a sparse update to a patient without touching omitted fields
+func (h *PatientsHandler) patch(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	existing, err := h.store.Patients().Get(ctx, int32(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+
+	var req patientPatchReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	p := *existing
+	changed := req.apply(&p)
+	if len(changed) == 0 {
+		c.JSON(http.StatusOK, existing)
+		return
+	}
+
+	updated, err := h.store.Patients().Update(ctx, p)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to patch patient %d", id)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update patient"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "patient.patch",
+		TargetType: "patient",
+		TargetID:   int(id),
+		Details: map[string]interface{}{
+			"fields":  changed,
+			"changes": auditDiff(existing, updated),
+		},
+	})
+
+	c.JSON(http.StatusOK, updated)
+}
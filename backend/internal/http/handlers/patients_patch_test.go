@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestPatientsHandler_Patch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	stored := models.Patient{
+		ID: 5, UserID: 1, Name: "Maria Santos", Age: 52, MenopauseStatus: "post",
+		Smoking: "current", BMI: 27.5, FamilyHistory: true, Chol: 210,
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantCode   int
+		wantUpdate bool
+		check      func(t *testing.T, p *models.Patient)
+	}{
+		{
+			name:       "omitted fields keep stored values",
+			body:       `{"smoking":"former"}`,
+			wantCode:   http.StatusOK,
+			wantUpdate: true,
+			check: func(t *testing.T, p *models.Patient) {
+				if p.Smoking != "former" {
+					t.Errorf("smoking = %q, want former", p.Smoking)
+				}
+				if p.Name != stored.Name || p.Age != stored.Age || p.BMI != stored.BMI || p.Chol != stored.Chol || !p.FamilyHistory {
+					t.Errorf("unspecified fields changed: %+v", p)
+				}
+			},
+		},
+		{
+			name:       "explicit zero values overwrite",
+			body:       `{"family_history":false,"chol":0,"smoking":""}`,
+			wantCode:   http.StatusOK,
+			wantUpdate: true,
+			check: func(t *testing.T, p *models.Patient) {
+				if p.FamilyHistory || p.Chol != 0 || p.Smoking != "" {
+					t.Errorf("zero values not applied: %+v", p)
+				}
+				if p.MenopauseStatus != "post" || p.Age != 52 {
+					t.Errorf("unspecified fields changed: %+v", p)
+				}
+			},
+		},
+		{
+			name:       "height and weight recompute bmi",
+			body:       `{"height_cm":160,"weight_kg":64}`,
+			wantCode:   http.StatusOK,
+			wantUpdate: true,
+			check: func(t *testing.T, p *models.Patient) {
+				if p.BMI != 25 || p.BMISource != models.BMISourceComputed {
+					t.Errorf("bmi = %v (%q), want 25 computed", p.BMI, p.BMISource)
+				}
+			},
+		},
+		{name: "unchanged values skip the write", body: `{"age":52}`, wantCode: http.StatusOK},
+		{name: "empty name rejected", body: `{"name":""}`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := stored
+			repo := &fakePatientRepo{stored: &s}
+			h := NewPatientsHandler(&fakeStore{patientRepo: repo})
+
+			r := gin.New()
+			r.Use(mockAuthMiddleware())
+			h.Register(r.Group("/patients"))
+
+			req, _ := http.NewRequest(http.MethodPatch, "/patients/5", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, w.Code, w.Body.String())
+			}
+			if (repo.lastUpdate != nil) != tc.wantUpdate {
+				t.Fatalf("update called = %v, want %v", repo.lastUpdate != nil, tc.wantUpdate)
+			}
+			if tc.check != nil {
+				tc.check(t, repo.lastUpdate)
+			}
+		})
+	}
+}
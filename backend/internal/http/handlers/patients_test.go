@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestPatientsHandler_Typeahead(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCount int
+		wantQuery string
+	}{
+		{name: "short query skips lookup", query: "a", wantCount: 0, wantQuery: ""},
+		{name: "matches returned", query: "%20ma%20", wantCount: 2, wantQuery: "ma"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakePatientRepo{matches: []models.PatientMatch{
+				{ID: 1, Name: "Maria Santos", Age: 52, MRN: "MRN-001"},
+				{ID: 2, Name: "Mae Cruz", Age: 48},
+			}}
+			h := NewPatientsHandler(&fakeStore{patientRepo: repo})
+
+			r := gin.New()
+			r.Use(mockAuthMiddleware())
+			h.Register(r.Group("/patients"))
+
+			req, _ := http.NewRequest(http.MethodGet, "/patients/typeahead?q="+tc.query, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+			var got []models.PatientMatch
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid json: %v", err)
+			}
+			if len(got) != tc.wantCount {
+				t.Errorf("expected %d matches, got %d", tc.wantCount, len(got))
+			}
+			if repo.lastQuery != tc.wantQuery {
+				t.Errorf("repo query = %q, want %q", repo.lastQuery, tc.wantQuery)
+			}
+			if tc.wantQuery != "" && repo.lastLimit != typeaheadLimit {
+				t.Errorf("repo limit = %d, want %d", repo.lastLimit, typeaheadLimit)
+			}
+		})
+	}
+}
+
+func TestPatientsHandler_ListFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		check    func(t *testing.T, p models.PatientListParams)
+	}{
+		{
+			name:     "filters and sort bound",
+			query:    "?search=ana&min_age=45&max_age=60&menopause_status=post&cluster=SIRD&min_risk=50&sort=risk_score&order=desc",
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, p models.PatientListParams) {
+				if p.Search != "ana" || p.Cluster != "SIRD" || p.MenopauseStatus != "post" {
+					t.Errorf("unexpected string filters: %+v", p)
+				}
+				if p.MinAge == nil || *p.MinAge != 45 || p.MaxAge == nil || *p.MaxAge != 60 {
+					t.Errorf("unexpected age range: %v-%v", p.MinAge, p.MaxAge)
+				}
+				if p.MinRisk == nil || *p.MinRisk != 50 || p.MaxRisk != nil {
+					t.Errorf("unexpected risk range: %v-%v", p.MinRisk, p.MaxRisk)
+				}
+				if p.Sort != "risk_score" || p.Order != "desc" {
+					t.Errorf("unexpected sort: %s %s", p.Sort, p.Order)
+				}
+			},
+		},
+		{
+			name:     "pagination bound",
+			query:    "?page=3&page_size=50",
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, p models.PatientListParams) {
+				if p.Page != 3 || p.PageSize != 50 {
+					t.Errorf("unexpected pagination: page=%d page_size=%d", p.Page, p.PageSize)
+				}
+			},
+		},
+		{
+			name:     "pagination defaults",
+			query:    "",
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, p models.PatientListParams) {
+				if p.Page != 1 || p.PageSize != 20 {
+					t.Errorf("unexpected defaults: page=%d page_size=%d", p.Page, p.PageSize)
+				}
+			},
+		},
+		{name: "page size too large", query: "?page_size=500", wantCode: http.StatusBadRequest},
+		{name: "unknown sort field", query: "?sort=password", wantCode: http.StatusBadRequest},
+		{name: "inverted age range", query: "?min_age=70&max_age=40", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakePatientRepo{patients: []models.Patient{{ID: 1, Name: "Ana"}}}
+			h := NewPatientsHandler(&fakeStore{repo: &fakeAssessmentRepo{}, patientRepo: repo})
+
+			r := gin.New()
+			r.Use(mockAuthMiddleware())
+			h.Register(r.Group("/patients"))
+
+			req, _ := http.NewRequest(http.MethodGet, "/patients"+tc.query, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tc.wantCode, w.Code, w.Body.String())
+			}
+			if tc.check != nil {
+				tc.check(t, repo.lastParams)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+	"github.com/skufu/DianaV2/backend/internal/worker"
+)
+
+const (
+	predictionWorkers   = 4
+	predictionQueueSize = 256
+	predictionSweepSize = 100
+	// predictionActor is recorded as the actor of predictions recovered by
+	// the sweep, whose requester is no longer known
+	predictionActor = "system:predictions"
+)
+
+// PredictionQueue scores assessments stored as pending_prediction in the
+// background, for model deployments too slow to wait on in a request. The
+// pending rows are the queue: assessments enqueued by a server that stopped
+// before scoring them are picked up by the periodic sweep. When a prediction
+// completes, assessment.created is published and the webhook, if any, is
+// notified.
+type PredictionQueue struct {
+	store     store.Store
+	predictor ml.Predictor
+	events    *events.Bus
+	webhook   string
+	client    *http.Client
+	jobs      chan predictionJob
+
+	mu       sync.Mutex
+	inFlight map[int64]bool
+}
+
+type predictionJob struct {
+	assessment models.Assessment
+	actor      string
+	userID     int32
+}
+
+// predictionNotice is the webhook body sent when a prediction completes
+type predictionNotice struct {
+	Event        string `json:"event"`
+	AssessmentID int64  `json:"assessment_id"`
+	PatientID    int64  `json:"patient_id"`
+	Cluster      string `json:"cluster"`
+	RiskScore    int    `json:"risk_score"`
+	ModelVersion string `json:"model_version,omitempty"`
+}
+
+func NewPredictionQueue(store store.Store, predictor ml.Predictor, webhookURL string) *PredictionQueue {
+	return &PredictionQueue{
+		store:     store,
+		predictor: predictor,
+		webhook:   webhookURL,
+		client:    &http.Client{Timeout: 5 * time.Second},
+		jobs:      make(chan predictionJob, predictionQueueSize),
+		inFlight:  map[int64]bool{},
+	}
+}
+
+// WithEvents publishes assessment.created once each prediction completes
+func (q *PredictionQueue) WithEvents(bus *events.Bus) *PredictionQueue {
+	q.events = bus
+	return q
+}
+
+// Start runs the prediction workers and the sweep under m.
+func (q *PredictionQueue) Start(m *worker.Manager) {
+	for i := 0; i < predictionWorkers; i++ {
+		m.Go("predictions", q.run)
+	}
+	m.Every("prediction-sweep", time.Minute, true, q.Sweep)
+}
+
+// Enqueue schedules a stored pending assessment for prediction. a must carry
+// the patient's age. When the queue is full the assessment is left for the
+// sweep.
+func (q *PredictionQueue) Enqueue(a models.Assessment, actor string, userID int32) {
+	if !q.claim(a.ID) {
+		return
+	}
+	select {
+	case q.jobs <- predictionJob{assessment: a, actor: actor, userID: userID}:
+	default:
+		q.release(a.ID)
+		log.Warn().Msgf("Prediction queue full; assessment %d left for the sweep", a.ID)
+	}
+}
+
+// Sweep enqueues pending assessments that are not already queued, such as
+// those left behind by a restart.
+func (q *PredictionQueue) Sweep(ctx context.Context) error {
+	pending, err := q.store.Assessments().ListPendingPrediction(ctx, predictionSweepSize)
+	if err != nil {
+		return err
+	}
+	for _, a := range pending {
+		owner, err := q.store.Patients().Owner(ctx, a.PatientID)
+		if err != nil {
+			logging.Ctx(ctx).Error().Err(err).Msgf("Pending assessment %d: patient %d", a.ID, a.PatientID)
+			continue
+		}
+		if patient, err := q.store.Patients().Get(ctx, int32(a.PatientID), owner); err == nil {
+			a.PatientAge = patient.Age
+		}
+		a.OnMedication = onMedication(ctx, q.store, a.PatientID, a.CreatedAt)
+		q.Enqueue(a, predictionActor, owner)
+	}
+	return nil
+}
+
+func (q *PredictionQueue) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-q.jobs:
+			if err := q.predict(ctx, job); err != nil && ctx.Err() == nil {
+				logging.Ctx(ctx).Error().Err(err).Msgf("Failed to predict assessment %d", job.assessment.ID)
+			}
+			q.release(job.assessment.ID)
+		}
+	}
+}
+
+// predict scores one assessment and stores the result. An assessment that
+// was re-scored or deleted while queued is skipped.
+func (q *PredictionQueue) predict(ctx context.Context, job predictionJob) error {
+	a := job.assessment
+	explanation := ml.Score(ctx, q.predictor, &a, true)
+	err := q.store.Assessments().CompletePrediction(ctx, a)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if explanation != nil {
+		if err := q.store.Assessments().SetExplanation(ctx, int32(a.ID), explanation); err != nil {
+			logging.Ctx(ctx).Error().Err(err).Msgf("Failed to store explanation for assessment %d", a.ID)
+		}
+	}
+	q.events.Publish(ctx, events.AssessmentCreated{Actor: job.actor, UserID: job.userID, Assessment: a})
+	if err := q.notify(ctx, a); err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Prediction webhook for assessment %d", a.ID)
+	}
+	return nil
+}
+
+// notify POSTs the completed prediction to the webhook, if one is set
+func (q *PredictionQueue) notify(ctx context.Context, a models.Assessment) error {
+	if q.webhook == "" {
+		return nil
+	}
+	body, err := json.Marshal(predictionNotice{
+		Event:        "assessment.predicted",
+		AssessmentID: a.ID,
+		PatientID:    a.PatientID,
+		Cluster:      a.Cluster,
+		RiskScore:    a.RiskScore,
+		ModelVersion: a.ModelVersion,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, q.webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// claim marks id as queued, reporting false if it already was
+func (q *PredictionQueue) claim(id int64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.inFlight[id] {
+		return false
+	}
+	q.inFlight[id] = true
+	return true
+}
+
+func (q *PredictionQueue) release(id int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.inFlight, id)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestAssessmentsHandler_Create_AsyncPrediction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var notices []predictionNotice
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n predictionNotice
+		_ = json.NewDecoder(r.Body).Decode(&n)
+		notices = append(notices, n)
+	}))
+	defer hook.Close()
+
+	repo := &fakeAssessmentRepo{}
+	st := &fakeStore{repo: repo, patientRepo: &fakePatientRepo{}}
+	bus := events.NewBus()
+	var published []events.AssessmentCreated
+	events.Subscribe(bus, "test", func(ctx context.Context, e events.AssessmentCreated) error {
+		published = append(published, e)
+		return nil
+	})
+	queue := NewPredictionQueue(st, ml.NewMockPredictor(), hook.URL).WithEvents(bus)
+	h := NewAssessmentsHandler(st, ml.NewMockPredictor(), "v1", "hash123").WithEvents(bus).WithAsyncPredictions(queue)
+
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	r.POST("/:id/assessments", h.create)
+
+	req, _ := http.NewRequest(http.MethodPost, "/4/assessments", bytes.NewBufferString(`{"fbs":110,"hba1c":6.1,"bmi":25}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Location") != "/api/v1/patients/4/assessments/1" {
+		t.Errorf("unexpected Location %q", w.Header().Get("Location"))
+	}
+	if repo.last.Cluster != models.ClusterPendingPrediction || repo.last.RiskScore != 0 {
+		t.Fatalf("expected a pending assessment stored, got cluster=%s risk=%d", repo.last.Cluster, repo.last.RiskScore)
+	}
+	if len(published) != 0 {
+		t.Fatalf("expected no event before the prediction, got %d", len(published))
+	}
+
+	// Work the queued job as a worker would
+	repo.all = []models.Assessment{repo.last}
+	repo.all[0].ID = 1
+	job := <-queue.jobs
+	if err := queue.predict(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+	queue.release(job.assessment.ID)
+
+	if repo.all[0].Cluster != "MARD" || repo.all[0].RiskScore != 45 {
+		t.Fatalf("expected the prediction stored, got cluster=%s risk=%d", repo.all[0].Cluster, repo.all[0].RiskScore)
+	}
+	if len(published) != 1 || published[0].Assessment.RiskScore != 45 {
+		t.Fatalf("expected assessment.created with the prediction, got %+v", published)
+	}
+	if len(notices) != 1 || notices[0].Event != "assessment.predicted" || notices[0].AssessmentID != 1 || notices[0].Cluster != "MARD" {
+		t.Fatalf("unexpected webhook notices %+v", notices)
+	}
+
+	// A completed assessment is not predicted again
+	if err := queue.Sweep(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(queue.jobs) != 0 {
+		t.Fatalf("expected nothing left to sweep, got %d jobs", len(queue.jobs))
+	}
+}
+
+func TestPredictionQueue_SweepRecoversPending(t *testing.T) {
+	repo := &fakeAssessmentRepo{all: []models.Assessment{
+		{ID: 7, PatientID: 3, Cluster: models.ClusterPendingPrediction},
+		{ID: 8, PatientID: 3, Cluster: "MOD", RiskScore: 30},
+	}}
+	queue := NewPredictionQueue(&fakeStore{repo: repo, patientRepo: &fakePatientRepo{}}, ml.NewMockPredictor(), "")
+
+	// Already queued assessments are not queued twice
+	for i := 0; i < 2; i++ {
+		if err := queue.Sweep(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(queue.jobs) != 1 {
+		t.Fatalf("expected one job, got %d", len(queue.jobs))
+	}
+	job := <-queue.jobs
+	if job.assessment.ID != 7 || job.actor != predictionActor || job.userID != 1 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+}
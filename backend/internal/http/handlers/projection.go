@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/stats"
+)
+
+// projectionHorizons are the months after the latest assessment that are
+// projected.
+var projectionHorizons = []int{3, 6, 12}
+
+// projectionLevel is the coverage of the projected bounds.
+const projectionLevel = 0.95
+
+// daysPerMonth converts elapsed time to months for fitting.
+const daysPerMonth = 365.25 / 12
+
+type projectionQuery struct {
+	Model string `form:"model" binding:"omitempty,oneof=linear exponential"`
+}
+
+// projectedMetric is one metric to project, with the range its values can take.
+type projectedMetric struct {
+	name     string
+	value    func(models.AssessmentTrend) (float64, bool)
+	min, max float64
+}
+
+var projectedMetrics = []projectedMetric{
+	{"hba1c", func(t models.AssessmentTrend) (float64, bool) { return t.HbA1c, t.HbA1c > 0 }, 0, math.Inf(1)},
+	{"fbs", func(t models.AssessmentTrend) (float64, bool) { return t.FBS, t.FBS > 0 }, 0, math.Inf(1)},
+	{"risk_score", func(t models.AssessmentTrend) (float64, bool) {
+		if t.RiskScore == nil {
+			return 0, false
+		}
+		return *t.RiskScore, true
+	}, 0, 1},
+}
+
+// projection fits a trend over the patient's assessments and projects HbA1c,
+// FBS and risk score 3, 6 and 12 months past the latest assessment
+// @Summary Project a patient's risk trajectory
+// @Description Fits a linear or exponential trend per metric and returns projected values with 95% prediction intervals
+// @Tags Patients
+// @Produce json
+// @Param id path int true "Patient ID"
+// @Param model query string false "linear or exponential" default(linear)
+// @Success 200 {object} models.PatientProjection
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /patients/{id}/projection [get]
+func (h *PatientsHandler) projection(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	var q projectionQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "model must be 'linear' or 'exponential'"})
+		return
+	}
+	if q.Model == "" {
+		q.Model = "linear"
+	}
+	if _, err := h.store.Patients().GetVisible(c.Request.Context(), int32(id), userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		return
+	}
+
+	trend, err := h.store.Assessments().GetTrend(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get trend data"})
+		return
+	}
+	c.JSON(http.StatusOK, projectTrend(id, trend, q.Model == "exponential"))
+}
+
+// projectTrend fits each metric against months since the first assessment.
+// An exponential model fits the logarithm of the values, so its bounds are
+// asymmetric. trend must be in date order, as GetTrend returns it.
+func projectTrend(patientID int64, trend []models.AssessmentTrend, exponential bool) models.PatientProjection {
+	out := models.PatientProjection{
+		PatientID: patientID,
+		Model:     "linear",
+		Level:     projectionLevel,
+		Metrics:   map[string]models.MetricProjection{},
+	}
+	if exponential {
+		out.Model = "exponential"
+	}
+	if len(trend) == 0 {
+		for _, m := range projectedMetrics {
+			out.Metrics[m.name] = models.MetricProjection{Reason: "needs at least 3 assessments at different times"}
+		}
+		return out
+	}
+	first, last := trend[0].CreatedAt, trend[len(trend)-1].CreatedAt
+	out.From = &last
+	months := func(t time.Time) float64 { return t.Sub(first).Hours() / 24 / daysPerMonth }
+
+	for _, m := range projectedMetrics {
+		var xs, ys []float64
+		positive := true
+		for _, t := range trend {
+			v, ok := m.value(t)
+			if !ok {
+				continue
+			}
+			positive = positive && v > 0
+			xs = append(xs, months(t.CreatedAt))
+			ys = append(ys, v)
+		}
+		proj := models.MetricProjection{N: len(xs)}
+		if exponential && !positive {
+			proj.Reason = "exponential model needs positive values"
+			out.Metrics[m.name] = proj
+			continue
+		}
+		if exponential {
+			for i := range ys {
+				ys[i] = math.Log(ys[i])
+			}
+		}
+		fit, ok := stats.FitLine(xs, ys)
+		if !ok {
+			proj.Reason = "needs at least 3 assessments at different times"
+			out.Metrics[m.name] = proj
+			continue
+		}
+
+		rate := fit.Slope
+		if exponential {
+			rate = math.Exp(fit.Slope) - 1
+		}
+		rate = round3(rate)
+		proj.RatePerMonth = &rate
+		for _, h := range projectionHorizons {
+			date := last.AddDate(0, h, 0)
+			x := months(date)
+			value := fit.Predict(x)
+			lower, upper := fit.PredictionInterval(x, projectionLevel)
+			if exponential {
+				value, lower, upper = math.Exp(value), math.Exp(lower), math.Exp(upper)
+			}
+			proj.Projections = append(proj.Projections, models.ProjectedValue{
+				Months: h,
+				Date:   date,
+				Value:  round3(clamp(value, m.min, m.max)),
+				Lower:  round3(clamp(lower, m.min, m.max)),
+				Upper:  round3(clamp(upper, m.min, m.max)),
+			})
+		}
+		out.Metrics[m.name] = proj
+	}
+	return out
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
+
+// round3 keeps risk scores, which are fractions, meaningful while trimming
+// noise from the other metrics.
+func round3(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
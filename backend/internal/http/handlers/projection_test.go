@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestPatientProjection(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	risk := func(v float64) *float64 { return &v }
+	var trend []models.AssessmentTrend
+	for i, hba1c := range []float64{6.0, 6.32, 6.58, 6.91} {
+		at := start.AddDate(0, 0, int(float64(i)*3*daysPerMonth))
+		trend = append(trend, models.AssessmentTrend{CreatedAt: at, HbA1c: hba1c, FBS: 110, RiskScore: risk(0.4 + 0.2*float64(i))})
+	}
+	// The second visit had no FBS recorded
+	trend[1].FBS = 0
+	r := baselineRouter(&fakeStore{patientRepo: &fakePatientRepo{}, repo: &fakeAssessmentRepo{trend: trend}})
+
+	w := contactRequest(r, http.MethodGet, "/patients/7/projection", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp models.PatientProjection
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != "linear" || resp.From == nil || !resp.From.Equal(trend[3].CreatedAt) {
+		t.Fatalf("unexpected header: %+v", resp)
+	}
+
+	hba1c := resp.Metrics["hba1c"]
+	if hba1c.N != 4 || hba1c.RatePerMonth == nil || math.Abs(*hba1c.RatePerMonth-0.1) > 0.01 {
+		t.Fatalf("expected about +0.1 HbA1c a month, got %+v", hba1c)
+	}
+	if len(hba1c.Projections) != 3 || hba1c.Projections[2].Months != 12 {
+		t.Fatalf("expected 3, 6 and 12 month projections, got %+v", hba1c.Projections)
+	}
+	p := hba1c.Projections[0]
+	if math.Abs(p.Value-7.2) > 0.05 || p.Lower >= p.Value || p.Upper <= p.Value {
+		t.Fatalf("unexpected 3 month projection: %+v", p)
+	}
+	if far := hba1c.Projections[2]; far.Upper-far.Lower <= p.Upper-p.Lower {
+		t.Fatalf("expected bounds to widen with the horizon: %+v", hba1c.Projections)
+	}
+
+	if fbs := resp.Metrics["fbs"]; fbs.N != 3 || len(fbs.Projections) != 3 {
+		t.Fatalf("expected FBS fitted on the 3 recorded values, got %+v", fbs)
+	}
+	for _, v := range resp.Metrics["risk_score"].Projections {
+		if v.Value > 1 || v.Upper > 1 {
+			t.Fatalf("risk score projected past 1: %+v", v)
+		}
+	}
+
+	w = contactRequest(r, http.MethodGet, "/patients/7/projection?model=exponential", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Model != "exponential" {
+		t.Fatalf("expected an exponential projection, got %d: %s", w.Code, w.Body.String())
+	}
+	if p := resp.Metrics["hba1c"].Projections[0]; p.Value-p.Lower >= p.Upper-p.Value {
+		t.Fatalf("expected log-scale bounds to skew upward: %+v", p)
+	}
+
+	if w := contactRequest(r, http.MethodGet, "/patients/7/projection?model=cubic", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown model: expected 400, got %d", w.Code)
+	}
+}
+
+func TestPatientProjection_TooFewAssessments(t *testing.T) {
+	trend := []models.AssessmentTrend{
+		{CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), HbA1c: 6.1},
+		{CreatedAt: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), HbA1c: 6.4},
+	}
+	r := baselineRouter(&fakeStore{patientRepo: &fakePatientRepo{}, repo: &fakeAssessmentRepo{trend: trend}})
+
+	w := contactRequest(r, http.MethodGet, "/patients/7/projection", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp models.PatientProjection
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	for name, m := range resp.Metrics {
+		if m.Reason == "" || m.Projections != nil {
+			t.Fatalf("%s: expected no projection, got %+v", name, m)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/pdf"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// reportGenerator returns a generator in the user's units and date format,
+// branded for the clinic the patient is shared with or, failing that, the
+// user's lowest-numbered clinic. The report is in the user's locale when
+// they chose one, otherwise the clinic's. Any lookup failure falls back to
+// an unbranded English report so a report is never blocked by its branding.
+func reportGenerator(ctx context.Context, st store.Store, userID int32, patient models.Patient) *pdf.ReportGenerator {
+	prefs := userPreferences(ctx, st, userID)
+	generator := pdf.NewReportGenerator("").WithUnits(prefs.Units).WithDateFormat(prefs.DateFormat)
+	if settings := reportSettings(ctx, st, userID, patient); settings != nil {
+		generator.WithLocale(settings.Locale).WithBranding(pdf.Branding{
+			ClinicName:    settings.Name,
+			ClinicAddress: settings.Address,
+			Logo:          settings.Logo,
+		})
+	}
+	if prefs.Locale != "" {
+		generator.WithLocale(prefs.Locale)
+	}
+	return generator
+}
+
+// reportSettings loads the report settings of the clinic a report on
+// patient is branded for, or returns nil if there is none.
+func reportSettings(ctx context.Context, st store.Store, userID int32, patient models.Patient) *models.ClinicReportSettings {
+	var clinicID int64
+	if patient.ClinicID != nil {
+		clinicID = *patient.ClinicID
+	} else {
+		id, ok, err := lowestClinic(ctx, st, userID)
+		if err != nil {
+			logging.Ctx(ctx).Error().Err(err).Msgf("Failed to resolve report clinic for user %d", userID)
+		}
+		if !ok {
+			return nil
+		}
+		clinicID = id
+	}
+
+	settings, err := st.Clinics().GetReportSettings(ctx, int32(clinicID))
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to load report settings for clinic %d", clinicID)
+		return nil
+	}
+	return settings
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// hba1cDiabeticCutoff is the ADA diagnostic threshold (%) that always alerts.
+const hba1cDiabeticCutoff = 6.5
+
+// RiskAlerter queues risk alerts for newly created assessments. An alert is
+// queued when HbA1c is in the diabetic range or the risk score reaches
+// threshold (0 disables the score criterion). A patient alerted within
+// cooldown is not alerted again.
+type RiskAlerter struct {
+	store     store.Store
+	threshold int
+	cooldown  time.Duration
+	events    *events.Bus
+}
+
+func NewRiskAlerter(store store.Store, threshold int, cooldown time.Duration) *RiskAlerter {
+	return &RiskAlerter{store: store, threshold: threshold, cooldown: cooldown}
+}
+
+// Subscribe raises alerts for assessment.created events on bus, and
+// publishes risk_alert.raised on it for each alert queued.
+func (r *RiskAlerter) Subscribe(bus *events.Bus) {
+	r.events = bus
+	events.Subscribe(bus, "risk_alerts", func(ctx context.Context, e events.AssessmentCreated) error {
+		r.raise(ctx, e.UserID, e.Assessment)
+		return nil
+	})
+}
+
+// riskAlertReasons lists which alert criteria an assessment meets.
+func riskAlertReasons(a models.Assessment, threshold int) []string {
+	var reasons []string
+	if a.HbA1c >= hba1cDiabeticCutoff {
+		reasons = append(reasons, models.RiskAlertHbA1c)
+	}
+	if threshold > 0 && a.RiskScore >= threshold {
+		reasons = append(reasons, models.RiskAlertRiskScore)
+	}
+	return reasons
+}
+
+// raise queues an alert for the owning clinician if the assessment meets the
+// criteria and the patient is outside the cooldown window. The patient is
+// added as a recipient on the channels they consented to. Failures are
+// logged only: the assessment is already stored.
+func (r *RiskAlerter) raise(ctx context.Context, userID int32, a models.Assessment) {
+	reasons := riskAlertReasons(a, r.threshold)
+	if len(reasons) == 0 {
+		return
+	}
+
+	alerts := r.store.RiskAlerts()
+	last, err := alerts.LastForPatient(ctx, a.PatientID)
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to check risk alert cooldown for patient %d", a.PatientID)
+		return
+	}
+	if last != nil && time.Since(last.CreatedAt) < r.cooldown {
+		return
+	}
+
+	contact, err := r.store.PatientContacts().Get(ctx, a.PatientID)
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to load contact for patient %d", a.PatientID)
+	}
+
+	alert, err := alerts.Enqueue(ctx, models.RiskAlert{
+		UserID:          int64(userID),
+		PatientID:       a.PatientID,
+		AssessmentID:    a.ID,
+		Reasons:         reasons,
+		RiskScore:       a.RiskScore,
+		HbA1c:           a.HbA1c,
+		PatientChannels: contact.Channels(),
+	})
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to queue risk alert for assessment %d", a.ID)
+		return
+	}
+	r.events.Publish(ctx, events.RiskAlertRaised{Alert: *alert})
+}
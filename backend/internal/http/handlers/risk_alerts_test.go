@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+type fakeRiskAlertRepo struct {
+	last   *models.RiskAlert
+	queued []models.RiskAlert
+}
+
+func (f *fakeRiskAlertRepo) Enqueue(ctx context.Context, a models.RiskAlert) (*models.RiskAlert, error) {
+	a.ID = int64(len(f.queued) + 1)
+	a.CreatedAt = time.Now()
+	f.queued = append(f.queued, a)
+	f.last = &a
+	return &a, nil
+}
+
+func (f *fakeRiskAlertRepo) LastForPatient(ctx context.Context, patientID int64) (*models.RiskAlert, error) {
+	return f.last, nil
+}
+
+// alertingAssessmentsHandler wires a RiskAlerter (threshold 67, 24h cooldown)
+// to the handler's events
+func alertingAssessmentsHandler(st *fakeStore) *AssessmentsHandler {
+	bus := events.NewBus()
+	NewRiskAlerter(st, 67, 24*time.Hour).Subscribe(bus)
+	return NewAssessmentsHandler(st, ml.NewMockPredictor(), "v1", "hash123").WithEvents(bus)
+}
+
+func TestRiskAlertReasons(t *testing.T) {
+	cases := []struct {
+		name      string
+		a         models.Assessment
+		threshold int
+		want      []string
+	}{
+		{"below both", models.Assessment{HbA1c: 5.9, RiskScore: 40}, 67, nil},
+		{"diabetic hba1c", models.Assessment{HbA1c: 6.5, RiskScore: 40}, 67, []string{models.RiskAlertHbA1c}},
+		{"score at threshold", models.Assessment{HbA1c: 5.5, RiskScore: 67}, 67, []string{models.RiskAlertRiskScore}},
+		{"both", models.Assessment{HbA1c: 7.1, RiskScore: 92}, 67, []string{models.RiskAlertHbA1c, models.RiskAlertRiskScore}},
+		{"score criterion disabled", models.Assessment{HbA1c: 5.5, RiskScore: 99}, 0, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := riskAlertReasons(tc.a, tc.threshold)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("got %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAssessmentsHandler_Create_RiskAlert(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	highRisk := `{"fbs":140,"hba1c":7.2,"bmi":24}`
+	lowRisk := `{"fbs":90,"hba1c":5.2,"bmi":22}`
+
+	cases := []struct {
+		name       string
+		body       string
+		last       *models.RiskAlert
+		wantQueued int
+	}{
+		{name: "high risk queues alert", body: highRisk, wantQueued: 1},
+		{name: "low risk does not alert", body: lowRisk, wantQueued: 0},
+		{name: "recent alert suppresses repeat", body: highRisk, last: &models.RiskAlert{CreatedAt: time.Now().Add(-2 * time.Hour)}, wantQueued: 0},
+		{name: "alert outside cooldown fires again", body: highRisk, last: &models.RiskAlert{CreatedAt: time.Now().Add(-25 * time.Hour)}, wantQueued: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			alerts := &fakeRiskAlertRepo{last: tc.last}
+			st := &fakeStore{repo: &fakeAssessmentRepo{}, patientRepo: &fakePatientRepo{}, alerts: alerts}
+			h := alertingAssessmentsHandler(st)
+
+			r := gin.New()
+			r.Use(mockAuthMiddleware())
+			h.Register(r.Group("/patients"))
+
+			req, _ := http.NewRequest(http.MethodPost, "/patients/9/assessments", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+			}
+			if len(alerts.queued) != tc.wantQueued {
+				t.Fatalf("queued %d alerts, want %d", len(alerts.queued), tc.wantQueued)
+			}
+			if tc.wantQueued > 0 {
+				a := alerts.queued[0]
+				if a.PatientID != 9 || a.UserID != 1 || a.AssessmentID != 1 {
+					t.Errorf("unexpected alert %+v", a)
+				}
+			}
+		})
+	}
+}
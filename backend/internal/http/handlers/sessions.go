@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// ActiveUserLookup adapts the store to middleware.ActiveUserLookup: the user
+// must exist and be active, and a named session must still have an active
+// refresh token.
+func ActiveUserLookup(st store.Store) middleware.ActiveUserLookup {
+	return func(ctx context.Context, userID int64, sessionID string) (bool, error) {
+		user, err := st.Users().FindByID(ctx, int32(userID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !user.IsActive {
+			return false, nil
+		}
+		if sessionID == "" {
+			return true, nil
+		}
+		sessions, err := st.RefreshTokens().ListUserSessions(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		for _, s := range sessions {
+			if s.ID == sessionID {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// SessionsHandler lets users see where they are signed in and sign out
+// other devices
+type SessionsHandler struct {
+	store store.Store
+}
+
+// NewSessionsHandler creates a new SessionsHandler
+func NewSessionsHandler(store store.Store) *SessionsHandler {
+	return &SessionsHandler{store: store}
+}
+
+// Register registers the session routes
+func (h *SessionsHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("", h.list)
+	rg.DELETE("", h.revokeOthers)
+	rg.DELETE("/:id", h.revoke)
+}
+
+// list returns the caller's active sessions, marking the one making the
+// request as current.
+// @Summary List my sessions
+// @Tags Users
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /users/sessions [get]
+func (h *SessionsHandler) list(c *gin.Context) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	sessions, err := h.store.RefreshTokens().ListUserSessions(c.Request.Context(), claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = claims.SessionID != "" && sessions[i].ID == claims.SessionID
+	}
+	c.JSON(http.StatusOK, gin.H{"data": sessions})
+}
+
+// revoke signs one session out. Its access token stays valid until it
+// expires unless AUTH_ACTIVE_CHECK refuses it sooner.
+// @Summary Sign out one session
+// @Tags Users
+// @Param id path string true "Session ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /users/sessions/{id} [delete]
+func (h *SessionsHandler) revoke(c *gin.Context) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	id := c.Param("id")
+	n, err := h.store.RefreshTokens().RevokeUserSession(c.Request.Context(), claims.UserID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+	if n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "auth.session_revoked",
+		TargetType: "user",
+		TargetID:   int(claims.UserID),
+		Details: map[string]interface{}{
+			"session_id": id,
+			"current":    id == claims.SessionID,
+		},
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// revokeOthers signs out every session except the caller's. A caller
+// without a session (an API key or an older token) signs out all of them.
+// @Summary Sign out all other sessions
+// @Tags Users
+// @Produce json
+// @Success 200 {object} map[string]int
+// @Router /users/sessions [delete]
+func (h *SessionsHandler) revokeOthers(c *gin.Context) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	n, err := h.store.RefreshTokens().RevokeOtherUserSessions(c.Request.Context(), claims.UserID, claims.SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+	if n > 0 {
+		_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+			Actor:      claims.Email,
+			Action:     "auth.sessions_revoked",
+			TargetType: "user",
+			TargetID:   int(claims.UserID),
+			Details:    map[string]interface{}{"revoked": n},
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"revoked": n})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/skufu/DianaV2/backend/internal/config"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+func TestSessions_ListAndRevoke(t *testing.T) {
+	mem := store.NewMemoryStore()
+	audit := &fakeAuditRepo{}
+	st := &fakeStore{
+		users:  &fakeUserRepo{user: mfaTestUser(t, 7, "clinician")},
+		tokens: mem.RefreshTokens(),
+		audit:  audit,
+	}
+	r := authRouter(config.Config{}, st, &fakeMailer{})
+	sessions := r.Group("/users/sessions")
+	sessions.Use(middleware.Auth("test"))
+	NewSessionsHandler(st).Register(sessions)
+
+	login := func(userAgent string) (access, refresh string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, "/auth/login", bytes.NewBufferString(`{"email":"doc@example.com","password":"secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp struct {
+			AccessToken  string `json:"access_token"`
+			RefreshToken string `json:"refresh_token"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusOK {
+			t.Fatalf("login: expected 200, got %d %s", w.Code, w.Body.String())
+		}
+		return resp.AccessToken, resp.RefreshToken
+	}
+	call := func(method, path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	list := func(token string) []models.Session {
+		t.Helper()
+		w := call(http.MethodGet, "/users/sessions", token)
+		var resp struct {
+			Data []models.Session `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusOK {
+			t.Fatalf("list: expected 200, got %d", w.Code)
+		}
+		return resp.Data
+	}
+
+	_, laptopRefresh := login("Laptop")
+	login("Phone")
+	tablet, _ := login("Tablet")
+
+	// A refresh keeps the session and the client it signed in from
+	if w := postJSON(r, "/auth/refresh", `{"refresh_token":"`+laptopRefresh+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("refresh: expected 200, got %d", w.Code)
+	}
+	got := list(tablet)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 sessions, got %+v", got)
+	}
+	// Most recently active first
+	if got[0].UserAgent != "Laptop" || got[0].Current || got[1].UserAgent != "Tablet" || !got[1].Current {
+		t.Fatalf("unexpected sessions %+v", got)
+	}
+
+	if w := call(http.MethodDelete, "/users/sessions/nope", tablet); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown session: expected 404, got %d", w.Code)
+	}
+	if w := call(http.MethodDelete, "/users/sessions/"+got[2].ID, tablet); w.Code != http.StatusNoContent {
+		t.Fatalf("revoke: expected 204, got %d", w.Code)
+	}
+
+	// Everywhere except here
+	w := call(http.MethodDelete, "/users/sessions", tablet)
+	if w.Code != http.StatusOK || w.Body.String() != `{"revoked":1}` {
+		t.Fatalf("revoke others: unexpected %d %s", w.Code, w.Body.String())
+	}
+	if got := list(tablet); len(got) != 1 || !got[0].Current {
+		t.Fatalf("expected only the current session, got %+v", got)
+	}
+	if len(audit.events) != 2 || audit.events[0].Action != "auth.session_revoked" || audit.events[1].Action != "auth.sessions_revoked" {
+		t.Fatalf("unexpected audit events %+v", audit.events)
+	}
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// tagRequest is the body of a tag create or rename
+type tagRequest struct {
+	Name string `json:"name"`
+}
+
+// name returns the trimmed name and the problem with it, or "" if it is valid
+func (r tagRequest) name() (string, string) {
+	name := strings.TrimSpace(r.Name)
+	switch {
+	case name == "":
+		return name, "name is required"
+	case utf8.RuneCountInString(name) > 60:
+		return name, "name must be at most 60 characters"
+	}
+	return name, ""
+}
+
+// TagsHandler manages a clinician's tags, the labels behind ad-hoc cohorts.
+// Tags are private to the clinician who made them.
+type TagsHandler struct {
+	store store.Store
+}
+
+func NewTagsHandler(store store.Store) *TagsHandler {
+	return &TagsHandler{store: store}
+}
+
+func (h *TagsHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("", h.list)
+	rg.POST("", h.create)
+	rg.PATCH("/:tagID", h.rename)
+	rg.DELETE("/:tagID", h.delete)
+}
+
+// audit records a tag change. Names are free text and left out.
+func (h *TagsHandler) audit(c *gin.Context, action string, tagID int64) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     action,
+		TargetType: "tag",
+		TargetID:   int(tagID),
+	})
+}
+
+// list returns the caller's tags by name, with how many patients carry each
+func (h *TagsHandler) list(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	tags, err := h.store.Tags().List(c.Request.Context(), int64(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tags"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tags})
+}
+
+func (h *TagsHandler) create(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req tagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	name, msg := req.name()
+	if msg != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+		return
+	}
+	tag, err := h.store.Tags().Create(c.Request.Context(), models.Tag{UserID: int64(userID), Name: name})
+	if errors.Is(err, store.ErrConflict) {
+		abortWithError(c, err, "you already have a tag with that name")
+		return
+	}
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to create tag for user %d", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create tag"})
+		return
+	}
+	h.audit(c, "tag.create", tag.ID)
+	c.JSON(http.StatusCreated, tag)
+}
+
+func (h *TagsHandler) rename(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	tagID, err := parseIDParam(c, "tagID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag ID"})
+		return
+	}
+	var req tagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	name, msg := req.name()
+	if msg != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+		return
+	}
+	tag, err := h.store.Tags().Rename(c.Request.Context(), tagID, int64(userID), name)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		abortWithError(c, err, "tag not found")
+		return
+	case errors.Is(err, store.ErrConflict):
+		abortWithError(c, err, "you already have a tag with that name")
+		return
+	case err != nil:
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to rename tag %d", tagID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rename tag"})
+		return
+	}
+	h.audit(c, "tag.update", tag.ID)
+	c.JSON(http.StatusOK, tag)
+}
+
+// delete removes the tag from every patient carrying it
+func (h *TagsHandler) delete(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	tagID, err := parseIDParam(c, "tagID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag ID"})
+		return
+	}
+	err = h.store.Tags().Delete(c.Request.Context(), tagID, int64(userID))
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "tag not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete tag"})
+		return
+	}
+	h.audit(c, "tag.delete", tagID)
+	c.Status(http.StatusNoContent)
+}
+
+// ownTag loads the caller's :tagID tag for a patient tag route
+func (h *PatientsHandler) ownTag(c *gin.Context) (*models.Tag, bool) {
+	userID, _ := getUserID(c)
+	tagID, err := parseIDParam(c, "tagID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag ID"})
+		return nil, false
+	}
+	tag, err := h.store.Tags().Get(c.Request.Context(), tagID, int64(userID))
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "tag not found")
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load tag"})
+		return nil, false
+	}
+	return tag, true
+}
+
+// auditPatientTag records a patient being tagged or untagged
+func (h *PatientsHandler) auditPatientTag(c *gin.Context, action string, patientID, tagID int64) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     action,
+		TargetType: "patient",
+		TargetID:   int(patientID),
+		Details:    map[string]interface{}{"tag_id": tagID},
+	})
+}
+
+// listPatientTags returns the caller's tags on the patient
+func (h *PatientsHandler) listPatientTags(c *gin.Context) {
+	patientID, ok := h.patientParam(c, false)
+	if !ok {
+		return
+	}
+	userID, _ := getUserID(c)
+	tags, err := h.store.Tags().ListForPatient(c.Request.Context(), patientID, int64(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tags"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tags})
+}
+
+// tagPatient puts one of the caller's tags on a patient they can see
+func (h *PatientsHandler) tagPatient(c *gin.Context) {
+	patientID, ok := h.patientParam(c, false)
+	if !ok {
+		return
+	}
+	tag, ok := h.ownTag(c)
+	if !ok {
+		return
+	}
+	if err := h.store.Tags().Attach(c.Request.Context(), tag.ID, patientID); err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to tag patient %d", patientID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to tag patient"})
+		return
+	}
+	h.auditPatientTag(c, "patient.tag.add", patientID, tag.ID)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *PatientsHandler) untagPatient(c *gin.Context) {
+	patientID, ok := h.patientParam(c, false)
+	if !ok {
+		return
+	}
+	tag, ok := h.ownTag(c)
+	if !ok {
+		return
+	}
+	err := h.store.Tags().Detach(c.Request.Context(), tag.ID, patientID)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "patient does not carry this tag")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to untag patient"})
+		return
+	}
+	h.auditPatientTag(c, "patient.tag.remove", patientID, tag.ID)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// tagsRouter serves the patient, tag and cohort routes as the given user
+func tagsRouter(st store.Store, claims middleware.UserClaims) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		c.Set("user", claims)
+		c.Next()
+	})
+	NewPatientsHandler(st).Register(r.Group("/patients"))
+	NewTagsHandler(st).Register(r.Group("/tags"))
+	NewCohortHandler(st).Register(r.Group("/analytics"))
+	return r
+}
+
+func createTag(t *testing.T, r *gin.Engine, name string) models.Tag {
+	t.Helper()
+	w := contactRequest(r, http.MethodPost, "/tags", `{"name":"`+name+`"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create tag: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var tag models.Tag
+	_ = json.Unmarshal(w.Body.Bytes(), &tag)
+	return tag
+}
+
+func TestTags_CRUD(t *testing.T) {
+	mem := store.NewMemoryStore()
+	r := tagsRouter(mem, ownerClaims)
+	path := ownedPatientPath(t, mem)
+
+	gdm := createTag(t, r, " GDM history ")
+	if gdm.Name != "GDM history" {
+		t.Fatalf("expected a trimmed name, got %q", gdm.Name)
+	}
+	if w := contactRequest(r, http.MethodPost, "/tags", `{"name":"gdm HISTORY"}`); w.Code != http.StatusConflict {
+		t.Errorf("duplicate name: expected 409, got %d", w.Code)
+	}
+	if w := contactRequest(r, http.MethodPost, "/tags", `{"name":" "}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("blank name: expected 422, got %d", w.Code)
+	}
+	arm := createTag(t, r, "study-arm-A")
+	tagPath := path + "/tags/" + strconv.FormatInt(gdm.ID, 10)
+
+	for i := 0; i < 2; i++ {
+		if w := contactRequest(r, http.MethodPut, tagPath, ""); w.Code != http.StatusNoContent {
+			t.Fatalf("tag patient: expected 204, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	w := contactRequest(r, http.MethodGet, path+"/tags", "")
+	var onPatient struct{ Data []models.Tag }
+	_ = json.Unmarshal(w.Body.Bytes(), &onPatient)
+	if len(onPatient.Data) != 1 || onPatient.Data[0].ID != gdm.ID || onPatient.Data[0].PatientCount != 1 {
+		t.Fatalf("expected the patient to carry GDM history once, got %s", w.Body.String())
+	}
+
+	w = contactRequest(r, http.MethodPatch, "/tags/"+strconv.FormatInt(arm.ID, 10), `{"name":"GDM History"}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("rename onto another tag: expected 409, got %d", w.Code)
+	}
+
+	// Tags are private: another clinician can neither use nor see them
+	other := tagsRouter(mem, middleware.UserClaims{UserID: 2, Email: "nurse@example.com", Role: "clinician"})
+	if w := contactRequest(other, http.MethodDelete, "/tags/"+strconv.FormatInt(gdm.ID, 10), ""); w.Code != http.StatusNotFound {
+		t.Errorf("another clinician's tag: expected 404, got %d", w.Code)
+	}
+	if w := contactRequest(other, http.MethodGet, "/patients?tag_id="+strconv.FormatInt(gdm.ID, 10), ""); w.Code != http.StatusBadRequest {
+		t.Errorf("filter by another clinician's tag: expected 400, got %d", w.Code)
+	}
+
+	if w := contactRequest(r, http.MethodDelete, tagPath, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("untag: expected 204, got %d", w.Code)
+	}
+	if w := contactRequest(r, http.MethodDelete, tagPath, ""); w.Code != http.StatusNotFound {
+		t.Errorf("untag twice: expected 404, got %d", w.Code)
+	}
+	if w := contactRequest(r, http.MethodDelete, "/tags/"+strconv.FormatInt(gdm.ID, 10), ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete tag: expected 204, got %d", w.Code)
+	}
+	w = contactRequest(r, http.MethodGet, "/tags", "")
+	var mine struct{ Data []models.Tag }
+	_ = json.Unmarshal(w.Body.Bytes(), &mine)
+	if len(mine.Data) != 1 || mine.Data[0].ID != arm.ID {
+		t.Errorf("expected only study-arm-A left, got %s", w.Body.String())
+	}
+}
+
+func TestTags_FilterPatientsAndCohorts(t *testing.T) {
+	ctx := context.Background()
+	mem := store.NewMemoryStore()
+	r := tagsRouter(mem, ownerClaims)
+	tagged, _ := mem.Patients().Create(ctx, models.Patient{UserID: 1, Name: "Ana Cruz", Age: 54})
+	untagged, _ := mem.Patients().Create(ctx, models.Patient{UserID: 1, Name: "Bea Santos", Age: 49})
+	_, _ = mem.Assessments().Create(ctx, models.Assessment{PatientID: tagged.ID, Cluster: "SIRD", RiskScore: 70})
+	_, _ = mem.Assessments().Create(ctx, models.Assessment{PatientID: untagged.ID, Cluster: "MOD", RiskScore: 20})
+
+	tag := createTag(t, r, "study-arm-A")
+	tagID := strconv.FormatInt(tag.ID, 10)
+	contactRequest(r, http.MethodPut, "/patients/"+strconv.FormatInt(tagged.ID, 10)+"/tags/"+tagID, "")
+
+	w := contactRequest(r, http.MethodGet, "/patients?tag_id="+tagID, "")
+	var list struct {
+		Data  []PatientSummary
+		Total int
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || list.Total != 1 || list.Data[0].Patient.ID != tagged.ID {
+		t.Fatalf("expected only the tagged patient, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = contactRequest(r, http.MethodGet, "/analytics/cohort?group_by=cluster&tag_id="+tagID, "")
+	var cohort struct {
+		Groups        []models.CohortGroup `json:"groups"`
+		TotalPatients int                  `json:"total_patients"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &cohort)
+	if w.Code != http.StatusOK || cohort.TotalPatients != 1 || len(cohort.Groups) != 1 || cohort.Groups[0].Name != "SIRD" {
+		t.Fatalf("expected a cohort of the tagged patient only, got %d: %s", w.Code, w.Body.String())
+	}
+
+	other := tagsRouter(mem, middleware.UserClaims{UserID: 2, Email: "nurse@example.com", Role: "clinician"})
+	if w := contactRequest(other, http.MethodGet, "/analytics/cohort?tag_id="+tagID, ""); w.Code != http.StatusForbidden {
+		t.Errorf("cohort of another clinician's tag: expected 403, got %d", w.Code)
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// TaskGenerator opens tasks in clinicians' inboxes without them asking: a
+// review task for each risk alert, and an overdue task for each patient not
+// assessed within overdue. A new assessment closes the patient's overdue
+// task.
+type TaskGenerator struct {
+	store   store.Store
+	overdue time.Duration
+	now     func() time.Time
+}
+
+func NewTaskGenerator(store store.Store, overdue time.Duration) *TaskGenerator {
+	return &TaskGenerator{store: store, overdue: overdue, now: time.Now}
+}
+
+// Subscribe opens review tasks on risk_alert.raised and closes overdue tasks
+// on assessment.created events on bus.
+func (g *TaskGenerator) Subscribe(bus *events.Bus) {
+	events.Subscribe(bus, "tasks", func(ctx context.Context, e events.RiskAlertRaised) error {
+		patientID, assessmentID := e.Alert.PatientID, e.Alert.AssessmentID
+		_, err := g.store.Tasks().Create(ctx, models.Task{
+			UserID:       e.Alert.UserID,
+			Kind:         models.TaskReviewHighRisk,
+			Title:        "Review high-risk assessment",
+			Status:       models.TaskOpen,
+			PatientID:    &patientID,
+			AssessmentID: &assessmentID,
+		})
+		return err
+	})
+	events.Subscribe(bus, "tasks", func(ctx context.Context, e events.AssessmentCreated) error {
+		_, err := g.store.Tasks().CloseForPatient(ctx, e.Assessment.PatientID, models.TaskOverdueAssessment, g.now())
+		return err
+	})
+}
+
+// Run opens an overdue task for every patient whose last assessment, or
+// creation if never assessed, is older than the overdue period and who has
+// no active overdue task.
+func (g *TaskGenerator) Run(ctx context.Context) error {
+	now := g.now()
+	opened, err := g.store.Tasks().OpenOverdueAssessments(ctx, now.Add(-g.overdue), now)
+	if err != nil {
+		return err
+	}
+	if opened > 0 {
+		logging.Ctx(ctx).Info().Msgf("Opened %d overdue assessment tasks", opened)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// taskTransitions lists the statuses a task may move to from each status.
+// Closed tasks can only be reopened.
+var taskTransitions = map[string][]string{
+	models.TaskOpen:       {models.TaskInProgress, models.TaskDone, models.TaskDismissed},
+	models.TaskInProgress: {models.TaskOpen, models.TaskDone, models.TaskDismissed},
+	models.TaskDone:       {models.TaskOpen},
+	models.TaskDismissed:  {models.TaskOpen},
+}
+
+// taskRequest is the body of a task create or update. Absent fields keep
+// their current value on update; title is required on create.
+type taskRequest struct {
+	Title     *string    `json:"title"`
+	PatientID *int64     `json:"patient_id"`
+	DueAt     *time.Time `json:"due_at"`
+	Status    *string    `json:"status"`
+}
+
+// validTitle returns the problem with title, or "" if it is valid
+func validTitle(title string) string {
+	switch {
+	case title == "":
+		return "title is required"
+	case utf8.RuneCountInString(title) > 200:
+		return "title must be at most 200 characters"
+	}
+	return ""
+}
+
+// taskAudit is how a task appears in audit details. Manual titles are free
+// text and left out.
+func taskAudit(t models.Task) map[string]interface{} {
+	details := map[string]interface{}{
+		"kind":   t.Kind,
+		"status": t.Status,
+	}
+	if t.PatientID != nil {
+		details["patient_id"] = *t.PatientID
+	}
+	return details
+}
+
+// TasksHandler serves a clinician's task inbox: review and overdue tasks
+// opened by TaskGenerator and manual tasks clinicians add themselves.
+type TasksHandler struct {
+	store store.Store
+	now   func() time.Time
+}
+
+func NewTasksHandler(store store.Store) *TasksHandler {
+	return &TasksHandler{store: store, now: time.Now}
+}
+
+func (h *TasksHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("", h.list)
+	rg.POST("", h.create)
+	rg.PATCH("/:taskID", h.update)
+	rg.DELETE("/:taskID", h.delete)
+}
+
+// audit records a task change
+func (h *TasksHandler) audit(c *gin.Context, action string, t models.Task) {
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(c.Request.Context(), models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     action,
+		TargetType: "task",
+		TargetID:   int(t.ID),
+		Details:    taskAudit(t),
+	})
+}
+
+// storedTask loads the caller's :taskID task
+func (h *TasksHandler) storedTask(c *gin.Context, userID int32) (*models.Task, bool) {
+	taskID, err := parseIDParam(c, "taskID")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+		return nil, false
+	}
+	t, err := h.store.Tasks().Get(c.Request.Context(), taskID, int64(userID))
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "task not found")
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load task"})
+		return nil, false
+	}
+	return t, true
+}
+
+// list returns the caller's tasks, soonest due first. ?status= takes a
+// comma-separated list of statuses and defaults to the active ones; ?kind=
+// keeps one kind.
+func (h *TasksHandler) list(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var params models.TaskListParams
+	if raw := c.Query("status"); raw != "" {
+		for _, s := range strings.Split(raw, ",") {
+			if !slices.Contains(models.TaskStatuses, s) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of " + strings.Join(models.TaskStatuses, ", ")})
+				return
+			}
+			params.Statuses = append(params.Statuses, s)
+		}
+	}
+	if params.Kind = c.Query("kind"); params.Kind != "" && !slices.Contains(models.TaskKinds, params.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be one of " + strings.Join(models.TaskKinds, ", ")})
+		return
+	}
+	tasks, err := h.store.Tasks().List(c.Request.Context(), int64(userID), params)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to list tasks of user %d", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tasks"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tasks})
+}
+
+// create adds a manual task, optionally about a patient the caller can see
+func (h *TasksHandler) create(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req taskRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Status != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	t := models.Task{UserID: int64(userID), Kind: models.TaskManual, Status: models.TaskOpen, DueAt: req.DueAt}
+	if req.Title != nil {
+		t.Title = strings.TrimSpace(*req.Title)
+	}
+	if msg := validTitle(t.Title); msg != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+		return
+	}
+	if req.PatientID != nil {
+		if _, err := h.store.Patients().GetVisible(c.Request.Context(), int32(*req.PatientID), userID); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "patient not found"})
+			return
+		}
+		t.PatientID = req.PatientID
+	}
+
+	created, err := h.store.Tasks().Create(c.Request.Context(), t)
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to create task for user %d", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create task"})
+		return
+	}
+	h.audit(c, "task.create", *created)
+	c.JSON(http.StatusCreated, created)
+}
+
+// update renames, reschedules or moves a task along taskTransitions. Closing
+// a task sets ClosedAt and reopening it clears it.
+func (h *TasksHandler) update(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	t, ok := h.storedTask(c, userID)
+	if !ok {
+		return
+	}
+	var req taskRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.PatientID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if req.Title != nil {
+		t.Title = strings.TrimSpace(*req.Title)
+		if msg := validTitle(t.Title); msg != "" {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+			return
+		}
+	}
+	if req.DueAt != nil {
+		t.DueAt = req.DueAt
+	}
+	if req.Status != nil && *req.Status != t.Status {
+		if !slices.Contains(models.TaskStatuses, *req.Status) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "status must be one of " + strings.Join(models.TaskStatuses, ", ")})
+			return
+		}
+		if !slices.Contains(taskTransitions[t.Status], *req.Status) {
+			c.JSON(http.StatusConflict, gin.H{"error": "a " + t.Status + " task cannot become " + *req.Status})
+			return
+		}
+		t.Status = *req.Status
+		if models.TaskClosed(t.Status) {
+			now := h.now()
+			t.ClosedAt = &now
+		} else {
+			t.ClosedAt = nil
+		}
+	}
+
+	updated, err := h.store.Tasks().Update(c.Request.Context(), *t)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "task not found")
+		return
+	}
+	if err != nil {
+		logging.Ctx(c.Request.Context()).Error().Err(err).Msgf("Failed to update task %d", t.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update task"})
+		return
+	}
+	h.audit(c, "task.update", *updated)
+	c.JSON(http.StatusOK, updated)
+}
+
+// delete removes a manual task. Automatic tasks are dismissed instead, so
+// the inbox keeps a record of them.
+func (h *TasksHandler) delete(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	t, ok := h.storedTask(c, userID)
+	if !ok {
+		return
+	}
+	if t.Kind != models.TaskManual {
+		c.JSON(http.StatusConflict, gin.H{"error": "only manual tasks can be deleted; dismiss this one instead"})
+		return
+	}
+	err = h.store.Tasks().Delete(c.Request.Context(), t.ID, int64(userID))
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "task not found")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete task"})
+		return
+	}
+	h.audit(c, "task.delete", *t)
+	c.Status(http.StatusNoContent)
+}
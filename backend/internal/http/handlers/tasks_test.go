@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// tasksRouter serves the patient and task routes as the given user
+func tasksRouter(st store.Store, claims middleware.UserClaims) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(func(c *gin.Context) {
+		c.Set("user", claims)
+		c.Next()
+	})
+	NewPatientsHandler(st).Register(r.Group("/patients"))
+	NewTasksHandler(st).Register(r.Group("/tasks"))
+	return r
+}
+
+func listTasks(t *testing.T, r *gin.Engine, path string) []models.Task {
+	t.Helper()
+	w := contactRequest(r, http.MethodGet, path, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: expected 200, got %d: %s", path, w.Code, w.Body.String())
+	}
+	var list struct{ Data []models.Task }
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	return list.Data
+}
+
+func TestTasks_ManualLifecycle(t *testing.T) {
+	mem := store.NewMemoryStore()
+	r := tasksRouter(mem, ownerClaims)
+	patientID := strings.TrimPrefix(ownedPatientPath(t, mem), "/patients/")
+
+	w := contactRequest(r, http.MethodPost, "/tasks", `{"title":" Call the lab ","patient_id":`+patientID+`}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var task models.Task
+	_ = json.Unmarshal(w.Body.Bytes(), &task)
+	if task.Title != "Call the lab" || task.Kind != models.TaskManual || task.Status != models.TaskOpen || task.PatientName != "Ana Cruz" {
+		t.Fatalf("unexpected task %+v", task)
+	}
+	path := "/tasks/" + strconv.FormatInt(task.ID, 10)
+
+	w = contactRequest(r, http.MethodPatch, path, `{"status":"done"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("complete: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &task)
+	if task.ClosedAt == nil {
+		t.Error("expected a closed task to record when it closed")
+	}
+	if got := listTasks(t, r, "/tasks"); len(got) != 0 {
+		t.Errorf("expected the inbox to hide closed tasks, got %+v", got)
+	}
+	if got := listTasks(t, r, "/tasks?status=done,dismissed"); len(got) != 1 {
+		t.Errorf("expected the done task when asked for, got %+v", got)
+	}
+	if w := contactRequest(r, http.MethodPatch, path, `{"status":"in_progress"}`); w.Code != http.StatusConflict {
+		t.Errorf("done to in_progress: expected 409, got %d", w.Code)
+	}
+	w = contactRequest(r, http.MethodPatch, path, `{"status":"open"}`)
+	_ = json.Unmarshal(w.Body.Bytes(), &task)
+	if w.Code != http.StatusOK || task.ClosedAt != nil {
+		t.Errorf("reopen: expected 200 and no closed_at, got %d: %s", w.Code, w.Body.String())
+	}
+
+	other := tasksRouter(mem, middleware.UserClaims{UserID: 2, Email: "nurse@example.com", Role: "clinician"})
+	if w := contactRequest(other, http.MethodPatch, path, `{"status":"done"}`); w.Code != http.StatusNotFound {
+		t.Errorf("another clinician's task: expected 404, got %d", w.Code)
+	}
+	if w := contactRequest(r, http.MethodDelete, path, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", w.Code)
+	}
+
+	events, _, _ := mem.AuditEvents().List(context.Background(), models.AuditListParams{Page: 1, PageSize: 20})
+	var actions []string
+	for _, e := range events {
+		actions = append(actions, e.Action)
+		if _, ok := e.Details["title"]; ok {
+			t.Error("task titles must not be audited")
+		}
+	}
+	for _, want := range []string{"task.create", "task.update", "task.delete"} {
+		if !strings.Contains(strings.Join(actions, ","), want) {
+			t.Errorf("expected audit action %s, got %v", want, actions)
+		}
+	}
+}
+
+func TestTasks_Validation(t *testing.T) {
+	mem := store.NewMemoryStore()
+	r := tasksRouter(mem, ownerClaims)
+	patientID := strings.TrimPrefix(ownedPatientPath(t, mem), "/patients/")
+	other := tasksRouter(mem, middleware.UserClaims{UserID: 2, Email: "nurse@example.com", Role: "clinician"})
+
+	for _, body := range []string{`{"title":"  "}`, `{"title":"` + strings.Repeat("a", 201) + `"}`} {
+		if w := contactRequest(r, http.MethodPost, "/tasks", body); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%.40s: expected 422, got %d", body, w.Code)
+		}
+	}
+	if w := contactRequest(other, http.MethodPost, "/tasks", `{"title":"Call","patient_id":`+patientID+`}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("another clinician's patient: expected 422, got %d", w.Code)
+	}
+	for _, query := range []string{"?status=closed", "?kind=reminder"} {
+		if w := contactRequest(r, http.MethodGet, "/tasks"+query, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestTaskGenerator_OpensAndClosesTasks(t *testing.T) {
+	ctx := context.Background()
+	mem := store.NewMemoryStore()
+	patient, _ := mem.Patients().Create(ctx, models.Patient{UserID: 1, Name: "Ana Cruz"})
+	bus := events.NewBus()
+	gen := NewTaskGenerator(mem, 180*24*time.Hour)
+	gen.Subscribe(bus)
+	r := tasksRouter(mem, ownerClaims)
+
+	bus.Publish(ctx, events.RiskAlertRaised{Alert: models.RiskAlert{UserID: 1, PatientID: patient.ID, AssessmentID: 7}})
+	review := listTasks(t, r, "/tasks?kind="+models.TaskReviewHighRisk)
+	if len(review) != 1 || review[0].AssessmentID == nil || *review[0].AssessmentID != 7 {
+		t.Fatalf("expected a review task for the alert, got %+v", review)
+	}
+	path := "/tasks/" + strconv.FormatInt(review[0].ID, 10)
+	if w := contactRequest(r, http.MethodDelete, path, ""); w.Code != http.StatusConflict {
+		t.Errorf("delete automatic task: expected 409, got %d", w.Code)
+	}
+	if w := contactRequest(r, http.MethodPatch, path, `{"status":"dismissed"}`); w.Code != http.StatusOK {
+		t.Errorf("dismiss automatic task: expected 200, got %d", w.Code)
+	}
+
+	// Half a year on, the never-assessed patient is overdue, once
+	gen.now = func() time.Time { return time.Now().Add(181 * 24 * time.Hour) }
+	for i := 0; i < 2; i++ {
+		if err := gen.Run(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	overdue := listTasks(t, r, "/tasks?kind="+models.TaskOverdueAssessment)
+	if len(overdue) != 1 || overdue[0].PatientID == nil || *overdue[0].PatientID != patient.ID {
+		t.Fatalf("expected one overdue task, got %+v", overdue)
+	}
+
+	bus.Publish(ctx, events.AssessmentCreated{UserID: 1, Assessment: models.Assessment{PatientID: patient.ID}})
+	if got := listTasks(t, r, "/tasks?kind="+models.TaskOverdueAssessment); len(got) != 0 {
+		t.Errorf("expected a new assessment to close the overdue task, got %+v", got)
+	}
+	if got := listTasks(t, r, "/tasks?status=done&kind="+models.TaskOverdueAssessment); len(got) != 1 || got[0].ClosedAt == nil {
+		t.Errorf("expected the overdue task to be done, got %+v", got)
+	}
+}
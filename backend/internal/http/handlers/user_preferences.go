@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/pdf"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+var (
+	preferenceUnits       = []string{models.UnitsMgDL, models.UnitsMmolL}
+	preferenceDateFormats = []string{models.DateFormatLong, models.DateFormatISO, models.DateFormatDMY, models.DateFormatMDY}
+)
+
+// userPreferences returns the user's stored preferences with defaults
+// filled in: mg/dL, long dates and the clinic's locale. A lookup failure
+// falls back to the defaults so it never blocks a report or a write.
+func userPreferences(ctx context.Context, st store.Store, userID int32) models.UserPreferences {
+	prefs, err := st.Users().Preferences(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logging.Ctx(ctx).Error().Err(err).Msgf("Failed to load preferences of user %d", userID)
+		}
+		prefs = &models.UserPreferences{}
+	}
+	if prefs.Units == "" {
+		prefs.Units = models.UnitsMgDL
+	}
+	if prefs.DateFormat == "" {
+		prefs.DateFormat = models.DateFormatLong
+	}
+	return *prefs
+}
+
+// preferencesRequest is the body of a preferences update. Absent fields keep
+// their current value; an empty locale goes back to the clinic's.
+type preferencesRequest struct {
+	Units      *string `json:"units"`
+	Locale     *string `json:"locale"`
+	DateFormat *string `json:"date_format"`
+}
+
+// apply copies the request onto prefs and returns the first problem with the
+// result, or "" if it is valid.
+func (r preferencesRequest) apply(prefs *models.UserPreferences) string {
+	if r.Units != nil {
+		prefs.Units = *r.Units
+	}
+	if r.Locale != nil {
+		prefs.Locale = *r.Locale
+	}
+	if r.DateFormat != nil {
+		prefs.DateFormat = *r.DateFormat
+	}
+	switch {
+	case !slices.Contains(preferenceUnits, prefs.Units):
+		return "units must be mg/dL or mmol/L"
+	case prefs.Locale != "" && !pdf.IsLocale(prefs.Locale):
+		return "locale must be en, fil or empty for the clinic's"
+	case !slices.Contains(preferenceDateFormats, prefs.DateFormat):
+		return "date_format must be long, iso, dmy or mdy"
+	}
+	return ""
+}
+
+// UserPreferencesHandler serves the caller's client settings
+type UserPreferencesHandler struct {
+	store store.Store
+}
+
+func NewUserPreferencesHandler(store store.Store) *UserPreferencesHandler {
+	return &UserPreferencesHandler{store: store}
+}
+
+func (h *UserPreferencesHandler) Register(rg *gin.RouterGroup) {
+	rg.GET("", h.get)
+	rg.PUT("", h.update)
+}
+
+// respond writes prefs with the patient list view to open with
+func (h *UserPreferencesHandler) respond(c *gin.Context, userID int32, prefs models.UserPreferences) {
+	view, err := h.store.PatientListViews().Default(c.Request.Context(), int64(userID))
+	switch {
+	case err == nil:
+		prefs.DefaultPatientView = view
+	case !errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// get returns the caller's units, locale and date format, including the
+// patient list view to open with
+func (h *UserPreferencesHandler) get(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	h.respond(c, userID, userPreferences(c.Request.Context(), h.store, userID))
+}
+
+// update changes the caller's units, locale or date format. The default
+// patient view is set on the view itself.
+func (h *UserPreferencesHandler) update(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req preferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	ctx := c.Request.Context()
+	prefs := userPreferences(ctx, h.store, userID)
+	if msg := req.apply(&prefs); msg != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+		return
+	}
+	err = h.store.Users().SavePreferences(ctx, userID, prefs)
+	if errors.Is(err, store.ErrNotFound) {
+		abortWithError(c, err, "user not found")
+		return
+	}
+	if err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to save preferences of user %d", userID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save preferences"})
+		return
+	}
+
+	claims := c.MustGet("user").(middleware.UserClaims)
+	_ = h.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      claims.Email,
+		Action:     "user.preferences_update",
+		TargetType: "user",
+		TargetID:   int(userID),
+		Details: map[string]interface{}{
+			"units":       prefs.Units,
+			"locale":      prefs.Locale,
+			"date_format": prefs.DateFormat,
+		},
+	})
+	h.respond(c, userID, prefs)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+func TestUserPreferences_Update(t *testing.T) {
+	mem := store.NewMemoryStore()
+	if _, err := mem.Users().Create(context.Background(), models.User{Email: "doc@example.com", Role: "clinician"}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	r := viewsRouter(mem, ownerClaims)
+
+	prefs := preferences(t, r)
+	if prefs.Units != models.UnitsMgDL || prefs.DateFormat != models.DateFormatLong || prefs.Locale != "" {
+		t.Fatalf("expected mg/dL, long dates and the clinic's locale by default, got %+v", prefs)
+	}
+
+	w := contactRequest(r, http.MethodPut, "/users/preferences", `{"units":"mmol/L","locale":"fil"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = contactRequest(r, http.MethodPut, "/users/preferences", `{"date_format":"dmy"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	want := models.UserPreferences{Units: models.UnitsMmolL, Locale: "fil", DateFormat: models.DateFormatDMY}
+	if got := preferences(t, r); got != want {
+		t.Fatalf("expected absent fields to keep their value, got %+v", got)
+	}
+
+	for _, body := range []string{`{"units":"mmol"}`, `{"locale":"de"}`, `{"date_format":"yyyy"}`} {
+		if w := contactRequest(r, http.MethodPut, "/users/preferences", body); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected 422, got %d", body, w.Code)
+		}
+	}
+	if got := preferences(t, r); got != want {
+		t.Errorf("rejected updates must not save, got %+v", got)
+	}
+}
+
+func TestUserPreferences_PlausibilityInPreferredUnits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	users := store.NewMemoryStore().Users()
+	_, _ = users.Create(context.Background(), models.User{Email: "test@example.com", Role: "admin"})
+	_ = users.SavePreferences(context.Background(), 1, models.UserPreferences{Units: models.UnitsMmolL})
+	st := &fakeStore{repo: &fakeAssessmentRepo{}, patientRepo: &fakePatientRepo{}, users: users,
+		clinicRepo: &fakeClinicRepo{mode: models.ValidationModeStrict}}
+
+	r := gin.New()
+	r.Use(mockAuthMiddleware())
+	NewAssessmentsHandler(st, ml.NewMockPredictor(), "v1", "hash123").Register(r.Group("/patients"))
+
+	w := contactRequest(r, http.MethodPost, "/patients/7/assessments", `{"fbs":600.5,"hba1c":5.5,"bmi":24}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct{ Fields []ml.FieldError }
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if len(body.Fields) != 1 || body.Fields[0].Unit != models.UnitsMmolL || body.Fields[0].Max != 33.3 {
+		t.Errorf("expected the fbs error in mmol/L, got %s", w.Body.String())
+	}
+}
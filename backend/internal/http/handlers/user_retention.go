@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/storage"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// retentionActor is recorded as the actor of purges run by the scheduler
+const retentionActor = "system:retention"
+
+// UserRetention purges the data of deactivated users once a grace period has
+// passed. Deactivating a user schedules the purge and reactivating them
+// cancels it. In anonymize mode the user's identifiers and their patients'
+// names, MRNs, contacts and photos are removed while clinical values are
+// kept for analytics; delete mode removes the user and their patients
+// outright.
+type UserRetention struct {
+	store store.Store
+	blobs storage.Storage
+	grace time.Duration
+	mode  string
+	now   func() time.Time
+}
+
+func NewUserRetention(store store.Store, blobs storage.Storage, grace time.Duration, mode string) *UserRetention {
+	return &UserRetention{store: store, blobs: blobs, grace: grace, mode: mode, now: time.Now}
+}
+
+// Subscribe schedules and cancels purges on user.deactivated and
+// user.activated events on bus.
+func (r *UserRetention) Subscribe(bus *events.Bus) {
+	events.Subscribe(bus, "user_retention", func(ctx context.Context, e events.UserDeactivated) error {
+		d, err := r.store.UserDeletions().Schedule(ctx, e.UserID, e.Actor, r.now().Add(r.grace))
+		if err != nil {
+			return fmt.Errorf("schedule deletion of user %d: %w", e.UserID, err)
+		}
+		return r.store.AuditEvents().Create(ctx, models.AuditEvent{
+			Actor:      e.Actor,
+			Action:     "user.deletion_scheduled",
+			TargetType: "user",
+			TargetID:   int(e.UserID),
+			Details: map[string]interface{}{
+				"deletion_id": d.ID,
+				"due_at":      d.DueAt,
+				"mode":        r.mode,
+			},
+		})
+	})
+	events.Subscribe(bus, "user_retention", func(ctx context.Context, e events.UserActivated) error {
+		d, err := r.store.UserDeletions().CancelForUser(ctx, e.UserID, e.Actor)
+		if err != nil {
+			return fmt.Errorf("cancel deletion of user %d: %w", e.UserID, err)
+		}
+		if d == nil {
+			return nil
+		}
+		return r.store.AuditEvents().Create(ctx, models.AuditEvent{
+			Actor:      e.Actor,
+			Action:     "user.deletion_cancelled",
+			TargetType: "user",
+			TargetID:   int(e.UserID),
+			Details:    map[string]interface{}{"deletion_id": d.ID},
+		})
+	})
+}
+
+// Run purges every deletion that is due. A failed purge is logged and left
+// pending for the next run; the others still go ahead. So is the purge of a
+// user whose patients a clinic still relies on, until they are transferred.
+func (r *UserRetention) Run(ctx context.Context) error {
+	due, err := r.store.UserDeletions().Due(ctx, r.now())
+	if err != nil {
+		return err
+	}
+	for _, d := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := r.purge(ctx, d)
+		switch {
+		case errors.Is(err, store.ErrClinicPatients):
+			logging.Ctx(ctx).Warn().Msgf("Not purging user %d until their clinic patients are transferred", d.UserID)
+		case err != nil:
+			logging.Ctx(ctx).Error().Err(err).Msgf("Failed to purge user %d", d.UserID)
+		}
+	}
+	return nil
+}
+
+func (r *UserRetention) purge(ctx context.Context, d models.UserDeletion) error {
+	purge, err := r.store.UserDeletions().Purge(ctx, d, r.mode, retentionActor)
+	if err != nil {
+		return err
+	}
+	if purge == nil {
+		// Reactivated or cancelled since it was listed
+		return nil
+	}
+	// Objects are removed after the rows are gone, so a failure here leaves
+	// unreferenced files rather than records pointing at nothing
+	for _, key := range append(purge.PhotoKeys, purge.AttachmentKeys...) {
+		if err := r.blobs.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			logging.Ctx(ctx).Error().Err(err).Msgf("Failed to delete object %s of purged user %d", key, d.UserID)
+		}
+	}
+	return r.store.AuditEvents().Create(ctx, models.AuditEvent{
+		Actor:      retentionActor,
+		Action:     "user.purge",
+		TargetType: "user",
+		TargetID:   int(d.UserID),
+		Details: map[string]interface{}{
+			"deletion_id":  d.ID,
+			"mode":         r.mode,
+			"patients":     purge.Patients,
+			"requested_by": d.RequestedBy,
+		},
+	})
+}
@@ -25,3 +25,13 @@ func getUserID(c *gin.Context) (int32, error) {
 	}
 	return int32(claims.UserID), nil
 }
+
+// abortWithError attaches err for middleware.ErrorHandler to map to a status
+// and stops the chain. message, when set, replaces the default message.
+func abortWithError(c *gin.Context, err error, message string) {
+	e := c.Error(err)
+	if message != "" {
+		e.SetMeta(message)
+	}
+	c.Abort()
+}
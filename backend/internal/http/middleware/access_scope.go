@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// accessScopeKey is the gin context key AccessScope stores the scope under
+const accessScopeKey = "access_scope"
+
+// ClinicMembershipLookup returns the IDs of the clinics the user belongs to.
+type ClinicMembershipLookup func(ctx context.Context, userID int64) ([]int32, error)
+
+// AccessScope resolves the caller's clinic memberships once per request and
+// stores the resulting models.AccessScope for handlers to read with
+// GetAccessScope. Callers granted admin.system see every clinic; everyone
+// else their own patients and those of their clinics' members. Requests
+// without user claims, such as reporting API tokens, pass untouched. Must
+// run after Auth or AuthOrAPIKey.
+//
+// Example usage:
+//
+//	analytics.Use(middleware.AccessScope(perms, handlers.ClinicMembershipLookup(st)))
+func AccessScope(perms PermissionLookup, clinics ClinicMembershipLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, ok := c.Get("user")
+		if !ok {
+			c.Next()
+			return
+		}
+		claims, ok := v.(UserClaims)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "invalid user context",
+			})
+			return
+		}
+
+		scope, err := ResolveAccessScope(c.Request.Context(), claims, perms, clinics)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to resolve access scope",
+			})
+			return
+		}
+		c.Set(accessScopeKey, scope)
+		c.Next()
+	}
+}
+
+// ResolveAccessScope builds the access scope of user, as AccessScope does.
+func ResolveAccessScope(ctx context.Context, user UserClaims, perms PermissionLookup, clinics ClinicMembershipLookup) (models.AccessScope, error) {
+	all, err := perms(ctx, user, 0, models.PermAdminSystem)
+	if err != nil {
+		return models.AccessScope{}, err
+	}
+	scope := models.AccessScope{All: all, UserID: user.UserID}
+	if !all {
+		if scope.ClinicIDs, err = clinics(ctx, user.UserID); err != nil {
+			return models.AccessScope{}, err
+		}
+	}
+	return scope, nil
+}
+
+// GetAccessScope returns the scope AccessScope resolved for the request, and
+// false if it did not run.
+func GetAccessScope(c *gin.Context) (models.AccessScope, bool) {
+	v, ok := c.Get(accessScopeKey)
+	if !ok {
+		return models.AccessScope{}, false
+	}
+	scope, ok := v.(models.AccessScope)
+	return scope, ok
+}
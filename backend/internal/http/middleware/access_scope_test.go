@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestAccessScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	perms := func(ctx context.Context, user UserClaims, clinicID int64, permission string) (bool, error) {
+		return user.Role == "admin" && permission == models.PermAdminSystem, nil
+	}
+	for _, tt := range []struct {
+		name       string
+		claims     *UserClaims
+		clinics    ClinicMembershipLookup
+		wantStatus int
+		want       *models.AccessScope
+	}{
+		{"admin", &UserClaims{UserID: 1, Role: "admin"}, nil, http.StatusOK, &models.AccessScope{All: true, UserID: 1}},
+		{"clinician", &UserClaims{UserID: 5, Role: "clinician"},
+			func(ctx context.Context, userID int64) ([]int32, error) { return []int32{4, 9}, nil },
+			http.StatusOK, &models.AccessScope{UserID: 5, ClinicIDs: []int32{4, 9}}},
+		{"lookup fails", &UserClaims{UserID: 5, Role: "clinician"},
+			func(ctx context.Context, userID int64) ([]int32, error) { return nil, errors.New("db down") },
+			http.StatusInternalServerError, nil},
+		{"api token", nil, nil, http.StatusOK, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			lookups := 0
+			clinics := func(ctx context.Context, userID int64) ([]int32, error) {
+				lookups++
+				return tt.clinics(ctx, userID)
+			}
+			var got *models.AccessScope
+			r := gin.New()
+			if tt.claims != nil {
+				r.Use(func(c *gin.Context) { c.Set("user", *tt.claims) })
+			}
+			r.Use(AccessScope(perms, clinics))
+			r.GET("/analytics", func(c *gin.Context) {
+				if scope, ok := GetAccessScope(c); ok {
+					got = &scope
+				}
+				c.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "/analytics", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d", tt.wantStatus, w.Code)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected scope %+v, got %+v", tt.want, got)
+			}
+			if tt.clinics == nil && lookups != 0 {
+				t.Fatal("memberships looked up needlessly")
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireActiveUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	active := map[int64]bool{1: true, 2: false}
+	lookups := 0
+	var lookupErr error
+	lookup := func(ctx context.Context, userID int64, sessionID string) (bool, error) {
+		lookups++
+		if lookupErr != nil {
+			return false, lookupErr
+		}
+		return active[userID] && sessionID != "revoked", nil
+	}
+	newRouter := func(ttl time.Duration) *gin.Engine {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			switch c.Query("as") {
+			case "":
+			case "key":
+				c.Set("user", UserClaims{UserID: 2})
+				c.Set("api_key", APIKeyClaims{KeyID: 1})
+			case "revoked":
+				c.Set("user", UserClaims{UserID: 1, SessionID: "revoked"})
+			case "inactive":
+				c.Set("user", UserClaims{UserID: 2})
+			default:
+				c.Set("user", UserClaims{UserID: 1, SessionID: "s1"})
+			}
+		})
+		r.Use(RequireActiveUser(lookup, ttl))
+		r.GET("/patients", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return r
+	}
+	get := func(r *gin.Engine, as string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/patients?as="+as, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	r := newRouter(time.Minute)
+	for as, want := range map[string]int{
+		"active":   http.StatusOK,
+		"inactive": http.StatusUnauthorized,
+		"revoked":  http.StatusUnauthorized,
+		"key":      http.StatusOK,
+		"":         http.StatusOK,
+	} {
+		if got := get(r, as); got != want {
+			t.Errorf("%q: expected %d, got %d", as, want, got)
+		}
+	}
+	if lookups != 3 {
+		t.Fatalf("expected 3 lookups, got %d", lookups)
+	}
+
+	// Cached answers survive a deactivation until the TTL runs out
+	active[1] = false
+	if got := get(r, "active"); got != http.StatusOK || lookups != 3 {
+		t.Errorf("expected the cached answer, got %d after %d lookups", got, lookups)
+	}
+	uncached := newRouter(0)
+	if got := get(uncached, "active"); got != http.StatusUnauthorized {
+		t.Errorf("uncached: expected 401, got %d", got)
+	}
+
+	lookupErr = errors.New("db down")
+	if got := get(uncached, "active"); got != http.StatusInternalServerError {
+		t.Errorf("failed lookup: expected 500, got %d", got)
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthOrAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	lookup := func(ctx context.Context, key string) (*APIKeyClaims, error) {
+		user := UserClaims{UserID: 7, Email: "script@example.com", Role: "clinician"}
+		switch key {
+		case "dik_reader":
+			return &APIKeyClaims{KeyID: 1, Scopes: []string{"read"}, User: user}, nil
+		case "dik_writer":
+			return &APIKeyClaims{KeyID: 2, Scopes: []string{"write"}, User: user}, nil
+		case "dik_exporter":
+			return &APIKeyClaims{KeyID: 3, Scopes: []string{"read", "export"}, User: user}, nil
+		}
+		return nil, errors.New("unknown key")
+	}
+	r := gin.New()
+	r.Use(AuthOrAPIKey("test-secret", lookup))
+	handler := func(c *gin.Context) {
+		if c.MustGet("user").(UserClaims).UserID != 7 {
+			t.Error("expected the key's user")
+		}
+		c.Status(http.StatusOK)
+	}
+	r.GET("/x", handler)
+	r.POST("/x", handler)
+	r.GET("/export", RequireAPIKeyScope("export"), handler)
+
+	cases := []struct {
+		name, method, path, key string
+		want                    int
+	}{
+		{"read with read key", http.MethodGet, "/x", "dik_reader", http.StatusOK},
+		{"read with write key", http.MethodGet, "/x", "dik_writer", http.StatusOK},
+		{"write with read key", http.MethodPost, "/x", "dik_reader", http.StatusForbidden},
+		{"write with write key", http.MethodPost, "/x", "dik_writer", http.StatusOK},
+		{"export without scope", http.MethodGet, "/export", "dik_writer", http.StatusForbidden},
+		{"export with scope", http.MethodGet, "/export", "dik_exporter", http.StatusOK},
+		{"unknown key", http.MethodGet, "/x", "dik_nope", http.StatusUnauthorized},
+		{"no key or JWT", http.MethodGet, "/x", "", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, tc.path, nil)
+			if tc.key != "" {
+				req.Header.Set(APIKeyHeader, tc.key)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPITokenAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	lookup := func(ctx context.Context, token string) (*APITokenClaims, error) {
+		switch token {
+		case "dia_reader":
+			return &APITokenClaims{TokenID: 1, Scopes: []string{"analytics:read"}}, nil
+		case "dia_other":
+			return &APITokenClaims{TokenID: 2, Scopes: []string{"something:else"}}, nil
+		}
+		return nil, errors.New("unknown token")
+	}
+	r := gin.New()
+	r.Use(APITokenAuth(lookup, "analytics:read"))
+	r.GET("/x", func(c *gin.Context) {
+		if _, exists := c.Get("user"); exists {
+			t.Error("API tokens must not set a user")
+		}
+		c.Status(http.StatusOK)
+	})
+
+	cases := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"valid token", "Bearer dia_reader", http.StatusOK},
+		{"missing header", "", http.StatusUnauthorized},
+		{"user JWT", "Bearer eyJhbGciOiJIUzI1NiJ9.e30.x", http.StatusUnauthorized},
+		{"unknown token", "Bearer dia_nope", http.StatusUnauthorized},
+		{"wrong scope", "Bearer dia_other", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/x", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, w.Code)
+			}
+		})
+	}
+}
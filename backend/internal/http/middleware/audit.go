@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
 	"strconv"
 
@@ -61,7 +62,10 @@ func (a *AuditLogger) LogAction(action, targetType string) gin.HandlerFunc {
 		// Build details from request
 		details := buildAuditDetails(c)
 
-		// Create audit event (fire and forget - don't block the response)
+		// Create audit event (fire and forget - don't block the response).
+		// Detach from the request context: it is cancelled once the handler returns,
+		// which would abort slower sinks such as the webhook.
+		ctx := context.WithoutCancel(c.Request.Context())
 		go func() {
 			event := models.AuditEvent{
 				Actor:      claims.Email,
@@ -70,7 +74,7 @@ func (a *AuditLogger) LogAction(action, targetType string) gin.HandlerFunc {
 				TargetID:   targetID,
 				Details:    details,
 			}
-			_ = a.store.AuditEvents().Create(c.Request.Context(), event)
+			_ = a.store.AuditEvents().Create(ctx, event)
 		}()
 	}
 }
@@ -1,8 +1,12 @@
 package middleware
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -13,9 +17,67 @@ type UserClaims struct {
 	UserID int64
 	Email  string
 	Role   string
+	// SudoUntil is set when the token was issued by POST /auth/sudo; zero otherwise
+	SudoUntil time.Time
+	// MFASetupOnly is set for users whose role requires two-factor
+	// authentication but who have not enrolled yet
+	MFASetupOnly bool
+	// SessionID names the refresh token family the token was issued for;
+	// empty for API keys and tokens issued before sessions were listed
+	SessionID string
+	// ImpersonatedBy is the email of the admin acting as this user through
+	// POST /admin/users/:id/impersonate; empty for the user's own tokens
+	ImpersonatedBy string
 }
 
-func Auth(jwtSecret string) gin.HandlerFunc {
+// JWTKeyID names a signing secret in the kid header without revealing it.
+func JWTKeyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:4])
+}
+
+// SignJWT signs claims with HS256 and secret, naming the secret in the kid
+// header.
+func SignJWT(secret string, claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = JWTKeyID(secret)
+	return token.SignedString([]byte(secret))
+}
+
+// ParseJWT verifies an HS256 token against the current secret and any
+// previous ones still accepted during a rotation. A token with a kid header
+// is only checked against that secret; tokens signed before kids were added
+// are tried against each secret in turn.
+func ParseJWT(tokenStr, secret string, previousSecrets ...string) (*jwt.Token, error) {
+	secrets := append([]string{secret}, previousSecrets...)
+	kid := ""
+	if unverified, _, err := jwt.NewParser().ParseUnverified(tokenStr, jwt.MapClaims{}); err == nil {
+		kid, _ = unverified.Header["kid"].(string)
+	}
+	err := jwt.ErrTokenSignatureInvalid
+	for _, s := range secrets {
+		if kid != "" && kid != JWTKeyID(s) {
+			continue
+		}
+		var token *jwt.Token
+		token, err = jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+			// Verify signing method
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, jwt.ErrSignatureInvalid
+			}
+			return []byte(s), nil
+		}, jwt.WithValidMethods([]string{"HS256"}))
+		if err == nil || !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			return token, err
+		}
+	}
+	return nil, err
+}
+
+// Auth authenticates the bearer access token. previousSecrets are still
+// accepted so the signing secret can be rotated without signing everyone
+// out; drop them once tokens signed with them have expired.
+func Auth(jwtSecret string, previousSecrets ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authz := c.GetHeader("Authorization")
 		if authz == "" || !strings.HasPrefix(authz, "Bearer ") {
@@ -25,13 +87,7 @@ func Auth(jwtSecret string) gin.HandlerFunc {
 		tokenStr := strings.TrimPrefix(authz, "Bearer ")
 
 		// Parse token with claims validation
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			// Verify signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(jwtSecret), nil
-		}, jwt.WithValidMethods([]string{"HS256"}))
+		token, err := ParseJWT(tokenStr, jwtSecret, previousSecrets...)
 
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
@@ -71,12 +127,27 @@ func Auth(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
-		// Store user claims in context for handlers to use
-		c.Set("user", UserClaims{
+		user := UserClaims{
 			UserID: int64(userID),
 			Email:  sub,
 			Role:   role,
-		})
+		}
+		// Optional elevation claim from a recent re-authentication
+		if sudoUntil, ok := claims["sudo_until"].(float64); ok {
+			user.SudoUntil = time.Unix(int64(sudoUntil), 0)
+		}
+		if setup, ok := claims["mfa_setup"].(bool); ok {
+			user.MFASetupOnly = setup
+		}
+		if sid, ok := claims["sid"].(string); ok {
+			user.SessionID = sid
+		}
+		if by, ok := claims["impersonated_by"].(string); ok {
+			user.ImpersonatedBy = by
+		}
+
+		// Store user claims in context for handlers to use
+		c.Set("user", user)
 
 		c.Next()
 	}
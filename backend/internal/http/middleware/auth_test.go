@@ -117,6 +117,56 @@ func TestAuth_WrongSecret(t *testing.T) {
 	}
 }
 
+func TestAuth_KeyRotation(t *testing.T) {
+	claims := jwt.MapClaims{
+		"sub":     "test@example.com",
+		"user_id": float64(1),
+		"role":    "clinician",
+		"scope":   "diana",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}
+	signedOld, _ := SignJWT("old-secret", claims)
+	signedNew, _ := SignJWT("new-secret", claims)
+	// Signed before tokens carried a kid
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("old-secret"))
+	// A kid naming one secret but signed with another
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = JWTKeyID("new-secret")
+	signedForged, _ := forged.SignedString([]byte("old-secret"))
+
+	rotating := gin.New()
+	rotating.Use(Auth("new-secret", "old-secret"))
+	rotating.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+	rotated := gin.New()
+	rotated.Use(Auth("new-secret"))
+	rotated.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		name  string
+		r     *gin.Engine
+		token string
+		want  int
+	}{
+		{"current key", rotating, signedNew, http.StatusOK},
+		{"previous key", rotating, signedOld, http.StatusOK},
+		{"previous key without kid", rotating, legacy, http.StatusOK},
+		{"kid of another key", rotating, signedForged, http.StatusUnauthorized},
+		{"previous key dropped", rotated, signedOld, http.StatusUnauthorized},
+		{"without kid after rotation", rotated, legacy, http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			w := httptest.NewRecorder()
+			tc.r.ServeHTTP(w, req)
+			if w.Code != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, w.Code)
+			}
+		})
+	}
+}
+
 func TestAuth_ExpiredToken(t *testing.T) {
 	secret := "test-secret"
 
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// Error codes returned in the "code" field of an error response
+const (
+	CodeNotFound  = "not_found"
+	CodeConflict  = "conflict"
+	CodeForbidden = "forbidden"
+	CodeInternal  = "internal"
+)
+
+// ErrorHandler writes errors handlers attach with c.Error as a structured
+// body: {code, message, request_id}. The status comes from the store
+// sentinel the error wraps; anything else is a 500 whose cause is logged but
+// not returned. A string set as the error's meta replaces the default
+// message. The body also carries "error" with the message, the shape
+// handlers that respond directly still use. Responses already written are
+// left alone.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		last := c.Errors.Last()
+		status, code, message := classifyError(last.Err)
+		if status == http.StatusInternalServerError {
+			LogWithRequestID(c).Error().Err(last.Err).Str("path", c.FullPath()).Msg("request failed")
+		}
+		if meta, ok := last.Meta.(string); ok && meta != "" {
+			message = meta
+		}
+		c.JSON(status, gin.H{
+			"code":       code,
+			"message":    message,
+			"request_id": GetRequestID(c),
+			"error":      message,
+		})
+	}
+}
+
+// classifyError maps err to a status, code and default message
+func classifyError(err error) (int, string, string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return http.StatusNotFound, CodeNotFound, "not found"
+	case errors.Is(err, store.ErrConflict):
+		return http.StatusConflict, CodeConflict, "conflict"
+	case errors.Is(err, store.ErrForbidden):
+		return http.StatusForbidden, CodeForbidden, "forbidden"
+	default:
+		return http.StatusInternalServerError, CodeInternal, "internal server error"
+	}
+}
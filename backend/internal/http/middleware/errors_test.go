@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(RequestID(), ErrorHandler())
+	r.GET("/missing", func(c *gin.Context) { _ = c.Error(fmt.Errorf("patient 7: %w", store.ErrNotFound)) })
+	r.GET("/duplicate", func(c *gin.Context) {
+		_ = c.Error(store.ErrConflict).SetMeta("email already exists")
+	})
+	r.GET("/denied", func(c *gin.Context) { _ = c.Error(store.ErrForbidden) })
+	r.GET("/broken", func(c *gin.Context) { _ = c.Error(errors.New("connection reset by peer")) })
+	r.GET("/written", func(c *gin.Context) {
+		_ = c.Error(store.ErrConflict)
+		c.JSON(http.StatusTeapot, gin.H{"error": "handled"})
+	})
+
+	cases := []struct {
+		path    string
+		status  int
+		code    string
+		message string
+	}{
+		{"/missing", http.StatusNotFound, CodeNotFound, "not found"},
+		{"/duplicate", http.StatusConflict, CodeConflict, "email already exists"},
+		{"/denied", http.StatusForbidden, CodeForbidden, "forbidden"},
+		{"/broken", http.StatusInternalServerError, CodeInternal, "internal server error"},
+	}
+	for _, tc := range cases {
+		req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("X-Request-ID", "req-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d", tc.path, tc.status, w.Code)
+		}
+		var body map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body["code"] != tc.code || body["message"] != tc.message || body["request_id"] != "req-1" || body["error"] != tc.message {
+			t.Fatalf("%s: unexpected body %v", tc.path, body)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/written", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusTeapot {
+		t.Fatalf("expected the handler's response to stand, got %d: %s", w.Code, w.Body.String())
+	}
+}
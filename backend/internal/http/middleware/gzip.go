@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gzipWriters reuses compressors across responses; each holds a few hundred
+// KB of state.
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		gz, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return gz
+	},
+}
+
+// Gzip compresses text, JSON and CSV responses for clients that accept
+// gzip. The response is compressed as it is written, so streamed bodies
+// stay streamed: a handler's Flush pushes what it has compressed so far.
+// Images, archives, PDFs and responses that already carry a
+// Content-Encoding pass through untouched.
+func Gzip() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &gzipWriter{ResponseWriter: c.Writer, accepts: acceptsGzip(c.GetHeader("Accept-Encoding"))}
+		c.Writer = w
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip, either
+// by name or through *, without giving it q=0.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q=")
+		if !ok {
+			return true
+		}
+		weight, err := strconv.ParseFloat(q, 64)
+		return err == nil && weight > 0
+	}
+	return false
+}
+
+// compressible reports whether a response of contentType is worth
+// compressing. Event streams are left alone so proxies see them as-is.
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/problem+json", "application/xml", "application/javascript", "application/x-ndjson":
+		return true
+	}
+	return false
+}
+
+// gzipWriter decides on the first write, once the handler has set its
+// headers, whether to compress the body.
+type gzipWriter struct {
+	gin.ResponseWriter
+	accepts bool
+	decided bool
+	gz      *gzip.Writer
+	// wrote is set once the handler writes a body, which the compressor may
+	// still be holding back from the underlying writer
+	wrote  bool
+	status int
+}
+
+// Written counts body bytes still buffered by the compressor, so
+// RequestTimeout and ErrorHandler do not append a second body.
+func (w *gzipWriter) Written() bool {
+	return w.wrote || w.ResponseWriter.Written()
+}
+
+// Status is the status the body was written with; it no longer changes
+// once the handler has written.
+func (w *gzipWriter) Status() int {
+	if w.wrote {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	if w.Written() {
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *gzipWriter) decide() {
+	w.decided = true
+	h := w.Header()
+	if !compressible(h.Get("Content-Type")) {
+		return
+	}
+	h.Add("Vary", "Accept-Encoding")
+	// Headers already sent by WriteHeaderNow can no longer announce gzip
+	status := w.Status()
+	if !w.accepts || w.Written() || h.Get("Content-Encoding") != "" ||
+		status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return
+	}
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	w.gz = gzipWriters.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.decide()
+	}
+	if !w.wrote {
+		w.wrote, w.status = true, w.ResponseWriter.Status()
+	}
+	if w.gz == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.gz.Write(b)
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends what has been compressed so far along with any buffered
+// output of the underlying writer.
+func (w *gzipWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// close writes the gzip footer and returns the compressor to the pool
+func (w *gzipWriter) close() {
+	if w.gz == nil {
+		return
+	}
+	_ = w.gz.Close()
+	gzipWriters.Put(w.gz)
+	w.gz = nil
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func gzipRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Gzip())
+	r.GET("/json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"rows": strings.Repeat("a", 2048)})
+	})
+	r.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/csv")
+		for i := 0; i < 3; i++ {
+			_, _ = c.Writer.WriteString("id,name\n")
+			c.Writer.Flush()
+		}
+	})
+	r.GET("/png", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", []byte("not really a png"))
+	})
+	r.GET("/unchanged", func(c *gin.Context) {
+		c.Status(http.StatusNotModified)
+	})
+	return r
+}
+
+func gzipGet(r *gin.Engine, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func gunzip(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("body is not gzip: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("read gzip body: %v", err)
+	}
+	return string(body)
+}
+
+func TestGzip(t *testing.T) {
+	r := gzipRouter()
+
+	w := gzipGet(r, "/json", "br, gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected a gzip body that varies on Accept-Encoding, got %v", w.Header())
+	}
+	if body := gunzip(t, w); !strings.Contains(body, strings.Repeat("a", 2048)) {
+		t.Errorf("unexpected body %q", body)
+	}
+
+	w = gzipGet(r, "/stream", "gzip")
+	if got := gunzip(t, w); got != strings.Repeat("id,name\n", 3) {
+		t.Errorf("flushed stream: unexpected body %q", got)
+	}
+
+	for _, tc := range []struct{ path, acceptEncoding string }{
+		{"/json", ""},
+		{"/json", "gzip;q=0, identity"},
+		{"/png", "gzip"},
+		{"/unchanged", "gzip"},
+	} {
+		if w := gzipGet(r, tc.path, tc.acceptEncoding); w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s with %q: expected no compression, got %v", tc.path, tc.acceptEncoding, w.Header())
+		}
+	}
+	if w := gzipGet(r, "/json", ""); w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("uncompressed JSON must still vary on Accept-Encoding, got %v", w.Header())
+	}
+}
+
+func TestGzip_WithTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler(), Gzip(), RequestTimeout(20*time.Millisecond, nil))
+	// The body is written, then the handler fails once the deadline passes
+	r.GET("/late", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		<-c.Request.Context().Done()
+		_ = c.Error(errors.New("export failed"))
+	})
+
+	w := gzipGet(r, "/late", "gzip")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzip 200, got %d %v", w.Code, w.Header())
+	}
+	if body := gunzip(t, w); body != `{"status":"ok"}` {
+		t.Errorf("expected the handler's body alone, got %q", body)
+	}
+}
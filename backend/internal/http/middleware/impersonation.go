@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// ImpersonatedByHeader is set on every response to an impersonation token,
+// naming the admin so the frontend can show a support-mode banner.
+const ImpersonatedByHeader = "X-Impersonated-By"
+
+// Impersonation audits every request made with an impersonation token as an
+// impersonation.request event whose actor is the admin, successful or not,
+// and flags the response with ImpersonatedByHeader. Other requests pass
+// untouched. Must run after Auth or AuthOrAPIKey.
+//
+// Example usage:
+//
+//	protected.Use(middleware.Impersonation(st.AuditEvents()))
+func Impersonation(events store.AuditEventRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, ok := c.Get("user")
+		if !ok {
+			c.Next()
+			return
+		}
+		claims, ok := v.(UserClaims)
+		if !ok || claims.ImpersonatedBy == "" {
+			c.Next()
+			return
+		}
+
+		// Headers must be set before the handler writes the body
+		c.Header(ImpersonatedByHeader, claims.ImpersonatedBy)
+		c.Next()
+
+		details := buildAuditDetails(c)
+		details["impersonated_user"] = claims.Email
+		// Detached like LogAction: the request context is cancelled once
+		// the handler returns
+		ctx := context.WithoutCancel(c.Request.Context())
+		go func() {
+			_ = events.Create(ctx, models.AuditEvent{
+				Actor:      claims.ImpersonatedBy,
+				Action:     "impersonation.request",
+				TargetType: "user",
+				TargetID:   int(claims.UserID),
+				Details:    details,
+			})
+		}()
+	}
+}
+
+// DenyImpersonation refuses impersonation tokens on routes that change how
+// the user signs in, such as sudo, two-factor enrollment and sessions, which
+// support staff must not touch. Must run after Auth or AuthOrAPIKey.
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if v, ok := c.Get("user"); ok {
+			if claims, ok := v.(UserClaims); ok && claims.ImpersonatedBy != "" {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"error":        "not available while impersonating",
+					"impersonated": true,
+				})
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+// chanAuditRepo hands created events to a channel, since Impersonation
+// records them in the background
+type chanAuditRepo struct {
+	store.AuditEventRepository
+	events chan models.AuditEvent
+}
+
+func (r *chanAuditRepo) Create(ctx context.Context, event models.AuditEvent) error {
+	r.events <- event
+	return nil
+}
+
+func TestImpersonation_AuditsAndFlags(t *testing.T) {
+	secret := "test-secret"
+	token, _ := SignJWT(secret, jwt.MapClaims{
+		"sub":             "clinician@example.com",
+		"user_id":         float64(7),
+		"role":            "clinician",
+		"scope":           "diana",
+		"exp":             time.Now().Add(time.Minute).Unix(),
+		"impersonated_by": "admin@example.com",
+	})
+	repo := &chanAuditRepo{events: make(chan models.AuditEvent, 1)}
+
+	r := gin.New()
+	r.Use(Auth(secret), Impersonation(repo))
+	r.GET("/patients", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+
+	req, _ := http.NewRequest(http.MethodGet, "/patients", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get(ImpersonatedByHeader); got != "admin@example.com" {
+		t.Fatalf("expected banner header naming the admin, got %q", got)
+	}
+	select {
+	case e := <-repo.events:
+		if e.Actor != "admin@example.com" || e.Action != "impersonation.request" || e.TargetID != 7 {
+			t.Fatalf("unexpected event %+v", e)
+		}
+		if e.Details["path"] != "/patients" || e.Details["status"] != http.StatusNotFound || e.Details["impersonated_user"] != "clinician@example.com" {
+			t.Fatalf("unexpected details %+v", e.Details)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request was not audited")
+	}
+}
+
+func TestImpersonation_OwnTokenUntouched(t *testing.T) {
+	repo := &chanAuditRepo{events: make(chan models.AuditEvent, 1)}
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user", UserClaims{UserID: 7, Email: "clinician@example.com"}) })
+	r.Use(Impersonation(repo))
+	r.GET("/patients", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req, _ := http.NewRequest(http.MethodGet, "/patients", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Header().Get(ImpersonatedByHeader) != "" {
+		t.Fatal("unexpected banner header")
+	}
+	select {
+	case e := <-repo.events:
+		t.Fatalf("unexpected event %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDenyImpersonation(t *testing.T) {
+	for name, tc := range map[string]struct {
+		claims UserClaims
+		want   int
+	}{
+		"own token":     {UserClaims{UserID: 1}, http.StatusOK},
+		"impersonating": {UserClaims{UserID: 1, ImpersonatedBy: "admin@example.com"}, http.StatusForbidden},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("user", tc.claims) })
+			r.Use(DenyImpersonation())
+			r.POST("/auth/sudo", func(c *gin.Context) { c.Status(http.StatusOK) })
+			req, _ := http.NewRequest(http.MethodPost, "/auth/sudo", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, w.Code)
+			}
+		})
+	}
+}
@@ -1,15 +1,13 @@
 package middleware
 
 import (
-	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
+	"github.com/skufu/DianaV2/backend/internal/logging"
 )
 
 // RequestID middleware adds a unique request ID to each request
@@ -25,21 +23,25 @@ func RequestID() gin.HandlerFunc {
 		c.Set("request_id", requestID)
 		c.Header("X-Request-ID", requestID)
 
-		// Add to request context for downstream use
-		ctx := context.WithValue(c.Request.Context(), "request_id", requestID)
-		c.Request = c.Request.WithContext(ctx)
+		// Add to request context so handlers, the store and background
+		// jobs log it through logging.Ctx
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID))
 
 		c.Next()
 	}
 }
 
-// Logger returns a gin middleware that logs HTTP requests using structured logging
+// Logger returns a gin middleware that logs HTTP requests using structured
+// logging. With PII redaction on, the query string is left out and client
+// IPs are hashed.
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
 		raw := c.Request.URL.RawQuery
-		requestID, _ := c.Get("request_id")
+		if logging.Redacting() {
+			raw = ""
+		}
 
 		// Skip health check endpoints in production to reduce noise
 		if shouldSkipLogging(path) {
@@ -47,13 +49,15 @@ func Logger() gin.HandlerFunc {
 			return
 		}
 
+		logger := logging.Ctx(c.Request.Context())
+		ip := logging.PII(c.ClientIP())
+
 		// Log incoming request
-		log.Debug().
-			Str("request_id", requestID.(string)).
+		logger.Debug().
 			Str("method", c.Request.Method).
 			Str("path", path).
 			Str("query", raw).
-			Str("ip", c.ClientIP()).
+			Str("ip", ip).
 			Str("user_agent", c.Request.UserAgent()).
 			Int64("content_length", c.Request.ContentLength).
 			Msg("incoming request")
@@ -66,15 +70,14 @@ func Logger() gin.HandlerFunc {
 		responseSize := c.Writer.Size()
 
 		// Build base log event with common fields
-		event := getLogEventForStatus(c.Writer.Status()).
-			Str("request_id", requestID.(string)).
+		event := getLogEventForStatus(logger, c.Writer.Status()).
 			Str("method", c.Request.Method).
 			Str("path", path).
 			Int("status", c.Writer.Status()).
 			Dur("latency", latency).
 			Str("latency_human", latency.String()).
 			Int("response_size", responseSize).
-			Str("ip", c.ClientIP())
+			Str("ip", ip)
 
 		// Add query params if present
 		if raw != "" {
@@ -95,12 +98,12 @@ func Logger() gin.HandlerFunc {
 			event.Bool("slow_request", true)
 		}
 
-		// Add user context if available (from JWT)
-		if userEmail, exists := c.Get("user_email"); exists {
-			event.Str("user_email", userEmail.(string))
-		}
-		if userRole, exists := c.Get("user_role"); exists {
-			event.Str("user_role", userRole.(string))
+		// Add user context if available (from JWT or API key). The ID is
+		// logged rather than the email.
+		if claims, ok := c.Get("user"); ok {
+			if uc, ok := claims.(UserClaims); ok {
+				event.Int64("user_id", uc.UserID).Str("user_role", uc.Role)
+			}
 		}
 
 		// Log with appropriate message based on status
@@ -109,55 +112,6 @@ func Logger() gin.HandlerFunc {
 	}
 }
 
-// InitLogger initializes the global zerolog logger with appropriate settings for different environments
-func InitLogger(env string) {
-	// Set consistent time format
-	zerolog.TimeFieldFormat = time.RFC3339Nano
-	zerolog.TimestampFieldName = "timestamp"
-	zerolog.MessageFieldName = "message"
-	zerolog.LevelFieldName = "level"
-
-	// Configure log level based on environment
-	switch env {
-	case "prod", "production":
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
-		// Production: structured JSON logging
-		log.Logger = zerolog.New(os.Stdout).With().
-			Timestamp().
-			Str("service", "diana-api").
-			Str("version", getVersion()).
-			Str("environment", env).
-			Logger()
-
-	case "staging", "stage":
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
-		// Staging: JSON with some debug info
-		log.Logger = zerolog.New(os.Stdout).With().
-			Timestamp().
-			Str("service", "diana-api").
-			Str("environment", env).
-			Logger()
-
-	default: // dev, development, or any other value
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
-		// Development: pretty console logging
-		consoleWriter := zerolog.ConsoleWriter{
-			Out:        os.Stderr,
-			TimeFormat: "15:04:05",
-			NoColor:    false,
-		}
-		log.Logger = zerolog.New(consoleWriter).With().
-			Timestamp().
-			Str("service", "diana-api").
-			Logger()
-	}
-
-	// Set global context fields
-	log.Logger = log.Logger.With().
-		Str("go_version", getGoVersion()).
-		Logger()
-}
-
 // Helper functions
 
 func generateRequestID() string {
@@ -177,16 +131,16 @@ func shouldSkipLogging(path string) bool {
 	return false
 }
 
-func getLogEventForStatus(status int) *zerolog.Event {
+func getLogEventForStatus(logger *zerolog.Logger, status int) *zerolog.Event {
 	switch {
 	case status >= 500:
-		return log.Error()
+		return logger.Error()
 	case status >= 400:
-		return log.Warn()
+		return logger.Warn()
 	case status >= 300:
-		return log.Info()
+		return logger.Info()
 	default:
-		return log.Info()
+		return logger.Info()
 	}
 }
 
@@ -203,18 +157,6 @@ func getLogMessage(status int, latency time.Duration) string {
 	}
 }
 
-func getVersion() string {
-	if version := os.Getenv("APP_VERSION"); version != "" {
-		return version
-	}
-	return "dev"
-}
-
-func getGoVersion() string {
-	// This would typically come from build info in a real app
-	return "go1.21"
-}
-
 // GetRequestID extracts the request ID from gin context
 func GetRequestID(c *gin.Context) string {
 	if requestID, exists := c.Get("request_id"); exists {
@@ -225,7 +167,5 @@ func GetRequestID(c *gin.Context) string {
 
 // LogWithRequestID creates a logger with request ID context
 func LogWithRequestID(c *gin.Context) *zerolog.Logger {
-	requestID := GetRequestID(c)
-	logger := log.With().Str("request_id", requestID).Logger()
-	return &logger
+	return logging.Ctx(c.Request.Context())
 }
\ No newline at end of file
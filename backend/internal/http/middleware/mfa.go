@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireMFAEnrollment refuses tokens issued to users who must enroll in
+// two-factor authentication first. Enrollment routes are registered outside
+// the groups that use it. Must run after Auth or AuthOrAPIKey.
+func RequireMFAEnrollment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, ok := c.Get("user"); ok && claims.(UserClaims).MFASetupOnly {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":                   "two-factor authentication must be set up first",
+				"mfa_enrollment_required": true,
+			})
+			return
+		}
+		c.Next()
+	}
+}
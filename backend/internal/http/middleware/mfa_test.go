@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireMFAEnrollment(t *testing.T) {
+	for name, tc := range map[string]struct {
+		claims UserClaims
+		want   int
+	}{
+		"enrolled or not required": {UserClaims{UserID: 1}, http.StatusOK},
+		"enrollment pending":       {UserClaims{UserID: 1, MFASetupOnly: true}, http.StatusForbidden},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("user", tc.claims) })
+			r.Use(RequireMFAEnrollment())
+			r.GET("/x", func(c *gin.Context) { c.Status(http.StatusOK) })
+			req, _ := http.NewRequest(http.MethodGet, "/x", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, w.Code)
+			}
+		})
+	}
+}
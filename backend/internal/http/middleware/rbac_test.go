@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -197,3 +199,133 @@ func TestAdminOnly(t *testing.T) {
 		})
 	}
 }
+
+// testPermissions grants clinicians patients.read, and clinic_admin of
+// clinic 4 to user 7
+func testPermissions(ctx context.Context, user UserClaims, clinicID int64, permission string) (bool, error) {
+	if user.Role == "broken" {
+		return false, errors.New("db down")
+	}
+	if user.Role == "clinician" && permission == "patients.read" {
+		return true, nil
+	}
+	return clinicID == 4 && user.UserID == 7 && permission == "clinic.manage", nil
+}
+
+func permissionRouter(role string, userID int64, mw gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user", UserClaims{UserID: userID, Role: role})
+		c.Next()
+	})
+	r.Use(mw)
+	r.Any("/clinics/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.Any("/patients", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestPermissionRequired(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		role       string
+		wantStatus int
+	}{
+		{"granted", "clinician", http.StatusOK},
+		{"not granted", "member", http.StatusForbidden},
+		{"lookup fails", "broken", http.StatusInternalServerError},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := permissionRouter(tt.role, 1, PermissionRequired(testPermissions, "patients.read"))
+			req, _ := http.NewRequest(http.MethodGet, "/patients", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestReadWritePermission(t *testing.T) {
+	r := permissionRouter("clinician", 1, ReadWritePermission(testPermissions, "patients.read", "patients.write"))
+	for method, want := range map[string]int{
+		http.MethodGet:    http.StatusOK,
+		http.MethodHead:   http.StatusOK,
+		http.MethodPost:   http.StatusForbidden,
+		http.MethodDelete: http.StatusForbidden,
+	} {
+		req, _ := http.NewRequest(method, "/patients", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d", method, want, w.Code)
+		}
+	}
+}
+
+func TestClinicPermissionRequired(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		userID     int64
+		path       string
+		wantStatus int
+	}{
+		{"own clinic", 7, "/clinics/4", http.StatusOK},
+		{"other clinic", 7, "/clinics/5", http.StatusForbidden},
+		{"not clinic admin", 8, "/clinics/4", http.StatusForbidden},
+		{"invalid id", 7, "/clinics/abc", http.StatusBadRequest},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := permissionRouter("clinician", tt.userID, ClinicPermissionRequired(testPermissions, "clinic.manage"))
+			req, _ := http.NewRequest(http.MethodPut, tt.path, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestClinicReadWritePermission(t *testing.T) {
+	// Clinicians read globally; user 9 reads through their role in clinic 4
+	lookup := func(ctx context.Context, user UserClaims, clinicID int64, permission string) (bool, error) {
+		if permission != "patients.read" {
+			return false, nil
+		}
+		return user.Role == "clinician" || (clinicID == 4 && user.UserID == 9), nil
+	}
+	clinics := func(ctx context.Context, userID int64) ([]int32, error) {
+		switch userID {
+		case 9:
+			return []int32{3, 4}, nil
+		case 10:
+			return nil, errors.New("db down")
+		}
+		return []int32{3}, nil
+	}
+	for _, tt := range []struct {
+		name       string
+		role       string
+		userID     int64
+		method     string
+		wantStatus int
+	}{
+		{"global read", "clinician", 1, http.MethodGet, http.StatusOK},
+		{"clinic role read", "member", 9, http.MethodGet, http.StatusOK},
+		{"clinic role does not write", "member", 9, http.MethodPost, http.StatusForbidden},
+		{"no clinic grants read", "member", 8, http.MethodGet, http.StatusForbidden},
+		{"membership lookup fails", "member", 10, http.MethodGet, http.StatusInternalServerError},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := permissionRouter(tt.role, tt.userID, ClinicReadWritePermission(lookup, clinics, "patients.read", "patients.write"))
+			req, _ := http.NewRequest(tt.method, "/patients", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
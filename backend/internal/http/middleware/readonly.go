@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadOnlyGuard rejects state-changing requests with 503 while isReadOnly
+// reports that the database is not accepting writes (e.g. after a failover).
+// Safe methods keep working so clinicians can still look up patients.
+func ReadOnlyGuard(isReadOnly func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if isReadOnly() {
+			c.Header("Retry-After", "30")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":     "database is in read-only mode; changes cannot be saved right now, please retry shortly",
+				"read_only": true,
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadOnlyGuard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	readOnly := false
+	r := gin.New()
+	r.Use(ReadOnlyGuard(func() bool { return readOnly }))
+	r.Any("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"method": c.Request.Method})
+	})
+
+	cases := []struct {
+		method   string
+		readOnly bool
+		want     int
+	}{
+		{"GET", false, http.StatusOK},
+		{"POST", false, http.StatusOK},
+		{"GET", true, http.StatusOK},
+		{"HEAD", true, http.StatusOK},
+		{"POST", true, http.StatusServiceUnavailable},
+		{"PUT", true, http.StatusServiceUnavailable},
+		{"PATCH", true, http.StatusServiceUnavailable},
+		{"DELETE", true, http.StatusServiceUnavailable},
+	}
+	for _, tc := range cases {
+		readOnly = tc.readOnly
+		req, _ := http.NewRequest(tc.method, "/test", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s (read-only=%v): got %d, want %d", tc.method, tc.readOnly, w.Code, tc.want)
+		}
+		if tc.want == http.StatusServiceUnavailable && w.Header().Get("Retry-After") == "" {
+			t.Errorf("%s: missing Retry-After header", tc.method)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// sloWindow is how many recent requests per route the report is computed over.
+const sloWindow = 1000
+
+// sloWarnInterval limits slow-request warnings to one per route per interval.
+const sloWarnInterval = time.Minute
+
+// SLORoute is the latency report for one route over its recent window.
+// BudgetBurn is the share of requests over target divided by the share the
+// objective allows: above 1 the error budget is being spent faster than it
+// accrues.
+type SLORoute struct {
+	Route      string  `json:"route"`
+	TargetMS   int64   `json:"target_ms"`
+	Count      int     `json:"count"`
+	OverTarget int     `json:"over_target"`
+	P50MS      float64 `json:"p50_ms"`
+	P95MS      float64 `json:"p95_ms"`
+	P99MS      float64 `json:"p99_ms"`
+	BudgetBurn float64 `json:"budget_burn"`
+	// Total counts every request since startup, not just the window
+	Total int64 `json:"total"`
+}
+
+type sloRoute struct {
+	target   time.Duration
+	samples  []time.Duration // ring buffer of the last sloWindow latencies
+	next     int
+	total    int64
+	lastWarn time.Time
+}
+
+// SLOTracker records per-route latency against targets. Routes are keyed by
+// method and route pattern, e.g. "POST /api/v1/patients/:id/assessments";
+// routes without their own target use the default.
+type SLOTracker struct {
+	defaultTarget time.Duration
+	targets       map[string]time.Duration
+	objective     float64
+
+	mu     sync.Mutex
+	routes map[string]*sloRoute
+	now    func() time.Time
+}
+
+// NewSLOTracker creates a tracker. objective is the fraction of requests that
+// should finish within target, e.g. 0.99.
+func NewSLOTracker(defaultTarget time.Duration, targets map[string]time.Duration, objective float64) *SLOTracker {
+	return &SLOTracker{
+		defaultTarget: defaultTarget,
+		targets:       targets,
+		objective:     objective,
+		routes:        map[string]*sloRoute{},
+		now:           time.Now,
+	}
+}
+
+// Objective returns the fraction of requests expected within target.
+func (t *SLOTracker) Objective() float64 { return t.objective }
+
+// Window returns how many recent requests per route the report covers.
+func (t *SLOTracker) Window() int { return sloWindow }
+
+// Middleware times every matched route. Unmatched requests (404s) are not
+// recorded so scanners cannot flood the report with made-up paths.
+func (t *SLOTracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := t.now()
+		c.Next()
+		if c.FullPath() == "" {
+			return
+		}
+		t.Record(c.Request.Method+" "+c.FullPath(), t.now().Sub(start))
+	}
+}
+
+// Record adds one request's latency and logs a warning, at most once a
+// minute per route, when it exceeds the route's target.
+func (t *SLOTracker) Record(route string, latency time.Duration) {
+	t.mu.Lock()
+	r, ok := t.routes[route]
+	if !ok {
+		target, ok := t.targets[route]
+		if !ok {
+			target = t.defaultTarget
+		}
+		r = &sloRoute{target: target, samples: make([]time.Duration, 0, sloWindow)}
+		t.routes[route] = r
+	}
+	if len(r.samples) < sloWindow {
+		r.samples = append(r.samples, latency)
+	} else {
+		r.samples[r.next] = latency
+	}
+	r.next = (r.next + 1) % sloWindow
+	r.total++
+
+	warn := false
+	if latency > r.target && t.now().Sub(r.lastWarn) >= sloWarnInterval {
+		r.lastWarn = t.now()
+		warn = true
+	}
+	target := r.target
+	t.mu.Unlock()
+
+	if warn {
+		log.Warn().Msgf("SLO: %s took %s, over its %s target", route, latency.Round(time.Millisecond), target)
+	}
+}
+
+// Report returns every recorded route, the worst budget burn first.
+func (t *SLOTracker) Report() []SLORoute {
+	t.mu.Lock()
+	out := make([]SLORoute, 0, len(t.routes))
+	for name, r := range t.routes {
+		out = append(out, r.report(name, t.objective))
+	}
+	t.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].BudgetBurn != out[j].BudgetBurn {
+			return out[i].BudgetBurn > out[j].BudgetBurn
+		}
+		return out[i].Route < out[j].Route
+	})
+	return out
+}
+
+func (r *sloRoute) report(name string, objective float64) SLORoute {
+	sorted := append([]time.Duration(nil), r.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	over := 0
+	for _, d := range sorted {
+		if d > r.target {
+			over++
+		}
+	}
+	rep := SLORoute{
+		Route:      name,
+		TargetMS:   r.target.Milliseconds(),
+		Count:      len(sorted),
+		OverTarget: over,
+		P50MS:      percentileMS(sorted, 0.50),
+		P95MS:      percentileMS(sorted, 0.95),
+		P99MS:      percentileMS(sorted, 0.99),
+		Total:      r.total,
+	}
+	if len(sorted) > 0 && objective < 1 {
+		rep.BudgetBurn = float64(over) / float64(len(sorted)) / (1 - objective)
+	}
+	return rep
+}
+
+// percentileMS returns the nearest-rank percentile of sorted in milliseconds.
+func percentileMS(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return float64(sorted[rank].Microseconds()) / 1000
+}
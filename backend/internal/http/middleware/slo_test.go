@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSLOTracker_Report(t *testing.T) {
+	slow := "POST /api/v1/patients/:id/assessments"
+	tr := NewSLOTracker(100*time.Millisecond, map[string]time.Duration{slow: time.Second}, 0.99)
+
+	// 100 requests at 10..1000ms; 90 exceed the 100ms default target
+	for i := 1; i <= 100; i++ {
+		tr.Record("GET /api/v1/patients", time.Duration(i*10)*time.Millisecond)
+	}
+	for i := 0; i < 98; i++ {
+		tr.Record(slow, 500*time.Millisecond)
+	}
+	tr.Record(slow, 2*time.Second)
+	tr.Record(slow, 3*time.Second)
+
+	rep := tr.Report()
+	if len(rep) != 2 {
+		t.Fatalf("expected 2 routes, got %+v", rep)
+	}
+	list, assess := rep[0], rep[1]
+	if list.Route != "GET /api/v1/patients" {
+		t.Fatalf("worst burn should come first, got %s", list.Route)
+	}
+	if list.TargetMS != 100 || list.OverTarget != 90 || list.P50MS != 500 || list.P95MS != 950 || list.P99MS != 990 {
+		t.Fatalf("unexpected list report %+v", list)
+	}
+	if list.BudgetBurn < 89.9 || list.BudgetBurn > 90.1 {
+		t.Fatalf("burn = %v, want 90", list.BudgetBurn)
+	}
+	// 2% over a 1% budget
+	if assess.TargetMS != 1000 || assess.OverTarget != 2 || assess.BudgetBurn < 1.99 || assess.BudgetBurn > 2.01 {
+		t.Fatalf("unexpected assessment report %+v", assess)
+	}
+	if assess.P99MS != 2000 {
+		t.Fatalf("p99 = %v, want 2000", assess.P99MS)
+	}
+}
+
+func TestSLOTracker_WindowSlides(t *testing.T) {
+	tr := NewSLOTracker(time.Second, nil, 0.99)
+	for i := 0; i < sloWindow; i++ {
+		tr.Record("GET /x", 5*time.Second)
+	}
+	for i := 0; i < sloWindow; i++ {
+		tr.Record("GET /x", time.Millisecond)
+	}
+	rep := tr.Report()[0]
+	if rep.Count != sloWindow || rep.OverTarget != 0 || rep.Total != 2*sloWindow {
+		t.Fatalf("old samples should have left the window: %+v", rep)
+	}
+}
+
+func TestSLOTracker_MiddlewareSkipsUnmatched(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tr := NewSLOTracker(time.Second, nil, 0.99)
+	r := gin.New()
+	r.Use(tr.Middleware())
+	r.GET("/patients/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/patients/1", "/patients/2", "/wp-login.php"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	rep := tr.Report()
+	if len(rep) != 1 || rep[0].Route != "GET /patients/:id" || rep[0].Count != 2 {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+}
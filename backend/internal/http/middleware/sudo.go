@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireSudo guards destructive operations behind a recent re-authentication.
+// The access token must carry a sudo_until claim in the future, which is only
+// issued by POST /auth/sudo after the user re-enters their password.
+// This middleware must be used AFTER the Auth middleware since it depends on UserClaims.
+//
+// Example usage:
+//
+//	users.DELETE("/:id", middleware.RequireSudo(), h.deactivateUser)
+func RequireSudo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userInterface, exists := c.Get("user")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "authentication required",
+			})
+			return
+		}
+
+		claims, ok := userInterface.(UserClaims)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "invalid user context",
+			})
+			return
+		}
+
+		if claims.SudoUntil.IsZero() || time.Now().After(claims.SudoUntil) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":         "re-authentication required",
+				"sudo_required": true,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
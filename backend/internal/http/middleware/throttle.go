@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// RateLimitStore keeps token buckets. Each bucket holds up to limit tokens
+// and refills continuously at limit per window; Take spends one token.
+// When no token is available it reports how long until one will be.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error)
+}
+
+// MemoryRateLimitStore keeps buckets in process memory. Limits are per
+// instance, so use a shared store when running several replicas.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	window  time.Duration
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{buckets: map[string]*bucket{}, now: time.Now}
+	go s.cleanup()
+	return s
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	perSecond := float64(limit) / window.Seconds()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit), updated: now, window: window}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit), b.tokens+now.Sub(b.updated).Seconds()*perSecond)
+	b.updated = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	wait := time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	return false, wait, nil
+}
+
+// cleanup drops buckets that have been idle long enough to be full again.
+func (s *MemoryRateLimitStore) cleanup() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.mu.Lock()
+		now := s.now()
+		for k, b := range s.buckets {
+			if now.Sub(b.updated) > b.window {
+				delete(s.buckets, k)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// ThrottleKey identifies who a request is counted against.
+type ThrottleKey func(c *gin.Context) string
+
+// ByIP counts requests per client IP.
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByRouteAndIP counts requests per client IP separately for each route.
+func ByRouteAndIP(c *gin.Context) string {
+	return c.FullPath() + ":" + ByIP(c)
+}
+
+// ByUser counts requests per authenticated user, falling back to the client
+// IP for anonymous requests. It must run after Auth.
+func ByUser(c *gin.Context) string {
+	if user, exists := c.Get("user"); exists {
+		if claims, ok := user.(UserClaims); ok {
+			return fmt.Sprintf("user:%d", claims.UserID)
+		}
+	}
+	return ByIP(c)
+}
+
+// ByAPIToken counts requests per scoped API token, falling back to the client
+// IP. It must run after APITokenAuth.
+func ByAPIToken(c *gin.Context) string {
+	if v, exists := c.Get("api_token"); exists {
+		if claims, ok := v.(APITokenClaims); ok {
+			return fmt.Sprintf("token:%d", claims.TokenID)
+		}
+	}
+	return ByIP(c)
+}
+
+// Throttle allows limit requests per window for each key in the given scope
+// and answers 429 with Retry-After once a bucket is empty. A limit of 0
+// disables throttling. If the store fails the request is let through, so a
+// limiter outage never takes the API down with it.
+func Throttle(store RateLimitStore, scope string, limit int, window time.Duration, key ThrottleKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+		ok, wait, err := store.Take(c.Request.Context(), scope+":"+key(c), limit, window)
+		if err != nil {
+			log.Error().Err(err).Msgf("rate limit store error (%s)", scope)
+			c.Next()
+			return
+		}
+		if !ok {
+			secs := int(math.Ceil(wait.Seconds()))
+			if secs < 1 {
+				secs = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(secs))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":       "rate limit exceeded",
+				"retry_after": secs,
+			})
+			return
+		}
+		c.Next()
+	}
+}
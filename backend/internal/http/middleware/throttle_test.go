@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMemoryRateLimitStore_RefillsContinuously(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := &MemoryRateLimitStore{buckets: map[string]*bucket{}, now: func() time.Time { return now }}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := s.Take(ctx, "k", 2, time.Minute); !ok {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+	ok, wait, _ := s.Take(ctx, "k", 2, time.Minute)
+	if ok {
+		t.Fatal("third request should be denied")
+	}
+	if wait != 30*time.Second {
+		t.Fatalf("wait = %v, want 30s (one token per 30s)", wait)
+	}
+
+	// Half a token's worth of time is not enough, a full one is
+	now = now.Add(15 * time.Second)
+	if ok, _, _ := s.Take(ctx, "k", 2, time.Minute); ok {
+		t.Fatal("should still be denied after 15s")
+	}
+	now = now.Add(15 * time.Second)
+	if ok, _, _ := s.Take(ctx, "k", 2, time.Minute); !ok {
+		t.Fatal("should be allowed after 30s")
+	}
+
+	if ok, _, _ := s.Take(ctx, "other", 2, time.Minute); !ok {
+		t.Fatal("other keys have their own bucket")
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	return false, 0, errors.New("store down")
+}
+
+func throttleRouter(store RateLimitStore, limit int, key ThrottleKey) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if c.GetHeader("X-User") != "" {
+			c.Set("user", UserClaims{UserID: 1, Email: "a@example.com"})
+		}
+		c.Next()
+	})
+	r.Use(Throttle(store, "test", limit, time.Minute, key))
+	r.GET("/a", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/b", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func throttleGet(r *gin.Engine, path, ip string, user bool) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = ip + ":1234"
+	if user {
+		req.Header.Set("X-User", "1")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestThrottle_RetryAfter(t *testing.T) {
+	r := throttleRouter(NewMemoryRateLimitStore(), 1, ByIP)
+
+	if w := throttleGet(r, "/a", "10.0.0.1", false); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	w := throttleGet(r, "/a", "10.0.0.1", false)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("Retry-After = %q, want 60", w.Header().Get("Retry-After"))
+	}
+	if w := throttleGet(r, "/a", "10.0.0.2", false); w.Code != http.StatusOK {
+		t.Fatalf("other IP: expected 200, got %d", w.Code)
+	}
+}
+
+func TestThrottle_Keys(t *testing.T) {
+	// Per route: /a and /b have separate buckets for the same IP
+	r := throttleRouter(NewMemoryRateLimitStore(), 1, ByRouteAndIP)
+	throttleGet(r, "/a", "10.0.0.1", false)
+	if w := throttleGet(r, "/b", "10.0.0.1", false); w.Code != http.StatusOK {
+		t.Fatalf("ByRouteAndIP: expected 200 on other route, got %d", w.Code)
+	}
+
+	// Per user: the same user is limited across IPs
+	r = throttleRouter(NewMemoryRateLimitStore(), 1, ByUser)
+	throttleGet(r, "/a", "10.0.0.1", true)
+	if w := throttleGet(r, "/a", "10.0.0.2", true); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("ByUser: expected 429 from second IP, got %d", w.Code)
+	}
+}
+
+func TestThrottle_DisabledAndFailOpen(t *testing.T) {
+	r := throttleRouter(failingStore{}, 0, ByIP)
+	if w := throttleGet(r, "/a", "10.0.0.1", false); w.Code != http.StatusOK {
+		t.Fatalf("limit 0: expected 200, got %d", w.Code)
+	}
+	r = throttleRouter(failingStore{}, 1, ByIP)
+	if w := throttleGet(r, "/a", "10.0.0.1", false); w.Code != http.StatusOK {
+		t.Fatalf("store error: expected request to pass, got %d", w.Code)
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+)
+
+// RequestTimeout gives each request a deadline on its context, so queries
+// and model calls made with it are cancelled once it passes instead of
+// holding a database connection. routes maps "METHOD /route/pattern" to its
+// own deadline; a pattern ending in * covers every route under it, the
+// longest match winning. Other routes get fallback; zero means no deadline.
+//
+// A handler that fails after the deadline answers 504 instead of its usual
+// 5xx; one that never answered gets a 504 here.
+func RequestTimeout(fallback time.Duration, routes map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d := routeTimeout(c.Request.Method+" "+c.FullPath(), fallback, routes)
+		if d <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		w := &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx}
+		c.Writer = w
+		defer func() { c.Writer = w.ResponseWriter }()
+
+		c.Next()
+
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return
+		}
+		logging.Ctx(ctx).Warn().Msgf("%s %s ran past its %s deadline", c.Request.Method, c.FullPath(), d)
+		if !c.Writer.Written() {
+			c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "request timed out"})
+		}
+	}
+}
+
+// routeTimeout picks the deadline of route: its own, else that of the
+// longest wildcard pattern covering it, else fallback
+func routeTimeout(route string, fallback time.Duration, routes map[string]time.Duration) time.Duration {
+	if d, ok := routes[route]; ok {
+		return d
+	}
+	best := -1
+	for pattern, d := range routes {
+		prefix, wildcard := strings.CutSuffix(pattern, "*")
+		if wildcard && strings.HasPrefix(route, prefix) && len(prefix) > best {
+			best, fallback = len(prefix), d
+		}
+	}
+	return fallback
+}
+
+// timeoutWriter turns a server error written after the deadline into a 504,
+// since the handler most likely failed because its context was cancelled
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx context.Context
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if code >= http.StatusInternalServerError && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		code = http.StatusGatewayTimeout
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestTimeout(20*time.Millisecond, map[string]time.Duration{
+		"GET /export/*":      time.Second,
+		"GET /export/quick":  20 * time.Millisecond,
+		"GET /unlimited/now": 0,
+	}))
+	// slow waits for its context like a query would and reports the failure
+	slow := func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list patients"})
+		case <-time.After(100 * time.Millisecond):
+			c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		}
+	}
+	r.GET("/patients", slow)
+	r.GET("/export/patients.csv", slow)
+	r.GET("/export/quick", slow)
+	r.GET("/unlimited/now", slow)
+	r.GET("/silent", func(c *gin.Context) { <-c.Request.Context().Done() })
+
+	for path, want := range map[string]int{
+		"/patients":            http.StatusGatewayTimeout,
+		"/export/patients.csv": http.StatusOK,
+		"/export/quick":        http.StatusGatewayTimeout,
+		"/unlimited/now":       http.StatusOK,
+		"/silent":              http.StatusGatewayTimeout,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d: %s", path, want, w.Code, w.Body.String())
+		}
+	}
+}
@@ -7,27 +7,61 @@ import (
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
+	"github.com/skufu/DianaV2/backend/internal/audit"
+	"github.com/skufu/DianaV2/backend/internal/chaos"
 	"github.com/skufu/DianaV2/backend/internal/config"
+	"github.com/skufu/DianaV2/backend/internal/events"
 	"github.com/skufu/DianaV2/backend/internal/http/handlers"
 	"github.com/skufu/DianaV2/backend/internal/http/middleware"
+	"github.com/skufu/DianaV2/backend/internal/mail"
 	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/storage"
 	"github.com/skufu/DianaV2/backend/internal/store"
+	"github.com/skufu/DianaV2/backend/internal/tracing"
+	"github.com/skufu/DianaV2/backend/internal/webhooks"
+	"github.com/skufu/DianaV2/backend/internal/worker"
 
 	// Import docs for swagger registration
 	_ "github.com/skufu/DianaV2/backend/docs"
 )
 
-func New(cfg config.Config, st store.Store) *gin.Engine {
+// New builds the API router. dbMonitor may be nil; when set, writes are
+// rejected with 503 while the database is read-only. limits holds the rate
+// limit buckets; nil keeps them in memory. Background jobs started by
+// handlers run under workers so shutdown can drain them.
+func New(cfg config.Config, st store.Store, dbMonitor *store.ReadOnlyMonitor, limits middleware.RateLimitStore, workers *worker.Manager) *gin.Engine {
 	r := gin.New()
-	r.Use(gin.Logger(), gin.Recovery())
+	r.Use(gin.Recovery())
+
+	// Start a span per request, continuing the caller's trace when it sent
+	// a traceparent header
+	r.Use(otelgin.Middleware(tracing.ServiceName))
+
+	// Tag each request with an ID, log it, and map errors handlers attach
+	// with c.Error to a structured {code, message, request_id} body
+	r.Use(middleware.RequestID(), middleware.Logger(), middleware.ErrorHandler())
 
 	// Add security headers to all responses
 	r.Use(middleware.SecurityHeaders())
 
+	// Compress text, JSON and CSV bodies for clients that accept gzip
+	r.Use(middleware.Gzip())
+
+	// Per-route latency against SLO targets, reported at /admin/slo
+	sloTargets := make(map[string]time.Duration, len(cfg.SLOTargetsMS))
+	for route, ms := range cfg.SLOTargetsMS {
+		sloTargets[route] = time.Duration(ms) * time.Millisecond
+	}
+	slo := middleware.NewSLOTracker(time.Duration(cfg.SLODefaultTargetMS)*time.Millisecond, sloTargets, cfg.SLOObjective)
+	r.Use(slo.Middleware())
+
 	corsCfg := cors.Config{
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		ExposeHeaders:    []string{middleware.ImpersonatedByHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
@@ -45,66 +79,287 @@ func New(cfg config.Config, st store.Store) *gin.Engine {
 
 	api := r.Group("/api/v1")
 
-	handlers.RegisterHealth(api)
+	handlers.RegisterHealth(api, dbMonitor.ReadOnly)
+	api.Use(middleware.ReadOnlyGuard(dbMonitor.ReadOnly))
+
+	// Deadline per request, so a slow query gives its connection back
+	// instead of holding it until it finishes
+	requestTimeouts := make(map[string]time.Duration, len(cfg.RequestTimeoutsMS))
+	for route, ms := range cfg.RequestTimeoutsMS {
+		requestTimeouts[route] = time.Duration(ms) * time.Millisecond
+	}
+	api.Use(middleware.RequestTimeout(time.Duration(cfg.RequestTimeoutMS)*time.Millisecond, requestTimeouts))
+
+	// Fault injection for resilience testing; nil (a no-op) unless CHAOS_ENABLED
+	faults := chaos.FromConfig(cfg)
+	api.Use(faults.Middleware())
 
 	// Create rate limiter: 30 requests per minute for auth endpoints
 	rateLimiter := middleware.NewRateLimiter(30, time.Minute)
+	if limits == nil {
+		limits = middleware.NewMemoryRateLimitStore()
+	}
 
 	// Auth endpoints with rate limiting
 	authGroup := api.Group("/auth")
 	authGroup.Use(middleware.RateLimit(rateLimiter))
-	authHandler := handlers.NewAuthHandler(cfg, st)
+	// Login and refresh get their own stricter per-IP buckets against brute force
+	var mailer mail.Mailer = mail.NewLogMailer()
+	if cfg.SMTPHost != "" {
+		mailer = mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	}
+	authHandler := handlers.NewAuthHandler(cfg, st).WithCredentialThrottle(
+		middleware.Throttle(limits, "auth", cfg.LoginRateLimit, time.Minute, middleware.ByRouteAndIP),
+	).WithMailer(mailer)
 	authHandler.Register(authGroup)
 
 	protected := api.Group("")
-	protected.Use(middleware.Auth(cfg.JWTSecret))
+	// Integration scripts may send an X-API-Key instead of a JWT
+	protected.Use(middleware.AuthOrAPIKey(cfg.JWTSecret, handlers.APIKeyLookup(st), cfg.JWTPreviousSecrets...))
+	// Optionally refuse tokens of users deactivated since they were issued
+	var activeUser gin.HandlerFunc
+	if cfg.AuthActiveCheck {
+		activeUser = middleware.RequireActiveUser(handlers.ActiveUserLookup(st), time.Duration(cfg.AuthActiveCacheSeconds)*time.Second)
+		protected.Use(activeUser)
+	}
+	protected.Use(middleware.Throttle(limits, "api", cfg.APIRateLimit, time.Minute, middleware.ByUser))
+	// Users whose role requires two-factor authentication must enroll first
+	protected.Use(middleware.RequireMFAEnrollment())
+	// Support staff acting as a user are audited on every request
+	protected.Use(middleware.Impersonation(st.AuditEvents()))
+	// Route groups check the permissions the caller's role grants
+	perms := handlers.PermissionLookup(st)
+	// Reads may also be granted by a clinic role, for patients shared with
+	// that clinic
+	patientAccess := middleware.ClinicReadWritePermission(perms, handlers.ClinicMembershipLookup(st), models.PermPatientsRead, models.PermPatientsWrite)
+	patients := protected.Group("/patients", patientAccess)
+	// Analytics and exports cover only the caller's clinics, resolved once
+	accessScope := middleware.AccessScope(perms, handlers.ClinicMembershipLookup(st))
+	analytics := protected.Group("/analytics", middleware.PermissionRequired(perms, models.PermAnalyticsRead), accessScope)
+
+	// Two-factor enrollment stays reachable before enrolling, so it is not
+	// under protected
+	mfaGroup := api.Group("/auth/mfa")
+	mfaGroup.Use(middleware.Auth(cfg.JWTSecret, cfg.JWTPreviousSecrets...))
+	if activeUser != nil {
+		mfaGroup.Use(activeUser)
+	}
+	mfaGroup.Use(middleware.RateLimit(rateLimiter), middleware.DenyImpersonation())
+	authHandler.RegisterMFA(mfaGroup)
 
-	patientHandler := handlers.NewPatientsHandler(st)
-	patientHandler.Register(protected.Group("/patients"))
+	// Re-authentication (sudo) needs a valid session, so it sits behind Auth
+	sudoGroup := protected.Group("/auth")
+	sudoGroup.Use(middleware.RateLimit(rateLimiter), middleware.DenyImpersonation())
+	authHandler.RegisterProtected(sudoGroup)
+
+	// Everything the frontend loads on startup, in one request
+	handlers.NewBootstrapHandler(st, map[string]bool{
+		"assessments_immutable": cfg.AssessmentsImmutable,
+		"registration_open":     cfg.RegistrationOpen,
+		"email_delivery":        cfg.SMTPHost != "",
+		"live_model":            cfg.ModelURL != "",
+		// Running experiments
+		"recommendation_experiment": cfg.RecommendationExperiment,
+	}).Register(protected)
+
+	// Domain events: handlers publish, side effects subscribe
+	bus := events.NewBus()
+	audit.Subscribe(bus, st.AuditEvents())
+
+	patientHandler := handlers.NewPatientsHandler(st).WithEvents(bus).WithMailer(mailer)
+	patientHandler.Register(patients)
+
+	var blobs storage.Storage = storage.NewLocalStorage(cfg.StorageDir)
+	if cfg.StorageBackend == "s3" {
+		blobs = storage.NewS3Storage(storage.S3Options{
+			Bucket:          cfg.S3Bucket,
+			Region:          cfg.S3Region,
+			Endpoint:        cfg.S3Endpoint,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+		})
+	}
+	patientPhotosHandler := handlers.NewPatientPhotosHandler(st, blobs, cfg.PatientPhotoMaxBytes)
+	patientPhotosHandler.Register(patients)
+	patientPhotosHandler.Subscribe(bus)
+
+	// Download links are signed, so the download route sits outside auth
+	attachmentsHandler := handlers.NewAssessmentAttachmentsHandler(st, blobs, cfg.AttachmentMaxBytes, cfg.JWTSecret, time.Duration(cfg.AttachmentURLTTLMinutes)*time.Minute)
+	attachmentsHandler.Register(patients)
+	attachmentsHandler.RegisterDownload(api)
+	attachmentsHandler.Subscribe(bus)
 
 	timeout := time.Duration(cfg.ModelTimeoutMS) * time.Millisecond
 	var predictor ml.Predictor
+	var predictionCache *ml.CachingPredictor
 	if cfg.ModelURL != "" {
-		predictor = ml.NewHTTPPredictor(cfg.ModelURL, cfg.ModelVersion, timeout)
+		// Injected model failures are failures too, so the fallback covers them
+		remote := faults.Predictor(ml.NewHTTPPredictor(cfg.ModelURL, cfg.ModelVersion, timeout))
+		if cfg.PredictionCacheSize > 0 {
+			predictionCache = ml.NewCachingPredictor(remote, cfg.PredictionCacheSize, st.PredictionCache())
+			remote = predictionCache
+		}
+		predictor = ml.NewCompositePredictor(remote, ml.NewFallbackPredictor())
 	} else {
-		predictor = ml.NewMockPredictor()
+		predictor = faults.Predictor(ml.NewMockPredictor())
+	}
+	assessmentHandler := handlers.NewAssessmentsHandler(st, predictor, cfg.ModelVersion, cfg.DatasetHash).WithEvents(bus).WithImmutable(cfg.AssessmentsImmutable).WithRecommendationExperiment(cfg.RecommendationExperiment)
+	if cfg.PredictionMode == "async" {
+		predictions := handlers.NewPredictionQueue(st, predictor, cfg.PredictionWebhookURL).WithEvents(bus)
+		predictions.Start(workers)
+		assessmentHandler.WithAsyncPredictions(predictions)
+	}
+	assessmentHandler.Register(patients)
+	handlers.NewRiskAlerter(st, cfg.RiskAlertThreshold, time.Duration(cfg.RiskAlertCooldownHours)*time.Hour).Subscribe(bus)
+	handlers.NewBaselineChecker(st).Subscribe(bus)
+
+	// Follow-ups the caller has to attend to, and reminders as they fall due
+	handlers.NewFollowUpsHandler(st).Register(protected.Group("/follow-ups"))
+	reminders := handlers.NewFollowUpReminder(st, mailer, cfg.AppBaseURL, time.Duration(cfg.FollowUpReminderHours)*time.Hour).WithEvents(bus)
+	workers.Every("follow-up-reminders", 15*time.Minute, true, reminders.Run)
+
+	// The caller's tags, for ad-hoc cohorts in patient lists and analytics
+	handlers.NewTagsHandler(st).Register(protected.Group("/tags"))
+
+	// The caller's task inbox, fed by risk alerts and overdue patients
+	handlers.NewTasksHandler(st).Register(protected.Group("/tasks"))
+	tasks := handlers.NewTaskGenerator(st, time.Duration(cfg.TaskOverdueAssessmentDays)*24*time.Hour)
+	tasks.Subscribe(bus)
+	if cfg.TaskOverdueAssessmentDays > 0 {
+		workers.Every("overdue-assessment-tasks", time.Hour, true, tasks.Run)
+	}
+
+	// Signed deliveries of assessment and patient events to external systems
+	dispatcher := webhooks.NewDispatcher(st.Webhooks())
+	dispatcher.Subscribe(bus)
+	dispatcher.Start(workers)
+
+	// Deactivated users' data is purged after the grace period, hourly
+	if cfg.RetentionGraceDays > 0 {
+		retention := handlers.NewUserRetention(st, blobs, time.Duration(cfg.RetentionGraceDays)*24*time.Hour, cfg.RetentionMode)
+		retention.Subscribe(bus)
+		workers.Every("user-retention", time.Hour, true, retention.Run)
 	}
-	assessmentHandler := handlers.NewAssessmentsHandler(st, predictor, cfg.ModelVersion, cfg.DatasetHash)
-	assessmentHandler.Register(protected.Group("/patients"))
+
+	// Batch scoring for research re-scoring of historical cohorts
+	batchHandler := handlers.NewBatchAssessmentsHandler(assessmentHandler, cfg.BatchMaxItems, cfg.BatchWorkers)
+	batchHandler.Register(protected.Group("/assessments", middleware.PermissionRequired(perms, models.PermPatientsWrite)))
+
+	// HL7 FHIR R4 view of patients and assessments for hospital integrations
+	handlers.NewFHIRHandler(assessmentHandler, cfg.BatchMaxItems).Register(protected.Group("/fhir", patientAccess))
 
 	analyticsHandler := handlers.NewAnalyticsHandler(st)
-	analyticsHandler.Register(protected.Group("/analytics"))
+	analyticsHandler.Register(analytics)
+	handlers.NewDataQualityHandler(st).Register(analytics)
 
 	exportHandler := handlers.NewExportHandler(st, cfg.ExportMaxRows)
-	exportHandler.Register(protected.Group("/export"))
+	exportScope := middleware.RequireAPIKeyScope(models.APIKeyScopeExport)
+	exportHandler.Register(protected.Group("/export", exportScope, accessScope))
+	handlers.NewUserExportHandler(st).Register(protected.Group("/users", exportScope))
+	// The caller's signed-in devices
+	handlers.NewSessionsHandler(st).Register(protected.Group("/users/sessions", middleware.DenyImpersonation()))
+	// Sign-in attempts against the caller's account
+	loginHistoryHandler := handlers.NewLoginHistoryHandler(st)
+	loginHistoryHandler.Register(protected.Group("/users/login-history"))
+	// The caller's saved patient list views and client settings
+	handlers.NewPatientListViewsHandler(st).Register(protected.Group("/users/patient-views"))
+	handlers.NewUserPreferencesHandler(st).Register(protected.Group("/users/preferences"))
 
 	// Cohort analysis handler (extends analytics group)
 	cohortHandler := handlers.NewCohortHandler(st)
-	cohortHandler.Register(protected.Group("/analytics"))
+	cohortHandler.Register(analytics)
+
+	// Read-only analytics for embedded dashboards, authenticated with scoped
+	// API tokens rather than user JWTs
+	reporting := api.Group("/reporting")
+	reporting.Use(middleware.APITokenAuth(handlers.APITokenLookup(st), models.ScopeAnalyticsRead))
+	reporting.Use(middleware.Throttle(limits, "reporting", cfg.APIRateLimit, time.Minute, middleware.ByAPIToken))
+	analyticsHandler.Register(reporting.Group("/analytics"))
+	cohortHandler.Register(reporting.Group("/analytics"))
+
+	// Lab interfaces push HL7v2 results, which become assessment drafts
+	integrations := api.Group("/integrations")
+	integrations.Use(middleware.APITokenAuth(handlers.APITokenLookup(st), models.ScopeHL7Ingest))
+	integrations.Use(middleware.Throttle(limits, "integrations", cfg.APIRateLimit, time.Minute, middleware.ByAPIToken))
+	handlers.NewHL7Handler(st).Register(integrations)
 
 	// Clinic dashboard handler
 	clinicHandler := handlers.NewClinicDashboardHandler(st)
 	clinicHandler.Register(protected.Group("/clinics"))
 
-	// Admin routes - protected by RBAC middleware (admin role required)
+	// Admin routes - protected by RBAC middleware (admin.system permission)
 	adminGroup := protected.Group("/admin")
-	adminGroup.Use(middleware.RoleRequired("admin"))
+	adminGroup.Use(middleware.PermissionRequired(perms, models.PermAdminSystem))
 	{
 		// Dashboard statistics handler
 		adminHandler := handlers.NewAdminDashboardHandler(st)
 		adminHandler.Register(adminGroup)
 
-		// User management handler
-		adminUsersHandler := handlers.NewAdminUsersHandler(st)
-		adminUsersHandler.Register(adminGroup)
-
 		// Audit logs handler
-		adminAuditHandler := handlers.NewAdminAuditHandler(st)
+		adminAuditHandler := handlers.NewAdminAuditHandler(st).WithExportLimit(cfg.ExportMaxRows)
 		adminAuditHandler.Register(adminGroup)
 
 		// Model traceability handler
 		adminModelsHandler := handlers.NewAdminModelsHandler(st)
 		adminModelsHandler.Register(adminGroup)
+
+		// Scoped API tokens for reporting clients
+		adminAPITokensHandler := handlers.NewAdminAPITokensHandler(st)
+		adminAPITokensHandler.Register(adminGroup)
+
+		// Bulk re-validation of historical assessments
+		adminRevalidationHandler := handlers.NewAdminRevalidationHandler(st).WithWorkers(workers)
+		adminRevalidationHandler.Register(adminGroup)
+
+		// Re-scoring of stored assessments with a model run
+		adminRescoreHandler := handlers.NewAdminRescoreHandler(st, predictor).WithWorkers(workers)
+		adminRescoreHandler.Register(adminGroup)
+
+		// Latency SLO report
+		adminSLOHandler := handlers.NewAdminSLOHandler(slo)
+		adminSLOHandler.Register(adminGroup)
+
+		// Outgoing webhooks for external systems
+		adminWebhooksHandler := handlers.NewAdminWebhooksHandler(st).WithAllowHTTP(cfg.Env != "production" && cfg.Env != "prod")
+		adminWebhooksHandler.Register(adminGroup)
+
+		// Prediction cache hit metrics
+		adminPredictionCacheHandler := handlers.NewAdminPredictionCacheHandler(predictionCache)
+		adminPredictionCacheHandler.Register(adminGroup)
+
+		// Outcomes of soft-launched experiments
+		adminExperimentsHandler := handlers.NewAdminExperimentsHandler(st, map[string]bool{
+			"recommendation_wording": cfg.RecommendationExperiment,
+		})
+		adminExperimentsHandler.Register(adminGroup)
+	}
+
+	// User and role management - admin.users permission
+	userAdminGroup := protected.Group("/admin")
+	userAdminGroup.Use(middleware.PermissionRequired(perms, models.PermAdminUsers))
+	{
+		// User management handler
+		adminUsersHandler := handlers.NewAdminUsersHandler(st).WithEvents(bus)
+		adminUsersHandler.Register(userAdminGroup)
+
+		// Login history across accounts for suspicious activity review
+		loginHistoryHandler.RegisterAdmin(userAdminGroup)
+
+		// Scheduled purges of deactivated users
+		adminDeletionsHandler := handlers.NewAdminDeletionsHandler(st)
+		adminDeletionsHandler.Register(userAdminGroup)
+
+		// API keys acting as a user for integration scripts
+		handlers.NewAdminAPIKeysHandler(st).Register(userAdminGroup)
+
+		// Support mode: short-lived tokens acting as a user
+		handlers.NewAdminImpersonationHandler(st, cfg.JWTSecret, time.Duration(cfg.ImpersonationTTLMinutes)*time.Minute).Register(userAdminGroup)
+
+		// Two-factor policy per role and resets
+		handlers.NewAdminMFAHandler(st).Register(userAdminGroup)
+
+		// Permissions granted by each role
+		handlers.NewAdminRolesHandler(st).Register(userAdminGroup)
 	}
 
 	return r
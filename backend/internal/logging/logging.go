@@ -0,0 +1,138 @@
+// Package logging configures the zerolog logger shared by the server and
+// carries the request ID through contexts, so every line logged while
+// serving a request can be found by its ID. In production, personal data
+// passed through PII is hashed rather than written out.
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	stdlog "log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Options configure Init
+type Options struct {
+	// Env selects JSON output ("production", "prod", "staging") or a
+	// console format for development
+	Env string
+	// Level is "debug", "info", "warn" or "error"; empty picks debug in
+	// development and info elsewhere
+	Level string
+	// RedactPII hashes values passed through PII
+	RedactPII bool
+	// Out defaults to stdout for JSON and stderr for the console format
+	Out io.Writer
+}
+
+var redact atomic.Bool
+
+// Init sets up the global logger. Output of the standard library log
+// package is routed through it too, at info level.
+func Init(opts Options) {
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	zerolog.TimestampFieldName = "timestamp"
+	zerolog.MessageFieldName = "message"
+	zerolog.LevelFieldName = "level"
+
+	json := opts.Env == "production" || opts.Env == "prod" || opts.Env == "staging" || opts.Env == "stage"
+	level := zerolog.InfoLevel
+	if !json {
+		level = zerolog.DebugLevel
+	}
+	if opts.Level != "" {
+		if l, err := zerolog.ParseLevel(opts.Level); err == nil {
+			level = l
+		}
+	}
+	zerolog.SetGlobalLevel(level)
+
+	out := opts.Out
+	if !json {
+		if out == nil {
+			out = os.Stderr
+		}
+		out = zerolog.ConsoleWriter{Out: out, TimeFormat: "15:04:05"}
+	} else if out == nil {
+		out = os.Stdout
+	}
+	ctx := zerolog.New(out).With().Timestamp().Str("service", "diana-api")
+	if json {
+		ctx = ctx.Str("environment", opts.Env)
+		if version := os.Getenv("APP_VERSION"); version != "" {
+			ctx = ctx.Str("version", version)
+		}
+	}
+	log.Logger = ctx.Logger()
+	redact.Store(opts.RedactPII)
+
+	stdlog.SetFlags(0)
+	stdlog.SetOutput(stdWriter{})
+}
+
+// stdWriter logs each line of the standard library logger at info level
+type stdWriter struct{}
+
+func (stdWriter) Write(p []byte) (int, error) {
+	msg := string(p)
+	if n := len(msg); n > 0 && msg[n-1] == '\n' {
+		msg = msg[:n-1]
+	}
+	log.Info().Msg(msg)
+	return len(p), nil
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns ctx carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Ctx returns the global logger, with the request_id and trace_id fields
+// when ctx carries them
+func Ctx(ctx context.Context) *zerolog.Logger {
+	id := RequestID(ctx)
+	span := trace.SpanContextFromContext(ctx)
+	if id == "" && !span.IsValid() {
+		return &log.Logger
+	}
+	lc := log.Logger.With()
+	if id != "" {
+		lc = lc.Str("request_id", id)
+	}
+	if span.IsValid() {
+		lc = lc.Str("trace_id", span.TraceID().String())
+	}
+	l := lc.Logger()
+	return &l
+}
+
+// PII returns value as is, or a short hash of it when redaction is on. The
+// hash is stable, so lines about the same person can still be matched.
+func PII(value string) string {
+	if !redact.Load() || value == "" {
+		return value
+	}
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+// Redacting reports whether PII is hashed
+func Redacting() bool {
+	return redact.Load()
+}
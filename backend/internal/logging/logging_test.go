@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stdlog "log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func initForTest(t *testing.T, opts Options) *bytes.Buffer {
+	t.Helper()
+	prev, prevLevel := log.Logger, zerolog.GlobalLevel()
+	t.Cleanup(func() {
+		log.Logger = prev
+		zerolog.SetGlobalLevel(prevLevel)
+		redact.Store(false)
+		stdlog.SetOutput(os.Stderr)
+		stdlog.SetFlags(stdlog.LstdFlags)
+	})
+	var buf bytes.Buffer
+	opts.Out = &buf
+	Init(opts)
+	return &buf
+}
+
+func TestCtx_RequestID(t *testing.T) {
+	buf := initForTest(t, Options{Env: "production"})
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	if got := RequestID(ctx); got != "req-1" {
+		t.Fatalf("RequestID = %q", got)
+	}
+	Ctx(ctx).Info().Msg("hello")
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected a JSON line, got %q", buf.String())
+	}
+	if line["request_id"] != "req-1" || line["message"] != "hello" || line["level"] != "info" {
+		t.Fatalf("unexpected line %v", line)
+	}
+
+	buf.Reset()
+	Ctx(context.Background()).Info().Msg("no id")
+	if strings.Contains(buf.String(), "request_id") {
+		t.Fatalf("expected no request_id, got %q", buf.String())
+	}
+}
+
+func TestInit_LevelAndStdlib(t *testing.T) {
+	buf := initForTest(t, Options{Env: "production", Level: "warn"})
+
+	log.Info().Msg("dropped")
+	if buf.Len() != 0 {
+		t.Fatalf("expected info to be dropped at warn, got %q", buf.String())
+	}
+	log.Warn().Msg("kept")
+	if !strings.Contains(buf.String(), "kept") {
+		t.Fatalf("expected the warning, got %q", buf.String())
+	}
+
+	// The standard library logger logs at info, below the level
+	buf.Reset()
+	stdlog.Printf("legacy")
+	if buf.Len() != 0 {
+		t.Fatalf("expected stdlib output at info, got %q", buf.String())
+	}
+}
+
+func TestPII(t *testing.T) {
+	initForTest(t, Options{Env: "dev"})
+	if got := PII("doc@example.com"); got != "doc@example.com" {
+		t.Fatalf("without redaction PII = %q", got)
+	}
+
+	initForTest(t, Options{Env: "production", RedactPII: true})
+	a, b := PII("doc@example.com"), PII("doc@example.com")
+	if a != b || strings.Contains(a, "doc") || !strings.HasPrefix(a, "sha256:") {
+		t.Fatalf("expected a stable hash, got %q and %q", a, b)
+	}
+	if PII("nurse@example.com") == a {
+		t.Fatal("expected different values to hash differently")
+	}
+	if PII("") != "" {
+		t.Fatal("expected an empty value to stay empty")
+	}
+}
@@ -0,0 +1,127 @@
+// Package loinc maps assessment biomarkers to the LOINC codes and UCUM units
+// lab systems report them in. It is shared by the FHIR and HL7v2
+// integrations.
+package loinc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// System is the LOINC code system URI
+const System = "http://loinc.org"
+
+// Biomarker is one assessment field and the LOINC code it is reported
+// under. Zero values are missing measurements.
+type Biomarker struct {
+	Key     string
+	Code    string
+	Display string
+	// Unit is the UCUM unit DIANA stores the value in
+	Unit string
+	// Category is the FHIR observation category
+	Category string
+	Get      func(models.Assessment) float64
+	Set      func(*models.Assessment, float64)
+}
+
+// BloodPressurePanel groups the systolic and diastolic measurements
+var BloodPressurePanel = Biomarker{Key: "bp", Code: "85354-9", Display: "Blood pressure panel with all children optional", Category: "vital-signs"}
+
+// Biomarkers lists every assessment field with a LOINC code
+var Biomarkers = []Biomarker{
+	{"fbs", "1558-6", "Fasting glucose [Mass/volume] in Serum or Plasma", "mg/dL", "laboratory",
+		func(a models.Assessment) float64 { return a.FBS }, func(a *models.Assessment, v float64) { a.FBS = v }},
+	{"hba1c", "4548-4", "Hemoglobin A1c/Hemoglobin.total in Blood", "%", "laboratory",
+		func(a models.Assessment) float64 { return a.HbA1c }, func(a *models.Assessment, v float64) { a.HbA1c = v }},
+	{"cholesterol", "2093-3", "Cholesterol [Mass/volume] in Serum or Plasma", "mg/dL", "laboratory",
+		func(a models.Assessment) float64 { return float64(a.Cholesterol) }, func(a *models.Assessment, v float64) { a.Cholesterol = round(v) }},
+	{"ldl", "13457-7", "Cholesterol in LDL [Mass/volume] in Serum or Plasma by calculation", "mg/dL", "laboratory",
+		func(a models.Assessment) float64 { return float64(a.LDL) }, func(a *models.Assessment, v float64) { a.LDL = round(v) }},
+	{"hdl", "2085-9", "Cholesterol in HDL [Mass/volume] in Serum or Plasma", "mg/dL", "laboratory",
+		func(a models.Assessment) float64 { return float64(a.HDL) }, func(a *models.Assessment, v float64) { a.HDL = round(v) }},
+	{"triglycerides", "2571-8", "Triglyceride [Mass/volume] in Serum or Plasma", "mg/dL", "laboratory",
+		func(a models.Assessment) float64 { return float64(a.Triglycerides) }, func(a *models.Assessment, v float64) { a.Triglycerides = round(v) }},
+	{"systolic", "8480-6", "Systolic blood pressure", "mm[Hg]", "vital-signs",
+		func(a models.Assessment) float64 { return float64(a.Systolic) }, func(a *models.Assessment, v float64) { a.Systolic = round(v) }},
+	{"diastolic", "8462-4", "Diastolic blood pressure", "mm[Hg]", "vital-signs",
+		func(a models.Assessment) float64 { return float64(a.Diastolic) }, func(a *models.Assessment, v float64) { a.Diastolic = round(v) }},
+	{"bmi", "39156-5", "Body mass index (BMI) [Ratio]", "kg/m2", "vital-signs",
+		func(a models.Assessment) float64 { return a.BMI }, func(a *models.Assessment, v float64) { a.BMI = v }},
+}
+
+// aliases are other LOINC codes accepted for a biomarker
+var aliases = map[string]string{
+	"18262-6": "ldl",   // LDL cholesterol, direct assay
+	"17856-6": "hba1c", // HbA1c by HPLC
+}
+
+// siFactors convert SI units to the units DIANA stores, per biomarker
+var siFactors = map[string]map[string]float64{
+	"fbs":           {"mmol/L": 18.016},
+	"cholesterol":   {"mmol/L": 38.67},
+	"ldl":           {"mmol/L": 38.67},
+	"hdl":           {"mmol/L": 38.67},
+	"triglycerides": {"mmol/L": 88.57},
+}
+
+func round(v float64) int { return int(math.Round(v)) }
+
+// Lookup finds the biomarker reported under code, including aliases.
+func Lookup(code string) (Biomarker, bool) {
+	key := aliases[code]
+	for _, b := range Biomarkers {
+		if b.Code == code || b.Key == key {
+			return b, true
+		}
+	}
+	return Biomarker{}, false
+}
+
+// Convert returns value in the biomarker's unit. unit must be that unit or
+// an SI unit it can be converted from, ignoring case as lab systems often
+// send "mg/dl".
+func (b Biomarker) Convert(value float64, unit string) (float64, error) {
+	if strings.EqualFold(unit, b.Unit) {
+		return value, nil
+	}
+	for si, factor := range siFactors[b.Key] {
+		if strings.EqualFold(unit, si) {
+			return value * factor, nil
+		}
+	}
+	return 0, fmt.Errorf("unit %q is not supported for %s; use %s", unit, b.Display, b.Unit)
+}
+
+// ByKey finds the biomarker for an assessment field, e.g. "fbs".
+func ByKey(key string) (Biomarker, bool) {
+	for _, b := range Biomarkers {
+		if b.Key == key {
+			return b, true
+		}
+	}
+	return Biomarker{}, false
+}
+
+// PreferredUnit returns the unit to show the biomarker in for a user who
+// prefers units (models.UnitsMgDL or models.UnitsMmolL): the SI unit when
+// the biomarker has one, otherwise the unit it is stored in.
+func (b Biomarker) PreferredUnit(units string) string {
+	if _, ok := siFactors[b.Key][units]; ok {
+		return units
+	}
+	return b.Unit
+}
+
+// Express returns value, stored in the biomarker's unit, in unit. It is the
+// inverse of Convert.
+func (b Biomarker) Express(value float64, unit string) (float64, error) {
+	factor, err := b.Convert(1, unit)
+	if err != nil {
+		return 0, err
+	}
+	return value / factor, nil
+}
@@ -0,0 +1,74 @@
+// Package mail sends transactional email (verification links, password
+// resets). Without SMTP configuration messages are written to the log so
+// development setups can follow links without a mail server.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// Mailer delivers a plain-text message to a single recipient.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// LogMailer writes messages to the log instead of sending them. With PII
+// redaction on, the recipient is hashed and the body left out, since it
+// can hold sign-in links.
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	event := logging.Ctx(ctx).Info().Str("to", logging.PII(to)).Str("subject", subject)
+	if !logging.Redacting() {
+		event.Str("body", body)
+	}
+	event.Msg("mail not sent, SMTP_HOST unset")
+	return nil
+}
+
+// SMTPMailer sends through an SMTP relay, authenticating when a username is set.
+type SMTPMailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(host, fmt.Sprint(port)),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, to, subject, body string) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+	return smtp.SendMail(m.addr, auth, m.from, []string{to}, m.message(to, subject, body))
+}
+
+func (m *SMTPMailer) message(to, subject, body string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
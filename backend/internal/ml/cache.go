@@ -0,0 +1,223 @@
+// CachingPredictor: skips model calls for inputs it has already scored.
+package ml
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// PredictionCacheStore is the persistent tier behind the in-memory cache.
+// Get returns nil when key is not stored.
+type PredictionCacheStore interface {
+	Get(ctx context.Context, key string) (*models.CachedPrediction, error)
+	Put(ctx context.Context, key string, p models.CachedPrediction) error
+}
+
+// cacheKeyInput is everything a prediction depends on: the features the model
+// reads and the model that reads them. Identifiers and timestamps are left
+// out so a re-submission of the same values hits.
+type cacheKeyInput struct {
+	FBS           float64 `json:"fbs"`
+	HbA1c         float64 `json:"hba1c"`
+	Cholesterol   int     `json:"cholesterol"`
+	LDL           int     `json:"ldl"`
+	HDL           int     `json:"hdl"`
+	Triglycerides int     `json:"triglycerides"`
+	Systolic      int     `json:"systolic"`
+	Diastolic     int     `json:"diastolic"`
+	Activity      string  `json:"activity"`
+	HistoryFlag   bool    `json:"history_flag"`
+	Smoking       string  `json:"smoking"`
+	Hypertension  string  `json:"hypertension"`
+	HeartDisease  string  `json:"heart_disease"`
+	BMI           float64 `json:"bmi"`
+	SelfReported  bool    `json:"self_reported"`
+	OnMedication  bool    `json:"on_medication"`
+	ModelVersion  string  `json:"model_version"`
+	DatasetHash   string  `json:"dataset_hash"`
+}
+
+// CacheKey hashes the inputs of a prediction
+func CacheKey(a models.Assessment) string {
+	b, _ := json.Marshal(cacheKeyInput{
+		FBS:           a.FBS,
+		HbA1c:         a.HbA1c,
+		Cholesterol:   a.Cholesterol,
+		LDL:           a.LDL,
+		HDL:           a.HDL,
+		Triglycerides: a.Triglycerides,
+		Systolic:      a.Systolic,
+		Diastolic:     a.Diastolic,
+		Activity:      a.Activity,
+		HistoryFlag:   a.HistoryFlag,
+		Smoking:       a.Smoking,
+		Hypertension:  a.Hypertension,
+		HeartDisease:  a.HeartDisease,
+		BMI:           a.BMI,
+		SelfReported:  a.SelfReported,
+		OnMedication:  a.OnMedication,
+		ModelVersion:  a.ModelVersion,
+		DatasetHash:   a.DatasetHash,
+	})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// CachingPredictor answers repeated inputs from an LRU of capacity entries,
+// then from the store, before calling next. Failed predictions (an "error"
+// or empty cluster) are not cached. A result cached without an explanation
+// does not answer a request for one. Store errors count as misses.
+type CachingPredictor struct {
+	next     Predictor
+	store    PredictionCacheStore
+	capacity int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // front is most recently used
+
+	memoryHits atomic.Int64
+	storeHits  atomic.Int64
+	misses     atomic.Int64
+}
+
+type cacheEntry struct {
+	key        string
+	prediction models.CachedPrediction
+}
+
+// NewCachingPredictor caches next's predictions. store may be nil to cache
+// in memory only.
+func NewCachingPredictor(next Predictor, capacity int, store PredictionCacheStore) *CachingPredictor {
+	return &CachingPredictor{
+		next:     next,
+		store:    store,
+		capacity: capacity,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+func (p *CachingPredictor) Predict(ctx context.Context, input models.Assessment) (string, int) {
+	cached := p.predict(ctx, input, false)
+	return cached.Cluster, cached.RiskScore
+}
+
+func (p *CachingPredictor) PredictWithExplanation(ctx context.Context, input models.Assessment) (string, int, map[string]interface{}) {
+	cached := p.predict(ctx, input, true)
+	return cached.Cluster, cached.RiskScore, cached.Explanation
+}
+
+// ExplainWithModel forwards to next when it can pin a model version; pinned
+// explanations are not cached.
+func (p *CachingPredictor) ExplainWithModel(ctx context.Context, input models.Assessment, version, datasetHash string) (map[string]interface{}, bool) {
+	pinned, ok := p.next.(PinnedExplainer)
+	if !ok {
+		return nil, false
+	}
+	return pinned.ExplainWithModel(ctx, input, version, datasetHash)
+}
+
+// PredictWithModel forwards to next when it can pin a model version; pinned
+// predictions are not cached.
+func (p *CachingPredictor) PredictWithModel(ctx context.Context, input models.Assessment, version, datasetHash string) (string, int, bool) {
+	pinned, ok := p.next.(PinnedPredictor)
+	if !ok {
+		return "", 0, false
+	}
+	return pinned.PredictWithModel(ctx, input, version, datasetHash)
+}
+
+// Stats reports the cache's size and lookups since startup
+func (p *CachingPredictor) Stats() models.PredictionCacheStats {
+	p.mu.Lock()
+	size := p.order.Len()
+	p.mu.Unlock()
+	s := models.PredictionCacheStats{
+		Capacity:   p.capacity,
+		Size:       size,
+		MemoryHits: p.memoryHits.Load(),
+		StoreHits:  p.storeHits.Load(),
+		Misses:     p.misses.Load(),
+	}
+	if total := s.MemoryHits + s.StoreHits + s.Misses; total > 0 {
+		s.HitRate = float64(s.MemoryHits+s.StoreHits) / float64(total)
+	}
+	return s
+}
+
+func (p *CachingPredictor) predict(ctx context.Context, input models.Assessment, explain bool) models.CachedPrediction {
+	key := CacheKey(input)
+	usable := func(c *models.CachedPrediction) bool { return c != nil && (c.Explained || !explain) }
+
+	if cached := p.get(key); usable(cached) {
+		p.memoryHits.Add(1)
+		return *cached
+	}
+	if p.store != nil {
+		cached, err := p.store.Get(ctx, key)
+		if err != nil {
+			logging.Ctx(ctx).Warn().Err(err).Msg("Prediction cache lookup failed")
+		} else if usable(cached) {
+			p.storeHits.Add(1)
+			p.add(key, *cached)
+			return *cached
+		}
+	}
+	p.misses.Add(1)
+
+	out := models.CachedPrediction{Explained: explain}
+	if explain {
+		out.Cluster, out.RiskScore, out.Explanation = p.next.PredictWithExplanation(ctx, input)
+	} else {
+		out.Cluster, out.RiskScore = p.next.Predict(ctx, input)
+	}
+	if out.Cluster == "" || out.Cluster == "error" {
+		return out
+	}
+	p.add(key, out)
+	if p.store != nil {
+		if err := p.store.Put(ctx, key, out); err != nil {
+			logging.Ctx(ctx).Warn().Err(err).Msg("Prediction cache write failed")
+		}
+	}
+	return out
+}
+
+func (p *CachingPredictor) get(key string) *models.CachedPrediction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	el, ok := p.entries[key]
+	if !ok {
+		return nil
+	}
+	p.order.MoveToFront(el)
+	cached := el.Value.(*cacheEntry).prediction
+	return &cached
+}
+
+// add stores prediction under key, evicting the least recently used entry
+// when full
+func (p *CachingPredictor) add(key string, prediction models.CachedPrediction) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if el, ok := p.entries[key]; ok {
+		el.Value.(*cacheEntry).prediction = prediction
+		p.order.MoveToFront(el)
+		return
+	}
+	p.entries[key] = p.order.PushFront(&cacheEntry{key: key, prediction: prediction})
+	if p.order.Len() > p.capacity {
+		oldest := p.order.Back()
+		p.order.Remove(oldest)
+		delete(p.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,91 @@
+package ml
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// countingPredictor counts calls and fails when cluster is "error"
+type countingPredictor struct {
+	calls   int
+	cluster string
+}
+
+func (p *countingPredictor) Predict(ctx context.Context, input models.Assessment) (string, int) {
+	p.calls++
+	return p.cluster, 70
+}
+
+func (p *countingPredictor) PredictWithExplanation(ctx context.Context, input models.Assessment) (string, int, map[string]interface{}) {
+	p.calls++
+	return p.cluster, 70, map[string]interface{}{"base_value": 0.5}
+}
+
+type mapCacheStore map[string]models.CachedPrediction
+
+func (m mapCacheStore) Get(ctx context.Context, key string) (*models.CachedPrediction, error) {
+	p, ok := m[key]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func (m mapCacheStore) Put(ctx context.Context, key string, p models.CachedPrediction) error {
+	m[key] = p
+	return nil
+}
+
+func TestCachingPredictor(t *testing.T) {
+	next := &countingPredictor{cluster: "SIRD"}
+	store := mapCacheStore{}
+	p := NewCachingPredictor(next, 1, store)
+	a := models.Assessment{ID: 1, PatientID: 3, BMI: 31, HbA1c: 6.2, ModelVersion: "v1"}
+
+	// Identifiers do not change the key; the model version does
+	resubmitted := a
+	resubmitted.ID, resubmitted.PatientID = 2, 9
+	p.Predict(context.Background(), a)
+	if cluster, risk := p.Predict(context.Background(), resubmitted); cluster != "SIRD" || risk != 70 || next.calls != 1 {
+		t.Fatalf("expected a memory hit, got %s/%d after %d calls", cluster, risk, next.calls)
+	}
+	upgraded := a
+	upgraded.ModelVersion = "v2"
+	p.Predict(context.Background(), upgraded)
+	if next.calls != 2 {
+		t.Fatalf("expected a new model version to miss, got %d calls", next.calls)
+	}
+
+	// With capacity 1, a's entry was evicted but the store still has it
+	p.Predict(context.Background(), a)
+	if next.calls != 2 {
+		t.Fatalf("expected a store hit, got %d calls", next.calls)
+	}
+
+	// A cached prediction without an explanation does not answer for one
+	if _, _, explanation := p.PredictWithExplanation(context.Background(), a); explanation == nil || next.calls != 3 {
+		t.Fatalf("expected the explanation fetched, got %v after %d calls", explanation, next.calls)
+	}
+	if _, _, explanation := p.PredictWithExplanation(context.Background(), a); explanation == nil || next.calls != 3 {
+		t.Fatalf("expected the explanation cached, got %v after %d calls", explanation, next.calls)
+	}
+
+	stats := p.Stats()
+	if stats.MemoryHits != 2 || stats.StoreHits != 1 || stats.Misses != 3 || stats.Size != 1 || stats.HitRate != 0.5 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCachingPredictor_SkipsFailures(t *testing.T) {
+	next := &countingPredictor{cluster: "error"}
+	store := mapCacheStore{}
+	p := NewCachingPredictor(next, 10, store)
+	a := models.Assessment{BMI: 25}
+	p.Predict(context.Background(), a)
+	p.Predict(context.Background(), a)
+	if next.calls != 2 || len(store) != 0 {
+		t.Fatalf("expected failures retried and not stored, got %d calls and %d stored", next.calls, len(store))
+	}
+}
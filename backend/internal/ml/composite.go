@@ -0,0 +1,99 @@
+// CompositePredictor: the model service, falling back to a local model.
+package ml
+
+import (
+	"context"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// FallbackAware is implemented by predictors that may answer from a
+// fallback model. fallback reports whether the fallback model made the
+// prediction.
+type FallbackAware interface {
+	PredictOrFallback(ctx context.Context, input models.Assessment, explain bool) (cluster string, risk int, explanation map[string]interface{}, fallback bool)
+}
+
+// CompositePredictor asks primary first and, when it fails (an "error" or
+// empty cluster, as HTTPPredictor reports an unreachable service), asks
+// fallback instead.
+type CompositePredictor struct {
+	primary  Predictor
+	fallback Predictor
+}
+
+func NewCompositePredictor(primary, fallback Predictor) *CompositePredictor {
+	return &CompositePredictor{primary: primary, fallback: fallback}
+}
+
+func (p *CompositePredictor) Predict(ctx context.Context, input models.Assessment) (string, int) {
+	cluster, risk, _, _ := p.PredictOrFallback(ctx, input, false)
+	return cluster, risk
+}
+
+func (p *CompositePredictor) PredictWithExplanation(ctx context.Context, input models.Assessment) (string, int, map[string]interface{}) {
+	cluster, risk, explanation, _ := p.PredictOrFallback(ctx, input, true)
+	return cluster, risk, explanation
+}
+
+func (p *CompositePredictor) PredictOrFallback(ctx context.Context, input models.Assessment, explain bool) (string, int, map[string]interface{}, bool) {
+	var cluster string
+	var risk int
+	var explanation map[string]interface{}
+	if explain {
+		cluster, risk, explanation = p.primary.PredictWithExplanation(ctx, input)
+	} else {
+		cluster, risk = p.primary.Predict(ctx, input)
+	}
+	if cluster != "" && cluster != "error" {
+		return cluster, risk, explanation, false
+	}
+	if explain {
+		cluster, risk, explanation = p.fallback.PredictWithExplanation(ctx, input)
+	} else {
+		cluster, risk = p.fallback.Predict(ctx, input)
+	}
+	return cluster, risk, explanation, true
+}
+
+// ExplainWithModel forwards to primary when it can pin a model version. The
+// fallback model has no explanations to pin.
+func (p *CompositePredictor) ExplainWithModel(ctx context.Context, input models.Assessment, version, datasetHash string) (map[string]interface{}, bool) {
+	pinned, ok := p.primary.(PinnedExplainer)
+	if !ok || version == FallbackModelVersion {
+		return nil, false
+	}
+	return pinned.ExplainWithModel(ctx, input, version, datasetHash)
+}
+
+// PredictWithModel forwards to primary when it can pin a model version. The
+// fallback model cannot stand in for another model.
+func (p *CompositePredictor) PredictWithModel(ctx context.Context, input models.Assessment, version, datasetHash string) (string, int, bool) {
+	pinned, ok := p.primary.(PinnedPredictor)
+	if !ok {
+		return "", 0, false
+	}
+	return pinned.PredictWithModel(ctx, input, version, datasetHash)
+}
+
+// Score predicts a in place with p, setting its cluster and risk score.
+// When a fallback model answered, the model version becomes
+// FallbackModelVersion and the dataset hash is cleared. The explanation is
+// only requested when explain is set.
+func Score(ctx context.Context, p Predictor, a *models.Assessment, explain bool) map[string]interface{} {
+	var explanation map[string]interface{}
+	if fa, ok := p.(FallbackAware); ok {
+		var fallback bool
+		a.Cluster, a.RiskScore, explanation, fallback = fa.PredictOrFallback(ctx, *a, explain)
+		if fallback {
+			a.ModelVersion, a.DatasetHash = FallbackModelVersion, ""
+		}
+		return explanation
+	}
+	if explain {
+		a.Cluster, a.RiskScore, explanation = p.PredictWithExplanation(ctx, *a)
+	} else {
+		a.Cluster, a.RiskScore = p.Predict(ctx, *a)
+	}
+	return explanation
+}
@@ -0,0 +1,42 @@
+package ml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestScore_FallsBackWhenModelUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	p := NewCompositePredictor(NewHTTPPredictor(srv.URL+"/predict", "v2", time.Second), NewFallbackPredictor())
+	a := models.Assessment{PatientAge: 50, BMI: 33, HbA1c: 6.4, ModelVersion: "v2", DatasetHash: "abc"}
+	if explanation := Score(context.Background(), p, &a, true); explanation != nil {
+		t.Errorf("expected no explanation from the fallback, got %v", explanation)
+	}
+	if a.Cluster != "SIRD" || a.RiskScore == 0 {
+		t.Errorf("expected a fallback prediction, got %s/%d", a.Cluster, a.RiskScore)
+	}
+	if a.ModelVersion != FallbackModelVersion || a.DatasetHash != "" {
+		t.Errorf("expected the fallback recorded, got version %q hash %q", a.ModelVersion, a.DatasetHash)
+	}
+	if _, ok := p.ExplainWithModel(context.Background(), a, FallbackModelVersion, ""); ok {
+		t.Error("expected no pinned explanation for the fallback model")
+	}
+}
+
+func TestScore_KeepsPrimaryModel(t *testing.T) {
+	p := NewCompositePredictor(NewMockPredictor(), NewFallbackPredictor())
+	a := models.Assessment{PatientID: 2, BMI: 24, HbA1c: 5.5, ModelVersion: "v2"}
+	Score(context.Background(), p, &a, false)
+	if a.Cluster != "MARD" || a.RiskScore != 45 || a.ModelVersion != "v2" {
+		t.Errorf("expected the primary prediction, got %s/%d from %q", a.Cluster, a.RiskScore, a.ModelVersion)
+	}
+}
@@ -0,0 +1,49 @@
+// Drift: how far a model's recent predictions have moved from its training data.
+package ml
+
+import (
+	"math"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// psiFloor stands in for a cluster share of zero, which would make the
+// index infinite
+const psiFloor = 0.0001
+
+// PopulationStability returns the population stability index of the cluster
+// shares in recent against training. Clusters missing from either side count
+// as a share of zero.
+func PopulationStability(recent, training models.PredictionDistribution) float64 {
+	psi := 0.0
+	seen := map[string]bool{}
+	add := func(cluster string) {
+		if seen[cluster] {
+			return
+		}
+		seen[cluster] = true
+		r := math.Max(recent.Clusters[cluster], psiFloor)
+		t := math.Max(training.Clusters[cluster], psiFloor)
+		psi += (r - t) * math.Log(r/t)
+	}
+	for cluster := range recent.Clusters {
+		add(cluster)
+	}
+	for cluster := range training.Clusters {
+		add(cluster)
+	}
+	return psi
+}
+
+// DriftLevel grades a population stability index with the usual cutoffs:
+// below 0.1 is stable and 0.25 or more is a significant shift.
+func DriftLevel(psi float64) string {
+	switch {
+	case psi >= 0.25:
+		return models.DriftSignificant
+	case psi >= 0.1:
+		return models.DriftModerate
+	default:
+		return models.DriftStable
+	}
+}
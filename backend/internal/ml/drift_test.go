@@ -0,0 +1,32 @@
+package ml
+
+import (
+	"math"
+	"testing"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestPopulationStability(t *testing.T) {
+	training := models.PredictionDistribution{Clusters: map[string]float64{"MOD": 0.5, "SIRD": 0.5}}
+	cases := []struct {
+		name   string
+		recent map[string]float64
+		psi    float64
+		level  string
+	}{
+		{"unchanged", map[string]float64{"MOD": 0.5, "SIRD": 0.5}, 0, models.DriftStable},
+		{"shifted", map[string]float64{"MOD": 0.7, "SIRD": 0.3}, 0.1695, models.DriftModerate},
+		// A cluster vanishing is floored rather than infinite
+		{"one cluster gone", map[string]float64{"MOD": 1}, 4.6043, models.DriftSignificant},
+	}
+	for _, tc := range cases {
+		psi := PopulationStability(models.PredictionDistribution{Clusters: tc.recent}, training)
+		if math.Abs(psi-tc.psi) > 0.001 {
+			t.Errorf("%s: psi = %.4f, want %.4f", tc.name, psi, tc.psi)
+		}
+		if level := DriftLevel(psi); level != tc.level {
+			t.Errorf("%s: level = %s, want %s", tc.name, level, tc.level)
+		}
+	}
+}
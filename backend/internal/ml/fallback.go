@@ -0,0 +1,87 @@
+// FallbackPredictor: a local rule-based model for when the model service is down.
+package ml
+
+import (
+	"context"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// FallbackModelVersion is stored as an assessment's model version when the
+// fallback model scored it, so these scores can be told apart and re-scored.
+const FallbackModelVersion = "fallback-findrisc-v1"
+
+// findriscMax is the highest score reachable from the items scored below.
+// The full FINDRISC questionnaire also asks about waist circumference and
+// diet, which assessments do not record.
+const findriscMax = 21
+
+// FallbackPredictor approximates the Finnish Diabetes Risk Score (FINDRISC)
+// from age, BMI, activity, hypertension, glucose markers and family history,
+// and assigns a cluster with the rules in docs/ml-api-contract.md. It needs
+// no model service and always answers.
+type FallbackPredictor struct{}
+
+func NewFallbackPredictor() *FallbackPredictor {
+	return &FallbackPredictor{}
+}
+
+func (p *FallbackPredictor) Predict(ctx context.Context, input models.Assessment) (string, int) {
+	return fallbackCluster(input), findriscPoints(input) * 100 / findriscMax
+}
+
+// PredictWithExplanation returns the fallback prediction without an
+// explanation; FINDRISC points are not SHAP values.
+func (p *FallbackPredictor) PredictWithExplanation(ctx context.Context, input models.Assessment) (string, int, map[string]interface{}) {
+	cluster, risk := p.Predict(ctx, input)
+	return cluster, risk, nil
+}
+
+// findriscPoints scores the FINDRISC items an assessment records. An unknown
+// age scores nothing.
+func findriscPoints(a models.Assessment) int {
+	points := 0
+	switch {
+	case a.PatientAge >= 65:
+		points += 4
+	case a.PatientAge >= 55:
+		points += 3
+	case a.PatientAge >= 45:
+		points += 2
+	}
+	switch {
+	case a.BMI > 30:
+		points += 3
+	case a.BMI >= 25:
+		points += 1
+	}
+	// Less than four hours of activity a week
+	if a.Activity == "sedentary" || a.Activity == "light" {
+		points += 2
+	}
+	if a.Hypertension == "yes" {
+		points += 2
+	}
+	// FINDRISC asks whether glucose was ever found high; a measured
+	// prediabetic value or worse answers it
+	if a.FBS >= 100 || a.HbA1c >= 5.7 {
+		points += 5
+	}
+	if a.HistoryFlag {
+		points += 5
+	}
+	return points
+}
+
+func fallbackCluster(a models.Assessment) string {
+	switch {
+	case a.BMI > 30 && a.HbA1c > 6.0:
+		return "SIRD"
+	case a.HbA1c > 6.5 && a.BMI < 27:
+		return "SIDD"
+	case a.PatientAge > 60 && a.HbA1c < 7.0:
+		return "MARD"
+	default:
+		return "MOD"
+	}
+}
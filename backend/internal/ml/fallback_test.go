@@ -0,0 +1,30 @@
+package ml
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestFallbackPredictor(t *testing.T) {
+	p := NewFallbackPredictor()
+	cases := []struct {
+		name    string
+		input   models.Assessment
+		cluster string
+		risk    int
+	}{
+		{"healthy", models.Assessment{PatientAge: 40, BMI: 22, FBS: 88, HbA1c: 5.1, Activity: "active"}, "MOD", 0},
+		{"older, overweight", models.Assessment{PatientAge: 62, BMI: 27, FBS: 95, HbA1c: 5.4, Activity: "moderate"}, "MARD", 19},
+		{"obese, raised glucose", models.Assessment{PatientAge: 50, BMI: 33, HbA1c: 6.4, Activity: "sedentary", Hypertension: "yes"}, "SIRD", 66},
+		{"lean, diabetic range", models.Assessment{PatientAge: 48, BMI: 23, FBS: 140, HbA1c: 7.2, HistoryFlag: true}, "SIDD", 57},
+		{"every item", models.Assessment{PatientAge: 70, BMI: 35, FBS: 130, Activity: "light", Hypertension: "yes", HistoryFlag: true}, "MARD", 100},
+	}
+	for _, tc := range cases {
+		cluster, risk := p.Predict(context.Background(), tc.input)
+		if cluster != tc.cluster || risk != tc.risk {
+			t.Errorf("%s: got %s/%d, want %s/%d", tc.name, cluster, risk, tc.cluster, tc.risk)
+		}
+	}
+}
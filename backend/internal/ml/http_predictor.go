@@ -6,9 +6,11 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/skufu/DianaV2/backend/internal/models"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type HTTPPredictor struct {
@@ -17,56 +19,165 @@ type HTTPPredictor struct {
 	version string
 }
 
+// predictReq is the body sent to the model service: the assessment plus
+// patient context that is not stored with it
+type predictReq struct {
+	models.Assessment
+	OnMedication bool `json:"on_medication"`
+}
+
 type predictResp struct {
 	Cluster   string `json:"risk_cluster"`
 	RiskScore int    `json:"risk_score"`
 }
 
+// explainResp is the model service's /explain payload: a prediction plus the
+// SHAP explanation as parallel arrays indexed by feature.
+type explainResp struct {
+	predictResp
+	Explanation *struct {
+		BaseValue     float64   `json:"base_value"`
+		ShapValues    []float64 `json:"shap_values"`
+		FeatureValues []float64 `json:"feature_values"`
+		FeatureNames  []string  `json:"feature_names"`
+	} `json:"explanation"`
+}
+
 // NewHTTPPredictor creates an HTTP-backed predictor that posts assessment data
 // to a model inference endpoint. Timeout applies to the entire request.
+// Requests carry the caller's trace in a traceparent header.
 func NewHTTPPredictor(url, version string, timeout time.Duration) *HTTPPredictor {
 	return &HTTPPredictor{
-		client:  &http.Client{Timeout: timeout},
+		client:  &http.Client{Timeout: timeout, Transport: otelhttp.NewTransport(http.DefaultTransport)},
 		url:     url,
 		version: version,
 	}
 }
 
-func (p *HTTPPredictor) Predict(input models.Assessment) (string, int) {
+func (p *HTTPPredictor) Predict(ctx context.Context, input models.Assessment) (string, int) {
 	if p.url == "" {
 		return "unknown", 0
 	}
 
-	body, err := json.Marshal(input)
-	if err != nil {
+	var out predictResp
+	if !p.post(ctx, p.url, input, &out) {
 		return "error", 0
 	}
+	if out.Cluster == "" {
+		return "error", 0
+	}
+	return out.Cluster, out.RiskScore
+}
+
+// PredictWithExplanation calls the model service's /explain endpoint, which
+// lives next to the predict URL. If the service cannot explain (e.g. SHAP is
+// not installed) it falls back to a plain prediction with a nil explanation.
+func (p *HTTPPredictor) PredictWithExplanation(ctx context.Context, input models.Assessment) (string, int, map[string]interface{}) {
+	if p.url == "" {
+		return "unknown", 0, nil
+	}
+
+	var out explainResp
+	if !p.post(ctx, strings.TrimRight(p.url, "/")+"/explain", input, &out) || out.Cluster == "" {
+		cluster, risk := p.Predict(ctx, input)
+		return cluster, risk, nil
+	}
+	return out.Cluster, out.RiskScore, out.explanation()
+}
+
+// explanation converts the parallel arrays into the row-per-feature shape the
+// PDF report renders. Types are generic so freshly predicted and stored-then-
+// decoded explanations look identical to consumers.
+func (r explainResp) explanation() map[string]interface{} {
+	e := r.Explanation
+	if e == nil || len(e.ShapValues) == 0 ||
+		len(e.ShapValues) != len(e.FeatureNames) || len(e.ShapValues) != len(e.FeatureValues) {
+		return nil
+	}
+	values := make([]interface{}, 0, len(e.ShapValues))
+	for i, v := range e.ShapValues {
+		values = append(values, map[string]interface{}{
+			"feature":       e.FeatureNames[i],
+			"feature_value": e.FeatureValues[i],
+			"shap_value":    v,
+		})
+	}
+	return map[string]interface{}{
+		"base_value":  e.BaseValue,
+		"shap_values": values,
+	}
+}
+
+// ExplainWithModel asks the /explain endpoint for a specific model version
+// and dataset hash. The model service must answer with the version it used
+// in an X-Model-Version response header; any other answer, including none,
+// counts as the version being unavailable.
+func (p *HTTPPredictor) ExplainWithModel(ctx context.Context, input models.Assessment, version, datasetHash string) (map[string]interface{}, bool) {
+	if p.url == "" || version == "" {
+		return nil, false
+	}
+
+	var out explainResp
+	header, ok := p.send(ctx, strings.TrimRight(p.url, "/")+"/explain", version, datasetHash, input, &out)
+	if !ok || header.Get("X-Model-Version") != version {
+		return nil, false
+	}
+	explanation := out.explanation()
+	return explanation, explanation != nil
+}
+
+// PredictWithModel asks the predict endpoint for a specific model version
+// and dataset hash. As with ExplainWithModel, the model service must echo
+// the version in an X-Model-Version response header.
+func (p *HTTPPredictor) PredictWithModel(ctx context.Context, input models.Assessment, version, datasetHash string) (string, int, bool) {
+	if p.url == "" || version == "" {
+		return "", 0, false
+	}
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, p.url, bytes.NewReader(body))
+	var out predictResp
+	header, ok := p.send(ctx, p.url, version, datasetHash, input, &out)
+	if !ok || header.Get("X-Model-Version") != version || out.Cluster == "" {
+		return "", 0, false
+	}
+	return out.Cluster, out.RiskScore, true
+}
+
+// post sends input as JSON to url and decodes a 200 response into out.
+// Returns false on any transport, status or decoding failure.
+func (p *HTTPPredictor) post(ctx context.Context, url string, input models.Assessment, out interface{}) bool {
+	_, ok := p.send(ctx, url, p.version, "", input, out)
+	return ok
+}
+
+// send is post with an explicit model version and dataset hash, returning
+// the response headers as well. The request keeps ctx's trace but not its
+// cancellation, so a client hanging up does not fail the prediction.
+func (p *HTTPPredictor) send(ctx context.Context, url, version, datasetHash string, input models.Assessment, out interface{}) (http.Header, bool) {
+	body, err := json.Marshal(predictReq{Assessment: input, OnMedication: input.OnMedication})
 	if err != nil {
-		return "error", 0
+		return nil, false
+	}
+
+	req, err := http.NewRequestWithContext(context.WithoutCancel(ctx), http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, false
 	}
 	req.Header.Set("Content-Type", "application/json")
-	if p.version != "" {
-		req.Header.Set("X-Model-Version", p.version)
+	if version != "" {
+		req.Header.Set("X-Model-Version", version)
+	}
+	if datasetHash != "" {
+		req.Header.Set("X-Dataset-Hash", datasetHash)
 	}
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "error", 0
+		return nil, false
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "error", 0
-	}
-
-	var out predictResp
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return "error", 0
-	}
-	if out.Cluster == "" {
-		return "error", 0
+		return nil, false
 	}
-	return out.Cluster, out.RiskScore
+	return resp.Header, json.NewDecoder(resp.Body).Decode(out) == nil
 }
@@ -0,0 +1,145 @@
+package ml
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestHTTPPredictor_PredictWithExplanation_FallsBack(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/predict/explain" {
+			// Model service without SHAP installed
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"risk_cluster": "MOD", "risk_score": 30})
+	}))
+	defer srv.Close()
+
+	p := NewHTTPPredictor(srv.URL+"/predict", "v1", time.Second)
+	cluster, risk, explanation := p.PredictWithExplanation(context.Background(), models.Assessment{BMI: 28})
+	if cluster != "MOD" || risk != 30 {
+		t.Fatalf("expected fallback prediction MOD/30, got %s/%d", cluster, risk)
+	}
+	if explanation != nil {
+		t.Fatalf("expected nil explanation, got %v", explanation)
+	}
+}
+
+func TestHTTPPredictor_SendsOnMedication(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"risk_cluster":"MOD","risk_score":30}`))
+	}))
+	defer srv.Close()
+
+	p := NewHTTPPredictor(srv.URL, "v1", time.Second)
+	for _, on := range []bool{true, false} {
+		p.Predict(context.Background(), models.Assessment{HbA1c: 7.1, OnMedication: on})
+		if got["on_medication"] != on || got["hba1c"] != 7.1 {
+			t.Errorf("payload = %v, want on_medication %v with the assessment's fields", got, on)
+		}
+	}
+	if CacheKey(models.Assessment{OnMedication: true}) == CacheKey(models.Assessment{}) {
+		t.Error("on_medication must be part of the prediction cache key")
+	}
+}
+
+func TestExplainResp_MismatchedArrays(t *testing.T) {
+	var r explainResp
+	if err := json.Unmarshal([]byte(`{"risk_cluster":"SIRD","explanation":{"shap_values":[0.1,0.2],"feature_values":[1],"feature_names":["bmi","hdl"]}}`), &r); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.explanation(); got != nil {
+		t.Fatalf("expected nil explanation for mismatched arrays, got %v", got)
+	}
+}
+
+func TestHTTPPredictor_ExplainWithModel(t *testing.T) {
+	served := "v1"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Model-Version") != "v1" || r.Header.Get("X-Dataset-Hash") != "abc" {
+			t.Errorf("pinned version not requested: %v", r.Header)
+		}
+		if served != "" {
+			w.Header().Set("X-Model-Version", served)
+		}
+		_, _ = w.Write([]byte(`{"risk_cluster":"MOD","risk_score":30,"explanation":{"base_value":0.5,"shap_values":[0.1],"feature_values":[28],"feature_names":["bmi"]}}`))
+	}))
+	defer srv.Close()
+
+	p := NewHTTPPredictor(srv.URL+"/predict", "v2", time.Second)
+	explanation, ok := p.ExplainWithModel(context.Background(), models.Assessment{BMI: 28}, "v1", "abc")
+	if !ok || explanation["base_value"] != 0.5 {
+		t.Fatalf("expected the v1 explanation, got %v (ok=%v)", explanation, ok)
+	}
+
+	// A service answering with another version, or not saying, is not pinned
+	for _, served = range []string{"v2", ""} {
+		if explanation, ok := p.ExplainWithModel(context.Background(), models.Assessment{BMI: 28}, "v1", "abc"); ok || explanation != nil {
+			t.Errorf("served %q: expected no explanation, got %v", served, explanation)
+		}
+	}
+}
+
+func TestHTTPPredictor_PredictWithModel(t *testing.T) {
+	served := "v3"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/predict" || r.Header.Get("X-Model-Version") != "v3" {
+			t.Errorf("pinned version not requested: %s %v", r.URL.Path, r.Header)
+		}
+		if served != "" {
+			w.Header().Set("X-Model-Version", served)
+		}
+		_, _ = w.Write([]byte(`{"risk_cluster":"SIRD","risk_score":80}`))
+	}))
+	defer srv.Close()
+
+	p := NewHTTPPredictor(srv.URL+"/predict", "v2", time.Second)
+	if cluster, risk, ok := p.PredictWithModel(context.Background(), models.Assessment{BMI: 32}, "v3", ""); !ok || cluster != "SIRD" || risk != 80 {
+		t.Fatalf("expected the v3 prediction, got %s/%d (ok=%v)", cluster, risk, ok)
+	}
+	for _, served = range []string{"v2", ""} {
+		if _, _, ok := p.PredictWithModel(context.Background(), models.Assessment{BMI: 32}, "v3", ""); ok {
+			t.Errorf("served %q: expected the version to be unavailable", served)
+		}
+	}
+}
+
+func TestHTTPPredictor_PropagatesTrace(t *testing.T) {
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"risk_cluster": "MOD", "risk_score": 30})
+	}))
+	defer srv.Close()
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "create assessment")
+	defer span.End()
+	p := NewHTTPPredictor(srv.URL+"/predict", "v1", time.Second)
+	if cluster, _ := p.Predict(ctx, models.Assessment{BMI: 28}); cluster != "MOD" {
+		t.Fatalf("expected MOD, got %s", cluster)
+	}
+	if !strings.Contains(traceparent, span.SpanContext().TraceID().String()) {
+		t.Fatalf("expected the caller's trace in traceparent, got %q", traceparent)
+	}
+}
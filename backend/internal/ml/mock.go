@@ -1,9 +1,33 @@
 package ml
 
-import "github.com/skufu/DianaV2/backend/internal/models"
+import (
+	"context"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
 
 type Predictor interface {
-	Predict(input models.Assessment) (cluster string, risk int)
+	Predict(ctx context.Context, input models.Assessment) (cluster string, risk int)
+	// PredictWithExplanation also returns SHAP feature contributions in the
+	// shape the PDF report expects. Explanation is nil when unavailable.
+	PredictWithExplanation(ctx context.Context, input models.Assessment) (cluster string, risk int, explanation map[string]interface{})
+}
+
+// PinnedExplainer is implemented by predictors that can explain an
+// assessment with a given model version instead of the current one, so
+// historical reports keep their meaning after a model upgrade. ok is false
+// when that version could not be served; the caller must then not
+// substitute another model's explanation.
+type PinnedExplainer interface {
+	ExplainWithModel(ctx context.Context, input models.Assessment, version, datasetHash string) (explanation map[string]interface{}, ok bool)
+}
+
+// PinnedPredictor is implemented by predictors that can score an assessment
+// with a given model version instead of the current one, e.g. to re-score
+// stored assessments with a newly activated model run. ok is false when
+// that version could not be served.
+type PinnedPredictor interface {
+	PredictWithModel(ctx context.Context, input models.Assessment, version, datasetHash string) (cluster string, risk int, ok bool)
 }
 
 type MockPredictor struct{}
@@ -12,7 +36,7 @@ func NewMockPredictor() *MockPredictor {
 	return &MockPredictor{}
 }
 
-func (m *MockPredictor) Predict(input models.Assessment) (string, int) {
+func (m *MockPredictor) Predict(ctx context.Context, input models.Assessment) (string, int) {
 	// Cluster assignments based on paper: SIDD, SIRD, MOD, MARD
 	// Simple deterministic rules to keep behavior stable during placeholder phase.
 	switch {
@@ -26,3 +50,17 @@ func (m *MockPredictor) Predict(input models.Assessment) (string, int) {
 		return "MOD", 30 // Mild Obesity-Related Diabetes
 	}
 }
+
+// PredictWithExplanation returns the mock prediction without an explanation;
+// the rule-based mock has no feature attributions to report.
+func (m *MockPredictor) PredictWithExplanation(ctx context.Context, input models.Assessment) (string, int, map[string]interface{}) {
+	cluster, risk := m.Predict(ctx, input)
+	return cluster, risk, nil
+}
+
+// PredictWithModel serves every version with the same rules, so re-scoring
+// can be tried without a model service.
+func (m *MockPredictor) PredictWithModel(ctx context.Context, input models.Assessment, version, datasetHash string) (string, int, bool) {
+	cluster, risk := m.Predict(ctx, input)
+	return cluster, risk, true
+}
@@ -1,6 +1,7 @@
 package ml
 
 import (
+	"context"
 	"testing"
 
 	"github.com/skufu/DianaV2/backend/internal/models"
@@ -57,7 +58,7 @@ func TestMockPredictor_Predict(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cluster, risk := p.Predict(tt.input)
+			cluster, risk := p.Predict(context.Background(), tt.input)
 			if cluster != tt.wantCluster {
 				t.Errorf("cluster = %q, want %q", cluster, tt.wantCluster)
 			}
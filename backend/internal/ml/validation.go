@@ -2,7 +2,13 @@
 // This file contains input validation utilities for biomarker data before ML prediction.
 package ml
 
-import "github.com/skufu/DianaV2/backend/internal/models"
+import (
+	"fmt"
+	"math"
+
+	"github.com/skufu/DianaV2/backend/internal/loinc"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
 
 // ValidationResult contains the outcome of biomarker validation
 type ValidationResult struct {
@@ -108,3 +114,131 @@ func FormatValidationStatus(result ValidationResult) string {
 	}
 	return status
 }
+
+// FieldError describes a single biomarker that failed a plausibility check
+type FieldError struct {
+	Field   string  `json:"field"`
+	Value   float64 `json:"value"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Unit    string  `json:"unit"`
+	Message string  `json:"message"`
+}
+
+// PlausibleRange bounds a biomarker to physiologically credible values
+type PlausibleRange struct {
+	Field string  `json:"field"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Unit  string  `json:"unit"`
+}
+
+// PlausibleRanges lists the bounds outside which a biomarker is almost certainly
+// a data entry or unit error rather than a real measurement. Zero values are
+// treated as "not provided" and are never checked.
+var PlausibleRanges = []PlausibleRange{
+	{Field: "fbs", Min: 40, Max: 600, Unit: "mg/dL"},
+	{Field: "hba1c", Min: 3, Max: 18, Unit: "%"},
+	{Field: "cholesterol", Min: 70, Max: 500, Unit: "mg/dL"},
+	{Field: "ldl", Min: 10, Max: 400, Unit: "mg/dL"},
+	{Field: "hdl", Min: 10, Max: 150, Unit: "mg/dL"},
+	{Field: "triglycerides", Min: 20, Max: 1500, Unit: "mg/dL"},
+	{Field: "systolic", Min: 70, Max: 250, Unit: "mmHg"},
+	{Field: "diastolic", Min: 40, Max: 150, Unit: "mmHg"},
+	{Field: "bmi", Min: 12, Max: 70, Unit: "kg/m²"},
+}
+
+// biomarkerValues keys an assessment's biomarkers by PlausibleRange field.
+func biomarkerValues(input models.Assessment) map[string]float64 {
+	return map[string]float64{
+		"fbs":           input.FBS,
+		"hba1c":         input.HbA1c,
+		"cholesterol":   float64(input.Cholesterol),
+		"ldl":           float64(input.LDL),
+		"hdl":           float64(input.HDL),
+		"triglycerides": float64(input.Triglycerides),
+		"systolic":      float64(input.Systolic),
+		"diastolic":     float64(input.Diastolic),
+		"bmi":           input.BMI,
+	}
+}
+
+// CheckPlausibility returns a FieldError for every provided biomarker that
+// falls outside PlausibleRanges. An empty result means the input is plausible.
+func CheckPlausibility(input models.Assessment) []FieldError {
+	values := biomarkerValues(input)
+
+	var errs []FieldError
+	for _, r := range PlausibleRanges {
+		v := values[r.Field]
+		if v == 0 {
+			continue
+		}
+		if v < r.Min || v > r.Max {
+			errs = append(errs, FieldError{
+				Field:   r.Field,
+				Value:   v,
+				Min:     r.Min,
+				Max:     r.Max,
+				Unit:    r.Unit,
+				Message: fmt.Sprintf("%s must be between %g and %g", r.Field, r.Min, r.Max),
+			})
+		}
+	}
+	return errs
+}
+
+// ExpressFieldErrors restates errs for a user who prefers units
+// (models.UnitsMgDL or models.UnitsMmolL), converting the value and bounds
+// of glucose and lipid fields and naming the unit in the message. Other
+// fields are returned as they are.
+func ExpressFieldErrors(errs []FieldError, units string) []FieldError {
+	out := make([]FieldError, len(errs))
+	for i, fe := range errs {
+		out[i] = fe
+		b, ok := loinc.ByKey(fe.Field)
+		if !ok || b.PreferredUnit(units) == b.Unit {
+			continue
+		}
+		unit := b.PreferredUnit(units)
+		express := func(v float64) float64 {
+			converted, _ := b.Express(v, unit)
+			return math.Round(converted*100) / 100
+		}
+		out[i].Value, out[i].Min, out[i].Max, out[i].Unit = express(fe.Value), express(fe.Min), express(fe.Max), unit
+		out[i].Message = fmt.Sprintf("%s must be between %g and %g %s", fe.Field, out[i].Min, out[i].Max, unit)
+	}
+	return out
+}
+
+// SelfReportedPenalty is taken off the quality score when the values were
+// reported by the patient rather than measured by a lab or clinician.
+const SelfReportedPenalty = 20
+
+// AssessQuality scores how complete and trustworthy an assessment's
+// biomarkers are, from 0 to 100: the share of PlausibleRanges biomarkers
+// provided with a plausible value, less SelfReportedPenalty when
+// self-reported. Implausible values count as missing.
+func AssessQuality(input models.Assessment) models.DataQuality {
+	values := biomarkerValues(input)
+	present := 0
+	for _, r := range PlausibleRanges {
+		if values[r.Field] != 0 {
+			present++
+		}
+	}
+	outOfRange := len(CheckPlausibility(input))
+
+	score := 100 * (present - outOfRange) / len(PlausibleRanges)
+	if input.SelfReported {
+		score -= SelfReportedPenalty
+	}
+	if score < 0 {
+		score = 0
+	}
+	return models.DataQuality{
+		Score:        score,
+		Completeness: math.Round(100*float64(present)/float64(len(PlausibleRanges))) / 100,
+		OutOfRange:   outOfRange,
+	}
+}
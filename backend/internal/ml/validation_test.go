@@ -197,3 +197,90 @@ func TestBiomarkerRanges(t *testing.T) {
 		t.Errorf("BMINormal = %v, want 25.0", BiomarkerRanges.BMINormal)
 	}
 }
+
+func TestCheckPlausibility(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      models.Assessment
+		wantFields []string
+	}{
+		{
+			name:  "plausible values",
+			input: models.Assessment{FBS: 130, HbA1c: 7.2, Systolic: 150, Diastolic: 95, BMI: 31},
+		},
+		{
+			name:  "zero values are not provided",
+			input: models.Assessment{BMI: 22},
+		},
+		{
+			name:       "fbs entered in mmol/L",
+			input:      models.Assessment{FBS: 6.1, BMI: 22},
+			wantFields: []string{"fbs"},
+		},
+		{
+			name:       "multiple implausible values",
+			input:      models.Assessment{HbA1c: 55, Systolic: 400, BMI: 9},
+			wantFields: []string{"hba1c", "systolic", "bmi"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := CheckPlausibility(tt.input)
+			if len(errs) != len(tt.wantFields) {
+				t.Fatalf("got %d field errors %v, want %v", len(errs), errs, tt.wantFields)
+			}
+			for i, want := range tt.wantFields {
+				if errs[i].Field != want {
+					t.Errorf("errs[%d].Field = %q, want %q", i, errs[i].Field, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExpressFieldErrors(t *testing.T) {
+	errs := CheckPlausibility(models.Assessment{FBS: 6.1, HbA1c: 55})
+
+	if got := ExpressFieldErrors(errs, models.UnitsMgDL); got[0] != errs[0] || got[1] != errs[1] {
+		t.Errorf("mg/dL should leave errors as they are, got %+v", got)
+	}
+
+	got := ExpressFieldErrors(errs, models.UnitsMmolL)
+	fbs := FieldError{Field: "fbs", Value: 0.34, Min: 2.22, Max: 33.3, Unit: "mmol/L",
+		Message: "fbs must be between 2.22 and 33.3 mmol/L"}
+	if got[0] != fbs {
+		t.Errorf("fbs = %+v, want %+v", got[0], fbs)
+	}
+	if got[1] != errs[1] {
+		t.Errorf("hba1c has no SI unit and should be unchanged, got %+v", got[1])
+	}
+}
+
+func TestAssessQuality(t *testing.T) {
+	full := models.Assessment{FBS: 95, HbA1c: 5.4, Cholesterol: 190, LDL: 110, HDL: 55,
+		Triglycerides: 140, Systolic: 120, Diastolic: 80, BMI: 24}
+	selfReported := full
+	selfReported.SelfReported = true
+	implausible := full
+	implausible.FBS = 5.3 // mmol/L
+
+	tests := []struct {
+		name  string
+		input models.Assessment
+		want  models.DataQuality
+	}{
+		{"complete and plausible", full, models.DataQuality{Score: 100, Completeness: 1}},
+		{"self-reported", selfReported, models.DataQuality{Score: 100 - SelfReportedPenalty, Completeness: 1}},
+		{"implausible value counts as missing", implausible, models.DataQuality{Score: 88, Completeness: 1, OutOfRange: 1}},
+		{"partial", models.Assessment{FBS: 95, HbA1c: 5.4, BMI: 24}, models.DataQuality{Score: 33, Completeness: 0.33}},
+		{"empty self-reported floors at zero", models.Assessment{SelfReported: true}, models.DataQuality{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AssessQuality(tt.input); got != tt.want {
+				t.Errorf("AssessQuality() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
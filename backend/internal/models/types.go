@@ -1,7 +1,11 @@
 // Domain models for users, patients, assessments, and analytics DTOs.
 package models
 
-import "time"
+import (
+	"math"
+	"slices"
+	"time"
+)
 
 type User struct {
 	ID           int64      `json:"id"`
@@ -11,8 +15,32 @@ type User struct {
 	IsActive     bool       `json:"is_active"`
 	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
 	CreatedBy    *int64     `json:"created_by,omitempty"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	// EmailVerifiedAt is nil for self-registered users who have not verified yet
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// FailedLoginAttempts counts consecutive failed logins since the last
+	// success or lock; LockedUntil is set while the account is locked out
+	FailedLoginAttempts int        `json:"failed_login_attempts"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	// Activity is only loaded by the admin user list; nil elsewhere.
+	Activity *UserActivity `json:"activity,omitempty"`
+}
+
+// UserActivity summarises how much a user works in the system. High-risk
+// assessments are those with a risk score of 67 or more. LastActivityAt is
+// the latest of the last login, patient change and assessment.
+type UserActivity struct {
+	PatientCount            int        `json:"patient_count"`
+	AssessmentCount         int        `json:"assessment_count"`
+	HighRiskAssessmentCount int        `json:"high_risk_assessment_count"`
+	LastAssessmentAt        *time.Time `json:"last_assessment_at,omitempty"`
+	LastActivityAt          *time.Time `json:"last_activity_at,omitempty"`
+}
+
+// LockedAt reports whether the account is locked out at the given time.
+func (u *User) LockedAt(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
 }
 
 type Patient struct {
@@ -35,8 +63,17 @@ type Patient struct {
 	LDL             int       `json:"ldl,omitempty"`
 	HDL             int       `json:"hdl,omitempty"`
 	Triglycerides   int       `json:"triglycerides,omitempty"`
+	MRN             string    `json:"mrn,omitempty"`
+	HeightCM        float64   `json:"height_cm,omitempty"`
+	WeightKG        float64   `json:"weight_kg,omitempty"`
+	BMISource       string    `json:"bmi_source,omitempty"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
+	// ClinicID shares the patient with every member of that clinic; nil
+	// keeps it private to the owner.
+	ClinicID *int64 `json:"clinic_id,omitempty"`
+	// Contact is only loaded for single-patient reads; nil elsewhere.
+	Contact *PatientContact `json:"contact,omitempty"`
 }
 
 type Assessment struct {
@@ -63,16 +100,189 @@ type Assessment struct {
 	ValidationStatus string    `json:"validation_status,omitempty"`
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
+	// Status is AssessmentStatusDraft while the assessment is being entered
+	// and AssessmentStatusFinal once it is scored
+	Status string `json:"status"`
+	// SelfReported marks values reported by the patient rather than measured
+	SelfReported bool `json:"self_reported,omitempty"`
+	// HeightCM and WeightKG are set when BMI was computed from them;
+	// BMISource says where BMI came from (see DeriveBMI)
+	HeightCM  float64 `json:"height_cm,omitempty"`
+	WeightKG  float64 `json:"weight_kg,omitempty"`
+	BMISource string  `json:"bmi_source,omitempty"`
+	// Quality is nil for assessments stored before quality scoring existed
+	Quality *DataQuality `json:"quality,omitempty"`
+	// AmendsAssessmentID and AmendmentChain are only set on amendment reads
+	// (single-assessment GET and amendment responses). The chain lists every
+	// version, oldest first.
+	AmendsAssessmentID *int64       `json:"amends_assessment_id,omitempty"`
+	AmendmentChain     []Assessment `json:"amendment_chain,omitempty"`
+	// InUnits restates the glucose and lipids in the reader's preferred
+	// units. It is only set in responses to users who prefer mmol/L and is
+	// not stored.
+	InUnits *BiomarkerUnits `json:"in_units,omitempty"`
+	// PatientAge is the patient's age when scored, for predictors that use
+	// it. It is not stored or sent to the model service.
+	PatientAge int `json:"-"`
+	// OnMedication is whether the patient was taking a medication when the
+	// assessment was taken. It is not stored; the HTTP predictor sends it
+	// to the model service as on_medication.
+	OnMedication bool `json:"-"`
+}
+
+// Assessment statuses. Drafts are stored as entered, without validation or
+// a prediction, and are left out of analytics until finalized.
+const (
+	AssessmentStatusDraft = "draft"
+	AssessmentStatusFinal = "final"
+)
+
+// Draft reports whether the assessment is a draft. Assessments without a
+// status are final.
+func (a Assessment) Draft() bool {
+	return a.Status == AssessmentStatusDraft
+}
+
+// FinalAssessments returns list without its drafts, in the same order
+func FinalAssessments(list []Assessment) []Assessment {
+	out := make([]Assessment, 0, len(list))
+	for _, a := range list {
+		if !a.Draft() {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// BMI sources. Records stored before BMI could be computed have none.
+const (
+	BMISourceProvided = "provided"
+	BMISourceComputed = "computed"
+)
+
+// DeriveBMI returns the BMI to store and where it came from. Height and
+// weight, when both are given, win over an entered BMI, so every client gets
+// the same rounding; otherwise the entered BMI is kept as provided. The
+// source is empty when there is no BMI at all.
+func DeriveBMI(bmi, heightCM, weightKG float64) (float64, string) {
+	if heightCM > 0 && weightKG > 0 {
+		m := heightCM / 100
+		return math.Round(weightKG/(m*m)*10) / 10, BMISourceComputed
+	}
+	if bmi > 0 {
+		return bmi, BMISourceProvided
+	}
+	return 0, ""
+}
+
+// ClusterPendingPrediction is the cluster of an assessment stored before its
+// prediction, when predictions run asynchronously. Its risk score is 0 until
+// the prediction completes.
+const ClusterPendingPrediction = "pending_prediction"
+
+// BiomarkerUnits are an assessment's glucose and lipids in Units. The
+// assessment's own fields stay in mg/dL.
+type BiomarkerUnits struct {
+	Units         string  `json:"units"`
+	FBS           float64 `json:"fbs"`
+	Cholesterol   float64 `json:"cholesterol"`
+	LDL           float64 `json:"ldl"`
+	HDL           float64 `json:"hdl"`
+	Triglycerides float64 `json:"triglycerides"`
+}
+
+// DataQuality describes how complete and plausible an assessment's
+// biomarkers are. Completeness is the share of biomarkers provided,
+// OutOfRange how many of those are implausible.
+type DataQuality struct {
+	Score        int     `json:"score"`
+	Completeness float64 `json:"completeness"`
+	OutOfRange   int     `json:"out_of_range"`
+}
+
+// ClinicianDataQuality aggregates assessment quality for one clinician.
+// Assessments without a quality score are not counted.
+type ClinicianDataQuality struct {
+	UserID          int64   `json:"user_id"`
+	Email           string  `json:"email"`
+	Assessments     int     `json:"assessments"`
+	AvgScore        float64 `json:"avg_score"`
+	AvgCompleteness float64 `json:"avg_completeness"`
+	OutOfRange      int     `json:"out_of_range"`
+	SelfReported    int     `json:"self_reported"`
+	// LowQuality counts assessments scoring below the report's threshold
+	LowQuality int `json:"low_quality"`
 }
 
 type RefreshToken struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	TokenHash string    `json:"token_hash"`
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	TokenHash string `json:"token_hash"`
+	// FamilyID links a login's refresh token to every token rotated from it
+	FamilyID  string    `json:"family_id,omitempty"`
 	ExpiresAt time.Time `json:"expires_at"`
 	Revoked   bool      `json:"revoked"`
 	CreatedAt time.Time `json:"created_at"`
 	RevokedAt time.Time `json:"revoked_at,omitempty"`
+	// UserAgent and IPAddress describe the client that signed in
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	SignedInAt time.Time `json:"signed_in_at"`
+}
+
+// Session is one signed-in client: the active refresh token of a token
+// family. ID is the family, so it stays the same across refreshes.
+type Session struct {
+	ID           string    `json:"id"`
+	UserAgent    string    `json:"user_agent"`
+	IPAddress    string    `json:"ip_address"`
+	SignedInAt   time.Time `json:"signed_in_at"`
+	LastActiveAt time.Time `json:"last_active_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	// Current marks the session of the access token making the request
+	Current bool `json:"current"`
+}
+
+// Login failure reasons recorded in the login history
+const (
+	LoginFailureUnknownEmail   = "unknown_email"
+	LoginFailureBadPassword    = "invalid_password"
+	LoginFailureLocked         = "locked"
+	LoginFailureUnverified     = "email_not_verified"
+	LoginFailureInactive       = "inactive"
+	LoginFailureInvalidMFACode = "invalid_mfa_code"
+)
+
+// LoginAttempt is one sign-in attempt. UserID is nil when the email matched
+// no account; FailureReason is empty on success.
+type LoginAttempt struct {
+	ID            int64     `json:"id"`
+	UserID        *int64    `json:"user_id,omitempty"`
+	Email         string    `json:"email"`
+	IPAddress     string    `json:"ip_address"`
+	UserAgent     string    `json:"user_agent"`
+	Success       bool      `json:"success"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// LoginHistoryParams filters the login history; zero fields match every
+// attempt. Limit defaults to 50.
+type LoginHistoryParams struct {
+	UserID     *int64
+	IPAddress  string
+	FailedOnly bool
+	Since      *time.Time
+	Limit      int
+}
+
+// SuspiciousIP is an address with repeated failed logins
+type SuspiciousIP struct {
+	IPAddress string `json:"ip_address"`
+	Failures  int    `json:"failures"`
+	// Accounts counts the distinct emails tried, high for credential stuffing
+	Accounts    int       `json:"accounts"`
+	LastAttempt time.Time `json:"last_attempt"`
 }
 
 type ClusterAnalytics struct {
@@ -80,10 +290,110 @@ type ClusterAnalytics struct {
 	Count   int    `json:"count"`
 }
 
+// TrendPoint holds biomarker averages for one time bucket. Only the
+// biomarkers that were asked for, and that have values in the bucket, are set.
 type TrendPoint struct {
-	Label string  `json:"label"`
-	HbA1c float64 `json:"hba1c"`
-	FBS   float64 `json:"fbs"`
+	Label         string    `json:"label"`
+	Start         time.Time `json:"start"`
+	Count         int       `json:"count"`
+	HbA1c         *float64  `json:"hba1c,omitempty"`
+	FBS           *float64  `json:"fbs,omitempty"`
+	BMI           *float64  `json:"bmi,omitempty"`
+	Cholesterol   *float64  `json:"cholesterol,omitempty"`
+	LDL           *float64  `json:"ldl,omitempty"`
+	HDL           *float64  `json:"hdl,omitempty"`
+	Triglycerides *float64  `json:"triglycerides,omitempty"`
+	Systolic      *float64  `json:"systolic,omitempty"`
+	Diastolic     *float64  `json:"diastolic,omitempty"`
+	RiskScore     *float64  `json:"risk_score,omitempty"`
+}
+
+// Field returns the average for biomarker, or nil for an unknown name.
+func (p *TrendPoint) Field(biomarker string) **float64 {
+	switch biomarker {
+	case "hba1c":
+		return &p.HbA1c
+	case "fbs":
+		return &p.FBS
+	case "bmi":
+		return &p.BMI
+	case "cholesterol":
+		return &p.Cholesterol
+	case "ldl":
+		return &p.LDL
+	case "hdl":
+		return &p.HDL
+	case "triglycerides":
+		return &p.Triglycerides
+	case "systolic":
+		return &p.Systolic
+	case "diastolic":
+		return &p.Diastolic
+	case "risk_score":
+		return &p.RiskScore
+	}
+	return nil
+}
+
+// TrendBiomarkers lists the biomarkers a trend can average, in response order.
+var TrendBiomarkers = []string{"hba1c", "fbs", "bmi", "cholesterol", "ldl", "hdl", "triglycerides", "systolic", "diastolic", "risk_score"}
+
+// Trend granularities
+const (
+	TrendWeek    = "week"
+	TrendMonth   = "month"
+	TrendQuarter = "quarter"
+)
+
+// TrendParams selects the assessments a trend covers and how they are
+// bucketed. Start is inclusive and End exclusive; either may be nil. An empty
+// Granularity means monthly buckets, and no Biomarkers means HbA1c and FBS.
+type TrendParams struct {
+	Start       *time.Time
+	End         *time.Time
+	Granularity string
+	Biomarkers  []string
+	Scope       CohortScope
+}
+
+// PatientWithLatest is a patient row joined with summary fields from their most
+// recent assessment. The latest fields are zero and LastVisit nil if none exist.
+type PatientWithLatest struct {
+	Patient   Patient
+	Cluster   string
+	RiskScore int
+	FBS       float64
+	HbA1c     float64
+	LastVisit *time.Time
+}
+
+// PatientMatch is the lightweight payload returned by the patient typeahead
+type PatientMatch struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Age       int        `json:"age,omitempty"`
+	MRN       string     `json:"mrn,omitempty"`
+	LastVisit *time.Time `json:"last_visit,omitempty"`
+}
+
+// PatientDuplicate is a pair of a user's patients that probably record the
+// same person. Similarity is the trigram similarity of their names, 0 to 1;
+// SameMRN is set when their MRNs match, whatever the names.
+type PatientDuplicate struct {
+	Patient    PatientMatch `json:"patient"`
+	Duplicate  PatientMatch `json:"duplicate"`
+	Similarity float64      `json:"similarity"`
+	SameMRN    bool         `json:"same_mrn"`
+}
+
+// PatientMerge counts what a merge moved from the merged patient onto the
+// surviving one
+type PatientMerge struct {
+	PatientID   int64 `json:"patient_id"`
+	MergedID    int64 `json:"merged_id"`
+	Assessments int   `json:"assessments"`
+	Notes       int   `json:"notes"`
+	Attachments int   `json:"attachments"`
 }
 
 // AssessmentTrend represents a single point in a patient's risk trend over time
@@ -100,6 +410,36 @@ type AssessmentTrend struct {
 	HDL           int       `json:"hdl"`
 }
 
+// ProjectedValue is a metric's projected value some months after the latest
+// assessment, with the bounds of its prediction interval.
+type ProjectedValue struct {
+	Months int       `json:"months"`
+	Date   time.Time `json:"date"`
+	Value  float64   `json:"value"`
+	Lower  float64   `json:"lower"`
+	Upper  float64   `json:"upper"`
+}
+
+// MetricProjection is the fitted trend of one metric. RatePerMonth is the
+// change per month for a linear model and the relative change per month for
+// an exponential one. Reason explains why a metric could not be projected.
+type MetricProjection struct {
+	N            int              `json:"n"`
+	RatePerMonth *float64         `json:"rate_per_month,omitempty"`
+	Projections  []ProjectedValue `json:"projections,omitempty"`
+	Reason       string           `json:"reason,omitempty"`
+}
+
+// PatientProjection projects a patient's HbA1c, FBS and risk score forward
+// from their assessment history.
+type PatientProjection struct {
+	PatientID int64                       `json:"patient_id"`
+	Model     string                      `json:"model"`
+	Level     float64                     `json:"level"`
+	From      *time.Time                  `json:"from"`
+	Metrics   map[string]MetricProjection `json:"metrics"`
+}
+
 // CohortGroup represents aggregated statistics for a patient group
 type CohortGroup struct {
 	Name              string  `json:"name"`
@@ -115,6 +455,100 @@ type CohortGroup struct {
 	HighRiskCount     int     `json:"high_risk_count,omitempty"`
 }
 
+// CohortScope narrows cohort statistics to one clinician's patients or to
+// the patients of a clinic's members. The zero value covers every patient.
+type CohortScope struct {
+	UserID   *int32 `json:"user_id,omitempty"`
+	ClinicID *int32 `json:"clinic_id,omitempty"`
+	// TagID further narrows the scope to the patients carrying that tag
+	TagID *int64 `json:"tag_id,omitempty"`
+	// ClinicIDs keeps the patients of members of any of these clinics. It
+	// comes from the caller's AccessScope, not the request.
+	ClinicIDs []int32 `json:"clinic_ids,omitempty"`
+}
+
+// IsZero reports whether the scope covers every patient.
+func (s CohortScope) IsZero() bool {
+	return s.UserID == nil && s.ClinicID == nil && s.TagID == nil && len(s.ClinicIDs) == 0
+}
+
+// AccessScope is the data a caller may see across clinics, resolved once
+// per request by middleware.AccessScope. All covers every clinic; otherwise
+// the caller sees their own patients and those of their clinics' members.
+type AccessScope struct {
+	All       bool    `json:"all"`
+	UserID    int64   `json:"user_id"`
+	ClinicIDs []int32 `json:"clinic_ids"`
+}
+
+// HasClinic reports whether the scope covers the clinic.
+func (s AccessScope) HasClinic(id int32) bool {
+	return s.All || slices.Contains(s.ClinicIDs, id)
+}
+
+// Restrict narrows a cohort scope to what s may see. A caller without
+// clinics is limited to their own patients.
+func (s AccessScope) Restrict(scope CohortScope) CohortScope {
+	if s.All {
+		return scope
+	}
+	if len(s.ClinicIDs) == 0 {
+		id := int32(s.UserID)
+		scope.UserID = &id
+		return scope
+	}
+	scope.ClinicIDs = s.ClinicIDs
+	return scope
+}
+
+// MetricMoments summarizes one metric within a cohort
+type MetricMoments struct {
+	N      int     `json:"n"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"`
+}
+
+// CohortSample holds the per-metric moments and risk level counts for a single
+// cohort group, enough to run significance tests without raw rows.
+type CohortSample struct {
+	Name            string                   `json:"name"`
+	Count           int                      `json:"count"`
+	Metrics         map[string]MetricMoments `json:"metrics"`
+	RiskLevelCounts [3]int                   `json:"risk_level_counts"` // low, moderate, high
+}
+
+// MetricComparison is a Welch t-test between two cohorts on one metric.
+// EffectSize is Cohen's d; test fields are omitted when the test is undefined.
+type MetricComparison struct {
+	Metric           string   `json:"metric"`
+	MeanA            float64  `json:"mean_a"`
+	MeanB            float64  `json:"mean_b"`
+	Difference       float64  `json:"difference"`
+	EffectSize       float64  `json:"effect_size"`
+	TStatistic       *float64 `json:"t_statistic,omitempty"`
+	DegreesOfFreedom *float64 `json:"degrees_of_freedom,omitempty"`
+	PValue           *float64 `json:"p_value,omitempty"`
+	Significant      bool     `json:"significant"`
+}
+
+// ChiSquareResult is a chi-square test of independence on risk level counts
+type ChiSquareResult struct {
+	Statistic        float64 `json:"statistic"`
+	DegreesOfFreedom int     `json:"degrees_of_freedom"`
+	PValue           float64 `json:"p_value"`
+	Significant      bool    `json:"significant"`
+}
+
+// CohortComparison compares two groups of the same cohort dimension
+type CohortComparison struct {
+	GroupBy          string             `json:"group_by"`
+	Alpha            float64            `json:"alpha"`
+	GroupA           CohortSample       `json:"group_a"`
+	GroupB           CohortSample       `json:"group_b"`
+	Metrics          []MetricComparison `json:"metrics"`
+	RiskDistribution *ChiSquareResult   `json:"risk_distribution,omitempty"`
+}
+
 // Clinic represents a clinic entity
 type Clinic struct {
 	ID        int64     `json:"id"`
@@ -124,12 +558,303 @@ type Clinic struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Validation modes control how a clinic treats implausible biomarker values.
+// Strict clinics reject them at write time; advisory clinics store them with warnings.
+const (
+	ValidationModeStrict   = "strict"
+	ValidationModeAdvisory = "advisory"
+)
+
+// ClinicReportSettings brand a clinic's PDF reports. Name and address come
+// from the clinic itself; Logo is a JPEG, served on its own endpoint.
+type ClinicReportSettings struct {
+	ClinicID int64  `json:"clinic_id"`
+	Name     string `json:"name"`
+	Address  string `json:"address,omitempty"`
+	Locale   string `json:"locale"`
+	HasLogo  bool   `json:"has_logo"`
+	Logo     []byte `json:"-"`
+}
+
+// PatientPhoto is the metadata of a stored patient photo; the image itself
+// lives in object storage under StorageKey.
+type PatientPhoto struct {
+	PatientID   int64     `json:"patient_id"`
+	StorageKey  string    `json:"-"`
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	SizeBytes   int       `json:"size_bytes"`
+	UploadedBy  int64     `json:"uploaded_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AssessmentAttachment is the metadata of a file backing an assessment,
+// such as the lab report its values were entered from; the file itself
+// lives in object storage under StorageKey. DownloadURL is a signed link
+// valid until URLExpiresAt, filled in when the attachment is returned.
+type AssessmentAttachment struct {
+	ID           int64      `json:"id"`
+	AssessmentID int64      `json:"assessment_id"`
+	PatientID    int64      `json:"patient_id"`
+	StorageKey   string     `json:"-"`
+	Filename     string     `json:"filename"`
+	ContentType  string     `json:"content_type"`
+	SizeBytes    int64      `json:"size_bytes"`
+	UploadedBy   int64      `json:"uploaded_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DownloadURL  string     `json:"download_url,omitempty"`
+	URLExpiresAt *time.Time `json:"url_expires_at,omitempty"`
+}
+
+// Patient contact channels
+const (
+	ContactChannelEmail = "email"
+	ContactChannelSMS   = "sms"
+)
+
+// PatientContact holds a patient's contact details. ConsentAt is maintained
+// by the store: set when consent is given, cleared when it is withdrawn.
+type PatientContact struct {
+	PatientID      int64      `json:"-"`
+	Phone          string     `json:"phone,omitempty"`
+	Email          string     `json:"email,omitempty"`
+	AddressLine1   string     `json:"address_line1,omitempty"`
+	AddressLine2   string     `json:"address_line2,omitempty"`
+	City           string     `json:"city,omitempty"`
+	Region         string     `json:"region,omitempty"`
+	PostalCode     string     `json:"postal_code,omitempty"`
+	Country        string     `json:"country,omitempty"`
+	ContactConsent bool       `json:"contact_consent"`
+	ConsentAt      *time.Time `json:"consent_at,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Channels lists the channels the patient may be contacted on directly.
+// It is empty unless the patient has consented.
+func (c *PatientContact) Channels() []string {
+	if c == nil || !c.ContactConsent {
+		return nil
+	}
+	var channels []string
+	if c.Email != "" {
+		channels = append(channels, ContactChannelEmail)
+	}
+	if c.Phone != "" {
+		channels = append(channels, ContactChannelSMS)
+	}
+	return channels
+}
+
+// Patient note categories
+const (
+	NoteGeneral    = "general"
+	NoteMedication = "medication"
+	NoteLifestyle  = "lifestyle"
+	NoteCarePlan   = "care_plan"
+)
+
+// NoteCategories lists the valid PatientNote categories
+var NoteCategories = []string{NoteGeneral, NoteMedication, NoteLifestyle, NoteCarePlan}
+
+// PatientNote is a clinician's free-text note or care plan entry on a
+// patient. AuthorID is nil once the author's account has been deleted;
+// AuthorEmail is filled in from the author's account when read.
+type PatientNote struct {
+	ID          int64     `json:"id"`
+	PatientID   int64     `json:"patient_id"`
+	AuthorID    *int64    `json:"author_id,omitempty"`
+	AuthorEmail string    `json:"author_email,omitempty"`
+	Category    string    `json:"category"`
+	Body        string    `json:"body"`
+	Pinned      bool      `json:"pinned"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PatientMedication is a medication a patient takes. StoppedAt is nil
+// while it is ongoing; Adherent is false when the patient is known not to
+// take it as prescribed.
+type PatientMedication struct {
+	ID        int64      `json:"id"`
+	PatientID int64      `json:"patient_id"`
+	Name      string     `json:"name"`
+	Dose      string     `json:"dose"`
+	StartedAt time.Time  `json:"started_at"`
+	StoppedAt *time.Time `json:"stopped_at"`
+	Adherent  bool       `json:"adherent"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// TakenAt reports whether the medication was being taken, as prescribed,
+// at t
+func (m PatientMedication) TakenAt(t time.Time) bool {
+	return m.Adherent && !m.StartedAt.After(t) && (m.StoppedAt == nil || m.StoppedAt.After(t))
+}
+
+// Follow-up list filters
+const (
+	FollowUpsOverdue  = "overdue"
+	FollowUpsUpcoming = "upcoming"
+)
+
+// FollowUp is a visit scheduled for a patient. It is the patient owner's to
+// attend to. CompletedAt is set once the visit took place; RemindedAt once
+// a reminder went out, and is cleared when the visit is rescheduled.
+type FollowUp struct {
+	ID          int64      `json:"id"`
+	PatientID   int64      `json:"patient_id"`
+	PatientName string     `json:"patient_name,omitempty"`
+	UserID      int64      `json:"user_id"`
+	DueAt       time.Time  `json:"due_at"`
+	Reason      string     `json:"reason"`
+	CompletedAt *time.Time `json:"completed_at"`
+	RemindedAt  *time.Time `json:"reminded_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Task kinds. Review and overdue tasks are created by the system; manual
+// tasks by clinicians for themselves.
+const (
+	TaskReviewHighRisk    = "review_high_risk"
+	TaskOverdueAssessment = "overdue_assessment"
+	TaskManual            = "manual"
+)
+
+// TaskKinds lists the valid Task kinds
+var TaskKinds = []string{TaskReviewHighRisk, TaskOverdueAssessment, TaskManual}
+
+// Task statuses. Open and in-progress tasks are active; done and dismissed
+// tasks are closed.
+const (
+	TaskOpen       = "open"
+	TaskInProgress = "in_progress"
+	TaskDone       = "done"
+	TaskDismissed  = "dismissed"
+)
+
+// TaskStatuses lists the valid Task statuses
+var TaskStatuses = []string{TaskOpen, TaskInProgress, TaskDone, TaskDismissed}
+
+// TaskClosed reports whether status is done or dismissed
+func TaskClosed(status string) bool {
+	return status == TaskDone || status == TaskDismissed
+}
+
+// Task is an actionable item in a clinician's inbox. PatientID and
+// AssessmentID point at what it is about, if anything; ClosedAt is set
+// while it is done or dismissed.
+type Task struct {
+	ID           int64      `json:"id"`
+	UserID       int64      `json:"user_id"`
+	Kind         string     `json:"kind"`
+	Title        string     `json:"title"`
+	Status       string     `json:"status"`
+	PatientID    *int64     `json:"patient_id"`
+	PatientName  string     `json:"patient_name,omitempty"`
+	AssessmentID *int64     `json:"assessment_id"`
+	DueAt        *time.Time `json:"due_at"`
+	ClosedAt     *time.Time `json:"closed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TaskListParams filters a clinician's tasks. No statuses means the active
+// ones.
+type TaskListParams struct {
+	Statuses []string
+	Kind     string
+}
+
+// Tag is a clinician's own label for an ad-hoc cohort of patients, such as
+// "GDM history" or "study-arm-A". PatientCount is filled in when tags are
+// listed.
+type Tag struct {
+	ID           int64     `json:"id"`
+	UserID       int64     `json:"user_id"`
+	Name         string    `json:"name"`
+	PatientCount int       `json:"patient_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
 // UserClinic represents a user's membership in a clinic
 type UserClinic struct {
 	Clinic
 	Role string `json:"role"` // 'member' or 'clinic_admin'
 }
 
+// Clinic roles are scoped to a single clinic and independent of the global user role.
+const (
+	ClinicRoleMember = "member"
+	ClinicRoleAdmin  = "clinic_admin"
+)
+
+// Permissions are granted to roles in the role_permissions table and checked
+// by middleware.PermissionRequired instead of comparing role names. Clinic
+// roles only grant their permissions within their clinic.
+const (
+	PermPatientsRead  = "patients.read"
+	PermPatientsWrite = "patients.write"
+	PermAnalyticsRead = "analytics.read"
+	PermAdminUsers    = "admin.users"
+	PermAdminSystem   = "admin.system"
+	PermClinicRead    = "clinic.read"
+	PermClinicManage  = "clinic.manage"
+)
+
+// AllPermissions lists every permission a role can be granted.
+var AllPermissions = []string{
+	PermPatientsRead, PermPatientsWrite, PermAnalyticsRead,
+	PermAdminUsers, PermAdminSystem, PermClinicRead, PermClinicManage,
+}
+
+// Role scopes: global roles are the users.role column, clinic roles the
+// role of a clinic membership.
+const (
+	RoleScopeGlobal = "global"
+	RoleScopeClinic = "clinic"
+)
+
+// RoleScopes maps each role to its scope.
+var RoleScopes = map[string]string{
+	"admin":          RoleScopeGlobal,
+	"clinician":      RoleScopeGlobal,
+	ClinicRoleAdmin:  RoleScopeClinic,
+	ClinicRoleMember: RoleScopeClinic,
+}
+
+// DefaultRolePermissions are the grants migration 0049 seeds, and the in-memory
+// store starts with.
+var DefaultRolePermissions = map[string][]string{
+	"admin":          AllPermissions,
+	"clinician":      {PermPatientsRead, PermPatientsWrite, PermAnalyticsRead},
+	ClinicRoleAdmin:  {PermClinicRead, PermClinicManage},
+	ClinicRoleMember: {PermClinicRead},
+}
+
+// Role is a role with the permissions it grants
+type Role struct {
+	Name        string   `json:"name"`
+	Scope       string   `json:"scope"`
+	Permissions []string `json:"permissions"`
+}
+
+// ClinicMember is a clinic directory entry with the member's activity
+type ClinicMember struct {
+	UserID           int64      `json:"user_id"`
+	Email            string     `json:"email"`
+	ClinicRole       string     `json:"clinic_role"`
+	GlobalRole       string     `json:"global_role"`
+	IsActive         bool       `json:"is_active"`
+	JoinedAt         time.Time  `json:"joined_at"`
+	LastLoginAt      *time.Time `json:"last_login_at,omitempty"`
+	PatientCount     int        `json:"patient_count"`
+	AssessmentCount  int        `json:"assessment_count"`
+	LastAssessmentAt *time.Time `json:"last_assessment_at,omitempty"`
+}
+
 // ClinicAggregate represents aggregate statistics for a clinic
 type ClinicAggregate struct {
 	TotalPatients        int     `json:"total_patients"`
@@ -151,6 +876,18 @@ type SystemStats struct {
 	NewUsersThisMonth    int     `json:"new_users_this_month"`
 }
 
+// StatsRange limits admin statistics to assessments created from Start
+// up to but not including End. A nil end is open.
+type StatsRange struct {
+	Start *time.Time
+	End   *time.Time
+}
+
+// Contains reports whether t falls within the range
+func (r StatsRange) Contains(t time.Time) bool {
+	return (r.Start == nil || !t.Before(*r.Start)) && (r.End == nil || t.Before(*r.End))
+}
+
 // ClinicComparison represents per-clinic statistics for admin comparison
 type ClinicComparison struct {
 	ClinicID        int64   `json:"clinic_id"`
@@ -172,6 +909,148 @@ type AuditEvent struct {
 	CreatedAt  time.Time              `json:"created_at"`
 }
 
+// Risk alert reasons
+const (
+	RiskAlertHbA1c     = "hba1c_diabetic"
+	RiskAlertRiskScore = "risk_score_threshold"
+)
+
+// RiskAlert is a queued notification that a patient's new assessment crossed
+// the alert criteria. DeliveredAt stays nil until a notifier sends it.
+// PatientChannels records where the patient consented to be reached when the
+// alert was raised; empty means the alert is for the clinician only.
+type RiskAlert struct {
+	ID              int64      `json:"id"`
+	UserID          int64      `json:"user_id"`
+	PatientID       int64      `json:"patient_id"`
+	AssessmentID    int64      `json:"assessment_id"`
+	Reasons         []string   `json:"reasons"`
+	RiskScore       int        `json:"risk_score"`
+	HbA1c           float64    `json:"hba1c,omitempty"`
+	PatientChannels []string   `json:"patient_channels,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	DeliveredAt     *time.Time `json:"delivered_at,omitempty"`
+}
+
+// Webhook events external systems can subscribe to
+const (
+	WebhookAssessmentCreated = "assessment.created"
+	WebhookPatientCreated    = "patient.created"
+	WebhookHighRisk          = "assessment.high_risk"
+	WebhookFollowUpDue       = "follow_up.due"
+)
+
+// WebhookEvents lists every event a webhook may subscribe to
+var WebhookEvents = []string{WebhookAssessmentCreated, WebhookPatientCreated, WebhookHighRisk, WebhookFollowUpDue}
+
+// Webhook is an external endpoint notified of DIANA events
+type Webhook struct {
+	ID          int64     `json:"id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description,omitempty"`
+	Events      []string  `json:"events"`
+	Active      bool      `json:"active"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// Secret signs deliveries. It is only returned when the webhook is
+	// created.
+	Secret string `json:"secret,omitempty"`
+}
+
+// Webhook delivery states
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event queued for one webhook. URL and Secret are
+// the webhook's, filled in when a delivery is claimed for sending.
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      int64      `json:"webhook_id"`
+	Event          string     `json:"event"`
+	Payload        []byte     `json:"-"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	URL            string     `json:"-"`
+	Secret         string     `json:"-"`
+}
+
+// Assessment draft states
+const (
+	DraftPending   = "pending"
+	DraftCompleted = "completed"
+	DraftDiscarded = "discarded"
+)
+
+// Assessment draft sources
+const DraftSourceHL7 = "hl7"
+
+// AssessmentDraft holds lab results received for a patient until a
+// clinician completes them into an assessment. Zero biomarkers were not in
+// the results.
+type AssessmentDraft struct {
+	ID               int64      `json:"id"`
+	PatientID        int64      `json:"patient_id"`
+	Source           string     `json:"source"`
+	MessageControlID string     `json:"message_control_id"`
+	SendingFacility  string     `json:"sending_facility,omitempty"`
+	FBS              float64    `json:"fbs,omitempty"`
+	HbA1c            float64    `json:"hba1c,omitempty"`
+	Cholesterol      int        `json:"cholesterol,omitempty"`
+	LDL              int        `json:"ldl,omitempty"`
+	HDL              int        `json:"hdl,omitempty"`
+	Triglycerides    int        `json:"triglycerides,omitempty"`
+	ObservedAt       *time.Time `json:"observed_at,omitempty"`
+	Status           string     `json:"status"`
+	AssessmentID     *int64     `json:"assessment_id,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ResolvedAt       *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Revalidation job states
+const (
+	RevalidationRunning   = "running"
+	RevalidationCompleted = "completed"
+	RevalidationFailed    = "failed"
+)
+
+// RevalidationJob re-runs the current validation rules over stored assessments
+type RevalidationJob struct {
+	ID         int64               `json:"id"`
+	Status     string              `json:"status"`
+	Since      time.Time           `json:"since"`
+	DryRun     bool                `json:"dry_run"`
+	StartedBy  string              `json:"started_by"`
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	Summary    RevalidationSummary `json:"summary"`
+}
+
+// RevalidationSummary tallies validation status changes found by a job.
+// WarningsAdded/WarningsRemoved count individual warning codes; ChangedIDs is
+// a bounded sample of the assessments whose status changed.
+type RevalidationSummary struct {
+	Scanned         int            `json:"scanned"`
+	Changed         int            `json:"changed"`
+	BecameOK        int            `json:"became_ok"`
+	BecameWarning   int            `json:"became_warning"`
+	WarningsAdded   map[string]int `json:"warnings_added"`
+	WarningsRemoved map[string]int `json:"warnings_removed"`
+	ChangedIDs      []int64        `json:"changed_ids"`
+	// QualityRescored counts assessments whose data quality score was
+	// missing or out of date
+	QualityRescored int `json:"quality_rescored"`
+}
+
 // ModelRun represents a training run of the ML model
 type ModelRun struct {
 	ID           int64     `json:"id"`
@@ -180,6 +1059,112 @@ type ModelRun struct {
 	Notes        string    `json:"notes,omitempty"`
 	IsActive     bool      `json:"is_active"`
 	CreatedAt    time.Time `json:"created_at"`
+	// Evaluation metrics reported by the training pipeline, each 0-1
+	Accuracy         *float64 `json:"accuracy,omitempty"`
+	AUC              *float64 `json:"auc,omitempty"`
+	CalibrationError *float64 `json:"calibration_error,omitempty"`
+	// TrainingDistribution is the cluster distribution of the training
+	// dataset, the baseline for drift reports
+	TrainingDistribution *PredictionDistribution `json:"training_distribution,omitempty"`
+}
+
+// PredictionDistribution summarizes a set of predictions: the share (0-1) of
+// each cluster and the mean risk score
+type PredictionDistribution struct {
+	Count         int                `json:"count"`
+	Clusters      map[string]float64 `json:"clusters"`
+	MeanRiskScore float64            `json:"mean_risk_score"`
+}
+
+// Drift levels of a ModelDriftReport, by population stability index
+const (
+	DriftStable      = "stable"
+	DriftModerate    = "moderate"
+	DriftSignificant = "significant"
+)
+
+// ModelDriftReport compares the predictions a model run made since Since
+// with the distribution of its training dataset. PSI, MeanRiskDelta and
+// Status are only set when the run has a training distribution and recent
+// predictions.
+type ModelDriftReport struct {
+	ModelRunID    int64                   `json:"model_run_id"`
+	ModelVersion  string                  `json:"model_version"`
+	DatasetHash   string                  `json:"dataset_hash,omitempty"`
+	Since         time.Time               `json:"since"`
+	Recent        PredictionDistribution  `json:"recent"`
+	Training      *PredictionDistribution `json:"training,omitempty"`
+	PSI           *float64                `json:"psi,omitempty"`
+	MeanRiskDelta *float64                `json:"mean_risk_delta,omitempty"`
+	Status        string                  `json:"status,omitempty"`
+}
+
+// AssessmentPrediction is an assessment re-scored by a model run, beside
+// the score it had when re-scored
+type AssessmentPrediction struct {
+	AssessmentID         int64     `json:"assessment_id"`
+	ModelRunID           int64     `json:"model_run_id"`
+	PreviousCluster      string    `json:"previous_cluster"`
+	PreviousRiskScore    int       `json:"previous_risk_score"`
+	PreviousModelVersion string    `json:"previous_model_version,omitempty"`
+	Cluster              string    `json:"cluster"`
+	RiskScore            int       `json:"risk_score"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+// Rescore job states
+const (
+	RescoreRunning   = "running"
+	RescoreCompleted = "completed"
+	RescoreFailed    = "failed"
+)
+
+// RescoreJob re-runs predictions for stored assessments with a model run.
+// Since and Cluster, when set, limit it to assessments created at or after
+// Since and to those currently in Cluster.
+type RescoreJob struct {
+	ID           int64          `json:"id"`
+	ModelRunID   int64          `json:"model_run_id"`
+	ModelVersion string         `json:"model_version"`
+	Status       string         `json:"status"`
+	Since        *time.Time     `json:"since,omitempty"`
+	Cluster      string         `json:"cluster,omitempty"`
+	StartedBy    string         `json:"started_by"`
+	StartedAt    time.Time      `json:"started_at"`
+	FinishedAt   *time.Time     `json:"finished_at,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	Summary      RescoreSummary `json:"summary"`
+}
+
+// RescoreSummary tallies a rescore job. Failed counts assessments the model
+// could not score; they are not stored.
+type RescoreSummary struct {
+	Scanned        int     `json:"scanned"`
+	Rescored       int     `json:"rescored"`
+	Failed         int     `json:"failed"`
+	ClusterChanged int     `json:"cluster_changed"`
+	MeanRiskDelta  float64 `json:"mean_risk_delta"`
+}
+
+// CachedPrediction is a model result stored under a hash of its inputs.
+// Explained is set when Explanation was requested; it may still be nil if
+// the model could not explain.
+type CachedPrediction struct {
+	Cluster     string                 `json:"cluster"`
+	RiskScore   int                    `json:"risk_score"`
+	Explanation map[string]interface{} `json:"explanation,omitempty"`
+	Explained   bool                   `json:"explained"`
+}
+
+// PredictionCacheStats counts prediction cache lookups since startup.
+// StoreHits are misses in memory answered by the database.
+type PredictionCacheStats struct {
+	Capacity   int     `json:"capacity"`
+	Size       int     `json:"size"`
+	MemoryHits int64   `json:"memory_hits"`
+	StoreHits  int64   `json:"store_hits"`
+	Misses     int64   `json:"misses"`
+	HitRate    float64 `json:"hit_rate"`
 }
 
 // UserListParams defines pagination and filter parameters for user listing
@@ -189,16 +1174,92 @@ type UserListParams struct {
 	Search   string `form:"search"`
 	Role     string `form:"role"`
 	IsActive *bool  `form:"is_active"`
+	Sort     string `form:"sort" binding:"omitempty,oneof=email created_at last_login_at patient_count assessment_count high_risk_assessment_count last_activity_at"`
+	Order    string `form:"order" binding:"omitempty,oneof=asc desc"`
+}
+
+// PatientListParams defines pagination, search, filter and sort parameters for
+// patient listing. Cluster and risk filters apply to each patient's latest assessment.
+type PatientListParams struct {
+	Page            int    `form:"page" binding:"omitempty,min=1"`
+	PageSize        int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	Search          string `form:"search"`
+	MinAge          *int   `form:"min_age" binding:"omitempty,min=0,max=150"`
+	MaxAge          *int   `form:"max_age" binding:"omitempty,min=0,max=150"`
+	MenopauseStatus string `form:"menopause_status"`
+	Cluster         string `form:"cluster"`
+	MinRisk         *int   `form:"min_risk" binding:"omitempty,min=0,max=100"`
+	MaxRisk         *int   `form:"max_risk" binding:"omitempty,min=0,max=100"`
+	Sort            string `form:"sort" binding:"omitempty,oneof=name age created_at updated_at risk_score"`
+	Order           string `form:"order" binding:"omitempty,oneof=asc desc"`
+	// ClinicID lists the patients shared with that clinic instead of the
+	// caller's own.
+	ClinicID *int32 `form:"clinic_id" binding:"omitempty,min=1"`
+	// TagID keeps the patients carrying one of the caller's tags
+	TagID *int64 `form:"tag_id" binding:"omitempty,min=1"`
+}
+
+// PatientListView is a named filter and sort combination for the patient
+// list. Filters holds the list's query parameters, such as "cluster" or
+// "sort", as the client would send them.
+type PatientListView struct {
+	ID        int64             `json:"id"`
+	UserID    int64             `json:"user_id"`
+	Name      string            `json:"name"`
+	Filters   map[string]string `json:"filters"`
+	IsDefault bool              `json:"is_default"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Unit systems for glucose and lipid values. HbA1c, blood pressure and BMI
+// are shown in the same units under both.
+const (
+	UnitsMgDL  = "mg/dL"
+	UnitsMmolL = "mmol/L"
+)
+
+// Date formats for reports. DateFormatLong spells out the month in the
+// report locale.
+const (
+	DateFormatLong = "long"
+	DateFormatISO  = "iso"
+	DateFormatDMY  = "dmy"
+	DateFormatMDY  = "mdy"
+)
+
+// UserPreferences holds a user's settings for the client
+type UserPreferences struct {
+	// Units is UnitsMgDL or UnitsMmolL; biomarkers are always stored in mg/dL
+	Units string `json:"units"`
+	// Locale overrides the clinic's report locale when set
+	Locale     string `json:"locale"`
+	DateFormat string `json:"date_format"`
+	// DefaultPatientView is the patient list view to open with, if any. It
+	// is read from the user's saved views, not stored with the rest.
+	DefaultPatientView *PatientListView `json:"default_patient_view"`
 }
 
 // AuditListParams defines pagination and filter parameters for audit log listing
 type AuditListParams struct {
-	Page      int       `form:"page" binding:"min=1"`
-	PageSize  int       `form:"page_size" binding:"min=1,max=100"`
-	Actor     string    `form:"actor"`
-	Action    string    `form:"action"`
-	StartDate time.Time `form:"start_date"`
-	EndDate   time.Time `form:"end_date"`
+	Page     int    `form:"page" binding:"min=1"`
+	PageSize int    `form:"page_size" binding:"min=1,max=100"`
+	Actor    string `form:"actor"`
+	Action   string `form:"action"`
+	// TargetType and TargetID narrow the log to one record, e.g. a patient
+	TargetType string    `form:"target_type"`
+	TargetID   int       `form:"target_id"`
+	StartDate  time.Time `form:"start_date"`
+	EndDate    time.Time `form:"end_date"`
+}
+
+// ListVersion summarises a list for conditional GETs. It changes whenever a
+// row is added, removed or updated: Count and IDSum catch a changed set of
+// rows, LastUpdated an edit. LastUpdated is zero for an empty list.
+type ListVersion struct {
+	Count       int
+	IDSum       int64
+	LastUpdated time.Time
 }
 
 // PaginatedResponse is a generic wrapper for paginated API responses
@@ -209,3 +1270,269 @@ type PaginatedResponse struct {
 	PageSize   int         `json:"page_size"`
 	TotalPages int         `json:"total_pages"`
 }
+
+// ListCursor is a position in a list ordered newest first by created_at,
+// then id. Keyset pages start after it instead of skipping an offset.
+type ListCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// CursorResponse is one keyset page. NextCursor resumes after the last row
+// and is empty on the final page.
+type CursorResponse struct {
+	Data       interface{} `json:"data"`
+	PageSize   int         `json:"page_size"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// AssessmentPageParams selects one keyset page of a patient's assessments,
+// newest first. After is nil for the first page. Once either quality bound
+// is set, unscored assessments are left out.
+type AssessmentPageParams struct {
+	After      *ListCursor
+	Limit      int
+	MinQuality *int
+	MaxQuality *int
+}
+
+// API token scopes
+const (
+	// ScopeAnalyticsRead allows read-only access to aggregate analytics
+	ScopeAnalyticsRead = "analytics:read"
+	// ScopeHL7Ingest allows lab interfaces to post HL7v2 results
+	ScopeHL7Ingest = "integrations:hl7"
+)
+
+// APIToken is a long-lived, narrowly scoped credential for non-interactive
+// clients such as embedded dashboards. Only a hash of the token is stored;
+// Prefix holds its first characters so admins can tell tokens apart.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  int64      `json:"created_by,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HasScope reports whether the token grants scope.
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// API key scopes. A key reads with read or write, changes data with write,
+// and needs export as well for bulk and whole-record exports.
+const (
+	APIKeyScopeRead   = "read"
+	APIKeyScopeWrite  = "write"
+	APIKeyScopeExport = "export"
+)
+
+// APIKey lets an integration script call the API as UserID without a
+// login. Like APIToken, only a hash of the key is stored.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	KeyHash    string     `json:"-"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  int64      `json:"created_by,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// MFAEnrollment is a user's authenticator app (TOTP) enrollment. EnabledAt
+// is nil until the user confirms a code from the app.
+type MFAEnrollment struct {
+	UserID    int64      `json:"user_id"`
+	Secret    string     `json:"-"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty"`
+	// LastStep is the last accepted TOTP time step
+	LastStep        int64     `json:"-"`
+	BackupCodesLeft int       `json:"backup_codes_left"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Baseline policies control what happens when a new assessment's smoking,
+// hypertension or heart disease value differs from the patient's baseline.
+const (
+	BaselinePolicyUpdate = "update"
+	BaselinePolicyFlag   = "flag"
+)
+
+// Baseline discrepancy resolutions
+const (
+	BaselineResolutionApplied   = "applied"
+	BaselineResolutionDismissed = "dismissed"
+)
+
+// BaselineDiscrepancy records an assessment value that disagreed with the
+// patient's baseline. It stays open until a clinician applies the assessment
+// value to the baseline or dismisses it.
+type BaselineDiscrepancy struct {
+	ID              int64      `json:"id"`
+	PatientID       int64      `json:"patient_id"`
+	AssessmentID    int64      `json:"assessment_id"`
+	Field           string     `json:"field"`
+	BaselineValue   string     `json:"baseline_value"`
+	AssessmentValue string     `json:"assessment_value"`
+	CreatedAt       time.Time  `json:"created_at"`
+	Resolution      string     `json:"resolution,omitempty"`
+	ResolvedBy      *int64     `json:"resolved_by,omitempty"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+}
+
+// PatientVersion is one update of a patient record. Before and After are full
+// snapshots keyed by column name; Changes lists only the fields that differ.
+type PatientVersion struct {
+	ID        int64                  `json:"id"`
+	PatientID int64                  `json:"patient_id"`
+	Version   int                    `json:"version"`
+	ChangedBy *int64                 `json:"changed_by,omitempty"`
+	ChangedAt time.Time              `json:"changed_at"`
+	Before    map[string]interface{} `json:"before"`
+	After     map[string]interface{} `json:"after"`
+	Changes   []PatientFieldChange   `json:"changes"`
+}
+
+// PatientFieldChange is one field's value before and after an update.
+type PatientFieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// PatientBundle is a patient's full record as one document, for referrals to
+// external specialists. Identifiers are left out when Redacted is set.
+type PatientBundle struct {
+	GeneratedAt           time.Time              `json:"generated_at"`
+	Redacted              bool                   `json:"redacted"`
+	Patient               Patient                `json:"patient"`
+	Photo                 *PatientPhoto          `json:"photo,omitempty"`
+	Assessments           []Assessment           `json:"assessments"`
+	Attachments           []AssessmentAttachment `json:"attachments"`
+	BaselineDiscrepancies []BaselineDiscrepancy  `json:"baseline_discrepancies"`
+	History               []PatientVersion       `json:"history"`
+	Notes                 []PatientNote          `json:"notes"`
+	Medications           []PatientMedication    `json:"medications"`
+	FollowUps             []FollowUp             `json:"follow_ups"`
+	LastRiskAlert         *RiskAlert             `json:"last_risk_alert,omitempty"`
+	Audit                 PatientBundleAudit     `json:"audit"`
+}
+
+// PatientBundleAudit summarizes the audit events recorded against a patient.
+type PatientBundleAudit struct {
+	Total  int          `json:"total"`
+	Recent []AuditEvent `json:"recent"`
+}
+
+// PatientExport is a patient's complete record in machine-readable form, for
+// data-portability requests. Unlike PatientBundle it carries the whole
+// history and audit trail.
+type PatientExport struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Patient     Patient          `json:"patient"`
+	Consent     PatientConsent   `json:"consent"`
+	Assessments []Assessment     `json:"assessments"`
+	History     []PatientVersion `json:"history"`
+	Audit       []AuditEvent     `json:"audit"`
+}
+
+// PatientConsent is the patient's current consent to be contacted directly
+// and the channels it covers.
+type PatientConsent struct {
+	ContactConsent bool       `json:"contact_consent"`
+	ConsentAt      *time.Time `json:"consent_at,omitempty"`
+	Channels       []string   `json:"channels"`
+}
+
+// UserExport is everything stored about a user's own account: the profile,
+// clinic memberships, the patients they own, what they did (Activity) and
+// what was done to their account (AccountEvents).
+type UserExport struct {
+	GeneratedAt   time.Time    `json:"generated_at"`
+	User          User         `json:"user"`
+	Clinics       []UserClinic `json:"clinics"`
+	Patients      []Patient    `json:"patients"`
+	Activity      []AuditEvent `json:"activity"`
+	AccountEvents []AuditEvent `json:"account_events"`
+}
+
+// ExperimentExposure records that an assessment was shown one variant of an
+// experiment. Unit is what the variant was assigned to, e.g. "clinic:3".
+type ExperimentExposure struct {
+	ID           int64     `json:"id"`
+	Experiment   string    `json:"experiment"`
+	Variant      string    `json:"variant"`
+	Unit         string    `json:"unit"`
+	UserID       int64     `json:"user_id"`
+	PatientID    int64     `json:"patient_id"`
+	AssessmentID int64     `json:"assessment_id"`
+	ExposedAt    time.Time `json:"exposed_at"`
+}
+
+// ExperimentVariantResult is the outcome of one variant: of the exposed
+// assessments old enough to judge, how many were followed by another
+// assessment of the same patient within the follow-up window.
+type ExperimentVariantResult struct {
+	Variant    string  `json:"variant"`
+	Exposures  int     `json:"exposures"`
+	Units      int     `json:"units"`
+	Eligible   int     `json:"eligible"`
+	FollowedUp int     `json:"followed_up"`
+	Rate       float64 `json:"rate"`
+}
+
+// User deletion statuses
+const (
+	DeletionPending   = "pending"
+	DeletionCancelled = "cancelled"
+	DeletionCompleted = "completed"
+)
+
+// Retention modes decide what happens to a deactivated user's data once the
+// grace period ends. Anonymize keeps clinical values and strips identifiers;
+// delete removes the user and every patient they own.
+const (
+	RetentionAnonymize = "anonymize"
+	RetentionDelete    = "delete"
+)
+
+// UserDeletion schedules a deactivated user's data for removal at DueAt.
+// Mode and Patients are recorded when it completes. Email is the user's
+// current address, only loaded while the user still exists.
+type UserDeletion struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	Email       string     `json:"email,omitempty"`
+	RequestedBy string     `json:"requested_by"`
+	RequestedAt time.Time  `json:"requested_at"`
+	DueAt       time.Time  `json:"due_at"`
+	Status      string     `json:"status"`
+	Mode        string     `json:"mode,omitempty"`
+	Patients    *int       `json:"patients,omitempty"`
+	ResolvedBy  string     `json:"resolved_by,omitempty"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
+
+// UserPurge is what purging a user removed. PhotoKeys and AttachmentKeys
+// are the object storage keys of the patient photos and assessment
+// attachments whose rows were deleted.
+type UserPurge struct {
+	Patients       int
+	PhotoKeys      []string
+	AttachmentKeys []string
+}
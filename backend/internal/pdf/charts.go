@@ -0,0 +1,228 @@
+package pdf
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// Chart sizes on the assessment report, in mm. Two charts share a row.
+const (
+	chartWidth  = 87.0
+	chartHeight = 62.0
+	chartGap    = 6.0
+)
+
+// plot maps data coordinates onto a rectangle of the page
+type plot struct {
+	x, y, w, h             float64
+	minX, maxX, minY, maxY float64
+}
+
+func (p plot) px(v float64) float64 {
+	if p.maxX == p.minX {
+		return p.x + p.w/2
+	}
+	return p.x + (v-p.minX)/(p.maxX-p.minX)*p.w
+}
+
+func (p plot) py(v float64) float64 {
+	return p.y + p.h - (v-p.minY)/(p.maxY-p.minY)*p.h
+}
+
+// axes draws the frame and y-axis tick labels
+func (p plot) axes(pdf *fpdf.Fpdf, yTicks []float64, format string) {
+	pdf.SetDrawColor(220, 220, 220)
+	pdf.SetLineWidth(0.1)
+	pdf.SetFont("Arial", "", 7)
+	pdf.SetTextColor(128, 128, 128)
+	for _, t := range yTicks {
+		y := p.py(t)
+		pdf.Line(p.x, y, p.x+p.w, y)
+		pdf.SetXY(p.x-11, y-1.5)
+		pdf.CellFormat(10, 3, fmt.Sprintf(format, t), "", 0, "R", false, 0, "")
+	}
+	pdf.SetDrawColor(128, 128, 128)
+	pdf.SetLineWidth(0.2)
+	pdf.Line(p.x, p.y, p.x, p.y+p.h)
+	pdf.Line(p.x, p.y+p.h, p.x+p.w, p.y+p.h)
+}
+
+// reference draws a dashed threshold line with a label; vertical lines sit
+// at an x value, horizontal ones at a y value. Lines outside the plot are
+// skipped.
+func (p plot) reference(pdf *fpdf.Fpdf, v float64, vertical bool, label string) {
+	pdf.SetDrawColor(239, 68, 68)
+	pdf.SetTextColor(239, 68, 68)
+	pdf.SetFont("Arial", "", 6)
+	pdf.SetDashPattern([]float64{1, 1}, 0)
+	if vertical && v > p.minX && v < p.maxX {
+		x := p.px(v)
+		pdf.Line(x, p.y, x, p.y+p.h)
+		pdf.SetXY(x+0.5, p.y)
+		pdf.CellFormat(12, 3, label, "", 0, "L", false, 0, "")
+	} else if !vertical && v > p.minY && v < p.maxY {
+		y := p.py(v)
+		pdf.Line(p.x, y, p.x+p.w, y)
+		pdf.SetXY(p.x+p.w-12, y-3)
+		pdf.CellFormat(12, 3, label, "", 0, "R", false, 0, "")
+	}
+	pdf.SetDashPattern([]float64{}, 0)
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// addCharts draws the risk score history and the HbA1c against FBS scatter
+// side by side. Only visits up to the reported assessment are plotted, so a
+// report on an older assessment looks as it did at the time.
+func (g *ReportGenerator) addCharts(pdf *fpdf.Fpdf, assessment models.Assessment, history []models.AssessmentTrend) {
+	var points []models.AssessmentTrend
+	for _, t := range history {
+		if !t.CreatedAt.After(assessment.CreatedAt) && t.ID != assessment.ID {
+			points = append(points, t)
+		}
+	}
+	// The reported assessment is always the last point, as stored
+	current := models.AssessmentTrend{ID: assessment.ID, CreatedAt: assessment.CreatedAt, HbA1c: assessment.HbA1c, FBS: assessment.FBS}
+	if assessment.RiskScore > 0 {
+		rs := float64(assessment.RiskScore) / 100
+		current.RiskScore = &rs
+	}
+	points = append(points, current)
+
+	// Keep the heading with the charts
+	if pdf.GetY()+12+chartHeight > contentBottom {
+		pdf.AddPage()
+	}
+	pdf.SetFont("Arial", "B", 14)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(180, 8, g.t("Results in Context"), "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	top := pdf.GetY()
+	g.addRiskChart(pdf, points, 15, top)
+	g.addGlucoseScatter(pdf, points, 15+chartWidth+chartGap, top)
+	pdf.SetY(top + chartHeight)
+	pdf.Ln(8)
+}
+
+// chartFrame draws the chart's border and title and returns the plot area
+// inside it, leaving room for tick labels on the left and below.
+func chartFrame(pdf *fpdf.Fpdf, title string, x, y float64) (px, py, pw, ph float64) {
+	pdf.SetDrawColor(200, 200, 200)
+	pdf.SetLineWidth(0.2)
+	pdf.Rect(x, y, chartWidth, chartHeight, "D")
+	pdf.SetXY(x+2, y+1)
+	pdf.SetFont("Arial", "B", 9)
+	pdf.SetTextColor(64, 64, 64)
+	pdf.CellFormat(chartWidth-4, 5, title, "", 0, "L", false, 0, "")
+	return x + 13, y + 9, chartWidth - 17, chartHeight - 19
+}
+
+// chartMessage writes a note in place of a chart's data
+func chartMessage(pdf *fpdf.Fpdf, x, y, w, h float64, msg string) {
+	pdf.SetXY(x, y+h/2-4)
+	pdf.SetFont("Arial", "I", 8)
+	pdf.SetTextColor(128, 128, 128)
+	pdf.MultiCell(w, 4, msg, "", "C", false)
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// addRiskChart plots the risk score of each visit against its date
+func (g *ReportGenerator) addRiskChart(pdf *fpdf.Fpdf, points []models.AssessmentTrend, x, y float64) {
+	px, py, pw, ph := chartFrame(pdf, g.t("Risk Score Over Time"), x, y)
+
+	var dates []time.Time
+	var scores []float64
+	for _, t := range points {
+		if t.RiskScore != nil {
+			dates = append(dates, t.CreatedAt)
+			scores = append(scores, *t.RiskScore*100)
+		}
+	}
+	if len(scores) < 2 {
+		chartMessage(pdf, px, py, pw, ph, g.t("The risk score history appears once the patient has more than one assessment."))
+		return
+	}
+
+	p := plot{x: px, y: py, w: pw, h: ph,
+		minX: float64(dates[0].Unix()), maxX: float64(dates[len(dates)-1].Unix()), minY: 0, maxY: 100}
+	p.axes(pdf, []float64{0, 25, 50, 75, 100}, "%.0f%%")
+
+	pdf.SetFont("Arial", "", 7)
+	pdf.SetTextColor(128, 128, 128)
+	pdf.SetXY(px-10, py+ph+1)
+	pdf.CellFormat(20, 3, g.shortDate(dates[0]), "", 0, "C", false, 0, "")
+	pdf.SetXY(px+pw-10, py+ph+1)
+	pdf.CellFormat(20, 3, g.shortDate(dates[len(dates)-1]), "", 0, "C", false, 0, "")
+
+	pdf.SetDrawColor(75, 0, 130)
+	pdf.SetFillColor(75, 0, 130)
+	pdf.SetLineWidth(0.4)
+	for i := range scores {
+		cx, cy := p.px(float64(dates[i].Unix())), p.py(scores[i])
+		if i > 0 {
+			pdf.Line(p.px(float64(dates[i-1].Unix())), p.py(scores[i-1]), cx, cy)
+		}
+		r := 0.7
+		if i == len(scores)-1 {
+			r = 1.3 // this assessment
+		}
+		pdf.Circle(cx, cy, r, "F")
+	}
+	pdf.SetLineWidth(0.2)
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// addGlucoseScatter plots HbA1c against FBS for every visit, with the
+// prediabetes and diabetes thresholds of both, so a patient can see which
+// zone each result falls in. This assessment is drawn larger.
+func (g *ReportGenerator) addGlucoseScatter(pdf *fpdf.Fpdf, points []models.AssessmentTrend, x, y float64) {
+	px, py, pw, ph := chartFrame(pdf, g.t("HbA1c vs. Fasting Blood Sugar"), x, y)
+
+	var recorded []models.AssessmentTrend
+	for _, t := range points {
+		if t.HbA1c > 0 && t.FBS > 0 {
+			recorded = append(recorded, t)
+		}
+	}
+	if len(recorded) == 0 {
+		chartMessage(pdf, px, py, pw, ph, g.t("HbA1c and fasting blood sugar were not both recorded."))
+		return
+	}
+
+	// Always show both thresholds of each biomarker
+	fbs := func(v float64) float64 { return g.express("fbs", v) }
+	p := plot{x: px, y: py, w: pw, h: ph, minX: fbs(70), maxX: fbs(150), minY: 4.5, maxY: 8}
+	for _, t := range recorded {
+		p.minX, p.maxX = min(p.minX, fbs(t.FBS-10)), max(p.maxX, fbs(t.FBS+10))
+		p.minY, p.maxY = min(p.minY, t.HbA1c-0.5), max(p.maxY, t.HbA1c+0.5)
+	}
+	var ticks []float64
+	for t := math.Ceil(p.minY); t <= p.maxY; t++ {
+		ticks = append(ticks, t)
+	}
+	p.axes(pdf, ticks, "%.0f%%")
+	p.reference(pdf, fbs(100), true, g.value("fbs", 100))
+	p.reference(pdf, fbs(126), true, g.value("fbs", 126))
+	p.reference(pdf, 5.7, false, "5.7%")
+	p.reference(pdf, 6.5, false, "6.5%")
+
+	pdf.SetFont("Arial", "", 7)
+	pdf.SetTextColor(128, 128, 128)
+	pdf.SetXY(px, py+ph+1)
+	pdf.CellFormat(pw, 3, fmt.Sprintf(g.t("Fasting blood sugar (%s)"), g.unit("fbs")), "", 0, "C", false, 0, "")
+
+	for i, t := range recorded {
+		r := 0.8
+		pdf.SetFillColor(160, 160, 160)
+		if i == len(recorded)-1 && t.ID == points[len(points)-1].ID {
+			r = 1.4 // this assessment
+			pdf.SetFillColor(75, 0, 130)
+		}
+		pdf.Circle(p.px(fbs(t.FBS)), p.py(t.HbA1c), r, "F")
+	}
+	pdf.SetTextColor(0, 0, 0)
+}
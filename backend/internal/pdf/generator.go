@@ -1,37 +1,101 @@
-// Package pdf provides PDF report generation for patient assessments.
+// Package pdf provides PDF report generation for patient assessments and
+// patient histories.
 package pdf
 
 import (
 	"bytes"
 	"fmt"
+	"net/http"
+	"os"
 	"time"
 
 	"github.com/go-pdf/fpdf"
 	"github.com/skufu/DianaV2/backend/internal/models"
 )
 
+// Recommendation wordings. RecommendationsStandard is the original advice;
+// RecommendationsAction says the same thing as concrete steps with a time
+// frame, and is being trialled against it.
+const (
+	RecommendationsStandard = "standard"
+	RecommendationsAction   = "action"
+)
+
+// disclaimer is printed in the footer of every report
+const disclaimer = "This report is generated by the DIANA diabetes risk assessment system and is intended for clinical reference only. " +
+	"It should not replace professional medical judgment. Please consult with a healthcare provider for diagnosis and treatment decisions."
+
+// RecommendationVariants lists the wordings, standard first.
+var RecommendationVariants = []string{RecommendationsStandard, RecommendationsAction}
+
 // ReportGenerator generates PDF reports for patient assessments
 type ReportGenerator struct {
-	logoPath string
+	logoPath        string
+	recommendations string
+	history         []models.AssessmentTrend
+	locale          string
+	units           string
+	dateFormat      string
+	branding        Branding
 }
 
-// NewReportGenerator creates a new PDF report generator
+// Branding identifies the clinic a report comes from. Logo is a JPEG or PNG
+// and replaces the generator's logoPath; every field is optional.
+type Branding struct {
+	ClinicName    string
+	ClinicAddress string
+	Logo          []byte
+}
+
+// NewReportGenerator creates a new PDF report generator. logoPath, when set,
+// is an image file drawn in the header of reports without a clinic logo.
 func NewReportGenerator(logoPath string) *ReportGenerator {
-	return &ReportGenerator{logoPath: logoPath}
+	return &ReportGenerator{logoPath: logoPath, recommendations: RecommendationsStandard, locale: LocaleEnglish}
+}
+
+// WithBranding puts the clinic's logo, name and address in the header
+func (g *ReportGenerator) WithBranding(b Branding) *ReportGenerator {
+	g.branding = b
+	return g
+}
+
+// WithLocale selects the language of headings and dates; unknown locales
+// fall back to English.
+func (g *ReportGenerator) WithLocale(locale string) *ReportGenerator {
+	g.locale = locale
+	return g
+}
+
+// WithRecommendations selects the recommendation wording; unknown values
+// fall back to the standard wording.
+func (g *ReportGenerator) WithRecommendations(variant string) *ReportGenerator {
+	g.recommendations = variant
+	return g
+}
+
+// WithHistory supplies the patient's assessment history, as GetTrend
+// returns it, for the charts on assessment reports. Without it the charts
+// show only the reported assessment.
+func (g *ReportGenerator) WithHistory(trend []models.AssessmentTrend) *ReportGenerator {
+	g.history = trend
+	return g
 }
 
-// GenerateAssessmentReport creates a PDF report for a patient assessment
+// GenerateAssessmentReport creates a PDF report for a patient assessment.
+// The cluster, score and shapData must all come from the model version
+// stored on the assessment; when shapData is nil, shapNote says why.
 func (g *ReportGenerator) GenerateAssessmentReport(
 	patient models.Patient,
 	assessment models.Assessment,
 	shapData map[string]interface{},
+	shapNote string,
 ) ([]byte, error) {
 	pdf := fpdf.New("P", "mm", "A4", "")
 	pdf.SetMargins(15, 15, 15)
 	pdf.AddPage()
 
 	// Header
-	g.addHeader(pdf, patient)
+	g.addHeader(pdf, g.t("DIANA Assessment Report"))
 
 	// Patient Information Section
 	g.addPatientInfo(pdf, patient)
@@ -42,9 +106,14 @@ func (g *ReportGenerator) GenerateAssessmentReport(
 	// Risk Assessment Section
 	g.addRiskAssessment(pdf, assessment)
 
+	// Risk history and HbA1c/FBS charts
+	g.addCharts(pdf, assessment, g.history)
+
 	// SHAP Explanation Section (if available)
 	if shapData != nil {
 		g.addSHAPExplanation(pdf, shapData)
+	} else if shapNote != "" {
+		g.addSHAPNote(pdf, shapNote)
 	}
 
 	// Recommendations Section
@@ -63,14 +132,16 @@ func (g *ReportGenerator) GenerateAssessmentReport(
 	return buf.Bytes(), nil
 }
 
-func (g *ReportGenerator) addHeader(pdf *fpdf.Fpdf, patient models.Patient) {
+func (g *ReportGenerator) addHeader(pdf *fpdf.Fpdf, title string) {
+	g.addBranding(pdf)
+
 	pdf.SetFont("Arial", "B", 20)
 	pdf.SetTextColor(75, 0, 130) // Indigo color
 
-	pdf.CellFormat(180, 12, "DIANA Assessment Report", "", 1, "C", false, 0, "")
+	pdf.CellFormat(180, 12, title, "", 1, "C", false, 0, "")
 	pdf.SetFont("Arial", "", 10)
 	pdf.SetTextColor(128, 128, 128)
-	pdf.CellFormat(180, 6, "Diabetes Risk Assessment for Menopausal Women", "", 1, "C", false, 0, "")
+	pdf.CellFormat(180, 6, g.t("Diabetes Risk Assessment for Menopausal Women"), "", 1, "C", false, 0, "")
 
 	pdf.Ln(5)
 	pdf.SetDrawColor(75, 0, 130)
@@ -78,10 +149,61 @@ func (g *ReportGenerator) addHeader(pdf *fpdf.Fpdf, patient models.Patient) {
 	pdf.Ln(8)
 }
 
+// addBranding draws the clinic logo at the left and the clinic name and
+// address at the right, above the title. A logo that cannot be read or
+// decoded is left out rather than failing the report.
+func (g *ReportGenerator) addBranding(pdf *fpdf.Fpdf) {
+	bottom := pdf.GetY()
+	logo := g.branding.Logo
+	if logo == nil && g.logoPath != "" {
+		logo, _ = os.ReadFile(g.logoPath)
+	}
+	if imageType := logoImageType(logo); imageType != "" {
+		opts := fpdf.ImageOptions{ImageType: imageType}
+		pdf.RegisterImageOptionsReader("logo", opts, bytes.NewReader(logo))
+		if pdf.Err() {
+			pdf.ClearError()
+		} else {
+			const logoHeight = 16.0
+			pdf.ImageOptions("logo", 15, pdf.GetY(), 0, logoHeight, false, opts, 0, "")
+			bottom = pdf.GetY() + logoHeight
+		}
+	}
+
+	if g.branding.ClinicName != "" || g.branding.ClinicAddress != "" {
+		pdf.SetXY(105, pdf.GetY())
+		pdf.SetTextColor(64, 64, 64)
+		if g.branding.ClinicName != "" {
+			pdf.SetFont("Arial", "B", 11)
+			pdf.CellFormat(90, 6, g.branding.ClinicName, "", 2, "R", false, 0, "")
+		}
+		if g.branding.ClinicAddress != "" {
+			pdf.SetFont("Arial", "", 8)
+			pdf.MultiCell(90, 4, g.branding.ClinicAddress, "", "R", false)
+		}
+		bottom = max(bottom, pdf.GetY())
+	}
+	pdf.SetXY(15, bottom)
+	if bottom > 15 {
+		pdf.Ln(4)
+	}
+}
+
+// logoImageType returns the fpdf image type of a JPEG or PNG logo, or ""
+func logoImageType(data []byte) string {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return "JPG"
+	case "image/png":
+		return "PNG"
+	}
+	return ""
+}
+
 func (g *ReportGenerator) addPatientInfo(pdf *fpdf.Fpdf, patient models.Patient) {
 	pdf.SetFont("Arial", "B", 14)
 	pdf.SetTextColor(0, 0, 0)
-	pdf.CellFormat(180, 8, "Patient Information", "", 1, "L", false, 0, "")
+	pdf.CellFormat(180, 8, g.t("Patient Information"), "", 1, "L", false, 0, "")
 
 	pdf.SetFont("Arial", "", 10)
 	pdf.SetTextColor(64, 64, 64)
@@ -90,13 +212,13 @@ func (g *ReportGenerator) addPatientInfo(pdf *fpdf.Fpdf, patient models.Patient)
 	col1Width := 40.0
 	col2Width := 50.0
 
-	g.addInfoRow(pdf, "Name:", patient.Name, col1Width, col2Width)
-	g.addInfoRow(pdf, "Age:", fmt.Sprintf("%d years", patient.Age), col1Width, col2Width)
-	g.addInfoRow(pdf, "Menopause Status:", patient.MenopauseStatus, col1Width, col2Width)
+	g.addInfoRow(pdf, g.t("Name:"), patient.Name, col1Width, col2Width)
+	g.addInfoRow(pdf, g.t("Age:"), fmt.Sprintf(g.t("%d years"), patient.Age), col1Width, col2Width)
+	g.addInfoRow(pdf, g.t("Menopause Status:"), patient.MenopauseStatus, col1Width, col2Width)
 	if patient.YearsMenopause > 0 {
-		g.addInfoRow(pdf, "Years Menopause:", fmt.Sprintf("%d", patient.YearsMenopause), col1Width, col2Width)
+		g.addInfoRow(pdf, g.t("Years Menopause:"), fmt.Sprintf("%d", patient.YearsMenopause), col1Width, col2Width)
 	}
-	g.addInfoRow(pdf, "Report Date:", time.Now().Format("January 2, 2006"), col1Width, col2Width)
+	g.addInfoRow(pdf, g.t("Report Date:"), g.longDate(time.Now()), col1Width, col2Width)
 
 	pdf.Ln(8)
 }
@@ -111,28 +233,28 @@ func (g *ReportGenerator) addInfoRow(pdf *fpdf.Fpdf, label, value string, labelW
 func (g *ReportGenerator) addBiomarkerSection(pdf *fpdf.Fpdf, assessment models.Assessment) {
 	pdf.SetFont("Arial", "B", 14)
 	pdf.SetTextColor(0, 0, 0)
-	pdf.CellFormat(180, 8, "Biomarker Values", "", 1, "L", false, 0, "")
+	pdf.CellFormat(180, 8, g.t("Biomarker Values"), "", 1, "L", false, 0, "")
 
 	// Table header
 	pdf.SetFillColor(75, 0, 130)
 	pdf.SetTextColor(255, 255, 255)
 	pdf.SetFont("Arial", "B", 10)
-	pdf.CellFormat(60, 8, "Biomarker", "1", 0, "C", true, 0, "")
-	pdf.CellFormat(40, 8, "Value", "1", 0, "C", true, 0, "")
-	pdf.CellFormat(40, 8, "Normal Range", "1", 0, "C", true, 0, "")
-	pdf.CellFormat(40, 8, "Status", "1", 1, "C", true, 0, "")
+	pdf.CellFormat(60, 8, g.t("Biomarker"), "1", 0, "C", true, 0, "")
+	pdf.CellFormat(40, 8, g.t("Value"), "1", 0, "C", true, 0, "")
+	pdf.CellFormat(40, 8, g.t("Normal Range"), "1", 0, "C", true, 0, "")
+	pdf.CellFormat(40, 8, g.t("Status"), "1", 1, "C", true, 0, "")
 
 	pdf.SetTextColor(0, 0, 0)
 	pdf.SetFont("Arial", "", 10)
 
 	// Biomarker rows
 	g.addBiomarkerRow(pdf, "HbA1c (%)", fmt.Sprintf("%.1f", assessment.HbA1c), "< 5.7", g.getHbA1cStatus(assessment.HbA1c))
-	g.addBiomarkerRow(pdf, "Fasting Blood Sugar (mg/dL)", fmt.Sprintf("%.0f", assessment.FBS), "< 100", g.getFBSStatus(assessment.FBS))
+	g.addBiomarkerRow(pdf, "Fasting Blood Sugar ("+g.unit("fbs")+")", g.value("fbs", assessment.FBS), "< "+g.value("fbs", 100), g.getFBSStatus(assessment.FBS))
 	g.addBiomarkerRow(pdf, "BMI (kg/m²)", fmt.Sprintf("%.1f", assessment.BMI), "18.5 - 24.9", g.getBMIStatus(assessment.BMI))
-	g.addBiomarkerRow(pdf, "Total Cholesterol (mg/dL)", fmt.Sprintf("%d", assessment.Cholesterol), "< 200", g.getCholStatus(assessment.Cholesterol))
-	g.addBiomarkerRow(pdf, "LDL (mg/dL)", fmt.Sprintf("%d", assessment.LDL), "< 100", g.getLDLStatus(assessment.LDL))
-	g.addBiomarkerRow(pdf, "HDL (mg/dL)", fmt.Sprintf("%d", assessment.HDL), "> 50", g.getHDLStatus(assessment.HDL))
-	g.addBiomarkerRow(pdf, "Triglycerides (mg/dL)", fmt.Sprintf("%d", assessment.Triglycerides), "< 150", g.getTGStatus(assessment.Triglycerides))
+	g.addBiomarkerRow(pdf, "Total Cholesterol ("+g.unit("cholesterol")+")", g.value("cholesterol", float64(assessment.Cholesterol)), "< "+g.value("cholesterol", 200), g.getCholStatus(assessment.Cholesterol))
+	g.addBiomarkerRow(pdf, "LDL ("+g.unit("ldl")+")", g.value("ldl", float64(assessment.LDL)), "< "+g.value("ldl", 100), g.getLDLStatus(assessment.LDL))
+	g.addBiomarkerRow(pdf, "HDL ("+g.unit("hdl")+")", g.value("hdl", float64(assessment.HDL)), "> "+g.value("hdl", 50), g.getHDLStatus(assessment.HDL))
+	g.addBiomarkerRow(pdf, "Triglycerides ("+g.unit("triglycerides")+")", g.value("triglycerides", float64(assessment.Triglycerides)), "< "+g.value("triglycerides", 150), g.getTGStatus(assessment.Triglycerides))
 
 	if assessment.Systolic > 0 || assessment.Diastolic > 0 {
 		bp := fmt.Sprintf("%d/%d", assessment.Systolic, assessment.Diastolic)
@@ -151,6 +273,13 @@ func (g *ReportGenerator) addBiomarkerRow(pdf *fpdf.Fpdf, name, value, normalRan
 	pdf.CellFormat(40, 7, normalRange, "1", 0, "C", false, 0, "")
 
 	// Status with color coding
+	g.setStatusColor(pdf, status)
+	pdf.CellFormat(40, 7, status, "1", 1, "C", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// setStatusColor sets the text color used for a biomarker status
+func (g *ReportGenerator) setStatusColor(pdf *fpdf.Fpdf, status string) {
 	switch status {
 	case "Normal":
 		pdf.SetTextColor(34, 139, 34) // Green
@@ -163,15 +292,12 @@ func (g *ReportGenerator) addBiomarkerRow(pdf *fpdf.Fpdf, name, value, normalRan
 	default:
 		pdf.SetTextColor(0, 0, 0)
 	}
-
-	pdf.CellFormat(40, 7, status, "1", 1, "C", false, 0, "")
-	pdf.SetTextColor(0, 0, 0)
 }
 
 func (g *ReportGenerator) addRiskAssessment(pdf *fpdf.Fpdf, assessment models.Assessment) {
 	pdf.SetFont("Arial", "B", 14)
 	pdf.SetTextColor(0, 0, 0)
-	pdf.CellFormat(180, 8, "Risk Assessment", "", 1, "L", false, 0, "")
+	pdf.CellFormat(180, 8, g.t("Risk Assessment"), "", 1, "L", false, 0, "")
 
 	// Risk cluster box
 	pdf.SetFont("Arial", "B", 12)
@@ -189,13 +315,38 @@ func (g *ReportGenerator) addRiskAssessment(pdf *fpdf.Fpdf, assessment models.As
 	}
 
 	pdf.SetTextColor(255, 255, 255)
-	pdf.CellFormat(90, 12, "Risk Cluster: "+assessment.Cluster, "1", 0, "C", true, 0, "")
+	pdf.CellFormat(90, 12, g.t("Risk Cluster: ")+assessment.Cluster, "1", 0, "C", true, 0, "")
 
 	// Risk score
 	pdf.SetFillColor(75, 0, 130)
-	riskScoreText := fmt.Sprintf("Risk Score: %d%%", assessment.RiskScore)
+	riskScoreText := fmt.Sprintf(g.t("Risk Score: %d%%"), assessment.RiskScore)
 	pdf.CellFormat(90, 12, riskScoreText, "1", 1, "C", true, 0, "")
 
+	pdf.SetTextColor(0, 0, 0)
+	if assessment.ModelVersion != "" {
+		model := "Model: " + assessment.ModelVersion
+		if assessment.DatasetHash != "" {
+			model += " (dataset " + assessment.DatasetHash + ")"
+		}
+		pdf.Ln(2)
+		pdf.SetFont("Arial", "", 9)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.CellFormat(180, 5, model, "", 1, "L", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+	pdf.Ln(8)
+}
+
+// addSHAPNote replaces the SHAP section when no explanation from the
+// assessment's model is available.
+func (g *ReportGenerator) addSHAPNote(pdf *fpdf.Fpdf, note string) {
+	pdf.SetFont("Arial", "B", 14)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(180, 8, g.t("AI Explanation (SHAP Analysis)"), "", 1, "L", false, 0, "")
+
+	pdf.SetFont("Arial", "I", 10)
+	pdf.SetTextColor(128, 128, 128)
+	pdf.MultiCell(180, 5, note, "", "L", false)
 	pdf.SetTextColor(0, 0, 0)
 	pdf.Ln(8)
 }
@@ -203,7 +354,7 @@ func (g *ReportGenerator) addRiskAssessment(pdf *fpdf.Fpdf, assessment models.As
 func (g *ReportGenerator) addSHAPExplanation(pdf *fpdf.Fpdf, shapData map[string]interface{}) {
 	pdf.SetFont("Arial", "B", 14)
 	pdf.SetTextColor(0, 0, 0)
-	pdf.CellFormat(180, 8, "AI Explanation (SHAP Analysis)", "", 1, "L", false, 0, "")
+	pdf.CellFormat(180, 8, g.t("AI Explanation (SHAP Analysis)"), "", 1, "L", false, 0, "")
 
 	pdf.SetFont("Arial", "", 10)
 	pdf.SetTextColor(64, 64, 64)
@@ -256,7 +407,7 @@ func (g *ReportGenerator) addSHAPExplanation(pdf *fpdf.Fpdf, shapData map[string
 func (g *ReportGenerator) addRecommendations(pdf *fpdf.Fpdf, assessment models.Assessment) {
 	pdf.SetFont("Arial", "B", 14)
 	pdf.SetTextColor(0, 0, 0)
-	pdf.CellFormat(180, 8, "Recommendations", "", 1, "L", false, 0, "")
+	pdf.CellFormat(180, 8, g.t("Recommendations"), "", 1, "L", false, 0, "")
 
 	pdf.SetFont("Arial", "", 10)
 	pdf.SetTextColor(64, 64, 64)
@@ -279,12 +430,10 @@ func (g *ReportGenerator) addFooter(pdf *fpdf.Fpdf) {
 	pdf.Line(15, pdf.GetY(), 195, pdf.GetY())
 	pdf.Ln(3)
 
-	disclaimer := "This report is generated by the DIANA diabetes risk assessment system and is intended for clinical reference only. " +
-		"It should not replace professional medical judgment. Please consult with a healthcare provider for diagnosis and treatment decisions."
-	pdf.MultiCell(180, 4, disclaimer, "", "C", false)
+	pdf.MultiCell(180, 4, g.t(disclaimer), "", "C", false)
 
 	pdf.Ln(2)
-	pdf.CellFormat(180, 4, fmt.Sprintf("Generated on %s | DIANA V2", time.Now().Format("2006-01-02 15:04")), "", 0, "C", false, 0, "")
+	pdf.CellFormat(180, 4, fmt.Sprintf(g.t("Generated on %s | DIANA V2"), time.Now().Format("2006-01-02 15:04")), "", 0, "C", false, 0, "")
 }
 
 // Status helper functions
@@ -362,41 +511,73 @@ func (g *ReportGenerator) getBPStatus(systolic, diastolic int) string {
 	return "Normal"
 }
 
+// recommendation is one piece of advice in each wording
+type recommendation struct {
+	standard, action string
+}
+
 func (g *ReportGenerator) getRecommendations(assessment models.Assessment) []string {
-	var recs []string
+	var recs []recommendation
 
 	// Based on HbA1c
 	if assessment.HbA1c >= 6.5 {
-		recs = append(recs, "Schedule follow-up with healthcare provider for diabetes management plan")
-		recs = append(recs, "Consider medication review and blood glucose monitoring")
+		recs = append(recs,
+			recommendation{"Schedule follow-up with healthcare provider for diabetes management plan",
+				"Book a visit with your healthcare provider within the next 4 weeks to agree a diabetes management plan"},
+			recommendation{"Consider medication review and blood glucose monitoring",
+				"Bring your current medicines to that visit and ask whether you should check your blood glucose at home"})
 	} else if assessment.HbA1c >= 5.7 {
-		recs = append(recs, "Implement lifestyle modifications to prevent diabetes progression")
-		recs = append(recs, "Monitor HbA1c every 3-6 months")
+		recs = append(recs,
+			recommendation{"Implement lifestyle modifications to prevent diabetes progression",
+				"Pick one change to start this week, such as a daily 20-minute walk or swapping sugary drinks for water"},
+			recommendation{"Monitor HbA1c every 3-6 months",
+				"Book your next HbA1c test for 3 months from today"})
 	}
 
 	// Based on BMI
 	if assessment.BMI >= 30 {
-		recs = append(recs, "Consult with nutritionist for weight management program")
-		recs = append(recs, "Aim for gradual weight loss of 5-10% of body weight")
+		recs = append(recs,
+			recommendation{"Consult with nutritionist for weight management program",
+				"Ask for a referral to a nutritionist at your next visit"},
+			recommendation{"Aim for gradual weight loss of 5-10% of body weight",
+				"Set a goal of losing 5% of your body weight over the next 6 months"})
 	} else if assessment.BMI >= 25 {
-		recs = append(recs, "Increase physical activity and adopt heart-healthy diet")
+		recs = append(recs,
+			recommendation{"Increase physical activity and adopt heart-healthy diet",
+				"Add 10 minutes of brisk walking to each day this week and fill half your plate with vegetables"})
 	}
 
 	// Based on lipids
 	if assessment.LDL >= 160 || assessment.Triglycerides >= 200 {
-		recs = append(recs, "Discuss lipid management with healthcare provider")
-		recs = append(recs, "Consider reducing saturated fats and increasing fiber intake")
+		recs = append(recs,
+			recommendation{"Discuss lipid management with healthcare provider",
+				"Ask your healthcare provider about your cholesterol results within the next 4 weeks"},
+			recommendation{"Consider reducing saturated fats and increasing fiber intake",
+				"Replace fried and fatty foods with whole grains, beans or vegetables at one meal every day"})
 	}
 
 	// Based on blood pressure
 	if assessment.Systolic >= 140 || assessment.Diastolic >= 90 {
-		recs = append(recs, "Monitor blood pressure regularly")
-		recs = append(recs, "Reduce sodium intake and manage stress")
+		recs = append(recs,
+			recommendation{"Monitor blood pressure regularly",
+				"Check your blood pressure twice a week and write the readings down"},
+			recommendation{"Reduce sodium intake and manage stress",
+				"Stop adding salt at the table and take 10 minutes each day to relax"})
 	}
 
 	// General recommendations
-	recs = append(recs, "Maintain regular physical activity (150+ minutes per week)")
-	recs = append(recs, "Schedule annual comprehensive health check-ups")
-
-	return recs
+	recs = append(recs,
+		recommendation{"Maintain regular physical activity (150+ minutes per week)",
+			"Plan 30 minutes of activity on 5 days each week"},
+		recommendation{"Schedule annual comprehensive health check-ups",
+			"Put a reminder in your calendar for a full check-up 12 months from today"})
+
+	out := make([]string, len(recs))
+	for i, r := range recs {
+		out[i] = r.standard
+		if g.recommendations == RecommendationsAction {
+			out[i] = r.action
+		}
+	}
+	return out
 }
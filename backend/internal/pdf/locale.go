@@ -0,0 +1,117 @@
+package pdf
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// Report locales. English is the default and the fallback for any text a
+// locale does not translate.
+const (
+	LocaleEnglish  = "en"
+	LocaleFilipino = "fil"
+)
+
+// Locales lists the supported report locales, English first.
+var Locales = []string{LocaleEnglish, LocaleFilipino}
+
+// IsLocale reports whether locale is a supported report locale
+func IsLocale(locale string) bool {
+	for _, l := range Locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// translations maps English report text to each other locale. Headings,
+// labels and fixed notes are translated; biomarker names, statuses and
+// recommendations stay in English, as clinicians chart them.
+var translations = map[string]map[string]string{
+	LocaleFilipino: {
+		"DIANA Assessment Report":                       "Ulat ng Pagsusuri ng DIANA",
+		"DIANA Patient Summary":                         "Buod ng Pasyente ng DIANA",
+		"Diabetes Risk Assessment for Menopausal Women": "Pagsusuri ng Panganib sa Diabetes para sa mga Babaeng Nasa Menopause",
+		"Patient Information":                           "Impormasyon ng Pasyente",
+		"Name:":                                         "Pangalan:",
+		"Age:":                                          "Edad:",
+		"%d years":                                      "%d taong gulang",
+		"Menopause Status:":                             "Katayuan ng Menopause:",
+		"Years Menopause:":                              "Taon ng Menopause:",
+		"Report Date:":                                  "Petsa ng Ulat:",
+		"Biomarker Values":                              "Mga Halaga ng Biomarker",
+		"Biomarker":                                     "Biomarker",
+		"Value":                                         "Halaga",
+		"Normal Range":                                  "Normal na Saklaw",
+		"Status":                                        "Katayuan",
+		"Risk Assessment":                               "Pagtatasa ng Panganib",
+		"Risk Cluster: ":                                "Cluster ng Panganib: ",
+		"Risk Score: %d%%":                              "Iskor ng Panganib: %d%%",
+		"AI Explanation (SHAP Analysis)":                "Paliwanag ng AI (SHAP Analysis)",
+		"Recommendations":                               "Mga Rekomendasyon",
+		"Risk Score (%)":                                "Iskor ng Panganib (%)",
+		"Results in Context":                            "Mga Resulta sa Konteksto",
+		"Risk Score Over Time":                          "Iskor ng Panganib sa Paglipas ng Panahon",
+		"HbA1c vs. Fasting Blood Sugar":                 "HbA1c laban sa Fasting Blood Sugar",
+		"Fasting blood sugar (%s)":                      "Fasting blood sugar (%s)",
+		"Assessment History":                            "Kasaysayan ng mga Pagsusuri",
+		"No assessments recorded.":                      "Walang naitalang pagsusuri.",
+		"%d assessments from %s to %s":                  "%d pagsusuri mula %s hanggang %s",
+		"Trends":                                        "Mga Trend",
+		"Not enough data":                               "Kulang ang datos",
+		"Clinical Notes":                                "Mga Klinikal na Tala",
+		"Pinned":                                        "Naka-pin",
+		"General":                                       "Pangkalahatan",
+		"Medication":                                    "Gamot",
+		"Lifestyle":                                     "Pamumuhay",
+		"Care plan":                                     "Plano ng pangangalaga",
+		"Date":                                          "Petsa",
+		"Risk":                                          "Panganib",
+		"Generated on %s | DIANA V2":                    "Ginawa noong %s | DIANA V2",
+		"The risk score history appears once the patient has more than one assessment.": "Lalabas ang kasaysayan ng iskor ng panganib kapag may higit sa isang pagsusuri na ang pasyente.",
+		"HbA1c and fasting blood sugar were not both recorded.":                         "Hindi parehong naitala ang HbA1c at fasting blood sugar.",
+		disclaimer: "Ang ulat na ito ay ginawa ng DIANA diabetes risk assessment system at para lamang sa klinikal na sanggunian. " +
+			"Hindi nito pinapalitan ang propesyonal na paghuhusgang medikal. Kumonsulta sa isang healthcare provider para sa diagnosis at mga desisyon sa paggamot.",
+	},
+}
+
+// Month names for locales Go's time package does not format
+var monthNames = map[string][12]string{
+	LocaleFilipino: {"Enero", "Pebrero", "Marso", "Abril", "Mayo", "Hunyo", "Hulyo", "Agosto", "Setyembre", "Oktubre", "Nobyembre", "Disyembre"},
+}
+
+var shortMonthNames = map[string][12]string{
+	LocaleFilipino: {"Ene", "Peb", "Mar", "Abr", "May", "Hun", "Hul", "Ago", "Set", "Okt", "Nob", "Dis"},
+}
+
+// t translates s into the report locale, falling back to s
+func (g *ReportGenerator) t(s string) string {
+	if tr, ok := translations[g.locale][s]; ok {
+		return tr
+	}
+	return s
+}
+
+// longDate formats a date as "January 2, 2006" in the report locale, or
+// numerically when the report has a numeric date format
+func (g *ReportGenerator) longDate(d time.Time) string {
+	switch g.dateFormat {
+	case models.DateFormatISO, models.DateFormatDMY, models.DateFormatMDY:
+		return g.numericDate(d)
+	}
+	if names, ok := monthNames[g.locale]; ok {
+		return fmt.Sprintf("%s %d, %d", names[d.Month()-1], d.Day(), d.Year())
+	}
+	return d.Format("January 2, 2006")
+}
+
+// shortDate formats a month as "Jan 2006" in the report locale
+func (g *ReportGenerator) shortDate(d time.Time) string {
+	if names, ok := shortMonthNames[g.locale]; ok {
+		return fmt.Sprintf("%s %d", names[d.Month()-1], d.Year())
+	}
+	return d.Format("Jan 2006")
+}
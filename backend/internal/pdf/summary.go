@@ -0,0 +1,321 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// historyColumns are the longitudinal table's headings and widths in mm
+var historyColumns = []struct {
+	title string
+	width float64
+}{
+	{"Date", 22}, {"HbA1c", 14}, {"FBS", 14}, {"BMI", 14}, {"Chol", 14}, {"LDL", 14},
+	{"HDL", 14}, {"TG", 14}, {"BP", 20}, {"Cluster", 26}, {"Risk", 14},
+}
+
+// contentBottom is the lowest y, in mm, that tables and charts may reach
+// before moving to a new page, leaving room for the footer.
+const contentBottom = 255
+
+// sparkline is one biomarker's values over time. Reference, when set, is
+// drawn as a dashed line if it falls within the plotted range.
+type sparkline struct {
+	title     string
+	format    string
+	reference float64
+	points    []sparkPoint
+}
+
+type sparkPoint struct {
+	at    float64 // Unix seconds
+	value float64
+}
+
+// noteCategoryTitles are the headings of each note category
+var noteCategoryTitles = map[string]string{
+	models.NoteGeneral:    "General",
+	models.NoteMedication: "Medication",
+	models.NoteLifestyle:  "Lifestyle",
+	models.NoteCarePlan:   "Care plan",
+}
+
+// GeneratePatientSummaryReport creates a PDF covering a patient's whole
+// assessment history: a table of every assessment, oldest first, sparkline
+// charts of HbA1c, FBS, BMI and risk score built from trend, and the
+// clinicians' notes in the order given.
+func (g *ReportGenerator) GeneratePatientSummaryReport(
+	patient models.Patient,
+	assessments []models.Assessment,
+	trend []models.AssessmentTrend,
+	notes []models.PatientNote,
+) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.AddPage()
+
+	g.addHeader(pdf, g.t("DIANA Patient Summary"))
+	g.addPatientInfo(pdf, patient)
+
+	sorted := append([]models.Assessment(nil), assessments...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreatedAt.Before(sorted[j].CreatedAt) })
+	g.addHistoryTable(pdf, sorted)
+
+	if len(trend) > 0 {
+		g.addSparklines(pdf, g.trendSparklines(trend))
+	}
+	if len(notes) > 0 {
+		g.addNotes(pdf, notes)
+	}
+
+	g.addFooter(pdf)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to generate PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (g *ReportGenerator) addHistoryTable(pdf *fpdf.Fpdf, assessments []models.Assessment) {
+	pdf.SetFont("Arial", "B", 14)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(180, 8, g.t("Assessment History"), "", 1, "L", false, 0, "")
+
+	pdf.SetFont("Arial", "", 10)
+	pdf.SetTextColor(64, 64, 64)
+	if len(assessments) == 0 {
+		pdf.CellFormat(180, 6, g.t("No assessments recorded."), "", 1, "L", false, 0, "")
+		pdf.Ln(8)
+		return
+	}
+	first, last := assessments[0].CreatedAt, assessments[len(assessments)-1].CreatedAt
+	pdf.CellFormat(180, 6, fmt.Sprintf(g.t("%d assessments from %s to %s"),
+		len(assessments), g.longDate(first), g.longDate(last)), "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	g.addHistoryHeader(pdf)
+	for _, a := range assessments {
+		if pdf.GetY()+6 > contentBottom {
+			pdf.AddPage()
+			g.addHistoryHeader(pdf)
+		}
+		g.addHistoryRow(pdf, a)
+	}
+	pdf.Ln(8)
+}
+
+func (g *ReportGenerator) addHistoryHeader(pdf *fpdf.Fpdf) {
+	pdf.SetFillColor(75, 0, 130)
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetFont("Arial", "B", 8)
+	for i, col := range historyColumns {
+		ln := 0
+		if i == len(historyColumns)-1 {
+			ln = 1
+		}
+		pdf.CellFormat(col.width, 7, g.t(col.title), "1", ln, "C", true, 0, "")
+	}
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Arial", "", 8)
+}
+
+func (g *ReportGenerator) addHistoryRow(pdf *fpdf.Fpdf, a models.Assessment) {
+	bp := "-"
+	if a.Systolic > 0 || a.Diastolic > 0 {
+		bp = fmt.Sprintf("%d/%d", a.Systolic, a.Diastolic)
+	}
+	cells := []struct {
+		value  string
+		status string
+	}{
+		{g.numericDate(a.CreatedAt), ""},
+		{optional(a.HbA1c > 0, "%.1f", a.HbA1c), g.getHbA1cStatus(a.HbA1c)},
+		{optional(a.FBS > 0, "%s", g.value("fbs", a.FBS)), g.getFBSStatus(a.FBS)},
+		{optional(a.BMI > 0, "%.1f", a.BMI), ""},
+		{optional(a.Cholesterol > 0, "%s", g.value("cholesterol", float64(a.Cholesterol))), ""},
+		{optional(a.LDL > 0, "%s", g.value("ldl", float64(a.LDL))), ""},
+		{optional(a.HDL > 0, "%s", g.value("hdl", float64(a.HDL))), ""},
+		{optional(a.Triglycerides > 0, "%s", g.value("triglycerides", float64(a.Triglycerides))), ""},
+		{bp, ""},
+		{a.Cluster, ""},
+		{optional(a.RiskScore > 0, "%d%%", a.RiskScore), ""},
+	}
+	for i, cell := range cells {
+		ln := 0
+		if i == len(cells)-1 {
+			ln = 1
+		}
+		// Only HbA1c and FBS are colored; other values are read against the
+		// single-assessment report's ranges
+		if cell.status != "" && cell.value != "-" {
+			g.setStatusColor(pdf, cell.status)
+		}
+		pdf.CellFormat(historyColumns[i].width, 6, cell.value, "1", ln, "C", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+}
+
+// addNotes lists each note under a line with its category, date and author
+func (g *ReportGenerator) addNotes(pdf *fpdf.Fpdf, notes []models.PatientNote) {
+	if pdf.GetY()+24 > contentBottom {
+		pdf.AddPage()
+	}
+	pdf.SetFont("Arial", "B", 14)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(180, 8, g.t("Clinical Notes"), "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	for _, n := range notes {
+		if pdf.GetY()+16 > contentBottom {
+			pdf.AddPage()
+		}
+		heading := g.t(noteCategoryTitles[n.Category]) + " | " + g.shortDate(n.CreatedAt)
+		if n.AuthorEmail != "" {
+			heading += " | " + n.AuthorEmail
+		}
+		if n.Pinned {
+			heading = g.t("Pinned") + " | " + heading
+		}
+		pdf.SetFont("Arial", "B", 9)
+		pdf.SetTextColor(75, 0, 130)
+		pdf.CellFormat(180, 5, heading, "", 1, "L", false, 0, "")
+		pdf.SetFont("Arial", "", 10)
+		pdf.SetTextColor(64, 64, 64)
+		pdf.MultiCell(180, 5, n.Body, "", "L", false)
+		pdf.Ln(3)
+	}
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(4)
+}
+
+// optional formats v, or returns "-" when the value was not recorded
+func optional(recorded bool, format string, v interface{}) string {
+	if !recorded {
+		return "-"
+	}
+	return fmt.Sprintf(format, v)
+}
+
+// trendSparklines builds the charts from the trend, skipping values the
+// assessment did not record. Fasting blood sugar is in the report's units.
+func (g *ReportGenerator) trendSparklines(trend []models.AssessmentTrend) []sparkline {
+	fbsFormat := "%.0f"
+	if g.unit("fbs") == models.UnitsMmolL {
+		fbsFormat = "%.1f"
+	}
+	lines := []sparkline{
+		{title: "HbA1c (%)", format: "%.1f", reference: 6.5},
+		{title: "Fasting Blood Sugar (" + g.unit("fbs") + ")", format: fbsFormat, reference: g.express("fbs", 126)},
+		{title: "BMI (kg/m²)", format: "%.1f", reference: 30},
+		{title: "Risk Score (%)", format: "%.0f"},
+	}
+	for _, t := range trend {
+		at := float64(t.CreatedAt.Unix())
+		if t.HbA1c > 0 {
+			lines[0].points = append(lines[0].points, sparkPoint{at, t.HbA1c})
+		}
+		if t.FBS > 0 {
+			lines[1].points = append(lines[1].points, sparkPoint{at, g.express("fbs", t.FBS)})
+		}
+		if t.BMI > 0 {
+			lines[2].points = append(lines[2].points, sparkPoint{at, t.BMI})
+		}
+		if t.RiskScore != nil {
+			lines[3].points = append(lines[3].points, sparkPoint{at, *t.RiskScore * 100})
+		}
+	}
+	return lines
+}
+
+// addSparklines draws the charts two to a row
+func (g *ReportGenerator) addSparklines(pdf *fpdf.Fpdf, lines []sparkline) {
+	const w, h, gap = 87.0, 26.0, 6.0
+	rows := (len(lines) + 1) / 2
+	if pdf.GetY()+10+float64(rows)*(h+gap) > contentBottom {
+		pdf.AddPage()
+	}
+	pdf.SetFont("Arial", "B", 14)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(180, 8, g.t("Trends"), "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	top := pdf.GetY()
+	for i, line := range lines {
+		x := 15 + float64(i%2)*(w+gap)
+		y := top + float64(i/2)*(h+gap)
+		g.addSparkline(pdf, line, x, y, w, h)
+	}
+	pdf.SetY(top + float64(rows)*(h+gap))
+	pdf.Ln(4)
+}
+
+func (g *ReportGenerator) addSparkline(pdf *fpdf.Fpdf, line sparkline, x, y, w, h float64) {
+	pdf.SetDrawColor(200, 200, 200)
+	pdf.Rect(x, y, w, h, "D")
+
+	pdf.SetXY(x+2, y+1)
+	pdf.SetFont("Arial", "B", 8)
+	pdf.SetTextColor(64, 64, 64)
+	pdf.CellFormat(w-4, 4, g.t(line.title), "", 0, "L", false, 0, "")
+
+	if len(line.points) < 2 {
+		pdf.SetXY(x+2, y+h/2-2)
+		pdf.SetFont("Arial", "I", 8)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.CellFormat(w-4, 4, g.t("Not enough data"), "", 0, "C", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		return
+	}
+
+	latest := line.points[len(line.points)-1].value
+	change := latest - line.points[0].value
+	pdf.SetXY(x+2, y+1)
+	pdf.SetFont("Arial", "", 8)
+	pdf.CellFormat(w-4, 4, fmt.Sprintf("latest "+line.format+" (%+"+line.format[1:]+")", latest, change), "", 0, "R", false, 0, "")
+
+	minV, maxV := line.points[0].value, line.points[0].value
+	for _, p := range line.points {
+		minV = min(minV, p.value)
+		maxV = max(maxV, p.value)
+	}
+	if maxV == minV {
+		minV, maxV = minV-1, maxV+1
+	}
+	first, last := line.points[0].at, line.points[len(line.points)-1].at
+	// Plot area inside the box, below the title
+	px, py, pw, ph := x+4, y+7, w-8, h-10
+	pos := func(p sparkPoint) (float64, float64) {
+		fx := 0.5
+		if last > first {
+			fx = (p.at - first) / (last - first)
+		}
+		return px + fx*pw, py + ph - (p.value-minV)/(maxV-minV)*ph
+	}
+
+	if line.reference > minV && line.reference < maxV {
+		ry := py + ph - (line.reference-minV)/(maxV-minV)*ph
+		pdf.SetDrawColor(239, 68, 68)
+		pdf.SetDashPattern([]float64{1, 1}, 0)
+		pdf.Line(px, ry, px+pw, ry)
+		pdf.SetDashPattern([]float64{}, 0)
+	}
+
+	pdf.SetDrawColor(75, 0, 130)
+	pdf.SetFillColor(75, 0, 130)
+	pdf.SetLineWidth(0.4)
+	for i, p := range line.points {
+		cx, cy := pos(p)
+		if i > 0 {
+			prevX, prevY := pos(line.points[i-1])
+			pdf.Line(prevX, prevY, cx, cy)
+		}
+		pdf.Circle(cx, cy, 0.6, "F")
+	}
+	pdf.SetLineWidth(0.2)
+	pdf.SetTextColor(0, 0, 0)
+}
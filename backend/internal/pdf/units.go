@@ -0,0 +1,67 @@
+package pdf
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/loinc"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// WithUnits shows glucose and lipids in units, models.UnitsMgDL or
+// models.UnitsMmolL; anything else is mg/dL. Statuses are still judged on
+// the stored mg/dL values.
+func (g *ReportGenerator) WithUnits(units string) *ReportGenerator {
+	g.units = units
+	return g
+}
+
+// WithDateFormat selects how report dates are written, one of the
+// models.DateFormat values; anything else spells out the month.
+func (g *ReportGenerator) WithDateFormat(format string) *ReportGenerator {
+	g.dateFormat = format
+	return g
+}
+
+// unit returns the unit the key biomarker is shown in
+func (g *ReportGenerator) unit(key string) string {
+	b, _ := loinc.ByKey(key)
+	return b.PreferredUnit(g.units)
+}
+
+// express returns v, a value of the key biomarker as stored, in the
+// report's units
+func (g *ReportGenerator) express(key string, v float64) float64 {
+	b, _ := loinc.ByKey(key)
+	out, err := b.Express(v, b.PreferredUnit(g.units))
+	if err != nil {
+		return v
+	}
+	return out
+}
+
+// value formats v, a value of the key biomarker as stored, in the report's
+// units: whole numbers in mg/dL, glucose to one and lipids to two decimals
+// in mmol/L.
+func (g *ReportGenerator) value(key string, v float64) string {
+	switch {
+	case g.unit(key) != models.UnitsMmolL:
+		return fmt.Sprintf("%.0f", g.express(key, v))
+	case key == "fbs":
+		return fmt.Sprintf("%.1f", g.express(key, v))
+	default:
+		return fmt.Sprintf("%.2f", g.express(key, v))
+	}
+}
+
+// numericDate formats a date for table cells in the report's date format,
+// ISO unless day or month first was chosen
+func (g *ReportGenerator) numericDate(d time.Time) string {
+	switch g.dateFormat {
+	case models.DateFormatDMY:
+		return d.Format("02/01/2006")
+	case models.DateFormatMDY:
+		return d.Format("01/02/2006")
+	}
+	return d.Format("2006-01-02")
+}
@@ -0,0 +1,292 @@
+// Package stats implements the small set of hypothesis tests used by cohort
+// comparison (Welch's t-test, Cohen's d and Pearson's chi-square test) and
+// the least-squares line used by patient projections.
+package stats
+
+import "math"
+
+// Moments summarizes a sample by size, mean and sample standard deviation
+type Moments struct {
+	N      int
+	Mean   float64
+	StdDev float64
+}
+
+// WelchTTest compares two sample means without assuming equal variances.
+// Returns the t statistic, Welch-Satterthwaite degrees of freedom and the
+// two-sided p-value. ok is false when either sample is too small or both
+// have zero variance, in which case the test is undefined.
+func WelchTTest(a, b Moments) (t, df, p float64, ok bool) {
+	if a.N < 2 || b.N < 2 {
+		return 0, 0, 0, false
+	}
+	va := a.StdDev * a.StdDev / float64(a.N)
+	vb := b.StdDev * b.StdDev / float64(b.N)
+	se := va + vb
+	if se == 0 {
+		return 0, 0, 0, false
+	}
+	t = (a.Mean - b.Mean) / math.Sqrt(se)
+	df = se * se / (va*va/float64(a.N-1) + vb*vb/float64(b.N-1))
+	p = StudentTTwoSided(t, df)
+	return t, df, p, true
+}
+
+// CohensD is the standardized mean difference using the pooled standard
+// deviation. Returns 0 when the pooled deviation is zero.
+func CohensD(a, b Moments) float64 {
+	if a.N+b.N <= 2 {
+		return 0
+	}
+	pooled := math.Sqrt((float64(a.N-1)*a.StdDev*a.StdDev + float64(b.N-1)*b.StdDev*b.StdDev) / float64(a.N+b.N-2))
+	if pooled == 0 {
+		return 0
+	}
+	return (a.Mean - b.Mean) / pooled
+}
+
+// ChiSquare runs Pearson's test of independence on a contingency table of
+// counts. Rows and columns with a zero total are dropped first. ok is false
+// when fewer than two rows or columns remain.
+func ChiSquare(table [][]float64) (stat float64, df int, p float64, ok bool) {
+	rows, cols := len(table), 0
+	if rows > 0 {
+		cols = len(table[0])
+	}
+	rowTotals := make([]float64, rows)
+	colTotals := make([]float64, cols)
+	total := 0.0
+	for i, row := range table {
+		for j, v := range row {
+			rowTotals[i] += v
+			colTotals[j] += v
+			total += v
+		}
+	}
+
+	usedRows, usedCols := 0, 0
+	for _, v := range rowTotals {
+		if v > 0 {
+			usedRows++
+		}
+	}
+	for _, v := range colTotals {
+		if v > 0 {
+			usedCols++
+		}
+	}
+	if usedRows < 2 || usedCols < 2 {
+		return 0, 0, 0, false
+	}
+
+	for i, row := range table {
+		for j, v := range row {
+			if rowTotals[i] == 0 || colTotals[j] == 0 {
+				continue
+			}
+			expected := rowTotals[i] * colTotals[j] / total
+			stat += (v - expected) * (v - expected) / expected
+		}
+	}
+	df = (usedRows - 1) * (usedCols - 1)
+	return stat, df, ChiSquareSurvival(stat, float64(df)), true
+}
+
+// StudentTTwoSided returns P(|T| >= |t|) for a Student t distribution
+func StudentTTwoSided(t, df float64) float64 {
+	return regIncBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// ChiSquareSurvival returns P(X >= x) for a chi-square distribution
+func ChiSquareSurvival(x, df float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	return regGammaQ(df/2, x/2)
+}
+
+// StudentTQuantile returns t such that P(|T| >= t) = p for a Student t
+// distribution, i.e. the two-sided critical value, found by bisection.
+func StudentTQuantile(p, df float64) float64 {
+	lo, hi := 0.0, 1.0
+	for StudentTTwoSided(hi, df) > p {
+		hi *= 2
+	}
+	for i := 0; i < 100; i++ {
+		mid := (lo + hi) / 2
+		if StudentTTwoSided(mid, df) > p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// LinearFit is an ordinary least-squares line y = Intercept + Slope*x.
+type LinearFit struct {
+	N         int
+	Intercept float64
+	Slope     float64
+	// ResidualSE is the standard error of the residuals, on N-2 degrees of freedom
+	ResidualSE float64
+	meanX      float64
+	sxx        float64
+}
+
+// FitLine fits a least-squares line through the points. ok is false with
+// fewer than three points or when every x is the same, since neither leaves
+// a residual error to bound predictions with.
+func FitLine(xs, ys []float64) (fit LinearFit, ok bool) {
+	n := len(xs)
+	if n < 3 || len(ys) != n {
+		return LinearFit{}, false
+	}
+	var sumX, sumY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/float64(n), sumY/float64(n)
+	var sxx, sxy float64
+	for i := range xs {
+		dx := xs[i] - meanX
+		sxx += dx * dx
+		sxy += dx * (ys[i] - meanY)
+	}
+	if sxx == 0 {
+		return LinearFit{}, false
+	}
+	fit = LinearFit{N: n, Slope: sxy / sxx, meanX: meanX, sxx: sxx}
+	fit.Intercept = meanY - fit.Slope*meanX
+	var sse float64
+	for i := range xs {
+		r := ys[i] - fit.Predict(xs[i])
+		sse += r * r
+	}
+	fit.ResidualSE = math.Sqrt(sse / float64(n-2))
+	return fit, true
+}
+
+// Predict returns the fitted value at x
+func (f LinearFit) Predict(x float64) float64 {
+	return f.Intercept + f.Slope*x
+}
+
+// PredictionInterval returns the bounds a new observation at x falls within
+// with probability level, e.g. 0.95.
+func (f LinearFit) PredictionInterval(x, level float64) (lower, upper float64) {
+	dx := x - f.meanX
+	half := StudentTQuantile(1-level, float64(f.N-2)) * f.ResidualSE * math.Sqrt(1+1/float64(f.N)+dx*dx/f.sxx)
+	y := f.Predict(x)
+	return y - half, y + half
+}
+
+const (
+	maxIter = 300
+	epsilon = 1e-14
+	tiny    = 1e-300
+)
+
+// regIncBeta is the regularized incomplete beta function I_x(a, b)
+func regIncBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	// The continued fraction converges fastest on this side of the mean
+	if x < (a+1)/(a+b+2) {
+		return front * betaCF(a, b, x) / a
+	}
+	return 1 - front*betaCF(b, a, 1-x)/b
+}
+
+// betaCF evaluates the incomplete beta continued fraction (modified Lentz)
+func betaCF(a, b, x float64) float64 {
+	qab, qap, qam := a+b, a+1, a-1
+	c, d := 1.0, 1-qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIter; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
+
+// regGammaQ is the regularized upper incomplete gamma function Q(a, x)
+func regGammaQ(a, x float64) float64 {
+	lga, _ := math.Lgamma(a)
+	if x < a+1 {
+		// Series for P(a, x), then complement
+		sum, del, ap := 1/a, 1/a, a
+		for n := 0; n < maxIter; n++ {
+			ap++
+			del *= x / ap
+			sum += del
+			if math.Abs(del) < math.Abs(sum)*epsilon {
+				break
+			}
+		}
+		return 1 - sum*math.Exp(-x+a*math.Log(x)-lga)
+	}
+	// Continued fraction for Q(a, x) (modified Lentz)
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i <= maxIter; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < epsilon {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lga) * h
+}
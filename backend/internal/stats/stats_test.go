@@ -0,0 +1,97 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func approx(t *testing.T, name string, got, want, tol float64) {
+	t.Helper()
+	if math.Abs(got-want) > tol {
+		t.Errorf("%s = %.6f, want %.6f (±%g)", name, got, want, tol)
+	}
+}
+
+func TestDistributions(t *testing.T) {
+	// Reference values from standard t and chi-square tables
+	approx(t, "t(2.228, 10)", StudentTTwoSided(2.228, 10), 0.05, 1e-3)
+	approx(t, "t(1.96, 1e6)", StudentTTwoSided(1.96, 1e6), 0.05, 1e-3)
+	approx(t, "t(0, 5)", StudentTTwoSided(0, 5), 1, 1e-12)
+	approx(t, "chi2(5.991, 2)", ChiSquareSurvival(5.991, 2), 0.05, 1e-3)
+	approx(t, "chi2(3.841, 1)", ChiSquareSurvival(3.841, 1), 0.05, 1e-3)
+	approx(t, "chi2(18.307, 10)", ChiSquareSurvival(18.307, 10), 0.05, 1e-3)
+}
+
+func TestWelchTTest(t *testing.T) {
+	a := Moments{N: 30, Mean: 7.2, StdDev: 1.1}
+	b := Moments{N: 25, Mean: 6.1, StdDev: 0.9}
+	tStat, df, p, ok := WelchTTest(a, b)
+	if !ok {
+		t.Fatal("expected test to be defined")
+	}
+	approx(t, "t", tStat, 4.0787, 1e-3)
+	approx(t, "df", df, 52.99, 0.05)
+	if p >= 0.001 {
+		t.Errorf("expected p < 0.001, got %f", p)
+	}
+
+	if _, _, _, ok := WelchTTest(Moments{N: 1, Mean: 5}, b); ok {
+		t.Error("expected undefined test for single-observation sample")
+	}
+	if _, _, _, ok := WelchTTest(Moments{N: 5, Mean: 5}, Moments{N: 5, Mean: 5}); ok {
+		t.Error("expected undefined test for zero variance")
+	}
+}
+
+func TestCohensD(t *testing.T) {
+	approx(t, "d", CohensD(Moments{N: 20, Mean: 10, StdDev: 2}, Moments{N: 20, Mean: 9, StdDev: 2}), 0.5, 1e-9)
+}
+
+func TestChiSquare(t *testing.T) {
+	stat, df, p, ok := ChiSquare([][]float64{{10, 20, 30}, {30, 20, 10}})
+	if !ok {
+		t.Fatal("expected test to be defined")
+	}
+	approx(t, "stat", stat, 20, 1e-9)
+	if df != 2 {
+		t.Errorf("df = %d, want 2", df)
+	}
+	approx(t, "p", p, math.Exp(-10), 1e-9)
+
+	// An empty column is dropped rather than dividing by zero
+	if _, df, _, ok := ChiSquare([][]float64{{5, 0, 5}, {2, 0, 8}}); !ok || df != 1 {
+		t.Errorf("expected df=1 after dropping empty column, got df=%d ok=%v", df, ok)
+	}
+	if _, _, _, ok := ChiSquare([][]float64{{5, 5, 5}, {0, 0, 0}}); ok {
+		t.Error("expected undefined test with a single non-empty row")
+	}
+}
+
+func TestStudentTQuantile(t *testing.T) {
+	approx(t, "t(0.05, 10)", StudentTQuantile(0.05, 10), 2.228, 1e-3)
+	approx(t, "t(0.05, 1)", StudentTQuantile(0.05, 1), 12.706, 1e-3)
+}
+
+func TestFitLine(t *testing.T) {
+	fit, ok := FitLine([]float64{0, 1, 2, 3, 4}, []float64{1, 3.1, 4.9, 7.2, 8.8})
+	if !ok {
+		t.Fatal("expected a fit")
+	}
+	approx(t, "slope", fit.Slope, 1.97, 1e-9)
+	approx(t, "intercept", fit.Intercept, 1.06, 1e-9)
+	approx(t, "residual se", fit.ResidualSE, 0.1742, 1e-4)
+
+	lo, hi := fit.PredictionInterval(2, 0.95)
+	approx(t, "centre", (lo+hi)/2, fit.Predict(2), 1e-9)
+	lo6, hi6 := fit.PredictionInterval(6, 0.95)
+	if hi6-lo6 <= hi-lo {
+		t.Errorf("interval should widen away from the data: %f <= %f", hi6-lo6, hi-lo)
+	}
+
+	if _, ok := FitLine([]float64{1, 2}, []float64{1, 2}); ok {
+		t.Error("expected no fit from two points")
+	}
+	if _, ok := FitLine([]float64{3, 3, 3}, []float64{1, 2, 3}); ok {
+		t.Error("expected no fit when every x is equal")
+	}
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+
+	// Register decoders for accepted upload formats.
+	_ "image/gif"
+	_ "image/png"
+)
+
+// ErrUnsupportedImage is returned when an upload is not a JPEG, PNG or GIF,
+// or its dimensions are unreasonable.
+var ErrUnsupportedImage = errors.New("storage: unsupported image format")
+
+// maxSourcePixels bounds decoded image size so a small, highly compressed
+// upload cannot expand into gigabytes of memory.
+const maxSourcePixels = 40_000_000
+
+// Thumbnail decodes an image, scales it down so neither side exceeds maxDim
+// (never up), and re-encodes it as JPEG. Re-encoding also drops any embedded
+// metadata such as EXIF location tags.
+func Thumbnail(r io.Reader, maxDim int) ([]byte, int, int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width*cfg.Height > maxSourcePixels {
+		return nil, 0, 0, ErrUnsupportedImage
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, 0, 0, ErrUnsupportedImage
+		}
+		return nil, 0, 0, err
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return nil, 0, 0, ErrUnsupportedImage
+	}
+	dw, dh := w, h
+	if w > maxDim || h > maxDim {
+		if w >= h {
+			dw, dh = maxDim, max(1, h*maxDim/w)
+		} else {
+			dw, dh = max(1, w*maxDim/h), maxDim
+		}
+	}
+	dst := downscale(src, dw, dh)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, 0, 0, err
+	}
+	return buf.Bytes(), dw, dh, nil
+}
+
+// downscale resizes src to dw x dh by averaging the source pixels covered by
+// each destination pixel (box filter). Transparent areas are flattened onto
+// white since JPEG has no alpha channel.
+func downscale(src image.Image, dw, dh int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0 := b.Min.Y + y*sh/dh
+		y1 := max(y0+1, b.Min.Y+(y+1)*sh/dh)
+		for x := 0; x < dw; x++ {
+			x0 := b.Min.X + x*sw/dw
+			x1 := max(x0+1, b.Min.X+(x+1)*sw/dw)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					// Composite over white (values are alpha-premultiplied)
+					white := 0xffff - uint64(ca)
+					r += uint64(cr) + white
+					g += uint64(cg) + white
+					bl += uint64(cb) + white
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(bl / n >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
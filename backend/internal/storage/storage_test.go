@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+)
+
+func TestLocalStorage_RoundTrip(t *testing.T) {
+	s := NewLocalStorage(t.TempDir())
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "patient-photos/1/a.jpg", bytes.NewReader([]byte("hello"))); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	rc, err := s.Get(ctx, "patient-photos/1/a.jpg")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != "hello" {
+		t.Fatalf("Get = %q, want hello", got)
+	}
+
+	if err := s.Delete(ctx, "patient-photos/1/a.jpg"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, "patient-photos/1/a.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after delete = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, "patient-photos/1/a.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("second Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalStorage_RejectsEscapingKeys(t *testing.T) {
+	s := NewLocalStorage(t.TempDir())
+	for _, key := range []string{"", "../x", "a/../../x", "/abs", "a//b", `a\b`} {
+		if err := s.Put(context.Background(), key, bytes.NewReader(nil)); err == nil {
+			t.Errorf("Put(%q) succeeded, want error", key)
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 1000, 500))
+	for y := 0; y < 500; y++ {
+		for x := 0; x < 1000; x++ {
+			src.Set(x, y, color.RGBA{R: 200, A: 0xff})
+		}
+	}
+	var in bytes.Buffer
+	if err := png.Encode(&in, src); err != nil {
+		t.Fatal(err)
+	}
+
+	data, w, h, err := Thumbnail(&in, 200)
+	if err != nil {
+		t.Fatalf("Thumbnail: %v", err)
+	}
+	if w != 200 || h != 100 {
+		t.Fatalf("size = %dx%d, want 200x100", w, h)
+	}
+	out, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("output is not JPEG: %v", err)
+	}
+	if b := out.Bounds(); b.Dx() != 200 || b.Dy() != 100 {
+		t.Fatalf("decoded size = %v", b)
+	}
+	if r, _, _, _ := out.At(100, 50).RGBA(); r>>8 < 180 {
+		t.Fatalf("colour not preserved, red = %d", r>>8)
+	}
+}
+
+func TestThumbnail_SmallImageNotUpscaled(t *testing.T) {
+	var in bytes.Buffer
+	_ = png.Encode(&in, image.NewRGBA(image.Rect(0, 0, 40, 30)))
+	_, w, h, err := Thumbnail(&in, 200)
+	if err != nil || w != 40 || h != 30 {
+		t.Fatalf("got %dx%d err=%v, want 40x30", w, h, err)
+	}
+}
+
+func TestThumbnail_RejectsNonImage(t *testing.T) {
+	if _, _, _, err := Thumbnail(bytes.NewReader([]byte("%PDF-1.4")), 200); !errors.Is(err, ErrUnsupportedImage) {
+		t.Fatalf("err = %v, want ErrUnsupportedImage", err)
+	}
+}
@@ -0,0 +1,274 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"golang.org/x/sync/singleflight"
+)
+
+// WithAnalyticsCache returns a store that answers the aggregate analytics
+// reads (cluster counts, trend averages, cohort statistics and admin system
+// statistics) from an in-memory cache for up to ttl. Concurrent misses for
+// the same read share one query. Assessment writes and patient changes made
+// through the returned store clear the cache; writes made by other replicas
+// or through another store are only seen once entries expire.
+func WithAnalyticsCache(st Store, ttl time.Duration) Store {
+	return &analyticsCacheStore{Store: st, cache: newAnalyticsCache(ttl)}
+}
+
+// analyticsCache is a TTL cache of read results keyed by read and arguments.
+// Cached values are shared between callers and must not be modified.
+type analyticsCache struct {
+	ttl   time.Duration
+	now   func() time.Time
+	group singleflight.Group
+
+	mu      sync.Mutex
+	gen     uint64
+	entries map[string]analyticsEntry
+}
+
+type analyticsEntry struct {
+	value   any
+	expires time.Time
+}
+
+func newAnalyticsCache(ttl time.Duration) *analyticsCache {
+	return &analyticsCache{ttl: ttl, now: time.Now, entries: map[string]analyticsEntry{}}
+}
+
+// invalidate drops every entry. Loads already running when it is called
+// still return to their callers but are not cached.
+func (c *analyticsCache) invalidate() {
+	c.mu.Lock()
+	c.gen++
+	c.entries = map[string]analyticsEntry{}
+	c.mu.Unlock()
+}
+
+// put caches value under key unless the cache was invalidated since the
+// load began at gen, sweeping expired entries so one-off keys do not pile up.
+func (c *analyticsCache) put(key string, gen uint64, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen != gen {
+		return
+	}
+	now := c.now()
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = analyticsEntry{value: value, expires: now.Add(c.ttl)}
+}
+
+// cachedRead returns the cached result of key, or calls load once for all
+// concurrent callers and caches its result. Errors are not cached. load runs
+// detached from the caller's cancellation, since other callers may share it.
+func cachedRead[T any](ctx context.Context, c *analyticsCache, key string, load func(context.Context) (T, error)) (T, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && c.now().Before(e.expires) {
+		c.mu.Unlock()
+		return e.value.(T), nil
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	// Keying the flight by generation keeps callers arriving after an
+	// invalidation from sharing a load that started before it
+	v, err, _ := c.group.Do(analyticsKey("flight", gen, key), func() (any, error) {
+		v, err := load(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		c.put(key, gen, v)
+		return v, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return v.(T), nil
+}
+
+// analyticsKey builds a cache key from a read name and its arguments
+func analyticsKey(name string, args ...any) string {
+	if len(args) == 0 {
+		return name
+	}
+	b, _ := json.Marshal(args)
+	return name + ":" + string(b)
+}
+
+type analyticsCacheStore struct {
+	Store
+	cache *analyticsCache
+}
+
+func (s *analyticsCacheStore) Assessments() AssessmentRepository {
+	return &cachedAssessmentRepo{AssessmentRepository: s.Store.Assessments(), cache: s.cache}
+}
+
+func (s *analyticsCacheStore) Patients() PatientRepository {
+	return &cachedPatientRepo{PatientRepository: s.Store.Patients(), cache: s.cache}
+}
+
+func (s *analyticsCacheStore) Cohort() CohortRepository {
+	return &cachedCohortRepo{CohortRepository: s.Store.Cohort(), cache: s.cache}
+}
+
+func (s *analyticsCacheStore) Clinics() ClinicRepository {
+	return &cachedClinicRepo{ClinicRepository: s.Store.Clinics(), cache: s.cache}
+}
+
+type cachedAssessmentRepo struct {
+	AssessmentRepository
+	cache *analyticsCache
+}
+
+func (r *cachedAssessmentRepo) ClusterCounts(ctx context.Context) ([]models.ClusterAnalytics, error) {
+	return cachedRead(ctx, r.cache, analyticsKey("cluster_counts"), r.AssessmentRepository.ClusterCounts)
+}
+
+func (r *cachedAssessmentRepo) ClusterCountsInScope(ctx context.Context, scope models.CohortScope) ([]models.ClusterAnalytics, error) {
+	return cachedRead(ctx, r.cache, analyticsKey("cluster_counts", scope), func(ctx context.Context) ([]models.ClusterAnalytics, error) {
+		return r.AssessmentRepository.ClusterCountsInScope(ctx, scope)
+	})
+}
+
+func (r *cachedAssessmentRepo) TrendAverages(ctx context.Context, params models.TrendParams) ([]models.TrendPoint, error) {
+	return cachedRead(ctx, r.cache, analyticsKey("trend_averages", params), func(ctx context.Context) ([]models.TrendPoint, error) {
+		return r.AssessmentRepository.TrendAverages(ctx, params)
+	})
+}
+
+func (r *cachedAssessmentRepo) Create(ctx context.Context, a models.Assessment) (*models.Assessment, error) {
+	defer r.cache.invalidate()
+	return r.AssessmentRepository.Create(ctx, a)
+}
+
+func (r *cachedAssessmentRepo) CreateBatch(ctx context.Context, items []models.Assessment) ([]models.Assessment, error) {
+	defer r.cache.invalidate()
+	return r.AssessmentRepository.CreateBatch(ctx, items)
+}
+
+func (r *cachedAssessmentRepo) Update(ctx context.Context, a models.Assessment, userID int32) (*models.Assessment, error) {
+	defer r.cache.invalidate()
+	return r.AssessmentRepository.Update(ctx, a, userID)
+}
+
+func (r *cachedAssessmentRepo) Delete(ctx context.Context, id int32, userID int32) error {
+	defer r.cache.invalidate()
+	return r.AssessmentRepository.Delete(ctx, id, userID)
+}
+
+func (r *cachedAssessmentRepo) SetValidationStatus(ctx context.Context, id int32, status string) error {
+	defer r.cache.invalidate()
+	return r.AssessmentRepository.SetValidationStatus(ctx, id, status)
+}
+
+func (r *cachedAssessmentRepo) Amend(ctx context.Context, originalID int64, a models.Assessment) (*models.Assessment, error) {
+	defer r.cache.invalidate()
+	return r.AssessmentRepository.Amend(ctx, originalID, a)
+}
+
+func (r *cachedAssessmentRepo) Finalize(ctx context.Context, a models.Assessment, userID int32) (*models.Assessment, error) {
+	defer r.cache.invalidate()
+	return r.AssessmentRepository.Finalize(ctx, a, userID)
+}
+
+func (r *cachedAssessmentRepo) CompletePrediction(ctx context.Context, a models.Assessment) error {
+	defer r.cache.invalidate()
+	return r.AssessmentRepository.CompletePrediction(ctx, a)
+}
+
+// cachedPatientRepo clears the cache on changes that move a patient's
+// assessments between cohort groups or scopes, or remove them
+type cachedPatientRepo struct {
+	PatientRepository
+	cache *analyticsCache
+}
+
+func (r *cachedPatientRepo) Update(ctx context.Context, p models.Patient) (*models.Patient, error) {
+	defer r.cache.invalidate()
+	return r.PatientRepository.Update(ctx, p)
+}
+
+func (r *cachedPatientRepo) Delete(ctx context.Context, id int32, userID int32) error {
+	defer r.cache.invalidate()
+	return r.PatientRepository.Delete(ctx, id, userID)
+}
+
+func (r *cachedPatientRepo) SetClinic(ctx context.Context, id int64, ownerID int32, clinicID *int32, changedBy int32) error {
+	defer r.cache.invalidate()
+	return r.PatientRepository.SetClinic(ctx, id, ownerID, clinicID, changedBy)
+}
+
+func (r *cachedPatientRepo) Merge(ctx context.Context, survivorID, mergedID int64, userID int32) (*models.PatientMerge, error) {
+	defer r.cache.invalidate()
+	return r.PatientRepository.Merge(ctx, survivorID, mergedID, userID)
+}
+
+func (r *cachedPatientRepo) Transfer(ctx context.Context, id int64, fromUserID, toUserID, changedBy int32) error {
+	defer r.cache.invalidate()
+	return r.PatientRepository.Transfer(ctx, id, fromUserID, toUserID, changedBy)
+}
+
+type cachedCohortRepo struct {
+	CohortRepository
+	cache *analyticsCache
+}
+
+func (r *cachedCohortRepo) StatsByCluster(ctx context.Context) ([]models.CohortGroup, error) {
+	return cachedRead(ctx, r.cache, analyticsKey("cohort_cluster"), r.CohortRepository.StatsByCluster)
+}
+
+func (r *cachedCohortRepo) StatsByRiskLevel(ctx context.Context) ([]models.CohortGroup, error) {
+	return cachedRead(ctx, r.cache, analyticsKey("cohort_risk_level"), r.CohortRepository.StatsByRiskLevel)
+}
+
+func (r *cachedCohortRepo) StatsByAgeGroup(ctx context.Context) ([]models.CohortGroup, error) {
+	return cachedRead(ctx, r.cache, analyticsKey("cohort_age_group"), r.CohortRepository.StatsByAgeGroup)
+}
+
+func (r *cachedCohortRepo) StatsByMenopauseStatus(ctx context.Context) ([]models.CohortGroup, error) {
+	return cachedRead(ctx, r.cache, analyticsKey("cohort_menopause_status"), r.CohortRepository.StatsByMenopauseStatus)
+}
+
+func (r *cachedCohortRepo) TotalPatientCount(ctx context.Context) (int, error) {
+	return cachedRead(ctx, r.cache, analyticsKey("cohort_patients"), r.CohortRepository.TotalPatientCount)
+}
+
+func (r *cachedCohortRepo) TotalAssessmentCount(ctx context.Context) (int, error) {
+	return cachedRead(ctx, r.cache, analyticsKey("cohort_assessments"), r.CohortRepository.TotalAssessmentCount)
+}
+
+func (r *cachedCohortRepo) ScopedStats(ctx context.Context, groupBy string, scope models.CohortScope) ([]models.CohortGroup, error) {
+	return cachedRead(ctx, r.cache, analyticsKey("cohort_scoped", groupBy, scope), func(ctx context.Context) ([]models.CohortGroup, error) {
+		return r.CohortRepository.ScopedStats(ctx, groupBy, scope)
+	})
+}
+
+func (r *cachedCohortRepo) ScopedTotals(ctx context.Context, scope models.CohortScope) (int, int, error) {
+	totals, err := cachedRead(ctx, r.cache, analyticsKey("cohort_scoped_totals", scope), func(ctx context.Context) ([2]int, error) {
+		patients, assessments, err := r.CohortRepository.ScopedTotals(ctx, scope)
+		return [2]int{patients, assessments}, err
+	})
+	return totals[0], totals[1], err
+}
+
+type cachedClinicRepo struct {
+	ClinicRepository
+	cache *analyticsCache
+}
+
+func (r *cachedClinicRepo) AdminSystemStats(ctx context.Context, rng models.StatsRange) (*models.SystemStats, error) {
+	return cachedRead(ctx, r.cache, analyticsKey("admin_system_stats", rng), func(ctx context.Context) (*models.SystemStats, error) {
+		return r.ClinicRepository.AdminSystemStats(ctx, rng)
+	})
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func TestAnalyticsCache_TTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := newAnalyticsCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	var loads int
+	load := func(context.Context) (int, error) {
+		loads++
+		return loads, nil
+	}
+	for i := 0; i < 3; i++ {
+		if v, err := cachedRead(ctx, c, "k", load); err != nil || v != 1 {
+			t.Fatalf("read %d = %d, %v; want the cached 1", i, v, err)
+		}
+	}
+	if v, _ := cachedRead(ctx, c, "other", load); v != 2 {
+		t.Fatalf("another key = %d, want a fresh load", v)
+	}
+
+	now = now.Add(time.Minute)
+	if v, _ := cachedRead(ctx, c, "k", load); v != 3 {
+		t.Fatalf("after the TTL = %d, want a fresh load", v)
+	}
+	if len(c.entries) != 1 {
+		t.Errorf("expired entries were not swept: %v", c.entries)
+	}
+
+	c.invalidate()
+	if v, _ := cachedRead(ctx, c, "k", load); v != 4 {
+		t.Fatalf("after invalidate = %d, want a fresh load", v)
+	}
+}
+
+func TestAnalyticsCache_ErrorsNotCached(t *testing.T) {
+	ctx := context.Background()
+	c := newAnalyticsCache(time.Minute)
+	fail := errors.New("db down")
+	if _, err := cachedRead(ctx, c, "k", func(context.Context) (int, error) { return 0, fail }); !errors.Is(err, fail) {
+		t.Fatalf("err = %v, want %v", err, fail)
+	}
+	if v, err := cachedRead(ctx, c, "k", func(context.Context) (int, error) { return 7, nil }); err != nil || v != 7 {
+		t.Fatalf("retry = %d, %v; want 7", v, err)
+	}
+}
+
+func TestAnalyticsCache_SharesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	c := newAnalyticsCache(time.Minute)
+	release := make(chan struct{})
+	var loads atomic.Int32
+	load := func(context.Context) (int, error) {
+		loads.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := cachedRead(ctx, c, "k", load); err != nil || v != 42 {
+				t.Errorf("read = %d, %v; want 42", v, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("loads = %d, want 1", n)
+	}
+}
+
+func TestAnalyticsCache_LoadDuringInvalidateNotCached(t *testing.T) {
+	ctx := context.Background()
+	c := newAnalyticsCache(time.Minute)
+	v, _ := cachedRead(ctx, c, "k", func(context.Context) (int, error) {
+		c.invalidate()
+		return 1, nil
+	})
+	if v != 1 {
+		t.Fatalf("read = %d, want 1", v)
+	}
+	if v, _ := cachedRead(ctx, c, "k", func(context.Context) (int, error) { return 2, nil }); v != 2 {
+		t.Fatalf("read = %d, want the stale load dropped", v)
+	}
+}
+
+func TestWithAnalyticsCache_InvalidatedByWrites(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemoryStore()
+	if err := mem.SeedDemo("hash"); err != nil {
+		t.Fatal(err)
+	}
+	st := WithAnalyticsCache(mem, time.Hour)
+	total := func() int {
+		counts, err := st.Assessments().ClusterCounts(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, c := range counts {
+			n += c.Count
+		}
+		return n
+	}
+	before := total()
+	assessments, err := mem.Assessments().ListAllLimited(ctx, 1)
+	if err != nil || len(assessments) == 0 {
+		t.Fatalf("seeded assessments = %v, err = %v", assessments, err)
+	}
+	a := assessments[0]
+	a.ID = 0
+
+	// Writes that bypass the cached store are not seen until expiry
+	if _, err := mem.Assessments().Create(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if got := total(); got != before {
+		t.Fatalf("total = %d, want the cached %d", got, before)
+	}
+
+	if _, err := st.Assessments().Create(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if got := total(); got != before+2 {
+		t.Fatalf("total after a cached-store write = %d, want %d", got, before+2)
+	}
+
+	stats, err := st.Clinics().AdminSystemStats(ctx, models.StatsRange{})
+	if err != nil || stats.TotalAssessments != before+2 {
+		t.Fatalf("system stats = %+v, err = %v", stats, err)
+	}
+}
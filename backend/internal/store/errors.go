@@ -0,0 +1,39 @@
+// errors.go: Store-level sentinel errors, so handlers can map failures to
+// HTTP statuses without inspecting driver messages.
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrNotFound is returned when the requested row does not exist or is not
+	// visible to the caller. Repositories report it as pgx.ErrNoRows, so the
+	// two are interchangeable with errors.Is.
+	ErrNotFound = pgx.ErrNoRows
+	// ErrConflict is returned when a write would violate a uniqueness rule,
+	// such as a second account with the same email.
+	ErrConflict = errors.New("conflict")
+	// ErrForbidden is returned when the caller may see a row but not change it.
+	ErrForbidden = errors.New("forbidden")
+	// ErrClinicPatients is returned by a user purge while the user still owns
+	// patients a clinic relies on. It wraps ErrConflict.
+	ErrClinicPatients = fmt.Errorf("%w: user owns patients shared with or held in a clinic", ErrConflict)
+)
+
+// uniqueViolation is the Postgres SQLSTATE for a unique constraint violation
+const uniqueViolation = "23505"
+
+// conflictError wraps unique violations in ErrConflict, keeping the original
+// error for logging. Other errors are returned unchanged.
+func conflictError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	}
+	return err
+}
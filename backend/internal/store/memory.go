@@ -0,0 +1,1395 @@
+// memory.go: In-memory Store for development and demos without Postgres.
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// MemoryStore keeps every table in maps guarded by one mutex. It follows the
+// Postgres store's visibility rules and errors (pgx.ErrNoRows for missing
+// rows, ErrConflict for duplicates) so handlers behave the same, but nothing
+// survives a restart.
+type MemoryStore struct {
+	mu  sync.RWMutex
+	ids map[string]int64
+
+	users         map[int64]*models.User
+	patients      map[int64]*models.Patient
+	assessments   map[int64]*models.Assessment
+	explanations  map[int64]map[string]interface{}
+	refreshTokens []*models.RefreshToken
+	clinics       map[int64]*memClinic
+	members       []*memMember
+	audit         []models.AuditEvent
+	modelRuns     []*models.ModelRun
+	alerts        []*models.RiskAlert
+	verifications map[string]*memToken
+	resets        map[string]*memToken
+	photos        map[int64]models.PatientPhoto
+	contacts      map[int64]models.PatientContact
+	apiTokens     []*models.APIToken
+	apiKeys       []*models.APIKey
+	mfa           map[int64]*models.MFAEnrollment
+	backupCodes   map[int64]map[string]bool // user -> code hash -> used
+	mfaRoles      []string
+	discrepancies []*models.BaselineDiscrepancy
+	versions      []models.PatientVersion
+	exposures     []models.ExperimentExposure
+	deletions     []*models.UserDeletion
+	predictions   map[string]models.CachedPrediction
+	rescores      []models.AssessmentPrediction
+	webhooks      []*models.Webhook
+	deliveries    []*models.WebhookDelivery
+	drafts        []*models.AssessmentDraft
+	notes         []*models.PatientNote
+	attachments   []*models.AssessmentAttachment
+	medications   []*models.PatientMedication
+	followUps     []*models.FollowUp
+	tasks         []*models.Task
+	tags          []*models.Tag
+	patientTags   map[int64]map[int64]bool // tag -> patient
+	listViews     []*models.PatientListView
+	preferences   map[int64]models.UserPreferences
+	permissions   map[string][]string // role -> sorted permissions
+	amended       map[int64]bool      // assessment -> replaced by an amendment
+	logins        []models.LoginAttempt
+}
+
+// memClinic is a clinic with the settings Postgres keeps as columns
+type memClinic struct {
+	models.Clinic
+	validationMode string
+	photosEnabled  bool
+	baselinePolicy string
+	locale         string
+	logo           []byte
+}
+
+type memMember struct {
+	clinicID, userID int64
+	role             string
+	joinedAt         time.Time
+}
+
+// memToken is a single-use email verification or password reset token
+type memToken struct {
+	userID    int64
+	expiresAt time.Time
+	used      bool
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		ids:           map[string]int64{},
+		users:         map[int64]*models.User{},
+		patients:      map[int64]*models.Patient{},
+		assessments:   map[int64]*models.Assessment{},
+		explanations:  map[int64]map[string]interface{}{},
+		clinics:       map[int64]*memClinic{},
+		verifications: map[string]*memToken{},
+		resets:        map[string]*memToken{},
+		photos:        map[int64]models.PatientPhoto{},
+		contacts:      map[int64]models.PatientContact{},
+		predictions:   map[string]models.CachedPrediction{},
+		preferences:   map[int64]models.UserPreferences{},
+		mfa:           map[int64]*models.MFAEnrollment{},
+		backupCodes:   map[int64]map[string]bool{},
+		patientTags:   map[int64]map[int64]bool{},
+		permissions:   defaultPermissions(),
+		amended:       map[int64]bool{},
+	}
+}
+
+// defaultPermissions copies models.DefaultRolePermissions, as migration 0049
+// seeds them.
+func defaultPermissions() map[string][]string {
+	out := make(map[string][]string, len(models.DefaultRolePermissions))
+	for role, perms := range models.DefaultRolePermissions {
+		out[role] = slices.Sorted(slices.Values(perms))
+	}
+	return out
+}
+
+func (s *MemoryStore) Close() {}
+
+func (s *MemoryStore) Users() UserRepository                 { return &memUserRepo{s} }
+func (s *MemoryStore) Patients() PatientRepository           { return &memPatientRepo{s} }
+func (s *MemoryStore) Assessments() AssessmentRepository     { return &memAssessmentRepo{s} }
+func (s *MemoryStore) RefreshTokens() RefreshTokenRepository { return &memRefreshTokenRepo{s} }
+func (s *MemoryStore) Cohort() CohortRepository              { return &memCohortRepo{s} }
+func (s *MemoryStore) Clinics() ClinicRepository             { return &memClinicRepo{s} }
+func (s *MemoryStore) AuditEvents() AuditEventRepository     { return &memAuditEventRepo{s} }
+func (s *MemoryStore) ModelRuns() ModelRunRepository         { return &memModelRunRepo{s} }
+func (s *MemoryStore) RiskAlerts() RiskAlertRepository       { return &memRiskAlertRepo{s} }
+func (s *MemoryStore) EmailVerifications() EmailVerificationRepository {
+	return &memEmailVerificationRepo{s}
+}
+func (s *MemoryStore) PasswordResets() PasswordResetRepository    { return &memPasswordResetRepo{s} }
+func (s *MemoryStore) PatientPhotos() PatientPhotoRepository      { return &memPatientPhotoRepo{s} }
+func (s *MemoryStore) PatientContacts() PatientContactRepository  { return &memPatientContactRepo{s} }
+func (s *MemoryStore) PatientNotes() PatientNoteRepository        { return &memPatientNoteRepo{s} }
+func (s *MemoryStore) APITokens() APITokenRepository              { return &memAPITokenRepo{s} }
+func (s *MemoryStore) APIKeys() APIKeyRepository                  { return &memAPIKeyRepo{s} }
+func (s *MemoryStore) MFA() MFARepository                         { return &memMFARepo{s} }
+func (s *MemoryStore) PatientHistory() PatientHistoryRepository   { return &memPatientHistoryRepo{s} }
+func (s *MemoryStore) Experiments() ExperimentRepository          { return &memExperimentRepo{s} }
+func (s *MemoryStore) UserDeletions() UserDeletionRepository      { return &memUserDeletionRepo{s} }
+func (s *MemoryStore) PredictionCache() PredictionCacheRepository { return &memPredictionCacheRepo{s} }
+func (s *MemoryStore) Webhooks() WebhookRepository                { return &memWebhookRepo{s} }
+func (s *MemoryStore) AssessmentDrafts() AssessmentDraftRepository {
+	return &memAssessmentDraftRepo{s}
+}
+func (s *MemoryStore) PatientMedications() PatientMedicationRepository {
+	return &memPatientMedicationRepo{s}
+}
+func (s *MemoryStore) FollowUps() FollowUpRepository { return &memFollowUpRepo{s} }
+func (s *MemoryStore) Tasks() TaskRepository         { return &memTaskRepo{s} }
+func (s *MemoryStore) Tags() TagRepository           { return &memTagRepo{s} }
+func (s *MemoryStore) PatientListViews() PatientListViewRepository {
+	return &memPatientListViewRepo{s}
+}
+func (s *MemoryStore) AssessmentAttachments() AssessmentAttachmentRepository {
+	return &memAssessmentAttachmentRepo{s}
+}
+func (s *MemoryStore) Roles() RoleRepository { return &memRoleRepo{s} }
+func (s *MemoryStore) LoginHistory() LoginHistoryRepository {
+	return &memLoginHistoryRepo{s}
+}
+func (s *MemoryStore) BaselineDiscrepancies() BaselineDiscrepancyRepository {
+	return &memBaselineDiscrepancyRepo{s}
+}
+
+// nextID returns the next serial id of table; callers hold the write lock.
+func (s *MemoryStore) nextID(table string) int64 {
+	s.ids[table]++
+	return s.ids[table]
+}
+
+// page returns the bounds of one page of n rows, with the Postgres store's
+// defaults of page 1 and 20 rows and a maximum of 100.
+func page(n, page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+	from := min((page-1)*pageSize, n)
+	return from, min(from+pageSize, n)
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type memUserRepo struct{ s *MemoryStore }
+
+// findByEmail returns the stored user; callers hold the lock.
+func (s *MemoryStore) findByEmail(email string) *models.User {
+	for _, u := range s.users {
+		if strings.EqualFold(u.Email, email) {
+			return u
+		}
+	}
+	return nil
+}
+
+func (r *memUserRepo) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	u := r.s.findByEmail(email)
+	if u == nil {
+		return nil, pgx.ErrNoRows
+	}
+	out := *u
+	return &out, nil
+}
+
+func (r *memUserRepo) FindByID(ctx context.Context, id int32) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	u, ok := r.s.users[int64(id)]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	out := *u
+	return &out, nil
+}
+
+// activity computes a user's admin list stats; callers hold the lock.
+func (s *MemoryStore) activity(u *models.User) *models.UserActivity {
+	a := &models.UserActivity{LastActivityAt: u.LastLoginAt}
+	later := func(cur *time.Time, t time.Time) *time.Time {
+		if cur == nil || t.After(*cur) {
+			return &t
+		}
+		return cur
+	}
+	for _, p := range s.patients {
+		if p.UserID != u.ID {
+			continue
+		}
+		a.PatientCount++
+		a.LastActivityAt = later(a.LastActivityAt, p.UpdatedAt)
+		for _, as := range s.assessments {
+			if as.PatientID != p.ID || as.Draft() || s.superseded(as) {
+				continue
+			}
+			a.AssessmentCount++
+			if as.RiskScore >= 67 {
+				a.HighRiskAssessmentCount++
+			}
+			a.LastAssessmentAt = later(a.LastAssessmentAt, as.CreatedAt)
+			a.LastActivityAt = later(a.LastActivityAt, as.CreatedAt)
+		}
+	}
+	return a
+}
+
+func (r *memUserRepo) List(ctx context.Context, params models.UserListParams) ([]models.User, int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	users := []models.User{}
+	for _, u := range r.s.users {
+		if params.Search != "" && !containsFold(u.Email, params.Search) {
+			continue
+		}
+		if params.Role != "" && u.Role != params.Role {
+			continue
+		}
+		if params.IsActive != nil && u.IsActive != *params.IsActive {
+			continue
+		}
+		out := *u
+		out.Activity = r.s.activity(u)
+		users = append(users, out)
+	}
+
+	// Unset values sort last whatever the direction, as NULLS LAST does
+	key := func(u models.User) (float64, string, bool) {
+		t := func(v *time.Time) (float64, string, bool) {
+			if v == nil {
+				return 0, "", false
+			}
+			return float64(v.UnixNano()), "", true
+		}
+		switch params.Sort {
+		case "email":
+			return 0, strings.ToLower(u.Email), true
+		case "created_at":
+			return float64(u.CreatedAt.UnixNano()), "", true
+		case "last_login_at":
+			return t(u.LastLoginAt)
+		case "patient_count":
+			return float64(u.Activity.PatientCount), "", true
+		case "assessment_count":
+			return float64(u.Activity.AssessmentCount), "", true
+		case "high_risk_assessment_count":
+			return float64(u.Activity.HighRiskAssessmentCount), "", true
+		case "last_activity_at":
+			return t(u.Activity.LastActivityAt)
+		}
+		return float64(u.CreatedAt.UnixNano()), "", true
+	}
+	desc := params.Order == "desc" || params.Sort == ""
+	sort.SliceStable(users, func(i, j int) bool {
+		ni, si, oki := key(users[i])
+		nj, sj, okj := key(users[j])
+		if oki != okj {
+			return oki
+		}
+		if ni == nj && si == sj {
+			return users[i].ID > users[j].ID
+		}
+		less := ni < nj || (ni == nj && si < sj)
+		if desc {
+			return !less
+		}
+		return less
+	})
+	from, to := page(len(users), params.Page, params.PageSize)
+	return users[from:to], len(users), nil
+}
+
+func (r *memUserRepo) Create(ctx context.Context, user models.User) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.createUser(user, true)
+}
+
+// createUser stores a new user, rejecting duplicate emails; callers hold the
+// write lock.
+func (s *MemoryStore) createUser(user models.User, verified bool) (*models.User, error) {
+	if s.findByEmail(user.Email) != nil {
+		return nil, ErrConflict
+	}
+	now := time.Now()
+	user.ID = s.nextID("users")
+	user.IsActive = true
+	user.CreatedAt, user.UpdatedAt = now, now
+	if verified {
+		user.EmailVerifiedAt = &now
+	}
+	stored := user
+	s.users[user.ID] = &stored
+	return &user, nil
+}
+
+func (r *memUserRepo) Update(ctx context.Context, user models.User) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[user.ID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	if user.Email != "" && !strings.EqualFold(user.Email, u.Email) {
+		if r.s.findByEmail(user.Email) != nil {
+			return nil, ErrConflict
+		}
+		u.Email = user.Email
+	}
+	if user.Role != "" {
+		u.Role = user.Role
+	}
+	u.UpdatedAt = time.Now()
+	out := *u
+	return &out, nil
+}
+
+// updateUser applies fn to the stored user, returning pgx.ErrNoRows if it
+// does not exist.
+func (r *memUserRepo) updateUser(id int32, fn func(u *models.User)) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[int64(id)]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	fn(u)
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *memUserRepo) Deactivate(ctx context.Context, id int32) error {
+	return r.updateUser(id, func(u *models.User) { u.IsActive = false })
+}
+
+func (r *memUserRepo) Activate(ctx context.Context, id int32) error {
+	return r.updateUser(id, func(u *models.User) { u.IsActive = true })
+}
+
+func (r *memUserRepo) UpdateLastLogin(ctx context.Context, id int32) error {
+	return r.updateUser(id, func(u *models.User) {
+		now := time.Now()
+		u.LastLoginAt = &now
+	})
+}
+
+func (r *memUserRepo) RecordFailedLogin(ctx context.Context, id int32, threshold int, lockout time.Duration) (*time.Time, error) {
+	var locked *time.Time
+	err := r.updateUser(id, func(u *models.User) {
+		if u.FailedLoginAttempts+1 < threshold {
+			u.FailedLoginAttempts++
+			return
+		}
+		until := time.Now().Add(lockout)
+		u.FailedLoginAttempts = 0
+		u.LockedUntil = &until
+		locked = &until
+	})
+	return locked, err
+}
+
+func (r *memUserRepo) ResetFailedLogins(ctx context.Context, id int32) error {
+	return r.updateUser(id, func(u *models.User) { u.FailedLoginAttempts = 0 })
+}
+
+func (r *memUserRepo) Unlock(ctx context.Context, id int32) error {
+	return r.updateUser(id, func(u *models.User) {
+		u.FailedLoginAttempts = 0
+		u.LockedUntil = nil
+	})
+}
+
+func (r *memUserRepo) Preferences(ctx context.Context, id int32) (*models.UserPreferences, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	if _, ok := r.s.users[int64(id)]; !ok {
+		return nil, pgx.ErrNoRows
+	}
+	prefs := r.s.preferences[int64(id)]
+	return &prefs, nil
+}
+
+func (r *memUserRepo) SavePreferences(ctx context.Context, id int32, prefs models.UserPreferences) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.users[int64(id)]; !ok {
+		return pgx.ErrNoRows
+	}
+	prefs.DefaultPatientView = nil
+	r.s.preferences[int64(id)] = prefs
+	return nil
+}
+
+type memRefreshTokenRepo struct{ s *MemoryStore }
+
+// findToken returns the stored token with hash; callers hold the lock.
+func (s *MemoryStore) findToken(hash string) *models.RefreshToken {
+	for _, t := range s.refreshTokens {
+		if t.TokenHash == hash {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) addRefreshToken(userID int64, hash, familyID string, expiresAt time.Time) *models.RefreshToken {
+	now := time.Now()
+	t := &models.RefreshToken{
+		ID:         s.nextID("refresh_tokens"),
+		UserID:     userID,
+		TokenHash:  hash,
+		FamilyID:   familyID,
+		ExpiresAt:  expiresAt,
+		CreatedAt:  now,
+		SignedInAt: now,
+	}
+	s.refreshTokens = append(s.refreshTokens, t)
+	out := *t
+	return &out
+}
+
+func revoke(t *models.RefreshToken, at time.Time) {
+	t.Revoked = true
+	t.RevokedAt = at
+}
+
+func (r *memRefreshTokenRepo) CreateRefreshToken(ctx context.Context, tokenHash string, userID int32, expiresAt time.Time) (*models.RefreshToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.s.findToken(tokenHash) != nil {
+		return nil, ErrConflict
+	}
+	return r.s.addRefreshToken(int64(userID), tokenHash, randomHex(16), expiresAt), nil
+}
+
+func (r *memRefreshTokenRepo) FindRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	t := r.s.findToken(tokenHash)
+	if t == nil || t.Revoked || !t.ExpiresAt.After(time.Now()) {
+		return nil, pgx.ErrNoRows
+	}
+	out := *t
+	return &out, nil
+}
+
+func (r *memRefreshTokenRepo) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if t := r.s.findToken(tokenHash); t != nil && !t.Revoked {
+		revoke(t, time.Now())
+	}
+	return nil
+}
+
+func (r *memRefreshTokenRepo) RevokeAllUserTokens(ctx context.Context, userID int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.revokeUserTokens(int64(userID))
+	return nil
+}
+
+// revokeUserTokens revokes every active token of the user; callers hold the
+// write lock.
+func (s *MemoryStore) revokeUserTokens(userID int64) {
+	now := time.Now()
+	for _, t := range s.refreshTokens {
+		if t.UserID == userID && !t.Revoked {
+			revoke(t, now)
+		}
+	}
+}
+
+// keepTokens keeps the tokens keep reports true for and returns how many
+// were dropped.
+func (s *MemoryStore) keepTokens(keep func(t *models.RefreshToken) bool) int {
+	kept := s.refreshTokens[:0]
+	for _, t := range s.refreshTokens {
+		if keep(t) {
+			kept = append(kept, t)
+		}
+	}
+	n := len(s.refreshTokens) - len(kept)
+	s.refreshTokens = kept
+	return n
+}
+
+func (r *memRefreshTokenRepo) DeleteExpiredTokens(ctx context.Context) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	r.s.keepTokens(func(t *models.RefreshToken) bool { return t.ExpiresAt.After(now) })
+	return nil
+}
+
+func (r *memRefreshTokenRepo) RevokeExcessUserTokens(ctx context.Context, userID int32, keep int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var active []*models.RefreshToken
+	now := time.Now()
+	for _, t := range r.s.refreshTokens {
+		if t.UserID == int64(userID) && !t.Revoked && t.ExpiresAt.After(now) {
+			active = append(active, t)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].ID > active[j].ID })
+	n := 0
+	for i := keep; i < len(active); i++ {
+		revoke(active[i], now)
+		n++
+	}
+	return n, nil
+}
+
+func (r *memRefreshTokenRepo) DeleteRevokedTokens(ctx context.Context, before time.Time) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.keepTokens(func(t *models.RefreshToken) bool {
+		return !t.Revoked || !t.RevokedAt.Before(before)
+	}), nil
+}
+
+func (r *memRefreshTokenRepo) LookupRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	t := r.s.findToken(tokenHash)
+	if t == nil {
+		return nil, pgx.ErrNoRows
+	}
+	out := *t
+	return &out, nil
+}
+
+func (r *memRefreshTokenRepo) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*models.RefreshToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	old := r.s.findToken(oldHash)
+	if old == nil || old.Revoked || !old.ExpiresAt.After(now) {
+		return nil, pgx.ErrNoRows
+	}
+	revoke(old, now)
+	r.s.addRefreshToken(old.UserID, newHash, old.FamilyID, expiresAt)
+	// The session keeps describing the client that signed in
+	t := r.s.findToken(newHash)
+	t.UserAgent, t.IPAddress, t.SignedInAt = old.UserAgent, old.IPAddress, old.SignedInAt
+	out := *t
+	return &out, nil
+}
+
+func (r *memRefreshTokenRepo) RevokeTokenFamily(ctx context.Context, familyID string) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	n := 0
+	now := time.Now()
+	for _, t := range r.s.refreshTokens {
+		if t.FamilyID == familyID && !t.Revoked {
+			revoke(t, now)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *memRefreshTokenRepo) RecordSessionClient(ctx context.Context, tokenHash, userAgent, ipAddress string) (*models.RefreshToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	t := r.s.findToken(tokenHash)
+	if t == nil {
+		return nil, pgx.ErrNoRows
+	}
+	t.UserAgent, t.IPAddress = truncateClientField(userAgent), truncateClientField(ipAddress)
+	out := *t
+	return &out, nil
+}
+
+func (r *memRefreshTokenRepo) ListUserSessions(ctx context.Context, userID int64) ([]models.Session, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	now := time.Now()
+	out := []models.Session{}
+	for i := len(r.s.refreshTokens) - 1; i >= 0; i-- {
+		t := r.s.refreshTokens[i]
+		if t.UserID != userID || t.Revoked || !t.ExpiresAt.After(now) {
+			continue
+		}
+		out = append(out, models.Session{
+			ID:           t.FamilyID,
+			UserAgent:    t.UserAgent,
+			IPAddress:    t.IPAddress,
+			SignedInAt:   t.SignedInAt,
+			LastActiveAt: t.CreatedAt,
+			ExpiresAt:    t.ExpiresAt,
+		})
+	}
+	return out, nil
+}
+
+func (r *memRefreshTokenRepo) RevokeUserSession(ctx context.Context, userID int64, familyID string) (int, error) {
+	return r.revokeUserSessions(userID, func(t *models.RefreshToken) bool { return t.FamilyID == familyID })
+}
+
+func (r *memRefreshTokenRepo) RevokeOtherUserSessions(ctx context.Context, userID int64, keepFamilyID string) (int, error) {
+	return r.revokeUserSessions(userID, func(t *models.RefreshToken) bool { return t.FamilyID != keepFamilyID })
+}
+
+// revokeUserSessions revokes the user's active tokens that match.
+func (r *memRefreshTokenRepo) revokeUserSessions(userID int64, match func(t *models.RefreshToken) bool) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	n := 0
+	now := time.Now()
+	for _, t := range r.s.refreshTokens {
+		if t.UserID == userID && !t.Revoked && match(t) {
+			revoke(t, now)
+			n++
+		}
+	}
+	return n, nil
+}
+
+type memAuditEventRepo struct{ s *MemoryStore }
+
+func (r *memAuditEventRepo) Create(ctx context.Context, event models.AuditEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	event.ID = r.s.nextID("audit_events")
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	r.s.audit = append(r.s.audit, event)
+	return nil
+}
+
+func (r *memAuditEventRepo) List(ctx context.Context, params models.AuditListParams) ([]models.AuditEvent, int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	events := []models.AuditEvent{}
+	// Newest first
+	for i := len(r.s.audit) - 1; i >= 0; i-- {
+		e := r.s.audit[i]
+		if params.Actor != "" && !containsFold(e.Actor, params.Actor) {
+			continue
+		}
+		if params.Action != "" && e.Action != params.Action {
+			continue
+		}
+		if params.TargetType != "" && e.TargetType != params.TargetType {
+			continue
+		}
+		if params.TargetID != 0 && e.TargetID != params.TargetID {
+			continue
+		}
+		if !params.StartDate.IsZero() && e.CreatedAt.Before(params.StartDate) {
+			continue
+		}
+		if !params.EndDate.IsZero() && e.CreatedAt.After(params.EndDate) {
+			continue
+		}
+		events = append(events, e)
+	}
+	from, to := page(len(events), params.Page, params.PageSize)
+	return events[from:to], len(events), nil
+}
+
+type memModelRunRepo struct{ s *MemoryStore }
+
+func (r *memModelRunRepo) List(ctx context.Context, limit, offset int) ([]models.ModelRun, int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	runs := []models.ModelRun{}
+	for i := len(r.s.modelRuns) - 1; i >= 0; i-- {
+		runs = append(runs, *r.s.modelRuns[i])
+	}
+	total := len(runs)
+	from := min(max(offset, 0), total)
+	return runs[from:min(from+limit, total)], total, nil
+}
+
+func (r *memModelRunRepo) GetActive(ctx context.Context) (*models.ModelRun, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, run := range r.s.modelRuns {
+		if run.IsActive {
+			out := *run
+			return &out, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *memModelRunRepo) Create(ctx context.Context, run models.ModelRun) (*models.ModelRun, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	run.ID = r.s.nextID("model_runs")
+	run.CreatedAt = time.Now()
+	stored := run
+	r.s.modelRuns = append(r.s.modelRuns, &stored)
+	return &run, nil
+}
+
+func (r *memModelRunRepo) SetActive(ctx context.Context, id int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	found := false
+	for _, run := range r.s.modelRuns {
+		found = found || run.ID == int64(id)
+	}
+	if !found {
+		return pgx.ErrNoRows
+	}
+	for _, run := range r.s.modelRuns {
+		run.IsActive = run.ID == int64(id)
+	}
+	return nil
+}
+
+func (r *memModelRunRepo) Get(ctx context.Context, id int32) (*models.ModelRun, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, run := range r.s.modelRuns {
+		if run.ID == int64(id) {
+			out := *run
+			return &out, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *memModelRunRepo) SavePrediction(ctx context.Context, p models.AssessmentPrediction) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	p.CreatedAt = time.Now()
+	for i, existing := range r.s.rescores {
+		if existing.AssessmentID == p.AssessmentID && existing.ModelRunID == p.ModelRunID {
+			r.s.rescores[i] = p
+			return nil
+		}
+	}
+	r.s.rescores = append(r.s.rescores, p)
+	return nil
+}
+
+func (r *memModelRunRepo) ListPredictions(ctx context.Context, runID int32, limit, offset int) ([]models.AssessmentPrediction, int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.AssessmentPrediction{}
+	for _, p := range r.s.rescores {
+		// Deleted assessments take their re-scores with them, as in Postgres
+		if _, ok := r.s.assessments[p.AssessmentID]; ok && p.ModelRunID == int64(runID) {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].AssessmentID < out[j].AssessmentID })
+	total := len(out)
+	from := min(max(offset, 0), total)
+	return out[from:min(from+limit, total)], total, nil
+}
+
+func (r *memModelRunRepo) Distribution(ctx context.Context, version, datasetHash string, since time.Time) (models.PredictionDistribution, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	counts := map[string]int{}
+	var riskSum int64
+	for _, a := range r.s.assessments {
+		switch {
+		case a.Draft() || r.s.superseded(a) || a.ModelVersion != version || a.DatasetHash != datasetHash || a.CreatedAt.Before(since):
+		case a.Cluster == "" || a.Cluster == "error" || a.Cluster == "unknown" || a.Cluster == models.ClusterPendingPrediction:
+		default:
+			counts[a.Cluster]++
+			riskSum += int64(a.RiskScore)
+		}
+	}
+	return newDistribution(counts, riskSum), nil
+}
+
+type memPredictionCacheRepo struct{ s *MemoryStore }
+
+func (r *memPredictionCacheRepo) Get(ctx context.Context, key string) (*models.CachedPrediction, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	p, ok := r.s.predictions[key]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func (r *memPredictionCacheRepo) Put(ctx context.Context, key string, p models.CachedPrediction) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.predictions[key] = p
+	return nil
+}
+
+type memWebhookRepo struct{ s *MemoryStore }
+
+// public copies a stored webhook without its secret
+func (r *memWebhookRepo) public(w *models.Webhook) *models.Webhook {
+	out := *w
+	out.Events = append([]string{}, w.Events...)
+	out.Secret = ""
+	return &out
+}
+
+func (r *memWebhookRepo) find(id int64) *models.Webhook {
+	for _, w := range r.s.webhooks {
+		if w.ID == id {
+			return w
+		}
+	}
+	return nil
+}
+
+func (r *memWebhookRepo) List(ctx context.Context) ([]models.Webhook, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.Webhook{}
+	for _, w := range r.s.webhooks {
+		out = append(out, *r.public(w))
+	}
+	return out, nil
+}
+
+func (r *memWebhookRepo) Get(ctx context.Context, id int64) (*models.Webhook, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	w := r.find(id)
+	if w == nil {
+		return nil, pgx.ErrNoRows
+	}
+	return r.public(w), nil
+}
+
+func (r *memWebhookRepo) Create(ctx context.Context, w models.Webhook) (*models.Webhook, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	w.ID = r.s.nextID("webhooks")
+	w.CreatedAt = time.Now()
+	w.UpdatedAt = w.CreatedAt
+	stored := w
+	r.s.webhooks = append(r.s.webhooks, &stored)
+	return &w, nil
+}
+
+func (r *memWebhookRepo) Update(ctx context.Context, w models.Webhook) (*models.Webhook, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stored := r.find(w.ID)
+	if stored == nil {
+		return nil, pgx.ErrNoRows
+	}
+	stored.URL, stored.Description, stored.Events, stored.Active = w.URL, w.Description, w.Events, w.Active
+	stored.UpdatedAt = time.Now()
+	return r.public(stored), nil
+}
+
+func (r *memWebhookRepo) Delete(ctx context.Context, id int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for i, w := range r.s.webhooks {
+		if w.ID == id {
+			r.s.webhooks = append(r.s.webhooks[:i], r.s.webhooks[i+1:]...)
+			kept := r.s.deliveries[:0]
+			for _, d := range r.s.deliveries {
+				if d.WebhookID != id {
+					kept = append(kept, d)
+				}
+			}
+			r.s.deliveries = kept
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (r *memWebhookRepo) Enqueue(ctx context.Context, event string, payload []byte) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	n := 0
+	for _, w := range r.s.webhooks {
+		if !w.Active || !slices.Contains(w.Events, event) {
+			continue
+		}
+		r.s.deliveries = append(r.s.deliveries, &models.WebhookDelivery{
+			ID:            r.s.nextID("webhook_deliveries"),
+			WebhookID:     w.ID,
+			Event:         event,
+			Payload:       payload,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		n++
+	}
+	return n, nil
+}
+
+func (r *memWebhookRepo) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	out := []models.WebhookDelivery{}
+	for _, d := range r.s.deliveries {
+		if len(out) == limit {
+			break
+		}
+		if d.Status != models.WebhookDeliveryPending || d.NextAttemptAt.After(now) {
+			continue
+		}
+		d.NextAttemptAt = now.Add(lease)
+		claimed := *d
+		w := r.find(d.WebhookID)
+		claimed.URL, claimed.Secret = w.URL, w.Secret
+		out = append(out, claimed)
+	}
+	return out, nil
+}
+
+func (r *memWebhookRepo) RecordAttempt(ctx context.Context, d models.WebhookDelivery) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, stored := range r.s.deliveries {
+		if stored.ID != d.ID {
+			continue
+		}
+		stored.Status, stored.Attempts, stored.NextAttemptAt = d.Status, d.Attempts, d.NextAttemptAt
+		stored.LastError, stored.LastStatusCode = d.LastError, d.LastStatusCode
+		stored.DeliveredAt = nil
+		if d.Status == models.WebhookDeliveryDelivered {
+			now := time.Now()
+			stored.DeliveredAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *memWebhookRepo) ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]models.WebhookDelivery, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.WebhookDelivery{}
+	for i := len(r.s.deliveries) - 1; i >= 0 && len(out) < limit; i-- {
+		if d := r.s.deliveries[i]; d.WebhookID == webhookID {
+			out = append(out, *d)
+		}
+	}
+	return out, nil
+}
+
+type memAssessmentDraftRepo struct{ s *MemoryStore }
+
+func (r *memAssessmentDraftRepo) Create(ctx context.Context, d models.AssessmentDraft) (*models.AssessmentDraft, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.drafts {
+		if existing.Source == d.Source && existing.MessageControlID == d.MessageControlID && existing.PatientID == d.PatientID {
+			return nil, ErrConflict
+		}
+	}
+	d.ID = r.s.nextID("assessment_drafts")
+	d.Status = models.DraftPending
+	d.AssessmentID, d.ResolvedAt = nil, nil
+	d.CreatedAt = time.Now()
+	stored := d
+	r.s.drafts = append(r.s.drafts, &stored)
+	return &d, nil
+}
+
+func (r *memAssessmentDraftRepo) ListPending(ctx context.Context, patientID int64) ([]models.AssessmentDraft, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.AssessmentDraft{}
+	for i := len(r.s.drafts) - 1; i >= 0; i-- {
+		if d := r.s.drafts[i]; d.PatientID == patientID && d.Status == models.DraftPending {
+			out = append(out, *d)
+		}
+	}
+	return out, nil
+}
+
+func (r *memAssessmentDraftRepo) resolve(id, patientID int64, status string, assessmentID *int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, d := range r.s.drafts {
+		if d.ID == id && d.PatientID == patientID && d.Status == models.DraftPending {
+			now := time.Now()
+			d.Status, d.AssessmentID, d.ResolvedAt = status, assessmentID, &now
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (r *memAssessmentDraftRepo) Discard(ctx context.Context, id, patientID int64) error {
+	return r.resolve(id, patientID, models.DraftDiscarded, nil)
+}
+
+func (r *memAssessmentDraftRepo) Complete(ctx context.Context, id, patientID, assessmentID int64) error {
+	return r.resolve(id, patientID, models.DraftCompleted, &assessmentID)
+}
+
+type memMFARepo struct{ s *MemoryStore }
+
+// enrollment returns a copy of the user's enrollment with the backup code
+// count; callers hold the lock.
+func (r *memMFARepo) enrollment(userID int64) (*models.MFAEnrollment, error) {
+	e, ok := r.s.mfa[userID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	out := *e
+	out.BackupCodesLeft = 0
+	for _, used := range r.s.backupCodes[userID] {
+		if !used {
+			out.BackupCodesLeft++
+		}
+	}
+	return &out, nil
+}
+
+func (r *memMFARepo) Get(ctx context.Context, userID int64) (*models.MFAEnrollment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.enrollment(userID)
+}
+
+func (r *memMFARepo) Begin(ctx context.Context, userID int64, secret string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if e, ok := r.s.mfa[userID]; ok && e.EnabledAt != nil {
+		return ErrConflict
+	}
+	r.s.mfa[userID] = &models.MFAEnrollment{UserID: userID, Secret: secret, CreatedAt: time.Now()}
+	return nil
+}
+
+func (r *memMFARepo) Enable(ctx context.Context, userID int64, step int64, codeHashes []string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	e, ok := r.s.mfa[userID]
+	if !ok || e.EnabledAt != nil {
+		return pgx.ErrNoRows
+	}
+	now := time.Now()
+	e.EnabledAt, e.LastStep = &now, step
+	r.s.replaceBackupCodes(userID, codeHashes)
+	return nil
+}
+
+func (r *memMFARepo) UseStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	e, ok := r.s.mfa[userID]
+	if !ok || e.EnabledAt == nil || e.LastStep >= step {
+		return false, nil
+	}
+	e.LastStep = step
+	return true, nil
+}
+
+func (r *memMFARepo) UseBackupCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	codes := r.s.backupCodes[userID]
+	if used, ok := codes[codeHash]; !ok || used {
+		return false, nil
+	}
+	codes[codeHash] = true
+	return true, nil
+}
+
+func (r *memMFARepo) ReplaceBackupCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.replaceBackupCodes(userID, codeHashes)
+	return nil
+}
+
+// replaceBackupCodes swaps the user's backup codes; callers hold the write
+// lock.
+func (s *MemoryStore) replaceBackupCodes(userID int64, codeHashes []string) {
+	codes := map[string]bool{}
+	for _, h := range codeHashes {
+		codes[h] = false
+	}
+	s.backupCodes[userID] = codes
+}
+
+func (r *memMFARepo) Disable(ctx context.Context, userID int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.mfa[userID]; !ok {
+		return pgx.ErrNoRows
+	}
+	delete(r.s.mfa, userID)
+	delete(r.s.backupCodes, userID)
+	return nil
+}
+
+func (r *memMFARepo) RequiredRoles(ctx context.Context) ([]string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return append([]string{}, r.s.mfaRoles...), nil
+}
+
+func (r *memMFARepo) SetRequiredRoles(ctx context.Context, roles []string, updatedBy int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.mfaRoles = append([]string{}, roles...)
+	sort.Strings(r.s.mfaRoles)
+	return nil
+}
+
+type memRoleRepo struct{ s *MemoryStore }
+
+func (r *memRoleRepo) List(ctx context.Context) ([]models.Role, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := make([]models.Role, 0, len(models.RoleScopes))
+	for name, scope := range models.RoleScopes {
+		out = append(out, models.Role{Name: name, Scope: scope, Permissions: append([]string{}, r.s.permissions[name]...)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (r *memRoleRepo) HasPermission(ctx context.Context, role, permission string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return slices.Contains(r.s.permissions[role], permission), nil
+}
+
+func (r *memRoleRepo) SetPermissions(ctx context.Context, role string, permissions []string, updatedBy int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.permissions[role] = slices.Compact(slices.Sorted(slices.Values(permissions)))
+	return nil
+}
+
+type memLoginHistoryRepo struct{ s *MemoryStore }
+
+func (r *memLoginHistoryRepo) Record(ctx context.Context, a models.LoginAttempt) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	a.ID = r.s.nextID("login_history")
+	a.CreatedAt = time.Now()
+	r.s.logins = append(r.s.logins, a)
+	return nil
+}
+
+func (r *memLoginHistoryRepo) List(ctx context.Context, params models.LoginHistoryParams) ([]models.LoginAttempt, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	limit := params.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	out := []models.LoginAttempt{}
+	for i := len(r.s.logins) - 1; i >= 0 && len(out) < limit; i-- {
+		a := r.s.logins[i]
+		if params.UserID != nil && (a.UserID == nil || *a.UserID != *params.UserID) ||
+			params.IPAddress != "" && a.IPAddress != params.IPAddress ||
+			params.FailedOnly && a.Success ||
+			params.Since != nil && a.CreatedAt.Before(*params.Since) {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func (r *memLoginHistoryRepo) SuspiciousIPs(ctx context.Context, since time.Time, minFailures int) ([]models.SuspiciousIP, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	byIP := map[string]*models.SuspiciousIP{}
+	emails := map[string]map[string]bool{}
+	for _, a := range r.s.logins {
+		if a.Success || a.CreatedAt.Before(since) {
+			continue
+		}
+		ip := byIP[a.IPAddress]
+		if ip == nil {
+			ip = &models.SuspiciousIP{IPAddress: a.IPAddress}
+			byIP[a.IPAddress] = ip
+			emails[a.IPAddress] = map[string]bool{}
+		}
+		ip.Failures++
+		emails[a.IPAddress][strings.ToLower(a.Email)] = true
+		if a.CreatedAt.After(ip.LastAttempt) {
+			ip.LastAttempt = a.CreatedAt
+		}
+	}
+	out := []models.SuspiciousIP{}
+	for addr, ip := range byIP {
+		if ip.Failures >= minFailures {
+			ip.Accounts = len(emails[addr])
+			out = append(out, *ip)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Failures != out[j].Failures {
+			return out[i].Failures > out[j].Failures
+		}
+		return out[i].IPAddress < out[j].IPAddress
+	})
+	return out, nil
+}
+
+type memPatientListViewRepo struct{ s *MemoryStore }
+
+// copyView returns a copy of v that does not share its filters; callers hold
+// the lock.
+func copyView(v *models.PatientListView) models.PatientListView {
+	out := *v
+	out.Filters = make(map[string]string, len(v.Filters))
+	for k, val := range v.Filters {
+		out.Filters[k] = val
+	}
+	return out
+}
+
+func (r *memPatientListViewRepo) List(ctx context.Context, userID int64) ([]models.PatientListView, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.PatientListView{}
+	for _, v := range r.s.listViews {
+		if v.UserID == userID {
+			out = append(out, copyView(v))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := strings.ToLower(out[i].Name), strings.ToLower(out[j].Name)
+		if a != b {
+			return a < b
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+// find returns the index of the stored view; callers hold the lock.
+func (r *memPatientListViewRepo) find(id, userID int64) (int, error) {
+	for i, v := range r.s.listViews {
+		if v.ID == id && v.UserID == userID {
+			return i, nil
+		}
+	}
+	return 0, pgx.ErrNoRows
+}
+
+func (r *memPatientListViewRepo) Get(ctx context.Context, id, userID int64) (*models.PatientListView, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	i, err := r.find(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := copyView(r.s.listViews[i])
+	return &out, nil
+}
+
+func (r *memPatientListViewRepo) Default(ctx context.Context, userID int64) (*models.PatientListView, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, v := range r.s.listViews {
+		if v.UserID == userID && v.IsDefault {
+			out := copyView(v)
+			return &out, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+// claim checks v's name is free and, if v is to be the default, clears the
+// user's current one; callers hold the write lock.
+func (r *memPatientListViewRepo) claim(v models.PatientListView) error {
+	for _, other := range r.s.listViews {
+		if other.UserID == v.UserID && other.ID != v.ID && strings.EqualFold(other.Name, v.Name) {
+			return ErrConflict
+		}
+	}
+	if v.IsDefault {
+		for _, other := range r.s.listViews {
+			if other.UserID == v.UserID && other.ID != v.ID {
+				other.IsDefault = false
+			}
+		}
+	}
+	return nil
+}
+
+func (r *memPatientListViewRepo) Create(ctx context.Context, v models.PatientListView) (*models.PatientListView, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if err := r.claim(v); err != nil {
+		return nil, err
+	}
+	v.ID = r.s.nextID("patient_list_views")
+	v.CreatedAt = time.Now()
+	v.UpdatedAt = v.CreatedAt
+	stored := copyView(&v)
+	r.s.listViews = append(r.s.listViews, &stored)
+	return &v, nil
+}
+
+func (r *memPatientListViewRepo) Update(ctx context.Context, v models.PatientListView) (*models.PatientListView, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	i, err := r.find(v.ID, v.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.claim(v); err != nil {
+		return nil, err
+	}
+	stored := r.s.listViews[i]
+	stored.Name, stored.IsDefault = v.Name, v.IsDefault
+	stored.Filters = copyView(&v).Filters
+	stored.UpdatedAt = time.Now()
+	out := copyView(stored)
+	return &out, nil
+}
+
+func (r *memPatientListViewRepo) Delete(ctx context.Context, id, userID int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	i, err := r.find(id, userID)
+	if err != nil {
+		return err
+	}
+	r.s.listViews = slices.Delete(r.s.listViews, i, i+1)
+	return nil
+}
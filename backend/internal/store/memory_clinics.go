@@ -0,0 +1,1108 @@
+// memory_clinics.go: In-memory clinics, cohort statistics, credentials and user deletions.
+package store
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+type memClinicRepo struct{ s *MemoryStore }
+
+func (r *memClinicRepo) List(ctx context.Context) ([]models.Clinic, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.Clinic{}
+	for _, c := range r.s.clinics {
+		out = append(out, c.Clinic)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (r *memClinicRepo) Get(ctx context.Context, id int32) (*models.Clinic, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	c, ok := r.s.clinics[int64(id)]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	out := c.Clinic
+	return &out, nil
+}
+
+func (r *memClinicRepo) Create(ctx context.Context, name, address string) (*models.Clinic, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	c := &memClinic{
+		Clinic:         models.Clinic{ID: r.s.nextID("clinics"), Name: name, Address: address, CreatedAt: now, UpdatedAt: now},
+		validationMode: models.ValidationModeAdvisory,
+		photosEnabled:  true,
+		baselinePolicy: models.BaselinePolicyFlag,
+		locale:         "en",
+	}
+	r.s.clinics[c.ID] = c
+	out := c.Clinic
+	return &out, nil
+}
+
+// clinicsOf returns the user's memberships; callers hold the lock.
+func (s *MemoryStore) clinicsOf(userID int64) []*memMember {
+	var out []*memMember
+	for _, m := range s.members {
+		if m.userID == userID {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// inAnyClinic reports whether the user belongs to a clinic; callers hold the
+// lock.
+func (s *MemoryStore) inAnyClinic(userID int64) bool {
+	for _, m := range s.members {
+		if m.userID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// member returns the user's membership of the clinic, or nil; callers hold
+// the lock.
+func (s *MemoryStore) member(clinicID, userID int64) *memMember {
+	for _, m := range s.members {
+		if m.clinicID == clinicID && m.userID == userID {
+			return m
+		}
+	}
+	return nil
+}
+
+func (r *memClinicRepo) ListUserClinics(ctx context.Context, userID int32) ([]models.UserClinic, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.UserClinic{}
+	for _, m := range r.s.clinicsOf(int64(userID)) {
+		if c, ok := r.s.clinics[m.clinicID]; ok {
+			out = append(out, models.UserClinic{Clinic: c.Clinic, Role: m.role})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (r *memClinicRepo) IsClinicAdmin(ctx context.Context, userID, clinicID int32) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	m := r.s.member(int64(clinicID), int64(userID))
+	return m != nil && m.role == models.ClinicRoleAdmin, nil
+}
+
+func (r *memClinicRepo) ClinicRole(ctx context.Context, userID, clinicID int32) (string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	m := r.s.member(int64(clinicID), int64(userID))
+	if m == nil {
+		return "", pgx.ErrNoRows
+	}
+	return m.role, nil
+}
+
+// riskTotals accumulates the aggregate columns shared by the clinic and
+// system dashboards.
+type riskTotals struct {
+	patients, assessments, highRisk, thisMonth int
+	riskSum                                    float64
+	riskN                                      int
+}
+
+// add counts a toward this month regardless of r, and toward the other
+// assessment columns only when it falls within r. Drafts are not counted.
+func (t *riskTotals) add(a *models.Assessment, monthStart time.Time, r models.StatsRange) {
+	if a.Draft() {
+		return
+	}
+	if !a.CreatedAt.Before(monthStart) {
+		t.thisMonth++
+	}
+	if !r.Contains(a.CreatedAt) {
+		return
+	}
+	t.assessments++
+	if a.RiskScore > 0 {
+		t.riskSum += float64(a.RiskScore)
+		t.riskN++
+	}
+	if a.RiskScore >= 67 {
+		t.highRisk++
+	}
+}
+
+func (t *riskTotals) avgRisk() float64 {
+	if t.riskN == 0 {
+		return 0
+	}
+	return t.riskSum / float64(t.riskN)
+}
+
+func monthStart() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+}
+
+// clinicTotals covers the patients owned by the clinic's members, as the
+// clinic dashboard does; callers hold the lock.
+func (s *MemoryStore) clinicTotals(clinicID int64, r models.StatsRange) riskTotals {
+	var t riskTotals
+	start := monthStart()
+	for _, p := range s.patients {
+		if !s.isMember(clinicID, p.UserID) {
+			continue
+		}
+		t.patients++
+		for _, a := range s.assessments {
+			if a.PatientID == p.ID && !s.superseded(a) {
+				t.add(a, start, r)
+			}
+		}
+	}
+	return t
+}
+
+func (r *memClinicRepo) ClinicAggregate(ctx context.Context, clinicID int32) (*models.ClinicAggregate, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	t := r.s.clinicTotals(int64(clinicID), models.StatsRange{})
+	return &models.ClinicAggregate{
+		TotalPatients:        t.patients,
+		TotalAssessments:     t.assessments,
+		AvgRiskScore:         t.avgRisk(),
+		HighRiskCount:        t.highRisk,
+		AssessmentsThisMonth: t.thisMonth,
+	}, nil
+}
+
+func (r *memClinicRepo) AdminSystemStats(ctx context.Context, sr models.StatsRange) (*models.SystemStats, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var t riskTotals
+	start := monthStart()
+	for _, a := range r.s.assessments {
+		if !r.s.superseded(a) {
+			t.add(a, start, sr)
+		}
+	}
+	newUsers := 0
+	for _, u := range r.s.users {
+		if !u.CreatedAt.Before(start) {
+			newUsers++
+		}
+	}
+	return &models.SystemStats{
+		TotalUsers:           len(r.s.users),
+		TotalPatients:        len(r.s.patients),
+		TotalAssessments:     t.assessments,
+		TotalClinics:         len(r.s.clinics),
+		AvgRiskScore:         t.avgRisk(),
+		HighRiskCount:        t.highRisk,
+		AssessmentsThisMonth: t.thisMonth,
+		NewUsersThisMonth:    newUsers,
+	}, nil
+}
+
+func (r *memClinicRepo) AdminClinicComparison(ctx context.Context, sr models.StatsRange) ([]models.ClinicComparison, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var out []models.ClinicComparison
+	for _, c := range r.s.clinics {
+		t := r.s.clinicTotals(c.ID, sr)
+		out = append(out, models.ClinicComparison{
+			ClinicID:        c.ID,
+			ClinicName:      c.Name,
+			PatientCount:    t.patients,
+			AssessmentCount: t.assessments,
+			AvgRiskScore:    t.avgRisk(),
+			HighRiskCount:   t.highRisk,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].PatientCount > out[j].PatientCount })
+	return out, nil
+}
+
+// clinicSetting reads a clinic's setting, or returns pgx.ErrNoRows.
+func clinicSetting[T any](r *memClinicRepo, clinicID int32, get func(c *memClinic) T) (T, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	c, ok := r.s.clinics[int64(clinicID)]
+	if !ok {
+		var zero T
+		return zero, pgx.ErrNoRows
+	}
+	return get(c), nil
+}
+
+// updateClinic applies fn to the clinic, or returns pgx.ErrNoRows.
+func (r *memClinicRepo) updateClinic(clinicID int32, fn func(c *memClinic)) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c, ok := r.s.clinics[int64(clinicID)]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	fn(c)
+	c.UpdatedAt = time.Now()
+	return nil
+}
+
+// userClinicsAll reports whether every one of the user's clinics passes
+// test; users without a clinic pass.
+func (r *memClinicRepo) userClinicsAll(userID int32, test func(c *memClinic) bool) bool {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, m := range r.s.clinicsOf(int64(userID)) {
+		if c, ok := r.s.clinics[m.clinicID]; ok && !test(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *memClinicRepo) GetValidationMode(ctx context.Context, clinicID int32) (string, error) {
+	return clinicSetting(r, clinicID, func(c *memClinic) string { return c.validationMode })
+}
+
+func (r *memClinicRepo) SetValidationMode(ctx context.Context, clinicID int32, mode string) error {
+	return r.updateClinic(clinicID, func(c *memClinic) { c.validationMode = mode })
+}
+
+func (r *memClinicRepo) ValidationModeForUser(ctx context.Context, userID int32) (string, error) {
+	if r.userClinicsAll(userID, func(c *memClinic) bool { return c.validationMode != models.ValidationModeStrict }) {
+		return models.ValidationModeAdvisory, nil
+	}
+	return models.ValidationModeStrict, nil
+}
+
+func (r *memClinicRepo) ListMembers(ctx context.Context, clinicID int32) ([]models.ClinicMember, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.ClinicMember{}
+	for _, m := range r.s.members {
+		u, ok := r.s.users[m.userID]
+		if m.clinicID != int64(clinicID) || !ok {
+			continue
+		}
+		activity := r.s.activity(u)
+		out = append(out, models.ClinicMember{
+			UserID:           u.ID,
+			Email:            u.Email,
+			ClinicRole:       m.role,
+			GlobalRole:       u.Role,
+			IsActive:         u.IsActive,
+			JoinedAt:         m.joinedAt,
+			LastLoginAt:      u.LastLoginAt,
+			PatientCount:     activity.PatientCount,
+			AssessmentCount:  activity.AssessmentCount,
+			LastAssessmentAt: activity.LastAssessmentAt,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		ai, aj := out[i].ClinicRole == models.ClinicRoleAdmin, out[j].ClinicRole == models.ClinicRoleAdmin
+		if ai != aj {
+			return ai
+		}
+		return out[i].Email < out[j].Email
+	})
+	return out, nil
+}
+
+func (r *memClinicRepo) AddMember(ctx context.Context, clinicID, userID int32, role string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.clinics[int64(clinicID)]; !ok {
+		return fmt.Errorf("clinic %d does not exist", clinicID)
+	}
+	if r.s.member(int64(clinicID), int64(userID)) != nil {
+		return ErrConflict
+	}
+	r.s.members = append(r.s.members, &memMember{clinicID: int64(clinicID), userID: int64(userID), role: role, joinedAt: time.Now()})
+	return nil
+}
+
+func (r *memClinicRepo) RemoveMember(ctx context.Context, clinicID, userID int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for i, m := range r.s.members {
+		if m.clinicID == int64(clinicID) && m.userID == int64(userID) {
+			r.s.members = append(r.s.members[:i], r.s.members[i+1:]...)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (r *memClinicRepo) SetMemberRole(ctx context.Context, clinicID, userID int32, role string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	m := r.s.member(int64(clinicID), int64(userID))
+	if m == nil {
+		return pgx.ErrNoRows
+	}
+	m.role = role
+	return nil
+}
+
+func (r *memClinicRepo) GetPatientPhotosEnabled(ctx context.Context, clinicID int32) (bool, error) {
+	return clinicSetting(r, clinicID, func(c *memClinic) bool { return c.photosEnabled })
+}
+
+func (r *memClinicRepo) SetPatientPhotosEnabled(ctx context.Context, clinicID int32, enabled bool) error {
+	return r.updateClinic(clinicID, func(c *memClinic) { c.photosEnabled = enabled })
+}
+
+func (r *memClinicRepo) PatientPhotosEnabledForUser(ctx context.Context, userID int32) (bool, error) {
+	return r.userClinicsAll(userID, func(c *memClinic) bool { return c.photosEnabled }), nil
+}
+
+func (r *memClinicRepo) GetBaselinePolicy(ctx context.Context, clinicID int32) (string, error) {
+	return clinicSetting(r, clinicID, func(c *memClinic) string { return c.baselinePolicy })
+}
+
+func (r *memClinicRepo) SetBaselinePolicy(ctx context.Context, clinicID int32, policy string) error {
+	return r.updateClinic(clinicID, func(c *memClinic) { c.baselinePolicy = policy })
+}
+
+func (r *memClinicRepo) BaselinePolicyForUser(ctx context.Context, userID int32) (string, error) {
+	r.s.mu.RLock()
+	none := len(r.s.clinicsOf(int64(userID))) == 0
+	r.s.mu.RUnlock()
+	if none || !r.userClinicsAll(userID, func(c *memClinic) bool { return c.baselinePolicy == models.BaselinePolicyUpdate }) {
+		return models.BaselinePolicyFlag, nil
+	}
+	return models.BaselinePolicyUpdate, nil
+}
+
+func (r *memClinicRepo) GetReportSettings(ctx context.Context, clinicID int32) (*models.ClinicReportSettings, error) {
+	return clinicSetting(r, clinicID, func(c *memClinic) *models.ClinicReportSettings {
+		return &models.ClinicReportSettings{
+			ClinicID: c.ID,
+			Name:     c.Name,
+			Address:  c.Address,
+			Locale:   c.locale,
+			HasLogo:  len(c.logo) > 0,
+			Logo:     c.logo,
+		}
+	})
+}
+
+func (r *memClinicRepo) SetReportLocale(ctx context.Context, clinicID int32, locale string) error {
+	return r.updateClinic(clinicID, func(c *memClinic) { c.locale = locale })
+}
+
+func (r *memClinicRepo) SetReportLogo(ctx context.Context, clinicID int32, logo []byte) error {
+	return r.updateClinic(clinicID, func(c *memClinic) { c.logo = logo })
+}
+
+func (r *memClinicRepo) SharedClinics(ctx context.Context, userIDs ...int32) ([]int32, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []int32{}
+	if len(userIDs) == 0 {
+		return out, nil
+	}
+	for _, m := range r.s.clinicsOf(int64(userIDs[0])) {
+		all := true
+		for _, id := range userIDs[1:] {
+			all = all && r.s.isMember(m.clinicID, int64(id))
+		}
+		if all {
+			out = append(out, int32(m.clinicID))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out, nil
+}
+
+type memCohortRepo struct{ s *MemoryStore }
+
+// inScope reports whether the assessment's patient falls within scope, as
+// cohortScopeSQL does; callers hold the lock.
+func (s *MemoryStore) inScope(a *models.Assessment, scope models.CohortScope) bool {
+	if scope.IsZero() {
+		return true
+	}
+	p, ok := s.patients[a.PatientID]
+	return ok && s.patientInScope(p, scope)
+}
+
+// patientInScope reports whether the patient falls within scope; callers
+// hold the lock.
+func (s *MemoryStore) patientInScope(p *models.Patient, scope models.CohortScope) bool {
+	if scope.UserID != nil && p.UserID != int64(*scope.UserID) {
+		return false
+	}
+	if scope.TagID != nil && !s.patientTags[*scope.TagID][p.ID] {
+		return false
+	}
+	if len(scope.ClinicIDs) > 0 && !slices.ContainsFunc(scope.ClinicIDs, func(id int32) bool { return s.isMember(int64(id), p.UserID) }) {
+		return false
+	}
+	return scope.ClinicID == nil || s.isMember(int64(*scope.ClinicID), p.UserID)
+}
+
+// cohortGroup names the assessment's group along a cohort dimension,
+// mirroring cohortGroupExprs; callers hold the lock.
+func (s *MemoryStore) cohortGroup(a *models.Assessment, groupBy string) string {
+	p := s.patients[a.PatientID]
+	switch groupBy {
+	case "cluster":
+		if a.Cluster == "" {
+			return "Unknown"
+		}
+		return a.Cluster
+	case "risk_level":
+		switch {
+		case a.RiskScore < 34:
+			return "Low"
+		case a.RiskScore < 67:
+			return "Moderate"
+		}
+		return "High"
+	case "age_group":
+		switch {
+		case p == nil || p.Age >= 65:
+			return "65+"
+		case p.Age < 45:
+			return "Under 45"
+		case p.Age < 55:
+			return "45-54"
+		}
+		return "55-64"
+	case "menopause_status":
+		if p == nil || p.MenopauseStatus == "" {
+			return "Unknown"
+		}
+		return p.MenopauseStatus
+	}
+	return ""
+}
+
+// riskLevel indexes CohortSample.RiskLevelCounts
+func riskLevel(score int) int {
+	switch {
+	case score < 34:
+		return 0
+	case score < 67:
+		return 1
+	}
+	return 2
+}
+
+func (r *memCohortRepo) stats(groupBy string, scope models.CohortScope) ([]models.CohortGroup, error) {
+	if _, ok := cohortGroupExprs[groupBy]; !ok {
+		return nil, fmt.Errorf("unknown cohort dimension %q", groupBy)
+	}
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	type acc struct {
+		group models.CohortGroup
+		sums  map[string]float64
+	}
+	groups := map[string]*acc{}
+	for _, a := range r.s.assessments {
+		if a.Draft() || r.s.superseded(a) || !r.s.inScope(a, scope) {
+			continue
+		}
+		name := r.s.cohortGroup(a, groupBy)
+		g, ok := groups[name]
+		if !ok {
+			g = &acc{group: models.CohortGroup{Name: name}, sums: map[string]float64{}}
+			groups[name] = g
+		}
+		g.group.Count++
+		for _, m := range cohortMetrics {
+			v, _ := biomarker(a, m)
+			g.sums[m] += v
+		}
+		switch riskLevel(a.RiskScore) {
+		case 0:
+			g.group.LowRiskCount++
+		case 1:
+			g.group.ModerateRiskCount++
+		default:
+			g.group.HighRiskCount++
+		}
+	}
+	out := []models.CohortGroup{}
+	for _, g := range groups {
+		n := float64(g.group.Count)
+		g.group.AvgHbA1c = g.sums["hba1c"] / n
+		g.group.AvgFBS = g.sums["fbs"] / n
+		g.group.AvgBMI = g.sums["bmi"] / n
+		g.group.AvgBPSystolic = g.sums["systolic"] / n
+		g.group.AvgBPDiastolic = g.sums["diastolic"] / n
+		g.group.AvgRiskScore = g.sums["risk_score"] / n
+		out = append(out, g.group)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (r *memCohortRepo) StatsByCluster(ctx context.Context) ([]models.CohortGroup, error) {
+	return r.stats("cluster", models.CohortScope{})
+}
+
+func (r *memCohortRepo) StatsByRiskLevel(ctx context.Context) ([]models.CohortGroup, error) {
+	return r.stats("risk_level", models.CohortScope{})
+}
+
+func (r *memCohortRepo) StatsByAgeGroup(ctx context.Context) ([]models.CohortGroup, error) {
+	return r.stats("age_group", models.CohortScope{})
+}
+
+func (r *memCohortRepo) StatsByMenopauseStatus(ctx context.Context) ([]models.CohortGroup, error) {
+	return r.stats("menopause_status", models.CohortScope{})
+}
+
+func (r *memCohortRepo) TotalPatientCount(ctx context.Context) (int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return len(r.s.patients), nil
+}
+
+func (r *memCohortRepo) TotalAssessmentCount(ctx context.Context) (int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	n := 0
+	for _, a := range r.s.assessments {
+		if !a.Draft() && !r.s.superseded(a) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *memCohortRepo) GroupSample(ctx context.Context, groupBy, name string, scope models.CohortScope) (*models.CohortSample, error) {
+	if _, ok := cohortGroupExprs[groupBy]; !ok {
+		return nil, fmt.Errorf("unknown cohort dimension %q", groupBy)
+	}
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	values := map[string][]float64{}
+	sample := models.CohortSample{Name: name, Metrics: make(map[string]models.MetricMoments, len(cohortMetrics))}
+	for _, a := range r.s.assessments {
+		if a.Draft() || r.s.superseded(a) || !r.s.inScope(a, scope) || r.s.cohortGroup(a, groupBy) != name {
+			continue
+		}
+		sample.Count++
+		sample.RiskLevelCounts[riskLevel(a.RiskScore)]++
+		for _, m := range cohortMetrics {
+			if v, ok := biomarker(a, m); ok {
+				values[m] = append(values[m], v)
+			}
+		}
+	}
+	for _, m := range cohortMetrics {
+		vs := values[m]
+		mm := models.MetricMoments{N: len(vs)}
+		for _, v := range vs {
+			mm.Mean += v
+		}
+		if mm.N > 0 {
+			mm.Mean /= float64(mm.N)
+		}
+		// Sample standard deviation, as STDDEV_SAMP
+		if mm.N > 1 {
+			var ss float64
+			for _, v := range vs {
+				ss += (v - mm.Mean) * (v - mm.Mean)
+			}
+			mm.StdDev = math.Sqrt(ss / float64(mm.N-1))
+		}
+		sample.Metrics[m] = mm
+	}
+	return &sample, nil
+}
+
+func (r *memCohortRepo) ScopedStats(ctx context.Context, groupBy string, scope models.CohortScope) ([]models.CohortGroup, error) {
+	return r.stats(groupBy, scope)
+}
+
+func (r *memCohortRepo) ScopedTotals(ctx context.Context, scope models.CohortScope) (int, int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	patients, assessments := 0, 0
+	for _, p := range r.s.patients {
+		if !r.s.patientInScope(p, scope) {
+			continue
+		}
+		patients++
+		for _, a := range r.s.assessments {
+			if a.PatientID == p.ID && !a.Draft() && !r.s.superseded(a) {
+				assessments++
+			}
+		}
+	}
+	return patients, assessments, nil
+}
+
+type memEmailVerificationRepo struct{ s *MemoryStore }
+
+func (r *memEmailVerificationRepo) Register(ctx context.Context, user models.User, tokenHash string, expiresAt time.Time) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, err := r.s.createUser(user, false)
+	if err != nil {
+		return nil, err
+	}
+	r.s.verifications[tokenHash] = &memToken{userID: u.ID, expiresAt: expiresAt}
+	return u, nil
+}
+
+func (r *memEmailVerificationRepo) CreateToken(ctx context.Context, userID int32, tokenHash string, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.verifications[tokenHash] = &memToken{userID: int64(userID), expiresAt: expiresAt}
+	return nil
+}
+
+// consume marks an unused, unexpired token used and returns it, or returns
+// pgx.ErrNoRows; callers hold the write lock.
+func consume(tokens map[string]*memToken, hash string) (*memToken, error) {
+	t, ok := tokens[hash]
+	if !ok || t.used || !t.expiresAt.After(time.Now()) {
+		return nil, pgx.ErrNoRows
+	}
+	t.used = true
+	return t, nil
+}
+
+func (r *memEmailVerificationRepo) Verify(ctx context.Context, tokenHash string) (int32, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	t, err := consume(r.s.verifications, tokenHash)
+	if err != nil {
+		return 0, err
+	}
+	if u, ok := r.s.users[t.userID]; ok && u.EmailVerifiedAt == nil {
+		now := time.Now()
+		u.EmailVerifiedAt = &now
+	}
+	return int32(t.userID), nil
+}
+
+type memPasswordResetRepo struct{ s *MemoryStore }
+
+func (r *memPasswordResetRepo) CreateToken(ctx context.Context, userID int32, tokenHash string, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.resets[tokenHash] = &memToken{userID: int64(userID), expiresAt: expiresAt}
+	return nil
+}
+
+func (r *memPasswordResetRepo) Reset(ctx context.Context, tokenHash, passwordHash string) (int32, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	t, err := consume(r.s.resets, tokenHash)
+	if err != nil {
+		return 0, err
+	}
+	if u, ok := r.s.users[t.userID]; ok {
+		u.PasswordHash = passwordHash
+		u.UpdatedAt = time.Now()
+	}
+	r.s.revokeUserTokens(t.userID)
+	return int32(t.userID), nil
+}
+
+type memAPITokenRepo struct{ s *MemoryStore }
+
+func (r *memAPITokenRepo) Create(ctx context.Context, token models.APIToken) (*models.APIToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, t := range r.s.apiTokens {
+		if t.TokenHash == token.TokenHash {
+			return nil, ErrConflict
+		}
+	}
+	token.ID = r.s.nextID("api_tokens")
+	token.CreatedAt = time.Now()
+	stored := token
+	r.s.apiTokens = append(r.s.apiTokens, &stored)
+	return &token, nil
+}
+
+func (r *memAPITokenRepo) List(ctx context.Context) ([]models.APIToken, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.APIToken{}
+	for i := len(r.s.apiTokens) - 1; i >= 0; i-- {
+		out = append(out, *r.s.apiTokens[i])
+	}
+	return out, nil
+}
+
+func (r *memAPITokenRepo) Revoke(ctx context.Context, id int64) (*models.APIToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, t := range r.s.apiTokens {
+		if t.ID == id && t.RevokedAt == nil {
+			now := time.Now()
+			t.RevokedAt = &now
+			out := *t
+			return &out, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *memAPITokenRepo) FindActive(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	now := time.Now()
+	for _, t := range r.s.apiTokens {
+		if t.TokenHash == tokenHash && t.RevokedAt == nil && (t.ExpiresAt == nil || t.ExpiresAt.After(now)) {
+			out := *t
+			return &out, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *memAPITokenRepo) MarkUsed(ctx context.Context, id int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, t := range r.s.apiTokens {
+		if t.ID == id {
+			now := time.Now()
+			t.LastUsedAt = &now
+		}
+	}
+	return nil
+}
+
+type memAPIKeyRepo struct{ s *MemoryStore }
+
+func (r *memAPIKeyRepo) Create(ctx context.Context, key models.APIKey) (*models.APIKey, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, k := range r.s.apiKeys {
+		if k.KeyHash == key.KeyHash {
+			return nil, ErrConflict
+		}
+	}
+	key.ID = r.s.nextID("api_keys")
+	key.CreatedAt = time.Now()
+	stored := key
+	r.s.apiKeys = append(r.s.apiKeys, &stored)
+	return &key, nil
+}
+
+func (r *memAPIKeyRepo) List(ctx context.Context, userID int64) ([]models.APIKey, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.APIKey{}
+	for i := len(r.s.apiKeys) - 1; i >= 0; i-- {
+		if userID == 0 || r.s.apiKeys[i].UserID == userID {
+			out = append(out, *r.s.apiKeys[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *memAPIKeyRepo) Revoke(ctx context.Context, id int64) (*models.APIKey, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, k := range r.s.apiKeys {
+		if k.ID == id && k.RevokedAt == nil {
+			now := time.Now()
+			k.RevokedAt = &now
+			out := *k
+			return &out, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *memAPIKeyRepo) FindActive(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	now := time.Now()
+	for _, k := range r.s.apiKeys {
+		if k.KeyHash == keyHash && k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(now)) {
+			out := *k
+			return &out, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *memAPIKeyRepo) MarkUsed(ctx context.Context, id int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, k := range r.s.apiKeys {
+		if k.ID == id {
+			now := time.Now()
+			k.LastUsedAt = &now
+		}
+	}
+	return nil
+}
+
+type memUserDeletionRepo struct{ s *MemoryStore }
+
+// withEmail returns the deletion with the user's current email; callers hold
+// the lock.
+func (s *MemoryStore) withEmail(d *models.UserDeletion) models.UserDeletion {
+	out := *d
+	if u, ok := s.users[d.UserID]; ok {
+		out.Email = u.Email
+	}
+	return out
+}
+
+func (r *memUserDeletionRepo) Schedule(ctx context.Context, userID int64, requestedBy string, dueAt time.Time) (*models.UserDeletion, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, d := range r.s.deletions {
+		if d.UserID == userID && d.Status == models.DeletionPending {
+			out := r.s.withEmail(d)
+			return &out, nil
+		}
+	}
+	d := &models.UserDeletion{
+		ID:          r.s.nextID("user_deletions"),
+		UserID:      userID,
+		RequestedBy: requestedBy,
+		RequestedAt: time.Now(),
+		DueAt:       dueAt,
+		Status:      models.DeletionPending,
+	}
+	r.s.deletions = append(r.s.deletions, d)
+	out := r.s.withEmail(d)
+	return &out, nil
+}
+
+func (r *memUserDeletionRepo) List(ctx context.Context, status string) ([]models.UserDeletion, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.UserDeletion{}
+	for i := len(r.s.deletions) - 1; i >= 0; i-- {
+		if d := r.s.deletions[i]; status == "" || d.Status == status {
+			out = append(out, r.s.withEmail(d))
+		}
+	}
+	return out, nil
+}
+
+// resolve moves a pending deletion to status; callers hold the write lock.
+func (s *MemoryStore) resolve(d *models.UserDeletion, status, by string) models.UserDeletion {
+	now := time.Now()
+	d.Status, d.ResolvedBy, d.ResolvedAt = status, by, &now
+	return s.withEmail(d)
+}
+
+func (r *memUserDeletionRepo) Cancel(ctx context.Context, id int64, by string) (*models.UserDeletion, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, d := range r.s.deletions {
+		if d.ID == id && d.Status == models.DeletionPending {
+			out := r.s.resolve(d, models.DeletionCancelled, by)
+			return &out, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *memUserDeletionRepo) CancelForUser(ctx context.Context, userID int64, by string) (*models.UserDeletion, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, d := range r.s.deletions {
+		if d.UserID == userID && d.Status == models.DeletionPending {
+			out := r.s.resolve(d, models.DeletionCancelled, by)
+			return &out, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memUserDeletionRepo) Due(ctx context.Context, now time.Time) ([]models.UserDeletion, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.UserDeletion{}
+	for _, d := range r.s.deletions {
+		if d.Status == models.DeletionPending && !d.DueAt.After(now) {
+			out = append(out, r.s.withEmail(d))
+		}
+	}
+	return out, nil
+}
+
+func (r *memUserDeletionRepo) Purge(ctx context.Context, d models.UserDeletion, mode, by string) (*models.UserPurge, error) {
+	if mode != models.RetentionAnonymize && mode != models.RetentionDelete {
+		return nil, fmt.Errorf("unknown retention mode %q", mode)
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var stored *models.UserDeletion
+	for _, cand := range r.s.deletions {
+		if cand.ID == d.ID {
+			stored = cand
+		}
+	}
+	if stored == nil {
+		return nil, pgx.ErrNoRows
+	}
+	if stored.Status != models.DeletionPending {
+		return nil, nil
+	}
+	if u, ok := r.s.users[stored.UserID]; ok && u.IsActive {
+		r.s.resolve(stored, models.DeletionCancelled, by)
+		return nil, nil
+	}
+
+	for _, p := range r.s.patients {
+		if p.UserID == stored.UserID && (p.ClinicID != nil || r.s.inAnyClinic(stored.UserID)) {
+			return nil, ErrClinicPatients
+		}
+	}
+
+	purge := &models.UserPurge{PhotoKeys: []string{}, AttachmentKeys: []string{}}
+	for id, p := range r.s.patients {
+		if p.UserID != stored.UserID {
+			continue
+		}
+		purge.Patients++
+		if photo, ok := r.s.photos[id]; ok {
+			purge.PhotoKeys = append(purge.PhotoKeys, photo.StorageKey)
+			delete(r.s.photos, id)
+		}
+		// Lab reports identify the patient, so they go in either mode
+		for _, a := range r.s.attachments {
+			if a.PatientID == id {
+				purge.AttachmentKeys = append(purge.AttachmentKeys, a.StorageKey)
+			}
+		}
+		r.s.dropAttachments(func(a *models.AssessmentAttachment) bool { return a.PatientID == id })
+		if mode == models.RetentionDelete {
+			r.s.deletePatient(id)
+			continue
+		}
+		delete(r.s.contacts, id)
+		// Notes are free text and may identify the patient
+		notes := r.s.notes[:0]
+		for _, n := range r.s.notes {
+			if n.PatientID != id {
+				notes = append(notes, n)
+			}
+		}
+		r.s.notes = notes
+		// Follow-up reasons are free text too, and nobody attends to them
+		r.s.dropFollowUps(id)
+		p.Name, p.MRN, p.UpdatedAt = fmt.Sprintf("Deleted patient %d", id), "", time.Now()
+		for i, v := range r.s.versions {
+			if v.PatientID != id {
+				continue
+			}
+			delete(v.Before, "name")
+			delete(v.Before, "mrn")
+			delete(v.After, "name")
+			delete(v.After, "mrn")
+			changes := []models.PatientFieldChange{}
+			for _, c := range v.Changes {
+				if c.Field != "name" && c.Field != "mrn" {
+					changes = append(changes, c)
+				}
+			}
+			r.s.versions[i].Changes = changes
+		}
+	}
+
+	userID := stored.UserID
+	// Manual task titles are free text, and nobody works the inbox any more
+	r.s.dropTasks(func(t *models.Task) bool { return t.UserID == userID })
+	// Tag names are free text too
+	tags := r.s.tags[:0]
+	for _, t := range r.s.tags {
+		if t.UserID == userID {
+			delete(r.s.patientTags, t.ID)
+		} else {
+			tags = append(tags, t)
+		}
+	}
+	r.s.tags = tags
+	// Saved searches can name patients
+	views := r.s.listViews[:0]
+	for _, v := range r.s.listViews {
+		if v.UserID != userID {
+			views = append(views, v)
+		}
+	}
+	r.s.listViews = views
+	r.s.keepTokens(func(t *models.RefreshToken) bool { return t.UserID != userID })
+	keys := r.s.apiKeys[:0]
+	for _, k := range r.s.apiKeys {
+		if k.UserID != userID {
+			keys = append(keys, k)
+		}
+	}
+	r.s.apiKeys = keys
+	delete(r.s.mfa, userID)
+	delete(r.s.backupCodes, userID)
+	for _, tokens := range []map[string]*memToken{r.s.verifications, r.s.resets} {
+		for hash, t := range tokens {
+			if t.userID == userID {
+				delete(tokens, hash)
+			}
+		}
+	}
+	members := r.s.members[:0]
+	for _, m := range r.s.members {
+		if m.userID != userID {
+			members = append(members, m)
+		}
+	}
+	r.s.members = members
+	// Sign-in attempts hold the user's IP addresses
+	logins := r.s.logins[:0]
+	for _, l := range r.s.logins {
+		if l.UserID == nil || *l.UserID != userID {
+			logins = append(logins, l)
+		}
+	}
+	r.s.logins = logins
+	if mode == models.RetentionDelete {
+		for _, u := range r.s.users {
+			if u.CreatedBy != nil && *u.CreatedBy == userID {
+				u.CreatedBy = nil
+			}
+		}
+		for _, n := range r.s.notes {
+			if n.AuthorID != nil && *n.AuthorID == userID {
+				n.AuthorID = nil
+			}
+		}
+		for _, a := range r.s.attachments {
+			if a.UploadedBy == userID {
+				a.UploadedBy = 0
+			}
+		}
+		delete(r.s.users, userID)
+	} else if u, ok := r.s.users[userID]; ok {
+		u.Email = fmt.Sprintf("deleted-user-%d@deleted.invalid", userID)
+		u.PasswordHash, u.IsActive, u.LockedUntil, u.FailedLoginAttempts = "", false, nil, 0
+		u.UpdatedAt = time.Now()
+	}
+
+	patients := purge.Patients
+	r.s.resolve(stored, models.DeletionCompleted, by)
+	stored.Mode, stored.Patients = mode, &patients
+	return purge, nil
+}
@@ -0,0 +1,2110 @@
+// memory_patients.go: In-memory patients, assessments and their related records.
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+type memPatientRepo struct{ s *MemoryStore }
+
+// isMember reports whether the user belongs to the clinic; callers hold the lock.
+func (s *MemoryStore) isMember(clinicID, userID int64) bool {
+	for _, m := range s.members {
+		if m.clinicID == clinicID && m.userID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// readsShared reports whether the user belongs to the clinic with a global
+// or clinic role granting patients.read, as sharedReadGrant does; callers
+// hold the lock.
+func (s *MemoryStore) readsShared(clinicID, userID int64) bool {
+	m := s.member(clinicID, userID)
+	if m == nil {
+		return false
+	}
+	if slices.Contains(s.permissions[m.role], models.PermPatientsRead) {
+		return true
+	}
+	u, ok := s.users[userID]
+	return ok && slices.Contains(s.permissions[u.Role], models.PermPatientsRead)
+}
+
+// visible reports whether the user owns the patient or may read it through a
+// clinic it is shared with, as visiblePatientFilter does.
+func (s *MemoryStore) visible(p *models.Patient, userID int64) bool {
+	return p.UserID == userID || (p.ClinicID != nil && s.readsShared(*p.ClinicID, userID))
+}
+
+// patientsOf returns the user's patients, newest first; callers hold the lock.
+func (s *MemoryStore) patientsOf(userID int64) []models.Patient {
+	out := []models.Patient{}
+	for _, p := range s.patients {
+		if p.UserID == userID {
+			out = append(out, *p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	return out
+}
+
+func (r *memPatientRepo) List(ctx context.Context, userID int32) ([]models.Patient, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.s.patientsOf(int64(userID)), nil
+}
+
+func (r *memPatientRepo) Get(ctx context.Context, id int32, userID int32) (*models.Patient, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	p, ok := r.s.patients[int64(id)]
+	if !ok || p.UserID != int64(userID) {
+		return nil, pgx.ErrNoRows
+	}
+	out := *p
+	return &out, nil
+}
+
+func (r *memPatientRepo) Create(ctx context.Context, p models.Patient) (*models.Patient, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	p.ID = r.s.nextID("patients")
+	p.CreatedAt, p.UpdatedAt = now, now
+	p.ClinicID, p.Contact = nil, nil
+	stored := p
+	r.s.patients[p.ID] = &stored
+	return &p, nil
+}
+
+// patientSnapshot is the patient as a versioned row, keyed by field name
+func patientSnapshot(p models.Patient) []byte {
+	p.Contact = nil
+	b, _ := json.Marshal(p)
+	return b
+}
+
+// changePatient applies fn to the owner's patient and records a version
+// when a field changed. Callers hold the write lock.
+func (s *MemoryStore) changePatient(id, ownerID, changedBy int64, fn func(p *models.Patient)) (*models.Patient, error) {
+	p, ok := s.patients[id]
+	if !ok || p.UserID != ownerID {
+		return nil, pgx.ErrNoRows
+	}
+	before := patientSnapshot(*p)
+	fn(p)
+	p.UpdatedAt = time.Now()
+	after := patientSnapshot(*p)
+	changes, err := diffPatientSnapshots(before, after)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) > 0 {
+		v := models.PatientVersion{
+			ID:        s.nextID("patient_versions"),
+			PatientID: id,
+			Version:   1,
+			ChangedBy: &changedBy,
+			ChangedAt: p.UpdatedAt,
+			Changes:   changes,
+		}
+		for _, prev := range s.versions {
+			if prev.PatientID == id && prev.Version >= v.Version {
+				v.Version = prev.Version + 1
+			}
+		}
+		_ = json.Unmarshal(before, &v.Before)
+		_ = json.Unmarshal(after, &v.After)
+		s.versions = append(s.versions, v)
+	}
+	out := *p
+	return &out, nil
+}
+
+func (r *memPatientRepo) Update(ctx context.Context, p models.Patient) (*models.Patient, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.changePatient(p.ID, p.UserID, p.UserID, func(cur *models.Patient) {
+		p.CreatedAt, p.ClinicID, p.Contact = cur.CreatedAt, cur.ClinicID, nil
+		*cur = p
+	})
+}
+
+func (r *memPatientRepo) Delete(ctx context.Context, id int32, userID int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if p, ok := r.s.patients[int64(id)]; ok && p.UserID == int64(userID) {
+		r.s.deletePatient(int64(id))
+	}
+	return nil
+}
+
+// deletePatient removes the patient and everything that references it, as
+// ON DELETE CASCADE does. Callers hold the write lock.
+func (s *MemoryStore) deletePatient(id int64) {
+	delete(s.patients, id)
+	delete(s.contacts, id)
+	delete(s.photos, id)
+	for aid, a := range s.assessments {
+		if a.PatientID == id {
+			delete(s.assessments, aid)
+			delete(s.explanations, aid)
+		}
+	}
+	alerts := s.alerts[:0]
+	for _, a := range s.alerts {
+		if a.PatientID != id {
+			alerts = append(alerts, a)
+		}
+	}
+	s.alerts = alerts
+	discrepancies := s.discrepancies[:0]
+	for _, d := range s.discrepancies {
+		if d.PatientID != id {
+			discrepancies = append(discrepancies, d)
+		}
+	}
+	s.discrepancies = discrepancies
+	versions := s.versions[:0]
+	for _, v := range s.versions {
+		if v.PatientID != id {
+			versions = append(versions, v)
+		}
+	}
+	s.versions = versions
+	drafts := s.drafts[:0]
+	for _, d := range s.drafts {
+		if d.PatientID != id {
+			drafts = append(drafts, d)
+		}
+	}
+	s.drafts = drafts
+	notes := s.notes[:0]
+	for _, n := range s.notes {
+		if n.PatientID != id {
+			notes = append(notes, n)
+		}
+	}
+	s.notes = notes
+	medications := s.medications[:0]
+	for _, m := range s.medications {
+		if m.PatientID != id {
+			medications = append(medications, m)
+		}
+	}
+	s.medications = medications
+	s.dropFollowUps(id)
+	s.dropTasks(func(t *models.Task) bool { return t.PatientID != nil && *t.PatientID == id })
+	for _, patients := range s.patientTags {
+		delete(patients, id)
+	}
+	s.dropAttachments(func(a *models.AssessmentAttachment) bool { return a.PatientID == id })
+}
+
+// dropTasks removes the tasks matching drop; callers hold the write lock.
+func (s *MemoryStore) dropTasks(drop func(t *models.Task) bool) {
+	tasks := s.tasks[:0]
+	for _, t := range s.tasks {
+		if !drop(t) {
+			tasks = append(tasks, t)
+		}
+	}
+	s.tasks = tasks
+}
+
+// dropFollowUps removes the patient's follow-ups; callers hold the write
+// lock.
+func (s *MemoryStore) dropFollowUps(patientID int64) {
+	followUps := s.followUps[:0]
+	for _, f := range s.followUps {
+		if f.PatientID != patientID {
+			followUps = append(followUps, f)
+		}
+	}
+	s.followUps = followUps
+}
+
+// dropAttachments removes the attachment rows matching drop; callers hold
+// the write lock.
+func (s *MemoryStore) dropAttachments(drop func(a *models.AssessmentAttachment) bool) {
+	attachments := s.attachments[:0]
+	for _, a := range s.attachments {
+		if !drop(a) {
+			attachments = append(attachments, a)
+		}
+	}
+	s.attachments = attachments
+}
+
+func (r *memPatientRepo) ListAllLimited(ctx context.Context, userID int32, limit int) ([]models.Patient, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := r.s.patientsOf(int64(userID))
+	return out[:min(limit, len(out))], nil
+}
+
+// EachLimited calls fn outside the lock, on a copy of the patients
+func (r *memPatientRepo) EachLimited(ctx context.Context, userID int32, limit int, fn func(models.Patient) error) error {
+	patients, _ := r.ListAllLimited(ctx, userID, limit)
+	for _, p := range patients {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EachLimitedByClinic calls fn outside the lock, on a copy of the patients
+func (r *memPatientRepo) EachLimitedByClinic(ctx context.Context, clinicID int32, limit int, fn func(models.Patient) error) error {
+	r.s.mu.RLock()
+	patients := []models.Patient{}
+	for _, p := range r.s.patients {
+		if p.ClinicID != nil && *p.ClinicID == int64(clinicID) {
+			patients = append(patients, *p)
+		}
+	}
+	r.s.mu.RUnlock()
+	sort.Slice(patients, func(i, j int) bool { return patients[i].ID > patients[j].ID })
+	for _, p := range patients[:min(limit, len(patients))] {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// latest returns the patient's most recent final assessment, or nil;
+// callers hold the lock.
+func (s *MemoryStore) latest(patientID int64) *models.Assessment {
+	var last *models.Assessment
+	for _, a := range s.assessments {
+		if a.PatientID == patientID && !a.Draft() && !s.superseded(a) && (last == nil || a.CreatedAt.After(last.CreatedAt)) {
+			last = a
+		}
+	}
+	return last
+}
+
+func (r *memPatientRepo) Typeahead(ctx context.Context, userID int32, q string, limit int) ([]models.PatientMatch, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	type match struct {
+		models.PatientMatch
+		rank int
+	}
+	var matches []match
+	lower := strings.ToLower(q)
+	for _, p := range r.s.patientsOf(int64(userID)) {
+		name, mrn := strings.ToLower(p.Name), strings.ToLower(p.MRN)
+		if !strings.Contains(name, lower) && (mrn == "" || !strings.HasPrefix(mrn, lower)) {
+			continue
+		}
+		// Exact MRN hits first, then names starting with the query, in
+		// place of trigram similarity
+		m := match{PatientMatch: models.PatientMatch{ID: p.ID, Name: p.Name, Age: p.Age, MRN: p.MRN}, rank: 2}
+		if mrn != "" && mrn == lower {
+			m.rank = 0
+		} else if strings.HasPrefix(name, lower) {
+			m.rank = 1
+		}
+		if a := r.s.latest(p.ID); a != nil {
+			t := a.CreatedAt
+			m.LastVisit = &t
+		}
+		matches = append(matches, m)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	out := []models.PatientMatch{}
+	for i := 0; i < len(matches) && i < limit; i++ {
+		out = append(out, matches[i].PatientMatch)
+	}
+	return out, nil
+}
+
+// patientListRows returns every patient the list matches, with their latest
+// assessment, in no particular order; callers hold the lock.
+func (s *MemoryStore) patientListRows(userID int32, params models.PatientListParams) ([]models.PatientWithLatest, []*models.Assessment) {
+	rows := []models.PatientWithLatest{}
+	var latest []*models.Assessment
+	for _, p := range s.patients {
+		if params.ClinicID != nil {
+			if p.ClinicID == nil || *p.ClinicID != int64(*params.ClinicID) || !s.readsShared(*p.ClinicID, int64(userID)) {
+				continue
+			}
+		} else if p.UserID != int64(userID) {
+			continue
+		}
+		if params.Search != "" && !containsFold(p.Name, params.Search) {
+			continue
+		}
+		if (params.MinAge != nil && p.Age < *params.MinAge) || (params.MaxAge != nil && p.Age > *params.MaxAge) {
+			continue
+		}
+		if params.MenopauseStatus != "" && p.MenopauseStatus != params.MenopauseStatus {
+			continue
+		}
+		row := models.PatientWithLatest{Patient: *p}
+		a := s.latest(p.ID)
+		if a != nil {
+			t := a.CreatedAt
+			row.Cluster, row.RiskScore, row.FBS, row.HbA1c, row.LastVisit = a.Cluster, a.RiskScore, a.FBS, a.HbA1c, &t
+		}
+		if params.Cluster != "" && (a == nil || a.Cluster != params.Cluster) {
+			continue
+		}
+		if (params.MinRisk != nil || params.MaxRisk != nil) && a == nil {
+			continue
+		}
+		if (params.MinRisk != nil && row.RiskScore < *params.MinRisk) || (params.MaxRisk != nil && row.RiskScore > *params.MaxRisk) {
+			continue
+		}
+		if params.TagID != nil && !s.patientTags[*params.TagID][p.ID] {
+			continue
+		}
+		rows = append(rows, row)
+		latest = append(latest, a)
+	}
+	return rows, latest
+}
+
+func (r *memPatientRepo) ListWithLatestAssessmentPaginated(ctx context.Context, userID int32, params models.PatientListParams) ([]models.PatientWithLatest, int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	rows, _ := r.s.patientListRows(userID, params)
+
+	key := func(p models.PatientWithLatest) (float64, string, bool) {
+		switch params.Sort {
+		case "name":
+			return 0, strings.ToLower(p.Patient.Name), true
+		case "age":
+			return float64(p.Patient.Age), "", true
+		case "created_at":
+			return float64(p.Patient.CreatedAt.UnixNano()), "", true
+		case "updated_at":
+			return float64(p.Patient.UpdatedAt.UnixNano()), "", true
+		case "risk_score":
+			return float64(p.RiskScore), "", p.LastVisit != nil
+		}
+		return 0, "", true
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		ni, si, oki := key(rows[i])
+		nj, sj, okj := key(rows[j])
+		if oki != okj {
+			return oki
+		}
+		if ni == nj && si == sj {
+			return rows[i].Patient.ID > rows[j].Patient.ID
+		}
+		less := ni < nj || (ni == nj && si < sj)
+		if params.Order == "desc" {
+			return !less
+		}
+		return less
+	})
+	from, to := page(len(rows), params.Page, params.PageSize)
+	return rows[from:to], len(rows), nil
+}
+
+// pastCursor reports whether a row created at createdAt with id comes after
+// the cursor in a newest-first list; every row does when after is nil.
+func pastCursor(createdAt time.Time, id int64, after *models.ListCursor) bool {
+	return after == nil || createdAt.Before(after.CreatedAt) || (createdAt.Equal(after.CreatedAt) && id < after.ID)
+}
+
+func (r *memPatientRepo) ListWithLatestAssessmentAfter(ctx context.Context, userID int32, params models.PatientListParams, after *models.ListCursor) ([]models.PatientWithLatest, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	rows, _ := r.s.patientListRows(userID, params)
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Patient.CreatedAt.Equal(rows[j].Patient.CreatedAt) {
+			return rows[i].Patient.ID > rows[j].Patient.ID
+		}
+		return rows[i].Patient.CreatedAt.After(rows[j].Patient.CreatedAt)
+	})
+	out := []models.PatientWithLatest{}
+	for _, row := range rows {
+		if len(out) < params.PageSize && pastCursor(row.Patient.CreatedAt, row.Patient.ID, after) {
+			out = append(out, row)
+		}
+	}
+	return out, nil
+}
+
+// addToVersion counts a row into a list version
+func addToVersion(v *models.ListVersion, id int64, updated time.Time) {
+	v.Count++
+	v.IDSum += id
+	if updated.After(v.LastUpdated) {
+		v.LastUpdated = updated
+	}
+}
+
+func (r *memPatientRepo) ListVersion(ctx context.Context, userID int32, params models.PatientListParams) (models.ListVersion, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	rows, latest := r.s.patientListRows(userID, params)
+	var v models.ListVersion
+	for i, row := range rows {
+		addToVersion(&v, row.Patient.ID, row.Patient.UpdatedAt)
+		if a := latest[i]; a != nil {
+			// The latest assessment shares the patient's row
+			v.IDSum += a.ID
+			if a.UpdatedAt.After(v.LastUpdated) {
+				v.LastUpdated = a.UpdatedAt
+			}
+		}
+	}
+	return v, nil
+}
+
+func (r *memPatientRepo) GetVisible(ctx context.Context, id int32, userID int32) (*models.Patient, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	p, ok := r.s.patients[int64(id)]
+	if !ok || !r.s.visible(p, int64(userID)) {
+		return nil, pgx.ErrNoRows
+	}
+	out := *p
+	return &out, nil
+}
+
+func (r *memPatientRepo) SetClinic(ctx context.Context, id int64, ownerID int32, clinicID *int32, changedBy int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	_, err := r.s.changePatient(id, int64(ownerID), int64(changedBy), func(p *models.Patient) {
+		p.ClinicID = nil
+		if clinicID != nil {
+			c := int64(*clinicID)
+			p.ClinicID = &c
+		}
+	})
+	return err
+}
+
+func (r *memPatientRepo) Owner(ctx context.Context, id int64) (int32, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	p, ok := r.s.patients[id]
+	if !ok {
+		return 0, pgx.ErrNoRows
+	}
+	return int32(p.UserID), nil
+}
+
+func (r *memPatientRepo) Transfer(ctx context.Context, id int64, fromUserID, toUserID, changedBy int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	_, err := r.s.changePatient(id, int64(fromUserID), int64(changedBy), func(p *models.Patient) {
+		p.UserID = int64(toUserID)
+	})
+	if err != nil {
+		return err
+	}
+	// Alerts not yet delivered should reach the clinician now responsible
+	for _, a := range r.s.alerts {
+		if a.PatientID == id && a.DeliveredAt == nil {
+			a.UserID = int64(toUserID)
+		}
+	}
+	return nil
+}
+
+func (r *memPatientRepo) ListByMRN(ctx context.Context, mrn string) ([]models.Patient, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.Patient{}
+	for _, p := range r.s.patients {
+		if p.MRN == mrn {
+			out = append(out, *p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// trigrams returns the set of trigrams in s the way pg_trgm counts them:
+// each lower-cased alphanumeric word padded with two spaces before and one
+// after.
+func trigrams(s string) map[string]bool {
+	out := map[string]bool{}
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		padded := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			out[string(padded[i:i+3])] = true
+		}
+	}
+	return out
+}
+
+// similarity mirrors pg_trgm's similarity(): shared trigrams over all
+// distinct trigrams of both strings.
+func similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	if all := len(ta) + len(tb) - shared; all > 0 {
+		return float64(shared) / float64(all)
+	}
+	return 0
+}
+
+func (r *memPatientRepo) Duplicates(ctx context.Context, userID int32, minSimilarity float64, limit int) ([]models.PatientDuplicate, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	patients := r.s.patientsOf(int64(userID))
+	sort.Slice(patients, func(i, j int) bool { return patients[i].ID < patients[j].ID })
+	out := []models.PatientDuplicate{}
+	for i, a := range patients {
+		for _, b := range patients[i+1:] {
+			sameMRN := a.MRN != "" && strings.EqualFold(a.MRN, b.MRN)
+			sim := similarity(a.Name, b.Name)
+			ageOK := a.Age == 0 || b.Age == 0 || a.Age-b.Age <= 1 && b.Age-a.Age <= 1
+			if !sameMRN && !(sim >= minSimilarity && ageOK) {
+				continue
+			}
+			out = append(out, models.PatientDuplicate{
+				Patient:    models.PatientMatch{ID: a.ID, Name: a.Name, Age: a.Age, MRN: a.MRN},
+				Duplicate:  models.PatientMatch{ID: b.ID, Name: b.Name, Age: b.Age, MRN: b.MRN},
+				Similarity: math.Round(sim*100) / 100,
+				SameMRN:    sameMRN,
+			})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].SameMRN != out[j].SameMRN {
+			return out[i].SameMRN
+		}
+		return out[i].Similarity > out[j].Similarity
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *memPatientRepo) Merge(ctx context.Context, survivorID, mergedID int64, userID int32) (*models.PatientMerge, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	s := r.s
+	survivor, ok := s.patients[survivorID]
+	merged, ok2 := s.patients[mergedID]
+	if !ok || !ok2 || survivorID == mergedID || !s.visible(survivor, int64(userID)) || !s.visible(merged, int64(userID)) {
+		return nil, ErrNotFound
+	}
+	if survivor.UserID != int64(userID) || merged.UserID != int64(userID) {
+		return nil, ErrForbidden
+	}
+	m := &models.PatientMerge{PatientID: survivorID, MergedID: mergedID}
+	for _, a := range s.assessments {
+		if a.PatientID == mergedID {
+			a.PatientID = survivorID
+			m.Assessments++
+		}
+	}
+	for _, n := range s.notes {
+		if n.PatientID == mergedID {
+			n.PatientID = survivorID
+			m.Notes++
+		}
+	}
+	for _, a := range s.attachments {
+		if a.PatientID == mergedID {
+			a.PatientID = survivorID
+			m.Attachments++
+		}
+	}
+	for _, a := range s.alerts {
+		if a.PatientID == mergedID {
+			a.PatientID = survivorID
+		}
+	}
+	for _, d := range s.discrepancies {
+		if d.PatientID == mergedID {
+			d.PatientID = survivorID
+		}
+	}
+	for _, med := range s.medications {
+		if med.PatientID == mergedID {
+			med.PatientID = survivorID
+		}
+	}
+	for _, f := range s.followUps {
+		if f.PatientID == mergedID {
+			f.PatientID = survivorID
+		}
+	}
+	for i := range s.exposures {
+		if s.exposures[i].PatientID == mergedID {
+			s.exposures[i].PatientID = survivorID
+		}
+	}
+	// Rows the survivor already has an equivalent of stay behind and go
+	// with the merged patient, as the unique indexes force in Postgres
+	activeOverdue := func(t *models.Task, patientID int64) bool {
+		return t.PatientID != nil && *t.PatientID == patientID && t.Kind == models.TaskOverdueAssessment &&
+			(t.Status == models.TaskOpen || t.Status == models.TaskInProgress)
+	}
+	survivorOverdue := slices.ContainsFunc(s.tasks, func(t *models.Task) bool { return activeOverdue(t, survivorID) })
+	for _, t := range s.tasks {
+		if t.PatientID != nil && *t.PatientID == mergedID && !(survivorOverdue && activeOverdue(t, mergedID)) {
+			id := survivorID
+			t.PatientID = &id
+		}
+	}
+	for _, d := range s.drafts {
+		if d.PatientID != mergedID {
+			continue
+		}
+		if !slices.ContainsFunc(s.drafts, func(o *models.AssessmentDraft) bool {
+			return o.PatientID == survivorID && o.Source == d.Source && o.MessageControlID == d.MessageControlID
+		}) {
+			d.PatientID = survivorID
+		}
+	}
+	for _, patients := range s.patientTags {
+		if patients[mergedID] {
+			patients[survivorID] = true
+		}
+	}
+	if c, ok := s.contacts[mergedID]; ok {
+		if _, has := s.contacts[survivorID]; !has {
+			c.PatientID = survivorID
+			s.contacts[survivorID] = c
+		}
+	}
+	s.deletePatient(mergedID)
+	return m, nil
+}
+
+type memPatientHistoryRepo struct{ s *MemoryStore }
+
+func (r *memPatientHistoryRepo) List(ctx context.Context, patientID int64) ([]models.PatientVersion, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.PatientVersion{}
+	for _, v := range r.s.versions {
+		if v.PatientID == patientID {
+			out = append(out, v)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version > out[j].Version })
+	return out, nil
+}
+
+type memPatientContactRepo struct{ s *MemoryStore }
+
+func (r *memPatientContactRepo) Get(ctx context.Context, patientID int64) (*models.PatientContact, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	c, ok := r.s.contacts[patientID]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (r *memPatientContactRepo) Put(ctx context.Context, contact models.PatientContact) (*models.PatientContact, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	prev, ok := r.s.contacts[contact.PatientID]
+	switch {
+	case !contact.ContactConsent:
+		contact.ConsentAt = nil
+	case ok && prev.ContactConsent:
+		contact.ConsentAt = prev.ConsentAt
+	default:
+		contact.ConsentAt = &now
+	}
+	contact.UpdatedAt = now
+	r.s.contacts[contact.PatientID] = contact
+	return &contact, nil
+}
+
+type memPatientNoteRepo struct{ s *MemoryStore }
+
+// withAuthor returns a copy of n with its author's email; callers hold the
+// lock.
+func (r *memPatientNoteRepo) withAuthor(n *models.PatientNote) models.PatientNote {
+	out := *n
+	if n.AuthorID != nil {
+		if u, ok := r.s.users[*n.AuthorID]; ok {
+			out.AuthorEmail = u.Email
+		}
+	}
+	return out
+}
+
+func (r *memPatientNoteRepo) List(ctx context.Context, patientID int64) ([]models.PatientNote, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.PatientNote{}
+	for i := len(r.s.notes) - 1; i >= 0; i-- {
+		if n := r.s.notes[i]; n.PatientID == patientID {
+			out = append(out, r.withAuthor(n))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Pinned && !out[j].Pinned })
+	return out, nil
+}
+
+// find returns the index of the stored note; callers hold the lock.
+func (r *memPatientNoteRepo) find(id, patientID int64) (int, error) {
+	for i, n := range r.s.notes {
+		if n.ID == id && n.PatientID == patientID {
+			return i, nil
+		}
+	}
+	return 0, pgx.ErrNoRows
+}
+
+func (r *memPatientNoteRepo) Get(ctx context.Context, id, patientID int64) (*models.PatientNote, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	i, err := r.find(id, patientID)
+	if err != nil {
+		return nil, err
+	}
+	n := r.withAuthor(r.s.notes[i])
+	return &n, nil
+}
+
+func (r *memPatientNoteRepo) Create(ctx context.Context, note models.PatientNote) (*models.PatientNote, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	note.ID = r.s.nextID("patient_notes")
+	note.CreatedAt = time.Now()
+	note.UpdatedAt = note.CreatedAt
+	stored := note
+	r.s.notes = append(r.s.notes, &stored)
+	out := r.withAuthor(&stored)
+	return &out, nil
+}
+
+func (r *memPatientNoteRepo) Update(ctx context.Context, note models.PatientNote) (*models.PatientNote, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	i, err := r.find(note.ID, note.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	n := r.s.notes[i]
+	n.Category, n.Body, n.Pinned, n.UpdatedAt = note.Category, note.Body, note.Pinned, time.Now()
+	out := r.withAuthor(n)
+	return &out, nil
+}
+
+func (r *memPatientNoteRepo) Delete(ctx context.Context, id, patientID int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	i, err := r.find(id, patientID)
+	if err != nil {
+		return err
+	}
+	r.s.notes = slices.Delete(r.s.notes, i, i+1)
+	return nil
+}
+
+type memPatientMedicationRepo struct{ s *MemoryStore }
+
+func (r *memPatientMedicationRepo) List(ctx context.Context, patientID int64) ([]models.PatientMedication, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.PatientMedication{}
+	for i := len(r.s.medications) - 1; i >= 0; i-- {
+		if m := r.s.medications[i]; m.PatientID == patientID {
+			out = append(out, *m)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].StartedAt.After(out[j].StartedAt) })
+	return out, nil
+}
+
+// find returns the index of the stored medication; callers hold the lock.
+func (r *memPatientMedicationRepo) find(id, patientID int64) (int, error) {
+	for i, m := range r.s.medications {
+		if m.ID == id && m.PatientID == patientID {
+			return i, nil
+		}
+	}
+	return 0, pgx.ErrNoRows
+}
+
+func (r *memPatientMedicationRepo) Get(ctx context.Context, id, patientID int64) (*models.PatientMedication, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	i, err := r.find(id, patientID)
+	if err != nil {
+		return nil, err
+	}
+	m := *r.s.medications[i]
+	return &m, nil
+}
+
+func (r *memPatientMedicationRepo) Create(ctx context.Context, m models.PatientMedication) (*models.PatientMedication, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	m.ID = r.s.nextID("patient_medications")
+	m.CreatedAt = time.Now()
+	m.UpdatedAt = m.CreatedAt
+	stored := m
+	r.s.medications = append(r.s.medications, &stored)
+	return &m, nil
+}
+
+func (r *memPatientMedicationRepo) Update(ctx context.Context, m models.PatientMedication) (*models.PatientMedication, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	i, err := r.find(m.ID, m.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	stored := r.s.medications[i]
+	stored.Name, stored.Dose, stored.StartedAt, stored.StoppedAt, stored.Adherent = m.Name, m.Dose, m.StartedAt, m.StoppedAt, m.Adherent
+	stored.UpdatedAt = time.Now()
+	out := *stored
+	return &out, nil
+}
+
+func (r *memPatientMedicationRepo) Delete(ctx context.Context, id, patientID int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	i, err := r.find(id, patientID)
+	if err != nil {
+		return err
+	}
+	r.s.medications = slices.Delete(r.s.medications, i, i+1)
+	return nil
+}
+
+type memFollowUpRepo struct{ s *MemoryStore }
+
+// withPatient returns a copy of f with its patient's owner and name; callers
+// hold the lock.
+func (r *memFollowUpRepo) withPatient(f *models.FollowUp) models.FollowUp {
+	out := *f
+	if p, ok := r.s.patients[f.PatientID]; ok {
+		out.UserID, out.PatientName = p.UserID, p.Name
+	}
+	return out
+}
+
+// where returns the follow-ups matching keep, soonest due first; callers
+// hold the lock.
+func (r *memFollowUpRepo) where(keep func(f *models.FollowUp) bool) []models.FollowUp {
+	out := []models.FollowUp{}
+	for _, f := range r.s.followUps {
+		if keep(f) {
+			out = append(out, r.withPatient(f))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].DueAt.Before(out[j].DueAt) })
+	return out
+}
+
+func (r *memFollowUpRepo) List(ctx context.Context, patientID int64) ([]models.FollowUp, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.where(func(f *models.FollowUp) bool { return f.PatientID == patientID }), nil
+}
+
+func (r *memFollowUpRepo) ListOpen(ctx context.Context, userID int64, due string, now time.Time) ([]models.FollowUp, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.where(func(f *models.FollowUp) bool {
+		p, ok := r.s.patients[f.PatientID]
+		switch {
+		case !ok || p.UserID != userID || f.CompletedAt != nil:
+			return false
+		case due == models.FollowUpsOverdue:
+			return f.DueAt.Before(now)
+		case due == models.FollowUpsUpcoming:
+			return !f.DueAt.Before(now)
+		}
+		return true
+	}), nil
+}
+
+// find returns the index of the stored follow-up; callers hold the lock.
+func (r *memFollowUpRepo) find(id, patientID int64) (int, error) {
+	for i, f := range r.s.followUps {
+		if f.ID == id && f.PatientID == patientID {
+			return i, nil
+		}
+	}
+	return 0, pgx.ErrNoRows
+}
+
+func (r *memFollowUpRepo) Get(ctx context.Context, id, patientID int64) (*models.FollowUp, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	i, err := r.find(id, patientID)
+	if err != nil {
+		return nil, err
+	}
+	out := r.withPatient(r.s.followUps[i])
+	return &out, nil
+}
+
+func (r *memFollowUpRepo) Create(ctx context.Context, f models.FollowUp) (*models.FollowUp, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	f.ID = r.s.nextID("follow_ups")
+	f.RemindedAt = nil
+	f.CreatedAt = time.Now()
+	f.UpdatedAt = f.CreatedAt
+	stored := f
+	r.s.followUps = append(r.s.followUps, &stored)
+	out := r.withPatient(&stored)
+	return &out, nil
+}
+
+func (r *memFollowUpRepo) Update(ctx context.Context, f models.FollowUp) (*models.FollowUp, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	i, err := r.find(f.ID, f.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	stored := r.s.followUps[i]
+	if !stored.DueAt.Equal(f.DueAt) {
+		stored.RemindedAt = nil
+	}
+	stored.DueAt, stored.Reason, stored.CompletedAt = f.DueAt, f.Reason, f.CompletedAt
+	stored.UpdatedAt = time.Now()
+	out := r.withPatient(stored)
+	return &out, nil
+}
+
+func (r *memFollowUpRepo) Delete(ctx context.Context, id, patientID int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	i, err := r.find(id, patientID)
+	if err != nil {
+		return err
+	}
+	r.s.followUps = slices.Delete(r.s.followUps, i, i+1)
+	return nil
+}
+
+func (r *memFollowUpRepo) ClaimReminders(ctx context.Context, before, now time.Time, limit int) ([]models.FollowUp, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	due := r.where(func(f *models.FollowUp) bool {
+		return f.CompletedAt == nil && f.RemindedAt == nil && f.DueAt.Before(before)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		for _, f := range r.s.followUps {
+			if f.ID == due[i].ID {
+				reminded := now
+				f.RemindedAt = &reminded
+				due[i].RemindedAt = &reminded
+			}
+		}
+	}
+	return due, nil
+}
+
+type memTaskRepo struct{ s *MemoryStore }
+
+// withPatient returns a copy of t with its patient's name; callers hold the
+// lock.
+func (r *memTaskRepo) withPatient(t *models.Task) models.Task {
+	out := *t
+	if t.PatientID != nil {
+		if p, ok := r.s.patients[*t.PatientID]; ok {
+			out.PatientName = p.Name
+		}
+	}
+	return out
+}
+
+func (r *memTaskRepo) List(ctx context.Context, userID int64, params models.TaskListParams) ([]models.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	statuses := params.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{models.TaskOpen, models.TaskInProgress}
+	}
+	out := []models.Task{}
+	for i := len(r.s.tasks) - 1; i >= 0; i-- {
+		t := r.s.tasks[i]
+		if t.UserID == userID && slices.Contains(statuses, t.Status) && (params.Kind == "" || t.Kind == params.Kind) {
+			out = append(out, r.withPatient(t))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i].DueAt, out[j].DueAt
+		switch {
+		case a == nil || b == nil:
+			return a != nil && b == nil
+		case !a.Equal(*b):
+			return a.Before(*b)
+		}
+		return out[i].CreatedAt.After(out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+// find returns the index of the stored task; callers hold the lock.
+func (r *memTaskRepo) find(id, userID int64) (int, error) {
+	for i, t := range r.s.tasks {
+		if t.ID == id && t.UserID == userID {
+			return i, nil
+		}
+	}
+	return 0, pgx.ErrNoRows
+}
+
+func (r *memTaskRepo) Get(ctx context.Context, id, userID int64) (*models.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	i, err := r.find(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := r.withPatient(r.s.tasks[i])
+	return &out, nil
+}
+
+// create stores t; callers hold the write lock.
+func (r *memTaskRepo) create(t models.Task, now time.Time) *models.Task {
+	t.ID = r.s.nextID("tasks")
+	if t.Status == "" {
+		t.Status = models.TaskOpen
+	}
+	t.CreatedAt, t.UpdatedAt = now, now
+	stored := t
+	r.s.tasks = append(r.s.tasks, &stored)
+	return &stored
+}
+
+func (r *memTaskRepo) Create(ctx context.Context, t models.Task) (*models.Task, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	out := r.withPatient(r.create(t, time.Now()))
+	return &out, nil
+}
+
+func (r *memTaskRepo) Update(ctx context.Context, t models.Task) (*models.Task, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	i, err := r.find(t.ID, t.UserID)
+	if err != nil {
+		return nil, err
+	}
+	stored := r.s.tasks[i]
+	stored.Title, stored.Status, stored.DueAt, stored.ClosedAt = t.Title, t.Status, t.DueAt, t.ClosedAt
+	stored.UpdatedAt = time.Now()
+	out := r.withPatient(stored)
+	return &out, nil
+}
+
+func (r *memTaskRepo) Delete(ctx context.Context, id, userID int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	i, err := r.find(id, userID)
+	if err != nil {
+		return err
+	}
+	r.s.tasks = slices.Delete(r.s.tasks, i, i+1)
+	return nil
+}
+
+// active reports whether the patient has an open or in-progress task of
+// kind; callers hold the lock.
+func (r *memTaskRepo) active(patientID int64, kind string) bool {
+	for _, t := range r.s.tasks {
+		if t.PatientID != nil && *t.PatientID == patientID && t.Kind == kind && !models.TaskClosed(t.Status) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memTaskRepo) OpenOverdueAssessments(ctx context.Context, before, now time.Time) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	last := map[int64]time.Time{}
+	for _, a := range r.s.assessments {
+		if !a.Draft() && a.CreatedAt.After(last[a.PatientID]) {
+			last[a.PatientID] = a.CreatedAt
+		}
+	}
+	opened := 0
+	for id, p := range r.s.patients {
+		at, assessed := last[id]
+		if !assessed {
+			at = p.CreatedAt
+		}
+		if p.UserID == 0 || !at.Before(before) || r.active(id, models.TaskOverdueAssessment) {
+			continue
+		}
+		patientID, due := id, now
+		r.create(models.Task{
+			UserID:    p.UserID,
+			Kind:      models.TaskOverdueAssessment,
+			Title:     "Patient overdue for assessment",
+			PatientID: &patientID,
+			DueAt:     &due,
+		}, now)
+		opened++
+	}
+	return opened, nil
+}
+
+func (r *memTaskRepo) CloseForPatient(ctx context.Context, patientID int64, kind string, now time.Time) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	closed := 0
+	for _, t := range r.s.tasks {
+		if t.PatientID != nil && *t.PatientID == patientID && t.Kind == kind && !models.TaskClosed(t.Status) {
+			closedAt := now
+			t.Status, t.ClosedAt, t.UpdatedAt = models.TaskDone, &closedAt, now
+			closed++
+		}
+	}
+	return closed, nil
+}
+
+type memTagRepo struct{ s *MemoryStore }
+
+// withCount returns a copy of t with its patient count; callers hold the
+// lock.
+func (r *memTagRepo) withCount(t *models.Tag) models.Tag {
+	out := *t
+	out.PatientCount = len(r.s.patientTags[t.ID])
+	return out
+}
+
+// sorted returns copies of the tags matching keep, by name; callers hold the
+// lock.
+func (r *memTagRepo) sorted(keep func(t *models.Tag) bool) []models.Tag {
+	out := []models.Tag{}
+	for _, t := range r.s.tags {
+		if keep(t) {
+			out = append(out, r.withCount(t))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := strings.ToLower(out[i].Name), strings.ToLower(out[j].Name)
+		if a != b {
+			return a < b
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+func (r *memTagRepo) List(ctx context.Context, userID int64) ([]models.Tag, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.sorted(func(t *models.Tag) bool { return t.UserID == userID }), nil
+}
+
+// find returns the index of the stored tag; callers hold the lock.
+func (r *memTagRepo) find(id, userID int64) (int, error) {
+	for i, t := range r.s.tags {
+		if t.ID == id && t.UserID == userID {
+			return i, nil
+		}
+	}
+	return 0, pgx.ErrNoRows
+}
+
+// taken reports whether another of the user's tags has name, ignoring case;
+// callers hold the lock.
+func (r *memTagRepo) taken(userID, exceptID int64, name string) bool {
+	for _, t := range r.s.tags {
+		if t.UserID == userID && t.ID != exceptID && strings.EqualFold(t.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memTagRepo) Get(ctx context.Context, id, userID int64) (*models.Tag, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	i, err := r.find(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := r.withCount(r.s.tags[i])
+	return &out, nil
+}
+
+func (r *memTagRepo) Create(ctx context.Context, tag models.Tag) (*models.Tag, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.taken(tag.UserID, 0, tag.Name) {
+		return nil, ErrConflict
+	}
+	tag.ID = r.s.nextID("tags")
+	tag.PatientCount = 0
+	tag.CreatedAt = time.Now()
+	stored := tag
+	r.s.tags = append(r.s.tags, &stored)
+	return &tag, nil
+}
+
+func (r *memTagRepo) Rename(ctx context.Context, id, userID int64, name string) (*models.Tag, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	i, err := r.find(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if r.taken(userID, id, name) {
+		return nil, ErrConflict
+	}
+	r.s.tags[i].Name = name
+	out := r.withCount(r.s.tags[i])
+	return &out, nil
+}
+
+func (r *memTagRepo) Delete(ctx context.Context, id, userID int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	i, err := r.find(id, userID)
+	if err != nil {
+		return err
+	}
+	r.s.tags = slices.Delete(r.s.tags, i, i+1)
+	delete(r.s.patientTags, id)
+	return nil
+}
+
+func (r *memTagRepo) ListForPatient(ctx context.Context, patientID, userID int64) ([]models.Tag, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.sorted(func(t *models.Tag) bool { return t.UserID == userID && r.s.patientTags[t.ID][patientID] }), nil
+}
+
+func (r *memTagRepo) Attach(ctx context.Context, tagID, patientID int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.s.patientTags[tagID] == nil {
+		r.s.patientTags[tagID] = map[int64]bool{}
+	}
+	r.s.patientTags[tagID][patientID] = true
+	return nil
+}
+
+func (r *memTagRepo) Detach(ctx context.Context, tagID, patientID int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if !r.s.patientTags[tagID][patientID] {
+		return pgx.ErrNoRows
+	}
+	delete(r.s.patientTags[tagID], patientID)
+	return nil
+}
+
+type memAssessmentAttachmentRepo struct{ s *MemoryStore }
+
+func (r *memAssessmentAttachmentRepo) Create(ctx context.Context, a models.AssessmentAttachment) (*models.AssessmentAttachment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	a.ID = r.s.nextID("assessment_attachments")
+	a.CreatedAt = time.Now()
+	stored := a
+	r.s.attachments = append(r.s.attachments, &stored)
+	return &a, nil
+}
+
+// where returns copies of the attachments matching keep, oldest first
+func (r *memAssessmentAttachmentRepo) where(keep func(a *models.AssessmentAttachment) bool) []models.AssessmentAttachment {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.AssessmentAttachment{}
+	for _, a := range r.s.attachments {
+		if keep(a) {
+			out = append(out, *a)
+		}
+	}
+	return out
+}
+
+func (r *memAssessmentAttachmentRepo) List(ctx context.Context, assessmentID int64) ([]models.AssessmentAttachment, error) {
+	return r.where(func(a *models.AssessmentAttachment) bool { return a.AssessmentID == assessmentID }), nil
+}
+
+func (r *memAssessmentAttachmentRepo) ListByPatient(ctx context.Context, patientID int64) ([]models.AssessmentAttachment, error) {
+	return r.where(func(a *models.AssessmentAttachment) bool { return a.PatientID == patientID }), nil
+}
+
+func (r *memAssessmentAttachmentRepo) Get(ctx context.Context, id, assessmentID int64) (*models.AssessmentAttachment, error) {
+	found := r.where(func(a *models.AssessmentAttachment) bool { return a.ID == id && a.AssessmentID == assessmentID })
+	if len(found) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return &found[0], nil
+}
+
+func (r *memAssessmentAttachmentRepo) GetByID(ctx context.Context, id int64) (*models.AssessmentAttachment, error) {
+	found := r.where(func(a *models.AssessmentAttachment) bool { return a.ID == id })
+	if len(found) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return &found[0], nil
+}
+
+func (r *memAssessmentAttachmentRepo) Delete(ctx context.Context, id, assessmentID int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for i, a := range r.s.attachments {
+		if a.ID == id && a.AssessmentID == assessmentID {
+			r.s.attachments = slices.Delete(r.s.attachments, i, i+1)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+type memPatientPhotoRepo struct{ s *MemoryStore }
+
+func (r *memPatientPhotoRepo) Get(ctx context.Context, patientID int64) (*models.PatientPhoto, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	p, ok := r.s.photos[patientID]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func (r *memPatientPhotoRepo) Put(ctx context.Context, photo models.PatientPhoto) (*models.PatientPhoto, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	prev, ok := r.s.photos[photo.PatientID]
+	photo.CreatedAt = time.Now()
+	r.s.photos[photo.PatientID] = photo
+	if !ok {
+		return nil, nil
+	}
+	return &prev, nil
+}
+
+func (r *memPatientPhotoRepo) Delete(ctx context.Context, patientID int64) (*models.PatientPhoto, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	prev, ok := r.s.photos[patientID]
+	if !ok {
+		return nil, nil
+	}
+	delete(r.s.photos, patientID)
+	return &prev, nil
+}
+
+type memBaselineDiscrepancyRepo struct{ s *MemoryStore }
+
+func (r *memBaselineDiscrepancyRepo) Create(ctx context.Context, items []models.BaselineDiscrepancy) ([]models.BaselineDiscrepancy, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	out := []models.BaselineDiscrepancy{}
+	for _, d := range items {
+		d.ID = r.s.nextID("baseline_discrepancies")
+		d.CreatedAt = time.Now()
+		stored := d
+		r.s.discrepancies = append(r.s.discrepancies, &stored)
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func (r *memBaselineDiscrepancyRepo) ListOpen(ctx context.Context, patientID int64) ([]models.BaselineDiscrepancy, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := []models.BaselineDiscrepancy{}
+	for _, d := range r.s.discrepancies {
+		if d.PatientID == patientID && d.ResolvedAt == nil {
+			out = append(out, *d)
+		}
+	}
+	return out, nil
+}
+
+func (r *memBaselineDiscrepancyRepo) Resolve(ctx context.Context, id, patientID int64, resolution string, userID int64) (*models.BaselineDiscrepancy, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, d := range r.s.discrepancies {
+		if d.ID == id && d.PatientID == patientID && d.ResolvedAt == nil {
+			now := time.Now()
+			d.Resolution, d.ResolvedBy, d.ResolvedAt = resolution, &userID, &now
+			out := *d
+			return &out, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+type memRiskAlertRepo struct{ s *MemoryStore }
+
+func (r *memRiskAlertRepo) Enqueue(ctx context.Context, alert models.RiskAlert) (*models.RiskAlert, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	alert.ID = r.s.nextID("risk_alerts")
+	alert.CreatedAt = time.Now()
+	stored := alert
+	r.s.alerts = append(r.s.alerts, &stored)
+	return &alert, nil
+}
+
+func (r *memRiskAlertRepo) LastForPatient(ctx context.Context, patientID int64) (*models.RiskAlert, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for i := len(r.s.alerts) - 1; i >= 0; i-- {
+		if a := r.s.alerts[i]; a.PatientID == patientID {
+			out := *a
+			return &out, nil
+		}
+	}
+	return nil, nil
+}
+
+type memExperimentRepo struct{ s *MemoryStore }
+
+func (r *memExperimentRepo) RecordExposure(ctx context.Context, e models.ExperimentExposure) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, prev := range r.s.exposures {
+		if prev.Experiment == e.Experiment && prev.AssessmentID == e.AssessmentID {
+			return nil
+		}
+	}
+	e.ID = r.s.nextID("experiment_exposures")
+	if e.ExposedAt.IsZero() {
+		e.ExposedAt = time.Now()
+	}
+	r.s.exposures = append(r.s.exposures, e)
+	return nil
+}
+
+func (r *memExperimentRepo) Results(ctx context.Context, experiment string, followUp time.Duration) ([]models.ExperimentVariantResult, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	byVariant := map[string]*models.ExperimentVariantResult{}
+	units := map[string]map[string]bool{}
+	cutoff := time.Now().Add(-followUp)
+	for _, e := range r.s.exposures {
+		if e.Experiment != experiment {
+			continue
+		}
+		v, ok := byVariant[e.Variant]
+		if !ok {
+			v = &models.ExperimentVariantResult{Variant: e.Variant}
+			byVariant[e.Variant] = v
+			units[e.Variant] = map[string]bool{}
+		}
+		v.Exposures++
+		units[e.Variant][e.Unit] = true
+		if e.ExposedAt.After(cutoff) {
+			continue
+		}
+		v.Eligible++
+		for _, a := range r.s.assessments {
+			if a.PatientID == e.PatientID && a.ID != e.AssessmentID && !a.Draft() && !r.s.superseded(a) &&
+				a.CreatedAt.After(e.ExposedAt) && !a.CreatedAt.After(e.ExposedAt.Add(followUp)) {
+				v.FollowedUp++
+				break
+			}
+		}
+	}
+	out := []models.ExperimentVariantResult{}
+	for name, v := range byVariant {
+		v.Units = len(units[name])
+		if v.Eligible > 0 {
+			v.Rate = math.Round(1000*float64(v.FollowedUp)/float64(v.Eligible)) / 1000
+		}
+		out = append(out, *v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Variant < out[j].Variant })
+	return out, nil
+}
+
+type memAssessmentRepo struct{ s *MemoryStore }
+
+// assessmentCopy returns a as reads return it, without amendment links
+func assessmentCopy(a *models.Assessment) models.Assessment {
+	out := *a
+	out.AmendsAssessmentID = nil
+	return out
+}
+
+// superseded reports whether an amendment has replaced a. Superseded
+// versions are left out of lists, analytics and exports and stay reachable
+// through the amendment chain; callers hold the lock.
+func (s *MemoryStore) superseded(a *models.Assessment) bool {
+	return s.amended[a.ID]
+}
+
+// assessmentsWhere returns the matching assessments, newest first; callers
+// hold the lock.
+func (s *MemoryStore) assessmentsWhere(keep func(a *models.Assessment) bool) []models.Assessment {
+	out := []models.Assessment{}
+	for _, a := range s.assessments {
+		if keep(a) {
+			out = append(out, assessmentCopy(a))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].ID > out[j].ID
+		}
+		return out[i].CreatedAt.After(out[j].CreatedAt)
+	})
+	return out
+}
+
+func (r *memAssessmentRepo) ListByPatient(ctx context.Context, patientID int64) ([]models.Assessment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.s.assessmentsWhere(func(a *models.Assessment) bool { return a.PatientID == patientID && !r.s.superseded(a) }), nil
+}
+
+func (r *memAssessmentRepo) ListByPatientPage(ctx context.Context, patientID int64, params models.AssessmentPageParams) ([]models.Assessment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	list := r.s.assessmentsWhere(func(a *models.Assessment) bool {
+		if a.PatientID != patientID || r.s.superseded(a) || !pastCursor(a.CreatedAt, a.ID, params.After) {
+			return false
+		}
+		if params.MinQuality == nil && params.MaxQuality == nil {
+			return true
+		}
+		return a.Quality != nil &&
+			(params.MinQuality == nil || a.Quality.Score >= *params.MinQuality) &&
+			(params.MaxQuality == nil || a.Quality.Score <= *params.MaxQuality)
+	})
+	return list[:min(params.Limit, len(list))], nil
+}
+
+func (r *memAssessmentRepo) ListVersion(ctx context.Context, patientID int64) (models.ListVersion, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var v models.ListVersion
+	for _, a := range r.s.assessments {
+		if a.PatientID == patientID && !r.s.superseded(a) {
+			addToVersion(&v, a.ID, a.UpdatedAt)
+		}
+	}
+	return v, nil
+}
+
+func (r *memAssessmentRepo) Get(ctx context.Context, id int32, userID int32) (*models.Assessment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	a, ok := r.s.assessments[int64(id)]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	p, ok := r.s.patients[a.PatientID]
+	if !ok || !r.s.visible(p, int64(userID)) {
+		return nil, pgx.ErrNoRows
+	}
+	out := assessmentCopy(a)
+	return &out, nil
+}
+
+// addAssessment stores a new assessment; callers hold the write lock.
+func (s *MemoryStore) addAssessment(a models.Assessment) models.Assessment {
+	now := time.Now()
+	a.ID = s.nextID("assessments")
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = now
+	}
+	a.UpdatedAt = now
+	if a.Status == "" {
+		a.Status = models.AssessmentStatusFinal
+	}
+	a.AmendsAssessmentID, a.AmendmentChain = nil, nil
+	stored := a
+	s.assessments[a.ID] = &stored
+	return a
+}
+
+func (r *memAssessmentRepo) Create(ctx context.Context, a models.Assessment) (*models.Assessment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	a.CreatedAt = time.Time{}
+	out := r.s.addAssessment(a)
+	return &out, nil
+}
+
+func (r *memAssessmentRepo) CreateBatch(ctx context.Context, items []models.Assessment) ([]models.Assessment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	out := make([]models.Assessment, 0, len(items))
+	for _, a := range items {
+		a.CreatedAt = time.Time{}
+		out = append(out, r.s.addAssessment(a))
+	}
+	return out, nil
+}
+
+// owned returns the stored assessment if it belongs to one of the user's
+// patients; callers hold the lock.
+func (s *MemoryStore) owned(id, userID int64) *models.Assessment {
+	a, ok := s.assessments[id]
+	if !ok {
+		return nil
+	}
+	if p, ok := s.patients[a.PatientID]; !ok || p.UserID != userID {
+		return nil
+	}
+	return a
+}
+
+func (r *memAssessmentRepo) Update(ctx context.Context, a models.Assessment, userID int32) (*models.Assessment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	cur := r.s.owned(a.ID, int64(userID))
+	if cur == nil || cur.PatientID != a.PatientID {
+		return nil, pgx.ErrNoRows
+	}
+	a.CreatedAt, a.UpdatedAt, a.Status = cur.CreatedAt, time.Now(), cur.Status
+	a.AmendsAssessmentID, a.AmendmentChain = cur.AmendsAssessmentID, nil
+	*cur = a
+	out := assessmentCopy(cur)
+	return &out, nil
+}
+
+func (r *memAssessmentRepo) UpdateDraft(ctx context.Context, a models.Assessment, userID int32) (*models.Assessment, error) {
+	return r.saveDraft(a, models.AssessmentStatusDraft, userID)
+}
+
+func (r *memAssessmentRepo) Finalize(ctx context.Context, a models.Assessment, userID int32) (*models.Assessment, error) {
+	return r.saveDraft(a, models.AssessmentStatusFinal, userID)
+}
+
+func (r *memAssessmentRepo) saveDraft(a models.Assessment, status string, userID int32) (*models.Assessment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	cur := r.s.owned(a.ID, int64(userID))
+	if cur == nil || cur.PatientID != a.PatientID || !cur.Draft() {
+		return nil, pgx.ErrNoRows
+	}
+	a.CreatedAt, a.UpdatedAt, a.Status = cur.CreatedAt, time.Now(), status
+	a.AmendsAssessmentID, a.AmendmentChain = cur.AmendsAssessmentID, nil
+	*cur = a
+	out := assessmentCopy(cur)
+	return &out, nil
+}
+
+func (r *memAssessmentRepo) Delete(ctx context.Context, id int32, userID int32) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.s.owned(int64(id), int64(userID)) == nil {
+		return pgx.ErrNoRows
+	}
+	delete(r.s.assessments, int64(id))
+	delete(r.s.explanations, int64(id))
+	r.s.dropAttachments(func(a *models.AssessmentAttachment) bool { return a.AssessmentID == int64(id) })
+	r.s.dropTasks(func(t *models.Task) bool { return t.AssessmentID != nil && *t.AssessmentID == int64(id) })
+	return nil
+}
+
+func (r *memAssessmentRepo) ClusterCounts(ctx context.Context) ([]models.ClusterAnalytics, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	counts := map[string]int{}
+	for _, a := range r.s.assessments {
+		if !a.Draft() && !r.s.superseded(a) {
+			counts[a.Cluster]++
+		}
+	}
+	out := []models.ClusterAnalytics{}
+	for cluster, n := range counts {
+		out = append(out, models.ClusterAnalytics{Cluster: cluster, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Cluster < out[j].Cluster })
+	return out, nil
+}
+
+func (r *memAssessmentRepo) ClusterCountsInScope(ctx context.Context, scope models.CohortScope) ([]models.ClusterAnalytics, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	counts := map[string]int{}
+	for _, a := range r.s.assessments {
+		if !a.Draft() && !r.s.superseded(a) && r.s.inScope(a, scope) {
+			counts[a.Cluster]++
+		}
+	}
+	out := []models.ClusterAnalytics{}
+	for cluster, n := range counts {
+		out = append(out, models.ClusterAnalytics{Cluster: cluster, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Cluster < out[j].Cluster })
+	return out, nil
+}
+
+// trendBucket returns the start and label of the bucket t falls in, matching
+// date_trunc and the to_char formats in trendLabels.
+func trendBucket(t time.Time, granularity string) (time.Time, string) {
+	t = t.UTC()
+	switch granularity {
+	case models.TrendWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		year, week := start.ISOWeek()
+		return start, fmt.Sprintf("%d-W%02d", year, week)
+	case models.TrendQuarter:
+		q := (int(t.Month()) - 1) / 3
+		return time.Date(t.Year(), time.Month(q*3+1), 1, 0, 0, 0, 0, time.UTC), fmt.Sprintf("%d-Q%d", t.Year(), q+1)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), t.Format("2006-01")
+}
+
+// biomarker returns an assessment's value of a trend or cohort metric and
+// whether it was recorded.
+func biomarker(a *models.Assessment, name string) (float64, bool) {
+	var v float64
+	switch name {
+	case "hba1c":
+		v = a.HbA1c
+	case "fbs":
+		v = a.FBS
+	case "bmi":
+		v = a.BMI
+	case "cholesterol":
+		v = float64(a.Cholesterol)
+	case "ldl":
+		v = float64(a.LDL)
+	case "hdl":
+		v = float64(a.HDL)
+	case "triglycerides":
+		v = float64(a.Triglycerides)
+	case "systolic":
+		v = float64(a.Systolic)
+	case "diastolic":
+		v = float64(a.Diastolic)
+	case "risk_score":
+		v = float64(a.RiskScore)
+	}
+	return v, v > 0
+}
+
+func (r *memAssessmentRepo) TrendAverages(ctx context.Context, params models.TrendParams) ([]models.TrendPoint, error) {
+	granularity := params.Granularity
+	if granularity == "" {
+		granularity = models.TrendMonth
+	}
+	if _, ok := trendLabels[granularity]; !ok {
+		return nil, fmt.Errorf("unknown trend granularity %q", granularity)
+	}
+	biomarkers := params.Biomarkers
+	if len(biomarkers) == 0 {
+		biomarkers = []string{"hba1c", "fbs"}
+	}
+	for _, b := range biomarkers {
+		if (&models.TrendPoint{}).Field(b) == nil {
+			return nil, fmt.Errorf("unknown trend biomarker %q", b)
+		}
+	}
+
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	type bucket struct {
+		point models.TrendPoint
+		sums  map[string]float64
+		ns    map[string]int
+	}
+	buckets := map[time.Time]*bucket{}
+	for _, a := range r.s.assessments {
+		if (params.Start != nil && a.CreatedAt.Before(*params.Start)) || (params.End != nil && !a.CreatedAt.Before(*params.End)) {
+			continue
+		}
+		if a.Draft() || r.s.superseded(a) || !r.s.inScope(a, params.Scope) {
+			continue
+		}
+		start, label := trendBucket(a.CreatedAt, granularity)
+		b, ok := buckets[start]
+		if !ok {
+			b = &bucket{point: models.TrendPoint{Label: label, Start: start}, sums: map[string]float64{}, ns: map[string]int{}}
+			buckets[start] = b
+		}
+		b.point.Count++
+		for _, name := range biomarkers {
+			if v, ok := biomarker(a, name); ok {
+				b.sums[name] += v
+				b.ns[name]++
+			}
+		}
+	}
+	out := []models.TrendPoint{}
+	for _, b := range buckets {
+		for _, name := range biomarkers {
+			if b.ns[name] > 0 {
+				avg := b.sums[name] / float64(b.ns[name])
+				*b.point.Field(name) = &avg
+			}
+		}
+		out = append(out, b.point)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out, nil
+}
+
+func (r *memAssessmentRepo) ListAllLimited(ctx context.Context, limit int) ([]models.Assessment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := r.s.assessmentsWhere(func(a *models.Assessment) bool { return !r.s.superseded(a) })
+	return out[:min(limit, len(out))], nil
+}
+
+func (r *memAssessmentRepo) ListAllLimitedByUser(ctx context.Context, userID int32, limit int) ([]models.Assessment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := r.s.assessmentsWhere(func(a *models.Assessment) bool {
+		p, ok := r.s.patients[a.PatientID]
+		return ok && p.UserID == int64(userID) && !r.s.superseded(a)
+	})
+	return out[:min(limit, len(out))], nil
+}
+
+// EachLimitedByUser calls fn outside the lock, on a copy of the assessments
+func (r *memAssessmentRepo) EachLimitedByUser(ctx context.Context, userID int32, limit int, fn func(models.Assessment) error) error {
+	r.s.mu.RLock()
+	assessments := r.s.assessmentsWhere(func(a *models.Assessment) bool {
+		p, ok := r.s.patients[a.PatientID]
+		return ok && p.UserID == int64(userID) && !a.Draft() && !r.s.superseded(a)
+	})
+	r.s.mu.RUnlock()
+	for _, a := range assessments[:min(limit, len(assessments))] {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EachLimitedByClinic calls fn outside the lock, on a copy of the assessments
+func (r *memAssessmentRepo) EachLimitedByClinic(ctx context.Context, clinicID int32, limit int, fn func(models.Assessment) error) error {
+	r.s.mu.RLock()
+	assessments := r.s.assessmentsWhere(func(a *models.Assessment) bool {
+		p, ok := r.s.patients[a.PatientID]
+		return ok && p.ClinicID != nil && *p.ClinicID == int64(clinicID) && !a.Draft() && !r.s.superseded(a)
+	})
+	r.s.mu.RUnlock()
+	for _, a := range assessments[:min(limit, len(assessments))] {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memAssessmentRepo) GetTrend(ctx context.Context, patientID int64) ([]models.AssessmentTrend, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	list := r.s.assessmentsWhere(func(a *models.Assessment) bool { return a.PatientID == patientID && !a.Draft() && !r.s.superseded(a) })
+	out := make([]models.AssessmentTrend, 0, len(list))
+	for i := len(list) - 1; i >= 0; i-- {
+		a := list[i]
+		t := models.AssessmentTrend{
+			ID: a.ID, CreatedAt: a.CreatedAt, Cluster: a.Cluster, HbA1c: a.HbA1c, BMI: a.BMI, FBS: a.FBS,
+			Triglycerides: a.Triglycerides, LDL: a.LDL, HDL: a.HDL,
+		}
+		if a.RiskScore > 0 {
+			rs := float64(a.RiskScore) / 100.0
+			t.RiskScore = &rs
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func (r *memAssessmentRepo) SetExplanation(ctx context.Context, id int32, explanation map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if explanation == nil {
+		delete(r.s.explanations, int64(id))
+	} else {
+		r.s.explanations[int64(id)] = explanation
+	}
+	return nil
+}
+
+func (r *memAssessmentRepo) GetExplanation(ctx context.Context, id int32) (map[string]interface{}, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.s.explanations[int64(id)], nil
+}
+
+func (r *memAssessmentRepo) ListSince(ctx context.Context, since time.Time, afterID int64, limit int) ([]models.Assessment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := r.s.assessmentsWhere(func(a *models.Assessment) bool {
+		return !a.Draft() && !a.CreatedAt.Before(since) && a.ID > afterID
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out[:min(limit, len(out))], nil
+}
+
+func (r *memAssessmentRepo) SetValidationStatus(ctx context.Context, id int32, status string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	a, ok := r.s.assessments[int64(id)]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	a.ValidationStatus = status
+	return nil
+}
+
+func (r *memAssessmentRepo) SetQuality(ctx context.Context, id int32, q models.DataQuality) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	a, ok := r.s.assessments[int64(id)]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	a.Quality = &q
+	return nil
+}
+
+func (r *memAssessmentRepo) QualityByClinician(ctx context.Context, userID *int32, lowBelow int) ([]models.ClinicianDataQuality, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	byUser := map[int64]*models.ClinicianDataQuality{}
+	for _, a := range r.s.assessments {
+		p, ok := r.s.patients[a.PatientID]
+		if a.Quality == nil || !ok || r.s.superseded(a) || (userID != nil && p.UserID != int64(*userID)) {
+			continue
+		}
+		q, ok := byUser[p.UserID]
+		if !ok {
+			q = &models.ClinicianDataQuality{UserID: p.UserID}
+			if u, ok := r.s.users[p.UserID]; ok {
+				q.Email = u.Email
+			}
+			byUser[p.UserID] = q
+		}
+		q.Assessments++
+		q.AvgScore += float64(a.Quality.Score)
+		q.AvgCompleteness += a.Quality.Completeness
+		q.OutOfRange += a.Quality.OutOfRange
+		if a.SelfReported {
+			q.SelfReported++
+		}
+		if a.Quality.Score < lowBelow {
+			q.LowQuality++
+		}
+	}
+	out := []models.ClinicianDataQuality{}
+	for _, q := range byUser {
+		n := float64(q.Assessments)
+		q.AvgScore = math.Round(q.AvgScore/n*10) / 10
+		q.AvgCompleteness = math.Round(q.AvgCompleteness/n*100) / 100
+		out = append(out, *q)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].AvgScore != out[j].AvgScore {
+			return out[i].AvgScore < out[j].AvgScore
+		}
+		return out[i].UserID < out[j].UserID
+	})
+	return out, nil
+}
+
+func (r *memAssessmentRepo) Amend(ctx context.Context, originalID int64, a models.Assessment) (*models.Assessment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.assessments[originalID]; !ok {
+		return nil, pgx.ErrNoRows
+	}
+	for _, other := range r.s.assessments {
+		if other.AmendsAssessmentID != nil && *other.AmendsAssessmentID == originalID {
+			return nil, ErrAlreadyAmended
+		}
+	}
+	a.CreatedAt = time.Time{}
+	out := r.s.addAssessment(a)
+	r.s.assessments[out.ID].AmendsAssessmentID = &originalID
+	r.s.amended[originalID] = true
+	out.AmendsAssessmentID = &originalID
+	return &out, nil
+}
+
+func (r *memAssessmentRepo) AmendmentChain(ctx context.Context, id int64) ([]models.Assessment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	a, ok := r.s.assessments[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	// Walk up to the original, then down through its amendments
+	for a.AmendsAssessmentID != nil {
+		up, ok := r.s.assessments[*a.AmendsAssessmentID]
+		if !ok {
+			break
+		}
+		a = up
+	}
+	chain := []models.Assessment{*a}
+	for next := a; next != nil; {
+		cur := next
+		next = nil
+		for _, other := range r.s.assessments {
+			if other.AmendsAssessmentID != nil && *other.AmendsAssessmentID == cur.ID {
+				next = other
+				chain = append(chain, *other)
+				break
+			}
+		}
+	}
+	return chain, nil
+}
+
+func (r *memAssessmentRepo) ListPendingPrediction(ctx context.Context, limit int) ([]models.Assessment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	out := r.s.assessmentsWhere(func(a *models.Assessment) bool { return a.Cluster == models.ClusterPendingPrediction })
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *memAssessmentRepo) CompletePrediction(ctx context.Context, a models.Assessment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	cur, ok := r.s.assessments[a.ID]
+	if !ok || cur.Cluster != models.ClusterPendingPrediction {
+		return pgx.ErrNoRows
+	}
+	cur.Cluster, cur.RiskScore, cur.ModelVersion, cur.DatasetHash = a.Cluster, a.RiskScore, a.ModelVersion, a.DatasetHash
+	cur.UpdatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,108 @@
+// memory_seed.go: Sample users, clinic, patients and assessments for the in-memory store.
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/ml"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// Demo accounts created by SeedDemo; the clinician matches cmd/seed.
+const (
+	DemoClinicianEmail = "clinician@example.com"
+	DemoAdminEmail     = "admin@example.com"
+	DemoPassword       = "password123"
+)
+
+// demoVisit is one backdated assessment of a demo patient
+type demoVisit struct {
+	monthsAgo int
+	fbs       float64
+	hba1c     float64
+	bmi       float64
+	systolic  int
+	diastolic int
+}
+
+var demoPatients = []struct {
+	patient models.Patient
+	visits  []demoVisit
+}{
+	{models.Patient{Name: "Maria Santos", Age: 52, MenopauseStatus: "postmenopausal", YearsMenopause: 3, Activity: "moderate", Smoking: "never", Hypertension: "no", HeartDisease: "no", MRN: "DEMO-001"},
+		[]demoVisit{{9, 118, 6.1, 31.2, 134, 86}, {5, 124, 6.4, 31.8, 138, 88}, {1, 131, 6.8, 32.1, 142, 90}}},
+	{models.Patient{Name: "Elena Cruz", Age: 47, MenopauseStatus: "perimenopausal", Activity: "high", Smoking: "never", Hypertension: "no", HeartDisease: "no", MRN: "DEMO-002"},
+		[]demoVisit{{8, 96, 5.4, 23.5, 118, 76}, {2, 99, 5.6, 23.9, 120, 78}}},
+	{models.Patient{Name: "Rosa Reyes", Age: 61, MenopauseStatus: "postmenopausal", YearsMenopause: 11, Activity: "low", Smoking: "former", Hypertension: "yes", HeartDisease: "no", MRN: "DEMO-003"},
+		[]demoVisit{{10, 142, 7.1, 25.4, 146, 92}, {6, 138, 6.9, 25.1, 144, 90}, {3, 135, 6.7, 24.8, 140, 88}, {0, 129, 6.6, 24.6, 136, 86}}},
+	{models.Patient{Name: "Ana Villanueva", Age: 56, MenopauseStatus: "postmenopausal", YearsMenopause: 6, Activity: "moderate", Smoking: "never", Hypertension: "no", HeartDisease: "no", MRN: "DEMO-004"},
+		[]demoVisit{{7, 108, 5.9, 28.3, 128, 82}, {1, 104, 5.8, 27.6, 126, 80}}},
+	{models.Patient{Name: "Carmen Lopez", Age: 66, MenopauseStatus: "postmenopausal", YearsMenopause: 16, Activity: "low", Smoking: "current", Hypertension: "yes", HeartDisease: "yes", MRN: "DEMO-005"},
+		[]demoVisit{{4, 151, 7.4, 33.0, 152, 94}}},
+	{models.Patient{Name: "Luz Garcia", Age: 43, MenopauseStatus: "premenopausal", Activity: "high", Smoking: "never", Hypertension: "no", HeartDisease: "no", MRN: "DEMO-006"},
+		[]demoVisit{{3, 90, 5.2, 22.1, 112, 72}}},
+}
+
+// SeedDemo adds a clinician and an admin, both signing in with DemoPassword
+// (passwordHash is its bcrypt hash), a clinic they both belong to, and
+// patients with a few months of assessments scored by the mock predictor.
+func (s *MemoryStore) SeedDemo(passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clinician, err := s.createUser(models.User{Email: DemoClinicianEmail, PasswordHash: passwordHash, Role: "clinician"}, true)
+	if err != nil {
+		return err
+	}
+	admin, err := s.createUser(models.User{Email: DemoAdminEmail, PasswordHash: passwordHash, Role: "admin"}, true)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	clinic := &memClinic{
+		Clinic:         models.Clinic{ID: s.nextID("clinics"), Name: "Demo Clinic", Address: "1 Example Street", CreatedAt: now, UpdatedAt: now},
+		validationMode: models.ValidationModeAdvisory,
+		photosEnabled:  true,
+		baselinePolicy: models.BaselinePolicyFlag,
+		locale:         "en",
+	}
+	s.clinics[clinic.ID] = clinic
+	s.members = append(s.members,
+		&memMember{clinicID: clinic.ID, userID: admin.ID, role: models.ClinicRoleAdmin, joinedAt: now},
+		&memMember{clinicID: clinic.ID, userID: clinician.ID, role: models.ClinicRoleMember, joinedAt: now})
+
+	predictor := ml.NewMockPredictor()
+	for _, demo := range demoPatients {
+		p := demo.patient
+		p.ID = s.nextID("patients")
+		p.UserID = clinician.ID
+		p.CreatedAt = now.AddDate(0, -demo.visits[0].monthsAgo, -1)
+		p.UpdatedAt = p.CreatedAt
+		last := demo.visits[len(demo.visits)-1]
+		p.BMI, p.BPSystolic, p.BPDiastolic = last.bmi, last.systolic, last.diastolic
+		s.patients[p.ID] = &p
+
+		for _, v := range demo.visits {
+			a := models.Assessment{
+				PatientID:    p.ID,
+				FBS:          v.fbs,
+				HbA1c:        v.hba1c,
+				BMI:          v.bmi,
+				Systolic:     v.systolic,
+				Diastolic:    v.diastolic,
+				Activity:     p.Activity,
+				Smoking:      p.Smoking,
+				Hypertension: p.Hypertension,
+				HeartDisease: p.HeartDisease,
+				ModelVersion: "demo",
+				CreatedAt:    now.AddDate(0, -v.monthsAgo, 0),
+			}
+			a.Cluster, a.RiskScore = predictor.Predict(context.Background(), a)
+			quality := ml.AssessQuality(a)
+			a.Quality = &quality
+			s.addAssessment(a)
+		}
+	}
+	return nil
+}
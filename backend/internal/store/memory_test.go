@@ -0,0 +1,231 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+var _ Store = (*MemoryStore)(nil)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	if err := s.SeedDemo("hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	clinician, err := s.Users().FindByEmail(ctx, DemoClinicianEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Users().Create(ctx, models.User{Email: DemoClinicianEmail, Role: "clinician"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("duplicate email: err = %v, want ErrConflict", err)
+	}
+
+	patients, err := s.Patients().List(ctx, int32(clinician.ID))
+	if err != nil || len(patients) != len(demoPatients) {
+		t.Fatalf("seeded patients = %d, err = %v", len(patients), err)
+	}
+	assessments, err := s.Assessments().ListByPatient(ctx, patients[0].ID)
+	if err != nil || len(assessments) == 0 || assessments[0].Cluster == "" || assessments[0].Quality == nil {
+		t.Fatalf("seeded assessments = %+v, err = %v", assessments, err)
+	}
+
+	// Another user sees neither the patients nor their assessments
+	other, err := s.Users().Create(ctx, models.User{Email: "other@example.com", Role: "clinician"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Patients().Get(ctx, int32(patients[0].ID), int32(other.ID)); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("foreign patient: err = %v, want ErrNoRows", err)
+	}
+	if _, err := s.Assessments().Get(ctx, int32(assessments[0].ID), int32(other.ID)); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("foreign assessment: err = %v, want ErrNoRows", err)
+	}
+
+	// Counts restricted to a clinic cover only its members' patients
+	clinic, err := s.Clinics().Create(ctx, "North", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Clinics().AddMember(ctx, int32(clinic.ID), int32(other.ID), models.ClinicRoleMember); err != nil {
+		t.Fatal(err)
+	}
+	inClinic := models.CohortScope{ClinicIDs: []int32{int32(clinic.ID)}}
+	if counts, err := s.Assessments().ClusterCountsInScope(ctx, inClinic); err != nil || len(counts) != 0 {
+		t.Errorf("cluster counts of an empty clinic = %+v, err = %v", counts, err)
+	}
+	if counts, _ := s.Assessments().ClusterCountsInScope(ctx, models.CohortScope{}); len(counts) == 0 {
+		t.Error("unrestricted cluster counts are empty")
+	}
+
+	// Amending twice conflicts, and the chain lists both versions
+	clusterTotal := func() int {
+		counts, _ := s.Assessments().ClusterCounts(ctx)
+		n := 0
+		for _, c := range counts {
+			n += c.Count
+		}
+		return n
+	}
+	counted := clusterTotal()
+	amended := assessments[0]
+	amended.HbA1c = 5.5
+	amendment, err := s.Assessments().Amend(ctx, assessments[0].ID, amended)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Assessments().Amend(ctx, assessments[0].ID, amended); !errors.Is(err, ErrAlreadyAmended) {
+		t.Errorf("second amendment: err = %v, want ErrAlreadyAmended", err)
+	}
+	chain, err := s.Assessments().AmendmentChain(ctx, amendment.ID)
+	if err != nil || len(chain) != 2 || chain[1].AmendsAssessmentID == nil || *chain[1].AmendsAssessmentID != assessments[0].ID {
+		t.Errorf("chain = %+v, err = %v", chain, err)
+	}
+	// The amendment replaces the original in lists and analytics
+	listed, _ := s.Assessments().ListByPatient(ctx, patients[0].ID)
+	if len(listed) != len(assessments) || listed[0].ID != amendment.ID {
+		t.Errorf("listed after amendment = %d (newest %d), want %d with %d first", len(listed), listed[0].ID, len(assessments), amendment.ID)
+	}
+	if n := clusterTotal(); n != counted {
+		t.Errorf("cluster counts after amendment = %d, want %d", n, counted)
+	}
+
+	// Notes carry their author's email; notes, attachments, medications and
+	// follow-ups go with the patient
+	authorID := clinician.ID
+	note, err := s.PatientNotes().Create(ctx, models.PatientNote{PatientID: patients[0].ID, AuthorID: &authorID, Category: models.NoteGeneral, Body: "note"})
+	if err != nil || note.AuthorEmail != DemoClinicianEmail {
+		t.Fatalf("note = %+v, err = %v", note, err)
+	}
+	if _, err := s.AssessmentAttachments().Create(ctx, models.AssessmentAttachment{AssessmentID: assessments[0].ID, PatientID: patients[0].ID, StorageKey: "k"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PatientMedications().Create(ctx, models.PatientMedication{PatientID: patients[0].ID, Name: "Metformin", StartedAt: time.Now(), Adherent: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FollowUps().Create(ctx, models.FollowUp{PatientID: patients[0].ID, DueAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Tasks().Create(ctx, models.Task{UserID: clinician.ID, Kind: models.TaskManual, Title: "Call", PatientID: &patients[0].ID}); err != nil {
+		t.Fatal(err)
+	}
+	tag, err := s.Tags().Create(ctx, models.Tag{UserID: clinician.ID, Name: "GDM history"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Tags().Attach(ctx, tag.ID, patients[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Patients().Delete(ctx, int32(patients[0].ID), int32(clinician.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if notes, _ := s.PatientNotes().List(ctx, patients[0].ID); len(notes) != 0 {
+		t.Errorf("notes of a deleted patient = %+v", notes)
+	}
+	if attachments, _ := s.AssessmentAttachments().ListByPatient(ctx, patients[0].ID); len(attachments) != 0 {
+		t.Errorf("attachments of a deleted patient = %+v", attachments)
+	}
+	if medications, _ := s.PatientMedications().List(ctx, patients[0].ID); len(medications) != 0 {
+		t.Errorf("medications of a deleted patient = %+v", medications)
+	}
+	if followUps, _ := s.FollowUps().List(ctx, patients[0].ID); len(followUps) != 0 {
+		t.Errorf("follow-ups of a deleted patient = %+v", followUps)
+	}
+	if tasks, _ := s.Tasks().List(ctx, clinician.ID, models.TaskListParams{}); len(tasks) != 0 {
+		t.Errorf("tasks about a deleted patient = %+v", tasks)
+	}
+	if tag, _ := s.Tags().Get(ctx, tag.ID, clinician.ID); tag == nil || tag.PatientCount != 0 {
+		t.Errorf("tag of a deleted patient = %+v, want it kept with no patients", tag)
+	}
+
+	groups, err := s.Cohort().StatsByCluster(ctx)
+	if err != nil || len(groups) == 0 {
+		t.Errorf("cohort groups = %+v, err = %v", groups, err)
+	}
+}
+
+func TestMemoryStore_Drafts(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	user, _ := s.Users().Create(ctx, models.User{Email: "c@example.com", Role: "clinician"})
+	patient, _ := s.Patients().Create(ctx, models.Patient{UserID: user.ID, Name: "Ana"})
+	final, _ := s.Assessments().Create(ctx, models.Assessment{PatientID: patient.ID, Cluster: "SIRD", RiskScore: 70})
+	draft, _ := s.Assessments().Create(ctx, models.Assessment{PatientID: patient.ID, HbA1c: 6.1, Status: models.AssessmentStatusDraft})
+	if final.Status != models.AssessmentStatusFinal || !draft.Draft() {
+		t.Fatalf("statuses = %q, %q", final.Status, draft.Status)
+	}
+
+	counted := func() int {
+		n, _ := s.Cohort().TotalAssessmentCount(ctx)
+		return n
+	}
+	if n := counted(); n != 1 {
+		t.Errorf("assessments counted with a draft = %d, want 1", n)
+	}
+	if trend, _ := s.Assessments().GetTrend(ctx, patient.ID); len(trend) != 1 {
+		t.Errorf("trend = %+v, want the final assessment only", trend)
+	}
+
+	// Only drafts can be saved as drafts or finalized
+	if _, err := s.Assessments().UpdateDraft(ctx, *final, int32(user.ID)); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("UpdateDraft of a final assessment: err = %v, want ErrNoRows", err)
+	}
+	draft.BMI = 24
+	if saved, err := s.Assessments().UpdateDraft(ctx, *draft, int32(user.ID)); err != nil || !saved.Draft() || saved.BMI != 24 {
+		t.Fatalf("UpdateDraft = %+v, %v", saved, err)
+	}
+	if n := counted(); n != 1 {
+		t.Errorf("assessments counted after saving a draft = %d, want 1", n)
+	}
+	draft.Cluster, draft.RiskScore = "MOD", 40
+	if _, err := s.Assessments().Finalize(ctx, *draft, int32(user.ID+1)); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Finalize by another user: err = %v, want ErrNoRows", err)
+	}
+	finalized, err := s.Assessments().Finalize(ctx, *draft, int32(user.ID))
+	if err != nil || finalized.Draft() || finalized.Cluster != "MOD" {
+		t.Fatalf("Finalize = %+v, %v", finalized, err)
+	}
+	if n := counted(); n != 2 {
+		t.Errorf("assessments counted after finalizing = %d, want 2", n)
+	}
+	if _, err := s.Assessments().Finalize(ctx, *draft, int32(user.ID)); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("second Finalize: err = %v, want ErrNoRows", err)
+	}
+}
+
+func TestMemoryStore_PurgeKeepsClinicPatients(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	user, _ := s.Users().Create(ctx, models.User{Email: "c@example.com", Role: "clinician"})
+	colleague, _ := s.Users().Create(ctx, models.User{Email: "d@example.com", Role: "clinician", IsActive: true})
+	clinic, _ := s.Clinics().Create(ctx, "North", "")
+	_ = s.Clinics().AddMember(ctx, int32(clinic.ID), int32(user.ID), models.ClinicRoleMember)
+	patient, _ := s.Patients().Create(ctx, models.Patient{UserID: user.ID, Name: "Ana"})
+	_ = s.Users().Deactivate(ctx, int32(user.ID))
+	d, _ := s.UserDeletions().Schedule(ctx, user.ID, "admin@example.com", time.Now())
+
+	if _, err := s.UserDeletions().Purge(ctx, *d, models.RetentionAnonymize, "system"); !errors.Is(err, ErrClinicPatients) {
+		t.Fatalf("purge of a clinic member: err = %v, want ErrClinicPatients", err)
+	}
+	if p, _ := s.Patients().Get(ctx, int32(patient.ID), int32(user.ID)); p == nil || p.Name != "Ana" {
+		t.Fatalf("clinic patient was changed: %+v", p)
+	}
+
+	// Once the patient is transferred the user's own data can go
+	if err := s.Patients().Transfer(ctx, patient.ID, int32(user.ID), int32(colleague.ID), int32(colleague.ID)); err != nil {
+		t.Fatal(err)
+	}
+	purge, err := s.UserDeletions().Purge(ctx, *d, models.RetentionAnonymize, "system")
+	if err != nil || purge == nil || purge.Patients != 0 {
+		t.Fatalf("purge after transfer = %+v, %v", purge, err)
+	}
+	if p, _ := s.Patients().Get(ctx, int32(patient.ID), int32(colleague.ID)); p == nil || p.Name != "Ana" {
+		t.Errorf("transferred patient was anonymized: %+v", p)
+	}
+}
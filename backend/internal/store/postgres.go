@@ -3,10 +3,14 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/skufu/DianaV2/backend/internal/models"
@@ -16,6 +20,8 @@ import (
 type PostgresStore struct {
 	pool *pgxpool.Pool
 	q    *sqlcgen.Queries
+	// cohortViews is set by UseCohortStatsViews
+	cohortViews bool
 }
 
 func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
@@ -37,15 +43,15 @@ func (s *PostgresStore) Users() UserRepository {
 }
 
 func (s *PostgresStore) Patients() PatientRepository {
-	return &pgPatientRepo{q: s.q}
+	return &pgPatientRepo{q: s.q, pool: s.pool}
 }
 
 func (s *PostgresStore) Assessments() AssessmentRepository {
-	return &pgAssessmentRepo{q: s.q}
+	return &pgAssessmentRepo{q: s.q, pool: s.pool}
 }
 
 func (s *PostgresStore) RefreshTokens() RefreshTokenRepository {
-	return &pgRefreshTokenRepo{q: s.q}
+	return &pgRefreshTokenRepo{q: s.q, pool: s.pool}
 }
 
 type pgUserRepo struct {
@@ -62,12 +68,16 @@ func (r *pgUserRepo) FindByEmail(ctx context.Context, email string) (*models.Use
 		return nil, err
 	}
 	return &models.User{
-		ID:           int64(row.ID),
-		Email:        row.Email,
-		PasswordHash: row.PasswordHash,
-		Role:         row.Role,
-		CreatedAt:    row.CreatedAt.Time,
-		UpdatedAt:    row.UpdatedAt.Time,
+		ID:                  int64(row.ID),
+		Email:               row.Email,
+		PasswordHash:        row.PasswordHash,
+		Role:                row.Role,
+		IsActive:            row.IsActive,
+		EmailVerifiedAt:     timePtr(row.EmailVerifiedAt),
+		FailedLoginAttempts: int(row.FailedLoginAttempts),
+		LockedUntil:         timePtr(row.LockedUntil),
+		CreatedAt:           row.CreatedAt.Time,
+		UpdatedAt:           row.UpdatedAt.Time,
 	}, nil
 }
 
@@ -80,16 +90,23 @@ func (r *pgUserRepo) FindByID(ctx context.Context, id int32) (*models.User, erro
 		return nil, err
 	}
 	return &models.User{
-		ID:           int64(row.ID),
-		Email:        row.Email,
-		PasswordHash: row.PasswordHash,
-		Role:         row.Role,
-		CreatedAt:    row.CreatedAt.Time,
-		UpdatedAt:    row.UpdatedAt.Time,
+		ID:                  int64(row.ID),
+		Email:               row.Email,
+		PasswordHash:        row.PasswordHash,
+		Role:                row.Role,
+		IsActive:            row.IsActive,
+		EmailVerifiedAt:     timePtr(row.EmailVerifiedAt),
+		FailedLoginAttempts: int(row.FailedLoginAttempts),
+		LockedUntil:         timePtr(row.LockedUntil),
+		CreatedAt:           row.CreatedAt.Time,
+		UpdatedAt:           row.UpdatedAt.Time,
 	}, nil
 }
 
-type pgPatientRepo struct{ q *sqlcgen.Queries }
+type pgPatientRepo struct {
+	q    *sqlcgen.Queries
+	pool *pgxpool.Pool
+}
 
 func (r *pgPatientRepo) List(ctx context.Context, userID int32) ([]models.Patient, error) {
 	if r.q == nil {
@@ -125,6 +142,10 @@ func (r *pgPatientRepo) Create(ctx context.Context, p models.Patient) (*models.P
 		Ldl:             intToPgInt(p.LDL),
 		Hdl:             intToPgInt(p.HDL),
 		Triglycerides:   intToPgInt(p.Triglycerides),
+		Mrn:             textToPg(p.MRN),
+		HeightCm:        optionalNumeric(p.HeightCM),
+		WeightKg:        optionalNumeric(p.WeightKG),
+		BmiSource:       textToPg(p.BMISource),
 	})
 	if err != nil {
 		return nil, err
@@ -152,7 +173,17 @@ func (r *pgPatientRepo) Update(ctx context.Context, p models.Patient) (*models.P
 	if r.q == nil {
 		return nil, errors.New("db not configured")
 	}
-	row, err := r.q.UpdatePatient(ctx, sqlcgen.UpdatePatientParams{
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := lockPatientSnapshot(ctx, tx, p.ID, p.UserID)
+	if err != nil {
+		return nil, err
+	}
+	row, err := r.q.WithTx(tx).UpdatePatient(ctx, sqlcgen.UpdatePatientParams{
 		ID:              int32(p.ID),
 		UserID:          int32(p.UserID),
 		Name:            p.Name,
@@ -172,10 +203,20 @@ func (r *pgPatientRepo) Update(ctx context.Context, p models.Patient) (*models.P
 		Ldl:             intToPgInt(p.LDL),
 		Hdl:             intToPgInt(p.HDL),
 		Triglycerides:   intToPgInt(p.Triglycerides),
+		Mrn:             textToPg(p.MRN),
+		HeightCm:        optionalNumeric(p.HeightCM),
+		WeightKg:        optionalNumeric(p.WeightKG),
+		BmiSource:       textToPg(p.BMISource),
 	})
 	if err != nil {
 		return nil, err
 	}
+	if err := recordPatientVersion(ctx, tx, p.ID, p.UserID, before); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
 	res := mapUpdatePatientRow(row)
 	return &res, nil
 }
@@ -204,7 +245,189 @@ func (r *pgPatientRepo) ListAllLimited(ctx context.Context, userID int32, limit
 	return mapPatientLimitedRows(rows), nil
 }
 
-type pgAssessmentRepo struct{ q *sqlcgen.Queries }
+// Typeahead matches patients by name substring or MRN prefix. Exact MRN hits
+// rank first, then trigram similarity on the name.
+func (r *pgPatientRepo) Typeahead(ctx context.Context, userID int32, q string, limit int) ([]models.PatientMatch, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	query := `
+		SELECT p.id, p.name, COALESCE(p.age, 0), COALESCE(p.mrn, ''), lv.last_visit
+		FROM patients p
+		LEFT JOIN LATERAL (
+			SELECT MAX(a.created_at) AS last_visit
+			FROM assessments a
+			WHERE a.patient_id = p.id AND a.status = 'final'
+			  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
+		) lv ON true
+		WHERE p.user_id = $1
+		  AND (p.name ILIKE '%' || $2 || '%' OR p.mrn ILIKE $2 || '%')
+		ORDER BY (p.mrn IS NOT NULL AND lower(p.mrn) = lower($3)) DESC,
+		         similarity(p.name, $3) DESC,
+		         p.name
+		LIMIT $4
+	`
+	rows, err := r.pool.Query(ctx, query, userID, escapeLike(q), q, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.PatientMatch{}
+	for rows.Next() {
+		var m models.PatientMatch
+		var lastVisit pgtype.Timestamptz
+		if err := rows.Scan(&m.ID, &m.Name, &m.Age, &m.MRN, &lastVisit); err != nil {
+			return nil, err
+		}
+		if lastVisit.Valid {
+			t := lastVisit.Time
+			m.LastVisit = &t
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// patientSortColumns maps PatientListParams.Sort values to SQL expressions.
+var patientSortColumns = map[string]string{
+	"name":       "p.name",
+	"age":        "p.age",
+	"created_at": "p.created_at",
+	"updated_at": "p.updated_at",
+	"risk_score": "la.risk_score",
+}
+
+// patientsWithLatest joins each patient to their latest final assessment as
+// la, for the patient list and its version
+const patientsWithLatest = `patients p
+		LEFT JOIN LATERAL (
+			SELECT a.id, a.cluster, a.risk_score, a.fbs, a.hba1c, a.created_at, a.updated_at
+			FROM assessments a
+			WHERE a.patient_id = p.id AND a.status = 'final'
+			  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
+			ORDER BY a.created_at DESC
+			LIMIT 1
+		) la ON true`
+
+// patientWithLatestColumns selects a patient and the summary of their latest
+// assessment from patientsWithLatest, in scanPatientWithLatest's order.
+const patientWithLatestColumns = `p.id, p.user_id, p.name, COALESCE(p.age, 0), COALESCE(p.menopause_status, ''),
+		       COALESCE(p.years_menopause, 0), COALESCE(p.bmi, 0)::float8, COALESCE(p.bp_systolic, 0),
+		       COALESCE(p.bp_diastolic, 0), COALESCE(p.activity, ''), COALESCE(p.phys_activity, false),
+		       COALESCE(p.smoking, ''), COALESCE(p.hypertension, ''), COALESCE(p.heart_disease, ''),
+		       COALESCE(p.family_history, false), COALESCE(p.chol, 0), COALESCE(p.ldl, 0),
+		       COALESCE(p.hdl, 0), COALESCE(p.triglycerides, 0), COALESCE(p.mrn, ''),
+		       COALESCE(p.height_cm, 0)::float8, COALESCE(p.weight_kg, 0)::float8, COALESCE(p.bmi_source, ''),
+		       p.created_at, p.updated_at, p.clinic_id,
+		       COALESCE(la.cluster, ''), COALESCE(la.risk_score, 0),
+		       COALESCE(la.fbs, 0)::float8, COALESCE(la.hba1c, 0)::float8, la.created_at`
+
+func scanPatientWithLatest(row pgx.Row) (models.PatientWithLatest, error) {
+	var pw models.PatientWithLatest
+	p := &pw.Patient
+	var lastVisit pgtype.Timestamptz
+	var clinicID pgtype.Int4
+	if err := row.Scan(&p.ID, &p.UserID, &p.Name, &p.Age, &p.MenopauseStatus,
+		&p.YearsMenopause, &p.BMI, &p.BPSystolic,
+		&p.BPDiastolic, &p.Activity, &p.PhysActivity,
+		&p.Smoking, &p.Hypertension, &p.HeartDisease,
+		&p.FamilyHistory, &p.Chol, &p.LDL,
+		&p.HDL, &p.Triglycerides, &p.MRN,
+		&p.HeightCM, &p.WeightKG, &p.BMISource,
+		&p.CreatedAt, &p.UpdatedAt, &clinicID,
+		&pw.Cluster, &pw.RiskScore,
+		&pw.FBS, &pw.HbA1c, &lastVisit); err != nil {
+		return models.PatientWithLatest{}, err
+	}
+	p.ClinicID = int4Ptr(clinicID)
+	if lastVisit.Valid {
+		t := lastVisit.Time
+		pw.LastVisit = &t
+	}
+	return pw, nil
+}
+
+// ListWithLatestAssessmentPaginated lists one page of a user's patients, each
+// joined with summary fields from their latest assessment in the same query.
+// Cluster and risk filters match against that latest assessment. Returns the
+// page and the total number of matching patients.
+func (r *pgPatientRepo) ListWithLatestAssessmentPaginated(ctx context.Context, userID int32, params models.PatientListParams) ([]models.PatientWithLatest, int, error) {
+	if r.pool == nil {
+		return nil, 0, errors.New("db not configured")
+	}
+
+	page := params.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := params.PageSize
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+	offset := (page - 1) * pageSize
+
+	where := patientListFilter(userID, params)
+	countQuery, args, err := psql.Select("COUNT(*)").From(patientsWithLatest).Where(where).ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+	var total int
+	if err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	list := psql.Select(patientWithLatestColumns).
+		From(patientsWithLatest).
+		Where(where)
+
+	// Sort column and direction come from a whitelist, never from raw input
+	if col, ok := patientSortColumns[params.Sort]; ok {
+		dir := "ASC"
+		if params.Order == "desc" {
+			dir = "DESC"
+		}
+		list = list.OrderBy(col+" "+dir+" NULLS LAST", "p.id DESC")
+	} else {
+		list = list.OrderBy("p.id DESC")
+	}
+	query, args, err := list.Limit(uint64(pageSize)).Offset(uint64(offset)).ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := []models.PatientWithLatest{}
+	for rows.Next() {
+		pw, err := scanPatientWithLatest(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, pw)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\\`, `\\\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+type pgAssessmentRepo struct {
+	q    *sqlcgen.Queries
+	pool *pgxpool.Pool
+}
 
 func (r *pgAssessmentRepo) ListByPatient(ctx context.Context, patientID int64) ([]models.Assessment, error) {
 	if r.q == nil {
@@ -221,28 +444,7 @@ func (r *pgAssessmentRepo) Create(ctx context.Context, a models.Assessment) (*mo
 	if r.q == nil {
 		return nil, errors.New("db not configured")
 	}
-	row, err := r.q.CreateAssessment(ctx, sqlcgen.CreateAssessmentParams{
-		PatientID:        int64ToPgInt(a.PatientID),
-		Fbs:              floatToNumeric(a.FBS),
-		Hba1c:            floatToNumeric(a.HbA1c),
-		Cholesterol:      intToPgInt(a.Cholesterol),
-		Ldl:              intToPgInt(a.LDL),
-		Hdl:              intToPgInt(a.HDL),
-		Triglycerides:    intToPgInt(a.Triglycerides),
-		Systolic:         intToPgInt(a.Systolic),
-		Diastolic:        intToPgInt(a.Diastolic),
-		Activity:         textToPg(a.Activity),
-		HistoryFlag:      boolToPg(a.HistoryFlag),
-		Smoking:          textToPg(a.Smoking),
-		Hypertension:     textToPg(a.Hypertension),
-		HeartDisease:     textToPg(a.HeartDisease),
-		Bmi:              floatToNumeric(a.BMI),
-		Cluster:          textToPg(a.Cluster),
-		RiskScore:        intToPgInt(a.RiskScore),
-		ModelVersion:     textToPg(a.ModelVersion),
-		DatasetHash:      textToPg(a.DatasetHash),
-		ValidationStatus: textToPg(a.ValidationStatus),
-	})
+	row, err := r.q.CreateAssessment(ctx, createAssessmentParams(a))
 	if err != nil {
 		return nil, err
 	}
@@ -250,6 +452,31 @@ func (r *pgAssessmentRepo) Create(ctx context.Context, a models.Assessment) (*mo
 	return &res, nil
 }
 
+func (r *pgAssessmentRepo) CreateBatch(ctx context.Context, items []models.Assessment) ([]models.Assessment, error) {
+	if r.q == nil || r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := r.q.WithTx(tx)
+	out := make([]models.Assessment, 0, len(items))
+	for i, a := range items {
+		row, err := qtx.CreateAssessment(ctx, createAssessmentParams(a))
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		out = append(out, mapCreateAssessmentRow(row))
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (r *pgAssessmentRepo) ClusterCounts(ctx context.Context) ([]models.ClusterAnalytics, error) {
 	if r.q == nil {
 		return nil, errors.New("db not configured")
@@ -268,30 +495,39 @@ func (r *pgAssessmentRepo) ClusterCounts(ctx context.Context) ([]models.ClusterA
 	return res, nil
 }
 
-func (r *pgAssessmentRepo) TrendAverages(ctx context.Context) ([]models.TrendPoint, error) {
-	if r.q == nil {
+func (r *pgAssessmentRepo) ClusterCountsInScope(ctx context.Context, scope models.CohortScope) ([]models.ClusterAnalytics, error) {
+	if r.pool == nil {
 		return nil, errors.New("db not configured")
 	}
-	rows, err := r.q.TrendAverages(ctx)
+	where, args := cohortScopeSQL(scope, nil)
+	rows, err := r.pool.Query(ctx, `
+		SELECT COALESCE(a.cluster, ''), COUNT(*)::int
+		FROM assessments a
+		JOIN patients p ON p.id = a.patient_id
+		WHERE a.status = 'final'
+		  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)`+where+`
+		GROUP BY COALESCE(a.cluster, '')
+		ORDER BY 1`, args...)
 	if err != nil {
 		return nil, err
 	}
-	var res []models.TrendPoint
-	for _, t := range rows {
-		res = append(res, models.TrendPoint{
-			Label: t.Label,
-			HbA1c: t.Hba1c,
-			FBS:   t.Fbs,
-		})
+	defer rows.Close()
+	res := []models.ClusterAnalytics{}
+	for rows.Next() {
+		var c models.ClusterAnalytics
+		if err := rows.Scan(&c.Cluster, &c.Count); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
 	}
-	return res, nil
+	return res, rows.Err()
 }
 
-func (r *pgAssessmentRepo) Get(ctx context.Context, id int32) (*models.Assessment, error) {
+func (r *pgAssessmentRepo) Get(ctx context.Context, id int32, userID int32) (*models.Assessment, error) {
 	if r.q == nil {
 		return nil, errors.New("db not configured")
 	}
-	row, err := r.q.GetAssessment(ctx, id)
+	row, err := r.q.GetAssessment(ctx, sqlcgen.GetAssessmentParams{ID: id, UserID: userID})
 	if err != nil {
 		return nil, err
 	}
@@ -299,11 +535,11 @@ func (r *pgAssessmentRepo) Get(ctx context.Context, id int32) (*models.Assessmen
 	return &res, nil
 }
 
-func (r *pgAssessmentRepo) Update(ctx context.Context, a models.Assessment) (*models.Assessment, error) {
+func (r *pgAssessmentRepo) Update(ctx context.Context, a models.Assessment, userID int32) (*models.Assessment, error) {
 	if r.q == nil {
 		return nil, errors.New("db not configured")
 	}
-	row, err := r.q.UpdateAssessment(ctx, sqlcgen.UpdateAssessmentParams{
+	params := sqlcgen.UpdateAssessmentParams{
 		ID:               int32(a.ID),
 		PatientID:        int64ToPgInt(a.PatientID),
 		Fbs:              floatToNumeric(a.FBS),
@@ -325,7 +561,14 @@ func (r *pgAssessmentRepo) Update(ctx context.Context, a models.Assessment) (*mo
 		ModelVersion:     textToPg(a.ModelVersion),
 		DatasetHash:      textToPg(a.DatasetHash),
 		ValidationStatus: textToPg(a.ValidationStatus),
-	})
+		SelfReported:     a.SelfReported,
+		UserID:           userID,
+		HeightCm:         optionalNumeric(a.HeightCM),
+		WeightKg:         optionalNumeric(a.WeightKG),
+		BmiSource:        textToPg(a.BMISource),
+	}
+	params.QualityScore, params.QualityCompleteness, params.QualityOutOfRange = qualityToPg(a.Quality)
+	row, err := r.q.UpdateAssessment(ctx, params)
 	if err != nil {
 		return nil, err
 	}
@@ -333,11 +576,52 @@ func (r *pgAssessmentRepo) Update(ctx context.Context, a models.Assessment) (*mo
 	return &res, nil
 }
 
-func (r *pgAssessmentRepo) Delete(ctx context.Context, id int32) error {
+func (r *pgAssessmentRepo) SetExplanation(ctx context.Context, id int32, explanation map[string]interface{}) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	var raw []byte
+	if explanation != nil {
+		b, err := json.Marshal(explanation)
+		if err != nil {
+			return err
+		}
+		raw = b
+	}
+	_, err := r.pool.Exec(ctx, `UPDATE assessments SET shap_explanation = $2 WHERE id = $1`, id, raw)
+	return err
+}
+
+func (r *pgAssessmentRepo) GetExplanation(ctx context.Context, id int32) (map[string]interface{}, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	var raw []byte
+	if err := r.pool.QueryRow(ctx, `SELECT shap_explanation FROM assessments WHERE id = $1`, id).Scan(&raw); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *pgAssessmentRepo) Delete(ctx context.Context, id int32, userID int32) error {
 	if r.q == nil {
 		return errors.New("db not configured")
 	}
-	return r.q.DeleteAssessment(ctx, id)
+	n, err := r.q.DeleteAssessment(ctx, sqlcgen.DeleteAssessmentParams{ID: id, UserID: userID})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
 }
 
 func (r *pgAssessmentRepo) ListAllLimited(ctx context.Context, limit int) ([]models.Assessment, error) {
@@ -380,6 +664,9 @@ func (r *pgAssessmentRepo) GetTrend(ctx context.Context, patientID int64) ([]mod
 	var trends []models.AssessmentTrend
 	for i := len(assessments) - 1; i >= 0; i-- {
 		a := assessments[i]
+		if a.Draft() {
+			continue
+		}
 		var riskScore *float64
 		if a.RiskScore > 0 {
 			rs := float64(a.RiskScore) / 100.0
@@ -401,7 +688,10 @@ func (r *pgAssessmentRepo) GetTrend(ctx context.Context, patientID int64) ([]mod
 	return trends, nil
 }
 
-type pgRefreshTokenRepo struct{ q *sqlcgen.Queries }
+type pgRefreshTokenRepo struct {
+	q    *sqlcgen.Queries
+	pool *pgxpool.Pool
+}
 
 func (r *pgRefreshTokenRepo) CreateRefreshToken(ctx context.Context, tokenHash string, userID int32, expiresAt time.Time) (*models.RefreshToken, error) {
 	if r.q == nil {
@@ -466,6 +756,25 @@ func (r *pgRefreshTokenRepo) DeleteExpiredTokens(ctx context.Context) error {
 	return r.q.DeleteExpiredTokens(ctx)
 }
 
+func (r *pgRefreshTokenRepo) RevokeExcessUserTokens(ctx context.Context, userID int32, keep int) (int, error) {
+	if r.q == nil {
+		return 0, errors.New("db not configured")
+	}
+	n, err := r.q.RevokeExcessUserTokens(ctx, sqlcgen.RevokeExcessUserTokensParams{
+		UserID: userID,
+		Offset: int32(keep),
+	})
+	return int(n), err
+}
+
+func (r *pgRefreshTokenRepo) DeleteRevokedTokens(ctx context.Context, before time.Time) (int, error) {
+	if r.q == nil {
+		return 0, errors.New("db not configured")
+	}
+	n, err := r.q.DeleteRevokedTokens(ctx, timeToPgTimestamp(before))
+	return int(n), err
+}
+
 // mapping helpers - patients
 func mapPatientRows(rows []sqlcgen.ListPatientsRow) []models.Patient {
 	var out []models.Patient
@@ -490,6 +799,10 @@ func mapPatientRows(rows []sqlcgen.ListPatientsRow) []models.Patient {
 			LDL:             intVal(r.Ldl),
 			HDL:             intVal(r.Hdl),
 			Triglycerides:   intVal(r.Triglycerides),
+			MRN:             textVal(r.Mrn),
+			HeightCM:        numericVal(r.HeightCm),
+			WeightKG:        numericVal(r.WeightKg),
+			BMISource:       textVal(r.BmiSource),
 			CreatedAt:       r.CreatedAt.Time,
 			UpdatedAt:       r.UpdatedAt.Time,
 		})
@@ -520,6 +833,10 @@ func mapPatientLimitedRows(rows []sqlcgen.ListPatientsLimitedRow) []models.Patie
 			LDL:             intVal(r.Ldl),
 			HDL:             intVal(r.Hdl),
 			Triglycerides:   intVal(r.Triglycerides),
+			MRN:             textVal(r.Mrn),
+			HeightCM:        numericVal(r.HeightCm),
+			WeightKG:        numericVal(r.WeightKg),
+			BMISource:       textVal(r.BmiSource),
 			CreatedAt:       r.CreatedAt.Time,
 			UpdatedAt:       r.UpdatedAt.Time,
 		})
@@ -548,6 +865,10 @@ func mapCreatePatientRow(r sqlcgen.CreatePatientRow) models.Patient {
 		LDL:             intVal(r.Ldl),
 		HDL:             intVal(r.Hdl),
 		Triglycerides:   intVal(r.Triglycerides),
+		MRN:             textVal(r.Mrn),
+		HeightCM:        numericVal(r.HeightCm),
+		WeightKG:        numericVal(r.WeightKg),
+		BMISource:       textVal(r.BmiSource),
 		CreatedAt:       r.CreatedAt.Time,
 		UpdatedAt:       r.UpdatedAt.Time,
 	}
@@ -574,6 +895,10 @@ func mapGetPatientRow(r sqlcgen.GetPatientRow) models.Patient {
 		LDL:             intVal(r.Ldl),
 		HDL:             intVal(r.Hdl),
 		Triglycerides:   intVal(r.Triglycerides),
+		MRN:             textVal(r.Mrn),
+		HeightCM:        numericVal(r.HeightCm),
+		WeightKG:        numericVal(r.WeightKg),
+		BMISource:       textVal(r.BmiSource),
 		CreatedAt:       r.CreatedAt.Time,
 		UpdatedAt:       r.UpdatedAt.Time,
 	}
@@ -600,12 +925,73 @@ func mapUpdatePatientRow(r sqlcgen.UpdatePatientRow) models.Patient {
 		LDL:             intVal(r.Ldl),
 		HDL:             intVal(r.Hdl),
 		Triglycerides:   intVal(r.Triglycerides),
+		MRN:             textVal(r.Mrn),
+		HeightCM:        numericVal(r.HeightCm),
+		WeightKG:        numericVal(r.WeightKg),
+		BMISource:       textVal(r.BmiSource),
 		CreatedAt:       r.CreatedAt.Time,
 		UpdatedAt:       r.UpdatedAt.Time,
 	}
 }
 
 // mapping helpers - assessments
+func createAssessmentParams(a models.Assessment) sqlcgen.CreateAssessmentParams {
+	p := sqlcgen.CreateAssessmentParams{
+		PatientID:        int64ToPgInt(a.PatientID),
+		Fbs:              floatToNumeric(a.FBS),
+		Hba1c:            floatToNumeric(a.HbA1c),
+		Cholesterol:      intToPgInt(a.Cholesterol),
+		Ldl:              intToPgInt(a.LDL),
+		Hdl:              intToPgInt(a.HDL),
+		Triglycerides:    intToPgInt(a.Triglycerides),
+		Systolic:         intToPgInt(a.Systolic),
+		Diastolic:        intToPgInt(a.Diastolic),
+		Activity:         textToPg(a.Activity),
+		HistoryFlag:      boolToPg(a.HistoryFlag),
+		Smoking:          textToPg(a.Smoking),
+		Hypertension:     textToPg(a.Hypertension),
+		HeartDisease:     textToPg(a.HeartDisease),
+		Bmi:              floatToNumeric(a.BMI),
+		Cluster:          textToPg(a.Cluster),
+		RiskScore:        intToPgInt(a.RiskScore),
+		ModelVersion:     textToPg(a.ModelVersion),
+		DatasetHash:      textToPg(a.DatasetHash),
+		ValidationStatus: textToPg(a.ValidationStatus),
+		SelfReported:     a.SelfReported,
+		Status:           a.Status,
+		HeightCm:         optionalNumeric(a.HeightCM),
+		WeightKg:         optionalNumeric(a.WeightKG),
+		BmiSource:        textToPg(a.BMISource),
+	}
+	if p.Status == "" {
+		p.Status = models.AssessmentStatusFinal
+	}
+	p.QualityScore, p.QualityCompleteness, p.QualityOutOfRange = qualityToPg(a.Quality)
+	return p
+}
+
+// qualityToPg splits a data quality score into its columns; a nil score is
+// stored as NULLs so it can be told apart from a score of zero.
+func qualityToPg(q *models.DataQuality) (pgtype.Int4, pgtype.Float4, pgtype.Int4) {
+	if q == nil {
+		return pgtype.Int4{}, pgtype.Float4{}, pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: int32(q.Score), Valid: true},
+		pgtype.Float4{Float32: float32(q.Completeness), Valid: true},
+		pgtype.Int4{Int32: int32(q.OutOfRange), Valid: true}
+}
+
+func qualityVal(a sqlcgen.Assessment) *models.DataQuality {
+	if !a.QualityScore.Valid {
+		return nil
+	}
+	return &models.DataQuality{
+		Score:        int(a.QualityScore.Int32),
+		Completeness: math.Round(100*float64(a.QualityCompleteness.Float32)) / 100,
+		OutOfRange:   int(a.QualityOutOfRange.Int32),
+	}
+}
+
 func mapAssessmentsByPatientRows(rows []sqlcgen.Assessment) []models.Assessment {
 	var out []models.Assessment
 	for _, r := range rows {
@@ -659,6 +1045,12 @@ func mapAssessment(a sqlcgen.Assessment) models.Assessment {
 		ValidationStatus: textVal(a.ValidationStatus),
 		CreatedAt:        a.CreatedAt.Time,
 		UpdatedAt:        a.UpdatedAt.Time,
+		SelfReported:     a.SelfReported,
+		Status:           a.Status,
+		HeightCM:         numericVal(a.HeightCm),
+		WeightKG:         numericVal(a.WeightKg),
+		BMISource:        textVal(a.BmiSource),
+		Quality:          qualityVal(a),
 	}
 }
 
@@ -692,6 +1084,22 @@ func textVal(t pgtype.Text) string {
 	return t.String
 }
 
+func timePtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
+
+func int4Ptr(v pgtype.Int4) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	i := int64(v.Int32)
+	return &i
+}
+
 func textToPg(v string) pgtype.Text {
 	if v == "" {
 		return pgtype.Text{Valid: false}
@@ -718,6 +1126,14 @@ func floatToNumeric(v float64) pgtype.Numeric {
 	return n
 }
 
+// optionalNumeric stores v, or NULL when it is not set
+func optionalNumeric(v float64) pgtype.Numeric {
+	if v <= 0 {
+		return pgtype.Numeric{}
+	}
+	return floatToNumeric(v)
+}
+
 func boolVal(b pgtype.Bool) bool {
 	if !b.Valid {
 		return false
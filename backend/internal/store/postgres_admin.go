@@ -7,6 +7,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/skufu/DianaV2/backend/internal/models"
@@ -28,6 +29,17 @@ func (s *PostgresStore) ModelRuns() ModelRunRepository {
 // Extended UserRepository methods (List, Create, Update, Deactivate)
 // ============================================================================
 
+// userSortColumns maps UserListParams.Sort values to SQL expressions.
+var userSortColumns = map[string]string{
+	"email":                      "u.email",
+	"created_at":                 "u.created_at",
+	"last_login_at":              "u.last_login_at",
+	"patient_count":              "COALESCE(p.patient_count, 0)",
+	"assessment_count":           "COALESCE(a.assessment_count, 0)",
+	"high_risk_assessment_count": "COALESCE(a.high_risk_count, 0)",
+	"last_activity_at":           "last_activity_at",
+}
+
 func (r *pgUserRepo) List(ctx context.Context, params models.UserListParams) ([]models.User, int, error) {
 	if r.pool == nil {
 		return nil, 0, errors.New("db not configured")
@@ -47,50 +59,62 @@ func (r *pgUserRepo) List(ctx context.Context, params models.UserListParams) ([]
 	}
 	offset := (page - 1) * pageSize
 
-	// Build query with filters
-	query := `
-		SELECT id, email, password_hash, role, 
-		       COALESCE(is_active, true) as is_active, 
-		       last_login_at, created_by, created_at, updated_at
-		FROM users
-		WHERE 1=1
-	`
-	countQuery := `SELECT COUNT(*) FROM users WHERE 1=1`
-	args := []interface{}{}
-	argNum := 1
+	where := userListFilter(params)
 
-	if params.Search != "" {
-		query += ` AND email ILIKE '%' || $` + itoa(argNum) + ` || '%'`
-		countQuery += ` AND email ILIKE '%' || $` + itoa(argNum) + ` || '%'`
-		args = append(args, params.Search)
-		argNum++
+	// Get total count
+	countQuery, args, err := psql.Select("COUNT(*)").From("users u").Where(where).ToSql()
+	if err != nil {
+		return nil, 0, err
 	}
-
-	if params.Role != "" {
-		query += ` AND role = $` + itoa(argNum)
-		countQuery += ` AND role = $` + itoa(argNum)
-		args = append(args, params.Role)
-		argNum++
+	var total int
+	err = r.pool.QueryRow(ctx, countQuery, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	if params.IsActive != nil {
-		query += ` AND COALESCE(is_active, true) = $` + itoa(argNum)
-		countQuery += ` AND COALESCE(is_active, true) = $` + itoa(argNum)
-		args = append(args, *params.IsActive)
-		argNum++
+	// Activity stats come from two grouped subqueries joined once, so the
+	// page costs the same whatever its size
+	list := psql.Select(`u.id, u.email, u.password_hash, u.role,
+		       COALESCE(u.is_active, true) as is_active,
+		       u.last_login_at, u.created_by, u.failed_login_attempts,
+		       CASE WHEN u.locked_until > NOW() THEN u.locked_until END AS locked_until,
+		       u.created_at, u.updated_at,
+		       COALESCE(p.patient_count, 0), COALESCE(a.assessment_count, 0),
+		       COALESCE(a.high_risk_count, 0), a.last_assessment_at,
+		       GREATEST(u.last_login_at, p.last_patient_at, a.last_assessment_at) AS last_activity_at`).
+		From(`users u
+		LEFT JOIN (
+			SELECT user_id, COUNT(*) AS patient_count, MAX(updated_at) AS last_patient_at
+			FROM patients
+			GROUP BY user_id
+		) p ON p.user_id = u.id
+		LEFT JOIN (
+			SELECT pt.user_id, COUNT(*) AS assessment_count,
+			       COUNT(*) FILTER (WHERE a.risk_score >= 67) AS high_risk_count,
+			       MAX(a.created_at) AS last_assessment_at
+			FROM assessments a
+			JOIN patients pt ON pt.id = a.patient_id
+			WHERE a.status = 'final'
+			  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
+			GROUP BY pt.user_id
+		) a ON a.user_id = u.id`).
+		Where(where)
+
+	// Sort column and direction come from a whitelist, never from raw input
+	if col, ok := userSortColumns[params.Sort]; ok {
+		dir := "ASC"
+		if params.Order == "desc" {
+			dir = "DESC"
+		}
+		list = list.OrderBy(col+" "+dir+" NULLS LAST", "u.id DESC")
+	} else {
+		list = list.OrderBy("u.created_at DESC")
 	}
-
-	// Get total count
-	var total int
-	err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total)
+	query, args, err := list.Limit(uint64(pageSize)).Offset(uint64(offset)).ToSql()
 	if err != nil {
 		return nil, 0, err
 	}
 
-	// Add pagination
-	query += ` ORDER BY created_at DESC LIMIT $` + itoa(argNum) + ` OFFSET $` + itoa(argNum+1)
-	args = append(args, pageSize, offset)
-
 	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, 0, err
@@ -100,15 +124,21 @@ func (r *pgUserRepo) List(ctx context.Context, params models.UserListParams) ([]
 	var users []models.User
 	for rows.Next() {
 		var u models.User
+		var activity models.UserActivity
 		var isActive bool
 		var lastLoginAt pgtype.Timestamptz
 		var createdBy pgtype.Int4
+		var lockedUntil pgtype.Timestamptz
 		var createdAt pgtype.Timestamptz
 		var updatedAt pgtype.Timestamptz
+		var lastAssessmentAt, lastActivityAt pgtype.Timestamptz
 
 		err := rows.Scan(
 			&u.ID, &u.Email, &u.PasswordHash, &u.Role,
-			&isActive, &lastLoginAt, &createdBy, &createdAt, &updatedAt,
+			&isActive, &lastLoginAt, &createdBy, &u.FailedLoginAttempts, &lockedUntil,
+			&createdAt, &updatedAt,
+			&activity.PatientCount, &activity.AssessmentCount,
+			&activity.HighRiskAssessmentCount, &lastAssessmentAt, &lastActivityAt,
 		)
 		if err != nil {
 			return nil, 0, err
@@ -121,8 +151,12 @@ func (r *pgUserRepo) List(ctx context.Context, params models.UserListParams) ([]
 			cb := int64(createdBy.Int32)
 			u.CreatedBy = &cb
 		}
+		u.LockedUntil = timePtr(lockedUntil)
 		u.CreatedAt = createdAt.Time
 		u.UpdatedAt = updatedAt.Time
+		activity.LastAssessmentAt = timePtr(lastAssessmentAt)
+		activity.LastActivityAt = timePtr(lastActivityAt)
+		u.Activity = &activity
 		users = append(users, u)
 	}
 
@@ -148,7 +182,7 @@ func (r *pgUserRepo) Create(ctx context.Context, user models.User) (*models.User
 	).Scan(&id, &createdAt, &updatedAt)
 
 	if err != nil {
-		return nil, err
+		return nil, conflictError(err)
 	}
 
 	user.ID = id
@@ -277,54 +311,29 @@ func (r *pgAuditEventRepo) List(ctx context.Context, params models.AuditListPara
 	}
 	offset := (page - 1) * pageSize
 
-	// Build query with filters
-	query := `
-		SELECT id, actor, action, target_type, target_id, details, created_at
-		FROM audit_events
-		WHERE 1=1
-	`
-	countQuery := `SELECT COUNT(*) FROM audit_events WHERE 1=1`
-	args := []interface{}{}
-	argNum := 1
-
-	if params.Actor != "" {
-		query += ` AND actor ILIKE '%' || $` + itoa(argNum) + ` || '%'`
-		countQuery += ` AND actor ILIKE '%' || $` + itoa(argNum) + ` || '%'`
-		args = append(args, params.Actor)
-		argNum++
-	}
-
-	if params.Action != "" {
-		query += ` AND action = $` + itoa(argNum)
-		countQuery += ` AND action = $` + itoa(argNum)
-		args = append(args, params.Action)
-		argNum++
-	}
-
-	if !params.StartDate.IsZero() {
-		query += ` AND created_at >= $` + itoa(argNum)
-		countQuery += ` AND created_at >= $` + itoa(argNum)
-		args = append(args, params.StartDate)
-		argNum++
-	}
-
-	if !params.EndDate.IsZero() {
-		query += ` AND created_at <= $` + itoa(argNum)
-		countQuery += ` AND created_at <= $` + itoa(argNum)
-		args = append(args, params.EndDate)
-		argNum++
-	}
+	where := auditListFilter(params)
 
 	// Get total count
+	countQuery, args, err := psql.Select("COUNT(*)").From("audit_events").Where(where).ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
 	var total int
-	err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total)
+	err = r.pool.QueryRow(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	// Add pagination
-	query += ` ORDER BY created_at DESC LIMIT $` + itoa(argNum) + ` OFFSET $` + itoa(argNum+1)
-	args = append(args, pageSize, offset)
+	query, args, err := psql.Select("id, actor, action, target_type, target_id, details, created_at").
+		From("audit_events").
+		Where(where).
+		OrderBy("created_at DESC").
+		Limit(uint64(pageSize)).
+		Offset(uint64(offset)).
+		ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
 
 	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
@@ -385,7 +394,8 @@ func (r *pgModelRunRepo) List(ctx context.Context, limit, offset int) ([]models.
 	}
 
 	query := `
-		SELECT id, model_version, dataset_hash, notes, created_at
+		SELECT id, model_version, dataset_hash, notes, is_active, created_at,
+		       accuracy, auc, calibration_error, training_distribution
 		FROM model_runs
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
@@ -398,15 +408,19 @@ func (r *pgModelRunRepo) List(ctx context.Context, limit, offset int) ([]models.
 	defer rows.Close()
 
 	var runs []models.ModelRun
-	isFirst := true
 	for rows.Next() {
 		var run models.ModelRun
 		var datasetHash, notes pgtype.Text
+		var distribution []byte
 
-		err := rows.Scan(&run.ID, &run.ModelVersion, &datasetHash, &notes, &run.CreatedAt)
+		err := rows.Scan(&run.ID, &run.ModelVersion, &datasetHash, &notes, &run.IsActive, &run.CreatedAt,
+			&run.Accuracy, &run.AUC, &run.CalibrationError, &distribution)
 		if err != nil {
 			return nil, 0, err
 		}
+		if run.TrainingDistribution, err = decodeDistribution(distribution); err != nil {
+			return nil, 0, err
+		}
 
 		if datasetHash.Valid {
 			run.DatasetHash = datasetHash.String
@@ -415,10 +429,6 @@ func (r *pgModelRunRepo) List(ctx context.Context, limit, offset int) ([]models.
 			run.Notes = notes.String
 		}
 
-		// Mark the first (most recent) as active
-		run.IsActive = isFirst
-		isFirst = false
-
 		runs = append(runs, run)
 	}
 
@@ -431,19 +441,25 @@ func (r *pgModelRunRepo) GetActive(ctx context.Context) (*models.ModelRun, error
 	}
 
 	query := `
-		SELECT id, model_version, dataset_hash, notes, created_at
+		SELECT id, model_version, dataset_hash, notes, created_at,
+		       accuracy, auc, calibration_error, training_distribution
 		FROM model_runs
-		ORDER BY created_at DESC
+		WHERE is_active
 		LIMIT 1
 	`
 
 	var run models.ModelRun
 	var datasetHash, notes pgtype.Text
+	var distribution []byte
 
-	err := r.pool.QueryRow(ctx, query).Scan(&run.ID, &run.ModelVersion, &datasetHash, &notes, &run.CreatedAt)
+	err := r.pool.QueryRow(ctx, query).Scan(&run.ID, &run.ModelVersion, &datasetHash, &notes, &run.CreatedAt,
+		&run.Accuracy, &run.AUC, &run.CalibrationError, &distribution)
 	if err != nil {
 		return nil, err
 	}
+	if run.TrainingDistribution, err = decodeDistribution(distribution); err != nil {
+		return nil, err
+	}
 
 	if datasetHash.Valid {
 		run.DatasetHash = datasetHash.String
@@ -461,37 +477,51 @@ func (r *pgModelRunRepo) Create(ctx context.Context, run models.ModelRun) (*mode
 		return nil, errors.New("db not configured")
 	}
 
+	distribution, err := encodeDistribution(run.TrainingDistribution)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-		INSERT INTO model_runs (model_version, dataset_hash, notes, created_at)
-		VALUES ($1, $2, $3, NOW())
+		INSERT INTO model_runs (model_version, dataset_hash, notes, accuracy, auc, calibration_error, training_distribution, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
 		RETURNING id, created_at
 	`
 
-	err := r.pool.QueryRow(ctx, query, run.ModelVersion, run.DatasetHash, run.Notes).Scan(&run.ID, &run.CreatedAt)
+	err = r.pool.QueryRow(ctx, query, run.ModelVersion, run.DatasetHash, run.Notes,
+		run.Accuracy, run.AUC, run.CalibrationError, distribution).Scan(&run.ID, &run.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
 
-	run.IsActive = true
+	// New runs are inactive until explicitly activated
+	run.IsActive = false
 	return &run, nil
 }
 
+// SetActive makes the given run the only active one. Both updates run in a
+// single transaction so readers never observe zero or two active runs.
+// Returns pgx.ErrNoRows if the run does not exist.
 func (r *pgModelRunRepo) SetActive(ctx context.Context, id int32) error {
-	// In our implementation, "active" is simply the most recent run.
-	// This method is a no-op but provided for interface compatibility.
-	// If we wanted explicit active marking, we'd add an is_active column.
-	return nil
-}
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
 
-// ============================================================================
-// Helper functions
-// ============================================================================
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
 
-// itoa converts an int to a string (simple implementation for query building)
-func itoa(n int) string {
-	if n < 10 {
-		return string(rune('0' + n))
+	if _, err := tx.Exec(ctx, `UPDATE model_runs SET is_active = false WHERE is_active AND id <> $1`, id); err != nil {
+		return err
 	}
-	return itoa(n/10) + string(rune('0'+n%10))
+	tag, err := tx.Exec(ctx, `UPDATE model_runs SET is_active = true WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return tx.Commit(ctx)
 }
-
@@ -0,0 +1,74 @@
+// postgres_alerts.go: Risk alert queue backed by the risk_alerts table.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) RiskAlerts() RiskAlertRepository {
+	return &pgRiskAlertRepo{pool: s.pool}
+}
+
+type pgRiskAlertRepo struct {
+	pool *pgxpool.Pool
+}
+
+const riskAlertColumns = `id, user_id, patient_id, assessment_id, reasons, risk_score, hba1c, patient_channels, created_at, delivered_at`
+
+func (r *pgRiskAlertRepo) Enqueue(ctx context.Context, alert models.RiskAlert) (*models.RiskAlert, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	hba1c := pgtype.Float8{Float64: alert.HbA1c, Valid: alert.HbA1c > 0}
+	channels := alert.PatientChannels
+	if channels == nil {
+		channels = []string{}
+	}
+	row := r.pool.QueryRow(ctx, `
+		INSERT INTO risk_alerts (user_id, patient_id, assessment_id, reasons, risk_score, hba1c, patient_channels)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+riskAlertColumns,
+		alert.UserID, alert.PatientID, alert.AssessmentID, alert.Reasons, alert.RiskScore, hba1c, channels)
+	return scanRiskAlert(row)
+}
+
+func (r *pgRiskAlertRepo) LastForPatient(ctx context.Context, patientID int64) (*models.RiskAlert, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	row := r.pool.QueryRow(ctx, `
+		SELECT `+riskAlertColumns+`
+		FROM risk_alerts
+		WHERE patient_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1`, patientID)
+	alert, err := scanRiskAlert(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return alert, err
+}
+
+func scanRiskAlert(row pgx.Row) (*models.RiskAlert, error) {
+	var a models.RiskAlert
+	var hba1c pgtype.Float8
+	var delivered pgtype.Timestamptz
+	if err := row.Scan(&a.ID, &a.UserID, &a.PatientID, &a.AssessmentID, &a.Reasons,
+		&a.RiskScore, &hba1c, &a.PatientChannels, &a.CreatedAt, &delivered); err != nil {
+		return nil, err
+	}
+	if hba1c.Valid {
+		a.HbA1c = hba1c.Float64
+	}
+	if delivered.Valid {
+		t := delivered.Time
+		a.DeliveredAt = &t
+	}
+	return &a, nil
+}
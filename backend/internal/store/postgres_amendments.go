@@ -0,0 +1,114 @@
+// postgres_amendments.go: Append-only assessment amendments.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (r *pgAssessmentRepo) Amend(ctx context.Context, originalID int64, a models.Assessment) (*models.Assessment, error) {
+	if r.q == nil || r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the original so two concurrent amendments cannot both succeed
+	var amended bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM assessments WHERE amends_assessment_id = a.id)
+		FROM assessments a WHERE a.id = $1
+		FOR UPDATE`, originalID).Scan(&amended)
+	if err != nil {
+		return nil, err
+	}
+	if amended {
+		return nil, ErrAlreadyAmended
+	}
+
+	row, err := r.q.WithTx(tx).CreateAssessment(ctx, createAssessmentParams(a))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE assessments SET amends_assessment_id = $2 WHERE id = $1`,
+		row.ID, originalID,
+	); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	res := mapCreateAssessmentRow(row)
+	res.AmendsAssessmentID = &originalID
+	return &res, nil
+}
+
+func (r *pgAssessmentRepo) AmendmentChain(ctx context.Context, id int64) ([]models.Assessment, error) {
+	if r.q == nil || r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	// Walk up to the original, then down through its amendments
+	rows, err := r.pool.Query(ctx, `
+		WITH RECURSIVE up AS (
+			SELECT id, amends_assessment_id FROM assessments WHERE id = $1
+			UNION ALL
+			SELECT a.id, a.amends_assessment_id FROM assessments a JOIN up ON a.id = up.amends_assessment_id
+		), down AS (
+			SELECT id, amends_assessment_id, 0 AS depth FROM up WHERE amends_assessment_id IS NULL
+			UNION ALL
+			SELECT a.id, a.amends_assessment_id, down.depth + 1
+			FROM assessments a JOIN down ON a.amends_assessment_id = down.id
+		)
+		SELECT id, amends_assessment_id FROM down ORDER BY depth`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type link struct {
+		id     int32
+		amends pgtype.Int4
+	}
+	var links []link
+	for rows.Next() {
+		var l link
+		if err := rows.Scan(&l.id, &l.amends); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Amendments stay with the patient, so the whole chain has one owner
+	var owner int32
+	err = r.pool.QueryRow(ctx, `
+		SELECT p.user_id FROM assessments a JOIN patients p ON p.id = a.patient_id
+		WHERE a.id = $1`, id).Scan(&owner)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := make([]models.Assessment, 0, len(links))
+	for _, l := range links {
+		a, err := r.Get(ctx, l.id, owner)
+		if err != nil {
+			return nil, err
+		}
+		if l.amends.Valid {
+			amends := int64(l.amends.Int32)
+			a.AmendsAssessmentID = &amends
+		}
+		chain = append(chain, *a)
+	}
+	return chain, nil
+}
@@ -0,0 +1,111 @@
+// postgres_api_keys.go: User-bound API keys for integration scripts.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) APIKeys() APIKeyRepository {
+	return &pgAPIKeyRepo{pool: s.pool}
+}
+
+type pgAPIKeyRepo struct {
+	pool *pgxpool.Pool
+}
+
+const apiKeyColumns = `id, user_id, name, key_hash, prefix, scopes, created_by, expires_at, last_used_at, revoked_at, created_at`
+
+func (r *pgAPIKeyRepo) Create(ctx context.Context, k models.APIKey) (*models.APIKey, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	createdBy := pgtype.Int4{Int32: int32(k.CreatedBy), Valid: k.CreatedBy > 0}
+	row := r.pool.QueryRow(ctx, `
+		INSERT INTO api_keys (user_id, name, key_hash, prefix, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+apiKeyColumns,
+		k.UserID, k.Name, k.KeyHash, k.Prefix, k.Scopes, createdBy, k.ExpiresAt)
+	key, err := scanAPIKey(row)
+	return key, conflictError(err)
+}
+
+func (r *pgAPIKeyRepo) List(ctx context.Context, userID int64) ([]models.APIKey, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE $1 = 0 OR user_id = $1
+		ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *pgAPIKeyRepo) Revoke(ctx context.Context, id int64) (*models.APIKey, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	row := r.pool.QueryRow(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING `+apiKeyColumns, id)
+	return scanAPIKey(row)
+}
+
+func (r *pgAPIKeyRepo) FindActive(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	row := r.pool.QueryRow(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())`, keyHash)
+	return scanAPIKey(row)
+}
+
+func (r *pgAPIKeyRepo) MarkUsed(ctx context.Context, id int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	_, err := r.pool.Exec(ctx, `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+func scanAPIKey(row pgx.Row) (*models.APIKey, error) {
+	var k models.APIKey
+	var id, userID int32
+	var createdBy pgtype.Int4
+	var expiresAt, lastUsedAt, revokedAt pgtype.Timestamptz
+	if err := row.Scan(&id, &userID, &k.Name, &k.KeyHash, &k.Prefix, &k.Scopes, &createdBy,
+		&expiresAt, &lastUsedAt, &revokedAt, &k.CreatedAt); err != nil {
+		return nil, err
+	}
+	k.ID, k.UserID = int64(id), int64(userID)
+	if createdBy.Valid {
+		k.CreatedBy = int64(createdBy.Int32)
+	}
+	k.ExpiresAt = timePtr(expiresAt)
+	k.LastUsedAt = timePtr(lastUsedAt)
+	k.RevokedAt = timePtr(revokedAt)
+	return &k, nil
+}
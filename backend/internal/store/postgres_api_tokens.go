@@ -0,0 +1,106 @@
+// postgres_api_tokens.go: Scoped API tokens for reporting clients.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) APITokens() APITokenRepository {
+	return &pgAPITokenRepo{pool: s.pool}
+}
+
+type pgAPITokenRepo struct {
+	pool *pgxpool.Pool
+}
+
+const apiTokenColumns = `id, name, token_hash, prefix, scopes, created_by, expires_at, last_used_at, revoked_at, created_at`
+
+func (r *pgAPITokenRepo) Create(ctx context.Context, t models.APIToken) (*models.APIToken, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	createdBy := pgtype.Int4{Int32: int32(t.CreatedBy), Valid: t.CreatedBy > 0}
+	row := r.pool.QueryRow(ctx, `
+		INSERT INTO api_tokens (name, token_hash, prefix, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+apiTokenColumns,
+		t.Name, t.TokenHash, t.Prefix, t.Scopes, createdBy, t.ExpiresAt)
+	return scanAPIToken(row)
+}
+
+func (r *pgAPITokenRepo) List(ctx context.Context) ([]models.APIToken, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+func (r *pgAPITokenRepo) Revoke(ctx context.Context, id int64) (*models.APIToken, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	row := r.pool.QueryRow(ctx, `
+		UPDATE api_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING `+apiTokenColumns, id)
+	return scanAPIToken(row)
+}
+
+func (r *pgAPITokenRepo) FindActive(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	row := r.pool.QueryRow(ctx, `
+		SELECT `+apiTokenColumns+`
+		FROM api_tokens
+		WHERE token_hash = $1 AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())`, tokenHash)
+	return scanAPIToken(row)
+}
+
+func (r *pgAPITokenRepo) MarkUsed(ctx context.Context, id int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	_, err := r.pool.Exec(ctx, `UPDATE api_tokens SET last_used_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+func scanAPIToken(row pgx.Row) (*models.APIToken, error) {
+	var t models.APIToken
+	var id int32
+	var createdBy pgtype.Int4
+	var expiresAt, lastUsedAt, revokedAt pgtype.Timestamptz
+	if err := row.Scan(&id, &t.Name, &t.TokenHash, &t.Prefix, &t.Scopes, &createdBy,
+		&expiresAt, &lastUsedAt, &revokedAt, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	t.ID = int64(id)
+	if createdBy.Valid {
+		t.CreatedBy = int64(createdBy.Int32)
+	}
+	t.ExpiresAt = timePtr(expiresAt)
+	t.LastUsedAt = timePtr(lastUsedAt)
+	t.RevokedAt = timePtr(revokedAt)
+	return &t, nil
+}
@@ -0,0 +1,115 @@
+// postgres_assessment_attachments.go: Metadata of files backing
+// assessments, such as lab reports.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) AssessmentAttachments() AssessmentAttachmentRepository {
+	return &pgAssessmentAttachmentRepo{pool: s.pool}
+}
+
+type pgAssessmentAttachmentRepo struct {
+	pool *pgxpool.Pool
+}
+
+const assessmentAttachmentColumns = `id, assessment_id, patient_id, storage_key, filename, content_type, size_bytes, uploaded_by, created_at`
+
+func scanAssessmentAttachment(row pgx.Row) (*models.AssessmentAttachment, error) {
+	var a models.AssessmentAttachment
+	var assessmentID, patientID int32
+	var uploadedBy *int32
+	if err := row.Scan(&a.ID, &assessmentID, &patientID, &a.StorageKey, &a.Filename, &a.ContentType,
+		&a.SizeBytes, &uploadedBy, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	a.AssessmentID, a.PatientID = int64(assessmentID), int64(patientID)
+	if uploadedBy != nil {
+		a.UploadedBy = int64(*uploadedBy)
+	}
+	return &a, nil
+}
+
+func (r *pgAssessmentAttachmentRepo) Create(ctx context.Context, a models.AssessmentAttachment) (*models.AssessmentAttachment, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	var uploadedBy *int64
+	if a.UploadedBy != 0 {
+		uploadedBy = &a.UploadedBy
+	}
+	return scanAssessmentAttachment(r.pool.QueryRow(ctx, `
+		INSERT INTO assessment_attachments (assessment_id, patient_id, storage_key, filename, content_type, size_bytes, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+assessmentAttachmentColumns,
+		a.AssessmentID, a.PatientID, a.StorageKey, a.Filename, a.ContentType, a.SizeBytes, uploadedBy))
+}
+
+func (r *pgAssessmentAttachmentRepo) list(ctx context.Context, where string, arg int64) ([]models.AssessmentAttachment, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+assessmentAttachmentColumns+`
+		FROM assessment_attachments
+		WHERE `+where+`
+		ORDER BY created_at, id`, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.AssessmentAttachment{}
+	for rows.Next() {
+		a, err := scanAssessmentAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *a)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgAssessmentAttachmentRepo) List(ctx context.Context, assessmentID int64) ([]models.AssessmentAttachment, error) {
+	return r.list(ctx, `assessment_id = $1`, assessmentID)
+}
+
+func (r *pgAssessmentAttachmentRepo) ListByPatient(ctx context.Context, patientID int64) ([]models.AssessmentAttachment, error) {
+	return r.list(ctx, `patient_id = $1`, patientID)
+}
+
+func (r *pgAssessmentAttachmentRepo) Get(ctx context.Context, id, assessmentID int64) (*models.AssessmentAttachment, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanAssessmentAttachment(r.pool.QueryRow(ctx,
+		`SELECT `+assessmentAttachmentColumns+` FROM assessment_attachments WHERE id = $1 AND assessment_id = $2`, id, assessmentID))
+}
+
+func (r *pgAssessmentAttachmentRepo) GetByID(ctx context.Context, id int64) (*models.AssessmentAttachment, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanAssessmentAttachment(r.pool.QueryRow(ctx,
+		`SELECT `+assessmentAttachmentColumns+` FROM assessment_attachments WHERE id = $1`, id))
+}
+
+func (r *pgAssessmentAttachmentRepo) Delete(ctx context.Context, id, assessmentID int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `DELETE FROM assessment_attachments WHERE id = $1 AND assessment_id = $2`, id, assessmentID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
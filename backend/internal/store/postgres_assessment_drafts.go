@@ -0,0 +1,151 @@
+// postgres_assessment_drafts.go: Lab results received from lab interfaces,
+// waiting to be completed into assessments, and the MRN lookup that matches
+// them to patients.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (r *pgPatientRepo) ListByMRN(ctx context.Context, mrn string) ([]models.Patient, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `SELECT id, user_id FROM patients WHERE mrn = $1 ORDER BY id`, mrn)
+	if err != nil {
+		return nil, err
+	}
+	type match struct{ id, owner int32 }
+	var matches []match
+	for rows.Next() {
+		var m match
+		if err := rows.Scan(&m.id, &m.owner); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := []models.Patient{}
+	for _, m := range matches {
+		p, err := r.Get(ctx, m.id, m.owner)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, nil
+}
+
+func (s *PostgresStore) AssessmentDrafts() AssessmentDraftRepository {
+	return &pgAssessmentDraftRepo{pool: s.pool}
+}
+
+type pgAssessmentDraftRepo struct {
+	pool *pgxpool.Pool
+}
+
+const assessmentDraftColumns = `id, patient_id, source, message_control_id, sending_facility,
+	COALESCE(fbs, 0)::float8, COALESCE(hba1c, 0)::float8, COALESCE(cholesterol, 0), COALESCE(ldl, 0),
+	COALESCE(hdl, 0), COALESCE(triglycerides, 0), observed_at, status, assessment_id, created_at, resolved_at`
+
+func scanAssessmentDraft(row pgx.Row) (*models.AssessmentDraft, error) {
+	var d models.AssessmentDraft
+	var facility pgtype.Text
+	if err := row.Scan(&d.ID, &d.PatientID, &d.Source, &d.MessageControlID, &facility,
+		&d.FBS, &d.HbA1c, &d.Cholesterol, &d.LDL, &d.HDL, &d.Triglycerides,
+		&d.ObservedAt, &d.Status, &d.AssessmentID, &d.CreatedAt, &d.ResolvedAt); err != nil {
+		return nil, err
+	}
+	d.SendingFacility = facility.String
+	return &d, nil
+}
+
+func optionalFloat(v float64) pgtype.Float8 {
+	return pgtype.Float8{Float64: v, Valid: v > 0}
+}
+
+func optionalInt(v int) pgtype.Int4 {
+	return pgtype.Int4{Int32: int32(v), Valid: v > 0}
+}
+
+func (r *pgAssessmentDraftRepo) Create(ctx context.Context, d models.AssessmentDraft) (*models.AssessmentDraft, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	created, err := scanAssessmentDraft(r.pool.QueryRow(ctx, `
+		INSERT INTO assessment_drafts (patient_id, source, message_control_id, sending_facility,
+			fbs, hba1c, cholesterol, ldl, hdl, triglycerides, observed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING `+assessmentDraftColumns,
+		d.PatientID, d.Source, d.MessageControlID, textToPg(d.SendingFacility),
+		optionalFloat(d.FBS), optionalFloat(d.HbA1c), optionalInt(d.Cholesterol), optionalInt(d.LDL),
+		optionalInt(d.HDL), optionalInt(d.Triglycerides), d.ObservedAt))
+	if err != nil {
+		return nil, conflictError(err)
+	}
+	return created, nil
+}
+
+func (r *pgAssessmentDraftRepo) ListPending(ctx context.Context, patientID int64) ([]models.AssessmentDraft, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+assessmentDraftColumns+`
+		FROM assessment_drafts
+		WHERE patient_id = $1 AND status = 'pending'
+		ORDER BY created_at DESC, id DESC
+	`, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.AssessmentDraft{}
+	for rows.Next() {
+		d, err := scanAssessmentDraft(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *d)
+	}
+	return out, rows.Err()
+}
+
+// resolve moves a pending draft to status
+func (r *pgAssessmentDraftRepo) resolve(ctx context.Context, id, patientID int64, status string, assessmentID *int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE assessment_drafts
+		SET status = $3, assessment_id = $4, resolved_at = NOW()
+		WHERE id = $1 AND patient_id = $2 AND status = 'pending'
+	`, id, patientID, status, assessmentID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *pgAssessmentDraftRepo) Discard(ctx context.Context, id, patientID int64) error {
+	return r.resolve(ctx, id, patientID, models.DraftDiscarded, nil)
+}
+
+func (r *pgAssessmentDraftRepo) Complete(ctx context.Context, id, patientID, assessmentID int64) error {
+	return r.resolve(ctx, id, patientID, models.DraftCompleted, &assessmentID)
+}
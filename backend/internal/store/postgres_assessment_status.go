@@ -0,0 +1,53 @@
+// postgres_assessment_status.go: Saving and finalizing draft assessments.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// updateDraftSQL sets every stored field of a draft, and status to $26. Only
+// drafts of patients owned by $27 match.
+const updateDraftSQL = `
+	UPDATE assessments
+	SET fbs = $2, hba1c = $3, cholesterol = $4, ldl = $5, hdl = $6, triglycerides = $7,
+	    systolic = $8, diastolic = $9, activity = $10, history_flag = $11, smoking = $12,
+	    hypertension = $13, heart_disease = $14, bmi = $15, cluster = $16, risk_score = $17,
+	    model_version = $18, dataset_hash = $19, validation_status = $20, self_reported = $21,
+	    quality_score = $22, quality_completeness = $23, quality_out_of_range = $24,
+	    height_cm = $28, weight_kg = $29, bmi_source = $30,
+	    status = $26, updated_at = NOW()
+	WHERE id = $1
+	  AND patient_id = $25
+	  AND status = 'draft'
+	  AND patient_id IN (SELECT id FROM patients WHERE user_id = $27)
+	RETURNING ` + assessmentColumns
+
+func (r *pgAssessmentRepo) UpdateDraft(ctx context.Context, a models.Assessment, userID int32) (*models.Assessment, error) {
+	return r.saveDraft(ctx, a, models.AssessmentStatusDraft, userID)
+}
+
+func (r *pgAssessmentRepo) Finalize(ctx context.Context, a models.Assessment, userID int32) (*models.Assessment, error) {
+	return r.saveDraft(ctx, a, models.AssessmentStatusFinal, userID)
+}
+
+func (r *pgAssessmentRepo) saveDraft(ctx context.Context, a models.Assessment, status string, userID int32) (*models.Assessment, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	p := createAssessmentParams(a)
+	res, err := scanAssessmentRow(r.pool.QueryRow(ctx, updateDraftSQL,
+		a.ID, p.Fbs, p.Hba1c, p.Cholesterol, p.Ldl, p.Hdl, p.Triglycerides,
+		p.Systolic, p.Diastolic, p.Activity, p.HistoryFlag, p.Smoking,
+		p.Hypertension, p.HeartDisease, p.Bmi, p.Cluster, p.RiskScore,
+		p.ModelVersion, p.DatasetHash, p.ValidationStatus, p.SelfReported,
+		p.QualityScore, p.QualityCompleteness, p.QualityOutOfRange,
+		p.PatientID, status, userID,
+		p.HeightCm, p.WeightKg, p.BmiSource))
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
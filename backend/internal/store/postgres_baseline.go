@@ -0,0 +1,152 @@
+// postgres_baseline.go: Baseline discrepancies and the per-clinic baseline policy.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) BaselineDiscrepancies() BaselineDiscrepancyRepository {
+	return &pgBaselineDiscrepancyRepo{pool: s.pool}
+}
+
+type pgBaselineDiscrepancyRepo struct {
+	pool *pgxpool.Pool
+}
+
+const baselineDiscrepancyColumns = `id, patient_id, assessment_id, field, baseline_value, assessment_value, created_at, resolution, resolved_by, resolved_at`
+
+func (r *pgBaselineDiscrepancyRepo) Create(ctx context.Context, items []models.BaselineDiscrepancy) ([]models.BaselineDiscrepancy, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	out := make([]models.BaselineDiscrepancy, 0, len(items))
+	for _, d := range items {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO baseline_discrepancies (patient_id, assessment_id, field, baseline_value, assessment_value)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING `+baselineDiscrepancyColumns,
+			d.PatientID, d.AssessmentID, d.Field, d.BaselineValue, d.AssessmentValue)
+		created, err := scanBaselineDiscrepancy(row)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *created)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *pgBaselineDiscrepancyRepo) ListOpen(ctx context.Context, patientID int64) ([]models.BaselineDiscrepancy, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+baselineDiscrepancyColumns+`
+		FROM baseline_discrepancies
+		WHERE patient_id = $1 AND resolved_at IS NULL
+		ORDER BY id`, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.BaselineDiscrepancy{}
+	for rows.Next() {
+		d, err := scanBaselineDiscrepancy(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *d)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgBaselineDiscrepancyRepo) Resolve(ctx context.Context, id, patientID int64, resolution string, userID int64) (*models.BaselineDiscrepancy, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	row := r.pool.QueryRow(ctx, `
+		UPDATE baseline_discrepancies
+		SET resolution = $3, resolved_by = $4, resolved_at = NOW()
+		WHERE id = $1 AND patient_id = $2 AND resolved_at IS NULL
+		RETURNING `+baselineDiscrepancyColumns,
+		id, patientID, resolution, userID)
+	return scanBaselineDiscrepancy(row)
+}
+
+func scanBaselineDiscrepancy(row pgx.Row) (*models.BaselineDiscrepancy, error) {
+	var d models.BaselineDiscrepancy
+	var resolution pgtype.Text
+	var resolvedBy pgtype.Int4
+	var resolvedAt pgtype.Timestamptz
+	if err := row.Scan(&d.ID, &d.PatientID, &d.AssessmentID, &d.Field, &d.BaselineValue,
+		&d.AssessmentValue, &d.CreatedAt, &resolution, &resolvedBy, &resolvedAt); err != nil {
+		return nil, err
+	}
+	d.Resolution = resolution.String
+	if resolvedBy.Valid {
+		by := int64(resolvedBy.Int32)
+		d.ResolvedBy = &by
+	}
+	d.ResolvedAt = timePtr(resolvedAt)
+	return &d, nil
+}
+
+func (r *pgClinicRepo) GetBaselinePolicy(ctx context.Context, clinicID int32) (string, error) {
+	if r.pool == nil {
+		return "", errors.New("db not configured")
+	}
+	var policy string
+	err := r.pool.QueryRow(ctx, `SELECT baseline_policy FROM clinics WHERE id = $1`, clinicID).Scan(&policy)
+	return policy, err
+}
+
+func (r *pgClinicRepo) SetBaselinePolicy(ctx context.Context, clinicID int32, policy string) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE clinics SET baseline_policy = $2, updated_at = NOW() WHERE id = $1`,
+		clinicID, policy,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *pgClinicRepo) BaselinePolicyForUser(ctx context.Context, userID int32) (string, error) {
+	if r.pool == nil {
+		return "", errors.New("db not configured")
+	}
+	var update bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT COALESCE(bool_and(c.baseline_policy = 'update'), false)
+		FROM clinics c
+		JOIN user_clinics uc ON uc.clinic_id = c.id
+		WHERE uc.user_id = $1`, userID).Scan(&update)
+	if err != nil {
+		return "", err
+	}
+	if update {
+		return models.BaselinePolicyUpdate, nil
+	}
+	return models.BaselinePolicyFlag, nil
+}
@@ -0,0 +1,105 @@
+// postgres_clinic_members.go: Clinic membership directory and clinic role management.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (r *pgClinicRepo) ListMembers(ctx context.Context, clinicID int32) ([]models.ClinicMember, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+
+	query := `
+		SELECT u.id, u.email, uc.role, u.role, COALESCE(u.is_active, true),
+		       uc.created_at, u.last_login_at,
+		       COALESCE(p.patient_count, 0), COALESCE(a.assessment_count, 0), a.last_assessment_at
+		FROM user_clinics uc
+		JOIN users u ON u.id = uc.user_id
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS patient_count FROM patients WHERE user_id = u.id
+		) p ON true
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS assessment_count, MAX(asm.created_at) AS last_assessment_at
+			FROM assessments asm
+			JOIN patients pt ON pt.id = asm.patient_id
+			WHERE pt.user_id = u.id AND asm.status = 'final'
+			  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = asm.id)
+		) a ON true
+		WHERE uc.clinic_id = $1
+		ORDER BY uc.role = 'clinic_admin' DESC, u.email
+	`
+
+	rows, err := r.pool.Query(ctx, query, clinicID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.ClinicMember{}
+	for rows.Next() {
+		var m models.ClinicMember
+		var lastLogin, lastAssessment pgtype.Timestamptz
+		if err := rows.Scan(&m.UserID, &m.Email, &m.ClinicRole, &m.GlobalRole, &m.IsActive,
+			&m.JoinedAt, &lastLogin, &m.PatientCount, &m.AssessmentCount, &lastAssessment); err != nil {
+			return nil, err
+		}
+		if lastLogin.Valid {
+			t := lastLogin.Time
+			m.LastLoginAt = &t
+		}
+		if lastAssessment.Valid {
+			t := lastAssessment.Time
+			m.LastAssessmentAt = &t
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (r *pgClinicRepo) AddMember(ctx context.Context, clinicID, userID int32, role string) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO user_clinics (user_id, clinic_id, role) VALUES ($1, $2, $3)`,
+		userID, clinicID, role)
+	return conflictError(err)
+}
+
+func (r *pgClinicRepo) RemoveMember(ctx context.Context, clinicID, userID int32) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM user_clinics WHERE user_id = $1 AND clinic_id = $2`,
+		userID, clinicID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *pgClinicRepo) SetMemberRole(ctx context.Context, clinicID, userID int32, role string) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE user_clinics SET role = $3 WHERE user_id = $1 AND clinic_id = $2`,
+		userID, clinicID, role)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -4,28 +4,40 @@ package store
 import (
 	"context"
 	"errors"
+	"fmt"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/skufu/DianaV2/backend/internal/models"
 	sqlcgen "github.com/skufu/DianaV2/backend/internal/store/sqlc"
 )
 
 // Cohort returns the CohortRepository implementation
 func (s *PostgresStore) Cohort() CohortRepository {
-	return &pgCohortRepo{q: s.q}
+	return &pgCohortRepo{q: s.q, pool: s.pool, views: s.cohortViews}
 }
 
 // Clinics returns the ClinicRepository implementation
 func (s *PostgresStore) Clinics() ClinicRepository {
-	return &pgClinicRepo{q: s.q}
+	return &pgClinicRepo{q: s.q, pool: s.pool}
 }
 
 // pgCohortRepo implements CohortRepository
-type pgCohortRepo struct{ q *sqlcgen.Queries }
+type pgCohortRepo struct {
+	q    *sqlcgen.Queries
+	pool *pgxpool.Pool
+	// views reads StatsByCluster, StatsByAgeGroup and StatsByMenopauseStatus
+	// from the cohort statistics views
+	views bool
+}
 
 func (r *pgCohortRepo) StatsByCluster(ctx context.Context) ([]models.CohortGroup, error) {
 	if r.q == nil {
 		return nil, errors.New("db not configured")
 	}
+	if r.views {
+		return r.statsFromView(ctx, "cohort_stats_by_cluster", true)
+	}
 	rows, err := r.q.CohortStatsByCluster(ctx)
 	if err != nil {
 		return nil, err
@@ -77,6 +89,9 @@ func (r *pgCohortRepo) StatsByAgeGroup(ctx context.Context) ([]models.CohortGrou
 	if r.q == nil {
 		return nil, errors.New("db not configured")
 	}
+	if r.views {
+		return r.statsFromView(ctx, "cohort_stats_by_age_group", false)
+	}
 	rows, err := r.q.CohortStatsByAgeGroup(ctx)
 	if err != nil {
 		return nil, err
@@ -101,6 +116,9 @@ func (r *pgCohortRepo) StatsByMenopauseStatus(ctx context.Context) ([]models.Coh
 	if r.q == nil {
 		return nil, errors.New("db not configured")
 	}
+	if r.views {
+		return r.statsFromView(ctx, "cohort_stats_by_menopause_status", false)
+	}
 	rows, err := r.q.CohortStatsByMenopauseStatus(ctx)
 	if err != nil {
 		return nil, err
@@ -143,8 +161,153 @@ func (r *pgCohortRepo) TotalAssessmentCount(ctx context.Context) (int, error) {
 	return int(count), nil
 }
 
+// cohortGroupExprs maps each cohort dimension to the SQL expression naming an
+// assessment's group. These mirror the CASE expressions in cohort.sql.
+var cohortGroupExprs = map[string]string{
+	"cluster": `COALESCE(a.cluster, 'Unknown')`,
+	"risk_level": `CASE WHEN a.risk_score < 34 THEN 'Low'
+		WHEN a.risk_score >= 34 AND a.risk_score < 67 THEN 'Moderate'
+		ELSE 'High' END`,
+	"age_group": `CASE WHEN p.age < 45 THEN 'Under 45'
+		WHEN p.age >= 45 AND p.age < 55 THEN '45-54'
+		WHEN p.age >= 55 AND p.age < 65 THEN '55-64'
+		ELSE '65+' END`,
+	"menopause_status": `COALESCE(p.menopause_status, 'Unknown')`,
+}
+
+// cohortMetrics are the assessment columns compared between cohorts
+var cohortMetrics = []string{"hba1c", "fbs", "bmi", "systolic", "diastolic", "risk_score"}
+
+// cohortScopeSQL returns the conditions restricting patients p to scope,
+// each prefixed with AND, numbering placeholders after the args already
+// passed. A clinic covers the patients owned by its members, as on the
+// clinic dashboard; a tag the patients carrying it.
+func cohortScopeSQL(scope models.CohortScope, args []interface{}) (string, []interface{}) {
+	var where string
+	if scope.UserID != nil {
+		args = append(args, *scope.UserID)
+		where += fmt.Sprintf(` AND p.user_id = $%d`, len(args))
+	}
+	if scope.ClinicID != nil {
+		args = append(args, *scope.ClinicID)
+		where += fmt.Sprintf(` AND p.user_id IN (SELECT user_id FROM user_clinics WHERE clinic_id = $%d)`, len(args))
+	}
+	if scope.TagID != nil {
+		args = append(args, *scope.TagID)
+		where += fmt.Sprintf(` AND p.id IN (SELECT patient_id FROM patient_tags WHERE tag_id = $%d)`, len(args))
+	}
+	if len(scope.ClinicIDs) > 0 {
+		args = append(args, scope.ClinicIDs)
+		where += fmt.Sprintf(` AND p.user_id IN (SELECT user_id FROM user_clinics WHERE clinic_id = ANY($%d))`, len(args))
+	}
+	return where, args
+}
+
+func (r *pgCohortRepo) ScopedStats(ctx context.Context, groupBy string, scope models.CohortScope) ([]models.CohortGroup, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	expr, ok := cohortGroupExprs[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown cohort dimension %q", groupBy)
+	}
+	where, args := cohortScopeSQL(scope, nil)
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+expr+` AS group_name,
+		       COUNT(*)::int,
+		       COALESCE(AVG(a.hba1c), 0)::float8,
+		       COALESCE(AVG(a.fbs), 0)::float8,
+		       COALESCE(AVG(a.bmi), 0)::float8,
+		       COALESCE(AVG(a.systolic), 0)::float8,
+		       COALESCE(AVG(a.diastolic), 0)::float8,
+		       COALESCE(AVG(a.risk_score), 0)::float8,
+		       COUNT(CASE WHEN a.risk_score < 34 THEN 1 END)::int,
+		       COUNT(CASE WHEN a.risk_score >= 34 AND a.risk_score < 67 THEN 1 END)::int,
+		       COUNT(CASE WHEN a.risk_score >= 67 THEN 1 END)::int
+		FROM assessments a
+		JOIN patients p ON a.patient_id = p.id
+		WHERE a.status = 'final'
+		  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)`+where+`
+		GROUP BY 1
+		ORDER BY 1`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.CohortGroup{}
+	for rows.Next() {
+		var g models.CohortGroup
+		if err := rows.Scan(&g.Name, &g.Count, &g.AvgHbA1c, &g.AvgFBS, &g.AvgBMI, &g.AvgBPSystolic,
+			&g.AvgBPDiastolic, &g.AvgRiskScore, &g.LowRiskCount, &g.ModerateRiskCount, &g.HighRiskCount); err != nil {
+			return nil, err
+		}
+		result = append(result, g)
+	}
+	return result, rows.Err()
+}
+
+func (r *pgCohortRepo) ScopedTotals(ctx context.Context, scope models.CohortScope) (int, int, error) {
+	if r.pool == nil {
+		return 0, 0, errors.New("db not configured")
+	}
+	where, args := cohortScopeSQL(scope, nil)
+	var patients, assessments int
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT p.id)::int, COUNT(a.id)::int
+		FROM patients p
+		LEFT JOIN assessments a ON a.patient_id = p.id AND a.status = 'final'
+		  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
+		WHERE true`+where, args...).Scan(&patients, &assessments)
+	return patients, assessments, err
+}
+
+func (r *pgCohortRepo) GroupSample(ctx context.Context, groupBy, name string, scope models.CohortScope) (*models.CohortSample, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	expr, ok := cohortGroupExprs[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown cohort dimension %q", groupBy)
+	}
+
+	query := `SELECT COUNT(*)::int`
+	for _, m := range cohortMetrics {
+		query += `, COUNT(a.` + m + `)::int, COALESCE(AVG(a.` + m + `), 0)::float8, COALESCE(STDDEV_SAMP(a.` + m + `), 0)::float8`
+	}
+	query += `,
+		COUNT(CASE WHEN a.risk_score < 34 THEN 1 END)::int,
+		COUNT(CASE WHEN a.risk_score >= 34 AND a.risk_score < 67 THEN 1 END)::int,
+		COUNT(CASE WHEN a.risk_score >= 67 THEN 1 END)::int
+		FROM assessments a
+		JOIN patients p ON a.patient_id = p.id
+		WHERE a.status = 'final'
+		  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id) AND ` + expr + ` = $1`
+	where, args := cohortScopeSQL(scope, []interface{}{name})
+	query += where
+
+	sample := models.CohortSample{Name: name, Metrics: make(map[string]models.MetricMoments, len(cohortMetrics))}
+	moments := make([]models.MetricMoments, len(cohortMetrics))
+	dest := []interface{}{&sample.Count}
+	for i := range moments {
+		dest = append(dest, &moments[i].N, &moments[i].Mean, &moments[i].StdDev)
+	}
+	dest = append(dest, &sample.RiskLevelCounts[0], &sample.RiskLevelCounts[1], &sample.RiskLevelCounts[2])
+
+	if err := r.pool.QueryRow(ctx, query, args...).Scan(dest...); err != nil {
+		return nil, err
+	}
+	for i, m := range cohortMetrics {
+		sample.Metrics[m] = moments[i]
+	}
+	return &sample, nil
+}
+
 // pgClinicRepo implements ClinicRepository
-type pgClinicRepo struct{ q *sqlcgen.Queries }
+type pgClinicRepo struct {
+	q    *sqlcgen.Queries
+	pool *pgxpool.Pool
+}
 
 func (r *pgClinicRepo) List(ctx context.Context) ([]models.Clinic, error) {
 	if r.q == nil {
@@ -242,6 +405,16 @@ func (r *pgClinicRepo) IsClinicAdmin(ctx context.Context, userID, clinicID int32
 	return result, nil
 }
 
+func (r *pgClinicRepo) ClinicRole(ctx context.Context, userID, clinicID int32) (string, error) {
+	if r.q == nil {
+		return "", errors.New("db not configured")
+	}
+	return r.q.GetUserClinicRole(ctx, sqlcgen.GetUserClinicRoleParams{
+		UserID:   userID,
+		ClinicID: clinicID,
+	})
+}
+
 func (r *pgClinicRepo) ClinicAggregate(ctx context.Context, clinicID int32) (*models.ClinicAggregate, error) {
 	if r.q == nil {
 		return nil, errors.New("db not configured")
@@ -259,44 +432,130 @@ func (r *pgClinicRepo) ClinicAggregate(ctx context.Context, clinicID int32) (*mo
 	}, nil
 }
 
-func (r *pgClinicRepo) AdminSystemStats(ctx context.Context) (*models.SystemStats, error) {
-	if r.q == nil {
+// statsRangeSQL appends r's bounds on a.created_at to args and returns the
+// matching conditions, each prefixed with AND.
+func statsRangeSQL(r models.StatsRange, args []interface{}) (string, []interface{}) {
+	var where string
+	if r.Start != nil {
+		args = append(args, *r.Start)
+		where += fmt.Sprintf(` AND a.created_at >= $%d`, len(args))
+	}
+	if r.End != nil {
+		args = append(args, *r.End)
+		where += fmt.Sprintf(` AND a.created_at < $%d`, len(args))
+	}
+	return where, args
+}
+
+func (r *pgClinicRepo) AdminSystemStats(ctx context.Context, sr models.StatsRange) (*models.SystemStats, error) {
+	if r.pool == nil {
 		return nil, errors.New("db not configured")
 	}
-	row, err := r.q.AdminSystemStats(ctx)
+	where, args := statsRangeSQL(sr, nil)
+	var stats models.SystemStats
+	err := r.pool.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*)::int FROM users),
+		       (SELECT COUNT(*)::int FROM patients),
+		       COUNT(*)::int,
+		       (SELECT COUNT(*)::int FROM clinics),
+		       COALESCE(AVG(a.risk_score), 0)::float8,
+		       COUNT(CASE WHEN a.risk_score >= 67 THEN 1 END)::int,
+		       (SELECT COUNT(*)::int FROM assessments m WHERE status = 'final' AND created_at >= date_trunc('month', CURRENT_DATE)
+		            AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = m.id)),
+		       (SELECT COUNT(*)::int FROM users WHERE created_at >= date_trunc('month', CURRENT_DATE))
+		FROM assessments a
+		WHERE a.status = 'final'
+		  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)`+where, args...).Scan(&stats.TotalUsers, &stats.TotalPatients, &stats.TotalAssessments,
+		&stats.TotalClinics, &stats.AvgRiskScore, &stats.HighRiskCount, &stats.AssessmentsThisMonth, &stats.NewUsersThisMonth)
 	if err != nil {
 		return nil, err
 	}
-	return &models.SystemStats{
-		TotalUsers:           int(row.TotalUsers),
-		TotalPatients:        int(row.TotalPatients),
-		TotalAssessments:     int(row.TotalAssessments),
-		TotalClinics:         int(row.TotalClinics),
-		AvgRiskScore:         row.AvgRiskScore,
-		HighRiskCount:        int(row.HighRiskCount),
-		AssessmentsThisMonth: int(row.AssessmentsThisMonth),
-		NewUsersThisMonth:    int(row.NewUsersThisMonth),
-	}, nil
+	return &stats, nil
 }
 
-func (r *pgClinicRepo) AdminClinicComparison(ctx context.Context) ([]models.ClinicComparison, error) {
-	if r.q == nil {
+func (r *pgClinicRepo) AdminClinicComparison(ctx context.Context, sr models.StatsRange) ([]models.ClinicComparison, error) {
+	if r.pool == nil {
 		return nil, errors.New("db not configured")
 	}
-	rows, err := r.q.AdminClinicComparison(ctx)
+	// The range sits in the join so clinics without assessments in it
+	// still appear with their patient counts.
+	where, args := statsRangeSQL(sr, nil)
+	rows, err := r.pool.Query(ctx, `
+		SELECT c.id,
+		       c.name,
+		       COUNT(DISTINCT p.id)::int AS patient_count,
+		       COUNT(a.id)::int,
+		       COALESCE(AVG(a.risk_score), 0)::float8,
+		       COUNT(CASE WHEN a.risk_score >= 67 THEN 1 END)::int
+		FROM clinics c
+		LEFT JOIN user_clinics uc ON c.id = uc.clinic_id
+		LEFT JOIN patients p ON p.user_id = uc.user_id
+		LEFT JOIN assessments a ON a.patient_id = p.id AND a.status = 'final'
+		  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)`+where+`
+		GROUP BY c.id, c.name
+		ORDER BY patient_count DESC`, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
 	var result []models.ClinicComparison
-	for _, row := range rows {
-		result = append(result, models.ClinicComparison{
-			ClinicID:        int64(row.ClinicID),
-			ClinicName:      row.ClinicName,
-			PatientCount:    int(row.PatientCount),
-			AssessmentCount: int(row.AssessmentCount),
-			AvgRiskScore:    row.AvgRiskScore,
-			HighRiskCount:   int(row.HighRiskCount),
-		})
+	for rows.Next() {
+		var cc models.ClinicComparison
+		if err := rows.Scan(&cc.ClinicID, &cc.ClinicName, &cc.PatientCount, &cc.AssessmentCount,
+			&cc.AvgRiskScore, &cc.HighRiskCount); err != nil {
+			return nil, err
+		}
+		result = append(result, cc)
+	}
+	return result, rows.Err()
+}
+
+func (r *pgClinicRepo) GetValidationMode(ctx context.Context, clinicID int32) (string, error) {
+	if r.pool == nil {
+		return "", errors.New("db not configured")
 	}
-	return result, nil
+	var mode string
+	err := r.pool.QueryRow(ctx, `SELECT validation_mode FROM clinics WHERE id = $1`, clinicID).Scan(&mode)
+	if err != nil {
+		return "", err
+	}
+	return mode, nil
+}
+
+func (r *pgClinicRepo) SetValidationMode(ctx context.Context, clinicID int32, mode string) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE clinics SET validation_mode = $2, updated_at = NOW() WHERE id = $1`,
+		clinicID, mode,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *pgClinicRepo) ValidationModeForUser(ctx context.Context, userID int32) (string, error) {
+	if r.pool == nil {
+		return "", errors.New("db not configured")
+	}
+	query := `
+		SELECT COALESCE(bool_or(c.validation_mode = 'strict'), false)
+		FROM clinics c
+		JOIN user_clinics uc ON uc.clinic_id = c.id
+		WHERE uc.user_id = $1
+	`
+	var strict bool
+	if err := r.pool.QueryRow(ctx, query, userID).Scan(&strict); err != nil {
+		return "", err
+	}
+	if strict {
+		return models.ValidationModeStrict, nil
+	}
+	return models.ValidationModeAdvisory, nil
 }
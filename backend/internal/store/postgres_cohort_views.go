@@ -0,0 +1,70 @@
+// Materialized cohort statistics for PostgresStore
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// cohortStatsViews are the materialized views created by
+// 0050_add_cohort_stats_views.sql
+var cohortStatsViews = []string{
+	"cohort_stats_by_cluster",
+	"cohort_stats_by_age_group",
+	"cohort_stats_by_menopause_status",
+}
+
+// UseCohortStatsViews makes Cohort().StatsByCluster, StatsByAgeGroup and
+// StatsByMenopauseStatus read the cohort statistics views instead of
+// scanning every assessment. Their results then lag writes until the next
+// RefreshCohortStatsViews. Call it before the store is shared.
+func (s *PostgresStore) UseCohortStatsViews(on bool) {
+	s.cohortViews = on
+}
+
+// RefreshCohortStatsViews recomputes the cohort statistics views. Reads of
+// the views are not blocked while it runs.
+func (s *PostgresStore) RefreshCohortStatsViews(ctx context.Context) error {
+	if s.pool == nil {
+		return errors.New("db not configured")
+	}
+	for _, view := range cohortStatsViews {
+		if _, err := s.pool.Exec(ctx, "REFRESH MATERIALIZED VIEW CONCURRENTLY "+view); err != nil {
+			return fmt.Errorf("refresh %s: %w", view, err)
+		}
+	}
+	return nil
+}
+
+// statsFromView reads the rows a StatsBy* query would return from view,
+// with the risk level counts only when withRisk is set, as StatsByCluster
+// alone reports them.
+func (r *pgCohortRepo) statsFromView(ctx context.Context, view string, withRisk bool) ([]models.CohortGroup, error) {
+	cols := `group_name, count, avg_hba1c, avg_fbs, avg_bmi, avg_bp_systolic, avg_bp_diastolic, avg_risk_score`
+	if withRisk {
+		cols += `, low_risk_count, moderate_risk_count, high_risk_count`
+	}
+	rows, err := r.pool.Query(ctx, `SELECT `+cols+` FROM `+view)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.CohortGroup
+	for rows.Next() {
+		var g models.CohortGroup
+		dest := []any{&g.Name, &g.Count, &g.AvgHbA1c, &g.AvgFBS, &g.AvgBMI,
+			&g.AvgBPSystolic, &g.AvgBPDiastolic, &g.AvgRiskScore}
+		if withRisk {
+			dest = append(dest, &g.LowRiskCount, &g.ModerateRiskCount, &g.HighRiskCount)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		result = append(result, g)
+	}
+	return result, rows.Err()
+}
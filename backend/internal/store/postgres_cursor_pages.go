@@ -0,0 +1,84 @@
+// postgres_cursor_pages.go: Keyset pages of the patient and assessment lists.
+package store
+
+import (
+	"context"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// assessmentColumns matches the column order scanAssessmentRow reads
+const assessmentColumns = `id, patient_id, fbs, hba1c, cholesterol, ldl, hdl, triglycerides, systolic, diastolic,
+	activity, history_flag, smoking, hypertension, heart_disease, bmi, cluster, risk_score,
+	model_version, dataset_hash, validation_status, created_at, updated_at, self_reported,
+	quality_score, quality_completeness, quality_out_of_range, status, height_cm, weight_kg, bmi_source`
+
+// beforeCursor keeps rows that come after the cursor in a newest-first
+// (created_at, id) ordering; nil keeps every row.
+func beforeCursor(createdAt, id string, after *models.ListCursor) sq.Sqlizer {
+	if after == nil {
+		return sq.And{}
+	}
+	return sq.Expr("("+createdAt+", "+id+") < (?, ?)", after.CreatedAt, after.ID)
+}
+
+func (r *pgPatientRepo) ListWithLatestAssessmentAfter(ctx context.Context, userID int32, params models.PatientListParams, after *models.ListCursor) ([]models.PatientWithLatest, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	query, args, err := psql.Select(patientWithLatestColumns).
+		From(patientsWithLatest).
+		Where(append(patientListFilter(userID, params), beforeCursor("p.created_at", "p.id", after))).
+		OrderBy("p.created_at DESC", "p.id DESC").
+		Limit(uint64(max(params.PageSize, 1))).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	out := []models.PatientWithLatest{}
+	err = eachRow(ctx, r.pool, query, scanPatientWithLatest, func(pw models.PatientWithLatest) error {
+		out = append(out, pw)
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *pgAssessmentRepo) ListByPatientPage(ctx context.Context, patientID int64, params models.AssessmentPageParams) ([]models.Assessment, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	where := sq.And{
+		sq.Eq{"patient_id": patientID},
+		sq.Expr("NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)"),
+		beforeCursor("created_at", "id", params.After),
+	}
+	if params.MinQuality != nil {
+		where = append(where, sq.GtOrEq{"quality_score": *params.MinQuality})
+	}
+	if params.MaxQuality != nil {
+		where = append(where, sq.LtOrEq{"quality_score": *params.MaxQuality})
+	}
+	query, args, err := psql.Select(assessmentColumns).
+		From("assessments a").
+		Where(where).
+		OrderBy("created_at DESC", "id DESC").
+		Limit(uint64(max(params.Limit, 1))).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	out := []models.Assessment{}
+	err = eachRow(ctx, r.pool, query, scanAssessmentRow, func(a models.Assessment) error {
+		out = append(out, a)
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
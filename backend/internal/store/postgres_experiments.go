@@ -0,0 +1,74 @@
+// postgres_experiments.go: Experiment exposures and per-variant outcomes.
+package store
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) Experiments() ExperimentRepository {
+	return &pgExperimentRepo{pool: s.pool}
+}
+
+type pgExperimentRepo struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgExperimentRepo) RecordExposure(ctx context.Context, e models.ExperimentExposure) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO experiment_exposures (experiment, variant, unit, user_id, patient_id, assessment_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (experiment, assessment_id) DO NOTHING`,
+		e.Experiment, e.Variant, e.Unit, e.UserID, e.PatientID, e.AssessmentID)
+	return err
+}
+
+func (r *pgExperimentRepo) Results(ctx context.Context, experiment string, followUp time.Duration) ([]models.ExperimentVariantResult, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		WITH e AS (
+			SELECT *, exposed_at <= NOW() - $2 * INTERVAL '1 second' AS eligible
+			FROM experiment_exposures
+			WHERE experiment = $1
+		)
+		SELECT e.variant, COUNT(*)::int, COUNT(DISTINCT e.unit)::int,
+		       COUNT(*) FILTER (WHERE e.eligible)::int,
+		       COUNT(*) FILTER (WHERE e.eligible AND EXISTS (
+		           SELECT 1 FROM assessments a
+		           WHERE a.patient_id = e.patient_id
+		             AND a.id <> e.assessment_id
+		             AND a.status = 'final'
+		             AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
+		             AND a.created_at > e.exposed_at
+		             AND a.created_at <= e.exposed_at + $2 * INTERVAL '1 second'))::int
+		FROM e
+		GROUP BY e.variant
+		ORDER BY e.variant`, experiment, int64(followUp.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.ExperimentVariantResult{}
+	for rows.Next() {
+		var v models.ExperimentVariantResult
+		if err := rows.Scan(&v.Variant, &v.Exposures, &v.Units, &v.Eligible, &v.FollowedUp); err != nil {
+			return nil, err
+		}
+		if v.Eligible > 0 {
+			v.Rate = math.Round(1000*float64(v.FollowedUp)/float64(v.Eligible)) / 1000
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
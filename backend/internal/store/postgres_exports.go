@@ -0,0 +1,127 @@
+// postgres_exports.go: Row-at-a-time reads behind the CSV and JSON exports.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	sqlcgen "github.com/skufu/DianaV2/backend/internal/store/sqlc"
+)
+
+// The columns and order match ListPatientsLimited and
+// ListAssessmentsLimitedByUser so the rows map like theirs.
+const (
+	eachPatientSQL = `
+		SELECT id, user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
+		       activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides, mrn,
+		       height_cm, weight_kg, bmi_source,
+		       created_at, updated_at
+		FROM patients
+		WHERE user_id = $1
+		ORDER BY id DESC
+		LIMIT $2`
+	eachPatientByClinicSQL = `
+		SELECT id, user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
+		       activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides, mrn,
+		       height_cm, weight_kg, bmi_source,
+		       created_at, updated_at
+		FROM patients
+		WHERE clinic_id = $1
+		ORDER BY id DESC
+		LIMIT $2`
+	eachAssessmentByUserSQL = `
+		SELECT a.id, a.patient_id, a.fbs, a.hba1c, a.cholesterol, a.ldl, a.hdl, a.triglycerides,
+		       a.systolic, a.diastolic, a.activity, a.history_flag, a.smoking, a.hypertension,
+		       a.heart_disease, a.bmi, a.cluster, a.risk_score, a.model_version, a.dataset_hash,
+		       a.validation_status, a.created_at, a.updated_at,
+		       a.self_reported, a.quality_score, a.quality_completeness, a.quality_out_of_range, a.status, a.height_cm, a.weight_kg, a.bmi_source
+		FROM assessments a
+		INNER JOIN patients p ON a.patient_id = p.id
+		WHERE p.user_id = $1 AND a.status = 'final'
+		  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
+		ORDER BY a.created_at DESC
+		LIMIT $2`
+	eachAssessmentByClinicSQL = `
+		SELECT a.id, a.patient_id, a.fbs, a.hba1c, a.cholesterol, a.ldl, a.hdl, a.triglycerides,
+		       a.systolic, a.diastolic, a.activity, a.history_flag, a.smoking, a.hypertension,
+		       a.heart_disease, a.bmi, a.cluster, a.risk_score, a.model_version, a.dataset_hash,
+		       a.validation_status, a.created_at, a.updated_at,
+		       a.self_reported, a.quality_score, a.quality_completeness, a.quality_out_of_range, a.status, a.height_cm, a.weight_kg, a.bmi_source
+		FROM assessments a
+		INNER JOIN patients p ON a.patient_id = p.id
+		WHERE p.clinic_id = $1 AND a.status = 'final'
+		  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
+		ORDER BY a.created_at DESC
+		LIMIT $2`
+)
+
+func scanPatientLimitedRow(row pgx.Row) (models.Patient, error) {
+	var i sqlcgen.ListPatientsLimitedRow
+	if err := row.Scan(
+		&i.ID, &i.UserID, &i.Name, &i.Age, &i.MenopauseStatus, &i.YearsMenopause, &i.Bmi,
+		&i.BpSystolic, &i.BpDiastolic, &i.Activity, &i.PhysActivity, &i.Smoking, &i.Hypertension,
+		&i.HeartDisease, &i.FamilyHistory, &i.Chol, &i.Ldl, &i.Hdl, &i.Triglycerides, &i.Mrn,
+		&i.HeightCm, &i.WeightKg, &i.BmiSource,
+		&i.CreatedAt, &i.UpdatedAt,
+	); err != nil {
+		return models.Patient{}, err
+	}
+	return mapPatientLimitedRows([]sqlcgen.ListPatientsLimitedRow{i})[0], nil
+}
+
+func scanAssessmentRow(row pgx.Row) (models.Assessment, error) {
+	var i sqlcgen.Assessment
+	if err := row.Scan(
+		&i.ID, &i.PatientID, &i.Fbs, &i.Hba1c, &i.Cholesterol, &i.Ldl, &i.Hdl, &i.Triglycerides,
+		&i.Systolic, &i.Diastolic, &i.Activity, &i.HistoryFlag, &i.Smoking, &i.Hypertension,
+		&i.HeartDisease, &i.Bmi, &i.Cluster, &i.RiskScore, &i.ModelVersion, &i.DatasetHash,
+		&i.ValidationStatus, &i.CreatedAt, &i.UpdatedAt,
+		&i.SelfReported, &i.QualityScore, &i.QualityCompleteness, &i.QualityOutOfRange, &i.Status,
+		&i.HeightCm, &i.WeightKg, &i.BmiSource,
+	); err != nil {
+		return models.Assessment{}, err
+	}
+	return mapAssessment(i), nil
+}
+
+// eachRow runs query and hands every row to fn through scan, stopping at the
+// first error from either.
+func eachRow[T any](ctx context.Context, pool *pgxpool.Pool, query string, scan func(pgx.Row) (T, error), fn func(T) error, args ...any) error {
+	if pool == nil {
+		return errors.New("db not configured")
+	}
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		v, err := scan(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *pgPatientRepo) EachLimited(ctx context.Context, userID int32, limit int, fn func(models.Patient) error) error {
+	return eachRow(ctx, r.pool, eachPatientSQL, scanPatientLimitedRow, fn, userID, limit)
+}
+
+func (r *pgAssessmentRepo) EachLimitedByUser(ctx context.Context, userID int32, limit int, fn func(models.Assessment) error) error {
+	return eachRow(ctx, r.pool, eachAssessmentByUserSQL, scanAssessmentRow, fn, userID, limit)
+}
+
+func (r *pgPatientRepo) EachLimitedByClinic(ctx context.Context, clinicID int32, limit int, fn func(models.Patient) error) error {
+	return eachRow(ctx, r.pool, eachPatientByClinicSQL, scanPatientLimitedRow, fn, clinicID, limit)
+}
+
+func (r *pgAssessmentRepo) EachLimitedByClinic(ctx context.Context, clinicID int32, limit int, fn func(models.Assessment) error) error {
+	return eachRow(ctx, r.pool, eachAssessmentByClinicSQL, scanAssessmentRow, fn, clinicID, limit)
+}
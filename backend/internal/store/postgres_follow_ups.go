@@ -0,0 +1,160 @@
+// postgres_follow_ups.go: Follow-up visits scheduled for patients.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) FollowUps() FollowUpRepository {
+	return &pgFollowUpRepo{pool: s.pool}
+}
+
+type pgFollowUpRepo struct {
+	pool *pgxpool.Pool
+}
+
+const followUpColumns = `f.id, f.patient_id, p.user_id, p.name, f.due_at, f.reason, f.completed_at, f.reminded_at, f.created_at, f.updated_at`
+
+func scanFollowUp(row pgx.Row) (*models.FollowUp, error) {
+	var f models.FollowUp
+	var userID *int32
+	if err := row.Scan(&f.ID, &f.PatientID, &userID, &f.PatientName, &f.DueAt, &f.Reason,
+		&f.CompletedAt, &f.RemindedAt, &f.CreatedAt, &f.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if userID != nil {
+		f.UserID = int64(*userID)
+	}
+	return &f, nil
+}
+
+func (r *pgFollowUpRepo) list(ctx context.Context, query string, args ...interface{}) ([]models.FollowUp, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.FollowUp{}
+	for rows.Next() {
+		f, err := scanFollowUp(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *f)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgFollowUpRepo) List(ctx context.Context, patientID int64) ([]models.FollowUp, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return r.list(ctx, `
+		SELECT `+followUpColumns+`
+		FROM follow_ups f JOIN patients p ON p.id = f.patient_id
+		WHERE f.patient_id = $1
+		ORDER BY f.due_at, f.id`, patientID)
+}
+
+func (r *pgFollowUpRepo) ListOpen(ctx context.Context, userID int64, due string, now time.Time) ([]models.FollowUp, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	where, args := `p.user_id = $1 AND f.completed_at IS NULL`, []interface{}{userID}
+	switch due {
+	case models.FollowUpsOverdue:
+		where, args = where+` AND f.due_at < $2`, append(args, now)
+	case models.FollowUpsUpcoming:
+		where, args = where+` AND f.due_at >= $2`, append(args, now)
+	}
+	return r.list(ctx, `
+		SELECT `+followUpColumns+`
+		FROM follow_ups f JOIN patients p ON p.id = f.patient_id
+		WHERE `+where+`
+		ORDER BY f.due_at, f.id`, args...)
+}
+
+func (r *pgFollowUpRepo) Get(ctx context.Context, id, patientID int64) (*models.FollowUp, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanFollowUp(r.pool.QueryRow(ctx, `
+		SELECT `+followUpColumns+`
+		FROM follow_ups f JOIN patients p ON p.id = f.patient_id
+		WHERE f.id = $1 AND f.patient_id = $2`, id, patientID))
+}
+
+func (r *pgFollowUpRepo) Create(ctx context.Context, f models.FollowUp) (*models.FollowUp, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanFollowUp(r.pool.QueryRow(ctx, `
+		WITH f AS (
+			INSERT INTO follow_ups (patient_id, due_at, reason, completed_at)
+			VALUES ($1, $2, $3, $4)
+			RETURNING *
+		)
+		SELECT `+followUpColumns+` FROM f JOIN patients p ON p.id = f.patient_id`,
+		f.PatientID, f.DueAt, f.Reason, f.CompletedAt))
+}
+
+func (r *pgFollowUpRepo) Update(ctx context.Context, f models.FollowUp) (*models.FollowUp, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanFollowUp(r.pool.QueryRow(ctx, `
+		WITH f AS (
+			UPDATE follow_ups
+			SET due_at = $3, reason = $4, completed_at = $5,
+			    reminded_at = CASE WHEN due_at = $3 THEN reminded_at END,
+			    updated_at = NOW()
+			WHERE id = $1 AND patient_id = $2
+			RETURNING *
+		)
+		SELECT `+followUpColumns+` FROM f JOIN patients p ON p.id = f.patient_id`,
+		f.ID, f.PatientID, f.DueAt, f.Reason, f.CompletedAt))
+}
+
+func (r *pgFollowUpRepo) Delete(ctx context.Context, id, patientID int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `DELETE FROM follow_ups WHERE id = $1 AND patient_id = $2`, id, patientID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ClaimReminders locks the rows it claims with SKIP LOCKED, so replicas
+// running the reminder job at the same time never remind twice.
+func (r *pgFollowUpRepo) ClaimReminders(ctx context.Context, before, now time.Time, limit int) ([]models.FollowUp, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return r.list(ctx, `
+		WITH f AS (
+			UPDATE follow_ups
+			SET reminded_at = $2
+			WHERE id IN (
+				SELECT id FROM follow_ups
+				WHERE completed_at IS NULL AND reminded_at IS NULL AND due_at < $1
+				ORDER BY due_at, id
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT `+followUpColumns+` FROM f JOIN patients p ON p.id = f.patient_id
+		ORDER BY f.due_at, f.id`, before, now, limit)
+}
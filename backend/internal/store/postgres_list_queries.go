@@ -0,0 +1,91 @@
+// postgres_list_queries.go: Filters of the paginated admin user, audit event
+// and patient lists, built with squirrel so every value is a bind parameter.
+package store
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// psql builds queries with $1, $2... placeholders
+var psql = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+// userListFilter returns the conditions of pgUserRepo.List. Columns are
+// qualified with u, the users table.
+func userListFilter(params models.UserListParams) sq.And {
+	where := sq.And{}
+	if params.Search != "" {
+		where = append(where, sq.Expr("u.email ILIKE '%' || ? || '%'", params.Search))
+	}
+	if params.Role != "" {
+		where = append(where, sq.Eq{"u.role": params.Role})
+	}
+	if params.IsActive != nil {
+		where = append(where, sq.Expr("COALESCE(u.is_active, true) = ?", *params.IsActive))
+	}
+	return where
+}
+
+// auditListFilter returns the conditions of pgAuditEventRepo.List
+func auditListFilter(params models.AuditListParams) sq.And {
+	where := sq.And{}
+	if params.Actor != "" {
+		where = append(where, sq.Expr("actor ILIKE '%' || ? || '%'", params.Actor))
+	}
+	if params.Action != "" {
+		where = append(where, sq.Eq{"action": params.Action})
+	}
+	if params.TargetType != "" {
+		where = append(where, sq.Eq{"target_type": params.TargetType})
+	}
+	if params.TargetID != 0 {
+		where = append(where, sq.Eq{"target_id": params.TargetID})
+	}
+	if !params.StartDate.IsZero() {
+		where = append(where, sq.GtOrEq{"created_at": params.StartDate})
+	}
+	if !params.EndDate.IsZero() {
+		where = append(where, sq.LtOrEq{"created_at": params.EndDate})
+	}
+	return where
+}
+
+// patientListFilter returns the conditions of
+// pgPatientRepo.ListWithLatestAssessmentPaginated: the user's own patients,
+// or those shared with a clinic the user belongs to and may read patients
+// in. Cluster and risk
+// conditions use la, the patient's latest assessment.
+func patientListFilter(userID int32, params models.PatientListParams) sq.And {
+	where := sq.And{sq.Eq{"p.user_id": userID}}
+	if params.ClinicID != nil {
+		where = sq.And{
+			sq.Eq{"p.clinic_id": *params.ClinicID},
+			sq.Expr("EXISTS (SELECT 1 FROM user_clinics uc WHERE uc.clinic_id = p.clinic_id AND uc.user_id = ? AND "+sharedReadGrant+")", userID),
+		}
+	}
+	if params.Search != "" {
+		where = append(where, sq.Expr("p.name ILIKE '%' || ? || '%'", escapeLike(params.Search)))
+	}
+	if params.MinAge != nil {
+		where = append(where, sq.GtOrEq{"p.age": *params.MinAge})
+	}
+	if params.MaxAge != nil {
+		where = append(where, sq.LtOrEq{"p.age": *params.MaxAge})
+	}
+	if params.MenopauseStatus != "" {
+		where = append(where, sq.Eq{"p.menopause_status": params.MenopauseStatus})
+	}
+	if params.Cluster != "" {
+		where = append(where, sq.Eq{"la.cluster": params.Cluster})
+	}
+	if params.MinRisk != nil {
+		where = append(where, sq.GtOrEq{"la.risk_score": *params.MinRisk})
+	}
+	if params.MaxRisk != nil {
+		where = append(where, sq.LtOrEq{"la.risk_score": *params.MaxRisk})
+	}
+	if params.TagID != nil {
+		where = append(where, sq.Expr("EXISTS (SELECT 1 FROM patient_tags pt WHERE pt.patient_id = p.id AND pt.tag_id = ?)", *params.TagID))
+	}
+	return where
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func whereSQL(t *testing.T, where sq.And) (string, []interface{}) {
+	t.Helper()
+	sql, args, err := psql.Select("1").From("t").Where(where).ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sql, args
+}
+
+func TestUserListFilter(t *testing.T) {
+	active := false
+	cases := []struct {
+		name   string
+		params models.UserListParams
+		where  string
+		args   []interface{}
+	}{
+		{"none", models.UserListParams{}, "(1=1)", nil},
+		{"search", models.UserListParams{Search: "doc"}, "(u.email ILIKE '%' || $1 || '%')", []interface{}{"doc"}},
+		{"role and active", models.UserListParams{Role: "admin", IsActive: &active},
+			"(u.role = $1 AND COALESCE(u.is_active, true) = $2)", []interface{}{"admin", false}},
+		{"all", models.UserListParams{Search: "'; DROP TABLE users; --", Role: "clinician", IsActive: &active},
+			"(u.email ILIKE '%' || $1 || '%' AND u.role = $2 AND COALESCE(u.is_active, true) = $3)",
+			[]interface{}{"'; DROP TABLE users; --", "clinician", false}},
+	}
+	for _, tc := range cases {
+		sql, args := whereSQL(t, userListFilter(tc.params))
+		if want := "SELECT 1 FROM t WHERE " + tc.where; sql != want {
+			t.Errorf("%s: sql = %q, want %q", tc.name, sql, want)
+		}
+		if !reflect.DeepEqual(args, tc.args) {
+			t.Errorf("%s: args = %v, want %v", tc.name, args, tc.args)
+		}
+	}
+}
+
+func TestAuditListFilter(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	cases := []struct {
+		name   string
+		params models.AuditListParams
+		where  string
+		args   []interface{}
+	}{
+		{"none", models.AuditListParams{}, "(1=1)", nil},
+		{"actor and action", models.AuditListParams{Actor: "admin", Action: "user.create"},
+			"(actor ILIKE '%' || $1 || '%' AND action = $2)", []interface{}{"admin", "user.create"}},
+		{"target", models.AuditListParams{TargetType: "patient", TargetID: 9},
+			"(target_type = $1 AND target_id = $2)", []interface{}{"patient", 9}},
+		{"dates", models.AuditListParams{StartDate: start, EndDate: end},
+			"(created_at >= $1 AND created_at <= $2)", []interface{}{start, end}},
+		{"all", models.AuditListParams{Actor: "a", Action: "b", TargetType: "c", TargetID: 1, StartDate: start, EndDate: end},
+			"(actor ILIKE '%' || $1 || '%' AND action = $2 AND target_type = $3 AND target_id = $4 AND created_at >= $5 AND created_at <= $6)",
+			[]interface{}{"a", "b", "c", 1, start, end}},
+	}
+	for _, tc := range cases {
+		sql, args := whereSQL(t, auditListFilter(tc.params))
+		if want := "SELECT 1 FROM t WHERE " + tc.where; sql != want {
+			t.Errorf("%s: sql = %q, want %q", tc.name, sql, want)
+		}
+		if !reflect.DeepEqual(args, tc.args) {
+			t.Errorf("%s: args = %v, want %v", tc.name, args, tc.args)
+		}
+	}
+}
+
+func TestPatientListFilter(t *testing.T) {
+	minAge, maxRisk := 40, 80
+	clinic := int32(3)
+	tag := int64(5)
+	cases := []struct {
+		name   string
+		params models.PatientListParams
+		where  string
+		args   []interface{}
+	}{
+		{"own patients", models.PatientListParams{}, "(p.user_id = $1)", []interface{}{int32(7)}},
+		{"clinic", models.PatientListParams{ClinicID: &clinic},
+			"(p.clinic_id = $1 AND EXISTS (SELECT 1 FROM user_clinics uc WHERE uc.clinic_id = p.clinic_id AND uc.user_id = $2 AND " + sharedReadGrant + "))",
+			[]interface{}{int32(3), int32(7)}},
+		{"search escapes wildcards", models.PatientListParams{Search: "50%_a"},
+			"(p.user_id = $1 AND p.name ILIKE '%' || $2 || '%')", []interface{}{int32(7), `50\%\_a`}},
+		{"filters", models.PatientListParams{MinAge: &minAge, MenopauseStatus: "post", Cluster: "SIRD", MaxRisk: &maxRisk},
+			"(p.user_id = $1 AND p.age >= $2 AND p.menopause_status = $3 AND la.cluster = $4 AND la.risk_score <= $5)",
+			[]interface{}{int32(7), 40, "post", "SIRD", 80}},
+		{"tag", models.PatientListParams{TagID: &tag},
+			"(p.user_id = $1 AND EXISTS (SELECT 1 FROM patient_tags pt WHERE pt.patient_id = p.id AND pt.tag_id = $2))",
+			[]interface{}{int32(7), int64(5)}},
+	}
+	for _, tc := range cases {
+		sql, args := whereSQL(t, patientListFilter(7, tc.params))
+		if want := "SELECT 1 FROM t WHERE " + tc.where; sql != want {
+			t.Errorf("%s: sql = %q, want %q", tc.name, sql, want)
+		}
+		if !reflect.DeepEqual(args, tc.args) {
+			t.Errorf("%s: args = %v, want %v", tc.name, args, tc.args)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// postgres_list_versions.go: List summaries behind conditional GETs.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// scanListVersion reads COUNT, id sum and MAX(updated_at) columns
+func scanListVersion(row pgx.Row) (models.ListVersion, error) {
+	var v models.ListVersion
+	var last pgtype.Timestamptz
+	if err := row.Scan(&v.Count, &v.IDSum, &last); err != nil {
+		return models.ListVersion{}, err
+	}
+	if last.Valid {
+		v.LastUpdated = last.Time
+	}
+	return v, nil
+}
+
+// ListVersion sums the ids of the patients and their latest assessments, so
+// a new or deleted latest assessment changes it as well as an edit does.
+func (r *pgPatientRepo) ListVersion(ctx context.Context, userID int32, params models.PatientListParams) (models.ListVersion, error) {
+	if r.pool == nil {
+		return models.ListVersion{}, errors.New("db not configured")
+	}
+	query, args, err := psql.Select(
+		"COUNT(*)",
+		"(COALESCE(SUM(p.id), 0) + COALESCE(SUM(la.id), 0))::bigint",
+		"MAX(GREATEST(p.updated_at, la.updated_at))",
+	).From(patientsWithLatest).Where(patientListFilter(userID, params)).ToSql()
+	if err != nil {
+		return models.ListVersion{}, err
+	}
+	return scanListVersion(r.pool.QueryRow(ctx, query, args...))
+}
+
+func (r *pgAssessmentRepo) ListVersion(ctx context.Context, patientID int64) (models.ListVersion, error) {
+	if r.pool == nil {
+		return models.ListVersion{}, errors.New("db not configured")
+	}
+	return scanListVersion(r.pool.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(id), 0)::bigint, MAX(updated_at)
+		FROM assessments a
+		WHERE patient_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)`, patientID))
+}
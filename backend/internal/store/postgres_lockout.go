@@ -0,0 +1,55 @@
+// postgres_lockout.go: Failed login tracking and account lockout.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func (r *pgUserRepo) RecordFailedLogin(ctx context.Context, id int32, threshold int, lockout time.Duration) (*time.Time, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	// SET expressions see the old row, so both columns agree on whether this
+	// failure reaches the threshold.
+	var locked bool
+	var lockedUntil pgtype.Timestamptz
+	err := r.pool.QueryRow(ctx, `
+		UPDATE users SET
+			failed_login_attempts = CASE
+				WHEN failed_login_attempts + 1 >= $2 THEN 0
+				ELSE failed_login_attempts + 1
+			END,
+			locked_until = CASE
+				WHEN failed_login_attempts + 1 >= $2 THEN NOW() + $3 * INTERVAL '1 second'
+				ELSE locked_until
+			END
+		WHERE id = $1
+		RETURNING failed_login_attempts = 0, locked_until`,
+		id, threshold, lockout.Seconds()).Scan(&locked, &lockedUntil)
+	if err != nil || !locked {
+		return nil, err
+	}
+	return timePtr(lockedUntil), nil
+}
+
+func (r *pgUserRepo) ResetFailedLogins(ctx context.Context, id int32) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	_, err := r.pool.Exec(ctx, `UPDATE users SET failed_login_attempts = 0 WHERE id = $1`, id)
+	return err
+}
+
+func (r *pgUserRepo) Unlock(ctx context.Context, id int32) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	_, err := r.pool.Exec(ctx, `
+		UPDATE users SET failed_login_attempts = 0, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,106 @@
+// postgres_login_history.go: Sign-in attempts for account and abuse review.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) LoginHistory() LoginHistoryRepository {
+	return &pgLoginHistoryRepo{pool: s.pool}
+}
+
+type pgLoginHistoryRepo struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgLoginHistoryRepo) Record(ctx context.Context, a models.LoginAttempt) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	var userID pgtype.Int4
+	if a.UserID != nil {
+		userID = pgtype.Int4{Int32: int32(*a.UserID), Valid: true}
+	}
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO login_history (user_id, email, ip_address, user_agent, success, failure_reason)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		userID, a.Email, a.IPAddress, a.UserAgent, a.Success, a.FailureReason)
+	return err
+}
+
+func (r *pgLoginHistoryRepo) List(ctx context.Context, params models.LoginHistoryParams) ([]models.LoginAttempt, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	var userID pgtype.Int4
+	if params.UserID != nil {
+		userID = pgtype.Int4{Int32: int32(*params.UserID), Valid: true}
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, user_id, email, ip_address, user_agent, success, failure_reason, created_at
+		FROM login_history
+		WHERE ($1::int IS NULL OR user_id = $1)
+		  AND ($2 = '' OR ip_address = $2)
+		  AND (NOT $3 OR NOT success)
+		  AND ($4::timestamptz IS NULL OR created_at >= $4)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $5`,
+		userID, params.IPAddress, params.FailedOnly, params.Since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.LoginAttempt{}
+	for rows.Next() {
+		var a models.LoginAttempt
+		var uid pgtype.Int4
+		if err := rows.Scan(&a.ID, &uid, &a.Email, &a.IPAddress, &a.UserAgent, &a.Success, &a.FailureReason, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if uid.Valid {
+			id := int64(uid.Int32)
+			a.UserID = &id
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgLoginHistoryRepo) SuspiciousIPs(ctx context.Context, since time.Time, minFailures int) ([]models.SuspiciousIP, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT ip_address, COUNT(*)::int, COUNT(DISTINCT lower(email))::int, MAX(created_at)
+		FROM login_history
+		WHERE NOT success AND created_at >= $1
+		GROUP BY ip_address
+		HAVING COUNT(*) >= $2
+		ORDER BY COUNT(*) DESC, ip_address`,
+		since, minFailures)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.SuspiciousIP{}
+	for rows.Next() {
+		var s models.SuspiciousIP
+		if err := rows.Scan(&s.IPAddress, &s.Failures, &s.Accounts, &s.LastAttempt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
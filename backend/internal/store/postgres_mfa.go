@@ -0,0 +1,201 @@
+// postgres_mfa.go: Two-factor enrollments, backup codes and required roles.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) MFA() MFARepository {
+	return &pgMFARepo{pool: s.pool}
+}
+
+type pgMFARepo struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgMFARepo) Get(ctx context.Context, userID int64) (*models.MFAEnrollment, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	var e models.MFAEnrollment
+	var enabledAt pgtype.Timestamptz
+	err := r.pool.QueryRow(ctx, `
+		SELECT m.secret, m.enabled_at, m.last_step, m.created_at,
+		       (SELECT COUNT(*) FROM user_backup_codes b WHERE b.user_id = m.user_id AND b.used_at IS NULL)
+		FROM user_mfa m
+		WHERE m.user_id = $1`, userID).Scan(&e.Secret, &enabledAt, &e.LastStep, &e.CreatedAt, &e.BackupCodesLeft)
+	if err != nil {
+		return nil, err
+	}
+	e.UserID = userID
+	e.EnabledAt = timePtr(enabledAt)
+	return &e, nil
+}
+
+func (r *pgMFARepo) Begin(ctx context.Context, userID int64, secret string) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `
+		INSERT INTO user_mfa (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_step = 0, created_at = NOW()
+		WHERE user_mfa.enabled_at IS NULL`, userID, secret)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *pgMFARepo) Enable(ctx context.Context, userID int64, step int64, codeHashes []string) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE user_mfa SET enabled_at = NOW(), last_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL`, userID, step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	if err := replaceBackupCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *pgMFARepo) UseStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	if r.pool == nil {
+		return false, errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE user_mfa SET last_step = $2
+		WHERE user_id = $1 AND enabled_at IS NOT NULL AND last_step < $2`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *pgMFARepo) UseBackupCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	if r.pool == nil {
+		return false, errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE user_backup_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *pgMFARepo) ReplaceBackupCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := replaceBackupCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// replaceBackupCodes swaps the user's backup codes within tx
+func replaceBackupCodes(ctx context.Context, tx pgx.Tx, userID int64, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM user_backup_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO user_backup_codes (user_id, code_hash)
+		SELECT $1, unnest($2::text[])`, userID, codeHashes)
+	return err
+}
+
+func (r *pgMFARepo) Disable(ctx context.Context, userID int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_backup_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *pgMFARepo) RequiredRoles(ctx context.Context) ([]string, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `SELECT role FROM mfa_required_roles ORDER BY role`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (r *pgMFARepo) SetRequiredRoles(ctx context.Context, roles []string, updatedBy int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_required_roles WHERE role <> ALL($1::text[])`, roles); err != nil {
+		return err
+	}
+	by := pgtype.Int4{Int32: int32(updatedBy), Valid: updatedBy > 0}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO mfa_required_roles (role, updated_by)
+		SELECT unnest($1::text[]), $2
+		ON CONFLICT (role) DO NOTHING`, roles, by); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
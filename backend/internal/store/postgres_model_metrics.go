@@ -0,0 +1,86 @@
+// postgres_model_metrics.go: Model run metrics and the prediction distributions drift reports compare.
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (r *pgModelRunRepo) Distribution(ctx context.Context, version, datasetHash string, since time.Time) (models.PredictionDistribution, error) {
+	if r.pool == nil {
+		return models.PredictionDistribution{}, errors.New("db not configured")
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT cluster, COUNT(*), COALESCE(SUM(risk_score), 0)
+		FROM assessments a
+		WHERE status = 'final'
+		  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
+		  AND model_version = $1
+		  AND COALESCE(dataset_hash, '') = $2
+		  AND created_at >= $3
+		  AND cluster IS NOT NULL
+		  AND cluster NOT IN ('error', 'unknown', $4)
+		GROUP BY cluster
+	`, version, datasetHash, since, models.ClusterPendingPrediction)
+	if err != nil {
+		return models.PredictionDistribution{}, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	var riskSum int64
+	for rows.Next() {
+		var cluster string
+		var count int
+		var sum int64
+		if err := rows.Scan(&cluster, &count, &sum); err != nil {
+			return models.PredictionDistribution{}, err
+		}
+		counts[cluster] = count
+		riskSum += sum
+	}
+	if err := rows.Err(); err != nil {
+		return models.PredictionDistribution{}, err
+	}
+	return newDistribution(counts, riskSum), nil
+}
+
+// newDistribution turns per-cluster counts and a risk score total into shares
+// and a mean
+func newDistribution(counts map[string]int, riskSum int64) models.PredictionDistribution {
+	out := models.PredictionDistribution{Clusters: map[string]float64{}}
+	for _, n := range counts {
+		out.Count += n
+	}
+	if out.Count == 0 {
+		return out
+	}
+	for cluster, n := range counts {
+		out.Clusters[cluster] = float64(n) / float64(out.Count)
+	}
+	out.MeanRiskScore = float64(riskSum) / float64(out.Count)
+	return out
+}
+
+func encodeDistribution(d *models.PredictionDistribution) ([]byte, error) {
+	if d == nil {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
+func decodeDistribution(b []byte) (*models.PredictionDistribution, error) {
+	if b == nil {
+		return nil, nil
+	}
+	var d models.PredictionDistribution
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,99 @@
+// postgres_model_predictions.go: Assessments re-scored by a model run.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// Get returns pgx.ErrNoRows if the run does not exist.
+func (r *pgModelRunRepo) Get(ctx context.Context, id int32) (*models.ModelRun, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+
+	var run models.ModelRun
+	var datasetHash, notes pgtype.Text
+	var distribution []byte
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, model_version, dataset_hash, notes, is_active, created_at,
+		       accuracy, auc, calibration_error, training_distribution
+		FROM model_runs
+		WHERE id = $1
+	`, id).Scan(&run.ID, &run.ModelVersion, &datasetHash, &notes, &run.IsActive, &run.CreatedAt,
+		&run.Accuracy, &run.AUC, &run.CalibrationError, &distribution)
+	if err != nil {
+		return nil, err
+	}
+	run.DatasetHash = datasetHash.String
+	run.Notes = notes.String
+	if run.TrainingDistribution, err = decodeDistribution(distribution); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *pgModelRunRepo) SavePrediction(ctx context.Context, p models.AssessmentPrediction) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO assessment_predictions (
+			assessment_id, model_run_id, previous_cluster, previous_risk_score,
+			previous_model_version, cluster, risk_score
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (assessment_id, model_run_id) DO UPDATE
+		SET previous_cluster = EXCLUDED.previous_cluster,
+		    previous_risk_score = EXCLUDED.previous_risk_score,
+		    previous_model_version = EXCLUDED.previous_model_version,
+		    cluster = EXCLUDED.cluster,
+		    risk_score = EXCLUDED.risk_score,
+		    created_at = NOW()
+	`, p.AssessmentID, p.ModelRunID, textToPg(p.PreviousCluster), p.PreviousRiskScore,
+		textToPg(p.PreviousModelVersion), p.Cluster, p.RiskScore)
+	return err
+}
+
+func (r *pgModelRunRepo) ListPredictions(ctx context.Context, runID int32, limit, offset int) ([]models.AssessmentPrediction, int, error) {
+	if r.pool == nil {
+		return nil, 0, errors.New("db not configured")
+	}
+
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM assessment_predictions WHERE model_run_id = $1`, runID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT assessment_id, model_run_id, previous_cluster, previous_risk_score,
+		       previous_model_version, cluster, risk_score, created_at
+		FROM assessment_predictions
+		WHERE model_run_id = $1
+		ORDER BY assessment_id
+		LIMIT $2 OFFSET $3
+	`, runID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := []models.AssessmentPrediction{}
+	for rows.Next() {
+		var p models.AssessmentPrediction
+		var prevCluster, prevVersion pgtype.Text
+		var prevRisk pgtype.Int4
+		if err := rows.Scan(&p.AssessmentID, &p.ModelRunID, &prevCluster, &prevRisk,
+			&prevVersion, &p.Cluster, &p.RiskScore, &p.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		p.PreviousCluster = prevCluster.String
+		p.PreviousRiskScore = int(prevRisk.Int32)
+		p.PreviousModelVersion = prevVersion.String
+		out = append(out, p)
+	}
+	return out, total, rows.Err()
+}
@@ -0,0 +1,74 @@
+// postgres_password_reset.go: Password reset tokens.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func (s *PostgresStore) PasswordResets() PasswordResetRepository {
+	return &pgPasswordResetRepo{pool: s.pool}
+}
+
+type pgPasswordResetRepo struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgPasswordResetRepo) CreateToken(ctx context.Context, userID int32, tokenHash string, expiresAt time.Time) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)`,
+		userID, tokenHash, expiresAt)
+	return err
+}
+
+func (r *pgPasswordResetRepo) Reset(ctx context.Context, tokenHash, passwordHash string) (int32, error) {
+	if r.pool == nil {
+		return 0, errors.New("db not configured")
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int32
+	err = tx.QueryRow(ctx, `
+		UPDATE password_reset_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`, tokenHash).Scan(&userID)
+	if err != nil {
+		return 0, err // pgx.ErrNoRows for unknown, used or expired tokens
+	}
+
+	// Retire any other outstanding reset links for this user
+	if _, err := tx.Exec(ctx, `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL`, userID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE users SET password_hash = $2, updated_at = NOW() WHERE id = $1`,
+		userID, passwordHash); err != nil {
+		return 0, err
+	}
+	// Sign out every session that may have been opened with the old password
+	if _, err := tx.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW()
+		WHERE user_id = $1 AND revoked = FALSE`, userID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
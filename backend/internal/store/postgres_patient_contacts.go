@@ -0,0 +1,75 @@
+// postgres_patient_contacts.go: Patient contact details and contact consent.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) PatientContacts() PatientContactRepository {
+	return &pgPatientContactRepo{pool: s.pool}
+}
+
+type pgPatientContactRepo struct {
+	pool *pgxpool.Pool
+}
+
+const patientContactColumns = `patient_id, phone, email, address_line1, address_line2, city, region, postal_code, country, contact_consent, consent_at, updated_at`
+
+func (r *pgPatientContactRepo) Get(ctx context.Context, patientID int64) (*models.PatientContact, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	row := r.pool.QueryRow(ctx, `SELECT `+patientContactColumns+` FROM patient_contacts WHERE patient_id = $1`, patientID)
+	contact, err := scanPatientContact(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return contact, err
+}
+
+func (r *pgPatientContactRepo) Put(ctx context.Context, c models.PatientContact) (*models.PatientContact, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	row := r.pool.QueryRow(ctx, `
+		INSERT INTO patient_contacts (patient_id, phone, email, address_line1, address_line2,
+			city, region, postal_code, country, contact_consent, consent_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CASE WHEN $10 THEN NOW() END)
+		ON CONFLICT (patient_id) DO UPDATE SET
+			phone = EXCLUDED.phone,
+			email = EXCLUDED.email,
+			address_line1 = EXCLUDED.address_line1,
+			address_line2 = EXCLUDED.address_line2,
+			city = EXCLUDED.city,
+			region = EXCLUDED.region,
+			postal_code = EXCLUDED.postal_code,
+			country = EXCLUDED.country,
+			contact_consent = EXCLUDED.contact_consent,
+			consent_at = CASE
+				WHEN NOT EXCLUDED.contact_consent THEN NULL
+				WHEN patient_contacts.contact_consent THEN patient_contacts.consent_at
+				ELSE NOW()
+			END,
+			updated_at = NOW()
+		RETURNING `+patientContactColumns,
+		c.PatientID, c.Phone, c.Email, c.AddressLine1, c.AddressLine2,
+		c.City, c.Region, c.PostalCode, c.Country, c.ContactConsent)
+	return scanPatientContact(row)
+}
+
+func scanPatientContact(row pgx.Row) (*models.PatientContact, error) {
+	var c models.PatientContact
+	var consentAt pgtype.Timestamptz
+	if err := row.Scan(&c.PatientID, &c.Phone, &c.Email, &c.AddressLine1, &c.AddressLine2,
+		&c.City, &c.Region, &c.PostalCode, &c.Country, &c.ContactConsent, &consentAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	c.ConsentAt = timePtr(consentAt)
+	return &c, nil
+}
@@ -0,0 +1,128 @@
+// postgres_patient_history.go: Field-level change history for patient records.
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) PatientHistory() PatientHistoryRepository {
+	return &pgPatientHistoryRepo{pool: s.pool}
+}
+
+type pgPatientHistoryRepo struct {
+	pool *pgxpool.Pool
+}
+
+// unversionedPatientFields change on every write and are left out of Changes.
+var unversionedPatientFields = map[string]bool{"updated_at": true}
+
+// lockPatientSnapshot locks the patient row for the rest of tx and returns
+// it as JSON. Returns pgx.ErrNoRows if the patient is not the user's.
+func lockPatientSnapshot(ctx context.Context, tx pgx.Tx, id, userID int64) ([]byte, error) {
+	var snapshot []byte
+	err := tx.QueryRow(ctx,
+		`SELECT to_jsonb(p) FROM patients p WHERE p.id = $1 AND p.user_id = $2 FOR UPDATE`,
+		id, userID,
+	).Scan(&snapshot)
+	return snapshot, err
+}
+
+// recordPatientVersion compares before with the patient's current row in tx
+// and stores a version when any field changed.
+func recordPatientVersion(ctx context.Context, tx pgx.Tx, id, userID int64, before []byte) error {
+	var after []byte
+	if err := tx.QueryRow(ctx, `SELECT to_jsonb(p) FROM patients p WHERE p.id = $1`, id).Scan(&after); err != nil {
+		return err
+	}
+	changes, err := diffPatientSnapshots(before, after)
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO patient_versions (patient_id, version, changed_by, before, after, changes)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5
+		FROM patient_versions WHERE patient_id = $1`,
+		id, userID, before, after, changesJSON)
+	return err
+}
+
+// diffPatientSnapshots lists the fields whose values differ, sorted by name.
+func diffPatientSnapshots(before, after []byte) ([]models.PatientFieldChange, error) {
+	var old, cur map[string]interface{}
+	if err := json.Unmarshal(before, &old); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &cur); err != nil {
+		return nil, err
+	}
+	changes := []models.PatientFieldChange{}
+	for field, v := range cur {
+		if unversionedPatientFields[field] || reflect.DeepEqual(old[field], v) {
+			continue
+		}
+		changes = append(changes, models.PatientFieldChange{Field: field, Old: old[field], New: v})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+func (r *pgPatientHistoryRepo) List(ctx context.Context, patientID int64) ([]models.PatientVersion, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, patient_id, version, changed_by, changed_at, before, after, changes
+		FROM patient_versions
+		WHERE patient_id = $1
+		ORDER BY version DESC`, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.PatientVersion{}
+	for rows.Next() {
+		v, err := scanPatientVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *v)
+	}
+	return out, rows.Err()
+}
+
+func scanPatientVersion(row pgx.Row) (*models.PatientVersion, error) {
+	var v models.PatientVersion
+	var changedBy pgtype.Int4
+	var before, after, changes []byte
+	if err := row.Scan(&v.ID, &v.PatientID, &v.Version, &changedBy, &v.ChangedAt, &before, &after, &changes); err != nil {
+		return nil, err
+	}
+	if changedBy.Valid {
+		by := int64(changedBy.Int32)
+		v.ChangedBy = &by
+	}
+	if err := json.Unmarshal(before, &v.Before); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &v.After); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(changes, &v.Changes); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
@@ -0,0 +1,27 @@
+package store
+
+import "testing"
+
+func TestDiffPatientSnapshots(t *testing.T) {
+	before := []byte(`{"id": 1, "name": "Ana", "bmi": 24.5, "smoking": "never", "updated_at": "2024-01-01T00:00:00Z"}`)
+	after := []byte(`{"id": 1, "name": "Ana", "bmi": 26.1, "smoking": "current", "updated_at": "2024-02-01T00:00:00Z"}`)
+
+	changes, err := diffPatientSnapshots(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("changes = %+v, want bmi and smoking only", changes)
+	}
+	if changes[0].Field != "bmi" || changes[0].Old != 24.5 || changes[0].New != 26.1 {
+		t.Errorf("changes[0] = %+v", changes[0])
+	}
+	if changes[1].Field != "smoking" || changes[1].Old != "never" || changes[1].New != "current" {
+		t.Errorf("changes[1] = %+v", changes[1])
+	}
+
+	unchanged, err := diffPatientSnapshots(before, before)
+	if err != nil || len(unchanged) != 0 {
+		t.Errorf("identical snapshots: changes = %+v, err = %v", unchanged, err)
+	}
+}
@@ -0,0 +1,136 @@
+// postgres_patient_list_views.go: Saved patient list filters.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) PatientListViews() PatientListViewRepository {
+	return &pgPatientListViewRepo{pool: s.pool}
+}
+
+type pgPatientListViewRepo struct {
+	pool *pgxpool.Pool
+}
+
+const patientListViewColumns = `id, user_id, name, filters, is_default, created_at, updated_at`
+
+func scanPatientListView(row pgx.Row) (*models.PatientListView, error) {
+	var v models.PatientListView
+	var userID int32
+	if err := row.Scan(&v.ID, &userID, &v.Name, &v.Filters, &v.IsDefault, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		return nil, err
+	}
+	v.UserID = int64(userID)
+	return &v, nil
+}
+
+func (r *pgPatientListViewRepo) List(ctx context.Context, userID int64) ([]models.PatientListView, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+patientListViewColumns+` FROM patient_list_views
+		WHERE user_id = $1
+		ORDER BY LOWER(name), id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.PatientListView{}
+	for rows.Next() {
+		v, err := scanPatientListView(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *v)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgPatientListViewRepo) Get(ctx context.Context, id, userID int64) (*models.PatientListView, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanPatientListView(r.pool.QueryRow(ctx, `
+		SELECT `+patientListViewColumns+` FROM patient_list_views
+		WHERE id = $1 AND user_id = $2`, id, userID))
+}
+
+func (r *pgPatientListViewRepo) Default(ctx context.Context, userID int64) (*models.PatientListView, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanPatientListView(r.pool.QueryRow(ctx, `
+		SELECT `+patientListViewColumns+` FROM patient_list_views
+		WHERE user_id = $1 AND is_default`, userID))
+}
+
+// save runs write in a transaction that first clears the user's default
+// when v is to become it, so idx_patient_list_views_default never trips.
+func (r *pgPatientListViewRepo) save(ctx context.Context, v models.PatientListView, write string, args ...interface{}) (*models.PatientListView, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if v.IsDefault {
+		if _, err := tx.Exec(ctx, `
+			UPDATE patient_list_views SET is_default = FALSE
+			WHERE user_id = $1 AND is_default AND id <> $2`, v.UserID, v.ID); err != nil {
+			return nil, err
+		}
+	}
+	saved, err := scanPatientListView(tx.QueryRow(ctx, write, args...))
+	if err != nil {
+		return nil, conflictError(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+func (r *pgPatientListViewRepo) Create(ctx context.Context, v models.PatientListView) (*models.PatientListView, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return r.save(ctx, v, `
+		INSERT INTO patient_list_views (user_id, name, filters, is_default)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+patientListViewColumns,
+		v.UserID, v.Name, v.Filters, v.IsDefault)
+}
+
+func (r *pgPatientListViewRepo) Update(ctx context.Context, v models.PatientListView) (*models.PatientListView, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return r.save(ctx, v, `
+		UPDATE patient_list_views
+		SET name = $3, filters = $4, is_default = $5, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+patientListViewColumns,
+		v.ID, v.UserID, v.Name, v.Filters, v.IsDefault)
+}
+
+func (r *pgPatientListViewRepo) Delete(ctx context.Context, id, userID int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `DELETE FROM patient_list_views WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
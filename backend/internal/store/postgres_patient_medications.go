@@ -0,0 +1,103 @@
+// postgres_patient_medications.go: Medications patients take.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) PatientMedications() PatientMedicationRepository {
+	return &pgPatientMedicationRepo{pool: s.pool}
+}
+
+type pgPatientMedicationRepo struct {
+	pool *pgxpool.Pool
+}
+
+const patientMedicationColumns = `id, patient_id, name, dose, started_at, stopped_at, adherent, created_at, updated_at`
+
+func scanPatientMedication(row pgx.Row) (*models.PatientMedication, error) {
+	var m models.PatientMedication
+	if err := row.Scan(&m.ID, &m.PatientID, &m.Name, &m.Dose, &m.StartedAt, &m.StoppedAt,
+		&m.Adherent, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *pgPatientMedicationRepo) List(ctx context.Context, patientID int64) ([]models.PatientMedication, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+patientMedicationColumns+`
+		FROM patient_medications
+		WHERE patient_id = $1
+		ORDER BY started_at DESC, id DESC
+	`, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.PatientMedication{}
+	for rows.Next() {
+		m, err := scanPatientMedication(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *m)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgPatientMedicationRepo) Get(ctx context.Context, id, patientID int64) (*models.PatientMedication, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanPatientMedication(r.pool.QueryRow(ctx, `
+		SELECT `+patientMedicationColumns+`
+		FROM patient_medications
+		WHERE id = $1 AND patient_id = $2`, id, patientID))
+}
+
+func (r *pgPatientMedicationRepo) Create(ctx context.Context, m models.PatientMedication) (*models.PatientMedication, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanPatientMedication(r.pool.QueryRow(ctx, `
+		INSERT INTO patient_medications (patient_id, name, dose, started_at, stopped_at, adherent)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+patientMedicationColumns,
+		m.PatientID, m.Name, m.Dose, m.StartedAt, m.StoppedAt, m.Adherent))
+}
+
+func (r *pgPatientMedicationRepo) Update(ctx context.Context, m models.PatientMedication) (*models.PatientMedication, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanPatientMedication(r.pool.QueryRow(ctx, `
+		UPDATE patient_medications
+		SET name = $3, dose = $4, started_at = $5, stopped_at = $6, adherent = $7, updated_at = NOW()
+		WHERE id = $1 AND patient_id = $2
+		RETURNING `+patientMedicationColumns,
+		m.ID, m.PatientID, m.Name, m.Dose, m.StartedAt, m.StoppedAt, m.Adherent))
+}
+
+func (r *pgPatientMedicationRepo) Delete(ctx context.Context, id, patientID int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `DELETE FROM patient_medications WHERE id = $1 AND patient_id = $2`, id, patientID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// postgres_patient_merge.go: Finding and merging duplicate patient records.
+package store
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (r *pgPatientRepo) Duplicates(ctx context.Context, userID int32, minSimilarity float64, limit int) ([]models.PatientDuplicate, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	// An age of 0 was never recorded, so it matches any age
+	rows, err := r.pool.Query(ctx, `
+		SELECT a.id, a.name, COALESCE(a.age, 0), COALESCE(a.mrn, ''),
+		       b.id, b.name, COALESCE(b.age, 0), COALESCE(b.mrn, ''),
+		       similarity(a.name, b.name)::float8 AS name_similarity,
+		       COALESCE(a.mrn <> '' AND lower(a.mrn) = lower(b.mrn), false) AS same_mrn
+		FROM patients a
+		JOIN patients b ON b.user_id = a.user_id AND b.id > a.id
+		WHERE a.user_id = $1
+		  AND (COALESCE(a.mrn <> '' AND lower(a.mrn) = lower(b.mrn), false)
+		       OR (similarity(a.name, b.name) >= $2
+		           AND (COALESCE(a.age, 0) = 0 OR COALESCE(b.age, 0) = 0 OR abs(a.age - b.age) <= 1)))
+		ORDER BY same_mrn DESC, name_similarity DESC, a.id, b.id
+		LIMIT $3`, userID, minSimilarity, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.PatientDuplicate{}
+	for rows.Next() {
+		var d models.PatientDuplicate
+		if err := rows.Scan(&d.Patient.ID, &d.Patient.Name, &d.Patient.Age, &d.Patient.MRN,
+			&d.Duplicate.ID, &d.Duplicate.Name, &d.Duplicate.Age, &d.Duplicate.MRN,
+			&d.Similarity, &d.SameMRN); err != nil {
+			return nil, err
+		}
+		d.Similarity = math.Round(d.Similarity*100) / 100
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// mergeMoves reassign the merged patient's ($2) records to the survivor
+// ($1). Rows the survivor already has an equivalent of stay behind and are
+// deleted with the merged patient: an active overdue-assessment task, a lab
+// result draft from the same message, a tag, contact details.
+var mergeMoves = []string{
+	`UPDATE risk_alerts SET patient_id = $1 WHERE patient_id = $2`,
+	`UPDATE baseline_discrepancies SET patient_id = $1 WHERE patient_id = $2`,
+	`UPDATE patient_medications SET patient_id = $1 WHERE patient_id = $2`,
+	`UPDATE follow_ups SET patient_id = $1 WHERE patient_id = $2`,
+	`UPDATE experiment_exposures SET patient_id = $1 WHERE patient_id = $2`,
+	`UPDATE tasks SET patient_id = $1
+	 WHERE patient_id = $2
+	   AND NOT (kind = 'overdue_assessment' AND status IN ('open', 'in_progress')
+	            AND EXISTS (SELECT 1 FROM tasks s WHERE s.patient_id = $1 AND s.kind = 'overdue_assessment' AND s.status IN ('open', 'in_progress')))`,
+	`UPDATE assessment_drafts d SET patient_id = $1
+	 WHERE d.patient_id = $2
+	   AND NOT EXISTS (SELECT 1 FROM assessment_drafts s
+	                   WHERE s.patient_id = $1 AND s.source = d.source AND s.message_control_id = d.message_control_id)`,
+	`INSERT INTO patient_tags (tag_id, patient_id, created_at)
+	 SELECT tag_id, $1, created_at FROM patient_tags WHERE patient_id = $2
+	 ON CONFLICT DO NOTHING`,
+	`UPDATE patient_contacts SET patient_id = $1
+	 WHERE patient_id = $2 AND NOT EXISTS (SELECT 1 FROM patient_contacts WHERE patient_id = $1)`,
+}
+
+// mergeDenied explains why the user does not own both patients: ErrForbidden
+// when they can still see both through a clinic, otherwise ErrNotFound.
+func (r *pgPatientRepo) mergeDenied(ctx context.Context, tx pgx.Tx, survivorID, mergedID int64, userID int32) error {
+	for _, id := range []int64{survivorID, mergedID} {
+		var visible bool
+		if err := tx.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM patients p WHERE `+visiblePatientFilter+`)`,
+			id, userID).Scan(&visible); err != nil {
+			return err
+		}
+		if !visible {
+			return ErrNotFound
+		}
+	}
+	return ErrForbidden
+}
+
+func (r *pgPatientRepo) Merge(ctx context.Context, survivorID, mergedID int64, userID int32) (*models.PatientMerge, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock both rows, in ID order so concurrent merges cannot deadlock
+	var locked int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM (
+			SELECT id FROM patients WHERE id IN ($1, $2) AND user_id = $3 ORDER BY id FOR UPDATE
+		) p`, survivorID, mergedID, userID).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if locked != 2 {
+		return nil, r.mergeDenied(ctx, tx, survivorID, mergedID, userID)
+	}
+
+	m := &models.PatientMerge{PatientID: survivorID, MergedID: mergedID}
+	for _, move := range []struct {
+		sql   string
+		count *int
+	}{
+		{`UPDATE assessments SET patient_id = $1 WHERE patient_id = $2`, &m.Assessments},
+		{`UPDATE patient_notes SET patient_id = $1 WHERE patient_id = $2`, &m.Notes},
+		{`UPDATE assessment_attachments SET patient_id = $1 WHERE patient_id = $2`, &m.Attachments},
+	} {
+		tag, err := tx.Exec(ctx, move.sql, survivorID, mergedID)
+		if err != nil {
+			return nil, err
+		}
+		*move.count = int(tag.RowsAffected())
+	}
+	for _, move := range mergeMoves {
+		if _, err := tx.Exec(ctx, move, survivorID, mergedID); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM patients WHERE id = $1`, mergedID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
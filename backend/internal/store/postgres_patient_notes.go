@@ -0,0 +1,115 @@
+// postgres_patient_notes.go: Clinicians' notes and care plan entries on
+// patients.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) PatientNotes() PatientNoteRepository {
+	return &pgPatientNoteRepo{pool: s.pool}
+}
+
+type pgPatientNoteRepo struct {
+	pool *pgxpool.Pool
+}
+
+const patientNoteColumns = `n.id, n.patient_id, n.author_id, COALESCE(u.email, ''), n.category, n.body, n.pinned, n.created_at, n.updated_at`
+
+func scanPatientNote(row pgx.Row) (*models.PatientNote, error) {
+	var n models.PatientNote
+	var authorID *int32
+	if err := row.Scan(&n.ID, &n.PatientID, &authorID, &n.AuthorEmail, &n.Category, &n.Body,
+		&n.Pinned, &n.CreatedAt, &n.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if authorID != nil {
+		id := int64(*authorID)
+		n.AuthorID = &id
+	}
+	return &n, nil
+}
+
+func (r *pgPatientNoteRepo) List(ctx context.Context, patientID int64) ([]models.PatientNote, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+patientNoteColumns+`
+		FROM patient_notes n LEFT JOIN users u ON u.id = n.author_id
+		WHERE n.patient_id = $1
+		ORDER BY n.pinned DESC, n.created_at DESC, n.id DESC
+	`, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.PatientNote{}
+	for rows.Next() {
+		n, err := scanPatientNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *n)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgPatientNoteRepo) Get(ctx context.Context, id, patientID int64) (*models.PatientNote, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanPatientNote(r.pool.QueryRow(ctx, `
+		SELECT `+patientNoteColumns+`
+		FROM patient_notes n LEFT JOIN users u ON u.id = n.author_id
+		WHERE n.id = $1 AND n.patient_id = $2`, id, patientID))
+}
+
+func (r *pgPatientNoteRepo) Create(ctx context.Context, note models.PatientNote) (*models.PatientNote, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanPatientNote(r.pool.QueryRow(ctx, `
+		WITH n AS (
+			INSERT INTO patient_notes (patient_id, author_id, category, body, pinned)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *
+		)
+		SELECT `+patientNoteColumns+` FROM n LEFT JOIN users u ON u.id = n.author_id`,
+		note.PatientID, note.AuthorID, note.Category, note.Body, note.Pinned))
+}
+
+func (r *pgPatientNoteRepo) Update(ctx context.Context, note models.PatientNote) (*models.PatientNote, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanPatientNote(r.pool.QueryRow(ctx, `
+		WITH n AS (
+			UPDATE patient_notes
+			SET category = $3, body = $4, pinned = $5, updated_at = NOW()
+			WHERE id = $1 AND patient_id = $2
+			RETURNING *
+		)
+		SELECT `+patientNoteColumns+` FROM n LEFT JOIN users u ON u.id = n.author_id`,
+		note.ID, note.PatientID, note.Category, note.Body, note.Pinned))
+}
+
+func (r *pgPatientNoteRepo) Delete(ctx context.Context, id, patientID int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `DELETE FROM patient_notes WHERE id = $1 AND patient_id = $2`, id, patientID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
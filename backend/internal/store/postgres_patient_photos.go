@@ -0,0 +1,139 @@
+// postgres_patient_photos.go: Patient photo metadata and the per-clinic photo toggle.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) PatientPhotos() PatientPhotoRepository {
+	return &pgPatientPhotoRepo{pool: s.pool}
+}
+
+type pgPatientPhotoRepo struct {
+	pool *pgxpool.Pool
+}
+
+const patientPhotoColumns = `patient_id, storage_key, content_type, width, height, size_bytes, uploaded_by, created_at`
+
+func (r *pgPatientPhotoRepo) Get(ctx context.Context, patientID int64) (*models.PatientPhoto, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	row := r.pool.QueryRow(ctx, `SELECT `+patientPhotoColumns+` FROM patient_photos WHERE patient_id = $1`, patientID)
+	photo, err := scanPatientPhoto(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return photo, err
+}
+
+func (r *pgPatientPhotoRepo) Put(ctx context.Context, photo models.PatientPhoto) (*models.PatientPhoto, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	prev, err := scanPatientPhoto(tx.QueryRow(ctx,
+		`SELECT `+patientPhotoColumns+` FROM patient_photos WHERE patient_id = $1 FOR UPDATE`, photo.PatientID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		prev, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	uploadedBy := pgtype.Int4{Int32: int32(photo.UploadedBy), Valid: photo.UploadedBy > 0}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO patient_photos (patient_id, storage_key, content_type, width, height, size_bytes, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (patient_id) DO UPDATE SET
+			storage_key = EXCLUDED.storage_key,
+			content_type = EXCLUDED.content_type,
+			width = EXCLUDED.width,
+			height = EXCLUDED.height,
+			size_bytes = EXCLUDED.size_bytes,
+			uploaded_by = EXCLUDED.uploaded_by,
+			created_at = NOW()`,
+		photo.PatientID, photo.StorageKey, photo.ContentType, photo.Width, photo.Height, photo.SizeBytes, uploadedBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return prev, nil
+}
+
+func (r *pgPatientPhotoRepo) Delete(ctx context.Context, patientID int64) (*models.PatientPhoto, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	row := r.pool.QueryRow(ctx, `DELETE FROM patient_photos WHERE patient_id = $1 RETURNING `+patientPhotoColumns, patientID)
+	photo, err := scanPatientPhoto(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return photo, err
+}
+
+func scanPatientPhoto(row pgx.Row) (*models.PatientPhoto, error) {
+	var p models.PatientPhoto
+	var uploadedBy pgtype.Int4
+	if err := row.Scan(&p.PatientID, &p.StorageKey, &p.ContentType, &p.Width, &p.Height,
+		&p.SizeBytes, &uploadedBy, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	if uploadedBy.Valid {
+		p.UploadedBy = int64(uploadedBy.Int32)
+	}
+	return &p, nil
+}
+
+func (r *pgClinicRepo) GetPatientPhotosEnabled(ctx context.Context, clinicID int32) (bool, error) {
+	if r.pool == nil {
+		return false, errors.New("db not configured")
+	}
+	var enabled bool
+	err := r.pool.QueryRow(ctx, `SELECT patient_photos_enabled FROM clinics WHERE id = $1`, clinicID).Scan(&enabled)
+	return enabled, err
+}
+
+func (r *pgClinicRepo) SetPatientPhotosEnabled(ctx context.Context, clinicID int32, enabled bool) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE clinics SET patient_photos_enabled = $2, updated_at = NOW() WHERE id = $1`,
+		clinicID, enabled,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *pgClinicRepo) PatientPhotosEnabledForUser(ctx context.Context, userID int32) (bool, error) {
+	if r.pool == nil {
+		return false, errors.New("db not configured")
+	}
+	var enabled bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT COALESCE(bool_and(c.patient_photos_enabled), true)
+		FROM clinics c
+		JOIN user_clinics uc ON uc.clinic_id = c.id
+		WHERE uc.user_id = $1`, userID).Scan(&enabled)
+	return enabled, err
+}
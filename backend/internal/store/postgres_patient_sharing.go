@@ -0,0 +1,68 @@
+// postgres_patient_sharing.go: Sharing patients with a clinic's members.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// sharedReadGrant matches clinic memberships uc whose holder may read the
+// clinic's shared patients: patients.read granted by their global role or by
+// their role in that clinic, as handlers.PermissionLookup decides. GetAssessment
+// in queries/assessments.sql spells out the same check.
+const sharedReadGrant = `EXISTS (SELECT 1 FROM users u JOIN role_permissions rp ON rp.role IN (u.role, uc.role)
+	WHERE u.id = uc.user_id AND rp.permission = '` + models.PermPatientsRead + `')`
+
+// visiblePatientFilter matches patients the user owns or that are shared with
+// one of the user's clinics, where the user may read patients. $1 is the
+// patient ID, $2 the user ID.
+const visiblePatientFilter = `p.id = $1 AND (p.user_id = $2 OR p.clinic_id IN (
+	SELECT uc.clinic_id FROM user_clinics uc WHERE uc.user_id = $2 AND ` + sharedReadGrant + `))`
+
+func (r *pgPatientRepo) GetVisible(ctx context.Context, id int32, userID int32) (*models.Patient, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	var owner int32
+	var clinicID pgtype.Int4
+	err := r.pool.QueryRow(ctx,
+		`SELECT p.user_id, p.clinic_id FROM patients p WHERE `+visiblePatientFilter,
+		id, userID).Scan(&owner, &clinicID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := r.Get(ctx, id, owner)
+	if err != nil {
+		return nil, err
+	}
+	p.ClinicID = int4Ptr(clinicID)
+	return p, nil
+}
+
+func (r *pgPatientRepo) SetClinic(ctx context.Context, id int64, ownerID int32, clinicID *int32, changedBy int32) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := lockPatientSnapshot(ctx, tx, id, int64(ownerID))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE patients SET clinic_id = $2, updated_at = NOW() WHERE id = $1`,
+		id, clinicID); err != nil {
+		return err
+	}
+	if err := recordPatientVersion(ctx, tx, id, int64(changedBy), before); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,66 @@
+// postgres_patient_transfer.go: Moving patients between clinicians.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *pgPatientRepo) Owner(ctx context.Context, id int64) (int32, error) {
+	if r.pool == nil {
+		return 0, errors.New("db not configured")
+	}
+	var owner int32
+	err := r.pool.QueryRow(ctx, `SELECT user_id FROM patients WHERE id = $1`, id).Scan(&owner)
+	return owner, err
+}
+
+func (r *pgPatientRepo) Transfer(ctx context.Context, id int64, fromUserID, toUserID, changedBy int32) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := lockPatientSnapshot(ctx, tx, id, int64(fromUserID))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE patients SET user_id = $2, updated_at = NOW() WHERE id = $1`,
+		id, toUserID); err != nil {
+		return err
+	}
+	// Alerts not yet delivered should reach the clinician now responsible
+	if _, err := tx.Exec(ctx,
+		`UPDATE risk_alerts SET user_id = $2 WHERE patient_id = $1 AND delivered_at IS NULL`,
+		id, toUserID); err != nil {
+		return err
+	}
+	if err := recordPatientVersion(ctx, tx, id, int64(changedBy), before); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *pgClinicRepo) SharedClinics(ctx context.Context, userIDs ...int32) ([]int32, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT clinic_id
+		FROM user_clinics
+		WHERE user_id = ANY($1::int[])
+		GROUP BY clinic_id
+		HAVING COUNT(DISTINCT user_id) = (SELECT COUNT(DISTINCT u) FROM unnest($1::int[]) u)
+		ORDER BY clinic_id`, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int32])
+}
@@ -0,0 +1,65 @@
+// postgres_prediction_cache.go: Model predictions keyed by a hash of their inputs.
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) PredictionCache() PredictionCacheRepository {
+	return &pgPredictionCacheRepo{pool: s.pool}
+}
+
+type pgPredictionCacheRepo struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgPredictionCacheRepo) Get(ctx context.Context, key string) (*models.CachedPrediction, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	var p models.CachedPrediction
+	var explanation []byte
+	err := r.pool.QueryRow(ctx, `
+		SELECT cluster, risk_score, explanation, explained
+		FROM prediction_cache
+		WHERE key = $1`, key).Scan(&p.Cluster, &p.RiskScore, &explanation, &p.Explained)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if explanation != nil {
+		if err := json.Unmarshal(explanation, &p.Explanation); err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+func (r *pgPredictionCacheRepo) Put(ctx context.Context, key string, p models.CachedPrediction) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	var explanation []byte
+	if p.Explanation != nil {
+		var err error
+		if explanation, err = json.Marshal(p.Explanation); err != nil {
+			return err
+		}
+	}
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO prediction_cache (key, cluster, risk_score, explanation, explained)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (key) DO UPDATE
+		SET cluster = EXCLUDED.cluster, risk_score = EXCLUDED.risk_score,
+		    explanation = EXCLUDED.explanation, explained = EXCLUDED.explained, created_at = NOW()`,
+		key, p.Cluster, p.RiskScore, explanation, p.Explained)
+	return err
+}
@@ -0,0 +1,57 @@
+// postgres_quality.go: Assessment data quality scores and per-clinician rollups.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (r *pgAssessmentRepo) SetQuality(ctx context.Context, id int32, q models.DataQuality) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	score, completeness, outOfRange := qualityToPg(&q)
+	_, err := r.pool.Exec(ctx, `
+		UPDATE assessments
+		SET quality_score = $2, quality_completeness = $3, quality_out_of_range = $4
+		WHERE id = $1`, id, score, completeness, outOfRange)
+	return err
+}
+
+func (r *pgAssessmentRepo) QualityByClinician(ctx context.Context, userID *int32, lowBelow int) ([]models.ClinicianDataQuality, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT u.id, u.email, COUNT(*),
+		       ROUND(AVG(a.quality_score)::numeric, 1)::float8,
+		       ROUND(AVG(a.quality_completeness)::numeric, 2)::float8,
+		       COALESCE(SUM(a.quality_out_of_range), 0),
+		       COUNT(*) FILTER (WHERE a.self_reported),
+		       COUNT(*) FILTER (WHERE a.quality_score < $2)
+		FROM assessments a
+		JOIN patients p ON p.id = a.patient_id
+		JOIN users u ON u.id = p.user_id
+		WHERE a.quality_score IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
+		  AND ($1::int IS NULL OR p.user_id = $1)
+		GROUP BY u.id, u.email
+		ORDER BY AVG(a.quality_score), u.id`, userID, lowBelow)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.ClinicianDataQuality{}
+	for rows.Next() {
+		var q models.ClinicianDataQuality
+		if err := rows.Scan(&q.UserID, &q.Email, &q.Assessments, &q.AvgScore, &q.AvgCompleteness,
+			&q.OutOfRange, &q.SelfReported, &q.LowQuality); err != nil {
+			return nil, err
+		}
+		out = append(out, q)
+	}
+	return out, rows.Err()
+}
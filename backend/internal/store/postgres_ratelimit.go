@@ -0,0 +1,62 @@
+// postgres_ratelimit.go: Token buckets shared across API replicas.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresRateLimitStore implements middleware.RateLimitStore on the
+// rate_limit_buckets table so every replica enforces the same limits.
+type PostgresRateLimitStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRateLimitStore(pool *pgxpool.Pool) *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{pool: pool}
+}
+
+// Take refills and spends from the bucket in one statement, so concurrent
+// requests for the same key serialise on the row.
+func (s *PostgresRateLimitStore) Take(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	if s.pool == nil {
+		return false, 0, errors.New("db not configured")
+	}
+	perSecond := float64(limit) / window.Seconds()
+	var tokens float64
+	var allowed bool
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+		VALUES ($1, $2::float8 - 1, TRUE, NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = CASE
+				WHEN LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $3::float8) >= 1
+				THEN LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $3::float8) - 1
+				ELSE LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $3::float8)
+			END,
+			allowed = LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $3::float8) >= 1,
+			updated_at = NOW()
+		RETURNING tokens, allowed`, key, float64(limit), perSecond).Scan(&tokens, &allowed)
+	if err != nil {
+		return false, 0, err
+	}
+	if allowed {
+		return true, 0, nil
+	}
+	return false, time.Duration((1 - tokens) / perSecond * float64(time.Second)), nil
+}
+
+// Prune deletes buckets untouched since before; they would be full anyway.
+func (s *PostgresRateLimitStore) Prune(ctx context.Context, before time.Time) (int, error) {
+	if s.pool == nil {
+		return 0, errors.New("db not configured")
+	}
+	tag, err := s.pool.Exec(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,86 @@
+// postgres_refresh_rotation.go: Refresh token rotation and token family revocation.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+const refreshTokenColumns = `id, user_id, token_hash, family_id, expires_at, revoked, created_at, revoked_at, user_agent, ip_address, signed_in_at`
+
+func (r *pgRefreshTokenRepo) LookupRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	row := r.pool.QueryRow(ctx, `SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token_hash = $1`, tokenHash)
+	return scanRefreshToken(row)
+}
+
+func (r *pgRefreshTokenRepo) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*models.RefreshToken, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Only one caller can revoke an active token, so concurrent rotations of
+	// the same token cannot both succeed.
+	var userID int32
+	var familyID, userAgent, ipAddress string
+	var signedInAt time.Time
+	err = tx.QueryRow(ctx, `
+		UPDATE refresh_tokens
+		SET revoked = TRUE, revoked_at = NOW()
+		WHERE token_hash = $1 AND revoked = FALSE AND expires_at > NOW()
+		RETURNING user_id, family_id, user_agent, ip_address, signed_in_at`, oldHash).Scan(&userID, &familyID, &userAgent, &ipAddress, &signedInAt)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := scanRefreshToken(tx.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at, user_agent, ip_address, signed_in_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+refreshTokenColumns, userID, newHash, familyID, expiresAt, userAgent, ipAddress, signedInAt))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *pgRefreshTokenRepo) RevokeTokenFamily(ctx context.Context, familyID string) (int, error) {
+	if r.pool == nil {
+		return 0, errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET revoked = TRUE, revoked_at = NOW()
+		WHERE family_id = $1 AND revoked = FALSE`, familyID)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func scanRefreshToken(row pgx.Row) (*models.RefreshToken, error) {
+	var t models.RefreshToken
+	var id, userID int32
+	var revokedAt pgtype.Timestamptz
+	if err := row.Scan(&id, &userID, &t.TokenHash, &t.FamilyID, &t.ExpiresAt, &t.Revoked, &t.CreatedAt, &revokedAt, &t.UserAgent, &t.IPAddress, &t.SignedInAt); err != nil {
+		return nil, err
+	}
+	t.ID = int64(id)
+	t.UserID = int64(userID)
+	t.RevokedAt = timestampVal(revokedAt)
+	return &t, nil
+}
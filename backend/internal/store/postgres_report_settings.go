@@ -0,0 +1,53 @@
+// postgres_report_settings.go: Per-clinic PDF report branding and locale.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (r *pgClinicRepo) GetReportSettings(ctx context.Context, clinicID int32) (*models.ClinicReportSettings, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	s := models.ClinicReportSettings{}
+	var address *string
+	err := r.pool.QueryRow(ctx,
+		`SELECT id, name, address, report_locale, report_logo FROM clinics WHERE id = $1`, clinicID,
+	).Scan(&s.ClinicID, &s.Name, &address, &s.Locale, &s.Logo)
+	if err != nil {
+		return nil, err
+	}
+	if address != nil {
+		s.Address = *address
+	}
+	s.HasLogo = len(s.Logo) > 0
+	return &s, nil
+}
+
+func (r *pgClinicRepo) SetReportLocale(ctx context.Context, clinicID int32, locale string) error {
+	return r.updateClinic(ctx, `UPDATE clinics SET report_locale = $2, updated_at = NOW() WHERE id = $1`, clinicID, locale)
+}
+
+func (r *pgClinicRepo) SetReportLogo(ctx context.Context, clinicID int32, logo []byte) error {
+	return r.updateClinic(ctx, `UPDATE clinics SET report_logo = $2, updated_at = NOW() WHERE id = $1`, clinicID, logo)
+}
+
+// updateClinic runs a single-clinic UPDATE, returning pgx.ErrNoRows if the
+// clinic does not exist.
+func (r *pgClinicRepo) updateClinic(ctx context.Context, sql string, clinicID int32, value interface{}) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, sql, clinicID, value)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+	sqlcgen "github.com/skufu/DianaV2/backend/internal/store/sqlc"
+)
+
+func (r *pgAssessmentRepo) ListSince(ctx context.Context, since time.Time, afterID int64, limit int) ([]models.Assessment, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, patient_id, fbs, hba1c, cholesterol, ldl, hdl, triglycerides, systolic, diastolic,
+		       activity, history_flag, smoking, hypertension, heart_disease, bmi, cluster, risk_score,
+		       model_version, dataset_hash, validation_status, created_at, updated_at, self_reported, quality_score, quality_completeness, quality_out_of_range, status, height_cm, weight_kg, bmi_source
+		FROM assessments
+		WHERE status = 'final' AND created_at >= $1 AND id > $2
+		ORDER BY id
+		LIMIT $3`, since, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []models.Assessment
+	for rows.Next() {
+		var i sqlcgen.Assessment
+		if err := rows.Scan(
+			&i.ID,
+			&i.PatientID,
+			&i.Fbs,
+			&i.Hba1c,
+			&i.Cholesterol,
+			&i.Ldl,
+			&i.Hdl,
+			&i.Triglycerides,
+			&i.Systolic,
+			&i.Diastolic,
+			&i.Activity,
+			&i.HistoryFlag,
+			&i.Smoking,
+			&i.Hypertension,
+			&i.HeartDisease,
+			&i.Bmi,
+			&i.Cluster,
+			&i.RiskScore,
+			&i.ModelVersion,
+			&i.DatasetHash,
+			&i.ValidationStatus,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SelfReported,
+			&i.QualityScore,
+			&i.QualityCompleteness,
+			&i.QualityOutOfRange,
+			&i.Status,
+			&i.HeightCm,
+			&i.WeightKg,
+			&i.BmiSource,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, mapAssessment(i))
+	}
+	return out, rows.Err()
+}
+
+func (r *pgAssessmentRepo) SetValidationStatus(ctx context.Context, id int32, status string) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	_, err := r.pool.Exec(ctx, `UPDATE assessments SET validation_status = $2, updated_at = NOW() WHERE id = $1`, id, status)
+	return err
+}
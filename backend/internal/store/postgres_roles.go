@@ -0,0 +1,88 @@
+// postgres_roles.go: Permissions granted to global and clinic roles.
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) Roles() RoleRepository {
+	return &pgRoleRepo{pool: s.pool}
+}
+
+type pgRoleRepo struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgRoleRepo) List(ctx context.Context) ([]models.Role, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `SELECT role, permission FROM role_permissions ORDER BY role, permission`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	granted := map[string][]string{}
+	for rows.Next() {
+		var role, permission string
+		if err := rows.Scan(&role, &permission); err != nil {
+			return nil, err
+		}
+		granted[role] = append(granted[role], permission)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]models.Role, 0, len(models.RoleScopes))
+	for name, scope := range models.RoleScopes {
+		perms := granted[name]
+		if perms == nil {
+			perms = []string{}
+		}
+		out = append(out, models.Role{Name: name, Scope: scope, Permissions: perms})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (r *pgRoleRepo) HasPermission(ctx context.Context, role, permission string) (bool, error) {
+	if r.pool == nil {
+		return false, errors.New("db not configured")
+	}
+	var ok bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM role_permissions WHERE role = $1 AND permission = $2)`,
+		role, permission).Scan(&ok)
+	return ok, err
+}
+
+func (r *pgRoleRepo) SetPermissions(ctx context.Context, role string, permissions []string, updatedBy int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM role_permissions WHERE role = $1 AND permission <> ALL($2::text[])`, role, permissions); err != nil {
+		return err
+	}
+	by := pgtype.Int4{Int32: int32(updatedBy), Valid: updatedBy > 0}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO role_permissions (role, permission, updated_by)
+		SELECT $1, unnest($2::text[]), $3
+		ON CONFLICT (role, permission) DO NOTHING`, role, permissions, by); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
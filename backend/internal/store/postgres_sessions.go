@@ -0,0 +1,82 @@
+// postgres_sessions.go: Signed-in sessions, one per active refresh token family.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// maxClientFieldLen bounds the stored user agent and IP address
+const maxClientFieldLen = 512
+
+func truncateClientField(s string) string {
+	if len(s) > maxClientFieldLen {
+		return s[:maxClientFieldLen]
+	}
+	return s
+}
+
+func (r *pgRefreshTokenRepo) RecordSessionClient(ctx context.Context, tokenHash, userAgent, ipAddress string) (*models.RefreshToken, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	row := r.pool.QueryRow(ctx, `
+		UPDATE refresh_tokens SET user_agent = $2, ip_address = $3
+		WHERE token_hash = $1
+		RETURNING `+refreshTokenColumns, tokenHash, truncateClientField(userAgent), truncateClientField(ipAddress))
+	return scanRefreshToken(row)
+}
+
+func (r *pgRefreshTokenRepo) ListUserSessions(ctx context.Context, userID int64) ([]models.Session, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT family_id, user_agent, ip_address, signed_in_at, created_at, expires_at
+		FROM refresh_tokens
+		WHERE user_id = $1 AND revoked = FALSE AND expires_at > NOW()
+		ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserAgent, &s.IPAddress, &s.SignedInAt, &s.LastActiveAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgRefreshTokenRepo) RevokeUserSession(ctx context.Context, userID int64, familyID string) (int, error) {
+	if r.pool == nil {
+		return 0, errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET revoked = TRUE, revoked_at = NOW()
+		WHERE user_id = $1 AND family_id = $2 AND revoked = FALSE`, userID, familyID)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (r *pgRefreshTokenRepo) RevokeOtherUserSessions(ctx context.Context, userID int64, keepFamilyID string) (int, error) {
+	if r.pool == nil {
+		return 0, errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET revoked = TRUE, revoked_at = NOW()
+		WHERE user_id = $1 AND family_id <> $2 AND revoked = FALSE`, userID, keepFamilyID)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,150 @@
+// postgres_tags.go: Clinicians' tags for ad-hoc patient cohorts.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) Tags() TagRepository {
+	return &pgTagRepo{pool: s.pool}
+}
+
+type pgTagRepo struct {
+	pool *pgxpool.Pool
+}
+
+const tagColumns = `t.id, t.user_id, t.name, (SELECT COUNT(*)::int FROM patient_tags pt WHERE pt.tag_id = t.id), t.created_at`
+
+func scanTag(row pgx.Row) (*models.Tag, error) {
+	var t models.Tag
+	var userID int32
+	if err := row.Scan(&t.ID, &userID, &t.Name, &t.PatientCount, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	t.UserID = int64(userID)
+	return &t, nil
+}
+
+func (r *pgTagRepo) list(ctx context.Context, query string, args ...interface{}) ([]models.Tag, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Tag{}
+	for rows.Next() {
+		t, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgTagRepo) List(ctx context.Context, userID int64) ([]models.Tag, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return r.list(ctx, `
+		SELECT `+tagColumns+` FROM tags t
+		WHERE t.user_id = $1
+		ORDER BY LOWER(t.name), t.id`, userID)
+}
+
+func (r *pgTagRepo) Get(ctx context.Context, id, userID int64) (*models.Tag, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanTag(r.pool.QueryRow(ctx, `
+		SELECT `+tagColumns+` FROM tags t
+		WHERE t.id = $1 AND t.user_id = $2`, id, userID))
+}
+
+func (r *pgTagRepo) Create(ctx context.Context, tag models.Tag) (*models.Tag, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	t, err := scanTag(r.pool.QueryRow(ctx, `
+		WITH t AS (
+			INSERT INTO tags (user_id, name) VALUES ($1, $2)
+			RETURNING *
+		)
+		SELECT `+tagColumns+` FROM t`, tag.UserID, tag.Name))
+	if err != nil {
+		return nil, conflictError(err)
+	}
+	return t, nil
+}
+
+func (r *pgTagRepo) Rename(ctx context.Context, id, userID int64, name string) (*models.Tag, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	t, err := scanTag(r.pool.QueryRow(ctx, `
+		WITH t AS (
+			UPDATE tags SET name = $3
+			WHERE id = $1 AND user_id = $2
+			RETURNING *
+		)
+		SELECT `+tagColumns+` FROM t`, id, userID, name))
+	if err != nil {
+		return nil, conflictError(err)
+	}
+	return t, nil
+}
+
+func (r *pgTagRepo) Delete(ctx context.Context, id, userID int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `DELETE FROM tags WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *pgTagRepo) ListForPatient(ctx context.Context, patientID, userID int64) ([]models.Tag, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return r.list(ctx, `
+		SELECT `+tagColumns+` FROM tags t
+		JOIN patient_tags pt ON pt.tag_id = t.id
+		WHERE pt.patient_id = $1 AND t.user_id = $2
+		ORDER BY LOWER(t.name), t.id`, patientID, userID)
+}
+
+func (r *pgTagRepo) Attach(ctx context.Context, tagID, patientID int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO patient_tags (tag_id, patient_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, tagID, patientID)
+	return err
+}
+
+func (r *pgTagRepo) Detach(ctx context.Context, tagID, patientID int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `DELETE FROM patient_tags WHERE tag_id = $1 AND patient_id = $2`, tagID, patientID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
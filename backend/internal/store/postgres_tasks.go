@@ -0,0 +1,154 @@
+// postgres_tasks.go: Clinicians' worklists.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) Tasks() TaskRepository {
+	return &pgTaskRepo{pool: s.pool}
+}
+
+type pgTaskRepo struct {
+	pool *pgxpool.Pool
+}
+
+const taskColumns = `t.id, t.user_id, t.kind, t.title, t.status, t.patient_id, COALESCE(p.name, ''), t.assessment_id, t.due_at, t.closed_at, t.created_at, t.updated_at`
+
+const taskFrom = ` FROM t LEFT JOIN patients p ON p.id = t.patient_id`
+
+func scanTask(row pgx.Row) (*models.Task, error) {
+	var t models.Task
+	var userID int32
+	if err := row.Scan(&t.ID, &userID, &t.Kind, &t.Title, &t.Status, &t.PatientID, &t.PatientName,
+		&t.AssessmentID, &t.DueAt, &t.ClosedAt, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	t.UserID = int64(userID)
+	return &t, nil
+}
+
+func (r *pgTaskRepo) List(ctx context.Context, userID int64, params models.TaskListParams) ([]models.Task, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	statuses := params.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{models.TaskOpen, models.TaskInProgress}
+	}
+	rows, err := r.pool.Query(ctx, `
+		WITH t AS (
+			SELECT * FROM tasks
+			WHERE user_id = $1 AND status = ANY($2) AND ($3 = '' OR kind = $3)
+		)
+		SELECT `+taskColumns+taskFrom+`
+		ORDER BY t.due_at NULLS LAST, t.created_at DESC, t.id DESC`,
+		userID, statuses, params.Kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Task{}
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgTaskRepo) Get(ctx context.Context, id, userID int64) (*models.Task, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanTask(r.pool.QueryRow(ctx, `
+		WITH t AS (SELECT * FROM tasks WHERE id = $1 AND user_id = $2)
+		SELECT `+taskColumns+taskFrom, id, userID))
+}
+
+func (r *pgTaskRepo) Create(ctx context.Context, t models.Task) (*models.Task, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanTask(r.pool.QueryRow(ctx, `
+		WITH t AS (
+			INSERT INTO tasks (user_id, kind, title, status, patient_id, assessment_id, due_at, closed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING *
+		)
+		SELECT `+taskColumns+taskFrom,
+		t.UserID, t.Kind, t.Title, t.Status, t.PatientID, t.AssessmentID, t.DueAt, t.ClosedAt))
+}
+
+func (r *pgTaskRepo) Update(ctx context.Context, t models.Task) (*models.Task, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanTask(r.pool.QueryRow(ctx, `
+		WITH t AS (
+			UPDATE tasks
+			SET title = $3, status = $4, due_at = $5, closed_at = $6, updated_at = NOW()
+			WHERE id = $1 AND user_id = $2
+			RETURNING *
+		)
+		SELECT `+taskColumns+taskFrom,
+		t.ID, t.UserID, t.Title, t.Status, t.DueAt, t.ClosedAt))
+}
+
+func (r *pgTaskRepo) Delete(ctx context.Context, id, userID int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `DELETE FROM tasks WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// OpenOverdueAssessments relies on idx_tasks_active_overdue, so replicas
+// running the job at the same time never open two tasks for one patient.
+func (r *pgTaskRepo) OpenOverdueAssessments(ctx context.Context, before, now time.Time) (int, error) {
+	if r.pool == nil {
+		return 0, errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `
+		INSERT INTO tasks (user_id, kind, title, patient_id, due_at)
+		SELECT p.user_id, 'overdue_assessment', 'Patient overdue for assessment', p.id, $2
+		FROM patients p
+		LEFT JOIN (
+			SELECT patient_id, MAX(created_at) AS last_at FROM assessments WHERE status = 'final' GROUP BY patient_id
+		) a ON a.patient_id = p.id
+		WHERE p.user_id IS NOT NULL AND COALESCE(a.last_at, p.created_at) < $1
+		ON CONFLICT DO NOTHING`, before, now)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (r *pgTaskRepo) CloseForPatient(ctx context.Context, patientID int64, kind string, now time.Time) (int, error) {
+	if r.pool == nil {
+		return 0, errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE tasks SET status = 'done', closed_at = $3, updated_at = $3
+		WHERE patient_id = $1 AND kind = $2 AND status IN ('open', 'in_progress')`,
+		patientID, kind, now)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
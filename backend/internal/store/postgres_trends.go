@@ -0,0 +1,93 @@
+// postgres_trends.go: Biomarker averages bucketed by week, month or quarter.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// trendLabels maps each granularity to the to_char format of its bucket label.
+var trendLabels = map[string]string{
+	models.TrendWeek:    `IYYY-"W"IW`,
+	models.TrendMonth:   `YYYY-MM`,
+	models.TrendQuarter: `YYYY-"Q"Q`,
+}
+
+func (r *pgAssessmentRepo) TrendAverages(ctx context.Context, params models.TrendParams) ([]models.TrendPoint, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	granularity := params.Granularity
+	if granularity == "" {
+		granularity = models.TrendMonth
+	}
+	label, ok := trendLabels[granularity]
+	if !ok {
+		return nil, fmt.Errorf("unknown trend granularity %q", granularity)
+	}
+	biomarkers := params.Biomarkers
+	if len(biomarkers) == 0 {
+		biomarkers = []string{"hba1c", "fbs"}
+	}
+	// Biomarker names double as column names, so only known ones reach the SQL
+	averages := make([]string, len(biomarkers))
+	for i, b := range biomarkers {
+		if (&models.TrendPoint{}).Field(b) == nil {
+			return nil, fmt.Errorf("unknown trend biomarker %q", b)
+		}
+		averages[i] = fmt.Sprintf(`AVG(a.%s)::float8`, b)
+	}
+
+	var args []interface{}
+	var where string
+	if params.Start != nil {
+		args = append(args, *params.Start)
+		where += fmt.Sprintf(` AND a.created_at >= $%d`, len(args))
+	}
+	if params.End != nil {
+		args = append(args, *params.End)
+		where += fmt.Sprintf(` AND a.created_at < $%d`, len(args))
+	}
+	scopeWhere, args := cohortScopeSQL(params.Scope, args)
+
+	bucket := fmt.Sprintf(`date_trunc('%s', a.created_at)`, granularity)
+	rows, err := r.pool.Query(ctx, `
+		SELECT to_char(`+bucket+`, '`+label+`'), `+bucket+` AS bucket, COUNT(*)::int,
+		       `+strings.Join(averages, ", ")+`
+		FROM assessments a
+		JOIN patients p ON p.id = a.patient_id
+		WHERE a.status = 'final'
+		  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)`+where+scopeWhere+`
+		GROUP BY bucket
+		ORDER BY bucket`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.TrendPoint{}
+	values := make([]pgtype.Float8, len(biomarkers))
+	for rows.Next() {
+		var p models.TrendPoint
+		dest := []interface{}{&p.Label, &p.Start, &p.Count}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		for i, b := range biomarkers {
+			if values[i].Valid {
+				v := values[i].Float64
+				*p.Field(b) = &v
+			}
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
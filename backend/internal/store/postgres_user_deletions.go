@@ -0,0 +1,283 @@
+// postgres_user_deletions.go: Scheduled removal of deactivated users' data.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) UserDeletions() UserDeletionRepository {
+	return &pgUserDeletionRepo{pool: s.pool}
+}
+
+type pgUserDeletionRepo struct {
+	pool *pgxpool.Pool
+}
+
+const userDeletionColumns = `d.id, d.user_id, COALESCE(u.email, ''), d.requested_by, d.requested_at, d.due_at,
+	d.status, COALESCE(d.mode, ''), d.patients, COALESCE(d.resolved_by, ''), d.resolved_at`
+
+const userDeletionFrom = ` FROM user_deletions d LEFT JOIN users u ON u.id = d.user_id`
+
+func scanUserDeletion(row pgx.Row) (*models.UserDeletion, error) {
+	var d models.UserDeletion
+	var userID int32
+	var patients *int32
+	err := row.Scan(&d.ID, &userID, &d.Email, &d.RequestedBy, &d.RequestedAt, &d.DueAt,
+		&d.Status, &d.Mode, &patients, &d.ResolvedBy, &d.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	d.UserID = int64(userID)
+	if patients != nil {
+		n := int(*patients)
+		d.Patients = &n
+	}
+	return &d, nil
+}
+
+func (r *pgUserDeletionRepo) Schedule(ctx context.Context, userID int64, requestedBy string, dueAt time.Time) (*models.UserDeletion, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO user_deletions (user_id, requested_by, due_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) WHERE status = 'pending' DO NOTHING`,
+		userID, requestedBy, dueAt)
+	if err != nil {
+		return nil, err
+	}
+	return scanUserDeletion(r.pool.QueryRow(ctx,
+		`SELECT `+userDeletionColumns+userDeletionFrom+` WHERE d.user_id = $1 AND d.status = 'pending'`, userID))
+}
+
+func (r *pgUserDeletionRepo) List(ctx context.Context, status string) ([]models.UserDeletion, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+userDeletionColumns+userDeletionFrom+` WHERE $1 = '' OR d.status = $1 ORDER BY d.requested_at DESC, d.id DESC`,
+		status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.UserDeletion{}
+	for rows.Next() {
+		d, err := scanUserDeletion(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *d)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgUserDeletionRepo) Cancel(ctx context.Context, id int64, by string) (*models.UserDeletion, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return r.cancel(ctx, r.pool, `d.id = $1`, id, by)
+}
+
+func (r *pgUserDeletionRepo) CancelForUser(ctx context.Context, userID int64, by string) (*models.UserDeletion, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	d, err := r.cancel(ctx, r.pool, `d.user_id = $1`, userID, by)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return d, err
+}
+
+// querier is satisfied by both the pool and a transaction
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func (r *pgUserDeletionRepo) cancel(ctx context.Context, q querier, where string, arg int64, by string) (*models.UserDeletion, error) {
+	return scanUserDeletion(q.QueryRow(ctx, `
+		WITH d AS (
+			UPDATE user_deletions d
+			SET status = 'cancelled', resolved_by = $2, resolved_at = NOW()
+			WHERE `+where+` AND d.status = 'pending'
+			RETURNING d.*
+		)
+		SELECT `+userDeletionColumns+` FROM d LEFT JOIN users u ON u.id = d.user_id`, arg, by))
+}
+
+func (r *pgUserDeletionRepo) Due(ctx context.Context, now time.Time) ([]models.UserDeletion, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+userDeletionColumns+userDeletionFrom+` WHERE d.status = 'pending' AND d.due_at <= $1 ORDER BY d.due_at, d.id`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.UserDeletion{}
+	for rows.Next() {
+		d, err := scanUserDeletion(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *d)
+	}
+	return out, rows.Err()
+}
+
+// anonymizeSteps strip identifiers from a user and the patients they own,
+// keeping clinical values so aggregate analytics are unchanged. Snapshots in
+// patient_versions lose the same fields as the patients themselves.
+var anonymizeSteps = []string{
+	`DELETE FROM patient_contacts WHERE patient_id IN (SELECT id FROM patients WHERE user_id = $1)`,
+	`DELETE FROM patient_notes WHERE patient_id IN (SELECT id FROM patients WHERE user_id = $1)`,
+	`DELETE FROM follow_ups WHERE patient_id IN (SELECT id FROM patients WHERE user_id = $1)`,
+	`DELETE FROM tasks WHERE user_id = $1`,
+	`DELETE FROM tags WHERE user_id = $1`,
+	`DELETE FROM patient_list_views WHERE user_id = $1`,
+	`UPDATE patient_versions SET before = before - 'name' - 'mrn', after = after - 'name' - 'mrn',
+		changes = COALESCE((SELECT jsonb_agg(c) FROM jsonb_array_elements(changes) c
+			WHERE c->>'field' NOT IN ('name', 'mrn')), '[]'::jsonb)
+	 WHERE patient_id IN (SELECT id FROM patients WHERE user_id = $1)`,
+	`UPDATE patients SET name = 'Deleted patient ' || id, mrn = NULL, updated_at = NOW() WHERE user_id = $1`,
+	`DELETE FROM refresh_tokens WHERE user_id = $1`,
+	`DELETE FROM email_verification_tokens WHERE user_id = $1`,
+	`DELETE FROM password_reset_tokens WHERE user_id = $1`,
+	`DELETE FROM api_keys WHERE user_id = $1`,
+	`DELETE FROM user_backup_codes WHERE user_id = $1`,
+	`DELETE FROM user_mfa WHERE user_id = $1`,
+	`DELETE FROM user_clinics WHERE user_id = $1`,
+	`DELETE FROM login_history WHERE user_id = $1`,
+	`UPDATE users SET email = 'deleted-user-' || id || '@deleted.invalid', password_hash = '',
+		is_active = false, locked_until = NULL, failed_login_attempts = 0, updated_at = NOW()
+	 WHERE id = $1`,
+}
+
+// deleteSteps remove the user; patients, assessments, tokens, memberships
+// and alerts go with them through ON DELETE CASCADE.
+var deleteSteps = []string{
+	`UPDATE users SET created_by = NULL WHERE created_by = $1`,
+	`DELETE FROM patients WHERE user_id = $1`,
+	`DELETE FROM users WHERE id = $1`,
+}
+
+func (r *pgUserDeletionRepo) Purge(ctx context.Context, d models.UserDeletion, mode, by string) (*models.UserPurge, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	steps := anonymizeSteps
+	switch mode {
+	case models.RetentionAnonymize:
+	case models.RetentionDelete:
+		steps = deleteSteps
+	default:
+		return nil, fmt.Errorf("unknown retention mode %q", mode)
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the deletion so a concurrent cancel or purge waits for this one
+	var status string
+	err = tx.QueryRow(ctx, `SELECT status FROM user_deletions WHERE id = $1 FOR UPDATE`, d.ID).Scan(&status)
+	if err != nil {
+		return nil, err
+	}
+	if status != models.DeletionPending {
+		return nil, tx.Commit(ctx)
+	}
+	var active bool
+	err = tx.QueryRow(ctx, `SELECT is_active FROM users WHERE id = $1 FOR UPDATE`, d.UserID).Scan(&active)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err, active = nil, false
+	}
+	if err != nil {
+		return nil, err
+	}
+	if active {
+		if _, err := r.cancel(ctx, tx, `d.id = $1`, d.ID, by); err != nil {
+			return nil, err
+		}
+		return nil, tx.Commit(ctx)
+	}
+
+	// The clinic still relies on these records; they must be transferred first
+	var clinicPatients bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM patients p
+			WHERE p.user_id = $1
+			  AND (p.clinic_id IS NOT NULL OR EXISTS (SELECT 1 FROM user_clinics uc WHERE uc.user_id = $1))
+		)`, d.UserID).Scan(&clinicPatients); err != nil {
+		return nil, err
+	}
+	if clinicPatients {
+		return nil, ErrClinicPatients
+	}
+
+	purge := &models.UserPurge{}
+	purge.PhotoKeys, err = purgeObjects(ctx, tx, "patient_photos", d.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// Lab reports identify the patient, so they go in either mode
+	purge.AttachmentKeys, err = purgeObjects(ctx, tx, "assessment_attachments", d.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM patients WHERE user_id = $1`, d.UserID).Scan(&purge.Patients); err != nil {
+		return nil, err
+	}
+
+	for _, sql := range steps {
+		if _, err := tx.Exec(ctx, sql, d.UserID); err != nil {
+			return nil, err
+		}
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE user_deletions
+		SET status = 'completed', mode = $2, patients = $3, resolved_by = $4, resolved_at = NOW()
+		WHERE id = $1`, d.ID, mode, purge.Patients, by)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return purge, nil
+}
+
+// purgeObjects deletes the rows of table, which keeps blob keys in
+// storage_key, belonging to the user's patients and returns their keys.
+func purgeObjects(ctx context.Context, tx pgx.Tx, table string, userID int64) ([]string, error) {
+	rows, err := tx.Query(ctx, `
+		DELETE FROM `+table+`
+		WHERE patient_id IN (SELECT id FROM patients WHERE user_id = $1)
+		RETURNING storage_key`, userID)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []string{}
+	}
+	return keys, nil
+}
@@ -0,0 +1,58 @@
+// postgres_user_preferences.go: Per-user units, locale and date format.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+// preference keys in users.preferences
+const (
+	prefUnits      = "units"
+	prefLocale     = "locale"
+	prefDateFormat = "date_format"
+)
+
+func (r *pgUserRepo) Preferences(ctx context.Context, id int32) (*models.UserPreferences, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	var stored map[string]string
+	if err := r.pool.QueryRow(ctx, `SELECT preferences FROM users WHERE id = $1`, id).Scan(&stored); err != nil {
+		return nil, err
+	}
+	return &models.UserPreferences{
+		Units:      stored[prefUnits],
+		Locale:     stored[prefLocale],
+		DateFormat: stored[prefDateFormat],
+	}, nil
+}
+
+func (r *pgUserRepo) SavePreferences(ctx context.Context, id int32, prefs models.UserPreferences) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	stored := map[string]string{}
+	for key, v := range map[string]string{
+		prefUnits:      prefs.Units,
+		prefLocale:     prefs.Locale,
+		prefDateFormat: prefs.DateFormat,
+	} {
+		if v != "" {
+			stored[key] = v
+		}
+	}
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE users SET preferences = $2, updated_at = NOW()
+		WHERE id = $1`, id, stored)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
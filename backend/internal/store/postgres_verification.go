@@ -0,0 +1,105 @@
+// postgres_verification.go: Self-registration and email verification tokens.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) EmailVerifications() EmailVerificationRepository {
+	return &pgEmailVerificationRepo{pool: s.pool}
+}
+
+type pgEmailVerificationRepo struct {
+	pool *pgxpool.Pool
+}
+
+func (r *pgEmailVerificationRepo) Register(ctx context.Context, user models.User, tokenHash string, expiresAt time.Time) (*models.User, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO users (email, password_hash, role, is_active, email_verified_at, created_at, updated_at)
+		VALUES ($1, $2, $3, true, NULL, NOW(), NOW())
+		RETURNING id, created_at, updated_at`,
+		user.Email, user.PasswordHash, user.Role,
+	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, conflictError(err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO email_verification_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)`,
+		user.ID, tokenHash, expiresAt); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	user.IsActive = true
+	user.EmailVerifiedAt = nil
+	return &user, nil
+}
+
+func (r *pgEmailVerificationRepo) CreateToken(ctx context.Context, userID int32, tokenHash string, expiresAt time.Time) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO email_verification_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)`,
+		userID, tokenHash, expiresAt)
+	return err
+}
+
+func (r *pgEmailVerificationRepo) Verify(ctx context.Context, tokenHash string) (int32, error) {
+	if r.pool == nil {
+		return 0, errors.New("db not configured")
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int32
+	err = tx.QueryRow(ctx, `
+		UPDATE email_verification_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`, tokenHash).Scan(&userID)
+	if err != nil {
+		return 0, err // pgx.ErrNoRows for unknown, used or expired tokens
+	}
+
+	// Retire any other outstanding tokens (e.g. from resends)
+	if _, err := tx.Exec(ctx, `
+		UPDATE email_verification_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL`, userID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1`, userID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
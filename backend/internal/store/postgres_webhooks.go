@@ -0,0 +1,203 @@
+// postgres_webhooks.go: Outgoing webhooks and their delivery outbox.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skufu/DianaV2/backend/internal/models"
+)
+
+func (s *PostgresStore) Webhooks() WebhookRepository {
+	return &pgWebhookRepo{pool: s.pool}
+}
+
+type pgWebhookRepo struct {
+	pool *pgxpool.Pool
+}
+
+const webhookColumns = `id, url, description, events, active, created_by, created_at, updated_at`
+
+func scanWebhook(row pgx.Row) (*models.Webhook, error) {
+	var w models.Webhook
+	var description pgtype.Text
+	if err := row.Scan(&w.ID, &w.URL, &description, &w.Events, &w.Active, &w.CreatedBy, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	w.Description = description.String
+	return &w, nil
+}
+
+func (r *pgWebhookRepo) List(ctx context.Context) ([]models.Webhook, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *w)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgWebhookRepo) Get(ctx context.Context, id int64) (*models.Webhook, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanWebhook(r.pool.QueryRow(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id))
+}
+
+func (r *pgWebhookRepo) Create(ctx context.Context, w models.Webhook) (*models.Webhook, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	created, err := scanWebhook(r.pool.QueryRow(ctx, `
+		INSERT INTO webhooks (url, description, secret, events, active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+webhookColumns,
+		w.URL, textToPg(w.Description), w.Secret, w.Events, w.Active, w.CreatedBy))
+	if err != nil {
+		return nil, err
+	}
+	created.Secret = w.Secret
+	return created, nil
+}
+
+func (r *pgWebhookRepo) Update(ctx context.Context, w models.Webhook) (*models.Webhook, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	return scanWebhook(r.pool.QueryRow(ctx, `
+		UPDATE webhooks
+		SET url = $2, description = $3, events = $4, active = $5, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+webhookColumns,
+		w.ID, w.URL, textToPg(w.Description), w.Events, w.Active))
+}
+
+func (r *pgWebhookRepo) Delete(ctx context.Context, id int64) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *pgWebhookRepo) Enqueue(ctx context.Context, event string, payload []byte) (int, error) {
+	if r.pool == nil {
+		return 0, errors.New("db not configured")
+	}
+	tag, err := r.pool.Exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT id, $1, $2 FROM webhooks WHERE active AND $1 = ANY(events)
+	`, event, payload)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// ClaimDue locks the due rows with SKIP LOCKED, so replicas polling at the
+// same time claim different deliveries.
+func (r *pgWebhookRepo) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		FROM webhooks w
+		WHERE w.id = d.webhook_id
+		  AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		  )
+		RETURNING d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at, d.created_at, w.url, w.secret
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.CreatedAt, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func (r *pgWebhookRepo) RecordAttempt(ctx context.Context, d models.WebhookDelivery) error {
+	if r.pool == nil {
+		return errors.New("db not configured")
+	}
+	var statusCode pgtype.Int4
+	if d.LastStatusCode != 0 {
+		statusCode = pgtype.Int4{Int32: int32(d.LastStatusCode), Valid: true}
+	}
+	_, err := r.pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, last_status_code = $6,
+		    delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() END
+		WHERE id = $1
+	`, d.ID, d.Status, d.Attempts, d.NextAttemptAt, textToPg(d.LastError), statusCode)
+	return err
+}
+
+func (r *pgWebhookRepo) ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]models.WebhookDelivery, error) {
+	if r.pool == nil {
+		return nil, errors.New("db not configured")
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, webhook_id, event, status, attempts, next_attempt_at, last_error, last_status_code, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var lastError pgtype.Text
+		var statusCode pgtype.Int4
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&lastError, &statusCode, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			return nil, err
+		}
+		d.LastError = lastError.String
+		d.LastStatusCode = int(statusCode.Int32)
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
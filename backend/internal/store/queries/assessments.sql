@@ -1,16 +1,18 @@
 -- name: ListAssessmentsByPatient :many
 SELECT id, patient_id, fbs, hba1c, cholesterol, ldl, hdl, triglycerides, systolic, diastolic,
        activity, history_flag, smoking, hypertension, heart_disease, bmi, cluster, risk_score,
-       model_version, dataset_hash, validation_status, created_at, updated_at
-FROM assessments
+       model_version, dataset_hash, validation_status, created_at, updated_at, self_reported, quality_score, quality_completeness, quality_out_of_range, status, height_cm, weight_kg, bmi_source
+FROM assessments a
 WHERE patient_id = $1
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 ORDER BY created_at DESC;
 
 -- name: ListAssessmentsLimited :many
 SELECT id, patient_id, fbs, hba1c, cholesterol, ldl, hdl, triglycerides, systolic, diastolic,
        activity, history_flag, smoking, hypertension, heart_disease, bmi, cluster, risk_score,
-       model_version, dataset_hash, validation_status, created_at, updated_at
-FROM assessments
+       model_version, dataset_hash, validation_status, created_at, updated_at, self_reported, quality_score, quality_completeness, quality_out_of_range, status, height_cm, weight_kg, bmi_source
+FROM assessments a
+WHERE NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 ORDER BY created_at DESC
 LIMIT $1;
 
@@ -18,10 +20,12 @@ LIMIT $1;
 SELECT a.id, a.patient_id, a.fbs, a.hba1c, a.cholesterol, a.ldl, a.hdl, a.triglycerides,
        a.systolic, a.diastolic, a.activity, a.history_flag, a.smoking, a.hypertension,
        a.heart_disease, a.bmi, a.cluster, a.risk_score, a.model_version, a.dataset_hash,
-       a.validation_status, a.created_at, a.updated_at
+       a.validation_status, a.created_at, a.updated_at,
+       a.self_reported, a.quality_score, a.quality_completeness, a.quality_out_of_range, a.status, a.height_cm, a.weight_kg, a.bmi_source
 FROM assessments a
 INNER JOIN patients p ON a.patient_id = p.id
 WHERE p.user_id = $1
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 ORDER BY a.created_at DESC
 LIMIT $2;
 
@@ -29,22 +33,32 @@ LIMIT $2;
 INSERT INTO assessments (
   patient_id, fbs, hba1c, cholesterol, ldl, hdl, triglycerides, systolic, diastolic,
   activity, history_flag, smoking, hypertension, heart_disease, bmi, cluster, risk_score,
-  model_version, dataset_hash, validation_status
+  model_version, dataset_hash, validation_status,
+  self_reported, quality_score, quality_completeness, quality_out_of_range, status, height_cm, weight_kg, bmi_source
 ) VALUES (
   $1, $2, $3, $4, $5, $6, $7, $8, $9,
   $10, $11, $12, $13, $14, $15, $16, $17,
-  $18, $19, $20
+  $18, $19, $20,
+  $21, $22, $23, $24, $25, $26, $27, $28
 )
 RETURNING id, patient_id, fbs, hba1c, cholesterol, ldl, hdl, triglycerides, systolic, diastolic,
           activity, history_flag, smoking, hypertension, heart_disease, bmi, cluster, risk_score,
-          model_version, dataset_hash, validation_status, created_at, updated_at;
+          model_version, dataset_hash, validation_status, created_at, updated_at, self_reported, quality_score, quality_completeness, quality_out_of_range, status, height_cm, weight_kg, bmi_source;
 
 -- name: GetAssessment :one
-SELECT id, patient_id, fbs, hba1c, cholesterol, ldl, hdl, triglycerides, systolic, diastolic,
-       activity, history_flag, smoking, hypertension, heart_disease, bmi, cluster, risk_score,
-       model_version, dataset_hash, validation_status, created_at, updated_at
-FROM assessments
-WHERE id = $1
+-- Only returns assessments of patients the user owns or may read through a
+-- clinic the patient is shared with, as visiblePatientFilter does.
+SELECT a.id, a.patient_id, a.fbs, a.hba1c, a.cholesterol, a.ldl, a.hdl, a.triglycerides, a.systolic, a.diastolic,
+       a.activity, a.history_flag, a.smoking, a.hypertension, a.heart_disease, a.bmi, a.cluster, a.risk_score,
+       a.model_version, a.dataset_hash, a.validation_status, a.created_at, a.updated_at, a.self_reported, a.quality_score, a.quality_completeness, a.quality_out_of_range, a.status, a.height_cm, a.weight_kg, a.bmi_source
+FROM assessments a
+INNER JOIN patients p ON a.patient_id = p.id
+WHERE a.id = $1
+  AND (p.user_id = $2 OR p.clinic_id IN (
+    SELECT uc.clinic_id FROM user_clinics uc
+    WHERE uc.user_id = $2
+      AND EXISTS (SELECT 1 FROM users u JOIN role_permissions rp ON rp.role IN (u.role, uc.role)
+                  WHERE u.id = uc.user_id AND rp.permission = 'patients.read')))
 LIMIT 1;
 
 -- name: UpdateAssessment :one
@@ -69,32 +83,37 @@ SET patient_id = $2,
     model_version = $19,
     dataset_hash = $20,
     validation_status = $21,
+    self_reported = $22,
+    quality_score = $23,
+    quality_completeness = $24,
+    quality_out_of_range = $25,
+    height_cm = $27,
+    weight_kg = $28,
+    bmi_source = $29,
     updated_at = NOW()
 WHERE id = $1
+  AND patient_id = $2
+  AND patient_id IN (SELECT id FROM patients WHERE user_id = $26)
 RETURNING id, patient_id, fbs, hba1c, cholesterol, ldl, hdl, triglycerides, systolic, diastolic,
           activity, history_flag, smoking, hypertension, heart_disease, bmi, cluster, risk_score,
-          model_version, dataset_hash, validation_status, created_at, updated_at;
+          model_version, dataset_hash, validation_status, created_at, updated_at, self_reported, quality_score, quality_completeness, quality_out_of_range, status, height_cm, weight_kg, bmi_source;
 
--- name: DeleteAssessment :exec
-DELETE FROM assessments
-WHERE id = $1;
+-- name: DeleteAssessment :execrows
+DELETE FROM assessments a
+USING patients p
+WHERE a.id = $1 AND a.patient_id = p.id AND p.user_id = $2;
 
 -- name: ClusterCounts :many
 SELECT COALESCE(cluster, '') AS cluster, COUNT(*) AS count
-FROM assessments
+FROM assessments a
+WHERE status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 GROUP BY COALESCE(cluster, '');
 
--- name: TrendAverages :many
-SELECT to_char(created_at, 'YYYY-MM') AS label,
-       COALESCE(avg(hba1c), 0)::float8 AS hba1c,
-       COALESCE(avg(fbs), 0)::float8 AS fbs
-FROM assessments
-GROUP BY label
-ORDER BY label;
-
 -- name: GetPatientAssessmentTrend :many
 SELECT id, created_at, risk_score, cluster, hba1c, bmi, fbs, 
        triglycerides, ldl, hdl
-FROM assessments
-WHERE patient_id = $1
+FROM assessments a
+WHERE patient_id = $1 AND status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 ORDER BY created_at ASC;
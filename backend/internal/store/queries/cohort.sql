@@ -13,7 +13,9 @@ SELECT
     COUNT(CASE WHEN risk_score < 34 THEN 1 END)::int AS low_risk_count,
     COUNT(CASE WHEN risk_score >= 34 AND risk_score < 67 THEN 1 END)::int AS moderate_risk_count,
     COUNT(CASE WHEN risk_score >= 67 THEN 1 END)::int AS high_risk_count
-FROM assessments
+FROM assessments a
+WHERE status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 GROUP BY COALESCE(cluster, 'Unknown');
 
 -- name: CohortStatsByRiskLevel :many
@@ -30,7 +32,9 @@ SELECT
     COALESCE(AVG(systolic), 0)::float8 AS avg_bp_systolic,
     COALESCE(AVG(diastolic), 0)::float8 AS avg_bp_diastolic,
     COALESCE(AVG(risk_score), 0)::float8 AS avg_risk_score
-FROM assessments
+FROM assessments a
+WHERE status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 GROUP BY 
     CASE 
         WHEN risk_score < 34 THEN 'Low'
@@ -55,6 +59,8 @@ SELECT
     COALESCE(AVG(a.risk_score), 0)::float8 AS avg_risk_score
 FROM assessments a
 JOIN patients p ON a.patient_id = p.id
+WHERE a.status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 GROUP BY 
     CASE 
         WHEN p.age < 45 THEN 'Under 45'
@@ -75,6 +81,8 @@ SELECT
     COALESCE(AVG(a.risk_score), 0)::float8 AS avg_risk_score
 FROM assessments a
 JOIN patients p ON a.patient_id = p.id
+WHERE a.status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 GROUP BY COALESCE(p.menopause_status, 'Unknown');
 
 -- name: ClinicAggregate :one
@@ -85,7 +93,8 @@ SELECT
     COUNT(CASE WHEN a.risk_score >= 67 THEN 1 END)::int AS high_risk_count,
     COUNT(CASE WHEN a.created_at >= date_trunc('month', CURRENT_DATE) THEN 1 END)::int AS assessments_this_month
 FROM patients p
-LEFT JOIN assessments a ON a.patient_id = p.id
+LEFT JOIN assessments a ON a.patient_id = p.id AND a.status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 WHERE p.user_id IN (SELECT user_id FROM user_clinics WHERE clinic_id = $1);
 
 -- name: ClinicCliniciansCount :one
@@ -93,34 +102,8 @@ SELECT COUNT(*)::int AS count
 FROM user_clinics
 WHERE clinic_id = $1;
 
--- name: AdminSystemStats :one
-SELECT 
-    (SELECT COUNT(*)::int FROM users) AS total_users,
-    (SELECT COUNT(*)::int FROM patients) AS total_patients,
-    (SELECT COUNT(*)::int FROM assessments) AS total_assessments,
-    (SELECT COUNT(*)::int FROM clinics) AS total_clinics,
-    COALESCE((SELECT AVG(risk_score) FROM assessments), 0)::float8 AS avg_risk_score,
-    (SELECT COUNT(*)::int FROM assessments WHERE risk_score >= 67) AS high_risk_count,
-    (SELECT COUNT(*)::int FROM assessments WHERE created_at >= date_trunc('month', CURRENT_DATE)) AS assessments_this_month,
-    (SELECT COUNT(*)::int FROM users WHERE created_at >= date_trunc('month', CURRENT_DATE)) AS new_users_this_month;
-
--- name: AdminClinicComparison :many
-SELECT 
-    c.id AS clinic_id,
-    c.name AS clinic_name,
-    COUNT(DISTINCT p.id)::int AS patient_count,
-    COUNT(a.id)::int AS assessment_count,
-    COALESCE(AVG(a.risk_score), 0)::float8 AS avg_risk_score,
-    COUNT(CASE WHEN a.risk_score >= 67 THEN 1 END)::int AS high_risk_count
-FROM clinics c
-LEFT JOIN user_clinics uc ON c.id = uc.clinic_id
-LEFT JOIN patients p ON p.user_id = uc.user_id
-LEFT JOIN assessments a ON a.patient_id = p.id
-GROUP BY c.id, c.name
-ORDER BY patient_count DESC;
-
 -- name: TotalAssessmentCount :one
-SELECT COUNT(*)::int AS count FROM assessments;
+SELECT COUNT(*)::int AS count FROM assessments a WHERE status = 'final' AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id);
 
 -- name: TotalPatientCount :one
 SELECT COUNT(*)::int AS count FROM patients;
@@ -1,7 +1,8 @@
 -- patients.sql: sqlc queries for patient CRUD/listing used by the Postgres store.
 -- name: ListPatients :many
 SELECT id, user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
-       activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides,
+       activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides, mrn,
+       height_cm, weight_kg, bmi_source,
        created_at, updated_at
 FROM patients
 WHERE user_id = $1
@@ -9,7 +10,8 @@ ORDER BY id DESC;
 
 -- name: ListPatientsLimited :many
 SELECT id, user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
-       activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides,
+       activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides, mrn,
+       height_cm, weight_kg, bmi_source,
        created_at, updated_at
 FROM patients
 WHERE user_id = $1
@@ -19,18 +21,22 @@ LIMIT $2;
 -- name: CreatePatient :one
 INSERT INTO patients (
   user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
-  activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides
+  activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides, mrn,
+  height_cm, weight_kg, bmi_source
 ) VALUES (
   $1, $2, $3, $4, $5, $6, $7, $8,
-  $9, $10, $11, $12, $13, $14, $15, $16, $17, $18
+  $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19,
+  $20, $21, $22
 )
 RETURNING id, user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
-          activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides,
+          activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides, mrn,
+          height_cm, weight_kg, bmi_source,
           created_at, updated_at;
 
 -- name: GetPatient :one
 SELECT id, user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
-       activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides,
+       activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides, mrn,
+       height_cm, weight_kg, bmi_source,
        created_at, updated_at
 FROM patients
 WHERE id = $1 AND user_id = $2
@@ -55,10 +61,15 @@ SET name = $3,
     ldl = $17,
     hdl = $18,
     triglycerides = $19,
+    mrn = $20,
+    height_cm = $21,
+    weight_kg = $22,
+    bmi_source = $23,
     updated_at = NOW()
 WHERE id = $1 AND user_id = $2
 RETURNING id, user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
-          activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides,
+          activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides, mrn,
+          height_cm, weight_kg, bmi_source,
           created_at, updated_at;
 
 -- name: DeletePatient :exec
@@ -27,3 +27,21 @@ AND revoked = FALSE;
 -- name: DeleteExpiredTokens :exec
 DELETE FROM refresh_tokens
 WHERE expires_at < NOW();
+
+-- name: RevokeExcessUserTokens :execrows
+UPDATE refresh_tokens
+SET revoked = TRUE,
+    revoked_at = NOW()
+WHERE id IN (
+  SELECT id FROM refresh_tokens
+  WHERE user_id = $1
+  AND revoked = FALSE
+  AND expires_at > NOW()
+  ORDER BY created_at DESC, id DESC
+  OFFSET $2
+);
+
+-- name: DeleteRevokedTokens :execrows
+DELETE FROM refresh_tokens
+WHERE revoked = TRUE
+AND revoked_at < $1;
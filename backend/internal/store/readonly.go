@@ -0,0 +1,90 @@
+// readonly.go: Detects when Postgres stops accepting writes (e.g. the primary
+// failed over to a read-only replica) so the API can degrade to read-only
+// instead of surfacing raw pg errors from every write endpoint.
+package store
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// pgReadOnlyTransaction is SQLSTATE read_only_sql_transaction, returned for
+// writes against a hot standby or a default_transaction_read_only server.
+const pgReadOnlyTransaction = "25006"
+
+// ReadOnlyMonitor tracks whether the database currently rejects writes. It is
+// a pgx.QueryTracer: install it on the pool config to flip into read-only mode
+// on the first rejected write, and run Watch to flip back once writes work.
+type ReadOnlyMonitor struct {
+	readOnly atomic.Bool
+}
+
+func NewReadOnlyMonitor() *ReadOnlyMonitor {
+	return &ReadOnlyMonitor{}
+}
+
+// ReadOnly reports whether writes are currently being rejected.
+func (m *ReadOnlyMonitor) ReadOnly() bool {
+	return m != nil && m.readOnly.Load()
+}
+
+func (m *ReadOnlyMonitor) set(readOnly bool, reason string) {
+	if m.readOnly.Swap(readOnly) == readOnly {
+		return
+	}
+	if readOnly {
+		log.Warn().Msgf("database is read-only (%s); rejecting writes", reason)
+	} else {
+		log.Info().Msgf("database accepts writes again (%s); leaving read-only mode", reason)
+	}
+}
+
+func (m *ReadOnlyMonitor) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return ctx
+}
+
+func (m *ReadOnlyMonitor) TraceQueryEnd(_ context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	if isReadOnlyError(data.Err) {
+		m.set(true, "write rejected with SQLSTATE "+pgReadOnlyTransaction)
+	}
+}
+
+// Watch probes the server every interval until ctx is done. Probe failures
+// (e.g. the database is unreachable) leave the current mode unchanged.
+func (m *ReadOnlyMonitor) Watch(ctx context.Context, pool *pgxpool.Pool, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.probe(ctx, pool)
+		}
+	}
+}
+
+func (m *ReadOnlyMonitor) probe(ctx context.Context, pool *pgxpool.Pool) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var readOnly bool
+	err := pool.QueryRow(ctx,
+		`SELECT pg_is_in_recovery() OR current_setting('transaction_read_only') = 'on'`,
+	).Scan(&readOnly)
+	if err != nil {
+		return
+	}
+	m.set(readOnly, "probe")
+}
+
+func isReadOnlyError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgReadOnlyTransaction
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestReadOnlyMonitor_TraceQueryEnd(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"success", nil, false},
+		{"other pg error", &pgconn.PgError{Code: "23505"}, false},
+		{"plain error", errors.New("connection reset"), false},
+		{"read-only transaction", &pgconn.PgError{Code: "25006"}, true},
+		{"wrapped read-only", fmt.Errorf("insert: %w", &pgconn.PgError{Code: "25006"}), true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewReadOnlyMonitor()
+			m.TraceQueryEnd(context.Background(), nil, pgx.TraceQueryEndData{Err: tc.err})
+			if m.ReadOnly() != tc.want {
+				t.Fatalf("ReadOnly() = %v, want %v", m.ReadOnly(), tc.want)
+			}
+		})
+	}
+}
+
+func TestReadOnlyMonitor_Recover(t *testing.T) {
+	m := NewReadOnlyMonitor()
+	m.set(true, "test")
+	m.set(false, "test")
+	if m.ReadOnly() {
+		t.Fatal("expected writable after recovery")
+	}
+
+	var nilMonitor *ReadOnlyMonitor
+	if nilMonitor.ReadOnly() {
+		t.Fatal("nil monitor should report writable")
+	}
+}
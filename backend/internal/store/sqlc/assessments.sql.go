@@ -13,7 +13,9 @@ import (
 
 const clusterCounts = `-- name: ClusterCounts :many
 SELECT COALESCE(cluster, '') AS cluster, COUNT(*) AS count
-FROM assessments
+FROM assessments a
+WHERE status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 GROUP BY COALESCE(cluster, '')
 `
 
@@ -46,38 +48,48 @@ const createAssessment = `-- name: CreateAssessment :one
 INSERT INTO assessments (
   patient_id, fbs, hba1c, cholesterol, ldl, hdl, triglycerides, systolic, diastolic,
   activity, history_flag, smoking, hypertension, heart_disease, bmi, cluster, risk_score,
-  model_version, dataset_hash, validation_status
+  model_version, dataset_hash, validation_status,
+  self_reported, quality_score, quality_completeness, quality_out_of_range, status, height_cm, weight_kg, bmi_source
 ) VALUES (
   $1, $2, $3, $4, $5, $6, $7, $8, $9,
   $10, $11, $12, $13, $14, $15, $16, $17,
-  $18, $19, $20
+  $18, $19, $20,
+  $21, $22, $23, $24, $25, $26, $27, $28
 )
 RETURNING id, patient_id, fbs, hba1c, cholesterol, ldl, hdl, triglycerides, systolic, diastolic,
           activity, history_flag, smoking, hypertension, heart_disease, bmi, cluster, risk_score,
-          model_version, dataset_hash, validation_status, created_at, updated_at
+          model_version, dataset_hash, validation_status, created_at, updated_at, self_reported, quality_score, quality_completeness, quality_out_of_range, status, height_cm, weight_kg, bmi_source
 `
 
 type CreateAssessmentParams struct {
-	PatientID        pgtype.Int4    `json:"patient_id"`
-	Fbs              pgtype.Numeric `json:"fbs"`
-	Hba1c            pgtype.Numeric `json:"hba1c"`
-	Cholesterol      pgtype.Int4    `json:"cholesterol"`
-	Ldl              pgtype.Int4    `json:"ldl"`
-	Hdl              pgtype.Int4    `json:"hdl"`
-	Triglycerides    pgtype.Int4    `json:"triglycerides"`
-	Systolic         pgtype.Int4    `json:"systolic"`
-	Diastolic        pgtype.Int4    `json:"diastolic"`
-	Activity         pgtype.Text    `json:"activity"`
-	HistoryFlag      pgtype.Bool    `json:"history_flag"`
-	Smoking          pgtype.Text    `json:"smoking"`
-	Hypertension     pgtype.Text    `json:"hypertension"`
-	HeartDisease     pgtype.Text    `json:"heart_disease"`
-	Bmi              pgtype.Numeric `json:"bmi"`
-	Cluster          pgtype.Text    `json:"cluster"`
-	RiskScore        pgtype.Int4    `json:"risk_score"`
-	ModelVersion     pgtype.Text    `json:"model_version"`
-	DatasetHash      pgtype.Text    `json:"dataset_hash"`
-	ValidationStatus pgtype.Text    `json:"validation_status"`
+	PatientID           pgtype.Int4    `json:"patient_id"`
+	Fbs                 pgtype.Numeric `json:"fbs"`
+	Hba1c               pgtype.Numeric `json:"hba1c"`
+	Cholesterol         pgtype.Int4    `json:"cholesterol"`
+	Ldl                 pgtype.Int4    `json:"ldl"`
+	Hdl                 pgtype.Int4    `json:"hdl"`
+	Triglycerides       pgtype.Int4    `json:"triglycerides"`
+	Systolic            pgtype.Int4    `json:"systolic"`
+	Diastolic           pgtype.Int4    `json:"diastolic"`
+	Activity            pgtype.Text    `json:"activity"`
+	HistoryFlag         pgtype.Bool    `json:"history_flag"`
+	Smoking             pgtype.Text    `json:"smoking"`
+	Hypertension        pgtype.Text    `json:"hypertension"`
+	HeartDisease        pgtype.Text    `json:"heart_disease"`
+	Bmi                 pgtype.Numeric `json:"bmi"`
+	Cluster             pgtype.Text    `json:"cluster"`
+	RiskScore           pgtype.Int4    `json:"risk_score"`
+	ModelVersion        pgtype.Text    `json:"model_version"`
+	DatasetHash         pgtype.Text    `json:"dataset_hash"`
+	ValidationStatus    pgtype.Text    `json:"validation_status"`
+	SelfReported        bool           `json:"self_reported"`
+	QualityScore        pgtype.Int4    `json:"quality_score"`
+	QualityCompleteness pgtype.Float4  `json:"quality_completeness"`
+	QualityOutOfRange   pgtype.Int4    `json:"quality_out_of_range"`
+	Status              string         `json:"status"`
+	HeightCm            pgtype.Numeric `json:"height_cm"`
+	WeightKg            pgtype.Numeric `json:"weight_kg"`
+	BmiSource           pgtype.Text    `json:"bmi_source"`
 }
 
 func (q *Queries) CreateAssessment(ctx context.Context, arg CreateAssessmentParams) (Assessment, error) {
@@ -102,6 +114,14 @@ func (q *Queries) CreateAssessment(ctx context.Context, arg CreateAssessmentPara
 		arg.ModelVersion,
 		arg.DatasetHash,
 		arg.ValidationStatus,
+		arg.SelfReported,
+		arg.QualityScore,
+		arg.QualityCompleteness,
+		arg.QualityOutOfRange,
+		arg.Status,
+		arg.HeightCm,
+		arg.WeightKg,
+		arg.BmiSource,
 	)
 	var i Assessment
 	err := row.Scan(
@@ -128,31 +148,61 @@ func (q *Queries) CreateAssessment(ctx context.Context, arg CreateAssessmentPara
 		&i.ValidationStatus,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SelfReported,
+		&i.QualityScore,
+		&i.QualityCompleteness,
+		&i.QualityOutOfRange,
+		&i.Status,
+		&i.HeightCm,
+		&i.WeightKg,
+		&i.BmiSource,
 	)
 	return i, err
 }
 
-const deleteAssessment = `-- name: DeleteAssessment :exec
-DELETE FROM assessments
-WHERE id = $1
+const deleteAssessment = `-- name: DeleteAssessment :execrows
+DELETE FROM assessments a
+USING patients p
+WHERE a.id = $1 AND a.patient_id = p.id AND p.user_id = $2
 `
 
-func (q *Queries) DeleteAssessment(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, deleteAssessment, id)
-	return err
+type DeleteAssessmentParams struct {
+	ID     int32 `json:"id"`
+	UserID int32 `json:"user_id"`
+}
+
+func (q *Queries) DeleteAssessment(ctx context.Context, arg DeleteAssessmentParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAssessment, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const getAssessment = `-- name: GetAssessment :one
-SELECT id, patient_id, fbs, hba1c, cholesterol, ldl, hdl, triglycerides, systolic, diastolic,
-       activity, history_flag, smoking, hypertension, heart_disease, bmi, cluster, risk_score,
-       model_version, dataset_hash, validation_status, created_at, updated_at
-FROM assessments
-WHERE id = $1
+SELECT a.id, a.patient_id, a.fbs, a.hba1c, a.cholesterol, a.ldl, a.hdl, a.triglycerides, a.systolic, a.diastolic,
+       a.activity, a.history_flag, a.smoking, a.hypertension, a.heart_disease, a.bmi, a.cluster, a.risk_score,
+       a.model_version, a.dataset_hash, a.validation_status, a.created_at, a.updated_at, a.self_reported, a.quality_score, a.quality_completeness, a.quality_out_of_range, a.status, a.height_cm, a.weight_kg, a.bmi_source
+FROM assessments a
+INNER JOIN patients p ON a.patient_id = p.id
+WHERE a.id = $1
+  AND (p.user_id = $2 OR p.clinic_id IN (
+    SELECT uc.clinic_id FROM user_clinics uc
+    WHERE uc.user_id = $2
+      AND EXISTS (SELECT 1 FROM users u JOIN role_permissions rp ON rp.role IN (u.role, uc.role)
+                  WHERE u.id = uc.user_id AND rp.permission = 'patients.read')))
 LIMIT 1
 `
 
-func (q *Queries) GetAssessment(ctx context.Context, id int32) (Assessment, error) {
-	row := q.db.QueryRow(ctx, getAssessment, id)
+type GetAssessmentParams struct {
+	ID     int32 `json:"id"`
+	UserID int32 `json:"user_id"`
+}
+
+// Only returns assessments of patients the user owns or may read through a
+// clinic the patient is shared with, as visiblePatientFilter does.
+func (q *Queries) GetAssessment(ctx context.Context, arg GetAssessmentParams) (Assessment, error) {
+	row := q.db.QueryRow(ctx, getAssessment, arg.ID, arg.UserID)
 	var i Assessment
 	err := row.Scan(
 		&i.ID,
@@ -178,6 +228,14 @@ func (q *Queries) GetAssessment(ctx context.Context, id int32) (Assessment, erro
 		&i.ValidationStatus,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SelfReported,
+		&i.QualityScore,
+		&i.QualityCompleteness,
+		&i.QualityOutOfRange,
+		&i.Status,
+		&i.HeightCm,
+		&i.WeightKg,
+		&i.BmiSource,
 	)
 	return i, err
 }
@@ -185,8 +243,9 @@ func (q *Queries) GetAssessment(ctx context.Context, id int32) (Assessment, erro
 const getPatientAssessmentTrend = `-- name: GetPatientAssessmentTrend :many
 SELECT id, created_at, risk_score, cluster, hba1c, bmi, fbs, 
        triglycerides, ldl, hdl
-FROM assessments
-WHERE patient_id = $1
+FROM assessments a
+WHERE patient_id = $1 AND status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 ORDER BY created_at ASC
 `
 
@@ -237,9 +296,10 @@ func (q *Queries) GetPatientAssessmentTrend(ctx context.Context, patientID pgtyp
 const listAssessmentsByPatient = `-- name: ListAssessmentsByPatient :many
 SELECT id, patient_id, fbs, hba1c, cholesterol, ldl, hdl, triglycerides, systolic, diastolic,
        activity, history_flag, smoking, hypertension, heart_disease, bmi, cluster, risk_score,
-       model_version, dataset_hash, validation_status, created_at, updated_at
-FROM assessments
+       model_version, dataset_hash, validation_status, created_at, updated_at, self_reported, quality_score, quality_completeness, quality_out_of_range, status, height_cm, weight_kg, bmi_source
+FROM assessments a
 WHERE patient_id = $1
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 ORDER BY created_at DESC
 `
 
@@ -276,6 +336,14 @@ func (q *Queries) ListAssessmentsByPatient(ctx context.Context, patientID pgtype
 			&i.ValidationStatus,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.SelfReported,
+			&i.QualityScore,
+			&i.QualityCompleteness,
+			&i.QualityOutOfRange,
+			&i.Status,
+			&i.HeightCm,
+			&i.WeightKg,
+			&i.BmiSource,
 		); err != nil {
 			return nil, err
 		}
@@ -290,8 +358,9 @@ func (q *Queries) ListAssessmentsByPatient(ctx context.Context, patientID pgtype
 const listAssessmentsLimited = `-- name: ListAssessmentsLimited :many
 SELECT id, patient_id, fbs, hba1c, cholesterol, ldl, hdl, triglycerides, systolic, diastolic,
        activity, history_flag, smoking, hypertension, heart_disease, bmi, cluster, risk_score,
-       model_version, dataset_hash, validation_status, created_at, updated_at
-FROM assessments
+       model_version, dataset_hash, validation_status, created_at, updated_at, self_reported, quality_score, quality_completeness, quality_out_of_range, status, height_cm, weight_kg, bmi_source
+FROM assessments a
+WHERE NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 ORDER BY created_at DESC
 LIMIT $1
 `
@@ -329,6 +398,14 @@ func (q *Queries) ListAssessmentsLimited(ctx context.Context, limit int32) ([]As
 			&i.ValidationStatus,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.SelfReported,
+			&i.QualityScore,
+			&i.QualityCompleteness,
+			&i.QualityOutOfRange,
+			&i.Status,
+			&i.HeightCm,
+			&i.WeightKg,
+			&i.BmiSource,
 		); err != nil {
 			return nil, err
 		}
@@ -344,10 +421,12 @@ const listAssessmentsLimitedByUser = `-- name: ListAssessmentsLimitedByUser :man
 SELECT a.id, a.patient_id, a.fbs, a.hba1c, a.cholesterol, a.ldl, a.hdl, a.triglycerides,
        a.systolic, a.diastolic, a.activity, a.history_flag, a.smoking, a.hypertension,
        a.heart_disease, a.bmi, a.cluster, a.risk_score, a.model_version, a.dataset_hash,
-       a.validation_status, a.created_at, a.updated_at
+       a.validation_status, a.created_at, a.updated_at,
+       a.self_reported, a.quality_score, a.quality_completeness, a.quality_out_of_range, a.status, a.height_cm, a.weight_kg, a.bmi_source
 FROM assessments a
 INNER JOIN patients p ON a.patient_id = p.id
 WHERE p.user_id = $1
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 ORDER BY a.created_at DESC
 LIMIT $2
 `
@@ -390,6 +469,14 @@ func (q *Queries) ListAssessmentsLimitedByUser(ctx context.Context, arg ListAsse
 			&i.ValidationStatus,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.SelfReported,
+			&i.QualityScore,
+			&i.QualityCompleteness,
+			&i.QualityOutOfRange,
+			&i.Status,
+			&i.HeightCm,
+			&i.WeightKg,
+			&i.BmiSource,
 		); err != nil {
 			return nil, err
 		}
@@ -401,41 +488,6 @@ func (q *Queries) ListAssessmentsLimitedByUser(ctx context.Context, arg ListAsse
 	return items, nil
 }
 
-const trendAverages = `-- name: TrendAverages :many
-SELECT to_char(created_at, 'YYYY-MM') AS label,
-       COALESCE(avg(hba1c), 0)::float8 AS hba1c,
-       COALESCE(avg(fbs), 0)::float8 AS fbs
-FROM assessments
-GROUP BY label
-ORDER BY label
-`
-
-type TrendAveragesRow struct {
-	Label string  `json:"label"`
-	Hba1c float64 `json:"hba1c"`
-	Fbs   float64 `json:"fbs"`
-}
-
-func (q *Queries) TrendAverages(ctx context.Context) ([]TrendAveragesRow, error) {
-	rows, err := q.db.Query(ctx, trendAverages)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []TrendAveragesRow
-	for rows.Next() {
-		var i TrendAveragesRow
-		if err := rows.Scan(&i.Label, &i.Hba1c, &i.Fbs); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
 const updateAssessment = `-- name: UpdateAssessment :one
 UPDATE assessments
 SET patient_id = $2,
@@ -458,35 +510,52 @@ SET patient_id = $2,
     model_version = $19,
     dataset_hash = $20,
     validation_status = $21,
+    self_reported = $22,
+    quality_score = $23,
+    quality_completeness = $24,
+    quality_out_of_range = $25,
+    height_cm = $27,
+    weight_kg = $28,
+    bmi_source = $29,
     updated_at = NOW()
 WHERE id = $1
+  AND patient_id = $2
+  AND patient_id IN (SELECT id FROM patients WHERE user_id = $26)
 RETURNING id, patient_id, fbs, hba1c, cholesterol, ldl, hdl, triglycerides, systolic, diastolic,
           activity, history_flag, smoking, hypertension, heart_disease, bmi, cluster, risk_score,
-          model_version, dataset_hash, validation_status, created_at, updated_at
+          model_version, dataset_hash, validation_status, created_at, updated_at, self_reported, quality_score, quality_completeness, quality_out_of_range, status, height_cm, weight_kg, bmi_source
 `
 
 type UpdateAssessmentParams struct {
-	ID               int32          `json:"id"`
-	PatientID        pgtype.Int4    `json:"patient_id"`
-	Fbs              pgtype.Numeric `json:"fbs"`
-	Hba1c            pgtype.Numeric `json:"hba1c"`
-	Cholesterol      pgtype.Int4    `json:"cholesterol"`
-	Ldl              pgtype.Int4    `json:"ldl"`
-	Hdl              pgtype.Int4    `json:"hdl"`
-	Triglycerides    pgtype.Int4    `json:"triglycerides"`
-	Systolic         pgtype.Int4    `json:"systolic"`
-	Diastolic        pgtype.Int4    `json:"diastolic"`
-	Activity         pgtype.Text    `json:"activity"`
-	HistoryFlag      pgtype.Bool    `json:"history_flag"`
-	Smoking          pgtype.Text    `json:"smoking"`
-	Hypertension     pgtype.Text    `json:"hypertension"`
-	HeartDisease     pgtype.Text    `json:"heart_disease"`
-	Bmi              pgtype.Numeric `json:"bmi"`
-	Cluster          pgtype.Text    `json:"cluster"`
-	RiskScore        pgtype.Int4    `json:"risk_score"`
-	ModelVersion     pgtype.Text    `json:"model_version"`
-	DatasetHash      pgtype.Text    `json:"dataset_hash"`
-	ValidationStatus pgtype.Text    `json:"validation_status"`
+	ID                  int32          `json:"id"`
+	PatientID           pgtype.Int4    `json:"patient_id"`
+	Fbs                 pgtype.Numeric `json:"fbs"`
+	Hba1c               pgtype.Numeric `json:"hba1c"`
+	Cholesterol         pgtype.Int4    `json:"cholesterol"`
+	Ldl                 pgtype.Int4    `json:"ldl"`
+	Hdl                 pgtype.Int4    `json:"hdl"`
+	Triglycerides       pgtype.Int4    `json:"triglycerides"`
+	Systolic            pgtype.Int4    `json:"systolic"`
+	Diastolic           pgtype.Int4    `json:"diastolic"`
+	Activity            pgtype.Text    `json:"activity"`
+	HistoryFlag         pgtype.Bool    `json:"history_flag"`
+	Smoking             pgtype.Text    `json:"smoking"`
+	Hypertension        pgtype.Text    `json:"hypertension"`
+	HeartDisease        pgtype.Text    `json:"heart_disease"`
+	Bmi                 pgtype.Numeric `json:"bmi"`
+	Cluster             pgtype.Text    `json:"cluster"`
+	RiskScore           pgtype.Int4    `json:"risk_score"`
+	ModelVersion        pgtype.Text    `json:"model_version"`
+	DatasetHash         pgtype.Text    `json:"dataset_hash"`
+	ValidationStatus    pgtype.Text    `json:"validation_status"`
+	SelfReported        bool           `json:"self_reported"`
+	QualityScore        pgtype.Int4    `json:"quality_score"`
+	QualityCompleteness pgtype.Float4  `json:"quality_completeness"`
+	QualityOutOfRange   pgtype.Int4    `json:"quality_out_of_range"`
+	UserID              int32          `json:"user_id"`
+	HeightCm            pgtype.Numeric `json:"height_cm"`
+	WeightKg            pgtype.Numeric `json:"weight_kg"`
+	BmiSource           pgtype.Text    `json:"bmi_source"`
 }
 
 func (q *Queries) UpdateAssessment(ctx context.Context, arg UpdateAssessmentParams) (Assessment, error) {
@@ -512,6 +581,14 @@ func (q *Queries) UpdateAssessment(ctx context.Context, arg UpdateAssessmentPara
 		arg.ModelVersion,
 		arg.DatasetHash,
 		arg.ValidationStatus,
+		arg.SelfReported,
+		arg.QualityScore,
+		arg.QualityCompleteness,
+		arg.QualityOutOfRange,
+		arg.UserID,
+		arg.HeightCm,
+		arg.WeightKg,
+		arg.BmiSource,
 	)
 	var i Assessment
 	err := row.Scan(
@@ -538,6 +615,14 @@ func (q *Queries) UpdateAssessment(ctx context.Context, arg UpdateAssessmentPara
 		&i.ValidationStatus,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SelfReported,
+		&i.QualityScore,
+		&i.QualityCompleteness,
+		&i.QualityOutOfRange,
+		&i.Status,
+		&i.HeightCm,
+		&i.WeightKg,
+		&i.BmiSource,
 	)
 	return i, err
 }
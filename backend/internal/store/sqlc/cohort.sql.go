@@ -9,97 +9,6 @@ import (
 	"context"
 )
 
-const adminClinicComparison = `-- name: AdminClinicComparison :many
-SELECT 
-    c.id AS clinic_id,
-    c.name AS clinic_name,
-    COUNT(DISTINCT p.id)::int AS patient_count,
-    COUNT(a.id)::int AS assessment_count,
-    COALESCE(AVG(a.risk_score), 0)::float8 AS avg_risk_score,
-    COUNT(CASE WHEN a.risk_score >= 67 THEN 1 END)::int AS high_risk_count
-FROM clinics c
-LEFT JOIN user_clinics uc ON c.id = uc.clinic_id
-LEFT JOIN patients p ON p.user_id = uc.user_id
-LEFT JOIN assessments a ON a.patient_id = p.id
-GROUP BY c.id, c.name
-ORDER BY patient_count DESC
-`
-
-type AdminClinicComparisonRow struct {
-	ClinicID        int32   `json:"clinic_id"`
-	ClinicName      string  `json:"clinic_name"`
-	PatientCount    int32   `json:"patient_count"`
-	AssessmentCount int32   `json:"assessment_count"`
-	AvgRiskScore    float64 `json:"avg_risk_score"`
-	HighRiskCount   int32   `json:"high_risk_count"`
-}
-
-func (q *Queries) AdminClinicComparison(ctx context.Context) ([]AdminClinicComparisonRow, error) {
-	rows, err := q.db.Query(ctx, adminClinicComparison)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []AdminClinicComparisonRow
-	for rows.Next() {
-		var i AdminClinicComparisonRow
-		if err := rows.Scan(
-			&i.ClinicID,
-			&i.ClinicName,
-			&i.PatientCount,
-			&i.AssessmentCount,
-			&i.AvgRiskScore,
-			&i.HighRiskCount,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
-const adminSystemStats = `-- name: AdminSystemStats :one
-SELECT 
-    (SELECT COUNT(*)::int FROM users) AS total_users,
-    (SELECT COUNT(*)::int FROM patients) AS total_patients,
-    (SELECT COUNT(*)::int FROM assessments) AS total_assessments,
-    (SELECT COUNT(*)::int FROM clinics) AS total_clinics,
-    COALESCE((SELECT AVG(risk_score) FROM assessments), 0)::float8 AS avg_risk_score,
-    (SELECT COUNT(*)::int FROM assessments WHERE risk_score >= 67) AS high_risk_count,
-    (SELECT COUNT(*)::int FROM assessments WHERE created_at >= date_trunc('month', CURRENT_DATE)) AS assessments_this_month,
-    (SELECT COUNT(*)::int FROM users WHERE created_at >= date_trunc('month', CURRENT_DATE)) AS new_users_this_month
-`
-
-type AdminSystemStatsRow struct {
-	TotalUsers           int32   `json:"total_users"`
-	TotalPatients        int32   `json:"total_patients"`
-	TotalAssessments     int32   `json:"total_assessments"`
-	TotalClinics         int32   `json:"total_clinics"`
-	AvgRiskScore         float64 `json:"avg_risk_score"`
-	HighRiskCount        int32   `json:"high_risk_count"`
-	AssessmentsThisMonth int32   `json:"assessments_this_month"`
-	NewUsersThisMonth    int32   `json:"new_users_this_month"`
-}
-
-func (q *Queries) AdminSystemStats(ctx context.Context) (AdminSystemStatsRow, error) {
-	row := q.db.QueryRow(ctx, adminSystemStats)
-	var i AdminSystemStatsRow
-	err := row.Scan(
-		&i.TotalUsers,
-		&i.TotalPatients,
-		&i.TotalAssessments,
-		&i.TotalClinics,
-		&i.AvgRiskScore,
-		&i.HighRiskCount,
-		&i.AssessmentsThisMonth,
-		&i.NewUsersThisMonth,
-	)
-	return i, err
-}
-
 const clinicAggregate = `-- name: ClinicAggregate :one
 SELECT 
     COUNT(DISTINCT p.id)::int AS total_patients,
@@ -108,7 +17,8 @@ SELECT
     COUNT(CASE WHEN a.risk_score >= 67 THEN 1 END)::int AS high_risk_count,
     COUNT(CASE WHEN a.created_at >= date_trunc('month', CURRENT_DATE) THEN 1 END)::int AS assessments_this_month
 FROM patients p
-LEFT JOIN assessments a ON a.patient_id = p.id
+LEFT JOIN assessments a ON a.patient_id = p.id AND a.status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 WHERE p.user_id IN (SELECT user_id FROM user_clinics WHERE clinic_id = $1)
 `
 
@@ -163,6 +73,8 @@ SELECT
     COALESCE(AVG(a.risk_score), 0)::float8 AS avg_risk_score
 FROM assessments a
 JOIN patients p ON a.patient_id = p.id
+WHERE a.status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 GROUP BY 
     CASE 
         WHEN p.age < 45 THEN 'Under 45'
@@ -226,7 +138,9 @@ SELECT
     COUNT(CASE WHEN risk_score < 34 THEN 1 END)::int AS low_risk_count,
     COUNT(CASE WHEN risk_score >= 34 AND risk_score < 67 THEN 1 END)::int AS moderate_risk_count,
     COUNT(CASE WHEN risk_score >= 67 THEN 1 END)::int AS high_risk_count
-FROM assessments
+FROM assessments a
+WHERE status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 GROUP BY COALESCE(cluster, 'Unknown')
 `
 
@@ -289,6 +203,8 @@ SELECT
     COALESCE(AVG(a.risk_score), 0)::float8 AS avg_risk_score
 FROM assessments a
 JOIN patients p ON a.patient_id = p.id
+WHERE a.status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 GROUP BY COALESCE(p.menopause_status, 'Unknown')
 `
 
@@ -346,7 +262,9 @@ SELECT
     COALESCE(AVG(systolic), 0)::float8 AS avg_bp_systolic,
     COALESCE(AVG(diastolic), 0)::float8 AS avg_bp_diastolic,
     COALESCE(AVG(risk_score), 0)::float8 AS avg_risk_score
-FROM assessments
+FROM assessments a
+WHERE status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 GROUP BY 
     CASE 
         WHEN risk_score < 34 THEN 'Low'
@@ -396,7 +314,7 @@ func (q *Queries) CohortStatsByRiskLevel(ctx context.Context) ([]CohortStatsByRi
 }
 
 const totalAssessmentCount = `-- name: TotalAssessmentCount :one
-SELECT COUNT(*)::int AS count FROM assessments
+SELECT COUNT(*)::int AS count FROM assessments a WHERE status = 'final' AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
 `
 
 func (q *Queries) TotalAssessmentCount(ctx context.Context) (int32, error) {
@@ -9,29 +9,37 @@ import (
 )
 
 type Assessment struct {
-	ID               int32              `json:"id"`
-	PatientID        pgtype.Int4        `json:"patient_id"`
-	Fbs              pgtype.Numeric     `json:"fbs"`
-	Hba1c            pgtype.Numeric     `json:"hba1c"`
-	Cholesterol      pgtype.Int4        `json:"cholesterol"`
-	Ldl              pgtype.Int4        `json:"ldl"`
-	Hdl              pgtype.Int4        `json:"hdl"`
-	Triglycerides    pgtype.Int4        `json:"triglycerides"`
-	Systolic         pgtype.Int4        `json:"systolic"`
-	Diastolic        pgtype.Int4        `json:"diastolic"`
-	Activity         pgtype.Text        `json:"activity"`
-	HistoryFlag      pgtype.Bool        `json:"history_flag"`
-	Smoking          pgtype.Text        `json:"smoking"`
-	Hypertension     pgtype.Text        `json:"hypertension"`
-	HeartDisease     pgtype.Text        `json:"heart_disease"`
-	Bmi              pgtype.Numeric     `json:"bmi"`
-	Cluster          pgtype.Text        `json:"cluster"`
-	RiskScore        pgtype.Int4        `json:"risk_score"`
-	ModelVersion     pgtype.Text        `json:"model_version"`
-	DatasetHash      pgtype.Text        `json:"dataset_hash"`
-	ValidationStatus pgtype.Text        `json:"validation_status"`
-	CreatedAt        pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	ID                  int32              `json:"id"`
+	PatientID           pgtype.Int4        `json:"patient_id"`
+	Fbs                 pgtype.Numeric     `json:"fbs"`
+	Hba1c               pgtype.Numeric     `json:"hba1c"`
+	Cholesterol         pgtype.Int4        `json:"cholesterol"`
+	Ldl                 pgtype.Int4        `json:"ldl"`
+	Hdl                 pgtype.Int4        `json:"hdl"`
+	Triglycerides       pgtype.Int4        `json:"triglycerides"`
+	Systolic            pgtype.Int4        `json:"systolic"`
+	Diastolic           pgtype.Int4        `json:"diastolic"`
+	Activity            pgtype.Text        `json:"activity"`
+	HistoryFlag         pgtype.Bool        `json:"history_flag"`
+	Smoking             pgtype.Text        `json:"smoking"`
+	Hypertension        pgtype.Text        `json:"hypertension"`
+	HeartDisease        pgtype.Text        `json:"heart_disease"`
+	Bmi                 pgtype.Numeric     `json:"bmi"`
+	Cluster             pgtype.Text        `json:"cluster"`
+	RiskScore           pgtype.Int4        `json:"risk_score"`
+	ModelVersion        pgtype.Text        `json:"model_version"`
+	DatasetHash         pgtype.Text        `json:"dataset_hash"`
+	ValidationStatus    pgtype.Text        `json:"validation_status"`
+	CreatedAt           pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt           pgtype.Timestamptz `json:"updated_at"`
+	SelfReported        bool               `json:"self_reported"`
+	QualityScore        pgtype.Int4        `json:"quality_score"`
+	QualityCompleteness pgtype.Float4      `json:"quality_completeness"`
+	QualityOutOfRange   pgtype.Int4        `json:"quality_out_of_range"`
+	Status              string             `json:"status"`
+	HeightCm            pgtype.Numeric     `json:"height_cm"`
+	WeightKg            pgtype.Numeric     `json:"weight_kg"`
+	BmiSource           pgtype.Text        `json:"bmi_source"`
 }
 
 type AuditEvent struct {
@@ -82,6 +90,10 @@ type Patient struct {
 	FamilyHistory   pgtype.Bool        `json:"family_history"`
 	PhysActivity    pgtype.Bool        `json:"phys_activity"`
 	UserID          int32              `json:"user_id"`
+	Mrn             pgtype.Text        `json:"mrn"`
+	HeightCm        pgtype.Numeric     `json:"height_cm"`
+	WeightKg        pgtype.Numeric     `json:"weight_kg"`
+	BmiSource       pgtype.Text        `json:"bmi_source"`
 }
 
 type RefreshToken struct {
@@ -14,13 +14,16 @@ import (
 const createPatient = `-- name: CreatePatient :one
 INSERT INTO patients (
   user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
-  activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides
+  activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides, mrn,
+  height_cm, weight_kg, bmi_source
 ) VALUES (
   $1, $2, $3, $4, $5, $6, $7, $8,
-  $9, $10, $11, $12, $13, $14, $15, $16, $17, $18
+  $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19,
+  $20, $21, $22
 )
 RETURNING id, user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
-          activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides,
+          activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides, mrn,
+          height_cm, weight_kg, bmi_source,
           created_at, updated_at
 `
 
@@ -43,6 +46,10 @@ type CreatePatientParams struct {
 	Ldl             pgtype.Int4    `json:"ldl"`
 	Hdl             pgtype.Int4    `json:"hdl"`
 	Triglycerides   pgtype.Int4    `json:"triglycerides"`
+	Mrn             pgtype.Text    `json:"mrn"`
+	HeightCm        pgtype.Numeric `json:"height_cm"`
+	WeightKg        pgtype.Numeric `json:"weight_kg"`
+	BmiSource       pgtype.Text    `json:"bmi_source"`
 }
 
 type CreatePatientRow struct {
@@ -65,6 +72,10 @@ type CreatePatientRow struct {
 	Ldl             pgtype.Int4        `json:"ldl"`
 	Hdl             pgtype.Int4        `json:"hdl"`
 	Triglycerides   pgtype.Int4        `json:"triglycerides"`
+	Mrn             pgtype.Text        `json:"mrn"`
+	HeightCm        pgtype.Numeric     `json:"height_cm"`
+	WeightKg        pgtype.Numeric     `json:"weight_kg"`
+	BmiSource       pgtype.Text        `json:"bmi_source"`
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
 }
@@ -89,6 +100,10 @@ func (q *Queries) CreatePatient(ctx context.Context, arg CreatePatientParams) (C
 		arg.Ldl,
 		arg.Hdl,
 		arg.Triglycerides,
+		arg.Mrn,
+		arg.HeightCm,
+		arg.WeightKg,
+		arg.BmiSource,
 	)
 	var i CreatePatientRow
 	err := row.Scan(
@@ -111,6 +126,10 @@ func (q *Queries) CreatePatient(ctx context.Context, arg CreatePatientParams) (C
 		&i.Ldl,
 		&i.Hdl,
 		&i.Triglycerides,
+		&i.Mrn,
+		&i.HeightCm,
+		&i.WeightKg,
+		&i.BmiSource,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -134,7 +153,8 @@ func (q *Queries) DeletePatient(ctx context.Context, arg DeletePatientParams) er
 
 const getPatient = `-- name: GetPatient :one
 SELECT id, user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
-       activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides,
+       activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides, mrn,
+       height_cm, weight_kg, bmi_source,
        created_at, updated_at
 FROM patients
 WHERE id = $1 AND user_id = $2
@@ -166,6 +186,10 @@ type GetPatientRow struct {
 	Ldl             pgtype.Int4        `json:"ldl"`
 	Hdl             pgtype.Int4        `json:"hdl"`
 	Triglycerides   pgtype.Int4        `json:"triglycerides"`
+	Mrn             pgtype.Text        `json:"mrn"`
+	HeightCm        pgtype.Numeric     `json:"height_cm"`
+	WeightKg        pgtype.Numeric     `json:"weight_kg"`
+	BmiSource       pgtype.Text        `json:"bmi_source"`
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
 }
@@ -193,6 +217,10 @@ func (q *Queries) GetPatient(ctx context.Context, arg GetPatientParams) (GetPati
 		&i.Ldl,
 		&i.Hdl,
 		&i.Triglycerides,
+		&i.Mrn,
+		&i.HeightCm,
+		&i.WeightKg,
+		&i.BmiSource,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -201,7 +229,8 @@ func (q *Queries) GetPatient(ctx context.Context, arg GetPatientParams) (GetPati
 
 const listPatients = `-- name: ListPatients :many
 SELECT id, user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
-       activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides,
+       activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides, mrn,
+       height_cm, weight_kg, bmi_source,
        created_at, updated_at
 FROM patients
 WHERE user_id = $1
@@ -228,6 +257,10 @@ type ListPatientsRow struct {
 	Ldl             pgtype.Int4        `json:"ldl"`
 	Hdl             pgtype.Int4        `json:"hdl"`
 	Triglycerides   pgtype.Int4        `json:"triglycerides"`
+	Mrn             pgtype.Text        `json:"mrn"`
+	HeightCm        pgtype.Numeric     `json:"height_cm"`
+	WeightKg        pgtype.Numeric     `json:"weight_kg"`
+	BmiSource       pgtype.Text        `json:"bmi_source"`
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
 }
@@ -262,6 +295,10 @@ func (q *Queries) ListPatients(ctx context.Context, userID int32) ([]ListPatient
 			&i.Ldl,
 			&i.Hdl,
 			&i.Triglycerides,
+			&i.Mrn,
+			&i.HeightCm,
+			&i.WeightKg,
+			&i.BmiSource,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -277,7 +314,8 @@ func (q *Queries) ListPatients(ctx context.Context, userID int32) ([]ListPatient
 
 const listPatientsLimited = `-- name: ListPatientsLimited :many
 SELECT id, user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
-       activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides,
+       activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides, mrn,
+       height_cm, weight_kg, bmi_source,
        created_at, updated_at
 FROM patients
 WHERE user_id = $1
@@ -310,6 +348,10 @@ type ListPatientsLimitedRow struct {
 	Ldl             pgtype.Int4        `json:"ldl"`
 	Hdl             pgtype.Int4        `json:"hdl"`
 	Triglycerides   pgtype.Int4        `json:"triglycerides"`
+	Mrn             pgtype.Text        `json:"mrn"`
+	HeightCm        pgtype.Numeric     `json:"height_cm"`
+	WeightKg        pgtype.Numeric     `json:"weight_kg"`
+	BmiSource       pgtype.Text        `json:"bmi_source"`
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
 }
@@ -343,6 +385,10 @@ func (q *Queries) ListPatientsLimited(ctx context.Context, arg ListPatientsLimit
 			&i.Ldl,
 			&i.Hdl,
 			&i.Triglycerides,
+			&i.Mrn,
+			&i.HeightCm,
+			&i.WeightKg,
+			&i.BmiSource,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -375,10 +421,15 @@ SET name = $3,
     ldl = $17,
     hdl = $18,
     triglycerides = $19,
+    mrn = $20,
+    height_cm = $21,
+    weight_kg = $22,
+    bmi_source = $23,
     updated_at = NOW()
 WHERE id = $1 AND user_id = $2
 RETURNING id, user_id, name, age, menopause_status, years_menopause, bmi, bp_systolic, bp_diastolic,
-          activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides,
+          activity, phys_activity, smoking, hypertension, heart_disease, family_history, chol, ldl, hdl, triglycerides, mrn,
+          height_cm, weight_kg, bmi_source,
           created_at, updated_at
 `
 
@@ -402,6 +453,10 @@ type UpdatePatientParams struct {
 	Ldl             pgtype.Int4    `json:"ldl"`
 	Hdl             pgtype.Int4    `json:"hdl"`
 	Triglycerides   pgtype.Int4    `json:"triglycerides"`
+	Mrn             pgtype.Text    `json:"mrn"`
+	HeightCm        pgtype.Numeric `json:"height_cm"`
+	WeightKg        pgtype.Numeric `json:"weight_kg"`
+	BmiSource       pgtype.Text    `json:"bmi_source"`
 }
 
 type UpdatePatientRow struct {
@@ -424,6 +479,10 @@ type UpdatePatientRow struct {
 	Ldl             pgtype.Int4        `json:"ldl"`
 	Hdl             pgtype.Int4        `json:"hdl"`
 	Triglycerides   pgtype.Int4        `json:"triglycerides"`
+	Mrn             pgtype.Text        `json:"mrn"`
+	HeightCm        pgtype.Numeric     `json:"height_cm"`
+	WeightKg        pgtype.Numeric     `json:"weight_kg"`
+	BmiSource       pgtype.Text        `json:"bmi_source"`
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
 }
@@ -449,6 +508,10 @@ func (q *Queries) UpdatePatient(ctx context.Context, arg UpdatePatientParams) (U
 		arg.Ldl,
 		arg.Hdl,
 		arg.Triglycerides,
+		arg.Mrn,
+		arg.HeightCm,
+		arg.WeightKg,
+		arg.BmiSource,
 	)
 	var i UpdatePatientRow
 	err := row.Scan(
@@ -471,6 +534,10 @@ func (q *Queries) UpdatePatient(ctx context.Context, arg UpdatePatientParams) (U
 		&i.Ldl,
 		&i.Hdl,
 		&i.Triglycerides,
+		&i.Mrn,
+		&i.HeightCm,
+		&i.WeightKg,
+		&i.BmiSource,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -48,6 +48,20 @@ func (q *Queries) DeleteExpiredTokens(ctx context.Context) error {
 	return err
 }
 
+const deleteRevokedTokens = `-- name: DeleteRevokedTokens :execrows
+DELETE FROM refresh_tokens
+WHERE revoked = TRUE
+AND revoked_at < $1
+`
+
+func (q *Queries) DeleteRevokedTokens(ctx context.Context, revokedAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRevokedTokens, revokedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const findRefreshToken = `-- name: FindRefreshToken :one
 SELECT id, user_id, token_hash, expires_at, revoked, created_at, revoked_at
 FROM refresh_tokens
@@ -85,6 +99,33 @@ func (q *Queries) RevokeAllUserTokens(ctx context.Context, userID int32) error {
 	return err
 }
 
+const revokeExcessUserTokens = `-- name: RevokeExcessUserTokens :execrows
+UPDATE refresh_tokens
+SET revoked = TRUE,
+    revoked_at = NOW()
+WHERE id IN (
+  SELECT id FROM refresh_tokens
+  WHERE user_id = $1
+  AND revoked = FALSE
+  AND expires_at > NOW()
+  ORDER BY created_at DESC, id DESC
+  OFFSET $2
+)
+`
+
+type RevokeExcessUserTokensParams struct {
+	UserID int32 `json:"user_id"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) RevokeExcessUserTokens(ctx context.Context, arg RevokeExcessUserTokensParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeExcessUserTokens, arg.UserID, arg.Offset)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked = TRUE,
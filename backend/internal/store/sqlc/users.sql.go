@@ -7,23 +7,42 @@ package sqlcgen
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const findUserByEmail = `-- name: FindUserByEmail :one
-SELECT id, email, password_hash, role, created_at, updated_at
+SELECT id, email, password_hash, role, is_active, email_verified_at, failed_login_attempts, locked_until, created_at, updated_at
 FROM users
 WHERE email = $1
 LIMIT 1
 `
 
-func (q *Queries) FindUserByEmail(ctx context.Context, email string) (User, error) {
+type FindUserByEmailRow struct {
+	ID                  int32              `json:"id"`
+	Email               string             `json:"email"`
+	PasswordHash        string             `json:"password_hash"`
+	Role                string             `json:"role"`
+	IsActive            bool               `json:"is_active"`
+	EmailVerifiedAt     pgtype.Timestamptz `json:"email_verified_at"`
+	FailedLoginAttempts int32              `json:"failed_login_attempts"`
+	LockedUntil         pgtype.Timestamptz `json:"locked_until"`
+	CreatedAt           pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt           pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) FindUserByEmail(ctx context.Context, email string) (FindUserByEmailRow, error) {
 	row := q.db.QueryRow(ctx, findUserByEmail, email)
-	var i User
+	var i FindUserByEmailRow
 	err := row.Scan(
 		&i.ID,
 		&i.Email,
 		&i.PasswordHash,
 		&i.Role,
+		&i.IsActive,
+		&i.EmailVerifiedAt,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -31,20 +50,37 @@ func (q *Queries) FindUserByEmail(ctx context.Context, email string) (User, erro
 }
 
 const findUserByID = `-- name: FindUserByID :one
-SELECT id, email, password_hash, role, created_at, updated_at
+SELECT id, email, password_hash, role, is_active, email_verified_at, failed_login_attempts, locked_until, created_at, updated_at
 FROM users
 WHERE id = $1
 LIMIT 1
 `
 
-func (q *Queries) FindUserByID(ctx context.Context, id int32) (User, error) {
+type FindUserByIDRow struct {
+	ID                  int32              `json:"id"`
+	Email               string             `json:"email"`
+	PasswordHash        string             `json:"password_hash"`
+	Role                string             `json:"role"`
+	IsActive            bool               `json:"is_active"`
+	EmailVerifiedAt     pgtype.Timestamptz `json:"email_verified_at"`
+	FailedLoginAttempts int32              `json:"failed_login_attempts"`
+	LockedUntil         pgtype.Timestamptz `json:"locked_until"`
+	CreatedAt           pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt           pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) FindUserByID(ctx context.Context, id int32) (FindUserByIDRow, error) {
 	row := q.db.QueryRow(ctx, findUserByID, id)
-	var i User
+	var i FindUserByIDRow
 	err := row.Scan(
 		&i.ID,
 		&i.Email,
 		&i.PasswordHash,
 		&i.Role,
+		&i.IsActive,
+		&i.EmailVerifiedAt,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/skufu/DianaV2/backend/internal/models"
@@ -16,6 +17,30 @@ type Store interface {
 	Clinics() ClinicRepository
 	AuditEvents() AuditEventRepository
 	ModelRuns() ModelRunRepository
+	RiskAlerts() RiskAlertRepository
+	EmailVerifications() EmailVerificationRepository
+	PasswordResets() PasswordResetRepository
+	PatientPhotos() PatientPhotoRepository
+	PatientContacts() PatientContactRepository
+	PatientNotes() PatientNoteRepository
+	PatientMedications() PatientMedicationRepository
+	FollowUps() FollowUpRepository
+	Tasks() TaskRepository
+	Tags() TagRepository
+	PatientListViews() PatientListViewRepository
+	AssessmentAttachments() AssessmentAttachmentRepository
+	APITokens() APITokenRepository
+	APIKeys() APIKeyRepository
+	MFA() MFARepository
+	BaselineDiscrepancies() BaselineDiscrepancyRepository
+	PatientHistory() PatientHistoryRepository
+	Experiments() ExperimentRepository
+	UserDeletions() UserDeletionRepository
+	PredictionCache() PredictionCacheRepository
+	Webhooks() WebhookRepository
+	AssessmentDrafts() AssessmentDraftRepository
+	Roles() RoleRepository
+	LoginHistory() LoginHistoryRepository
 	Close()
 }
 
@@ -29,36 +54,182 @@ type UserRepository interface {
 	Deactivate(ctx context.Context, id int32) error
 	Activate(ctx context.Context, id int32) error
 	UpdateLastLogin(ctx context.Context, id int32) error
+	// RecordFailedLogin counts a failed login. When the count reaches
+	// threshold the account is locked for lockout and the count restarts;
+	// the new lock expiry is returned, or nil if this failure did not lock.
+	RecordFailedLogin(ctx context.Context, id int32, threshold int, lockout time.Duration) (*time.Time, error)
+	// ResetFailedLogins clears the failure count after a successful login.
+	ResetFailedLogins(ctx context.Context, id int32) error
+	// Unlock lifts a lockout and clears the failure count.
+	Unlock(ctx context.Context, id int32) error
+	// Preferences returns the user's stored units, locale and date format;
+	// unset fields are empty. The default patient view is not filled in.
+	Preferences(ctx context.Context, id int32) (*models.UserPreferences, error)
+	// SavePreferences replaces the user's stored preferences.
+	SavePreferences(ctx context.Context, id int32, prefs models.UserPreferences) error
 }
 
 type PatientRepository interface {
 	List(ctx context.Context, userID int32) ([]models.Patient, error)
 	Get(ctx context.Context, id int32, userID int32) (*models.Patient, error)
 	Create(ctx context.Context, p models.Patient) (*models.Patient, error)
+	// Update saves p and, in the same transaction, records a PatientVersion
+	// when any field changed. p.UserID is the owner making the change.
 	Update(ctx context.Context, p models.Patient) (*models.Patient, error)
 	Delete(ctx context.Context, id int32, userID int32) error
 	ListAllLimited(ctx context.Context, userID int32, limit int) ([]models.Patient, error)
+	// EachLimited calls fn with up to limit of the user's patients, newest
+	// first, as they are read rather than after collecting them, and stops at
+	// the first error fn returns.
+	EachLimited(ctx context.Context, userID int32, limit int, fn func(models.Patient) error) error
+	// EachLimitedByClinic is EachLimited over the patients shared with the
+	// clinic, whoever owns them.
+	EachLimitedByClinic(ctx context.Context, clinicID int32, limit int, fn func(models.Patient) error) error
+	Typeahead(ctx context.Context, userID int32, q string, limit int) ([]models.PatientMatch, error)
+	ListWithLatestAssessmentPaginated(ctx context.Context, userID int32, params models.PatientListParams) ([]models.PatientWithLatest, int, error)
+	// ListWithLatestAssessmentAfter is the keyset form of
+	// ListWithLatestAssessmentPaginated: up to params.PageSize patients
+	// created before after, newest first, or from the newest when after is
+	// nil. Page, Sort and Order are ignored and no total is counted.
+	ListWithLatestAssessmentAfter(ctx context.Context, userID int32, params models.PatientListParams, after *models.ListCursor) ([]models.PatientWithLatest, error)
+	// ListVersion summarises every patient ListWithLatestAssessmentPaginated
+	// would match, ignoring paging, together with their latest assessments.
+	ListVersion(ctx context.Context, userID int32, params models.PatientListParams) (models.ListVersion, error)
+	// GetVisible is Get for read access: it also returns patients shared with
+	// one of the user's clinics, with ClinicID set.
+	GetVisible(ctx context.Context, id int32, userID int32) (*models.Patient, error)
+	// SetClinic shares the patient with clinicID, or makes it private when
+	// clinicID is nil, recording the change in the patient's history as
+	// changedBy. Returns pgx.ErrNoRows if ownerID does not own the patient.
+	SetClinic(ctx context.Context, id int64, ownerID int32, clinicID *int32, changedBy int32) error
+	// Owner returns the ID of the user the patient belongs to.
+	Owner(ctx context.Context, id int64) (int32, error)
+	// Transfer gives the patient and its undelivered risk alerts to
+	// toUserID, recording the change in the patient's history as changedBy.
+	// Returns pgx.ErrNoRows if fromUserID no longer owns the patient.
+	Transfer(ctx context.Context, id int64, fromUserID, toUserID, changedBy int32) error
+	// ListByMRN returns every patient with exactly this MRN, whoever owns
+	// them, for matching results from lab interfaces.
+	ListByMRN(ctx context.Context, mrn string) ([]models.Patient, error)
+	// Duplicates lists up to limit pairs of the user's patients that share
+	// an MRN, or whose names are at least minSimilarity alike with ages at
+	// most a year apart. Ages that were never recorded do not rule a pair
+	// out. Shared MRNs come first, then the closest names.
+	Duplicates(ctx context.Context, userID int32, minSimilarity float64, limit int) ([]models.PatientDuplicate, error)
+	// Merge moves the assessments, notes, attachments and everything else
+	// recorded against mergedID onto survivorID, then deletes mergedID, in
+	// one transaction. Both must belong to userID. Returns ErrForbidden when
+	// either is only shared with userID through a clinic, and ErrNotFound when
+	// userID cannot see it at all.
+	Merge(ctx context.Context, survivorID, mergedID int64, userID int32) (*models.PatientMerge, error)
 }
 
 type AssessmentRepository interface {
 	ListByPatient(ctx context.Context, patientID int64) ([]models.Assessment, error)
-	Get(ctx context.Context, id int32) (*models.Assessment, error)
+	// ListByPatientPage is a keyset page of ListByPatient
+	ListByPatientPage(ctx context.Context, patientID int64, params models.AssessmentPageParams) ([]models.Assessment, error)
+	// ListVersion summarises the assessments ListByPatient returns.
+	ListVersion(ctx context.Context, patientID int64) (models.ListVersion, error)
+	// Get, Update and Delete only reach assessments of patients visible to
+	// userID (owned or shared through a clinic for Get, owned otherwise) and
+	// return pgx.ErrNoRows for any other assessment.
+	Get(ctx context.Context, id int32, userID int32) (*models.Assessment, error)
 	Create(ctx context.Context, a models.Assessment) (*models.Assessment, error)
-	Update(ctx context.Context, a models.Assessment) (*models.Assessment, error)
-	Delete(ctx context.Context, id int32) error
+	// CreateBatch inserts all assessments in a single transaction; either every
+	// row is persisted or none are.
+	CreateBatch(ctx context.Context, items []models.Assessment) ([]models.Assessment, error)
+	Update(ctx context.Context, a models.Assessment, userID int32) (*models.Assessment, error)
+	Delete(ctx context.Context, id int32, userID int32) error
+	// UpdateDraft replaces the fields of a draft of a patient userID owns,
+	// leaving it a draft. Returns pgx.ErrNoRows if a is not such a draft.
+	UpdateDraft(ctx context.Context, a models.Assessment, userID int32) (*models.Assessment, error)
+	// Finalize stores a's fields, prediction and validation outcome on a
+	// draft of a patient userID owns and marks it final. Returns
+	// pgx.ErrNoRows if a is not such a draft, including one finalized
+	// concurrently.
+	Finalize(ctx context.Context, a models.Assessment, userID int32) (*models.Assessment, error)
+	// ClusterCounts and the other aggregate reads below count final
+	// assessments only; drafts are left out until they are finalized.
 	ClusterCounts(ctx context.Context) ([]models.ClusterAnalytics, error)
-	TrendAverages(ctx context.Context) ([]models.TrendPoint, error)
+	// ClusterCountsInScope is ClusterCounts over the assessments of patients
+	// within scope.
+	ClusterCountsInScope(ctx context.Context, scope models.CohortScope) ([]models.ClusterAnalytics, error)
+	TrendAverages(ctx context.Context, params models.TrendParams) ([]models.TrendPoint, error)
 	ListAllLimited(ctx context.Context, limit int) ([]models.Assessment, error)
 	ListAllLimitedByUser(ctx context.Context, userID int32, limit int) ([]models.Assessment, error)
+	// EachLimitedByUser is ListAllLimitedByUser calling fn with each final
+	// assessment as it is read, stopping at the first error fn returns.
+	EachLimitedByUser(ctx context.Context, userID int32, limit int, fn func(models.Assessment) error) error
+	// EachLimitedByClinic is EachLimitedByUser over the assessments of
+	// patients shared with the clinic.
+	EachLimitedByClinic(ctx context.Context, clinicID int32, limit int, fn func(models.Assessment) error) error
 	GetTrend(ctx context.Context, patientID int64) ([]models.AssessmentTrend, error)
+	// SetExplanation stores the SHAP explanation for an assessment; nil clears it.
+	SetExplanation(ctx context.Context, id int32, explanation map[string]interface{}) error
+	// GetExplanation returns the stored SHAP explanation, or nil if none exists.
+	GetExplanation(ctx context.Context, id int32) (map[string]interface{}, error)
+	// ListSince pages through final assessments created at or after since,
+	// ordered by id; pass the last seen id as afterID to fetch the next page.
+	ListSince(ctx context.Context, since time.Time, afterID int64, limit int) ([]models.Assessment, error)
+	SetValidationStatus(ctx context.Context, id int32, status string) error
+	SetQuality(ctx context.Context, id int32, q models.DataQuality) error
+	// QualityByClinician aggregates scored assessments per owning clinician,
+	// lowest average first, counting those scoring below lowBelow as low
+	// quality. A nil userID includes every clinician.
+	QualityByClinician(ctx context.Context, userID *int32, lowBelow int) ([]models.ClinicianDataQuality, error)
+	// Amend stores a as a new assessment amending originalID, leaving the
+	// original untouched. Returns ErrAlreadyAmended if originalID is not the
+	// latest version of its chain.
+	Amend(ctx context.Context, originalID int64, a models.Assessment) (*models.Assessment, error)
+	// AmendmentChain returns every version in id's amendment chain, oldest
+	// first, with AmendsAssessmentID set. An unamended assessment is a chain
+	// of one.
+	AmendmentChain(ctx context.Context, id int64) ([]models.Assessment, error)
+	// ListPendingPrediction returns up to limit assessments still waiting for
+	// an asynchronous prediction, oldest first.
+	ListPendingPrediction(ctx context.Context, limit int) ([]models.Assessment, error)
+	// CompletePrediction stores a's cluster, risk score, model version and
+	// dataset hash if the assessment is still pending a prediction. Returns
+	// pgx.ErrNoRows once it has been predicted, re-scored or deleted.
+	CompletePrediction(ctx context.Context, a models.Assessment) error
 }
 
+// ErrAlreadyAmended is returned when amending an assessment that already has
+// an amendment; only the latest version of a chain can be amended. It wraps
+// ErrConflict.
+var ErrAlreadyAmended = fmt.Errorf("%w: assessment already amended", ErrConflict)
+
 type RefreshTokenRepository interface {
 	CreateRefreshToken(ctx context.Context, tokenHash string, userID int32, expiresAt time.Time) (*models.RefreshToken, error)
 	FindRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
 	RevokeRefreshToken(ctx context.Context, tokenHash string) error
 	RevokeAllUserTokens(ctx context.Context, userID int32) error
 	DeleteExpiredTokens(ctx context.Context) error
+	// RevokeExcessUserTokens keeps the user's newest keep active sessions and
+	// revokes the rest, returning how many were revoked.
+	RevokeExcessUserTokens(ctx context.Context, userID int32, keep int) (int, error)
+	// DeleteRevokedTokens purges tokens revoked before the cutoff.
+	DeleteRevokedTokens(ctx context.Context, before time.Time) (int, error)
+	// LookupRefreshToken returns a token whatever its state (revoked or
+	// expired) so replays of rotated tokens can be detected.
+	LookupRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// RotateRefreshToken revokes the active token oldHash and issues newHash in
+	// the same family. Returns pgx.ErrNoRows if oldHash is no longer active.
+	RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*models.RefreshToken, error)
+	// RevokeTokenFamily revokes every active token in a family, returning how many.
+	RevokeTokenFamily(ctx context.Context, familyID string) (int, error)
+	// RecordSessionClient stores the client that signed in on a new token,
+	// returning the token.
+	RecordSessionClient(ctx context.Context, tokenHash, userAgent, ipAddress string) (*models.RefreshToken, error)
+	// ListUserSessions returns the user's active sessions, most recently
+	// active first.
+	ListUserSessions(ctx context.Context, userID int64) ([]models.Session, error)
+	// RevokeUserSession revokes one of the user's sessions, returning how
+	// many tokens were revoked; 0 if the user has no such active session.
+	RevokeUserSession(ctx context.Context, userID int64, familyID string) (int, error)
+	// RevokeOtherUserSessions revokes every active session of the user except
+	// keepFamilyID, returning how many tokens were revoked.
+	RevokeOtherUserSessions(ctx context.Context, userID int64, keepFamilyID string) (int, error)
 }
 
 type CohortRepository interface {
@@ -68,6 +239,15 @@ type CohortRepository interface {
 	StatsByMenopauseStatus(ctx context.Context) ([]models.CohortGroup, error)
 	TotalPatientCount(ctx context.Context) (int, error)
 	TotalAssessmentCount(ctx context.Context) (int, error)
+	// GroupSample returns metric moments for one group of a cohort dimension
+	// (cluster, risk_level, age_group, menopause_status). A group with no
+	// assessments yields a zero-count sample, not an error.
+	GroupSample(ctx context.Context, groupBy, name string, scope models.CohortScope) (*models.CohortSample, error)
+	// ScopedStats groups assessments within scope by a cohort dimension, with
+	// the same columns as StatsByCluster.
+	ScopedStats(ctx context.Context, groupBy string, scope models.CohortScope) ([]models.CohortGroup, error)
+	// ScopedTotals counts the patients and assessments within scope.
+	ScopedTotals(ctx context.Context, scope models.CohortScope) (patients, assessments int, err error)
 }
 
 type ClinicRepository interface {
@@ -76,9 +256,43 @@ type ClinicRepository interface {
 	Create(ctx context.Context, name, address string) (*models.Clinic, error)
 	ListUserClinics(ctx context.Context, userID int32) ([]models.UserClinic, error)
 	IsClinicAdmin(ctx context.Context, userID, clinicID int32) (bool, error)
+	// ClinicRole returns the user's role in the clinic, or pgx.ErrNoRows if
+	// they are not a member.
+	ClinicRole(ctx context.Context, userID, clinicID int32) (string, error)
 	ClinicAggregate(ctx context.Context, clinicID int32) (*models.ClinicAggregate, error)
-	AdminSystemStats(ctx context.Context) (*models.SystemStats, error)
-	AdminClinicComparison(ctx context.Context) ([]models.ClinicComparison, error)
+	// AdminSystemStats and AdminClinicComparison count only the assessments
+	// within r in their assessment totals, average and high-risk count.
+	AdminSystemStats(ctx context.Context, r models.StatsRange) (*models.SystemStats, error)
+	AdminClinicComparison(ctx context.Context, r models.StatsRange) ([]models.ClinicComparison, error)
+	GetValidationMode(ctx context.Context, clinicID int32) (string, error)
+	SetValidationMode(ctx context.Context, clinicID int32, mode string) error
+	// ValidationModeForUser resolves the effective mode across all of a user's
+	// clinics: strict if any clinic is strict, advisory otherwise.
+	ValidationModeForUser(ctx context.Context, userID int32) (string, error)
+	// ListMembers returns the clinic directory with per-member activity stats.
+	ListMembers(ctx context.Context, clinicID int32) ([]models.ClinicMember, error)
+	AddMember(ctx context.Context, clinicID, userID int32, role string) error
+	// RemoveMember and SetMemberRole return pgx.ErrNoRows if the user is not a member.
+	RemoveMember(ctx context.Context, clinicID, userID int32) error
+	SetMemberRole(ctx context.Context, clinicID, userID int32, role string) error
+	GetPatientPhotosEnabled(ctx context.Context, clinicID int32) (bool, error)
+	SetPatientPhotosEnabled(ctx context.Context, clinicID int32, enabled bool) error
+	// PatientPhotosEnabledForUser is false if any of the user's clinics has
+	// disabled patient photos.
+	PatientPhotosEnabledForUser(ctx context.Context, userID int32) (bool, error)
+	GetBaselinePolicy(ctx context.Context, clinicID int32) (string, error)
+	SetBaselinePolicy(ctx context.Context, clinicID int32, policy string) error
+	// BaselinePolicyForUser is update only if every one of the user's clinics
+	// uses update; flag otherwise, including for users without a clinic.
+	BaselinePolicyForUser(ctx context.Context, userID int32) (string, error)
+	// GetReportSettings returns pgx.ErrNoRows if the clinic does not exist.
+	GetReportSettings(ctx context.Context, clinicID int32) (*models.ClinicReportSettings, error)
+	SetReportLocale(ctx context.Context, clinicID int32, locale string) error
+	// SetReportLogo replaces the clinic's logo; a nil logo removes it.
+	SetReportLogo(ctx context.Context, clinicID int32, logo []byte) error
+	// SharedClinics returns the IDs of the clinics that every one of userIDs
+	// belongs to, in ascending order.
+	SharedClinics(ctx context.Context, userIDs ...int32) ([]int32, error)
 }
 
 // AuditEventRepository provides access to audit logs for admin transparency
@@ -93,5 +307,361 @@ type ModelRunRepository interface {
 	GetActive(ctx context.Context) (*models.ModelRun, error)
 	Create(ctx context.Context, run models.ModelRun) (*models.ModelRun, error)
 	SetActive(ctx context.Context, id int32) error
+	// Get returns pgx.ErrNoRows if the run does not exist
+	Get(ctx context.Context, id int32) (*models.ModelRun, error)
+	// SavePrediction stores an assessment's re-score by a run, replacing an
+	// earlier one by the same run
+	SavePrediction(ctx context.Context, p models.AssessmentPrediction) error
+	// ListPredictions pages through a run's re-scores by assessment id
+	ListPredictions(ctx context.Context, runID int32, limit, offset int) ([]models.AssessmentPrediction, int, error)
+	// Distribution summarizes the predictions stamped with a model version
+	// and dataset hash on assessments created since the given time
+	Distribution(ctx context.Context, version, datasetHash string, since time.Time) (models.PredictionDistribution, error)
+}
+
+
+// RiskAlertRepository queues risk alerts raised on assessment creation
+type RiskAlertRepository interface {
+	Enqueue(ctx context.Context, alert models.RiskAlert) (*models.RiskAlert, error)
+	// LastForPatient returns the most recent alert for a patient, or nil if none.
+	LastForPatient(ctx context.Context, patientID int64) (*models.RiskAlert, error)
+}
+
+// WebhookRepository manages outgoing webhooks and the outbox of their
+// deliveries. Get, Update and Delete return pgx.ErrNoRows for an unknown id;
+// List and Get leave Secret empty.
+type WebhookRepository interface {
+	List(ctx context.Context) ([]models.Webhook, error)
+	Get(ctx context.Context, id int64) (*models.Webhook, error)
+	Create(ctx context.Context, w models.Webhook) (*models.Webhook, error)
+	// Update changes the URL, description, events and active flag
+	Update(ctx context.Context, w models.Webhook) (*models.Webhook, error)
+	Delete(ctx context.Context, id int64) error
+	// Enqueue queues payload for every active webhook subscribed to event
+	// and returns how many deliveries were queued
+	Enqueue(ctx context.Context, event string, payload []byte) (int, error)
+	// ClaimDue returns up to limit pending deliveries that are due, with
+	// their webhook's URL and secret, and moves their next attempt lease
+	// ahead so other replicas skip them while they are being sent
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	// RecordAttempt stores the outcome of sending d: its Status, Attempts,
+	// NextAttemptAt, LastError and LastStatusCode
+	RecordAttempt(ctx context.Context, d models.WebhookDelivery) error
+	// ListDeliveries returns a webhook's most recent deliveries, newest first
+	ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]models.WebhookDelivery, error)
+}
+
+// AssessmentDraftRepository stores lab results waiting to be completed into
+// assessments. Discard and Complete only reach pending drafts of patientID
+// and return pgx.ErrNoRows for any other draft.
+type AssessmentDraftRepository interface {
+	// Create returns ErrConflict if the message already created a draft for
+	// the patient
+	Create(ctx context.Context, d models.AssessmentDraft) (*models.AssessmentDraft, error)
+	// ListPending returns the patient's pending drafts, newest first
+	ListPending(ctx context.Context, patientID int64) ([]models.AssessmentDraft, error)
+	Discard(ctx context.Context, id, patientID int64) error
+	// Complete marks the draft as used by assessmentID
+	Complete(ctx context.Context, id, patientID, assessmentID int64) error
+}
+
+// EmailVerificationRepository manages self-registration and its single-use
+// email verification tokens. Tokens are stored hashed.
+type EmailVerificationRepository interface {
+	// Register creates an unverified user together with its first token.
+	Register(ctx context.Context, user models.User, tokenHash string, expiresAt time.Time) (*models.User, error)
+	CreateToken(ctx context.Context, userID int32, tokenHash string, expiresAt time.Time) error
+	// Verify consumes an unused, unexpired token and marks its user verified.
+	// Returns pgx.ErrNoRows if the token is unknown, used or expired.
+	Verify(ctx context.Context, tokenHash string) (int32, error)
+}
+
+// PasswordResetRepository manages single-use password reset tokens (stored hashed).
+type PasswordResetRepository interface {
+	CreateToken(ctx context.Context, userID int32, tokenHash string, expiresAt time.Time) error
+	// Reset consumes an unused, unexpired token, sets the user's password hash
+	// and revokes all of the user's refresh tokens. Returns pgx.ErrNoRows if
+	// the token is unknown, used or expired.
+	Reset(ctx context.Context, tokenHash, passwordHash string) (int32, error)
+}
+
+// PatientPhotoRepository stores patient photo metadata; image bytes are kept
+// in object storage.
+type PatientPhotoRepository interface {
+	// Get returns the patient's photo metadata, or nil if none exists.
+	Get(ctx context.Context, patientID int64) (*models.PatientPhoto, error)
+	// Put saves the photo, returning the replaced photo (nil if there was none)
+	// so its stored object can be removed.
+	Put(ctx context.Context, photo models.PatientPhoto) (*models.PatientPhoto, error)
+	// Delete removes and returns the photo metadata, or nil if none existed.
+	Delete(ctx context.Context, patientID int64) (*models.PatientPhoto, error)
+}
+
+// AssessmentAttachmentRepository stores assessment attachment metadata;
+// file bytes are kept in object storage. Get and Delete only reach
+// attachments of assessmentID and return pgx.ErrNoRows for any other.
+type AssessmentAttachmentRepository interface {
+	Create(ctx context.Context, a models.AssessmentAttachment) (*models.AssessmentAttachment, error)
+	// List returns the assessment's attachments, oldest first
+	List(ctx context.Context, assessmentID int64) ([]models.AssessmentAttachment, error)
+	// ListByPatient returns the attachments of all the patient's assessments
+	ListByPatient(ctx context.Context, patientID int64) ([]models.AssessmentAttachment, error)
+	Get(ctx context.Context, id, assessmentID int64) (*models.AssessmentAttachment, error)
+	// GetByID looks an attachment up by id alone, for signed downloads
+	GetByID(ctx context.Context, id int64) (*models.AssessmentAttachment, error)
+	Delete(ctx context.Context, id, assessmentID int64) error
+}
+
+// PatientContactRepository stores patient contact details and contact consent.
+type PatientContactRepository interface {
+	// Get returns the patient's contact details, or nil if none are stored.
+	Get(ctx context.Context, patientID int64) (*models.PatientContact, error)
+	// Put replaces the contact details. ConsentAt is stamped when consent is
+	// given, kept while it stays given and cleared when it is withdrawn.
+	Put(ctx context.Context, contact models.PatientContact) (*models.PatientContact, error)
+}
+
+// PatientNoteRepository stores clinicians' notes on patients. Get, Update
+// and Delete only reach notes of patientID and return pgx.ErrNoRows for any
+// other note.
+type PatientNoteRepository interface {
+	// List returns the patient's notes, pinned first, then newest first
+	List(ctx context.Context, patientID int64) ([]models.PatientNote, error)
+	Get(ctx context.Context, id, patientID int64) (*models.PatientNote, error)
+	Create(ctx context.Context, note models.PatientNote) (*models.PatientNote, error)
+	// Update replaces the note's category, body and pinned flag
+	Update(ctx context.Context, note models.PatientNote) (*models.PatientNote, error)
+	Delete(ctx context.Context, id, patientID int64) error
+}
+
+// PatientMedicationRepository stores the medications patients take. Get,
+// Update and Delete only reach medications of patientID and return
+// pgx.ErrNoRows for any other.
+type PatientMedicationRepository interface {
+	// List returns the patient's medications, most recently started first
+	List(ctx context.Context, patientID int64) ([]models.PatientMedication, error)
+	Get(ctx context.Context, id, patientID int64) (*models.PatientMedication, error)
+	Create(ctx context.Context, m models.PatientMedication) (*models.PatientMedication, error)
+	// Update replaces the medication's name, dose, dates and adherence
+	Update(ctx context.Context, m models.PatientMedication) (*models.PatientMedication, error)
+	Delete(ctx context.Context, id, patientID int64) error
+}
+
+// FollowUpRepository stores follow-up visits. UserID and PatientName are
+// filled from the patient. Get, Update and Delete only reach follow-ups of
+// patientID and return pgx.ErrNoRows for any other.
+type FollowUpRepository interface {
+	// List returns the patient's follow-ups, soonest due first
+	List(ctx context.Context, patientID int64) ([]models.FollowUp, error)
+	// ListOpen returns the incomplete follow-ups of the patients userID owns,
+	// soonest due first. due narrows them to those due before now
+	// (models.FollowUpsOverdue) or from now on (models.FollowUpsUpcoming).
+	ListOpen(ctx context.Context, userID int64, due string, now time.Time) ([]models.FollowUp, error)
+	Get(ctx context.Context, id, patientID int64) (*models.FollowUp, error)
+	Create(ctx context.Context, f models.FollowUp) (*models.FollowUp, error)
+	// Update replaces the due date, reason and completion. Moving the due
+	// date clears RemindedAt, so the new date is reminded of again.
+	Update(ctx context.Context, f models.FollowUp) (*models.FollowUp, error)
+	Delete(ctx context.Context, id, patientID int64) error
+	// ClaimReminders marks up to limit incomplete, unreminded follow-ups due
+	// before before as reminded at now and returns them, soonest due first.
+	ClaimReminders(ctx context.Context, before, now time.Time, limit int) ([]models.FollowUp, error)
 }
 
+// TaskRepository stores clinicians' worklists. PatientName is filled from
+// the patient. Get, Update and Delete only reach tasks of userID and return
+// pgx.ErrNoRows for any other.
+type TaskRepository interface {
+	// List returns the user's tasks, soonest due first, then newest first.
+	// Tasks without a due date come after those with one.
+	List(ctx context.Context, userID int64, params models.TaskListParams) ([]models.Task, error)
+	Get(ctx context.Context, id, userID int64) (*models.Task, error)
+	Create(ctx context.Context, t models.Task) (*models.Task, error)
+	// Update replaces the task's title, status, due date and ClosedAt
+	Update(ctx context.Context, t models.Task) (*models.Task, error)
+	Delete(ctx context.Context, id, userID int64) error
+	// OpenOverdueAssessments opens an overdue_assessment task, due now, for
+	// the owner of every patient last assessed (or, never assessed, created)
+	// before before that has no active one. Returns how many were opened.
+	OpenOverdueAssessments(ctx context.Context, before, now time.Time) (int, error)
+	// CloseForPatient marks the patient's active tasks of kind done at now.
+	// Returns how many were closed.
+	CloseForPatient(ctx context.Context, patientID int64, kind string, now time.Time) (int, error)
+}
+
+// TagRepository stores clinicians' tags and which patients carry them.
+// Get, Rename and Delete only reach tags of userID and return pgx.ErrNoRows
+// for any other. Names are unique per user regardless of case; a clash
+// returns ErrConflict.
+type TagRepository interface {
+	// List returns the user's tags by name, with how many patients carry each
+	List(ctx context.Context, userID int64) ([]models.Tag, error)
+	Get(ctx context.Context, id, userID int64) (*models.Tag, error)
+	Create(ctx context.Context, tag models.Tag) (*models.Tag, error)
+	Rename(ctx context.Context, id, userID int64, name string) (*models.Tag, error)
+	Delete(ctx context.Context, id, userID int64) error
+	// ListForPatient returns the user's tags the patient carries, by name
+	ListForPatient(ctx context.Context, patientID, userID int64) ([]models.Tag, error)
+	// Attach tags the patient; tagging a patient twice is not an error
+	Attach(ctx context.Context, tagID, patientID int64) error
+	// Detach untags the patient, returning pgx.ErrNoRows if it was not tagged
+	Detach(ctx context.Context, tagID, patientID int64) error
+}
+
+// PatientListViewRepository stores users' saved patient list views. Get,
+// Update and Delete only reach views of userID and return pgx.ErrNoRows for
+// any other. Names are unique per user regardless of case; a clash returns
+// ErrConflict.
+type PatientListViewRepository interface {
+	// List returns the user's views by name
+	List(ctx context.Context, userID int64) ([]models.PatientListView, error)
+	Get(ctx context.Context, id, userID int64) (*models.PatientListView, error)
+	// Default returns the user's default view, or pgx.ErrNoRows if none is
+	Default(ctx context.Context, userID int64) (*models.PatientListView, error)
+	// Create and Update make the view the user's only default when
+	// IsDefault is set
+	Create(ctx context.Context, v models.PatientListView) (*models.PatientListView, error)
+	// Update replaces the view's name, filters and default flag
+	Update(ctx context.Context, v models.PatientListView) (*models.PatientListView, error)
+	Delete(ctx context.Context, id, userID int64) error
+}
+
+// APITokenRepository manages scoped API tokens. Tokens are stored hashed.
+type APITokenRepository interface {
+	Create(ctx context.Context, token models.APIToken) (*models.APIToken, error)
+	// List returns all tokens, newest first, including revoked ones.
+	List(ctx context.Context) ([]models.APIToken, error)
+	// Revoke revokes an active token. Returns pgx.ErrNoRows if the token is
+	// unknown or already revoked.
+	Revoke(ctx context.Context, id int64) (*models.APIToken, error)
+	// FindActive returns the unrevoked, unexpired token with the given hash,
+	// or pgx.ErrNoRows.
+	FindActive(ctx context.Context, tokenHash string) (*models.APIToken, error)
+	// MarkUsed records that the token was just used.
+	MarkUsed(ctx context.Context, id int64) error
+}
+
+// MFARepository stores two-factor enrollments, backup codes and the roles
+// that must enroll. Backup codes are stored hashed.
+type MFARepository interface {
+	// Get returns the user's enrollment, pending or enabled, or pgx.ErrNoRows.
+	Get(ctx context.Context, userID int64) (*models.MFAEnrollment, error)
+	// Begin stores a secret awaiting confirmation, replacing an earlier
+	// pending one. Returns ErrConflict if MFA is already enabled.
+	Begin(ctx context.Context, userID int64, secret string) error
+	// Enable confirms the pending secret, records step as used and replaces
+	// the backup codes. Returns pgx.ErrNoRows if nothing is pending.
+	Enable(ctx context.Context, userID int64, step int64, codeHashes []string) error
+	// UseStep accepts step if it is after the last accepted one, so a code
+	// cannot be replayed. Reports whether it was accepted.
+	UseStep(ctx context.Context, userID int64, step int64) (bool, error)
+	// UseBackupCode spends an unused backup code. Reports whether one matched.
+	UseBackupCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+	// ReplaceBackupCodes discards the user's backup codes for new ones.
+	ReplaceBackupCodes(ctx context.Context, userID int64, codeHashes []string) error
+	// Disable removes the enrollment and backup codes. Returns pgx.ErrNoRows
+	// if the user had none.
+	Disable(ctx context.Context, userID int64) error
+	// RequiredRoles lists the roles that must enroll.
+	RequiredRoles(ctx context.Context) ([]string, error)
+	// SetRequiredRoles replaces the roles that must enroll.
+	SetRequiredRoles(ctx context.Context, roles []string, updatedBy int64) error
+}
+
+// RoleRepository stores the permissions each role grants.
+type RoleRepository interface {
+	// List returns every role in models.RoleScopes with its permissions,
+	// sorted by name.
+	List(ctx context.Context) ([]models.Role, error)
+	// HasPermission reports whether role grants permission.
+	HasPermission(ctx context.Context, role, permission string) (bool, error)
+	// SetPermissions replaces the permissions role grants.
+	SetPermissions(ctx context.Context, role string, permissions []string, updatedBy int64) error
+}
+
+// LoginHistoryRepository records sign-in attempts. Lists are newest first.
+type LoginHistoryRepository interface {
+	Record(ctx context.Context, a models.LoginAttempt) error
+	List(ctx context.Context, params models.LoginHistoryParams) ([]models.LoginAttempt, error)
+	// SuspiciousIPs returns the addresses with at least minFailures failed
+	// attempts since, most failures first.
+	SuspiciousIPs(ctx context.Context, since time.Time, minFailures int) ([]models.SuspiciousIP, error)
+}
+
+// APIKeyRepository manages user-bound API keys. Keys are stored hashed.
+type APIKeyRepository interface {
+	Create(ctx context.Context, key models.APIKey) (*models.APIKey, error)
+	// List returns keys newest first, including revoked ones; userID 0
+	// lists every user's keys.
+	List(ctx context.Context, userID int64) ([]models.APIKey, error)
+	// Revoke revokes an active key. Returns pgx.ErrNoRows if the key is
+	// unknown or already revoked.
+	Revoke(ctx context.Context, id int64) (*models.APIKey, error)
+	// FindActive returns the unrevoked, unexpired key with the given hash,
+	// or pgx.ErrNoRows.
+	FindActive(ctx context.Context, keyHash string) (*models.APIKey, error)
+	// MarkUsed records that the key was just used.
+	MarkUsed(ctx context.Context, id int64) error
+}
+
+// BaselineDiscrepancyRepository stores disagreements between assessments and
+// patient baselines that are waiting for review.
+type BaselineDiscrepancyRepository interface {
+	Create(ctx context.Context, items []models.BaselineDiscrepancy) ([]models.BaselineDiscrepancy, error)
+	// ListOpen returns the patient's unresolved discrepancies, oldest first.
+	ListOpen(ctx context.Context, patientID int64) ([]models.BaselineDiscrepancy, error)
+	// Resolve closes an open discrepancy of the patient. Returns
+	// pgx.ErrNoRows if it is unknown, belongs to another patient or is
+	// already resolved.
+	Resolve(ctx context.Context, id, patientID int64, resolution string, userID int64) (*models.BaselineDiscrepancy, error)
+}
+
+// PatientHistoryRepository reads the versions PatientRepository.Update
+// records for every change to a patient.
+type PatientHistoryRepository interface {
+	// List returns the patient's versions, newest first.
+	List(ctx context.Context, patientID int64) ([]models.PatientVersion, error)
+}
+
+// ExperimentRepository stores which variant of a soft-launched experiment
+// each assessment was shown, and derives outcomes from later assessments.
+type ExperimentRepository interface {
+	// RecordExposure stores e unless its assessment was already exposed to
+	// the experiment; the first exposure wins.
+	RecordExposure(ctx context.Context, e models.ExperimentExposure) error
+	// Results summarizes each variant of experiment. Exposures younger than
+	// followUp are counted but not yet eligible for the outcome.
+	Results(ctx context.Context, experiment string, followUp time.Duration) ([]models.ExperimentVariantResult, error)
+}
+
+// UserDeletionRepository schedules and carries out the removal of
+// deactivated users' data.
+type UserDeletionRepository interface {
+	// Schedule records a deletion due at dueAt, or returns the one already
+	// pending for the user.
+	Schedule(ctx context.Context, userID int64, requestedBy string, dueAt time.Time) (*models.UserDeletion, error)
+	// List returns deletions with status, newest first; "" lists all.
+	List(ctx context.Context, status string) ([]models.UserDeletion, error)
+	// Cancel cancels a pending deletion. Returns pgx.ErrNoRows if it is not pending.
+	Cancel(ctx context.Context, id int64, by string) (*models.UserDeletion, error)
+	// CancelForUser cancels the user's pending deletion; nil if there is none.
+	CancelForUser(ctx context.Context, userID int64, by string) (*models.UserDeletion, error)
+	// Due returns pending deletions whose grace period ended by now.
+	Due(ctx context.Context, now time.Time) ([]models.UserDeletion, error)
+	// Purge anonymizes or deletes the user and the patients they own and
+	// completes the deletion, in one transaction. A user reactivated in the
+	// meantime is left alone: the deletion is cancelled and nil returned.
+	// While the user still belongs to a clinic or owns a patient shared with
+	// one, nothing is purged and ErrClinicPatients is returned; the deletion
+	// stays pending until those patients are transferred.
+	Purge(ctx context.Context, d models.UserDeletion, mode, by string) (*models.UserPurge, error)
+}
+
+// PredictionCacheRepository persists cached predictions so they survive
+// restarts and are shared between replicas.
+type PredictionCacheRepository interface {
+	// Get returns the prediction stored under key, or nil if there is none.
+	Get(ctx context.Context, key string) (*models.CachedPrediction, error)
+	// Put stores p under key, replacing any earlier entry.
+	Put(ctx context.Context, key string, p models.CachedPrediction) error
+}
@@ -0,0 +1,120 @@
+// Package tracing exports OpenTelemetry spans over OTLP/HTTP, so one slow
+// request can be followed from the API through its queries to the model
+// service. Without an endpoint the global tracer provider stays a no-op and
+// instrumentation costs next to nothing.
+package tracing
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName names the API in traces
+const ServiceName = "diana-api"
+
+// Options configure Init
+type Options struct {
+	// Endpoint is the collector's base URL; spans go to <Endpoint>/v1/traces
+	Endpoint string
+	// SampleRatio is the fraction of new traces recorded
+	SampleRatio float64
+	// Env is recorded as deployment.environment
+	Env string
+}
+
+// Init installs the W3C trace context propagator and, when an endpoint is
+// set, a tracer provider exporting to it. The returned function flushes
+// and stops the exporter.
+func Init(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(strings.TrimRight(opts.Endpoint, "/")+"/v1/traces"))
+	if err != nil {
+		return nil, err
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("service.name", ServiceName),
+		attribute.String("deployment.environment", opts.Env),
+	}
+	if version := os.Getenv("APP_VERSION"); version != "" {
+		attrs = append(attrs, attribute.String("service.version", version))
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attrs...)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// QueryTracer wraps next (which may be nil) in a pgx query tracer that
+// records a span per query. Arguments are not recorded, since they can hold
+// patient data; the SQL itself uses placeholders.
+func QueryTracer(next pgx.QueryTracer) pgx.QueryTracer {
+	return &queryTracer{next: next}
+}
+
+type queryTracer struct {
+	next pgx.QueryTracer
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = otel.Tracer("github.com/skufu/DianaV2/backend/internal/tracing").Start(ctx, "db "+operation(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", data.SQL),
+		))
+	if t.next != nil {
+		ctx = t.next.TraceQueryStart(ctx, conn, data)
+	}
+	return ctx
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	if t.next != nil {
+		t.next.TraceQueryEnd(ctx, conn, data)
+	}
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	} else {
+		span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	}
+	span.End()
+}
+
+// operation names a query for its span: the sqlc query name when sql has
+// one, otherwise its first keyword, e.g. SELECT
+func operation(sql string) string {
+	for _, line := range strings.Split(sql, "\n") {
+		line = strings.TrimSpace(line)
+		if name, ok := strings.CutPrefix(line, "-- name: "); ok {
+			if fields := strings.Fields(name); len(fields) > 0 {
+				return fields[0]
+			}
+		}
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		return strings.ToUpper(strings.Fields(line)[0])
+	}
+	return "query"
+}
@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOperation(t *testing.T) {
+	cases := map[string]string{
+		"select id from users":                       "SELECT",
+		"-- name: GetUser :one\nSELECT * FROM users": "GetUser",
+		"\n  -- note\n  UPDATE users SET name = $1":  "UPDATE",
+		"": "query",
+	}
+	for sql, want := range cases {
+		if got := operation(sql); got != want {
+			t.Errorf("operation(%q) = %q, want %q", sql, got, want)
+		}
+	}
+}
+
+type recordingTracer struct{ started, ended int }
+
+func (r *recordingTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	r.started++
+	return ctx
+}
+
+func (r *recordingTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {
+	r.ended++
+}
+
+func TestQueryTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	next := &recordingTracer{}
+	tracer := QueryTracer(next)
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1", Args: []any{"doc@example.com"}})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1")})
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "DELETE FROM users"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("boom")})
+
+	if next.started != 2 || next.ended != 2 {
+		t.Fatalf("expected the wrapped tracer to run, got %+v", next)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "db SELECT" || spans[1].Name() != "db DELETE" {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+	for _, kv := range spans[0].Attributes() {
+		if kv.Value.AsString() == "doc@example.com" {
+			t.Fatal("query arguments must not be recorded")
+		}
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("expected a failed query to mark its span, got %+v", spans[1].Status())
+	}
+}
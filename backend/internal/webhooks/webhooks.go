@@ -0,0 +1,242 @@
+// Package webhooks notifies external systems, such as EHRs, of DIANA events.
+// Events are written to an outbox (webhook_deliveries) when they happen and
+// a background dispatcher POSTs them, retrying with backoff until the
+// receiver answers 2xx. Delivery is at least once: receivers should ignore
+// a repeated X-Diana-Delivery id.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+	"github.com/skufu/DianaV2/backend/internal/worker"
+)
+
+const (
+	pollInterval = 5 * time.Second
+	batchSize    = 50
+	// lease keeps a claimed delivery from other replicas while it is sent;
+	// a replica that dies mid-send leaves it to be retried after this
+	lease = time.Minute
+	// MaxAttempts is how many times a delivery is tried before it is
+	// marked failed
+	MaxAttempts  = 8
+	firstBackoff = 30 * time.Second
+	maxBackoff   = 6 * time.Hour
+)
+
+// Request headers sent with every delivery
+const (
+	SignatureHeader = "X-Diana-Signature"
+	EventHeader     = "X-Diana-Event"
+	DeliveryHeader  = "X-Diana-Delivery"
+)
+
+// envelope is the body POSTed to receivers
+type envelope struct {
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Payloads carry identifiers and scores only; receivers fetch anything else
+// through the API.
+type assessmentData struct {
+	AssessmentID int64     `json:"assessment_id"`
+	PatientID    int64     `json:"patient_id"`
+	Cluster      string    `json:"cluster"`
+	RiskScore    int       `json:"risk_score"`
+	ModelVersion string    `json:"model_version,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type patientData struct {
+	PatientID int64  `json:"patient_id"`
+	UserID    int64  `json:"user_id"`
+	ClinicID  *int64 `json:"clinic_id,omitempty"`
+	MRN       string `json:"mrn,omitempty"`
+}
+
+type highRiskData struct {
+	AssessmentID int64    `json:"assessment_id"`
+	PatientID    int64    `json:"patient_id"`
+	RiskScore    int      `json:"risk_score"`
+	Reasons      []string `json:"reasons"`
+}
+
+// followUpData leaves out the reason, which is free text
+type followUpData struct {
+	FollowUpID int64     `json:"follow_up_id"`
+	PatientID  int64     `json:"patient_id"`
+	UserID     int64     `json:"user_id"`
+	DueAt      time.Time `json:"due_at"`
+}
+
+// Dispatcher queues and sends webhook deliveries.
+type Dispatcher struct {
+	repo   store.WebhookRepository
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewDispatcher(repo store.WebhookRepository) *Dispatcher {
+	return &Dispatcher{
+		repo:   repo,
+		client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+	}
+}
+
+// Subscribe queues deliveries for the events on bus that webhooks can
+// subscribe to.
+func (d *Dispatcher) Subscribe(bus *events.Bus) {
+	events.Subscribe(bus, "webhooks", func(ctx context.Context, e events.AssessmentCreated) error {
+		a := e.Assessment
+		return d.enqueue(ctx, models.WebhookAssessmentCreated, assessmentData{
+			AssessmentID: a.ID,
+			PatientID:    a.PatientID,
+			Cluster:      a.Cluster,
+			RiskScore:    a.RiskScore,
+			ModelVersion: a.ModelVersion,
+			CreatedAt:    a.CreatedAt,
+		})
+	})
+	events.Subscribe(bus, "webhooks", func(ctx context.Context, e events.PatientCreated) error {
+		return d.enqueue(ctx, models.WebhookPatientCreated, patientData{
+			PatientID: e.Patient.ID,
+			UserID:    e.Patient.UserID,
+			ClinicID:  e.Patient.ClinicID,
+			MRN:       e.Patient.MRN,
+		})
+	})
+	events.Subscribe(bus, "webhooks", func(ctx context.Context, e events.RiskAlertRaised) error {
+		return d.enqueue(ctx, models.WebhookHighRisk, highRiskData{
+			AssessmentID: e.Alert.AssessmentID,
+			PatientID:    e.Alert.PatientID,
+			RiskScore:    e.Alert.RiskScore,
+			Reasons:      e.Alert.Reasons,
+		})
+	})
+	events.Subscribe(bus, "webhooks", func(ctx context.Context, e events.FollowUpDue) error {
+		return d.enqueue(ctx, models.WebhookFollowUpDue, followUpData{
+			FollowUpID: e.FollowUp.ID,
+			PatientID:  e.FollowUp.PatientID,
+			UserID:     e.FollowUp.UserID,
+			DueAt:      e.FollowUp.DueAt,
+		})
+	})
+}
+
+// Start sends due deliveries every few seconds under m.
+func (d *Dispatcher) Start(m *worker.Manager) {
+	m.Every("webhooks", pollInterval, true, d.DeliverDue)
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, event string, data interface{}) error {
+	body, err := json.Marshal(envelope{Event: event, OccurredAt: d.now().UTC(), Data: data})
+	if err != nil {
+		return err
+	}
+	_, err = d.repo.Enqueue(ctx, event, body)
+	return err
+}
+
+// DeliverDue sends every delivery that is due and records each outcome.
+func (d *Dispatcher) DeliverDue(ctx context.Context) error {
+	for {
+		due, err := d.repo.ClaimDue(ctx, batchSize, lease)
+		if err != nil {
+			return err
+		}
+		for _, delivery := range due {
+			if err := ctx.Err(); err != nil {
+				// Left claimed; retried once the lease runs out
+				return err
+			}
+			d.attempt(ctx, delivery)
+		}
+		if len(due) < batchSize {
+			return nil
+		}
+	}
+}
+
+// attempt sends one delivery and schedules a retry if it fails.
+func (d *Dispatcher) attempt(ctx context.Context, delivery models.WebhookDelivery) {
+	status, err := d.send(ctx, delivery)
+	delivery.Attempts++
+	delivery.LastStatusCode = status
+	delivery.LastError = ""
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliveryDelivered
+	case delivery.Attempts >= MaxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = err.Error()
+		logging.Ctx(ctx).Error().Err(err).Msgf("Webhook delivery %d to webhook %d failed for good", delivery.ID, delivery.WebhookID)
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = d.now().Add(Backoff(delivery.Attempts))
+	}
+	if err := d.repo.RecordAttempt(context.WithoutCancel(ctx), delivery); err != nil {
+		logging.Ctx(ctx).Error().Err(err).Msgf("Failed to record webhook delivery %d", delivery.ID)
+	}
+}
+
+// send POSTs a delivery and returns the receiver's status code, 0 if none
+func (d *Dispatcher) send(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, d.now().Unix(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header for body sent at timestamp:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>" keyed by secret>".
+// Receivers recompute it and should reject old timestamps to stop replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	t := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is the wait before the attempt after the given number of failed
+// attempts: 30s doubling each time, at most 6h.
+func Backoff(attempts int) time.Duration {
+	wait := firstBackoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxBackoff)
+}
@@ -0,0 +1,139 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skufu/DianaV2/backend/internal/events"
+	"github.com/skufu/DianaV2/backend/internal/models"
+	"github.com/skufu/DianaV2/backend/internal/store"
+)
+
+func TestDispatcher_DeliversSignedEvents(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	var got []received
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, received{r.Header.Clone(), body})
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	repo := store.NewMemoryStore().Webhooks()
+	hook, _ := repo.Create(ctx, models.Webhook{URL: srv.URL, Secret: "s3cret", Active: true,
+		Events: []string{models.WebhookAssessmentCreated, models.WebhookHighRisk}})
+	// Not subscribed, and inactive: neither is sent anything
+	_, _ = repo.Create(ctx, models.Webhook{URL: srv.URL, Secret: "x", Active: true, Events: []string{models.WebhookPatientCreated}})
+	_, _ = repo.Create(ctx, models.Webhook{URL: srv.URL, Secret: "x", Active: false, Events: []string{models.WebhookAssessmentCreated}})
+
+	d := NewDispatcher(repo)
+	bus := events.NewBus()
+	d.Subscribe(bus)
+	bus.Publish(ctx, events.AssessmentCreated{Assessment: models.Assessment{ID: 9, PatientID: 4, Cluster: "SIRD", RiskScore: 85}})
+	bus.Publish(ctx, events.RiskAlertRaised{Alert: models.RiskAlert{AssessmentID: 9, PatientID: 4, RiskScore: 85, Reasons: []string{"risk_score"}}})
+
+	if err := d.DeliverDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", len(got))
+	}
+
+	first := got[0]
+	if first.header.Get(EventHeader) != models.WebhookAssessmentCreated || first.header.Get(DeliveryHeader) == "" {
+		t.Errorf("unexpected headers %v", first.header)
+	}
+	sig := first.header.Get(SignatureHeader)
+	ts := strings.TrimPrefix(strings.Split(sig, ",")[0], "t=")
+	unix, _ := strconv.ParseInt(ts, 10, 64)
+	if sig != Sign("s3cret", unix, first.body) {
+		t.Errorf("signature %q does not match the body", sig)
+	}
+	var env struct {
+		Event string         `json:"event"`
+		Data  assessmentData `json:"data"`
+	}
+	if err := json.Unmarshal(first.body, &env); err != nil || env.Data.AssessmentID != 9 || env.Data.Cluster != "SIRD" {
+		t.Fatalf("unexpected body %s", first.body)
+	}
+	if got[1].header.Get(EventHeader) != models.WebhookHighRisk {
+		t.Errorf("expected the high-risk event second, got %s", got[1].header.Get(EventHeader))
+	}
+
+	deliveries, _ := repo.ListDeliveries(ctx, hook.ID, 10)
+	for _, dl := range deliveries {
+		if dl.Status != models.WebhookDeliveryDelivered || dl.Attempts != 1 || dl.LastStatusCode != 200 {
+			t.Errorf("unexpected delivery %+v", dl)
+		}
+	}
+	// Nothing is sent twice
+	if err := d.DeliverDue(ctx); err != nil || len(got) != 2 {
+		t.Fatalf("expected no further deliveries, got %d (%v)", len(got), err)
+	}
+}
+
+func TestDispatcher_RetriesThenFails(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	repo := store.NewMemoryStore().Webhooks()
+	hook, _ := repo.Create(ctx, models.Webhook{URL: srv.URL, Secret: "s", Active: true, Events: []string{models.WebhookPatientCreated}})
+	d := NewDispatcher(repo)
+	if err := d.enqueue(ctx, models.WebhookPatientCreated, patientData{PatientID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.DeliverDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, _ := repo.ListDeliveries(ctx, hook.ID, 10)
+	dl := deliveries[0]
+	if dl.Status != models.WebhookDeliveryPending || dl.Attempts != 1 || dl.LastStatusCode != 503 || dl.LastError == "" {
+		t.Fatalf("expected a pending retry, got %+v", dl)
+	}
+	if wait := time.Until(dl.NextAttemptAt); wait < 25*time.Second {
+		t.Fatalf("expected the retry to wait about %v, got %v", Backoff(1), wait)
+	}
+
+	// Let every retry fall due at once
+	dl.NextAttemptAt = time.Now()
+	_ = repo.RecordAttempt(ctx, dl)
+	d.now = func() time.Time { return time.Now().Add(-24 * time.Hour) }
+	for i := 1; i < MaxAttempts+2; i++ {
+		if err := d.DeliverDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deliveries, _ = repo.ListDeliveries(ctx, hook.ID, 10)
+	if dl := deliveries[0]; dl.Status != models.WebhookDeliveryFailed || dl.Attempts != MaxAttempts || calls != MaxAttempts {
+		t.Fatalf("expected failure after %d attempts, got %+v after %d calls", MaxAttempts, dl, calls)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		5:  8 * time.Minute,
+		20: 6 * time.Hour,
+	} {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
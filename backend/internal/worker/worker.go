@@ -0,0 +1,101 @@
+// Package worker runs the server's background jobs (periodic cleanups,
+// database probes, admin-triggered jobs) under one context, so shutdown can
+// cancel them and wait for in-flight work to finish instead of abandoning it
+// mid-write.
+package worker
+
+import (
+	"context"
+	"github.com/skufu/DianaV2/backend/internal/logging"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Manager tracks background jobs. A nil *Manager runs jobs untracked on a
+// background context, which keeps callers that do not need draining (tests,
+// one-off tools) simple.
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewManager() *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in a goroutine. fn must return promptly once ctx is done.
+// After Shutdown has started, fn is not run at all.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	if m == nil {
+		go run(context.Background(), name, fn)
+		return
+	}
+	if m.ctx.Err() != nil {
+		logging.Ctx(m.ctx).Warn().Msgf("worker %s not started: shutting down", name)
+		return
+	}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		run(m.ctx, name, fn)
+	}()
+}
+
+// Every runs fn every interval until shutdown, and once immediately if
+// runNow is set. A run in progress when shutdown starts is waited for; its
+// ctx is cancelled so it can stop early. Errors are logged.
+func (m *Manager) Every(name string, interval time.Duration, runNow bool, fn func(ctx context.Context) error) {
+	m.Go(name, func(ctx context.Context) {
+		tick := func() {
+			if err := fn(ctx); err != nil && ctx.Err() == nil {
+				logging.Ctx(ctx).Error().Err(err).Msgf("worker %s", name)
+			}
+		}
+		if runNow {
+			tick()
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				tick()
+			}
+		}
+	})
+}
+
+// Shutdown cancels every job and waits for them to return, or for ctx to
+// expire, in which case ctx's error is returned.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+	m.cancel()
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run calls fn, logging a panic instead of taking the server down.
+func run(ctx context.Context, name string, fn func(ctx context.Context)) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Ctx(ctx).Error().Interface("panic", r).Str("stack", string(debug.Stack())).Msgf("worker %s panicked", name)
+		}
+	}()
+	fn(ctx)
+}
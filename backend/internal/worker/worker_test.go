@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestManager_ShutdownWaitsForRunningJob(t *testing.T) {
+	m := NewManager()
+	var runs atomic.Int32
+	started := make(chan struct{})
+	var finished atomic.Bool
+	m.Every("slow", time.Hour, true, func(ctx context.Context) error {
+		runs.Add(1)
+		close(started)
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond) // finish the write in progress
+		finished.Store(true)
+		return ctx.Err()
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if !finished.Load() || runs.Load() != 1 {
+		t.Fatalf("shutdown returned before the job finished (runs=%d)", runs.Load())
+	}
+
+	// Jobs started after shutdown never run
+	m.Go("late", func(ctx context.Context) { t.Error("late job ran") })
+}
+
+func TestManager_ShutdownTimesOut(t *testing.T) {
+	m := NewManager()
+	release := make(chan struct{})
+	defer close(release)
+	m.Go("stuck", func(ctx context.Context) { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v, want deadline exceeded", err)
+	}
+}
+
+func TestManager_RecoversPanics(t *testing.T) {
+	m := NewManager()
+	m.Go("buggy", func(ctx context.Context) { panic("bug") })
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+func TestManager_Nil(t *testing.T) {
+	var m *Manager
+	done := make(chan struct{})
+	m.Go("untracked", func(ctx context.Context) { close(done) })
+	<-done
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
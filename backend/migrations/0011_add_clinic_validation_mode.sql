@@ -0,0 +1,10 @@
+-- +goose Up
+-- Per-clinic validation strictness: 'strict' rejects implausible biomarkers,
+-- 'advisory' stores them with warnings (the historical behaviour)
+ALTER TABLE clinics
+    ADD COLUMN IF NOT EXISTS validation_mode TEXT NOT NULL DEFAULT 'advisory'
+    CHECK (validation_mode IN ('strict', 'advisory'));
+
+-- +goose Down
+ALTER TABLE clinics
+    DROP COLUMN IF EXISTS validation_mode;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Medical record number plus trigram indexes backing the patient typeahead
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE patients
+    ADD COLUMN IF NOT EXISTS mrn TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_patients_name_trgm ON patients USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_patients_mrn_trgm ON patients USING gin (mrn gin_trgm_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_patients_mrn_trgm;
+DROP INDEX IF EXISTS idx_patients_name_trgm;
+
+ALTER TABLE patients
+    DROP COLUMN IF EXISTS mrn;
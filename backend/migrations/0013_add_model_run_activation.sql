@@ -0,0 +1,18 @@
+-- +goose Up
+-- Explicit model activation replaces "most recent run is active"
+ALTER TABLE model_runs
+    ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT false;
+
+-- Preserve current behaviour: the latest run stays active after migrating
+UPDATE model_runs SET is_active = true
+WHERE id = (SELECT id FROM model_runs ORDER BY created_at DESC LIMIT 1);
+
+-- At most one active run at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_model_runs_single_active
+    ON model_runs (is_active) WHERE is_active;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_model_runs_single_active;
+
+ALTER TABLE model_runs
+    DROP COLUMN IF EXISTS is_active;
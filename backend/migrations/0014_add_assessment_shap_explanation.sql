@@ -0,0 +1,8 @@
+-- +goose Up
+-- SHAP feature contributions captured at prediction time, rendered in PDF reports
+ALTER TABLE assessments
+    ADD COLUMN IF NOT EXISTS shap_explanation JSONB;
+
+-- +goose Down
+ALTER TABLE assessments
+    DROP COLUMN IF EXISTS shap_explanation;
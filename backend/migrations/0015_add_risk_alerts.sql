@@ -0,0 +1,21 @@
+-- +goose Up
+-- Risk alerts raised when a new assessment crosses the alert criteria.
+-- Rows are queued for delivery (delivered_at NULL) and also drive the
+-- per-patient cooldown that prevents repeat alerts.
+CREATE TABLE IF NOT EXISTS risk_alerts (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    patient_id INT NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    assessment_id INT NOT NULL REFERENCES assessments(id) ON DELETE CASCADE,
+    reasons TEXT[] NOT NULL,
+    risk_score INT NOT NULL,
+    hba1c NUMERIC(4,2),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_risk_alerts_patient_created ON risk_alerts(patient_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_risk_alerts_undelivered ON risk_alerts(created_at) WHERE delivered_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS risk_alerts;
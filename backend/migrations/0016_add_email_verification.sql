@@ -0,0 +1,26 @@
+-- +goose Up
+-- Self-registration: accounts created by admins, the seed command or before
+-- this migration count as verified (DEFAULT NOW()); self-registered users are
+-- inserted with NULL until they confirm their email.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+ALTER TABLE users
+    ALTER COLUMN email_verified_at SET DEFAULT NOW();
+
+-- Single-use email verification tokens (stored hashed, like refresh tokens)
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user ON email_verification_tokens(user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS email_verification_tokens;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS email_verified_at;
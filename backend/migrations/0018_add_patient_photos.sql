@@ -0,0 +1,22 @@
+-- +goose Up
+-- Optional patient photos. Image bytes live in object storage under
+-- storage_key; this table only holds metadata. Clinics can switch the
+-- feature off entirely with patient_photos_enabled.
+ALTER TABLE clinics
+    ADD COLUMN IF NOT EXISTS patient_photos_enabled BOOLEAN NOT NULL DEFAULT TRUE;
+
+CREATE TABLE IF NOT EXISTS patient_photos (
+    patient_id INT PRIMARY KEY REFERENCES patients(id) ON DELETE CASCADE,
+    storage_key TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    size_bytes INT NOT NULL,
+    uploaded_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS patient_photos;
+ALTER TABLE clinics
+    DROP COLUMN IF EXISTS patient_photos_enabled;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Refresh token rotation: every token rotated from a login shares its
+-- family_id so a replayed (already rotated) token can revoke the whole chain.
+-- Existing tokens each start their own family.
+ALTER TABLE refresh_tokens
+    ADD COLUMN IF NOT EXISTS family_id TEXT NOT NULL DEFAULT md5(random()::text || clock_timestamp()::text);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_refresh_tokens_family_id;
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS family_id;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Token buckets for RATE_LIMIT_STORE=postgres, shared by all API replicas.
+-- Rows are disposable: idle buckets are pruned by the daily cleanup job.
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS rate_limit_buckets;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- Patient contact details, kept apart from the clinical record so lists and
+-- exports never carry them. consent_at records when the patient last opted
+-- in to being contacted directly and is NULL while consent is withheld.
+CREATE TABLE IF NOT EXISTS patient_contacts (
+    patient_id INT PRIMARY KEY REFERENCES patients(id) ON DELETE CASCADE,
+    phone TEXT NOT NULL DEFAULT '',
+    email TEXT NOT NULL DEFAULT '',
+    address_line1 TEXT NOT NULL DEFAULT '',
+    address_line2 TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL DEFAULT '',
+    region TEXT NOT NULL DEFAULT '',
+    postal_code TEXT NOT NULL DEFAULT '',
+    country CHAR(2) NOT NULL DEFAULT '',
+    contact_consent BOOLEAN NOT NULL DEFAULT FALSE,
+    consent_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Channels the patient could be reached on when the alert was raised.
+ALTER TABLE risk_alerts
+    ADD COLUMN IF NOT EXISTS patient_channels TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE risk_alerts
+    DROP COLUMN IF EXISTS patient_channels;
+DROP TABLE IF EXISTS patient_contacts;
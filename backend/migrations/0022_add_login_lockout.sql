@@ -0,0 +1,11 @@
+-- +goose Up
+-- Per-account lockout after repeated failed logins. failed_login_attempts
+-- counts consecutive failures and resets on success or when a lock starts.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS failed_login_attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN IF EXISTS locked_until,
+    DROP COLUMN IF EXISTS failed_login_attempts;
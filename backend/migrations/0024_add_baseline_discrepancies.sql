@@ -0,0 +1,29 @@
+-- +goose Up
+-- Patients carry baseline smoking/hypertension/heart_disease values that
+-- assessments record again. baseline_policy decides what happens when a new
+-- assessment disagrees: 'update' overwrites the baseline, 'flag' records a
+-- discrepancy for a clinician to review.
+ALTER TABLE clinics
+    ADD COLUMN IF NOT EXISTS baseline_policy TEXT NOT NULL DEFAULT 'flag'
+    CHECK (baseline_policy IN ('update', 'flag'));
+
+CREATE TABLE IF NOT EXISTS baseline_discrepancies (
+    id SERIAL PRIMARY KEY,
+    patient_id INT NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    assessment_id INT NOT NULL REFERENCES assessments(id) ON DELETE CASCADE,
+    field TEXT NOT NULL,
+    baseline_value TEXT NOT NULL,
+    assessment_value TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolution TEXT CHECK (resolution IN ('applied', 'dismissed')),
+    resolved_by INT REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_baseline_discrepancies_open
+    ON baseline_discrepancies (patient_id) WHERE resolved_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS baseline_discrepancies;
+ALTER TABLE clinics
+    DROP COLUMN IF EXISTS baseline_policy;
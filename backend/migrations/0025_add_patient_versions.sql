@@ -0,0 +1,18 @@
+-- +goose Up
+-- Every patient update stores the row before and after, plus the fields that
+-- changed, so clinical edits can be traced field by field. Snapshots use the
+-- column names of the patients table.
+CREATE TABLE IF NOT EXISTS patient_versions (
+    id SERIAL PRIMARY KEY,
+    patient_id INT NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    changed_by INT REFERENCES users(id) ON DELETE SET NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    before JSONB NOT NULL,
+    after JSONB NOT NULL,
+    changes JSONB NOT NULL,
+    UNIQUE (patient_id, version)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS patient_versions;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- With ASSESSMENTS_IMMUTABLE on, edits create a new assessment that amends the
+-- previous one instead of overwriting it. Each assessment can be amended once,
+-- so a chain is linear: original -> amendment -> amendment of the amendment.
+ALTER TABLE assessments
+    ADD COLUMN IF NOT EXISTS amends_assessment_id INT REFERENCES assessments(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_assessments_amends
+    ON assessments (amends_assessment_id) WHERE amends_assessment_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_assessments_amends;
+ALTER TABLE assessments
+    DROP COLUMN IF EXISTS amends_assessment_id;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- A patient can be shared with one clinic, whose members can then view it.
+-- The owner (user_id) stays the only one who can change it. Deleting the
+-- clinic makes the patient private again.
+ALTER TABLE patients
+    ADD COLUMN IF NOT EXISTS clinic_id INT REFERENCES clinics(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_patients_clinic_id ON patients(clinic_id) WHERE clinic_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_patients_clinic_id;
+ALTER TABLE patients
+    DROP COLUMN IF EXISTS clinic_id;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Data quality per assessment, computed from the biomarkers when the
+-- assessment is stored: quality_score (0-100), the share of biomarkers
+-- provided and the number of implausible ones. NULL on rows stored before
+-- this migration until the re-validation job backfills them.
+ALTER TABLE assessments
+    ADD COLUMN IF NOT EXISTS self_reported BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS quality_score INT,
+    ADD COLUMN IF NOT EXISTS quality_completeness REAL,
+    ADD COLUMN IF NOT EXISTS quality_out_of_range INT;
+
+-- +goose Down
+ALTER TABLE assessments
+    DROP COLUMN IF EXISTS quality_out_of_range,
+    DROP COLUMN IF EXISTS quality_completeness,
+    DROP COLUMN IF EXISTS quality_score,
+    DROP COLUMN IF EXISTS self_reported;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- One row per assessment the first time a variant of an experiment is shown
+-- for it, e.g. the recommendation wording printed on its PDF report. unit is
+-- what the variant was assigned to ("clinic:3" or "user:7"). Outcomes are
+-- derived from later assessments, so nothing else is stored.
+CREATE TABLE IF NOT EXISTS experiment_exposures (
+    id BIGSERIAL PRIMARY KEY,
+    experiment TEXT NOT NULL,
+    variant TEXT NOT NULL,
+    unit TEXT NOT NULL,
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    patient_id INT NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    assessment_id INT NOT NULL REFERENCES assessments(id) ON DELETE CASCADE,
+    exposed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (experiment, assessment_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_experiment_exposures_experiment ON experiment_exposures(experiment, variant);
+
+-- +goose Down
+DROP TABLE IF EXISTS experiment_exposures;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Per-clinic branding for PDF reports. report_logo holds the logo as a small
+-- re-encoded JPEG, so it is stored inline rather than in object storage.
+ALTER TABLE clinics
+    ADD COLUMN IF NOT EXISTS report_locale TEXT NOT NULL DEFAULT 'en'
+        CHECK (report_locale IN ('en', 'fil')),
+    ADD COLUMN IF NOT EXISTS report_logo BYTEA;
+
+-- +goose Down
+ALTER TABLE clinics
+    DROP COLUMN IF EXISTS report_logo,
+    DROP COLUMN IF EXISTS report_locale;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Deactivating a user schedules their data for removal once the retention
+-- grace period ends. user_id has no foreign key: the row is the record that
+-- the deletion happened and outlives a hard-deleted user.
+CREATE TABLE IF NOT EXISTS user_deletions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    requested_by TEXT NOT NULL,
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    due_at TIMESTAMPTZ NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'cancelled', 'completed')),
+    mode TEXT CHECK (mode IN ('anonymize', 'delete')),
+    patients INT,
+    resolved_by TEXT,
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_deletions_pending ON user_deletions(user_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_user_deletions_due ON user_deletions(due_at) WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE IF EXISTS user_deletions;
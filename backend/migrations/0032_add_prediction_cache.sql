@@ -0,0 +1,15 @@
+-- +goose Up
+-- Predictions keyed by a hash of the model inputs, model version and dataset
+-- hash, so identical re-submissions skip the model call. Rows hold no
+-- identifiers and can be dropped at any time.
+CREATE TABLE IF NOT EXISTS prediction_cache (
+    key TEXT PRIMARY KEY,
+    cluster TEXT NOT NULL,
+    risk_score INT NOT NULL,
+    explanation JSONB,
+    explained BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS prediction_cache;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Assessments re-scored by a model run, e.g. after activating a new model.
+-- The assessment's score at the time is kept alongside so the two models can
+-- be compared even if the assessment is edited later.
+CREATE TABLE IF NOT EXISTS assessment_predictions (
+    assessment_id INT NOT NULL REFERENCES assessments(id) ON DELETE CASCADE,
+    model_run_id INT NOT NULL REFERENCES model_runs(id) ON DELETE CASCADE,
+    previous_cluster TEXT,
+    previous_risk_score INT,
+    previous_model_version TEXT,
+    cluster TEXT NOT NULL,
+    risk_score INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (assessment_id, model_run_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_assessment_predictions_run ON assessment_predictions(model_run_id, assessment_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS assessment_predictions;
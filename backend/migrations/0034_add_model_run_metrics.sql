@@ -0,0 +1,19 @@
+-- +goose Up
+-- Evaluation metrics reported by the training pipeline, and the cluster
+-- distribution of the training dataset that drift reports compare against.
+ALTER TABLE model_runs
+    ADD COLUMN IF NOT EXISTS accuracy DOUBLE PRECISION CHECK (accuracy BETWEEN 0 AND 1),
+    ADD COLUMN IF NOT EXISTS auc DOUBLE PRECISION CHECK (auc BETWEEN 0 AND 1),
+    ADD COLUMN IF NOT EXISTS calibration_error DOUBLE PRECISION CHECK (calibration_error BETWEEN 0 AND 1),
+    ADD COLUMN IF NOT EXISTS training_distribution JSONB;
+
+-- Drift reports read a model version's recent assessments
+CREATE INDEX IF NOT EXISTS idx_assessments_model_version_created_at ON assessments(model_version, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_assessments_model_version_created_at;
+ALTER TABLE model_runs
+    DROP COLUMN IF EXISTS training_distribution,
+    DROP COLUMN IF EXISTS calibration_error,
+    DROP COLUMN IF EXISTS auc,
+    DROP COLUMN IF EXISTS accuracy;
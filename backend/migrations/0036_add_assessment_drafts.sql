@@ -0,0 +1,29 @@
+-- +goose Up
+-- Lab results received from lab interfaces (HL7v2 ORU^R01), waiting for a
+-- clinician to complete them into an assessment. Biomarkers absent from the
+-- message are NULL.
+CREATE TABLE IF NOT EXISTS assessment_drafts (
+    id BIGSERIAL PRIMARY KEY,
+    patient_id BIGINT NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    source TEXT NOT NULL,
+    message_control_id TEXT NOT NULL,
+    sending_facility TEXT,
+    fbs NUMERIC(6,2),
+    hba1c NUMERIC(4,2),
+    cholesterol INT,
+    ldl INT,
+    hdl INT,
+    triglycerides INT,
+    observed_at TIMESTAMPTZ,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'discarded')),
+    assessment_id BIGINT REFERENCES assessments(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ,
+    -- A resent message does not create a second draft
+    UNIQUE (source, message_control_id, patient_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_assessment_drafts_pending ON assessment_drafts(patient_id, created_at DESC) WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE IF EXISTS assessment_drafts;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- API keys let integration scripts call the regular API as a user without
+-- scripting the login flow. Unlike api_tokens, which are bound to no user
+-- and only reach the reporting and integration routes, a key acts with its
+-- user's role and visibility, limited by its scopes. Only a SHA-256 hash of
+-- the key is stored; prefix keeps its first characters for identification.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    prefix TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS api_keys;
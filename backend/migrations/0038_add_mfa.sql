@@ -0,0 +1,34 @@
+-- +goose Up
+-- Two-factor authentication with authenticator app codes (TOTP). A row is
+-- created when a user starts enrollment and is enabled once they confirm a
+-- code. last_step is the last accepted time step, so a code is never
+-- accepted twice.
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMPTZ,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Single-use recovery codes for a lost authenticator, stored hashed.
+CREATE TABLE IF NOT EXISTS user_backup_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, code_hash)
+);
+
+-- Roles whose users must enroll before using the API.
+CREATE TABLE IF NOT EXISTS mfa_required_roles (
+    role TEXT PRIMARY KEY,
+    updated_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS mfa_required_roles;
+DROP TABLE IF EXISTS user_backup_codes;
+DROP TABLE IF EXISTS user_mfa;
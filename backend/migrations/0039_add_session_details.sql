@@ -0,0 +1,16 @@
+-- +goose Up
+-- Session listing: the client that signed in is recorded on its first
+-- refresh token and copied to every token rotated from it, as is the time
+-- of the original sign-in.
+ALTER TABLE refresh_tokens
+    ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS ip_address TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS signed_in_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+UPDATE refresh_tokens SET signed_in_at = created_at;
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS signed_in_at,
+    DROP COLUMN IF EXISTS ip_address,
+    DROP COLUMN IF EXISTS user_agent;
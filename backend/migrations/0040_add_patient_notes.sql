@@ -0,0 +1,18 @@
+-- +goose Up
+-- Clinicians' notes and care plan entries on a patient. author_id is kept
+-- NULL once the author's account is deleted so the note outlives them.
+CREATE TABLE IF NOT EXISTS patient_notes (
+    id BIGSERIAL PRIMARY KEY,
+    patient_id BIGINT NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    author_id INT REFERENCES users(id) ON DELETE SET NULL,
+    category TEXT NOT NULL DEFAULT 'general' CHECK (category IN ('general', 'medication', 'lifestyle', 'care_plan')),
+    body TEXT NOT NULL,
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_patient_notes_patient ON patient_notes(patient_id, pinned DESC, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS patient_notes;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Files backing an assessment, such as the lab report its values were
+-- entered from. File bytes live in object storage under storage_key; this
+-- table only holds metadata. patient_id is denormalized so a patient's
+-- files can be found for cleanup after the assessments are gone.
+CREATE TABLE IF NOT EXISTS assessment_attachments (
+    id BIGSERIAL PRIMARY KEY,
+    assessment_id INT NOT NULL REFERENCES assessments(id) ON DELETE CASCADE,
+    patient_id INT NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    storage_key TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    uploaded_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_assessment_attachments_assessment ON assessment_attachments(assessment_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_assessment_attachments_patient ON assessment_attachments(patient_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS assessment_attachments;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- Medications a patient takes. A row with no stopped_at is ongoing;
+-- adherent is the clinician's judgement of whether it is taken as
+-- prescribed.
+CREATE TABLE IF NOT EXISTS patient_medications (
+    id BIGSERIAL PRIMARY KEY,
+    patient_id BIGINT NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    dose TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ NOT NULL,
+    stopped_at TIMESTAMPTZ,
+    adherent BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (stopped_at IS NULL OR stopped_at >= started_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_patient_medications_patient ON patient_medications(patient_id, started_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS patient_medications;
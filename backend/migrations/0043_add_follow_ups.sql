@@ -0,0 +1,19 @@
+-- +goose Up
+-- Follow-up visits scheduled for patients. The patient's owner attends to
+-- them; reminded_at records that a reminder went out for due_at.
+CREATE TABLE IF NOT EXISTS follow_ups (
+    id BIGSERIAL PRIMARY KEY,
+    patient_id BIGINT NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    due_at TIMESTAMPTZ NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    completed_at TIMESTAMPTZ,
+    reminded_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_follow_ups_patient ON follow_ups(patient_id, due_at);
+CREATE INDEX IF NOT EXISTS idx_follow_ups_open ON follow_ups(due_at) WHERE completed_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS follow_ups;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- Clinicians' worklist. The system opens review_high_risk and
+-- overdue_assessment tasks; manual tasks are written by the clinician. At
+-- most one overdue_assessment task per patient is active at a time.
+CREATE TABLE IF NOT EXISTS tasks (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('review_high_risk', 'overdue_assessment', 'manual')),
+    title TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'in_progress', 'done', 'dismissed')),
+    patient_id BIGINT REFERENCES patients(id) ON DELETE CASCADE,
+    assessment_id BIGINT REFERENCES assessments(id) ON DELETE CASCADE,
+    due_at TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_user_status ON tasks(user_id, status);
+CREATE INDEX IF NOT EXISTS idx_tasks_patient ON tasks(patient_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_active_overdue ON tasks(patient_id)
+    WHERE kind = 'overdue_assessment' AND status IN ('open', 'in_progress');
+
+-- +goose Down
+DROP TABLE IF EXISTS tasks;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- Clinicians' own labels for ad-hoc cohorts, beyond the model's clusters.
+-- A tag belongs to the clinician who made it; names are unique per
+-- clinician regardless of case.
+CREATE TABLE IF NOT EXISTS tags (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags(user_id, LOWER(name));
+
+CREATE TABLE IF NOT EXISTS patient_tags (
+    tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    patient_id BIGINT NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tag_id, patient_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_patient_tags_patient ON patient_tags(patient_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS patient_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Named filter and sort combinations for the patient list, saved per user.
+-- filters holds the list's query parameters; at most one view per user is
+-- the default.
+CREATE TABLE IF NOT EXISTS patient_list_views (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    filters JSONB NOT NULL DEFAULT '{}'::jsonb,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_patient_list_views_user_name ON patient_list_views(user_id, LOWER(name));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_patient_list_views_default ON patient_list_views(user_id) WHERE is_default;
+
+-- +goose Down
+DROP TABLE IF EXISTS patient_list_views;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Per-user display settings: units (mg/dL or mmol/L), report locale and
+-- date format. Biomarkers are always stored in mg/dL and % regardless.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS preferences JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS preferences;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Keyset pages of the patient and assessment lists seek on (created_at, id)
+-- newest first within one owner or patient instead of counting past an offset.
+CREATE INDEX IF NOT EXISTS idx_patients_user_created_id ON patients(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_assessments_patient_created_id ON assessments(patient_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_assessments_patient_created_id;
+DROP INDEX IF EXISTS idx_patients_user_created_id;
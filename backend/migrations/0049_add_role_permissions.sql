@@ -0,0 +1,31 @@
+-- +goose Up
+-- Permissions each role grants. Global roles are users.role; clinic roles
+-- are user_clinics.role and only grant within that clinic. Routes check
+-- permissions rather than role names, so grants can change without a
+-- deploy.
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role TEXT NOT NULL,
+    permission TEXT NOT NULL,
+    updated_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'patients.read'),
+    ('admin', 'patients.write'),
+    ('admin', 'analytics.read'),
+    ('admin', 'admin.users'),
+    ('admin', 'admin.system'),
+    ('admin', 'clinic.read'),
+    ('admin', 'clinic.manage'),
+    ('clinician', 'patients.read'),
+    ('clinician', 'patients.write'),
+    ('clinician', 'analytics.read'),
+    ('clinic_admin', 'clinic.read'),
+    ('clinic_admin', 'clinic.manage'),
+    ('member', 'clinic.read')
+ON CONFLICT DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS role_permissions;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Every sign-in attempt, kept for users reviewing their own account and for
+-- admins looking for brute-force or credential stuffing. Failures against
+-- unknown emails have no user_id.
+CREATE TABLE IF NOT EXISTS login_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    ip_address TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    success BOOLEAN NOT NULL,
+    failure_reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_history_user_created ON login_history(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_history_failures ON login_history(created_at DESC, ip_address) WHERE NOT success;
+
+-- +goose Down
+DROP TABLE IF EXISTS login_history;
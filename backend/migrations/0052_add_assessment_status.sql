@@ -0,0 +1,131 @@
+-- +goose Up
+-- Assessments saved mid-consultation are drafts: stored as entered, without
+-- validation or a prediction, and left out of analytics until a clinician
+-- finalizes them. Every assessment stored before this migration is final.
+ALTER TABLE assessments
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'final' CHECK (status IN ('draft', 'final'));
+
+CREATE INDEX IF NOT EXISTS idx_assessments_drafts
+    ON assessments (patient_id) WHERE status = 'draft';
+
+-- The cohort statistics views are rebuilt to leave drafts out
+DROP MATERIALIZED VIEW IF EXISTS cohort_stats_by_menopause_status;
+DROP MATERIALIZED VIEW IF EXISTS cohort_stats_by_age_group;
+DROP MATERIALIZED VIEW IF EXISTS cohort_stats_by_cluster;
+
+CREATE MATERIALIZED VIEW cohort_stats_by_cluster AS
+SELECT
+    COALESCE(cluster, 'Unknown') AS group_name,
+    COUNT(*)::int AS count,
+    COALESCE(AVG(hba1c), 0)::float8 AS avg_hba1c,
+    COALESCE(AVG(fbs), 0)::float8 AS avg_fbs,
+    COALESCE(AVG(bmi), 0)::float8 AS avg_bmi,
+    COALESCE(AVG(systolic), 0)::float8 AS avg_bp_systolic,
+    COALESCE(AVG(diastolic), 0)::float8 AS avg_bp_diastolic,
+    COALESCE(AVG(risk_score), 0)::float8 AS avg_risk_score,
+    COUNT(CASE WHEN risk_score < 34 THEN 1 END)::int AS low_risk_count,
+    COUNT(CASE WHEN risk_score >= 34 AND risk_score < 67 THEN 1 END)::int AS moderate_risk_count,
+    COUNT(CASE WHEN risk_score >= 67 THEN 1 END)::int AS high_risk_count
+FROM assessments
+WHERE status = 'final'
+GROUP BY COALESCE(cluster, 'Unknown');
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cohort_stats_by_cluster_group ON cohort_stats_by_cluster(group_name);
+
+CREATE MATERIALIZED VIEW cohort_stats_by_age_group AS
+SELECT
+    CASE
+        WHEN p.age < 45 THEN 'Under 45'
+        WHEN p.age >= 45 AND p.age < 55 THEN '45-54'
+        WHEN p.age >= 55 AND p.age < 65 THEN '55-64'
+        ELSE '65+'
+    END AS group_name,
+    COUNT(*)::int AS count,
+    COALESCE(AVG(a.hba1c), 0)::float8 AS avg_hba1c,
+    COALESCE(AVG(a.fbs), 0)::float8 AS avg_fbs,
+    COALESCE(AVG(a.bmi), 0)::float8 AS avg_bmi,
+    COALESCE(AVG(a.systolic), 0)::float8 AS avg_bp_systolic,
+    COALESCE(AVG(a.diastolic), 0)::float8 AS avg_bp_diastolic,
+    COALESCE(AVG(a.risk_score), 0)::float8 AS avg_risk_score
+FROM assessments a
+JOIN patients p ON a.patient_id = p.id
+WHERE a.status = 'final'
+GROUP BY 1;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cohort_stats_by_age_group_group ON cohort_stats_by_age_group(group_name);
+
+CREATE MATERIALIZED VIEW cohort_stats_by_menopause_status AS
+SELECT
+    COALESCE(p.menopause_status, 'Unknown') AS group_name,
+    COUNT(*)::int AS count,
+    COALESCE(AVG(a.hba1c), 0)::float8 AS avg_hba1c,
+    COALESCE(AVG(a.fbs), 0)::float8 AS avg_fbs,
+    COALESCE(AVG(a.bmi), 0)::float8 AS avg_bmi,
+    COALESCE(AVG(a.systolic), 0)::float8 AS avg_bp_systolic,
+    COALESCE(AVG(a.diastolic), 0)::float8 AS avg_bp_diastolic,
+    COALESCE(AVG(a.risk_score), 0)::float8 AS avg_risk_score
+FROM assessments a
+JOIN patients p ON a.patient_id = p.id
+WHERE a.status = 'final'
+GROUP BY COALESCE(p.menopause_status, 'Unknown');
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cohort_stats_by_menopause_status_group ON cohort_stats_by_menopause_status(group_name);
+
+-- +goose Down
+-- The views depend on the column, so they are rebuilt as 0050 left them
+DROP MATERIALIZED VIEW IF EXISTS cohort_stats_by_menopause_status;
+DROP MATERIALIZED VIEW IF EXISTS cohort_stats_by_age_group;
+DROP MATERIALIZED VIEW IF EXISTS cohort_stats_by_cluster;
+
+CREATE MATERIALIZED VIEW cohort_stats_by_cluster AS
+SELECT
+    COALESCE(cluster, 'Unknown') AS group_name,
+    COUNT(*)::int AS count,
+    COALESCE(AVG(hba1c), 0)::float8 AS avg_hba1c,
+    COALESCE(AVG(fbs), 0)::float8 AS avg_fbs,
+    COALESCE(AVG(bmi), 0)::float8 AS avg_bmi,
+    COALESCE(AVG(systolic), 0)::float8 AS avg_bp_systolic,
+    COALESCE(AVG(diastolic), 0)::float8 AS avg_bp_diastolic,
+    COALESCE(AVG(risk_score), 0)::float8 AS avg_risk_score,
+    COUNT(CASE WHEN risk_score < 34 THEN 1 END)::int AS low_risk_count,
+    COUNT(CASE WHEN risk_score >= 34 AND risk_score < 67 THEN 1 END)::int AS moderate_risk_count,
+    COUNT(CASE WHEN risk_score >= 67 THEN 1 END)::int AS high_risk_count
+FROM assessments
+GROUP BY COALESCE(cluster, 'Unknown');
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cohort_stats_by_cluster_group ON cohort_stats_by_cluster(group_name);
+
+CREATE MATERIALIZED VIEW cohort_stats_by_age_group AS
+SELECT
+    CASE
+        WHEN p.age < 45 THEN 'Under 45'
+        WHEN p.age >= 45 AND p.age < 55 THEN '45-54'
+        WHEN p.age >= 55 AND p.age < 65 THEN '55-64'
+        ELSE '65+'
+    END AS group_name,
+    COUNT(*)::int AS count,
+    COALESCE(AVG(a.hba1c), 0)::float8 AS avg_hba1c,
+    COALESCE(AVG(a.fbs), 0)::float8 AS avg_fbs,
+    COALESCE(AVG(a.bmi), 0)::float8 AS avg_bmi,
+    COALESCE(AVG(a.systolic), 0)::float8 AS avg_bp_systolic,
+    COALESCE(AVG(a.diastolic), 0)::float8 AS avg_bp_diastolic,
+    COALESCE(AVG(a.risk_score), 0)::float8 AS avg_risk_score
+FROM assessments a
+JOIN patients p ON a.patient_id = p.id
+GROUP BY 1;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cohort_stats_by_age_group_group ON cohort_stats_by_age_group(group_name);
+
+CREATE MATERIALIZED VIEW cohort_stats_by_menopause_status AS
+SELECT
+    COALESCE(p.menopause_status, 'Unknown') AS group_name,
+    COUNT(*)::int AS count,
+    COALESCE(AVG(a.hba1c), 0)::float8 AS avg_hba1c,
+    COALESCE(AVG(a.fbs), 0)::float8 AS avg_fbs,
+    COALESCE(AVG(a.bmi), 0)::float8 AS avg_bmi,
+    COALESCE(AVG(a.systolic), 0)::float8 AS avg_bp_systolic,
+    COALESCE(AVG(a.diastolic), 0)::float8 AS avg_bp_diastolic,
+    COALESCE(AVG(a.risk_score), 0)::float8 AS avg_risk_score
+FROM assessments a
+JOIN patients p ON a.patient_id = p.id
+GROUP BY COALESCE(p.menopause_status, 'Unknown');
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cohort_stats_by_menopause_status_group ON cohort_stats_by_menopause_status(group_name);
+
+DROP INDEX IF EXISTS idx_assessments_drafts;
+ALTER TABLE assessments
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- BMI is computed from height and weight when both are entered, so clients
+-- no longer round it differently. bmi_source records whether the stored BMI
+-- was 'computed' or 'provided' as entered; rows stored before have none.
+ALTER TABLE patients
+    ADD COLUMN IF NOT EXISTS height_cm NUMERIC(5,1),
+    ADD COLUMN IF NOT EXISTS weight_kg NUMERIC(5,1),
+    ADD COLUMN IF NOT EXISTS bmi_source TEXT CHECK (bmi_source IN ('provided', 'computed'));
+
+ALTER TABLE assessments
+    ADD COLUMN IF NOT EXISTS height_cm NUMERIC(5,1),
+    ADD COLUMN IF NOT EXISTS weight_kg NUMERIC(5,1),
+    ADD COLUMN IF NOT EXISTS bmi_source TEXT CHECK (bmi_source IN ('provided', 'computed'));
+
+-- +goose Down
+ALTER TABLE assessments
+    DROP COLUMN IF EXISTS bmi_source,
+    DROP COLUMN IF EXISTS weight_kg,
+    DROP COLUMN IF EXISTS height_cm;
+
+ALTER TABLE patients
+    DROP COLUMN IF EXISTS bmi_source,
+    DROP COLUMN IF EXISTS weight_kg,
+    DROP COLUMN IF EXISTS height_cm;
@@ -0,0 +1,125 @@
+-- +goose Up
+-- An amendment replaces the assessment it amends, so the cohort statistics
+-- views are rebuilt to count only the latest version of each assessment.
+-- Superseded versions stay reachable through the amendment chain.
+DROP MATERIALIZED VIEW IF EXISTS cohort_stats_by_menopause_status;
+DROP MATERIALIZED VIEW IF EXISTS cohort_stats_by_age_group;
+DROP MATERIALIZED VIEW IF EXISTS cohort_stats_by_cluster;
+
+CREATE MATERIALIZED VIEW cohort_stats_by_cluster AS
+SELECT
+    COALESCE(cluster, 'Unknown') AS group_name,
+    COUNT(*)::int AS count,
+    COALESCE(AVG(hba1c), 0)::float8 AS avg_hba1c,
+    COALESCE(AVG(fbs), 0)::float8 AS avg_fbs,
+    COALESCE(AVG(bmi), 0)::float8 AS avg_bmi,
+    COALESCE(AVG(systolic), 0)::float8 AS avg_bp_systolic,
+    COALESCE(AVG(diastolic), 0)::float8 AS avg_bp_diastolic,
+    COALESCE(AVG(risk_score), 0)::float8 AS avg_risk_score,
+    COUNT(CASE WHEN risk_score < 34 THEN 1 END)::int AS low_risk_count,
+    COUNT(CASE WHEN risk_score >= 34 AND risk_score < 67 THEN 1 END)::int AS moderate_risk_count,
+    COUNT(CASE WHEN risk_score >= 67 THEN 1 END)::int AS high_risk_count
+FROM assessments a
+WHERE status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
+GROUP BY COALESCE(cluster, 'Unknown');
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cohort_stats_by_cluster_group ON cohort_stats_by_cluster(group_name);
+
+CREATE MATERIALIZED VIEW cohort_stats_by_age_group AS
+SELECT
+    CASE
+        WHEN p.age < 45 THEN 'Under 45'
+        WHEN p.age >= 45 AND p.age < 55 THEN '45-54'
+        WHEN p.age >= 55 AND p.age < 65 THEN '55-64'
+        ELSE '65+'
+    END AS group_name,
+    COUNT(*)::int AS count,
+    COALESCE(AVG(a.hba1c), 0)::float8 AS avg_hba1c,
+    COALESCE(AVG(a.fbs), 0)::float8 AS avg_fbs,
+    COALESCE(AVG(a.bmi), 0)::float8 AS avg_bmi,
+    COALESCE(AVG(a.systolic), 0)::float8 AS avg_bp_systolic,
+    COALESCE(AVG(a.diastolic), 0)::float8 AS avg_bp_diastolic,
+    COALESCE(AVG(a.risk_score), 0)::float8 AS avg_risk_score
+FROM assessments a
+JOIN patients p ON a.patient_id = p.id
+WHERE a.status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
+GROUP BY 1;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cohort_stats_by_age_group_group ON cohort_stats_by_age_group(group_name);
+
+CREATE MATERIALIZED VIEW cohort_stats_by_menopause_status AS
+SELECT
+    COALESCE(p.menopause_status, 'Unknown') AS group_name,
+    COUNT(*)::int AS count,
+    COALESCE(AVG(a.hba1c), 0)::float8 AS avg_hba1c,
+    COALESCE(AVG(a.fbs), 0)::float8 AS avg_fbs,
+    COALESCE(AVG(a.bmi), 0)::float8 AS avg_bmi,
+    COALESCE(AVG(a.systolic), 0)::float8 AS avg_bp_systolic,
+    COALESCE(AVG(a.diastolic), 0)::float8 AS avg_bp_diastolic,
+    COALESCE(AVG(a.risk_score), 0)::float8 AS avg_risk_score
+FROM assessments a
+JOIN patients p ON a.patient_id = p.id
+WHERE a.status = 'final'
+  AND NOT EXISTS (SELECT 1 FROM assessments n WHERE n.amends_assessment_id = a.id)
+GROUP BY COALESCE(p.menopause_status, 'Unknown');
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cohort_stats_by_menopause_status_group ON cohort_stats_by_menopause_status(group_name);
+
+-- +goose Down
+DROP MATERIALIZED VIEW IF EXISTS cohort_stats_by_menopause_status;
+DROP MATERIALIZED VIEW IF EXISTS cohort_stats_by_age_group;
+DROP MATERIALIZED VIEW IF EXISTS cohort_stats_by_cluster;
+
+CREATE MATERIALIZED VIEW cohort_stats_by_cluster AS
+SELECT
+    COALESCE(cluster, 'Unknown') AS group_name,
+    COUNT(*)::int AS count,
+    COALESCE(AVG(hba1c), 0)::float8 AS avg_hba1c,
+    COALESCE(AVG(fbs), 0)::float8 AS avg_fbs,
+    COALESCE(AVG(bmi), 0)::float8 AS avg_bmi,
+    COALESCE(AVG(systolic), 0)::float8 AS avg_bp_systolic,
+    COALESCE(AVG(diastolic), 0)::float8 AS avg_bp_diastolic,
+    COALESCE(AVG(risk_score), 0)::float8 AS avg_risk_score,
+    COUNT(CASE WHEN risk_score < 34 THEN 1 END)::int AS low_risk_count,
+    COUNT(CASE WHEN risk_score >= 34 AND risk_score < 67 THEN 1 END)::int AS moderate_risk_count,
+    COUNT(CASE WHEN risk_score >= 67 THEN 1 END)::int AS high_risk_count
+FROM assessments
+WHERE status = 'final'
+GROUP BY COALESCE(cluster, 'Unknown');
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cohort_stats_by_cluster_group ON cohort_stats_by_cluster(group_name);
+
+CREATE MATERIALIZED VIEW cohort_stats_by_age_group AS
+SELECT
+    CASE
+        WHEN p.age < 45 THEN 'Under 45'
+        WHEN p.age >= 45 AND p.age < 55 THEN '45-54'
+        WHEN p.age >= 55 AND p.age < 65 THEN '55-64'
+        ELSE '65+'
+    END AS group_name,
+    COUNT(*)::int AS count,
+    COALESCE(AVG(a.hba1c), 0)::float8 AS avg_hba1c,
+    COALESCE(AVG(a.fbs), 0)::float8 AS avg_fbs,
+    COALESCE(AVG(a.bmi), 0)::float8 AS avg_bmi,
+    COALESCE(AVG(a.systolic), 0)::float8 AS avg_bp_systolic,
+    COALESCE(AVG(a.diastolic), 0)::float8 AS avg_bp_diastolic,
+    COALESCE(AVG(a.risk_score), 0)::float8 AS avg_risk_score
+FROM assessments a
+JOIN patients p ON a.patient_id = p.id
+WHERE a.status = 'final'
+GROUP BY 1;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cohort_stats_by_age_group_group ON cohort_stats_by_age_group(group_name);
+
+CREATE MATERIALIZED VIEW cohort_stats_by_menopause_status AS
+SELECT
+    COALESCE(p.menopause_status, 'Unknown') AS group_name,
+    COUNT(*)::int AS count,
+    COALESCE(AVG(a.hba1c), 0)::float8 AS avg_hba1c,
+    COALESCE(AVG(a.fbs), 0)::float8 AS avg_fbs,
+    COALESCE(AVG(a.bmi), 0)::float8 AS avg_bmi,
+    COALESCE(AVG(a.systolic), 0)::float8 AS avg_bp_systolic,
+    COALESCE(AVG(a.diastolic), 0)::float8 AS avg_bp_diastolic,
+    COALESCE(AVG(a.risk_score), 0)::float8 AS avg_risk_score
+FROM assessments a
+JOIN patients p ON a.patient_id = p.id
+WHERE a.status = 'final'
+GROUP BY COALESCE(p.menopause_status, 'Unknown');
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cohort_stats_by_menopause_status_group ON cohort_stats_by_menopause_status(group_name);
@@ -0,0 +1,32 @@
+// Package migrations embeds the Goose SQL migrations, so cmd/migrate and
+// cmd/server apply them from the binary whatever the working directory.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// FS holds every migration at its root
+//
+//go:embed *.sql
+var FS embed.FS
+
+// Up applies every pending migration to db. A Postgres advisory lock makes
+// concurrent callers, such as replicas starting together, wait for the
+// first one rather than race it.
+func Up(ctx context.Context, db *sql.DB) ([]*goose.MigrationResult, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, err
+	}
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, FS, goose.WithSessionLocker(locker))
+	if err != nil {
+		return nil, err
+	}
+	return provider.Up(ctx)
+}
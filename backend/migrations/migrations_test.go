@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestFS_MigrationsAreWellFormed(t *testing.T) {
+	goose.SetBaseFS(FS)
+	defer goose.SetBaseFS(nil)
+	collected, err := goose.CollectMigrations(".", 0, goose.MaxVersion)
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	names, _ := fs.Glob(FS, "*.sql")
+	if len(collected) == 0 || len(collected) != len(names) {
+		t.Fatalf("collected %d of %d embedded migrations", len(collected), len(names))
+	}
+	for _, name := range names {
+		body, _ := fs.ReadFile(FS, name)
+		if !strings.Contains(string(body), "-- +goose Up") {
+			t.Errorf("%s has no -- +goose Up section", name)
+		}
+	}
+}
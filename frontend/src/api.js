@@ -80,6 +80,10 @@ const attemptTokenRefresh = async () => {
 
   const data = await res.json();
   localStorage.setItem('diana_token', data.access_token);
+  // Refresh tokens are single-use: keep the rotated one for the next refresh
+  if (data.refresh_token) {
+    localStorage.setItem('diana_refresh_token', data.refresh_token);
+  }
   return data.access_token;
 };
 
@@ -154,14 +158,38 @@ export const loginApi = (email, password) =>
     body: JSON.stringify({ email, password }),
   });
 
+// Re-verify the current password; returns an access token elevated for sensitive admin actions
+export const sudoApi = (token, password) =>
+  apiFetch('/api/v1/auth/sudo', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json', Authorization: `Bearer ${token}` },
+    body: JSON.stringify({ password }),
+  });
+
+// Profile, feature flags, clinics and biomarker ranges in one request.
+// Partial responses (a section failed server-side) are not cached.
+export const fetchBootstrapApi = async (token) => {
+  const cacheKey = '/api/v1/bootstrap';
+  const cached = getCached(cacheKey);
+  if (cached) return cached;
+
+  const data = await apiFetch(cacheKey, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+  if (!data?.partial?.length) setCache(cacheKey, data, 60000);
+  return data;
+};
+
 export const fetchPatientsApi = async (token) => {
   const cacheKey = '/api/v1/patients';
   const cached = getCached(cacheKey);
   if (cached) return cached;
 
-  const data = await apiFetch('/api/v1/patients', {
+  const result = await apiFetch('/api/v1/patients?page_size=100', {
     headers: { Authorization: `Bearer ${token}` },
   });
+  // The list endpoint returns a paginated envelope; callers expect the rows.
+  const data = result?.data ?? [];
   setCache(cacheKey, data);
   return data;
 };
@@ -190,8 +218,16 @@ export const fetchClusterDistributionApi = async (token) => {
   return data;
 };
 
-export const fetchTrendAnalyticsApi = async (token) => {
-  const cacheKey = '/api/v1/analytics/biomarker-trends';
+export const fetchTrendAnalyticsApi = async (token, { start, end, granularity, biomarkers, userId, clinicId } = {}) => {
+  const params = new URLSearchParams();
+  if (start) params.set('start', start);
+  if (end) params.set('end', end);
+  if (granularity) params.set('granularity', granularity);
+  if (biomarkers?.length) params.set('biomarkers', biomarkers.join(','));
+  if (userId) params.set('user_id', userId);
+  if (clinicId) params.set('clinic_id', clinicId);
+  const query = params.toString();
+  const cacheKey = `/api/v1/analytics/biomarker-trends${query ? `?${query}` : ''}`;
   const cached = getCached(cacheKey);
   if (cached) return cached;
 
@@ -230,6 +266,17 @@ export const createAssessmentApi = async (token, patientId, payload) => {
   return result;
 };
 
+// Preview an assessment (validation + prediction) without saving it
+export const dryRunAssessmentApi = (token, patientId, payload) =>
+  apiFetch(`/api/v1/patients/${patientId}/assessments:dryRun`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify(payload),
+  });
+
 // Patient individual operations
 export const getPatientApi = (token, patientId) =>
   apiFetch(`/api/v1/patients/${patientId}`, {
@@ -252,6 +299,137 @@ export const deletePatientApi = (token, patientId) =>
     headers: { Authorization: `Bearer ${token}` },
   });
 
+// Patient photo (optional, can be disabled per clinic)
+export const fetchPatientPhotoApi = async (token, patientId) => {
+  const res = await fetch(`${API_BASE}/api/v1/patients/${patientId}/photo`, {
+    headers: { Authorization: `Bearer ${token}` },
+    cache: 'no-store',
+  });
+  if (res.status === 404) return null;
+  if (!res.ok) throw new Error(`Failed to load photo: ${res.status}`);
+  return res.blob();
+};
+
+export const uploadPatientPhotoApi = (token, patientId, file) => {
+  const form = new FormData();
+  form.append('photo', file);
+  return apiFetch(`/api/v1/patients/${patientId}/photo`, {
+    method: 'PUT',
+    headers: { Authorization: `Bearer ${token}` },
+    body: form,
+  });
+};
+
+export const deletePatientPhotoApi = (token, patientId) =>
+  apiFetch(`/api/v1/patients/${patientId}/photo`, {
+    method: 'DELETE',
+    headers: { Authorization: `Bearer ${token}` },
+  });
+
+// Patient contact details and contact consent
+export const getPatientContactApi = (token, patientId) =>
+  apiFetch(`/api/v1/patients/${patientId}/contact`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+
+export const updatePatientContactApi = (token, patientId, contact) =>
+  apiFetch(`/api/v1/patients/${patientId}/contact`, {
+    method: 'PUT',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify(contact),
+  });
+
+export const getBaselineDiscrepanciesApi = (token, patientId) =>
+  apiFetch(`/api/v1/patients/${patientId}/baseline-discrepancies`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+
+// action is 'apply' (copy the assessment value to the baseline) or 'dismiss'
+export const resolveBaselineDiscrepancyApi = (token, patientId, discrepancyId, action) =>
+  apiFetch(`/api/v1/patients/${patientId}/baseline-discrepancies/${discrepancyId}/resolve`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify({ action }),
+  });
+
+export const getPatientHistoryApi = (token, patientId) =>
+  apiFetch(`/api/v1/patients/${patientId}/history`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+
+export const getPatientProjectionApi = (token, patientId, model = 'linear') =>
+  apiFetch(`/api/v1/patients/${patientId}/projection?model=${model}`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+
+// Full patient record for referrals; redact drops name, MRN, contact and photo.
+// Returns a ZIP blob holding bundle.json.
+export const downloadPatientBundleApi = async (token, patientId, { redact = false } = {}) => {
+  const query = new URLSearchParams({ format: 'zip' });
+  if (redact) query.set('redact', 'identifiers');
+  const res = await fetch(`${API_BASE}/api/v1/patients/${patientId}/bundle?${query}`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+  if (!res.ok) throw new Error(`Failed to export patient bundle: ${res.status}`);
+  return res.blob();
+};
+
+// Complete patient record for data-portability requests. format 'json' or
+// 'csv'; csv returns a ZIP blob of one CSV per section.
+export const downloadPatientExportApi = async (token, patientId, format = 'json') => {
+  const res = await fetch(`${API_BASE}/api/v1/patients/${patientId}/export?format=${format}`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+  if (!res.ok) throw new Error(`Failed to export patient: ${res.status}`);
+  return res.blob();
+};
+
+// Everything stored about the signed-in user's account, as a blob
+export const downloadUserExportApi = async (token, format = 'json') => {
+  const res = await fetch(`${API_BASE}/api/v1/users/export?format=${format}`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+  if (!res.ok) throw new Error(`Failed to export account data: ${res.status}`);
+  return res.blob();
+};
+
+// PDF of the patient's whole assessment history. Returns a PDF blob.
+export const downloadPatientSummaryReportApi = async (token, patientId) => {
+  const res = await fetch(`${API_BASE}/api/v1/patients/${patientId}/report`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+  if (!res.ok) throw new Error(`Failed to download patient summary: ${res.status}`);
+  return res.blob();
+};
+
+// Admin or clinic_admin only; notify emails the receiving clinician
+export const transferPatientApi = (token, patientId, toUserId, { notify = false } = {}) =>
+  apiFetch(`/api/v1/patients/${patientId}/transfer`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify({ to_user_id: toUserId, notify }),
+  });
+
+// clinicId null stops sharing
+export const setPatientClinicApi = (token, patientId, clinicId) =>
+  apiFetch(`/api/v1/patients/${patientId}/clinic`, {
+    method: 'PUT',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify({ clinic_id: clinicId }),
+  });
+
 // Assessment individual operations
 export const getAssessmentApi = (token, patientId, assessmentId) =>
   apiFetch(`/api/v1/patients/${patientId}/assessments/${assessmentId}`, {
@@ -282,6 +460,41 @@ export const refreshTokenApi = (refreshToken) =>
     body: JSON.stringify({ refresh_token: refreshToken }),
   });
 
+export const registerApi = (email, password) =>
+  apiFetch('/api/v1/auth/register', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ email, password }),
+  });
+
+export const verifyEmailApi = (token) =>
+  apiFetch('/api/v1/auth/verify-email', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ token }),
+  });
+
+export const resendVerificationApi = (email) =>
+  apiFetch('/api/v1/auth/resend-verification', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ email }),
+  });
+
+export const forgotPasswordApi = (email) =>
+  apiFetch('/api/v1/auth/forgot-password', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ email }),
+  });
+
+export const resetPasswordApi = (token, password) =>
+  apiFetch('/api/v1/auth/reset-password', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ token, password }),
+  });
+
 export const logoutApi = (refreshToken) =>
   apiFetch('/api/v1/auth/logout', {
     method: 'POST',
@@ -314,11 +527,26 @@ export const fetchMLClustersApi = () => mlFetch('/analytics/clusters');
 
 export const getMLVisualizationUrl = (name) => `${ML_BASE}/analytics/visualizations/${name}`;
 
+export const fetchDataQualityApi = async (token, below = 60) => {
+  const cacheKey = `/api/v1/analytics/data-quality?below=${below}`;
+  const cached = getCached(cacheKey);
+  if (cached) return cached;
+
+  const data = await apiFetch(cacheKey, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+  setCache(cacheKey, data, 60000);
+  return data;
+};
+
 // ============================================================
 // Cohort Analysis API
 // ============================================================
-export const fetchCohortAnalysisApi = async (token, groupBy = 'cluster') => {
-  const cacheKey = `/api/v1/analytics/cohort?groupBy=${groupBy}`;
+export const fetchCohortAnalysisApi = async (token, groupBy = 'cluster', { userId, clinicId } = {}) => {
+  const params = new URLSearchParams({ group_by: groupBy });
+  if (userId) params.set('user_id', userId);
+  if (clinicId) params.set('clinic_id', clinicId);
+  const cacheKey = `/api/v1/analytics/cohort?${params}`;
   const cached = getCached(cacheKey);
   if (cached) return cached;
 
@@ -356,6 +584,98 @@ export const fetchClinicDashboardApi = async (token, clinicId) => {
   return data;
 };
 
+export const fetchClinicMembersApi = (token, clinicId) =>
+  apiFetch(`/api/v1/clinics/${clinicId}/members`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+
+export const addClinicMemberApi = (token, clinicId, email, role = 'member') =>
+  apiFetch(`/api/v1/clinics/${clinicId}/members`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify({ email, role }),
+  });
+
+export const removeClinicMemberApi = (token, clinicId, userId) =>
+  apiFetch(`/api/v1/clinics/${clinicId}/members/${userId}`, {
+    method: 'DELETE',
+    headers: { Authorization: `Bearer ${token}` },
+  });
+
+export const setClinicMemberRoleApi = (token, clinicId, userId, role) =>
+  apiFetch(`/api/v1/clinics/${clinicId}/members/${userId}/role`, {
+    method: 'PUT',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify({ role }),
+  });
+
+export const setClinicPatientPhotosApi = (token, clinicId, enabled) =>
+  apiFetch(`/api/v1/clinics/${clinicId}/patient-photos`, {
+    method: 'PUT',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify({ enabled }),
+  });
+
+export const setClinicBaselinePolicyApi = (token, clinicId, policy) =>
+  apiFetch(`/api/v1/clinics/${clinicId}/baseline-policy`, {
+    method: 'PUT',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify({ policy }),
+  });
+
+export const fetchClinicReportSettingsApi = (token, clinicId) =>
+  apiFetch(`/api/v1/clinics/${clinicId}/report-settings`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+
+// locale is 'en' or 'fil'
+export const setClinicReportSettingsApi = (token, clinicId, locale) =>
+  apiFetch(`/api/v1/clinics/${clinicId}/report-settings`, {
+    method: 'PUT',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify({ locale }),
+  });
+
+export const fetchClinicReportLogoApi = async (token, clinicId) => {
+  const res = await fetch(`${API_BASE}/api/v1/clinics/${clinicId}/report-logo`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+  if (res.status === 404) return null;
+  if (!res.ok) throw new Error(`Failed to load clinic logo: ${res.status}`);
+  return res.blob();
+};
+
+export const uploadClinicReportLogoApi = (token, clinicId, file) => {
+  const form = new FormData();
+  form.append('logo', file);
+  return apiFetch(`/api/v1/clinics/${clinicId}/report-logo`, {
+    method: 'PUT',
+    headers: { Authorization: `Bearer ${token}` },
+    body: form,
+  });
+};
+
+export const deleteClinicReportLogoApi = (token, clinicId) =>
+  apiFetch(`/api/v1/clinics/${clinicId}/report-logo`, {
+    method: 'DELETE',
+    headers: { Authorization: `Bearer ${token}` },
+  });
+
 // ============================================================
 // Admin Dashboard API
 // ============================================================
@@ -383,8 +703,22 @@ export const fetchAdminClinicsApi = async (token) => {
   return data;
 };
 
-export const fetchClinicComparisonApi = async (token) => {
-  const cacheKey = '/api/v1/admin/clinic-comparison';
+export const fetchAdminStatsApi = async (token, params = {}) => {
+  const query = new URLSearchParams(params).toString();
+  const cacheKey = `/api/v1/admin/stats${query ? `?${query}` : ''}`;
+  const cached = getCached(cacheKey);
+  if (cached) return cached;
+
+  const data = await apiFetch(cacheKey, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+  setCache(cacheKey, data, 60000);
+  return data;
+};
+
+export const fetchClinicComparisonApi = async (token, params = {}) => {
+  const query = new URLSearchParams(params).toString();
+  const cacheKey = `/api/v1/admin/clinic-comparison${query ? `?${query}` : ''}`;
   const cached = getCached(cacheKey);
   if (cached) return cached;
 
@@ -444,16 +778,97 @@ export const activateAdminUserApi = async (token, userId) => {
   return result;
 };
 
+export const unlockAdminUserApi = async (token, userId) => {
+  const result = await apiFetch(`/api/v1/admin/users/${userId}/unlock`, {
+    method: 'POST',
+    headers: { Authorization: `Bearer ${token}` },
+  });
+  invalidateCache('/api/v1/admin/users');
+  return result;
+};
+
+// Requires a recent sudo token; responses to the returned token carry an
+// X-Impersonated-By header naming the admin
+export const impersonateAdminUserApi = (token, userId, reason) =>
+  apiFetch(`/api/v1/admin/users/${userId}/impersonate`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json', Authorization: `Bearer ${token}` },
+    body: JSON.stringify({ reason }),
+  });
+
+// ============================================================
+// Admin Roles (permissions granted by each role)
+// ============================================================
+export const fetchAdminRolesApi = (token) =>
+  apiFetch('/api/v1/admin/roles', {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+
+// Requires a recent sudo token; replaces the role's permissions
+export const setRolePermissionsApi = (token, role, permissions) =>
+  apiFetch(`/api/v1/admin/roles/${role}/permissions`, {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json', Authorization: `Bearer ${token}` },
+    body: JSON.stringify({ permissions }),
+  });
+
+// ============================================================
+// Admin API Tokens (scoped tokens for embedded reporting dashboards)
+// ============================================================
+export const fetchApiTokensApi = (token) =>
+  apiFetch('/api/v1/admin/api-tokens', {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+
+// Requires a recent sudo token; the plaintext token is only returned here
+export const createApiTokenApi = (token, { name, scopes = ['analytics:read'], expiresInDays = 0 }) =>
+  apiFetch('/api/v1/admin/api-tokens', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json', Authorization: `Bearer ${token}` },
+    body: JSON.stringify({ name, scopes, expires_in_days: expiresInDays }),
+  });
+
+export const revokeApiTokenApi = (token, tokenId) =>
+  apiFetch(`/api/v1/admin/api-tokens/${tokenId}`, {
+    method: 'DELETE',
+    headers: { Authorization: `Bearer ${token}` },
+  });
+
+// ============================================================
+// Admin Data Retention (scheduled purges of deactivated users)
+// ============================================================
+export const fetchUserDeletionsApi = (token, status = '') =>
+  apiFetch(`/api/v1/admin/deletions${status ? `?status=${status}` : ''}`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+
+export const cancelUserDeletionApi = (token, deletionId) =>
+  apiFetch(`/api/v1/admin/deletions/${deletionId}/cancel`, {
+    method: 'POST',
+    headers: { Authorization: `Bearer ${token}` },
+  });
+
 // ============================================================
 // Admin Audit Logs API
 // ============================================================
 export const fetchAuditLogsApi = async (token, params = {}) => {
   const query = new URLSearchParams(params).toString();
-  return apiFetch(`/api/v1/admin/audit?${query}`, {
+  return apiFetch(`/api/v1/admin/audit-events?${query}`, {
     headers: { Authorization: `Bearer ${token}` },
   });
 };
 
+// Every event matching the filters (page and page_size are ignored), capped
+// at the server's export limit. Returns a CSV blob.
+export const downloadAuditLogsCsvApi = async (token, params = {}) => {
+  const query = new URLSearchParams({ ...params, format: 'csv' }).toString();
+  const res = await fetch(`${API_BASE}/api/v1/admin/audit-events?${query}`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+  if (!res.ok) throw new Error(`Failed to export audit logs: ${res.status}`);
+  return res.blob();
+};
+
 // ============================================================
 // Admin Model Runs API
 // ============================================================
@@ -470,3 +885,39 @@ export const fetchActiveModelApi = async (token) => {
   });
 };
 
+
+// ============================================================
+// Admin Assessment Re-validation API
+// ============================================================
+export const startRevalidationApi = async (token, since, dryRun = false) => {
+  const query = new URLSearchParams({ since, dry_run: String(dryRun) }).toString();
+  return apiFetch(`/api/v1/admin/assessments/revalidate?${query}`, {
+    method: 'POST',
+    headers: { Authorization: `Bearer ${token}` },
+  });
+};
+
+export const fetchRevalidationJobApi = async (token, jobId) => {
+  return apiFetch(`/api/v1/admin/assessments/revalidate/${jobId}`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+};
+
+// ============================================================
+// Admin Latency SLO API
+// ============================================================
+export const fetchAdminSLOApi = async (token) => {
+  return apiFetch('/api/v1/admin/slo', {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+};
+
+// ============================================================
+// Admin Experiments API
+// ============================================================
+export const fetchExperimentResultsApi = async (token, name, followUpDays = 180) => {
+  const query = new URLSearchParams({ follow_up_days: String(followUpDays) }).toString();
+  return apiFetch(`/api/v1/admin/experiments/${encodeURIComponent(name)}/results?${query}`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+};
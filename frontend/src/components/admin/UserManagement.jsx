@@ -5,6 +5,7 @@ import {
     createAdminUserApi,
     updateAdminUserApi,
     deactivateAdminUserApi,
+    sudoApi,
     activateAdminUserApi
 } from '../../api';
 import {
@@ -115,7 +116,16 @@ const UserManagement = ({ token }) => {
         if (!confirm(`Deactivate user ${user.email}?`)) return;
 
         try {
-            await deactivateAdminUserApi(token, user.id);
+            try {
+                await deactivateAdminUserApi(token, user.id);
+            } catch (err) {
+                // Destructive actions need a recent password re-verification
+                if (!err.message?.includes('sudo_required')) throw err;
+                const password = prompt('Confirm your password to continue');
+                if (!password) return;
+                const elevated = await sudoApi(token, password);
+                await deactivateAdminUserApi(elevated.access_token, user.id);
+            }
             setSuccess('User deactivated');
             loadUsers();
             setTimeout(() => setSuccess(null), 3000);